@@ -0,0 +1,269 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/your-org/5g-network/common/metrics"
+	"go.uber.org/zap"
+)
+
+// ErrOpen is returned for requests failed fast while the breaker is open
+var ErrOpen = errors.New("circuit breaker open")
+
+// Default breaker settings
+const (
+	DefaultFailureRatio     = 0.5
+	DefaultMinRequests      = 10
+	DefaultInterval         = 30 * time.Second
+	DefaultOpenTimeout      = 10 * time.Second
+	DefaultHalfOpenRequests = 1
+)
+
+// State is the state of a circuit breaker
+type State int
+
+const (
+	StateClosed   State = iota // Requests pass, failures are counted
+	StateOpen                  // Requests fail fast
+	StateHalfOpen              // A few probe requests pass
+)
+
+// String returns the state name
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return fmt.Sprintf("State(%d)", int(s))
+}
+
+// Config configures a circuit breaker. Zero fields fall back to the defaults
+// above.
+type Config struct {
+	Enabled bool `yaml:"enabled"`
+
+	// The breaker opens once the failure ratio of the requests counted in an
+	// interval reaches FailureRatio, after at least MinRequests requests
+	FailureRatio float64       `yaml:"failure_ratio"`
+	MinRequests  int           `yaml:"min_requests"`
+	Interval     time.Duration `yaml:"interval"`
+
+	// How long the breaker stays open before probing with HalfOpenRequests
+	// requests, all of which must succeed for it to close
+	OpenTimeout      time.Duration `yaml:"open_timeout"`
+	HalfOpenRequests int           `yaml:"half_open_requests"`
+}
+
+// Validate rejects out of range settings
+func (c *Config) Validate() error {
+	if c.FailureRatio < 0 || c.FailureRatio > 1 {
+		return fmt.Errorf("circuit breaker failure_ratio must be between 0 and 1")
+	}
+	if c.MinRequests < 0 || c.HalfOpenRequests < 0 {
+		return fmt.Errorf("circuit breaker request counts must not be negative")
+	}
+	if c.Interval < 0 || c.OpenTimeout < 0 {
+		return fmt.Errorf("circuit breaker durations must not be negative")
+	}
+	return nil
+}
+
+// withDefaults returns the config with zero fields set to the defaults
+func (c Config) withDefaults() Config {
+	if c.FailureRatio == 0 {
+		c.FailureRatio = DefaultFailureRatio
+	}
+	if c.MinRequests == 0 {
+		c.MinRequests = DefaultMinRequests
+	}
+	if c.Interval == 0 {
+		c.Interval = DefaultInterval
+	}
+	if c.OpenTimeout == 0 {
+		c.OpenTimeout = DefaultOpenTimeout
+	}
+	if c.HalfOpenRequests == 0 {
+		c.HalfOpenRequests = DefaultHalfOpenRequests
+	}
+	return c
+}
+
+// Breaker is a circuit breaker guarding the requests to one target NF
+type Breaker struct {
+	target string
+	cfg    Config
+	logger *zap.Logger
+	now    func() time.Time // Replaceable in tests
+
+	mu         sync.Mutex
+	state      State
+	generation uint64    // Bumped on every state change, outcomes of older requests are dropped
+	expiry     time.Time // End of the counting interval when closed, of the open period when open
+	requests   int
+	failures   int
+	successes  int // Half-open probes that succeeded
+}
+
+// New creates a closed circuit breaker for the requests to target
+func New(target string, cfg Config, logger *zap.Logger) *Breaker {
+	b := &Breaker{
+		target: target,
+		cfg:    cfg.withDefaults(),
+		logger: logger,
+		now:    time.Now,
+	}
+	b.setState(StateClosed, b.now())
+	return b
+}
+
+// State returns the current state of the breaker
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance(b.now())
+	return b.state
+}
+
+// result is the outcome of a request admitted by the breaker
+type result int
+
+const (
+	resultSuccess result = iota
+	resultFailure
+	resultIgnored // Given up by the caller, saying nothing of the target
+)
+
+// allow admits a request, returning the generation its result must be
+// reported in, or ErrOpen when it must fail fast
+func (b *Breaker) allow() (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance(b.now())
+
+	switch b.state {
+	case StateOpen:
+		metrics.CircuitBreakerRejections.WithLabelValues(b.target).Inc()
+		return 0, ErrOpen
+	case StateHalfOpen:
+		if b.requests >= b.cfg.HalfOpenRequests {
+			metrics.CircuitBreakerRejections.WithLabelValues(b.target).Inc()
+			return 0, ErrOpen
+		}
+	}
+	b.requests++
+	return b.generation, nil
+}
+
+// done records the result of a request admitted in generation
+func (b *Breaker) done(generation uint64, res result) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.advance(now)
+	if generation != b.generation {
+		return
+	}
+
+	switch {
+	case res == resultIgnored:
+		b.requests--
+	case b.state == StateClosed && res == resultFailure:
+		b.failures++
+		if b.requests >= b.cfg.MinRequests && float64(b.failures)/float64(b.requests) >= b.cfg.FailureRatio {
+			b.setState(StateOpen, now)
+		}
+	case b.state == StateHalfOpen && res == resultFailure:
+		b.setState(StateOpen, now)
+	case b.state == StateHalfOpen:
+		b.successes++
+		if b.successes >= b.cfg.HalfOpenRequests {
+			b.setState(StateClosed, now)
+		}
+	}
+}
+
+// advance moves the breaker past an elapsed counting interval or open period
+func (b *Breaker) advance(now time.Time) {
+	if now.Before(b.expiry) {
+		return
+	}
+	switch b.state {
+	case StateClosed:
+		b.setState(StateClosed, now) // Start a new counting interval
+	case StateOpen:
+		b.setState(StateHalfOpen, now)
+	}
+}
+
+// setState switches the breaker to state, resetting its counts
+func (b *Breaker) setState(state State, now time.Time) {
+	if state != b.state {
+		b.logger.Info("Circuit breaker state changed",
+			zap.String("target", b.target),
+			zap.Stringer("from", b.state),
+			zap.Stringer("to", state),
+		)
+	}
+
+	b.state = state
+	b.generation++
+	b.requests, b.failures, b.successes = 0, 0, 0
+	switch state {
+	case StateClosed:
+		b.expiry = now.Add(b.cfg.Interval)
+	case StateOpen:
+		b.expiry = now.Add(b.cfg.OpenTimeout)
+	case StateHalfOpen:
+		b.expiry = time.Time{}
+	}
+	metrics.CircuitBreakerState.WithLabelValues(b.target).Set(float64(state))
+}
+
+// Transport guards the requests of an HTTP client with a circuit breaker.
+// Connection errors and 5xx responses count as failures.
+type Transport struct {
+	next    http.RoundTripper
+	breaker *Breaker
+}
+
+// NewTransport wraps next, http.DefaultTransport when nil, with breaker
+func NewTransport(next http.RoundTripper, breaker *Breaker) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{next: next, breaker: breaker}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	generation, err := t.breaker.allow()
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("%w: %s", err, t.breaker.target)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	switch {
+	case errors.Is(err, context.Canceled):
+		t.breaker.done(generation, resultIgnored)
+	case err != nil, resp.StatusCode >= http.StatusInternalServerError:
+		t.breaker.done(generation, resultFailure)
+	default:
+		t.breaker.done(generation, resultSuccess)
+	}
+	return resp, err
+}
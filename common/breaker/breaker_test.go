@@ -0,0 +1,150 @@
+package breaker
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/your-org/5g-network/common/metrics"
+	"go.uber.org/zap"
+)
+
+// backend answers with the status it is set to, counting the requests that
+// reach it
+type backend struct {
+	*httptest.Server
+	status atomic.Int32
+	hits   atomic.Int32
+}
+
+func newBackend(t *testing.T) *backend {
+	t.Helper()
+
+	b := &backend{}
+	b.status.Store(http.StatusOK)
+	b.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b.hits.Add(1)
+		w.WriteHeader(int(b.status.Load()))
+	}))
+	t.Cleanup(b.Close)
+	return b
+}
+
+// newTestBreaker returns a breaker on a fake clock and a client it guards
+func newTestBreaker(target string, cfg Config) (*Breaker, *http.Client, *time.Time) {
+	b := New(target, cfg, zap.NewNop())
+	now := time.Now()
+	b.now = func() time.Time { return now }
+	return b, &http.Client{Transport: NewTransport(nil, b)}, &now
+}
+
+func get(t *testing.T, client *http.Client, url string) (int, error) {
+	t.Helper()
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func TestBreakerOpensFailsFastAndRecovers(t *testing.T) {
+	udr := newBackend(t)
+	b, client, now := newTestBreaker("UDR-recovery", Config{MinRequests: 4, FailureRatio: 0.5, OpenTimeout: 10 * time.Second})
+
+	// The UDR goes down: failures below the minimum request count leave the
+	// breaker closed
+	udr.status.Store(http.StatusServiceUnavailable)
+	for i := 0; i < 3; i++ {
+		status, err := get(t, client, udr.URL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, status)
+	}
+	assert.Equal(t, StateClosed, b.State())
+
+	_, err := get(t, client, udr.URL)
+	require.NoError(t, err)
+	assert.Equal(t, StateOpen, b.State())
+	assert.Equal(t, float64(StateOpen), testutil.ToFloat64(metrics.CircuitBreakerState.WithLabelValues("UDR-recovery")))
+
+	// Requests fail fast without reaching the UDR
+	_, err = get(t, client, udr.URL)
+	assert.True(t, errors.Is(err, ErrOpen))
+	assert.Equal(t, int32(4), udr.hits.Load())
+	assert.Equal(t, float64(1), testutil.ToFloat64(metrics.CircuitBreakerRejections.WithLabelValues("UDR-recovery")))
+
+	// Once the open period is over a probe goes through and, the UDR having
+	// healed, closes the breaker
+	udr.status.Store(http.StatusOK)
+	*now = now.Add(10 * time.Second)
+	assert.Equal(t, StateHalfOpen, b.State())
+
+	status, err := get(t, client, udr.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, StateClosed, b.State())
+	assert.Equal(t, float64(StateClosed), testutil.ToFloat64(metrics.CircuitBreakerState.WithLabelValues("UDR-recovery")))
+}
+
+func TestBreakerReopensOnFailedProbe(t *testing.T) {
+	udm := newBackend(t)
+	udm.Close() // Connection refused
+	b, client, now := newTestBreaker("UDM-probe", Config{MinRequests: 2, OpenTimeout: time.Second})
+
+	for i := 0; i < 2; i++ {
+		_, err := get(t, client, udm.URL)
+		assert.Error(t, err)
+	}
+	require.Equal(t, StateOpen, b.State())
+
+	*now = now.Add(time.Second)
+	_, err := get(t, client, udm.URL)
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrOpen), "the probe reaches the UDM")
+	assert.Equal(t, StateOpen, b.State())
+
+	_, err = get(t, client, udm.URL)
+	assert.True(t, errors.Is(err, ErrOpen))
+}
+
+func TestBreakerIgnoresClientErrors(t *testing.T) {
+	udr := newBackend(t)
+	udr.status.Store(http.StatusNotFound)
+	b, client, _ := newTestBreaker("UDR-4xx", Config{MinRequests: 2})
+
+	for i := 0; i < 5; i++ {
+		status, err := get(t, client, udr.URL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, status)
+	}
+	assert.Equal(t, StateClosed, b.State())
+}
+
+func TestBreakerCountsFailuresPerInterval(t *testing.T) {
+	udr := newBackend(t)
+	b, client, now := newTestBreaker("UDR-interval", Config{MinRequests: 4, FailureRatio: 0.5, Interval: time.Minute})
+
+	udr.status.Store(http.StatusInternalServerError)
+	for i := 0; i < 3; i++ {
+		get(t, client, udr.URL)
+	}
+
+	// The failures of the previous interval are forgotten
+	*now = now.Add(time.Minute)
+	get(t, client, udr.URL)
+	assert.Equal(t, StateClosed, b.State())
+}
+
+func TestConfigValidate(t *testing.T) {
+	assert.NoError(t, (&Config{}).Validate())
+	assert.Error(t, (&Config{FailureRatio: 1.5}).Validate())
+	assert.Error(t, (&Config{MinRequests: -1}).Validate())
+	assert.Error(t, (&Config{OpenTimeout: -time.Second}).Validate())
+}
@@ -0,0 +1,62 @@
+package buildinfo
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+)
+
+// Info describes the build of a network function
+type Info struct {
+	NFType    string   `json:"nfType"`
+	Version   string   `json:"version"`
+	GitCommit string   `json:"gitCommit"`
+	BuildTime string   `json:"buildTime"`
+	GoVersion string   `json:"goVersion"`
+	Features  []string `json:"features"`
+}
+
+// New creates build info from the variables injected at link time. When no
+// commit is injected, the VCS revision recorded by the Go toolchain is used.
+func New(nfType, version, gitCommit, buildTime string, features ...string) *Info {
+	if gitCommit == "" || gitCommit == "unknown" {
+		gitCommit = vcsRevision()
+	}
+	if features == nil {
+		features = []string{}
+	}
+
+	return &Info{
+		NFType:    nfType,
+		Version:   version,
+		GitCommit: gitCommit,
+		BuildTime: buildTime,
+		GoVersion: runtime.Version(),
+		Features:  features,
+	}
+}
+
+// Handler serves the build info as JSON (GET /version)
+func (i *Info) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(i)
+	}
+}
+
+// vcsRevision returns the VCS revision embedded by the Go toolchain
+func vcsRevision() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			return setting.Value
+		}
+	}
+	return "unknown"
+}
@@ -0,0 +1,38 @@
+package buildinfo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandlerReturnsInjectedBuildVariables(t *testing.T) {
+	info := New("AMF", "v1.2.3", "abc1234", "2026-01-02T03:04:05Z", "emergency-registration", "tls-hardening")
+
+	rec := httptest.NewRecorder()
+	info.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var got Info
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+	assert.Equal(t, "AMF", got.NFType)
+	assert.Equal(t, "v1.2.3", got.Version)
+	assert.Equal(t, "abc1234", got.GitCommit)
+	assert.Equal(t, "2026-01-02T03:04:05Z", got.BuildTime)
+	assert.Equal(t, runtime.Version(), got.GoVersion)
+	assert.Equal(t, []string{"emergency-registration", "tls-hardening"}, got.Features)
+}
+
+func TestNewDefaultsMissingCommitAndFeatures(t *testing.T) {
+	info := New("SMF", "dev", "unknown", "unknown")
+
+	assert.NotEmpty(t, info.GitCommit)
+	assert.NotNil(t, info.Features)
+}
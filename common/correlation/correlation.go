@@ -0,0 +1,144 @@
+// Package correlation propagates a correlation ID and W3C trace context
+// (traceparent) across the SBI calls made on behalf of one request, so the
+// logs of every NF it crosses (e.g. AMF→AUSF→UDM→UDR) can be joined.
+package correlation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Propagated headers
+const (
+	HeaderCorrelationID = "X-Correlation-ID"
+	HeaderTraceParent   = "traceparent"
+)
+
+// ids identifies the request a context belongs to
+type ids struct {
+	correlationID string
+	traceID       string // 32 hex digits
+	flags         string // 2 hex digits
+}
+
+type contextKey struct{}
+
+// WithID returns a context carrying a correlation ID, starting a new trace
+func WithID(ctx context.Context, correlationID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, ids{
+		correlationID: correlationID,
+		traceID:       randomHex(16),
+		flags:         "00",
+	})
+}
+
+// ID returns the correlation ID carried by a context, empty when none
+func ID(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(ids)
+	return id.correlationID
+}
+
+// Field returns the correlation ID of a context as a log field
+func Field(ctx context.Context) zap.Field {
+	return zap.String("correlation_id", ID(ctx))
+}
+
+// Middleware stores the correlation ID and trace of inbound requests in their
+// context. Both are taken from the request headers when present; a missing
+// correlation ID falls back to the trace ID, a missing trace is started. The
+// correlation ID is echoed in the response.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := ids{correlationID: r.Header.Get(HeaderCorrelationID)}
+		id.traceID, id.flags = parseTraceParent(r.Header.Get(HeaderTraceParent))
+		if id.traceID == "" {
+			id.traceID, id.flags = randomHex(16), "00"
+		}
+		if id.correlationID == "" {
+			id.correlationID = id.traceID
+		}
+
+		w.Header().Set(HeaderCorrelationID, id.correlationID)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), contextKey{}, id)))
+	})
+}
+
+// Transport sets the correlation ID and a traceparent with a new span on
+// outbound requests. Requests made outside an inbound request get a new ID.
+type Transport struct {
+	next   http.RoundTripper
+	logger *zap.Logger
+}
+
+// NewTransport wraps next, http.DefaultTransport when nil, with correlation
+// header propagation
+func NewTransport(next http.RoundTripper, logger *zap.Logger) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{next: next, logger: logger}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	id, ok := req.Context().Value(contextKey{}).(ids)
+	if !ok {
+		id = ids{traceID: randomHex(16), flags: "00"}
+		id.correlationID = id.traceID
+	}
+
+	// A RoundTripper must not modify the caller's request
+	req = req.Clone(req.Context())
+	if req.Header.Get(HeaderCorrelationID) == "" {
+		req.Header.Set(HeaderCorrelationID, id.correlationID)
+	}
+	if req.Header.Get(HeaderTraceParent) == "" {
+		req.Header.Set(HeaderTraceParent, fmt.Sprintf("00-%s-%s-%s", id.traceID, randomHex(8), id.flags))
+	}
+
+	t.logger.Debug("Outbound SBI request",
+		zap.String("method", req.Method),
+		zap.String("url", req.URL.String()),
+		zap.String("correlation_id", req.Header.Get(HeaderCorrelationID)),
+	)
+
+	return t.next.RoundTrip(req)
+}
+
+// parseTraceParent returns the trace ID and flags of a version 00
+// traceparent header, empty when it is missing or malformed
+func parseTraceParent(header string) (traceID, flags string) {
+	parts := strings.Split(header, "-")
+	if len(parts) != 4 || parts[0] != "00" ||
+		!isHex(parts[1], 32) || !isHex(parts[2], 16) || !isHex(parts[3], 2) ||
+		parts[1] == strings.Repeat("0", 32) || parts[2] == strings.Repeat("0", 16) {
+		return "", ""
+	}
+	return parts[1], parts[3]
+}
+
+// isHex reports whether s is n lowercase hex digits
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// randomHex returns n random bytes in hex
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
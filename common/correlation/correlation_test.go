@@ -0,0 +1,103 @@
+package correlation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newRelay returns an NF that calls backend for every request it serves,
+// as the AUSF calls the UDM
+func newRelay(t *testing.T, backend string) *httptest.Server {
+	t.Helper()
+
+	client := &http.Client{Transport: NewTransport(nil, zap.NewNop())}
+	relay := httptest.NewServer(Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, backend, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	})))
+	t.Cleanup(relay.Close)
+	return relay
+}
+
+func TestPropagatesInboundIDs(t *testing.T) {
+	var outbound http.Header
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outbound = r.Header.Clone()
+	}))
+	defer backend.Close()
+	relay := newRelay(t, backend.URL)
+
+	req, err := http.NewRequest(http.MethodGet, relay.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set(HeaderCorrelationID, "reg-imsi-001010000000001")
+	req.Header.Set(HeaderTraceParent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, "reg-imsi-001010000000001", resp.Header.Get(HeaderCorrelationID))
+	assert.Equal(t, "reg-imsi-001010000000001", outbound.Get(HeaderCorrelationID))
+
+	// Same trace, new span
+	traceID, flags := parseTraceParent(outbound.Get(HeaderTraceParent))
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", traceID)
+	assert.Equal(t, "01", flags)
+	assert.NotContains(t, outbound.Get(HeaderTraceParent), "00f067aa0ba902b7")
+}
+
+func TestStartsTraceWithoutInboundIDs(t *testing.T) {
+	var outbound http.Header
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outbound = r.Header.Clone()
+	}))
+	defer backend.Close()
+	relay := newRelay(t, backend.URL)
+
+	req, err := http.NewRequest(http.MethodGet, relay.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set(HeaderTraceParent, "00-not-a-trace-01")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	id := resp.Header.Get(HeaderCorrelationID)
+	assert.Len(t, id, 32)
+	assert.Equal(t, id, outbound.Get(HeaderCorrelationID))
+	assert.True(t, strings.HasPrefix(outbound.Get(HeaderTraceParent), "00-"+id+"-"))
+}
+
+func TestTransportOutsideRequest(t *testing.T) {
+	var outbound http.Header
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outbound = r.Header.Clone()
+	}))
+	defer backend.Close()
+	client := &http.Client{Transport: NewTransport(nil, zap.NewNop())}
+
+	// Background work gets a fresh ID unless it sets one
+	resp, err := client.Get(backend.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.NotEmpty(t, outbound.Get(HeaderCorrelationID))
+	traceID, _ := parseTraceParent(outbound.Get(HeaderTraceParent))
+	assert.NotEmpty(t, traceID)
+
+	ctx := WithID(context.Background(), "nrf-heartbeat")
+	assert.Equal(t, "nrf-heartbeat", ID(ctx))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, backend.URL, nil)
+	require.NoError(t, err)
+	resp, err = client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "nrf-heartbeat", outbound.Get(HeaderCorrelationID))
+}
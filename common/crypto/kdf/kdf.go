@@ -0,0 +1,101 @@
+// Package kdf implements the generic 3GPP key derivation function (TS 33.220
+// Annex B) and the 5G AKA key derivations built on it (TS 33.501 Annex A).
+package kdf
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// FC values for 5G AKA derivations (TS 33.501 Annex A)
+const (
+	FCKAUSF   = 0x6A // A.2: KAUSF from CK || IK
+	FCRESStar = 0x6B // A.4: RES* and XRES*
+	FCKSEAF   = 0x6C // A.6: KSEAF from KAUSF
+	FCKAMF    = 0x6D // A.7: KAMF from KSEAF
+	FCAlgKey  = 0x69 // A.8: NAS and AS algorithm keys
+)
+
+// Algorithm type distinguishers for algorithm key derivation (TS 33.501
+// Table A.8-1)
+const (
+	NNASEncAlg = 0x01
+	NNASIntAlg = 0x02
+)
+
+// maxParamLen is the largest parameter length encodable in Li
+const maxParamLen = 0xffff
+
+// Derive computes HMAC-SHA-256(key, FC || P0 || L0 || ... || Pn || Ln), where
+// Li is the two-byte length of Pi (TS 33.220 B.2)
+func Derive(key []byte, fc byte, params ...[]byte) ([]byte, error) {
+	s := []byte{fc}
+	for i, p := range params {
+		if len(p) > maxParamLen {
+			return nil, fmt.Errorf("KDF parameter P%d too long: %d bytes", i, len(p))
+		}
+		s = append(s, p...)
+		s = binary.BigEndian.AppendUint16(s, uint16(len(p)))
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(s)
+	return mac.Sum(nil), nil
+}
+
+// KAUSF derives KAUSF from CK || IK with P0 = serving network name and
+// P1 = SQN XOR AK (TS 33.501 A.2)
+func KAUSF(ck, ik []byte, servingNetworkName string, sqnXorAK []byte) ([]byte, error) {
+	return Derive(concat(ck, ik), FCKAUSF, []byte(servingNetworkName), sqnXorAK)
+}
+
+// XRESStar derives RES* or XRES* from CK || IK with P0 = serving network
+// name, P1 = RAND and P2 = RES or XRES. It is the 128 least significant bits
+// of the KDF output (TS 33.501 A.4).
+func XRESStar(ck, ik []byte, servingNetworkName string, rand, xres []byte) ([]byte, error) {
+	out, err := Derive(concat(ck, ik), FCRESStar, []byte(servingNetworkName), rand, xres)
+	if err != nil {
+		return nil, err
+	}
+	return out[16:], nil
+}
+
+// HXRESStar computes HXRES* as the 128 least significant bits of
+// SHA-256(RAND || XRES*) (TS 33.501 A.5)
+func HXRESStar(rand, xresStar []byte) []byte {
+	sum := sha256.Sum256(concat(rand, xresStar))
+	return sum[16:]
+}
+
+// KSEAF derives KSEAF from KAUSF with P0 = serving network name
+// (TS 33.501 A.6)
+func KSEAF(kausf []byte, servingNetworkName string) ([]byte, error) {
+	return Derive(kausf, FCKSEAF, []byte(servingNetworkName))
+}
+
+// KAMF derives KAMF from KSEAF with P0 = SUPI and P1 = ABBA (TS 33.501
+// A.7). An IMSI SUPI is input as its digits, without the "imsi-" prefix.
+func KAMF(kseaf []byte, supi string, abba []byte) ([]byte, error) {
+	return Derive(kseaf, FCKAMF, []byte(strings.TrimPrefix(supi, "imsi-")), abba)
+}
+
+// AlgorithmKey derives the key of a NAS or AS security algorithm from KAMF
+// or KgNB with P0 = algorithm type distinguisher and P1 = algorithm
+// identity. It is the 128 least significant bits of the KDF output (TS
+// 33.501 A.8).
+func AlgorithmKey(key []byte, distinguisher, algorithm byte) ([]byte, error) {
+	out, err := Derive(key, FCAlgKey, []byte{distinguisher}, []byte{algorithm})
+	if err != nil {
+		return nil, err
+	}
+	return out[16:], nil
+}
+
+func concat(a, b []byte) []byte {
+	out := make([]byte, 0, len(a)+len(b))
+	out = append(out, a...)
+	return append(out, b...)
+}
@@ -0,0 +1,91 @@
+package kdf
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Inputs are the MILENAGE outputs of TS 35.208 test set 1. Expected values
+// were computed independently from the TS 33.220 B.2 definition.
+const (
+	testCK       = "b40ba9a3c58b2a05bbf0d987b21bf8cb"
+	testIK       = "f769bcd751044604127672711c6d3441"
+	testRAND     = "23553cbe9637a89d218ae64dae47bf35"
+	testRES      = "a54211d5e3ba50bf"
+	testSQNXorAK = "55f328b43577"
+	testSNName   = "5G:mnc001.mcc001.3gppnetwork.org"
+
+	testKAUSF     = "474698caf02cc715db2ec0726510cfee6caa5bb1a649cb01224f2e23af94de1b"
+	testXRESStar  = "f236a7417272bfb2d66d4d670733b527"
+	testHXRESStar = "20a71900b01776bfd773e8c15a825446"
+	testKSEAF     = "8dff166c02edd5b177950d50cdd3fe93756cc53951856a95cb5ee9aabd35e220"
+
+	testSUPI    = "imsi-001010000000001"
+	testKAMF    = "daae216bc3dc9c6e0db9e56d2b744ea247d67eed51fdf2411847d056ec45a666"
+	testKNASenc = "d4c73a6303aa6b0cae734c0518134f1e" // 128-NEA2
+	testKNASint = "06c661bdcb505f1690bea90685d939f5" // 128-NIA2
+)
+
+func decode(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+func TestKAUSF(t *testing.T) {
+	kausf, err := KAUSF(decode(t, testCK), decode(t, testIK), testSNName, decode(t, testSQNXorAK))
+	require.NoError(t, err)
+	assert.Equal(t, testKAUSF, hex.EncodeToString(kausf))
+}
+
+func TestXRESStar(t *testing.T) {
+	xresStar, err := XRESStar(decode(t, testCK), decode(t, testIK), testSNName, decode(t, testRAND), decode(t, testRES))
+	require.NoError(t, err)
+	assert.Equal(t, testXRESStar, hex.EncodeToString(xresStar))
+
+	hxresStar := HXRESStar(decode(t, testRAND), xresStar)
+	assert.Equal(t, testHXRESStar, hex.EncodeToString(hxresStar))
+}
+
+func TestKSEAF(t *testing.T) {
+	kseaf, err := KSEAF(decode(t, testKAUSF), testSNName)
+	require.NoError(t, err)
+	assert.Equal(t, testKSEAF, hex.EncodeToString(kseaf))
+}
+
+func TestKAMF(t *testing.T) {
+	kamf, err := KAMF(decode(t, testKSEAF), testSUPI, []byte{0x00, 0x00})
+	require.NoError(t, err)
+	assert.Equal(t, testKAMF, hex.EncodeToString(kamf))
+
+	// The IMSI digits are the SUPI input with or without the prefix
+	bare, err := KAMF(decode(t, testKSEAF), "001010000000001", []byte{0x00, 0x00})
+	require.NoError(t, err)
+	assert.Equal(t, kamf, bare)
+}
+
+func TestAlgorithmKey(t *testing.T) {
+	knasEnc, err := AlgorithmKey(decode(t, testKAMF), NNASEncAlg, 2)
+	require.NoError(t, err)
+	assert.Equal(t, testKNASenc, hex.EncodeToString(knasEnc))
+
+	knasInt, err := AlgorithmKey(decode(t, testKAMF), NNASIntAlg, 2)
+	require.NoError(t, err)
+	assert.Equal(t, testKNASint, hex.EncodeToString(knasInt))
+}
+
+func TestDerive_EncodesParameterLengths(t *testing.T) {
+	// Moving a byte between parameters changes the encoded lengths
+	a, err := Derive([]byte("key"), 0x6C, []byte("ab"), []byte("c"))
+	require.NoError(t, err)
+	b, err := Derive([]byte("key"), 0x6C, []byte("a"), []byte("bc"))
+	require.NoError(t, err)
+	assert.NotEqual(t, a, b)
+
+	_, err = Derive([]byte("key"), 0x6C, make([]byte, maxParamLen+1))
+	assert.Error(t, err)
+}
@@ -0,0 +1,256 @@
+// Package milenage implements the 3GPP MILENAGE algorithm set (TS 35.206)
+// used by 5G AKA to derive MAC-A/MAC-S, RES, CK, IK and the anonymity keys.
+package milenage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+)
+
+// Field sizes in bytes
+const (
+	KeyLen  = 16
+	RANDLen = 16
+	SQNLen  = 6
+	AMFLen  = 2
+	MACLen  = 8
+	RESLen  = 8
+	AKLen   = 6
+	AUTNLen = SQNLen + AMFLen + MACLen
+	AUTSLen = SQNLen + MACLen
+)
+
+// resyncAMF is the dummy AMF used for MAC-S (TS 33.102 6.3.3)
+var resyncAMF = []byte{0x00, 0x00}
+
+// ErrMACSMismatch is returned when the MAC-S in AUTS fails verification
+var ErrMACSMismatch = errors.New("MAC-S verification failed")
+
+// Rotation amounts r1..r5 in bytes and the last byte of constants c1..c5
+// (TS 35.206 4.1). All rotations are whole bytes.
+var (
+	rotations = [5]int{8, 0, 4, 8, 12}
+	constants = [5]byte{0x00, 0x01, 0x02, 0x04, 0x08}
+)
+
+// Milenage computes f1-f5 for one subscriber key and OPc
+type Milenage struct {
+	block cipher.Block
+	opc   [16]byte
+}
+
+// New creates a MILENAGE instance for subscriber key k and the operator
+// variant OPc
+func New(k, opc []byte) (*Milenage, error) {
+	if len(k) != KeyLen {
+		return nil, fmt.Errorf("K must be %d bytes, got %d", KeyLen, len(k))
+	}
+	if len(opc) != KeyLen {
+		return nil, fmt.Errorf("OPc must be %d bytes, got %d", KeyLen, len(opc))
+	}
+
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES cipher: %w", err)
+	}
+
+	m := &Milenage{block: block}
+	copy(m.opc[:], opc)
+	return m, nil
+}
+
+// ComputeOPc derives OPc = E[K](OP) XOR OP
+func ComputeOPc(k, op []byte) ([]byte, error) {
+	if len(k) != KeyLen {
+		return nil, fmt.Errorf("K must be %d bytes, got %d", KeyLen, len(k))
+	}
+	if len(op) != KeyLen {
+		return nil, fmt.Errorf("OP must be %d bytes, got %d", KeyLen, len(op))
+	}
+
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES cipher: %w", err)
+	}
+
+	opc := make([]byte, KeyLen)
+	block.Encrypt(opc, op)
+	xor(opc, op)
+	return opc, nil
+}
+
+// F1 computes the network authentication code MAC-A (f1) and the
+// resynchronisation code MAC-S (f1*)
+func (m *Milenage) F1(rand, sqn, amf []byte) (macA, macS []byte, err error) {
+	if err := checkLen("RAND", rand, RANDLen); err != nil {
+		return nil, nil, err
+	}
+	if err := checkLen("SQN", sqn, SQNLen); err != nil {
+		return nil, nil, err
+	}
+	if err := checkLen("AMF", amf, AMFLen); err != nil {
+		return nil, nil, err
+	}
+
+	temp := m.temp(rand)
+
+	// IN1 = SQN || AMF || SQN || AMF
+	var in1 [16]byte
+	copy(in1[0:6], sqn)
+	copy(in1[6:8], amf)
+	copy(in1[8:14], sqn)
+	copy(in1[14:16], amf)
+
+	// OUT1 = E[K](TEMP XOR rot(IN1 XOR OPc, r1) XOR c1) XOR OPc
+	xor(in1[:], m.opc[:])
+	block := rotate(in1, rotations[0])
+	xor(block[:], temp[:])
+	block[15] ^= constants[0]
+	out := m.encrypt(block)
+
+	return out[0:8], out[8:16], nil
+}
+
+// F2345 computes RES (f2), CK (f3), IK (f4) and AK (f5)
+func (m *Milenage) F2345(rand []byte) (res, ck, ik, ak []byte, err error) {
+	if err := checkLen("RAND", rand, RANDLen); err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	temp := m.temp(rand)
+
+	out2 := m.out(temp, 1)
+	out3 := m.out(temp, 2)
+	out4 := m.out(temp, 3)
+
+	return out2[8:16], out3[:], out4[:], out2[0:6], nil
+}
+
+// F5Star computes the resynchronisation anonymity key AK (f5*)
+func (m *Milenage) F5Star(rand []byte) ([]byte, error) {
+	if err := checkLen("RAND", rand, RANDLen); err != nil {
+		return nil, err
+	}
+
+	out5 := m.out(m.temp(rand), 4)
+	return out5[0:6], nil
+}
+
+// GenerateAUTN computes AUTN = (SQN XOR AK) || AMF || MAC-A together with
+// the RES, CK, IK and AK of the same challenge
+func (m *Milenage) GenerateAUTN(rand, sqn, amf []byte) (autn, res, ck, ik, ak []byte, err error) {
+	macA, _, err := m.F1(rand, sqn, amf)
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+	res, ck, ik, ak, err = m.F2345(rand)
+	if err != nil {
+		return nil, nil, nil, nil, nil, err
+	}
+
+	autn = make([]byte, AUTNLen)
+	for i := 0; i < SQNLen; i++ {
+		autn[i] = sqn[i] ^ ak[i]
+	}
+	copy(autn[6:8], amf)
+	copy(autn[8:16], macA)
+	return autn, res, ck, ik, ak, nil
+}
+
+// GenerateAUTS computes the resynchronisation token
+// AUTS = (SQN_MS XOR AK*) || MAC-S that a USIM returns on a sequence number
+// failure (TS 33.102 6.3.3)
+func (m *Milenage) GenerateAUTS(rand, sqnMS []byte) ([]byte, error) {
+	_, macS, err := m.F1(rand, sqnMS, resyncAMF)
+	if err != nil {
+		return nil, err
+	}
+	akStar, err := m.F5Star(rand)
+	if err != nil {
+		return nil, err
+	}
+
+	auts := make([]byte, AUTSLen)
+	for i := 0; i < SQNLen; i++ {
+		auts[i] = sqnMS[i] ^ akStar[i]
+	}
+	copy(auts[6:], macS)
+	return auts, nil
+}
+
+// RecoverSQN extracts SQN_MS from AUTS and verifies its MAC-S. It returns an
+// error if the MAC-S does not match.
+func (m *Milenage) RecoverSQN(rand, auts []byte) ([]byte, error) {
+	if err := checkLen("AUTS", auts, AUTSLen); err != nil {
+		return nil, err
+	}
+	akStar, err := m.F5Star(rand)
+	if err != nil {
+		return nil, err
+	}
+
+	sqnMS := make([]byte, SQNLen)
+	for i := range sqnMS {
+		sqnMS[i] = auts[i] ^ akStar[i]
+	}
+
+	_, macS, err := m.F1(rand, sqnMS, resyncAMF)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(macS, auts[6:]) != 1 {
+		return nil, ErrMACSMismatch
+	}
+	return sqnMS, nil
+}
+
+// temp computes TEMP = E[K](RAND XOR OPc)
+func (m *Milenage) temp(rand []byte) [16]byte {
+	var in, out [16]byte
+	copy(in[:], rand)
+	xor(in[:], m.opc[:])
+	m.block.Encrypt(out[:], in[:])
+	return out
+}
+
+// out computes OUTi = E[K](rot(TEMP XOR OPc, ri) XOR ci) XOR OPc for
+// i = 2..5, indexed from zero
+func (m *Milenage) out(temp [16]byte, i int) [16]byte {
+	xor(temp[:], m.opc[:])
+	block := rotate(temp, rotations[i])
+	block[15] ^= constants[i]
+	return m.encrypt(block)
+}
+
+// encrypt returns E[K](in) XOR OPc
+func (m *Milenage) encrypt(in [16]byte) [16]byte {
+	var out [16]byte
+	m.block.Encrypt(out[:], in[:])
+	xor(out[:], m.opc[:])
+	return out
+}
+
+// rotate cyclically rotates a 128-bit block left by n bytes
+func rotate(in [16]byte, n int) [16]byte {
+	var out [16]byte
+	for i := range out {
+		out[i] = in[(i+n)%16]
+	}
+	return out
+}
+
+func xor(dst, src []byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
+
+func checkLen(name string, b []byte, n int) error {
+	if len(b) != n {
+		return fmt.Errorf("%s must be %d bytes, got %d", name, n, len(b))
+	}
+	return nil
+}
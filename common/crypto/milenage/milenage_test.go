@@ -0,0 +1,178 @@
+package milenage
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSet is a MILENAGE conformance test set from TS 35.208 4.3
+type testSet struct {
+	name                               string
+	k, rand, sqn, amf, op, opc         string
+	f1, f1Star, f2, f5, f3, f4, f5Star string
+}
+
+var testSets = []testSet{
+	{
+		name:   "test set 1",
+		k:      "465b5ce8b199b49faa5f0a2ee238a6bc",
+		rand:   "23553cbe9637a89d218ae64dae47bf35",
+		sqn:    "ff9bb4d0b607",
+		amf:    "b9b9",
+		op:     "cdc202d5123e20f62b6d676ac72cb318",
+		opc:    "cd63cb71954a9f4e48a5994e37a02baf",
+		f1:     "4a9ffac354dfafb3",
+		f1Star: "01cfaf9ec4e871e9",
+		f2:     "a54211d5e3ba50bf",
+		f5:     "aa689c648370",
+		f3:     "b40ba9a3c58b2a05bbf0d987b21bf8cb",
+		f4:     "f769bcd751044604127672711c6d3441",
+		f5Star: "451e8beca43b",
+	},
+	{
+		name:   "test set 2",
+		k:      "0396eb317b6d1c36f19c1c84cd6ffd16",
+		rand:   "c00d603103dcee52c4478119494202e8",
+		sqn:    "fd8eef40df7d",
+		amf:    "af17",
+		op:     "ff53bade17df5d4e793073ce9d7579fa",
+		opc:    "53c15671c60a4b731c55b4a441c0bde2",
+		f1:     "5df5b31807e258b0",
+		f1Star: "a8c016e51ef4a343",
+		f2:     "d3a628ed988620f0",
+		f5:     "c47783995f72",
+		f3:     "58c433ff7a7082acd424220f2b67c556",
+		f4:     "21a8c1f929702adb3e738488b9f5c5da",
+		f5Star: "30f1197061c1",
+	},
+}
+
+func decode(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+func TestComputeOPc(t *testing.T) {
+	for _, ts := range testSets {
+		t.Run(ts.name, func(t *testing.T) {
+			opc, err := ComputeOPc(decode(t, ts.k), decode(t, ts.op))
+			require.NoError(t, err)
+			assert.Equal(t, ts.opc, hex.EncodeToString(opc))
+		})
+	}
+}
+
+func TestF1(t *testing.T) {
+	for _, ts := range testSets {
+		t.Run(ts.name, func(t *testing.T) {
+			m, err := New(decode(t, ts.k), decode(t, ts.opc))
+			require.NoError(t, err)
+
+			macA, macS, err := m.F1(decode(t, ts.rand), decode(t, ts.sqn), decode(t, ts.amf))
+			require.NoError(t, err)
+			assert.Equal(t, ts.f1, hex.EncodeToString(macA))
+			assert.Equal(t, ts.f1Star, hex.EncodeToString(macS))
+		})
+	}
+}
+
+func TestF2345(t *testing.T) {
+	for _, ts := range testSets {
+		t.Run(ts.name, func(t *testing.T) {
+			m, err := New(decode(t, ts.k), decode(t, ts.opc))
+			require.NoError(t, err)
+
+			res, ck, ik, ak, err := m.F2345(decode(t, ts.rand))
+			require.NoError(t, err)
+			assert.Equal(t, ts.f2, hex.EncodeToString(res))
+			assert.Equal(t, ts.f3, hex.EncodeToString(ck))
+			assert.Equal(t, ts.f4, hex.EncodeToString(ik))
+			assert.Equal(t, ts.f5, hex.EncodeToString(ak))
+
+			akStar, err := m.F5Star(decode(t, ts.rand))
+			require.NoError(t, err)
+			assert.Equal(t, ts.f5Star, hex.EncodeToString(akStar))
+		})
+	}
+}
+
+func TestGenerateAUTN(t *testing.T) {
+	ts := testSets[0]
+	m, err := New(decode(t, ts.k), decode(t, ts.opc))
+	require.NoError(t, err)
+
+	autn, res, _, _, ak, err := m.GenerateAUTN(decode(t, ts.rand), decode(t, ts.sqn), decode(t, ts.amf))
+	require.NoError(t, err)
+	assert.Equal(t, "55f328b43577"+ts.amf+ts.f1, hex.EncodeToString(autn))
+	assert.Equal(t, ts.f2, hex.EncodeToString(res))
+	assert.Equal(t, ts.f5, hex.EncodeToString(ak))
+}
+
+func TestAUTSRoundTrip(t *testing.T) {
+	for _, ts := range testSets {
+		t.Run(ts.name, func(t *testing.T) {
+			m, err := New(decode(t, ts.k), decode(t, ts.opc))
+			require.NoError(t, err)
+			rand, sqn := decode(t, ts.rand), decode(t, ts.sqn)
+
+			auts, err := m.GenerateAUTS(rand, sqn)
+			require.NoError(t, err)
+			require.Len(t, auts, AUTSLen)
+
+			// Conc(SQN_MS) is concealed with AK* and MAC-S uses AMF 0000
+			akStar := decode(t, ts.f5Star)
+			for i := 0; i < SQNLen; i++ {
+				assert.Equal(t, sqn[i]^akStar[i], auts[i])
+			}
+			_, macS, err := m.F1(rand, sqn, []byte{0x00, 0x00})
+			require.NoError(t, err)
+			assert.Equal(t, macS, auts[6:])
+
+			recovered, err := m.RecoverSQN(rand, auts)
+			require.NoError(t, err)
+			assert.Equal(t, sqn, recovered)
+		})
+	}
+}
+
+func TestRecoverSQN_RejectsBadMACS(t *testing.T) {
+	ts := testSets[0]
+	m, err := New(decode(t, ts.k), decode(t, ts.opc))
+	require.NoError(t, err)
+	rand := decode(t, ts.rand)
+
+	auts, err := m.GenerateAUTS(rand, decode(t, ts.sqn))
+	require.NoError(t, err)
+
+	tampered := append([]byte(nil), auts...)
+	tampered[len(tampered)-1] ^= 0x01
+	_, err = m.RecoverSQN(rand, tampered)
+	assert.ErrorIs(t, err, ErrMACSMismatch)
+
+	// A different SQN_MS invalidates MAC-S too
+	tampered = append([]byte(nil), auts...)
+	tampered[0] ^= 0x80
+	_, err = m.RecoverSQN(rand, tampered)
+	assert.ErrorIs(t, err, ErrMACSMismatch)
+
+	_, err = m.RecoverSQN(rand, auts[:10])
+	assert.Error(t, err)
+}
+
+func TestInvalidLengths(t *testing.T) {
+	_, err := New(make([]byte, 15), make([]byte, 16))
+	assert.Error(t, err)
+
+	m, err := New(make([]byte, 16), make([]byte, 16))
+	require.NoError(t, err)
+
+	_, _, err = m.F1(make([]byte, 16), make([]byte, 5), make([]byte, 2))
+	assert.Error(t, err)
+	_, _, _, _, err = m.F2345(make([]byte, 8))
+	assert.Error(t, err)
+}
@@ -0,0 +1,273 @@
+// Package suci de-conceals Subscription Concealed Identifiers (TS 33.501
+// 6.12.2 and Annex C) into the SUPI they protect.
+package suci
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Protection scheme identifiers (TS 33.501 Annex C.1)
+const (
+	SchemeNull     = 0
+	SchemeProfileA = 1 // ECIES over Curve25519
+	SchemeProfileB = 2 // ECIES over secp256r1
+)
+
+// ECIES parameters common to both profiles (TS 33.501 C.3.4)
+const (
+	encKeyLen = 16 // AES-128 key
+	icbLen    = 16 // AES-CTR initial counter block
+	macKeyLen = 32 // HMAC-SHA-256 key
+	macLen    = 8  // Truncated MAC tag
+
+	profileAPublicKeyLen = 32 // X25519 point
+	profileBPublicKeyLen = 33 // Compressed P-256 point
+)
+
+var (
+	// ErrInvalidSUCI is returned when a SUCI string is malformed
+	ErrInvalidSUCI = errors.New("invalid SUCI")
+
+	// ErrUnsupportedScheme is returned for protection schemes other than the
+	// null scheme and ECIES profiles A and B, or for non-IMSI SUPIs
+	ErrUnsupportedScheme = errors.New("unsupported SUCI protection scheme")
+
+	// ErrUnknownKey is returned when no home network key has the SUCI's key
+	// identifier and protection scheme
+	ErrUnknownKey = errors.New("unknown home network public key identifier")
+
+	// ErrMACFailure is returned when the scheme output fails its MAC check
+	ErrMACFailure = errors.New("SUCI MAC verification failed")
+)
+
+// SUCI is a parsed SUCI string (TS 29.503 6.1.6.2.3
+// suci-0-<mcc>-<mnc>-<routing indicator>-<scheme>-<key id>-<scheme output>)
+type SUCI struct {
+	SUPIType               int // 0: IMSI, 1: network specific identifier
+	MCC                    string
+	MNC                    string
+	RoutingIndicator       string
+	ProtectionScheme       int
+	HomeNetworkPublicKeyID int
+	SchemeOutput           string // MSIN for the null scheme, hex otherwise
+}
+
+// HomeNetworkKey is a home network private key used to de-conceal SUCIs
+// protected with an ECIES profile
+type HomeNetworkKey struct {
+	ID         int    // Home network public key identifier
+	Scheme     int    // SchemeProfileA or SchemeProfileB
+	PrivateKey []byte // Raw X25519 scalar or P-256 private scalar
+}
+
+// IsSUCI reports whether an identifier is a SUCI rather than a SUPI
+func IsSUCI(id string) bool {
+	return strings.HasPrefix(id, "suci-")
+}
+
+// Parse parses a SUCI string
+func Parse(s string) (*SUCI, error) {
+	parts := strings.Split(s, "-")
+	if len(parts) != 8 || parts[0] != "suci" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidSUCI, s)
+	}
+
+	supiType, err1 := strconv.Atoi(parts[1])
+	scheme, err2 := strconv.Atoi(parts[5])
+	keyID, err3 := strconv.Atoi(parts[6])
+	if err := errors.Join(err1, err2, err3); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSUCI, err)
+	}
+	if !isDigits(parts[2], 3, 3) || !isDigits(parts[3], 2, 3) || !isDigits(parts[4], 1, 4) {
+		return nil, fmt.Errorf("%w: bad MCC, MNC or routing indicator in %q", ErrInvalidSUCI, s)
+	}
+	if parts[7] == "" {
+		return nil, fmt.Errorf("%w: empty scheme output", ErrInvalidSUCI)
+	}
+
+	return &SUCI{
+		SUPIType:               supiType,
+		MCC:                    parts[2],
+		MNC:                    parts[3],
+		RoutingIndicator:       parts[4],
+		ProtectionScheme:       scheme,
+		HomeNetworkPublicKeyID: keyID,
+		SchemeOutput:           parts[7],
+	}, nil
+}
+
+// ToSUPI de-conceals a SUCI string into an "imsi-" SUPI, using keys for the
+// ECIES profiles. Null-scheme SUCIs need no key.
+func ToSUPI(s string, keys []HomeNetworkKey) (string, error) {
+	suci, err := Parse(s)
+	if err != nil {
+		return "", err
+	}
+	if suci.SUPIType != 0 {
+		return "", fmt.Errorf("%w: SUPI type %d", ErrUnsupportedScheme, suci.SUPIType)
+	}
+
+	var msin string
+	switch suci.ProtectionScheme {
+	case SchemeNull:
+		if !isDigits(suci.SchemeOutput, 1, 10) {
+			return "", fmt.Errorf("%w: null-scheme MSIN %q", ErrInvalidSUCI, suci.SchemeOutput)
+		}
+		msin = suci.SchemeOutput
+	case SchemeProfileA, SchemeProfileB:
+		key, ok := findKey(keys, suci.HomeNetworkPublicKeyID, suci.ProtectionScheme)
+		if !ok {
+			return "", fmt.Errorf("%w: %d", ErrUnknownKey, suci.HomeNetworkPublicKeyID)
+		}
+		output, err := hex.DecodeString(suci.SchemeOutput)
+		if err != nil {
+			return "", fmt.Errorf("%w: scheme output is not hex", ErrInvalidSUCI)
+		}
+		plaintext, err := decrypt(suci.ProtectionScheme, key.PrivateKey, output)
+		if err != nil {
+			return "", err
+		}
+		msin, err = decodeMSIN(plaintext)
+		if err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("%w: %d", ErrUnsupportedScheme, suci.ProtectionScheme)
+	}
+
+	return "imsi-" + suci.MCC + suci.MNC + msin, nil
+}
+
+// decrypt recovers the plaintext of an ECIES scheme output
+// (ephemeral public key || ciphertext || MAC tag, TS 33.501 C.3.3)
+func decrypt(scheme int, privateKey, output []byte) ([]byte, error) {
+	pubLen := profileAPublicKeyLen
+	if scheme == SchemeProfileB {
+		pubLen = profileBPublicKeyLen
+	}
+	if len(output) <= pubLen+macLen {
+		return nil, fmt.Errorf("%w: scheme output too short", ErrInvalidSUCI)
+	}
+
+	ephemeral := output[:pubLen]
+	ciphertext := output[pubLen : len(output)-macLen]
+	tag := output[len(output)-macLen:]
+
+	shared, err := sharedSecret(scheme, privateKey, ephemeral)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := x963KDF(shared, ephemeral, encKeyLen+icbLen+macKeyLen)
+	encKey := keys[:encKeyLen]
+	icb := keys[encKeyLen : encKeyLen+icbLen]
+	macKey := keys[encKeyLen+icbLen:]
+
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(ciphertext)
+	if !hmac.Equal(mac.Sum(nil)[:macLen], tag) {
+		return nil, ErrMACFailure
+	}
+
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCTR(block, icb).XORKeyStream(plaintext, ciphertext)
+	return plaintext, nil
+}
+
+// sharedSecret computes the ECDH shared secret with the UE's ephemeral key
+func sharedSecret(scheme int, privateKey, ephemeral []byte) ([]byte, error) {
+	var (
+		curve ecdh.Curve
+		pub   []byte
+	)
+	switch scheme {
+	case SchemeProfileA:
+		curve, pub = ecdh.X25519(), ephemeral
+	default:
+		x, y := elliptic.UnmarshalCompressed(elliptic.P256(), ephemeral)
+		if x == nil {
+			return nil, fmt.Errorf("%w: invalid ephemeral public key", ErrInvalidSUCI)
+		}
+		curve, pub = ecdh.P256(), elliptic.Marshal(elliptic.P256(), x, y)
+	}
+
+	priv, err := curve.NewPrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid home network private key: %w", err)
+	}
+	ephemeralKey, err := curve.NewPublicKey(pub)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid ephemeral public key", ErrInvalidSUCI)
+	}
+	return priv.ECDH(ephemeralKey)
+}
+
+// x963KDF is the ANSI X9.63 KDF with SHA-256 (SEC 1 3.6.1)
+func x963KDF(z, sharedInfo []byte, length int) []byte {
+	var out []byte
+	counter := make([]byte, 4)
+	for i := uint32(1); len(out) < length; i++ {
+		binary.BigEndian.PutUint32(counter, i)
+		h := sha256.New()
+		h.Write(z)
+		h.Write(counter)
+		h.Write(sharedInfo)
+		out = h.Sum(out)
+	}
+	return out[:length]
+}
+
+// decodeMSIN decodes a BCD MSIN with swapped nibbles and an 0xF filler
+func decodeMSIN(b []byte) (string, error) {
+	var sb strings.Builder
+	for i, octet := range b {
+		for j, digit := range []byte{octet & 0x0f, octet >> 4} {
+			if digit == 0x0f && i == len(b)-1 && j == 1 {
+				break
+			}
+			if digit > 9 {
+				return "", fmt.Errorf("%w: MSIN is not BCD", ErrInvalidSUCI)
+			}
+			sb.WriteByte('0' + digit)
+		}
+	}
+	return sb.String(), nil
+}
+
+// findKey returns the home network key with an identifier and scheme
+func findKey(keys []HomeNetworkKey, id, scheme int) (HomeNetworkKey, bool) {
+	for _, k := range keys {
+		if k.ID == id && k.Scheme == scheme {
+			return k, true
+		}
+	}
+	return HomeNetworkKey{}, false
+}
+
+// isDigits reports whether s has between min and max decimal digits only
+func isDigits(s string, min, max int) bool {
+	if len(s) < min || len(s) > max {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
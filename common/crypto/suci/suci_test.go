@@ -0,0 +1,81 @@
+package suci
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test vectors from TS 33.501 Annex C.4.3 (profile A) and C.4.4 (profile B)
+const (
+	profileAPrivateKey = "c53c22208b61860b06c62e5406a7b330c2b577aa5558981510d128247d38bd1d"
+	profileASUCI       = "suci-0-208-93-0-1-1-b2e92f836055a255837debf850b528997ce0201cb82adfe4be1f587d07d8457dcb02352410cddd9e730ef3fa87"
+
+	profileBPrivateKey = "f1ab1074477ebcc7f554ea1c5fc368b1616730155e0041ac447d6301975fecda"
+	profileBSUCI       = "suci-0-208-93-0-2-2-039aab8376597021e855679a9778ea0b67396e68c66df32c0f41e9acca2da9b9d146a33fc2716ac7dae96aa30a4d"
+
+	vectorSUPI = "imsi-20893001002086"
+)
+
+func testKeys(t *testing.T) []HomeNetworkKey {
+	a, err := hex.DecodeString(profileAPrivateKey)
+	require.NoError(t, err)
+	b, err := hex.DecodeString(profileBPrivateKey)
+	require.NoError(t, err)
+	return []HomeNetworkKey{
+		{ID: 1, Scheme: SchemeProfileA, PrivateKey: a},
+		{ID: 2, Scheme: SchemeProfileB, PrivateKey: b},
+	}
+}
+
+func TestToSUPI_ProfileA(t *testing.T) {
+	supi, err := ToSUPI(profileASUCI, testKeys(t))
+	require.NoError(t, err)
+	assert.Equal(t, vectorSUPI, supi)
+}
+
+func TestToSUPI_ProfileB(t *testing.T) {
+	supi, err := ToSUPI(profileBSUCI, testKeys(t))
+	require.NoError(t, err)
+	assert.Equal(t, vectorSUPI, supi)
+}
+
+func TestToSUPI_NullScheme(t *testing.T) {
+	supi, err := ToSUPI("suci-0-001-01-0000-0-0-0123456789", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "imsi-001010123456789", supi)
+}
+
+func TestToSUPI_Rejected(t *testing.T) {
+	// Flip a bit of the ciphertext so the MAC no longer matches
+	tampered := profileASUCI[:len(profileASUCI)-20] + "0" + profileASUCI[len(profileASUCI)-19:]
+
+	tests := []struct {
+		name string
+		suci string
+		err  error
+	}{
+		{"tampered ciphertext", tampered, ErrMACFailure},
+		{"unknown key id", "suci-0-208-93-0-1-7-" + profileASUCI[len("suci-0-208-93-0-1-1-"):], ErrUnknownKey},
+		{"key of another profile", "suci-0-208-93-0-2-1-" + profileASUCI[len("suci-0-208-93-0-1-1-"):], ErrUnknownKey},
+		{"unsupported scheme", "suci-0-208-93-0-3-1-00", ErrUnsupportedScheme},
+		{"NAI SUPI", "suci-1-208-93-0-0-0-0123456789", ErrUnsupportedScheme},
+		{"missing fields", "suci-0-208-93-0-0-0123456789", ErrInvalidSUCI},
+		{"non-digit null MSIN", "suci-0-208-93-0-0-0-01234x", ErrInvalidSUCI},
+		{"short scheme output", "suci-0-208-93-0-1-1-b2e92f", ErrInvalidSUCI},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ToSUPI(tt.suci, testKeys(t))
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestIsSUCI(t *testing.T) {
+	assert.True(t, IsSUCI(profileASUCI))
+	assert.False(t, IsSUCI("imsi-001010123456789"))
+}
@@ -24,11 +24,15 @@ type DataPlane interface {
 	// Install usage reporting rules
 	InstallURR(ctx context.Context, sessionID uint64, urr *URR) error
 
+	// Install buffering action rules
+	InstallBAR(ctx context.Context, sessionID uint64, bar *BAR) error
+
 	// Remove rules
 	RemovePDR(ctx context.Context, sessionID uint64, pdrID uint16) error
 	RemoveFAR(ctx context.Context, sessionID uint64, farID uint16) error
 	RemoveQER(ctx context.Context, sessionID uint64, qerID uint16) error
 	RemoveURR(ctx context.Context, sessionID uint64, urrID uint32) error
+	RemoveBAR(ctx context.Context, sessionID uint64, barID uint16) error
 
 	// Remove entire session
 	RemoveSession(ctx context.Context, sessionID uint64) error
@@ -39,6 +43,9 @@ type DataPlane interface {
 	// Get statistics
 	GetStats(ctx context.Context) (*Stats, error)
 
+	// Register a handler for session reports raised by the data plane
+	SetReportHandler(handler ReportHandler)
+
 	// Shutdown
 	Shutdown(ctx context.Context) error
 }
@@ -96,6 +103,22 @@ type FAR struct {
 	BARID                uint16
 }
 
+// Apply Action flags (3GPP TS 29.244 8.2.26)
+const (
+	ApplyActionDrop uint8 = 0x01
+	ApplyActionForw uint8 = 0x02
+	ApplyActionBuff uint8 = 0x04
+	ApplyActionNocp uint8 = 0x08
+	ApplyActionDupl uint8 = 0x10
+)
+
+// BAR (Buffering Action Rule)
+type BAR struct {
+	BARID                          uint16
+	DownlinkDataNotificationDelay  time.Duration
+	SuggestedBufferingPacketsCount uint8 // 0 means the UPF default
+}
+
 // ForwardingParameters defines where to forward packets
 type ForwardingParameters struct {
 	DestinationInterface string // "ACCESS", "CORE", "CP-FUNCTION"
@@ -117,8 +140,26 @@ type QER struct {
 	DLFlowLevelMarking *DLFlowLevelMarking
 	QoSFlowIdentifier  uint8
 	ReflectiveQoS      bool
+	QoSMonitoring      *QoSMonitoring // Set when the SMF requests QoS monitoring
+}
+
+// QoSMonitoring holds per QoS flow packet delay monitoring parameters
+// (3GPP TS 29.244 5.24.4, TS 23.501 5.33.3)
+type QoSMonitoring struct {
+	RequestedMonitoring uint8 // Bit flags: DL=0x01, UL=0x02, RP=0x04
+	DownlinkThreshold   time.Duration
+	UplinkThreshold     time.Duration
+	RoundTripThreshold  time.Duration
+	MinimumWaitTime     time.Duration // Minimum time between two reports
 }
 
+// Requested QoS monitoring flags
+const (
+	QoSMonitoringDownlink  uint8 = 0x01
+	QoSMonitoringUplink    uint8 = 0x02
+	QoSMonitoringRoundTrip uint8 = 0x04
+)
+
 // URR (Usage Reporting Rule)
 type URR struct {
 	URRID             uint32
@@ -130,6 +171,23 @@ type URR struct {
 	QuotaHoldingTime  time.Duration
 }
 
+// Measurement Method flags (3GPP TS 29.244 8.2.40)
+const (
+	MeasurementMethodVolume   uint8 = 0x01
+	MeasurementMethodDuration uint8 = 0x02
+	MeasurementMethodEvent    uint8 = 0x04
+)
+
+// Reporting Triggers and Usage Report Trigger flags (3GPP TS 29.244 8.2.19,
+// 8.2.41). Octet 5 is carried in the low byte, octet 6 in the next.
+const (
+	ReportingTriggerPeriodic        uint32 = 0x01   // PERIO
+	ReportingTriggerVolumeThreshold uint32 = 0x02   // VOLTH
+	ReportingTriggerTimeThreshold   uint32 = 0x04   // TIMTH
+	UsageReportTriggerImmediate     uint32 = 0x80   // IMMER, usage reports only
+	UsageReportTriggerTermination   uint32 = 0x0800 // TERMR, usage reports only
+)
+
 // FTEID (Fully Qualified TEID)
 type FTEID struct {
 	TEID     uint32
@@ -212,6 +270,71 @@ type Packet struct {
 	Protocol  uint8
 	Interface string // "N3", "N6", "N9"
 	TEID      uint32 // For GTP-U packets
+	QFI       uint8  // From the PDU Session Container
+
+	// QoS monitoring information carried in the UL PDU Session Container
+	SendTimestamp time.Time     // UL sending time stamp set by the RAN
+	DownlinkDelay time.Duration // DL delay result measured by the RAN
+}
+
+// ReportHandler receives session reports from the data plane
+type ReportHandler func(report *SessionReport)
+
+// SessionReport is raised by the data plane towards the control plane
+// (PFCP Session Report Request, 3GPP TS 29.244 7.5.8)
+type SessionReport struct {
+	SessionID            uint64
+	DownlinkDataReport   *DownlinkDataReport // First packet notification (NOCP)
+	ErrorIndication      *ErrorIndicationReport
+	QoSMonitoringReports []QoSMonitoringReport
+	UsageReports         []UsageReport
+	Timestamp            time.Time
+}
+
+// DownlinkDataReport notifies the control plane of downlink data arrival
+// for a FAR with the NOCP apply action (3GPP TS 29.244 5.2.3.1)
+type DownlinkDataReport struct {
+	PDRID uint16
+	FARID uint16
+	QFI   uint8
+}
+
+// ErrorIndicationReport notifies the control plane that a GTP-U peer sent
+// an Error Indication for a tunnel of the session (3GPP TS 29.244 7.5.8.4)
+type ErrorIndicationReport struct {
+	RemoteFTEID FTEID // The tunnel the peer does not know
+}
+
+// QoSMonitoringReport carries packet delay measurements for a QoS flow
+type QoSMonitoringReport struct {
+	QERID          uint16
+	QFI            uint8
+	DownlinkDelay  time.Duration
+	UplinkDelay    time.Duration
+	RoundTripDelay time.Duration
+	Timestamp      time.Time
+}
+
+// UsageReport carries the usage measured by a URR since its previous report
+// (3GPP TS 29.244 7.5.8.3)
+type UsageReport struct {
+	URRID          uint32
+	SequenceNumber uint32 // UR-SEQN
+	Trigger        uint32 // Usage Report Trigger flags
+	StartTime      time.Time
+	EndTime        time.Time
+	Volume         VolumeMeasurement
+	Duration       time.Duration
+}
+
+// VolumeMeasurement holds the bytes and packets counted by a URR
+type VolumeMeasurement struct {
+	TotalVolume     uint64
+	UplinkVolume    uint64
+	DownlinkVolume  uint64
+	TotalPackets    uint64
+	UplinkPackets   uint64
+	DownlinkPackets uint64
 }
 
 // Stats holds data plane statistics
@@ -235,6 +358,7 @@ type Stats struct {
 
 	// QoS stats
 	QoSViolations uint64
+	QERDrops      []QERDrops // Per QER enforcement drops
 
 	// Errors
 	Errors map[string]uint64
@@ -243,6 +367,15 @@ type Stats struct {
 	Timestamp time.Time
 }
 
+// QERDrops counts the packets one QER dropped, because its gate is closed
+// or the packet exceeded its MBR
+type QERDrops struct {
+	SessionID uint64
+	QERID     uint16
+	Uplink    uint64
+	Downlink  uint64
+}
+
 // Error types
 const (
 	ErrSessionNotFound = "session_not_found"
@@ -0,0 +1,113 @@
+package dataplane
+
+import (
+	"sync"
+	"time"
+)
+
+// QoSMonitor measures per QoS flow packet delays and paces the reports. It
+// is shared by the simulated data plane and the GTP-U handler.
+type QoSMonitor struct {
+	mu         sync.Mutex
+	lastReport map[qosMonitorKey]time.Time
+}
+
+type qosMonitorKey struct {
+	sessionID uint64
+	qerID     uint16
+}
+
+// NewQoSMonitor creates a new QoS monitor
+func NewQoSMonitor() *QoSMonitor {
+	return &QoSMonitor{
+		lastReport: make(map[qosMonitorKey]time.Time),
+	}
+}
+
+// Measure computes the delays for an uplink packet and returns a report when
+// a configured threshold is exceeded and the minimum wait time has elapsed
+func (m *QoSMonitor) Measure(sessionID uint64, qer *QER, packet *Packet) *QoSMonitoringReport {
+	mon := qer.QoSMonitoring
+	if mon == nil || mon.RequestedMonitoring == 0 {
+		return nil
+	}
+
+	// Measurements are carried by the RAN in uplink PDU Session Containers
+	if packet.Interface != "N3" {
+		return nil
+	}
+	if qer.QFI != 0 && packet.QFI != 0 && qer.QFI != packet.QFI {
+		return nil
+	}
+
+	now := packet.Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	report := &QoSMonitoringReport{
+		QERID:     qer.QERID,
+		QFI:       qer.QFI,
+		Timestamp: now,
+	}
+	if report.QFI == 0 {
+		report.QFI = packet.QFI
+	}
+
+	hasUL := !packet.SendTimestamp.IsZero()
+	hasDL := packet.DownlinkDelay > 0
+
+	var ulDelay time.Duration
+	if hasUL {
+		ulDelay = now.Sub(packet.SendTimestamp)
+		if ulDelay < 0 {
+			ulDelay = 0
+		}
+	}
+
+	exceeded := false
+	if mon.RequestedMonitoring&QoSMonitoringUplink != 0 && hasUL {
+		report.UplinkDelay = ulDelay
+		exceeded = exceeded || thresholdExceeded(ulDelay, mon.UplinkThreshold)
+	}
+	if mon.RequestedMonitoring&QoSMonitoringDownlink != 0 && hasDL {
+		report.DownlinkDelay = packet.DownlinkDelay
+		exceeded = exceeded || thresholdExceeded(packet.DownlinkDelay, mon.DownlinkThreshold)
+	}
+	if mon.RequestedMonitoring&QoSMonitoringRoundTrip != 0 && hasUL && hasDL {
+		report.RoundTripDelay = ulDelay + packet.DownlinkDelay
+		exceeded = exceeded || thresholdExceeded(report.RoundTripDelay, mon.RoundTripThreshold)
+	}
+
+	if !exceeded {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := qosMonitorKey{sessionID: sessionID, qerID: qer.QERID}
+	if last, ok := m.lastReport[key]; ok && now.Sub(last) < mon.MinimumWaitTime {
+		return nil
+	}
+	m.lastReport[key] = now
+
+	return report
+}
+
+// Forget drops the reporting state of a session
+func (m *QoSMonitor) Forget(sessionID uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key := range m.lastReport {
+		if key.sessionID == sessionID {
+			delete(m.lastReport, key)
+		}
+	}
+}
+
+// thresholdExceeded reports whether a delay exceeds a configured threshold
+func thresholdExceeded(delay, threshold time.Duration) bool {
+	return threshold > 0 && delay > threshold
+}
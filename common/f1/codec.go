@@ -0,0 +1,516 @@
+package f1
+
+import (
+	"fmt"
+
+	"github.com/your-org/5g-network/common/per"
+)
+
+// F1AP elementary procedure codes (TS 38.473 9.4.7)
+const (
+	PROCEDURE_F1_SETUP                        = 1
+	PROCEDURE_UE_CONTEXT_SETUP                = 5
+	PROCEDURE_INITIAL_UL_RRC_MESSAGE_TRANSFER = 11
+	PROCEDURE_DL_RRC_MESSAGE_TRANSFER         = 12
+	PROCEDURE_UL_RRC_MESSAGE_TRANSFER         = 13
+)
+
+// F1AP-PDU choices (TS 38.473 9.4.2)
+const (
+	pduInitiatingMessage   = 0
+	pduSuccessfulOutcome   = 1
+	pduUnsuccessfulOutcome = 2
+	pduChoices             = 4 // Including choice-extension
+)
+
+// Message is an F1AP message that Encode and Decode support
+type Message interface {
+	MessageType() int
+}
+
+func (*F1SetupRequest) MessageType() int         { return F1AP_F1_SETUP_REQUEST }
+func (*F1SetupResponse) MessageType() int        { return F1AP_F1_SETUP_RESPONSE }
+func (*UEContextSetupRequest) MessageType() int  { return F1AP_UE_CONTEXT_SETUP_REQUEST }
+func (*UEContextSetupResponse) MessageType() int { return F1AP_UE_CONTEXT_SETUP_RESPONSE }
+func (*InitialULRRCMessage) MessageType() int    { return F1AP_INITIAL_UL_RRC_MESSAGE_TRANSFER }
+func (*DLRRCMessage) MessageType() int           { return F1AP_DL_RRC_MESSAGE_TRANSFER }
+func (*ULRRCMessage) MessageType() int           { return F1AP_UL_RRC_MESSAGE_TRANSFER }
+
+// procedure locates a message in the F1AP-PDU
+type procedure struct {
+	pdu         int
+	code        uint8
+	criticality uint8
+}
+
+var procedures = map[int]procedure{
+	F1AP_F1_SETUP_REQUEST:                {pduInitiatingMessage, PROCEDURE_F1_SETUP, per.CRITICALITY_REJECT},
+	F1AP_F1_SETUP_RESPONSE:               {pduSuccessfulOutcome, PROCEDURE_F1_SETUP, per.CRITICALITY_REJECT},
+	F1AP_UE_CONTEXT_SETUP_REQUEST:        {pduInitiatingMessage, PROCEDURE_UE_CONTEXT_SETUP, per.CRITICALITY_REJECT},
+	F1AP_UE_CONTEXT_SETUP_RESPONSE:       {pduSuccessfulOutcome, PROCEDURE_UE_CONTEXT_SETUP, per.CRITICALITY_REJECT},
+	F1AP_INITIAL_UL_RRC_MESSAGE_TRANSFER: {pduInitiatingMessage, PROCEDURE_INITIAL_UL_RRC_MESSAGE_TRANSFER, per.CRITICALITY_IGNORE},
+	F1AP_DL_RRC_MESSAGE_TRANSFER:         {pduInitiatingMessage, PROCEDURE_DL_RRC_MESSAGE_TRANSFER, per.CRITICALITY_IGNORE},
+	F1AP_UL_RRC_MESSAGE_TRANSFER:         {pduInitiatingMessage, PROCEDURE_UL_RRC_MESSAGE_TRANSFER, per.CRITICALITY_IGNORE},
+}
+
+// Encode encodes an F1AP message as an aligned PER F1AP-PDU (TS 38.473 9.4)
+func Encode(msg Message) ([]byte, error) {
+	proc, ok := procedures[msg.MessageType()]
+	if !ok {
+		return nil, fmt.Errorf("unsupported f1ap message type %d", msg.MessageType())
+	}
+	ies, err := messageIEs(msg)
+	if err != nil {
+		return nil, fmt.Errorf("f1ap message type %d: %w", msg.MessageType(), err)
+	}
+
+	var w per.Writer
+	if err := w.PutConstrained(uint64(proc.pdu), 0, pduChoices-1); err != nil {
+		return nil, err
+	}
+	if err := w.PutConstrained(uint64(proc.code), 0, 255); err != nil {
+		return nil, err
+	}
+	if err := w.PutConstrained(uint64(proc.criticality), 0, criticalityValues-1); err != nil {
+		return nil, err
+	}
+	err = w.PutOpenType(func(w *per.Writer) error {
+		per.PutSequence(w)
+		return per.PutContainer(w, ies, 0)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("f1ap message type %d: %w", msg.MessageType(), err)
+	}
+	return w.Bytes(), nil
+}
+
+// Decode decodes an aligned PER F1AP-PDU
+func Decode(data []byte) (Message, error) {
+	r := per.NewReader(data)
+	pdu, err := r.GetConstrained(0, pduChoices-1)
+	if err != nil {
+		return nil, err
+	}
+	code, err := r.GetConstrained(0, 255)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.GetConstrained(0, criticalityValues-1); err != nil {
+		return nil, err
+	}
+	value, err := r.GetOpenType()
+	if err != nil {
+		return nil, err
+	}
+	if r.Remaining() != 0 {
+		return nil, fmt.Errorf("f1ap pdu has %d trailing octets", r.Remaining())
+	}
+
+	msgType := -1
+	for t, proc := range procedures {
+		if proc.pdu == int(pdu) && proc.code == uint8(code) {
+			msgType = t
+		}
+	}
+	if msgType < 0 {
+		return nil, fmt.Errorf("unsupported f1ap procedure %d in pdu choice %d", code, pdu)
+	}
+
+	if _, err := per.GetSequence(value, 0); err != nil {
+		return nil, err
+	}
+	ies, err := per.GetContainer(value, 0)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := decodeMessage(msgType, ies)
+	if err != nil {
+		return nil, fmt.Errorf("f1ap message type %d: %w", msgType, err)
+	}
+	return msg, nil
+}
+
+// messageIEs lists the protocol IEs of a message
+func messageIEs(msg Message) ([]per.ProtocolIE, error) {
+	switch m := msg.(type) {
+	case *F1SetupRequest:
+		ies := []per.ProtocolIE{
+			transactionIDIE(m.TransactionID),
+			{ID: IE_GNB_DU_ID, Criticality: per.CRITICALITY_REJECT, Encode: func(w *per.Writer) error {
+				return w.PutConstrained(m.GNBDUID, 0, maxGNBDUID)
+			}},
+		}
+		if m.GNBDUName != "" {
+			ies = append(ies, nameIE(IE_GNB_DU_NAME, m.GNBDUName))
+		}
+		if len(m.ServedCellsToAdd) > 0 {
+			ies = append(ies, per.ProtocolIE{ID: IE_GNB_DU_SERVED_CELLS_LIST, Criticality: per.CRITICALITY_REJECT, Encode: func(w *per.Writer) error {
+				return per.PutItemList(w, m.ServedCellsToAdd, maxCellingNBDU, IE_GNB_DU_SERVED_CELLS_ITEM, per.CRITICALITY_REJECT, putServedCell)
+			}})
+		}
+		ies = append(ies, per.ProtocolIE{ID: IE_GNB_DU_RRC_VERSION, Criticality: per.CRITICALITY_REJECT, Encode: func(w *per.Writer) error {
+			return putRRCVersion(w, m.GNBDURRCVersion)
+		}})
+		return ies, nil
+
+	case *F1SetupResponse:
+		ies := []per.ProtocolIE{transactionIDIE(m.TransactionID)}
+		if m.GNBCUNAME != "" {
+			ies = append(ies, nameIE(IE_GNB_CU_NAME, m.GNBCUNAME))
+		}
+		if len(m.CellsToActivate) > 0 {
+			ies = append(ies, per.ProtocolIE{ID: IE_CELLS_TO_BE_ACTIVATED_LIST, Criticality: per.CRITICALITY_REJECT, Encode: func(w *per.Writer) error {
+				return per.PutItemList(w, m.CellsToActivate, maxCellingNBDU, IE_CELLS_TO_BE_ACTIVATED_LIST_ITEM, per.CRITICALITY_REJECT, putCellToActivate)
+			}})
+		}
+		ies = append(ies, per.ProtocolIE{ID: IE_GNB_CU_RRC_VERSION, Criticality: per.CRITICALITY_REJECT, Encode: func(w *per.Writer) error {
+			return putRRCVersion(w, m.GNBCURRCVersion)
+		}})
+		return ies, nil
+
+	case *UEContextSetupRequest:
+		if m.SpCell == nil {
+			return nil, fmt.Errorf("missing spcell")
+		}
+		ies := []per.ProtocolIE{
+			ueF1APIDIE(IE_GNB_CU_UE_F1AP_ID, per.CRITICALITY_REJECT, m.GNBCUUEF1APID),
+			ueF1APIDIE(IE_GNB_DU_UE_F1AP_ID, per.CRITICALITY_IGNORE, m.GNBDUUEF1APID),
+			{ID: IE_SPCELL_ID, Criticality: per.CRITICALITY_REJECT, Encode: func(w *per.Writer) error {
+				return putNRCGI(w, m.SpCell.ServCellID)
+			}},
+			{ID: IE_SERV_CELL_INDEX, Criticality: per.CRITICALITY_REJECT, Encode: func(w *per.Writer) error {
+				return w.PutExtConstrained(uint64(m.SpCell.ServCellIndex), 0, 31)
+			}},
+		}
+		if m.SpCell.ServCellULCfg != nil {
+			ies = append(ies, per.ProtocolIE{ID: IE_SPCELL_UL_CONFIGURED, Criticality: per.CRITICALITY_IGNORE, Encode: func(w *per.Writer) error {
+				// Cell UL Configured ENUMERATED {none, ul, sul, ul-and-sul, ...}
+				var configured uint64
+				if m.SpCell.ServCellULCfg.CellULConfigured {
+					configured = 1
+				}
+				return w.PutExtConstrained(configured, 0, 3)
+			}})
+		}
+		// CU to DU RRC Information is mandatory, if empty
+		rrcInfo := m.CUtoDURRCInfo
+		if rrcInfo == nil {
+			rrcInfo = &CUtoDURRCInformation{}
+		}
+		ies = append(ies, per.ProtocolIE{ID: IE_CU_TO_DU_RRC_INFORMATION, Criticality: per.CRITICALITY_REJECT, Encode: func(w *per.Writer) error {
+			return putCUtoDURRCInformation(w, rrcInfo)
+		}})
+		if len(m.SRBsToBeSetup) > 0 {
+			ies = append(ies, per.ProtocolIE{ID: IE_SRBS_TO_BE_SETUP_LIST, Criticality: per.CRITICALITY_REJECT, Encode: func(w *per.Writer) error {
+				return per.PutItemList(w, m.SRBsToBeSetup, maxnoofSRBs, IE_SRBS_TO_BE_SETUP_ITEM, per.CRITICALITY_REJECT, putSRBToBeSetup)
+			}})
+		}
+		if len(m.DRBsToBeSetup) > 0 {
+			ies = append(ies, per.ProtocolIE{ID: IE_DRBS_TO_BE_SETUP_LIST, Criticality: per.CRITICALITY_REJECT, Encode: func(w *per.Writer) error {
+				return per.PutItemList(w, m.DRBsToBeSetup, maxnoofDRBs, IE_DRBS_TO_BE_SETUP_ITEM, per.CRITICALITY_REJECT, putDRBToBeSetup)
+			}})
+		}
+		return ies, nil
+
+	case *UEContextSetupResponse:
+		if m.DUtoCURRCInfo == nil {
+			return nil, fmt.Errorf("missing du to cu rrc information")
+		}
+		ies := []per.ProtocolIE{
+			ueF1APIDIE(IE_GNB_CU_UE_F1AP_ID, per.CRITICALITY_REJECT, m.GNBCUUEF1APID),
+			ueF1APIDIE(IE_GNB_DU_UE_F1AP_ID, per.CRITICALITY_REJECT, m.GNBDUUEF1APID),
+			{ID: IE_DU_TO_CU_RRC_INFORMATION, Criticality: per.CRITICALITY_REJECT, Encode: func(w *per.Writer) error {
+				return putDUtoCURRCInformation(w, m.DUtoCURRCInfo)
+			}},
+		}
+		if len(m.DRBsSetup) > 0 {
+			ies = append(ies, per.ProtocolIE{ID: IE_DRBS_SETUP_LIST, Criticality: per.CRITICALITY_IGNORE, Encode: func(w *per.Writer) error {
+				return per.PutItemList(w, m.DRBsSetup, maxnoofDRBs, IE_DRBS_SETUP_ITEM, per.CRITICALITY_IGNORE, putDRBSetup)
+			}})
+		}
+		if len(m.SRBsFailedToSetup) > 0 {
+			ies = append(ies, per.ProtocolIE{ID: IE_SRBS_FAILED_TO_BE_SETUP_LIST, Criticality: per.CRITICALITY_IGNORE, Encode: func(w *per.Writer) error {
+				return per.PutItemList(w, m.SRBsFailedToSetup, maxnoofSRBs, IE_SRBS_FAILED_TO_BE_SETUP_ITEM, per.CRITICALITY_IGNORE,
+					func(w *per.Writer, srb *SRBFailedToSetup) error {
+						return putFailedToSetup(w, uint64(srb.SRBID), 0, 3, srb.Cause)
+					})
+			}})
+		}
+		if len(m.DRBsFailedToSetup) > 0 {
+			ies = append(ies, per.ProtocolIE{ID: IE_DRBS_FAILED_TO_BE_SETUP_LIST, Criticality: per.CRITICALITY_IGNORE, Encode: func(w *per.Writer) error {
+				return per.PutItemList(w, m.DRBsFailedToSetup, maxnoofDRBs, IE_DRBS_FAILED_TO_BE_SETUP_ITEM, per.CRITICALITY_IGNORE,
+					func(w *per.Writer, drb *DRBFailedToSetup) error {
+						return putFailedToSetup(w, uint64(drb.DRBID), 1, 32, drb.Cause)
+					})
+			}})
+		}
+		if len(m.SRBsSetup) > 0 {
+			ies = append(ies, per.ProtocolIE{ID: IE_SRBS_SETUP_LIST, Criticality: per.CRITICALITY_IGNORE, Encode: func(w *per.Writer) error {
+				return per.PutItemList(w, m.SRBsSetup, maxnoofSRBs, IE_SRBS_SETUP_ITEM, per.CRITICALITY_IGNORE, putSRBSetup)
+			}})
+		}
+		return ies, nil
+
+	case *InitialULRRCMessage:
+		ies := []per.ProtocolIE{
+			ueF1APIDIE(IE_GNB_DU_UE_F1AP_ID, per.CRITICALITY_REJECT, m.GNBDUUEF1APID),
+			{ID: IE_NRCGI, Criticality: per.CRITICALITY_REJECT, Encode: func(w *per.Writer) error {
+				return putNRCGI(w, m.NRCGI)
+			}},
+			{ID: IE_C_RNTI, Criticality: per.CRITICALITY_REJECT, Encode: func(w *per.Writer) error {
+				return w.PutExtConstrained(uint64(m.CRNTI), 0, 65535)
+			}},
+			rrcContainerIE(m.RRCContainer),
+		}
+		if len(m.DUtoCURRCContainer) > 0 {
+			ies = append(ies, per.ProtocolIE{ID: IE_DU_TO_CU_RRC_CONTAINER, Criticality: per.CRITICALITY_REJECT, Encode: func(w *per.Writer) error {
+				return w.PutOctetString(m.DUtoCURRCContainer, 0, -1)
+			}})
+		}
+		return ies, nil
+
+	case *DLRRCMessage:
+		return rrcMessageTransferIEs(m.GNBCUUEF1APID, m.GNBDUUEF1APID, m.SRBID, m.RRCContainer), nil
+	case *ULRRCMessage:
+		return rrcMessageTransferIEs(m.GNBCUUEF1APID, m.GNBDUUEF1APID, m.SRBID, m.RRCContainer), nil
+	default:
+		return nil, fmt.Errorf("unsupported message")
+	}
+}
+
+func transactionIDIE(id uint8) per.ProtocolIE {
+	return per.ProtocolIE{ID: IE_TRANSACTION_ID, Criticality: per.CRITICALITY_REJECT, Encode: func(w *per.Writer) error {
+		return w.PutExtConstrained(uint64(id), 0, 255)
+	}}
+}
+
+// nameIE encodes a gNB-DU or gNB-CU name, a PrintableString (SIZE(1..150,...))
+func nameIE(id uint16, name string) per.ProtocolIE {
+	return per.ProtocolIE{ID: id, Criticality: per.CRITICALITY_IGNORE, Encode: func(w *per.Writer) error {
+		w.PutBool(false)
+		return w.PutOctetString([]byte(name), 1, maxNameLength)
+	}}
+}
+
+func ueF1APIDIE(id uint16, criticality uint8, ueID uint32) per.ProtocolIE {
+	return per.ProtocolIE{ID: id, Criticality: criticality, Encode: func(w *per.Writer) error {
+		return w.PutConstrained(uint64(ueID), 0, maxUEF1APID)
+	}}
+}
+
+func rrcContainerIE(container []byte) per.ProtocolIE {
+	return per.ProtocolIE{ID: IE_RRC_CONTAINER, Criticality: per.CRITICALITY_REJECT, Encode: func(w *per.Writer) error {
+		return w.PutOctetString(container, 0, -1)
+	}}
+}
+
+func rrcMessageTransferIEs(cuUEID, duUEID uint32, srbID uint8, container []byte) []per.ProtocolIE {
+	return []per.ProtocolIE{
+		ueF1APIDIE(IE_GNB_CU_UE_F1AP_ID, per.CRITICALITY_REJECT, cuUEID),
+		ueF1APIDIE(IE_GNB_DU_UE_F1AP_ID, per.CRITICALITY_REJECT, duUEID),
+		{ID: IE_SRBID, Criticality: per.CRITICALITY_REJECT, Encode: func(w *per.Writer) error {
+			return w.PutExtConstrained(uint64(srbID), 0, 3)
+		}},
+		rrcContainerIE(container),
+	}
+}
+
+// decodeMessage builds a message from its protocol IEs. Unknown IEs are
+// skipped; missing mandatory IEs are an error.
+func decodeMessage(msgType int, ies []per.DecodedIE) (Message, error) {
+	var msg Message
+	var mandatory []uint16
+	var decode func(ie per.DecodedIE) error
+
+	switch msgType {
+	case F1AP_F1_SETUP_REQUEST:
+		m := &F1SetupRequest{}
+		msg, mandatory = m, []uint16{IE_TRANSACTION_ID, IE_GNB_DU_ID, IE_GNB_DU_RRC_VERSION}
+		decode = func(ie per.DecodedIE) (err error) {
+			switch ie.ID {
+			case IE_TRANSACTION_ID:
+				m.TransactionID, err = getTransactionID(ie.Value)
+			case IE_GNB_DU_ID:
+				m.GNBDUID, err = ie.Value.GetConstrained(0, maxGNBDUID)
+			case IE_GNB_DU_NAME:
+				m.GNBDUName, err = getName(ie.Value)
+			case IE_GNB_DU_SERVED_CELLS_LIST:
+				m.ServedCellsToAdd, err = per.GetItemList(ie.Value, maxCellingNBDU, IE_GNB_DU_SERVED_CELLS_ITEM, getServedCell)
+			case IE_GNB_DU_RRC_VERSION:
+				m.GNBDURRCVersion, err = getRRCVersion(ie.Value)
+			}
+			return err
+		}
+
+	case F1AP_F1_SETUP_RESPONSE:
+		m := &F1SetupResponse{}
+		msg, mandatory = m, []uint16{IE_TRANSACTION_ID, IE_GNB_CU_RRC_VERSION}
+		decode = func(ie per.DecodedIE) (err error) {
+			switch ie.ID {
+			case IE_TRANSACTION_ID:
+				m.TransactionID, err = getTransactionID(ie.Value)
+			case IE_GNB_CU_NAME:
+				m.GNBCUNAME, err = getName(ie.Value)
+			case IE_CELLS_TO_BE_ACTIVATED_LIST:
+				m.CellsToActivate, err = per.GetItemList(ie.Value, maxCellingNBDU, IE_CELLS_TO_BE_ACTIVATED_LIST_ITEM, getCellToActivate)
+			case IE_GNB_CU_RRC_VERSION:
+				m.GNBCURRCVersion, err = getRRCVersion(ie.Value)
+			}
+			return err
+		}
+
+	case F1AP_UE_CONTEXT_SETUP_REQUEST:
+		m := &UEContextSetupRequest{SpCell: &SpCell{}}
+		msg, mandatory = m, []uint16{IE_GNB_CU_UE_F1AP_ID, IE_SPCELL_ID, IE_SERV_CELL_INDEX, IE_CU_TO_DU_RRC_INFORMATION}
+		decode = func(ie per.DecodedIE) (err error) {
+			switch ie.ID {
+			case IE_GNB_CU_UE_F1AP_ID:
+				m.GNBCUUEF1APID, err = getUEF1APID(ie.Value)
+			case IE_GNB_DU_UE_F1AP_ID:
+				m.GNBDUUEF1APID, err = getUEF1APID(ie.Value)
+			case IE_SPCELL_ID:
+				m.SpCell.ServCellID, err = getNRCGI(ie.Value)
+			case IE_SERV_CELL_INDEX:
+				var index uint64
+				index, err = ie.Value.GetExtConstrained(0, 31)
+				m.SpCell.ServCellIndex = uint8(index)
+			case IE_SPCELL_UL_CONFIGURED:
+				var configured uint64
+				if configured, err = ie.Value.GetExtConstrained(0, 3); err == nil {
+					m.SpCell.ServCellULCfg = &CellULConfiguration{CellULConfigured: configured != 0}
+				}
+			case IE_CU_TO_DU_RRC_INFORMATION:
+				m.CUtoDURRCInfo, err = getCUtoDURRCInformation(ie.Value)
+			case IE_SRBS_TO_BE_SETUP_LIST:
+				m.SRBsToBeSetup, err = per.GetItemList(ie.Value, maxnoofSRBs, IE_SRBS_TO_BE_SETUP_ITEM, getSRBToBeSetup)
+			case IE_DRBS_TO_BE_SETUP_LIST:
+				m.DRBsToBeSetup, err = per.GetItemList(ie.Value, maxnoofDRBs, IE_DRBS_TO_BE_SETUP_ITEM, getDRBToBeSetup)
+			}
+			return err
+		}
+
+	case F1AP_UE_CONTEXT_SETUP_RESPONSE:
+		m := &UEContextSetupResponse{}
+		msg, mandatory = m, []uint16{IE_GNB_CU_UE_F1AP_ID, IE_GNB_DU_UE_F1AP_ID, IE_DU_TO_CU_RRC_INFORMATION}
+		decode = func(ie per.DecodedIE) (err error) {
+			switch ie.ID {
+			case IE_GNB_CU_UE_F1AP_ID:
+				m.GNBCUUEF1APID, err = getUEF1APID(ie.Value)
+			case IE_GNB_DU_UE_F1AP_ID:
+				m.GNBDUUEF1APID, err = getUEF1APID(ie.Value)
+			case IE_DU_TO_CU_RRC_INFORMATION:
+				m.DUtoCURRCInfo, err = getDUtoCURRCInformation(ie.Value)
+			case IE_DRBS_SETUP_LIST:
+				m.DRBsSetup, err = per.GetItemList(ie.Value, maxnoofDRBs, IE_DRBS_SETUP_ITEM, getDRBSetup)
+			case IE_SRBS_FAILED_TO_BE_SETUP_LIST:
+				m.SRBsFailedToSetup, err = per.GetItemList(ie.Value, maxnoofSRBs, IE_SRBS_FAILED_TO_BE_SETUP_ITEM,
+					func(r *per.Reader) (*SRBFailedToSetup, error) {
+						id, cause, err := getFailedToSetup(r, 0, 3)
+						return &SRBFailedToSetup{SRBID: id, Cause: cause}, err
+					})
+			case IE_DRBS_FAILED_TO_BE_SETUP_LIST:
+				m.DRBsFailedToSetup, err = per.GetItemList(ie.Value, maxnoofDRBs, IE_DRBS_FAILED_TO_BE_SETUP_ITEM,
+					func(r *per.Reader) (*DRBFailedToSetup, error) {
+						id, cause, err := getFailedToSetup(r, 1, 32)
+						return &DRBFailedToSetup{DRBID: id, Cause: cause}, err
+					})
+			case IE_SRBS_SETUP_LIST:
+				m.SRBsSetup, err = per.GetItemList(ie.Value, maxnoofSRBs, IE_SRBS_SETUP_ITEM, getSRBSetup)
+			}
+			return err
+		}
+
+	case F1AP_INITIAL_UL_RRC_MESSAGE_TRANSFER:
+		m := &InitialULRRCMessage{}
+		msg, mandatory = m, []uint16{IE_GNB_DU_UE_F1AP_ID, IE_NRCGI, IE_C_RNTI, IE_RRC_CONTAINER}
+		decode = func(ie per.DecodedIE) (err error) {
+			switch ie.ID {
+			case IE_GNB_DU_UE_F1AP_ID:
+				m.GNBDUUEF1APID, err = getUEF1APID(ie.Value)
+			case IE_NRCGI:
+				m.NRCGI, err = getNRCGI(ie.Value)
+			case IE_C_RNTI:
+				var crnti uint64
+				crnti, err = ie.Value.GetExtConstrained(0, 65535)
+				m.CRNTI = uint16(crnti)
+			case IE_RRC_CONTAINER:
+				m.RRCContainer, err = ie.Value.GetOctetString(0, -1)
+			case IE_DU_TO_CU_RRC_CONTAINER:
+				m.DUtoCURRCContainer, err = ie.Value.GetOctetString(0, -1)
+			}
+			return err
+		}
+
+	case F1AP_DL_RRC_MESSAGE_TRANSFER:
+		m := &DLRRCMessage{}
+		msg, mandatory = m, rrcMessageTransferMandatoryIEs
+		decode = func(ie per.DecodedIE) error {
+			return getRRCMessageTransferIE(ie, &m.GNBCUUEF1APID, &m.GNBDUUEF1APID, &m.SRBID, &m.RRCContainer)
+		}
+	case F1AP_UL_RRC_MESSAGE_TRANSFER:
+		m := &ULRRCMessage{}
+		msg, mandatory = m, rrcMessageTransferMandatoryIEs
+		decode = func(ie per.DecodedIE) error {
+			return getRRCMessageTransferIE(ie, &m.GNBCUUEF1APID, &m.GNBDUUEF1APID, &m.SRBID, &m.RRCContainer)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported message")
+	}
+
+	seen := make(map[uint16]bool, len(ies))
+	for _, ie := range ies {
+		if seen[ie.ID] {
+			return nil, fmt.Errorf("duplicate ie %d", ie.ID)
+		}
+		seen[ie.ID] = true
+		if err := decode(ie); err != nil {
+			return nil, fmt.Errorf("ie %d: %w", ie.ID, err)
+		}
+	}
+	for _, id := range mandatory {
+		if !seen[id] {
+			return nil, fmt.Errorf("missing mandatory ie %d", id)
+		}
+	}
+	return msg, nil
+}
+
+var rrcMessageTransferMandatoryIEs = []uint16{IE_GNB_CU_UE_F1AP_ID, IE_GNB_DU_UE_F1AP_ID, IE_SRBID, IE_RRC_CONTAINER}
+
+func getRRCMessageTransferIE(ie per.DecodedIE, cuUEID, duUEID *uint32, srbID *uint8, container *[]byte) (err error) {
+	switch ie.ID {
+	case IE_GNB_CU_UE_F1AP_ID:
+		*cuUEID, err = getUEF1APID(ie.Value)
+	case IE_GNB_DU_UE_F1AP_ID:
+		*duUEID, err = getUEF1APID(ie.Value)
+	case IE_SRBID:
+		var id uint64
+		id, err = ie.Value.GetExtConstrained(0, 3)
+		*srbID = uint8(id)
+	case IE_RRC_CONTAINER:
+		*container, err = ie.Value.GetOctetString(0, -1)
+	}
+	return err
+}
+
+func getTransactionID(r *per.Reader) (uint8, error) {
+	id, err := r.GetExtConstrained(0, 255)
+	return uint8(id), err
+}
+
+func getName(r *per.Reader) (string, error) {
+	extended, err := r.GetBool()
+	if err != nil {
+		return "", err
+	}
+	if extended {
+		return "", fmt.Errorf("name outside its size root")
+	}
+	name, err := r.GetOctetString(1, maxNameLength)
+	return string(name), err
+}
+
+func getUEF1APID(r *per.Reader) (uint32, error) {
+	id, err := r.GetConstrained(0, maxUEF1APID)
+	return uint32(id), err
+}
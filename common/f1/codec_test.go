@@ -0,0 +1,315 @@
+package f1
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loadVector reads a hex encoded test vector, ignoring comments and whitespace
+func loadVector(t *testing.T, name string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+
+	var hexStr strings.Builder
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hexStr.WriteString(strings.ReplaceAll(line, " ", ""))
+	}
+
+	raw, err := hex.DecodeString(hexStr.String())
+	require.NoError(t, err)
+	return raw
+}
+
+// roundTrip encodes and decodes a message, and checks that re-encoding the
+// decoded message gives the same octets
+func roundTrip(t *testing.T, msg Message) Message {
+	t.Helper()
+
+	data, err := Encode(msg)
+	require.NoError(t, err)
+	decoded, err := Decode(data)
+	require.NoError(t, err)
+	require.Equal(t, msg.MessageType(), decoded.MessageType())
+
+	again, err := Encode(decoded)
+	require.NoError(t, err)
+	assert.Equal(t, data, again)
+	return decoded
+}
+
+func testNRCGI(cellID uint64) *NRCGI {
+	return &NRCGI{PLMNID: &PLMNID{MCC: "001", MNC: "01"}, NRCellID: cellID}
+}
+
+func testQoS() *QoSFlowLevelQoSParameters {
+	return &QoSFlowLevelQoSParameters{
+		QoSCharacteristics: &QoSCharacteristics{
+			NonDynamic5QI: &NonDynamic5QIDescriptor{FiveQI: 9, QoSPriorityLevel: 90},
+		},
+		NGRANAllocationRetentionPriority: &AllocationRetentionPriority{
+			PriorityLevel:           8,
+			PreemptionCapability:    "SHALL_NOT_TRIGGER_PREEMPTION",
+			PreemptionVulnerability: "PREEMPTABLE",
+		},
+	}
+}
+
+func TestDLRRCMessageTransfer_Vector(t *testing.T) {
+	vector := loadVector(t, "dl_rrc_message_transfer.hex")
+	msg := &DLRRCMessage{GNBCUUEF1APID: 1, GNBDUUEF1APID: 2, SRBID: 1, RRCContainer: []byte{0xab, 0xcd}}
+
+	data, err := Encode(msg)
+	require.NoError(t, err)
+	assert.Equal(t, vector, data)
+
+	decoded, err := Decode(vector)
+	require.NoError(t, err)
+	assert.Equal(t, msg, decoded)
+}
+
+func TestF1Setup_RoundTrip(t *testing.T) {
+	req := &F1SetupRequest{
+		TransactionID: 3,
+		GNBDUID:       0xFFFFFFFFF,
+		GNBDUName:     "du-1",
+		ServedCellsToAdd: []*ServedCell{{
+			ServedCellInfo: &ServedCellInfo{
+				NRCGI:             testNRCGI(0x123456789),
+				NRPCI:             1007,
+				FiveGSTAC:         []byte{0x00, 0x00, 0x01},
+				ConfiguredEPS_TAC: []byte{0x00, 0x01},
+				ServedPLMNs: []*ServedPLMN{
+					{
+						PLMNID: &PLMNID{MCC: "001", MNC: "01"},
+						SliceSupportList: []*SliceSupport{
+							{SST: 1, SD: []byte{0x00, 0x00, 0x01}},
+							{SST: 2},
+						},
+					},
+					{PLMNID: &PLMNID{MCC: "310", MNC: "410"}},
+				},
+				NRModeInfo: &NRModeInfo{TDD: &TDDInfo{
+					NRARFCN:               632628,
+					TransmissionBandwidth: 106,
+					FreqBands:             []uint16{78},
+					SCS:                   30,
+				}},
+				MeasurementTimingConfiguration: []byte{0x10, 0x20},
+			},
+			GNBDUSYSINFO: &SystemInformation{MIB: []byte{0x01, 0x02, 0x03}, SIB1: []byte{0x04, 0x05}},
+		}, {
+			ServedCellInfo: &ServedCellInfo{
+				NRCGI:       testNRCGI(2),
+				ServedPLMNs: []*ServedPLMN{{PLMNID: &PLMNID{MCC: "001", MNC: "01"}}},
+				NRModeInfo: &NRModeInfo{FDD: &FDDInfo{
+					ULARFCN:                 384000,
+					DLARFCN:                 422000,
+					ULTransmissionBandwidth: 52,
+					DLTransmissionBandwidth: 52,
+					FreqBands:               []uint16{1},
+					SCS:                     15,
+				}},
+				MeasurementTimingConfiguration: []byte{},
+			},
+		}},
+		GNBDURRCVersion: &RRCVersion{Latest: []byte{0xE0}, Extended: []byte{17, 3, 0}},
+	}
+	assert.Equal(t, req, roundTrip(t, req))
+
+	resp := &F1SetupResponse{
+		TransactionID:   3,
+		GNBCUNAME:       "cu-1",
+		CellsToActivate: []*CellToActivate{{NRCGI: testNRCGI(0x123456789)}, {NRCGI: testNRCGI(2)}},
+		GNBCURRCVersion: &RRCVersion{Latest: []byte{0xE0}},
+	}
+	assert.Equal(t, resp, roundTrip(t, resp))
+}
+
+func TestUEContextSetup_RoundTrip(t *testing.T) {
+	gbr := testQoS()
+	gbr.QoSCharacteristics = &QoSCharacteristics{Dynamic5QI: &Dynamic5QIDescriptor{
+		QoSPriorityLevel:   20,
+		PacketDelayBudget:  150,
+		PacketErrorRate:    &PacketErrorRate{Scalar: 1, Exponent: 6},
+		AveragingWindow:    2000,
+		MaxDataBurstVolume: 4095,
+	}}
+	gbr.GBRQoSFlowInfo = &GBRQoSFlowInformation{
+		MaxFlowBitRateDL:        4000000000000,
+		MaxFlowBitRateUL:        1000000,
+		GuaranteedFlowBitRateDL: 500000,
+		GuaranteedFlowBitRateUL: 0,
+		MaxPacketLossRateDL:     10,
+	}
+	gbr.ReflectiveQoSAttribute = true
+
+	req := &UEContextSetupRequest{
+		GNBCUUEF1APID: 0xFFFFFFFF,
+		GNBDUUEF1APID: 7,
+		SpCell: &SpCell{
+			ServCellIndex: 31,
+			ServCellID:    testNRCGI(1),
+			ServCellULCfg: &CellULConfiguration{CellULConfigured: true},
+		},
+		SRBsToBeSetup: []*SRBToBeSetup{{SRBID: 1}, {SRBID: 2, DuplicationIndication: true}},
+		DRBsToBeSetup: []*DRBToBeSetup{
+			{
+				DRBID:      1,
+				QoSInfo:    testQoS(),
+				SNSSAI:     &SliceSupport{SST: 1, SD: []byte{0x00, 0x00, 0x01}},
+				QoSFlowIDs: []uint8{1, 2},
+				ULUPTNLInfo: []*UPTransportLayerInformation{{GTPTunnel: &GTPTunnel{
+					TransportLayerAddress: net.ParseIP("10.0.0.1").To4(),
+					GTPTEID:               0x12345678,
+				}}},
+				RLCMode:         "AM",
+				ULConfiguration: &ULConfiguration{ULUEConfiguration: "SHARED"},
+			},
+			{
+				DRBID:      32,
+				QoSInfo:    gbr,
+				SNSSAI:     &SliceSupport{SST: 2},
+				QoSFlowIDs: []uint8{63},
+				ULUPTNLInfo: []*UPTransportLayerInformation{{GTPTunnel: &GTPTunnel{
+					TransportLayerAddress: net.ParseIP("2001:db8::1"),
+					GTPTEID:               1,
+				}}},
+				RLCMode:               "UM",
+				DuplicationIndication: true,
+			},
+		},
+		CUtoDURRCInfo: &CUtoDURRCInformation{UECapabilityRAT: []byte{0x01}, MeasConfig: []byte{0x02, 0x03}},
+	}
+	assert.Equal(t, req, roundTrip(t, req))
+
+	resp := &UEContextSetupResponse{
+		GNBCUUEF1APID: 0xFFFFFFFF,
+		GNBDUUEF1APID: 7,
+		DUtoCURRCInfo: &DUtoCURRCInformation{CellGroupConfig: []byte{0x5c, 0x00}, RequestedP_MaxFR1: 23},
+		SRBsSetup:     []*SRBSetup{{SRBID: 1}, {SRBID: 2}},
+		DRBsSetup: []*DRBSetup{{
+			DRBID: 1,
+			DLUPTNLInfo: []*UPTransportLayerInformation{{GTPTunnel: &GTPTunnel{
+				TransportLayerAddress: net.ParseIP("10.0.0.2").To4(),
+				GTPTEID:               0x87654321,
+			}}},
+		}},
+		SRBsFailedToSetup: []*SRBFailedToSetup{{SRBID: 3, Cause: &Cause{Protocol: &CauseProtocol{Value: "semantic-error"}}}},
+		DRBsFailedToSetup: []*DRBFailedToSetup{
+			{DRBID: 32, Cause: &Cause{RadioNetwork: &CauseRadioNetwork{Value: "no-radio-resources-available"}}},
+			{DRBID: 2},
+		},
+	}
+	assert.Equal(t, resp, roundTrip(t, resp))
+}
+
+func TestRRCMessageTransfer_RoundTrip(t *testing.T) {
+	initial := &InitialULRRCMessage{
+		GNBDUUEF1APID:      1,
+		NRCGI:              testNRCGI(0xFFFFFFFFF),
+		CRNTI:              0x4601,
+		RRCContainer:       []byte{0x1d, 0xec, 0x89, 0xd0, 0x57, 0x66},
+		DUtoCURRCContainer: bytes.Repeat([]byte{0x5c}, 300),
+	}
+	assert.Equal(t, initial, roundTrip(t, initial))
+
+	dl := &DLRRCMessage{GNBCUUEF1APID: 1, GNBDUUEF1APID: 1, SRBID: 0, RRCContainer: []byte{0x20, 0x40}}
+	assert.Equal(t, dl, roundTrip(t, dl))
+
+	ul := &ULRRCMessage{GNBCUUEF1APID: 1, GNBDUUEF1APID: 1, SRBID: 1, RRCContainer: bytes.Repeat([]byte{0xa5}, 200)}
+	assert.Equal(t, ul, roundTrip(t, ul))
+}
+
+func TestEncode_InvalidMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+	}{
+		{"missing rrc version", &F1SetupRequest{GNBDUID: 1}},
+		{"du id over 36 bits", &F1SetupRequest{GNBDUID: 1 << 36, GNBDURRCVersion: &RRCVersion{Latest: []byte{0}}}},
+		{"name too long", &F1SetupResponse{GNBCUNAME: strings.Repeat("a", 151), GNBCURRCVersion: &RRCVersion{Latest: []byte{0}}}},
+		{"missing spcell", &UEContextSetupRequest{}},
+		{"invalid plmn", &InitialULRRCMessage{NRCGI: &NRCGI{PLMNID: &PLMNID{MCC: "1", MNC: "01"}}}},
+		{"srb id out of range", &DLRRCMessage{SRBID: 4}},
+		{"unsupported rlc mode", &UEContextSetupRequest{
+			SpCell: &SpCell{ServCellID: testNRCGI(1)},
+			DRBsToBeSetup: []*DRBToBeSetup{{
+				DRBID: 1, QoSInfo: testQoS(), SNSSAI: &SliceSupport{SST: 1}, QoSFlowIDs: []uint8{1},
+				ULUPTNLInfo: []*UPTransportLayerInformation{{GTPTunnel: &GTPTunnel{TransportLayerAddress: net.IPv4(10, 0, 0, 1)}}},
+				RLCMode:     "TM",
+			}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Encode(tt.msg)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDecode_Malformed(t *testing.T) {
+	vector := loadVector(t, "dl_rrc_message_transfer.hex")
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"truncated header", vector[:3]},
+		{"truncated value", vector[:len(vector)-1]},
+		{"trailing octets", append(append([]byte(nil), vector...), 0x00)},
+		{"unknown procedure", func() []byte {
+			b := append([]byte(nil), vector...)
+			b[1] = 0x63
+			return b
+		}()},
+		{"missing mandatory ie", func() []byte {
+			// Drop the RRC-Container IE and shorten the lengths to match
+			b := append([]byte(nil), vector[:len(vector)-7]...)
+			b[3] -= 7
+			b[6] = 3
+			return b
+		}()},
+		{"duplicate ie", func() []byte {
+			// Repeat gNB-CU UE F1AP ID in place of gNB-DU UE F1AP ID
+			b := append([]byte(nil), vector...)
+			b[14] = 0x28
+			return b
+		}()},
+		{"ie length beyond value", func() []byte {
+			b := append([]byte(nil), vector...)
+			b[10] = 0x7f
+			return b
+		}()},
+		{"srb id outside extension root", func() []byte {
+			b := append([]byte(nil), vector...)
+			b[23] = 0x80
+			return b
+		}()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(tt.data)
+			assert.Error(t, err)
+		})
+	}
+}
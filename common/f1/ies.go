@@ -0,0 +1,1327 @@
+package f1
+
+import (
+	"fmt"
+	"github.com/your-org/5g-network/common/per"
+	"net"
+)
+
+// F1AP protocol IE identifiers (TS 38.473 9.4.7)
+const (
+	IE_CAUSE                           = 0
+	IE_CELLS_TO_BE_ACTIVATED_LIST      = 3
+	IE_CELLS_TO_BE_ACTIVATED_LIST_ITEM = 4
+	IE_CU_TO_DU_RRC_INFORMATION        = 9
+	IE_DRBS_FAILED_TO_BE_SETUP_ITEM    = 14
+	IE_DRBS_FAILED_TO_BE_SETUP_LIST    = 15
+	IE_DRBS_SETUP_ITEM                 = 26
+	IE_DRBS_SETUP_LIST                 = 27
+	IE_DRBS_TO_BE_SETUP_ITEM           = 34
+	IE_DRBS_TO_BE_SETUP_LIST           = 35
+	IE_DU_TO_CU_RRC_INFORMATION        = 39
+	IE_GNB_CU_UE_F1AP_ID               = 40
+	IE_GNB_DU_UE_F1AP_ID               = 41
+	IE_GNB_DU_ID                       = 42
+	IE_GNB_DU_SERVED_CELLS_ITEM        = 43
+	IE_GNB_DU_SERVED_CELLS_LIST        = 44
+	IE_GNB_DU_NAME                     = 45
+	IE_RRC_CONTAINER                   = 50
+	IE_SPCELL_ID                       = 63
+	IE_SRBID                           = 64
+	IE_SRBS_FAILED_TO_BE_SETUP_ITEM    = 65
+	IE_SRBS_FAILED_TO_BE_SETUP_LIST    = 66
+	IE_SRBS_TO_BE_SETUP_ITEM           = 73
+	IE_SRBS_TO_BE_SETUP_LIST           = 74
+	IE_TRANSACTION_ID                  = 78
+	IE_GNB_CU_NAME                     = 82
+	IE_C_RNTI                          = 95
+	IE_SPCELL_UL_CONFIGURED            = 96
+	IE_SERV_CELL_INDEX                 = 107
+	IE_NRCGI                           = 111
+	IE_DU_TO_CU_RRC_CONTAINER          = 128
+	IE_TAI_SLICE_SUPPORT_LIST          = 131
+	IE_DRB_INFORMATION                 = 164
+	IE_GNB_CU_RRC_VERSION              = 170
+	IE_GNB_DU_RRC_VERSION              = 171
+	IE_LATEST_RRC_VERSION_ENHANCED     = 199
+	IE_SRBS_SETUP_ITEM                 = 202
+	IE_SRBS_SETUP_LIST                 = 203
+)
+
+// Bounds from the F1AP ASN.1 (TS 38.473 9.4.5, 9.4.6)
+const (
+	maxCellingNBDU            = 512
+	maxnoofBPLMNs             = 6
+	maxnoofSliceItems         = 1024
+	maxnoofNrCellBands        = 32
+	maxnoofSRBs               = 8
+	maxnoofDRBs               = 64
+	maxnoofQoSFlows           = 64
+	maxnoofUPTNLInformation   = 2
+	maxNRARFCN                = 3279165
+	maxBitRate                = 4000000000000
+	maxGNBDUID                = 1<<36 - 1
+	maxUEF1APID               = 1<<32 - 1
+	maxNRPCI                  = 1007
+	maxNameLength             = 150
+	maxTransportLayerAddress  = 160
+	nrCellIdentityLength      = 36
+	latestRRCVersionLength    = 3
+	rrcVersionEnhancedLength  = 3
+	criticalityValues         = 3
+	causeRadioNetworkChoice   = 0
+	causeTransportChoice      = 1
+	causeProtocolChoice       = 2
+	causeMiscChoice           = 3
+	causeChoices              = 5 // Including choice-extension
+	nrModeInfoChoices         = 3 // FDD, TDD, choice-extension
+	qosCharacteristicsChoices = 3 // Non-dynamic, dynamic, choice-extension
+)
+
+// Enumerations used by the IEs; extensible ones only support their root
+var (
+	causeRadioNetworkValues = []string{
+		"unspecified", "rl-failure-rlc", "unknown-or-already-allocated-gnb-cu-ue-f1ap-id",
+		"unknown-or-already-allocated-gnb-du-ue-f1ap-id", "unknown-or-inconsistent-pair-of-ue-f1ap-id",
+		"interaction-with-other-procedure", "not-supported-qci-value", "action-desirable-for-radio-reasons",
+		"no-radio-resources-available", "procedure-cancelled", "normal-release",
+	}
+	causeTransportValues = []string{"unspecified", "transport-resource-unavailable"}
+	causeProtocolValues  = []string{
+		"transfer-syntax-error", "abstract-syntax-error-reject", "abstract-syntax-error-ignore-and-notify",
+		"message-not-compatible-with-receiver-state", "semantic-error", "abstract-syntax-error-falsely-constructed-message",
+		"unspecified",
+	}
+	causeMiscValues = []string{
+		"control-processing-overload", "not-enough-user-plane-processing-resources", "hardware-failure",
+		"om-intervention", "unspecified",
+	}
+	preemptionCapabilityValues    = []string{"SHALL_NOT_TRIGGER_PREEMPTION", "MAY_TRIGGER_PREEMPTION"}
+	preemptionVulnerabilityValues = []string{"NOT_PREEMPTABLE", "PREEMPTABLE"}
+	ulUEConfigurationValues       = []string{"NO_DATA", "SHARED", "ONLY"}
+	// RLC modes rlc-am, rlc-um-bidirectional, rlc-um-unidirectional-ul and
+	// rlc-um-unidirectional-dl
+	rlcModeValues = []string{"AM", "UM", "UM", "UM"}
+	nrSCSValues   = []uint16{15, 30, 60, 120}
+	nrNRBValues   = []uint16{
+		11, 18, 24, 25, 31, 32, 38, 51, 52, 65, 66, 78, 79, 93, 106, 107, 121, 132, 133, 135,
+		160, 162, 189, 216, 217, 245, 264, 270, 273,
+	}
+)
+
+// PLMN Identity (TS 38.473 9.3.1.14)
+func putPLMNIdentity(w *per.Writer, plmn *PLMNID) error {
+	if plmn == nil {
+		return fmt.Errorf("missing plmn identity")
+	}
+	return per.PutPLMNIdentity(w, plmn.MCC, plmn.MNC)
+}
+
+func getPLMNIdentity(r *per.Reader) (*PLMNID, error) {
+	mcc, mnc, err := per.GetPLMNIdentity(r)
+	if err != nil {
+		return nil, err
+	}
+	return &PLMNID{MCC: mcc, MNC: mnc}, nil
+}
+
+// NR CGI (TS 38.473 9.3.1.12)
+func putNRCGI(w *per.Writer, cgi *NRCGI) error {
+	if cgi == nil {
+		return fmt.Errorf("missing nr cgi")
+	}
+	if cgi.NRCellID >= 1<<nrCellIdentityLength {
+		return fmt.Errorf("nr cell identity %d exceeds 36 bits", cgi.NRCellID)
+	}
+	per.PutSequence(w, false)
+	if err := putPLMNIdentity(w, cgi.PLMNID); err != nil {
+		return err
+	}
+	id := cgi.NRCellID << 4
+	cellID := []byte{byte(id >> 32), byte(id >> 24), byte(id >> 16), byte(id >> 8), byte(id)}
+	return w.PutBitString(cellID, nrCellIdentityLength, nrCellIdentityLength, nrCellIdentityLength)
+}
+
+func getNRCGI(r *per.Reader) (*NRCGI, error) {
+	present, err := per.GetSequence(r, 1)
+	if err != nil {
+		return nil, err
+	}
+	plmn, err := getPLMNIdentity(r)
+	if err != nil {
+		return nil, err
+	}
+	cellID, _, err := r.GetBitString(nrCellIdentityLength, nrCellIdentityLength)
+	if err != nil {
+		return nil, err
+	}
+	var id uint64
+	for _, b := range cellID {
+		id = id<<8 | uint64(b)
+	}
+	return &NRCGI{PLMNID: plmn, NRCellID: id >> 4}, per.SkipExtensions(r, present[0])
+}
+
+// RRC Version (TS 38.473 9.3.1.70)
+func putRRCVersion(w *per.Writer, version *RRCVersion) error {
+	if version == nil {
+		return fmt.Errorf("missing rrc version")
+	}
+	hasEnhanced := len(version.Extended) > 0
+	per.PutSequence(w, hasEnhanced)
+	if err := w.PutBitString(version.Latest, latestRRCVersionLength, latestRRCVersionLength, latestRRCVersionLength); err != nil {
+		return fmt.Errorf("latest rrc version: %w", err)
+	}
+	if !hasEnhanced {
+		return nil
+	}
+	return per.PutContainer(w, []per.ProtocolIE{{ID: IE_LATEST_RRC_VERSION_ENHANCED, Criticality: per.CRITICALITY_REJECT, Encode: func(w *per.Writer) error {
+		return w.PutOctetString(version.Extended, rrcVersionEnhancedLength, rrcVersionEnhancedLength)
+	}}}, 1)
+}
+
+func getRRCVersion(r *per.Reader) (*RRCVersion, error) {
+	present, err := per.GetSequence(r, 1)
+	if err != nil {
+		return nil, err
+	}
+	latest, _, err := r.GetBitString(latestRRCVersionLength, latestRRCVersionLength)
+	if err != nil {
+		return nil, err
+	}
+	version := &RRCVersion{Latest: latest}
+	if !present[0] {
+		return version, nil
+	}
+	extensions, err := per.GetContainer(r, 1)
+	if err != nil {
+		return nil, err
+	}
+	for _, ext := range extensions {
+		if ext.ID == IE_LATEST_RRC_VERSION_ENHANCED {
+			if version.Extended, err = ext.Value.GetOctetString(rrcVersionEnhancedLength, rrcVersionEnhancedLength); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return version, nil
+}
+
+// GNB-DU Served Cells Item (TS 38.473 9.3.1.10, 9.3.1.18)
+func putServedCell(w *per.Writer, cell *ServedCell) error {
+	if cell == nil || cell.ServedCellInfo == nil {
+		return fmt.Errorf("missing served cell information")
+	}
+	per.PutSequence(w, cell.GNBDUSYSINFO != nil, false)
+	if err := putServedCellInfo(w, cell.ServedCellInfo); err != nil {
+		return err
+	}
+	if cell.GNBDUSYSINFO == nil {
+		return nil
+	}
+	per.PutSequence(w, false)
+	if err := w.PutOctetString(cell.GNBDUSYSINFO.MIB, 0, -1); err != nil {
+		return err
+	}
+	return w.PutOctetString(cell.GNBDUSYSINFO.SIB1, 0, -1)
+}
+
+func getServedCell(r *per.Reader) (*ServedCell, error) {
+	present, err := per.GetSequence(r, 2)
+	if err != nil {
+		return nil, err
+	}
+	cell := &ServedCell{}
+	if cell.ServedCellInfo, err = getServedCellInfo(r); err != nil {
+		return nil, err
+	}
+	if present[0] {
+		sysPresent, err := per.GetSequence(r, 1)
+		if err != nil {
+			return nil, err
+		}
+		cell.GNBDUSYSINFO = &SystemInformation{}
+		if cell.GNBDUSYSINFO.MIB, err = r.GetOctetString(0, -1); err != nil {
+			return nil, err
+		}
+		if cell.GNBDUSYSINFO.SIB1, err = r.GetOctetString(0, -1); err != nil {
+			return nil, err
+		}
+		if err := per.SkipExtensions(r, sysPresent[0]); err != nil {
+			return nil, err
+		}
+	}
+	return cell, per.SkipExtensions(r, present[1])
+}
+
+func putServedCellInfo(w *per.Writer, info *ServedCellInfo) error {
+	hasTAC, hasEPSTAC := len(info.FiveGSTAC) > 0, len(info.ConfiguredEPS_TAC) > 0
+	per.PutSequence(w, hasTAC, hasEPSTAC, false)
+	if err := putNRCGI(w, info.NRCGI); err != nil {
+		return err
+	}
+	if err := w.PutConstrained(uint64(info.NRPCI), 0, maxNRPCI); err != nil {
+		return fmt.Errorf("nr pci: %w", err)
+	}
+	if hasTAC {
+		if err := w.PutOctetString(info.FiveGSTAC, 3, 3); err != nil {
+			return fmt.Errorf("5gs tac: %w", err)
+		}
+	}
+	if hasEPSTAC {
+		if err := w.PutOctetString(info.ConfiguredEPS_TAC, 2, 2); err != nil {
+			return fmt.Errorf("configured eps tac: %w", err)
+		}
+	}
+	if err := per.PutList(w, info.ServedPLMNs, 1, maxnoofBPLMNs, putServedPLMN); err != nil {
+		return fmt.Errorf("served plmns: %w", err)
+	}
+	if err := putNRModeInfo(w, info.NRModeInfo); err != nil {
+		return err
+	}
+	return w.PutOctetString(info.MeasurementTimingConfiguration, 0, -1)
+}
+
+func getServedCellInfo(r *per.Reader) (*ServedCellInfo, error) {
+	present, err := per.GetSequence(r, 3)
+	if err != nil {
+		return nil, err
+	}
+	info := &ServedCellInfo{}
+	if info.NRCGI, err = getNRCGI(r); err != nil {
+		return nil, err
+	}
+	pci, err := r.GetConstrained(0, maxNRPCI)
+	if err != nil {
+		return nil, err
+	}
+	info.NRPCI = uint16(pci)
+	if present[0] {
+		if info.FiveGSTAC, err = r.GetOctetString(3, 3); err != nil {
+			return nil, err
+		}
+	}
+	if present[1] {
+		if info.ConfiguredEPS_TAC, err = r.GetOctetString(2, 2); err != nil {
+			return nil, err
+		}
+	}
+	if info.ServedPLMNs, err = per.GetList(r, 1, maxnoofBPLMNs, getServedPLMN); err != nil {
+		return nil, err
+	}
+	if info.NRModeInfo, err = getNRModeInfo(r); err != nil {
+		return nil, err
+	}
+	if info.MeasurementTimingConfiguration, err = r.GetOctetString(0, -1); err != nil {
+		return nil, err
+	}
+	return info, per.SkipExtensions(r, present[2])
+}
+
+// Served PLMNs Item; the slices are the TAI Slice Support List extension
+func putServedPLMN(w *per.Writer, plmn *ServedPLMN) error {
+	if plmn == nil {
+		return fmt.Errorf("missing served plmn")
+	}
+	hasSlices := len(plmn.SliceSupportList) > 0
+	per.PutSequence(w, hasSlices)
+	if err := putPLMNIdentity(w, plmn.PLMNID); err != nil {
+		return err
+	}
+	if !hasSlices {
+		return nil
+	}
+	return per.PutContainer(w, []per.ProtocolIE{{ID: IE_TAI_SLICE_SUPPORT_LIST, Criticality: per.CRITICALITY_IGNORE, Encode: func(w *per.Writer) error {
+		return per.PutList(w, plmn.SliceSupportList, 1, maxnoofSliceItems, func(w *per.Writer, slice *SliceSupport) error {
+			per.PutSequence(w, false)
+			return putSNSSAI(w, slice)
+		})
+	}}}, 1)
+}
+
+func getServedPLMN(r *per.Reader) (*ServedPLMN, error) {
+	present, err := per.GetSequence(r, 1)
+	if err != nil {
+		return nil, err
+	}
+	plmn := &ServedPLMN{}
+	if plmn.PLMNID, err = getPLMNIdentity(r); err != nil {
+		return nil, err
+	}
+	if !present[0] {
+		return plmn, nil
+	}
+	extensions, err := per.GetContainer(r, 1)
+	if err != nil {
+		return nil, err
+	}
+	for _, ext := range extensions {
+		if ext.ID != IE_TAI_SLICE_SUPPORT_LIST {
+			continue
+		}
+		plmn.SliceSupportList, err = per.GetList(ext.Value, 1, maxnoofSliceItems, func(r *per.Reader) (*SliceSupport, error) {
+			present, err := per.GetSequence(r, 1)
+			if err != nil {
+				return nil, err
+			}
+			slice, err := getSNSSAI(r)
+			if err != nil {
+				return nil, err
+			}
+			return slice, per.SkipExtensions(r, present[0])
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return plmn, nil
+}
+
+// S-NSSAI (TS 38.473 9.3.1.38)
+func putSNSSAI(w *per.Writer, slice *SliceSupport) error {
+	if slice == nil {
+		return fmt.Errorf("missing s-nssai")
+	}
+	hasSD := len(slice.SD) > 0
+	per.PutSequence(w, hasSD, false)
+	if err := w.PutOctetString([]byte{slice.SST}, 1, 1); err != nil {
+		return err
+	}
+	if hasSD {
+		if err := w.PutOctetString(slice.SD, 3, 3); err != nil {
+			return fmt.Errorf("sd: %w", err)
+		}
+	}
+	return nil
+}
+
+func getSNSSAI(r *per.Reader) (*SliceSupport, error) {
+	present, err := per.GetSequence(r, 2)
+	if err != nil {
+		return nil, err
+	}
+	sst, err := r.GetOctetString(1, 1)
+	if err != nil {
+		return nil, err
+	}
+	slice := &SliceSupport{SST: sst[0]}
+	if present[0] {
+		if slice.SD, err = r.GetOctetString(3, 3); err != nil {
+			return nil, err
+		}
+	}
+	return slice, per.SkipExtensions(r, present[1])
+}
+
+// NR Mode Info (TS 38.473 9.3.1.17)
+func putNRModeInfo(w *per.Writer, mode *NRModeInfo) error {
+	switch {
+	case mode != nil && mode.FDD != nil:
+		fdd := mode.FDD
+		if err := w.PutConstrained(0, 0, nrModeInfoChoices-1); err != nil {
+			return err
+		}
+		per.PutSequence(w, false)
+		if err := putNRFreqInfo(w, fdd.ULARFCN, fdd.FreqBands); err != nil {
+			return err
+		}
+		if err := putNRFreqInfo(w, fdd.DLARFCN, fdd.FreqBands); err != nil {
+			return err
+		}
+		if err := putTransmissionBandwidth(w, fdd.SCS, fdd.ULTransmissionBandwidth); err != nil {
+			return err
+		}
+		return putTransmissionBandwidth(w, fdd.SCS, fdd.DLTransmissionBandwidth)
+	case mode != nil && mode.TDD != nil:
+		tdd := mode.TDD
+		if err := w.PutConstrained(1, 0, nrModeInfoChoices-1); err != nil {
+			return err
+		}
+		per.PutSequence(w, false)
+		if err := putNRFreqInfo(w, tdd.NRARFCN, tdd.FreqBands); err != nil {
+			return err
+		}
+		return putTransmissionBandwidth(w, tdd.SCS, tdd.TransmissionBandwidth)
+	default:
+		return fmt.Errorf("missing nr mode info")
+	}
+}
+
+func getNRModeInfo(r *per.Reader) (*NRModeInfo, error) {
+	choice, err := r.GetConstrained(0, nrModeInfoChoices-1)
+	if err != nil {
+		return nil, err
+	}
+	switch choice {
+	case 0:
+		present, err := per.GetSequence(r, 1)
+		if err != nil {
+			return nil, err
+		}
+		fdd := &FDDInfo{}
+		if fdd.ULARFCN, _, err = getNRFreqInfo(r); err != nil {
+			return nil, err
+		}
+		if fdd.DLARFCN, fdd.FreqBands, err = getNRFreqInfo(r); err != nil {
+			return nil, err
+		}
+		if fdd.SCS, fdd.ULTransmissionBandwidth, err = getTransmissionBandwidth(r); err != nil {
+			return nil, err
+		}
+		if _, fdd.DLTransmissionBandwidth, err = getTransmissionBandwidth(r); err != nil {
+			return nil, err
+		}
+		return &NRModeInfo{FDD: fdd}, per.SkipExtensions(r, present[0])
+	case 1:
+		present, err := per.GetSequence(r, 1)
+		if err != nil {
+			return nil, err
+		}
+		tdd := &TDDInfo{}
+		if tdd.NRARFCN, tdd.FreqBands, err = getNRFreqInfo(r); err != nil {
+			return nil, err
+		}
+		if tdd.SCS, tdd.TransmissionBandwidth, err = getTransmissionBandwidth(r); err != nil {
+			return nil, err
+		}
+		return &NRModeInfo{TDD: tdd}, per.SkipExtensions(r, present[0])
+	default:
+		return nil, fmt.Errorf("unsupported nr mode info choice %d", choice)
+	}
+}
+
+// NR Frequency Info (TS 38.473 9.3.1.17); no SUL and no supported SUL bands
+func putNRFreqInfo(w *per.Writer, arfcn uint32, bands []uint16) error {
+	per.PutSequence(w, false, false)
+	if err := w.PutConstrained(uint64(arfcn), 0, maxNRARFCN); err != nil {
+		return fmt.Errorf("nr arfcn: %w", err)
+	}
+	return per.PutList(w, bands, 1, maxnoofNrCellBands, func(w *per.Writer, band uint16) error {
+		per.PutSequence(w, false)
+		if err := w.PutExtConstrained(uint64(band), 1, 1024); err != nil {
+			return fmt.Errorf("nr band: %w", err)
+		}
+		return w.PutLength(0, 0, maxnoofNrCellBands)
+	})
+}
+
+func getNRFreqInfo(r *per.Reader) (uint32, []uint16, error) {
+	present, err := per.GetSequence(r, 2)
+	if err != nil {
+		return 0, nil, err
+	}
+	arfcn, err := r.GetConstrained(0, maxNRARFCN)
+	if err != nil {
+		return 0, nil, err
+	}
+	if present[0] {
+		return 0, nil, fmt.Errorf("sul information is not supported")
+	}
+	bands, err := per.GetList(r, 1, maxnoofNrCellBands, func(r *per.Reader) (uint16, error) {
+		present, err := per.GetSequence(r, 1)
+		if err != nil {
+			return 0, err
+		}
+		band, err := r.GetExtConstrained(1, 1024)
+		if err != nil {
+			return 0, err
+		}
+		if n, err := r.GetLength(0, maxnoofNrCellBands); err != nil || n != 0 {
+			return 0, fmt.Errorf("supported sul bands are not supported")
+		}
+		return uint16(band), per.SkipExtensions(r, present[0])
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return uint32(arfcn), bands, per.SkipExtensions(r, present[1])
+}
+
+// Transmission Bandwidth (TS 38.473 9.3.1.15)
+func putTransmissionBandwidth(w *per.Writer, scs, nrb uint16) error {
+	per.PutSequence(w, false)
+	if err := per.PutEnumerated(w, scs, nrSCSValues, true); err != nil {
+		return fmt.Errorf("nr scs: %w", err)
+	}
+	if err := per.PutEnumerated(w, nrb, nrNRBValues, true); err != nil {
+		return fmt.Errorf("nr nrb: %w", err)
+	}
+	return nil
+}
+
+func getTransmissionBandwidth(r *per.Reader) (uint16, uint16, error) {
+	present, err := per.GetSequence(r, 1)
+	if err != nil {
+		return 0, 0, err
+	}
+	scs, err := per.GetEnumerated(r, nrSCSValues, true)
+	if err != nil {
+		return 0, 0, err
+	}
+	nrb, err := per.GetEnumerated(r, nrNRBValues, true)
+	if err != nil {
+		return 0, 0, err
+	}
+	return scs, nrb, per.SkipExtensions(r, present[0])
+}
+
+// Cells to be Activated List Item (TS 38.473 9.2.1.5)
+func putCellToActivate(w *per.Writer, cell *CellToActivate) error {
+	if cell == nil {
+		return fmt.Errorf("missing cell to activate")
+	}
+	per.PutSequence(w, false, false)
+	return putNRCGI(w, cell.NRCGI)
+}
+
+func getCellToActivate(r *per.Reader) (*CellToActivate, error) {
+	present, err := per.GetSequence(r, 2)
+	if err != nil {
+		return nil, err
+	}
+	cgi, err := getNRCGI(r)
+	if err != nil {
+		return nil, err
+	}
+	if present[0] {
+		if _, err := r.GetConstrained(0, maxNRPCI); err != nil {
+			return nil, err
+		}
+	}
+	return &CellToActivate{NRCGI: cgi}, per.SkipExtensions(r, present[1])
+}
+
+// CU to DU RRC Information (TS 38.473 9.3.1.25)
+func putCUtoDURRCInformation(w *per.Writer, info *CUtoDURRCInformation) error {
+	fields := [][]byte{info.CGConfigInfo, info.UECapabilityRAT, info.MeasConfig}
+	per.PutSequence(w, len(fields[0]) > 0, len(fields[1]) > 0, len(fields[2]) > 0, false)
+	for _, field := range fields {
+		if len(field) == 0 {
+			continue
+		}
+		if err := w.PutOctetString(field, 0, -1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getCUtoDURRCInformation(r *per.Reader) (*CUtoDURRCInformation, error) {
+	present, err := per.GetSequence(r, 4)
+	if err != nil {
+		return nil, err
+	}
+	info := &CUtoDURRCInformation{}
+	for i, field := range []*[]byte{&info.CGConfigInfo, &info.UECapabilityRAT, &info.MeasConfig} {
+		if !present[i] {
+			continue
+		}
+		if *field, err = r.GetOctetString(0, -1); err != nil {
+			return nil, err
+		}
+	}
+	return info, per.SkipExtensions(r, present[3])
+}
+
+// DU to CU RRC Information (TS 38.473 9.3.1.26)
+func putDUtoCURRCInformation(w *per.Writer, info *DUtoCURRCInformation) error {
+	hasMeasGap, hasPMax := len(info.MeasGapConfig) > 0, info.RequestedP_MaxFR1 != 0
+	per.PutSequence(w, hasMeasGap, hasPMax, false)
+	if err := w.PutOctetString(info.CellGroupConfig, 0, -1); err != nil {
+		return err
+	}
+	if hasMeasGap {
+		if err := w.PutOctetString(info.MeasGapConfig, 0, -1); err != nil {
+			return err
+		}
+	}
+	if hasPMax {
+		return w.PutOctetString([]byte{info.RequestedP_MaxFR1}, 0, -1)
+	}
+	return nil
+}
+
+func getDUtoCURRCInformation(r *per.Reader) (*DUtoCURRCInformation, error) {
+	present, err := per.GetSequence(r, 3)
+	if err != nil {
+		return nil, err
+	}
+	info := &DUtoCURRCInformation{}
+	if info.CellGroupConfig, err = r.GetOctetString(0, -1); err != nil {
+		return nil, err
+	}
+	if present[0] {
+		if info.MeasGapConfig, err = r.GetOctetString(0, -1); err != nil {
+			return nil, err
+		}
+	}
+	if present[1] {
+		pMax, err := r.GetOctetString(0, -1)
+		if err != nil {
+			return nil, err
+		}
+		if len(pMax) != 1 {
+			return nil, fmt.Errorf("requested p-max fr1 of %d octets", len(pMax))
+		}
+		info.RequestedP_MaxFR1 = pMax[0]
+	}
+	return info, per.SkipExtensions(r, present[2])
+}
+
+// SRBs to be Setup Item (TS 38.473 9.2.2.1)
+func putSRBToBeSetup(w *per.Writer, srb *SRBToBeSetup) error {
+	per.PutSequence(w, srb.DuplicationIndication, false)
+	if err := w.PutExtConstrained(uint64(srb.SRBID), 0, 3); err != nil {
+		return fmt.Errorf("srb id: %w", err)
+	}
+	if srb.DuplicationIndication {
+		// ENUMERATED {true, ..., false}: the root holds only true
+		w.PutBool(false)
+	}
+	return nil
+}
+
+func getSRBToBeSetup(r *per.Reader) (*SRBToBeSetup, error) {
+	present, err := per.GetSequence(r, 2)
+	if err != nil {
+		return nil, err
+	}
+	id, err := r.GetExtConstrained(0, 3)
+	if err != nil {
+		return nil, err
+	}
+	srb := &SRBToBeSetup{SRBID: uint8(id)}
+	if present[0] {
+		if srb.DuplicationIndication, err = per.GetEnumerated(r, []bool{true}, true); err != nil {
+			return nil, err
+		}
+	}
+	return srb, per.SkipExtensions(r, present[1])
+}
+
+// DRBs to be Setup Item (TS 38.473 9.2.2.1). The QoS information is the
+// DRB Information choice extension, with every QoS flow mapped to the DRB
+// given the DRB's QoS parameters.
+func putDRBToBeSetup(w *per.Writer, drb *DRBToBeSetup) error {
+	per.PutSequence(w, drb.ULConfiguration != nil, drb.DuplicationIndication, false)
+	if err := w.PutExtConstrained(uint64(drb.DRBID), 1, 32); err != nil {
+		return fmt.Errorf("drb id: %w", err)
+	}
+
+	// QoS Information: CHOICE {eUTRANQoS, choice-extension}
+	w.PutBits(1, 1)
+	err := per.PutField(w, per.ProtocolIE{ID: IE_DRB_INFORMATION, Criticality: per.CRITICALITY_IGNORE, Encode: func(w *per.Writer) error {
+		per.PutPresence(w, false, false)
+		if err := putQoSFlowLevelQoSParameters(w, drb.QoSInfo); err != nil {
+			return err
+		}
+		if err := putSNSSAI(w, drb.SNSSAI); err != nil {
+			return err
+		}
+		return per.PutList(w, drb.QoSFlowIDs, 1, maxnoofQoSFlows, func(w *per.Writer, qfi uint8) error {
+			per.PutPresence(w, false)
+			if err := w.PutConstrained(uint64(qfi), 0, 63); err != nil {
+				return fmt.Errorf("qos flow identifier: %w", err)
+			}
+			return putQoSFlowLevelQoSParameters(w, drb.QoSInfo)
+		})
+	}})
+	if err != nil {
+		return err
+	}
+
+	if err := per.PutList(w, drb.ULUPTNLInfo, 1, maxnoofUPTNLInformation, putUPTNLInformationItem); err != nil {
+		return fmt.Errorf("ul up tnl information: %w", err)
+	}
+	rlcMode := 0
+	switch drb.RLCMode {
+	case "AM":
+	case "UM":
+		rlcMode = 1
+	default:
+		return fmt.Errorf("unsupported rlc mode %q", drb.RLCMode)
+	}
+	w.PutBool(false)
+	if err := w.PutConstrained(uint64(rlcMode), 0, uint64(len(rlcModeValues)-1)); err != nil {
+		return err
+	}
+	if drb.ULConfiguration != nil {
+		per.PutSequence(w, false)
+		if err := per.PutEnumerated(w, drb.ULConfiguration.ULUEConfiguration, ulUEConfigurationValues, true); err != nil {
+			return fmt.Errorf("ul ue configuration: %w", err)
+		}
+	}
+	if drb.DuplicationIndication {
+		// Duplication Activation ENUMERATED {active, inactive, ...}
+		w.PutBool(false)
+		w.PutBits(0, 1)
+	}
+	return nil
+}
+
+func getDRBToBeSetup(r *per.Reader) (*DRBToBeSetup, error) {
+	present, err := per.GetSequence(r, 3)
+	if err != nil {
+		return nil, err
+	}
+	id, err := r.GetExtConstrained(1, 32)
+	if err != nil {
+		return nil, err
+	}
+	drb := &DRBToBeSetup{DRBID: uint8(id)}
+
+	choice, err := r.GetBits(1)
+	if err != nil {
+		return nil, err
+	}
+	if choice != 1 {
+		return nil, fmt.Errorf("e-utran qos is not supported")
+	}
+	ie, err := per.GetField(r)
+	if err != nil {
+		return nil, err
+	}
+	if ie.ID != IE_DRB_INFORMATION {
+		return nil, fmt.Errorf("unsupported qos information ie %d", ie.ID)
+	}
+	infoPresent, err := per.GetPresence(ie.Value, 2)
+	if err != nil {
+		return nil, err
+	}
+	if drb.QoSInfo, err = getQoSFlowLevelQoSParameters(ie.Value); err != nil {
+		return nil, err
+	}
+	if drb.SNSSAI, err = getSNSSAI(ie.Value); err != nil {
+		return nil, err
+	}
+	if infoPresent[0] {
+		// Notification Control ENUMERATED {active, not-active, ...}
+		if _, err := per.GetEnumerated(ie.Value, []bool{true, false}, true); err != nil {
+			return nil, err
+		}
+	}
+	drb.QoSFlowIDs, err = per.GetList(ie.Value, 1, maxnoofQoSFlows, func(r *per.Reader) (uint8, error) {
+		present, err := per.GetPresence(r, 1)
+		if err != nil {
+			return 0, err
+		}
+		qfi, err := r.GetConstrained(0, 63)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := getQoSFlowLevelQoSParameters(r); err != nil {
+			return 0, err
+		}
+		return uint8(qfi), per.SkipExtensions(r, present[0])
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := per.SkipExtensions(ie.Value, infoPresent[1]); err != nil {
+		return nil, err
+	}
+
+	if drb.ULUPTNLInfo, err = per.GetList(r, 1, maxnoofUPTNLInformation, getUPTNLInformationItem); err != nil {
+		return nil, err
+	}
+	if drb.RLCMode, err = per.GetEnumerated(r, rlcModeValues, true); err != nil {
+		return nil, err
+	}
+	if present[0] {
+		ulPresent, err := per.GetSequence(r, 1)
+		if err != nil {
+			return nil, err
+		}
+		drb.ULConfiguration = &ULConfiguration{}
+		if drb.ULConfiguration.ULUEConfiguration, err = per.GetEnumerated(r, ulUEConfigurationValues, true); err != nil {
+			return nil, err
+		}
+		if err := per.SkipExtensions(r, ulPresent[0]); err != nil {
+			return nil, err
+		}
+	}
+	if present[1] {
+		active, err := per.GetEnumerated(r, []bool{true, false}, true)
+		if err != nil {
+			return nil, err
+		}
+		drb.DuplicationIndication = active
+	}
+	return drb, per.SkipExtensions(r, present[2])
+}
+
+// QoS Flow Level QoS Parameters (TS 38.473 9.3.1.45)
+func putQoSFlowLevelQoSParameters(w *per.Writer, qos *QoSFlowLevelQoSParameters) error {
+	if qos == nil || qos.QoSCharacteristics == nil {
+		return fmt.Errorf("missing qos flow level qos parameters")
+	}
+	if qos.NGRANAllocationRetentionPriority == nil {
+		return fmt.Errorf("missing ng-ran allocation and retention priority")
+	}
+	per.PutPresence(w, qos.GBRQoSFlowInfo != nil, qos.ReflectiveQoSAttribute, false)
+
+	switch chars := qos.QoSCharacteristics; {
+	case chars.NonDynamic5QI != nil:
+		d := chars.NonDynamic5QI
+		if err := w.PutConstrained(0, 0, qosCharacteristicsChoices-1); err != nil {
+			return err
+		}
+		per.PutPresence(w, d.QoSPriorityLevel != 0, d.AveragingWindow != 0, d.MaxDataBurstVolume != 0, false)
+		if err := w.PutExtConstrained(uint64(d.FiveQI), 0, 255); err != nil {
+			return err
+		}
+		if d.QoSPriorityLevel != 0 {
+			if err := w.PutConstrained(uint64(d.QoSPriorityLevel), 1, 127); err != nil {
+				return fmt.Errorf("qos priority level: %w", err)
+			}
+		}
+		if err := putBurstParameters(w, d.AveragingWindow, d.MaxDataBurstVolume); err != nil {
+			return err
+		}
+	case chars.Dynamic5QI != nil:
+		d := chars.Dynamic5QI
+		if d.PacketErrorRate == nil {
+			return fmt.Errorf("missing packet error rate")
+		}
+		if err := w.PutConstrained(1, 0, qosCharacteristicsChoices-1); err != nil {
+			return err
+		}
+		per.PutPresence(w, false, false, d.AveragingWindow != 0, d.MaxDataBurstVolume != 0, false)
+		if err := w.PutConstrained(uint64(d.QoSPriorityLevel), 1, 127); err != nil {
+			return fmt.Errorf("qos priority level: %w", err)
+		}
+		if err := w.PutExtConstrained(uint64(d.PacketDelayBudget), 0, 1023); err != nil {
+			return fmt.Errorf("packet delay budget: %w", err)
+		}
+		per.PutSequence(w, false)
+		if err := w.PutExtConstrained(uint64(d.PacketErrorRate.Scalar), 0, 9); err != nil {
+			return fmt.Errorf("per scalar: %w", err)
+		}
+		if err := w.PutExtConstrained(uint64(d.PacketErrorRate.Exponent), 0, 9); err != nil {
+			return fmt.Errorf("per exponent: %w", err)
+		}
+		if err := putBurstParameters(w, d.AveragingWindow, d.MaxDataBurstVolume); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("missing qos characteristics")
+	}
+
+	arp := qos.NGRANAllocationRetentionPriority
+	per.PutPresence(w, false)
+	if err := w.PutConstrained(uint64(arp.PriorityLevel), 0, 15); err != nil {
+		return fmt.Errorf("arp priority level: %w", err)
+	}
+	if err := per.PutEnumerated(w, arp.PreemptionCapability, preemptionCapabilityValues, false); err != nil {
+		return fmt.Errorf("pre-emption capability: %w", err)
+	}
+	if err := per.PutEnumerated(w, arp.PreemptionVulnerability, preemptionVulnerabilityValues, false); err != nil {
+		return fmt.Errorf("pre-emption vulnerability: %w", err)
+	}
+
+	if gbr := qos.GBRQoSFlowInfo; gbr != nil {
+		per.PutPresence(w, gbr.MaxPacketLossRateDL != 0, gbr.MaxPacketLossRateUL != 0, false)
+		for _, rate := range []uint64{gbr.MaxFlowBitRateDL, gbr.MaxFlowBitRateUL, gbr.GuaranteedFlowBitRateDL, gbr.GuaranteedFlowBitRateUL} {
+			if err := w.PutExtConstrained(rate, 0, maxBitRate); err != nil {
+				return fmt.Errorf("bit rate: %w", err)
+			}
+		}
+		for _, rate := range []uint16{gbr.MaxPacketLossRateDL, gbr.MaxPacketLossRateUL} {
+			if rate == 0 {
+				continue
+			}
+			if err := w.PutConstrained(uint64(rate), 0, 1000); err != nil {
+				return fmt.Errorf("max packet loss rate: %w", err)
+			}
+		}
+	}
+	if qos.ReflectiveQoSAttribute {
+		// ENUMERATED {subject-to, ...}
+		w.PutBool(false)
+	}
+	return nil
+}
+
+// putBurstParameters encodes the optional averaging window and maximum
+// data burst volume; zero values are absent
+func putBurstParameters(w *per.Writer, averagingWindow uint16, maxDataBurstVolume uint32) error {
+	if averagingWindow != 0 {
+		if err := w.PutExtConstrained(uint64(averagingWindow), 0, 4095); err != nil {
+			return fmt.Errorf("averaging window: %w", err)
+		}
+	}
+	if maxDataBurstVolume != 0 {
+		if err := w.PutExtConstrained(uint64(maxDataBurstVolume), 0, 4095); err != nil {
+			return fmt.Errorf("max data burst volume: %w", err)
+		}
+	}
+	return nil
+}
+
+func getBurstParameters(r *per.Reader, hasWindow, hasVolume bool) (uint16, uint32, error) {
+	var window, volume uint64
+	var err error
+	if hasWindow {
+		if window, err = r.GetExtConstrained(0, 4095); err != nil {
+			return 0, 0, err
+		}
+	}
+	if hasVolume {
+		if volume, err = r.GetExtConstrained(0, 4095); err != nil {
+			return 0, 0, err
+		}
+	}
+	return uint16(window), uint32(volume), nil
+}
+
+func getQoSFlowLevelQoSParameters(r *per.Reader) (*QoSFlowLevelQoSParameters, error) {
+	present, err := per.GetPresence(r, 3)
+	if err != nil {
+		return nil, err
+	}
+	qos := &QoSFlowLevelQoSParameters{QoSCharacteristics: &QoSCharacteristics{}}
+
+	choice, err := r.GetConstrained(0, qosCharacteristicsChoices-1)
+	if err != nil {
+		return nil, err
+	}
+	switch choice {
+	case 0:
+		charPresent, err := per.GetPresence(r, 4)
+		if err != nil {
+			return nil, err
+		}
+		d := &NonDynamic5QIDescriptor{}
+		fiveQI, err := r.GetExtConstrained(0, 255)
+		if err != nil {
+			return nil, err
+		}
+		d.FiveQI = uint8(fiveQI)
+		if charPresent[0] {
+			level, err := r.GetConstrained(1, 127)
+			if err != nil {
+				return nil, err
+			}
+			d.QoSPriorityLevel = uint8(level)
+		}
+		if d.AveragingWindow, d.MaxDataBurstVolume, err = getBurstParameters(r, charPresent[1], charPresent[2]); err != nil {
+			return nil, err
+		}
+		if err := per.SkipExtensions(r, charPresent[3]); err != nil {
+			return nil, err
+		}
+		qos.QoSCharacteristics.NonDynamic5QI = d
+	case 1:
+		charPresent, err := per.GetPresence(r, 5)
+		if err != nil {
+			return nil, err
+		}
+		d := &Dynamic5QIDescriptor{PacketErrorRate: &PacketErrorRate{}}
+		level, err := r.GetConstrained(1, 127)
+		if err != nil {
+			return nil, err
+		}
+		d.QoSPriorityLevel = uint8(level)
+		budget, err := r.GetExtConstrained(0, 1023)
+		if err != nil {
+			return nil, err
+		}
+		d.PacketDelayBudget = uint16(budget)
+		perPresent, err := per.GetSequence(r, 1)
+		if err != nil {
+			return nil, err
+		}
+		scalar, err := r.GetExtConstrained(0, 9)
+		if err != nil {
+			return nil, err
+		}
+		exponent, err := r.GetExtConstrained(0, 9)
+		if err != nil {
+			return nil, err
+		}
+		d.PacketErrorRate.Scalar, d.PacketErrorRate.Exponent = uint8(scalar), uint8(exponent)
+		if err := per.SkipExtensions(r, perPresent[0]); err != nil {
+			return nil, err
+		}
+		if charPresent[0] {
+			if _, err := r.GetExtConstrained(0, 255); err != nil {
+				return nil, err
+			}
+		}
+		if charPresent[1] {
+			if _, err := r.GetConstrained(0, 1); err != nil {
+				return nil, err
+			}
+		}
+		if d.AveragingWindow, d.MaxDataBurstVolume, err = getBurstParameters(r, charPresent[2], charPresent[3]); err != nil {
+			return nil, err
+		}
+		if err := per.SkipExtensions(r, charPresent[4]); err != nil {
+			return nil, err
+		}
+		qos.QoSCharacteristics.Dynamic5QI = d
+	default:
+		return nil, fmt.Errorf("unsupported qos characteristics choice %d", choice)
+	}
+
+	arpPresent, err := per.GetPresence(r, 1)
+	if err != nil {
+		return nil, err
+	}
+	arp := &AllocationRetentionPriority{}
+	level, err := r.GetConstrained(0, 15)
+	if err != nil {
+		return nil, err
+	}
+	arp.PriorityLevel = uint8(level)
+	if arp.PreemptionCapability, err = per.GetEnumerated(r, preemptionCapabilityValues, false); err != nil {
+		return nil, err
+	}
+	if arp.PreemptionVulnerability, err = per.GetEnumerated(r, preemptionVulnerabilityValues, false); err != nil {
+		return nil, err
+	}
+	if err := per.SkipExtensions(r, arpPresent[0]); err != nil {
+		return nil, err
+	}
+	qos.NGRANAllocationRetentionPriority = arp
+
+	if present[0] {
+		gbrPresent, err := per.GetPresence(r, 3)
+		if err != nil {
+			return nil, err
+		}
+		gbr := &GBRQoSFlowInformation{}
+		for _, rate := range []*uint64{&gbr.MaxFlowBitRateDL, &gbr.MaxFlowBitRateUL, &gbr.GuaranteedFlowBitRateDL, &gbr.GuaranteedFlowBitRateUL} {
+			if *rate, err = r.GetExtConstrained(0, maxBitRate); err != nil {
+				return nil, err
+			}
+		}
+		for i, rate := range []*uint16{&gbr.MaxPacketLossRateDL, &gbr.MaxPacketLossRateUL} {
+			if !gbrPresent[i] {
+				continue
+			}
+			v, err := r.GetConstrained(0, 1000)
+			if err != nil {
+				return nil, err
+			}
+			*rate = uint16(v)
+		}
+		if err := per.SkipExtensions(r, gbrPresent[2]); err != nil {
+			return nil, err
+		}
+		qos.GBRQoSFlowInfo = gbr
+	}
+	if present[1] {
+		if _, err := per.GetEnumerated(r, []bool{true}, true); err != nil {
+			return nil, err
+		}
+		qos.ReflectiveQoSAttribute = true
+	}
+	return qos, per.SkipExtensions(r, present[2])
+}
+
+// UL/DL UP TNL Information to be Setup Item, holding a UP Transport Layer
+// Information CHOICE {gTPTunnel, choice-extension} (TS 38.473 9.3.2.1)
+func putUPTNLInformationItem(w *per.Writer, info *UPTransportLayerInformation) error {
+	if info == nil || info.GTPTunnel == nil {
+		return fmt.Errorf("missing gtp tunnel")
+	}
+	per.PutSequence(w, false)
+	w.PutBits(0, 1)
+
+	tunnel := info.GTPTunnel
+	address := tunnel.TransportLayerAddress
+	if v4 := address.To4(); v4 != nil {
+		address = v4
+	}
+	if len(address) == 0 {
+		return fmt.Errorf("missing transport layer address")
+	}
+	per.PutSequence(w, false)
+	w.PutBool(false)
+	if err := w.PutBitString(address, 8*len(address), 1, maxTransportLayerAddress); err != nil {
+		return err
+	}
+	teid := tunnel.GTPTEID
+	return w.PutOctetString([]byte{byte(teid >> 24), byte(teid >> 16), byte(teid >> 8), byte(teid)}, 4, 4)
+}
+
+func getUPTNLInformationItem(r *per.Reader) (*UPTransportLayerInformation, error) {
+	present, err := per.GetSequence(r, 1)
+	if err != nil {
+		return nil, err
+	}
+	choice, err := r.GetBits(1)
+	if err != nil {
+		return nil, err
+	}
+	if choice != 0 {
+		return nil, fmt.Errorf("unsupported up transport layer information choice")
+	}
+	tunnelPresent, err := per.GetSequence(r, 1)
+	if err != nil {
+		return nil, err
+	}
+	extended, err := r.GetBool()
+	if err != nil {
+		return nil, err
+	}
+	if extended {
+		return nil, fmt.Errorf("transport layer address outside its size root")
+	}
+	address, n, err := r.GetBitString(1, maxTransportLayerAddress)
+	if err != nil {
+		return nil, err
+	}
+	if n != 32 && n != 128 {
+		return nil, fmt.Errorf("unsupported transport layer address of %d bits", n)
+	}
+	teid, err := r.GetOctetString(4, 4)
+	if err != nil {
+		return nil, err
+	}
+	if err := per.SkipExtensions(r, tunnelPresent[0]); err != nil {
+		return nil, err
+	}
+	return &UPTransportLayerInformation{GTPTunnel: &GTPTunnel{
+		TransportLayerAddress: net.IP(address),
+		GTPTEID:               uint32(teid[0])<<24 | uint32(teid[1])<<16 | uint32(teid[2])<<8 | uint32(teid[3]),
+	}}, per.SkipExtensions(r, present[0])
+}
+
+// DRBs Setup Item (TS 38.473 9.2.2.2); no LCID
+func putDRBSetup(w *per.Writer, drb *DRBSetup) error {
+	per.PutSequence(w, false, false)
+	if err := w.PutExtConstrained(uint64(drb.DRBID), 1, 32); err != nil {
+		return fmt.Errorf("drb id: %w", err)
+	}
+	return per.PutList(w, drb.DLUPTNLInfo, 1, maxnoofUPTNLInformation, putUPTNLInformationItem)
+}
+
+func getDRBSetup(r *per.Reader) (*DRBSetup, error) {
+	present, err := per.GetSequence(r, 2)
+	if err != nil {
+		return nil, err
+	}
+	id, err := r.GetExtConstrained(1, 32)
+	if err != nil {
+		return nil, err
+	}
+	if present[0] {
+		if _, err := r.GetExtConstrained(1, 32); err != nil {
+			return nil, err
+		}
+	}
+	drb := &DRBSetup{DRBID: uint8(id)}
+	if drb.DLUPTNLInfo, err = per.GetList(r, 1, maxnoofUPTNLInformation, getUPTNLInformationItem); err != nil {
+		return nil, err
+	}
+	return drb, per.SkipExtensions(r, present[1])
+}
+
+// SRBs Setup Item (TS 38.473 9.2.2.2). The LCID of SRB1..3 is the SRB ID
+// (TS 38.331 9.2.1).
+func putSRBSetup(w *per.Writer, srb *SRBSetup) error {
+	per.PutSequence(w, false)
+	if err := w.PutExtConstrained(uint64(srb.SRBID), 0, 3); err != nil {
+		return fmt.Errorf("srb id: %w", err)
+	}
+	if err := w.PutExtConstrained(uint64(srb.SRBID), 1, 32); err != nil {
+		return fmt.Errorf("lcid of srb %d: %w", srb.SRBID, err)
+	}
+	return nil
+}
+
+func getSRBSetup(r *per.Reader) (*SRBSetup, error) {
+	present, err := per.GetSequence(r, 1)
+	if err != nil {
+		return nil, err
+	}
+	id, err := r.GetExtConstrained(0, 3)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.GetExtConstrained(1, 32); err != nil {
+		return nil, err
+	}
+	return &SRBSetup{SRBID: uint8(id)}, per.SkipExtensions(r, present[0])
+}
+
+// SRBs and DRBs Failed to be Setup Items (TS 38.473 9.2.2.2)
+func putFailedToSetup(w *per.Writer, id, lb, ub uint64, cause *Cause) error {
+	per.PutSequence(w, cause != nil, false)
+	if err := w.PutExtConstrained(id, lb, ub); err != nil {
+		return err
+	}
+	if cause != nil {
+		return putCause(w, cause)
+	}
+	return nil
+}
+
+func getFailedToSetup(r *per.Reader, lb, ub uint64) (uint8, *Cause, error) {
+	present, err := per.GetSequence(r, 2)
+	if err != nil {
+		return 0, nil, err
+	}
+	id, err := r.GetExtConstrained(lb, ub)
+	if err != nil {
+		return 0, nil, err
+	}
+	var cause *Cause
+	if present[0] {
+		if cause, err = getCause(r); err != nil {
+			return 0, nil, err
+		}
+	}
+	return uint8(id), cause, per.SkipExtensions(r, present[1])
+}
+
+// Cause (TS 38.473 9.3.1.2)
+func putCause(w *per.Writer, cause *Cause) error {
+	var choice int
+	var value string
+	var values []string
+	switch {
+	case cause.RadioNetwork != nil:
+		choice, value, values = causeRadioNetworkChoice, cause.RadioNetwork.Value, causeRadioNetworkValues
+	case cause.Transport != nil:
+		choice, value, values = causeTransportChoice, cause.Transport.Value, causeTransportValues
+	case cause.Protocol != nil:
+		choice, value, values = causeProtocolChoice, cause.Protocol.Value, causeProtocolValues
+	case cause.Misc != nil:
+		choice, value, values = causeMiscChoice, cause.Misc.Value, causeMiscValues
+	default:
+		return fmt.Errorf("empty cause")
+	}
+	if err := w.PutConstrained(uint64(choice), 0, causeChoices-1); err != nil {
+		return err
+	}
+	if err := per.PutEnumerated(w, value, values, true); err != nil {
+		return fmt.Errorf("cause: %w", err)
+	}
+	return nil
+}
+
+func getCause(r *per.Reader) (*Cause, error) {
+	choice, err := r.GetConstrained(0, causeChoices-1)
+	if err != nil {
+		return nil, err
+	}
+	switch choice {
+	case causeRadioNetworkChoice:
+		value, err := per.GetEnumerated(r, causeRadioNetworkValues, true)
+		return &Cause{RadioNetwork: &CauseRadioNetwork{Value: value}}, err
+	case causeTransportChoice:
+		value, err := per.GetEnumerated(r, causeTransportValues, true)
+		return &Cause{Transport: &CauseTransport{Value: value}}, err
+	case causeProtocolChoice:
+		value, err := per.GetEnumerated(r, causeProtocolValues, true)
+		return &Cause{Protocol: &CauseProtocol{Value: value}}, err
+	case causeMiscChoice:
+		value, err := per.GetEnumerated(r, causeMiscValues, true)
+		return &Cause{Misc: &CauseMisc{Value: value}}, err
+	default:
+		return nil, fmt.Errorf("unsupported cause choice %d", choice)
+	}
+}
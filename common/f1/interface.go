@@ -69,7 +69,7 @@ type F1SetupResponse struct {
 
 // ServedCell information
 type ServedCell struct {
-	ServedCellIndex uint8
+	ServedCellIndex uint8 // Local to the DU; not carried in F1AP
 	ServedCellInfo  *ServedCellInfo
 	GNBDUSYSINFO    *SystemInformation
 }
@@ -119,18 +119,23 @@ type NRModeInfo struct {
 type FDDInfo struct {
 	ULARFCN                 uint32
 	DLARFCN                 uint32
-	ULTransmissionBandwidth uint16
-	DLTransmissionBandwidth uint16
+	ULTransmissionBandwidth uint16   // Resource blocks
+	DLTransmissionBandwidth uint16   // Resource blocks
+	FreqBands               []uint16 // NR operating bands
+	SCS                     uint16   // Subcarrier spacing in kHz
 }
 
 // TDDInfo
 type TDDInfo struct {
 	NRARFCN               uint32
-	TransmissionBandwidth uint16
+	TransmissionBandwidth uint16   // Resource blocks
+	FreqBands             []uint16 // NR operating bands
+	SCS                   uint16   // Subcarrier spacing in kHz
 }
 
 // SystemInformation
 type SystemInformation struct {
+	MIB  []byte // Master Information Block
 	SIB1 []byte // System Information Block 1
 }
 
@@ -141,8 +146,8 @@ type CellToActivate struct {
 
 // RRCVersion
 type RRCVersion struct {
-	Latest   []byte
-	Extended []byte
+	Latest   []byte // 3-bit latest RRC version, in the top bits of one octet
+	Extended []byte // Optional 3-octet latest RRC version enhanced
 }
 
 // UEContextSetupRequest - CU -> DU
@@ -215,6 +220,8 @@ type SRBToBeSetup struct {
 type DRBToBeSetup struct {
 	DRBID                 uint8
 	QoSInfo               *QoSFlowLevelQoSParameters
+	SNSSAI                *SliceSupport
+	QoSFlowIDs            []uint8 // QoS flows mapped to the DRB
 	ULUPTNLInfo           []*UPTransportLayerInformation
 	RLCMode               string // "AM", "UM", "TM"
 	ULConfiguration       *ULConfiguration
@@ -392,7 +399,8 @@ type CUConfigurationUpdate struct {
 	CellsToDeactivate []*NRCGI
 }
 
-// Cause
+// Cause. Values are the ASN.1 identifiers of TS 38.473, e.g.
+// "normal-release".
 type Cause struct {
 	RadioNetwork *CauseRadioNetwork
 	Transport    *CauseTransport
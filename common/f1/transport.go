@@ -0,0 +1,70 @@
+package f1
+
+import (
+	"time"
+
+	"github.com/your-org/5g-network/common/sctp"
+)
+
+// F1-C transport (3GPP TS 38.472)
+const (
+	SCTP_PORT     = 38472
+	F1AP_PPID     = 62
+	STREAM_NON_UE = 0 // Reserved for non-UE-associated signalling, e.g. F1 Setup
+)
+
+// Listen listens for F1 associations from gNB-DUs on address, a host:port
+func Listen(cfg sctp.Config, address string) (sctp.Listener, error) {
+	cfg.PPID = F1AP_PPID
+	return sctp.Listen(cfg, address)
+}
+
+// Dial establishes an F1 association with the gNB-CU at address
+func Dial(cfg sctp.Config, address string, timeout time.Duration) (sctp.Association, error) {
+	cfg.PPID = F1AP_PPID
+	return sctp.Dial(cfg, address, timeout)
+}
+
+// StreamFor returns the stream a message is sent on. Non-UE-associated
+// procedures use stream 0; UE-associated ones are spread over the other
+// streams by gNB-DU UE F1AP ID, so that one UE always uses one stream
+// (TS 38.472 7).
+func StreamFor(msg Message, streams uint16) uint16 {
+	var duUEID uint32
+	switch m := msg.(type) {
+	case *UEContextSetupRequest:
+		duUEID = m.GNBDUUEF1APID
+	case *UEContextSetupResponse:
+		duUEID = m.GNBDUUEF1APID
+	case *InitialULRRCMessage:
+		duUEID = m.GNBDUUEF1APID
+	case *DLRRCMessage:
+		duUEID = m.GNBDUUEF1APID
+	case *ULRRCMessage:
+		duUEID = m.GNBDUUEF1APID
+	default:
+		return STREAM_NON_UE
+	}
+	if streams < 2 {
+		return STREAM_NON_UE
+	}
+	return 1 + uint16(duUEID%uint32(streams-1))
+}
+
+// SendMessage encodes msg and sends it on the stream StreamFor selects
+func SendMessage(a sctp.Association, msg Message) error {
+	pdu, err := Encode(msg)
+	if err != nil {
+		return err
+	}
+	return a.Send(StreamFor(msg, a.Streams()), pdu)
+}
+
+// ReceiveMessage receives and decodes the next F1AP message
+func ReceiveMessage(a sctp.Association) (Message, error) {
+	pdu, _, err := a.Receive()
+	if err != nil {
+		return nil, err
+	}
+	return Decode(pdu)
+}
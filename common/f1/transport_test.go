@@ -0,0 +1,108 @@
+package f1
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/your-org/5g-network/common/sctp"
+)
+
+// exchangeF1Setup runs an F1 Setup between a DU and a CU over the transport
+func exchangeF1Setup(t *testing.T, cfg sctp.Config) {
+	t.Helper()
+
+	listener, err := Listen(cfg, "127.0.0.1:0")
+	if errors.Is(err, sctp.ErrUnsupported) {
+		t.Skipf("no SCTP here: %v", err)
+	}
+	require.NoError(t, err)
+	defer listener.Close()
+
+	type accepted struct {
+		assoc sctp.Association
+		err   error
+	}
+	acceptCh := make(chan accepted, 1)
+	go func() {
+		a, err := listener.Accept()
+		acceptCh <- accepted{a, err}
+	}()
+
+	du, err := Dial(cfg, listener.Addr().String(), 5*time.Second)
+	require.NoError(t, err)
+	defer du.Close()
+
+	acc := <-acceptCh
+	require.NoError(t, acc.err)
+	cu := acc.assoc
+	defer cu.Close()
+	assert.Equal(t, du.LocalAddr().String(), cu.RemoteAddr().String())
+
+	req := &F1SetupRequest{
+		TransactionID: 1,
+		GNBDUID:       1,
+		GNBDUName:     "du-1",
+		ServedCellsToAdd: []*ServedCell{{
+			ServedCellInfo: &ServedCellInfo{
+				NRCGI:       testNRCGI(1),
+				ServedPLMNs: []*ServedPLMN{{PLMNID: &PLMNID{MCC: "001", MNC: "01"}}},
+				NRModeInfo: &NRModeInfo{TDD: &TDDInfo{
+					NRARFCN: 632628, TransmissionBandwidth: 106, FreqBands: []uint16{78}, SCS: 30,
+				}},
+				MeasurementTimingConfiguration: []byte{},
+			},
+		}},
+		GNBDURRCVersion: &RRCVersion{Latest: []byte{0xE0}},
+	}
+	require.NoError(t, SendMessage(du, req))
+
+	pdu, stream, err := cu.Receive()
+	require.NoError(t, err)
+	assert.Equal(t, uint16(STREAM_NON_UE), stream)
+	got, err := Decode(pdu)
+	require.NoError(t, err)
+	assert.Equal(t, req, got)
+
+	resp := &F1SetupResponse{
+		TransactionID:   1,
+		GNBCUNAME:       "cu-1",
+		CellsToActivate: []*CellToActivate{{NRCGI: testNRCGI(1)}},
+		GNBCURRCVersion: &RRCVersion{Latest: []byte{0xE0}},
+	}
+	require.NoError(t, SendMessage(cu, resp))
+	gotResp, err := ReceiveMessage(du)
+	require.NoError(t, err)
+	assert.Equal(t, resp, gotResp)
+
+	// UE-associated signalling goes on a UE stream
+	ul := &InitialULRRCMessage{GNBDUUEF1APID: 5, NRCGI: testNRCGI(1), CRNTI: 0x4601, RRCContainer: []byte{0x01}}
+	require.NoError(t, SendMessage(du, ul))
+	_, stream, err = cu.Receive()
+	require.NoError(t, err)
+	assert.Equal(t, StreamFor(ul, du.Streams()), stream)
+	assert.NotEqual(t, uint16(STREAM_NON_UE), stream)
+
+	// The DU going away ends the association
+	require.NoError(t, du.Close())
+	_, _, err = cu.Receive()
+	assert.ErrorIs(t, err, sctp.ErrAssociationClosed)
+}
+
+func TestTransport_SCTP(t *testing.T) {
+	exchangeF1Setup(t, sctp.Config{Protocol: sctp.PROTOCOL_SCTP, HeartbeatInterval: time.Second})
+}
+
+func TestTransport_TCPFallback(t *testing.T) {
+	exchangeF1Setup(t, sctp.Config{Protocol: sctp.PROTOCOL_TCP})
+}
+
+func TestStreamFor(t *testing.T) {
+	assert.Equal(t, uint16(STREAM_NON_UE), StreamFor(&F1SetupRequest{}, 4))
+	assert.Equal(t, uint16(1), StreamFor(&DLRRCMessage{GNBDUUEF1APID: 3}, 4))
+	assert.Equal(t, uint16(3), StreamFor(&ULRRCMessage{GNBDUUEF1APID: 5}, 4))
+	assert.Equal(t, StreamFor(&UEContextSetupRequest{GNBDUUEF1APID: 7}, 4),
+		StreamFor(&UEContextSetupResponse{GNBDUUEF1APID: 7}, 4))
+}
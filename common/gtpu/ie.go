@@ -0,0 +1,109 @@
+package gtpu
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// GTP-U Information Element types (3GPP TS 29.281 Table 8.1-1). Types below
+// 128 are TV with a fixed length, the others TLV.
+const (
+	IE_RECOVERY          = 14
+	IE_TEID_DATA_I       = 16
+	IE_GTPU_PEER_ADDRESS = 133
+	IE_PRIVATE_EXTENSION = 255
+)
+
+// tvLengths holds the value lengths of the known TV IEs
+var tvLengths = map[uint8]int{
+	IE_RECOVERY:    1,
+	IE_TEID_DATA_I: 4,
+}
+
+// ErrorIndication represents the IEs of an Error Indication (3GPP TS 29.281
+// 7.3.1), sent by a node receiving a G-PDU for a TEID it does not know
+type ErrorIndication struct {
+	TEID        uint32 // TEID Data I: the unknown TEID
+	PeerAddress net.IP // GTP-U peer address: the node reporting it
+}
+
+// NewErrorIndication creates an Error Indication packet
+func NewErrorIndication(e *ErrorIndication, seq uint16) *Packet {
+	addr := e.PeerAddress.To4()
+	if addr == nil {
+		addr = e.PeerAddress.To16()
+	}
+
+	payload := make([]byte, 5, 5+3+len(addr))
+	payload[0] = IE_TEID_DATA_I
+	binary.BigEndian.PutUint32(payload[1:5], e.TEID)
+	payload = append(payload, IE_GTPU_PEER_ADDRESS, 0, 0)
+	binary.BigEndian.PutUint16(payload[6:8], uint16(len(addr)))
+	payload = append(payload, addr...)
+
+	return &Packet{
+		Header: Header{
+			Version:        GTPU_VERSION,
+			ProtocolType:   1,
+			MessageType:    GTPU_ERROR_INDICATION,
+			HasSequence:    true,
+			SequenceNumber: seq,
+		},
+		Payload: payload,
+	}
+}
+
+// ParseErrorIndication decodes the IEs of an Error Indication
+func ParseErrorIndication(payload []byte) (*ErrorIndication, error) {
+	e := &ErrorIndication{}
+	var hasTEID bool
+
+	for offset := 0; offset < len(payload); {
+		ieType := payload[offset]
+		offset++
+
+		var value []byte
+		if ieType < 128 {
+			n, known := tvLengths[ieType]
+			if !known {
+				return nil, fmt.Errorf("unknown gtp-u tv ie %d", ieType)
+			}
+			if offset+n > len(payload) {
+				return nil, fmt.Errorf("gtp-u ie %d truncated", ieType)
+			}
+			value = payload[offset : offset+n]
+			offset += n
+		} else {
+			if offset+2 > len(payload) {
+				return nil, fmt.Errorf("gtp-u ie %d truncated", ieType)
+			}
+			n := int(binary.BigEndian.Uint16(payload[offset : offset+2]))
+			offset += 2
+			if offset+n > len(payload) {
+				return nil, fmt.Errorf("gtp-u ie %d truncated", ieType)
+			}
+			value = payload[offset : offset+n]
+			offset += n
+		}
+
+		switch ieType {
+		case IE_TEID_DATA_I:
+			e.TEID = binary.BigEndian.Uint32(value)
+			hasTEID = true
+		case IE_GTPU_PEER_ADDRESS:
+			if len(value) != net.IPv4len && len(value) != net.IPv6len {
+				return nil, fmt.Errorf("gtp-u peer address has invalid length %d", len(value))
+			}
+			e.PeerAddress = net.IP(append([]byte(nil), value...))
+		}
+	}
+
+	if !hasTEID {
+		return nil, fmt.Errorf("error indication missing TEID data I")
+	}
+	if e.PeerAddress == nil {
+		return nil, fmt.Errorf("error indication missing gtp-u peer address")
+	}
+	return e, nil
+}
@@ -0,0 +1,399 @@
+package gtpu
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// GTP-U Message Types (3GPP TS 29.281 Table 6.1-1)
+const (
+	GTPU_ECHO_REQUEST     = 1
+	GTPU_ECHO_RESPONSE    = 2
+	GTPU_ERROR_INDICATION = 26
+	GTPU_END_MARKER       = 254
+	GTPU_G_PDU            = 255
+)
+
+// GTP-U Extension Header Types (3GPP TS 29.281 Figure 5.2.1-3)
+const (
+	EXT_HEADER_NONE                  = 0x00
+	EXT_HEADER_UDP_PORT              = 0x40
+	EXT_HEADER_PDU_SESSION_CONTAINER = 0x85
+)
+
+// PDU Session Container PDU types (3GPP TS 38.415 5.5.2)
+const (
+	PDU_TYPE_DL_PDU_SESSION_INFORMATION = 0
+	PDU_TYPE_UL_PDU_SESSION_INFORMATION = 1
+)
+
+// GTP-U header sizes and flags
+const (
+	GTPU_VERSION                = 1
+	GTPU_HEADER_LENGTH          = 8 // Mandatory part
+	GTPU_OPTIONAL_HEADER_LENGTH = 4 // Sequence, N-PDU, next extension type
+
+	gtpuFlagPT  uint8 = 0x10
+	gtpuFlagE   uint8 = 0x04
+	gtpuFlagS   uint8 = 0x02
+	gtpuFlagPN  uint8 = 0x01
+	gtpuFlagAny       = gtpuFlagE | gtpuFlagS | gtpuFlagPN
+)
+
+// ExtensionHeader represents a GTP-U extension header. Content excludes the
+// length octet and the next extension header type octet.
+type ExtensionHeader struct {
+	Type    uint8
+	Content []byte
+}
+
+// Header represents the GTP-U header (3GPP TS 29.281 5.1)
+type Header struct {
+	Version          uint8
+	ProtocolType     uint8 // 1 = GTP, 0 = GTP'
+	MessageType      uint8
+	Length           uint16 // Octets after the mandatory 8 octet header
+	TEID             uint32
+	HasSequence      bool
+	SequenceNumber   uint16
+	HasNPDU          bool
+	NPDUNumber       uint8
+	ExtensionHeaders []ExtensionHeader
+}
+
+// Packet represents a GTP-U packet
+type Packet struct {
+	Header  Header
+	Payload []byte
+}
+
+// NewGPDU creates a G-PDU carrying a user plane packet
+func NewGPDU(teid uint32, payload []byte, extHeaders ...ExtensionHeader) *Packet {
+	return &Packet{
+		Header: Header{
+			Version:          GTPU_VERSION,
+			ProtocolType:     1,
+			MessageType:      GTPU_G_PDU,
+			TEID:             teid,
+			ExtensionHeaders: extHeaders,
+		},
+		Payload: payload,
+	}
+}
+
+// NewEndMarker creates an End Marker, the last packet sent on a tunnel
+// before the path switches away from it (3GPP TS 29.281 7.3.2)
+func NewEndMarker(teid uint32) *Packet {
+	return &Packet{
+		Header: Header{
+			Version:      GTPU_VERSION,
+			ProtocolType: 1,
+			MessageType:  GTPU_END_MARKER,
+			TEID:         teid,
+		},
+	}
+}
+
+// Parse decodes a GTP-U packet
+func Parse(data []byte) (*Packet, error) {
+	if len(data) < GTPU_HEADER_LENGTH {
+		return nil, fmt.Errorf("gtp-u packet too short: %d octets", len(data))
+	}
+
+	flags := data[0]
+	header := Header{
+		Version:      flags >> 5,
+		ProtocolType: (flags & gtpuFlagPT) >> 4,
+		MessageType:  data[1],
+		Length:       binary.BigEndian.Uint16(data[2:4]),
+		TEID:         binary.BigEndian.Uint32(data[4:8]),
+	}
+
+	if header.Version != GTPU_VERSION {
+		return nil, fmt.Errorf("unsupported gtp-u version: %d", header.Version)
+	}
+
+	end := GTPU_HEADER_LENGTH + int(header.Length)
+	if end > len(data) {
+		return nil, fmt.Errorf("gtp-u length %d exceeds buffer of %d octets", header.Length, len(data))
+	}
+
+	offset := GTPU_HEADER_LENGTH
+	if flags&gtpuFlagAny != 0 {
+		if end < offset+GTPU_OPTIONAL_HEADER_LENGTH {
+			return nil, fmt.Errorf("gtp-u optional header truncated")
+		}
+
+		header.HasSequence = flags&gtpuFlagS != 0
+		header.SequenceNumber = binary.BigEndian.Uint16(data[offset : offset+2])
+		header.HasNPDU = flags&gtpuFlagPN != 0
+		header.NPDUNumber = data[offset+2]
+		nextType := data[offset+3]
+		offset += GTPU_OPTIONAL_HEADER_LENGTH
+
+		if flags&gtpuFlagE == 0 {
+			nextType = EXT_HEADER_NONE
+		}
+
+		for nextType != EXT_HEADER_NONE {
+			if offset >= end {
+				return nil, fmt.Errorf("gtp-u extension header 0x%02x truncated", nextType)
+			}
+
+			extLen := int(data[offset]) * 4
+			if extLen == 0 || offset+extLen > end {
+				return nil, fmt.Errorf("gtp-u extension header 0x%02x has invalid length %d", nextType, extLen)
+			}
+
+			header.ExtensionHeaders = append(header.ExtensionHeaders, ExtensionHeader{
+				Type:    nextType,
+				Content: append([]byte(nil), data[offset+1:offset+extLen-1]...),
+			})
+
+			nextType = data[offset+extLen-1]
+			offset += extLen
+		}
+	}
+
+	return &Packet{
+		Header:  header,
+		Payload: data[offset:end],
+	}, nil
+}
+
+// Marshal encodes the packet, computing the length field
+func (p *Packet) Marshal() []byte {
+	h := &p.Header
+
+	hasOptional := h.HasSequence || h.HasNPDU || len(h.ExtensionHeaders) > 0
+
+	size := GTPU_HEADER_LENGTH + len(p.Payload)
+	if hasOptional {
+		size += GTPU_OPTIONAL_HEADER_LENGTH
+	}
+	for _, ext := range h.ExtensionHeaders {
+		size += ext.Len()
+	}
+
+	buf := make([]byte, size)
+
+	version := h.Version
+	if version == 0 {
+		version = GTPU_VERSION
+	}
+	buf[0] = version << 5
+	if h.ProtocolType != 0 {
+		buf[0] |= gtpuFlagPT
+	}
+	if len(h.ExtensionHeaders) > 0 {
+		buf[0] |= gtpuFlagE
+	}
+	if h.HasSequence {
+		buf[0] |= gtpuFlagS
+	}
+	if h.HasNPDU {
+		buf[0] |= gtpuFlagPN
+	}
+	buf[1] = h.MessageType
+	binary.BigEndian.PutUint16(buf[2:4], uint16(size-GTPU_HEADER_LENGTH))
+	binary.BigEndian.PutUint32(buf[4:8], h.TEID)
+
+	offset := GTPU_HEADER_LENGTH
+	if hasOptional {
+		binary.BigEndian.PutUint16(buf[offset:offset+2], h.SequenceNumber)
+		buf[offset+2] = h.NPDUNumber
+		if len(h.ExtensionHeaders) > 0 {
+			buf[offset+3] = h.ExtensionHeaders[0].Type
+		}
+		offset += GTPU_OPTIONAL_HEADER_LENGTH
+	}
+
+	for i, ext := range h.ExtensionHeaders {
+		extLen := ext.Len()
+		buf[offset] = byte(extLen / 4)
+		copy(buf[offset+1:], ext.Content)
+		if i+1 < len(h.ExtensionHeaders) {
+			buf[offset+extLen-1] = h.ExtensionHeaders[i+1].Type
+		}
+		offset += extLen
+	}
+
+	copy(buf[offset:], p.Payload)
+	return buf
+}
+
+// Len returns the encoded length of the extension header, padded to a
+// multiple of 4 octets including the length and next type octets
+func (e ExtensionHeader) Len() int {
+	n := len(e.Content) + 2
+	if rem := n % 4; rem != 0 {
+		n += 4 - rem
+	}
+	return n
+}
+
+// FindExtensionHeader returns the first extension header of the given type
+func (h *Header) FindExtensionHeader(extType uint8) (ExtensionHeader, bool) {
+	for _, ext := range h.ExtensionHeaders {
+		if ext.Type == extType {
+			return ext, true
+		}
+	}
+	return ExtensionHeader{}, false
+}
+
+// PDUSessionContainer represents the PDU Session Container extension
+// header content (3GPP TS 38.415 5.5.2.1/5.5.2.2)
+type PDUSessionContainer struct {
+	PDUType uint8 // DL or UL PDU SESSION INFORMATION
+	QFI     uint8
+	RQI     bool // Reflective QoS Indicator (DL only)
+	PPP     bool // Paging Policy Presence (DL only)
+	PPI     uint8
+
+	// QoS monitoring information (UL only). The time stamps are present in
+	// QoS Monitoring Packets (QMP), the delay results are zero when absent.
+	QMP                bool
+	DLSendTimeRepeated time.Time
+	DLReceivedTime     time.Time
+	ULSendTime         time.Time
+	DLDelayResult      time.Duration
+	ULDelayResult      time.Duration
+}
+
+// UL PDU SESSION INFORMATION flags of the first octet (3GPP TS 38.415 5.5.2.2)
+const (
+	ulFlagQMP        uint8 = 0x08
+	ulFlagDLDelayInd uint8 = 0x04
+	ulFlagULDelayInd uint8 = 0x02
+)
+
+// QoS monitoring field sizes and units of UL PDU SESSION INFORMATION
+const (
+	qmpTimeStampLength = 8
+	delayResultLength  = 4
+	delayResultUnit    = 100 * time.Microsecond
+
+	// ntpEpochOffset is the number of seconds between 1900-01-01 and
+	// 1970-01-01
+	ntpEpochOffset = 2208988800
+)
+
+// NewPDUSessionContainer creates a PDU Session Container extension header
+func NewPDUSessionContainer(c *PDUSessionContainer) ExtensionHeader {
+	content := []byte{c.PDUType << 4, c.QFI & 0x3F}
+	if c.PDUType == PDU_TYPE_DL_PDU_SESSION_INFORMATION {
+		if c.RQI {
+			content[1] |= 0x40
+		}
+		if c.PPP {
+			content[1] |= 0x80
+			content = append(content, c.PPI<<5, 0, 0, 0)
+		}
+	}
+	if c.PDUType == PDU_TYPE_UL_PDU_SESSION_INFORMATION {
+		if c.QMP {
+			content[0] |= ulFlagQMP
+			content = appendNTPTime(content, c.DLSendTimeRepeated)
+			content = appendNTPTime(content, c.DLReceivedTime)
+			content = appendNTPTime(content, c.ULSendTime)
+		}
+		if c.DLDelayResult > 0 {
+			content[0] |= ulFlagDLDelayInd
+			content = binary.BigEndian.AppendUint32(content, uint32(c.DLDelayResult/delayResultUnit))
+		}
+		if c.ULDelayResult > 0 {
+			content[0] |= ulFlagULDelayInd
+			content = binary.BigEndian.AppendUint32(content, uint32(c.ULDelayResult/delayResultUnit))
+		}
+	}
+	return ExtensionHeader{Type: EXT_HEADER_PDU_SESSION_CONTAINER, Content: content}
+}
+
+// ParsePDUSessionContainer decodes a PDU Session Container extension header
+func ParsePDUSessionContainer(ext ExtensionHeader) (*PDUSessionContainer, error) {
+	if ext.Type != EXT_HEADER_PDU_SESSION_CONTAINER {
+		return nil, fmt.Errorf("extension header 0x%02x is not a PDU session container", ext.Type)
+	}
+	if len(ext.Content) < 2 {
+		return nil, fmt.Errorf("PDU session container too short: %d octets", len(ext.Content))
+	}
+
+	c := &PDUSessionContainer{
+		PDUType: ext.Content[0] >> 4,
+		QFI:     ext.Content[1] & 0x3F,
+	}
+	if c.PDUType == PDU_TYPE_DL_PDU_SESSION_INFORMATION {
+		c.RQI = ext.Content[1]&0x40 != 0
+		c.PPP = ext.Content[1]&0x80 != 0
+		if c.PPP && len(ext.Content) > 2 {
+			c.PPI = ext.Content[2] >> 5
+		}
+	}
+	if c.PDUType == PDU_TYPE_UL_PDU_SESSION_INFORMATION {
+		if err := parseULQoSMonitoring(c, ext.Content); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// parseULQoSMonitoring decodes the QoS monitoring time stamps and delay
+// results following the first two octets of UL PDU SESSION INFORMATION
+func parseULQoSMonitoring(c *PDUSessionContainer, content []byte) error {
+	flags := content[0]
+	offset := 2
+
+	need := offset
+	if flags&ulFlagQMP != 0 {
+		need += 3 * qmpTimeStampLength
+	}
+	if flags&ulFlagDLDelayInd != 0 {
+		need += delayResultLength
+	}
+	if flags&ulFlagULDelayInd != 0 {
+		need += delayResultLength
+	}
+	if len(content) < need {
+		return fmt.Errorf("UL PDU session information truncated: %d octets, need %d", len(content), need)
+	}
+
+	if flags&ulFlagQMP != 0 {
+		c.QMP = true
+		c.DLSendTimeRepeated = ntpTime(content[offset:])
+		c.DLReceivedTime = ntpTime(content[offset+qmpTimeStampLength:])
+		c.ULSendTime = ntpTime(content[offset+2*qmpTimeStampLength:])
+		offset += 3 * qmpTimeStampLength
+	}
+	if flags&ulFlagDLDelayInd != 0 {
+		c.DLDelayResult = time.Duration(binary.BigEndian.Uint32(content[offset:])) * delayResultUnit
+		offset += delayResultLength
+	}
+	if flags&ulFlagULDelayInd != 0 {
+		c.ULDelayResult = time.Duration(binary.BigEndian.Uint32(content[offset:])) * delayResultUnit
+	}
+	return nil
+}
+
+// appendNTPTime appends t as a 64 bit NTP time stamp (IETF RFC 5905)
+func appendNTPTime(b []byte, t time.Time) []byte {
+	if t.IsZero() {
+		return append(b, make([]byte, qmpTimeStampLength)...)
+	}
+	seconds := uint64(t.Unix() + ntpEpochOffset)
+	fraction := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	return binary.BigEndian.AppendUint64(b, seconds<<32|fraction)
+}
+
+// ntpTime decodes a 64 bit NTP time stamp, the zero time when it is zero
+func ntpTime(b []byte) time.Time {
+	v := binary.BigEndian.Uint64(b)
+	if v == 0 {
+		return time.Time{}
+	}
+	seconds := int64(v>>32) - ntpEpochOffset
+	nanos := (v & 0xffffffff) * uint64(time.Second) >> 32
+	return time.Unix(seconds, int64(nanos))
+}
@@ -0,0 +1,211 @@
+package gtpu
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loadVector reads a hex encoded test vector, ignoring comments and whitespace
+func loadVector(t *testing.T, name string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+
+	var hexStr strings.Builder
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hexStr.WriteString(strings.ReplaceAll(line, " ", ""))
+	}
+
+	raw, err := hex.DecodeString(hexStr.String())
+	require.NoError(t, err)
+	return raw
+}
+
+func TestConformance_RoundTrip(t *testing.T) {
+	vectors := []string{
+		"echo_request.hex",
+		"echo_response.hex",
+		"end_marker.hex",
+		"gpdu_ul_pdu_session_container.hex",
+		"gpdu_dl_pdu_session_container.hex",
+		"error_indication.hex",
+	}
+
+	for _, name := range vectors {
+		t.Run(name, func(t *testing.T) {
+			raw := loadVector(t, name)
+
+			pkt, err := Parse(raw)
+			require.NoError(t, err)
+			assert.Equal(t, raw, pkt.Marshal())
+		})
+	}
+}
+
+func TestConformance_EchoRequest(t *testing.T) {
+	pkt, err := Parse(loadVector(t, "echo_request.hex"))
+	require.NoError(t, err)
+
+	assert.Equal(t, uint8(GTPU_ECHO_REQUEST), pkt.Header.MessageType)
+	assert.True(t, pkt.Header.HasSequence)
+	assert.Equal(t, uint16(1), pkt.Header.SequenceNumber)
+	assert.Empty(t, pkt.Payload)
+}
+
+func TestConformance_EndMarker(t *testing.T) {
+	raw := loadVector(t, "end_marker.hex")
+
+	pkt, err := Parse(raw)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(GTPU_END_MARKER), pkt.Header.MessageType)
+	assert.Equal(t, uint32(1), pkt.Header.TEID)
+	assert.Empty(t, pkt.Payload)
+
+	assert.Equal(t, raw, NewEndMarker(1).Marshal())
+}
+
+func TestConformance_GPDUWithULPDUSessionContainer(t *testing.T) {
+	raw := loadVector(t, "gpdu_ul_pdu_session_container.hex")
+
+	pkt, err := Parse(raw)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(GTPU_G_PDU), pkt.Header.MessageType)
+	assert.Equal(t, uint32(1), pkt.Header.TEID)
+	require.Len(t, pkt.Header.ExtensionHeaders, 1)
+	assert.Len(t, pkt.Payload, 20)
+	assert.Equal(t, uint8(0x45), pkt.Payload[0])
+
+	ext, ok := pkt.Header.FindExtensionHeader(EXT_HEADER_PDU_SESSION_CONTAINER)
+	require.True(t, ok)
+
+	container, err := ParsePDUSessionContainer(ext)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(PDU_TYPE_UL_PDU_SESSION_INFORMATION), container.PDUType)
+	assert.Equal(t, uint8(9), container.QFI)
+
+	// Building the same packet from structs yields identical bytes
+	built := NewGPDU(1, pkt.Payload, NewPDUSessionContainer(&PDUSessionContainer{
+		PDUType: PDU_TYPE_UL_PDU_SESSION_INFORMATION,
+		QFI:     9,
+	}))
+	assert.Equal(t, raw, built.Marshal())
+}
+
+func TestConformance_GPDUWithDLPDUSessionContainer(t *testing.T) {
+	raw := loadVector(t, "gpdu_dl_pdu_session_container.hex")
+
+	pkt, err := Parse(raw)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0x12345678), pkt.Header.TEID)
+
+	ext, ok := pkt.Header.FindExtensionHeader(EXT_HEADER_PDU_SESSION_CONTAINER)
+	require.True(t, ok)
+
+	container, err := ParsePDUSessionContainer(ext)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(PDU_TYPE_DL_PDU_SESSION_INFORMATION), container.PDUType)
+	assert.Equal(t, uint8(5), container.QFI)
+	assert.True(t, container.RQI)
+
+	built := NewGPDU(0x12345678, pkt.Payload, NewPDUSessionContainer(container))
+	assert.Equal(t, raw, built.Marshal())
+}
+
+func TestConformance_ErrorIndication(t *testing.T) {
+	raw := loadVector(t, "error_indication.hex")
+
+	pkt, err := Parse(raw)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(GTPU_ERROR_INDICATION), pkt.Header.MessageType)
+	assert.Zero(t, pkt.Header.TEID)
+
+	e, err := ParseErrorIndication(pkt.Payload)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0x100), e.TEID)
+	assert.Equal(t, net.IPv4(192, 168, 1, 10).To4(), e.PeerAddress)
+
+	assert.Equal(t, raw, NewErrorIndication(e, 1).Marshal())
+}
+
+func TestParseErrorIndication_Malformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+	}{
+		{"empty", nil},
+		{"missing peer address", []byte{0x10, 0x00, 0x00, 0x01, 0x00}},
+		{"missing teid", []byte{0x85, 0x00, 0x04, 0xc0, 0xa8, 0x01, 0x0a}},
+		{"truncated teid", []byte{0x10, 0x00, 0x00}},
+		{"peer address beyond payload", []byte{0x10, 0x00, 0x00, 0x01, 0x00, 0x85, 0x00, 0x10, 0xc0}},
+		{"invalid peer address length", []byte{0x10, 0x00, 0x00, 0x01, 0x00, 0x85, 0x00, 0x02, 0xc0, 0xa8}},
+		{"unknown tv ie", []byte{0x7f, 0x00}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseErrorIndication(tt.payload)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParse_Malformed(t *testing.T) {
+	raw := loadVector(t, "gpdu_ul_pdu_session_container.hex")
+
+	// Truncated header
+	_, err := Parse(raw[:4])
+	assert.Error(t, err)
+
+	// Length exceeds buffer
+	_, err = Parse(raw[:len(raw)-1])
+	assert.Error(t, err)
+
+	// Zero length extension header
+	bad := append([]byte(nil), raw...)
+	bad[12] = 0
+	_, err = Parse(bad)
+	assert.Error(t, err)
+}
+
+func TestPDUSessionContainer_ULQoSMonitoring(t *testing.T) {
+	sent := time.Unix(1_700_000_000, 250_000_000)
+	ext := NewPDUSessionContainer(&PDUSessionContainer{
+		PDUType:       PDU_TYPE_UL_PDU_SESSION_INFORMATION,
+		QFI:           9,
+		QMP:           true,
+		ULSendTime:    sent,
+		DLDelayResult: 12 * time.Millisecond,
+	})
+	assert.Equal(t, uint8(0x1c), ext.Content[0]) // UL, QMP, DL Delay Ind
+	assert.Len(t, ext.Content, 2+3*8+4)
+
+	container, err := ParsePDUSessionContainer(ext)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(9), container.QFI)
+	assert.True(t, container.QMP)
+	assert.True(t, container.DLSendTimeRepeated.IsZero())
+	assert.WithinDuration(t, sent, container.ULSendTime, time.Microsecond)
+	assert.Equal(t, 12*time.Millisecond, container.DLDelayResult)
+	assert.Zero(t, container.ULDelayResult)
+
+	// The flags announce fields the container does not carry
+	ext.Content = ext.Content[:len(ext.Content)-1]
+	_, err = ParsePDUSessionContainer(ext)
+	assert.Error(t, err)
+}
@@ -0,0 +1,58 @@
+// Package loglevel serves the level of a network function's logger, so it can
+// be raised for live debugging without a restart
+package loglevel
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/your-org/5g-network/common/sbi"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Level is the body of the log level endpoint, e.g. {"level":"debug"}
+type Level struct {
+	Level string `json:"level"`
+}
+
+// Handler serves the current log level on GET and changes it on PUT
+// (/admin/log-level). level must be the level the NF's loggers are built
+// from.
+func Handler(level zap.AtomicLevel, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var req Level
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				sbi.WriteProblem(w, sbi.NewProblem(http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err))
+				return
+			}
+			if req.Level == "" {
+				sbi.WriteProblem(w, sbi.NewProblem(http.StatusBadRequest, sbi.CauseMandatoryIEMissing, "level is required", nil))
+				return
+			}
+			parsed, err := zapcore.ParseLevel(req.Level)
+			if err != nil {
+				sbi.WriteProblem(w, sbi.NewProblem(http.StatusBadRequest, sbi.CauseMandatoryIEIncorrect, "invalid log level", err))
+				return
+			}
+
+			old := level.Level()
+			level.SetLevel(parsed)
+			logger.Info("Log level changed",
+				zap.Stringer("old", old),
+				zap.Stringer("new", parsed),
+			)
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(Level{Level: level.Level().String()})
+	}
+}
@@ -0,0 +1,81 @@
+package loglevel
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/your-org/5g-network/common/sbi"
+)
+
+func serve(t *testing.T, handler http.HandlerFunc, method, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(method, "/admin/log-level", strings.NewReader(body)))
+	return rec
+}
+
+func TestHandlerTogglesLevel(t *testing.T) {
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	core, logs := observer.New(level)
+	logger := zap.New(core)
+	handler := Handler(level, logger)
+
+	logger.Debug("before")
+	assert.Zero(t, logs.FilterMessage("before").Len())
+
+	rec := serve(t, handler, http.MethodPut, `{"level":"debug"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"level":"debug"}`, rec.Body.String())
+
+	logger.Debug("after")
+	assert.Equal(t, 1, logs.FilterMessage("after").Len(), "debug logs are emitted")
+	assert.Equal(t, 1, logs.FilterMessage("Log level changed").Len())
+
+	rec = serve(t, handler, http.MethodGet, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var got Level
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&got))
+	assert.Equal(t, "debug", got.Level)
+
+	rec = serve(t, handler, http.MethodPut, `{"level":"warn"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	logger.Debug("silenced")
+	logger.Info("silenced")
+	assert.Zero(t, logs.FilterMessage("silenced").Len())
+}
+
+func TestHandlerRejectsInvalidLevel(t *testing.T) {
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	handler := Handler(level, zap.NewNop())
+
+	for name, tc := range map[string]struct {
+		body  string
+		cause string
+	}{
+		"malformed": {body: `{"level":`, cause: sbi.CauseInvalidMsgFormat},
+		"missing":   {body: `{}`, cause: sbi.CauseMandatoryIEMissing},
+		"unknown":   {body: `{"level":"verbose"}`, cause: sbi.CauseMandatoryIEIncorrect},
+	} {
+		t.Run(name, func(t *testing.T) {
+			rec := serve(t, handler, http.MethodPut, tc.body)
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+			var problem sbi.ProblemDetails
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&problem))
+			assert.Equal(t, tc.cause, problem.Cause)
+		})
+	}
+	assert.Equal(t, zapcore.InfoLevel, level.Level())
+
+	rec := serve(t, handler, http.MethodPost, `{"level":"debug"}`)
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
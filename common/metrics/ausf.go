@@ -41,6 +41,13 @@ var (
 			Help: "Number of active authentication contexts",
 		},
 	)
+
+	ExpiredAuthContexts = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "ausf_expired_auth_contexts_total",
+			Help: "Total number of authentication contexts removed unconfirmed after their TTL",
+		},
+	)
 )
 
 // RecordAuthenticationAttempt records an authentication attempt
@@ -62,3 +69,8 @@ func RecordAKAVectorGeneration(result string) {
 func SetActiveAuthContexts(count int) {
 	ActiveAuthContexts.Set(float64(count))
 }
+
+// RecordExpiredAuthContexts counts auth contexts removed after expiring
+func RecordExpiredAuthContexts(count int) {
+	ExpiredAuthContexts.Add(float64(count))
+}
@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultResponseSizeBuckets are the response size buckets in bytes used when
+// none are configured
+var DefaultResponseSizeBuckets = prometheus.ExponentialBuckets(100, 10, 6)
+
+// EBPFHTTPBuckets holds the histogram bucket boundaries of the eBPF HTTP
+// metrics. Empty boundaries select the defaults.
+type EBPFHTTPBuckets struct {
+	Duration     []float64 // Seconds, prometheus.DefBuckets by default
+	ResponseSize []float64 // Bytes, DefaultResponseSizeBuckets by default
+}
+
+// EBPFHTTPMetrics are RED metrics of the HTTP requests an NF served, built
+// from the events captured by the eBPF tracer
+type EBPFHTTPMetrics struct {
+	requests     *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	responseSize *prometheus.HistogramVec
+}
+
+// NewEBPFHTTPMetrics creates the eBPF HTTP metrics of an NF and registers
+// them with registerer, normally the Registerer of the NF's Registry; the
+// default registry when nil
+func NewEBPFHTTPMetrics(nfName string, buckets EBPFHTTPBuckets, registerer prometheus.Registerer) (*EBPFHTTPMetrics, error) {
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+
+	durationBuckets := buckets.Duration
+	if len(durationBuckets) == 0 {
+		durationBuckets = prometheus.DefBuckets
+	}
+	sizeBuckets := buckets.ResponseSize
+	if len(sizeBuckets) == 0 {
+		sizeBuckets = DefaultResponseSizeBuckets
+	}
+	if err := validateBuckets(durationBuckets); err != nil {
+		return nil, fmt.Errorf("invalid duration buckets: %w", err)
+	}
+	if err := validateBuckets(sizeBuckets); err != nil {
+		return nil, fmt.Errorf("invalid response size buckets: %w", err)
+	}
+
+	nfLabel := prometheus.Labels{"nf": nfName}
+	m := &EBPFHTTPMetrics{
+		requests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "ebpf_http_requests_total",
+				Help:        "Total number of HTTP requests captured by eBPF",
+				ConstLabels: nfLabel,
+			},
+			[]string{"method", "path", "status"},
+		),
+		duration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:        "ebpf_http_request_duration_seconds",
+				Help:        "HTTP request latency in seconds captured by eBPF",
+				ConstLabels: nfLabel,
+				Buckets:     durationBuckets,
+			},
+			[]string{"method", "path", "status"},
+		),
+		responseSize: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:        "ebpf_http_response_size_bytes",
+				Help:        "HTTP response size in bytes captured by eBPF",
+				ConstLabels: nfLabel,
+				Buckets:     sizeBuckets,
+			},
+			[]string{"method", "path"},
+		),
+	}
+
+	for _, c := range []prometheus.Collector{m.requests, m.duration, m.responseSize} {
+		if err := registerer.Register(c); err != nil {
+			return nil, fmt.Errorf("failed to register eBPF HTTP metrics: %w", err)
+		}
+	}
+	return m, nil
+}
+
+// Observe records a served HTTP request
+func (m *EBPFHTTPMetrics) Observe(method, path string, status int, duration time.Duration, responseSize int) {
+	statusLabel := strconv.Itoa(status)
+	m.requests.WithLabelValues(method, path, statusLabel).Inc()
+	m.duration.WithLabelValues(method, path, statusLabel).Observe(duration.Seconds())
+	m.responseSize.WithLabelValues(method, path).Observe(float64(responseSize))
+}
+
+// validateBuckets checks that bucket boundaries are strictly increasing
+func validateBuckets(buckets []float64) error {
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] <= buckets[i-1] {
+			return fmt.Errorf("bucket %g does not exceed %g", buckets[i], buckets[i-1])
+		}
+	}
+	return nil
+}
@@ -7,7 +7,6 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 )
 
@@ -57,23 +56,32 @@ var (
 
 // MetricsServer represents a Prometheus metrics HTTP server
 type MetricsServer struct {
-	port   int
-	server *http.Server
-	logger *zap.Logger
+	port     int
+	registry *Registry
+	server   *http.Server
+	logger   *zap.Logger
 }
 
-// NewMetricsServer creates a new metrics server
-func NewMetricsServer(port int, logger *zap.Logger) *MetricsServer {
+// NewMetricsServer creates the metrics server of an NF, serving its registry
+// on the port assigned to its NF type
+func NewMetricsServer(registry *Registry, logger *zap.Logger) *MetricsServer {
+	port, _ := Port(registry.NFType()) // Known, the registry was created
 	return &MetricsServer{
-		port:   port,
-		logger: logger,
+		port:     port,
+		registry: registry,
+		logger:   logger,
 	}
 }
 
+// Port returns the port the metrics server listens on
+func (m *MetricsServer) Port() int {
+	return m.port
+}
+
 // Start starts the metrics HTTP server
 func (m *MetricsServer) Start() error {
 	mux := http.NewServeMux()
-	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/metrics", m.registry.Handler())
 
 	// Health endpoint
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -88,7 +96,9 @@ func (m *MetricsServer) Start() error {
 		WriteTimeout: 10 * time.Second,
 	}
 
-	m.logger.Info("Starting metrics server", zap.Int("port", m.port))
+	m.logger.Info("Starting metrics server",
+		zap.String("nf_type", m.registry.NFType()),
+		zap.Int("port", m.port))
 	return m.server.ListenAndServe()
 }
 
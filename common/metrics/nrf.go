@@ -5,7 +5,8 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
-// NRF-specific metrics
+// NRF-specific metrics. The type of the NF an event concerns is labelled
+// registered_nf_type, nf_type being the NRF's own.
 var (
 	// NF Registration metrics
 	RegisteredNFsTotal = promauto.NewGaugeVec(
@@ -13,7 +14,7 @@ var (
 			Name: "nrf_registered_nfs_total",
 			Help: "Total number of registered NFs by type",
 		},
-		[]string{"nf_type"},
+		[]string{"registered_nf_type"},
 	)
 
 	NFRegistrations = promauto.NewCounterVec(
@@ -21,7 +22,7 @@ var (
 			Name: "nrf_nf_registrations_total",
 			Help: "Total number of NF registrations",
 		},
-		[]string{"nf_type", "status"},
+		[]string{"registered_nf_type", "status"},
 	)
 
 	NFDeregistrations = promauto.NewCounterVec(
@@ -29,7 +30,7 @@ var (
 			Name: "nrf_nf_deregistrations_total",
 			Help: "Total number of NF deregistrations",
 		},
-		[]string{"nf_type"},
+		[]string{"registered_nf_type"},
 	)
 
 	// Discovery metrics
@@ -55,7 +56,7 @@ var (
 			Name: "nrf_heartbeats_received_total",
 			Help: "Total number of heartbeats received",
 		},
-		[]string{"nf_type"},
+		[]string{"registered_nf_type"},
 	)
 )
 
@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics ports of the NFs. 9093 and 9100 are left to Alertmanager and
+// node_exporter, 9096 to the UPF admin server.
+const (
+	PortNRF   = 9090
+	PortUDR   = 9091
+	PortUDM   = 9092
+	PortAMF   = 9094
+	PortSMF   = 9095
+	PortAUSF  = 9097
+	PortUPF   = 9098
+	PortNSSF  = 9099
+	PortPCF   = 9101
+	PortNWDAF = 9102
+)
+
+var ports = map[string]int{
+	"NRF":   PortNRF,
+	"UDR":   PortUDR,
+	"UDM":   PortUDM,
+	"AMF":   PortAMF,
+	"SMF":   PortSMF,
+	"AUSF":  PortAUSF,
+	"UPF":   PortUPF,
+	"NSSF":  PortNSSF,
+	"PCF":   PortPCF,
+	"NWDAF": PortNWDAF,
+}
+
+// baseCollectors are the metrics every NF exposes
+var baseCollectors = []prometheus.Collector{
+	HTTPRequestsTotal,
+	HTTPRequestDuration,
+	ServiceUp,
+	NRFRegistered,
+	NRFHeartbeatFailures,
+	SBIRequests,
+	SBIRequestDuration,
+	SBIRequestsInFlight,
+	CircuitBreakerState,
+	CircuitBreakerRejections,
+}
+
+// nfCollectors are the metrics specific to each NF type
+var nfCollectors = map[string][]prometheus.Collector{
+	"NRF": {RegisteredNFsTotal, NFRegistrations, NFDeregistrations, DiscoveryRequests, ActiveSubscriptions, HeartbeatsReceived},
+	"UDR": {SubscriberQueries, SubscriberUpdates, DatabaseQueryDuration, DatabaseErrors, AuthSubscriptionQueries, ActiveSDMSubscriptions},
+	"UDM": {VectorGenerations, VectorGenerationDuration, SDMRequests, UEContextRegistrations, ActiveUEContexts, SQNIncrements},
+	"AMF": {RegisteredUEs, RegistrationAttempts, AuthenticationRequests, HandoverAttempts, ActiveConnections},
+	"SMF": {ActivePDUSessions, PDUSessionEstablishments, PDUSessionModifications, PDUSessionReleases,
+		SMFPFCPSessionsActive, SMFPFCPMessages, UPFRestarts, ActiveQoSFlows},
+	"AUSF": {AuthenticationAttempts, AuthenticationDuration, AKAVectorGenerations, ActiveAuthContexts},
+	"UPF": {GTPUPackets, GTPUBytes, GTPUPacketsDropped, UPFActiveSessions, UPFPFCPSessionEstablishments,
+		UPFPFCPMessages, QoSViolations, DNNBytes, UplinkThroughput, DownlinkThroughput},
+	"NSSF":  {},
+	"PCF":   {},
+	"NWDAF": {},
+}
+
+// Port returns the metrics port assigned to an NF type
+func Port(nfType string) (int, error) {
+	port, ok := ports[nfType]
+	if !ok {
+		return 0, fmt.Errorf("unknown NF type %q", nfType)
+	}
+	return port, nil
+}
+
+// Registry holds the metrics an NF exposes: the base metrics, those of its
+// NF type and the Go runtime and process metrics, all labelled with the NF
+// type and instance ID
+type Registry struct {
+	nfType     string
+	registry   *prometheus.Registry
+	registerer prometheus.Registerer
+}
+
+// NewRegistry creates the metrics registry of an NF instance
+func NewRegistry(nfType, instanceID string) (*Registry, error) {
+	specific, ok := nfCollectors[nfType]
+	if !ok {
+		return nil, fmt.Errorf("unknown NF type %q", nfType)
+	}
+
+	registry := prometheus.NewRegistry()
+	r := &Registry{
+		nfType:   nfType,
+		registry: registry,
+		registerer: prometheus.WrapRegistererWith(prometheus.Labels{
+			"nf_type":        nfType,
+			"nf_instance_id": instanceID,
+		}, registry),
+	}
+
+	cs := []prometheus.Collector{
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	}
+	cs = append(cs, baseCollectors...)
+	cs = append(cs, specific...)
+	for _, c := range cs {
+		if err := r.registerer.Register(c); err != nil {
+			return nil, fmt.Errorf("failed to register %s metrics: %w", nfType, err)
+		}
+	}
+	return r, nil
+}
+
+// NFType returns the type of the NF whose metrics the registry holds
+func (r *Registry) NFType() string {
+	return r.nfType
+}
+
+// Registerer registers further metrics of the NF, labelled like the others
+func (r *Registry) Registerer() prometheus.Registerer {
+	return r.registerer
+}
+
+// Gatherer returns the metrics of the NF
+func (r *Registry) Gatherer() prometheus.Gatherer {
+	return r.registry
+}
+
+// Handler serves the metrics of the NF in the Prometheus exposition format
+func (r *Registry) Handler() http.Handler {
+	return promhttp.HandlerFor(r.registry, promhttp.HandlerOpts{})
+}
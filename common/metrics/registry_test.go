@@ -0,0 +1,91 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gatheredFamilies(t *testing.T, r *Registry) map[string]map[string]string {
+	t.Helper()
+
+	families, err := r.Gatherer().Gather()
+	require.NoError(t, err)
+
+	// Labels of the first series of each family
+	labels := make(map[string]map[string]string)
+	for _, family := range families {
+		if len(family.GetMetric()) == 0 {
+			continue
+		}
+		pairs := make(map[string]string)
+		for _, pair := range family.GetMetric()[0].GetLabel() {
+			pairs[pair.GetName()] = pair.GetValue()
+		}
+		labels[family.GetName()] = pairs
+	}
+	return labels
+}
+
+func TestRegistryExposesBaseMetrics(t *testing.T) {
+	r, err := NewRegistry("AMF", "amf-instance-1")
+	require.NoError(t, err)
+	assert.Equal(t, "AMF", r.NFType())
+
+	SetServiceUp(true)
+	RecordHTTPRequest("GET", "/health", "200", 0.01)
+	SetRegisteredUEs(3)
+
+	families := gatheredFamilies(t, r)
+	for _, name := range []string{
+		"service_up",
+		"nrf_registered",
+		"nrf_heartbeat_failures_total",
+		"http_requests_total",
+		"http_request_duration_seconds",
+		"amf_registered_ues_total",
+		"go_goroutines",
+		"process_start_time_seconds",
+	} {
+		require.Contains(t, families, name)
+		assert.Equal(t, "AMF", families[name]["nf_type"], name)
+		assert.Equal(t, "amf-instance-1", families[name]["nf_instance_id"], name)
+	}
+
+	// Metrics of other NF types are left out
+	assert.NotContains(t, families, "smf_active_pdu_sessions")
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `service_up{nf_instance_id="amf-instance-1",nf_type="AMF"} 1`)
+}
+
+func TestRegistryPerNFType(t *testing.T) {
+	for nfType, port := range ports {
+		r, err := NewRegistry(nfType, "instance")
+		require.NoError(t, err, nfType)
+		assert.Contains(t, gatheredFamilies(t, r), "service_up", nfType)
+
+		server := NewMetricsServer(r, nil)
+		assert.Equal(t, port, server.Port(), nfType)
+	}
+
+	_, err := NewRegistry("XYZ", "instance")
+	assert.ErrorContains(t, err, "unknown NF type")
+	_, err = Port("XYZ")
+	assert.Error(t, err)
+}
+
+func TestMetricsPortsAreUnique(t *testing.T) {
+	seen := make(map[int]string)
+	for nfType, port := range ports {
+		other, dup := seen[port]
+		assert.False(t, dup, "%s and %s share port %d", nfType, other, port)
+		seen[port] = nfType
+	}
+	assert.Len(t, nfCollectors, len(ports), "every NF type has a port")
+}
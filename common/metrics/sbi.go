@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// SBI server metrics, recorded by Middleware and labelled with the NF by the
+// Registry
+var (
+	SBIRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sbi_requests_total",
+			Help: "Total number of SBI requests served, by route and status class",
+		},
+		[]string{"method", "route", "status_class"},
+	)
+
+	SBIRequestDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "sbi_request_duration_seconds",
+			Help:    "SBI request latency in seconds, by route and status class",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method", "route", "status_class"},
+	)
+
+	SBIRequestsInFlight = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sbi_requests_in_flight",
+			Help: "Number of SBI requests being served",
+		},
+		[]string{"method"},
+	)
+
+	CircuitBreakerState = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "sbi_circuit_breaker_state",
+			Help: "State of the circuit breaker towards an NF (0 = closed, 1 = open, 2 = half-open)",
+		},
+		[]string{"target"},
+	)
+
+	CircuitBreakerRejections = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "sbi_circuit_breaker_rejections_total",
+			Help: "Total number of SBI requests failed fast by an open circuit breaker",
+		},
+		[]string{"target"},
+	)
+)
+
+// unmatchedRoute labels requests no route matched, keeping the path out of
+// the labels
+const unmatchedRoute = "unmatched"
+
+// probePaths are health probes and build info requests, left out of the SBI
+// metrics
+var probePaths = map[string]bool{
+	"/health":  true,
+	"/ready":   true,
+	"/version": true,
+}
+
+// Middleware records the latency, status class and concurrency of the
+// requests a chi router serves, labelled by route pattern. It must be used on
+// the root router, ahead of the middleware that can answer on its own.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if probePaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		inFlight := SBIRequestsInFlight.WithLabelValues(r.Method)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := unmatchedRoute
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		class := statusClass(status)
+
+		SBIRequests.WithLabelValues(r.Method, route, class).Inc()
+		SBIRequestDuration.WithLabelValues(r.Method, route, class).Observe(time.Since(start).Seconds())
+	})
+}
+
+// statusClass returns the class of an HTTP status, e.g. "2xx"
+func statusClass(status int) string {
+	return fmt.Sprintf("%dxx", status/100)
+}
@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// findSeries returns the series of a gathered family with the given labels
+func findSeries(t *testing.T, r *Registry, name string, labels map[string]string) *dto.Metric {
+	t.Helper()
+
+	families, err := r.Gatherer().Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	series:
+		for _, metric := range family.GetMetric() {
+			got := make(map[string]string)
+			for _, pair := range metric.GetLabel() {
+				got[pair.GetName()] = pair.GetValue()
+			}
+			for k, v := range labels {
+				if got[k] != v {
+					continue series
+				}
+			}
+			return metric
+		}
+	}
+	return nil
+}
+
+func TestMiddlewareRecordsRoutes(t *testing.T) {
+	r, err := NewRegistry("UDM", "udm-instance-1")
+	require.NoError(t, err)
+
+	router := chi.NewRouter()
+	router.Use(Middleware)
+	router.Get("/health", func(w http.ResponseWriter, r *http.Request) {})
+	router.Route("/nudm-test/v1", func(sub chi.Router) {
+		sub.Get("/supi/{supi}/data", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+		sub.Post("/supi/{supi}/data", func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "bad", http.StatusBadRequest)
+		})
+	})
+
+	serve := func(method, path string) {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, path, nil))
+	}
+	serve(http.MethodGet, "/nudm-test/v1/supi/imsi-001010000000001/data")
+	serve(http.MethodGet, "/nudm-test/v1/supi/imsi-001010000000002/data")
+	serve(http.MethodPost, "/nudm-test/v1/supi/imsi-001010000000001/data")
+	serve(http.MethodGet, "/unknown")
+	serve(http.MethodGet, "/health")
+
+	// Requests are labelled by route pattern, not path, and by NF
+	ok := map[string]string{
+		"nf_type":        "UDM",
+		"nf_instance_id": "udm-instance-1",
+		"method":         http.MethodGet,
+		"route":          "/nudm-test/v1/supi/{supi}/data",
+		"status_class":   "2xx",
+	}
+	counter := findSeries(t, r, "sbi_requests_total", ok)
+	require.NotNil(t, counter)
+	assert.Equal(t, 2.0, counter.GetCounter().GetValue())
+	histogram := findSeries(t, r, "sbi_request_duration_seconds", ok)
+	require.NotNil(t, histogram)
+	assert.Equal(t, uint64(2), histogram.GetHistogram().GetSampleCount())
+
+	clientError := findSeries(t, r, "sbi_requests_total", map[string]string{
+		"method":       http.MethodPost,
+		"route":        "/nudm-test/v1/supi/{supi}/data",
+		"status_class": "4xx",
+	})
+	require.NotNil(t, clientError)
+	assert.Equal(t, 1.0, clientError.GetCounter().GetValue())
+
+	unmatched := findSeries(t, r, "sbi_requests_total", map[string]string{"route": unmatchedRoute, "status_class": "4xx"})
+	require.NotNil(t, unmatched)
+	assert.Equal(t, 1.0, unmatched.GetCounter().GetValue())
+
+	// Probes are left out and nothing is in flight once served
+	assert.Nil(t, findSeries(t, r, "sbi_requests_total", map[string]string{"route": "/health"}))
+	inFlight := findSeries(t, r, "sbi_requests_in_flight", map[string]string{"method": http.MethodGet})
+	require.NotNil(t, inFlight)
+	assert.Zero(t, inFlight.GetGauge().GetValue())
+}
+
+func TestStatusClass(t *testing.T) {
+	assert.Equal(t, "2xx", statusClass(http.StatusNoContent))
+	assert.Equal(t, "5xx", statusClass(http.StatusServiceUnavailable))
+}
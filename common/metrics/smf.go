@@ -55,6 +55,14 @@ var (
 		[]string{"type", "direction"},
 	)
 
+	UPFRestarts = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "smf_upf_restarts_detected_total",
+			Help: "Total number of UPF restarts detected through the PFCP Recovery Time Stamp",
+		},
+		[]string{"upf"},
+	)
+
 	// QoS Flow metrics
 	ActiveQoSFlows = promauto.NewGauge(
 		prometheus.GaugeOpts{
@@ -94,6 +102,11 @@ func RecordSMFPFCPMessage(msgType, direction string) {
 	SMFPFCPMessages.WithLabelValues(msgType, direction).Inc()
 }
 
+// RecordUPFRestart records a detected UPF restart
+func RecordUPFRestart(upfNodeID string) {
+	UPFRestarts.WithLabelValues(upfNodeID).Inc()
+}
+
 // SetActiveQoSFlows sets the number of active QoS flows
 func SetActiveQoSFlows(count int) {
 	ActiveQoSFlows.Set(float64(count))
@@ -66,6 +66,15 @@ var (
 		[]string{"qfi"},
 	)
 
+	// Per DNN traffic
+	DNNBytes = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "upf_dnn_bytes_total",
+			Help: "Total number of user plane bytes forwarded per DNN",
+		},
+		[]string{"dnn", "direction"},
+	)
+
 	// Throughput
 	UplinkThroughput = promauto.NewGauge(
 		prometheus.GaugeOpts{
@@ -93,6 +102,11 @@ func RecordGTPUPacketDropped(reason string) {
 	GTPUPacketsDropped.WithLabelValues(reason).Inc()
 }
 
+// RecordDNNBytes records user plane bytes forwarded for a DNN
+func RecordDNNBytes(dnn, direction string, bytes int) {
+	DNNBytes.WithLabelValues(dnn, direction).Add(float64(bytes))
+}
+
 // SetUPFActiveSessions sets the number of active sessions
 func SetUPFActiveSessions(count int) {
 	UPFActiveSessions.Set(float64(count))
@@ -0,0 +1,244 @@
+package nas
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/your-org/5g-network/common/crypto/suci"
+)
+
+// 5GS mobile identity types (TS 24.501 9.11.3.4)
+const (
+	MOBILE_IDENTITY_NONE      = 0
+	MOBILE_IDENTITY_SUCI      = 1
+	MOBILE_IDENTITY_GUTI      = 2
+	MOBILE_IDENTITY_IMEI      = 3
+	MOBILE_IDENTITY_5G_S_TMSI = 4
+	MOBILE_IDENTITY_IMEISV    = 5
+)
+
+// Mobile identity lengths of the fixed size identity types
+const (
+	SUCI_HEADER_LENGTH = 8  // Type octet up to the home network public key identifier
+	GUTI_LENGTH        = 11 // Type octet, PLMN, AMF ID and 5G-TMSI
+)
+
+// MobileIdentity is a 5GS mobile identity IE value (TS 24.501 9.11.3.4)
+type MobileIdentity struct {
+	Value []byte
+}
+
+// GUTI is a 5G-GUTI as carried in a 5GS mobile identity (TS 23.003 2.10.1)
+type GUTI struct {
+	MCC         string
+	MNC         string
+	AMFRegionID uint8
+	AMFSetID    uint16 // 10 bits
+	AMFPointer  uint8  // 6 bits
+	TMSI        uint32
+}
+
+// Type returns the identity type, MOBILE_IDENTITY_NONE for an empty value
+func (m MobileIdentity) Type() uint8 {
+	if len(m.Value) == 0 {
+		return MOBILE_IDENTITY_NONE
+	}
+	return m.Value[0] & 0x07
+}
+
+// NewSUCIMobileIdentity encodes a SUCI string (TS 29.503 6.1.6.2.3) with an
+// IMSI based SUPI. The null scheme output is the MSIN in BCD; other scheme
+// outputs are carried as they are.
+func NewSUCIMobileIdentity(s string) (MobileIdentity, error) {
+	parsed, err := suci.Parse(s)
+	if err != nil {
+		return MobileIdentity{}, err
+	}
+	if parsed.SUPIType != 0 {
+		return MobileIdentity{}, fmt.Errorf("unsupported suci supi type %d", parsed.SUPIType)
+	}
+
+	var output []byte
+	if parsed.ProtectionScheme == suci.SchemeNull {
+		output, err = encodeBCD(parsed.SchemeOutput)
+	} else {
+		output, err = hex.DecodeString(parsed.SchemeOutput)
+	}
+	if err != nil {
+		return MobileIdentity{}, fmt.Errorf("invalid suci scheme output: %w", err)
+	}
+
+	plmn, err := encodePLMN(parsed.MCC, parsed.MNC)
+	if err != nil {
+		return MobileIdentity{}, err
+	}
+	routing, err := encodeRoutingIndicator(parsed.RoutingIndicator)
+	if err != nil {
+		return MobileIdentity{}, err
+	}
+
+	value := make([]byte, 0, SUCI_HEADER_LENGTH+len(output))
+	value = append(value, MOBILE_IDENTITY_SUCI)
+	value = append(value, plmn...)
+	value = append(value, routing...)
+	value = append(value, byte(parsed.ProtectionScheme)&0x0F, byte(parsed.HomeNetworkPublicKeyID))
+	value = append(value, output...)
+	return MobileIdentity{Value: value}, nil
+}
+
+// SUCI decodes a SUCI identity into its string form
+func (m MobileIdentity) SUCI() (string, error) {
+	if m.Type() != MOBILE_IDENTITY_SUCI {
+		return "", fmt.Errorf("mobile identity type %d is not a suci", m.Type())
+	}
+	if len(m.Value) < SUCI_HEADER_LENGTH {
+		return "", fmt.Errorf("suci too short: %d octets", len(m.Value))
+	}
+	if supiFormat := (m.Value[0] >> 4) & 0x07; supiFormat != 0 {
+		return "", fmt.Errorf("unsupported suci supi format %d", supiFormat)
+	}
+
+	mcc, mnc := decodePLMN(m.Value[1:4])
+	routing := decodeBCD(m.Value[4:6])
+	if routing == "" {
+		routing = "0"
+	}
+	scheme := int(m.Value[6] & 0x0F)
+	keyID := int(m.Value[7])
+
+	output := hex.EncodeToString(m.Value[SUCI_HEADER_LENGTH:])
+	if scheme == suci.SchemeNull {
+		output = decodeBCD(m.Value[SUCI_HEADER_LENGTH:])
+	}
+
+	return fmt.Sprintf("suci-0-%s-%s-%s-%d-%d-%s", mcc, mnc, routing, scheme, keyID, output), nil
+}
+
+// NewGUTIMobileIdentity encodes a 5G-GUTI
+func NewGUTIMobileIdentity(g GUTI) (MobileIdentity, error) {
+	plmn, err := encodePLMN(g.MCC, g.MNC)
+	if err != nil {
+		return MobileIdentity{}, err
+	}
+
+	value := make([]byte, GUTI_LENGTH)
+	value[0] = 0xF0 | MOBILE_IDENTITY_GUTI
+	copy(value[1:4], plmn)
+	value[4] = g.AMFRegionID
+	binary.BigEndian.PutUint16(value[5:7], (g.AMFSetID&0x3FF)<<6|uint16(g.AMFPointer&0x3F))
+	binary.BigEndian.PutUint32(value[7:11], g.TMSI)
+	return MobileIdentity{Value: value}, nil
+}
+
+// GUTI decodes a 5G-GUTI identity
+func (m MobileIdentity) GUTI() (*GUTI, error) {
+	if m.Type() != MOBILE_IDENTITY_GUTI {
+		return nil, fmt.Errorf("mobile identity type %d is not a 5g-guti", m.Type())
+	}
+	if len(m.Value) != GUTI_LENGTH {
+		return nil, fmt.Errorf("5g-guti has invalid length %d", len(m.Value))
+	}
+
+	mcc, mnc := decodePLMN(m.Value[1:4])
+	amfSet := binary.BigEndian.Uint16(m.Value[5:7])
+	return &GUTI{
+		MCC:         mcc,
+		MNC:         mnc,
+		AMFRegionID: m.Value[4],
+		AMFSetID:    amfSet >> 6,
+		AMFPointer:  uint8(amfSet & 0x3F),
+		TMSI:        binary.BigEndian.Uint32(m.Value[7:11]),
+	}, nil
+}
+
+// encodePLMN encodes an MCC and MNC in the three octet BCD layout of
+// TS 24.501 Figure 9.11.3.4.3
+func encodePLMN(mcc, mnc string) ([]byte, error) {
+	if !isDigits(mcc) || len(mcc) != 3 || !isDigits(mnc) || (len(mnc) != 2 && len(mnc) != 3) {
+		return nil, fmt.Errorf("invalid plmn %s-%s", mcc, mnc)
+	}
+
+	mnc3 := byte(0x0F)
+	if len(mnc) == 3 {
+		mnc3 = mnc[2] - '0'
+	}
+	return []byte{
+		(mcc[1]-'0')<<4 | (mcc[0] - '0'),
+		mnc3<<4 | (mcc[2] - '0'),
+		(mnc[1]-'0')<<4 | (mnc[0] - '0'),
+	}, nil
+}
+
+// decodePLMN decodes a three octet BCD PLMN
+func decodePLMN(b []byte) (string, string) {
+	mcc := []byte{'0' + b[0]&0x0F, '0' + b[0]>>4, '0' + b[1]&0x0F}
+	mnc := []byte{'0' + b[2]&0x0F, '0' + b[2]>>4}
+	if b[1]>>4 != 0x0F {
+		mnc = append(mnc, '0'+b[1]>>4)
+	}
+	return string(mcc), string(mnc)
+}
+
+// encodeRoutingIndicator encodes a routing indicator of up to 4 digits in
+// two BCD octets padded with 0xF
+func encodeRoutingIndicator(routing string) ([]byte, error) {
+	if len(routing) > 4 {
+		return nil, fmt.Errorf("invalid routing indicator %q", routing)
+	}
+	padded := routing + strings.Repeat("F", 4-len(routing))
+	return encodeBCD(padded)
+}
+
+// encodeBCD encodes digits two per octet, low half octet first. An odd
+// digit count leaves 0xF in the last high half octet; 'F' digits encode
+// as filler.
+func encodeBCD(digits string) ([]byte, error) {
+	out := make([]byte, (len(digits)+1)/2)
+	for i := range out {
+		out[i] = 0xF0
+	}
+	for i := 0; i < len(digits); i++ {
+		d := digits[i]
+		var v byte
+		switch {
+		case d >= '0' && d <= '9':
+			v = d - '0'
+		case d == 'F':
+			v = 0x0F
+		default:
+			return nil, fmt.Errorf("invalid digit %q", d)
+		}
+		if i%2 == 0 {
+			out[i/2] = out[i/2]&0xF0 | v
+		} else {
+			out[i/2] = out[i/2]&0x0F | v<<4
+		}
+	}
+	return out, nil
+}
+
+// decodeBCD decodes BCD digits, stopping at the first filler
+func decodeBCD(b []byte) string {
+	var digits strings.Builder
+	for _, octet := range b {
+		for _, v := range []byte{octet & 0x0F, octet >> 4} {
+			if v > 9 {
+				return digits.String()
+			}
+			digits.WriteString(strconv.Itoa(int(v)))
+		}
+	}
+	return digits.String()
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,470 @@
+package nas
+
+import (
+	"fmt"
+	"time"
+)
+
+// 5GS registration types (TS 24.501 9.11.3.7)
+const (
+	REGISTRATION_TYPE_INITIAL   = 1
+	REGISTRATION_TYPE_MOBILITY  = 2
+	REGISTRATION_TYPE_PERIODIC  = 3
+	REGISTRATION_TYPE_EMERGENCY = 4
+)
+
+// 5GS registration result values (TS 24.501 9.11.3.6)
+const (
+	REGISTRATION_RESULT_3GPP_ACCESS       = 1
+	REGISTRATION_RESULT_NON_3GPP_ACCESS   = 2
+	REGISTRATION_RESULT_3GPP_AND_NON_3GPP = 3
+)
+
+// registrationResultSMSAllowed is the SMS allowed bit of the 5GS
+// registration result
+const registrationResultSMSAllowed uint8 = 0x08
+
+// Optional IEIs of the supported messages (TS 24.501 8.2)
+const (
+	IEI_ALLOWED_NSSAI           = 0x15
+	IEI_AUTN                    = 0x20
+	IEI_RAND                    = 0x21
+	IEI_RES_STAR                = 0x2D
+	IEI_UE_SECURITY_CAPABILITY  = 0x2E
+	IEI_REQUESTED_NSSAI         = 0x2F
+	IEI_ABBA                    = 0x38
+	IEI_LAST_VISITED_TAI        = 0x52
+	IEI_SELECTED_EPS_ALGORITHMS = 0x57
+	IEI_T3512                   = 0x5E
+	IEI_NAS_MESSAGE_CONTAINER   = 0x71
+	IEI_5GS_MOBILE_IDENTITY     = 0x77
+	IEI_IMEISV_REQUEST          = 0xE0 // Type 1, value in the low half octet
+)
+
+// imeisvRequested is the IMEISV request value asking for the IMEISV
+const imeisvRequested uint8 = 0x01
+
+// GPRS timer 3 units (TS 24.008 10.5.7.4a), indexed by their unit value
+var gprsTimer3Units = [...]time.Duration{
+	10 * time.Minute,
+	time.Hour,
+	10 * time.Hour,
+	2 * time.Second,
+	30 * time.Second,
+	time.Minute,
+	320 * time.Hour,
+}
+
+// gprsTimer3Order lists the timer units from the finest to the coarsest
+var gprsTimer3Order = [...]uint8{3, 4, 5, 0, 1, 2, 6}
+
+// SNSSAI is an S-NSSAI as carried in NSSAI IEs (TS 24.501 9.11.2.8),
+// without the mapped HPLMN values
+type SNSSAI struct {
+	SST uint8
+	SD  []byte // 3 octets, nil when absent
+}
+
+// RegistrationRequest is sent by the UE to register with the network
+// (TS 24.501 8.2.6)
+type RegistrationRequest struct {
+	RegistrationType     uint8 // REGISTRATION_TYPE_*
+	FollowOnRequest      bool
+	NgKSI                uint8 // TSC in bit 4, key set identifier in bits 3-1
+	MobileIdentity       MobileIdentity
+	UESecurityCapability []byte
+	RequestedNSSAI       []SNSSAI
+	LastVisitedTAI       []byte // 6 octets, nil when absent
+}
+
+// MessageType implements Message
+func (m *RegistrationRequest) MessageType() uint8 { return MSG_REGISTRATION_REQUEST }
+
+// Marshal encodes the message
+func (m *RegistrationRequest) Marshal() []byte {
+	buf := newMessage(MSG_REGISTRATION_REQUEST)
+
+	regType := m.RegistrationType & 0x07
+	if m.FollowOnRequest {
+		regType |= 0x08
+	}
+	buf = append(buf, m.NgKSI<<4|regType)
+	buf = append(buf, byte(len(m.MobileIdentity.Value)>>8), byte(len(m.MobileIdentity.Value)))
+	buf = append(buf, m.MobileIdentity.Value...)
+
+	if m.UESecurityCapability != nil {
+		buf = appendTLV(buf, IEI_UE_SECURITY_CAPABILITY, m.UESecurityCapability)
+	}
+	if m.RequestedNSSAI != nil {
+		buf = appendTLV(buf, IEI_REQUESTED_NSSAI, marshalNSSAI(m.RequestedNSSAI))
+	}
+	if m.LastVisitedTAI != nil {
+		buf = append(buf, IEI_LAST_VISITED_TAI)
+		buf = append(buf, m.LastVisitedTAI...)
+	}
+	return buf
+}
+
+func parseRegistrationRequest(body []byte) (Message, error) {
+	if len(body) < 1 {
+		return nil, fmt.Errorf("registration request too short")
+	}
+
+	m := &RegistrationRequest{
+		RegistrationType: body[0] & 0x07,
+		FollowOnRequest:  body[0]&0x08 != 0,
+		NgKSI:            body[0] >> 4,
+	}
+
+	identity, offset, err := readLVE(body, 1)
+	if err != nil {
+		return nil, fmt.Errorf("registration request mobile identity: %w", err)
+	}
+	m.MobileIdentity = MobileIdentity{Value: clone(identity)}
+
+	fixed := map[uint8]int{IEI_LAST_VISITED_TAI: 6}
+	err = parseOptionalIEs(body[offset:], fixed, func(iei uint8, value []byte) error {
+		switch iei {
+		case IEI_UE_SECURITY_CAPABILITY:
+			m.UESecurityCapability = clone(value)
+		case IEI_REQUESTED_NSSAI:
+			nssai, err := parseNSSAI(value)
+			if err != nil {
+				return fmt.Errorf("requested nssai: %w", err)
+			}
+			m.RequestedNSSAI = nssai
+		case IEI_LAST_VISITED_TAI:
+			m.LastVisitedTAI = clone(value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// RegistrationAccept is sent by the AMF when it accepts a registration
+// (TS 24.501 8.2.7)
+type RegistrationAccept struct {
+	RegistrationResult uint8 // REGISTRATION_RESULT_*
+	SMSAllowed         bool
+	GUTI               *MobileIdentity
+	AllowedNSSAI       []SNSSAI
+	T3512              time.Duration // Zero when absent
+}
+
+// MessageType implements Message
+func (m *RegistrationAccept) MessageType() uint8 { return MSG_REGISTRATION_ACCEPT }
+
+// Marshal encodes the message
+func (m *RegistrationAccept) Marshal() []byte {
+	buf := newMessage(MSG_REGISTRATION_ACCEPT)
+
+	result := m.RegistrationResult & 0x07
+	if m.SMSAllowed {
+		result |= registrationResultSMSAllowed
+	}
+	buf = appendLV(buf, []byte{result})
+
+	if m.GUTI != nil {
+		buf = appendTLVE(buf, IEI_5GS_MOBILE_IDENTITY, m.GUTI.Value)
+	}
+	if m.AllowedNSSAI != nil {
+		buf = appendTLV(buf, IEI_ALLOWED_NSSAI, marshalNSSAI(m.AllowedNSSAI))
+	}
+	if m.T3512 > 0 {
+		buf = appendTLV(buf, IEI_T3512, []byte{EncodeGPRSTimer3(m.T3512)})
+	}
+	return buf
+}
+
+func parseRegistrationAccept(body []byte) (Message, error) {
+	result, offset, err := readLV(body, 0)
+	if err != nil {
+		return nil, fmt.Errorf("registration accept result: %w", err)
+	}
+	if len(result) != 1 {
+		return nil, fmt.Errorf("registration accept result has invalid length %d", len(result))
+	}
+
+	m := &RegistrationAccept{
+		RegistrationResult: result[0] & 0x07,
+		SMSAllowed:         result[0]&registrationResultSMSAllowed != 0,
+	}
+
+	err = parseOptionalIEs(body[offset:], nil, func(iei uint8, value []byte) error {
+		switch iei {
+		case IEI_5GS_MOBILE_IDENTITY:
+			m.GUTI = &MobileIdentity{Value: clone(value)}
+		case IEI_ALLOWED_NSSAI:
+			nssai, err := parseNSSAI(value)
+			if err != nil {
+				return fmt.Errorf("allowed nssai: %w", err)
+			}
+			m.AllowedNSSAI = nssai
+		case IEI_T3512:
+			if len(value) != 1 {
+				return fmt.Errorf("t3512 has invalid length %d", len(value))
+			}
+			m.T3512 = DecodeGPRSTimer3(value[0])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// AuthenticationRequest starts 5G AKA on the UE (TS 24.501 8.2.1)
+type AuthenticationRequest struct {
+	NgKSI uint8
+	ABBA  []byte
+	RAND  []byte // 16 octets, nil when absent
+	AUTN  []byte // 16 octets, nil when absent
+}
+
+// MessageType implements Message
+func (m *AuthenticationRequest) MessageType() uint8 { return MSG_AUTHENTICATION_REQUEST }
+
+// Marshal encodes the message
+func (m *AuthenticationRequest) Marshal() []byte {
+	buf := newMessage(MSG_AUTHENTICATION_REQUEST)
+	buf = append(buf, m.NgKSI&0x0F)
+	buf = appendLV(buf, m.ABBA)
+
+	if m.RAND != nil {
+		buf = append(buf, IEI_RAND)
+		buf = append(buf, m.RAND...)
+	}
+	if m.AUTN != nil {
+		buf = appendTLV(buf, IEI_AUTN, m.AUTN)
+	}
+	return buf
+}
+
+func parseAuthenticationRequest(body []byte) (Message, error) {
+	if len(body) < 1 {
+		return nil, fmt.Errorf("authentication request too short")
+	}
+
+	abba, offset, err := readLV(body, 1)
+	if err != nil {
+		return nil, fmt.Errorf("authentication request abba: %w", err)
+	}
+	m := &AuthenticationRequest{
+		NgKSI: body[0] & 0x0F,
+		ABBA:  clone(abba),
+	}
+
+	fixed := map[uint8]int{IEI_RAND: 16}
+	err = parseOptionalIEs(body[offset:], fixed, func(iei uint8, value []byte) error {
+		switch iei {
+		case IEI_RAND:
+			m.RAND = clone(value)
+		case IEI_AUTN:
+			m.AUTN = clone(value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// AuthenticationResponse carries the RES* computed by the UE
+// (TS 24.501 8.2.2)
+type AuthenticationResponse struct {
+	RESStar []byte // 16 octets, nil when absent
+}
+
+// MessageType implements Message
+func (m *AuthenticationResponse) MessageType() uint8 { return MSG_AUTHENTICATION_RESPONSE }
+
+// Marshal encodes the message
+func (m *AuthenticationResponse) Marshal() []byte {
+	buf := newMessage(MSG_AUTHENTICATION_RESPONSE)
+	if m.RESStar != nil {
+		buf = appendTLV(buf, IEI_RES_STAR, m.RESStar)
+	}
+	return buf
+}
+
+func parseAuthenticationResponse(body []byte) (Message, error) {
+	m := &AuthenticationResponse{}
+	err := parseOptionalIEs(body, nil, func(iei uint8, value []byte) error {
+		if iei == IEI_RES_STAR {
+			m.RESStar = clone(value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SecurityModeCommand selects the NAS security algorithms and takes the new
+// NAS security context into use (TS 24.501 8.2.25)
+type SecurityModeCommand struct {
+	CipheringAlgorithm           uint8 // 5G-EA0 to 5G-EA7
+	IntegrityAlgorithm           uint8 // 5G-IA0 to 5G-IA7
+	NgKSI                        uint8
+	ReplayedUESecurityCapability []byte
+	IMEISVRequest                bool
+	SelectedEPSAlgorithms        *uint8
+	ABBA                         []byte // nil when absent
+}
+
+// MessageType implements Message
+func (m *SecurityModeCommand) MessageType() uint8 { return MSG_SECURITY_MODE_COMMAND }
+
+// Marshal encodes the message
+func (m *SecurityModeCommand) Marshal() []byte {
+	buf := newMessage(MSG_SECURITY_MODE_COMMAND)
+	buf = append(buf, (m.CipheringAlgorithm&0x0F)<<4|m.IntegrityAlgorithm&0x0F)
+	buf = append(buf, m.NgKSI&0x0F)
+	buf = appendLV(buf, m.ReplayedUESecurityCapability)
+
+	if m.IMEISVRequest {
+		buf = append(buf, IEI_IMEISV_REQUEST|imeisvRequested)
+	}
+	if m.SelectedEPSAlgorithms != nil {
+		buf = append(buf, IEI_SELECTED_EPS_ALGORITHMS, *m.SelectedEPSAlgorithms)
+	}
+	if m.ABBA != nil {
+		buf = appendTLV(buf, IEI_ABBA, m.ABBA)
+	}
+	return buf
+}
+
+func parseSecurityModeCommand(body []byte) (Message, error) {
+	if len(body) < 2 {
+		return nil, fmt.Errorf("security mode command too short")
+	}
+
+	capability, offset, err := readLV(body, 2)
+	if err != nil {
+		return nil, fmt.Errorf("security mode command replayed ue security capability: %w", err)
+	}
+	m := &SecurityModeCommand{
+		CipheringAlgorithm:           body[0] >> 4,
+		IntegrityAlgorithm:           body[0] & 0x0F,
+		NgKSI:                        body[1] & 0x0F,
+		ReplayedUESecurityCapability: clone(capability),
+	}
+
+	fixed := map[uint8]int{IEI_SELECTED_EPS_ALGORITHMS: 1}
+	err = parseOptionalIEs(body[offset:], fixed, func(iei uint8, value []byte) error {
+		switch iei {
+		case IEI_IMEISV_REQUEST:
+			m.IMEISVRequest = value[0]&0x07 == imeisvRequested
+		case IEI_SELECTED_EPS_ALGORITHMS:
+			algorithms := value[0]
+			m.SelectedEPSAlgorithms = &algorithms
+		case IEI_ABBA:
+			m.ABBA = clone(value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SecurityModeComplete confirms the UE took the new NAS security context
+// into use (TS 24.501 8.2.26)
+type SecurityModeComplete struct {
+	IMEISV              *MobileIdentity
+	NASMessageContainer []byte // Initial NAS message retransmitted in full, nil when absent
+}
+
+// MessageType implements Message
+func (m *SecurityModeComplete) MessageType() uint8 { return MSG_SECURITY_MODE_COMPLETE }
+
+// Marshal encodes the message
+func (m *SecurityModeComplete) Marshal() []byte {
+	buf := newMessage(MSG_SECURITY_MODE_COMPLETE)
+	if m.IMEISV != nil {
+		buf = appendTLVE(buf, IEI_5GS_MOBILE_IDENTITY, m.IMEISV.Value)
+	}
+	if m.NASMessageContainer != nil {
+		buf = appendTLVE(buf, IEI_NAS_MESSAGE_CONTAINER, m.NASMessageContainer)
+	}
+	return buf
+}
+
+func parseSecurityModeComplete(body []byte) (Message, error) {
+	m := &SecurityModeComplete{}
+	err := parseOptionalIEs(body, nil, func(iei uint8, value []byte) error {
+		switch iei {
+		case IEI_5GS_MOBILE_IDENTITY:
+			m.IMEISV = &MobileIdentity{Value: clone(value)}
+		case IEI_NAS_MESSAGE_CONTAINER:
+			m.NASMessageContainer = clone(value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// marshalNSSAI encodes the S-NSSAI list of an NSSAI IE
+func marshalNSSAI(nssai []SNSSAI) []byte {
+	var buf []byte
+	for _, s := range nssai {
+		buf = appendLV(buf, append([]byte{s.SST}, s.SD...))
+	}
+	return buf
+}
+
+// parseNSSAI decodes the S-NSSAI list of an NSSAI IE. Mapped HPLMN values
+// are ignored.
+func parseNSSAI(data []byte) ([]SNSSAI, error) {
+	nssai := []SNSSAI{}
+	for offset := 0; offset < len(data); {
+		value, next, err := readLV(data, offset)
+		if err != nil {
+			return nil, err
+		}
+		if len(value) == 0 {
+			return nil, fmt.Errorf("empty s-nssai")
+		}
+
+		s := SNSSAI{SST: value[0]}
+		if len(value) >= 4 {
+			s.SD = clone(value[1:4])
+		}
+		nssai = append(nssai, s)
+		offset = next
+	}
+	return nssai, nil
+}
+
+// EncodeGPRSTimer3 encodes a timer value as a GPRS timer 3 octet, using the
+// finest unit that holds it and rounding up to the next unit step
+func EncodeGPRSTimer3(d time.Duration) uint8 {
+	for _, unit := range gprsTimer3Order {
+		step := gprsTimer3Units[unit]
+		value := (d + step - 1) / step
+		if value <= 0x1F {
+			return unit<<5 | uint8(value)
+		}
+	}
+	return 6<<5 | 0x1F
+}
+
+// DecodeGPRSTimer3 decodes a GPRS timer 3 octet. A deactivated timer
+// decodes as zero.
+func DecodeGPRSTimer3(v uint8) time.Duration {
+	unit := v >> 5
+	if int(unit) >= len(gprsTimer3Units) {
+		return 0
+	}
+	return time.Duration(v&0x1F) * gprsTimer3Units[unit]
+}
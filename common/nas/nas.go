@@ -0,0 +1,265 @@
+// Package nas encodes and decodes 5GS mobility management (5GMM) NAS
+// messages exchanged between the UE and the AMF (TS 24.501).
+package nas
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Extended protocol discriminators (TS 24.007 11.2.3.1.1A)
+const (
+	EPD_5GSM = 0x2E
+	EPD_5GMM = 0x7E
+)
+
+// Security header types (TS 24.501 9.3.1)
+const (
+	SECURITY_HEADER_PLAIN                                    = 0
+	SECURITY_HEADER_INTEGRITY_PROTECTED                      = 1
+	SECURITY_HEADER_INTEGRITY_PROTECTED_CIPHERED             = 2
+	SECURITY_HEADER_INTEGRITY_PROTECTED_NEW_CONTEXT          = 3
+	SECURITY_HEADER_INTEGRITY_PROTECTED_CIPHERED_NEW_CONTEXT = 4
+)
+
+// 5GMM message types (TS 24.501 Table 9.7.1)
+const (
+	MSG_REGISTRATION_REQUEST    = 0x41
+	MSG_REGISTRATION_ACCEPT     = 0x42
+	MSG_AUTHENTICATION_REQUEST  = 0x56
+	MSG_AUTHENTICATION_RESPONSE = 0x57
+	MSG_SECURITY_MODE_COMMAND   = 0x5D
+	MSG_SECURITY_MODE_COMPLETE  = 0x5E
+)
+
+// NAS header sizes
+const (
+	PLAIN_HEADER_LENGTH     = 3 // EPD, security header type, message type
+	PROTECTED_HEADER_LENGTH = 7 // EPD, security header type, MAC, sequence number
+)
+
+// NGKSI_NO_KEY is the NAS key set identifier value meaning no key is
+// available (TS 24.501 9.11.3.32)
+const NGKSI_NO_KEY = 0x07
+
+// PDU is a 5GMM NAS PDU (TS 24.501 9.1). A security protected PDU carries
+// the message authentication code and sequence number of the plain 5GMM
+// message it wraps; a plain PDU is the message itself.
+type PDU struct {
+	SecurityHeaderType uint8
+	MAC                uint32
+	SequenceNumber     uint8
+	Message            []byte // Plain 5GMM message, ciphered for header types 2 and 4
+}
+
+// Message is a plain 5GMM message
+type Message interface {
+	MessageType() uint8
+	Marshal() []byte
+}
+
+// NewPDU wraps a message in a plain NAS PDU
+func NewPDU(msg Message) *PDU {
+	return &PDU{
+		SecurityHeaderType: SECURITY_HEADER_PLAIN,
+		Message:            msg.Marshal(),
+	}
+}
+
+// ParsePDU decodes a 5GMM NAS PDU, splitting off the security protected
+// header when present
+func ParsePDU(data []byte) (*PDU, error) {
+	if len(data) < PLAIN_HEADER_LENGTH {
+		return nil, fmt.Errorf("nas pdu too short: %d octets", len(data))
+	}
+	if data[0] != EPD_5GMM {
+		return nil, fmt.Errorf("unsupported extended protocol discriminator: 0x%02x", data[0])
+	}
+
+	pdu := &PDU{SecurityHeaderType: data[1] & 0x0F}
+	switch pdu.SecurityHeaderType {
+	case SECURITY_HEADER_PLAIN:
+		pdu.Message = data
+	case SECURITY_HEADER_INTEGRITY_PROTECTED,
+		SECURITY_HEADER_INTEGRITY_PROTECTED_CIPHERED,
+		SECURITY_HEADER_INTEGRITY_PROTECTED_NEW_CONTEXT,
+		SECURITY_HEADER_INTEGRITY_PROTECTED_CIPHERED_NEW_CONTEXT:
+		if len(data) < PROTECTED_HEADER_LENGTH+PLAIN_HEADER_LENGTH {
+			return nil, fmt.Errorf("security protected nas pdu too short: %d octets", len(data))
+		}
+		pdu.MAC = binary.BigEndian.Uint32(data[2:6])
+		pdu.SequenceNumber = data[6]
+		pdu.Message = data[PROTECTED_HEADER_LENGTH:]
+	default:
+		return nil, fmt.Errorf("unsupported security header type: %d", pdu.SecurityHeaderType)
+	}
+
+	return pdu, nil
+}
+
+// Marshal encodes the PDU, prefixing the security protected header unless
+// the PDU is plain
+func (p *PDU) Marshal() []byte {
+	if !p.IsProtected() {
+		return append([]byte(nil), p.Message...)
+	}
+
+	buf := make([]byte, PROTECTED_HEADER_LENGTH, PROTECTED_HEADER_LENGTH+len(p.Message))
+	buf[0] = EPD_5GMM
+	buf[1] = p.SecurityHeaderType & 0x0F
+	binary.BigEndian.PutUint32(buf[2:6], p.MAC)
+	buf[6] = p.SequenceNumber
+	return append(buf, p.Message...)
+}
+
+// IsProtected reports whether the PDU carries a security protected header
+func (p *PDU) IsProtected() bool {
+	return p.SecurityHeaderType != SECURITY_HEADER_PLAIN
+}
+
+// IsCiphered reports whether the message of the PDU is ciphered
+func (p *PDU) IsCiphered() bool {
+	return p.SecurityHeaderType == SECURITY_HEADER_INTEGRITY_PROTECTED_CIPHERED ||
+		p.SecurityHeaderType == SECURITY_HEADER_INTEGRITY_PROTECTED_CIPHERED_NEW_CONTEXT
+}
+
+// MessageType returns the type of the plain message the PDU carries, or 0
+// when it is ciphered
+func (p *PDU) MessageType() uint8 {
+	if p.IsCiphered() || len(p.Message) < PLAIN_HEADER_LENGTH {
+		return 0
+	}
+	return p.Message[2]
+}
+
+// Decode decodes the plain message carried by the PDU
+func (p *PDU) Decode() (Message, error) {
+	if p.IsCiphered() {
+		return nil, fmt.Errorf("nas message is ciphered")
+	}
+	return Decode(p.Message)
+}
+
+// Decode decodes a plain 5GMM message
+func Decode(data []byte) (Message, error) {
+	if len(data) < PLAIN_HEADER_LENGTH {
+		return nil, fmt.Errorf("nas message too short: %d octets", len(data))
+	}
+	if data[0] != EPD_5GMM {
+		return nil, fmt.Errorf("unsupported extended protocol discriminator: 0x%02x", data[0])
+	}
+	if data[1]&0x0F != SECURITY_HEADER_PLAIN {
+		return nil, fmt.Errorf("nas message is security protected")
+	}
+
+	body := data[PLAIN_HEADER_LENGTH:]
+	switch data[2] {
+	case MSG_REGISTRATION_REQUEST:
+		return parseRegistrationRequest(body)
+	case MSG_REGISTRATION_ACCEPT:
+		return parseRegistrationAccept(body)
+	case MSG_AUTHENTICATION_REQUEST:
+		return parseAuthenticationRequest(body)
+	case MSG_AUTHENTICATION_RESPONSE:
+		return parseAuthenticationResponse(body)
+	case MSG_SECURITY_MODE_COMMAND:
+		return parseSecurityModeCommand(body)
+	case MSG_SECURITY_MODE_COMPLETE:
+		return parseSecurityModeComplete(body)
+	default:
+		return nil, fmt.Errorf("unsupported 5gmm message type: 0x%02x", data[2])
+	}
+}
+
+// newMessage starts a plain 5GMM message of the given type
+func newMessage(msgType uint8) []byte {
+	return []byte{EPD_5GMM, SECURITY_HEADER_PLAIN, msgType}
+}
+
+// appendLV appends a type 4 value with a one octet length
+func appendLV(buf, value []byte) []byte {
+	buf = append(buf, byte(len(value)))
+	return append(buf, value...)
+}
+
+// appendTLV appends an optional type 4 IE
+func appendTLV(buf []byte, iei uint8, value []byte) []byte {
+	return appendLV(append(buf, iei), value)
+}
+
+// appendTLVE appends an optional type 6 IE, which has a two octet length
+func appendTLVE(buf []byte, iei uint8, value []byte) []byte {
+	buf = append(buf, iei, byte(len(value)>>8), byte(len(value)))
+	return append(buf, value...)
+}
+
+// readLV reads a type 4 value at offset, returning it and the offset after it
+func readLV(data []byte, offset int) ([]byte, int, error) {
+	if offset >= len(data) {
+		return nil, 0, fmt.Errorf("nas ie truncated at octet %d", offset)
+	}
+	end := offset + 1 + int(data[offset])
+	if end > len(data) {
+		return nil, 0, fmt.Errorf("nas ie length %d exceeds buffer", data[offset])
+	}
+	return data[offset+1 : end], end, nil
+}
+
+// readLVE reads a type 6 value at offset, returning it and the offset after it
+func readLVE(data []byte, offset int) ([]byte, int, error) {
+	if offset+2 > len(data) {
+		return nil, 0, fmt.Errorf("nas ie truncated at octet %d", offset)
+	}
+	length := int(binary.BigEndian.Uint16(data[offset : offset+2]))
+	end := offset + 2 + length
+	if end > len(data) {
+		return nil, 0, fmt.Errorf("nas ie length %d exceeds buffer", length)
+	}
+	return data[offset+2 : end], end, nil
+}
+
+// parseOptionalIEs walks the optional IEs of a message. The format of an IE
+// follows from its IEI (TS 24.007 11.2.4): IEIs from 0x80 are type 1 with
+// the value in the low half octet, 0x70-0x7F are type 6, and the rest are
+// type 4 unless fixed lists them as type 3 with a fixed value length. fn
+// gets the IEI, with the value half octet masked off for type 1 IEs.
+func parseOptionalIEs(data []byte, fixed map[uint8]int, fn func(iei uint8, value []byte) error) error {
+	offset := 0
+	for offset < len(data) {
+		iei := data[offset]
+		var value []byte
+		var err error
+
+		switch {
+		case iei >= 0x80:
+			value = []byte{iei & 0x0F}
+			iei &= 0xF0
+			offset++
+		case iei&0xF0 == 0x70:
+			value, offset, err = readLVE(data, offset+1)
+		default:
+			if n, ok := fixed[iei]; ok {
+				if offset+1+n > len(data) {
+					return fmt.Errorf("nas ie 0x%02x truncated", iei)
+				}
+				value = data[offset+1 : offset+1+n]
+				offset += 1 + n
+			} else {
+				value, offset, err = readLV(data, offset+1)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("nas ie 0x%02x: %w", iei, err)
+		}
+
+		if err := fn(iei, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clone copies an IE value out of the decoded buffer
+func clone(value []byte) []byte {
+	return append([]byte(nil), value...)
+}
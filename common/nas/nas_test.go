@@ -0,0 +1,283 @@
+package nas
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loadVector reads a hex encoded test vector, ignoring comments and whitespace
+func loadVector(t *testing.T, name string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+
+	var hexStr strings.Builder
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hexStr.WriteString(strings.ReplaceAll(line, " ", ""))
+	}
+
+	raw, err := hex.DecodeString(hexStr.String())
+	require.NoError(t, err)
+	return raw
+}
+
+// decodeVector parses a vector and decodes its message. Ciphered vectors
+// use 5G-EA0, so their message is readable as is.
+func decodeVector(t *testing.T, name string) (*PDU, Message) {
+	t.Helper()
+
+	pdu, err := ParsePDU(loadVector(t, name))
+	require.NoError(t, err)
+	msg, err := Decode(pdu.Message)
+	require.NoError(t, err)
+	return pdu, msg
+}
+
+func TestConformance_RoundTrip(t *testing.T) {
+	vectors := []string{
+		"registration_request.hex",
+		"authentication_request.hex",
+		"authentication_response.hex",
+		"security_mode_command.hex",
+		"security_mode_complete.hex",
+		"registration_accept.hex",
+	}
+
+	for _, name := range vectors {
+		t.Run(name, func(t *testing.T) {
+			raw := loadVector(t, name)
+
+			pdu, msg := decodeVector(t, name)
+			assert.Equal(t, raw, pdu.Marshal())
+
+			// Re-encoding the decoded message yields identical bytes
+			pdu.Message = msg.Marshal()
+			assert.Equal(t, raw, pdu.Marshal())
+		})
+	}
+}
+
+func TestConformance_RegistrationRequest(t *testing.T) {
+	pdu, msg := decodeVector(t, "registration_request.hex")
+	assert.False(t, pdu.IsProtected())
+	assert.Equal(t, uint8(MSG_REGISTRATION_REQUEST), pdu.MessageType())
+
+	req, ok := msg.(*RegistrationRequest)
+	require.True(t, ok)
+	assert.Equal(t, uint8(REGISTRATION_TYPE_INITIAL), req.RegistrationType)
+	assert.True(t, req.FollowOnRequest)
+	assert.Equal(t, uint8(NGKSI_NO_KEY), req.NgKSI)
+	assert.Equal(t, []byte{0xF0, 0xF0}, req.UESecurityCapability)
+	assert.Equal(t, []SNSSAI{{SST: 1, SD: []byte{0, 0, 1}}}, req.RequestedNSSAI)
+
+	suci, err := req.MobileIdentity.SUCI()
+	require.NoError(t, err)
+	assert.Equal(t, "suci-0-001-01-0000-0-0-0000000001", suci)
+
+	// Building the same message from structs yields identical bytes
+	identity, err := NewSUCIMobileIdentity(suci)
+	require.NoError(t, err)
+	built := &RegistrationRequest{
+		RegistrationType:     REGISTRATION_TYPE_INITIAL,
+		FollowOnRequest:      true,
+		NgKSI:                NGKSI_NO_KEY,
+		MobileIdentity:       identity,
+		UESecurityCapability: []byte{0xF0, 0xF0},
+		RequestedNSSAI:       []SNSSAI{{SST: 1, SD: []byte{0, 0, 1}}},
+	}
+	assert.Equal(t, loadVector(t, "registration_request.hex"), NewPDU(built).Marshal())
+}
+
+func TestConformance_AuthenticationRequest(t *testing.T) {
+	_, msg := decodeVector(t, "authentication_request.hex")
+
+	req, ok := msg.(*AuthenticationRequest)
+	require.True(t, ok)
+	assert.Equal(t, uint8(0), req.NgKSI)
+	assert.Equal(t, []byte{0, 0}, req.ABBA)
+	assert.Equal(t, "0123456789abcdef0123456789abcdef", hex.EncodeToString(req.RAND))
+	assert.Equal(t, "fedcba9876543210fedcba9876543210", hex.EncodeToString(req.AUTN))
+}
+
+func TestConformance_AuthenticationResponse(t *testing.T) {
+	_, msg := decodeVector(t, "authentication_response.hex")
+
+	resp, ok := msg.(*AuthenticationResponse)
+	require.True(t, ok)
+	assert.Equal(t, "00112233445566778899aabbccddeeff", hex.EncodeToString(resp.RESStar))
+}
+
+func TestConformance_SecurityModeCommand(t *testing.T) {
+	pdu, msg := decodeVector(t, "security_mode_command.hex")
+	assert.Equal(t, uint8(SECURITY_HEADER_INTEGRITY_PROTECTED_NEW_CONTEXT), pdu.SecurityHeaderType)
+	assert.Equal(t, uint32(0xA1B2C3D4), pdu.MAC)
+	assert.Equal(t, uint8(0), pdu.SequenceNumber)
+	assert.False(t, pdu.IsCiphered())
+	assert.Equal(t, uint8(MSG_SECURITY_MODE_COMMAND), pdu.MessageType())
+
+	cmd, ok := msg.(*SecurityModeCommand)
+	require.True(t, ok)
+	assert.Equal(t, uint8(0), cmd.CipheringAlgorithm)
+	assert.Equal(t, uint8(2), cmd.IntegrityAlgorithm)
+	assert.Equal(t, []byte{0xF0, 0xF0}, cmd.ReplayedUESecurityCapability)
+	assert.True(t, cmd.IMEISVRequest)
+	assert.Nil(t, cmd.ABBA)
+}
+
+func TestConformance_SecurityModeComplete(t *testing.T) {
+	pdu, msg := decodeVector(t, "security_mode_complete.hex")
+	assert.True(t, pdu.IsCiphered())
+	assert.Equal(t, uint8(0), pdu.MessageType(), "ciphered messages have no readable type")
+	_, err := pdu.Decode()
+	assert.Error(t, err)
+
+	complete, ok := msg.(*SecurityModeComplete)
+	require.True(t, ok)
+	require.NotNil(t, complete.IMEISV)
+	assert.Equal(t, uint8(MOBILE_IDENTITY_IMEISV), complete.IMEISV.Type())
+
+	inner, err := Decode(complete.NASMessageContainer)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(MSG_REGISTRATION_REQUEST), inner.MessageType())
+}
+
+func TestConformance_RegistrationAccept(t *testing.T) {
+	pdu, msg := decodeVector(t, "registration_accept.hex")
+	assert.Equal(t, uint8(SECURITY_HEADER_INTEGRITY_PROTECTED_CIPHERED), pdu.SecurityHeaderType)
+	assert.Equal(t, uint32(0x11223344), pdu.MAC)
+	assert.Equal(t, uint8(1), pdu.SequenceNumber)
+
+	accept, ok := msg.(*RegistrationAccept)
+	require.True(t, ok)
+	assert.Equal(t, uint8(REGISTRATION_RESULT_3GPP_ACCESS), accept.RegistrationResult)
+	assert.True(t, accept.SMSAllowed)
+	assert.Equal(t, []SNSSAI{{SST: 1, SD: []byte{0, 0, 1}}}, accept.AllowedNSSAI)
+	assert.Equal(t, time.Hour, accept.T3512)
+
+	require.NotNil(t, accept.GUTI)
+	guti, err := accept.GUTI.GUTI()
+	require.NoError(t, err)
+	assert.Equal(t, &GUTI{
+		MCC:         "001",
+		MNC:         "01",
+		AMFRegionID: 0x80,
+		AMFSetID:    4,
+		AMFPointer:  1,
+		TMSI:        0x12345678,
+	}, guti)
+
+	identity, err := NewGUTIMobileIdentity(*guti)
+	require.NoError(t, err)
+	assert.Equal(t, accept.GUTI.Value, identity.Value)
+}
+
+func TestMobileIdentity_SUCI(t *testing.T) {
+	tests := []string{
+		"suci-0-001-01-0-0-0-0000000001",
+		"suci-0-310-410-1234-0-0-123456789",
+		"suci-0-208-93-0000-1-1-b2e92f836055a255837debf850b528997ce0201cb82adfe4be1f587d07d8457dcb02352410cddd9e730ef3fa87",
+	}
+
+	for _, s := range tests {
+		identity, err := NewSUCIMobileIdentity(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, uint8(MOBILE_IDENTITY_SUCI), identity.Type())
+
+		decoded, err := identity.SUCI()
+		require.NoError(t, err, s)
+		assert.Equal(t, s, decoded)
+	}
+
+	_, err := NewSUCIMobileIdentity("imsi-001010000000001")
+	assert.Error(t, err)
+}
+
+func TestGPRSTimer3(t *testing.T) {
+	tests := []struct {
+		timer   time.Duration
+		encoded uint8
+	}{
+		{4 * time.Second, 0x62},
+		{5 * time.Minute, 0x8A},
+		{54 * time.Minute, 0x06},
+		{3 * time.Hour, 0x12},
+		{20 * time.Hour, 0x34},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.encoded, EncodeGPRSTimer3(tt.timer), tt.timer)
+	}
+
+	// 54 minutes has no exact encoding and rounds up to the next step
+	assert.Equal(t, time.Hour, DecodeGPRSTimer3(EncodeGPRSTimer3(54*time.Minute)))
+	assert.Equal(t, time.Duration(0), DecodeGPRSTimer3(0xE0), "deactivated")
+}
+
+func TestParse_Malformed(t *testing.T) {
+	raw := loadVector(t, "registration_request.hex")
+
+	// Truncated header
+	_, err := ParsePDU(raw[:2])
+	assert.Error(t, err)
+
+	// 5GSM PDUs are not 5GMM
+	bad := append([]byte(nil), raw...)
+	bad[0] = EPD_5GSM
+	_, err = ParsePDU(bad)
+	assert.Error(t, err)
+
+	// Mobile identity length beyond the buffer
+	_, err = Decode(raw[:10])
+	assert.Error(t, err)
+
+	// Optional IE length beyond the buffer
+	_, err = Decode(raw[:len(raw)-2])
+	assert.Error(t, err)
+
+	// Unknown security header type
+	bad = append([]byte(nil), raw...)
+	bad[1] = 0x05
+	_, err = ParsePDU(bad)
+	assert.Error(t, err)
+
+	// Security protected header without a message behind it
+	_, err = ParsePDU([]byte{EPD_5GMM, SECURITY_HEADER_INTEGRITY_PROTECTED, 0, 0, 0, 0, 0})
+	assert.Error(t, err)
+
+	// Unsupported message type
+	_, err = Decode([]byte{EPD_5GMM, SECURITY_HEADER_PLAIN, 0x44})
+	assert.Error(t, err)
+}
+
+func TestDecode_SkipsUnknownIEs(t *testing.T) {
+	raw := loadVector(t, "registration_request.hex")
+
+	// 5GMM capability (type 4), MICO indication (type 1) and EPS NAS
+	// message container (type 6) are not decoded but must be skipped
+	extended := append(append([]byte(nil), raw...),
+		0x10, 0x01, 0x03,
+		0xB1,
+		0x70, 0x00, 0x02, 0xAA, 0xBB,
+	)
+
+	msg, err := Decode(extended)
+	require.NoError(t, err)
+	req, ok := msg.(*RegistrationRequest)
+	require.True(t, ok)
+	assert.Equal(t, []SNSSAI{{SST: 1, SD: []byte{0, 0, 1}}}, req.RequestedNSSAI)
+}
@@ -0,0 +1,165 @@
+package nas
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// 5G NAS ciphering algorithms (TS 33.501 5.11.1.1)
+const (
+	NEA0 = 0 // Null ciphering
+	NEA1 = 1 // 128-NEA1, SNOW 3G
+	NEA2 = 2 // 128-NEA2, AES
+	NEA3 = 3 // 128-NEA3, ZUC
+)
+
+// 5G NAS integrity algorithms (TS 33.501 5.11.1.2)
+const (
+	NIA0 = 0 // Null integrity
+	NIA1 = 1 // 128-NIA1, SNOW 3G
+	NIA2 = 2 // 128-NIA2, AES
+	NIA3 = 3 // 128-NIA3, ZUC
+)
+
+// Direction input of the security algorithms (TS 33.501 D.2.1)
+const (
+	DIRECTION_UPLINK   = 0
+	DIRECTION_DOWNLINK = 1
+)
+
+// BEARER_3GPP is the bearer input for NAS over 3GPP access: the NAS
+// connection identifier (TS 33.501 6.4.3.1)
+const BEARER_3GPP = 0
+
+// KEY_LENGTH is the length of a 128-bit NAS algorithm key
+const KEY_LENGTH = 16
+
+// ErrUnsupportedAlgorithm is returned for NEA and NIA algorithms that are
+// not implemented
+var ErrUnsupportedAlgorithm = errors.New("unsupported NAS security algorithm")
+
+// Cipher ciphers or deciphers data with a NEA algorithm. NEA0 returns the
+// data unchanged; 128-NEA2 is AES-128 in counter mode (TS 33.401 B.1.3).
+func Cipher(algorithm uint8, key []byte, count uint32, bearer, direction uint8, data []byte) ([]byte, error) {
+	switch algorithm {
+	case NEA0:
+		return append([]byte(nil), data...), nil
+	case NEA2:
+		block, err := newAlgorithmCipher(key)
+		if err != nil {
+			return nil, err
+		}
+
+		// Counter block: COUNT || BEARER || DIRECTION || 0^26, then 64 zero bits
+		iv := make([]byte, aes.BlockSize)
+		binary.BigEndian.PutUint32(iv[0:4], count)
+		iv[4] = (bearer&0x1F)<<3 | (direction&0x01)<<2
+
+		out := make([]byte, len(data))
+		cipher.NewCTR(block, iv).XORKeyStream(out, data)
+		return out, nil
+	default:
+		return nil, fmt.Errorf("%w: NEA%d", ErrUnsupportedAlgorithm, algorithm)
+	}
+}
+
+// ComputeMAC computes the 32-bit message authentication code of data with
+// a NIA algorithm. NIA0 yields a zero MAC; 128-NIA2 is AES-128-CMAC over
+// COUNT || BEARER || DIRECTION || 0^26 || data (TS 33.401 B.2.3).
+func ComputeMAC(algorithm uint8, key []byte, count uint32, bearer, direction uint8, data []byte) (uint32, error) {
+	switch algorithm {
+	case NIA0:
+		return 0, nil
+	case NIA2:
+		block, err := newAlgorithmCipher(key)
+		if err != nil {
+			return 0, err
+		}
+
+		msg := make([]byte, 8, 8+len(data))
+		binary.BigEndian.PutUint32(msg[0:4], count)
+		msg[4] = (bearer&0x1F)<<3 | (direction&0x01)<<2
+		msg = append(msg, data...)
+
+		return binary.BigEndian.Uint32(cmac(block, msg)[:4]), nil
+	default:
+		return 0, fmt.Errorf("%w: NIA%d", ErrUnsupportedAlgorithm, algorithm)
+	}
+}
+
+// VerifyMAC reports whether mac is the NIA MAC of data, in constant time
+func VerifyMAC(algorithm uint8, key []byte, count uint32, bearer, direction uint8, data []byte, mac uint32) (bool, error) {
+	expected, err := ComputeMAC(algorithm, key, count, bearer, direction, data)
+	if err != nil {
+		return false, err
+	}
+
+	var a, b [4]byte
+	binary.BigEndian.PutUint32(a[:], expected)
+	binary.BigEndian.PutUint32(b[:], mac)
+	return subtle.ConstantTimeCompare(a[:], b[:]) == 1, nil
+}
+
+func newAlgorithmCipher(key []byte) (cipher.Block, error) {
+	if len(key) != KEY_LENGTH {
+		return nil, fmt.Errorf("nas algorithm key must be %d octets, got %d", KEY_LENGTH, len(key))
+	}
+	return aes.NewCipher(key)
+}
+
+// cmac computes AES-CMAC (RFC 4493)
+func cmac(block cipher.Block, msg []byte) []byte {
+	k1, k2 := cmacSubkeys(block)
+
+	n := (len(msg) + aes.BlockSize - 1) / aes.BlockSize
+	complete := n > 0 && len(msg)%aes.BlockSize == 0
+	if n == 0 {
+		n = 1
+	}
+
+	last := make([]byte, aes.BlockSize)
+	tail := msg[(n-1)*aes.BlockSize:]
+	if complete {
+		subtle.XORBytes(last, tail, k1)
+	} else {
+		copy(last, tail)
+		last[len(tail)] = 0x80
+		subtle.XORBytes(last, last, k2)
+	}
+
+	x := make([]byte, aes.BlockSize)
+	for i := 0; i < n-1; i++ {
+		subtle.XORBytes(x, x, msg[i*aes.BlockSize:(i+1)*aes.BlockSize])
+		block.Encrypt(x, x)
+	}
+	subtle.XORBytes(x, x, last)
+	block.Encrypt(x, x)
+	return x
+}
+
+// cmacSubkeys derives the CMAC subkeys K1 and K2 (RFC 4493 2.3)
+func cmacSubkeys(block cipher.Block) ([]byte, []byte) {
+	l := make([]byte, aes.BlockSize)
+	block.Encrypt(l, l)
+	k1 := shiftLeft(l)
+	k2 := shiftLeft(k1)
+	return k1, k2
+}
+
+// shiftLeft shifts a block left by one bit, applying the CMAC constant
+// Rb when the most significant bit is shifted out
+func shiftLeft(in []byte) []byte {
+	out := make([]byte, len(in))
+	for i := 0; i < len(in)-1; i++ {
+		out[i] = in[i]<<1 | in[i+1]>>7
+	}
+	out[len(in)-1] = in[len(in)-1] << 1
+	if in[0]&0x80 != 0 {
+		out[len(in)-1] ^= 0x87
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+package nas
+
+import (
+	"crypto/aes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+func TestCMAC_RFC4493(t *testing.T) {
+	block, err := aes.NewCipher(decodeHex(t, "2b7e151628aed2a6abf7158809cf4f3c"))
+	require.NoError(t, err)
+
+	message := decodeHex(t, "6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e51"+
+		"30c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710")
+
+	tests := []struct {
+		length int
+		mac    string
+	}{
+		{0, "bb1d6929e95937287fa37d129b756746"},
+		{16, "070a16b46b4d4144f79bdd9dd04a287c"},
+		{40, "dfa66747de9ae63030ca32611497c827"},
+		{64, "51f0bebf7e3b9d92fc49741779363cfe"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.mac, hex.EncodeToString(cmac(block, message[:tt.length])), "length %d", tt.length)
+	}
+}
+
+// 128-EEA2 test set 1 (TS 33.401 C.1). The message is 253 bits long, so
+// the three low bits of the last octet are not compared.
+func TestCipher_NEA2(t *testing.T) {
+	key := decodeHex(t, "d3c5d592327fb11c4035c6680af8c6d1")
+	plaintext := decodeHex(t, "981ba6824c1bfb1ab485472029b71d808ce33e2cc3c0b5fc1f3de8a6dc66b1f0")
+	expected := decodeHex(t, "e9fed8a63d155304d71df20bf3e82214b20ed7dad2f233dc3c22d7bdeeed8e78")
+
+	ciphertext, err := Cipher(NEA2, key, 0x398a59b4, 0x15, DIRECTION_DOWNLINK, plaintext)
+	require.NoError(t, err)
+	last := len(expected) - 1
+	assert.Equal(t, expected[:last], ciphertext[:last])
+	assert.Equal(t, expected[last]&0xF8, ciphertext[last]&0xF8)
+
+	// Counter mode deciphers with the same operation
+	deciphered, err := Cipher(NEA2, key, 0x398a59b4, 0x15, DIRECTION_DOWNLINK, ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, plaintext, deciphered)
+}
+
+// 128-EIA2 test set 2 (TS 33.401 C.2)
+func TestComputeMAC_NIA2(t *testing.T) {
+	key := decodeHex(t, "d3c5d592327fb11c4035c6680af8c6d1")
+	message := decodeHex(t, "484583d5afe082ae")
+
+	mac, err := ComputeMAC(NIA2, key, 0x398a59b4, 0x1a, DIRECTION_DOWNLINK, message)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0xb93787e6), mac)
+
+	ok, err := VerifyMAC(NIA2, key, 0x398a59b4, 0x1a, DIRECTION_DOWNLINK, message, mac)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// Any change to the COUNT, direction or message fails verification
+	ok, _ = VerifyMAC(NIA2, key, 0x398a59b5, 0x1a, DIRECTION_DOWNLINK, message, mac)
+	assert.False(t, ok)
+	ok, _ = VerifyMAC(NIA2, key, 0x398a59b4, 0x1a, DIRECTION_UPLINK, message, mac)
+	assert.False(t, ok)
+	tampered := append([]byte(nil), message...)
+	tampered[0] ^= 0x01
+	ok, _ = VerifyMAC(NIA2, key, 0x398a59b4, 0x1a, DIRECTION_DOWNLINK, tampered, mac)
+	assert.False(t, ok)
+}
+
+func TestNullAlgorithms(t *testing.T) {
+	data := []byte{0x7e, 0x00, 0x5e}
+
+	out, err := Cipher(NEA0, nil, 1, BEARER_3GPP, DIRECTION_UPLINK, data)
+	require.NoError(t, err)
+	assert.Equal(t, data, out)
+
+	mac, err := ComputeMAC(NIA0, nil, 1, BEARER_3GPP, DIRECTION_UPLINK, data)
+	require.NoError(t, err)
+	assert.Zero(t, mac)
+}
+
+func TestUnsupportedAlgorithms(t *testing.T) {
+	key := make([]byte, KEY_LENGTH)
+
+	_, err := Cipher(NEA1, key, 0, BEARER_3GPP, DIRECTION_UPLINK, nil)
+	assert.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+	_, err = ComputeMAC(NIA3, key, 0, BEARER_3GPP, DIRECTION_UPLINK, nil)
+	assert.ErrorIs(t, err, ErrUnsupportedAlgorithm)
+
+	_, err = ComputeMAC(NIA2, key[:8], 0, BEARER_3GPP, DIRECTION_UPLINK, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,87 @@
+package netutil
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultReadPollInterval bounds how long a read blocks before the context
+// is checked again
+const DefaultReadPollInterval = 100 * time.Millisecond
+
+// Bounds of the backoff after a failed read, so a persistent socket error
+// does not spin the read loop
+const (
+	minReadErrorBackoff = 10 * time.Millisecond
+	maxReadErrorBackoff = time.Second
+)
+
+// UDPHandler processes a received datagram. The data buffer is reused once
+// the handler returns.
+type UDPHandler func(data []byte, addr *net.UDPAddr)
+
+// ServeUDP reads datagrams from conn and passes them to handler until ctx is
+// cancelled. Reads use deadlines so cancellation is noticed within
+// DefaultReadPollInterval even when no packets arrive. Other read errors are
+// logged and retried with backoff; ServeUDP returns when conn is closed.
+// ServeUDP owns conn and closes it before returning.
+func ServeUDP(ctx context.Context, conn *net.UDPConn, bufferSize int, handler UDPHandler, logger *zap.Logger) error {
+	defer conn.Close()
+
+	buffer := make([]byte, bufferSize)
+	var backoff time.Duration
+
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if err := conn.SetReadDeadline(time.Now().Add(DefaultReadPollInterval)); err != nil {
+			return err
+		}
+
+		n, addr, err := conn.ReadFromUDP(buffer)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return err
+			}
+
+			backoff = nextReadErrorBackoff(backoff)
+			logger.Error("Failed to read UDP datagram",
+				zap.String("local", conn.LocalAddr().String()),
+				zap.Duration("retry_in", backoff),
+				zap.Error(err))
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(backoff):
+			}
+			continue
+		}
+		backoff = 0
+
+		handler(buffer[:n], addr)
+	}
+}
+
+// nextReadErrorBackoff doubles the backoff after a failed read, within
+// minReadErrorBackoff and maxReadErrorBackoff
+func nextReadErrorBackoff(backoff time.Duration) time.Duration {
+	backoff *= 2
+	if backoff < minReadErrorBackoff {
+		return minReadErrorBackoff
+	}
+	if backoff > maxReadErrorBackoff {
+		return maxReadErrorBackoff
+	}
+	return backoff
+}
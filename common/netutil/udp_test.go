@@ -0,0 +1,107 @@
+package netutil
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func listenUDP(t *testing.T) *net.UDPConn {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	return conn
+}
+
+func TestServeUDPExitsOnCancelWithoutTraffic(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	conn := listenUDP(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- ServeUDP(ctx, conn, 1500, func([]byte, *net.UDPAddr) {}, logger)
+	}()
+
+	// Let the loop block in a read
+	time.Sleep(50 * time.Millisecond)
+
+	cancelled := time.Now()
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+		assert.Less(t, time.Since(cancelled), 2*DefaultReadPollInterval+50*time.Millisecond)
+	case <-time.After(time.Second):
+		t.Fatal("ServeUDP did not exit after context cancellation")
+	}
+
+	// The socket is closed once the loop returns
+	_, err := conn.WriteToUDP([]byte{0}, conn.LocalAddr().(*net.UDPAddr))
+	assert.True(t, errors.Is(err, net.ErrClosed))
+}
+
+func TestServeUDPDeliversDatagrams(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	conn := listenUDP(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan string, 1)
+	go ServeUDP(ctx, conn, 1500, func(data []byte, addr *net.UDPAddr) {
+		received <- string(data)
+	}, logger)
+
+	client, err := net.DialUDP("udp", nil, conn.LocalAddr().(*net.UDPAddr))
+	require.NoError(t, err)
+	defer client.Close()
+
+	_, err = client.Write([]byte("ping"))
+	require.NoError(t, err)
+
+	select {
+	case data := <-received:
+		assert.Equal(t, "ping", data)
+	case <-time.After(time.Second):
+		t.Fatal("datagram not delivered")
+	}
+}
+
+func TestServeUDPReturnsWhenSocketClosed(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	conn := listenUDP(t)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- ServeUDP(context.Background(), conn, 1500, func([]byte, *net.UDPAddr) {}, logger)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, conn.Close())
+
+	select {
+	case err := <-done:
+		assert.True(t, errors.Is(err, net.ErrClosed))
+	case <-time.After(time.Second):
+		t.Fatal("ServeUDP did not exit after socket close")
+	}
+}
+
+func TestNextReadErrorBackoff(t *testing.T) {
+	backoff := nextReadErrorBackoff(0)
+	assert.Equal(t, minReadErrorBackoff, backoff)
+
+	backoff = nextReadErrorBackoff(backoff)
+	assert.Equal(t, 2*minReadErrorBackoff, backoff)
+
+	assert.Equal(t, maxReadErrorBackoff, nextReadErrorBackoff(maxReadErrorBackoff))
+}
@@ -0,0 +1,430 @@
+package ngap
+
+import (
+	"fmt"
+
+	"github.com/your-org/5g-network/common/per"
+)
+
+// NGAP elementary procedure codes (TS 38.413 9.4.7)
+const (
+	PROCEDURE_DOWNLINK_NAS_TRANSPORT = 4
+	PROCEDURE_INITIAL_CONTEXT_SETUP  = 14
+	PROCEDURE_INITIAL_UE_MESSAGE     = 15
+	PROCEDURE_NG_SETUP               = 21
+	PROCEDURE_UPLINK_NAS_TRANSPORT   = 46
+)
+
+// NGAP-PDU choices (TS 38.413 9.4.2); the PDU is an extensible CHOICE
+const (
+	pduInitiatingMessage   = 0
+	pduSuccessfulOutcome   = 1
+	pduUnsuccessfulOutcome = 2
+	pduChoices             = 3
+	criticalityValues      = 3
+)
+
+// Message is an NGAP message that Encode and Decode support
+type Message interface {
+	MessageType() int
+}
+
+func (*NGSetupRequest) MessageType() int              { return NGAP_NG_SETUP_REQUEST }
+func (*NGSetupResponse) MessageType() int             { return NGAP_NG_SETUP_RESPONSE }
+func (*InitialUEMessage) MessageType() int            { return NGAP_INITIAL_UE_MESSAGE }
+func (*DownlinkNASTransport) MessageType() int        { return NGAP_DOWNLINK_NAS_TRANSPORT }
+func (*UplinkNASTransport) MessageType() int          { return NGAP_UPLINK_NAS_TRANSPORT }
+func (*InitialContextSetupRequest) MessageType() int  { return NGAP_INITIAL_CONTEXT_SETUP_REQUEST }
+func (*InitialContextSetupResponse) MessageType() int { return NGAP_INITIAL_CONTEXT_SETUP_RESPONSE }
+
+// procedure locates a message in the NGAP-PDU
+type procedure struct {
+	pdu         int
+	code        uint8
+	criticality uint8
+}
+
+var procedures = map[int]procedure{
+	NGAP_NG_SETUP_REQUEST:               {pduInitiatingMessage, PROCEDURE_NG_SETUP, per.CRITICALITY_REJECT},
+	NGAP_NG_SETUP_RESPONSE:              {pduSuccessfulOutcome, PROCEDURE_NG_SETUP, per.CRITICALITY_REJECT},
+	NGAP_INITIAL_UE_MESSAGE:             {pduInitiatingMessage, PROCEDURE_INITIAL_UE_MESSAGE, per.CRITICALITY_IGNORE},
+	NGAP_DOWNLINK_NAS_TRANSPORT:         {pduInitiatingMessage, PROCEDURE_DOWNLINK_NAS_TRANSPORT, per.CRITICALITY_IGNORE},
+	NGAP_UPLINK_NAS_TRANSPORT:           {pduInitiatingMessage, PROCEDURE_UPLINK_NAS_TRANSPORT, per.CRITICALITY_IGNORE},
+	NGAP_INITIAL_CONTEXT_SETUP_REQUEST:  {pduInitiatingMessage, PROCEDURE_INITIAL_CONTEXT_SETUP, per.CRITICALITY_REJECT},
+	NGAP_INITIAL_CONTEXT_SETUP_RESPONSE: {pduSuccessfulOutcome, PROCEDURE_INITIAL_CONTEXT_SETUP, per.CRITICALITY_REJECT},
+}
+
+// Encode encodes an NGAP message as an aligned PER NGAP-PDU (TS 38.413 9.4)
+func Encode(msg Message) ([]byte, error) {
+	proc, ok := procedures[msg.MessageType()]
+	if !ok {
+		return nil, fmt.Errorf("unsupported ngap message type %d", msg.MessageType())
+	}
+	ies, err := messageIEs(msg)
+	if err != nil {
+		return nil, fmt.Errorf("ngap message type %d: %w", msg.MessageType(), err)
+	}
+
+	var w per.Writer
+	if err := w.PutExtConstrained(uint64(proc.pdu), 0, pduChoices-1); err != nil {
+		return nil, err
+	}
+	if err := w.PutConstrained(uint64(proc.code), 0, 255); err != nil {
+		return nil, err
+	}
+	if err := w.PutConstrained(uint64(proc.criticality), 0, criticalityValues-1); err != nil {
+		return nil, err
+	}
+	err = w.PutOpenType(func(w *per.Writer) error {
+		per.PutSequence(w)
+		return per.PutContainer(w, ies, 0)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ngap message type %d: %w", msg.MessageType(), err)
+	}
+	return w.Bytes(), nil
+}
+
+// Decode decodes an aligned PER NGAP-PDU
+func Decode(data []byte) (Message, error) {
+	r := per.NewReader(data)
+	pdu, err := r.GetExtConstrained(0, pduChoices-1)
+	if err != nil {
+		return nil, err
+	}
+	code, err := r.GetConstrained(0, 255)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.GetConstrained(0, criticalityValues-1); err != nil {
+		return nil, err
+	}
+	value, err := r.GetOpenType()
+	if err != nil {
+		return nil, err
+	}
+	if r.Remaining() != 0 {
+		return nil, fmt.Errorf("ngap pdu has %d trailing octets", r.Remaining())
+	}
+
+	msgType := -1
+	for t, proc := range procedures {
+		if proc.pdu == int(pdu) && proc.code == uint8(code) {
+			msgType = t
+		}
+	}
+	if msgType < 0 {
+		return nil, fmt.Errorf("unsupported ngap procedure %d in pdu choice %d", code, pdu)
+	}
+
+	if _, err := per.GetSequence(value, 0); err != nil {
+		return nil, err
+	}
+	ies, err := per.GetContainer(value, 0)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := decodeMessage(msgType, ies)
+	if err != nil {
+		return nil, fmt.Errorf("ngap message type %d: %w", msgType, err)
+	}
+	return msg, nil
+}
+
+// messageIEs lists the protocol IEs of a message
+func messageIEs(msg Message) ([]per.ProtocolIE, error) {
+	switch m := msg.(type) {
+	case *NGSetupRequest:
+		ies := []per.ProtocolIE{
+			{ID: IE_GLOBAL_RAN_NODE_ID, Criticality: per.CRITICALITY_REJECT, Encode: func(w *per.Writer) error {
+				return putGlobalRANNodeID(w, m.GlobalRANNodeID)
+			}},
+		}
+		if m.RANNodeName != "" {
+			ies = append(ies, nameIE(IE_RAN_NODE_NAME, per.CRITICALITY_IGNORE, m.RANNodeName))
+		}
+		ies = append(ies,
+			per.ProtocolIE{ID: IE_SUPPORTED_TA_LIST, Criticality: per.CRITICALITY_REJECT, Encode: func(w *per.Writer) error {
+				return per.PutList(w, m.SupportedTAs, 1, maxnoofTACs, putSupportedTA)
+			}},
+			per.ProtocolIE{ID: IE_DEFAULT_PAGING_DRX, Criticality: per.CRITICALITY_IGNORE, Encode: func(w *per.Writer) error {
+				return per.PutEnumerated(w, m.DefaultPagingDRX, pagingDRXValues, true)
+			}},
+		)
+		return ies, nil
+
+	case *NGSetupResponse:
+		return []per.ProtocolIE{
+			nameIE(IE_AMF_NAME, per.CRITICALITY_REJECT, m.AMFName),
+			{ID: IE_SERVED_GUAMI_LIST, Criticality: per.CRITICALITY_REJECT, Encode: func(w *per.Writer) error {
+				return per.PutList(w, m.ServedGUAMIs, 1, maxnoofServedGUAMIs, putServedGUAMI)
+			}},
+			{ID: IE_RELATIVE_AMF_CAPACITY, Criticality: per.CRITICALITY_IGNORE, Encode: func(w *per.Writer) error {
+				return w.PutConstrained(uint64(m.RelativeAMFCapacity), 0, maxRelativeAMFCapacity)
+			}},
+			{ID: IE_PLMN_SUPPORT_LIST, Criticality: per.CRITICALITY_REJECT, Encode: func(w *per.Writer) error {
+				return per.PutList(w, m.PLMNSupportList, 1, maxnoofPLMNs, putPLMNSupport)
+			}},
+		}, nil
+
+	case *InitialUEMessage:
+		ies := []per.ProtocolIE{
+			ranUENGAPIDIE(per.CRITICALITY_REJECT, m.RANUENGAPID),
+			nasPDUIE(per.CRITICALITY_REJECT, m.NASPDU),
+			userLocationIE(per.CRITICALITY_REJECT, m.UserLocation),
+			{ID: IE_RRC_ESTABLISHMENT_CAUSE, Criticality: per.CRITICALITY_IGNORE, Encode: func(w *per.Writer) error {
+				return per.PutEnumerated(w, m.RRCEstablishmentCause, rrcEstablishmentCauseValues, true)
+			}},
+		}
+		if m.FiveGSTMSI != nil {
+			ies = append(ies, per.ProtocolIE{ID: IE_FIVEG_S_TMSI, Criticality: per.CRITICALITY_REJECT, Encode: func(w *per.Writer) error {
+				return putFiveGSTMSI(w, m.FiveGSTMSI)
+			}})
+		}
+		if m.UEContextRequested {
+			ies = append(ies, per.ProtocolIE{ID: IE_UE_CONTEXT_REQUEST, Criticality: per.CRITICALITY_IGNORE, Encode: func(w *per.Writer) error {
+				// UEContextRequest ENUMERATED {requested, ...}
+				return w.PutExtConstrained(ueContextRequestRequested, 0, 0)
+			}})
+		}
+		return ies, nil
+
+	case *DownlinkNASTransport:
+		return []per.ProtocolIE{
+			amfUENGAPIDIE(per.CRITICALITY_REJECT, m.AMFUENGAPID),
+			ranUENGAPIDIE(per.CRITICALITY_REJECT, m.RANUENGAPID),
+			nasPDUIE(per.CRITICALITY_REJECT, m.NASPDU),
+		}, nil
+
+	case *UplinkNASTransport:
+		return []per.ProtocolIE{
+			amfUENGAPIDIE(per.CRITICALITY_REJECT, m.AMFUENGAPID),
+			ranUENGAPIDIE(per.CRITICALITY_REJECT, m.RANUENGAPID),
+			nasPDUIE(per.CRITICALITY_REJECT, m.NASPDU),
+			userLocationIE(per.CRITICALITY_IGNORE, m.UserLocation),
+		}, nil
+
+	case *InitialContextSetupRequest:
+		ies := []per.ProtocolIE{
+			amfUENGAPIDIE(per.CRITICALITY_REJECT, m.AMFUENGAPID),
+			ranUENGAPIDIE(per.CRITICALITY_REJECT, m.RANUENGAPID),
+			{ID: IE_GUAMI, Criticality: per.CRITICALITY_REJECT, Encode: func(w *per.Writer) error {
+				return putGUAMI(w, m.GUAMI)
+			}},
+			{ID: IE_ALLOWED_NSSAI, Criticality: per.CRITICALITY_REJECT, Encode: func(w *per.Writer) error {
+				return per.PutList(w, m.AllowedNSSAI, 1, maxnoofAllowedSNSSAIs, func(w *per.Writer, slice *SNSSAI) error {
+					// Allowed NSSAI Item SEQUENCE {s-NSSAI, iE-Extensions, ...}
+					per.PutSequence(w, false)
+					return putSNSSAI(w, slice)
+				})
+			}},
+			{ID: IE_UE_SECURITY_CAPABILITIES, Criticality: per.CRITICALITY_REJECT, Encode: func(w *per.Writer) error {
+				return putUESecurityCapabilities(w, m.UESecurityCapabilities)
+			}},
+			{ID: IE_SECURITY_KEY, Criticality: per.CRITICALITY_REJECT, Encode: func(w *per.Writer) error {
+				return w.PutBitString(m.SecurityKey, 8*len(m.SecurityKey), securityKeyLength, securityKeyLength)
+			}},
+		}
+		if len(m.NASPDU) > 0 {
+			ies = append(ies, nasPDUIE(per.CRITICALITY_IGNORE, m.NASPDU))
+		}
+		return ies, nil
+
+	case *InitialContextSetupResponse:
+		return []per.ProtocolIE{
+			amfUENGAPIDIE(per.CRITICALITY_IGNORE, m.AMFUENGAPID),
+			ranUENGAPIDIE(per.CRITICALITY_IGNORE, m.RANUENGAPID),
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported message")
+	}
+}
+
+func nameIE(id uint16, criticality uint8, name string) per.ProtocolIE {
+	return per.ProtocolIE{ID: id, Criticality: criticality, Encode: func(w *per.Writer) error {
+		return putName(w, name)
+	}}
+}
+
+func amfUENGAPIDIE(criticality uint8, id uint64) per.ProtocolIE {
+	return per.ProtocolIE{ID: IE_AMF_UE_NGAP_ID, Criticality: criticality, Encode: func(w *per.Writer) error {
+		return w.PutConstrained(id, 0, maxAMFUENGAPID)
+	}}
+}
+
+func ranUENGAPIDIE(criticality uint8, id uint32) per.ProtocolIE {
+	return per.ProtocolIE{ID: IE_RAN_UE_NGAP_ID, Criticality: criticality, Encode: func(w *per.Writer) error {
+		return w.PutConstrained(uint64(id), 0, maxRANUENGAPID)
+	}}
+}
+
+func nasPDUIE(criticality uint8, pdu []byte) per.ProtocolIE {
+	return per.ProtocolIE{ID: IE_NAS_PDU, Criticality: criticality, Encode: func(w *per.Writer) error {
+		return w.PutOctetString(pdu, 0, -1)
+	}}
+}
+
+func userLocationIE(criticality uint8, loc *UserLocationInformationNR) per.ProtocolIE {
+	return per.ProtocolIE{ID: IE_USER_LOCATION_INFORMATION, Criticality: criticality, Encode: func(w *per.Writer) error {
+		return putUserLocation(w, loc)
+	}}
+}
+
+// decodeMessage builds a message from its protocol IEs. Unknown IEs are
+// skipped; missing mandatory IEs are an error.
+func decodeMessage(msgType int, ies []per.DecodedIE) (Message, error) {
+	var msg Message
+	var mandatory []uint16
+	var decode func(ie per.DecodedIE) error
+
+	switch msgType {
+	case NGAP_NG_SETUP_REQUEST:
+		m := &NGSetupRequest{}
+		msg, mandatory = m, []uint16{IE_GLOBAL_RAN_NODE_ID, IE_SUPPORTED_TA_LIST, IE_DEFAULT_PAGING_DRX}
+		decode = func(ie per.DecodedIE) (err error) {
+			switch ie.ID {
+			case IE_GLOBAL_RAN_NODE_ID:
+				m.GlobalRANNodeID, err = getGlobalRANNodeID(ie.Value)
+			case IE_RAN_NODE_NAME:
+				m.RANNodeName, err = getName(ie.Value)
+			case IE_SUPPORTED_TA_LIST:
+				m.SupportedTAs, err = per.GetList(ie.Value, 1, maxnoofTACs, getSupportedTA)
+			case IE_DEFAULT_PAGING_DRX:
+				m.DefaultPagingDRX, err = per.GetEnumerated(ie.Value, pagingDRXValues, true)
+			}
+			return err
+		}
+
+	case NGAP_NG_SETUP_RESPONSE:
+		m := &NGSetupResponse{}
+		msg, mandatory = m, []uint16{IE_AMF_NAME, IE_SERVED_GUAMI_LIST, IE_RELATIVE_AMF_CAPACITY, IE_PLMN_SUPPORT_LIST}
+		decode = func(ie per.DecodedIE) (err error) {
+			switch ie.ID {
+			case IE_AMF_NAME:
+				m.AMFName, err = getName(ie.Value)
+			case IE_SERVED_GUAMI_LIST:
+				m.ServedGUAMIs, err = per.GetList(ie.Value, 1, maxnoofServedGUAMIs, getServedGUAMI)
+			case IE_RELATIVE_AMF_CAPACITY:
+				var capacity uint64
+				capacity, err = ie.Value.GetConstrained(0, maxRelativeAMFCapacity)
+				m.RelativeAMFCapacity = uint8(capacity)
+			case IE_PLMN_SUPPORT_LIST:
+				m.PLMNSupportList, err = per.GetList(ie.Value, 1, maxnoofPLMNs, getPLMNSupport)
+			}
+			return err
+		}
+
+	case NGAP_INITIAL_UE_MESSAGE:
+		m := &InitialUEMessage{}
+		msg, mandatory = m, []uint16{IE_RAN_UE_NGAP_ID, IE_NAS_PDU, IE_USER_LOCATION_INFORMATION, IE_RRC_ESTABLISHMENT_CAUSE}
+		decode = func(ie per.DecodedIE) (err error) {
+			switch ie.ID {
+			case IE_RAN_UE_NGAP_ID:
+				m.RANUENGAPID, err = getRANUENGAPID(ie.Value)
+			case IE_NAS_PDU:
+				m.NASPDU, err = ie.Value.GetOctetString(0, -1)
+			case IE_USER_LOCATION_INFORMATION:
+				m.UserLocation, err = getUserLocation(ie.Value)
+			case IE_RRC_ESTABLISHMENT_CAUSE:
+				m.RRCEstablishmentCause, err = per.GetEnumerated(ie.Value, rrcEstablishmentCauseValues, true)
+			case IE_FIVEG_S_TMSI:
+				m.FiveGSTMSI, err = getFiveGSTMSI(ie.Value)
+			case IE_UE_CONTEXT_REQUEST:
+				_, err = ie.Value.GetExtConstrained(0, 0)
+				m.UEContextRequested = err == nil
+			}
+			return err
+		}
+
+	case NGAP_DOWNLINK_NAS_TRANSPORT:
+		m := &DownlinkNASTransport{}
+		msg, mandatory = m, nasTransportMandatoryIEs
+		decode = func(ie per.DecodedIE) error {
+			return getNASTransportIE(ie, &m.AMFUENGAPID, &m.RANUENGAPID, &m.NASPDU)
+		}
+	case NGAP_UPLINK_NAS_TRANSPORT:
+		m := &UplinkNASTransport{}
+		msg, mandatory = m, []uint16{IE_AMF_UE_NGAP_ID, IE_RAN_UE_NGAP_ID, IE_NAS_PDU, IE_USER_LOCATION_INFORMATION}
+		decode = func(ie per.DecodedIE) (err error) {
+			if ie.ID == IE_USER_LOCATION_INFORMATION {
+				m.UserLocation, err = getUserLocation(ie.Value)
+				return err
+			}
+			return getNASTransportIE(ie, &m.AMFUENGAPID, &m.RANUENGAPID, &m.NASPDU)
+		}
+
+	case NGAP_INITIAL_CONTEXT_SETUP_REQUEST:
+		m := &InitialContextSetupRequest{}
+		msg, mandatory = m, []uint16{
+			IE_AMF_UE_NGAP_ID, IE_RAN_UE_NGAP_ID, IE_GUAMI, IE_ALLOWED_NSSAI,
+			IE_UE_SECURITY_CAPABILITIES, IE_SECURITY_KEY,
+		}
+		decode = func(ie per.DecodedIE) (err error) {
+			switch ie.ID {
+			case IE_AMF_UE_NGAP_ID:
+				m.AMFUENGAPID, err = getAMFUENGAPID(ie.Value)
+			case IE_RAN_UE_NGAP_ID:
+				m.RANUENGAPID, err = getRANUENGAPID(ie.Value)
+			case IE_GUAMI:
+				m.GUAMI, err = getGUAMI(ie.Value)
+			case IE_ALLOWED_NSSAI:
+				m.AllowedNSSAI, err = per.GetList(ie.Value, 1, maxnoofAllowedSNSSAIs, getSNSSAIItem)
+			case IE_UE_SECURITY_CAPABILITIES:
+				m.UESecurityCapabilities, err = getUESecurityCapabilities(ie.Value)
+			case IE_SECURITY_KEY:
+				m.SecurityKey, _, err = ie.Value.GetBitString(securityKeyLength, securityKeyLength)
+			case IE_NAS_PDU:
+				m.NASPDU, err = ie.Value.GetOctetString(0, -1)
+			}
+			return err
+		}
+
+	case NGAP_INITIAL_CONTEXT_SETUP_RESPONSE:
+		m := &InitialContextSetupResponse{}
+		msg, mandatory = m, []uint16{IE_AMF_UE_NGAP_ID, IE_RAN_UE_NGAP_ID}
+		decode = func(ie per.DecodedIE) (err error) {
+			switch ie.ID {
+			case IE_AMF_UE_NGAP_ID:
+				m.AMFUENGAPID, err = getAMFUENGAPID(ie.Value)
+			case IE_RAN_UE_NGAP_ID:
+				m.RANUENGAPID, err = getRANUENGAPID(ie.Value)
+			}
+			return err
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported message")
+	}
+
+	seen := make(map[uint16]bool, len(ies))
+	for _, ie := range ies {
+		if seen[ie.ID] {
+			return nil, fmt.Errorf("duplicate ie %d", ie.ID)
+		}
+		seen[ie.ID] = true
+		if err := decode(ie); err != nil {
+			return nil, fmt.Errorf("ie %d: %w", ie.ID, err)
+		}
+	}
+	for _, id := range mandatory {
+		if !seen[id] {
+			return nil, fmt.Errorf("missing mandatory ie %d", id)
+		}
+	}
+	return msg, nil
+}
+
+var nasTransportMandatoryIEs = []uint16{IE_AMF_UE_NGAP_ID, IE_RAN_UE_NGAP_ID, IE_NAS_PDU}
+
+func getNASTransportIE(ie per.DecodedIE, amfUEID *uint64, ranUEID *uint32, nasPDU *[]byte) (err error) {
+	switch ie.ID {
+	case IE_AMF_UE_NGAP_ID:
+		*amfUEID, err = getAMFUENGAPID(ie.Value)
+	case IE_RAN_UE_NGAP_ID:
+		*ranUEID, err = getRANUENGAPID(ie.Value)
+	case IE_NAS_PDU:
+		*nasPDU, err = ie.Value.GetOctetString(0, -1)
+	}
+	return err
+}
@@ -0,0 +1,238 @@
+package ngap
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loadVector reads a hex encoded test vector, ignoring comments and whitespace
+func loadVector(t *testing.T, name string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+
+	var hexStr strings.Builder
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hexStr.WriteString(strings.ReplaceAll(line, " ", ""))
+	}
+
+	raw, err := hex.DecodeString(hexStr.String())
+	require.NoError(t, err)
+	return raw
+}
+
+// roundTrip encodes and decodes a message, and checks that re-encoding the
+// decoded message gives the same octets
+func roundTrip(t *testing.T, msg Message) Message {
+	t.Helper()
+
+	data, err := Encode(msg)
+	require.NoError(t, err)
+	decoded, err := Decode(data)
+	require.NoError(t, err)
+	require.Equal(t, msg.MessageType(), decoded.MessageType())
+
+	again, err := Encode(decoded)
+	require.NoError(t, err)
+	assert.Equal(t, data, again)
+	return decoded
+}
+
+func testPLMN() *PLMNID {
+	return &PLMNID{MCC: "001", MNC: "01"}
+}
+
+func testUserLocation() *UserLocationInformationNR {
+	return &UserLocationInformationNR{
+		NRCGI: &NRCGI{PLMNID: testPLMN(), NRCellID: 0x000000010},
+		TAI:   &TAI{PLMNID: testPLMN(), TAC: 1},
+	}
+}
+
+func testGUAMI() *GUAMI {
+	return &GUAMI{PLMNID: testPLMN(), AMFRegionID: 0xCA, AMFSetID: 0x3F8, AMFPointer: 0x3F}
+}
+
+func TestDownlinkNASTransport_Vector(t *testing.T) {
+	vector := loadVector(t, "downlink_nas_transport.hex")
+	msg := &DownlinkNASTransport{AMFUENGAPID: 1, RANUENGAPID: 2, NASPDU: []byte{0x7e, 0x00, 0x56}}
+
+	data, err := Encode(msg)
+	require.NoError(t, err)
+	assert.Equal(t, vector, data)
+
+	decoded, err := Decode(vector)
+	require.NoError(t, err)
+	assert.Equal(t, msg, decoded)
+}
+
+func TestNGSetup_RoundTrip(t *testing.T) {
+	req := &NGSetupRequest{
+		GlobalRANNodeID: &GlobalGNBID{PLMNID: testPLMN(), GNBID: 0x000001, GNBIDLength: 22},
+		RANNodeName:     "gnb-1",
+		SupportedTAs: []*SupportedTA{{
+			TAC: 1,
+			BroadcastPLMNs: []*BroadcastPLMN{{
+				PLMNID:           testPLMN(),
+				SliceSupportList: []*SNSSAI{{SST: 1}, {SST: 1, SD: []byte{0x01, 0x02, 0x03}}},
+			}},
+		}},
+		DefaultPagingDRX: 128,
+	}
+	assert.Equal(t, req, roundTrip(t, req))
+
+	// A 32-bit gNB ID fills the bit string
+	req.GlobalRANNodeID = &GlobalGNBID{PLMNID: &PLMNID{MCC: "310", MNC: "410"}, GNBID: 0xFFFFFFFF, GNBIDLength: 32}
+	req.RANNodeName = ""
+	assert.Equal(t, req, roundTrip(t, req))
+
+	resp := &NGSetupResponse{
+		AMFName:             "amf-1",
+		ServedGUAMIs:        []*GUAMI{testGUAMI()},
+		RelativeAMFCapacity: 255,
+		PLMNSupportList: []*PLMNSupport{{
+			PLMNID:           testPLMN(),
+			SliceSupportList: []*SNSSAI{{SST: 1}},
+		}},
+	}
+	assert.Equal(t, resp, roundTrip(t, resp))
+}
+
+func TestInitialUEMessage_RoundTrip(t *testing.T) {
+	msg := &InitialUEMessage{
+		RANUENGAPID:           1,
+		NASPDU:                []byte{0x7e, 0x00, 0x41, 0x79, 0x00, 0x0d, 0x01},
+		UserLocation:          testUserLocation(),
+		RRCEstablishmentCause: "mo-Signalling",
+	}
+	assert.Equal(t, msg, roundTrip(t, msg))
+
+	msg.FiveGSTMSI = &FiveGSTMSI{AMFSetID: 0x3F8, AMFPointer: 1, FiveGTMSI: 0xC0000001}
+	msg.UEContextRequested = true
+	msg.RANUENGAPID = 1<<32 - 1
+	assert.Equal(t, msg, roundTrip(t, msg))
+}
+
+func TestNASTransport_RoundTrip(t *testing.T) {
+	dl := &DownlinkNASTransport{AMFUENGAPID: 1<<40 - 1, RANUENGAPID: 7, NASPDU: bytes.Repeat([]byte{0xa5}, 300)}
+	assert.Equal(t, dl, roundTrip(t, dl))
+
+	ul := &UplinkNASTransport{AMFUENGAPID: 1, RANUENGAPID: 7, NASPDU: []byte{0x7e, 0x00, 0x43}, UserLocation: testUserLocation()}
+	assert.Equal(t, ul, roundTrip(t, ul))
+}
+
+func TestInitialContextSetup_RoundTrip(t *testing.T) {
+	req := &InitialContextSetupRequest{
+		AMFUENGAPID:  1,
+		RANUENGAPID:  2,
+		GUAMI:        testGUAMI(),
+		AllowedNSSAI: []*SNSSAI{{SST: 1, SD: []byte{0x00, 0x00, 0x01}}},
+		UESecurityCapabilities: &UESecurityCapabilities{
+			NREncryptionAlgorithms:          0xE000,
+			NRIntegrityProtectionAlgorithms: 0xE000,
+		},
+		SecurityKey: bytes.Repeat([]byte{0x5a}, 32),
+		NASPDU:      []byte{0x7e, 0x02, 0x42},
+	}
+	assert.Equal(t, req, roundTrip(t, req))
+
+	resp := &InitialContextSetupResponse{AMFUENGAPID: 1, RANUENGAPID: 2}
+	assert.Equal(t, resp, roundTrip(t, resp))
+}
+
+func TestEncode_InvalidMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+	}{
+		{"missing global ran node id", &NGSetupRequest{DefaultPagingDRX: 32}},
+		{"gnb id too short", &NGSetupRequest{GlobalRANNodeID: &GlobalGNBID{PLMNID: testPLMN(), GNBIDLength: 21}}},
+		{"gnb id over its length", &NGSetupRequest{GlobalRANNodeID: &GlobalGNBID{PLMNID: testPLMN(), GNBID: 1 << 22, GNBIDLength: 22}}},
+		{"unsupported paging drx", &NGSetupRequest{
+			GlobalRANNodeID:  &GlobalGNBID{PLMNID: testPLMN(), GNBIDLength: 22},
+			SupportedTAs:     []*SupportedTA{{TAC: 1, BroadcastPLMNs: []*BroadcastPLMN{{PLMNID: testPLMN(), SliceSupportList: []*SNSSAI{{SST: 1}}}}}},
+			DefaultPagingDRX: 100,
+		}},
+		{"no served guamis", &NGSetupResponse{AMFName: "amf-1", PLMNSupportList: []*PLMNSupport{{PLMNID: testPLMN()}}}},
+		{"amf set id over 10 bits", &NGSetupResponse{AMFName: "amf-1", ServedGUAMIs: []*GUAMI{{PLMNID: testPLMN(), AMFSetID: 1 << 10}}}},
+		{"unsupported establishment cause", &InitialUEMessage{UserLocation: testUserLocation(), RRCEstablishmentCause: "mo-Unknown"}},
+		{"missing user location", &UplinkNASTransport{}},
+		{"tac over 24 bits", &InitialUEMessage{UserLocation: &UserLocationInformationNR{
+			NRCGI: testUserLocation().NRCGI, TAI: &TAI{PLMNID: testPLMN(), TAC: 1 << 24},
+		}, RRCEstablishmentCause: "mo-Signalling"}},
+		{"security key too short", &InitialContextSetupRequest{
+			GUAMI: testGUAMI(), AllowedNSSAI: []*SNSSAI{{SST: 1}},
+			UESecurityCapabilities: &UESecurityCapabilities{}, SecurityKey: make([]byte, 16),
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Encode(tt.msg)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestDecode_Malformed(t *testing.T) {
+	vector := loadVector(t, "downlink_nas_transport.hex")
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"truncated header", vector[:3]},
+		{"truncated value", vector[:len(vector)-1]},
+		{"trailing octets", append(append([]byte(nil), vector...), 0x00)},
+		{"pdu extension", func() []byte {
+			b := append([]byte(nil), vector...)
+			b[0] = 0x80
+			return b
+		}()},
+		{"unknown procedure", func() []byte {
+			b := append([]byte(nil), vector...)
+			b[1] = 0x63
+			return b
+		}()},
+		{"missing mandatory ie", func() []byte {
+			// Drop the NAS-PDU IE and shorten the lengths to match
+			b := append([]byte(nil), vector[:len(vector)-8]...)
+			b[3] -= 8
+			b[6] = 2
+			return b
+		}()},
+		{"duplicate ie", func() []byte {
+			// Repeat AMF UE NGAP ID in place of RAN UE NGAP ID
+			b := append([]byte(nil), vector...)
+			b[14] = 0x0a
+			return b
+		}()},
+		{"ie length beyond value", func() []byte {
+			b := append([]byte(nil), vector...)
+			b[10] = 0x7f
+			return b
+		}()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(tt.data)
+			assert.Error(t, err)
+		})
+	}
+}
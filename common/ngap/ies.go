@@ -0,0 +1,610 @@
+package ngap
+
+import (
+	"fmt"
+
+	"github.com/your-org/5g-network/common/per"
+)
+
+// NGAP protocol IE identifiers (TS 38.413 9.4.7)
+const (
+	IE_ALLOWED_NSSAI             = 0
+	IE_AMF_NAME                  = 1
+	IE_AMF_UE_NGAP_ID            = 10
+	IE_CAUSE                     = 15
+	IE_DEFAULT_PAGING_DRX        = 21
+	IE_FIVEG_S_TMSI              = 26
+	IE_GLOBAL_RAN_NODE_ID        = 27
+	IE_GUAMI                     = 28
+	IE_NAS_PDU                   = 38
+	IE_PLMN_SUPPORT_LIST         = 80
+	IE_RAN_NODE_NAME             = 82
+	IE_RAN_UE_NGAP_ID            = 85
+	IE_RELATIVE_AMF_CAPACITY     = 86
+	IE_RRC_ESTABLISHMENT_CAUSE   = 90
+	IE_SECURITY_KEY              = 94
+	IE_SERVED_GUAMI_LIST         = 96
+	IE_SUPPORTED_TA_LIST         = 102
+	IE_UE_CONTEXT_REQUEST        = 112
+	IE_UE_SECURITY_CAPABILITIES  = 119
+	IE_USER_LOCATION_INFORMATION = 121
+)
+
+// Bounds from the NGAP ASN.1 (TS 38.413 9.4.5, 9.4.6)
+const (
+	maxnoofTACs               = 256
+	maxnoofBPLMNs             = 12
+	maxnoofSliceItems         = 1024
+	maxnoofServedGUAMIs       = 256
+	maxnoofPLMNs              = 12
+	maxnoofAllowedSNSSAIs     = 8
+	maxAMFUENGAPID            = 1<<40 - 1
+	maxRANUENGAPID            = 1<<32 - 1
+	maxNameLength             = 150
+	minGNBIDLength            = 22
+	maxGNBIDLength            = 32
+	nrCellIdentityLength      = 36
+	tacLength                 = 3
+	securityKeyLength         = 256
+	algorithmsLength          = 16
+	amfRegionIDLength         = 8
+	amfSetIDLength            = 10
+	amfPointerLength          = 6
+	fiveGTMSILength           = 4
+	globalRANNodeIDChoices    = 4 // gNB, ng-eNB, N3IWF, choice-extension
+	gnbIDChoices              = 2 // gNB-ID, choice-extension
+	userLocationInfoChoices   = 4 // E-UTRA, NR, N3IWF, choice-extension
+	globalRANNodeIDGNBChoice  = 0
+	userLocationInfoNRChoice  = 1
+	maxRelativeAMFCapacity    = 255
+	ueContextRequestRequested = 0
+)
+
+// Enumerations used by the IEs; extensible ones only support their root
+var (
+	pagingDRXValues             = []uint16{32, 64, 128, 256}
+	rrcEstablishmentCauseValues = []string{
+		"emergency", "highPriorityAccess", "mt-Access", "mo-Signalling", "mo-Data",
+		"mo-VoiceCall", "mo-VideoCall", "mo-SMS", "mps-PriorityAccess", "mcs-PriorityAccess",
+	}
+)
+
+// PLMN Identity (TS 38.413 9.3.3.5)
+func putPLMNIdentity(w *per.Writer, plmn *PLMNID) error {
+	if plmn == nil {
+		return fmt.Errorf("missing plmn identity")
+	}
+	return per.PutPLMNIdentity(w, plmn.MCC, plmn.MNC)
+}
+
+func getPLMNIdentity(r *per.Reader) (*PLMNID, error) {
+	mcc, mnc, err := per.GetPLMNIdentity(r)
+	if err != nil {
+		return nil, err
+	}
+	return &PLMNID{MCC: mcc, MNC: mnc}, nil
+}
+
+// putUint encodes the low n bits of v as a BIT STRING (SIZE(n))
+func putUint(w *per.Writer, v uint64, n int) error {
+	if n < 64 && v >= 1<<n {
+		return fmt.Errorf("%d exceeds %d bits", v, n)
+	}
+	v <<= 64 - n
+	b := make([]byte, (n+7)/8)
+	for i := range b {
+		b[i] = byte(v >> (56 - 8*i))
+	}
+	return w.PutBitString(b, n, n, n)
+}
+
+func getUint(r *per.Reader, n int) (uint64, error) {
+	b, _, err := r.GetBitString(n, n)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, o := range b {
+		v = v<<8 | uint64(o)
+	}
+	return v >> (8*len(b) - n), nil
+}
+
+// TAC, an OCTET STRING (SIZE(3)) (TS 38.413 9.3.3.10)
+func putTAC(w *per.Writer, tac uint32) error {
+	if tac >= 1<<24 {
+		return fmt.Errorf("tac %d exceeds 24 bits", tac)
+	}
+	return w.PutOctetString([]byte{byte(tac >> 16), byte(tac >> 8), byte(tac)}, tacLength, tacLength)
+}
+
+func getTAC(r *per.Reader) (uint32, error) {
+	b, err := r.GetOctetString(tacLength, tacLength)
+	if err != nil {
+		return 0, err
+	}
+	return uint32(b[0])<<16 | uint32(b[1])<<8 | uint32(b[2]), nil
+}
+
+// AMF Name and RAN Node Name, a PrintableString (SIZE(1..150,...))
+func putName(w *per.Writer, name string) error {
+	w.PutBool(false)
+	return w.PutOctetString([]byte(name), 1, maxNameLength)
+}
+
+func getName(r *per.Reader) (string, error) {
+	extended, err := r.GetBool()
+	if err != nil {
+		return "", err
+	}
+	if extended {
+		return "", fmt.Errorf("name outside its size root")
+	}
+	name, err := r.GetOctetString(1, maxNameLength)
+	return string(name), err
+}
+
+func getAMFUENGAPID(r *per.Reader) (uint64, error) {
+	return r.GetConstrained(0, maxAMFUENGAPID)
+}
+
+func getRANUENGAPID(r *per.Reader) (uint32, error) {
+	id, err := r.GetConstrained(0, maxRANUENGAPID)
+	return uint32(id), err
+}
+
+// Global RAN Node ID, only its gNB choice (TS 38.413 9.3.1.5)
+func putGlobalRANNodeID(w *per.Writer, id *GlobalGNBID) error {
+	if id == nil {
+		return fmt.Errorf("missing global ran node id")
+	}
+	if id.GNBIDLength < minGNBIDLength || id.GNBIDLength > maxGNBIDLength {
+		return fmt.Errorf("gnb id length %d outside %d..%d", id.GNBIDLength, minGNBIDLength, maxGNBIDLength)
+	}
+	if id.GNBIDLength < 32 && id.GNBID >= 1<<id.GNBIDLength {
+		return fmt.Errorf("gnb id %d exceeds %d bits", id.GNBID, id.GNBIDLength)
+	}
+	if err := w.PutConstrained(globalRANNodeIDGNBChoice, 0, globalRANNodeIDChoices-1); err != nil {
+		return err
+	}
+	per.PutSequence(w, false)
+	if err := putPLMNIdentity(w, id.PLMNID); err != nil {
+		return err
+	}
+	if err := w.PutConstrained(0, 0, gnbIDChoices-1); err != nil {
+		return err
+	}
+	v := id.GNBID << (32 - id.GNBIDLength)
+	return w.PutBitString([]byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)},
+		int(id.GNBIDLength), minGNBIDLength, maxGNBIDLength)
+}
+
+func getGlobalRANNodeID(r *per.Reader) (*GlobalGNBID, error) {
+	choice, err := r.GetConstrained(0, globalRANNodeIDChoices-1)
+	if err != nil {
+		return nil, err
+	}
+	if choice != globalRANNodeIDGNBChoice {
+		return nil, fmt.Errorf("unsupported global ran node id choice %d", choice)
+	}
+	present, err := per.GetSequence(r, 1)
+	if err != nil {
+		return nil, err
+	}
+	plmn, err := getPLMNIdentity(r)
+	if err != nil {
+		return nil, err
+	}
+	if choice, err = r.GetConstrained(0, gnbIDChoices-1); err != nil {
+		return nil, err
+	}
+	if choice != 0 {
+		return nil, fmt.Errorf("unsupported gnb id choice %d", choice)
+	}
+	b, n, err := r.GetBitString(minGNBIDLength, maxGNBIDLength)
+	if err != nil {
+		return nil, err
+	}
+	var v uint32
+	for _, o := range b {
+		v = v<<8 | uint32(o)
+	}
+	id := &GlobalGNBID{PLMNID: plmn, GNBID: v >> (8*len(b) - n), GNBIDLength: uint8(n)}
+	return id, per.SkipExtensions(r, present[0])
+}
+
+// Supported TA Item (TS 38.413 9.2.6.1)
+func putSupportedTA(w *per.Writer, ta *SupportedTA) error {
+	if ta == nil {
+		return fmt.Errorf("missing supported ta")
+	}
+	per.PutSequence(w, false)
+	if err := putTAC(w, ta.TAC); err != nil {
+		return err
+	}
+	return per.PutList(w, ta.BroadcastPLMNs, 1, maxnoofBPLMNs, putBroadcastPLMN)
+}
+
+func getSupportedTA(r *per.Reader) (*SupportedTA, error) {
+	present, err := per.GetSequence(r, 1)
+	if err != nil {
+		return nil, err
+	}
+	ta := &SupportedTA{}
+	if ta.TAC, err = getTAC(r); err != nil {
+		return nil, err
+	}
+	if ta.BroadcastPLMNs, err = per.GetList(r, 1, maxnoofBPLMNs, getBroadcastPLMN); err != nil {
+		return nil, err
+	}
+	return ta, per.SkipExtensions(r, present[0])
+}
+
+// Broadcast PLMN Item (TS 38.413 9.2.6.1)
+func putBroadcastPLMN(w *per.Writer, plmn *BroadcastPLMN) error {
+	if plmn == nil {
+		return fmt.Errorf("missing broadcast plmn")
+	}
+	return putPLMNSlices(w, plmn.PLMNID, plmn.SliceSupportList)
+}
+
+func getBroadcastPLMN(r *per.Reader) (*BroadcastPLMN, error) {
+	plmn, slices, err := getPLMNSlices(r)
+	if err != nil {
+		return nil, err
+	}
+	return &BroadcastPLMN{PLMNID: plmn, SliceSupportList: slices}, nil
+}
+
+// PLMN Support Item (TS 38.413 9.2.6.2)
+func putPLMNSupport(w *per.Writer, plmn *PLMNSupport) error {
+	if plmn == nil {
+		return fmt.Errorf("missing plmn support")
+	}
+	return putPLMNSlices(w, plmn.PLMNID, plmn.SliceSupportList)
+}
+
+func getPLMNSupport(r *per.Reader) (*PLMNSupport, error) {
+	plmn, slices, err := getPLMNSlices(r)
+	if err != nil {
+		return nil, err
+	}
+	return &PLMNSupport{PLMNID: plmn, SliceSupportList: slices}, nil
+}
+
+// putPLMNSlices encodes a PLMN with its Slice Support List, the shape of
+// both Broadcast PLMN and PLMN Support Items
+func putPLMNSlices(w *per.Writer, plmn *PLMNID, slices []*SNSSAI) error {
+	per.PutSequence(w, false)
+	if err := putPLMNIdentity(w, plmn); err != nil {
+		return err
+	}
+	return per.PutList(w, slices, 1, maxnoofSliceItems, func(w *per.Writer, slice *SNSSAI) error {
+		// Slice Support Item SEQUENCE {s-NSSAI, iE-Extensions, ...}
+		per.PutSequence(w, false)
+		return putSNSSAI(w, slice)
+	})
+}
+
+func getPLMNSlices(r *per.Reader) (*PLMNID, []*SNSSAI, error) {
+	present, err := per.GetSequence(r, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+	plmn, err := getPLMNIdentity(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	slices, err := per.GetList(r, 1, maxnoofSliceItems, getSNSSAIItem)
+	if err != nil {
+		return nil, nil, err
+	}
+	return plmn, slices, per.SkipExtensions(r, present[0])
+}
+
+// getSNSSAIItem decodes a Slice Support or Allowed NSSAI Item, an S-NSSAI
+// in an extensible SEQUENCE
+func getSNSSAIItem(r *per.Reader) (*SNSSAI, error) {
+	present, err := per.GetSequence(r, 1)
+	if err != nil {
+		return nil, err
+	}
+	slice, err := getSNSSAI(r)
+	if err != nil {
+		return nil, err
+	}
+	return slice, per.SkipExtensions(r, present[0])
+}
+
+// S-NSSAI (TS 38.413 9.3.1.24)
+func putSNSSAI(w *per.Writer, slice *SNSSAI) error {
+	if slice == nil {
+		return fmt.Errorf("missing s-nssai")
+	}
+	hasSD := len(slice.SD) > 0
+	per.PutSequence(w, hasSD, false)
+	if err := w.PutOctetString([]byte{slice.SST}, 1, 1); err != nil {
+		return err
+	}
+	if hasSD {
+		if err := w.PutOctetString(slice.SD, 3, 3); err != nil {
+			return fmt.Errorf("sd: %w", err)
+		}
+	}
+	return nil
+}
+
+func getSNSSAI(r *per.Reader) (*SNSSAI, error) {
+	present, err := per.GetSequence(r, 2)
+	if err != nil {
+		return nil, err
+	}
+	sst, err := r.GetOctetString(1, 1)
+	if err != nil {
+		return nil, err
+	}
+	slice := &SNSSAI{SST: sst[0]}
+	if present[0] {
+		if slice.SD, err = r.GetOctetString(3, 3); err != nil {
+			return nil, err
+		}
+	}
+	return slice, per.SkipExtensions(r, present[1])
+}
+
+// GUAMI (TS 38.413 9.3.3.3)
+func putGUAMI(w *per.Writer, guami *GUAMI) error {
+	if guami == nil {
+		return fmt.Errorf("missing guami")
+	}
+	per.PutSequence(w, false)
+	if err := putPLMNIdentity(w, guami.PLMNID); err != nil {
+		return err
+	}
+	if err := putUint(w, uint64(guami.AMFRegionID), amfRegionIDLength); err != nil {
+		return fmt.Errorf("amf region id: %w", err)
+	}
+	if err := putUint(w, uint64(guami.AMFSetID), amfSetIDLength); err != nil {
+		return fmt.Errorf("amf set id: %w", err)
+	}
+	if err := putUint(w, uint64(guami.AMFPointer), amfPointerLength); err != nil {
+		return fmt.Errorf("amf pointer: %w", err)
+	}
+	return nil
+}
+
+func getGUAMI(r *per.Reader) (*GUAMI, error) {
+	present, err := per.GetSequence(r, 1)
+	if err != nil {
+		return nil, err
+	}
+	guami := &GUAMI{}
+	if guami.PLMNID, err = getPLMNIdentity(r); err != nil {
+		return nil, err
+	}
+	region, err := getUint(r, amfRegionIDLength)
+	if err != nil {
+		return nil, err
+	}
+	set, err := getUint(r, amfSetIDLength)
+	if err != nil {
+		return nil, err
+	}
+	pointer, err := getUint(r, amfPointerLength)
+	if err != nil {
+		return nil, err
+	}
+	guami.AMFRegionID, guami.AMFSetID, guami.AMFPointer = uint8(region), uint16(set), uint8(pointer)
+	return guami, per.SkipExtensions(r, present[0])
+}
+
+// Served GUAMI Item (TS 38.413 9.2.6.2); no backup AMF name
+func putServedGUAMI(w *per.Writer, guami *GUAMI) error {
+	per.PutSequence(w, false, false)
+	return putGUAMI(w, guami)
+}
+
+func getServedGUAMI(r *per.Reader) (*GUAMI, error) {
+	present, err := per.GetSequence(r, 2)
+	if err != nil {
+		return nil, err
+	}
+	guami, err := getGUAMI(r)
+	if err != nil {
+		return nil, err
+	}
+	if present[0] {
+		if _, err := getName(r); err != nil {
+			return nil, err
+		}
+	}
+	return guami, per.SkipExtensions(r, present[1])
+}
+
+// NR CGI (TS 38.413 9.3.1.7)
+func putNRCGI(w *per.Writer, cgi *NRCGI) error {
+	if cgi == nil {
+		return fmt.Errorf("missing nr cgi")
+	}
+	per.PutSequence(w, false)
+	if err := putPLMNIdentity(w, cgi.PLMNID); err != nil {
+		return err
+	}
+	if err := putUint(w, cgi.NRCellID, nrCellIdentityLength); err != nil {
+		return fmt.Errorf("nr cell identity: %w", err)
+	}
+	return nil
+}
+
+func getNRCGI(r *per.Reader) (*NRCGI, error) {
+	present, err := per.GetSequence(r, 1)
+	if err != nil {
+		return nil, err
+	}
+	plmn, err := getPLMNIdentity(r)
+	if err != nil {
+		return nil, err
+	}
+	id, err := getUint(r, nrCellIdentityLength)
+	if err != nil {
+		return nil, err
+	}
+	return &NRCGI{PLMNID: plmn, NRCellID: id}, per.SkipExtensions(r, present[0])
+}
+
+// TAI (TS 38.413 9.3.3.11)
+func putTAI(w *per.Writer, tai *TAI) error {
+	if tai == nil {
+		return fmt.Errorf("missing tai")
+	}
+	per.PutSequence(w, false)
+	if err := putPLMNIdentity(w, tai.PLMNID); err != nil {
+		return err
+	}
+	return putTAC(w, tai.TAC)
+}
+
+func getTAI(r *per.Reader) (*TAI, error) {
+	present, err := per.GetSequence(r, 1)
+	if err != nil {
+		return nil, err
+	}
+	tai := &TAI{}
+	if tai.PLMNID, err = getPLMNIdentity(r); err != nil {
+		return nil, err
+	}
+	if tai.TAC, err = getTAC(r); err != nil {
+		return nil, err
+	}
+	return tai, per.SkipExtensions(r, present[0])
+}
+
+// User Location Information, only its NR choice and without a timestamp
+// (TS 38.413 9.3.1.16)
+func putUserLocation(w *per.Writer, loc *UserLocationInformationNR) error {
+	if loc == nil {
+		return fmt.Errorf("missing user location information")
+	}
+	if err := w.PutConstrained(userLocationInfoNRChoice, 0, userLocationInfoChoices-1); err != nil {
+		return err
+	}
+	per.PutSequence(w, false, false)
+	if err := putNRCGI(w, loc.NRCGI); err != nil {
+		return err
+	}
+	return putTAI(w, loc.TAI)
+}
+
+func getUserLocation(r *per.Reader) (*UserLocationInformationNR, error) {
+	choice, err := r.GetConstrained(0, userLocationInfoChoices-1)
+	if err != nil {
+		return nil, err
+	}
+	if choice != userLocationInfoNRChoice {
+		return nil, fmt.Errorf("unsupported user location information choice %d", choice)
+	}
+	present, err := per.GetSequence(r, 2)
+	if err != nil {
+		return nil, err
+	}
+	loc := &UserLocationInformationNR{}
+	if loc.NRCGI, err = getNRCGI(r); err != nil {
+		return nil, err
+	}
+	if loc.TAI, err = getTAI(r); err != nil {
+		return nil, err
+	}
+	if present[0] {
+		// TimeStamp OCTET STRING (SIZE(4))
+		if _, err := r.GetOctetString(4, 4); err != nil {
+			return nil, err
+		}
+	}
+	return loc, per.SkipExtensions(r, present[1])
+}
+
+// 5G-S-TMSI (TS 38.413 9.3.3.20)
+func putFiveGSTMSI(w *per.Writer, tmsi *FiveGSTMSI) error {
+	per.PutSequence(w, false)
+	if err := putUint(w, uint64(tmsi.AMFSetID), amfSetIDLength); err != nil {
+		return fmt.Errorf("amf set id: %w", err)
+	}
+	if err := putUint(w, uint64(tmsi.AMFPointer), amfPointerLength); err != nil {
+		return fmt.Errorf("amf pointer: %w", err)
+	}
+	t := tmsi.FiveGTMSI
+	return w.PutOctetString([]byte{byte(t >> 24), byte(t >> 16), byte(t >> 8), byte(t)}, fiveGTMSILength, fiveGTMSILength)
+}
+
+func getFiveGSTMSI(r *per.Reader) (*FiveGSTMSI, error) {
+	present, err := per.GetSequence(r, 1)
+	if err != nil {
+		return nil, err
+	}
+	set, err := getUint(r, amfSetIDLength)
+	if err != nil {
+		return nil, err
+	}
+	pointer, err := getUint(r, amfPointerLength)
+	if err != nil {
+		return nil, err
+	}
+	t, err := r.GetOctetString(fiveGTMSILength, fiveGTMSILength)
+	if err != nil {
+		return nil, err
+	}
+	tmsi := &FiveGSTMSI{
+		AMFSetID:   uint16(set),
+		AMFPointer: uint8(pointer),
+		FiveGTMSI:  uint32(t[0])<<24 | uint32(t[1])<<16 | uint32(t[2])<<8 | uint32(t[3]),
+	}
+	return tmsi, per.SkipExtensions(r, present[0])
+}
+
+// UE Security Capabilities (TS 38.413 9.3.1.86); each algorithm set is a
+// BIT STRING (SIZE(16,...))
+func putUESecurityCapabilities(w *per.Writer, caps *UESecurityCapabilities) error {
+	if caps == nil {
+		return fmt.Errorf("missing ue security capabilities")
+	}
+	per.PutSequence(w, false)
+	for _, algorithms := range []uint16{
+		caps.NREncryptionAlgorithms, caps.NRIntegrityProtectionAlgorithms,
+		caps.EUTRAEncryptionAlgorithms, caps.EUTRAIntegrityProtectionAlgorithms,
+	} {
+		w.PutBool(false)
+		if err := putUint(w, uint64(algorithms), algorithmsLength); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getUESecurityCapabilities(r *per.Reader) (*UESecurityCapabilities, error) {
+	present, err := per.GetSequence(r, 1)
+	if err != nil {
+		return nil, err
+	}
+	var algorithms [4]uint16
+	for i := range algorithms {
+		extended, err := r.GetBool()
+		if err != nil {
+			return nil, err
+		}
+		if extended {
+			return nil, fmt.Errorf("algorithms outside their size root")
+		}
+		v, err := getUint(r, algorithmsLength)
+		if err != nil {
+			return nil, err
+		}
+		algorithms[i] = uint16(v)
+	}
+	caps := &UESecurityCapabilities{
+		NREncryptionAlgorithms:             algorithms[0],
+		NRIntegrityProtectionAlgorithms:    algorithms[1],
+		EUTRAEncryptionAlgorithms:          algorithms[2],
+		EUTRAIntegrityProtectionAlgorithms: algorithms[3],
+	}
+	return caps, per.SkipExtensions(r, present[0])
+}
@@ -0,0 +1,156 @@
+// Package ngap encodes and decodes the NG Application Protocol messages
+// exchanged between the NG-RAN and the AMF over N2 (3GPP TS 38.413), and
+// carries them over SCTP (TS 38.412).
+package ngap
+
+// NGAP message types (3GPP TS 38.413)
+const (
+	NGAP_NG_SETUP_REQUEST               = 0
+	NGAP_NG_SETUP_RESPONSE              = 1
+	NGAP_NG_SETUP_FAILURE               = 2
+	NGAP_INITIAL_UE_MESSAGE             = 3
+	NGAP_DOWNLINK_NAS_TRANSPORT         = 4
+	NGAP_UPLINK_NAS_TRANSPORT           = 5
+	NGAP_INITIAL_CONTEXT_SETUP_REQUEST  = 6
+	NGAP_INITIAL_CONTEXT_SETUP_RESPONSE = 7
+	NGAP_INITIAL_CONTEXT_SETUP_FAILURE  = 8
+)
+
+// NGSetupRequest - NG-RAN node -> AMF
+type NGSetupRequest struct {
+	GlobalRANNodeID  *GlobalGNBID
+	RANNodeName      string
+	SupportedTAs     []*SupportedTA
+	DefaultPagingDRX uint16 // Radio frames: 32, 64, 128 or 256
+}
+
+// NGSetupResponse - AMF -> NG-RAN node
+type NGSetupResponse struct {
+	AMFName             string
+	ServedGUAMIs        []*GUAMI
+	RelativeAMFCapacity uint8
+	PLMNSupportList     []*PLMNSupport
+}
+
+// InitialUEMessage - NG-RAN node -> AMF, carrying the first uplink NAS
+// message of a UE
+type InitialUEMessage struct {
+	RANUENGAPID           uint32
+	NASPDU                []byte
+	UserLocation          *UserLocationInformationNR
+	RRCEstablishmentCause string      // e.g. "mo-Signalling"
+	FiveGSTMSI            *FiveGSTMSI // Optional
+	UEContextRequested    bool
+}
+
+// DownlinkNASTransport - AMF -> NG-RAN node
+type DownlinkNASTransport struct {
+	AMFUENGAPID uint64
+	RANUENGAPID uint32
+	NASPDU      []byte
+}
+
+// UplinkNASTransport - NG-RAN node -> AMF
+type UplinkNASTransport struct {
+	AMFUENGAPID  uint64
+	RANUENGAPID  uint32
+	NASPDU       []byte
+	UserLocation *UserLocationInformationNR
+}
+
+// InitialContextSetupRequest - AMF -> NG-RAN node, establishing the UE
+// context with the AS security key once the UE is registered
+type InitialContextSetupRequest struct {
+	AMFUENGAPID            uint64
+	RANUENGAPID            uint32
+	GUAMI                  *GUAMI
+	AllowedNSSAI           []*SNSSAI
+	UESecurityCapabilities *UESecurityCapabilities
+	SecurityKey            []byte // KgNB, 32 octets
+	NASPDU                 []byte // Optional, e.g. the Registration Accept
+}
+
+// InitialContextSetupResponse - NG-RAN node -> AMF
+type InitialContextSetupResponse struct {
+	AMFUENGAPID uint64
+	RANUENGAPID uint32
+}
+
+// PLMNID is a PLMN identity
+type PLMNID struct {
+	MCC string
+	MNC string
+}
+
+// GlobalGNBID identifies a gNB
+type GlobalGNBID struct {
+	PLMNID      *PLMNID
+	GNBID       uint32
+	GNBIDLength uint8 // Bits, 22 to 32
+}
+
+// SupportedTA is a tracking area served by the NG-RAN node
+type SupportedTA struct {
+	TAC            uint32 // 24 bits
+	BroadcastPLMNs []*BroadcastPLMN
+}
+
+// BroadcastPLMN is a PLMN broadcast in a tracking area with its slices
+type BroadcastPLMN struct {
+	PLMNID           *PLMNID
+	SliceSupportList []*SNSSAI
+}
+
+// GUAMI is a Globally Unique AMF Identifier
+type GUAMI struct {
+	PLMNID      *PLMNID
+	AMFRegionID uint8
+	AMFSetID    uint16 // 10 bits
+	AMFPointer  uint8  // 6 bits
+}
+
+// PLMNSupport lists the slices the AMF supports in a PLMN
+type PLMNSupport struct {
+	PLMNID           *PLMNID
+	SliceSupportList []*SNSSAI
+}
+
+// SNSSAI is a Single Network Slice Selection Assistance Information
+type SNSSAI struct {
+	SST uint8
+	SD  []byte // 3 octets, optional
+}
+
+// TAI is a Tracking Area Identity
+type TAI struct {
+	PLMNID *PLMNID
+	TAC    uint32 // 24 bits
+}
+
+// NRCGI is an NR Cell Global Identifier
+type NRCGI struct {
+	PLMNID   *PLMNID
+	NRCellID uint64 // 36 bits
+}
+
+// UserLocationInformationNR locates a UE in an NR cell
+type UserLocationInformationNR struct {
+	NRCGI *NRCGI
+	TAI   *TAI
+}
+
+// FiveGSTMSI is the 5G-S-TMSI of a UE
+type FiveGSTMSI struct {
+	AMFSetID   uint16 // 10 bits
+	AMFPointer uint8  // 6 bits
+	FiveGTMSI  uint32
+}
+
+// UESecurityCapabilities holds the algorithms the UE supports, as 16-bit
+// masks with the first algorithm in the most significant bit
+type UESecurityCapabilities struct {
+	NREncryptionAlgorithms             uint16
+	NRIntegrityProtectionAlgorithms    uint16
+	EUTRAEncryptionAlgorithms          uint16
+	EUTRAIntegrityProtectionAlgorithms uint16
+}
@@ -0,0 +1,71 @@
+package ngap
+
+import (
+	"time"
+
+	"github.com/your-org/5g-network/common/sctp"
+)
+
+// NG-C transport (3GPP TS 38.412)
+const (
+	SCTP_PORT     = 38412
+	NGAP_PPID     = 60
+	STREAM_NON_UE = 0 // Reserved for non-UE-associated signalling, e.g. NG Setup
+)
+
+// Listen listens for NG associations from NG-RAN nodes on address, a
+// host:port
+func Listen(cfg sctp.Config, address string) (sctp.Listener, error) {
+	cfg.PPID = NGAP_PPID
+	return sctp.Listen(cfg, address)
+}
+
+// Dial establishes an NG association with the AMF at address
+func Dial(cfg sctp.Config, address string, timeout time.Duration) (sctp.Association, error) {
+	cfg.PPID = NGAP_PPID
+	return sctp.Dial(cfg, address, timeout)
+}
+
+// StreamFor returns the stream a message is sent on. Non-UE-associated
+// procedures use stream 0; UE-associated ones are spread over the other
+// streams by RAN UE NGAP ID, so that one UE always uses one stream
+// (TS 38.412 7).
+func StreamFor(msg Message, streams uint16) uint16 {
+	var ranUEID uint32
+	switch m := msg.(type) {
+	case *InitialUEMessage:
+		ranUEID = m.RANUENGAPID
+	case *DownlinkNASTransport:
+		ranUEID = m.RANUENGAPID
+	case *UplinkNASTransport:
+		ranUEID = m.RANUENGAPID
+	case *InitialContextSetupRequest:
+		ranUEID = m.RANUENGAPID
+	case *InitialContextSetupResponse:
+		ranUEID = m.RANUENGAPID
+	default:
+		return STREAM_NON_UE
+	}
+	if streams < 2 {
+		return STREAM_NON_UE
+	}
+	return 1 + uint16(ranUEID%uint32(streams-1))
+}
+
+// SendMessage encodes msg and sends it on the stream StreamFor selects
+func SendMessage(a sctp.Association, msg Message) error {
+	pdu, err := Encode(msg)
+	if err != nil {
+		return err
+	}
+	return a.Send(StreamFor(msg, a.Streams()), pdu)
+}
+
+// ReceiveMessage receives and decodes the next NGAP message
+func ReceiveMessage(a sctp.Association) (Message, error) {
+	pdu, _, err := a.Receive()
+	if err != nil {
+		return nil, err
+	}
+	return Decode(pdu)
+}
@@ -0,0 +1,15 @@
+package ngap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamFor(t *testing.T) {
+	assert.Equal(t, uint16(STREAM_NON_UE), StreamFor(&NGSetupRequest{}, 4))
+	assert.Equal(t, uint16(STREAM_NON_UE), StreamFor(&InitialUEMessage{RANUENGAPID: 3}, 1))
+	assert.Equal(t, uint16(1), StreamFor(&InitialUEMessage{RANUENGAPID: 3}, 4))
+	assert.Equal(t, StreamFor(&InitialUEMessage{RANUENGAPID: 7}, 4),
+		StreamFor(&DownlinkNASTransport{RANUENGAPID: 7}, 4))
+}
@@ -0,0 +1,204 @@
+// Package notify delivers notifications in the background from a bounded
+// queue and pool of workers, retrying failed deliveries with exponential
+// backoff. It backs the NF status, data change and authentication event
+// notifiers of the NFs.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Defaults for notification delivery
+const (
+	DefaultWorkers       = 4
+	DefaultQueueSize     = 1000
+	DefaultRetryInterval = 500 * time.Millisecond
+)
+
+// Config sizes a Queue. Zero fields fall back to the defaults above;
+// MaxAttempts 0 or 1 disables retries.
+type Config struct {
+	Workers       int           // Delivery goroutines
+	QueueSize     int           // Notifications waiting for delivery
+	MaxAttempts   int           // Including the first attempt
+	RetryInterval time.Duration // Before the first retry, doubled after every retry
+}
+
+// Hooks are called by a Queue as a notification is delivered. Deliver is
+// required, the others are optional.
+type Hooks[T any] struct {
+	// Deliver makes one delivery attempt. Errors wrapped with Permanent
+	// are not retried.
+	Deliver func(n T) error
+	// Delivered is called once an attempt succeeds
+	Delivered func(n T, attempts int)
+	// Retrying is called before waiting to retry a failed attempt
+	Retrying func(n T, attempt int, backoff time.Duration, err error)
+	// Failed is called when delivery gives up
+	Failed func(n T, attempts int, err error)
+	// Dropped is called for a notification discarded because the queue is
+	// full, or because the queue stopped while it waited to be retried
+	Dropped func(n T)
+}
+
+// Queue delivers notifications of type T in the background
+type Queue[T any] struct {
+	cfg    Config
+	hooks  Hooks[T]
+	queue  chan T
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewQueue creates a queue and starts its delivery workers. A single worker
+// delivers notifications in the order they were queued.
+func NewQueue[T any](cfg Config, hooks Hooks[T]) *Queue[T] {
+	if cfg.Workers <= 0 {
+		cfg.Workers = DefaultWorkers
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultQueueSize
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = DefaultRetryInterval
+	}
+
+	q := &Queue[T]{
+		cfg:    cfg,
+		hooks:  hooks,
+		queue:  make(chan T, cfg.QueueSize),
+		stopCh: make(chan struct{}),
+	}
+	for i := 0; i < cfg.Workers; i++ {
+		q.wg.Add(1)
+		go q.run()
+	}
+	return q
+}
+
+// Enqueue schedules a notification for delivery without blocking. The
+// notification is dropped and false returned if the queue is full.
+func (q *Queue[T]) Enqueue(n T) bool {
+	select {
+	case q.queue <- n:
+		return true
+	default:
+		if q.hooks.Dropped != nil {
+			q.hooks.Dropped(n)
+		}
+		return false
+	}
+}
+
+// Len returns the number of notifications waiting for delivery
+func (q *Queue[T]) Len() int {
+	return len(q.queue)
+}
+
+// Stop terminates the delivery workers and returns the number of
+// notifications still queued, which are not delivered
+func (q *Queue[T]) Stop() int {
+	close(q.stopCh)
+	q.wg.Wait()
+	return len(q.queue)
+}
+
+// run delivers queued notifications until the queue is stopped
+func (q *Queue[T]) run() {
+	defer q.wg.Done()
+
+	for {
+		select {
+		case n := <-q.queue:
+			q.deliver(n)
+		case <-q.stopCh:
+			return
+		}
+	}
+}
+
+// deliver attempts a delivery until it succeeds, fails permanently or runs
+// out of attempts
+func (q *Queue[T]) deliver(n T) {
+	backoff := q.cfg.RetryInterval
+	for attempt := 1; ; attempt++ {
+		err := q.hooks.Deliver(n)
+		if err == nil {
+			if q.hooks.Delivered != nil {
+				q.hooks.Delivered(n, attempt)
+			}
+			return
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) || attempt >= q.cfg.MaxAttempts {
+			if q.hooks.Failed != nil {
+				q.hooks.Failed(n, attempt, err)
+			}
+			return
+		}
+
+		if q.hooks.Retrying != nil {
+			q.hooks.Retrying(n, attempt, backoff, err)
+		}
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-q.stopCh:
+			if q.hooks.Dropped != nil {
+				q.hooks.Dropped(n)
+			}
+			return
+		}
+	}
+}
+
+// permanentError marks a delivery failure that retrying will not fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// PostJSON POSTs a JSON body to a callback URI. Transport errors and 5xx
+// responses are worth retrying; an invalid URI or another non-2xx response
+// is returned as a Permanent error.
+func PostJSON(client *http.Client, timeout time.Duration, uri string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri, bytes.NewReader(body))
+	if err != nil {
+		return Permanent(fmt.Errorf("invalid callback URI: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500:
+		return fmt.Errorf("callback returned status %d", resp.StatusCode)
+	case resp.StatusCode >= 300:
+		return Permanent(fmt.Errorf("callback returned status %d", resp.StatusCode))
+	}
+	return nil
+}
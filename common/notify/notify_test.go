@@ -0,0 +1,103 @@
+package notify
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueue_RetriesUntilDelivered(t *testing.T) {
+	var attempts atomic.Int32
+	delivered := make(chan int, 1)
+
+	q := NewQueue(Config{MaxAttempts: 3, RetryInterval: time.Millisecond}, Hooks[string]{
+		Deliver: func(string) error {
+			if attempts.Add(1) < 3 {
+				return errors.New("unavailable")
+			}
+			return nil
+		},
+		Delivered: func(_ string, n int) { delivered <- n },
+	})
+	defer q.Stop()
+
+	require.True(t, q.Enqueue("event"))
+	select {
+	case n := <-delivered:
+		assert.Equal(t, 3, n)
+	case <-time.After(time.Second):
+		t.Fatal("notification not delivered")
+	}
+}
+
+func TestQueue_PermanentErrorIsNotRetried(t *testing.T) {
+	var attempts atomic.Int32
+	failed := make(chan int, 1)
+
+	q := NewQueue(Config{MaxAttempts: 5, RetryInterval: time.Millisecond}, Hooks[string]{
+		Deliver: func(string) error {
+			attempts.Add(1)
+			return Permanent(errors.New("gone"))
+		},
+		Failed: func(_ string, n int, err error) { failed <- n },
+	})
+	defer q.Stop()
+
+	q.Enqueue("event")
+	select {
+	case n := <-failed:
+		assert.Equal(t, 1, n)
+	case <-time.After(time.Second):
+		t.Fatal("delivery did not give up")
+	}
+	assert.Equal(t, int32(1), attempts.Load())
+}
+
+func TestQueue_DropsWhenFull(t *testing.T) {
+	block := make(chan struct{})
+	var dropped atomic.Int32
+
+	q := NewQueue(Config{Workers: 1, QueueSize: 1}, Hooks[string]{
+		Deliver: func(string) error { <-block; return nil },
+		Dropped: func(string) { dropped.Add(1) },
+	})
+
+	// The worker holds the first, the queue the second
+	require.True(t, q.Enqueue("first"))
+	require.Eventually(t, func() bool { return q.Len() == 0 }, time.Second, time.Millisecond)
+	require.True(t, q.Enqueue("second"))
+	assert.False(t, q.Enqueue("third"))
+	assert.Equal(t, int32(1), dropped.Load())
+
+	close(block)
+	q.Stop()
+}
+
+func TestPostJSON(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusNoContent)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer server.Close()
+
+	var permanent *permanentError
+	assert.NoError(t, PostJSON(server.Client(), time.Second, server.URL, []byte(`{}`)))
+
+	status.Store(http.StatusServiceUnavailable)
+	err := PostJSON(server.Client(), time.Second, server.URL, []byte(`{}`))
+	require.Error(t, err)
+	assert.False(t, errors.As(err, &permanent))
+
+	status.Store(http.StatusNotFound)
+	err = PostJSON(server.Client(), time.Second, server.URL, []byte(`{}`))
+	assert.True(t, errors.As(err, &permanent))
+}
@@ -0,0 +1,255 @@
+package nrfpool
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DefaultProbeInterval is how long a consumer stays on a secondary NRF
+// before trying the primary again
+const DefaultProbeInterval = 30 * time.Second
+
+// lookupSRV resolves DNS SRV records, replaceable in tests
+var lookupSRV = net.DefaultResolver.LookupSRV
+
+// Options configures NRF redundancy for a consumer NF. They complement the
+// primary NRF URL of each NF configuration.
+type Options struct {
+	// Secondary NRF base URLs, tried in order after the primary
+	URLs []string `yaml:"urls"`
+
+	// DNS SRV name (e.g. _nrf._tcp.5gc.local) resolving to NRF instances
+	SRVName   string `yaml:"srv_name"`
+	SRVScheme string `yaml:"srv_scheme"` // http or https (default http)
+
+	// How long to stay on a secondary before re-probing the primary
+	ProbeInterval time.Duration `yaml:"probe_interval"`
+}
+
+// Validate checks the NRF redundancy options
+func (o *Options) Validate() error {
+	for _, u := range o.URLs {
+		if _, err := url.ParseRequestURI(u); err != nil {
+			return fmt.Errorf("invalid NRF URL %q: %w", u, err)
+		}
+	}
+
+	switch o.SRVScheme {
+	case "", "http", "https":
+	default:
+		return fmt.Errorf("invalid NRF SRV scheme %q", o.SRVScheme)
+	}
+
+	if o.ProbeInterval < 0 {
+		return fmt.Errorf("NRF probe interval must not be negative")
+	}
+	return nil
+}
+
+// Pool tracks the configured NRF endpoints and fails over between them. The
+// first endpoint is the primary; once a secondary is in use the primary is
+// re-probed on the first request after the probe interval. Consumers send
+// heartbeats periodically, so the primary is picked up again without a
+// dedicated probing goroutine.
+type Pool struct {
+	mu            sync.Mutex
+	endpoints     []string
+	active        int
+	nextProbe     time.Time
+	probeInterval time.Duration
+	logger        *zap.Logger
+}
+
+// New creates an endpoint pool from the primary URL and the redundancy
+// options, resolving the SRV name if one is configured
+func New(ctx context.Context, primary string, opts Options, logger *zap.Logger) (*Pool, error) {
+	var endpoints []string
+	seen := make(map[string]bool)
+	add := func(u string) {
+		u = strings.TrimRight(u, "/")
+		if u != "" && !seen[u] {
+			seen[u] = true
+			endpoints = append(endpoints, u)
+		}
+	}
+
+	add(primary)
+	for _, u := range opts.URLs {
+		add(u)
+	}
+
+	if opts.SRVName != "" {
+		resolved, err := resolveSRV(ctx, opts.SRVName, opts.SRVScheme)
+		if err != nil && len(endpoints) == 0 {
+			return nil, err
+		}
+		if err != nil {
+			logger.Warn("Failed to resolve NRF SRV records, using static endpoints",
+				zap.String("srv_name", opts.SRVName),
+				zap.Error(err),
+			)
+		}
+		for _, u := range resolved {
+			add(u)
+		}
+	}
+
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("no NRF endpoints configured")
+	}
+
+	probeInterval := opts.ProbeInterval
+	if probeInterval == 0 {
+		probeInterval = DefaultProbeInterval
+	}
+
+	return &Pool{
+		endpoints:     endpoints,
+		probeInterval: probeInterval,
+		logger:        logger,
+	}, nil
+}
+
+// Endpoints returns the NRF base URLs in failover order
+func (p *Pool) Endpoints() []string {
+	return append([]string(nil), p.endpoints...)
+}
+
+// Current returns the base URL of the NRF currently in use
+func (p *Pool) Current() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.endpoints[p.active]
+}
+
+// Do sends a request built for each NRF base URL in turn until one answers.
+// Transport errors and 5xx responses move on to the next endpoint; any other
+// response is returned to the caller. If every endpoint fails, the last 5xx
+// response (or error) is returned.
+func (p *Pool) Do(client *http.Client, build func(baseURL string) (*http.Request, error)) (*http.Response, error) {
+	var lastResp *http.Response
+	var lastErr error
+
+	order := p.order()
+	for n, i := range order {
+		req, err := build(p.endpoints[i])
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := client.Do(req)
+		if err == nil && resp.StatusCode < http.StatusInternalServerError {
+			p.markHealthy(i)
+			return resp, nil
+		}
+		p.markFailed(i)
+
+		if err != nil {
+			lastErr = err
+			p.logger.Warn("NRF request failed",
+				zap.String("nrf_url", p.endpoints[i]),
+				zap.Error(err),
+			)
+		} else {
+			lastErr = fmt.Errorf("NRF %s returned status %d", p.endpoints[i], resp.StatusCode)
+			p.logger.Warn("NRF returned server error",
+				zap.String("nrf_url", p.endpoints[i]),
+				zap.Int("status", resp.StatusCode),
+			)
+			if lastResp != nil {
+				lastResp.Body.Close()
+			}
+			lastResp = resp
+		}
+
+		// Stop early if the caller gave up
+		if req.Context().Err() != nil || n == len(order)-1 {
+			break
+		}
+	}
+
+	if lastResp != nil {
+		return lastResp, nil
+	}
+	return nil, fmt.Errorf("all NRF endpoints failed: %w", lastErr)
+}
+
+// order returns the endpoint indices to try for the next request
+func (p *Pool) order() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	order := make([]int, 0, len(p.endpoints))
+	if p.active != 0 && !time.Now().Before(p.nextProbe) {
+		order = append(order, 0)
+	}
+	order = append(order, p.active)
+	for i := range p.endpoints {
+		if i != p.active && i != order[0] {
+			order = append(order, i)
+		}
+	}
+	return order
+}
+
+// markHealthy switches to the endpoint that answered
+func (p *Pool) markHealthy(i int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if i == p.active {
+		return
+	}
+
+	if i == 0 {
+		p.logger.Info("Primary NRF is reachable again", zap.String("nrf_url", p.endpoints[i]))
+	} else {
+		p.logger.Warn("Failing over to secondary NRF",
+			zap.String("from", p.endpoints[p.active]),
+			zap.String("to", p.endpoints[i]),
+		)
+		p.nextProbe = time.Now().Add(p.probeInterval)
+	}
+	p.active = i
+}
+
+// markFailed delays the next probe when the primary is still unreachable
+func (p *Pool) markFailed(i int) {
+	if i != 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.active != 0 {
+		p.nextProbe = time.Now().Add(p.probeInterval)
+	}
+}
+
+// resolveSRV returns NRF base URLs for the SRV targets, in priority and
+// weight order as sorted by the resolver
+func resolveSRV(ctx context.Context, name, scheme string) ([]string, error) {
+	if scheme == "" {
+		scheme = "http"
+	}
+
+	_, records, err := lookupSRV(ctx, "", "", name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve NRF SRV name %s: %w", name, err)
+	}
+
+	urls := make([]string, 0, len(records))
+	for _, r := range records {
+		host := strings.TrimSuffix(r.Target, ".")
+		urls = append(urls, fmt.Sprintf("%s://%s", scheme, net.JoinHostPort(host, fmt.Sprint(r.Port))))
+	}
+	return urls, nil
+}
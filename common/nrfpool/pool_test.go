@@ -0,0 +1,170 @@
+package nrfpool
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newNRF starts a fake NRF answering 204 and counting requests
+func newNRF(t *testing.T) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+// downURL returns the URL of an NRF that refuses connections
+func downURL(t *testing.T) string {
+	t.Helper()
+
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	return srv.URL
+}
+
+func heartbeat(t *testing.T, pool *Pool) *http.Response {
+	t.Helper()
+
+	resp, err := pool.Do(http.DefaultClient, func(baseURL string) (*http.Request, error) {
+		return http.NewRequest(http.MethodPatch, baseURL+"/nnrf-nfm/v1/nf-instances/nf-1/heartbeat", nil)
+	})
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp
+}
+
+func TestFailoverToSecondaryWhenPrimaryDown(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	secondary, hits := newNRF(t)
+	primary := downURL(t)
+
+	pool, err := New(context.Background(), primary, Options{URLs: []string{secondary.URL}}, logger)
+	require.NoError(t, err)
+
+	resp := heartbeat(t, pool)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(hits))
+	assert.Equal(t, secondary.URL, pool.Current())
+
+	// Subsequent requests stay on the secondary until the probe interval
+	heartbeat(t, pool)
+	assert.Equal(t, int32(2), atomic.LoadInt32(hits))
+}
+
+func TestPrimaryReprobedAfterInterval(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	secondary, _ := newNRF(t)
+
+	// Reserve an address for the primary and bring it up later
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	primaryURL := "http://" + listener.Addr().String()
+	listener.Close()
+
+	pool, err := New(context.Background(), primaryURL, Options{
+		URLs:          []string{secondary.URL},
+		ProbeInterval: 20 * time.Millisecond,
+	}, logger)
+	require.NoError(t, err)
+
+	heartbeat(t, pool)
+	require.Equal(t, secondary.URL, pool.Current())
+
+	listener, err = net.Listen("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	primary := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	primary.Listener = listener
+	primary.Start()
+	defer primary.Close()
+
+	time.Sleep(30 * time.Millisecond)
+	heartbeat(t, pool)
+	assert.Equal(t, primaryURL, pool.Current())
+}
+
+func TestServerErrorTriggersFailoverButClientErrorDoesNot(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	secondary, hits := newNRF(t)
+
+	status := int32(http.StatusServiceUnavailable)
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer primary.Close()
+
+	pool, err := New(context.Background(), primary.URL, Options{URLs: []string{secondary.URL}}, logger)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusNoContent, heartbeat(t, pool).StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(hits))
+
+	// A 404 from the primary is an answer, not an outage
+	atomic.StoreInt32(&status, http.StatusNotFound)
+	pool, err = New(context.Background(), primary.URL, Options{URLs: []string{secondary.URL}}, logger)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusNotFound, heartbeat(t, pool).StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(hits))
+}
+
+func TestAllEndpointsDown(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+
+	pool, err := New(context.Background(), downURL(t), Options{URLs: []string{downURL(t)}}, logger)
+	require.NoError(t, err)
+
+	_, err = pool.Do(http.DefaultClient, func(baseURL string) (*http.Request, error) {
+		return http.NewRequest(http.MethodGet, baseURL, nil)
+	})
+	assert.ErrorContains(t, err, "all NRF endpoints failed")
+}
+
+func TestSRVNameResolvesEndpoints(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+
+	lookupSRV = func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+		if name != "_nrf._tcp.5gc.local" {
+			return "", nil, fmt.Errorf("no such host")
+		}
+		return name, []*net.SRV{
+			{Target: "nrf-0.5gc.local.", Port: 8080, Priority: 10},
+			{Target: "nrf-1.5gc.local.", Port: 8080, Priority: 20},
+		}, nil
+	}
+	defer func() { lookupSRV = net.DefaultResolver.LookupSRV }()
+
+	pool, err := New(context.Background(), "", Options{SRVName: "_nrf._tcp.5gc.local"}, logger)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http://nrf-0.5gc.local:8080", "http://nrf-1.5gc.local:8080"}, pool.Endpoints())
+
+	// A failed lookup falls back to the static URL
+	pool, err = New(context.Background(), "http://nrf:8080", Options{SRVName: "_nrf._tcp.other"}, logger)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http://nrf:8080"}, pool.Endpoints())
+
+	_, err = New(context.Background(), "", Options{SRVName: "_nrf._tcp.other"}, logger)
+	assert.Error(t, err)
+}
+
+func TestOptionsValidate(t *testing.T) {
+	assert.NoError(t, (&Options{URLs: []string{"http://nrf-2:8080"}}).Validate())
+	assert.Error(t, (&Options{URLs: []string{"nrf-2"}}).Validate())
+	assert.Error(t, (&Options{SRVScheme: "ftp"}).Validate())
+}
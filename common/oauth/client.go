@@ -0,0 +1,220 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ClientConfig lets an NF obtain access tokens from the NRF for its requests
+// to other NFs (TS 29.510 5.4.2.2)
+type ClientConfig struct {
+	Enabled          bool   `yaml:"enabled"`
+	ClientSecret     string `yaml:"client_secret"`      // Authenticates the NF to the NRF
+	ClientSecretFile string `yaml:"client_secret_file"` // Read instead of client_secret when set
+}
+
+// Validate checks that an enabled configuration has a client secret
+func (c *ClientConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	_, err := c.secret()
+	return err
+}
+
+// secret returns the client secret read from file, or the inline secret
+func (c *ClientConfig) secret() (string, error) {
+	secret := c.ClientSecret
+	if c.ClientSecretFile != "" {
+		data, err := os.ReadFile(c.ClientSecretFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read client secret: %w", err)
+		}
+		secret = strings.TrimSpace(string(data))
+	}
+	if secret == "" {
+		return "", fmt.Errorf("client secret is required")
+	}
+	return secret, nil
+}
+
+// tokenResponse is the NRF's AccessTokenRsp or AccessTokenErr
+// (TS 29.510 6.3.5.2.3, 6.3.5.2.5)
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	ExpiresIn        int    `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// tokenKey identifies the tokens a source caches
+type tokenKey struct {
+	targetNFType string
+	scope        string
+}
+
+// cachedToken is a token and the time it should be replaced
+type cachedToken struct {
+	token     string
+	refreshAt time.Time
+}
+
+// TokenSource obtains access tokens from the NRF for an NF and caches them
+// until shortly before they expire
+type TokenSource struct {
+	tokenURL     string
+	nfInstanceID string
+	nfType       string
+	secret       string
+	client       *http.Client
+	logger       *zap.Logger
+
+	mu     sync.Mutex
+	tokens map[tokenKey]cachedToken
+	now    func() time.Time
+}
+
+// NewTokenSource creates the token source of an NF from its configuration.
+// It returns nil when cfg is disabled; a nil source adds no tokens.
+func NewTokenSource(cfg ClientConfig, nrfURL, nfInstanceID, nfType string, transport http.RoundTripper, logger *zap.Logger) (*TokenSource, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	secret, err := cfg.secret()
+	if err != nil {
+		return nil, err
+	}
+	if nrfURL == "" {
+		return nil, fmt.Errorf("NRF URL is required to obtain access tokens")
+	}
+
+	return &TokenSource{
+		tokenURL:     strings.TrimSuffix(nrfURL, "/") + "/oauth2/token",
+		nfInstanceID: nfInstanceID,
+		nfType:       nfType,
+		secret:       secret,
+		client:       &http.Client{Timeout: 10 * time.Second, Transport: transport},
+		logger:       logger,
+		tokens:       make(map[tokenKey]cachedToken),
+		now:          time.Now,
+	}, nil
+}
+
+// Token returns an access token granting scope at NFs of type targetNFType,
+// requesting a new one when the cached token is close to expiry
+func (s *TokenSource) Token(ctx context.Context, targetNFType, scope string) (string, error) {
+	key := tokenKey{targetNFType: targetNFType, scope: scope}
+
+	// Holding the lock while fetching keeps concurrent requests from asking
+	// the NRF for the same token
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cached, ok := s.tokens[key]; ok && s.now().Before(cached.refreshAt) {
+		return cached.token, nil
+	}
+
+	rsp, err := s.requestToken(ctx, key)
+	if err != nil {
+		return "", err
+	}
+
+	// Refresh once 90% of the lifetime has passed
+	lifetime := time.Duration(rsp.ExpiresIn) * time.Second
+	s.tokens[key] = cachedToken{
+		token:     rsp.AccessToken,
+		refreshAt: s.now().Add(lifetime * 9 / 10),
+	}
+
+	s.logger.Debug("Access token obtained",
+		zap.String("target_nf_type", targetNFType),
+		zap.String("scope", scope),
+		zap.Duration("expires_in", lifetime),
+	)
+	return rsp.AccessToken, nil
+}
+
+// requestToken asks the NRF for a token (TS 29.510 5.4.2.2), authenticating
+// with the client secret (RFC 6749 2.3.1)
+func (s *TokenSource) requestToken(ctx context.Context, key tokenKey) (*tokenResponse, error) {
+	form := url.Values{
+		"grant_type":   {"client_credentials"},
+		"nfInstanceId": {s.nfInstanceID},
+		"nfType":       {s.nfType},
+		"targetNfType": {key.targetNFType},
+		"scope":        {key.scope},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(s.nfInstanceID, s.secret)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request access token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var rsp tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rsp); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("access token request rejected with %d: %s %s", resp.StatusCode, rsp.Error, rsp.ErrorDescription)
+	}
+	if rsp.AccessToken == "" {
+		return nil, fmt.Errorf("token response has no access token")
+	}
+	return &rsp, nil
+}
+
+// Transport returns a RoundTripper adding an access token to requests sent
+// to NFs of type targetNFType. The scope requested is the service name, the
+// first segment of the request path (TS 29.501 4.4.1). Requests to
+// DefaultSkipPaths go without a token. A nil source returns next unchanged.
+func (s *TokenSource) Transport(next http.RoundTripper, targetNFType string) http.RoundTripper {
+	if s == nil {
+		return next
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &tokenTransport{source: s, next: next, targetNFType: targetNFType}
+}
+
+// tokenTransport adds bearer tokens to outgoing requests
+type tokenTransport struct {
+	source       *TokenSource
+	next         http.RoundTripper
+	targetNFType string
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Authorization") != "" || slices.Contains(DefaultSkipPaths, req.URL.Path) {
+		return t.next.RoundTrip(req)
+	}
+
+	service, _, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, "/"), "/")
+	token, err := t.source.Token(req.Context(), t.targetNFType, service)
+	if err != nil {
+		return nil, err
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,116 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newTestNRF serves token requests from amf-instance, authenticated with
+// secret, and counts the tokens issued
+func newTestNRF(t *testing.T, secret string, issued *atomic.Int32) *httptest.Server {
+	nrf := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		w.Header().Set("Content-Type", "application/json")
+
+		clientID, clientSecret, ok := r.BasicAuth()
+		if !ok || clientID != r.PostForm.Get("nfInstanceId") || clientSecret != secret {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+
+		issued.Add(1)
+		token, err := Sign(&Claims{
+			Issuer:    "nrf-instance",
+			Subject:   clientID,
+			Audience:  []string{r.PostForm.Get("targetNfType")},
+			Scope:     r.PostForm.Get("scope"),
+			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		}, testKey)
+		require.NoError(t, err)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": token,
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"scope":        r.PostForm.Get("scope"),
+		})
+	}))
+	t.Cleanup(nrf.Close)
+	return nrf
+}
+
+func TestTokenSourceAuthorizesRequests(t *testing.T) {
+	var issued atomic.Int32
+	nrf := newTestNRF(t, "amf-secret", &issued)
+	producer := httptest.NewServer(newProtectedRouter(Config{Enabled: true, SigningKey: string(testKey)}))
+	t.Cleanup(producer.Close)
+
+	logger, _ := zap.NewDevelopment()
+	source, err := NewTokenSource(ClientConfig{Enabled: true, ClientSecret: "amf-secret"},
+		nrf.URL, "amf-instance", "AMF", http.DefaultTransport, logger)
+	require.NoError(t, err)
+	client := &http.Client{Transport: source.Transport(http.DefaultTransport, "AUSF")}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Post(producer.URL+"/nausf-auth/v1/ue-authentications", "application/json", strings.NewReader("{}"))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	}
+	assert.Equal(t, int32(1), issued.Load(), "the token is cached")
+
+	// Another service needs a token with its own scope
+	resp, err := client.Post(producer.URL+"/nausf-sorprotection/v1/imsi-001010000000001/ue-sor", "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), issued.Load())
+
+	// Probes go without a token
+	resp, err = client.Get(producer.URL + "/health")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), issued.Load())
+
+	// A token close to expiry is replaced
+	source.now = func() time.Time { return time.Now().Add(55 * time.Minute) }
+	_, err = source.Token(context.Background(), "AUSF", "nausf-auth")
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), issued.Load())
+}
+
+func TestTokenSourceRejectedByNRF(t *testing.T) {
+	var issued atomic.Int32
+	nrf := newTestNRF(t, "amf-secret", &issued)
+
+	logger, _ := zap.NewDevelopment()
+	source, err := NewTokenSource(ClientConfig{Enabled: true, ClientSecret: "wrong"},
+		nrf.URL, "amf-instance", "AMF", http.DefaultTransport, logger)
+	require.NoError(t, err)
+
+	_, err = source.Token(context.Background(), "AUSF", "nausf-auth")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid_client")
+}
+
+func TestTokenSourceDisabled(t *testing.T) {
+	source, err := NewTokenSource(ClientConfig{}, "", "amf-instance", "AMF", nil, zap.NewNop())
+	require.NoError(t, err)
+	assert.Nil(t, source)
+	assert.Equal(t, http.DefaultTransport, source.Transport(http.DefaultTransport, "AUSF"))
+
+	cfg := ClientConfig{Enabled: true}
+	assert.Error(t, cfg.Validate())
+}
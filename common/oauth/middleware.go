@@ -0,0 +1,144 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/your-org/5g-network/common/sbi"
+	"go.uber.org/zap"
+)
+
+// MinKeyLength is the shortest accepted HS256 signing key (RFC 7518 3.2)
+const MinKeyLength = 32
+
+// DefaultSkipPaths are served without a token so probes and scrapers keep
+// working when validation is enabled
+var DefaultSkipPaths = []string{"/health", "/ready", "/version"}
+
+// Config enables access token validation on an NF's SBI server
+// (TS 33.501 13.4.1)
+type Config struct {
+	Enabled        bool     `yaml:"enabled"`
+	SigningKey     string   `yaml:"signing_key"`      // HS256 key shared with the NRF
+	SigningKeyFile string   `yaml:"signing_key_file"` // Read instead of signing_key when set
+	SkipPaths      []string `yaml:"skip_paths"`       // Served without a token, in addition to DefaultSkipPaths
+}
+
+// Validate checks that an enabled configuration has a usable key
+func (c *Config) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	_, err := LoadKey(c.SigningKey, c.SigningKeyFile)
+	return err
+}
+
+// LoadKey returns the signing key read from file, or key when file is empty
+func LoadKey(key, file string) ([]byte, error) {
+	data := []byte(key)
+	if file != "" {
+		var err error
+		data, err = os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signing key: %w", err)
+		}
+	}
+	if len(data) < MinKeyLength {
+		return nil, fmt.Errorf("signing key must be at least %d bytes", MinKeyLength)
+	}
+	return data, nil
+}
+
+// claimsKey is the request context key of the verified token claims
+type claimsKey struct{}
+
+// ClaimsFromContext returns the claims of the access token the request was
+// authorized with, or nil when it carried none
+func ClaimsFromContext(ctx context.Context) *Claims {
+	claims, _ := ctx.Value(claimsKey{}).(*Claims)
+	return claims
+}
+
+// Middleware rejects SBI requests without a valid bearer token issued for
+// audiences, normally the NF type and instance ID. It passes every request
+// through when cfg is disabled. A key that cannot be loaded fails closed;
+// config validation reports it at startup. Routes check the scope of the
+// token with RequireScope.
+func Middleware(cfg Config, logger *zap.Logger, audiences ...string) func(http.Handler) http.Handler {
+	if !cfg.Enabled {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	key, keyErr := LoadKey(cfg.SigningKey, cfg.SigningKeyFile)
+	if keyErr != nil {
+		logger.Error("Access token validation unavailable, rejecting SBI requests", zap.Error(keyErr))
+	}
+	verifier := NewVerifier(key, audiences...)
+
+	skip := make(map[string]bool)
+	for _, path := range append(DefaultSkipPaths, cfg.SkipPaths...) {
+		skip[path] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if skip[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if keyErr != nil {
+				writeProblem(w, http.StatusInternalServerError, "Access token validation unavailable", "", sbi.CauseSystemFailure)
+				return
+			}
+
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" {
+				w.Header().Set("WWW-Authenticate", `Bearer`)
+				writeProblem(w, http.StatusUnauthorized, "Unauthorized", "missing bearer token", sbi.CauseUnauthorized)
+				return
+			}
+
+			claims, err := verifier.Verify(token)
+			if err != nil {
+				logger.Debug("Access token rejected",
+					zap.String("path", r.URL.Path),
+					zap.Error(err),
+				)
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="invalid_token", error_description=%q`, err.Error()))
+				writeProblem(w, http.StatusUnauthorized, "Unauthorized", err.Error(), sbi.CauseUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey{}, claims)))
+		})
+	}
+}
+
+// RequireScope rejects requests whose access token does not grant service,
+// the name of the SBI service the route belongs to. Requests Middleware let
+// through without a token, when validation is disabled, pass.
+func RequireScope(service string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if claims := ClaimsFromContext(r.Context()); claims != nil && !claims.HasScope(service) {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, service))
+				writeProblem(w, http.StatusForbidden, "Forbidden", ErrInsufficientScope.Error(), sbi.CauseUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writeProblem writes a ProblemDetails response
+func writeProblem(w http.ResponseWriter, status int, title, detail, cause string) {
+	sbi.WriteProblem(w, &sbi.ProblemDetails{
+		Status: status,
+		Title:  title,
+		Detail: detail,
+		Cause:  cause,
+	})
+}
@@ -0,0 +1,138 @@
+package oauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/common/sbi"
+)
+
+func newProtectedRouter(cfg Config) *chi.Mux {
+	logger, _ := zap.NewDevelopment()
+
+	r := chi.NewRouter()
+	r.Use(Middleware(cfg, logger, "AUSF", "ausf-instance"))
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	r.Route("/nausf-auth/v1", func(r chi.Router) {
+		r.Use(RequireScope("nausf-auth"))
+		r.Post("/ue-authentications", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		})
+	})
+	r.Route("/nausf-sorprotection/v1", func(r chi.Router) {
+		r.Use(RequireScope("nausf-sorprotection"))
+		r.Post("/{supi}/ue-sor", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+	})
+	return r
+}
+
+func signedToken(t *testing.T, audience string, exp time.Time) string {
+	claims := testClaims(exp)
+	claims.Audience = []string{audience}
+	token, err := Sign(claims, testKey)
+	require.NoError(t, err)
+	return token
+}
+
+func TestMiddleware(t *testing.T) {
+	router := newProtectedRouter(Config{Enabled: true, SigningKey: string(testKey)})
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"valid token", signedToken(t, "AUSF", time.Now().Add(time.Hour)), http.StatusCreated},
+		{"valid instance audience", signedToken(t, "ausf-instance", time.Now().Add(time.Hour)), http.StatusCreated},
+		{"expired token", signedToken(t, "AUSF", time.Now().Add(-time.Minute)), http.StatusUnauthorized},
+		{"wrong audience", signedToken(t, "UDM", time.Now().Add(time.Hour)), http.StatusUnauthorized},
+		{"missing token", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/nausf-auth/v1/ue-authentications", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.status, rec.Code)
+			if tt.status != http.StatusUnauthorized {
+				return
+			}
+			assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+			assert.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+
+			var problem sbi.ProblemDetails
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&problem))
+			assert.Equal(t, http.StatusUnauthorized, problem.Status)
+			assert.Equal(t, sbi.CauseUnauthorized, problem.Cause)
+		})
+	}
+}
+
+func TestMiddlewareChecksRouteScope(t *testing.T) {
+	router := newProtectedRouter(Config{Enabled: true, SigningKey: string(testKey)})
+
+	// The token only grants nausf-auth
+	req := httptest.NewRequest(http.MethodPost, "/nausf-sorprotection/v1/imsi-001010000000001/ue-sor", nil)
+	req.Header.Set("Authorization", "Bearer "+signedToken(t, "AUSF", time.Now().Add(time.Hour)))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Header().Get("WWW-Authenticate"), `error="insufficient_scope"`)
+
+	var problem sbi.ProblemDetails
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&problem))
+	assert.Equal(t, ErrInsufficientScope.Error(), problem.Detail)
+
+	// Without validation every route is open
+	router = newProtectedRouter(Config{})
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/nausf-sorprotection/v1/imsi-001010000000001/ue-sor", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestMiddlewareSkipsHealth(t *testing.T) {
+	router := newProtectedRouter(Config{Enabled: true, SigningKey: string(testKey)})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestMiddlewareDisabled(t *testing.T) {
+	router := newProtectedRouter(Config{})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/nausf-auth/v1/ue-authentications", nil))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+}
+
+func TestMiddlewareFailsClosedWithoutKey(t *testing.T) {
+	router := newProtectedRouter(Config{Enabled: true, SigningKey: "short"})
+
+	req := httptest.NewRequest(http.MethodPost, "/nausf-auth/v1/ue-authentications", nil)
+	req.Header.Set("Authorization", "Bearer "+signedToken(t, "AUSF", time.Now().Add(time.Hour)))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	cfg := Config{Enabled: true, SigningKey: "short"}
+	assert.Error(t, cfg.Validate())
+}
@@ -0,0 +1,132 @@
+package oauth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidToken is returned when an access token is malformed or its
+	// signature does not verify
+	ErrInvalidToken = errors.New("invalid access token")
+
+	// ErrTokenExpired is returned when an access token is past its exp claim
+	ErrTokenExpired = errors.New("access token expired")
+
+	// ErrAudienceMismatch is returned when an access token was not issued for
+	// the verifying NF
+	ErrAudienceMismatch = errors.New("access token audience mismatch")
+
+	// ErrInsufficientScope is returned when an access token does not grant the
+	// requested service
+	ErrInsufficientScope = errors.New("access token scope does not cover service")
+)
+
+// jwtHeader is the JOSE header of every token; tokens are HS256 signed
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// Claims are the claims of an NRF access token (TS 29.510 6.3.5.2.4
+// AccessTokenClaims)
+type Claims struct {
+	Issuer    string   `json:"iss"`   // NRF instance ID
+	Subject   string   `json:"sub"`   // NF instance ID of the consumer
+	Audience  []string `json:"aud"`   // Producer NF instance ID or NF type
+	Scope     string   `json:"scope"` // Space separated service names
+	IssuedAt  int64    `json:"iat"`
+	ExpiresAt int64    `json:"exp"`
+}
+
+// HasScope reports whether the claims grant access to a service
+func (c *Claims) HasScope(service string) bool {
+	for _, s := range strings.Fields(c.Scope) {
+		if s == service {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign encodes claims as a JWT signed with key
+func Sign(claims *Claims, key []byte) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode claims: %w", err)
+	}
+
+	signingInput := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sign(signingInput, key)), nil
+}
+
+// Verifier checks access tokens presented to a producer NF
+type Verifier struct {
+	key       []byte
+	audiences []string
+	now       func() time.Time
+}
+
+// NewVerifier creates a verifier for tokens signed with key. A token is
+// accepted when its audience names one of audiences, typically the NF
+// instance ID and NF type of the producer.
+func NewVerifier(key []byte, audiences ...string) *Verifier {
+	return &Verifier{
+		key:       key,
+		audiences: audiences,
+		now:       time.Now,
+	}
+}
+
+// Verify checks the signature, expiry and audience of a token and returns its
+// claims
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return nil, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, sign(parts[0]+"."+parts[1], v.key)) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if !v.now().Before(time.Unix(claims.ExpiresAt, 0)) {
+		return nil, ErrTokenExpired
+	}
+	if !v.audienceMatches(claims.Audience) {
+		return nil, ErrAudienceMismatch
+	}
+	return &claims, nil
+}
+
+// audienceMatches reports whether the token audience names this verifier
+func (v *Verifier) audienceMatches(audience []string) bool {
+	for _, aud := range audience {
+		for _, expected := range v.audiences {
+			if aud == expected {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sign computes the HS256 signature of a JWT signing input
+func sign(signingInput string, key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(signingInput))
+	return mac.Sum(nil)
+}
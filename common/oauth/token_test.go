@@ -0,0 +1,69 @@
+package oauth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func testClaims(exp time.Time) *Claims {
+	return &Claims{
+		Issuer:    "nrf-instance",
+		Subject:   "amf-instance",
+		Audience:  []string{"AUSF"},
+		Scope:     "nausf-auth",
+		IssuedAt:  time.Now().Unix(),
+		ExpiresAt: exp.Unix(),
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	token, err := Sign(testClaims(time.Now().Add(time.Hour)), testKey)
+	require.NoError(t, err)
+
+	claims, err := NewVerifier(testKey, "ausf-instance", "AUSF").Verify(token)
+	require.NoError(t, err)
+	assert.Equal(t, "amf-instance", claims.Subject)
+	assert.True(t, claims.HasScope("nausf-auth"))
+	assert.False(t, claims.HasScope("nudm-uecm"))
+}
+
+func TestVerifyRejectsExpiredToken(t *testing.T) {
+	token, err := Sign(testClaims(time.Now().Add(-time.Second)), testKey)
+	require.NoError(t, err)
+
+	_, err = NewVerifier(testKey, "AUSF").Verify(token)
+	assert.ErrorIs(t, err, ErrTokenExpired)
+}
+
+func TestVerifyRejectsAudienceMismatch(t *testing.T) {
+	token, err := Sign(testClaims(time.Now().Add(time.Hour)), testKey)
+	require.NoError(t, err)
+
+	_, err = NewVerifier(testKey, "udm-instance", "UDM").Verify(token)
+	assert.ErrorIs(t, err, ErrAudienceMismatch)
+}
+
+func TestVerifyRejectsBadSignature(t *testing.T) {
+	token, err := Sign(testClaims(time.Now().Add(time.Hour)), testKey)
+	require.NoError(t, err)
+
+	_, err = NewVerifier([]byte("another-key-another-key-another-k"), "AUSF").Verify(token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// Altered claims keep the original signature
+	forged, err := Sign(&Claims{Audience: []string{"AUSF"}, Scope: "nausf-auth", ExpiresAt: time.Now().Add(time.Hour).Unix()}, testKey)
+	require.NoError(t, err)
+	parts := strings.Split(token, ".")
+	forgedParts := strings.Split(forged, ".")
+	_, err = NewVerifier(testKey, "AUSF").Verify(parts[0] + "." + forgedParts[1] + "." + parts[2])
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = NewVerifier(testKey, "AUSF").Verify("not-a-token")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
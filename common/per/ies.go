@@ -0,0 +1,263 @@
+package per
+
+import "fmt"
+
+// Bounds shared by the application protocols' ASN.1
+const (
+	maxProtocolIEs    = 65535
+	criticalityValues = 3
+)
+
+// Criticality of procedures and IEs
+const (
+	CRITICALITY_REJECT = 0
+	CRITICALITY_IGNORE = 1
+	CRITICALITY_NOTIFY = 2
+)
+
+// ProtocolIE is a field of a protocol IE or extension container
+type ProtocolIE struct {
+	ID          uint16
+	Criticality uint8
+	Encode      func(*Writer) error
+}
+
+// DecodedIE is a field read from a protocol IE or extension container
+type DecodedIE struct {
+	ID    uint16
+	Value *Reader
+}
+
+// PutField encodes a ProtocolIE-Field, which is also a single container
+func PutField(w *Writer, ie ProtocolIE) error {
+	if err := w.PutConstrained(uint64(ie.ID), 0, maxProtocolIEs); err != nil {
+		return err
+	}
+	if err := w.PutConstrained(uint64(ie.Criticality), 0, criticalityValues-1); err != nil {
+		return err
+	}
+	if err := w.PutOpenType(ie.Encode); err != nil {
+		return fmt.Errorf("ie %d: %w", ie.ID, err)
+	}
+	return nil
+}
+
+func GetField(r *Reader) (DecodedIE, error) {
+	id, err := r.GetConstrained(0, maxProtocolIEs)
+	if err != nil {
+		return DecodedIE{}, err
+	}
+	if _, err := r.GetConstrained(0, criticalityValues-1); err != nil {
+		return DecodedIE{}, err
+	}
+	value, err := r.GetOpenType()
+	if err != nil {
+		return DecodedIE{}, fmt.Errorf("ie %d: %w", id, err)
+	}
+	return DecodedIE{ID: uint16(id), Value: value}, nil
+}
+
+// PutContainer encodes a protocol IE container (lb 0) or a protocol
+// extension container (lb 1)
+func PutContainer(w *Writer, ies []ProtocolIE, lb int) error {
+	if err := w.PutLength(len(ies), lb, maxProtocolIEs); err != nil {
+		return err
+	}
+	for _, ie := range ies {
+		if err := PutField(w, ie); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func GetContainer(r *Reader, lb int) ([]DecodedIE, error) {
+	n, err := r.GetLength(lb, maxProtocolIEs)
+	if err != nil {
+		return nil, err
+	}
+	ies := make([]DecodedIE, 0, n)
+	for i := 0; i < n; i++ {
+		ie, err := GetField(r)
+		if err != nil {
+			return nil, err
+		}
+		ies = append(ies, ie)
+	}
+	return ies, nil
+}
+
+// PutSequence encodes the preamble of an extensible SEQUENCE: the extension
+// bit, which is never set, and the presence bits of its optional components
+func PutSequence(w *Writer, optional ...bool) {
+	w.PutBool(false)
+	PutPresence(w, optional...)
+}
+
+// PutPresence encodes the presence bits of the optional components of a
+// SEQUENCE without an extension marker
+func PutPresence(w *Writer, optional ...bool) {
+	for _, present := range optional {
+		w.PutBool(present)
+	}
+}
+
+// GetSequence decodes the preamble of an extensible SEQUENCE with n
+// optional components
+func GetSequence(r *Reader, n int) ([]bool, error) {
+	extended, err := r.GetBool()
+	if err != nil {
+		return nil, err
+	}
+	if extended {
+		return nil, fmt.Errorf("sequence extension additions are not supported")
+	}
+	return GetPresence(r, n)
+}
+
+func GetPresence(r *Reader, n int) ([]bool, error) {
+	present := make([]bool, n)
+	for i := range present {
+		var err error
+		if present[i], err = r.GetBool(); err != nil {
+			return nil, err
+		}
+	}
+	return present, nil
+}
+
+// SkipExtensions reads and discards an iE-Extensions container
+func SkipExtensions(r *Reader, present bool) error {
+	if !present {
+		return nil
+	}
+	_, err := GetContainer(r, 1)
+	return err
+}
+
+// PutEnumerated encodes value as an ENUMERATED over values
+func PutEnumerated[T comparable](w *Writer, value T, values []T, extensible bool) error {
+	for i, v := range values {
+		if v == value {
+			if extensible {
+				w.PutBool(false)
+			}
+			return w.PutConstrained(uint64(i), 0, uint64(len(values)-1))
+		}
+	}
+	return fmt.Errorf("unsupported enumerated value %v", value)
+}
+
+func GetEnumerated[T any](r *Reader, values []T, extensible bool) (T, error) {
+	var zero T
+	if extensible {
+		extended, err := r.GetBool()
+		if err != nil {
+			return zero, err
+		}
+		if extended {
+			return zero, fmt.Errorf("enumerated value outside its extension root")
+		}
+	}
+	i, err := r.GetConstrained(0, uint64(len(values)-1))
+	if err != nil {
+		return zero, err
+	}
+	return values[i], nil
+}
+
+// PutList encodes a SEQUENCE OF with SIZE(lb..ub)
+func PutList[T any](w *Writer, items []T, lb, ub int, fn func(*Writer, T) error) error {
+	if err := w.PutLength(len(items), lb, ub); err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := fn(w, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func GetList[T any](r *Reader, lb, ub int, fn func(*Reader) (T, error)) ([]T, error) {
+	n, err := r.GetLength(lb, ub)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]T, 0, n)
+	for i := 0; i < n; i++ {
+		item, err := fn(r)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// PutItemList encodes a list of single containers of one IE
+func PutItemList[T any](w *Writer, items []T, ub int, id uint16, criticality uint8, fn func(*Writer, T) error) error {
+	return PutList(w, items, 1, ub, func(w *Writer, item T) error {
+		return PutField(w, ProtocolIE{id, criticality, func(w *Writer) error { return fn(w, item) }})
+	})
+}
+
+func GetItemList[T any](r *Reader, ub int, id uint16, fn func(*Reader) (T, error)) ([]T, error) {
+	return GetList(r, 1, ub, func(r *Reader) (T, error) {
+		var zero T
+		ie, err := GetField(r)
+		if err != nil {
+			return zero, err
+		}
+		if ie.ID != id {
+			return zero, fmt.Errorf("unexpected ie %d in list of ie %d", ie.ID, id)
+		}
+		return fn(ie.Value)
+	})
+}
+
+// PutPLMNIdentity encodes a PLMN-Identity, the MCC and MNC TBCD encoded in
+// three octets with a filler digit for two-digit MNCs (TS 38.413 9.3.3.5)
+func PutPLMNIdentity(w *Writer, mcc, mnc string) error {
+	if len(mcc) != 3 || (len(mnc) != 2 && len(mnc) != 3) {
+		return fmt.Errorf("invalid plmn identity %s-%s", mcc, mnc)
+	}
+	for _, d := range mcc + mnc {
+		if d < '0' || d > '9' {
+			return fmt.Errorf("invalid plmn identity %s-%s", mcc, mnc)
+		}
+	}
+	mnc3 := byte(0xF)
+	if len(mnc) == 3 {
+		mnc3 = mnc[2] - '0'
+	}
+	return w.PutOctetString([]byte{
+		(mcc[1]-'0')<<4 | (mcc[0] - '0'),
+		mnc3<<4 | (mcc[2] - '0'),
+		(mnc[1]-'0')<<4 | (mnc[0] - '0'),
+	}, 3, 3)
+}
+
+func GetPLMNIdentity(r *Reader) (string, string, error) {
+	b, err := r.GetOctetString(3, 3)
+	if err != nil {
+		return "", "", err
+	}
+	var mcc, mnc []byte
+	for _, d := range []byte{b[0] & 0x0F, b[0] >> 4, b[1] & 0x0F} {
+		if d > 9 {
+			return "", "", fmt.Errorf("invalid plmn identity digit 0x%x", d)
+		}
+		mcc = append(mcc, '0'+d)
+	}
+	for _, d := range []byte{b[2] & 0x0F, b[2] >> 4, b[1] >> 4} {
+		if d == 0xF && len(mnc) == 2 {
+			break
+		}
+		if d > 9 {
+			return "", "", fmt.Errorf("invalid plmn identity digit 0x%x", d)
+		}
+		mnc = append(mnc, '0'+d)
+	}
+	return string(mcc), string(mnc), nil
+}
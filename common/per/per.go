@@ -0,0 +1,361 @@
+// Package per implements the ASN.1 aligned packed encoding rules (ITU-T
+// X.691), the transfer syntax of the 3GPP RAN application protocols such as
+// F1AP (TS 38.473 9.5) and NGAP (TS 38.413 9.5). It covers the subset of
+// PER those protocols use, and the protocol IE containers they share.
+package per
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// Writer encodes values in aligned PER
+type Writer struct {
+	buf  []byte
+	used int // Bits used in the last octet of buf; 0 or 8 when aligned
+}
+
+// PutBits appends the n least significant bits of v, most significant first
+func (w *Writer) PutBits(v uint64, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if w.used == 0 || w.used == 8 {
+			w.buf = append(w.buf, 0)
+			w.used = 0
+		}
+		if v>>uint(i)&1 == 1 {
+			w.buf[len(w.buf)-1] |= 0x80 >> uint(w.used)
+		}
+		w.used++
+	}
+}
+
+func (w *Writer) PutBool(b bool) {
+	if b {
+		w.PutBits(1, 1)
+	} else {
+		w.PutBits(0, 1)
+	}
+}
+
+// Align pads with zero bits up to the next octet boundary
+func (w *Writer) Align() {
+	w.used = 0
+}
+
+func (w *Writer) PutOctets(b []byte) {
+	w.Align()
+	w.buf = append(w.buf, b...)
+}
+
+// PutConstrained encodes v as a constrained whole number in lb..ub (X.691
+// 10.5.7.2, 10.5.7.3)
+func (w *Writer) PutConstrained(v, lb, ub uint64) error {
+	if v < lb || v > ub {
+		return fmt.Errorf("value %d outside %d..%d", v, lb, ub)
+	}
+	rng := ub - lb + 1
+	v -= lb
+	switch {
+	case rng == 1:
+	case rng <= 255:
+		w.PutBits(v, bits.Len64(rng-1))
+	case rng == 256:
+		w.Align()
+		w.PutBits(v, 8)
+	case rng <= 65536:
+		w.Align()
+		w.PutBits(v, 16)
+	default:
+		// Indefinite length case: the octet count as a constrained whole
+		// number, then the value in as few octets as possible
+		n := octetLen(v)
+		if err := w.PutConstrained(uint64(n), 1, uint64(octetLen(ub-lb))); err != nil {
+			return err
+		}
+		w.Align()
+		w.PutBits(v, 8*n)
+	}
+	return nil
+}
+
+// PutExtConstrained encodes an integer with an extensible constraint whose
+// value lies in the root
+func (w *Writer) PutExtConstrained(v, lb, ub uint64) error {
+	w.PutBool(false)
+	return w.PutConstrained(v, lb, ub)
+}
+
+// PutLength encodes a length determinant. Lengths with an upper bound below
+// 64K are constrained whole numbers; unbounded lengths are one or two
+// aligned octets (X.691 11.9)
+func (w *Writer) PutLength(n, lb, ub int) error {
+	if ub >= 0 && ub < 65536 {
+		return w.PutConstrained(uint64(n), uint64(lb), uint64(ub))
+	}
+	w.Align()
+	switch {
+	case n < 128:
+		w.PutBits(uint64(n), 8)
+	case n < 16384:
+		w.PutBits(0x8000|uint64(n), 16)
+	default:
+		return fmt.Errorf("length %d needs fragmentation", n)
+	}
+	return nil
+}
+
+// PutOctetString encodes an OCTET STRING with SIZE(lb..ub); ub < 0 means
+// unbounded (X.691 17)
+func (w *Writer) PutOctetString(b []byte, lb, ub int) error {
+	if len(b) < lb || (ub >= 0 && len(b) > ub) {
+		return fmt.Errorf("octet string of %d octets outside %d..%d", len(b), lb, ub)
+	}
+	switch {
+	case lb == ub && ub <= 2:
+		for _, o := range b {
+			w.PutBits(uint64(o), 8)
+		}
+		return nil
+	case lb == ub && ub < 65536:
+		w.PutOctets(b)
+		return nil
+	}
+	if err := w.PutLength(len(b), lb, ub); err != nil {
+		return err
+	}
+	if len(b) > 0 {
+		w.PutOctets(b)
+	}
+	return nil
+}
+
+// PutBitString encodes a BIT STRING of n bits taken from the most
+// significant bits of b, with SIZE(lb..ub) (X.691 16)
+func (w *Writer) PutBitString(b []byte, n, lb, ub int) error {
+	if n < lb || n > ub || n > 8*len(b) {
+		return fmt.Errorf("bit string of %d bits outside %d..%d", n, lb, ub)
+	}
+	if lb != ub {
+		if err := w.PutLength(n, lb, ub); err != nil {
+			return err
+		}
+	}
+	if lb != ub || n > 16 {
+		w.Align()
+	}
+	for i := 0; i < n; i++ {
+		w.PutBits(uint64(b[i/8]>>(7-uint(i%8))&1), 1)
+	}
+	return nil
+}
+
+// PutOpenType encodes the value written by fn as an open type: a length
+// determinant and the complete encoding in octets (X.691 11.2)
+func (w *Writer) PutOpenType(fn func(*Writer) error) error {
+	var inner Writer
+	if err := fn(&inner); err != nil {
+		return err
+	}
+	value := inner.Bytes()
+	if err := w.PutLength(len(value), 0, -1); err != nil {
+		return err
+	}
+	w.PutOctets(value)
+	return nil
+}
+
+// Bytes returns the encoding; an empty encoding is one zero octet
+// (X.691 11.1)
+func (w *Writer) Bytes() []byte {
+	if len(w.buf) == 0 {
+		return []byte{0}
+	}
+	return w.buf
+}
+
+// Reader decodes values in aligned PER
+type Reader struct {
+	buf []byte
+	pos int // Bit position
+}
+
+func NewReader(b []byte) *Reader {
+	return &Reader{buf: b}
+}
+
+// Remaining returns the number of octets not yet read, counting a partly
+// read octet as read
+func (r *Reader) Remaining() int {
+	return len(r.buf) - (r.pos+7)/8
+}
+
+func (r *Reader) GetBits(n int) (uint64, error) {
+	if n > 64 || r.pos+n > 8*len(r.buf) {
+		return 0, fmt.Errorf("pdu truncated at bit %d", r.pos)
+	}
+	var v uint64
+	for i := 0; i < n; i++ {
+		bit := r.buf[r.pos/8] >> (7 - uint(r.pos%8)) & 1
+		v = v<<1 | uint64(bit)
+		r.pos++
+	}
+	return v, nil
+}
+
+func (r *Reader) GetBool() (bool, error) {
+	v, err := r.GetBits(1)
+	return v == 1, err
+}
+
+func (r *Reader) Align() {
+	r.pos = (r.pos + 7) &^ 7
+}
+
+func (r *Reader) GetOctets(n int) ([]byte, error) {
+	r.Align()
+	if n < 0 || r.pos/8+n > len(r.buf) {
+		return nil, fmt.Errorf("pdu truncated: %d octets at octet %d", n, r.pos/8)
+	}
+	out := make([]byte, n)
+	copy(out, r.buf[r.pos/8:])
+	r.pos += 8 * n
+	return out, nil
+}
+
+func (r *Reader) GetConstrained(lb, ub uint64) (uint64, error) {
+	rng := ub - lb + 1
+	var v uint64
+	var err error
+	switch {
+	case rng == 1:
+	case rng <= 255:
+		v, err = r.GetBits(bits.Len64(rng - 1))
+	case rng == 256:
+		r.Align()
+		v, err = r.GetBits(8)
+	case rng <= 65536:
+		r.Align()
+		v, err = r.GetBits(16)
+	default:
+		var n uint64
+		if n, err = r.GetConstrained(1, uint64(octetLen(ub-lb))); err != nil {
+			return 0, err
+		}
+		r.Align()
+		v, err = r.GetBits(8 * int(n))
+	}
+	if err != nil {
+		return 0, err
+	}
+	if v > ub-lb {
+		return 0, fmt.Errorf("value %d outside %d..%d", v+lb, lb, ub)
+	}
+	return v + lb, nil
+}
+
+// GetExtConstrained decodes an integer with an extensible constraint; values
+// outside the root are not supported
+func (r *Reader) GetExtConstrained(lb, ub uint64) (uint64, error) {
+	extended, err := r.GetBool()
+	if err != nil {
+		return 0, err
+	}
+	if extended {
+		return 0, fmt.Errorf("integer outside its extension root")
+	}
+	return r.GetConstrained(lb, ub)
+}
+
+func (r *Reader) GetLength(lb, ub int) (int, error) {
+	if ub >= 0 && ub < 65536 {
+		n, err := r.GetConstrained(uint64(lb), uint64(ub))
+		return int(n), err
+	}
+	r.Align()
+	first, err := r.GetBits(8)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case first&0x80 == 0:
+		return int(first), nil
+	case first&0xC0 == 0x80:
+		second, err := r.GetBits(8)
+		if err != nil {
+			return 0, err
+		}
+		return int(first&0x3F)<<8 | int(second), nil
+	default:
+		return 0, fmt.Errorf("fragmented length determinants are not supported")
+	}
+}
+
+func (r *Reader) GetOctetString(lb, ub int) ([]byte, error) {
+	switch {
+	case lb == ub && ub <= 2:
+		out := make([]byte, ub)
+		for i := range out {
+			v, err := r.GetBits(8)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = byte(v)
+		}
+		return out, nil
+	case lb == ub && ub < 65536:
+		return r.GetOctets(ub)
+	}
+	n, err := r.GetLength(lb, ub)
+	if err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		return []byte{}, nil
+	}
+	return r.GetOctets(n)
+}
+
+// GetBitString decodes a BIT STRING with SIZE(lb..ub), returning the bits
+// in the most significant bits of the octets and the bit count
+func (r *Reader) GetBitString(lb, ub int) ([]byte, int, error) {
+	n := lb
+	if lb != ub {
+		var err error
+		if n, err = r.GetLength(lb, ub); err != nil {
+			return nil, 0, err
+		}
+	}
+	if lb != ub || n > 16 {
+		r.Align()
+	}
+	out := make([]byte, (n+7)/8)
+	for i := 0; i < n; i++ {
+		bit, err := r.GetBits(1)
+		if err != nil {
+			return nil, 0, err
+		}
+		out[i/8] |= byte(bit) << (7 - uint(i%8))
+	}
+	return out, n, nil
+}
+
+// GetOpenType returns a reader over the encoding of an open type value
+func (r *Reader) GetOpenType() (*Reader, error) {
+	n, err := r.GetLength(0, -1)
+	if err != nil {
+		return nil, err
+	}
+	value, err := r.GetOctets(n)
+	if err != nil {
+		return nil, err
+	}
+	return NewReader(value), nil
+}
+
+// octetLen is the number of octets needed to hold v, at least one
+func octetLen(v uint64) int {
+	if v == 0 {
+		return 1
+	}
+	return (bits.Len64(v) + 7) / 8
+}
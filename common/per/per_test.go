@@ -0,0 +1,32 @@
+package per
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPER_ConstrainedWholeNumber(t *testing.T) {
+	tests := []struct {
+		v, lb, ub uint64
+		expected  string
+	}{
+		{5, 0, 7, "a0"},           // 3-bit field
+		{12, 0, 255, "0c"},        // One aligned octet
+		{1000, 0, 1007, "03e8"},   // Two aligned octets
+		{1, 0, 1<<32 - 1, "0001"}, // Octet count, then octets
+		{0xFFFFFFFF, 0, 1<<32 - 1, "c0ffffffff"},
+	}
+
+	for _, tt := range tests {
+		var w Writer
+		require.NoError(t, w.PutConstrained(tt.v, tt.lb, tt.ub))
+		assert.Equal(t, tt.expected, hex.EncodeToString(w.Bytes()), "%d in %d..%d", tt.v, tt.lb, tt.ub)
+
+		v, err := NewReader(w.Bytes()).GetConstrained(tt.lb, tt.ub)
+		require.NoError(t, err)
+		assert.Equal(t, tt.v, v)
+	}
+}
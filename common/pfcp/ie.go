@@ -0,0 +1,1099 @@
+package pfcp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+)
+
+// PFCP IE Types (3GPP TS 29.244 Table 8.1.2-1)
+const (
+	IE_CREATE_PDR                 = 1
+	IE_PDI                        = 2
+	IE_CREATE_FAR                 = 3
+	IE_FORWARDING_PARAMETERS      = 4
+	IE_DUPLICATING_PARAMETERS     = 5
+	IE_CREATE_URR                 = 6
+	IE_CREATE_QER                 = 7
+	IE_CREATED_PDR                = 8
+	IE_UPDATE_PDR                 = 9
+	IE_UPDATE_FAR                 = 10
+	IE_UPDATE_FORWARDING_PARAMS   = 11
+	IE_UPDATE_BAR_REPORT_RESP     = 12
+	IE_UPDATE_URR                 = 13
+	IE_UPDATE_QER                 = 14
+	IE_REMOVE_PDR                 = 15
+	IE_REMOVE_FAR                 = 16
+	IE_REMOVE_URR                 = 17
+	IE_REMOVE_QER                 = 18
+	IE_CAUSE                      = 19
+	IE_SOURCE_INTERFACE           = 20
+	IE_F_TEID                     = 21
+	IE_NETWORK_INSTANCE           = 22
+	IE_SDF_FILTER                 = 23
+	IE_GATE_STATUS                = 25
+	IE_MBR                        = 26
+	IE_GBR                        = 27
+	IE_PRECEDENCE                 = 29
+	IE_VOLUME_THRESHOLD           = 31
+	IE_TIME_THRESHOLD             = 32
+	IE_REPORTING_TRIGGERS         = 37
+	IE_REPORT_TYPE                = 39
+	IE_OFFENDING_IE               = 40
+	IE_DESTINATION_INTERFACE      = 42
+	IE_UP_FUNCTION_FEATURES       = 43
+	IE_APPLY_ACTION               = 44
+	IE_DL_DATA_SERVICE_INFO       = 45
+	IE_DL_BUFFERING_DURATION      = 47
+	IE_DL_BUFFERING_PACKET_COUNT  = 48
+	IE_PDR_ID                     = 56
+	IE_F_SEID                     = 57
+	IE_NODE_ID                    = 60
+	IE_MEASUREMENT_METHOD         = 62
+	IE_USAGE_REPORT_TRIGGER       = 63
+	IE_MEASUREMENT_PERIOD         = 64
+	IE_VOLUME_MEASUREMENT         = 66
+	IE_DURATION_MEASUREMENT       = 67
+	IE_START_TIME                 = 75
+	IE_END_TIME                   = 76
+	IE_USAGE_REPORT_MOD_RESP      = 78
+	IE_USAGE_REPORT_DEL_RESP      = 79
+	IE_USAGE_REPORT_REPORT_REQ    = 80
+	IE_URR_ID                     = 81
+	IE_DOWNLINK_DATA_REPORT       = 83
+	IE_OUTER_HEADER_CREATION      = 84
+	IE_CREATE_BAR                 = 85
+	IE_UPDATE_BAR_MOD_REQ         = 86
+	IE_REMOVE_BAR                 = 87
+	IE_BAR_ID                     = 88
+	IE_CP_FUNCTION_FEATURES       = 89
+	IE_UE_IP_ADDRESS              = 93
+	IE_OUTER_HEADER_REMOVAL       = 95
+	IE_RECOVERY_TIME_STAMP        = 96
+	IE_ERROR_INDICATION_REPORT    = 99
+	IE_UR_SEQN                    = 104
+	IE_FAR_ID                     = 108
+	IE_QER_ID                     = 109
+	IE_ASSOCIATION_RELEASE_REQ    = 111
+	IE_GRACEFUL_RELEASE_PERIOD    = 112
+	IE_PDN_TYPE                   = 113
+	IE_QFI                        = 124
+	IE_EVENT_TIME_STAMP           = 156
+	IE_QOS_MONITORING_CONTROL     = 242
+	IE_REQUESTED_QOS_MONITORING   = 243
+	IE_PACKET_DELAY_THRESHOLDS    = 245
+	IE_MINIMUM_WAIT_TIME          = 246
+	IE_QOS_MONITORING_REPORT      = 247
+	IE_QOS_MONITORING_MEASUREMENT = 248
+)
+
+// Cause values (3GPP TS 29.244 8.2.1)
+const (
+	CAUSE_REQUEST_ACCEPTED             = 1
+	CAUSE_REQUEST_REJECTED             = 64
+	CAUSE_SESSION_CONTEXT_NOT_FOUND    = 65
+	CAUSE_MANDATORY_IE_MISSING         = 66
+	CAUSE_CONDITIONAL_IE_MISSING       = 67
+	CAUSE_INVALID_LENGTH               = 68
+	CAUSE_MANDATORY_IE_INCORRECT       = 69
+	CAUSE_NO_ESTABLISHED_ASSOCIATION   = 72
+	CAUSE_RULE_CREATION_FAILURE        = 73
+	CAUSE_NO_RESOURCES_AVAILABLE       = 75
+	CAUSE_SERVICE_NOT_SUPPORTED        = 76
+	CAUSE_SYSTEM_FAILURE               = 77
+	CAUSE_REDIRECTION_REQUESTED        = 78
+	CAUSE_ALL_DYNAMIC_ADDRESS_OCCUPIED = 79
+)
+
+// Interface values for Source/Destination Interface IEs (3GPP TS 29.244 8.2.2)
+const (
+	INTERFACE_ACCESS      = 0
+	INTERFACE_CORE        = 1
+	INTERFACE_SGI_LAN     = 2
+	INTERFACE_CP_FUNCTION = 3
+)
+
+// Apply Action flags (3GPP TS 29.244 8.2.26)
+const (
+	APPLY_ACTION_DROP = 0x01
+	APPLY_ACTION_FORW = 0x02
+	APPLY_ACTION_BUFF = 0x04
+	APPLY_ACTION_NOCP = 0x08
+	APPLY_ACTION_DUPL = 0x10
+)
+
+// Gate Status values (3GPP TS 29.244 8.2.7)
+const (
+	GATE_STATUS_OPEN   = 0
+	GATE_STATUS_CLOSED = 1
+)
+
+// Report Type flags (3GPP TS 29.244 8.2.21)
+const (
+	REPORT_TYPE_DLDR = 0x01 // Downlink Data Report
+	REPORT_TYPE_USAR = 0x02 // Usage Report
+	REPORT_TYPE_ERIR = 0x04 // Error Indication Report
+	REPORT_TYPE_UPIR = 0x08 // User Plane Inactivity Report
+	REPORT_TYPE_SESR = 0x20 // Session Report, carrying QoS Monitoring Reports
+)
+
+// Reporting Triggers and Usage Report Trigger flags (3GPP TS 29.244 8.2.19,
+// 8.2.41). Octet 5 is carried in the low byte, octet 6 in the next.
+const (
+	REPORTING_TRIGGER_PERIO    = 0x01   // Periodic
+	REPORTING_TRIGGER_VOLTH    = 0x02   // Volume threshold
+	REPORTING_TRIGGER_TIMTH    = 0x04   // Time threshold
+	USAGE_REPORT_TRIGGER_IMMER = 0x80   // Immediate report, usage reports only
+	USAGE_REPORT_TRIGGER_TERMR = 0x0800 // Termination report, usage reports only
+)
+
+// Measurement Method flags (3GPP TS 29.244 8.2.40)
+const (
+	MEASUREMENT_METHOD_DURAT = 0x01
+	MEASUREMENT_METHOD_VOLUM = 0x02
+	MEASUREMENT_METHOD_EVENT = 0x04
+)
+
+// QoS monitoring direction flags, shared by the Requested QoS Monitoring,
+// Packet Delay Thresholds and QoS Monitoring Measurement IEs
+// (3GPP TS 29.244 8.2.169, 8.2.171, 8.2.173)
+const (
+	QOS_MONITORING_DL = 0x01 // Downlink packet delay
+	QOS_MONITORING_UL = 0x02 // Uplink packet delay
+	QOS_MONITORING_RP = 0x04 // Round trip packet delay
+)
+
+// DL Data Service Information flags (3GPP TS 29.244 8.2.27)
+const (
+	DL_DATA_SERVICE_INFO_PPI  = 0x01
+	DL_DATA_SERVICE_INFO_QFII = 0x02
+)
+
+// Node ID types (3GPP TS 29.244 8.2.38)
+const (
+	NODE_ID_TYPE_IPV4 = 0
+	NODE_ID_TYPE_IPV6 = 1
+	NODE_ID_TYPE_FQDN = 2
+)
+
+// Outer Header Creation descriptions (3GPP TS 29.244 8.2.56)
+const (
+	OUTER_HEADER_CREATION_GTPU_UDP_IPV4 = 0x0100
+	OUTER_HEADER_CREATION_GTPU_UDP_IPV6 = 0x0200
+)
+
+// Outer Header Removal descriptions (3GPP TS 29.244 8.2.64)
+const (
+	OUTER_HEADER_REMOVAL_GTPU_UDP_IPV4 = 0
+	OUTER_HEADER_REMOVAL_GTPU_UDP_IPV6 = 1
+)
+
+// ntpEpochOffset is the number of seconds between 1900-01-01 and 1970-01-01
+const ntpEpochOffset = 2208988800
+
+// NewIE creates an IE with a raw value
+func NewIE(ieType uint16, value []byte) *IE {
+	return &IE{Type: ieType, Value: value}
+}
+
+// NewGroupedIE creates a grouped IE from child IEs
+func NewGroupedIE(ieType uint16, children ...*IE) *IE {
+	return &IE{Type: ieType, Value: MarshalIEs(children)}
+}
+
+// NewUint8IE creates an IE with a one octet value
+func NewUint8IE(ieType uint16, v uint8) *IE {
+	return &IE{Type: ieType, Value: []byte{v}}
+}
+
+// NewUint16IE creates an IE with a two octet value
+func NewUint16IE(ieType uint16, v uint16) *IE {
+	value := make([]byte, 2)
+	binary.BigEndian.PutUint16(value, v)
+	return &IE{Type: ieType, Value: value}
+}
+
+// NewUint32IE creates an IE with a four octet value
+func NewUint32IE(ieType uint16, v uint32) *IE {
+	value := make([]byte, 4)
+	binary.BigEndian.PutUint32(value, v)
+	return &IE{Type: ieType, Value: value}
+}
+
+// Uint8 decodes a one octet value
+func (ie *IE) Uint8() (uint8, error) {
+	if len(ie.Value) < 1 {
+		return 0, fmt.Errorf("IE type %d: value too short for uint8", ie.Type)
+	}
+	return ie.Value[0], nil
+}
+
+// Uint16 decodes a two octet value
+func (ie *IE) Uint16() (uint16, error) {
+	if len(ie.Value) < 2 {
+		return 0, fmt.Errorf("IE type %d: value too short for uint16", ie.Type)
+	}
+	return binary.BigEndian.Uint16(ie.Value[:2]), nil
+}
+
+// Uint32 decodes a four octet value
+func (ie *IE) Uint32() (uint32, error) {
+	if len(ie.Value) < 4 {
+		return 0, fmt.Errorf("IE type %d: value too short for uint32", ie.Type)
+	}
+	return binary.BigEndian.Uint32(ie.Value[:4]), nil
+}
+
+// NewCauseIE creates a Cause IE
+func NewCauseIE(cause uint8) *IE {
+	return NewUint8IE(IE_CAUSE, cause)
+}
+
+// NewRecoveryTimeStampIE creates a Recovery Time Stamp IE (NTP seconds)
+func NewRecoveryTimeStampIE(t time.Time) *IE {
+	return NewUint32IE(IE_RECOVERY_TIME_STAMP, uint32(t.Unix()+ntpEpochOffset))
+}
+
+// RecoveryTimeStamp decodes a Recovery Time Stamp IE
+func (ie *IE) RecoveryTimeStamp() (time.Time, error) {
+	v, err := ie.Uint32()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(v)-ntpEpochOffset, 0), nil
+}
+
+// NodeID represents the Node ID IE value
+type NodeID struct {
+	Type uint8
+	IP   net.IP
+	FQDN string
+}
+
+// NewNodeIDIE creates a Node ID IE from an IP address or FQDN
+func NewNodeIDIE(nodeID string) *IE {
+	if ip := net.ParseIP(nodeID); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			return NewIE(IE_NODE_ID, append([]byte{NODE_ID_TYPE_IPV4}, ip4...))
+		}
+		return NewIE(IE_NODE_ID, append([]byte{NODE_ID_TYPE_IPV6}, ip.To16()...))
+	}
+	return NewIE(IE_NODE_ID, append([]byte{NODE_ID_TYPE_FQDN}, encodeFQDN(nodeID)...))
+}
+
+// NodeID decodes a Node ID IE
+func (ie *IE) NodeID() (*NodeID, error) {
+	if len(ie.Value) < 1 {
+		return nil, fmt.Errorf("node ID IE empty")
+	}
+
+	nodeID := &NodeID{Type: ie.Value[0] & 0x0F}
+	value := ie.Value[1:]
+
+	switch nodeID.Type {
+	case NODE_ID_TYPE_IPV4:
+		if len(value) < net.IPv4len {
+			return nil, fmt.Errorf("node ID IPv4 too short")
+		}
+		nodeID.IP = net.IP(append([]byte(nil), value[:net.IPv4len]...))
+	case NODE_ID_TYPE_IPV6:
+		if len(value) < net.IPv6len {
+			return nil, fmt.Errorf("node ID IPv6 too short")
+		}
+		nodeID.IP = net.IP(append([]byte(nil), value[:net.IPv6len]...))
+	case NODE_ID_TYPE_FQDN:
+		nodeID.FQDN = decodeFQDN(value)
+	default:
+		return nil, fmt.Errorf("unknown node ID type: %d", nodeID.Type)
+	}
+
+	return nodeID, nil
+}
+
+// String returns the Node ID as an address or FQDN
+func (n *NodeID) String() string {
+	if n.Type == NODE_ID_TYPE_FQDN {
+		return n.FQDN
+	}
+	return n.IP.String()
+}
+
+// FSEID represents the F-SEID IE value
+type FSEID struct {
+	SEID uint64
+	IPv4 net.IP
+	IPv6 net.IP
+}
+
+// NewFSEIDIE creates an F-SEID IE
+func NewFSEIDIE(seid uint64, ipv4, ipv6 net.IP) *IE {
+	value := make([]byte, 9)
+	binary.BigEndian.PutUint64(value[1:9], seid)
+	if ip4 := ipv4.To4(); ip4 != nil {
+		value[0] |= 0x02
+		value = append(value, ip4...)
+	}
+	if ipv6 != nil {
+		value[0] |= 0x01
+		value = append(value, ipv6.To16()...)
+	}
+	return NewIE(IE_F_SEID, value)
+}
+
+// FSEID decodes an F-SEID IE
+func (ie *IE) FSEID() (*FSEID, error) {
+	if len(ie.Value) < 9 {
+		return nil, fmt.Errorf("F-SEID IE too short: %d octets", len(ie.Value))
+	}
+
+	flags := ie.Value[0]
+	fseid := &FSEID{SEID: binary.BigEndian.Uint64(ie.Value[1:9])}
+	offset := 9
+
+	if flags&0x02 != 0 {
+		if len(ie.Value) < offset+net.IPv4len {
+			return nil, fmt.Errorf("F-SEID IPv4 address truncated")
+		}
+		fseid.IPv4 = net.IP(append([]byte(nil), ie.Value[offset:offset+net.IPv4len]...))
+		offset += net.IPv4len
+	}
+	if flags&0x01 != 0 {
+		if len(ie.Value) < offset+net.IPv6len {
+			return nil, fmt.Errorf("F-SEID IPv6 address truncated")
+		}
+		fseid.IPv6 = net.IP(append([]byte(nil), ie.Value[offset:offset+net.IPv6len]...))
+	}
+
+	return fseid, nil
+}
+
+// FTEID represents the F-TEID IE value
+type FTEID struct {
+	TEID     uint32
+	IPv4     net.IP
+	IPv6     net.IP
+	ChooseID bool  // CH: UP function allocates the TEID
+	ChooseV4 bool  // With CH, request an IPv4 address
+	ChooseV6 bool  // With CH, request an IPv6 address
+	HasCHID  bool  // CHID: same TEID for PDRs sharing the Choose ID
+	CHID     uint8 // Choose ID
+}
+
+// NewFTEIDIE creates an F-TEID IE
+func NewFTEIDIE(fteid *FTEID) *IE {
+	var flags uint8
+	value := []byte{0}
+
+	if fteid.ChooseID {
+		flags |= 0x04
+		if fteid.ChooseV4 {
+			flags |= 0x01
+		}
+		if fteid.ChooseV6 {
+			flags |= 0x02
+		}
+		if fteid.HasCHID {
+			flags |= 0x08
+			value = append(value, fteid.CHID)
+		}
+		value[0] = flags
+		return NewIE(IE_F_TEID, value)
+	}
+
+	value = append(value, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(value[1:5], fteid.TEID)
+	if ip4 := fteid.IPv4.To4(); ip4 != nil {
+		flags |= 0x01
+		value = append(value, ip4...)
+	}
+	if fteid.IPv6 != nil {
+		flags |= 0x02
+		value = append(value, fteid.IPv6.To16()...)
+	}
+	value[0] = flags
+	return NewIE(IE_F_TEID, value)
+}
+
+// FTEID decodes an F-TEID IE
+func (ie *IE) FTEID() (*FTEID, error) {
+	if len(ie.Value) < 1 {
+		return nil, fmt.Errorf("F-TEID IE empty")
+	}
+
+	flags := ie.Value[0]
+	fteid := &FTEID{}
+
+	if flags&0x04 != 0 {
+		fteid.ChooseID = true
+		fteid.ChooseV4 = flags&0x01 != 0
+		fteid.ChooseV6 = flags&0x02 != 0
+		if flags&0x08 != 0 {
+			if len(ie.Value) < 2 {
+				return nil, fmt.Errorf("F-TEID choose ID truncated")
+			}
+			fteid.HasCHID = true
+			fteid.CHID = ie.Value[1]
+		}
+		return fteid, nil
+	}
+
+	if len(ie.Value) < 5 {
+		return nil, fmt.Errorf("F-TEID IE too short: %d octets", len(ie.Value))
+	}
+	fteid.TEID = binary.BigEndian.Uint32(ie.Value[1:5])
+	offset := 5
+
+	if flags&0x01 != 0 {
+		if len(ie.Value) < offset+net.IPv4len {
+			return nil, fmt.Errorf("F-TEID IPv4 address truncated")
+		}
+		fteid.IPv4 = net.IP(append([]byte(nil), ie.Value[offset:offset+net.IPv4len]...))
+		offset += net.IPv4len
+	}
+	if flags&0x02 != 0 {
+		if len(ie.Value) < offset+net.IPv6len {
+			return nil, fmt.Errorf("F-TEID IPv6 address truncated")
+		}
+		fteid.IPv6 = net.IP(append([]byte(nil), ie.Value[offset:offset+net.IPv6len]...))
+	}
+
+	return fteid, nil
+}
+
+// UEIPAddress represents the UE IP Address IE value
+type UEIPAddress struct {
+	IPv4          net.IP
+	IPv6          net.IP
+	IsDestination bool // S/D flag: address is the destination (downlink)
+	// IPv6PrefixLength is the delegated prefix length (IP6PL flag). Zero
+	// means the default /64.
+	IPv6PrefixLength uint8
+}
+
+// NewUEIPAddressIE creates a UE IP Address IE
+func NewUEIPAddressIE(ueIP *UEIPAddress) *IE {
+	value := []byte{0}
+	if ueIP.IsDestination {
+		value[0] |= 0x04
+	}
+	if ip4 := ueIP.IPv4.To4(); ip4 != nil {
+		value[0] |= 0x02
+		value = append(value, ip4...)
+	}
+	if ueIP.IPv6 != nil {
+		value[0] |= 0x01
+		value = append(value, ueIP.IPv6.To16()...)
+		if ueIP.IPv6PrefixLength != 0 {
+			value[0] |= 0x40
+			value = append(value, ueIP.IPv6PrefixLength)
+		}
+	}
+	return NewIE(IE_UE_IP_ADDRESS, value)
+}
+
+// UEIPAddress decodes a UE IP Address IE
+func (ie *IE) UEIPAddress() (*UEIPAddress, error) {
+	if len(ie.Value) < 1 {
+		return nil, fmt.Errorf("UE IP address IE empty")
+	}
+
+	flags := ie.Value[0]
+	ueIP := &UEIPAddress{IsDestination: flags&0x04 != 0}
+	offset := 1
+
+	if flags&0x02 != 0 {
+		if len(ie.Value) < offset+net.IPv4len {
+			return nil, fmt.Errorf("UE IPv4 address truncated")
+		}
+		ueIP.IPv4 = net.IP(append([]byte(nil), ie.Value[offset:offset+net.IPv4len]...))
+		offset += net.IPv4len
+	}
+	if flags&0x01 != 0 {
+		if len(ie.Value) < offset+net.IPv6len {
+			return nil, fmt.Errorf("UE IPv6 address truncated")
+		}
+		ueIP.IPv6 = net.IP(append([]byte(nil), ie.Value[offset:offset+net.IPv6len]...))
+		offset += net.IPv6len
+	}
+	if flags&0x08 != 0 {
+		// IPv6 Prefix Delegation Bits are not used
+		offset++
+	}
+	if flags&0x40 != 0 {
+		if len(ie.Value) < offset+1 {
+			return nil, fmt.Errorf("UE IPv6 prefix length truncated")
+		}
+		ueIP.IPv6PrefixLength = ie.Value[offset]
+	}
+
+	return ueIP, nil
+}
+
+// OuterHeaderCreation represents the Outer Header Creation IE value
+type OuterHeaderCreation struct {
+	Description uint16
+	TEID        uint32
+	IPv4        net.IP
+	IPv6        net.IP
+}
+
+// NewOuterHeaderCreationIE creates an Outer Header Creation IE
+func NewOuterHeaderCreationIE(ohc *OuterHeaderCreation) *IE {
+	value := make([]byte, 6)
+	binary.BigEndian.PutUint16(value[0:2], ohc.Description)
+	binary.BigEndian.PutUint32(value[2:6], ohc.TEID)
+	if ohc.Description&OUTER_HEADER_CREATION_GTPU_UDP_IPV4 != 0 {
+		value = append(value, ohc.IPv4.To4()...)
+	}
+	if ohc.Description&OUTER_HEADER_CREATION_GTPU_UDP_IPV6 != 0 {
+		value = append(value, ohc.IPv6.To16()...)
+	}
+	return NewIE(IE_OUTER_HEADER_CREATION, value)
+}
+
+// OuterHeaderCreation decodes an Outer Header Creation IE
+func (ie *IE) OuterHeaderCreation() (*OuterHeaderCreation, error) {
+	if len(ie.Value) < 6 {
+		return nil, fmt.Errorf("outer header creation IE too short: %d octets", len(ie.Value))
+	}
+
+	ohc := &OuterHeaderCreation{
+		Description: binary.BigEndian.Uint16(ie.Value[0:2]),
+		TEID:        binary.BigEndian.Uint32(ie.Value[2:6]),
+	}
+	offset := 6
+
+	if ohc.Description&OUTER_HEADER_CREATION_GTPU_UDP_IPV4 != 0 {
+		if len(ie.Value) < offset+net.IPv4len {
+			return nil, fmt.Errorf("outer header creation IPv4 address truncated")
+		}
+		ohc.IPv4 = net.IP(append([]byte(nil), ie.Value[offset:offset+net.IPv4len]...))
+		offset += net.IPv4len
+	}
+	if ohc.Description&OUTER_HEADER_CREATION_GTPU_UDP_IPV6 != 0 {
+		if len(ie.Value) < offset+net.IPv6len {
+			return nil, fmt.Errorf("outer header creation IPv6 address truncated")
+		}
+		ohc.IPv6 = net.IP(append([]byte(nil), ie.Value[offset:offset+net.IPv6len]...))
+	}
+
+	return ohc, nil
+}
+
+// DownlinkDataReport represents the Downlink Data Report IE of a Session
+// Report Request (3GPP TS 29.244 7.5.8.2)
+type DownlinkDataReport struct {
+	PDRID uint16
+	QFI   uint8 // From the DL Data Service Information, 0 when absent
+}
+
+// NewDownlinkDataReportIE creates a Downlink Data Report IE
+func NewDownlinkDataReportIE(r *DownlinkDataReport) *IE {
+	children := []*IE{NewUint16IE(IE_PDR_ID, r.PDRID)}
+	if r.QFI != 0 {
+		children = append(children, NewIE(IE_DL_DATA_SERVICE_INFO,
+			[]byte{DL_DATA_SERVICE_INFO_QFII, r.QFI & 0x3f}))
+	}
+	return NewGroupedIE(IE_DOWNLINK_DATA_REPORT, children...)
+}
+
+// DownlinkDataReport decodes a Downlink Data Report IE
+func (ie *IE) DownlinkDataReport() (*DownlinkDataReport, error) {
+	children, err := ie.ChildIEs()
+	if err != nil {
+		return nil, fmt.Errorf("downlink data report: %w", err)
+	}
+
+	pdrID := FindIE(children, IE_PDR_ID)
+	if pdrID == nil {
+		return nil, fmt.Errorf("downlink data report: missing PDR ID")
+	}
+	r := &DownlinkDataReport{}
+	if r.PDRID, err = pdrID.Uint16(); err != nil {
+		return nil, err
+	}
+
+	if info := FindIE(children, IE_DL_DATA_SERVICE_INFO); info != nil && len(info.Value) > 0 {
+		flags := info.Value[0]
+		offset := 1
+		if flags&DL_DATA_SERVICE_INFO_PPI != 0 {
+			offset++
+		}
+		if flags&DL_DATA_SERVICE_INFO_QFII != 0 {
+			if len(info.Value) <= offset {
+				return nil, fmt.Errorf("DL data service information QFI truncated")
+			}
+			r.QFI = info.Value[offset] & 0x3f
+		}
+	}
+	return r, nil
+}
+
+// ErrorIndicationReport represents the Error Indication Report IE of a
+// Session Report Request (3GPP TS 29.244 7.5.8.4)
+type ErrorIndicationReport struct {
+	RemoteFTEIDs []*FTEID // Tunnels a GTP-U peer sent an Error Indication for
+}
+
+// NewErrorIndicationReportIE creates an Error Indication Report IE
+func NewErrorIndicationReportIE(r *ErrorIndicationReport) *IE {
+	children := make([]*IE, 0, len(r.RemoteFTEIDs))
+	for _, fteid := range r.RemoteFTEIDs {
+		children = append(children, NewFTEIDIE(fteid))
+	}
+	return NewGroupedIE(IE_ERROR_INDICATION_REPORT, children...)
+}
+
+// ErrorIndicationReport decodes an Error Indication Report IE
+func (ie *IE) ErrorIndicationReport() (*ErrorIndicationReport, error) {
+	children, err := ie.ChildIEs()
+	if err != nil {
+		return nil, fmt.Errorf("error indication report: %w", err)
+	}
+
+	fteids := FindIEs(children, IE_F_TEID)
+	if len(fteids) == 0 {
+		return nil, fmt.Errorf("error indication report: missing remote F-TEID")
+	}
+	r := &ErrorIndicationReport{}
+	for _, child := range fteids {
+		fteid, err := child.FTEID()
+		if err != nil {
+			return nil, fmt.Errorf("error indication report: %w", err)
+		}
+		r.RemoteFTEIDs = append(r.RemoteFTEIDs, fteid)
+	}
+	return r, nil
+}
+
+// QoSMonitoring represents the QoS Monitoring per QoS flow Control
+// Information IE (3GPP TS 29.244 7.5.2.9). It is carried inside the Create
+// and Update QER IEs of the QoS flow it monitors.
+type QoSMonitoring struct {
+	Requested          uint8 // QOS_MONITORING_* flags
+	DownlinkThreshold  time.Duration
+	UplinkThreshold    time.Duration
+	RoundTripThreshold time.Duration
+	MinimumWaitTime    time.Duration
+}
+
+// NewQoSMonitoringIE creates a QoS Monitoring per QoS flow Control
+// Information IE
+func NewQoSMonitoringIE(m *QoSMonitoring) *IE {
+	children := []*IE{NewUint8IE(IE_REQUESTED_QOS_MONITORING, m.Requested&0x07)}
+	if value := encodeDelays(m.DownlinkThreshold, m.UplinkThreshold, m.RoundTripThreshold); len(value) > 1 {
+		children = append(children, NewIE(IE_PACKET_DELAY_THRESHOLDS, value))
+	}
+	if m.MinimumWaitTime > 0 {
+		children = append(children, NewUint32IE(IE_MINIMUM_WAIT_TIME, uint32(m.MinimumWaitTime/time.Second)))
+	}
+	return NewGroupedIE(IE_QOS_MONITORING_CONTROL, children...)
+}
+
+// QoSMonitoring decodes a QoS Monitoring per QoS flow Control Information IE
+func (ie *IE) QoSMonitoring() (*QoSMonitoring, error) {
+	children, err := ie.ChildIEs()
+	if err != nil {
+		return nil, fmt.Errorf("QoS monitoring: %w", err)
+	}
+
+	requested := FindIE(children, IE_REQUESTED_QOS_MONITORING)
+	if requested == nil {
+		return nil, fmt.Errorf("QoS monitoring: missing requested QoS monitoring")
+	}
+	m := &QoSMonitoring{}
+	if m.Requested, err = requested.Uint8(); err != nil {
+		return nil, fmt.Errorf("QoS monitoring: %w", err)
+	}
+	m.Requested &= 0x07
+
+	if thresholds := FindIE(children, IE_PACKET_DELAY_THRESHOLDS); thresholds != nil {
+		m.DownlinkThreshold, m.UplinkThreshold, m.RoundTripThreshold, err = decodeDelays(thresholds.Value)
+		if err != nil {
+			return nil, fmt.Errorf("packet delay thresholds: %w", err)
+		}
+	}
+	if wait := FindIE(children, IE_MINIMUM_WAIT_TIME); wait != nil {
+		seconds, err := wait.Uint32()
+		if err != nil {
+			return nil, fmt.Errorf("minimum wait time: %w", err)
+		}
+		m.MinimumWaitTime = time.Duration(seconds) * time.Second
+	}
+	return m, nil
+}
+
+// QoSMonitoringReport represents the QoS Monitoring Report IE of a Session
+// Report Request (3GPP TS 29.244 7.5.8.6). Delays not measured are zero.
+type QoSMonitoringReport struct {
+	QFI            uint8
+	DownlinkDelay  time.Duration
+	UplinkDelay    time.Duration
+	RoundTripDelay time.Duration
+	EventTime      time.Time
+}
+
+// NewQoSMonitoringReportIE creates a QoS Monitoring Report IE
+func NewQoSMonitoringReportIE(r *QoSMonitoringReport) *IE {
+	return NewGroupedIE(IE_QOS_MONITORING_REPORT,
+		NewUint8IE(IE_QFI, r.QFI&0x3f),
+		NewIE(IE_QOS_MONITORING_MEASUREMENT, encodeDelays(r.DownlinkDelay, r.UplinkDelay, r.RoundTripDelay)),
+		NewUint32IE(IE_EVENT_TIME_STAMP, uint32(r.EventTime.Unix()+ntpEpochOffset)),
+	)
+}
+
+// QoSMonitoringReport decodes a QoS Monitoring Report IE
+func (ie *IE) QoSMonitoringReport() (*QoSMonitoringReport, error) {
+	children, err := ie.ChildIEs()
+	if err != nil {
+		return nil, fmt.Errorf("QoS monitoring report: %w", err)
+	}
+
+	qfi := FindIE(children, IE_QFI)
+	measurement := FindIE(children, IE_QOS_MONITORING_MEASUREMENT)
+	if qfi == nil || measurement == nil {
+		return nil, fmt.Errorf("QoS monitoring report: missing QFI or measurement")
+	}
+	r := &QoSMonitoringReport{}
+	if r.QFI, err = qfi.Uint8(); err != nil {
+		return nil, fmt.Errorf("QoS monitoring report: %w", err)
+	}
+	r.QFI &= 0x3f
+	r.DownlinkDelay, r.UplinkDelay, r.RoundTripDelay, err = decodeDelays(measurement.Value)
+	if err != nil {
+		return nil, fmt.Errorf("QoS monitoring measurement: %w", err)
+	}
+	if ts := FindIE(children, IE_EVENT_TIME_STAMP); ts != nil {
+		v, err := ts.Uint32()
+		if err != nil {
+			return nil, fmt.Errorf("event time stamp: %w", err)
+		}
+		r.EventTime = time.Unix(int64(v)-ntpEpochOffset, 0)
+	}
+	return r, nil
+}
+
+// NewReportingTriggersIE creates a Reporting Triggers or Usage Report
+// Trigger IE
+func NewReportingTriggersIE(ieType uint16, triggers uint32) *IE {
+	return NewIE(ieType, []byte{byte(triggers), byte(triggers >> 8), byte(triggers >> 16)})
+}
+
+// ReportingTriggers decodes a Reporting Triggers or Usage Report Trigger IE.
+// Octets the peer's release does not send are zero.
+func (ie *IE) ReportingTriggers() (uint32, error) {
+	if len(ie.Value) < 1 {
+		return 0, fmt.Errorf("reporting triggers IE empty")
+	}
+	var triggers uint32
+	for i, octet := range ie.Value[:min(len(ie.Value), 3)] {
+		triggers |= uint32(octet) << (8 * i)
+	}
+	return triggers, nil
+}
+
+// VolumeThreshold represents the Volume Threshold IE (3GPP TS 29.244
+// 8.2.13) in bytes. Zero volumes are not present.
+type VolumeThreshold struct {
+	Total    uint64
+	Uplink   uint64
+	Downlink uint64
+}
+
+// NewVolumeThresholdIE creates a Volume Threshold IE
+func NewVolumeThresholdIE(v *VolumeThreshold) *IE {
+	return NewIE(IE_VOLUME_THRESHOLD, encodeVolumes([]uint64{v.Total, v.Uplink, v.Downlink}))
+}
+
+// VolumeThreshold decodes a Volume Threshold IE
+func (ie *IE) VolumeThreshold() (*VolumeThreshold, error) {
+	volumes, err := decodeVolumes(ie.Value, 3)
+	if err != nil {
+		return nil, fmt.Errorf("volume threshold: %w", err)
+	}
+	return &VolumeThreshold{Total: volumes[0], Uplink: volumes[1], Downlink: volumes[2]}, nil
+}
+
+// VolumeMeasurement represents the Volume Measurement IE (3GPP TS 29.244
+// 8.2.44). Zero counts are not present.
+type VolumeMeasurement struct {
+	TotalVolume     uint64
+	UplinkVolume    uint64
+	DownlinkVolume  uint64
+	TotalPackets    uint64
+	UplinkPackets   uint64
+	DownlinkPackets uint64
+}
+
+// NewVolumeMeasurementIE creates a Volume Measurement IE
+func NewVolumeMeasurementIE(v *VolumeMeasurement) *IE {
+	return NewIE(IE_VOLUME_MEASUREMENT, encodeVolumes([]uint64{
+		v.TotalVolume, v.UplinkVolume, v.DownlinkVolume,
+		v.TotalPackets, v.UplinkPackets, v.DownlinkPackets,
+	}))
+}
+
+// VolumeMeasurement decodes a Volume Measurement IE
+func (ie *IE) VolumeMeasurement() (*VolumeMeasurement, error) {
+	counts, err := decodeVolumes(ie.Value, 6)
+	if err != nil {
+		return nil, fmt.Errorf("volume measurement: %w", err)
+	}
+	return &VolumeMeasurement{
+		TotalVolume:     counts[0],
+		UplinkVolume:    counts[1],
+		DownlinkVolume:  counts[2],
+		TotalPackets:    counts[3],
+		UplinkPackets:   counts[4],
+		DownlinkPackets: counts[5],
+	}, nil
+}
+
+// UsageReport represents the Usage Report IE of a Session Report Request,
+// Session Modification Response or Session Deletion Response (3GPP TS
+// 29.244 7.5.8.3, 7.5.5.2, 7.5.7.2)
+type UsageReport struct {
+	URRID          uint32
+	SequenceNumber uint32 // UR-SEQN
+	Trigger        uint32 // Usage Report Trigger flags
+	StartTime      time.Time
+	EndTime        time.Time
+	Volume         *VolumeMeasurement // nil when volume is not measured
+	Duration       time.Duration      // Zero when duration is not measured
+}
+
+// NewUsageReportIE creates a Usage Report IE of the given type
+func NewUsageReportIE(ieType uint16, r *UsageReport) *IE {
+	children := []*IE{
+		NewUint32IE(IE_URR_ID, r.URRID),
+		NewUint32IE(IE_UR_SEQN, r.SequenceNumber),
+		NewReportingTriggersIE(IE_USAGE_REPORT_TRIGGER, r.Trigger),
+	}
+	if !r.StartTime.IsZero() {
+		children = append(children, NewUint32IE(IE_START_TIME, uint32(r.StartTime.Unix()+ntpEpochOffset)))
+	}
+	if !r.EndTime.IsZero() {
+		children = append(children, NewUint32IE(IE_END_TIME, uint32(r.EndTime.Unix()+ntpEpochOffset)))
+	}
+	if r.Volume != nil {
+		children = append(children, NewVolumeMeasurementIE(r.Volume))
+	}
+	if r.Duration > 0 {
+		children = append(children, NewUint32IE(IE_DURATION_MEASUREMENT, uint32(r.Duration/time.Second)))
+	}
+	return NewGroupedIE(ieType, children...)
+}
+
+// UsageReport decodes a Usage Report IE
+func (ie *IE) UsageReport() (*UsageReport, error) {
+	children, err := ie.ChildIEs()
+	if err != nil {
+		return nil, fmt.Errorf("usage report: %w", err)
+	}
+
+	urrID := FindIE(children, IE_URR_ID)
+	seqn := FindIE(children, IE_UR_SEQN)
+	trigger := FindIE(children, IE_USAGE_REPORT_TRIGGER)
+	if urrID == nil || seqn == nil || trigger == nil {
+		return nil, fmt.Errorf("usage report: missing URR ID, UR-SEQN or trigger")
+	}
+	r := &UsageReport{}
+	if r.URRID, err = urrID.Uint32(); err != nil {
+		return nil, fmt.Errorf("usage report: %w", err)
+	}
+	if r.SequenceNumber, err = seqn.Uint32(); err != nil {
+		return nil, fmt.Errorf("usage report: %w", err)
+	}
+	if r.Trigger, err = trigger.ReportingTriggers(); err != nil {
+		return nil, fmt.Errorf("usage report: %w", err)
+	}
+
+	for _, child := range children {
+		switch child.Type {
+		case IE_START_TIME, IE_END_TIME:
+			var v uint32
+			if v, err = child.Uint32(); err == nil {
+				t := time.Unix(int64(v)-ntpEpochOffset, 0)
+				if child.Type == IE_START_TIME {
+					r.StartTime = t
+				} else {
+					r.EndTime = t
+				}
+			}
+		case IE_VOLUME_MEASUREMENT:
+			r.Volume, err = child.VolumeMeasurement()
+		case IE_DURATION_MEASUREMENT:
+			var seconds uint32
+			seconds, err = child.Uint32()
+			r.Duration = time.Duration(seconds) * time.Second
+		}
+		if err != nil {
+			return nil, fmt.Errorf("usage report %d: %w", r.URRID, err)
+		}
+	}
+	return r, nil
+}
+
+// encodeVolumes encodes the flags octet followed by the eight octet values
+// of the non-zero volumes, flag bit i announcing volumes[i]
+func encodeVolumes(volumes []uint64) []byte {
+	value := []byte{0}
+	for i, v := range volumes {
+		if v == 0 {
+			continue
+		}
+		value[0] |= 1 << i
+		value = binary.BigEndian.AppendUint64(value, v)
+	}
+	return value
+}
+
+// decodeVolumes decodes the first n volumes written by encodeVolumes
+func decodeVolumes(value []byte, n int) ([]uint64, error) {
+	if len(value) < 1 {
+		return nil, fmt.Errorf("empty value")
+	}
+	flags := value[0]
+	offset := 1
+	volumes := make([]uint64, n)
+	for i := range volumes {
+		if flags&(1<<i) == 0 {
+			continue
+		}
+		if len(value) < offset+8 {
+			return nil, fmt.Errorf("value truncated")
+		}
+		volumes[i] = binary.BigEndian.Uint64(value[offset:])
+		offset += 8
+	}
+	return volumes, nil
+}
+
+// encodeDelays encodes the flags octet followed by the four octet
+// millisecond values of the non-zero downlink, uplink and round trip delays,
+// as used by the Packet Delay Thresholds and QoS Monitoring Measurement IEs
+func encodeDelays(dl, ul, rp time.Duration) []byte {
+	value := []byte{0}
+	for i, d := range []time.Duration{dl, ul, rp} {
+		if d <= 0 {
+			continue
+		}
+		value[0] |= 1 << i
+		value = binary.BigEndian.AppendUint32(value, uint32(d/time.Millisecond))
+	}
+	return value
+}
+
+// decodeDelays decodes the values written by encodeDelays
+func decodeDelays(value []byte) (dl, ul, rp time.Duration, err error) {
+	if len(value) < 1 {
+		return 0, 0, 0, fmt.Errorf("empty value")
+	}
+	flags := value[0]
+	offset := 1
+	delays := make([]time.Duration, 3)
+	for i := range delays {
+		if flags&(1<<i) == 0 {
+			continue
+		}
+		if len(value) < offset+4 {
+			return 0, 0, 0, fmt.Errorf("value truncated")
+		}
+		delays[i] = time.Duration(binary.BigEndian.Uint32(value[offset:])) * time.Millisecond
+		offset += 4
+	}
+	return delays[0], delays[1], delays[2], nil
+}
+
+// BitRate represents the MBR and GBR IE values in bps. On the wire the rates
+// are 40 bit values in kbps (3GPP TS 29.244 8.2.8, 8.2.9).
+type BitRate struct {
+	Uplink   uint64
+	Downlink uint64
+}
+
+// NewBitRateIE creates an MBR or GBR IE
+func NewBitRateIE(ieType uint16, br *BitRate) *IE {
+	value := make([]byte, 10)
+	putUint40(value[0:5], br.Uplink/1000)
+	putUint40(value[5:10], br.Downlink/1000)
+	return NewIE(ieType, value)
+}
+
+// BitRate decodes an MBR or GBR IE
+func (ie *IE) BitRate() (*BitRate, error) {
+	if len(ie.Value) < 10 {
+		return nil, fmt.Errorf("bit rate IE too short: %d octets", len(ie.Value))
+	}
+	return &BitRate{
+		Uplink:   uint40(ie.Value[0:5]) * 1000,
+		Downlink: uint40(ie.Value[5:10]) * 1000,
+	}, nil
+}
+
+// NewGateStatusIE creates a Gate Status IE with the same gate in both
+// directions
+func NewGateStatusIE(gate uint8) *IE {
+	return NewUint8IE(IE_GATE_STATUS, (gate&0x03)<<2|gate&0x03)
+}
+
+// GateStatus decodes a Gate Status IE as uplink and downlink gates
+func (ie *IE) GateStatus() (uplink, downlink uint8, err error) {
+	v, err := ie.Uint8()
+	if err != nil {
+		return 0, 0, err
+	}
+	return (v >> 2) & 0x03, v & 0x03, nil
+}
+
+// putUint40 writes the low 40 bits of v
+func putUint40(b []byte, v uint64) {
+	for i := 4; i >= 0; i-- {
+		b[i] = byte(v)
+		v >>= 8
+	}
+}
+
+// uint40 reads a 40 bit value
+func uint40(b []byte) uint64 {
+	var v uint64
+	for _, octet := range b[:5] {
+		v = v<<8 | uint64(octet)
+	}
+	return v
+}
+
+// encodeFQDN encodes a domain name as DNS labels (3GPP TS 29.244 8.2.38)
+func encodeFQDN(fqdn string) []byte {
+	var buf []byte
+	start := 0
+	for i := 0; i <= len(fqdn); i++ {
+		if i == len(fqdn) || fqdn[i] == '.' {
+			if i > start {
+				buf = append(buf, byte(i-start))
+				buf = append(buf, fqdn[start:i]...)
+			}
+			start = i + 1
+		}
+	}
+	return buf
+}
+
+// decodeFQDN decodes DNS labels into a dotted domain name
+func decodeFQDN(data []byte) string {
+	var name []byte
+	for offset := 0; offset < len(data); {
+		n := int(data[offset])
+		offset++
+		if n == 0 || offset+n > len(data) {
+			break
+		}
+		if len(name) > 0 {
+			name = append(name, '.')
+		}
+		name = append(name, data[offset:offset+n]...)
+		offset += n
+	}
+	return string(name)
+}
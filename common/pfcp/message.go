@@ -0,0 +1,296 @@
+package pfcp
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// PFCP Message Types (3GPP TS 29.244 Table 7.3-1)
+const (
+	PFCP_HEARTBEAT_REQUEST              = 1
+	PFCP_HEARTBEAT_RESPONSE             = 2
+	PFCP_ASSOCIATION_SETUP_REQUEST      = 5
+	PFCP_ASSOCIATION_SETUP_RESPONSE     = 6
+	PFCP_ASSOCIATION_RELEASE_REQUEST    = 9
+	PFCP_ASSOCIATION_RELEASE_RESPONSE   = 10
+	PFCP_SESSION_ESTABLISHMENT_REQUEST  = 50
+	PFCP_SESSION_ESTABLISHMENT_RESPONSE = 51
+	PFCP_SESSION_MODIFICATION_REQUEST   = 52
+	PFCP_SESSION_MODIFICATION_RESPONSE  = 53
+	PFCP_SESSION_DELETION_REQUEST       = 54
+	PFCP_SESSION_DELETION_RESPONSE      = 55
+	PFCP_SESSION_REPORT_REQUEST         = 56
+	PFCP_SESSION_REPORT_RESPONSE        = 57
+)
+
+// PFCP header and IE sizes
+const (
+	PFCP_VERSION                 = 1
+	PFCP_HEADER_LENGTH           = 8  // Without SEID
+	PFCP_HEADER_LENGTH_WITH_SEID = 16 // With SEID
+	PFCP_IE_HEADER_LENGTH        = 4  // Type + Length
+	PFCP_IE_ENTERPRISE_ID_LENGTH = 2  // Vendor-specific IEs only
+
+	pfcpMandatoryHeaderLength  = 4 // Flags, type, length
+	pfcpSequenceAndSpareLength = 4 // 24-bit sequence + spare/priority
+	pfcpSEIDLength             = 8
+
+	pfcpFlagSEID            uint8 = 0x01
+	pfcpFlagMessagePriority uint8 = 0x02
+)
+
+// Header represents the PFCP message header (3GPP TS 29.244 7.2.2)
+type Header struct {
+	Version         uint8
+	MessageType     uint8
+	MessageLength   uint16 // Octets following the mandatory first 4 octets
+	HasSEID         bool
+	SEID            uint64
+	SequenceNumber  uint32 // 24 bits
+	MessagePriority uint8  // 4 bits, only when the MP flag is set
+	HasPriority     bool
+}
+
+// IE represents a PFCP Information Element in TLV format (3GPP TS 29.244 8.1.1).
+// Grouped IEs keep their encoded children in Value; use ChildIEs to decode them.
+type IE struct {
+	Type         uint16
+	EnterpriseID uint16 // Present only for vendor-specific IEs (Type >= 32768)
+	Value        []byte
+}
+
+// Message represents a PFCP message
+type Message struct {
+	Header Header
+	IEs    []*IE
+}
+
+// NewMessage creates a node-related message (no SEID)
+func NewMessage(msgType uint8, seqNum uint32, ies ...*IE) *Message {
+	return &Message{
+		Header: Header{
+			Version:        PFCP_VERSION,
+			MessageType:    msgType,
+			SequenceNumber: seqNum,
+		},
+		IEs: ies,
+	}
+}
+
+// NewSessionMessage creates a session-related message (with SEID)
+func NewSessionMessage(msgType uint8, seid uint64, seqNum uint32, ies ...*IE) *Message {
+	msg := NewMessage(msgType, seqNum, ies...)
+	msg.Header.HasSEID = true
+	msg.Header.SEID = seid
+	return msg
+}
+
+// ParseHeader decodes a PFCP header and returns it with the header length in octets
+func ParseHeader(data []byte) (*Header, int, error) {
+	if len(data) < PFCP_HEADER_LENGTH {
+		return nil, 0, fmt.Errorf("pfcp header too short: %d octets", len(data))
+	}
+
+	header := &Header{
+		Version:       (data[0] >> 5) & 0x07,
+		HasPriority:   data[0]&pfcpFlagMessagePriority != 0,
+		HasSEID:       data[0]&pfcpFlagSEID != 0,
+		MessageType:   data[1],
+		MessageLength: binary.BigEndian.Uint16(data[2:4]),
+	}
+
+	if header.Version != PFCP_VERSION {
+		return nil, 0, fmt.Errorf("unsupported pfcp version: %d", header.Version)
+	}
+
+	offset := pfcpMandatoryHeaderLength
+	if header.HasSEID {
+		if len(data) < PFCP_HEADER_LENGTH_WITH_SEID {
+			return nil, 0, fmt.Errorf("pfcp header with SEID too short: %d octets", len(data))
+		}
+		header.SEID = binary.BigEndian.Uint64(data[offset : offset+pfcpSEIDLength])
+		offset += pfcpSEIDLength
+	}
+
+	header.SequenceNumber = uint32(data[offset])<<16 | uint32(data[offset+1])<<8 | uint32(data[offset+2])
+	if header.HasPriority {
+		header.MessagePriority = data[offset+3] >> 4
+	}
+	offset += pfcpSequenceAndSpareLength
+
+	if int(header.MessageLength) < offset-pfcpMandatoryHeaderLength {
+		return nil, 0, fmt.Errorf("pfcp message length %d shorter than header", header.MessageLength)
+	}
+
+	return header, offset, nil
+}
+
+// Parse decodes a complete PFCP message
+func Parse(data []byte) (*Message, error) {
+	header, headerLen, err := ParseHeader(data)
+	if err != nil {
+		return nil, err
+	}
+
+	total := pfcpMandatoryHeaderLength + int(header.MessageLength)
+	if total > len(data) {
+		return nil, fmt.Errorf("pfcp message length %d exceeds buffer of %d octets", total, len(data))
+	}
+
+	ies, err := ParseIEs(data[headerLen:total])
+	if err != nil {
+		return nil, fmt.Errorf("pfcp message type %d: %w", header.MessageType, err)
+	}
+
+	return &Message{Header: *header, IEs: ies}, nil
+}
+
+// ParseIEs decodes a sequence of TLV encoded IEs
+func ParseIEs(data []byte) ([]*IE, error) {
+	ies := make([]*IE, 0)
+
+	for offset := 0; offset < len(data); {
+		if len(data)-offset < PFCP_IE_HEADER_LENGTH {
+			return nil, fmt.Errorf("truncated IE header at offset %d", offset)
+		}
+
+		ie := &IE{Type: binary.BigEndian.Uint16(data[offset : offset+2])}
+		length := int(binary.BigEndian.Uint16(data[offset+2 : offset+4]))
+		offset += PFCP_IE_HEADER_LENGTH
+
+		if offset+length > len(data) {
+			return nil, fmt.Errorf("IE type %d length %d exceeds remaining %d octets", ie.Type, length, len(data)-offset)
+		}
+
+		value := data[offset : offset+length]
+		if ie.IsVendorSpecific() {
+			if length < PFCP_IE_ENTERPRISE_ID_LENGTH {
+				return nil, fmt.Errorf("vendor-specific IE type %d missing enterprise ID", ie.Type)
+			}
+			ie.EnterpriseID = binary.BigEndian.Uint16(value[:2])
+			value = value[2:]
+		}
+		ie.Value = append([]byte(nil), value...)
+
+		ies = append(ies, ie)
+		offset += length
+	}
+
+	return ies, nil
+}
+
+// Marshal encodes the message, computing the message length
+func (m *Message) Marshal() []byte {
+	body := MarshalIEs(m.IEs)
+
+	headerLen := PFCP_HEADER_LENGTH
+	if m.Header.HasSEID {
+		headerLen = PFCP_HEADER_LENGTH_WITH_SEID
+	}
+
+	msg := make([]byte, headerLen+len(body))
+
+	version := m.Header.Version
+	if version == 0 {
+		version = PFCP_VERSION
+	}
+	msg[0] = version << 5
+	if m.Header.HasPriority {
+		msg[0] |= pfcpFlagMessagePriority
+	}
+	if m.Header.HasSEID {
+		msg[0] |= pfcpFlagSEID
+	}
+	msg[1] = m.Header.MessageType
+	binary.BigEndian.PutUint16(msg[2:4], uint16(len(msg)-pfcpMandatoryHeaderLength))
+
+	offset := pfcpMandatoryHeaderLength
+	if m.Header.HasSEID {
+		binary.BigEndian.PutUint64(msg[offset:offset+pfcpSEIDLength], m.Header.SEID)
+		offset += pfcpSEIDLength
+	}
+
+	msg[offset] = byte(m.Header.SequenceNumber >> 16)
+	msg[offset+1] = byte(m.Header.SequenceNumber >> 8)
+	msg[offset+2] = byte(m.Header.SequenceNumber)
+	if m.Header.HasPriority {
+		msg[offset+3] = m.Header.MessagePriority << 4
+	}
+
+	copy(msg[headerLen:], body)
+	return msg
+}
+
+// MarshalIEs encodes a sequence of IEs
+func MarshalIEs(ies []*IE) []byte {
+	size := 0
+	for _, ie := range ies {
+		size += ie.Len()
+	}
+
+	buf := make([]byte, 0, size)
+	for _, ie := range ies {
+		buf = append(buf, ie.Marshal()...)
+	}
+	return buf
+}
+
+// IsVendorSpecific reports whether the IE carries an Enterprise ID
+func (ie *IE) IsVendorSpecific() bool {
+	return ie.Type&0x8000 != 0
+}
+
+// Len returns the encoded length of the IE including its header
+func (ie *IE) Len() int {
+	n := PFCP_IE_HEADER_LENGTH + len(ie.Value)
+	if ie.IsVendorSpecific() {
+		n += PFCP_IE_ENTERPRISE_ID_LENGTH
+	}
+	return n
+}
+
+// Marshal encodes the IE in TLV format
+func (ie *IE) Marshal() []byte {
+	buf := make([]byte, ie.Len())
+	binary.BigEndian.PutUint16(buf[0:2], ie.Type)
+	binary.BigEndian.PutUint16(buf[2:4], uint16(len(buf)-PFCP_IE_HEADER_LENGTH))
+
+	offset := PFCP_IE_HEADER_LENGTH
+	if ie.IsVendorSpecific() {
+		binary.BigEndian.PutUint16(buf[offset:offset+2], ie.EnterpriseID)
+		offset += PFCP_IE_ENTERPRISE_ID_LENGTH
+	}
+	copy(buf[offset:], ie.Value)
+	return buf
+}
+
+// ChildIEs decodes the value of a grouped IE
+func (ie *IE) ChildIEs() ([]*IE, error) {
+	return ParseIEs(ie.Value)
+}
+
+// FindIE returns the first IE of the given type
+func FindIE(ies []*IE, ieType uint16) *IE {
+	for _, ie := range ies {
+		if ie.Type == ieType {
+			return ie
+		}
+	}
+	return nil
+}
+
+// FindIEs returns all IEs of the given type
+func FindIEs(ies []*IE, ieType uint16) []*IE {
+	var found []*IE
+	for _, ie := range ies {
+		if ie.Type == ieType {
+			found = append(found, ie)
+		}
+	}
+	return found
+}
+
+// FindIE returns the first IE of the given type in the message
+func (m *Message) FindIE(ieType uint16) *IE {
+	return FindIE(m.IEs, ieType)
+}
@@ -0,0 +1,484 @@
+package pfcp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loadVector reads a hex encoded test vector, ignoring comments and whitespace
+func loadVector(t testing.TB, name string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+
+	var hexStr strings.Builder
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hexStr.WriteString(strings.ReplaceAll(line, " ", ""))
+	}
+
+	raw, err := hex.DecodeString(hexStr.String())
+	require.NoError(t, err)
+	return raw
+}
+
+func TestConformance_RoundTrip(t *testing.T) {
+	vectors := []string{
+		"heartbeat_request.hex",
+		"heartbeat_response.hex",
+		"association_setup_request.hex",
+		"association_setup_response.hex",
+		"session_establishment_request.hex",
+		"session_establishment_response.hex",
+	}
+
+	for _, name := range vectors {
+		t.Run(name, func(t *testing.T) {
+			raw := loadVector(t, name)
+
+			msg, err := Parse(raw)
+			require.NoError(t, err)
+			assert.Equal(t, raw, msg.Marshal())
+		})
+	}
+}
+
+func TestConformance_HeartbeatRequest(t *testing.T) {
+	msg, err := Parse(loadVector(t, "heartbeat_request.hex"))
+	require.NoError(t, err)
+
+	assert.Equal(t, uint8(PFCP_VERSION), msg.Header.Version)
+	assert.Equal(t, uint8(PFCP_HEARTBEAT_REQUEST), msg.Header.MessageType)
+	assert.False(t, msg.Header.HasSEID)
+	assert.Equal(t, uint32(1), msg.Header.SequenceNumber)
+
+	ts, err := msg.FindIE(IE_RECOVERY_TIME_STAMP).RecoveryTimeStamp()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0xe6d01a2b-ntpEpochOffset), ts.Unix())
+
+	// Building the same message from structs yields identical bytes
+	built := NewMessage(PFCP_HEARTBEAT_REQUEST, 1, NewRecoveryTimeStampIE(ts))
+	assert.Equal(t, loadVector(t, "heartbeat_request.hex"), built.Marshal())
+}
+
+func TestConformance_AssociationSetup(t *testing.T) {
+	req, err := Parse(loadVector(t, "association_setup_request.hex"))
+	require.NoError(t, err)
+	assert.Equal(t, uint8(PFCP_ASSOCIATION_SETUP_REQUEST), req.Header.MessageType)
+	assert.Equal(t, uint32(2), req.Header.SequenceNumber)
+
+	nodeID, err := req.FindIE(IE_NODE_ID).NodeID()
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", nodeID.String())
+
+	resp, err := Parse(loadVector(t, "association_setup_response.hex"))
+	require.NoError(t, err)
+	assert.Equal(t, uint8(PFCP_ASSOCIATION_SETUP_RESPONSE), resp.Header.MessageType)
+
+	cause, err := resp.FindIE(IE_CAUSE).Uint8()
+	require.NoError(t, err)
+	assert.Equal(t, uint8(CAUSE_REQUEST_ACCEPTED), cause)
+
+	ts, err := resp.FindIE(IE_RECOVERY_TIME_STAMP).RecoveryTimeStamp()
+	require.NoError(t, err)
+
+	built := NewMessage(PFCP_ASSOCIATION_SETUP_RESPONSE, 2,
+		NewNodeIDIE("10.0.0.2"),
+		NewCauseIE(CAUSE_REQUEST_ACCEPTED),
+		NewRecoveryTimeStampIE(ts),
+		NewUint16IE(IE_UP_FUNCTION_FEATURES, 0x0100),
+	)
+	assert.Equal(t, loadVector(t, "association_setup_response.hex"), built.Marshal())
+}
+
+func TestConformance_SessionEstablishmentRequest(t *testing.T) {
+	raw := loadVector(t, "session_establishment_request.hex")
+
+	msg, err := Parse(raw)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(PFCP_SESSION_ESTABLISHMENT_REQUEST), msg.Header.MessageType)
+	assert.True(t, msg.Header.HasSEID)
+	assert.Equal(t, uint64(0), msg.Header.SEID)
+	assert.Equal(t, uint32(3), msg.Header.SequenceNumber)
+
+	fseid, err := msg.FindIE(IE_F_SEID).FSEID()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), fseid.SEID)
+	assert.Equal(t, "10.0.0.1", fseid.IPv4.String())
+
+	createPDR, err := msg.FindIE(IE_CREATE_PDR).ChildIEs()
+	require.NoError(t, err)
+
+	pdrID, err := FindIE(createPDR, IE_PDR_ID).Uint16()
+	require.NoError(t, err)
+	assert.Equal(t, uint16(1), pdrID)
+
+	precedence, err := FindIE(createPDR, IE_PRECEDENCE).Uint32()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(255), precedence)
+
+	pdi, err := FindIE(createPDR, IE_PDI).ChildIEs()
+	require.NoError(t, err)
+
+	fteid, err := FindIE(pdi, IE_F_TEID).FTEID()
+	require.NoError(t, err)
+	assert.True(t, fteid.ChooseID)
+	assert.True(t, fteid.ChooseV4)
+
+	ueIP, err := FindIE(pdi, IE_UE_IP_ADDRESS).UEIPAddress()
+	require.NoError(t, err)
+	assert.Equal(t, "10.60.0.1", ueIP.IPv4.String())
+	assert.False(t, ueIP.IsDestination)
+
+	createFAR, err := msg.FindIE(IE_CREATE_FAR).ChildIEs()
+	require.NoError(t, err)
+	action, err := FindIE(createFAR, IE_APPLY_ACTION).Uint8()
+	require.NoError(t, err)
+	assert.Equal(t, uint8(APPLY_ACTION_FORW), action)
+
+	// Building the same message from structs yields identical bytes
+	built := NewSessionMessage(PFCP_SESSION_ESTABLISHMENT_REQUEST, 0, 3,
+		NewNodeIDIE("10.0.0.1"),
+		NewFSEIDIE(1, net.ParseIP("10.0.0.1"), nil),
+		NewGroupedIE(IE_CREATE_PDR,
+			NewUint16IE(IE_PDR_ID, 1),
+			NewUint32IE(IE_PRECEDENCE, 255),
+			NewGroupedIE(IE_PDI,
+				NewUint8IE(IE_SOURCE_INTERFACE, INTERFACE_ACCESS),
+				NewFTEIDIE(&FTEID{ChooseID: true, ChooseV4: true}),
+				NewUEIPAddressIE(&UEIPAddress{IPv4: net.ParseIP("10.60.0.1")}),
+			),
+			NewUint8IE(IE_OUTER_HEADER_REMOVAL, OUTER_HEADER_REMOVAL_GTPU_UDP_IPV4),
+			NewUint32IE(IE_FAR_ID, 1),
+		),
+		NewGroupedIE(IE_CREATE_FAR,
+			NewUint32IE(IE_FAR_ID, 1),
+			NewUint8IE(IE_APPLY_ACTION, APPLY_ACTION_FORW),
+			NewGroupedIE(IE_FORWARDING_PARAMETERS,
+				NewUint8IE(IE_DESTINATION_INTERFACE, INTERFACE_CORE),
+			),
+		),
+	)
+	assert.Equal(t, raw, built.Marshal())
+}
+
+func TestConformance_SessionEstablishmentResponse(t *testing.T) {
+	raw := loadVector(t, "session_establishment_response.hex")
+
+	msg, err := Parse(raw)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(PFCP_SESSION_ESTABLISHMENT_RESPONSE), msg.Header.MessageType)
+	assert.Equal(t, uint64(1), msg.Header.SEID)
+
+	createdPDR, err := msg.FindIE(IE_CREATED_PDR).ChildIEs()
+	require.NoError(t, err)
+
+	fteid, err := FindIE(createdPDR, IE_F_TEID).FTEID()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), fteid.TEID)
+	assert.Equal(t, "10.0.0.2", fteid.IPv4.String())
+
+	built := NewSessionMessage(PFCP_SESSION_ESTABLISHMENT_RESPONSE, 1, 3,
+		NewNodeIDIE("10.0.0.2"),
+		NewCauseIE(CAUSE_REQUEST_ACCEPTED),
+		NewFSEIDIE(2, net.ParseIP("10.0.0.2"), nil),
+		NewGroupedIE(IE_CREATED_PDR,
+			NewUint16IE(IE_PDR_ID, 1),
+			NewFTEIDIE(&FTEID{TEID: 1, IPv4: net.ParseIP("10.0.0.2")}),
+		),
+	)
+	assert.Equal(t, raw, built.Marshal())
+}
+
+func TestParse_Malformed(t *testing.T) {
+	raw := loadVector(t, "session_establishment_request.hex")
+
+	// Truncated header
+	_, err := Parse(raw[:6])
+	assert.Error(t, err)
+
+	// Message length exceeds buffer
+	_, err = Parse(raw[:len(raw)-1])
+	assert.Error(t, err)
+
+	// IE length exceeds message
+	bad := append([]byte(nil), raw...)
+	bad[18] = 0xff
+	_, err = Parse(bad)
+	assert.Error(t, err)
+}
+
+func TestParseHeader_SequenceNumber(t *testing.T) {
+	tests := []struct {
+		name      string
+		raw       []byte
+		seid      uint64
+		seq       uint32
+		headerLen int
+	}{
+		{
+			name: "without SEID",
+			raw: []byte{
+				0x20, PFCP_HEARTBEAT_REQUEST, 0x00, 0x04,
+				0xab, 0xcd, 0xef, 0x00,
+			},
+			seq:       0xabcdef,
+			headerLen: PFCP_HEADER_LENGTH,
+		},
+		{
+			name: "with SEID",
+			raw: []byte{
+				0x21, PFCP_SESSION_MODIFICATION_REQUEST, 0x00, 0x0c,
+				0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+				0x12, 0x34, 0x56, 0x00,
+			},
+			seid:      0x0102030405060708,
+			seq:       0x123456,
+			headerLen: PFCP_HEADER_LENGTH_WITH_SEID,
+		},
+		{
+			name: "priority octet is not part of the sequence",
+			raw: []byte{
+				0x23, PFCP_SESSION_MODIFICATION_REQUEST, 0x00, 0x0c,
+				0, 0, 0, 0, 0, 0, 0, 0x01,
+				0xff, 0xff, 0xff, 0xf0,
+			},
+			seid:      1,
+			seq:       0xffffff,
+			headerLen: PFCP_HEADER_LENGTH_WITH_SEID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header, headerLen, err := ParseHeader(tt.raw)
+			require.NoError(t, err)
+			assert.Equal(t, tt.seq, header.SequenceNumber)
+			assert.Equal(t, tt.seid, header.SEID)
+			assert.Equal(t, tt.headerLen, headerLen)
+
+			msg, err := Parse(tt.raw)
+			require.NoError(t, err)
+			assert.Equal(t, tt.raw, msg.Marshal())
+		})
+	}
+}
+
+func TestParseHeader_Truncated(t *testing.T) {
+	full := []byte{
+		0x21, PFCP_SESSION_MODIFICATION_REQUEST, 0x00, 0x0c,
+		0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+		0x12, 0x34, 0x56, 0x00,
+	}
+
+	// Every prefix short of the full header is rejected, never read past
+	for n := 0; n < len(full); n++ {
+		assert.NotPanics(t, func() {
+			_, _, err := ParseHeader(full[:n])
+			assert.Error(t, err, "%d octets", n)
+		})
+	}
+
+	// Without SEID only the 8 octet header is needed
+	noSEID := []byte{0x20, PFCP_HEARTBEAT_REQUEST, 0x00, 0x04, 0x00, 0x00, 0x01, 0x00}
+	for n := 0; n < len(noSEID); n++ {
+		_, _, err := ParseHeader(noSEID[:n])
+		assert.Error(t, err, "%d octets", n)
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, name := range []string{
+		"heartbeat_request.hex",
+		"association_setup_request.hex",
+		"session_establishment_request.hex",
+	} {
+		f.Add(loadVector(f, name))
+	}
+
+	// Malformed input must be rejected, never panic
+	f.Fuzz(func(t *testing.T, data []byte) {
+		msg, err := Parse(data)
+		if err != nil {
+			return
+		}
+		for _, ie := range msg.IEs {
+			ie.ChildIEs()
+		}
+	})
+}
+
+func TestNodeID_FQDN(t *testing.T) {
+	ie := NewNodeIDIE("upf-1.5gc.mnc01.mcc001.3gppnetwork.org")
+
+	nodeID, err := ie.NodeID()
+	require.NoError(t, err)
+	assert.Equal(t, uint8(NODE_ID_TYPE_FQDN), nodeID.Type)
+	assert.Equal(t, "upf-1.5gc.mnc01.mcc001.3gppnetwork.org", nodeID.String())
+}
+
+func TestBitRate_RoundTrip(t *testing.T) {
+	ie := NewBitRateIE(IE_MBR, &BitRate{Uplink: 1_000_000_000, Downlink: 2_000_000_000})
+	assert.Equal(t, []byte{0x00, 0x00, 0x0f, 0x42, 0x40, 0x00, 0x00, 0x1e, 0x84, 0x80}, ie.Value)
+
+	br, err := ie.BitRate()
+	require.NoError(t, err)
+	assert.Equal(t, &BitRate{Uplink: 1_000_000_000, Downlink: 2_000_000_000}, br)
+}
+
+func TestGateStatus(t *testing.T) {
+	ul, dl, err := NewGateStatusIE(GATE_STATUS_CLOSED).GateStatus()
+	require.NoError(t, err)
+	assert.Equal(t, uint8(GATE_STATUS_CLOSED), ul)
+	assert.Equal(t, uint8(GATE_STATUS_CLOSED), dl)
+}
+
+func TestDownlinkDataReport_RoundTrip(t *testing.T) {
+	ie := NewDownlinkDataReportIE(&DownlinkDataReport{PDRID: 2, QFI: 9})
+	assert.Equal(t, []byte{
+		0x00, 0x38, 0x00, 0x02, 0x00, 0x02, // PDR ID
+		0x00, 0x2d, 0x00, 0x02, 0x02, 0x09, // DL Data Service Information, QFII
+	}, ie.Value)
+
+	report, err := ie.DownlinkDataReport()
+	require.NoError(t, err)
+	assert.Equal(t, &DownlinkDataReport{PDRID: 2, QFI: 9}, report)
+
+	report, err = NewDownlinkDataReportIE(&DownlinkDataReport{PDRID: 2}).DownlinkDataReport()
+	require.NoError(t, err)
+	assert.Equal(t, &DownlinkDataReport{PDRID: 2}, report)
+}
+
+func TestErrorIndicationReport_RoundTrip(t *testing.T) {
+	ie := NewErrorIndicationReportIE(&ErrorIndicationReport{
+		RemoteFTEIDs: []*FTEID{{TEID: 0x100, IPv4: net.IPv4(192, 168, 1, 10).To4()}},
+	})
+	assert.Equal(t, []byte{
+		0x00, 0x15, 0x00, 0x09, 0x01, 0x00, 0x00, 0x01, 0x00, 0xc0, 0xa8, 0x01, 0x0a, // Remote F-TEID, V4
+	}, ie.Value)
+
+	report, err := ie.ErrorIndicationReport()
+	require.NoError(t, err)
+	require.Len(t, report.RemoteFTEIDs, 1)
+	assert.Equal(t, uint32(0x100), report.RemoteFTEIDs[0].TEID)
+	assert.Equal(t, net.IPv4(192, 168, 1, 10).To4(), report.RemoteFTEIDs[0].IPv4)
+
+	_, err = NewGroupedIE(IE_ERROR_INDICATION_REPORT).ErrorIndicationReport()
+	assert.Error(t, err)
+}
+
+func TestQoSMonitoring_RoundTrip(t *testing.T) {
+	ie := NewQoSMonitoringIE(&QoSMonitoring{
+		Requested:         QOS_MONITORING_DL | QOS_MONITORING_UL,
+		DownlinkThreshold: 20 * time.Millisecond,
+		MinimumWaitTime:   5 * time.Second,
+	})
+	assert.Equal(t, []byte{
+		0x00, 0xf3, 0x00, 0x01, 0x03, // Requested QoS Monitoring, DL and UL
+		0x00, 0xf5, 0x00, 0x05, 0x01, 0x00, 0x00, 0x00, 0x14, // Packet Delay Thresholds, DL 20 ms
+		0x00, 0xf6, 0x00, 0x04, 0x00, 0x00, 0x00, 0x05, // Minimum Wait Time
+	}, ie.Value)
+
+	m, err := ie.QoSMonitoring()
+	require.NoError(t, err)
+	assert.Equal(t, &QoSMonitoring{
+		Requested:         QOS_MONITORING_DL | QOS_MONITORING_UL,
+		DownlinkThreshold: 20 * time.Millisecond,
+		MinimumWaitTime:   5 * time.Second,
+	}, m)
+
+	_, err = NewGroupedIE(IE_QOS_MONITORING_CONTROL).QoSMonitoring()
+	assert.Error(t, err)
+}
+
+func TestQoSMonitoringReport_RoundTrip(t *testing.T) {
+	eventTime := time.Unix(1_700_000_000, 0)
+	ie := NewQoSMonitoringReportIE(&QoSMonitoringReport{
+		QFI:           9,
+		DownlinkDelay: 12 * time.Millisecond,
+		UplinkDelay:   30 * time.Millisecond,
+		EventTime:     eventTime,
+	})
+
+	report, err := ie.QoSMonitoringReport()
+	require.NoError(t, err)
+	assert.Equal(t, uint8(9), report.QFI)
+	assert.Equal(t, 12*time.Millisecond, report.DownlinkDelay)
+	assert.Equal(t, 30*time.Millisecond, report.UplinkDelay)
+	assert.Zero(t, report.RoundTripDelay)
+	assert.True(t, eventTime.Equal(report.EventTime))
+
+	truncated := NewGroupedIE(IE_QOS_MONITORING_REPORT,
+		NewUint8IE(IE_QFI, 9),
+		NewIE(IE_QOS_MONITORING_MEASUREMENT, []byte{QOS_MONITORING_DL, 0x00}),
+	)
+	_, err = truncated.QoSMonitoringReport()
+	assert.Error(t, err)
+}
+
+func TestReportingTriggers(t *testing.T) {
+	ie := NewReportingTriggersIE(IE_REPORTING_TRIGGERS, REPORTING_TRIGGER_VOLTH|USAGE_REPORT_TRIGGER_TERMR)
+	assert.Equal(t, []byte{0x02, 0x08, 0x00}, ie.Value)
+
+	triggers, err := ie.ReportingTriggers()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(REPORTING_TRIGGER_VOLTH|USAGE_REPORT_TRIGGER_TERMR), triggers)
+
+	// Peers of earlier releases send two octets
+	triggers, err = NewIE(IE_REPORTING_TRIGGERS, []byte{0x04, 0x00}).ReportingTriggers()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(REPORTING_TRIGGER_TIMTH), triggers)
+}
+
+func TestVolumeThreshold_RoundTrip(t *testing.T) {
+	ie := NewVolumeThresholdIE(&VolumeThreshold{Total: 1_000_000})
+	assert.Equal(t, []byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x0f, 0x42, 0x40}, ie.Value)
+
+	threshold, err := ie.VolumeThreshold()
+	require.NoError(t, err)
+	assert.Equal(t, &VolumeThreshold{Total: 1_000_000}, threshold)
+
+	_, err = NewIE(IE_VOLUME_THRESHOLD, []byte{0x01, 0x00}).VolumeThreshold()
+	assert.Error(t, err)
+}
+
+func TestUsageReport_RoundTrip(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	ie := NewUsageReportIE(IE_USAGE_REPORT_REPORT_REQ, &UsageReport{
+		URRID:          1,
+		SequenceNumber: 3,
+		Trigger:        REPORTING_TRIGGER_VOLTH,
+		StartTime:      start,
+		EndTime:        start.Add(time.Minute),
+		Volume:         &VolumeMeasurement{TotalVolume: 1500, UplinkVolume: 1000, DownlinkVolume: 500, TotalPackets: 3},
+		Duration:       time.Minute,
+	})
+
+	report, err := ie.UsageReport()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), report.URRID)
+	assert.Equal(t, uint32(3), report.SequenceNumber)
+	assert.Equal(t, uint32(REPORTING_TRIGGER_VOLTH), report.Trigger)
+	assert.True(t, start.Equal(report.StartTime))
+	assert.True(t, start.Add(time.Minute).Equal(report.EndTime))
+	assert.Equal(t, &VolumeMeasurement{TotalVolume: 1500, UplinkVolume: 1000, DownlinkVolume: 500, TotalPackets: 3}, report.Volume)
+	assert.Equal(t, time.Minute, report.Duration)
+
+	_, err = NewGroupedIE(IE_USAGE_REPORT_REPORT_REQ, NewUint32IE(IE_URR_ID, 1)).UsageReport()
+	assert.Error(t, err)
+}
@@ -0,0 +1,259 @@
+// Package reload re-reads the configuration of a network function on SIGHUP
+// and applies the fields that can change while it runs.
+package reload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ErrUnsafeChange is returned when a reload changes fields that only take
+// effect on restart, such as bind addresses
+var ErrUnsafeChange = errors.New("configuration change requires a restart")
+
+// Change is a configuration field that differs between two configurations,
+// identified by its YAML path, e.g. "nrf.heartbeat_interval"
+type Change struct {
+	Path string
+	Old  any
+	New  any
+}
+
+// Reloader holds the running configuration of a network function and
+// replaces it when the configuration file is reloaded. A reload is applied
+// as a whole or not at all: it is rejected when the file fails to load or
+// validate, or when it changes a field outside the safe paths.
+type Reloader[C any] struct {
+	path   string
+	load   func(path string) (*C, error)
+	safe   []string
+	logger *zap.Logger
+
+	mu      sync.Mutex // Serializes reloads
+	current atomic.Pointer[C]
+	level   zap.AtomicLevel
+	levelOf func(*C) string
+	hooks   []func(*C)
+}
+
+// New creates a reloader of the configuration loaded from path. load reads
+// and validates the file, normally the config package's Load. safe lists
+// the YAML paths that may change at runtime; a path covers the fields below
+// it.
+func New[C any](path string, cfg *C, load func(path string) (*C, error), safe []string, logger *zap.Logger) *Reloader[C] {
+	r := &Reloader[C]{
+		path:   path,
+		load:   load,
+		safe:   safe,
+		logger: logger,
+	}
+	r.current.Store(cfg)
+	return r
+}
+
+// Config returns the running configuration
+func (r *Reloader[C]) Config() *C {
+	return r.current.Load()
+}
+
+// SetLogLevel makes level follow the configured log level returned by
+// levelOf, applying the running configuration's level now. An empty level
+// means info.
+func (r *Reloader[C]) SetLogLevel(level zap.AtomicLevel, levelOf func(*C) string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	parsed, err := zapcore.ParseLevel(levelOf(r.current.Load()))
+	if err != nil {
+		return fmt.Errorf("invalid log level: %w", err)
+	}
+	level.SetLevel(parsed)
+	r.level = level
+	r.levelOf = levelOf
+	return nil
+}
+
+// OnReload registers a function applying the safe fields of a reloaded
+// configuration, called after the new configuration is in place
+func (r *Reloader[C]) OnReload(apply func(cfg *C)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.hooks = append(r.hooks, apply)
+}
+
+// Ticker returns a ticker at the interval read from the running
+// configuration, reset when a reload changes it. Reloaded intervals that are
+// not positive are ignored.
+func (r *Reloader[C]) Ticker(interval func(cfg *C) time.Duration) *time.Ticker {
+	ticker := time.NewTicker(interval(r.Config()))
+	r.OnReload(func(cfg *C) {
+		if d := interval(cfg); d > 0 {
+			ticker.Reset(d)
+		}
+	})
+	return ticker
+}
+
+// Reload reads the configuration file again and applies it, returning the
+// changed fields. The running configuration is kept when an error is
+// returned.
+func (r *Reloader[C]) Reload() ([]Change, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next, err := r.load(r.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	changes := Diff(r.current.Load(), next)
+	var unsafe []string
+	for _, change := range changes {
+		if !r.isSafe(change.Path) {
+			unsafe = append(unsafe, change.Path)
+		}
+	}
+	if len(unsafe) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnsafeChange, strings.Join(unsafe, ", "))
+	}
+
+	var level zapcore.Level
+	if r.levelOf != nil {
+		if level, err = zapcore.ParseLevel(r.levelOf(next)); err != nil {
+			return nil, fmt.Errorf("invalid log level: %w", err)
+		}
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	r.current.Store(next)
+	if r.levelOf != nil {
+		r.level.SetLevel(level)
+	}
+	for _, apply := range r.hooks {
+		apply(next)
+	}
+
+	for _, change := range changes {
+		r.logger.Info("Configuration changed",
+			zap.String("field", change.Path),
+			zap.Any("old", change.Old),
+			zap.Any("new", change.New),
+		)
+	}
+	return changes, nil
+}
+
+// Watch reloads the configuration on every SIGHUP until ctx is done
+func (r *Reloader[C]) Watch(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			r.logger.Info("SIGHUP received, reloading configuration", zap.String("config", r.path))
+			changes, err := r.Reload()
+			if err != nil {
+				r.logger.Error("Configuration reload rejected", zap.Error(err))
+				continue
+			}
+			r.logger.Info("Configuration reloaded", zap.Int("changes", len(changes)))
+		}
+	}
+}
+
+// isSafe reports whether a field may change at runtime
+func (r *Reloader[C]) isSafe(path string) bool {
+	for _, safe := range r.safe {
+		if path == safe || strings.HasPrefix(path, safe+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff returns the fields that differ between two configurations of the
+// same type. Structs are compared field by field under their YAML names;
+// slices and maps are compared as a whole.
+func Diff(old, new any) []Change {
+	var changes []Change
+	diff("", reflect.ValueOf(old), reflect.ValueOf(new), &changes)
+	return changes
+}
+
+func diff(path string, a, b reflect.Value, changes *[]Change) {
+	if a.Kind() == reflect.Pointer {
+		if a.IsNil() || b.IsNil() {
+			if a.IsNil() != b.IsNil() {
+				*changes = append(*changes, Change{Path: path, Old: a.Interface(), New: b.Interface()})
+			}
+			return
+		}
+		a, b = a.Elem(), b.Elem()
+	}
+
+	// Structs without exported fields, such as time.Time, are values
+	if a.Kind() != reflect.Struct || !hasExportedFields(a.Type()) {
+		if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			*changes = append(*changes, Change{Path: path, Old: a.Interface(), New: b.Interface()})
+		}
+		return
+	}
+
+	for i := 0; i < a.NumField(); i++ {
+		field := a.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, inline := yamlName(field)
+		if name == "-" {
+			continue
+		}
+		fieldPath := path
+		if !inline {
+			fieldPath = strings.TrimPrefix(path+"."+name, ".")
+		}
+		diff(fieldPath, a.Field(i), b.Field(i), changes)
+	}
+}
+
+func hasExportedFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).IsExported() {
+			return true
+		}
+	}
+	return false
+}
+
+// yamlName returns the YAML key of a struct field, following the defaults of
+// gopkg.in/yaml.v3
+func yamlName(field reflect.StructField) (name string, inline bool) {
+	tag := field.Tag.Get("yaml")
+	name, options, _ := strings.Cut(tag, ",")
+	if strings.Contains(options, "inline") {
+		return "", true
+	}
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	return name, false
+}
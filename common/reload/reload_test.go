@@ -0,0 +1,196 @@
+package reload
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"gopkg.in/yaml.v3"
+)
+
+type testConfig struct {
+	SBI struct {
+		BindAddress string `yaml:"bind_address"`
+	} `yaml:"sbi"`
+	NRF struct {
+		HeartbeatInterval time.Duration `yaml:"heartbeat_interval"`
+	} `yaml:"nrf"`
+	Observability struct {
+		Logging struct {
+			Level string `yaml:"level"`
+		} `yaml:"logging"`
+	} `yaml:"observability"`
+}
+
+var testSafePaths = []string{"observability.logging", "nrf.heartbeat_interval"}
+
+func loadTestConfig(path string) (*testConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg testConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+func writeTestConfig(t *testing.T, path, level, bindAddress string, heartbeat time.Duration) {
+	t.Helper()
+
+	var cfg testConfig
+	cfg.SBI.BindAddress = bindAddress
+	cfg.NRF.HeartbeatInterval = heartbeat
+	cfg.Observability.Logging.Level = level
+	data, err := yaml.Marshal(&cfg)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+}
+
+// newTestReloader returns a reloader of a config file at info level whose
+// logger follows the configured level
+func newTestReloader(t *testing.T) (*Reloader[testConfig], string, zap.AtomicLevel, *observer.ObservedLogs) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "nf.yaml")
+	writeTestConfig(t, path, "info", "0.0.0.0", 10*time.Second)
+	cfg, err := loadTestConfig(path)
+	require.NoError(t, err)
+
+	level := zap.NewAtomicLevel()
+	core, logs := observer.New(level)
+	r := New(path, cfg, loadTestConfig, testSafePaths, zap.New(core))
+	require.NoError(t, r.SetLogLevel(level, func(c *testConfig) string { return c.Observability.Logging.Level }))
+	return r, path, level, logs
+}
+
+func TestReloadAppliesLogLevel(t *testing.T) {
+	r, path, level, logs := newTestReloader(t)
+	assert.False(t, level.Enabled(zapcore.DebugLevel))
+
+	var applied *testConfig
+	r.OnReload(func(cfg *testConfig) { applied = cfg })
+
+	writeTestConfig(t, path, "debug", "0.0.0.0", 30*time.Second)
+	changes, err := r.Reload()
+	require.NoError(t, err)
+
+	assert.True(t, level.Enabled(zapcore.DebugLevel), "the new log level takes effect")
+	assert.ElementsMatch(t, []Change{
+		{Path: "observability.logging.level", Old: "info", New: "debug"},
+		{Path: "nrf.heartbeat_interval", Old: 10 * time.Second, New: 30 * time.Second},
+	}, changes)
+	assert.Same(t, r.Config(), applied)
+	assert.Equal(t, 30*time.Second, r.Config().NRF.HeartbeatInterval)
+	assert.Equal(t, 2, logs.FilterMessage("Configuration changed").Len())
+
+	// Reloading an unchanged file is a no-op
+	changes, err = r.Reload()
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestReloadRejectsUnsafeChanges(t *testing.T) {
+	r, path, level, _ := newTestReloader(t)
+	running := r.Config()
+
+	// The safe log level change is not applied along with the bind address
+	writeTestConfig(t, path, "debug", "10.0.0.1", 10*time.Second)
+	_, err := r.Reload()
+	require.ErrorIs(t, err, ErrUnsafeChange)
+	assert.Contains(t, err.Error(), "sbi.bind_address")
+	assert.Same(t, running, r.Config())
+	assert.False(t, level.Enabled(zapcore.DebugLevel))
+
+	writeTestConfig(t, path, "verbose", "0.0.0.0", 10*time.Second)
+	_, err = r.Reload()
+	assert.ErrorContains(t, err, "invalid log level")
+	assert.Same(t, running, r.Config())
+
+	require.NoError(t, os.WriteFile(path, []byte("sbi: ["), 0o600))
+	_, err = r.Reload()
+	assert.ErrorContains(t, err, "failed to load configuration")
+	assert.Same(t, running, r.Config())
+}
+
+func TestTickerFollowsReloadedInterval(t *testing.T) {
+	r, path, _, _ := newTestReloader(t)
+	ticker := r.Ticker(func(c *testConfig) time.Duration { return c.NRF.HeartbeatInterval })
+	defer ticker.Stop()
+
+	writeTestConfig(t, path, "info", "0.0.0.0", 10*time.Millisecond)
+	_, err := r.Reload()
+	require.NoError(t, err)
+
+	select {
+	case <-ticker.C:
+	case <-time.After(time.Second):
+		t.Fatal("ticker not reset to the reloaded interval")
+	}
+}
+
+func TestWatchReloadsOnSIGHUP(t *testing.T) {
+	r, path, level, logs := newTestReloader(t)
+	writeTestConfig(t, path, "debug", "0.0.0.0", 10*time.Second)
+
+	// Keep SIGHUP from ending the process before Watch handles it
+	ignored := make(chan os.Signal, 1)
+	signal.Notify(ignored, syscall.SIGHUP)
+	defer signal.Stop(ignored)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		r.Watch(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Signal until Watch has picked up the handler
+	require.Eventually(t, func() bool {
+		_ = syscall.Kill(os.Getpid(), syscall.SIGHUP)
+		return level.Enabled(zapcore.DebugLevel)
+	}, 2*time.Second, 50*time.Millisecond)
+	assert.Positive(t, logs.FilterMessage("Configuration reloaded").Len())
+}
+
+func TestDiff(t *testing.T) {
+	type inner struct {
+		Name string `yaml:"name"`
+	}
+	type config struct {
+		Inner    inner  `yaml:"inner"`
+		Ptr      *inner `yaml:"ptr"`
+		Embed    inner  `yaml:",inline"`
+		Skipped  string `yaml:"-"`
+		Untagged int
+		Map      map[string]string `yaml:"map"`
+		private  string
+	}
+
+	old := &config{Inner: inner{"a"}, Ptr: &inner{"p"}, Embed: inner{"e"}, Skipped: "x", Untagged: 1, Map: map[string]string{"k": "v"}, private: "a"}
+	same := *old
+	same.Ptr = &inner{"p"}
+	same.private = "b"
+	assert.Empty(t, Diff(old, &same))
+
+	changed := &config{Inner: inner{"b"}, Embed: inner{"f"}, Skipped: "y", Untagged: 2, Map: map[string]string{"k": "w"}}
+	var paths []string
+	for _, change := range Diff(old, changed) {
+		paths = append(paths, change.Path)
+	}
+	assert.Equal(t, []string{"inner.name", "ptr", "name", "untagged", "map"}, paths)
+}
@@ -0,0 +1,168 @@
+package retry
+
+import (
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Default retry policy
+const (
+	DefaultMaxAttempts = 3
+	DefaultBaseDelay   = 100 * time.Millisecond
+	DefaultMaxDelay    = 2 * time.Second
+	DefaultJitter      = 0.2
+)
+
+// Policy configures the retries of inter-NF requests. Zero fields fall back
+// to the defaults above; MaxAttempts 1 disables retries.
+type Policy struct {
+	MaxAttempts int           `yaml:"max_attempts"` // Including the first attempt
+	BaseDelay   time.Duration `yaml:"base_delay"`   // Doubled after every retry
+	MaxDelay    time.Duration `yaml:"max_delay"`
+	Jitter      float64       `yaml:"jitter"`   // Fraction of each delay randomized, 0 to 1
+	Deadline    time.Duration `yaml:"deadline"` // Retries that would end past it are not started, unlimited when 0
+}
+
+// Validate rejects negative or out of range settings
+func (p *Policy) Validate() error {
+	if p.MaxAttempts < 0 {
+		return fmt.Errorf("retry max_attempts must not be negative")
+	}
+	if p.BaseDelay < 0 || p.MaxDelay < 0 || p.Deadline < 0 {
+		return fmt.Errorf("retry delays must not be negative")
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return fmt.Errorf("retry jitter must be between 0 and 1")
+	}
+	return nil
+}
+
+// withDefaults returns the policy with zero fields set to the defaults
+func (p Policy) withDefaults() Policy {
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = DefaultMaxAttempts
+	}
+	if p.BaseDelay == 0 {
+		p.BaseDelay = DefaultBaseDelay
+	}
+	if p.MaxDelay == 0 {
+		p.MaxDelay = DefaultMaxDelay
+	}
+	if p.Jitter == 0 {
+		p.Jitter = DefaultJitter
+	}
+	return p
+}
+
+// delay returns the backoff before retry n (1 for the first retry): the base
+// delay doubled n-1 times, capped at the maximum, less up to the jitter
+// fraction of it
+func (p *Policy) delay(n int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < n && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d - time.Duration(rand.Float64()*p.Jitter*float64(d))
+}
+
+// Transport retries idempotent requests (GET and PUT) failing with a
+// connection error or a 502, 503 or 504 response, backing off exponentially
+// between attempts
+type Transport struct {
+	next   http.RoundTripper
+	policy Policy
+	logger *zap.Logger
+
+	sleep func(time.Duration) <-chan time.Time // Replaceable in tests
+}
+
+// NewTransport wraps next, http.DefaultTransport when nil, with retries
+// following the policy
+func NewTransport(next http.RoundTripper, policy Policy, logger *zap.Logger) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Transport{
+		next:   next,
+		policy: policy.withDefaults(),
+		logger: logger,
+		sleep:  time.After,
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !retryable(req) {
+		return t.next.RoundTrip(req)
+	}
+
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if !shouldRetry(resp, err) || req.Context().Err() != nil || attempt >= t.policy.MaxAttempts {
+			return resp, err
+		}
+
+		delay := t.policy.delay(attempt)
+		if t.policy.Deadline > 0 && time.Since(start)+delay > t.policy.Deadline {
+			return resp, err
+		}
+
+		// The response is discarded in favour of the retry
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		t.logger.Debug("Retrying SBI request",
+			zap.String("method", req.Method),
+			zap.String("url", req.URL.String()),
+			zap.Int("attempt", attempt+1),
+			zap.Duration("delay", delay),
+			zap.Error(err),
+		)
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-t.sleep(delay):
+		}
+
+		if req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("failed to rewind request body: %w", err)
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+	}
+}
+
+// retryable reports whether a request is idempotent and can be sent again
+func retryable(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodPut {
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// shouldRetry reports whether an attempt failed transiently
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package retry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// newFlakyServer fails the first failures requests with status, then answers
+// 200 echoing the request body
+func newFlakyServer(t *testing.T, failures int32, status int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits
+}
+
+// newTestClient returns a client retrying without waiting
+func newTestClient(policy Policy) *http.Client {
+	transport := NewTransport(nil, policy, zap.NewNop())
+	transport.sleep = func(time.Duration) <-chan time.Time {
+		ch := make(chan time.Time, 1)
+		ch <- time.Now()
+		return ch
+	}
+	return &http.Client{Transport: transport}
+}
+
+func TestRetriesTransientFailures(t *testing.T) {
+	server, hits := newFlakyServer(t, 2, http.StatusServiceUnavailable)
+
+	resp, err := newTestClient(Policy{}).Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(3), hits.Load())
+}
+
+func TestReplaysPutBody(t *testing.T) {
+	server, hits := newFlakyServer(t, 1, http.StatusBadGateway)
+
+	req, err := http.NewRequest(http.MethodPut, server.URL, bytes.NewReader([]byte(`{"sqn":"000000000021"}`)))
+	require.NoError(t, err)
+	resp, err := newTestClient(Policy{}).Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, `{"sqn":"000000000021"}`, string(body))
+	assert.Equal(t, int32(2), hits.Load())
+}
+
+func TestDoesNotRetry(t *testing.T) {
+	t.Run("client error", func(t *testing.T) {
+		server, hits := newFlakyServer(t, 1, http.StatusBadRequest)
+
+		resp, err := newTestClient(Policy{}).Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, int32(1), hits.Load())
+	})
+
+	t.Run("non-idempotent method", func(t *testing.T) {
+		server, hits := newFlakyServer(t, 1, http.StatusServiceUnavailable)
+
+		resp, err := newTestClient(Policy{}).Post(server.URL, "application/json", bytes.NewReader([]byte("{}")))
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Equal(t, int32(1), hits.Load())
+	})
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		server, hits := newFlakyServer(t, 5, http.StatusGatewayTimeout)
+
+		resp, err := newTestClient(Policy{MaxAttempts: 2}).Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+		assert.Equal(t, int32(2), hits.Load())
+	})
+
+	t.Run("past the deadline", func(t *testing.T) {
+		server, hits := newFlakyServer(t, 5, http.StatusServiceUnavailable)
+
+		policy := Policy{BaseDelay: time.Second, Deadline: 500 * time.Millisecond}
+		resp, err := newTestClient(policy).Get(server.URL)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		assert.Equal(t, int32(1), hits.Load())
+	})
+}
+
+func TestRetriesConnectionErrors(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	var attempts int
+	transport := NewTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		return http.DefaultTransport.RoundTrip(req)
+	}), Policy{BaseDelay: time.Millisecond}, zap.NewNop())
+
+	_, err := (&http.Client{Transport: transport}).Get(server.URL)
+	assert.Error(t, err)
+	assert.Equal(t, DefaultMaxAttempts, attempts)
+}
+
+func TestHonorsContextCancellation(t *testing.T) {
+	server, hits := newFlakyServer(t, 5, http.StatusServiceUnavailable)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	transport := NewTransport(nil, Policy{BaseDelay: time.Minute, MaxDelay: time.Minute}, zap.NewNop())
+	start := time.Now()
+	_, err = (&http.Client{Transport: transport}).Do(req)
+
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Equal(t, int32(1), hits.Load())
+}
+
+func TestDelayBacksOffWithJitter(t *testing.T) {
+	policy := Policy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second, Jitter: 0.5}
+
+	for n, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 4: 800 * time.Millisecond, 6: time.Second} {
+		d := policy.delay(n)
+		assert.LessOrEqual(t, d, want, "retry %d", n)
+		assert.GreaterOrEqual(t, d, want/2, "retry %d", n)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, (&Policy{}).Validate())
+	assert.Error(t, (&Policy{MaxAttempts: -1}).Validate())
+	assert.Error(t, (&Policy{BaseDelay: -time.Second}).Validate())
+	assert.Error(t, (&Policy{Jitter: 1.5}).Validate())
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
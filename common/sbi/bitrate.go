@@ -0,0 +1,51 @@
+package sbi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// bitRateUnits are the BitRate units in increasing order (TS 29.571
+// 5.5.2)
+var bitRateUnits = []struct {
+	name  string
+	scale uint64
+}{
+	{"bps", 1},
+	{"Kbps", 1e3},
+	{"Mbps", 1e6},
+	{"Gbps", 1e9},
+	{"Tbps", 1e12},
+}
+
+// ParseBitRate parses a BitRate string such as "1 Gbps" or "2.5 Mbps" into
+// bits per second (TS 29.571 5.5.2)
+func ParseBitRate(s string) (uint64, error) {
+	value, unit, ok := strings.Cut(strings.TrimSpace(s), " ")
+	if !ok {
+		return 0, fmt.Errorf("invalid bit rate %q: missing unit", s)
+	}
+	for _, u := range bitRateUnits {
+		if !strings.EqualFold(unit, u.name) {
+			continue
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || f < 0 {
+			return 0, fmt.Errorf("invalid bit rate %q", s)
+		}
+		return uint64(f * float64(u.scale)), nil
+	}
+	return 0, fmt.Errorf("invalid bit rate %q: unknown unit %q", s, unit)
+}
+
+// FormatBitRate formats bits per second as a BitRate string in the largest
+// unit the value is a whole multiple of
+func FormatBitRate(bps uint64) string {
+	for i := len(bitRateUnits) - 1; i > 0; i-- {
+		if u := bitRateUnits[i]; bps >= u.scale && bps%u.scale == 0 {
+			return fmt.Sprintf("%d %s", bps/u.scale, u.name)
+		}
+	}
+	return fmt.Sprintf("%d bps", bps)
+}
@@ -0,0 +1,45 @@
+package sbi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBitRate(t *testing.T) {
+	for s, want := range map[string]uint64{
+		"0 bps":    0,
+		"512 bps":  512,
+		"100 Kbps": 100_000,
+		"2.5 Mbps": 2_500_000,
+		"1 Gbps":   1_000_000_000,
+		"1 gbps":   1_000_000_000,
+		"2 Tbps":   2_000_000_000_000,
+	} {
+		got, err := ParseBitRate(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, want, got, s)
+	}
+
+	for _, s := range []string{"", "100", "100 Xbps", "fast Mbps", "-1 Mbps"} {
+		_, err := ParseBitRate(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestFormatBitRate(t *testing.T) {
+	for bps, want := range map[uint64]string{
+		0:             "0 bps",
+		999:           "999 bps",
+		100_000:       "100 Kbps",
+		2_500_000:     "2500 Kbps",
+		1_000_000_000: "1 Gbps",
+	} {
+		assert.Equal(t, want, FormatBitRate(bps))
+
+		parsed, err := ParseBitRate(want)
+		require.NoError(t, err)
+		assert.Equal(t, bps, parsed)
+	}
+}
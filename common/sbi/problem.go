@@ -0,0 +1,62 @@
+package sbi
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ContentTypeProblem is the media type of SBI error responses (RFC 7807)
+const ContentTypeProblem = "application/problem+json"
+
+// ContentType5GNAS is the media type of a binary 5G NAS PDU (TS 29.518 6.1.2.2)
+const ContentType5GNAS = "application/vnd.3gpp.5gnas"
+
+// Application error causes carried in ProblemDetails (TS 29.500 Table
+// 5.2.7.2-1 and the NF service specifications)
+const (
+	CauseInvalidMsgFormat       = "INVALID_MSG_FORMAT"
+	CauseInvalidQueryParam      = "INVALID_QUERY_PARAM"
+	CauseMandatoryIEMissing     = "MANDATORY_IE_MISSING"
+	CauseMandatoryIEIncorrect   = "MANDATORY_IE_INCORRECT"
+	CauseUnauthorized           = "UNAUTHORIZED"
+	CauseAuthenticationRejected = "AUTHENTICATION_REJECTED"
+	CauseSubscriberNotFound     = "SUBSCRIBER_NOT_FOUND"
+	CauseDataNotFound           = "DATA_NOT_FOUND"
+	CauseContextNotFound        = "CONTEXT_NOT_FOUND"
+	CauseSubscriptionNotFound   = "SUBSCRIPTION_NOT_FOUND"
+	CauseResourceAlreadyExists  = "RESOURCE_ALREADY_EXISTS"
+	CauseSystemFailure          = "SYSTEM_FAILURE"
+	CauseNFCongestion           = "NF_CONGESTION"
+	CauseNFCongestionRisk       = "NF_CONGESTION_RISK"
+)
+
+// ProblemDetails is the error body of SBI responses (TS 29.571 5.2.4.1,
+// RFC 7807)
+type ProblemDetails struct {
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title,omitempty"`
+	Status   int    `json:"status,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Cause    string `json:"cause,omitempty"`
+}
+
+// NewProblem creates a ProblemDetails whose detail is err, when not nil
+func NewProblem(status int, cause, title string, err error) *ProblemDetails {
+	problem := &ProblemDetails{
+		Title:  title,
+		Status: status,
+		Cause:  cause,
+	}
+	if err != nil {
+		problem.Detail = err.Error()
+	}
+	return problem
+}
+
+// WriteProblem writes problem with its status as application/problem+json
+func WriteProblem(w http.ResponseWriter, problem *ProblemDetails) error {
+	w.Header().Set("Content-Type", ContentTypeProblem)
+	w.WriteHeader(problem.Status)
+	return json.NewEncoder(w).Encode(problem)
+}
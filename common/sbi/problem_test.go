@@ -0,0 +1,39 @@
+package sbi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteProblem(t *testing.T) {
+	rec := httptest.NewRecorder()
+	err := WriteProblem(rec, NewProblem(http.StatusNotFound, CauseSubscriberNotFound, "subscriber not found", errors.New("imsi-001010000000001")))
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, ContentTypeProblem, rec.Header().Get("Content-Type"))
+
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, map[string]interface{}{
+		"status": float64(http.StatusNotFound),
+		"title":  "subscriber not found",
+		"detail": "imsi-001010000000001",
+		"cause":  CauseSubscriberNotFound,
+	}, body)
+}
+
+func TestNewProblemWithoutError(t *testing.T) {
+	problem := NewProblem(http.StatusNotFound, CauseContextNotFound, "UE context not found", nil)
+	assert.Empty(t, problem.Detail)
+
+	data, err := json.Marshal(problem)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "detail")
+}
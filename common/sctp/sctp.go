@@ -0,0 +1,227 @@
+// Package sctp carries the PDUs of SCTP based application protocols, such
+// as F1AP and NGAP, over kernel SCTP associations (RFC 9260). A TCP
+// fallback framing each PDU with its stream serves environments without
+// SCTP.
+package sctp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Transport protocols for Config.Protocol
+const (
+	PROTOCOL_SCTP = "sctp"
+	PROTOCOL_TCP  = "tcp"
+)
+
+const (
+	DefaultStreams           = 4
+	DefaultHeartbeatInterval = 30 * time.Second // RFC 9260 HB.interval
+
+	// Largest PDU accepted
+	maxMessageSize = 1 << 20
+)
+
+var (
+	// ErrUnsupported is returned when the platform or kernel has no SCTP
+	ErrUnsupported = errors.New("sctp is not supported")
+
+	// ErrAssociationClosed is returned by Receive once the peer has shut
+	// down or the association has been lost
+	ErrAssociationClosed = errors.New("association closed")
+)
+
+// Config selects and tunes the transport
+type Config struct {
+	// Protocol is "sctp" (default) or "tcp" for environments without SCTP
+	Protocol string `yaml:"protocol"`
+
+	// Streams is the number of outbound streams requested, at least two:
+	// stream 0 for non-UE-associated signalling and the rest for UEs
+	Streams uint16 `yaml:"streams"`
+
+	// HeartbeatInterval is the SCTP heartbeat interval, or the TCP
+	// keep-alive period for the fallback
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"`
+
+	// PPID is the payload protocol identifier of the application protocol;
+	// set by the protocol's package, not configured
+	PPID uint32 `yaml:"-"`
+}
+
+// withDefaults fills in unset fields
+func (c Config) withDefaults() (Config, error) {
+	if c.Protocol == "" {
+		c.Protocol = PROTOCOL_SCTP
+	}
+	if c.Protocol != PROTOCOL_SCTP && c.Protocol != PROTOCOL_TCP {
+		return c, fmt.Errorf("invalid transport protocol: %s", c.Protocol)
+	}
+	if c.Streams == 0 {
+		c.Streams = DefaultStreams
+	}
+	if c.Streams < 2 {
+		return c, fmt.Errorf("transport needs at least 2 streams, got %d", c.Streams)
+	}
+	if c.HeartbeatInterval == 0 {
+		c.HeartbeatInterval = DefaultHeartbeatInterval
+	}
+	return c, nil
+}
+
+// Association carries PDUs between two endpoints. Each PDU is sent on a
+// stream; PDUs on one stream are delivered in order.
+type Association interface {
+	// Send sends one PDU on a stream
+	Send(stream uint16, pdu []byte) error
+
+	// Receive blocks until the next PDU arrives and returns it with the
+	// stream it arrived on
+	Receive() (pdu []byte, stream uint16, err error)
+
+	// Streams is the number of outbound streams available
+	Streams() uint16
+
+	LocalAddr() net.Addr
+	RemoteAddr() net.Addr
+	Close() error
+}
+
+// Listener accepts associations
+type Listener interface {
+	Accept() (Association, error)
+	Addr() net.Addr
+	Close() error
+}
+
+// Listen listens for associations on address, a host:port
+func Listen(cfg Config, address string) (Listener, error) {
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Protocol == PROTOCOL_TCP {
+		l, err := net.Listen("tcp", address)
+		if err != nil {
+			return nil, err
+		}
+		return &tcpListener{listener: l, cfg: cfg}, nil
+	}
+	return listenSCTP(cfg, address)
+}
+
+// Dial establishes an association with the endpoint at address
+func Dial(cfg Config, address string, timeout time.Duration) (Association, error) {
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Protocol == PROTOCOL_TCP {
+		conn, err := net.DialTimeout("tcp", address, timeout)
+		if err != nil {
+			return nil, err
+		}
+		return newTCPAssociation(conn.(*net.TCPConn), cfg), nil
+	}
+	return dialSCTP(cfg, address, timeout)
+}
+
+// tcpListener accepts TCP fallback associations
+type tcpListener struct {
+	listener net.Listener
+	cfg      Config
+}
+
+func (l *tcpListener) Accept() (Association, error) {
+	conn, err := l.listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return newTCPAssociation(conn.(*net.TCPConn), l.cfg), nil
+}
+
+func (l *tcpListener) Addr() net.Addr {
+	return l.listener.Addr()
+}
+
+func (l *tcpListener) Close() error {
+	return l.listener.Close()
+}
+
+// tcpAssociation emulates an association over TCP. Each PDU is framed as
+// stream (2 octets), length (4 octets) and the PDU; all streams share the
+// one ordered byte stream.
+type tcpAssociation struct {
+	conn    *net.TCPConn
+	streams uint16
+	writeMu sync.Mutex
+}
+
+func newTCPAssociation(conn *net.TCPConn, cfg Config) *tcpAssociation {
+	conn.SetNoDelay(true)
+	conn.SetKeepAlive(true)
+	conn.SetKeepAlivePeriod(cfg.HeartbeatInterval)
+	return &tcpAssociation{conn: conn, streams: cfg.Streams}
+}
+
+func (a *tcpAssociation) Send(stream uint16, pdu []byte) error {
+	if stream >= a.streams {
+		return fmt.Errorf("stream %d outside the %d outbound streams", stream, a.streams)
+	}
+	if len(pdu) > maxMessageSize {
+		return fmt.Errorf("message of %d octets too large", len(pdu))
+	}
+
+	frame := make([]byte, 6+len(pdu))
+	binary.BigEndian.PutUint16(frame[0:2], stream)
+	binary.BigEndian.PutUint32(frame[2:6], uint32(len(pdu)))
+	copy(frame[6:], pdu)
+
+	a.writeMu.Lock()
+	defer a.writeMu.Unlock()
+	_, err := a.conn.Write(frame)
+	return err
+}
+
+func (a *tcpAssociation) Receive() ([]byte, uint16, error) {
+	var header [6]byte
+	if _, err := io.ReadFull(a.conn, header[:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, 0, ErrAssociationClosed
+		}
+		return nil, 0, err
+	}
+	stream := binary.BigEndian.Uint16(header[0:2])
+	length := binary.BigEndian.Uint32(header[2:6])
+	if length > maxMessageSize {
+		return nil, 0, fmt.Errorf("message of %d octets too large", length)
+	}
+
+	pdu := make([]byte, length)
+	if _, err := io.ReadFull(a.conn, pdu); err != nil {
+		return nil, 0, err
+	}
+	return pdu, stream, nil
+}
+
+func (a *tcpAssociation) Streams() uint16 {
+	return a.streams
+}
+
+func (a *tcpAssociation) LocalAddr() net.Addr {
+	return a.conn.LocalAddr()
+}
+
+func (a *tcpAssociation) RemoteAddr() net.Addr {
+	return a.conn.RemoteAddr()
+}
+
+func (a *tcpAssociation) Close() error {
+	return a.conn.Close()
+}
@@ -0,0 +1,493 @@
+//go:build linux && !386
+
+package sctp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Linux kernel SCTP sockets API (RFC 6458, linux/sctp.h). One-to-one style
+// sockets are used: one socket per association, like TCP.
+const (
+	ipprotoSCTP = 132
+	solSCTP     = 132
+
+	// Socket options
+	sctpInitMsg        = 2
+	sctpNoDelay        = 3
+	sctpPeerAddrParams = 9
+	sctpEvents         = 11
+	sctpStatus         = 14
+
+	// Ancillary data type of struct sctp_sndrcvinfo
+	sctpSndRcv = 1
+
+	// Notifications
+	msgNotification   = 0x8000
+	sctpAssocChange   = 0x8001
+	sctpShutdownEvent = 0x8005
+
+	// sac_state of SCTP_ASSOC_CHANGE
+	sctpCommLost     = 1
+	sctpShutdownComp = 3
+	sctpCantStrAssoc = 4
+
+	sppHBEnable = 1
+
+	sndRcvInfoSize = 32
+	// struct sctp_paddrparams up to spp_flags; accepted by all kernels
+	paddrParamsSize = 152
+	statusSize      = 176
+
+	receiveBufferSize = 65536
+)
+
+// sctpAddr is the address of an SCTP endpoint
+type sctpAddr struct {
+	IP   net.IP
+	Port int
+}
+
+func (a *sctpAddr) Network() string { return "sctp" }
+
+func (a *sctpAddr) String() string {
+	return net.JoinHostPort(a.IP.String(), strconv.Itoa(a.Port))
+}
+
+// sctpListener accepts SCTP associations
+type sctpListener struct {
+	file   *os.File
+	raw    syscall.RawConn
+	addr   net.Addr
+	cfg    Config
+	closed atomic.Bool
+}
+
+func listenSCTP(cfg Config, address string) (Listener, error) {
+	sa, family, err := sctpSockaddr(address)
+	if err != nil {
+		return nil, err
+	}
+	fd, err := newSCTPSocket(family, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.SetsockoptInt(fd, syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1); err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("setsockopt", err)
+	}
+	if err := syscall.Bind(fd, sa); err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("bind", err)
+	}
+	if err := syscall.Listen(fd, syscall.SOMAXCONN); err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("listen", err)
+	}
+	local, err := syscall.Getsockname(fd)
+	if err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("getsockname", err)
+	}
+
+	file := os.NewFile(uintptr(fd), "sctp-listener")
+	raw, err := file.SyscallConn()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &sctpListener{file: file, raw: raw, addr: toSCTPAddr(local), cfg: cfg}, nil
+}
+
+func (l *sctpListener) Accept() (Association, error) {
+	var fd int
+	var acceptErr error
+	err := l.raw.Read(func(s uintptr) bool {
+		fd, _, acceptErr = syscall.Accept4(int(s), syscall.SOCK_NONBLOCK|syscall.SOCK_CLOEXEC)
+		return acceptErr != syscall.EAGAIN
+	})
+	if l.closed.Load() {
+		return nil, net.ErrClosed
+	}
+	if err != nil {
+		return nil, err
+	}
+	if acceptErr != nil {
+		return nil, os.NewSyscallError("accept", acceptErr)
+	}
+	return newSCTPAssociation(os.NewFile(uintptr(fd), "sctp-association"), l.cfg)
+}
+
+func (l *sctpListener) Addr() net.Addr {
+	return l.addr
+}
+
+func (l *sctpListener) Close() error {
+	l.closed.Store(true)
+	return l.file.Close()
+}
+
+// sctpAssociation is an association over one SCTP one-to-one socket
+type sctpAssociation struct {
+	file    *os.File
+	raw     syscall.RawConn
+	streams uint16
+	ppid    uint32
+	local   net.Addr
+	remote  net.Addr
+	closed  atomic.Bool
+
+	buf []byte // Receive buffers, used by one reader at a time
+	oob []byte
+}
+
+func dialSCTP(cfg Config, address string, timeout time.Duration) (Association, error) {
+	sa, family, err := sctpSockaddr(address)
+	if err != nil {
+		return nil, err
+	}
+	fd, err := newSCTPSocket(family, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	file := os.NewFile(uintptr(fd), "sctp-association")
+	if err := connectSCTP(file, sa, timeout); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to establish sctp association with %s: %w", address, err)
+	}
+	return newSCTPAssociation(file, cfg)
+}
+
+// connectSCTP starts association setup and waits for it to complete
+func connectSCTP(file *os.File, sa syscall.Sockaddr, timeout time.Duration) error {
+	raw, err := file.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var connectErr error
+	if err := raw.Control(func(s uintptr) {
+		connectErr = syscall.Connect(int(s), sa)
+	}); err != nil {
+		return err
+	}
+	if connectErr == nil {
+		return nil
+	}
+	if connectErr != syscall.EINPROGRESS {
+		return os.NewSyscallError("connect", connectErr)
+	}
+
+	if timeout > 0 {
+		file.SetWriteDeadline(time.Now().Add(timeout))
+		defer file.SetWriteDeadline(time.Time{})
+	}
+	connectErr = nil
+	err = raw.Write(func(s uintptr) bool {
+		soErr, err := syscall.GetsockoptInt(int(s), syscall.SOL_SOCKET, syscall.SO_ERROR)
+		if err != nil {
+			connectErr = os.NewSyscallError("getsockopt", err)
+			return true
+		}
+		if soErr != 0 {
+			connectErr = os.NewSyscallError("connect", syscall.Errno(soErr))
+			return true
+		}
+		// Still connecting while the peer address is unknown
+		_, err = syscall.Getpeername(int(s))
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	return connectErr
+}
+
+func newSCTPAssociation(file *os.File, cfg Config) (*sctpAssociation, error) {
+	raw, err := file.SyscallConn()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	var local, remote syscall.Sockaddr
+	status := make([]byte, statusSize)
+	var sockErr error
+	if err := raw.Control(func(s uintptr) {
+		if local, sockErr = syscall.Getsockname(int(s)); sockErr != nil {
+			sockErr = os.NewSyscallError("getsockname", sockErr)
+			return
+		}
+		if remote, sockErr = syscall.Getpeername(int(s)); sockErr != nil {
+			sockErr = os.NewSyscallError("getpeername", sockErr)
+			return
+		}
+		sockErr = getsockopt(int(s), sctpStatus, status)
+	}); err != nil {
+		sockErr = err
+	}
+	if sockErr != nil {
+		file.Close()
+		return nil, sockErr
+	}
+
+	// The peer may have granted fewer streams than were requested
+	streams := cfg.Streams
+	if out := binary.NativeEndian.Uint16(status[18:20]); out < streams {
+		streams = out
+	}
+
+	return &sctpAssociation{
+		file:    file,
+		raw:     raw,
+		streams: streams,
+		ppid:    cfg.PPID,
+		local:   toSCTPAddr(local),
+		remote:  toSCTPAddr(remote),
+		buf:     make([]byte, receiveBufferSize),
+		oob:     make([]byte, syscall.CmsgSpace(sndRcvInfoSize)),
+	}, nil
+}
+
+func (a *sctpAssociation) Send(stream uint16, pdu []byte) error {
+	if stream >= a.streams {
+		return fmt.Errorf("stream %d outside the %d outbound streams", stream, a.streams)
+	}
+
+	// struct sctp_sndrcvinfo: sinfo_stream, and sinfo_ppid which the
+	// kernel passes through unchanged, so in network byte order
+	info := make([]byte, sndRcvInfoSize)
+	binary.NativeEndian.PutUint16(info[0:2], stream)
+	binary.BigEndian.PutUint32(info[8:12], a.ppid)
+
+	oob := make([]byte, syscall.CmsgSpace(len(info)))
+	h := (*syscall.Cmsghdr)(unsafe.Pointer(&oob[0]))
+	h.Level = solSCTP
+	h.Type = sctpSndRcv
+	h.SetLen(syscall.CmsgLen(len(info)))
+	copy(oob[syscall.CmsgLen(0):], info)
+
+	var sendErr error
+	err := a.raw.Write(func(s uintptr) bool {
+		sendErr = syscall.Sendmsg(int(s), pdu, oob, nil, 0)
+		return sendErr != syscall.EAGAIN
+	})
+	if err != nil {
+		return err
+	}
+	if sendErr == syscall.EPIPE || sendErr == syscall.ECONNRESET {
+		return ErrAssociationClosed
+	}
+	if sendErr != nil {
+		return os.NewSyscallError("sendmsg", sendErr)
+	}
+	return nil
+}
+
+func (a *sctpAssociation) Receive() ([]byte, uint16, error) {
+	var msg []byte
+	for {
+		var n, oobn, flags int
+		var recvErr error
+		err := a.raw.Read(func(s uintptr) bool {
+			n, oobn, flags, _, recvErr = syscall.Recvmsg(int(s), a.buf, a.oob, 0)
+			return recvErr != syscall.EAGAIN
+		})
+		if a.closed.Load() {
+			return nil, 0, net.ErrClosed
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		if recvErr == syscall.ECONNRESET {
+			return nil, 0, ErrAssociationClosed
+		}
+		if recvErr != nil {
+			return nil, 0, os.NewSyscallError("recvmsg", recvErr)
+		}
+		if n == 0 && flags == 0 {
+			return nil, 0, ErrAssociationClosed
+		}
+
+		if flags&msgNotification != 0 {
+			if associationEnded(a.buf[:n]) {
+				return nil, 0, ErrAssociationClosed
+			}
+			continue
+		}
+
+		// A message larger than the buffer arrives in parts, the last one
+		// marked end of record
+		msg = append(msg, a.buf[:n]...)
+		if flags&syscall.MSG_EOR == 0 {
+			if len(msg) > maxMessageSize {
+				return nil, 0, fmt.Errorf("message of more than %d octets too large", maxMessageSize)
+			}
+			continue
+		}
+
+		stream, ppid, ok := parseSndRcvInfo(a.oob[:oobn])
+		if !ok || ppid != a.ppid {
+			// Another protocol's; discarded
+			msg = nil
+			continue
+		}
+		return msg, stream, nil
+	}
+}
+
+func (a *sctpAssociation) Streams() uint16 {
+	return a.streams
+}
+
+func (a *sctpAssociation) LocalAddr() net.Addr {
+	return a.local
+}
+
+func (a *sctpAssociation) RemoteAddr() net.Addr {
+	return a.remote
+}
+
+// Close shuts the association down gracefully
+func (a *sctpAssociation) Close() error {
+	a.closed.Store(true)
+	return a.file.Close()
+}
+
+// newSCTPSocket opens a non-blocking one-to-one SCTP socket requesting
+// cfg.Streams streams each way, with heartbeats every cfg.HeartbeatInterval
+// and notifications of the association ending
+func newSCTPSocket(family int, cfg Config) (int, error) {
+	fd, err := syscall.Socket(family, syscall.SOCK_STREAM|syscall.SOCK_NONBLOCK|syscall.SOCK_CLOEXEC, ipprotoSCTP)
+	if err != nil {
+		if errors.Is(err, syscall.EPROTONOSUPPORT) || errors.Is(err, syscall.ESOCKTNOSUPPORT) {
+			return -1, fmt.Errorf("%w: %v", ErrUnsupported, err)
+		}
+		return -1, os.NewSyscallError("socket", err)
+	}
+
+	// struct sctp_initmsg: sinit_num_ostreams, sinit_max_instreams
+	initMsg := make([]byte, 8)
+	binary.NativeEndian.PutUint16(initMsg[0:2], cfg.Streams)
+	binary.NativeEndian.PutUint16(initMsg[2:4], cfg.Streams)
+
+	// struct sctp_event_subscribe: data_io, association, address,
+	// send_failure, peer_error, shutdown
+	events := []byte{1, 1, 0, 0, 0, 1}
+
+	// struct sctp_paddrparams: spp_hbinterval in milliseconds and
+	// spp_flags, for every peer address
+	params := make([]byte, paddrParamsSize)
+	binary.NativeEndian.PutUint32(params[132:136], uint32(cfg.HeartbeatInterval.Milliseconds()))
+	binary.NativeEndian.PutUint32(params[146:150], sppHBEnable)
+
+	for _, opt := range []struct {
+		name  int
+		value []byte
+	}{
+		{sctpInitMsg, initMsg},
+		{sctpEvents, events},
+		{sctpPeerAddrParams, params},
+	} {
+		if err := setsockopt(fd, opt.name, opt.value); err != nil {
+			syscall.Close(fd)
+			return -1, err
+		}
+	}
+	if err := syscall.SetsockoptInt(fd, solSCTP, sctpNoDelay, 1); err != nil {
+		syscall.Close(fd)
+		return -1, os.NewSyscallError("setsockopt", err)
+	}
+	return fd, nil
+}
+
+// sctpSockaddr resolves a host:port; an empty host is the IPv4 wildcard
+func sctpSockaddr(address string) (syscall.Sockaddr, int, error) {
+	addr, err := net.ResolveTCPAddr("tcp", address)
+	if err != nil {
+		return nil, 0, err
+	}
+	if ip4 := addr.IP.To4(); ip4 != nil || addr.IP == nil {
+		sa := &syscall.SockaddrInet4{Port: addr.Port}
+		copy(sa.Addr[:], ip4)
+		return sa, syscall.AF_INET, nil
+	}
+	sa := &syscall.SockaddrInet6{Port: addr.Port}
+	copy(sa.Addr[:], addr.IP.To16())
+	return sa, syscall.AF_INET6, nil
+}
+
+func toSCTPAddr(sa syscall.Sockaddr) net.Addr {
+	switch sa := sa.(type) {
+	case *syscall.SockaddrInet4:
+		return &sctpAddr{IP: append(net.IP(nil), sa.Addr[:]...), Port: sa.Port}
+	case *syscall.SockaddrInet6:
+		return &sctpAddr{IP: append(net.IP(nil), sa.Addr[:]...), Port: sa.Port}
+	}
+	return &sctpAddr{}
+}
+
+// parseSndRcvInfo returns the stream and payload protocol identifier of a
+// received message
+func parseSndRcvInfo(oob []byte) (uint16, uint32, bool) {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return 0, 0, false
+	}
+	for _, m := range msgs {
+		if m.Header.Level == solSCTP && m.Header.Type == sctpSndRcv && len(m.Data) >= 12 {
+			return binary.NativeEndian.Uint16(m.Data[0:2]), binary.BigEndian.Uint32(m.Data[8:12]), true
+		}
+	}
+	return 0, 0, false
+}
+
+// associationEnded reports whether a notification tells of the association
+// being lost or shut down
+func associationEnded(n []byte) bool {
+	if len(n) < 2 {
+		return false
+	}
+	switch binary.NativeEndian.Uint16(n[0:2]) {
+	case sctpAssocChange:
+		if len(n) < 10 {
+			return false
+		}
+		switch binary.NativeEndian.Uint16(n[8:10]) {
+		case sctpCommLost, sctpShutdownComp, sctpCantStrAssoc:
+			return true
+		}
+	case sctpShutdownEvent:
+		return true
+	}
+	return false
+}
+
+func setsockopt(fd, name int, value []byte) error {
+	_, _, errno := syscall.Syscall6(syscall.SYS_SETSOCKOPT, uintptr(fd), solSCTP, uintptr(name),
+		uintptr(unsafe.Pointer(&value[0])), uintptr(len(value)), 0)
+	if errno != 0 {
+		return os.NewSyscallError("setsockopt", errno)
+	}
+	return nil
+}
+
+func getsockopt(fd, name int, value []byte) error {
+	length := uint32(len(value))
+	_, _, errno := syscall.Syscall6(syscall.SYS_GETSOCKOPT, uintptr(fd), solSCTP, uintptr(name),
+		uintptr(unsafe.Pointer(&value[0])), uintptr(unsafe.Pointer(&length)), 0)
+	if errno != 0 {
+		return os.NewSyscallError("getsockopt", errno)
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+//go:build !linux || 386
+
+package sctp
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// The kernel SCTP sockets are used on Linux; 386 lacks the direct
+// setsockopt system call they are configured through.
+
+// listenSCTP reports SCTP as unsupported
+func listenSCTP(cfg Config, address string) (Listener, error) {
+	return nil, fmt.Errorf("%w on %s/%s", ErrUnsupported, runtime.GOOS, runtime.GOARCH)
+}
+
+// dialSCTP reports SCTP as unsupported
+func dialSCTP(cfg Config, address string, timeout time.Duration) (Association, error) {
+	return nil, fmt.Errorf("%w on %s/%s", ErrUnsupported, runtime.GOOS, runtime.GOARCH)
+}
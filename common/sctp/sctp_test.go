@@ -0,0 +1,78 @@
+package sctp
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// connect establishes an association over the configured transport,
+// returning both ends
+func connect(t *testing.T, cfg Config) (client, server Association) {
+	t.Helper()
+
+	listener, err := Listen(cfg, "127.0.0.1:0")
+	if errors.Is(err, ErrUnsupported) {
+		t.Skipf("no SCTP here: %v", err)
+	}
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	accepted := make(chan Association, 1)
+	go func() {
+		a, err := listener.Accept()
+		assert.NoError(t, err)
+		accepted <- a
+	}()
+
+	client, err = Dial(cfg, listener.Addr().String(), 5*time.Second)
+	require.NoError(t, err)
+	server = <-accepted
+	require.NotNil(t, server)
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return client, server
+}
+
+func testStreams(t *testing.T, cfg Config) {
+	client, server := connect(t, cfg)
+	assert.Equal(t, client.LocalAddr().String(), server.RemoteAddr().String())
+
+	require.NoError(t, client.Send(0, []byte{0x01}))
+	require.NoError(t, client.Send(client.Streams()-1, []byte{0x02, 0x03}))
+	assert.Error(t, client.Send(client.Streams(), []byte{0x04}))
+
+	pdu, stream, err := server.Receive()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x01}, pdu)
+	assert.Equal(t, uint16(0), stream)
+
+	pdu, stream, err = server.Receive()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x02, 0x03}, pdu)
+	assert.Equal(t, client.Streams()-1, stream)
+
+	require.NoError(t, client.Close())
+	_, _, err = server.Receive()
+	assert.ErrorIs(t, err, ErrAssociationClosed)
+}
+
+func TestSCTP_Streams(t *testing.T) {
+	testStreams(t, Config{Protocol: PROTOCOL_SCTP, PPID: 60, HeartbeatInterval: time.Second})
+}
+
+func TestTCP_Streams(t *testing.T) {
+	testStreams(t, Config{Protocol: PROTOCOL_TCP})
+}
+
+func TestConfig_Invalid(t *testing.T) {
+	_, err := Listen(Config{Protocol: "udp"}, "127.0.0.1:0")
+	assert.Error(t, err)
+	_, err = Dial(Config{Protocol: PROTOCOL_TCP, Streams: 1}, "127.0.0.1:1", time.Second)
+	assert.Error(t, err)
+}
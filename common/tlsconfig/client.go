@@ -0,0 +1,78 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// ClientConfig holds the TLS settings of SBI clients: the CA bundle servers
+// are verified against and, for mutual TLS, the certificate presented to
+// them. An empty CA file uses the system roots.
+type ClientConfig struct {
+	CAFile     string `yaml:"ca_file"`
+	CertFile   string `yaml:"cert_file"`
+	KeyFile    string `yaml:"key_file"`
+	ServerName string `yaml:"server_name"` // Overrides the host name servers are verified against
+
+	// InsecureSkipVerify accepts any server certificate. For development
+	// only.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+
+	Options `yaml:",inline"`
+}
+
+// Validate rejects unreadable credentials and weak TLS settings
+func (c *ClientConfig) Validate() error {
+	_, err := c.Build()
+	return err
+}
+
+// Build returns a client tls.Config applying the settings
+func (c *ClientConfig) Build() (*tls.Config, error) {
+	cfg, err := c.Options.Build()
+	if err != nil {
+		return nil, err
+	}
+	cfg.ServerName = c.ServerName
+	cfg.InsecureSkipVerify = c.InsecureSkipVerify
+
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tls ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in tls ca_file %s", c.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		if c.CertFile == "" || c.KeyFile == "" {
+			return nil, fmt.Errorf("tls cert_file and key_file must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load tls client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// Transport returns an HTTP transport dialing SBI servers with the settings,
+// to be shared by the clients of an NF
+func (c *ClientConfig) Transport() (*http.Transport, error) {
+	cfg, err := c.Build()
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = cfg
+	return transport, nil
+}
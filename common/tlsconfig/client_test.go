@@ -0,0 +1,188 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCA issues the certificates of a test PKI
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns the PEM certificate and key of a leaf for commonName
+func (ca *testCA) issue(t *testing.T, commonName string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{commonName},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+// newMutualTLSServer returns a server requiring client certificates issued by
+// ca, answering with the common name of the client
+func newMutualTLSServer(t *testing.T, ca *testCA) *httptest.Server {
+	t.Helper()
+
+	certPEM, keyPEM := ca.issue(t, "nrf.5gc.test", x509.ExtKeyUsageServerAuth)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	require.NoError(t, err)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestClientAuthenticatesWithMutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	server := newMutualTLSServer(t, ca)
+	certPEM, keyPEM := ca.issue(t, "amf.5gc.test", x509.ExtKeyUsageClientAuth)
+
+	cfg := &ClientConfig{
+		CAFile:     writeFile(t, "ca.crt", ca.pem),
+		CertFile:   writeFile(t, "amf.crt", certPEM),
+		KeyFile:    writeFile(t, "amf.key", keyPEM),
+		ServerName: "nrf.5gc.test",
+	}
+	transport, err := cfg.Transport()
+	require.NoError(t, err)
+
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "amf.5gc.test", string(body), "server sees the client certificate")
+}
+
+func TestClientWithoutCertificateRejected(t *testing.T) {
+	ca := newTestCA(t)
+	server := newMutualTLSServer(t, ca)
+
+	transport, err := (&ClientConfig{CAFile: writeFile(t, "ca.crt", ca.pem)}).Transport()
+	require.NoError(t, err)
+
+	_, err = (&http.Client{Transport: transport}).Get(server.URL)
+	assert.Error(t, err)
+}
+
+func TestClientVerifiesServer(t *testing.T) {
+	ca := newTestCA(t)
+	server := newMutualTLSServer(t, ca)
+	certPEM, keyPEM := ca.issue(t, "amf.5gc.test", x509.ExtKeyUsageClientAuth)
+	certFile := writeFile(t, "amf.crt", certPEM)
+	keyFile := writeFile(t, "amf.key", keyPEM)
+
+	// The server's CA is not in the system roots
+	transport, err := (&ClientConfig{CertFile: certFile, KeyFile: keyFile}).Transport()
+	require.NoError(t, err)
+	_, err = (&http.Client{Transport: transport}).Get(server.URL)
+	assert.Error(t, err)
+
+	transport, err = (&ClientConfig{CertFile: certFile, KeyFile: keyFile, InsecureSkipVerify: true}).Transport()
+	require.NoError(t, err)
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+}
+
+func TestClientConfigValidate(t *testing.T) {
+	ca := newTestCA(t)
+	certPEM, _ := ca.issue(t, "amf.5gc.test", x509.ExtKeyUsageClientAuth)
+
+	tests := []struct {
+		name string
+		cfg  ClientConfig
+	}{
+		{"missing ca file", ClientConfig{CAFile: filepath.Join(t.TempDir(), "missing.crt")}},
+		{"ca file without certificates", ClientConfig{CAFile: writeFile(t, "empty.crt", []byte("not a certificate"))}},
+		{"cert without key", ClientConfig{CertFile: writeFile(t, "amf.crt", certPEM)}},
+		{"weak options", ClientConfig{Options: Options{MinVersion: "1.1"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, tt.cfg.Validate())
+		})
+	}
+
+	assert.NoError(t, (&ClientConfig{}).Validate())
+}
@@ -0,0 +1,138 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+)
+
+// Options holds TLS hardening options for SBI servers (3GPP TS 33.210 6.2).
+// Empty fields fall back to the secure defaults below.
+type Options struct {
+	MinVersion       string   `yaml:"min_version"`       // "1.2" or "1.3"
+	CipherSuites     []string `yaml:"cipher_suites"`     // IANA names, applies to TLS 1.2
+	CurvePreferences []string `yaml:"curve_preferences"` // "X25519", "P-256", "P-384", "P-521"
+}
+
+// Default TLS settings
+var (
+	DefaultMinVersion = "1.2"
+
+	DefaultCipherSuites = []string{
+		"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+		"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+		"TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+		"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+		"TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256",
+		"TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256",
+	}
+
+	DefaultCurvePreferences = []string{"X25519", "P-256", "P-384"}
+)
+
+var versions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var weakVersions = map[string]bool{
+	"1.0": true,
+	"1.1": true,
+}
+
+var curves = map[string]tls.CurveID{
+	"X25519": tls.X25519,
+	"P-256":  tls.CurveP256,
+	"P-384":  tls.CurveP384,
+	"P-521":  tls.CurveP521,
+}
+
+// Validate rejects unknown or weak TLS settings
+func (o *Options) Validate() error {
+	_, err := o.Build()
+	return err
+}
+
+// Build returns a server tls.Config applying the options
+func (o *Options) Build() (*tls.Config, error) {
+	minVersion := o.MinVersion
+	if minVersion == "" {
+		minVersion = DefaultMinVersion
+	}
+	version, ok := versions[minVersion]
+	if !ok {
+		if weakVersions[minVersion] {
+			return nil, fmt.Errorf("tls min_version %s is not allowed, must be 1.2 or higher", minVersion)
+		}
+		return nil, fmt.Errorf("unknown tls min_version: %s", minVersion)
+	}
+
+	cipherNames := o.CipherSuites
+	if len(cipherNames) == 0 {
+		cipherNames = DefaultCipherSuites
+	}
+	ciphers, err := parseCipherSuites(cipherNames)
+	if err != nil {
+		return nil, err
+	}
+	// An empty list would make Go fall back to its own TLS 1.2 defaults
+	if len(ciphers) == 0 && version < tls.VersionTLS13 {
+		return nil, fmt.Errorf("tls cipher_suites only lists TLS 1.3 suites, add a TLS 1.2 suite or set min_version 1.3")
+	}
+
+	curveNames := o.CurvePreferences
+	if len(curveNames) == 0 {
+		curveNames = DefaultCurvePreferences
+	}
+	curvePrefs := make([]tls.CurveID, 0, len(curveNames))
+	for _, name := range curveNames {
+		curve, ok := curves[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported tls curve: %s", name)
+		}
+		curvePrefs = append(curvePrefs, curve)
+	}
+
+	return &tls.Config{
+		MinVersion:       version,
+		CipherSuites:     ciphers,
+		CurvePreferences: curvePrefs,
+	}, nil
+}
+
+// parseCipherSuites maps IANA cipher suite names to IDs. Only forward secret
+// AEAD suites are accepted; TLS 1.3 suites are always enabled by Go and are
+// skipped.
+func parseCipherSuites(names []string) ([]uint16, error) {
+	secure := make(map[string]*tls.CipherSuite)
+	for _, suite := range tls.CipherSuites() {
+		secure[suite.Name] = suite
+	}
+	insecure := make(map[string]bool)
+	for _, suite := range tls.InsecureCipherSuites() {
+		insecure[suite.Name] = true
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		suite, ok := secure[name]
+		switch {
+		case insecure[name]:
+			return nil, fmt.Errorf("tls cipher suite %s is insecure", name)
+		case !ok:
+			return nil, fmt.Errorf("unknown tls cipher suite: %s", name)
+		case isTLS13Only(suite):
+			continue
+		case !strings.HasPrefix(name, "TLS_ECDHE_") || strings.Contains(name, "_CBC_"):
+			return nil, fmt.Errorf("tls cipher suite %s is weak, use an ECDHE AEAD suite", name)
+		}
+		ids = append(ids, suite.ID)
+	}
+
+	return ids, nil
+}
+
+// isTLS13Only reports whether the suite is only used by TLS 1.3
+func isTLS13Only(suite *tls.CipherSuite) bool {
+	return len(suite.SupportedVersions) == 1 && suite.SupportedVersions[0] == tls.VersionTLS13
+}
@@ -0,0 +1,107 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTLSServer(t *testing.T, opts *Options) *httptest.Server {
+	t.Helper()
+
+	cfg, err := opts.Build()
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = cfg
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func dial(server *httptest.Server, minVersion, maxVersion uint16) error {
+	conn, err := tls.Dial("tcp", server.Listener.Addr().String(), &tls.Config{
+		InsecureSkipVerify: true,
+		MinVersion:         minVersion,
+		MaxVersion:         maxVersion,
+	})
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func TestTLS11HandshakeRejected(t *testing.T) {
+	server := newTLSServer(t, &Options{MinVersion: "1.2"})
+
+	err := dial(server, tls.VersionTLS10, tls.VersionTLS11)
+	assert.Error(t, err)
+
+	err = dial(server, tls.VersionTLS12, tls.VersionTLS12)
+	assert.NoError(t, err)
+}
+
+func TestTLS12HandshakeRejectedWithMinimum13(t *testing.T) {
+	server := newTLSServer(t, &Options{MinVersion: "1.3"})
+
+	assert.Error(t, dial(server, tls.VersionTLS12, tls.VersionTLS12))
+	assert.NoError(t, dial(server, tls.VersionTLS13, tls.VersionTLS13))
+}
+
+func TestDefaults(t *testing.T) {
+	cfg, err := (&Options{}).Build()
+	require.NoError(t, err)
+
+	assert.Equal(t, uint16(tls.VersionTLS12), cfg.MinVersion)
+	assert.Len(t, cfg.CipherSuites, len(DefaultCipherSuites))
+	assert.Equal(t, []tls.CurveID{tls.X25519, tls.CurveP256, tls.CurveP384}, cfg.CurvePreferences)
+}
+
+func TestValidateRejectsWeakConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"tls 1.1 minimum", Options{MinVersion: "1.1"}},
+		{"unknown version", Options{MinVersion: "2.0"}},
+		{"insecure cipher", Options{CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}}},
+		{"cbc cipher", Options{CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA"}}},
+		{"static rsa cipher", Options{CipherSuites: []string{"TLS_RSA_WITH_AES_128_GCM_SHA256"}}},
+		{"unknown cipher", Options{CipherSuites: []string{"TLS_FOO"}}},
+		{"unknown curve", Options{CurvePreferences: []string{"P-192"}}},
+		{"tls 1.3 suites only", Options{CipherSuites: []string{"TLS_AES_128_GCM_SHA256"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, tt.opts.Validate())
+		})
+	}
+}
+
+func TestValidateAcceptsTLS13Suites(t *testing.T) {
+	opts := Options{
+		MinVersion:   "1.3",
+		CipherSuites: []string{"TLS_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"},
+	}
+	cfg, err := opts.Build()
+	require.NoError(t, err)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384}, cfg.CipherSuites)
+}
+
+func TestValidateAcceptsTLS13OnlySuitesWithMinimum13(t *testing.T) {
+	opts := Options{
+		MinVersion:   "1.3",
+		CipherSuites: []string{"TLS_AES_128_GCM_SHA256", "TLS_CHACHA20_POLY1305_SHA256"},
+	}
+	cfg, err := opts.Build()
+	require.NoError(t, err)
+	assert.Empty(t, cfg.CipherSuites)
+}
@@ -9,10 +9,17 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/your-org/5g-network/common/buildinfo"
+	"github.com/your-org/5g-network/common/correlation"
 	"github.com/your-org/5g-network/common/metrics"
+	"github.com/your-org/5g-network/common/nrfpool"
+	"github.com/your-org/5g-network/common/oauth"
+	"github.com/your-org/5g-network/common/reload"
+	"github.com/your-org/5g-network/common/retry"
 	"github.com/your-org/5g-network/nf/amf/internal/client"
 	"github.com/your-org/5g-network/nf/amf/internal/config"
 	amfcontext "github.com/your-org/5g-network/nf/amf/internal/context"
+	"github.com/your-org/5g-network/nf/amf/internal/n2"
 	"github.com/your-org/5g-network/nf/amf/internal/server"
 	"github.com/your-org/5g-network/nf/amf/internal/service"
 	"go.uber.org/zap"
@@ -22,6 +29,7 @@ import (
 var (
 	Version   = "dev"
 	BuildTime = "unknown"
+	GitCommit = "unknown"
 )
 
 func main() {
@@ -29,8 +37,9 @@ func main() {
 	configPath := flag.String("config", "nf/amf/config/amf.yaml", "path to configuration file")
 	flag.Parse()
 
-	// Create logger
-	logger := createLogger("info")
+	// Create logger, at the configured level once loaded
+	level := zap.NewAtomicLevel()
+	logger := createLogger(level)
 	defer logger.Sync()
 
 	logger.Info("Starting AMF (Access and Mobility Management Function)",
@@ -52,8 +61,32 @@ func main() {
 		zap.String("guami", cfg.GetGUAMI()),
 	)
 
+	// Reload the log level, NRF heartbeat interval and NAS timers on SIGHUP
+	reloader := reload.New(*configPath, cfg, config.Load, []string{
+		"observability.logging.level",
+		"nrf.heartbeat_interval",
+		"timers",
+	}, logger)
+	if err := reloader.SetLogLevel(level, func(c *config.Config) string { return c.Observability.Logging.Level }); err != nil {
+		logger.Fatal("Invalid configuration", zap.Error(err))
+	}
+
+	// HTTP transport of the SBI clients, with mutual TLS when configured,
+	// retries of transient failures and the correlation ID of the request served
+	tlsTransport, err := cfg.SBI.ClientTLS.Transport()
+	if err != nil {
+		logger.Fatal("Invalid client TLS configuration", zap.Error(err))
+	}
+	sbiTransport := correlation.NewTransport(retry.NewTransport(tlsTransport, cfg.SBI.ClientRetry, logger), logger)
+
+	// Access tokens for requests to other NFs, from the primary NRF
+	tokens, err := oauth.NewTokenSource(cfg.SBI.ClientOAuth2, cfg.NRF.URL, cfg.NF.InstanceID, "AMF", sbiTransport, logger)
+	if err != nil {
+		logger.Fatal("Invalid client OAuth2 configuration", zap.Error(err))
+	}
+
 	// Create AUSF client
-	ausfClient := client.NewAUSFClient(cfg.AUSF.URL, cfg.AUSF.Timeout, logger)
+	ausfClient := client.NewAUSFClient(cfg.AUSF.URL, cfg.AUSF.Timeout, tokens.Transport(sbiTransport, "AUSF"), logger)
 	logger.Info("AUSF client initialized")
 
 	// Create UE context manager
@@ -62,15 +95,51 @@ func main() {
 
 	// Create registration service
 	registrationService := service.NewRegistrationService(cfg, ausfClient, contextManager, logger)
+	registrationService.SetSMFClient(client.NewSMFClient(cfg.SMF.Timeout, tokens.Transport(sbiTransport, "SMF"), logger))
+	registrationService.SetUDMClient(client.NewUDMClient(cfg.UDM.URL, cfg.UDM.Timeout, tokens.Transport(sbiTransport, "UDM"), logger))
 	logger.Info("Registration service initialized")
 
+	// Create paging service
+	pagingService := service.NewPagingService(cfg, contextManager, logger)
+	logger.Info("Paging service initialized")
+
+	reloader.OnReload(func(c *config.Config) {
+		registrationService.SetTimers(c.Timers)
+		pagingService.SetTimers(c.Timers)
+	})
+
+	// Create NAS service
+	nasService := service.NewNASService(cfg, registrationService, contextManager, logger)
+	logger.Info("NAS service initialized")
+
+	// Start the NGAP listener for gNBs
+	if cfg.N2.Enabled {
+		n2Server, err := n2.NewServer(cfg, nasService, logger)
+		if err != nil {
+			logger.Fatal("Failed to start N2 server", zap.Error(err))
+		}
+		defer n2Server.Close()
+		go n2Server.Serve()
+		logger.Info("N2 server started",
+			zap.String("address", n2Server.Addr().String()),
+			zap.String("transport", cfg.N2.Transport.Protocol),
+		)
+	}
+
 	// Create HTTP server
-	srv := server.NewServer(cfg, registrationService, contextManager, logger)
+	srv := server.NewServer(cfg, registrationService, pagingService, contextManager, logger)
+	srv.SetNASService(nasService)
+	srv.SetBuildInfo(buildinfo.New("AMF", Version, GitCommit, BuildTime,
+		"namf-comm", "namf-auth", "namf-reg", "namf-nas", "emergency-registration", "paging", "pdu-session-establishment", "log-level"))
+	srv.SetLogLevel(level)
 
-	// Initialize metrics server
-	metricsServer := metrics.NewMetricsServer(9094, logger)
+	// Initialize metrics server, on the port assigned to the AMF
+	metricsRegistry, err := metrics.NewRegistry("AMF", cfg.NF.InstanceID)
+	if err != nil {
+		logger.Fatal("Failed to create metrics registry", zap.Error(err))
+	}
+	metricsServer := metrics.NewMetricsServer(metricsRegistry, logger)
 	go func() {
-		logger.Info("Starting metrics server on :9094")
 		if err := metricsServer.Start(); err != nil {
 			logger.Error("Metrics server error", zap.Error(err))
 		}
@@ -84,7 +153,12 @@ func main() {
 	// Register with NRF if enabled
 	ctx := context.Background()
 	if cfg.NRF.Enabled {
-		nrfClient := client.NewNRFClient(cfg.NRF.URL, logger)
+		endpoints, err := nrfpool.New(ctx, cfg.NRF.URL, cfg.NRF.Options, logger)
+		if err != nil {
+			logger.Fatal("Failed to resolve NRF endpoints", zap.Error(err))
+		}
+		nrfClient := client.NewNRFClient(endpoints, sbiTransport, logger)
+		registrationService.SetNRFClient(nrfClient)
 
 		profile := &client.NFProfile{
 			NFInstanceID: cfg.NF.InstanceID,
@@ -119,7 +193,7 @@ func main() {
 
 			// Start heartbeat goroutine
 			go func() {
-				ticker := time.NewTicker(cfg.NRF.HeartbeatInterval)
+				ticker := reloader.Ticker(func(c *config.Config) time.Duration { return c.NRF.HeartbeatInterval })
 				defer ticker.Stop()
 
 				for {
@@ -143,6 +217,8 @@ func main() {
 		}
 	}
 
+	go reloader.Watch(ctx)
+
 	// Start HTTP server in a goroutine
 	serverErrors := make(chan error, 1)
 	go func() {
@@ -177,24 +253,10 @@ func main() {
 	}
 }
 
-// createLogger creates a structured logger
-func createLogger(level string) *zap.Logger {
-	var zapLevel zapcore.Level
-	switch level {
-	case "debug":
-		zapLevel = zapcore.DebugLevel
-	case "info":
-		zapLevel = zapcore.InfoLevel
-	case "warn":
-		zapLevel = zapcore.WarnLevel
-	case "error":
-		zapLevel = zapcore.ErrorLevel
-	default:
-		zapLevel = zapcore.InfoLevel
-	}
-
+// createLogger creates a structured logger whose level can change at runtime
+func createLogger(level zap.AtomicLevel) *zap.Logger {
 	config := zap.NewProductionConfig()
-	config.Level = zap.NewAtomicLevelAt(zapLevel)
+	config.Level = level
 	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 
 	logger, err := config.Build()
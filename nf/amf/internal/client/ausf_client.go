@@ -20,11 +20,12 @@ type AUSFClient struct {
 }
 
 // NewAUSFClient creates a new AUSF client
-func NewAUSFClient(baseURL string, timeout time.Duration, logger *zap.Logger) *AUSFClient {
+func NewAUSFClient(baseURL string, timeout time.Duration, transport http.RoundTripper, logger *zap.Logger) *AUSFClient {
 	return &AUSFClient{
 		baseURL: baseURL,
 		client: &http.Client{
-			Timeout: timeout,
+			Timeout:   timeout,
+			Transport: transport,
 		},
 		logger: logger,
 	}
@@ -7,24 +7,28 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"time"
 
 	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/common/nrfpool"
 )
 
 // NRFClient handles communication with NRF
 type NRFClient struct {
-	baseURL string
-	client  *http.Client
-	logger  *zap.Logger
+	endpoints *nrfpool.Pool
+	client    *http.Client
+	logger    *zap.Logger
 }
 
 // NewNRFClient creates a new NRF client
-func NewNRFClient(baseURL string, logger *zap.Logger) *NRFClient {
+func NewNRFClient(endpoints *nrfpool.Pool, transport http.RoundTripper, logger *zap.Logger) *NRFClient {
 	return &NRFClient{
-		baseURL: baseURL,
+		endpoints: endpoints,
 		client: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:   10 * time.Second,
+			Transport: transport,
 		},
 		logger: logger,
 	}
@@ -58,6 +62,12 @@ type AMFInfo struct {
 	BackupInfoAMFSet []string `json:"backupInfoAmfSet,omitempty"`
 }
 
+// SNSSAI represents Single Network Slice Selection Assistance Information
+type SNSSAI struct {
+	SST uint8  `json:"sst"`
+	SD  string `json:"sd,omitempty"`
+}
+
 // GUAMI represents Globally Unique AMF Identifier
 type GUAMI struct {
 	PLMNID PLMNID `json:"plmnId"`
@@ -82,21 +92,20 @@ type NFServiceVersion struct {
 
 // Register registers AMF with NRF
 func (c *NRFClient) Register(ctx context.Context, profile *NFProfile) error {
-	url := fmt.Sprintf("%s/nnrf-nfm/v1/nf-instances/%s", c.baseURL, profile.NFInstanceID)
-
 	body, err := json.Marshal(profile)
 	if err != nil {
 		return fmt.Errorf("failed to marshal profile: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewReader(body))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.client.Do(req)
+	resp, err := c.endpoints.Do(c.client, func(baseURL string) (*http.Request, error) {
+		url := fmt.Sprintf("%s/nnrf-nfm/v1/nf-instances/%s", baseURL, profile.NFInstanceID)
+		req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
 	if err != nil {
 		return fmt.Errorf("failed to send request: %w", err)
 	}
@@ -113,14 +122,14 @@ func (c *NRFClient) Register(ctx context.Context, profile *NFProfile) error {
 
 // Deregister removes AMF registration from NRF
 func (c *NRFClient) Deregister(ctx context.Context, nfInstanceID string) error {
-	url := fmt.Sprintf("%s/nnrf-nfm/v1/nf-instances/%s", c.baseURL, nfInstanceID)
-
-	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	resp, err := c.client.Do(req)
+	resp, err := c.endpoints.Do(c.client, func(baseURL string) (*http.Request, error) {
+		url := fmt.Sprintf("%s/nnrf-nfm/v1/nf-instances/%s", baseURL, nfInstanceID)
+		req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		return req, nil
+	})
 	if err != nil {
 		return fmt.Errorf("failed to send request: %w", err)
 	}
@@ -137,14 +146,14 @@ func (c *NRFClient) Deregister(ctx context.Context, nfInstanceID string) error {
 
 // Heartbeat sends heartbeat to NRF
 func (c *NRFClient) Heartbeat(ctx context.Context, nfInstanceID string) error {
-	url := fmt.Sprintf("%s/nnrf-nfm/v1/nf-instances/%s/heartbeat", c.baseURL, nfInstanceID)
-
-	req, err := http.NewRequestWithContext(ctx, "PATCH", url, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	resp, err := c.client.Do(req)
+	resp, err := c.endpoints.Do(c.client, func(baseURL string) (*http.Request, error) {
+		url := fmt.Sprintf("%s/nnrf-nfm/v1/nf-instances/%s/heartbeat", baseURL, nfInstanceID)
+		req, err := http.NewRequestWithContext(ctx, "PATCH", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		return req, nil
+	})
 	if err != nil {
 		return fmt.Errorf("failed to send request: %w", err)
 	}
@@ -158,3 +167,49 @@ func (c *NRFClient) Heartbeat(ctx context.Context, nfInstanceID string) error {
 	c.logger.Debug("Heartbeat sent to NRF", zap.String("nf_instance_id", nfInstanceID))
 	return nil
 }
+
+// DiscoverSMFs queries the NRF for SMFs serving dnn on snssai
+// (TS 29.510 5.3.2.2)
+func (c *NRFClient) DiscoverSMFs(ctx context.Context, dnn string, snssai SNSSAI) ([]NFProfile, error) {
+	snssais, err := json.Marshal([]SNSSAI{snssai})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal S-NSSAI: %w", err)
+	}
+
+	query := url.Values{}
+	query.Set("target-nf-type", "SMF")
+	query.Set("requester-nf-type", "AMF")
+	query.Set("dnn", dnn)
+	query.Set("snssais", string(snssais))
+
+	resp, err := c.endpoints.Do(c.client, func(baseURL string) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/nnrf-disc/v1/nf-instances?"+query.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("NRF returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result struct {
+		NFInstances []NFProfile `json:"nfInstances"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	c.logger.Debug("SMFs discovered",
+		zap.String("dnn", dnn),
+		zap.Uint8("sst", snssai.SST),
+		zap.Int("count", len(result.NFInstances)),
+	)
+	return result.NFInstances, nil
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/common/nrfpool"
+)
+
+func TestRegisterFailsOverToSecondaryNRF(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+
+	primary := httptest.NewServer(http.NotFoundHandler())
+	primary.Close()
+
+	var registered, heartbeats int
+	secondary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			registered++
+			w.WriteHeader(http.StatusCreated)
+		case http.MethodPatch:
+			heartbeats++
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer secondary.Close()
+
+	endpoints, err := nrfpool.New(context.Background(), primary.URL, nrfpool.Options{
+		URLs: []string{secondary.URL},
+	}, logger)
+	require.NoError(t, err)
+
+	c := NewNRFClient(endpoints, nil, logger)
+	ctx := context.Background()
+
+	require.NoError(t, c.Register(ctx, &NFProfile{NFInstanceID: "amf-1", NFType: "AMF"}))
+	require.NoError(t, c.Heartbeat(ctx, "amf-1"))
+
+	assert.Equal(t, 1, registered)
+	assert.Equal(t, 1, heartbeats)
+	assert.Equal(t, secondary.URL, endpoints.Current())
+}
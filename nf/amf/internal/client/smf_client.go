@@ -0,0 +1,157 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// SMFClient handles communication with the SMFs serving the PDU sessions of
+// UEs. Unlike the AUSF, each session may be served by a different SMF, so
+// requests take the SMF's URI.
+type SMFClient struct {
+	client *http.Client
+	logger *zap.Logger
+}
+
+// NewSMFClient creates a new SMF client
+func NewSMFClient(timeout time.Duration, transport http.RoundTripper, logger *zap.Logger) *SMFClient {
+	return &SMFClient{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+		},
+		logger: logger,
+	}
+}
+
+// CreateSMContextRequest represents an SM context creation request
+// (TS 29.502 6.1.6.2.2)
+type CreateSMContextRequest struct {
+	SUPI           string `json:"supi"`
+	PDUSessionID   uint8  `json:"pduSessionId"`
+	DNN            string `json:"dnn"`
+	SNSSAI         SNSSAI `json:"snssai"`
+	PDUSessionType string `json:"pduSessionType"`
+	SSCMode        string `json:"sscMode,omitempty"`
+
+	// Downlink tunnel endpoint at the gNB
+	GNBN3Address    string `json:"gnbN3Address"`
+	GNBTEIDDownlink uint32 `json:"gnbTeidUplink"`
+}
+
+// BitRate represents an uplink and downlink bit rate in bps
+type BitRate struct {
+	Uplink   uint64 `json:"uplink"`
+	Downlink uint64 `json:"downlink"`
+}
+
+// CreateSMContextResponse represents an SM context creation response
+type CreateSMContextResponse struct {
+	Result         string  `json:"result"` // "SUCCESS", "FAILURE"
+	SUPI           string  `json:"supi"`
+	PDUSessionID   uint8   `json:"pduSessionId"`
+	PDUSessionType string  `json:"pduSessionType,omitempty"`
+	SSCMode        string  `json:"sscMode,omitempty"`
+	UEIPv4Address  string  `json:"ueIpv4Address,omitempty"`
+	UEIPv6Prefix   string  `json:"ueIpv6Prefix,omitempty"`
+	SessionAMBR    BitRate `json:"sessionAmbr"`
+
+	// Uplink tunnel endpoint at the UPF
+	UPFN3Address  string `json:"upfN3Address"`
+	UPFTEIDUplink uint32 `json:"upfTeidDownlink"`
+
+	Reason string `json:"reason,omitempty"`
+}
+
+// CreateSMContext creates the SM context of a PDU session at an SMF
+// (Nsmf_PDUSession_CreateSMContext)
+func (c *SMFClient) CreateSMContext(ctx context.Context, smfURI string, req *CreateSMContextRequest) (*CreateSMContextResponse, error) {
+	url := fmt.Sprintf("%s/nsmf-pdusession/v1/sm-contexts", smfURI)
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	c.logger.Debug("Creating SM context at SMF",
+		zap.String("supi", req.SUPI),
+		zap.Uint8("pdu_session_id", req.PDUSessionID),
+		zap.String("url", url),
+	)
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("SMF returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var createResp CreateSMContextResponse
+	if err := json.NewDecoder(resp.Body).Decode(&createResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &createResp, nil
+}
+
+// ReleaseSMContextRequest represents an SM context release request
+// (TS 29.502 6.1.6.2.6)
+type ReleaseSMContextRequest struct {
+	SUPI         string `json:"supi"`
+	PDUSessionID uint8  `json:"pduSessionId"`
+	Cause        string `json:"cause,omitempty"`
+}
+
+// ReleaseSMContext releases the SM context of a PDU session at the SMF
+// serving it (Nsmf_PDUSession_ReleaseSMContext)
+func (c *SMFClient) ReleaseSMContext(ctx context.Context, smfURI, smContextRef string, req *ReleaseSMContextRequest) error {
+	url := fmt.Sprintf("%s/nsmf-pdusession/v1/sm-contexts/%s/release", smfURI, url.PathEscape(smContextRef))
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	c.logger.Debug("Releasing SM context at SMF",
+		zap.String("supi", req.SUPI),
+		zap.Uint8("pdu_session_id", req.PDUSessionID),
+		zap.String("url", url),
+	)
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("SMF returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrSubscriberNotFound is returned when the UDM holds no subscription data
+// for a SUPI
+var ErrSubscriberNotFound = errors.New("subscriber not found")
+
+// UDMClient handles communication with the UDM
+type UDMClient struct {
+	baseURL string
+	client  *http.Client
+	logger  *zap.Logger
+}
+
+// NewUDMClient creates a new UDM client
+func NewUDMClient(baseURL string, timeout time.Duration, transport http.RoundTripper, logger *zap.Logger) *UDMClient {
+	return &UDMClient{
+		baseURL: baseURL,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+		},
+		logger: logger,
+	}
+}
+
+// AccessAndMobilitySubscriptionData represents the AM subscription data of
+// a UE (TS 29.503 6.1.6.2.4), reduced to what the AMF uses
+type AccessAndMobilitySubscriptionData struct {
+	GPSIs []string `json:"gpsis,omitempty"`
+	NSSAI *NSSAI   `json:"nssai,omitempty"`
+}
+
+// NSSAI represents the subscribed S-NSSAIs of a UE (TS 29.503 6.1.6.2.2)
+type NSSAI struct {
+	DefaultSingleNSSAIs []SNSSAI `json:"defaultSingleNssais,omitempty"`
+	SingleNSSAIs        []SNSSAI `json:"singleNssais,omitempty"`
+}
+
+// GetAMData retrieves the AM subscription data of a UE
+// (Nudm_SDM_Get, TS 29.503 5.2.2.2.3)
+func (c *UDMClient) GetAMData(ctx context.Context, supi string) (*AccessAndMobilitySubscriptionData, error) {
+	url := fmt.Sprintf("%s/nudm-sdm/v1/supi/%s/am-data", c.baseURL, url.PathEscape(supi))
+
+	httpReq, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Accept", "application/json")
+
+	c.logger.Debug("Getting AM data from UDM",
+		zap.String("supi", supi),
+		zap.String("url", url),
+	)
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrSubscriberNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("UDM returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var amData AccessAndMobilitySubscriptionData
+	if err := json.NewDecoder(resp.Body).Decode(&amData); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &amData, nil
+}
+
+// AMF3GPPAccessRegistration registers the AMF serving a UE over 3GPP
+// access with the UDM (TS 29.503 6.2.6.2.2)
+type AMF3GPPAccessRegistration struct {
+	AMFInstanceID          string       `json:"amfInstanceId"`
+	GUAMI                  ServingGUAMI `json:"guami"`
+	RATType                string       `json:"ratType"` // NR, EUTRA
+	PEI                    string       `json:"pei,omitempty"`
+	InitialRegistrationInd bool         `json:"initialRegistrationInd,omitempty"`
+	RegistrationTime       time.Time    `json:"registrationTime,omitempty"`
+}
+
+// ServingGUAMI identifies the serving AMF in its UDM registration
+type ServingGUAMI struct {
+	PLMNID      PLMNID `json:"plmnId"`
+	AMFRegionID string `json:"amfRegionId"`
+	AMFSetID    string `json:"amfSetId"`
+	AMFPointer  string `json:"amfPointer"`
+}
+
+// RegisterAMF3GPPAccess registers the AMF as serving a UE over 3GPP access
+// (Nudm_UECM_Registration, TS 29.503 5.3.2.2.2)
+func (c *UDMClient) RegisterAMF3GPPAccess(ctx context.Context, supi string, registration *AMF3GPPAccessRegistration) error {
+	url := fmt.Sprintf("%s/nudm-uecm/v1/supi/%s/registrations/amf-3gpp-access", c.baseURL, url.PathEscape(supi))
+
+	body, err := json.Marshal(registration)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	c.logger.Debug("Registering AMF with UDM",
+		zap.String("supi", supi),
+		zap.String("url", url),
+	)
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("UDM returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+// DeregisterAMF3GPPAccess removes the AMF registration of a UE over 3GPP
+// access. A UE without registration is already deregistered.
+func (c *UDMClient) DeregisterAMF3GPPAccess(ctx context.Context, supi string) error {
+	url := fmt.Sprintf("%s/nudm-uecm/v1/supi/%s/registrations/amf-3gpp-access", c.baseURL, url.PathEscape(supi))
+
+	httpReq, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	c.logger.Debug("Deregistering AMF from UDM",
+		zap.String("supi", supi),
+		zap.String("url", url),
+	)
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("UDM returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
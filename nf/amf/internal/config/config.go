@@ -5,6 +5,11 @@ import (
 	"os"
 	"time"
 
+	"github.com/your-org/5g-network/common/nrfpool"
+	"github.com/your-org/5g-network/common/oauth"
+	"github.com/your-org/5g-network/common/retry"
+	"github.com/your-org/5g-network/common/sctp"
+	"github.com/your-org/5g-network/common/tlsconfig"
 	"gopkg.in/yaml.v3"
 )
 
@@ -12,13 +17,16 @@ import (
 type Config struct {
 	NF             NFConfig             `yaml:"nf"`
 	SBI            SBIConfig            `yaml:"sbi"`
+	N2             N2Config             `yaml:"n2"`
 	NRF            NRFConfig            `yaml:"nrf"`
 	AUSF           AUSFConfig           `yaml:"ausf"`
 	UDM            UDMConfig            `yaml:"udm"`
+	SMF            SMFConfig            `yaml:"smf"`
 	PLMN           PLMNConfig           `yaml:"plmn"`
 	AMF            AMFConfig            `yaml:"amf"`
 	Security       SecurityConfig       `yaml:"security"`
 	NetworkSlicing NetworkSlicingConfig `yaml:"network_slicing"`
+	Emergency      EmergencyConfig      `yaml:"emergency"`
 	Timers         TimersConfig         `yaml:"timers"`
 	Observability  ObservabilityConfig  `yaml:"observability"`
 }
@@ -32,10 +40,14 @@ type NFConfig struct {
 
 // SBIConfig contains Service-Based Interface configuration
 type SBIConfig struct {
-	Scheme      string    `yaml:"scheme"`
-	BindAddress string    `yaml:"bind_address"`
-	Port        int       `yaml:"port"`
-	TLS         TLSConfig `yaml:"tls"`
+	Scheme       string                 `yaml:"scheme"`
+	BindAddress  string                 `yaml:"bind_address"`
+	Port         int                    `yaml:"port"`
+	TLS          TLSConfig              `yaml:"tls"`
+	ClientTLS    tlsconfig.ClientConfig `yaml:"client_tls"`   // TLS towards the SBIs of other NFs
+	ClientRetry  retry.Policy           `yaml:"client_retry"` // Retries of idempotent requests to other NFs
+	OAuth2       oauth.Config           `yaml:"oauth2"`
+	ClientOAuth2 oauth.ClientConfig     `yaml:"client_oauth2"` // Access tokens for requests to other NFs
 }
 
 // TLSConfig contains TLS configuration
@@ -43,6 +55,18 @@ type TLSConfig struct {
 	Enabled  bool   `yaml:"enabled"`
 	CertFile string `yaml:"cert_file"`
 	KeyFile  string `yaml:"key_file"`
+
+	tlsconfig.Options `yaml:",inline"`
+}
+
+// N2Config contains the NGAP listener for gNBs (TS 38.412)
+type N2Config struct {
+	Enabled     bool   `yaml:"enabled"`
+	BindAddress string `yaml:"bind_address"`
+	Port        int    `yaml:"port"`
+
+	// SCTP, or the TCP fallback where the kernel has no SCTP
+	Transport sctp.Config `yaml:"transport"`
 }
 
 // NRFConfig contains NRF client configuration
@@ -50,6 +74,9 @@ type NRFConfig struct {
 	URL               string        `yaml:"url"`
 	Enabled           bool          `yaml:"enabled"`
 	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"`
+
+	// Secondary NRFs and DNS SRV discovery for failover
+	nrfpool.Options `yaml:",inline"`
 }
 
 // AUSFConfig contains AUSF client configuration
@@ -64,11 +91,33 @@ type UDMConfig struct {
 	Timeout time.Duration `yaml:"timeout"`
 }
 
+// SMFConfig contains SMF client configuration. The SMF of each PDU session
+// is addressed by the URI recorded with it.
+type SMFConfig struct {
+	Timeout time.Duration `yaml:"timeout"`
+}
+
 // PLMNConfig contains PLMN configuration
 type PLMNConfig struct {
 	MCC string `yaml:"mcc"` // Mobile Country Code
 	MNC string `yaml:"mnc"` // Mobile Network Code
 	TAC string `yaml:"tac"` // Tracking Area Code
+
+	// Further TACs of the tracking areas served by the AMF, besides TAC
+	ServedTACs []string `yaml:"served_tacs"`
+}
+
+// ServesTAC reports whether a tracking area is served by the AMF
+func (p *PLMNConfig) ServesTAC(tac string) bool {
+	if tac == p.TAC {
+		return true
+	}
+	for _, served := range p.ServedTACs {
+		if tac == served {
+			return true
+		}
+	}
+	return false
 }
 
 // AMFConfig contains AMF-specific configuration
@@ -97,10 +146,20 @@ type NetworkSlicingConfig struct {
 	Enabled bool `yaml:"enabled"`
 }
 
+// EmergencyConfig contains emergency services configuration
+type EmergencyConfig struct {
+	// AllowUnauthenticated permits emergency registration without a
+	// successful primary authentication (TS 23.501 5.16.4)
+	AllowUnauthenticated bool   `yaml:"allow_unauthenticated"`
+	DNN                  string `yaml:"dnn"`
+	SNSSAI               SNSSAI `yaml:"snssai"`
+}
+
 // TimersConfig contains NAS timer configuration (in seconds)
 type TimersConfig struct {
 	T3502 int `yaml:"t3502"` // Registration retry
 	T3512 int `yaml:"t3512"` // Periodic registration
+	T3513 int `yaml:"t3513"` // Paging
 	T3522 int `yaml:"t3522"` // Deregistration
 	T3550 int `yaml:"t3550"` // NAS message
 	T3560 int `yaml:"t3560"` // Authentication
@@ -167,8 +226,16 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid sbi.port: %d", c.SBI.Port)
 	}
 
-	if c.NRF.Enabled && c.NRF.URL == "" {
-		return fmt.Errorf("nrf.url is required when nrf.enabled is true")
+	if c.N2.Enabled && (c.N2.Port <= 0 || c.N2.Port > 65535) {
+		return fmt.Errorf("invalid n2.port: %d", c.N2.Port)
+	}
+
+	if c.NRF.Enabled && c.NRF.URL == "" && c.NRF.SRVName == "" {
+		return fmt.Errorf("nrf.url or nrf.srv_name is required when nrf.enabled is true")
+	}
+
+	if err := c.NRF.Validate(); err != nil {
+		return fmt.Errorf("invalid nrf: %w", err)
 	}
 
 	if c.AUSF.URL == "" {
@@ -195,6 +262,32 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("at least one ciphering algorithm must be configured")
 	}
 
+	if c.Emergency.AllowUnauthenticated && c.Emergency.DNN == "" {
+		return fmt.Errorf("emergency.dnn is required when emergency.allow_unauthenticated is true")
+	}
+
+	if c.SBI.TLS.Enabled {
+		if err := c.SBI.TLS.Validate(); err != nil {
+			return fmt.Errorf("invalid sbi.tls: %w", err)
+		}
+	}
+
+	if err := c.SBI.ClientTLS.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.client_tls: %w", err)
+	}
+
+	if err := c.SBI.ClientRetry.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.client_retry: %w", err)
+	}
+
+	if err := c.SBI.OAuth2.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.oauth2: %w", err)
+	}
+
+	if err := c.SBI.ClientOAuth2.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.client_oauth2: %w", err)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,115 @@
+package context
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// gutiPrefix starts the string form of a 5G-GUTI (TS 29.571 5.4.4.3)
+const gutiPrefix = "5g-guti-"
+
+// maxTMSIAttempts bounds the random draws for a free 5G-TMSI
+const maxTMSIAttempts = 16
+
+// ErrTMSIExhausted is returned when no unused 5G-TMSI could be drawn
+var ErrTMSIExhausted = errors.New("no free 5G-TMSI")
+
+// GUTI is a 5G Globally Unique Temporary Identifier: the GUAMI of the
+// serving AMF followed by a 5G-TMSI (TS 23.003 2.10.1)
+type GUTI struct {
+	PLMNID      PLMNID
+	AMFRegionID uint8
+	AMFSetID    uint16 // 10 bits
+	AMFPointer  uint8  // 6 bits
+	TMSI        uint32
+}
+
+// String formats the GUTI as 5g-guti-<MCC><MNC><AMF ID><5G-TMSI>, with the
+// AMF ID and 5G-TMSI in hex
+func (g GUTI) String() string {
+	amfID := uint32(g.AMFRegionID)<<16 | uint32(g.AMFSetID&0x3ff)<<6 | uint32(g.AMFPointer&0x3f)
+	return fmt.Sprintf("%s%s%s%06x%08x", gutiPrefix, g.PLMNID.MCC, g.PLMNID.MNC, amfID, g.TMSI)
+}
+
+// IsGUTI reports whether a UE identifier is a 5G-GUTI string
+func IsGUTI(id string) bool {
+	return strings.HasPrefix(id, gutiPrefix)
+}
+
+// AllocateGUTI assigns the UE a new 5G-GUTI built from its serving GUAMI and
+// an unused random 5G-TMSI, releasing the GUTI it held before (TS 23.502
+// 4.2.2.2.2). The UE's PLMN and AMF identifiers must already be set.
+func (m *UEContextManager) AllocateGUTI(ue *UEContext) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tmsi, err := m.freeTMSI()
+	if err != nil {
+		return "", err
+	}
+
+	ue.mu.Lock()
+	defer ue.mu.Unlock()
+
+	if ue.GUTI != "" {
+		delete(m.gutis, ue.GUTI)
+		delete(m.tmsis, ue.TMSI)
+	}
+
+	guti := GUTI{
+		PLMNID:      ue.TAI.PLMNID,
+		AMFRegionID: ue.AMFRegionID,
+		AMFSetID:    ue.AMFSetID,
+		AMFPointer:  ue.AMFPointer,
+		TMSI:        tmsi,
+	}.String()
+
+	ue.GUTI = guti
+	ue.TMSI = tmsi
+	m.gutis[guti] = ue.SUPI
+	m.tmsis[tmsi] = true
+	return guti, nil
+}
+
+// GetContextByGUTI retrieves a UE context by its current 5G-GUTI
+func (m *UEContextManager) GetContextByGUTI(guti string) (*UEContext, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	supi, exists := m.gutis[guti]
+	if !exists {
+		return nil, false
+	}
+	ctx, exists := m.contexts[supi]
+	return ctx, exists
+}
+
+// freeTMSI draws a random 5G-TMSI not in use; random values keep the TMSI
+// unpredictable (TS 33.501 6.12.3). Callers hold m.mu.
+func (m *UEContextManager) freeTMSI() (uint32, error) {
+	buf := make([]byte, 4)
+	for i := 0; i < maxTMSIAttempts; i++ {
+		if _, err := rand.Read(buf); err != nil {
+			return 0, fmt.Errorf("failed to draw 5G-TMSI: %w", err)
+		}
+		tmsi := binary.BigEndian.Uint32(buf)
+		if !m.tmsis[tmsi] {
+			return tmsi, nil
+		}
+	}
+	return 0, ErrTMSIExhausted
+}
+
+// releaseGUTI drops the GUTI mapping of a UE. Callers hold m.mu.
+func (m *UEContextManager) releaseGUTI(ue *UEContext) {
+	ue.mu.RLock()
+	defer ue.mu.RUnlock()
+
+	if ue.GUTI != "" && m.gutis[ue.GUTI] == ue.SUPI {
+		delete(m.gutis, ue.GUTI)
+		delete(m.tmsis, ue.TMSI)
+	}
+}
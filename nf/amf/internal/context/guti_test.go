@@ -0,0 +1,83 @@
+package context
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRegisteredUE(m *UEContextManager, supi string) *UEContext {
+	ue := m.CreateContext(supi)
+	ue.TAI.PLMNID = PLMNID{MCC: "001", MNC: "01"}
+	ue.AMFRegionID = 0x80
+	ue.AMFSetID = 1
+	ue.AMFPointer = 1
+	return ue
+}
+
+func TestGUTI_String(t *testing.T) {
+	guti := GUTI{
+		PLMNID:      PLMNID{MCC: "001", MNC: "01"},
+		AMFRegionID: 0x80,
+		AMFSetID:    1,
+		AMFPointer:  1,
+		TMSI:        0x12345678,
+	}
+	assert.Equal(t, "5g-guti-0010180004112345678", guti.String())
+	assert.True(t, IsGUTI(guti.String()))
+	assert.False(t, IsGUTI("imsi-001010000000001"))
+}
+
+func TestAllocateGUTI_Unique(t *testing.T) {
+	m := NewUEContextManager()
+
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		ue := newRegisteredUE(m, fmt.Sprintf("imsi-00101%010d", i))
+		guti, err := m.AllocateGUTI(ue)
+		require.NoError(t, err)
+		assert.False(t, seen[guti], "GUTI %s allocated twice", guti)
+		seen[guti] = true
+		assert.Equal(t, guti, ue.GUTI)
+	}
+}
+
+func TestGetContextByGUTI(t *testing.T) {
+	m := NewUEContextManager()
+	ue := newRegisteredUE(m, "imsi-001010000000001")
+
+	guti, err := m.AllocateGUTI(ue)
+	require.NoError(t, err)
+
+	found, exists := m.GetContextByGUTI(guti)
+	require.True(t, exists)
+	assert.Same(t, ue, found)
+
+	_, exists = m.GetContextByGUTI("5g-guti-0010180004100000000")
+	assert.False(t, exists)
+
+	m.RemoveContext(ue.SUPI)
+	_, exists = m.GetContextByGUTI(guti)
+	assert.False(t, exists)
+}
+
+func TestAllocateGUTI_ReallocationFreesOld(t *testing.T) {
+	m := NewUEContextManager()
+	ue := newRegisteredUE(m, "imsi-001010000000001")
+
+	first, err := m.AllocateGUTI(ue)
+	require.NoError(t, err)
+	second, err := m.AllocateGUTI(ue)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, second)
+
+	_, exists := m.GetContextByGUTI(first)
+	assert.False(t, exists)
+	found, exists := m.GetContextByGUTI(second)
+	require.True(t, exists)
+	assert.Same(t, ue, found)
+	assert.Len(t, m.gutis, 1)
+	assert.Len(t, m.tmsis, 1)
+}
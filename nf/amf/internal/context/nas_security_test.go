@@ -0,0 +1,151 @@
+package context
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/your-org/5g-network/common/nas"
+)
+
+func newNASSecurityContext(t *testing.T) *SecurityContext {
+	t.Helper()
+
+	sc := &SecurityContext{
+		KSEAF:              "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		CipheringAlgorithm: "NEA2",
+		IntegrityAlgorithm: "NIA2",
+	}
+	require.NoError(t, sc.DeriveNASKeys("imsi-001010000000001", []byte{0x00, 0x00}))
+	return sc
+}
+
+// uplinkPDU protects an uplink NAS message the way the UE does
+func uplinkPDU(t *testing.T, sc *SecurityContext, msg []byte, count uint32) []byte {
+	t.Helper()
+
+	ciphered, err := nas.Cipher(nas.NEA2, nasKey(sc.KNASenc), count, nas.BEARER_3GPP, nas.DIRECTION_UPLINK, msg)
+	require.NoError(t, err)
+	pdu := &nas.PDU{
+		SecurityHeaderType: nas.SECURITY_HEADER_INTEGRITY_PROTECTED_CIPHERED,
+		SequenceNumber:     uint8(count),
+		Message:            ciphered,
+	}
+	pdu.MAC, err = nas.ComputeMAC(nas.NIA2, nasKey(sc.KNASint), count, nas.BEARER_3GPP, nas.DIRECTION_UPLINK,
+		append([]byte{pdu.SequenceNumber}, pdu.Message...))
+	require.NoError(t, err)
+	return pdu.Marshal()
+}
+
+func TestDeriveNASKeys(t *testing.T) {
+	sc := newNASSecurityContext(t)
+	assert.Len(t, sc.KAMF, 64)
+	assert.Len(t, sc.KNASenc, 2*nas.KEY_LENGTH)
+	assert.Len(t, sc.KNASint, 2*nas.KEY_LENGTH)
+	assert.NotEqual(t, sc.KNASenc, sc.KNASint)
+
+	sc.CipheringAlgorithm = "AES"
+	assert.Error(t, sc.DeriveNASKeys("imsi-001010000000001", []byte{0x00, 0x00}))
+
+	short := &SecurityContext{KSEAF: "00", CipheringAlgorithm: "NEA2", IntegrityAlgorithm: "NIA2"}
+	assert.Error(t, short.DeriveNASKeys("imsi-001010000000001", []byte{0x00, 0x00}))
+}
+
+func TestProtectNAS(t *testing.T) {
+	sc := newNASSecurityContext(t)
+	msg := []byte{nas.EPD_5GMM, nas.SECURITY_HEADER_PLAIN, nas.MSG_REGISTRATION_ACCEPT, 0x01, 0x01}
+
+	for count := uint32(0); count < 2; count++ {
+		data, err := sc.ProtectNAS(msg, nas.SECURITY_HEADER_INTEGRITY_PROTECTED_CIPHERED)
+		require.NoError(t, err)
+
+		pdu, err := nas.ParsePDU(data)
+		require.NoError(t, err)
+		assert.Equal(t, uint8(count), pdu.SequenceNumber)
+		assert.NotEqual(t, msg, pdu.Message, "ciphered")
+
+		ok, err := nas.VerifyMAC(nas.NIA2, nasKey(sc.KNASint), count, nas.BEARER_3GPP, nas.DIRECTION_DOWNLINK,
+			append([]byte{pdu.SequenceNumber}, pdu.Message...), pdu.MAC)
+		require.NoError(t, err)
+		assert.True(t, ok)
+
+		plain, err := nas.Cipher(nas.NEA2, nasKey(sc.KNASenc), count, nas.BEARER_3GPP, nas.DIRECTION_DOWNLINK, pdu.Message)
+		require.NoError(t, err)
+		assert.Equal(t, msg, plain)
+	}
+	assert.Equal(t, uint32(2), sc.DownlinkNASCount)
+
+	// Integrity protected only
+	data, err := sc.ProtectNAS(msg, nas.SECURITY_HEADER_INTEGRITY_PROTECTED)
+	require.NoError(t, err)
+	pdu, err := nas.ParsePDU(data)
+	require.NoError(t, err)
+	assert.Equal(t, msg, pdu.Message)
+
+	_, err = sc.ProtectNAS(msg, nas.SECURITY_HEADER_PLAIN)
+	assert.Error(t, err)
+	_, err = (&SecurityContext{}).ProtectNAS(msg, nas.SECURITY_HEADER_INTEGRITY_PROTECTED)
+	assert.Error(t, err)
+}
+
+func TestUnprotectNAS(t *testing.T) {
+	sc := newNASSecurityContext(t)
+	msg := []byte{nas.EPD_5GMM, nas.SECURITY_HEADER_PLAIN, nas.MSG_SECURITY_MODE_COMPLETE}
+
+	plain, err := sc.UnprotectNAS(uplinkPDU(t, sc, msg, 0))
+	require.NoError(t, err)
+	assert.Equal(t, msg, plain)
+	assert.Equal(t, uint32(1), sc.UplinkNASCount)
+
+	// Lost PDUs are skipped over
+	plain, err = sc.UnprotectNAS(uplinkPDU(t, sc, msg, 5))
+	require.NoError(t, err)
+	assert.Equal(t, msg, plain)
+	assert.Equal(t, uint32(6), sc.UplinkNASCount)
+}
+
+func TestUnprotectNAS_MACFailure(t *testing.T) {
+	sc := newNASSecurityContext(t)
+	msg := []byte{nas.EPD_5GMM, nas.SECURITY_HEADER_PLAIN, nas.MSG_SECURITY_MODE_COMPLETE}
+
+	data := uplinkPDU(t, sc, msg, 0)
+	data[len(data)-1] ^= 0x01
+	_, err := sc.UnprotectNAS(data)
+	assert.ErrorIs(t, err, ErrNASMACFailure)
+
+	data = uplinkPDU(t, sc, msg, 0)
+	data[2] ^= 0x01 // MAC
+	_, err = sc.UnprotectNAS(data)
+	assert.ErrorIs(t, err, ErrNASMACFailure)
+
+	// A failed PDU does not move the uplink NAS COUNT
+	assert.Zero(t, sc.UplinkNASCount)
+
+	_, err = sc.UnprotectNAS(msg)
+	assert.Error(t, err)
+}
+
+func TestUnprotectNAS_Replay(t *testing.T) {
+	sc := newNASSecurityContext(t)
+	msg := []byte{nas.EPD_5GMM, nas.SECURITY_HEADER_PLAIN, nas.MSG_SECURITY_MODE_COMPLETE}
+
+	data := uplinkPDU(t, sc, msg, 0)
+	_, err := sc.UnprotectNAS(data)
+	require.NoError(t, err)
+	_, err = sc.UnprotectNAS(data)
+	assert.ErrorIs(t, err, ErrNASReplay)
+}
+
+func TestUnprotectNAS_SequenceNumberWrap(t *testing.T) {
+	sc := newNASSecurityContext(t)
+	msg := []byte{nas.EPD_5GMM, nas.SECURITY_HEADER_PLAIN, nas.MSG_SECURITY_MODE_COMPLETE}
+	sc.UplinkNASCount = 0xFF
+
+	_, err := sc.UnprotectNAS(uplinkPDU(t, sc, msg, 0xFF))
+	require.NoError(t, err)
+
+	// Sequence number 0 now belongs to overflow counter 1
+	_, err = sc.UnprotectNAS(uplinkPDU(t, sc, msg, 0x100))
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0x101), sc.UplinkNASCount)
+}
@@ -1,6 +1,7 @@
 package context
 
 import (
+	"sort"
 	"sync"
 	"time"
 )
@@ -12,6 +13,8 @@ type UEContext struct {
 	SUCI string // Subscription Concealed Identifier
 	GPSI string // Generic Public Subscription Identifier
 	PEI  string // Permanent Equipment Identifier
+	GUTI string // 5G-GUTI allocated by this AMF, empty until registration
+	TMSI uint32 // 5G-TMSI part of GUTI
 
 	// Registration State
 	RegistrationState RegistrationState
@@ -27,6 +30,10 @@ type UEContext struct {
 	AllowedNSSAI    []SNSSAI
 	ConfiguredNSSAI []SNSSAI
 
+	// Emergency services
+	EmergencyRegistered bool   // Registered for emergency services only
+	EmergencyDNN        string // Only DNN usable while EmergencyRegistered
+
 	// AMF Context
 	GUAMI       string // Globally Unique AMF Identifier
 	AMFRegionID uint8
@@ -97,6 +104,12 @@ type SecurityContext struct {
 	// State
 	NASSecurityEstablished bool
 	ASSecurityEstablished  bool
+
+	// Algorithm identifiers resolved by DeriveNASKeys
+	cipheringID uint8
+	integrityID uint8
+
+	mu sync.Mutex // Guards the NAS keys and COUNTs
 }
 
 // PDUSessionInfo represents PDU session information
@@ -106,6 +119,12 @@ type PDUSessionInfo struct {
 	SNSSAI        SNSSAI
 	SessionAMBR   SessionAMBR
 	SMFInstanceID string
+	SMFURI        string // API root of the serving SMF
+	SMContextRef  string // SM context at the SMF, "<SUPI>-<SessionID>" when empty
+	UEIPv4Address string
+	UEIPv6Prefix  string
+	UPFN3Address  string // Uplink tunnel endpoint at the UPF
+	UPFTEID       uint32
 	State         PDUSessionState
 	CreatedAt     time.Time
 }
@@ -120,9 +139,10 @@ type SessionAMBR struct {
 type PDUSessionState string
 
 const (
-	PDUSessionStateActive   PDUSessionState = "ACTIVE"
-	PDUSessionStateInactive PDUSessionState = "INACTIVE"
-	PDUSessionStateReleased PDUSessionState = "RELEASED"
+	PDUSessionStateEstablishing PDUSessionState = "ESTABLISHING"
+	PDUSessionStateActive       PDUSessionState = "ACTIVE"
+	PDUSessionStateInactive     PDUSessionState = "INACTIVE"
+	PDUSessionStateReleased     PDUSessionState = "RELEASED"
 )
 
 // NewUEContext creates a new UE context
@@ -175,6 +195,20 @@ func (ue *UEContext) AddPDUSession(session *PDUSessionInfo) {
 	ue.LastActivityAt = time.Now()
 }
 
+// ReservePDUSession adds a PDU session unless the UE already has one with
+// the same session ID, reporting whether it was added
+func (ue *UEContext) ReservePDUSession(session *PDUSessionInfo) bool {
+	ue.mu.Lock()
+	defer ue.mu.Unlock()
+
+	if _, exists := ue.PDUSessions[session.SessionID]; exists {
+		return false
+	}
+	ue.PDUSessions[session.SessionID] = session
+	ue.LastActivityAt = time.Now()
+	return true
+}
+
 // RemovePDUSession removes a PDU session
 func (ue *UEContext) RemovePDUSession(sessionID uint8) {
 	ue.mu.Lock()
@@ -193,6 +227,77 @@ func (ue *UEContext) GetPDUSession(sessionID uint8) (*PDUSessionInfo, bool) {
 	return session, exists
 }
 
+// GetPDUSessions returns the PDU sessions of the UE, ordered by session ID
+func (ue *UEContext) GetPDUSessions() []*PDUSessionInfo {
+	ue.mu.RLock()
+	defer ue.mu.RUnlock()
+
+	sessions := make([]*PDUSessionInfo, 0, len(ue.PDUSessions))
+	for _, session := range ue.PDUSessions {
+		sessions = append(sessions, session)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].SessionID < sessions[j].SessionID })
+	return sessions
+}
+
+// UpdateLocation sets the tracking area the UE is in
+func (ue *UEContext) UpdateLocation(tai TrackingAreaIdentity) {
+	ue.mu.Lock()
+	defer ue.mu.Unlock()
+
+	ue.TAI = tai
+	ue.LastActivityAt = time.Now()
+}
+
+// RefreshRegistration restarts the timers of a registered UE on a
+// registration update, keeping the time it registered
+func (ue *UEContext) RefreshRegistration() {
+	ue.mu.Lock()
+	defer ue.mu.Unlock()
+
+	ue.LastActivityAt = time.Now()
+}
+
+// SetEmergencyRegistered marks the UE as registered for emergency services only
+func (ue *UEContext) SetEmergencyRegistered(dnn string) {
+	ue.mu.Lock()
+	defer ue.mu.Unlock()
+
+	ue.EmergencyRegistered = true
+	ue.EmergencyDNN = dnn
+	ue.LastActivityAt = time.Now()
+}
+
+// ClearEmergencyRegistered lifts the emergency services limit once the UE
+// registers normally
+func (ue *UEContext) ClearEmergencyRegistered() {
+	ue.mu.Lock()
+	defer ue.mu.Unlock()
+
+	ue.EmergencyRegistered = false
+	ue.EmergencyDNN = ""
+}
+
+// IsEmergencyRegistered checks if UE has limited (emergency) service
+func (ue *UEContext) IsEmergencyRegistered() bool {
+	ue.mu.RLock()
+	defer ue.mu.RUnlock()
+
+	return ue.EmergencyRegistered
+}
+
+// IsDNNAllowed checks if the UE may use the given DNN. An emergency
+// registered UE is restricted to the emergency DNN.
+func (ue *UEContext) IsDNNAllowed(dnn string) bool {
+	ue.mu.RLock()
+	defer ue.mu.RUnlock()
+
+	if ue.EmergencyRegistered {
+		return dnn == ue.EmergencyDNN
+	}
+	return true
+}
+
 // IsRegistered checks if UE is registered
 func (ue *UEContext) IsRegistered() bool {
 	ue.mu.RLock()
@@ -212,6 +317,8 @@ func (ue *UEContext) IsConnected() bool {
 // UEContextManager manages all UE contexts
 type UEContextManager struct {
 	contexts map[string]*UEContext // SUPI -> UE Context
+	gutis    map[string]string     // 5G-GUTI -> SUPI
+	tmsis    map[uint32]bool       // 5G-TMSIs in use
 	mu       sync.RWMutex
 }
 
@@ -219,6 +326,8 @@ type UEContextManager struct {
 func NewUEContextManager() *UEContextManager {
 	return &UEContextManager{
 		contexts: make(map[string]*UEContext),
+		gutis:    make(map[string]string),
+		tmsis:    make(map[uint32]bool),
 	}
 }
 
@@ -227,6 +336,9 @@ func (m *UEContextManager) CreateContext(supi string) *UEContext {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if old, exists := m.contexts[supi]; exists {
+		m.releaseGUTI(old)
+	}
 	ctx := NewUEContext(supi)
 	m.contexts[supi] = ctx
 	return ctx
@@ -241,6 +353,14 @@ func (m *UEContextManager) GetContext(supi string) (*UEContext, bool) {
 	return ctx, exists
 }
 
+// LookupContext retrieves a UE context by SUPI or by its current 5G-GUTI
+func (m *UEContextManager) LookupContext(ueID string) (*UEContext, bool) {
+	if IsGUTI(ueID) {
+		return m.GetContextByGUTI(ueID)
+	}
+	return m.GetContext(ueID)
+}
+
 // GetOrCreateContext gets an existing context or creates a new one
 func (m *UEContextManager) GetOrCreateContext(supi string) *UEContext {
 	// Try to get first
@@ -252,11 +372,14 @@ func (m *UEContextManager) GetOrCreateContext(supi string) *UEContext {
 	return m.CreateContext(supi)
 }
 
-// RemoveContext removes a UE context
+// RemoveContext removes a UE context and frees its GUTI
 func (m *UEContextManager) RemoveContext(supi string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if ctx, exists := m.contexts[supi]; exists {
+		m.releaseGUTI(ctx)
+	}
 	delete(m.contexts, supi)
 }
 
@@ -0,0 +1,192 @@
+package n2
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/common/nas"
+	"github.com/your-org/5g-network/common/ngap"
+	"github.com/your-org/5g-network/common/sctp"
+	"github.com/your-org/5g-network/nf/amf/internal/client"
+	"github.com/your-org/5g-network/nf/amf/internal/config"
+	amfcontext "github.com/your-org/5g-network/nf/amf/internal/context"
+	"github.com/your-org/5g-network/nf/amf/internal/service"
+)
+
+const (
+	testRAND    = "0123456789abcdef0123456789abcdef"
+	testAUTN    = "fedcba9876543210fedcba9876543210"
+	testRESStar = "00112233445566778899aabbccddeeff"
+	testKSEAF   = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+)
+
+// startServer starts an N2 server bridging to a NAS service backed by a
+// fake AUSF that accepts testRESStar
+func startServer(t *testing.T, transport sctp.Config) *Server {
+	t.Helper()
+
+	ausf := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/5g-aka-confirmation") {
+			var req client.AuthConfirmationRequest
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			result := client.AuthConfirmationResponse{AuthResult: "AUTHENTICATION_FAILURE"}
+			if req.RES == testRESStar {
+				result = client.AuthConfirmationResponse{
+					AuthResult: "AUTHENTICATION_SUCCESS",
+					SUPI:       "imsi-001010000000001",
+					KSEAF:      testKSEAF,
+				}
+			}
+			json.NewEncoder(w).Encode(result)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(client.UEAuthenticationResponse{
+			AuthType:      "5G_AKA",
+			AuthCtxID:     "ctx-1",
+			Var5gAuthData: &client.Var5gAuthData{RAND: testRAND, AUTN: testAUTN},
+		})
+	}))
+	t.Cleanup(ausf.Close)
+
+	cfg := &config.Config{
+		NF:   config.NFConfig{Name: "amf-1"},
+		N2:   config.N2Config{Enabled: true, BindAddress: "127.0.0.1", Transport: transport},
+		PLMN: config.PLMNConfig{MCC: "001", MNC: "01", TAC: "000001"},
+		AMF: config.AMFConfig{
+			RegionID:        128,
+			SetID:           1,
+			Pointer:         1,
+			SupportedSNSSAI: []config.SNSSAI{{SST: 1, SD: "000001"}},
+		},
+		Security: config.SecurityConfig{
+			IntegrityOrder: []string{"NIA2"},
+			CipheringOrder: []string{"NEA2"},
+		},
+		Timers: config.TimersConfig{T3512: 3240},
+	}
+
+	logger, _ := zap.NewDevelopment()
+	contextManager := amfcontext.NewUEContextManager()
+	registrationService := service.NewRegistrationService(cfg, client.NewAUSFClient(ausf.URL, time.Second, nil, logger), contextManager, logger)
+	nasService := service.NewNASService(cfg, registrationService, contextManager, logger)
+
+	srv, err := NewServer(cfg, nasService, logger)
+	if errors.Is(err, sctp.ErrUnsupported) {
+		t.Skipf("no SCTP here: %v", err)
+	}
+	require.NoError(t, err)
+	t.Cleanup(func() { srv.Close() })
+	go srv.Serve()
+	return srv
+}
+
+func testUserLocation() *ngap.UserLocationInformationNR {
+	plmn := &ngap.PLMNID{MCC: "001", MNC: "01"}
+	return &ngap.UserLocationInformationNR{
+		NRCGI: &ngap.NRCGI{PLMNID: plmn, NRCellID: 0x10},
+		TAI:   &ngap.TAI{PLMNID: plmn, TAC: 1},
+	}
+}
+
+// receiveDownlinkNAS receives a Downlink NAS Transport and decodes its NAS
+// message into the expected type
+func receiveDownlinkNAS[M nas.Message](t *testing.T, gnb sctp.Association) (*ngap.DownlinkNASTransport, M) {
+	t.Helper()
+
+	msg, err := ngap.ReceiveMessage(gnb)
+	require.NoError(t, err)
+	dl, ok := msg.(*ngap.DownlinkNASTransport)
+	require.True(t, ok, "unexpected ngap message type %d", msg.MessageType())
+
+	pdu, err := nas.ParsePDU(dl.NASPDU)
+	require.NoError(t, err)
+	// None of the messages expected here is ciphered
+	nasMsg, err := nas.Decode(pdu.Message)
+	require.NoError(t, err)
+	typed, ok := nasMsg.(M)
+	require.True(t, ok, "unexpected nas message type 0x%02x", nasMsg.MessageType())
+	return dl, typed
+}
+
+// registerOverN2 sets up a gNB with the AMF and takes a UE through
+// authentication with NAS carried in NGAP, as the gNB-CU does
+func registerOverN2(t *testing.T, transport sctp.Config) {
+	srv := startServer(t, transport)
+
+	gnb, err := ngap.Dial(transport, srv.Addr().String(), 5*time.Second)
+	require.NoError(t, err)
+	defer gnb.Close()
+
+	// NG Setup
+	plmn := &ngap.PLMNID{MCC: "001", MNC: "01"}
+	require.NoError(t, ngap.SendMessage(gnb, &ngap.NGSetupRequest{
+		GlobalRANNodeID: &ngap.GlobalGNBID{PLMNID: plmn, GNBID: 1, GNBIDLength: 22},
+		RANNodeName:     "gnb-1",
+		SupportedTAs: []*ngap.SupportedTA{{
+			TAC:            1,
+			BroadcastPLMNs: []*ngap.BroadcastPLMN{{PLMNID: plmn, SliceSupportList: []*ngap.SNSSAI{{SST: 1}}}},
+		}},
+		DefaultPagingDRX: 128,
+	}))
+	msg, err := ngap.ReceiveMessage(gnb)
+	require.NoError(t, err)
+	setup, ok := msg.(*ngap.NGSetupResponse)
+	require.True(t, ok)
+	assert.Equal(t, "amf-1", setup.AMFName)
+	assert.Equal(t, []*ngap.GUAMI{{PLMNID: plmn, AMFRegionID: 128, AMFSetID: 1, AMFPointer: 1}}, setup.ServedGUAMIs)
+	assert.Equal(t, []*ngap.SNSSAI{{SST: 1, SD: []byte{0, 0, 1}}}, setup.PLMNSupportList[0].SliceSupportList)
+
+	// Initial UE Message with a Registration Request -> Authentication Request
+	identity, err := nas.NewSUCIMobileIdentity("suci-0-001-01-0000-0-0-0000000001")
+	require.NoError(t, err)
+	registration := &nas.RegistrationRequest{
+		RegistrationType:     nas.REGISTRATION_TYPE_INITIAL,
+		NgKSI:                nas.NGKSI_NO_KEY,
+		MobileIdentity:       identity,
+		UESecurityCapability: []byte{0xF0, 0xF0},
+	}
+	require.NoError(t, ngap.SendMessage(gnb, &ngap.InitialUEMessage{
+		RANUENGAPID:           7,
+		NASPDU:                nas.NewPDU(registration).Marshal(),
+		UserLocation:          testUserLocation(),
+		RRCEstablishmentCause: "mo-Signalling",
+	}))
+	dl, authReq := receiveDownlinkNAS[*nas.AuthenticationRequest](t, gnb)
+	assert.Equal(t, uint32(7), dl.RANUENGAPID)
+	assert.Equal(t, uint64(1), dl.AMFUENGAPID)
+	assert.Equal(t, testRAND, hex.EncodeToString(authReq.RAND))
+
+	// Uplink NAS Transport with the Authentication Response -> Security
+	// Mode Command on the same UE connection
+	resStar, _ := hex.DecodeString(testRESStar)
+	require.NoError(t, ngap.SendMessage(gnb, &ngap.UplinkNASTransport{
+		AMFUENGAPID:  dl.AMFUENGAPID,
+		RANUENGAPID:  7,
+		NASPDU:       nas.NewPDU(&nas.AuthenticationResponse{RESStar: resStar}).Marshal(),
+		UserLocation: testUserLocation(),
+	}))
+	dl, cmd := receiveDownlinkNAS[*nas.SecurityModeCommand](t, gnb)
+	assert.Equal(t, uint32(7), dl.RANUENGAPID)
+	assert.Equal(t, uint8(nas.NEA2), cmd.CipheringAlgorithm)
+}
+
+func TestN2_InitialUEMessage_TCPFallback(t *testing.T) {
+	registerOverN2(t, sctp.Config{Protocol: sctp.PROTOCOL_TCP})
+}
+
+func TestN2_InitialUEMessage_SCTP(t *testing.T) {
+	registerOverN2(t, sctp.Config{Protocol: sctp.PROTOCOL_SCTP, HeartbeatInterval: time.Second})
+}
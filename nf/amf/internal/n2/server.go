@@ -0,0 +1,278 @@
+// Package n2 terminates the NGAP associations of NG-RAN nodes at the AMF
+// (TS 38.413) and bridges the NAS PDUs they carry to the NAS service.
+package n2
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/your-org/5g-network/common/ngap"
+	"github.com/your-org/5g-network/common/sctp"
+	"github.com/your-org/5g-network/nf/amf/internal/config"
+	"go.uber.org/zap"
+)
+
+// relativeAMFCapacity is advertised in NG Setup Responses; there is one AMF
+// in the set
+const relativeAMFCapacity = 255
+
+// NASHandler handles an uplink NAS PDU of the UE on a RAN connection and
+// returns the downlink NAS PDU answering it
+type NASHandler interface {
+	HandleUplinkNAS(ctx context.Context, ranUEID string, data []byte) ([]byte, error)
+}
+
+// Server accepts NG associations from gNBs
+type Server struct {
+	config   *config.Config
+	nas      NASHandler
+	listener sctp.Listener
+	logger   *zap.Logger
+
+	mu        sync.Mutex
+	conns     map[string]*ranConnection // By gNB address
+	ues       map[uint64]*ranUE         // By AMF UE NGAP ID
+	nextAMFID uint64
+}
+
+// ranConnection is the NG association with a gNB
+type ranConnection struct {
+	assoc sctp.Association
+	gnbID *ngap.GlobalGNBID // Set by NG Setup
+}
+
+// ranUE is the UE-associated logical NG connection of a UE
+type ranUE struct {
+	conn        *ranConnection
+	amfUENGAPID uint64
+	ranUENGAPID uint32
+}
+
+// NewServer listens for NG associations on the configured N2 address
+func NewServer(cfg *config.Config, nas NASHandler, logger *zap.Logger) (*Server, error) {
+	address := net.JoinHostPort(cfg.N2.BindAddress, strconv.Itoa(cfg.N2.Port))
+	listener, err := ngap.Listen(cfg.N2.Transport, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+
+	return &Server{
+		config:    cfg,
+		nas:       nas,
+		listener:  listener,
+		logger:    logger,
+		conns:     make(map[string]*ranConnection),
+		ues:       make(map[uint64]*ranUE),
+		nextAMFID: 1,
+	}, nil
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Serve accepts gNB associations until the server is closed
+func (s *Server) Serve() {
+	for {
+		assoc, err := s.listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			s.logger.Warn("Failed to accept NG association", zap.Error(err))
+			continue
+		}
+
+		conn := &ranConnection{assoc: assoc}
+		s.mu.Lock()
+		s.conns[assoc.RemoteAddr().String()] = conn
+		s.mu.Unlock()
+
+		s.logger.Info("NG association established",
+			zap.String("gnb_address", assoc.RemoteAddr().String()),
+			zap.Uint16("streams", assoc.Streams()),
+		)
+		go s.serve(conn)
+	}
+}
+
+// serve handles the NGAP messages of one gNB until the association ends.
+// Messages are handled in order, so that the NAS messages of a UE are.
+func (s *Server) serve(conn *ranConnection) {
+	defer s.remove(conn)
+
+	for {
+		pdu, _, err := conn.assoc.Receive()
+		if errors.Is(err, sctp.ErrAssociationClosed) || errors.Is(err, net.ErrClosed) {
+			s.logger.Info("NG association closed",
+				zap.String("gnb_address", conn.assoc.RemoteAddr().String()))
+			return
+		}
+		if err != nil {
+			s.logger.Warn("NG association failed", zap.Error(err))
+			return
+		}
+
+		msg, err := ngap.Decode(pdu)
+		if err != nil {
+			s.logger.Warn("Discarding malformed NGAP message", zap.Error(err))
+			continue
+		}
+
+		switch m := msg.(type) {
+		case *ngap.NGSetupRequest:
+			s.handleNGSetup(conn, m)
+		case *ngap.InitialUEMessage:
+			s.handleInitialUEMessage(conn, m)
+		case *ngap.UplinkNASTransport:
+			s.handleUplinkNASTransport(conn, m)
+		case *ngap.InitialContextSetupResponse:
+			s.logger.Info("Initial Context Setup completed",
+				zap.Uint64("amf_ue_ngap_id", m.AMFUENGAPID),
+				zap.Uint32("ran_ue_ngap_id", m.RANUENGAPID))
+		default:
+			s.logger.Debug("Ignoring NGAP message", zap.Int("message_type", msg.MessageType()))
+		}
+	}
+}
+
+// handleNGSetup accepts the gNB with the GUAMI and slices of this AMF
+func (s *Server) handleNGSetup(conn *ranConnection, req *ngap.NGSetupRequest) {
+	resp, err := s.setupResponse()
+	if err != nil {
+		s.logger.Error("Failed to build NG Setup Response", zap.Error(err))
+		return
+	}
+	if err := ngap.SendMessage(conn.assoc, resp); err != nil {
+		s.logger.Warn("Failed to send NG Setup Response", zap.Error(err))
+		return
+	}
+
+	s.mu.Lock()
+	conn.gnbID = req.GlobalRANNodeID
+	s.mu.Unlock()
+
+	s.logger.Info("NG Setup completed",
+		zap.Uint32("gnb_id", req.GlobalRANNodeID.GNBID),
+		zap.String("ran_node_name", req.RANNodeName),
+		zap.Int("supported_tas", len(req.SupportedTAs)),
+	)
+}
+
+// setupResponse describes this AMF from its configuration
+func (s *Server) setupResponse() (*ngap.NGSetupResponse, error) {
+	plmn := &ngap.PLMNID{MCC: s.config.PLMN.MCC, MNC: s.config.PLMN.MNC}
+
+	slices := make([]*ngap.SNSSAI, 0, len(s.config.AMF.SupportedSNSSAI))
+	for _, snssai := range s.config.AMF.SupportedSNSSAI {
+		sd, err := hex.DecodeString(snssai.SD)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sd %q: %w", snssai.SD, err)
+		}
+		slices = append(slices, &ngap.SNSSAI{SST: snssai.SST, SD: sd})
+	}
+
+	return &ngap.NGSetupResponse{
+		AMFName: s.config.NF.Name,
+		ServedGUAMIs: []*ngap.GUAMI{{
+			PLMNID:      plmn,
+			AMFRegionID: s.config.AMF.RegionID,
+			AMFSetID:    s.config.AMF.SetID,
+			AMFPointer:  s.config.AMF.Pointer,
+		}},
+		RelativeAMFCapacity: relativeAMFCapacity,
+		PLMNSupportList:     []*ngap.PLMNSupport{{PLMNID: plmn, SliceSupportList: slices}},
+	}, nil
+}
+
+// handleInitialUEMessage opens the NG connection of a UE with an AMF UE
+// NGAP ID and passes its first NAS message on
+func (s *Server) handleInitialUEMessage(conn *ranConnection, msg *ngap.InitialUEMessage) {
+	s.mu.Lock()
+	ue := &ranUE{conn: conn, amfUENGAPID: s.nextAMFID, ranUENGAPID: msg.RANUENGAPID}
+	s.nextAMFID++
+	s.ues[ue.amfUENGAPID] = ue
+	s.mu.Unlock()
+
+	s.logger.Info("Initial UE Message",
+		zap.Uint64("amf_ue_ngap_id", ue.amfUENGAPID),
+		zap.Uint32("ran_ue_ngap_id", ue.ranUENGAPID),
+		zap.String("rrc_establishment_cause", msg.RRCEstablishmentCause),
+	)
+	s.forwardNAS(ue, msg.NASPDU)
+}
+
+// handleUplinkNASTransport passes a NAS message on a UE's NG connection on
+func (s *Server) handleUplinkNASTransport(conn *ranConnection, msg *ngap.UplinkNASTransport) {
+	s.mu.Lock()
+	ue, exists := s.ues[msg.AMFUENGAPID]
+	s.mu.Unlock()
+
+	if !exists || ue.conn != conn || ue.ranUENGAPID != msg.RANUENGAPID {
+		s.logger.Warn("Uplink NAS Transport for an unknown UE",
+			zap.Uint64("amf_ue_ngap_id", msg.AMFUENGAPID),
+			zap.Uint32("ran_ue_ngap_id", msg.RANUENGAPID))
+		return
+	}
+	s.forwardNAS(ue, msg.NASPDU)
+}
+
+// forwardNAS hands an uplink NAS PDU to the NAS service and sends the
+// answer back in a Downlink NAS Transport. The NAS service knows the UE
+// by its AMF UE NGAP ID, which unlike the RAN UE NGAP ID is unique across
+// gNBs.
+func (s *Server) forwardNAS(ue *ranUE, nasPDU []byte) {
+	downlink, err := s.nas.HandleUplinkNAS(context.Background(), strconv.FormatUint(ue.amfUENGAPID, 10), nasPDU)
+	if err != nil {
+		s.logger.Warn("Failed to handle uplink NAS message",
+			zap.Uint64("amf_ue_ngap_id", ue.amfUENGAPID),
+			zap.Error(err))
+		return
+	}
+	if len(downlink) == 0 {
+		return
+	}
+
+	err = ngap.SendMessage(ue.conn.assoc, &ngap.DownlinkNASTransport{
+		AMFUENGAPID: ue.amfUENGAPID,
+		RANUENGAPID: ue.ranUENGAPID,
+		NASPDU:      downlink,
+	})
+	if err != nil {
+		s.logger.Warn("Failed to send Downlink NAS Transport",
+			zap.Uint64("amf_ue_ngap_id", ue.amfUENGAPID),
+			zap.Error(err))
+	}
+}
+
+// remove forgets a gNB whose association has ended, with its UEs
+func (s *Server) remove(conn *ranConnection) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.conns, conn.assoc.RemoteAddr().String())
+	for id, ue := range s.ues {
+		if ue.conn == conn {
+			delete(s.ues, id)
+		}
+	}
+	conn.assoc.Close()
+}
+
+// Close stops accepting gNBs and ends all associations
+func (s *Server) Close() error {
+	err := s.listener.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, conn := range s.conns {
+		conn.assoc.Close()
+	}
+	return err
+}
@@ -2,19 +2,26 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/your-org/5g-network/common/metrics"
+	"github.com/your-org/5g-network/common/sbi"
 	"github.com/your-org/5g-network/nf/amf/internal/service"
 	"go.uber.org/zap"
 )
 
+// maxNASPDUSize bounds an uplink NAS PDU; NAS messages are at most 65535
+// octets once the 5GMM header is added (TS 24.501 9.11.3.33)
+const maxNASPDUSize = 65535 + 16
+
 // handleAuthenticationRequest handles POST request to initiate UE authentication
 func (s *AMFServer) handleAuthenticationRequest(w http.ResponseWriter, r *http.Request) {
 	var req service.AuthenticationRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		s.respondError(w, http.StatusBadRequest, "invalid request body", err)
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
 		return
 	}
 
@@ -24,7 +31,7 @@ func (s *AMFServer) handleAuthenticationRequest(w http.ResponseWriter, r *http.R
 
 	response, err := s.registrationService.InitiateAuthentication(r.Context(), &req)
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, "failed to initiate authentication", err)
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to initiate authentication", err)
 		metrics.RecordAuthenticationRequest("failed")
 		return
 	}
@@ -46,7 +53,7 @@ func (s *AMFServer) handleAuthenticationConfirm(w http.ResponseWriter, r *http.R
 
 	var req service.AuthenticationConfirmRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		s.respondError(w, http.StatusBadRequest, "invalid request body", err)
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
 		return
 	}
 	req.AuthCtxID = authCtxID
@@ -57,7 +64,7 @@ func (s *AMFServer) handleAuthenticationConfirm(w http.ResponseWriter, r *http.R
 
 	response, err := s.registrationService.ConfirmAuthentication(r.Context(), &req)
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, "failed to confirm authentication", err)
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to confirm authentication", err)
 		return
 	}
 
@@ -73,7 +80,7 @@ func (s *AMFServer) handleAuthenticationConfirm(w http.ResponseWriter, r *http.R
 func (s *AMFServer) handleRegistrationRequest(w http.ResponseWriter, r *http.Request) {
 	var req service.RegistrationRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		s.respondError(w, http.StatusBadRequest, "invalid request body", err)
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
 		return
 	}
 
@@ -84,7 +91,7 @@ func (s *AMFServer) handleRegistrationRequest(w http.ResponseWriter, r *http.Req
 
 	response, err := s.registrationService.RegisterUE(r.Context(), &req)
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, "failed to register UE", err)
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to register UE", err)
 		metrics.RecordRegistrationAttempt("failed")
 		return
 	}
@@ -122,7 +129,7 @@ func (s *AMFServer) handleDeregistration(w http.ResponseWriter, r *http.Request)
 
 	err := s.registrationService.DeregisterUE(r.Context(), supi)
 	if err != nil {
-		s.respondError(w, http.StatusNotFound, "failed to deregister UE", err)
+		s.respondProblem(w, http.StatusNotFound, sbi.CauseContextNotFound, "failed to deregister UE", err)
 		return
 	}
 
@@ -141,24 +148,28 @@ func (s *AMFServer) handleDeregistration(w http.ResponseWriter, r *http.Request)
 func (s *AMFServer) handleGetUEContext(w http.ResponseWriter, r *http.Request) {
 	ueContextID := chi.URLParam(r, "ueContextId")
 
-	// For simplicity, ueContextId == SUPI
-	ueCtx, exists := s.contextManager.GetContext(ueContextID)
+	// ueContextId is the SUPI or the 5G-GUTI the UE was allocated
+	ueCtx, exists := s.contextManager.LookupContext(ueContextID)
 	if !exists {
-		s.respondError(w, http.StatusNotFound, "UE context not found", nil)
+		s.respondProblem(w, http.StatusNotFound, sbi.CauseContextNotFound, "UE context not found", nil)
 		return
 	}
 
 	s.respondJSON(w, http.StatusOK, map[string]interface{}{
 		"supi":              ueCtx.SUPI,
+		"guti":              ueCtx.GUTI,
 		"registrationState": ueCtx.RegistrationState,
 		"connectionState":   ueCtx.ConnectionState,
 		"guami":             ueCtx.GUAMI,
 		"tai":               ueCtx.TAI,
 		"allowedNssai":      ueCtx.AllowedNSSAI,
+		"emergency":         ueCtx.IsEmergencyRegistered(),
 	})
 }
 
-// handleReleaseUEContext handles POST request to release UE context
+// handleReleaseUEContext handles POST request to release UE context. The
+// PDU sessions are released at their SMFs and the NG connection at the gNB
+// before the context is removed.
 func (s *AMFServer) handleReleaseUEContext(w http.ResponseWriter, r *http.Request) {
 	ueContextID := chi.URLParam(r, "ueContextId")
 
@@ -166,28 +177,145 @@ func (s *AMFServer) handleReleaseUEContext(w http.ResponseWriter, r *http.Reques
 		zap.String("ue_context_id", ueContextID),
 	)
 
-	// For simplicity, ueContextId == SUPI
-	err := s.registrationService.DeregisterUE(r.Context(), ueContextID)
-	if err != nil {
-		s.respondError(w, http.StatusNotFound, "failed to release UE context", err)
+	err := s.registrationService.ReleaseUEContext(r.Context(), ueContextID, service.ReleaseCauseNormal)
+	switch {
+	case errors.Is(err, service.ErrUEContextNotFound):
+		s.respondProblem(w, http.StatusNotFound, sbi.CauseContextNotFound, "UE context not found", err)
+		return
+	case err != nil:
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "UE context released with failures", err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// handleN1N2Transfer handles POST request for N1/N2 message transfer
+// handleEstablishPDUSession handles a UE-requested PDU session establishment,
+// returning the session created at the selected SMF
+func (s *AMFServer) handleEstablishPDUSession(w http.ResponseWriter, r *http.Request) {
+	ueContextID := chi.URLParam(r, "ueContextId")
+
+	var req service.PDUSessionEstablishmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
+		return
+	}
+	if req.PDUSessionID == 0 || req.DNN == "" {
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseMandatoryIEMissing, "pduSessionId and dnn are required", nil)
+		return
+	}
+
+	session, err := s.registrationService.EstablishPDUSession(r.Context(), ueContextID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUEContextNotFound), errors.Is(err, service.ErrUENotRegistered):
+			s.respondProblem(w, http.StatusNotFound, sbi.CauseContextNotFound, "UE context not found", err)
+		case errors.Is(err, service.ErrPDUSessionExists):
+			s.respondProblem(w, http.StatusConflict, sbi.CauseResourceAlreadyExists, "PDU session already exists", err)
+		case errors.Is(err, service.ErrDNNNotAllowed), errors.Is(err, service.ErrSNSSAINotAllowed):
+			s.respondProblem(w, http.StatusForbidden, sbi.CauseUnauthorized, "DNN or S-NSSAI not allowed", err)
+		case errors.Is(err, service.ErrNoSMFAvailable):
+			s.respondProblem(w, http.StatusServiceUnavailable, sbi.CauseSystemFailure, "no SMF available", err)
+		default:
+			s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "PDU session establishment failed", err)
+		}
+		return
+	}
+
+	s.respondJSON(w, http.StatusCreated, map[string]interface{}{
+		"pduSessionId":    session.SessionID,
+		"dnn":             session.DNN,
+		"snssai":          session.SNSSAI,
+		"smfInstanceId":   session.SMFInstanceID,
+		"ueIpv4Address":   session.UEIPv4Address,
+		"ueIpv6Prefix":    session.UEIPv6Prefix,
+		"upfN3Address":    session.UPFN3Address,
+		"upfTeidDownlink": session.UPFTEID,
+		"state":           session.State,
+	})
+}
+
+// handleN1N2Transfer handles an N1N2MessageTransfer (TS 29.518 5.2.2.3.1).
+// An idle UE is paged and 202 is returned; a connected UE gets the message
+// forwarded and 200 is returned.
 func (s *AMFServer) handleN1N2Transfer(w http.ResponseWriter, r *http.Request) {
 	ueContextID := chi.URLParam(r, "ueContextId")
 
+	var req service.N1N2MessageTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
+		return
+	}
+
 	s.logger.Info("N1/N2 message transfer",
 		zap.String("ue_context_id", ueContextID),
+		zap.Uint8("pdu_session_id", req.PDUSessionID),
 	)
 
-	// Simplified - just acknowledge
-	s.respondJSON(w, http.StatusOK, map[string]string{
-		"status": "accepted",
-	})
+	resp, err := s.pagingService.TransferN1N2Message(r.Context(), ueContextID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrUEContextNotFound), errors.Is(err, service.ErrUENotRegistered):
+			s.respondProblem(w, http.StatusNotFound, sbi.CauseContextNotFound, "UE context not found", err)
+		default:
+			s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "N1/N2 message transfer failed", err)
+		}
+		return
+	}
+
+	status := http.StatusOK
+	if resp.Cause == service.AttemptingToReachUE {
+		status = http.StatusAccepted
+	}
+	s.respondJSON(w, status, resp)
+}
+
+// handleServiceRequest handles the Service Request a UE sends when it
+// leaves IDLE, including in response to paging
+func (s *AMFServer) handleServiceRequest(w http.ResponseWriter, r *http.Request) {
+	ueID := chi.URLParam(r, "supi")
+
+	if err := s.pagingService.HandlePagingResponse(ueID); err != nil {
+		s.respondProblem(w, http.StatusNotFound, sbi.CauseContextNotFound, "UE context not found", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleUplinkNAS handles an uplink NAS PDU (AMF-specific stand-in for
+// NGAP Uplink NAS Transport), returning the downlink NAS PDU it triggers
+func (s *AMFServer) handleUplinkNAS(w http.ResponseWriter, r *http.Request) {
+	ranUEID := chi.URLParam(r, "ranUeNgapId")
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxNASPDUSize+1))
+	if err != nil || len(data) > maxNASPDUSize {
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid NAS PDU", err)
+		return
+	}
+
+	downlink, err := s.nasService.HandleUplinkNAS(r.Context(), ranUEID, data)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidNASMessage),
+			errors.Is(err, service.ErrUnsupportedNASMessage),
+			errors.Is(err, service.ErrUnexpectedNASMessage):
+			s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid NAS PDU", err)
+		case errors.Is(err, service.ErrUEContextNotFound):
+			s.respondProblem(w, http.StatusNotFound, sbi.CauseContextNotFound, "UE context not found", err)
+		case errors.Is(err, service.ErrAuthenticationRejected):
+			s.respondProblem(w, http.StatusForbidden, sbi.CauseAuthenticationRejected, "authentication rejected", err)
+		case errors.Is(err, service.ErrRegistrationRejected):
+			s.respondProblem(w, http.StatusForbidden, sbi.CauseUnauthorized, "registration rejected", err)
+		default:
+			s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to handle NAS PDU", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", sbi.ContentType5GNAS)
+	w.WriteHeader(http.StatusOK)
+	w.Write(downlink)
 }
 
 // handleListUEContexts handles GET request for listing all UE contexts
@@ -9,6 +9,12 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/your-org/5g-network/common/buildinfo"
+	"github.com/your-org/5g-network/common/correlation"
+	"github.com/your-org/5g-network/common/loglevel"
+	"github.com/your-org/5g-network/common/metrics"
+	"github.com/your-org/5g-network/common/oauth"
+	"github.com/your-org/5g-network/common/sbi"
 	"github.com/your-org/5g-network/nf/amf/internal/config"
 	amfcontext "github.com/your-org/5g-network/nf/amf/internal/context"
 	"github.com/your-org/5g-network/nf/amf/internal/service"
@@ -24,6 +30,8 @@ type AMFServer struct {
 
 	// Services
 	registrationService *service.RegistrationService
+	pagingService       *service.PagingService
+	nasService          *service.NASService
 	contextManager      *amfcontext.UEContextManager
 }
 
@@ -31,6 +39,7 @@ type AMFServer struct {
 func NewServer(
 	cfg *config.Config,
 	registrationService *service.RegistrationService,
+	pagingService *service.PagingService,
 	contextManager *amfcontext.UEContextManager,
 	logger *zap.Logger,
 ) *AMFServer {
@@ -39,6 +48,7 @@ func NewServer(
 		router:              chi.NewRouter(),
 		logger:              logger,
 		registrationService: registrationService,
+		pagingService:       pagingService,
 		contextManager:      contextManager,
 	}
 
@@ -51,10 +61,13 @@ func NewServer(
 // setupMiddleware configures HTTP middleware
 func (s *AMFServer) setupMiddleware() {
 	s.router.Use(middleware.RequestID)
+	s.router.Use(correlation.Middleware)
 	s.router.Use(middleware.RealIP)
+	s.router.Use(metrics.Middleware)
 	s.router.Use(s.loggingMiddleware)
 	s.router.Use(middleware.Recoverer)
 	s.router.Use(middleware.Timeout(60 * time.Second))
+	s.router.Use(oauth.Middleware(s.config.SBI.OAuth2, s.logger, "AMF", s.config.NF.InstanceID))
 }
 
 // setupRoutes configures HTTP routes
@@ -66,24 +79,33 @@ func (s *AMFServer) setupRoutes() {
 
 	// Namf_Communication service (TS 29.518)
 	s.router.Route("/namf-comm/v1", func(r chi.Router) {
+		r.Use(oauth.RequireScope("namf-comm"))
+
 		// UE Context Management
 		r.Get("/ue-contexts/{ueContextId}", s.handleGetUEContext)
 		r.Post("/ue-contexts/{ueContextId}/release", s.handleReleaseUEContext)
 
+		// UE-requested PDU session establishment (AMF-specific stand-in for
+		// the N1 SM message of the UE)
+		r.Post("/ue-contexts/{ueContextId}/pdu-sessions", s.handleEstablishPDUSession)
+
 		// N1 Message Transfer
 		r.Post("/ue-contexts/{ueContextId}/n1-n2-messages", s.handleN1N2Transfer)
 	})
 
 	// UE Authentication (AMF-specific, not in 3GPP but useful for testing)
 	s.router.Route("/namf-auth/v1", func(r chi.Router) {
+		r.Use(oauth.RequireScope("namf-auth"))
 		r.Post("/authenticate", s.handleAuthenticationRequest)
 		r.Put("/authenticate/{authCtxId}/confirm", s.handleAuthenticationConfirm)
 	})
 
 	// UE Registration (AMF-specific, not in 3GPP but useful for testing)
 	s.router.Route("/namf-reg/v1", func(r chi.Router) {
+		r.Use(oauth.RequireScope("namf-reg"))
 		r.Post("/register", s.handleRegistrationRequest)
 		r.Delete("/ue-contexts/{supi}", s.handleDeregistration)
+		r.Post("/ue-contexts/{supi}/service-request", s.handleServiceRequest)
 	})
 
 	// Admin endpoints
@@ -93,6 +115,26 @@ func (s *AMFServer) setupRoutes() {
 	})
 }
 
+// SetNASService accepts uplink NAS PDUs on the RAN connections of UEs,
+// answering each with the downlink NAS PDU it triggers
+func (s *AMFServer) SetNASService(nasService *service.NASService) {
+	s.nasService = nasService
+	s.router.With(oauth.RequireScope("namf-nas")).Post("/namf-nas/v1/ran-ues/{ranUeNgapId}/uplink-nas", s.handleUplinkNAS)
+}
+
+// SetBuildInfo exposes the NF build info on GET /version
+func (s *AMFServer) SetBuildInfo(info *buildinfo.Info) {
+	s.router.Get("/version", info.Handler())
+}
+
+// SetLogLevel exposes the level the NF's loggers are built from on
+// /admin/log-level, read with GET and changed at runtime with PUT
+func (s *AMFServer) SetLogLevel(level zap.AtomicLevel) {
+	handler := loglevel.Handler(level, s.logger)
+	s.router.Get("/admin/log-level", handler)
+	s.router.Put("/admin/log-level", handler)
+}
+
 // Start starts the HTTP server
 func (s *AMFServer) Start() error {
 	addr := fmt.Sprintf("%s:%d", s.config.SBI.BindAddress, s.config.SBI.Port)
@@ -108,6 +150,11 @@ func (s *AMFServer) Start() error {
 	s.logger.Info("Starting AMF HTTP server", zap.String("address", addr))
 
 	if s.config.SBI.TLS.Enabled {
+		tlsConfig, err := s.config.SBI.TLS.Build()
+		if err != nil {
+			return fmt.Errorf("invalid TLS configuration: %w", err)
+		}
+		s.server.TLSConfig = tlsConfig
 		return s.server.ListenAndServeTLS(s.config.SBI.TLS.CertFile, s.config.SBI.TLS.KeyFile)
 	}
 
@@ -141,6 +188,7 @@ func (s *AMFServer) loggingMiddleware(next http.Handler) http.Handler {
 			zap.Duration("duration", time.Since(start)),
 			zap.String("remote_addr", r.RemoteAddr),
 			zap.String("request_id", middleware.GetReqID(r.Context())),
+			correlation.Field(r.Context()),
 		)
 	})
 }
@@ -156,22 +204,13 @@ func (s *AMFServer) respondJSON(w http.ResponseWriter, status int, data interfac
 	}
 }
 
-func (s *AMFServer) respondError(w http.ResponseWriter, status int, message string, err error) {
-	s.logger.Error(message, zap.Error(err))
-
-	w.Header().Set("Content-Type", "application/problem+json")
-	w.WriteHeader(status)
-
-	response := map[string]interface{}{
-		"status": status,
-		"title":  message,
-	}
+// respondProblem writes a ProblemDetails error response
+func (s *AMFServer) respondProblem(w http.ResponseWriter, status int, cause, title string, err error) {
+	s.logger.Error(title, zap.String("cause", cause), zap.Error(err))
 
-	if err != nil {
-		response["detail"] = err.Error()
+	if err := sbi.WriteProblem(w, sbi.NewProblem(status, cause, title, err)); err != nil {
+		s.logger.Error("Failed to encode problem details", zap.Error(err))
 	}
-
-	json.NewEncoder(w).Encode(response)
 }
 
 // Health check handlers
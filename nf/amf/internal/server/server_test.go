@@ -0,0 +1,137 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/your-org/5g-network/common/sbi"
+	"github.com/your-org/5g-network/nf/amf/internal/config"
+	amfcontext "github.com/your-org/5g-network/nf/amf/internal/context"
+	"github.com/your-org/5g-network/nf/amf/internal/service"
+)
+
+func TestErrorResponsesAreProblemDetails(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	contextManager := amfcontext.NewUEContextManager()
+	s := NewServer(&config.Config{}, nil, nil, contextManager, logger)
+	s.SetNASService(service.NewNASService(&config.Config{}, nil, contextManager, logger))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		cause  string
+	}{
+		{"unknown UE context", http.MethodGet, "/namf-comm/v1/ue-contexts/imsi-001010000000001", "", http.StatusNotFound, sbi.CauseContextNotFound},
+		{"malformed registration", http.MethodPost, "/namf-reg/v1/register", "{", http.StatusBadRequest, sbi.CauseInvalidMsgFormat},
+		{"truncated NAS PDU", http.MethodPost, "/namf-nas/v1/ran-ues/1/uplink-nas", "\x7e\x00", http.StatusBadRequest, sbi.CauseInvalidMsgFormat},
+		{"NAS message out of order", http.MethodPost, "/namf-nas/v1/ran-ues/1/uplink-nas", "\x7e\x00\x57", http.StatusBadRequest, sbi.CauseInvalidMsgFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+
+			require.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, sbi.ContentTypeProblem, rec.Header().Get("Content-Type"))
+
+			var problem sbi.ProblemDetails
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&problem))
+			assert.Equal(t, tt.status, problem.Status)
+			assert.Equal(t, tt.cause, problem.Cause)
+			assert.NotEmpty(t, problem.Title)
+		})
+	}
+}
+
+func TestN1N2TransferStatus(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	cfg := &config.Config{}
+	contextManager := amfcontext.NewUEContextManager()
+	s := NewServer(cfg, nil, service.NewPagingService(cfg, contextManager, logger), contextManager, logger)
+
+	idle := contextManager.CreateContext("imsi-001010000000001")
+	idle.UpdateRegistrationState(amfcontext.RegistrationStateRegistered)
+	connected := contextManager.CreateContext("imsi-001010000000002")
+	connected.UpdateRegistrationState(amfcontext.RegistrationStateRegistered)
+	connected.UpdateConnectionState(amfcontext.ConnectionStateConnected)
+
+	tests := []struct {
+		supi   string
+		status int
+		cause  string
+	}{
+		{idle.SUPI, http.StatusAccepted, service.AttemptingToReachUE},
+		{connected.SUPI, http.StatusOK, service.N1N2TransferInitiated},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost,
+			"/namf-comm/v1/ue-contexts/"+tt.supi+"/n1-n2-messages", strings.NewReader(`{"pduSessionId":1}`)))
+
+		require.Equal(t, tt.status, rec.Code, tt.supi)
+		var resp service.N1N2MessageTransferResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		assert.Equal(t, tt.cause, resp.Cause)
+	}
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost,
+		"/namf-reg/v1/ue-contexts/"+idle.SUPI+"/service-request", nil))
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	assert.True(t, idle.IsConnected())
+}
+
+func TestLogLevelEndpoint(t *testing.T) {
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	core, logs := observer.New(level)
+	logger := zap.New(core)
+	cfg := &config.Config{}
+	contextManager := amfcontext.NewUEContextManager()
+	s := NewServer(cfg, nil, service.NewPagingService(cfg, contextManager, logger), contextManager, logger)
+	s.SetLogLevel(level)
+
+	idle := contextManager.CreateContext("imsi-001010000000001")
+	idle.UpdateRegistrationState(amfcontext.RegistrationStateRegistered)
+	transfer := func() {
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost,
+			"/namf-comm/v1/ue-contexts/"+idle.SUPI+"/n1-n2-messages", strings.NewReader(`{"pduSessionId":1}`)))
+		require.Equal(t, http.StatusAccepted, rec.Code)
+	}
+
+	// Paging an already paged UE is only logged at debug level
+	transfer()
+	transfer()
+	assert.Zero(t, logs.FilterMessage("UE already being paged").Len())
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/admin/log-level", strings.NewReader(`{"level":"debug"}`)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	transfer()
+	assert.Equal(t, 1, logs.FilterMessage("UE already being paged").Len())
+
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/log-level", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"level":"debug"}`, rec.Body.String())
+
+	// The other admin endpoints are still routed
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/ue-contexts", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,340 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/your-org/5g-network/common/nas"
+	"github.com/your-org/5g-network/nf/amf/internal/config"
+	amfcontext "github.com/your-org/5g-network/nf/amf/internal/context"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrInvalidNASMessage is returned when an uplink NAS PDU cannot be decoded
+	ErrInvalidNASMessage = errors.New("invalid NAS message")
+	// ErrUnsupportedNASMessage is returned for NAS messages the AMF does not handle
+	ErrUnsupportedNASMessage = errors.New("unsupported NAS message")
+	// ErrUnexpectedNASMessage is returned when a NAS message arrives out of
+	// order, e.g. an Authentication Response without an Authentication Request
+	ErrUnexpectedNASMessage = errors.New("unexpected NAS message")
+	// ErrAuthenticationRejected is returned when the AUSF rejects the RES*
+	ErrAuthenticationRejected = errors.New("authentication rejected")
+	// ErrRegistrationRejected is returned when a registration is not accepted
+	ErrRegistrationRejected = errors.New("registration rejected")
+)
+
+// nasRegistrationTypes maps 5GS registration types to RegistrationRequest types
+var nasRegistrationTypes = map[uint8]string{
+	nas.REGISTRATION_TYPE_INITIAL:   "INITIAL",
+	nas.REGISTRATION_TYPE_MOBILITY:  "MOBILITY",
+	nas.REGISTRATION_TYPE_PERIODIC:  "PERIODIC",
+	nas.REGISTRATION_TYPE_EMERGENCY: "EMERGENCY",
+}
+
+// nasUE is the NAS state of a UE between its Registration Request and the
+// Registration Accept
+type nasUE struct {
+	registration *nas.RegistrationRequest
+	authCtxID    string
+	supi         string
+	ngKSI        uint8
+}
+
+// NASService routes uplink 5GMM NAS PDUs to the registration procedures
+// and answers them with downlink NAS PDUs (TS 24.501 5.5.1.2). The NG-RAN
+// connection of a UE is identified by the RAN UE NGAP ID on the HTTP
+// stand-in and by the AMF UE NGAP ID over N2. Once the UE is
+// authenticated, NAS PDUs are protected with the UE's NAS security context.
+type NASService struct {
+	config              *config.Config
+	registrationService *RegistrationService
+	contextManager      *amfcontext.UEContextManager
+	logger              *zap.Logger
+
+	mu  sync.Mutex
+	ues map[string]*nasUE // RAN connection ID -> NAS state
+}
+
+// NewNASService creates a new NAS service
+func NewNASService(
+	cfg *config.Config,
+	registrationService *RegistrationService,
+	contextManager *amfcontext.UEContextManager,
+	logger *zap.Logger,
+) *NASService {
+	return &NASService{
+		config:              cfg,
+		registrationService: registrationService,
+		contextManager:      contextManager,
+		logger:              logger,
+		ues:                 make(map[string]*nasUE),
+	}
+}
+
+// HandleUplinkNAS handles an uplink NAS PDU from the UE on a RAN
+// connection and returns the downlink NAS PDU answering it
+func (s *NASService) HandleUplinkNAS(ctx context.Context, ranUEID string, data []byte) ([]byte, error) {
+	pdu, err := nas.ParsePDU(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidNASMessage, err)
+	}
+
+	s.mu.Lock()
+	ue := s.ues[ranUEID]
+	authenticating := ue != nil && ue.authCtxID != ""
+	secured := ue != nil && ue.supi != ""
+	var supi string
+	if ue != nil {
+		supi = ue.supi
+	}
+	s.mu.Unlock()
+
+	plain := pdu.Message
+	if pdu.IsProtected() {
+		secCtx := s.securityContext(supi)
+		switch {
+		case secCtx != nil:
+			if plain, err = secCtx.UnprotectNAS(data); err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrInvalidNASMessage, err)
+			}
+		case pdu.IsCiphered():
+			return nil, fmt.Errorf("%w: ciphered message without a NAS security context", ErrUnexpectedNASMessage)
+		default:
+			// An initial message protected with a context the AMF does not
+			// hold is read as it is; the UE is authenticated again (TS
+			// 24.501 4.4.4.3)
+		}
+	}
+	msg, err := nas.Decode(plain)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidNASMessage, err)
+	}
+
+	s.logger.Info("Uplink NAS message",
+		zap.String("ran_ue_id", ranUEID),
+		zap.Uint8("message_type", msg.MessageType()),
+		zap.Uint8("security_header_type", pdu.SecurityHeaderType),
+	)
+
+	switch m := msg.(type) {
+	case *nas.RegistrationRequest:
+		return s.handleRegistrationRequest(ctx, ranUEID, m)
+	case *nas.AuthenticationResponse:
+		if !authenticating {
+			return nil, fmt.Errorf("%w: no authentication in progress", ErrUnexpectedNASMessage)
+		}
+		return s.handleAuthenticationResponse(ctx, ranUEID, ue, m)
+	case *nas.SecurityModeComplete:
+		if !secured {
+			return nil, fmt.Errorf("%w: no security mode command sent", ErrUnexpectedNASMessage)
+		}
+		if !pdu.IsProtected() {
+			return nil, fmt.Errorf("%w: security mode complete is not integrity protected", ErrUnexpectedNASMessage)
+		}
+		return s.handleSecurityModeComplete(ctx, ranUEID, ue, m)
+	default:
+		return nil, fmt.Errorf("%w: message type 0x%02x", ErrUnsupportedNASMessage, msg.MessageType())
+	}
+}
+
+// handleRegistrationRequest starts authentication of the UE, answering
+// with an Authentication Request
+func (s *NASService) handleRegistrationRequest(ctx context.Context, ranUEID string, req *nas.RegistrationRequest) ([]byte, error) {
+	ueID, err := s.identify(req.MobileIdentity)
+	if err != nil {
+		return nil, err
+	}
+
+	authResp, err := s.registrationService.InitiateAuthentication(ctx, &AuthenticationRequest{SUPI: ueID})
+	if err != nil {
+		return nil, err
+	}
+
+	rand, err1 := hex.DecodeString(authResp.RAND)
+	autn, err2 := hex.DecodeString(authResp.AUTN)
+	if err := errors.Join(err1, err2); err != nil {
+		return nil, fmt.Errorf("invalid authentication vector from AUSF: %w", err)
+	}
+
+	// The UE has no native security context here, so ngKSI 0 is free
+	ue := &nasUE{registration: req, authCtxID: authResp.AuthCtxID}
+	s.mu.Lock()
+	s.ues[ranUEID] = ue
+	s.mu.Unlock()
+
+	return nas.NewPDU(&nas.AuthenticationRequest{
+		NgKSI: ue.ngKSI,
+		ABBA:  defaultABBA,
+		RAND:  rand,
+		AUTN:  autn,
+	}).Marshal(), nil
+}
+
+// handleAuthenticationResponse confirms the RES* with the AUSF and takes
+// the new security context into use with a Security Mode Command
+func (s *NASService) handleAuthenticationResponse(ctx context.Context, ranUEID string, ue *nasUE, resp *nas.AuthenticationResponse) ([]byte, error) {
+	confirm, err := s.registrationService.ConfirmAuthentication(ctx, &AuthenticationConfirmRequest{
+		AuthCtxID: ue.authCtxID,
+		RES:       hex.EncodeToString(resp.RESStar),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if confirm.Result != "SUCCESS" {
+		s.release(ranUEID)
+		return nil, ErrAuthenticationRejected
+	}
+
+	secCtx := s.securityContext(confirm.SUPI)
+	if secCtx == nil {
+		return nil, ErrUEContextNotFound
+	}
+	ciphering, err1 := amfcontext.NASAlgorithmID(secCtx.CipheringAlgorithm, "NEA")
+	integrity, err2 := amfcontext.NASAlgorithmID(secCtx.IntegrityAlgorithm, "NIA")
+	if err := errors.Join(err1, err2); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	ue.supi = confirm.SUPI
+	s.mu.Unlock()
+
+	cmd := &nas.SecurityModeCommand{
+		CipheringAlgorithm:           ciphering,
+		IntegrityAlgorithm:           integrity,
+		NgKSI:                        ue.ngKSI,
+		ReplayedUESecurityCapability: ue.registration.UESecurityCapability,
+		IMEISVRequest:                true,
+	}
+	return secCtx.ProtectNAS(cmd.Marshal(), nas.SECURITY_HEADER_INTEGRITY_PROTECTED_NEW_CONTEXT)
+}
+
+// handleSecurityModeComplete registers the UE, answering with a
+// Registration Accept carrying its new 5G-GUTI
+func (s *NASService) handleSecurityModeComplete(ctx context.Context, ranUEID string, ue *nasUE, complete *nas.SecurityModeComplete) ([]byte, error) {
+	// A UE that sent a partial initial message retransmits it in full
+	registration := ue.registration
+	if complete.NASMessageContainer != nil {
+		inner, err := nas.Decode(complete.NASMessageContainer)
+		if err != nil {
+			return nil, fmt.Errorf("%w: nas message container: %v", ErrInvalidNASMessage, err)
+		}
+		if req, ok := inner.(*nas.RegistrationRequest); ok {
+			registration = req
+		}
+	}
+
+	req := &RegistrationRequest{
+		SUPI:             ue.supi,
+		RegistrationType: nasRegistrationTypes[registration.RegistrationType],
+		FollowOnRequest:  registration.FollowOnRequest,
+	}
+	for _, snssai := range registration.RequestedNSSAI {
+		req.RequestedNSSAI = append(req.RequestedNSSAI, amfcontext.SNSSAI{
+			SST: snssai.SST,
+			SD:  hex.EncodeToString(snssai.SD),
+		})
+	}
+
+	resp, err := s.registrationService.RegisterUE(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	s.release(ranUEID)
+	if resp.Result != "SUCCESS" {
+		return nil, fmt.Errorf("%w: %s", ErrRegistrationRejected, resp.Reason)
+	}
+
+	ueCtx, exists := s.contextManager.GetContext(ue.supi)
+	if !exists || ueCtx.SecurityContext == nil {
+		return nil, ErrUEContextNotFound
+	}
+	guti, err := nas.NewGUTIMobileIdentity(nas.GUTI{
+		MCC:         ueCtx.TAI.PLMNID.MCC,
+		MNC:         ueCtx.TAI.PLMNID.MNC,
+		AMFRegionID: ueCtx.AMFRegionID,
+		AMFSetID:    ueCtx.AMFSetID,
+		AMFPointer:  ueCtx.AMFPointer,
+		TMSI:        ueCtx.TMSI,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode 5G-GUTI: %w", err)
+	}
+
+	accept := &nas.RegistrationAccept{
+		RegistrationResult: nas.REGISTRATION_RESULT_3GPP_ACCESS,
+		GUTI:               &guti,
+		AllowedNSSAI:       []nas.SNSSAI{},
+		T3512:              time.Duration(resp.T3512) * time.Second,
+	}
+	for _, snssai := range resp.AllowedNSSAI {
+		sd, err := hex.DecodeString(snssai.SD)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed S-NSSAI SD %q: %w", snssai.SD, err)
+		}
+		if len(sd) == 0 {
+			sd = nil
+		}
+		accept.AllowedNSSAI = append(accept.AllowedNSSAI, nas.SNSSAI{SST: snssai.SST, SD: sd})
+	}
+
+	return ueCtx.SecurityContext.ProtectNAS(accept.Marshal(), nas.SECURITY_HEADER_INTEGRITY_PROTECTED_CIPHERED)
+}
+
+// identify returns the SUCI or SUPI to authenticate a UE with. A 5G-GUTI
+// must have been allocated by this AMF.
+func (s *NASService) identify(identity nas.MobileIdentity) (string, error) {
+	switch identity.Type() {
+	case nas.MOBILE_IDENTITY_SUCI:
+		suci, err := identity.SUCI()
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidNASMessage, err)
+		}
+		return suci, nil
+	case nas.MOBILE_IDENTITY_GUTI:
+		g, err := identity.GUTI()
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidNASMessage, err)
+		}
+		guti := amfcontext.GUTI{
+			PLMNID:      amfcontext.PLMNID{MCC: g.MCC, MNC: g.MNC},
+			AMFRegionID: g.AMFRegionID,
+			AMFSetID:    g.AMFSetID,
+			AMFPointer:  g.AMFPointer,
+			TMSI:        g.TMSI,
+		}.String()
+		ueCtx, exists := s.contextManager.GetContextByGUTI(guti)
+		if !exists {
+			return "", fmt.Errorf("%w: %s", ErrUEContextNotFound, guti)
+		}
+		return ueCtx.SUPI, nil
+	default:
+		return "", fmt.Errorf("%w: mobile identity type %d", ErrUnsupportedNASMessage, identity.Type())
+	}
+}
+
+// securityContext returns the NAS security context of an authenticated UE,
+// or nil when there is none
+func (s *NASService) securityContext(supi string) *amfcontext.SecurityContext {
+	if supi == "" {
+		return nil
+	}
+	ueCtx, exists := s.contextManager.GetContext(supi)
+	if !exists {
+		return nil
+	}
+	return ueCtx.SecurityContext
+}
+
+// release drops the NAS state of a RAN connection
+func (s *NASService) release(ranUEID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.ues, ranUEID)
+}
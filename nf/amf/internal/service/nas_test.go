@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/your-org/5g-network/common/nas"
+	"github.com/your-org/5g-network/nf/amf/internal/client"
+	amfcontext "github.com/your-org/5g-network/nf/amf/internal/context"
+	"go.uber.org/zap"
+)
+
+const (
+	testRAND    = "0123456789abcdef0123456789abcdef"
+	testAUTN    = "fedcba9876543210fedcba9876543210"
+	testRESStar = "00112233445566778899aabbccddeeff"
+	testKSEAF   = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+)
+
+// newNASTestService creates a NAS service backed by a fake AUSF that
+// accepts testRESStar for the SUCI of imsi-001010000000001
+func newNASTestService(t *testing.T, ciphering string) (*NASService, *amfcontext.UEContextManager) {
+	ausf := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/5g-aka-confirmation") {
+			var req client.AuthConfirmationRequest
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			result := client.AuthConfirmationResponse{AuthResult: "AUTHENTICATION_FAILURE"}
+			if req.RES == testRESStar {
+				result = client.AuthConfirmationResponse{
+					AuthResult: "AUTHENTICATION_SUCCESS",
+					SUPI:       "imsi-001010000000001",
+					KSEAF:      testKSEAF,
+				}
+			}
+			json.NewEncoder(w).Encode(result)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(client.UEAuthenticationResponse{
+			AuthType:      "5G_AKA",
+			AuthCtxID:     "ctx-1",
+			Var5gAuthData: &client.Var5gAuthData{RAND: testRAND, AUTN: testAUTN},
+		})
+	}))
+	t.Cleanup(ausf.Close)
+
+	cfg := newTestConfig()
+	cfg.Security.CipheringOrder = []string{ciphering}
+
+	logger, _ := zap.NewDevelopment()
+	contextManager := amfcontext.NewUEContextManager()
+	registrationService := NewRegistrationService(cfg, client.NewAUSFClient(ausf.URL, time.Second, nil, logger), contextManager, logger)
+	return NewNASService(cfg, registrationService, contextManager, logger), contextManager
+}
+
+func testRegistrationRequest(t *testing.T) *nas.RegistrationRequest {
+	identity, err := nas.NewSUCIMobileIdentity("suci-0-001-01-0000-0-0-0000000001")
+	require.NoError(t, err)
+	return &nas.RegistrationRequest{
+		RegistrationType:     nas.REGISTRATION_TYPE_INITIAL,
+		NgKSI:                nas.NGKSI_NO_KEY,
+		MobileIdentity:       identity,
+		UESecurityCapability: []byte{0xF0, 0xF0},
+		RequestedNSSAI:       []nas.SNSSAI{{SST: 1, SD: []byte{0, 0, 1}}},
+	}
+}
+
+// decodeDownlink decodes a downlink NAS PDU into the expected message type
+func decodeDownlink[M nas.Message](t *testing.T, data []byte) (*nas.PDU, M) {
+	t.Helper()
+
+	pdu, err := nas.ParsePDU(data)
+	require.NoError(t, err)
+	msg, err := nas.Decode(pdu.Message)
+	require.NoError(t, err)
+	typed, ok := msg.(M)
+	require.True(t, ok, "unexpected message type 0x%02x", msg.MessageType())
+	return pdu, typed
+}
+
+// protectUplink protects an uplink NAS message the way the UE does, with
+// the NAS keys of the AMF's security context for the UE
+func protectUplink(t *testing.T, secCtx *amfcontext.SecurityContext, msg nas.Message, headerType uint8, count uint32) []byte {
+	t.Helper()
+
+	ciphering, err := amfcontext.NASAlgorithmID(secCtx.CipheringAlgorithm, "NEA")
+	require.NoError(t, err)
+	integrity, err := amfcontext.NASAlgorithmID(secCtx.IntegrityAlgorithm, "NIA")
+	require.NoError(t, err)
+	knasEnc, _ := hex.DecodeString(secCtx.KNASenc)
+	knasInt, _ := hex.DecodeString(secCtx.KNASint)
+
+	pdu := &nas.PDU{SecurityHeaderType: headerType, SequenceNumber: uint8(count), Message: msg.Marshal()}
+	if pdu.IsCiphered() {
+		pdu.Message, err = nas.Cipher(ciphering, knasEnc, count, nas.BEARER_3GPP, nas.DIRECTION_UPLINK, pdu.Message)
+		require.NoError(t, err)
+	}
+	pdu.MAC, err = nas.ComputeMAC(integrity, knasInt, count, nas.BEARER_3GPP, nas.DIRECTION_UPLINK,
+		append([]byte{pdu.SequenceNumber}, pdu.Message...))
+	require.NoError(t, err)
+	return pdu.Marshal()
+}
+
+// unprotectDownlink checks the MAC of a downlink NAS PDU and deciphers it
+// the way the UE does
+func unprotectDownlink(t *testing.T, secCtx *amfcontext.SecurityContext, data []byte, count uint32) []byte {
+	t.Helper()
+
+	ciphering, err := amfcontext.NASAlgorithmID(secCtx.CipheringAlgorithm, "NEA")
+	require.NoError(t, err)
+	integrity, err := amfcontext.NASAlgorithmID(secCtx.IntegrityAlgorithm, "NIA")
+	require.NoError(t, err)
+	knasEnc, _ := hex.DecodeString(secCtx.KNASenc)
+	knasInt, _ := hex.DecodeString(secCtx.KNASint)
+
+	pdu, err := nas.ParsePDU(data)
+	require.NoError(t, err)
+	ok, err := nas.VerifyMAC(integrity, knasInt, count, nas.BEARER_3GPP, nas.DIRECTION_DOWNLINK,
+		append([]byte{pdu.SequenceNumber}, pdu.Message...), pdu.MAC)
+	require.NoError(t, err)
+	require.True(t, ok, "downlink MAC")
+	if !pdu.IsCiphered() {
+		return data
+	}
+	pdu.Message, err = nas.Cipher(ciphering, knasEnc, count, nas.BEARER_3GPP, nas.DIRECTION_DOWNLINK, pdu.Message)
+	require.NoError(t, err)
+	return pdu.Marshal()
+}
+
+func TestNASRegistration(t *testing.T) {
+	svc, contextManager := newNASTestService(t, "NEA2")
+	ctx := context.Background()
+
+	// Registration Request -> Authentication Request
+	downlink, err := svc.HandleUplinkNAS(ctx, "1", nas.NewPDU(testRegistrationRequest(t)).Marshal())
+	require.NoError(t, err)
+	pdu, authReq := decodeDownlink[*nas.AuthenticationRequest](t, downlink)
+	assert.False(t, pdu.IsProtected())
+	assert.Equal(t, testRAND, hex.EncodeToString(authReq.RAND))
+	assert.Equal(t, testAUTN, hex.EncodeToString(authReq.AUTN))
+
+	// Authentication Response -> Security Mode Command
+	resStar, _ := hex.DecodeString(testRESStar)
+	downlink, err = svc.HandleUplinkNAS(ctx, "1", nas.NewPDU(&nas.AuthenticationResponse{RESStar: resStar}).Marshal())
+	require.NoError(t, err)
+	ueCtx, exists := contextManager.GetContext("imsi-001010000000001")
+	require.True(t, exists)
+	secCtx := ueCtx.SecurityContext
+	require.NotNil(t, secCtx)
+	assert.Len(t, secCtx.KNASenc, 2*nas.KEY_LENGTH)
+	assert.Len(t, secCtx.KNASint, 2*nas.KEY_LENGTH)
+
+	pdu, cmd := decodeDownlink[*nas.SecurityModeCommand](t, unprotectDownlink(t, secCtx, downlink, 0))
+	assert.Equal(t, uint8(nas.SECURITY_HEADER_INTEGRITY_PROTECTED_NEW_CONTEXT), pdu.SecurityHeaderType)
+	assert.Equal(t, uint8(nas.NEA2), cmd.CipheringAlgorithm)
+	assert.Equal(t, uint8(nas.NIA2), cmd.IntegrityAlgorithm)
+	assert.Equal(t, []byte{0xF0, 0xF0}, cmd.ReplayedUESecurityCapability)
+
+	// Security Mode Complete, ciphered with 5G-EA2 -> Registration Accept
+	complete := protectUplink(t, secCtx, &nas.SecurityModeComplete{}, nas.SECURITY_HEADER_INTEGRITY_PROTECTED_CIPHERED_NEW_CONTEXT, 0)
+	downlink, err = svc.HandleUplinkNAS(ctx, "1", complete)
+	require.NoError(t, err)
+	pdu, accept := decodeDownlink[*nas.RegistrationAccept](t, unprotectDownlink(t, secCtx, downlink, 1))
+	assert.Equal(t, uint8(nas.SECURITY_HEADER_INTEGRITY_PROTECTED_CIPHERED), pdu.SecurityHeaderType)
+	assert.Equal(t, uint8(1), pdu.SequenceNumber)
+	assert.Equal(t, []nas.SNSSAI{{SST: 1, SD: []byte{0, 0, 1}}}, accept.AllowedNSSAI)
+	assert.Equal(t, time.Hour, accept.T3512, "54 minutes round up to the next GPRS timer 3 step")
+
+	assert.True(t, ueCtx.IsRegistered())
+
+	require.NotNil(t, accept.GUTI)
+	guti, err := accept.GUTI.GUTI()
+	require.NoError(t, err)
+	assert.Equal(t, ueCtx.TMSI, guti.TMSI)
+	assert.Equal(t, uint8(128), guti.AMFRegionID)
+
+	// A later registration identifies the UE by the allocated 5G-GUTI
+	req := testRegistrationRequest(t)
+	req.RegistrationType = nas.REGISTRATION_TYPE_MOBILITY
+	req.MobileIdentity = *accept.GUTI
+	_, err = svc.HandleUplinkNAS(ctx, "2", nas.NewPDU(req).Marshal())
+	require.NoError(t, err)
+}
+
+func TestNASRegistration_Rejected(t *testing.T) {
+	svc, _ := newNASTestService(t, "NEA2")
+	ctx := context.Background()
+
+	// Messages out of order
+	_, err := svc.HandleUplinkNAS(ctx, "1", nas.NewPDU(&nas.AuthenticationResponse{}).Marshal())
+	assert.ErrorIs(t, err, ErrUnexpectedNASMessage)
+	_, err = svc.HandleUplinkNAS(ctx, "1", nas.NewPDU(&nas.SecurityModeComplete{}).Marshal())
+	assert.ErrorIs(t, err, ErrUnexpectedNASMessage)
+
+	// Undecodable and unknown identities
+	_, err = svc.HandleUplinkNAS(ctx, "1", []byte{nas.EPD_5GMM})
+	assert.ErrorIs(t, err, ErrInvalidNASMessage)
+	req := testRegistrationRequest(t)
+	req.MobileIdentity, err = nas.NewGUTIMobileIdentity(nas.GUTI{MCC: "001", MNC: "01", TMSI: 1})
+	require.NoError(t, err)
+	_, err = svc.HandleUplinkNAS(ctx, "1", nas.NewPDU(req).Marshal())
+	assert.ErrorIs(t, err, ErrUEContextNotFound)
+
+	// A wrong RES* ends the procedure
+	_, err = svc.HandleUplinkNAS(ctx, "1", nas.NewPDU(testRegistrationRequest(t)).Marshal())
+	require.NoError(t, err)
+	_, err = svc.HandleUplinkNAS(ctx, "1", nas.NewPDU(&nas.AuthenticationResponse{RESStar: make([]byte, 16)}).Marshal())
+	assert.ErrorIs(t, err, ErrAuthenticationRejected)
+	_, err = svc.HandleUplinkNAS(ctx, "1", nas.NewPDU(&nas.AuthenticationResponse{}).Marshal())
+	assert.ErrorIs(t, err, ErrUnexpectedNASMessage)
+
+	// Ciphered messages cannot be read without a NAS security context
+	complete := &nas.PDU{
+		SecurityHeaderType: nas.SECURITY_HEADER_INTEGRITY_PROTECTED_CIPHERED_NEW_CONTEXT,
+		Message:            (&nas.SecurityModeComplete{}).Marshal(),
+	}
+	_, err = svc.HandleUplinkNAS(ctx, "1", complete.Marshal())
+	assert.ErrorIs(t, err, ErrUnexpectedNASMessage)
+
+	// A Security Mode Complete must carry a valid MAC
+	_, err = svc.HandleUplinkNAS(ctx, "1", nas.NewPDU(testRegistrationRequest(t)).Marshal())
+	require.NoError(t, err)
+	resStar, _ := hex.DecodeString(testRESStar)
+	_, err = svc.HandleUplinkNAS(ctx, "1", nas.NewPDU(&nas.AuthenticationResponse{RESStar: resStar}).Marshal())
+	require.NoError(t, err)
+	_, err = svc.HandleUplinkNAS(ctx, "1", nas.NewPDU(&nas.SecurityModeComplete{}).Marshal())
+	assert.ErrorIs(t, err, ErrUnexpectedNASMessage)
+	complete.MAC = 0xdeadbeef
+	_, err = svc.HandleUplinkNAS(ctx, "1", complete.Marshal())
+	assert.ErrorIs(t, err, ErrInvalidNASMessage)
+	assert.ErrorIs(t, err, amfcontext.ErrNASMACFailure)
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/your-org/5g-network/nf/amf/internal/client"
+	amfcontext "github.com/your-org/5g-network/nf/amf/internal/context"
+	"go.uber.org/zap"
+)
+
+// Causes of rejected S-NSSAIs (TS 24.501 9.11.3.46)
+const (
+	// The UE is not subscribed to the slice
+	RejectCauseNotAvailableInPLMN = "S-NSSAI_NOT_AVAILABLE_IN_PLMN"
+	// The slice is not supported in the UE's tracking area
+	RejectCauseNotAvailableInRegArea = "S-NSSAI_NOT_AVAILABLE_IN_REG_AREA"
+)
+
+// errSubscriptionNotFound is returned by selectNSSAI when the UDM has no
+// subscription data for the UE
+var errSubscriptionNotFound = errors.New("subscription not found")
+
+// RejectedSNSSAI is a requested S-NSSAI the UE is not allowed, with why
+type RejectedSNSSAI struct {
+	SNSSAI amfcontext.SNSSAI `json:"snssai"`
+	Cause  string            `json:"cause"`
+}
+
+// selectNSSAI determines the allowed NSSAI of a registering UE (TS 23.501
+// 5.15.4.1): the requested S-NSSAIs that are both subscribed and supported
+// in the tracking areas of the AMF. The others are rejected. A UE that
+// requests none is offered its default subscribed S-NSSAIs.
+func (s *RegistrationService) selectNSSAI(ctx context.Context, supi string, requested []amfcontext.SNSSAI) ([]amfcontext.SNSSAI, []RejectedSNSSAI, error) {
+	supported := s.supportedNSSAI()
+
+	candidates := requested
+	var subscribed []amfcontext.SNSSAI
+	if s.udmClient != nil {
+		var defaults []amfcontext.SNSSAI
+		var err error
+		subscribed, defaults, err = s.subscribedNSSAI(ctx, supi)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(candidates) == 0 {
+			candidates = defaults
+		}
+	} else if len(candidates) == 0 {
+		candidates = supported
+	}
+
+	allowed := []amfcontext.SNSSAI{}
+	var rejected []RejectedSNSSAI
+	for _, snssai := range candidates {
+		switch {
+		case s.udmClient != nil && !containsSNSSAI(subscribed, snssai):
+			rejected = append(rejected, RejectedSNSSAI{SNSSAI: snssai, Cause: RejectCauseNotAvailableInPLMN})
+		case !containsSNSSAI(supported, snssai):
+			rejected = append(rejected, RejectedSNSSAI{SNSSAI: snssai, Cause: RejectCauseNotAvailableInRegArea})
+		default:
+			allowed = append(allowed, snssai)
+		}
+	}
+
+	if len(rejected) > 0 {
+		s.logger.Info("Requested S-NSSAIs rejected",
+			zap.String("supi", supi),
+			zap.Any("rejected_nssai", rejected),
+		)
+	}
+	return allowed, rejected, nil
+}
+
+// subscribedNSSAI returns the subscribed S-NSSAIs of a UE from the UDM and
+// those marked default, all of them when none is
+func (s *RegistrationService) subscribedNSSAI(ctx context.Context, supi string) (subscribed, defaults []amfcontext.SNSSAI, err error) {
+	amData, err := s.udmClient.GetAMData(ctx, supi)
+	if errors.Is(err, client.ErrSubscriberNotFound) {
+		return nil, nil, errSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get subscribed NSSAI from UDM: %w", err)
+	}
+	if amData.NSSAI == nil {
+		return nil, nil, nil
+	}
+
+	for _, snssai := range amData.NSSAI.DefaultSingleNSSAIs {
+		defaults = append(defaults, amfcontext.SNSSAI{SST: snssai.SST, SD: snssai.SD})
+	}
+	subscribed = append(subscribed, defaults...)
+	for _, snssai := range amData.NSSAI.SingleNSSAIs {
+		subscribed = append(subscribed, amfcontext.SNSSAI{SST: snssai.SST, SD: snssai.SD})
+	}
+	if len(defaults) == 0 {
+		defaults = subscribed
+	}
+	return subscribed, defaults, nil
+}
+
+// supportedNSSAI returns the S-NSSAIs supported by the AMF, in all the
+// tracking areas it serves
+func (s *RegistrationService) supportedNSSAI() []amfcontext.SNSSAI {
+	supported := make([]amfcontext.SNSSAI, len(s.config.AMF.SupportedSNSSAI))
+	for i, snssai := range s.config.AMF.SupportedSNSSAI {
+		supported[i] = amfcontext.SNSSAI{
+			SST: snssai.SST,
+			SD:  snssai.SD,
+		}
+	}
+	return supported
+}
+
+// containsSNSSAI reports whether an S-NSSAI is in a list, comparing SDs
+// regardless of hex digit case
+func containsSNSSAI(nssai []amfcontext.SNSSAI, snssai amfcontext.SNSSAI) bool {
+	for _, s := range nssai {
+		if s.SST == snssai.SST && strings.EqualFold(s.SD, snssai.SD) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/your-org/5g-network/nf/amf/internal/config"
+	amfcontext "github.com/your-org/5g-network/nf/amf/internal/context"
+	"go.uber.org/zap"
+)
+
+// defaultT3513 is the paging timer used when timers.t3513 is not configured
+const defaultT3513 = 6 * time.Second
+
+// N1N2MessageTransfer causes (TS 29.518 6.1.6.3.5)
+const (
+	N1N2TransferInitiated = "N1_N2_TRANSFER_INITIATED"
+	AttemptingToReachUE   = "ATTEMPTING_TO_REACH_UE"
+)
+
+var (
+	// ErrUEContextNotFound is returned when no UE context matches the SUPI or GUTI
+	ErrUEContextNotFound = errors.New("UE context not found")
+	// ErrUENotRegistered is returned when downlink signalling targets a
+	// deregistered UE, which cannot be paged
+	ErrUENotRegistered = errors.New("UE not registered")
+)
+
+// N1N2MessageTransferRequest is the body of an N1N2MessageTransfer request
+// (TS 29.518 6.1.6.2.24), reduced to the fields the AMF acts on
+type N1N2MessageTransferRequest struct {
+	PDUSessionID           uint8  `json:"pduSessionId,omitempty"`
+	N1MessageClass         string `json:"n1MessageClass,omitempty"` // e.g. "SM"
+	N2InfoClass            string `json:"n2InfoClass,omitempty"`    // e.g. "SM"
+	N1N2FailureTxfNotifURI string `json:"n1n2FailureTxfNotifURI,omitempty"`
+}
+
+// N1N2MessageTransferResponse is the body of an N1N2MessageTransfer response
+type N1N2MessageTransferResponse struct {
+	Cause string `json:"cause"`
+}
+
+// PagingRequest is the NGAP Paging message for an idle UE (TS 38.413 9.2.4.1)
+type PagingRequest struct {
+	SUPI         string                            `json:"supi"`
+	AMFSetID     uint16                            `json:"amfSetId"`
+	AMFPointer   uint8                             `json:"amfPointer"`
+	TMSI         uint32                            `json:"5gTmsi"`
+	TAIList      []amfcontext.TrackingAreaIdentity `json:"taiList"`
+	PDUSessionID uint8                             `json:"pduSessionId,omitempty"`
+}
+
+// PagingSender delivers paging requests to the gNBs serving a TAI list
+type PagingSender interface {
+	SendPaging(ctx context.Context, req *PagingRequest) error
+}
+
+// PagingService pages idle UEs when downlink signalling or data is
+// waiting for them (TS 23.502 4.2.3.3)
+type PagingService struct {
+	config         *config.Config
+	contextManager *amfcontext.UEContextManager
+	logger         *zap.Logger
+	sender         PagingSender
+
+	mu      sync.Mutex
+	pending map[string]*time.Timer // SUPI -> T3513
+
+	// NAS timers, replaced on configuration reload
+	timers atomic.Pointer[config.TimersConfig]
+}
+
+// NewPagingService creates a new paging service
+func NewPagingService(
+	cfg *config.Config,
+	contextManager *amfcontext.UEContextManager,
+	logger *zap.Logger,
+) *PagingService {
+	s := &PagingService{
+		config:         cfg,
+		contextManager: contextManager,
+		logger:         logger,
+		pending:        make(map[string]*time.Timer),
+	}
+	s.SetTimers(cfg.Timers)
+	return s
+}
+
+// SetTimers sets the NAS timers, T3513 applying to paging started afterwards
+func (s *PagingService) SetTimers(timers config.TimersConfig) {
+	s.timers.Store(&timers)
+}
+
+// SetPagingSender sets where paging requests are sent. Without a sender
+// paging requests are only logged.
+func (s *PagingService) SetPagingSender(sender PagingSender) {
+	s.sender = sender
+}
+
+// TransferN1N2Message handles an N1N2MessageTransfer for the UE identified
+// by SUPI or 5G-GUTI. A connected UE gets the message forwarded directly;
+// an idle UE is paged and the transfer completes once it responds.
+func (s *PagingService) TransferN1N2Message(ctx context.Context, ueID string, req *N1N2MessageTransferRequest) (*N1N2MessageTransferResponse, error) {
+	ueCtx, exists := s.contextManager.LookupContext(ueID)
+	if !exists {
+		return nil, ErrUEContextNotFound
+	}
+	if !ueCtx.IsRegistered() {
+		return nil, ErrUENotRegistered
+	}
+
+	if ueCtx.IsConnected() {
+		s.logger.Info("Forwarding N1/N2 message to connected UE",
+			zap.String("supi", ueCtx.SUPI),
+			zap.Uint8("pdu_session_id", req.PDUSessionID),
+		)
+		return &N1N2MessageTransferResponse{Cause: N1N2TransferInitiated}, nil
+	}
+
+	if err := s.page(ctx, ueCtx, req.PDUSessionID); err != nil {
+		return nil, err
+	}
+	return &N1N2MessageTransferResponse{Cause: AttemptingToReachUE}, nil
+}
+
+// page sends a paging request for an idle UE to its registration area and
+// starts T3513. A UE already being paged is not paged again.
+func (s *PagingService) page(ctx context.Context, ueCtx *amfcontext.UEContext, pduSessionID uint8) error {
+	s.mu.Lock()
+	if _, paging := s.pending[ueCtx.SUPI]; paging {
+		s.mu.Unlock()
+		s.logger.Debug("UE already being paged", zap.String("supi", ueCtx.SUPI))
+		return nil
+	}
+	supi := ueCtx.SUPI
+	s.pending[supi] = time.AfterFunc(s.t3513(), func() { s.pagingExpired(supi) })
+	s.mu.Unlock()
+
+	req := &PagingRequest{
+		SUPI:         supi,
+		AMFSetID:     ueCtx.AMFSetID,
+		AMFPointer:   ueCtx.AMFPointer,
+		TMSI:         ueCtx.TMSI,
+		TAIList:      []amfcontext.TrackingAreaIdentity{ueCtx.TAI},
+		PDUSessionID: pduSessionID,
+	}
+
+	s.logger.Info("Paging UE",
+		zap.String("supi", supi),
+		zap.String("tac", ueCtx.TAI.TAC),
+		zap.Uint32("5g_tmsi", req.TMSI),
+	)
+
+	if s.sender == nil {
+		return nil
+	}
+	if err := s.sender.SendPaging(ctx, req); err != nil {
+		s.stopPaging(supi)
+		return fmt.Errorf("failed to send paging request: %w", err)
+	}
+	return nil
+}
+
+// HandlePagingResponse handles the Service Request an idle UE sends in
+// response to paging, moving it to CONNECTED and stopping T3513
+func (s *PagingService) HandlePagingResponse(ueID string) error {
+	ueCtx, exists := s.contextManager.LookupContext(ueID)
+	if !exists {
+		return ErrUEContextNotFound
+	}
+
+	paged := s.stopPaging(ueCtx.SUPI)
+	ueCtx.UpdateConnectionState(amfcontext.ConnectionStateConnected)
+
+	s.logger.Info("UE connected",
+		zap.String("supi", ueCtx.SUPI),
+		zap.Bool("paged", paged),
+	)
+	return nil
+}
+
+// IsPaging reports whether a paging request for the UE is awaiting a response
+func (s *PagingService) IsPaging(supi string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, paging := s.pending[supi]
+	return paging
+}
+
+// stopPaging stops T3513 for the UE, reporting whether it was running
+func (s *PagingService) stopPaging(supi string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	timer, paging := s.pending[supi]
+	if !paging {
+		return false
+	}
+	timer.Stop()
+	delete(s.pending, supi)
+	return true
+}
+
+// pagingExpired runs when T3513 expires without a paging response
+func (s *PagingService) pagingExpired(supi string) {
+	if !s.stopPaging(supi) {
+		return
+	}
+	s.logger.Warn("Paging failed, no response from UE",
+		zap.String("supi", supi),
+		zap.Duration("t3513", s.t3513()),
+	)
+}
+
+func (s *PagingService) t3513() time.Duration {
+	if t3513 := s.timers.Load().T3513; t3513 > 0 {
+		return time.Duration(t3513) * time.Second
+	}
+	return defaultT3513
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/your-org/5g-network/nf/amf/internal/config"
+	amfcontext "github.com/your-org/5g-network/nf/amf/internal/context"
+	"go.uber.org/zap"
+)
+
+type recordingPagingSender struct {
+	requests []*PagingRequest
+}
+
+func (r *recordingPagingSender) SendPaging(ctx context.Context, req *PagingRequest) error {
+	r.requests = append(r.requests, req)
+	return nil
+}
+
+func newTestPagingService(t *testing.T, state amfcontext.ConnectionState) (*PagingService, *recordingPagingSender, *amfcontext.UEContext) {
+	t.Helper()
+
+	cfg := newTestConfig()
+	svc, contextManager := newTestService(cfg)
+	ueCtx := contextManager.CreateContext("imsi-001010000000001")
+	ueCtx.SetSecurityContext(&amfcontext.SecurityContext{NASSecurityEstablished: true})
+	resp, err := svc.RegisterUE(context.Background(), &RegistrationRequest{
+		SUPI:             ueCtx.SUPI,
+		RegistrationType: "INITIAL",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "SUCCESS", resp.Result)
+	ueCtx.UpdateConnectionState(state)
+
+	logger, _ := zap.NewDevelopment()
+	paging := NewPagingService(cfg, contextManager, logger)
+	sender := &recordingPagingSender{}
+	paging.SetPagingSender(sender)
+	return paging, sender, ueCtx
+}
+
+func TestTransferN1N2Message_PagesIdleUE(t *testing.T) {
+	paging, sender, ueCtx := newTestPagingService(t, amfcontext.ConnectionStateIdle)
+
+	resp, err := paging.TransferN1N2Message(context.Background(), ueCtx.GUTI, &N1N2MessageTransferRequest{PDUSessionID: 1})
+	require.NoError(t, err)
+	assert.Equal(t, AttemptingToReachUE, resp.Cause)
+
+	require.Len(t, sender.requests, 1)
+	req := sender.requests[0]
+	assert.Equal(t, ueCtx.SUPI, req.SUPI)
+	assert.Equal(t, ueCtx.TMSI, req.TMSI)
+	assert.Equal(t, []amfcontext.TrackingAreaIdentity{ueCtx.TAI}, req.TAIList)
+	assert.Equal(t, uint8(1), req.PDUSessionID)
+	assert.True(t, paging.IsPaging(ueCtx.SUPI))
+
+	// Downlink data arriving while paging does not page again
+	_, err = paging.TransferN1N2Message(context.Background(), ueCtx.SUPI, &N1N2MessageTransferRequest{PDUSessionID: 1})
+	require.NoError(t, err)
+	assert.Len(t, sender.requests, 1)
+
+	require.NoError(t, paging.HandlePagingResponse(ueCtx.SUPI))
+	assert.True(t, ueCtx.IsConnected())
+	assert.False(t, paging.IsPaging(ueCtx.SUPI))
+}
+
+func TestTransferN1N2Message_ConnectedUEPassthrough(t *testing.T) {
+	paging, sender, ueCtx := newTestPagingService(t, amfcontext.ConnectionStateConnected)
+
+	resp, err := paging.TransferN1N2Message(context.Background(), ueCtx.SUPI, &N1N2MessageTransferRequest{PDUSessionID: 1})
+	require.NoError(t, err)
+	assert.Equal(t, N1N2TransferInitiated, resp.Cause)
+	assert.Empty(t, sender.requests)
+	assert.False(t, paging.IsPaging(ueCtx.SUPI))
+}
+
+func TestTransferN1N2Message_PagingExpires(t *testing.T) {
+	paging, _, ueCtx := newTestPagingService(t, amfcontext.ConnectionStateIdle)
+
+	_, err := paging.TransferN1N2Message(context.Background(), ueCtx.SUPI, &N1N2MessageTransferRequest{})
+	require.NoError(t, err)
+	require.True(t, paging.IsPaging(ueCtx.SUPI))
+
+	paging.pagingExpired(ueCtx.SUPI)
+	assert.False(t, paging.IsPaging(ueCtx.SUPI))
+	assert.False(t, ueCtx.IsConnected())
+}
+
+func TestTransferN1N2Message_UnknownUE(t *testing.T) {
+	paging, _, _ := newTestPagingService(t, amfcontext.ConnectionStateIdle)
+
+	_, err := paging.TransferN1N2Message(context.Background(), "imsi-001019999999999", &N1N2MessageTransferRequest{})
+	assert.ErrorIs(t, err, ErrUEContextNotFound)
+}
+
+func TestPagingService_SetTimers(t *testing.T) {
+	paging, _, _ := newTestPagingService(t, amfcontext.ConnectionStateIdle)
+	assert.Equal(t, defaultT3513, paging.t3513())
+
+	paging.SetTimers(config.TimersConfig{T3513: 10})
+	assert.Equal(t, 10*time.Second, paging.t3513())
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/your-org/5g-network/nf/amf/internal/client"
+	amfcontext "github.com/your-org/5g-network/nf/amf/internal/context"
+	"go.uber.org/zap"
+)
+
+// pduSessionServiceName is the SMF service PDU sessions are created with
+const pduSessionServiceName = "nsmf-pdusession"
+
+var (
+	// ErrPDUSessionExists is returned when the UE already has a PDU session
+	// with the requested ID
+	ErrPDUSessionExists = errors.New("PDU session already exists")
+	// ErrDNNNotAllowed is returned when the UE may not use the requested DNN
+	ErrDNNNotAllowed = errors.New("DNN not allowed")
+	// ErrSNSSAINotAllowed is returned when the requested S-NSSAI is not in
+	// the UE's allowed NSSAI
+	ErrSNSSAINotAllowed = errors.New("S-NSSAI not allowed")
+	// ErrNoSMFAvailable is returned when no SMF serves the requested DNN and
+	// S-NSSAI
+	ErrNoSMFAvailable = errors.New("no SMF available")
+	// ErrPDUSessionRejected is returned when the SMF did not establish the
+	// PDU session
+	ErrPDUSessionRejected = errors.New("PDU session establishment rejected")
+)
+
+// PDUSessionEstablishmentRequest represents a UE-requested PDU session
+// establishment (TS 23.502 4.3.2.2.1)
+type PDUSessionEstablishmentRequest struct {
+	PDUSessionID   uint8             `json:"pduSessionId"`
+	DNN            string            `json:"dnn"`
+	SNSSAI         amfcontext.SNSSAI `json:"snssai"`
+	PDUSessionType string            `json:"pduSessionType"`
+	SSCMode        string            `json:"sscMode,omitempty"`
+
+	// Downlink tunnel endpoint at the gNB
+	GNBN3Address    string `json:"gnbN3Address"`
+	GNBTEIDDownlink uint32 `json:"gnbTeidUplink"`
+}
+
+// SetNRFClient sets the client SMFs are discovered with
+func (s *RegistrationService) SetNRFClient(nrfClient *client.NRFClient) {
+	s.nrfClient = nrfClient
+}
+
+// EstablishPDUSession establishes a PDU session for a UE identified by SUPI
+// or 5G-GUTI: an SMF serving the DNN on the S-NSSAI is discovered through the
+// NRF, the SM context created there and the session stored in the UE context
+// with its user plane tunnel and UE address.
+func (s *RegistrationService) EstablishPDUSession(ctx context.Context, ueID string, req *PDUSessionEstablishmentRequest) (*amfcontext.PDUSessionInfo, error) {
+	ueCtx, exists := s.contextManager.LookupContext(ueID)
+	if !exists {
+		return nil, ErrUEContextNotFound
+	}
+	if !ueCtx.IsRegistered() {
+		return nil, ErrUENotRegistered
+	}
+	if !ueCtx.IsDNNAllowed(req.DNN) {
+		return nil, fmt.Errorf("%w: %s", ErrDNNNotAllowed, req.DNN)
+	}
+	if !isSNSSAIAllowed(ueCtx.AllowedNSSAI, req.SNSSAI) {
+		return nil, fmt.Errorf("%w: sst %d sd %q", ErrSNSSAINotAllowed, req.SNSSAI.SST, req.SNSSAI.SD)
+	}
+	if s.smfClient == nil {
+		return nil, fmt.Errorf("no SMF client configured")
+	}
+
+	// The session ID is reserved until the SMF answers, so concurrent
+	// requests for the same ID cannot both reach an SMF
+	reserved := ueCtx.ReservePDUSession(&amfcontext.PDUSessionInfo{
+		SessionID: req.PDUSessionID,
+		DNN:       req.DNN,
+		SNSSAI:    req.SNSSAI,
+		State:     amfcontext.PDUSessionStateEstablishing,
+		CreatedAt: time.Now(),
+	})
+	if !reserved {
+		return nil, fmt.Errorf("%w: %d", ErrPDUSessionExists, req.PDUSessionID)
+	}
+
+	session, err := s.createSMContext(ctx, ueCtx, req)
+	if err != nil {
+		ueCtx.RemovePDUSession(req.PDUSessionID)
+		return nil, err
+	}
+	ueCtx.AddPDUSession(session)
+
+	s.logger.Info("PDU session established",
+		zap.String("supi", ueCtx.SUPI),
+		zap.Uint8("pdu_session_id", session.SessionID),
+		zap.String("smf_instance_id", session.SMFInstanceID),
+		zap.String("ue_ip", session.UEIPv4Address),
+		zap.String("upf_n3_address", session.UPFN3Address),
+	)
+	return session, nil
+}
+
+// createSMContext creates the SM context of a PDU session at an SMF selected
+// for its DNN and S-NSSAI and returns the established session
+func (s *RegistrationService) createSMContext(ctx context.Context, ueCtx *amfcontext.UEContext, req *PDUSessionEstablishmentRequest) (*amfcontext.PDUSessionInfo, error) {
+	s.logger.Info("Establishing PDU session",
+		zap.String("supi", ueCtx.SUPI),
+		zap.Uint8("pdu_session_id", req.PDUSessionID),
+		zap.String("dnn", req.DNN),
+		zap.Uint8("sst", req.SNSSAI.SST),
+	)
+
+	snssai := client.SNSSAI{SST: req.SNSSAI.SST, SD: req.SNSSAI.SD}
+	smfInstanceID, smfURI, err := s.selectSMF(ctx, req.DNN, snssai)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.smfClient.CreateSMContext(ctx, smfURI, &client.CreateSMContextRequest{
+		SUPI:            ueCtx.SUPI,
+		PDUSessionID:    req.PDUSessionID,
+		DNN:             req.DNN,
+		SNSSAI:          snssai,
+		PDUSessionType:  req.PDUSessionType,
+		SSCMode:         req.SSCMode,
+		GNBN3Address:    req.GNBN3Address,
+		GNBTEIDDownlink: req.GNBTEIDDownlink,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrPDUSessionRejected, err)
+	}
+	if resp.Result != "SUCCESS" {
+		return nil, fmt.Errorf("%w: %s", ErrPDUSessionRejected, resp.Reason)
+	}
+
+	return &amfcontext.PDUSessionInfo{
+		SessionID: req.PDUSessionID,
+		DNN:       req.DNN,
+		SNSSAI:    req.SNSSAI,
+		SessionAMBR: amfcontext.SessionAMBR{
+			Uplink:   resp.SessionAMBR.Uplink,
+			Downlink: resp.SessionAMBR.Downlink,
+		},
+		SMFInstanceID: smfInstanceID,
+		SMFURI:        smfURI,
+		UEIPv4Address: resp.UEIPv4Address,
+		UEIPv6Prefix:  resp.UEIPv6Prefix,
+		UPFN3Address:  resp.UPFN3Address,
+		UPFTEID:       resp.UPFTEIDUplink,
+		State:         amfcontext.PDUSessionStateActive,
+		CreatedAt:     time.Now(),
+	}, nil
+}
+
+// selectSMF discovers the SMFs serving dnn on snssai and returns the
+// instance ID and API root of the one with the highest priority
+func (s *RegistrationService) selectSMF(ctx context.Context, dnn string, snssai client.SNSSAI) (string, string, error) {
+	if s.nrfClient == nil {
+		return "", "", fmt.Errorf("%w: NRF discovery disabled", ErrNoSMFAvailable)
+	}
+
+	profiles, err := s.nrfClient.DiscoverSMFs(ctx, dnn, snssai)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to discover SMFs: %w", err)
+	}
+
+	// A lower value is a higher priority (TS 29.510 6.1.6.2.2)
+	sort.SliceStable(profiles, func(i, j int) bool { return profiles[i].Priority < profiles[j].Priority })
+	for _, profile := range profiles {
+		for _, svc := range profile.NFServices {
+			if svc.ServiceName != pduSessionServiceName || len(svc.IPv4EndPoints) == 0 {
+				continue
+			}
+			scheme := svc.Scheme
+			if scheme == "" {
+				scheme = "http"
+			}
+			return profile.NFInstanceID, fmt.Sprintf("%s://%s", scheme, svc.IPv4EndPoints[0]), nil
+		}
+	}
+	return "", "", fmt.Errorf("%w: dnn %s sst %d", ErrNoSMFAvailable, dnn, snssai.SST)
+}
+
+// isSNSSAIAllowed checks if snssai is in the allowed NSSAI of a UE. A UE
+// without an allowed NSSAI is not restricted.
+func isSNSSAIAllowed(allowed []amfcontext.SNSSAI, snssai amfcontext.SNSSAI) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if a == snssai {
+			return true
+		}
+	}
+	return false
+}
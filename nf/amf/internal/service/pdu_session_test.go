@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/your-org/5g-network/common/nrfpool"
+	"github.com/your-org/5g-network/nf/amf/internal/client"
+	amfcontext "github.com/your-org/5g-network/nf/amf/internal/context"
+	"go.uber.org/zap"
+)
+
+// newFakeNRF returns an NRF discovering the SMF at smfURL and recording the
+// discovery queries it receives
+func newFakeNRF(t *testing.T, smfURL string, queries *[]string) *httptest.Server {
+	t.Helper()
+
+	endpoint := strings.TrimPrefix(smfURL, "http://")
+	nrf := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*queries = append(*queries, r.URL.RawQuery)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"nfInstances": []client.NFProfile{{
+				NFInstanceID: "smf-1",
+				NFType:       "SMF",
+				NFServices: []client.NFService{{
+					ServiceName:   "nsmf-pdusession",
+					Scheme:        "http",
+					IPv4EndPoints: []string{endpoint},
+				}},
+			}},
+		})
+	}))
+	t.Cleanup(nrf.Close)
+	return nrf
+}
+
+func newPDUSessionTestService(t *testing.T, nrfURL string) (*RegistrationService, *amfcontext.UEContextManager) {
+	t.Helper()
+
+	svc, contextManager := newTestService(newTestConfig())
+	endpoints, err := nrfpool.New(context.Background(), nrfURL, nrfpool.Options{}, zap.NewNop())
+	require.NoError(t, err)
+	svc.SetNRFClient(client.NewNRFClient(endpoints, nil, zap.NewNop()))
+	svc.SetSMFClient(client.NewSMFClient(time.Second, nil, zap.NewNop()))
+
+	ueCtx := contextManager.CreateContext("imsi-001010000000001")
+	ueCtx.UpdateRegistrationState(amfcontext.RegistrationStateRegistered)
+	ueCtx.AllowedNSSAI = []amfcontext.SNSSAI{{SST: 1, SD: "010203"}}
+	return svc, contextManager
+}
+
+func TestEstablishPDUSession(t *testing.T) {
+	var created []client.CreateSMContextRequest
+	smf := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/nsmf-pdusession/v1/sm-contexts", r.URL.Path)
+		var req client.CreateSMContextRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		created = append(created, req)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(client.CreateSMContextResponse{
+			Result:        "SUCCESS",
+			SUPI:          req.SUPI,
+			PDUSessionID:  req.PDUSessionID,
+			UEIPv4Address: "10.60.0.1",
+			SessionAMBR:   client.BitRate{Uplink: 100000000, Downlink: 200000000},
+			UPFN3Address:  "192.168.1.50",
+			UPFTEIDUplink: 0x1001,
+		})
+	}))
+	defer smf.Close()
+
+	var queries []string
+	nrf := newFakeNRF(t, smf.URL, &queries)
+	svc, contextManager := newPDUSessionTestService(t, nrf.URL)
+
+	session, err := svc.EstablishPDUSession(context.Background(), "imsi-001010000000001", &PDUSessionEstablishmentRequest{
+		PDUSessionID:    1,
+		DNN:             "internet",
+		SNSSAI:          amfcontext.SNSSAI{SST: 1, SD: "010203"},
+		PDUSessionType:  "IPV4",
+		GNBN3Address:    "192.168.1.10",
+		GNBTEIDDownlink: 0x2001,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, queries, 1)
+	assert.Contains(t, queries[0], "target-nf-type=SMF")
+	assert.Contains(t, queries[0], "dnn=internet")
+
+	require.Len(t, created, 1)
+	assert.Equal(t, client.CreateSMContextRequest{
+		SUPI:            "imsi-001010000000001",
+		PDUSessionID:    1,
+		DNN:             "internet",
+		SNSSAI:          client.SNSSAI{SST: 1, SD: "010203"},
+		PDUSessionType:  "IPV4",
+		GNBN3Address:    "192.168.1.10",
+		GNBTEIDDownlink: 0x2001,
+	}, created[0])
+
+	ueCtx, _ := contextManager.GetContext("imsi-001010000000001")
+	stored, exists := ueCtx.GetPDUSession(1)
+	require.True(t, exists)
+	assert.Same(t, session, stored)
+	assert.Equal(t, "smf-1", stored.SMFInstanceID)
+	assert.Equal(t, smf.URL, stored.SMFURI)
+	assert.Equal(t, "10.60.0.1", stored.UEIPv4Address)
+	assert.Equal(t, "192.168.1.50", stored.UPFN3Address)
+	assert.Equal(t, uint32(0x1001), stored.UPFTEID)
+	assert.Equal(t, uint64(200000000), stored.SessionAMBR.Downlink)
+	assert.Equal(t, amfcontext.PDUSessionStateActive, stored.State)
+
+	// The SMF is only asked once per session ID
+	_, err = svc.EstablishPDUSession(context.Background(), "imsi-001010000000001", &PDUSessionEstablishmentRequest{
+		PDUSessionID: 1,
+		DNN:          "internet",
+		SNSSAI:       amfcontext.SNSSAI{SST: 1, SD: "010203"},
+	})
+	assert.ErrorIs(t, err, ErrPDUSessionExists)
+	assert.Len(t, created, 1)
+}
+
+func TestEstablishPDUSession_Rejected(t *testing.T) {
+	smf := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"title":"no UE IP address available"}`, http.StatusBadRequest)
+	}))
+	defer smf.Close()
+
+	var queries []string
+	nrf := newFakeNRF(t, smf.URL, &queries)
+	svc, contextManager := newPDUSessionTestService(t, nrf.URL)
+
+	req := &PDUSessionEstablishmentRequest{
+		PDUSessionID: 1,
+		DNN:          "internet",
+		SNSSAI:       amfcontext.SNSSAI{SST: 1, SD: "010203"},
+	}
+	_, err := svc.EstablishPDUSession(context.Background(), "imsi-001010000000001", req)
+	assert.ErrorIs(t, err, ErrPDUSessionRejected)
+
+	ueCtx, _ := contextManager.GetContext("imsi-001010000000001")
+	assert.Empty(t, ueCtx.GetPDUSessions())
+
+	// Slices outside the allowed NSSAI never reach the NRF
+	req.SNSSAI = amfcontext.SNSSAI{SST: 2}
+	_, err = svc.EstablishPDUSession(context.Background(), "imsi-001010000000001", req)
+	assert.ErrorIs(t, err, ErrSNSSAINotAllowed)
+	assert.Len(t, queries, 1)
+
+	_, err = svc.EstablishPDUSession(context.Background(), "imsi-001010000000002", req)
+	assert.ErrorIs(t, err, ErrUEContextNotFound)
+}
+
+func TestEstablishPDUSession_ConcurrentSameID(t *testing.T) {
+	var created atomic.Int32
+	received := make(chan struct{})
+	release := make(chan struct{})
+	smf := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		created.Add(1)
+		close(received)
+		<-release
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(client.CreateSMContextResponse{Result: "SUCCESS", UEIPv4Address: "10.60.0.1"})
+	}))
+	defer smf.Close()
+
+	var queries []string
+	nrf := newFakeNRF(t, smf.URL, &queries)
+	svc, contextManager := newPDUSessionTestService(t, nrf.URL)
+
+	req := &PDUSessionEstablishmentRequest{
+		PDUSessionID: 1,
+		DNN:          "internet",
+		SNSSAI:       amfcontext.SNSSAI{SST: 1, SD: "010203"},
+	}
+	errs := make(chan error, 1)
+	go func() {
+		_, err := svc.EstablishPDUSession(context.Background(), "imsi-001010000000001", req)
+		errs <- err
+	}()
+
+	// The ID is taken while the first request waits for the SMF
+	<-received
+	ueCtx, _ := contextManager.GetContext("imsi-001010000000001")
+	pending, exists := ueCtx.GetPDUSession(1)
+	require.True(t, exists)
+	assert.Equal(t, amfcontext.PDUSessionStateEstablishing, pending.State)
+
+	_, err := svc.EstablishPDUSession(context.Background(), "imsi-001010000000001", req)
+	assert.ErrorIs(t, err, ErrPDUSessionExists)
+
+	close(release)
+	require.NoError(t, <-errs)
+	assert.Equal(t, int32(1), created.Load())
+
+	stored, exists := ueCtx.GetPDUSession(1)
+	require.True(t, exists)
+	assert.Equal(t, amfcontext.PDUSessionStateActive, stored.State)
+}
@@ -2,20 +2,39 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync/atomic"
 
+	"github.com/your-org/5g-network/common/crypto/suci"
 	"github.com/your-org/5g-network/nf/amf/internal/client"
 	"github.com/your-org/5g-network/nf/amf/internal/config"
 	amfcontext "github.com/your-org/5g-network/nf/amf/internal/context"
 	"go.uber.org/zap"
 )
 
+// defaultABBA is the ABBA parameter sent to UEs and bound into KAMF: no
+// security features are negotiated (TS 33.501 A.7.1)
+var defaultABBA = []byte{0x00, 0x00}
+
 // RegistrationService handles UE registration procedures
 type RegistrationService struct {
 	config         *config.Config
 	ausfClient     *client.AUSFClient
 	contextManager *amfcontext.UEContextManager
 	logger         *zap.Logger
+
+	// SMF selection, PDU session setup and UE context release towards the
+	// SMFs and the gNB, optional
+	nrfClient     *client.NRFClient
+	smfClient     *client.SMFClient
+	releaseSender UEContextReleaseSender
+
+	// Subscribed NSSAI lookup, optional
+	udmClient *client.UDMClient
+
+	// NAS timers, replaced on configuration reload
+	timers atomic.Pointer[config.TimersConfig]
 }
 
 // NewRegistrationService creates a new registration service
@@ -25,37 +44,55 @@ func NewRegistrationService(
 	contextManager *amfcontext.UEContextManager,
 	logger *zap.Logger,
 ) *RegistrationService {
-	return &RegistrationService{
+	s := &RegistrationService{
 		config:         cfg,
 		ausfClient:     ausfClient,
 		contextManager: contextManager,
 		logger:         logger,
 	}
+	s.SetTimers(cfg.Timers)
+	return s
+}
+
+// SetTimers sets the NAS timers sent to registering UEs
+func (s *RegistrationService) SetTimers(timers config.TimersConfig) {
+	s.timers.Store(&timers)
 }
 
 // RegistrationRequest represents a UE registration request
 type RegistrationRequest struct {
 	SUPI             string              `json:"supi"`
-	RegistrationType string              `json:"registrationType"` // "INITIAL", "MOBILITY", "PERIODIC"
+	PEI              string              `json:"pei,omitempty"`    // Used to identify an emergency UE without SUPI
+	GUTI             string              `json:"guti,omitempty"`   // 5G-GUTI from a previous registration, used when SUPI is absent
+	RegistrationType string              `json:"registrationType"` // "INITIAL", "MOBILITY", "PERIODIC", "EMERGENCY"
 	FollowOnRequest  bool                `json:"followOnRequest"`
 	RequestedNSSAI   []amfcontext.SNSSAI `json:"requestedNssai,omitempty"`
+
+	// Tracking area the UE registers from, as reported by the gNB; the
+	// configured TAC when absent
+	TAI *amfcontext.TrackingAreaIdentity `json:"tai,omitempty"`
 }
 
 // RegistrationResponse represents a registration response
 type RegistrationResponse struct {
-	Result          string                          `json:"result"` // "SUCCESS", "FAILURE"
-	SUPI            string                          `json:"supi"`
-	GUAMI           string                          `json:"guami"`
-	AllowedNSSAI    []amfcontext.SNSSAI             `json:"allowedNssai,omitempty"`
-	ConfiguredNSSAI []amfcontext.SNSSAI             `json:"configuredNssai,omitempty"`
-	TAI             amfcontext.TrackingAreaIdentity `json:"tai"`
-	T3512           int                             `json:"t3512"` // Periodic registration timer
-	Reason          string                          `json:"reason,omitempty"`
+	Result              string                          `json:"result"` // "SUCCESS", "FAILURE"
+	SUPI                string                          `json:"supi"`
+	GUAMI               string                          `json:"guami"`
+	GUTI                string                          `json:"guti,omitempty"`
+	AllowedNSSAI        []amfcontext.SNSSAI             `json:"allowedNssai,omitempty"`
+	ConfiguredNSSAI     []amfcontext.SNSSAI             `json:"configuredNssai,omitempty"`
+	TAI                 amfcontext.TrackingAreaIdentity `json:"tai"`
+	T3512               int                             `json:"t3512"` // Periodic registration timer
+	EmergencyRegistered bool                            `json:"emergencyRegistered,omitempty"`
+	EmergencyDNN        string                          `json:"emergencyDnn,omitempty"`
+	RejectedNSSAI       []RejectedSNSSAI                `json:"rejectedNssai,omitempty"`
+	PDUSessionStatus    []uint8                         `json:"pduSessionStatus,omitempty"` // Sessions kept by a registration update
+	Reason              string                          `json:"reason,omitempty"`
 }
 
 // AuthenticationRequest represents an authentication request
 type AuthenticationRequest struct {
-	SUPI string `json:"supi"`
+	SUPI string `json:"supi"` // SUPI or SUCI
 }
 
 // AuthenticationResponse represents an authentication response
@@ -85,8 +122,12 @@ func (s *RegistrationService) InitiateAuthentication(ctx context.Context, req *A
 		zap.String("supi", req.SUPI),
 	)
 
-	// Get or create UE context
-	ueCtx := s.contextManager.GetOrCreateContext(req.SUPI)
+	// A SUCI only resolves to the SUPI once the home network authenticates
+	// the UE, so its context is created on confirmation
+	var ueCtx *amfcontext.UEContext
+	if !suci.IsSUCI(req.SUPI) {
+		ueCtx = s.contextManager.GetOrCreateContext(req.SUPI)
+	}
 
 	// Build serving network name
 	servingNetworkName := fmt.Sprintf("5G:mnc%s.mcc%s.3gppnetwork.org",
@@ -104,7 +145,9 @@ func (s *RegistrationService) InitiateAuthentication(ctx context.Context, req *A
 	}
 
 	// Store authentication context temporarily
-	ueCtx.UpdateConnectionState(amfcontext.ConnectionStateConnected)
+	if ueCtx != nil {
+		ueCtx.UpdateConnectionState(amfcontext.ConnectionStateConnected)
+	}
 
 	s.logger.Info("Authentication initiated via AUSF",
 		zap.String("supi", req.SUPI),
@@ -144,10 +187,12 @@ func (s *RegistrationService) ConfirmAuthentication(ctx context.Context, req *Au
 		}, nil
 	}
 
-	// Get UE context
+	// Get UE context. A UE that identified itself with a SUCI has none until
+	// the AUSF returns its SUPI.
 	ueCtx, exists := s.contextManager.GetContext(ausfResp.SUPI)
 	if !exists {
-		return nil, fmt.Errorf("UE context not found for SUPI: %s", ausfResp.SUPI)
+		ueCtx = s.contextManager.GetOrCreateContext(ausfResp.SUPI)
+		ueCtx.UpdateConnectionState(amfcontext.ConnectionStateConnected)
 	}
 
 	// Establish security context with KSEAF from AUSF
@@ -157,6 +202,9 @@ func (s *RegistrationService) ConfirmAuthentication(ctx context.Context, req *Au
 		IntegrityAlgorithm:     s.config.Security.IntegrityOrder[0],
 		CipheringAlgorithm:     s.config.Security.CipheringOrder[0],
 	}
+	if err := secCtx.DeriveNASKeys(ausfResp.SUPI, defaultABBA); err != nil {
+		return nil, fmt.Errorf("failed to derive NAS keys: %w", err)
+	}
 	ueCtx.SetSecurityContext(secCtx)
 
 	s.logger.Info("Authentication successful",
@@ -171,15 +219,30 @@ func (s *RegistrationService) ConfirmAuthentication(ctx context.Context, req *Au
 	}, nil
 }
 
-// RegisterUE handles UE registration
+// RegisterUE handles UE registration. An initial registration allocates
+// the UE a new 5G-GUTI and allowed NSSAI. A registered UE's mobility update
+// moves it to a new tracking area and reallocates its 5G-GUTI, and its
+// periodic update only restarts its timers; both keep its PDU sessions. An
+// update from a UE not registered with this AMF is handled as an initial
+// registration.
 func (s *RegistrationService) RegisterUE(ctx context.Context, req *RegistrationRequest) (*RegistrationResponse, error) {
 	s.logger.Info("Processing UE registration",
 		zap.String("supi", req.SUPI),
 		zap.String("type", req.RegistrationType),
 	)
 
-	// Get UE context
-	ueCtx, exists := s.contextManager.GetContext(req.SUPI)
+	if req.RegistrationType == "EMERGENCY" {
+		return s.registerEmergency(req)
+	}
+
+	// Get UE context, by its previous 5G-GUTI when no SUPI is given
+	var ueCtx *amfcontext.UEContext
+	var exists bool
+	if req.SUPI == "" && req.GUTI != "" {
+		ueCtx, exists = s.contextManager.GetContextByGUTI(req.GUTI)
+	} else {
+		ueCtx, exists = s.contextManager.GetContext(req.SUPI)
+	}
 	if !exists {
 		return &RegistrationResponse{
 			Result: "FAILURE",
@@ -195,51 +258,289 @@ func (s *RegistrationService) RegisterUE(ctx context.Context, req *RegistrationR
 		}, nil
 	}
 
-	// Determine allowed NSSAI (simplified - accept all requested)
-	allowedNSSAI := req.RequestedNSSAI
-	if len(allowedNSSAI) == 0 {
-		// Use default from config
-		allowedNSSAI = make([]amfcontext.SNSSAI, len(s.config.AMF.SupportedSNSSAI))
-		for i, snssai := range s.config.AMF.SupportedSNSSAI {
-			allowedNSSAI[i] = amfcontext.SNSSAI{
-				SST: snssai.SST,
-				SD:  snssai.SD,
-			}
+	tai := s.registrationTAI(req)
+	if !s.servesTAI(tai) {
+		s.logger.Warn("Registration from a tracking area not served",
+			zap.String("supi", ueCtx.SUPI),
+			zap.String("tac", tai.TAC),
+		)
+		return &RegistrationResponse{
+			Result: "FAILURE",
+			Reason: "Tracking area not allowed",
+		}, nil
+	}
+
+	if ueCtx.IsRegistered() {
+		switch req.RegistrationType {
+		case "MOBILITY":
+			return s.updateMobility(ctx, ueCtx, req, tai)
+		case "PERIODIC":
+			return s.updatePeriodic(ueCtx), nil
 		}
 	}
 
+	allowedNSSAI, rejectedNSSAI, err := s.selectNSSAI(ctx, ueCtx.SUPI, req.RequestedNSSAI)
+	if err != nil {
+		if errors.Is(err, errSubscriptionNotFound) {
+			return &RegistrationResponse{
+				Result: "FAILURE",
+				Reason: "Subscription not found",
+			}, nil
+		}
+		return nil, err
+	}
+	if len(allowedNSSAI) == 0 {
+		return &RegistrationResponse{
+			Result:        "FAILURE",
+			RejectedNSSAI: rejectedNSSAI,
+			Reason:        "No network slices available",
+		}, nil
+	}
+
 	// Update UE context
-	ueCtx.AllowedNSSAI = allowedNSSAI
-	ueCtx.ConfiguredNSSAI = allowedNSSAI
-	ueCtx.GUAMI = s.config.GetGUAMI()
-	ueCtx.AMFRegionID = s.config.AMF.RegionID
-	ueCtx.AMFSetID = s.config.AMF.SetID
-	ueCtx.AMFPointer = s.config.AMF.Pointer
-	ueCtx.TAI = amfcontext.TrackingAreaIdentity{
-		PLMNID: amfcontext.PLMNID{
-			MCC: s.config.PLMN.MCC,
-			MNC: s.config.PLMN.MNC,
-		},
-		TAC: s.config.PLMN.TAC,
+	guti, err := s.completeRegistration(ueCtx, allowedNSSAI, tai, false)
+	if err != nil {
+		return nil, err
 	}
-	ueCtx.UpdateRegistrationState(amfcontext.RegistrationStateRegistered)
+	s.registerWithUDM(ctx, ueCtx, req.RegistrationType == "INITIAL")
 
 	s.logger.Info("UE registered successfully",
-		zap.String("supi", req.SUPI),
+		zap.String("supi", ueCtx.SUPI),
 		zap.String("guami", ueCtx.GUAMI),
+		zap.String("guti", guti),
 	)
 
 	return &RegistrationResponse{
 		Result:          "SUCCESS",
-		SUPI:            req.SUPI,
+		SUPI:            ueCtx.SUPI,
 		GUAMI:           ueCtx.GUAMI,
+		GUTI:            guti,
 		AllowedNSSAI:    allowedNSSAI,
 		ConfiguredNSSAI: allowedNSSAI,
+		RejectedNSSAI:   rejectedNSSAI,
+		TAI:             ueCtx.TAI,
+		T3512:           s.timers.Load().T3512,
+	}, nil
+}
+
+// updateMobility handles the mobility registration update of a registered
+// UE that entered a new tracking area: its location is updated and it is
+// allocated a new 5G-GUTI, keeping its PDU sessions
+func (s *RegistrationService) updateMobility(ctx context.Context, ueCtx *amfcontext.UEContext, req *RegistrationRequest, tai amfcontext.TrackingAreaIdentity) (*RegistrationResponse, error) {
+	// A UE requesting slices again gets them re-evaluated, keeping those it
+	// was allowed when none of them is
+	var rejectedNSSAI []RejectedSNSSAI
+	if len(req.RequestedNSSAI) > 0 {
+		var allowedNSSAI []amfcontext.SNSSAI
+		var err error
+		allowedNSSAI, rejectedNSSAI, err = s.selectNSSAI(ctx, ueCtx.SUPI, req.RequestedNSSAI)
+		if err != nil && !errors.Is(err, errSubscriptionNotFound) {
+			return nil, err
+		}
+		if len(allowedNSSAI) > 0 {
+			ueCtx.AllowedNSSAI = allowedNSSAI
+			ueCtx.ConfiguredNSSAI = allowedNSSAI
+		}
+	}
+
+	previous := ueCtx.TAI
+	ueCtx.UpdateLocation(tai)
+
+	guti, err := s.contextManager.AllocateGUTI(ueCtx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate 5G-GUTI: %w", err)
+	}
+	ueCtx.RefreshRegistration()
+
+	s.logger.Info("UE mobility registration updated",
+		zap.String("supi", ueCtx.SUPI),
+		zap.String("previous_tac", previous.TAC),
+		zap.String("tac", tai.TAC),
+		zap.String("guti", guti),
+	)
+
+	resp := s.updateResponse(ueCtx)
+	resp.GUTI = guti
+	resp.RejectedNSSAI = rejectedNSSAI
+	return resp, nil
+}
+
+// updatePeriodic handles the periodic registration update of a registered
+// UE, which only restarts its timers
+func (s *RegistrationService) updatePeriodic(ueCtx *amfcontext.UEContext) *RegistrationResponse {
+	ueCtx.RefreshRegistration()
+
+	s.logger.Debug("UE periodic registration updated",
+		zap.String("supi", ueCtx.SUPI),
+	)
+
+	return s.updateResponse(ueCtx)
+}
+
+// updateResponse answers a registration update with the UE context, listing
+// the PDU sessions it keeps
+func (s *RegistrationService) updateResponse(ueCtx *amfcontext.UEContext) *RegistrationResponse {
+	resp := &RegistrationResponse{
+		Result:          "SUCCESS",
+		SUPI:            ueCtx.SUPI,
+		GUAMI:           ueCtx.GUAMI,
+		GUTI:            ueCtx.GUTI,
+		AllowedNSSAI:    ueCtx.AllowedNSSAI,
+		ConfiguredNSSAI: ueCtx.ConfiguredNSSAI,
 		TAI:             ueCtx.TAI,
-		T3512:           s.config.Timers.T3512,
+		T3512:           s.timers.Load().T3512,
+	}
+	for _, session := range ueCtx.GetPDUSessions() {
+		resp.PDUSessionStatus = append(resp.PDUSessionStatus, session.SessionID)
+	}
+	return resp
+}
+
+// registrationTAI returns the tracking area a UE registers from
+func (s *RegistrationService) registrationTAI(req *RegistrationRequest) amfcontext.TrackingAreaIdentity {
+	if req.TAI != nil {
+		return *req.TAI
+	}
+	return amfcontext.TrackingAreaIdentity{
+		PLMNID: amfcontext.PLMNID{
+			MCC: s.config.PLMN.MCC,
+			MNC: s.config.PLMN.MNC,
+		},
+		TAC: s.config.PLMN.TAC,
+	}
+}
+
+// servesTAI reports whether a tracking area is in the PLMN and TAI list
+// served by the AMF
+func (s *RegistrationService) servesTAI(tai amfcontext.TrackingAreaIdentity) bool {
+	return tai.PLMNID.MCC == s.config.PLMN.MCC &&
+		tai.PLMNID.MNC == s.config.PLMN.MNC &&
+		s.config.PLMN.ServesTAC(tai.TAC)
+}
+
+// registerEmergency handles an emergency registration. A UE that has
+// already authenticated proves it by presenting the 5G-GUTI of its
+// context and keeps its security context; otherwise the registration is
+// only accepted when the AMF is configured to allow unauthenticated
+// emergency services, and the UE is identified by its PEI alone so that a
+// claimed SUPI never gives access to another UE's context. Either way the
+// UE is limited to the emergency DNN and slice.
+func (s *RegistrationService) registerEmergency(req *RegistrationRequest) (*RegistrationResponse, error) {
+	if req.SUPI == "" && req.PEI == "" && req.GUTI == "" {
+		return &RegistrationResponse{
+			Result: "FAILURE",
+			Reason: "SUPI or PEI required for emergency registration",
+		}, nil
+	}
+
+	var ueCtx *amfcontext.UEContext
+	if req.GUTI != "" {
+		if existing, exists := s.contextManager.GetContextByGUTI(req.GUTI); exists &&
+			existing.SecurityContext != nil && existing.SecurityContext.NASSecurityEstablished &&
+			(req.SUPI == "" || req.SUPI == existing.SUPI) {
+			ueCtx = existing
+		}
+	}
+	authenticated := ueCtx != nil
+
+	if !authenticated {
+		if !s.config.Emergency.AllowUnauthenticated {
+			return &RegistrationResponse{
+				Result: "FAILURE",
+				Reason: "Emergency registration without authentication not allowed",
+			}, nil
+		}
+		if req.PEI == "" {
+			return &RegistrationResponse{
+				Result: "FAILURE",
+				Reason: "PEI required for emergency registration without authentication",
+			}, nil
+		}
+		if existing, exists := s.contextManager.GetContext(req.PEI); exists && existing.SecurityContext != nil {
+			return &RegistrationResponse{
+				Result: "FAILURE",
+				Reason: "UE context in use",
+			}, nil
+		}
+	}
+
+	// Emergency services use the configured emergency slice, falling back
+	// to the first supported S-NSSAI
+	emergencySNSSAI := s.config.Emergency.SNSSAI
+	if emergencySNSSAI.SST == 0 {
+		if len(s.config.AMF.SupportedSNSSAI) == 0 {
+			return &RegistrationResponse{
+				Result: "FAILURE",
+				Reason: "No network slices available",
+			}, nil
+		}
+		emergencySNSSAI = s.config.AMF.SupportedSNSSAI[0]
+	}
+	allowedNSSAI := []amfcontext.SNSSAI{
+		{SST: emergencySNSSAI.SST, SD: emergencySNSSAI.SD},
+	}
+
+	supi := ""
+	if authenticated {
+		supi = ueCtx.SUPI
+	} else {
+		ueCtx = s.contextManager.GetOrCreateContext(req.PEI)
+	}
+	if req.PEI != "" {
+		ueCtx.PEI = req.PEI
+	}
+	guti, err := s.completeRegistration(ueCtx, allowedNSSAI, s.registrationTAI(req), true)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Warn("UE registered for emergency services",
+		zap.String("supi", supi),
+		zap.String("pei", req.PEI),
+		zap.Bool("authenticated", authenticated),
+		zap.String("dnn", s.config.Emergency.DNN),
+	)
+
+	return &RegistrationResponse{
+		Result:              "SUCCESS",
+		SUPI:                supi,
+		GUAMI:               ueCtx.GUAMI,
+		GUTI:                guti,
+		AllowedNSSAI:        allowedNSSAI,
+		ConfiguredNSSAI:     allowedNSSAI,
+		TAI:                 ueCtx.TAI,
+		T3512:               s.timers.Load().T3512,
+		EmergencyRegistered: true,
+		EmergencyDNN:        s.config.Emergency.DNN,
 	}, nil
 }
 
+// completeRegistration stores the serving AMF, allowed slices and location
+// in the UE context, allocates it a new 5G-GUTI and marks it registered.
+// An emergency registration limits the UE to the emergency DNN; any other
+// lifts that limit.
+func (s *RegistrationService) completeRegistration(ueCtx *amfcontext.UEContext, allowedNSSAI []amfcontext.SNSSAI, tai amfcontext.TrackingAreaIdentity, emergency bool) (string, error) {
+	ueCtx.AllowedNSSAI = allowedNSSAI
+	ueCtx.ConfiguredNSSAI = allowedNSSAI
+	ueCtx.GUAMI = s.config.GetGUAMI()
+	ueCtx.AMFRegionID = s.config.AMF.RegionID
+	ueCtx.AMFSetID = s.config.AMF.SetID
+	ueCtx.AMFPointer = s.config.AMF.Pointer
+	ueCtx.UpdateLocation(tai)
+	if emergency {
+		ueCtx.SetEmergencyRegistered(s.config.Emergency.DNN)
+	} else {
+		ueCtx.ClearEmergencyRegistered()
+	}
+
+	guti, err := s.contextManager.AllocateGUTI(ueCtx)
+	if err != nil {
+		return "", fmt.Errorf("failed to allocate 5G-GUTI: %w", err)
+	}
+	ueCtx.UpdateRegistrationState(amfcontext.RegistrationStateRegistered)
+	return guti, nil
+}
+
 // DeregisterUE handles UE deregistration
 func (s *RegistrationService) DeregisterUE(ctx context.Context, supi string) error {
 	s.logger.Info("Processing UE deregistration",
@@ -257,6 +558,7 @@ func (s *RegistrationService) DeregisterUE(ctx context.Context, supi string) err
 
 	// Remove context
 	s.contextManager.RemoveContext(supi)
+	s.deregisterFromUDM(ctx, supi)
 
 	s.logger.Info("UE deregistered",
 		zap.String("supi", supi),
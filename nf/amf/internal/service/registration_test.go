@@ -0,0 +1,572 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/your-org/5g-network/nf/amf/internal/client"
+	"github.com/your-org/5g-network/nf/amf/internal/config"
+	amfcontext "github.com/your-org/5g-network/nf/amf/internal/context"
+	"go.uber.org/zap"
+)
+
+func newTestConfig() *config.Config {
+	return &config.Config{
+		PLMN: config.PLMNConfig{MCC: "001", MNC: "01", TAC: "000001"},
+		AMF: config.AMFConfig{
+			RegionID: 128,
+			SetID:    1,
+			Pointer:  1,
+			SupportedSNSSAI: []config.SNSSAI{
+				{SST: 1, SD: "000001"},
+				{SST: 2, SD: "000002"},
+			},
+		},
+		Security: config.SecurityConfig{
+			IntegrityOrder: []string{"NIA2"},
+			CipheringOrder: []string{"NEA2"},
+		},
+		Emergency: config.EmergencyConfig{
+			AllowUnauthenticated: true,
+			DNN:                  "sos",
+		},
+		Timers: config.TimersConfig{T3512: 3240},
+	}
+}
+
+func newTestService(cfg *config.Config) (*RegistrationService, *amfcontext.UEContextManager) {
+	logger, _ := zap.NewDevelopment()
+	contextManager := amfcontext.NewUEContextManager()
+	return NewRegistrationService(cfg, nil, contextManager, logger), contextManager
+}
+
+func TestRegisterUE_RequiresAuthentication(t *testing.T) {
+	svc, _ := newTestService(newTestConfig())
+
+	resp, err := svc.RegisterUE(context.Background(), &RegistrationRequest{
+		SUPI:             "imsi-001010000000001",
+		RegistrationType: "INITIAL",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "FAILURE", resp.Result)
+}
+
+func TestRegisterUE_EmergencyWithoutAuthentication(t *testing.T) {
+	svc, contextManager := newTestService(newTestConfig())
+
+	resp, err := svc.RegisterUE(context.Background(), &RegistrationRequest{
+		PEI:              "imei-490154203237518",
+		RegistrationType: "EMERGENCY",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "SUCCESS", resp.Result)
+	assert.True(t, resp.EmergencyRegistered)
+	assert.Equal(t, "sos", resp.EmergencyDNN)
+	assert.Equal(t, []amfcontext.SNSSAI{{SST: 1, SD: "000001"}}, resp.AllowedNSSAI)
+
+	ueCtx, exists := contextManager.GetContext("imei-490154203237518")
+	require.True(t, exists)
+	assert.True(t, ueCtx.IsRegistered())
+	assert.True(t, ueCtx.IsEmergencyRegistered())
+	assert.Nil(t, ueCtx.SecurityContext)
+
+	// Limited to the emergency DNN
+	assert.True(t, ueCtx.IsDNNAllowed("sos"))
+	assert.False(t, ueCtx.IsDNNAllowed("internet"))
+}
+
+func TestRegisterUE_EmergencyDisabled(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Emergency.AllowUnauthenticated = false
+	svc, contextManager := newTestService(cfg)
+
+	resp, err := svc.RegisterUE(context.Background(), &RegistrationRequest{
+		SUPI:             "imsi-001010000000001",
+		RegistrationType: "EMERGENCY",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "FAILURE", resp.Result)
+
+	_, exists := contextManager.GetContext("imsi-001010000000001")
+	assert.False(t, exists)
+}
+
+func TestRegisterUE_EmergencyAuthenticatedUE(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.Emergency.AllowUnauthenticated = false
+	svc, contextManager := newTestService(cfg)
+
+	ueCtx := contextManager.CreateContext("imsi-001010000000001")
+	ueCtx.SetSecurityContext(&amfcontext.SecurityContext{NASSecurityEstablished: true})
+	resp, err := svc.RegisterUE(context.Background(), &RegistrationRequest{
+		SUPI:             "imsi-001010000000001",
+		RegistrationType: "INITIAL",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "SUCCESS", resp.Result)
+
+	// The UE proves it authenticated with the 5G-GUTI it was allocated
+	resp, err = svc.RegisterUE(context.Background(), &RegistrationRequest{
+		GUTI:             resp.GUTI,
+		RegistrationType: "EMERGENCY",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "SUCCESS", resp.Result)
+	assert.Equal(t, "imsi-001010000000001", resp.SUPI)
+	assert.True(t, ueCtx.IsEmergencyRegistered())
+	assert.NotNil(t, ueCtx.SecurityContext)
+
+	// A normal registration lifts the emergency limit
+	resp, err = svc.RegisterUE(context.Background(), &RegistrationRequest{
+		SUPI:             "imsi-001010000000001",
+		RegistrationType: "INITIAL",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "SUCCESS", resp.Result)
+	assert.False(t, ueCtx.IsEmergencyRegistered())
+	assert.True(t, ueCtx.IsDNNAllowed("internet"))
+}
+
+func TestRegisterUE_EmergencyCannotTakeOverContext(t *testing.T) {
+	svc, contextManager := newTestService(newTestConfig())
+
+	ueCtx := contextManager.CreateContext("imsi-001010000000001")
+	ueCtx.SetSecurityContext(&amfcontext.SecurityContext{NASSecurityEstablished: true})
+	resp, err := svc.RegisterUE(context.Background(), &RegistrationRequest{
+		SUPI:             "imsi-001010000000001",
+		RegistrationType: "INITIAL",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "SUCCESS", resp.Result)
+	allowed := ueCtx.AllowedNSSAI
+	guti := ueCtx.GUTI
+
+	// Claiming the SUPI without a PEI is rejected
+	resp, err = svc.RegisterUE(context.Background(), &RegistrationRequest{
+		SUPI:             "imsi-001010000000001",
+		RegistrationType: "EMERGENCY",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "FAILURE", resp.Result)
+
+	// With a PEI the UE is registered on its own context
+	resp, err = svc.RegisterUE(context.Background(), &RegistrationRequest{
+		SUPI:             "imsi-001010000000001",
+		PEI:              "imei-490154203237518",
+		RegistrationType: "EMERGENCY",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "SUCCESS", resp.Result)
+	assert.Empty(t, resp.SUPI)
+
+	assert.False(t, ueCtx.IsEmergencyRegistered())
+	assert.Equal(t, allowed, ueCtx.AllowedNSSAI)
+	assert.Equal(t, guti, ueCtx.GUTI)
+	_, exists := contextManager.GetContext("imei-490154203237518")
+	assert.True(t, exists)
+}
+
+func TestRegisterUE_EmergencyRequiresIdentity(t *testing.T) {
+	svc, _ := newTestService(newTestConfig())
+
+	resp, err := svc.RegisterUE(context.Background(), &RegistrationRequest{
+		RegistrationType: "EMERGENCY",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "FAILURE", resp.Result)
+}
+
+func TestRegisterUE_EmergencyWithoutSlice(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.AMF.SupportedSNSSAI = nil
+	svc, contextManager := newTestService(cfg)
+
+	resp, err := svc.RegisterUE(context.Background(), &RegistrationRequest{
+		PEI:              "imei-490154203237518",
+		RegistrationType: "EMERGENCY",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "FAILURE", resp.Result)
+	assert.Equal(t, "No network slices available", resp.Reason)
+
+	_, exists := contextManager.GetContext("imei-490154203237518")
+	assert.False(t, exists)
+}
+
+func TestAuthentication_SUCICreatesContextForSUPI(t *testing.T) {
+	const (
+		testSUCI = "suci-0-001-01-0000-0-0-0000000001"
+		testSUPI = "imsi-001010000000001"
+	)
+
+	// Fake AUSF that resolves the SUCI on confirmation
+	ausf := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/5g-aka-confirmation") {
+			json.NewEncoder(w).Encode(client.AuthConfirmationResponse{
+				AuthResult: "AUTHENTICATION_SUCCESS",
+				SUPI:       testSUPI,
+				KSEAF:      testKSEAF,
+			})
+			return
+		}
+
+		var req client.UEAuthenticationRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, testSUCI, req.SUPI)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(client.UEAuthenticationResponse{
+			AuthType:      "5G_AKA",
+			AuthCtxID:     "ctx-1",
+			Var5gAuthData: &client.Var5gAuthData{RAND: "00", AUTN: "00"},
+		})
+	}))
+	defer ausf.Close()
+
+	logger, _ := zap.NewDevelopment()
+	contextManager := amfcontext.NewUEContextManager()
+	svc := NewRegistrationService(newTestConfig(), client.NewAUSFClient(ausf.URL, time.Second, nil, logger), contextManager, logger)
+	ctx := context.Background()
+
+	_, err := svc.InitiateAuthentication(ctx, &AuthenticationRequest{SUPI: testSUCI})
+	require.NoError(t, err)
+	_, exists := contextManager.GetContext(testSUCI)
+	assert.False(t, exists, "no UE context is keyed by the SUCI")
+
+	resp, err := svc.ConfirmAuthentication(ctx, &AuthenticationConfirmRequest{AuthCtxID: "ctx-1", RES: "00"})
+	require.NoError(t, err)
+	assert.Equal(t, "SUCCESS", resp.Result)
+	assert.Equal(t, testSUPI, resp.SUPI)
+
+	ueCtx, exists := contextManager.GetContext(testSUPI)
+	require.True(t, exists)
+	assert.NotNil(t, ueCtx.SecurityContext)
+}
+
+func TestConfirmAuthentication_DerivesNASKeys(t *testing.T) {
+	// KSEAF and the keys derived from it for imsi-001010000000001, ABBA
+	// 0x0000, 128-NEA2 and 128-NIA2
+	const (
+		kseaf   = "8dff166c02edd5b177950d50cdd3fe93756cc53951856a95cb5ee9aabd35e220"
+		kamf    = "daae216bc3dc9c6e0db9e56d2b744ea247d67eed51fdf2411847d056ec45a666"
+		knasEnc = "d4c73a6303aa6b0cae734c0518134f1e"
+		knasInt = "06c661bdcb505f1690bea90685d939f5"
+	)
+
+	ausfKSEAF := kseaf
+	ausf := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(client.AuthConfirmationResponse{
+			AuthResult: "AUTHENTICATION_SUCCESS",
+			SUPI:       "imsi-001010000000001",
+			KSEAF:      ausfKSEAF,
+		})
+	}))
+	defer ausf.Close()
+
+	logger, _ := zap.NewDevelopment()
+	contextManager := amfcontext.NewUEContextManager()
+	svc := NewRegistrationService(newTestConfig(), client.NewAUSFClient(ausf.URL, time.Second, nil, logger), contextManager, logger)
+	ctx := context.Background()
+
+	confirm := func() *amfcontext.SecurityContext {
+		_, err := svc.ConfirmAuthentication(ctx, &AuthenticationConfirmRequest{AuthCtxID: "ctx-1", RES: "00"})
+		require.NoError(t, err)
+		ueCtx, exists := contextManager.GetContext("imsi-001010000000001")
+		require.True(t, exists)
+		require.NotNil(t, ueCtx.SecurityContext)
+		return ueCtx.SecurityContext
+	}
+
+	secCtx := confirm()
+	assert.Equal(t, kamf, secCtx.KAMF)
+	assert.Equal(t, knasEnc, secCtx.KNASenc)
+	assert.Equal(t, knasInt, secCtx.KNASint)
+	for _, key := range []string{secCtx.KAMF, secCtx.KNASenc, secCtx.KNASint} {
+		_, err := hex.DecodeString(key)
+		assert.NoError(t, err)
+	}
+	assert.Len(t, secCtx.KAMF, 64, "256-bit KAMF")
+	assert.Len(t, secCtx.KNASenc, 32, "128-bit KNASenc")
+	assert.Len(t, secCtx.KNASint, 32, "128-bit KNASint")
+
+	// A new authentication with the same inputs derives the same keys
+	again := confirm()
+	assert.NotSame(t, secCtx, again)
+	assert.Equal(t, secCtx.KAMF, again.KAMF)
+	assert.Equal(t, secCtx.KNASenc, again.KNASenc)
+	assert.Equal(t, secCtx.KNASint, again.KNASint)
+
+	// A KSEAF that is not 256 bits cannot be used
+	ausfKSEAF = "00"
+	_, err := svc.ConfirmAuthentication(ctx, &AuthenticationConfirmRequest{AuthCtxID: "ctx-1", RES: "00"})
+	assert.Error(t, err)
+}
+
+func TestRegisterUE_AllocatesNewGUTI(t *testing.T) {
+	svc, contextManager := newTestService(newTestConfig())
+
+	ueCtx := contextManager.CreateContext("imsi-001010000000001")
+	ueCtx.SetSecurityContext(&amfcontext.SecurityContext{NASSecurityEstablished: true})
+
+	first, err := svc.RegisterUE(context.Background(), &RegistrationRequest{
+		SUPI:             "imsi-001010000000001",
+		RegistrationType: "INITIAL",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "SUCCESS", first.Result)
+	assert.True(t, amfcontext.IsGUTI(first.GUTI))
+
+	// A mobility registration identifies the UE by its GUTI only
+	second, err := svc.RegisterUE(context.Background(), &RegistrationRequest{
+		GUTI:             first.GUTI,
+		RegistrationType: "MOBILITY",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "SUCCESS", second.Result)
+	assert.Equal(t, "imsi-001010000000001", second.SUPI)
+	assert.NotEqual(t, first.GUTI, second.GUTI)
+
+	_, exists := contextManager.GetContextByGUTI(first.GUTI)
+	assert.False(t, exists)
+}
+
+// registeredUE registers an authenticated UE with a PDU session
+func registeredUE(t *testing.T, svc *RegistrationService, contextManager *amfcontext.UEContextManager) (*amfcontext.UEContext, *RegistrationResponse) {
+	t.Helper()
+
+	ueCtx := contextManager.CreateContext("imsi-001010000000001")
+	ueCtx.SetSecurityContext(&amfcontext.SecurityContext{NASSecurityEstablished: true})
+	resp, err := svc.RegisterUE(context.Background(), &RegistrationRequest{
+		SUPI:             "imsi-001010000000001",
+		RegistrationType: "INITIAL",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "SUCCESS", resp.Result)
+
+	ueCtx.AddPDUSession(&amfcontext.PDUSessionInfo{SessionID: 5, DNN: "internet", State: amfcontext.PDUSessionStateActive})
+	return ueCtx, resp
+}
+
+func TestRegisterUE_MobilityUpdateKeepsSessions(t *testing.T) {
+	cfg := newTestConfig()
+	cfg.PLMN.ServedTACs = []string{"000002"}
+	svc, contextManager := newTestService(cfg)
+	ueCtx, initial := registeredUE(t, svc, contextManager)
+	assert.Equal(t, "000001", initial.TAI.TAC)
+
+	newTAI := amfcontext.TrackingAreaIdentity{PLMNID: amfcontext.PLMNID{MCC: "001", MNC: "01"}, TAC: "000002"}
+	resp, err := svc.RegisterUE(context.Background(), &RegistrationRequest{
+		GUTI:             initial.GUTI,
+		RegistrationType: "MOBILITY",
+		TAI:              &newTAI,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "SUCCESS", resp.Result)
+	assert.Equal(t, newTAI, resp.TAI)
+	assert.Equal(t, newTAI, ueCtx.TAI)
+	assert.NotEqual(t, initial.GUTI, resp.GUTI)
+	assert.Equal(t, initial.AllowedNSSAI, resp.AllowedNSSAI)
+
+	_, kept := ueCtx.GetPDUSession(5)
+	assert.True(t, kept)
+	assert.Equal(t, []uint8{5}, resp.PDUSessionStatus)
+
+	// A tracking area outside the served list is rejected
+	otherTAI := amfcontext.TrackingAreaIdentity{PLMNID: amfcontext.PLMNID{MCC: "001", MNC: "01"}, TAC: "000009"}
+	resp, err = svc.RegisterUE(context.Background(), &RegistrationRequest{
+		SUPI:             "imsi-001010000000001",
+		RegistrationType: "MOBILITY",
+		TAI:              &otherTAI,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "FAILURE", resp.Result)
+	assert.Equal(t, newTAI, ueCtx.TAI)
+}
+
+func TestRegisterUE_PeriodicUpdateRefreshesTimers(t *testing.T) {
+	svc, contextManager := newTestService(newTestConfig())
+	ueCtx, initial := registeredUE(t, svc, contextManager)
+	registeredAt := ueCtx.RegisteredAt
+	lastActivity := ueCtx.LastActivityAt
+
+	time.Sleep(time.Millisecond)
+	resp, err := svc.RegisterUE(context.Background(), &RegistrationRequest{
+		SUPI:             "imsi-001010000000001",
+		RegistrationType: "PERIODIC",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "SUCCESS", resp.Result)
+	assert.Equal(t, initial.GUTI, resp.GUTI)
+	assert.Equal(t, initial.TAI, resp.TAI)
+	assert.Equal(t, 3240, resp.T3512)
+	assert.Equal(t, []uint8{5}, resp.PDUSessionStatus)
+
+	assert.Equal(t, initial.GUTI, ueCtx.GUTI)
+	assert.Equal(t, registeredAt, ueCtx.RegisteredAt)
+	assert.True(t, ueCtx.LastActivityAt.After(lastActivity))
+}
+
+// newUDMClient returns a client of a fake UDM serving the AM data of
+// imsi-001010000000001
+func newUDMClient(t *testing.T, amData client.AccessAndMobilitySubscriptionData) *client.UDMClient {
+	t.Helper()
+
+	udm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/nudm-sdm/v1/supi/imsi-001010000000001/am-data" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(amData)
+	}))
+	t.Cleanup(udm.Close)
+
+	logger, _ := zap.NewDevelopment()
+	return client.NewUDMClient(udm.URL, time.Second, nil, logger)
+}
+
+func TestRegisterUE_NSSAIIntersectsSubscribedAndSupported(t *testing.T) {
+	svc, contextManager := newTestService(newTestConfig())
+	svc.SetUDMClient(newUDMClient(t, client.AccessAndMobilitySubscriptionData{
+		NSSAI: &client.NSSAI{
+			DefaultSingleNSSAIs: []client.SNSSAI{{SST: 1, SD: "000001"}},
+			SingleNSSAIs:        []client.SNSSAI{{SST: 3, SD: "000003"}},
+		},
+	}))
+
+	ueCtx := contextManager.CreateContext("imsi-001010000000001")
+	ueCtx.SetSecurityContext(&amfcontext.SecurityContext{NASSecurityEstablished: true})
+
+	// Slice 1 is subscribed and supported, 2 supported only, 3 subscribed only
+	resp, err := svc.RegisterUE(context.Background(), &RegistrationRequest{
+		SUPI:             "imsi-001010000000001",
+		RegistrationType: "INITIAL",
+		RequestedNSSAI: []amfcontext.SNSSAI{
+			{SST: 1, SD: "000001"},
+			{SST: 2, SD: "000002"},
+			{SST: 3, SD: "000003"},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "SUCCESS", resp.Result)
+	assert.Equal(t, []amfcontext.SNSSAI{{SST: 1, SD: "000001"}}, resp.AllowedNSSAI)
+	assert.Equal(t, []RejectedSNSSAI{
+		{SNSSAI: amfcontext.SNSSAI{SST: 2, SD: "000002"}, Cause: RejectCauseNotAvailableInPLMN},
+		{SNSSAI: amfcontext.SNSSAI{SST: 3, SD: "000003"}, Cause: RejectCauseNotAvailableInRegArea},
+	}, resp.RejectedNSSAI)
+	assert.Equal(t, resp.AllowedNSSAI, ueCtx.AllowedNSSAI)
+
+	// Without a requested NSSAI the default subscribed slices are allowed
+	resp, err = svc.RegisterUE(context.Background(), &RegistrationRequest{
+		SUPI:             "imsi-001010000000001",
+		RegistrationType: "INITIAL",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "SUCCESS", resp.Result)
+	assert.Equal(t, []amfcontext.SNSSAI{{SST: 1, SD: "000001"}}, resp.AllowedNSSAI)
+	assert.Empty(t, resp.RejectedNSSAI)
+
+	// No requested slice allowed
+	resp, err = svc.RegisterUE(context.Background(), &RegistrationRequest{
+		SUPI:             "imsi-001010000000001",
+		RegistrationType: "INITIAL",
+		RequestedNSSAI:   []amfcontext.SNSSAI{{SST: 2, SD: "000002"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "FAILURE", resp.Result)
+	assert.Len(t, resp.RejectedNSSAI, 1)
+}
+
+func TestRegisterUE_UnknownSubscriber(t *testing.T) {
+	svc, contextManager := newTestService(newTestConfig())
+	svc.SetUDMClient(newUDMClient(t, client.AccessAndMobilitySubscriptionData{}))
+
+	ueCtx := contextManager.CreateContext("imsi-001010000000002")
+	ueCtx.SetSecurityContext(&amfcontext.SecurityContext{NASSecurityEstablished: true})
+
+	resp, err := svc.RegisterUE(context.Background(), &RegistrationRequest{
+		SUPI:             "imsi-001010000000002",
+		RegistrationType: "INITIAL",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "FAILURE", resp.Result)
+	assert.False(t, ueCtx.IsRegistered())
+}
+
+func TestRegisterUE_RegistersWithUDM(t *testing.T) {
+	var registrations []client.AMF3GPPAccessRegistration
+	var deregistrations int
+	failRegistration := false
+	udm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/nudm-sdm/v1/supi/imsi-001010000000001/am-data":
+			json.NewEncoder(w).Encode(client.AccessAndMobilitySubscriptionData{
+				NSSAI: &client.NSSAI{DefaultSingleNSSAIs: []client.SNSSAI{{SST: 1, SD: "000001"}}},
+			})
+		case r.URL.Path != "/nudm-uecm/v1/supi/imsi-001010000000001/registrations/amf-3gpp-access":
+			http.NotFound(w, r)
+		case r.Method == http.MethodPut && failRegistration:
+			w.WriteHeader(http.StatusInternalServerError)
+		case r.Method == http.MethodPut:
+			var registration client.AMF3GPPAccessRegistration
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&registration))
+			registrations = append(registrations, registration)
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodDelete:
+			deregistrations++
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer udm.Close()
+
+	cfg := newTestConfig()
+	cfg.NF.InstanceID = "amf-instance-1"
+	svc, contextManager := newTestService(cfg)
+	logger, _ := zap.NewDevelopment()
+	svc.SetUDMClient(client.NewUDMClient(udm.URL, time.Second, nil, logger))
+	ctx := context.Background()
+
+	ueCtx := contextManager.CreateContext("imsi-001010000000001")
+	ueCtx.SetSecurityContext(&amfcontext.SecurityContext{NASSecurityEstablished: true})
+	resp, err := svc.RegisterUE(ctx, &RegistrationRequest{
+		SUPI:             "imsi-001010000000001",
+		RegistrationType: "INITIAL",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "SUCCESS", resp.Result)
+
+	require.Len(t, registrations, 1)
+	assert.Equal(t, "amf-instance-1", registrations[0].AMFInstanceID)
+	assert.Equal(t, client.ServingGUAMI{
+		PLMNID:      client.PLMNID{MCC: "001", MNC: "01"},
+		AMFRegionID: "80",
+		AMFSetID:    "001",
+		AMFPointer:  "01",
+	}, registrations[0].GUAMI)
+	assert.Equal(t, "NR", registrations[0].RATType)
+	assert.True(t, registrations[0].InitialRegistrationInd)
+
+	require.NoError(t, svc.DeregisterUE(ctx, "imsi-001010000000001"))
+	assert.Equal(t, 1, deregistrations)
+
+	// A UDM failure does not fail the registration
+	failRegistration = true
+	ueCtx = contextManager.CreateContext("imsi-001010000000001")
+	ueCtx.SetSecurityContext(&amfcontext.SecurityContext{NASSecurityEstablished: true})
+	resp, err = svc.RegisterUE(ctx, &RegistrationRequest{
+		SUPI:             "imsi-001010000000001",
+		RegistrationType: "INITIAL",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "SUCCESS", resp.Result)
+	assert.True(t, ueCtx.IsRegistered())
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/your-org/5g-network/nf/amf/internal/client"
+	amfcontext "github.com/your-org/5g-network/nf/amf/internal/context"
+	"go.uber.org/zap"
+)
+
+// ReleaseCauseNormal is the cause of an AMF-initiated UE context release
+// without a specific reason (TS 38.413 9.3.1.2, NAS cause normal-release)
+const ReleaseCauseNormal = "normal-release"
+
+// ErrUEContextReleaseIncomplete is returned when a UE context was released
+// locally but its SMFs or gNB could not all be told
+var ErrUEContextReleaseIncomplete = errors.New("UE context release incomplete")
+
+// UEContextReleaseCommand is the NGAP UE Context Release Command releasing
+// the NG connection of a UE (TS 38.413 9.2.2.5)
+type UEContextReleaseCommand struct {
+	SUPI  string `json:"supi"`
+	Cause string `json:"cause"`
+}
+
+// UEContextReleaseSender delivers UE Context Release Commands to the gNB
+// serving a UE
+type UEContextReleaseSender interface {
+	SendUEContextRelease(ctx context.Context, cmd *UEContextReleaseCommand) error
+}
+
+// SetSMFClient sets the client creating and releasing the SM contexts of
+// PDU sessions
+func (s *RegistrationService) SetSMFClient(smfClient *client.SMFClient) {
+	s.smfClient = smfClient
+}
+
+// SetUEContextReleaseSender sets where UE Context Release Commands are sent.
+// Without a sender they are only logged.
+func (s *RegistrationService) SetUEContextReleaseSender(sender UEContextReleaseSender) {
+	s.releaseSender = sender
+}
+
+// ReleaseUEContext releases the context of a UE identified by SUPI or
+// 5G-GUTI on the network side (TS 23.502 4.2.6): its PDU sessions at their
+// SMFs, then its NG connection at the gNB, then the local context. A step
+// that fails does not stop the others; the local context is always removed
+// and the failures are returned together, wrapped in
+// ErrUEContextReleaseIncomplete.
+func (s *RegistrationService) ReleaseUEContext(ctx context.Context, ueID, cause string) error {
+	ueCtx, exists := s.contextManager.LookupContext(ueID)
+	if !exists {
+		return ErrUEContextNotFound
+	}
+
+	s.logger.Info("Releasing UE context",
+		zap.String("supi", ueCtx.SUPI),
+		zap.String("cause", cause),
+	)
+
+	var errs []error
+	for _, session := range ueCtx.GetPDUSessions() {
+		if err := s.releaseSMContext(ctx, ueCtx.SUPI, session, cause); err != nil {
+			s.logger.Error("Failed to release PDU session at SMF",
+				zap.String("supi", ueCtx.SUPI),
+				zap.Uint8("pdu_session_id", session.SessionID),
+				zap.String("smf_uri", session.SMFURI),
+				zap.Error(err),
+			)
+			errs = append(errs, fmt.Errorf("PDU session %d: %w", session.SessionID, err))
+		}
+		ueCtx.RemovePDUSession(session.SessionID)
+	}
+
+	// Only a connected UE has an NG connection to release
+	if ueCtx.IsConnected() {
+		if err := s.sendUEContextRelease(ctx, &UEContextReleaseCommand{SUPI: ueCtx.SUPI, Cause: cause}); err != nil {
+			s.logger.Error("Failed to send UE Context Release Command",
+				zap.String("supi", ueCtx.SUPI),
+				zap.Error(err),
+			)
+			errs = append(errs, fmt.Errorf("N2 release: %w", err))
+		}
+	}
+
+	ueCtx.UpdateRegistrationState(amfcontext.RegistrationStateDeregistered)
+	ueCtx.UpdateConnectionState(amfcontext.ConnectionStateIdle)
+	s.contextManager.RemoveContext(ueCtx.SUPI)
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrUEContextReleaseIncomplete, errors.Join(errs...))
+	}
+
+	s.logger.Info("UE context released",
+		zap.String("supi", ueCtx.SUPI),
+	)
+	return nil
+}
+
+// releaseSMContext releases a PDU session of the UE at its SMF
+func (s *RegistrationService) releaseSMContext(ctx context.Context, supi string, session *amfcontext.PDUSessionInfo, cause string) error {
+	if s.smfClient == nil {
+		return fmt.Errorf("no SMF client configured")
+	}
+	if session.SMFURI == "" {
+		return fmt.Errorf("serving SMF unknown")
+	}
+
+	smContextRef := session.SMContextRef
+	if smContextRef == "" {
+		smContextRef = fmt.Sprintf("%s-%d", supi, session.SessionID)
+	}
+	return s.smfClient.ReleaseSMContext(ctx, session.SMFURI, smContextRef, &client.ReleaseSMContextRequest{
+		SUPI:         supi,
+		PDUSessionID: session.SessionID,
+		Cause:        cause,
+	})
+}
+
+// sendUEContextRelease sends a UE Context Release Command to the gNB
+func (s *RegistrationService) sendUEContextRelease(ctx context.Context, cmd *UEContextReleaseCommand) error {
+	s.logger.Info("Sending UE Context Release Command",
+		zap.String("supi", cmd.SUPI),
+		zap.String("cause", cmd.Cause),
+	)
+
+	if s.releaseSender == nil {
+		return nil
+	}
+	return s.releaseSender.SendUEContextRelease(ctx, cmd)
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/your-org/5g-network/nf/amf/internal/client"
+	amfcontext "github.com/your-org/5g-network/nf/amf/internal/context"
+	"go.uber.org/zap"
+)
+
+// fakeSMF records the SM context releases it receives
+type fakeSMF struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	paths    []string
+	releases []client.ReleaseSMContextRequest
+}
+
+func newFakeSMF(t *testing.T, status int) *fakeSMF {
+	t.Helper()
+
+	smf := &fakeSMF{}
+	smf.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req client.ReleaseSMContextRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		smf.mu.Lock()
+		smf.paths = append(smf.paths, r.Method+" "+r.URL.Path)
+		smf.releases = append(smf.releases, req)
+		smf.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(smf.Close)
+	return smf
+}
+
+type recordingReleaseSender struct {
+	commands []*UEContextReleaseCommand
+	err      error
+}
+
+func (r *recordingReleaseSender) SendUEContextRelease(ctx context.Context, cmd *UEContextReleaseCommand) error {
+	r.commands = append(r.commands, cmd)
+	return r.err
+}
+
+// newReleaseTestUE returns a connected UE with PDU session 1 on smf1 and
+// PDU session 2 on smf2
+func newReleaseTestUE(t *testing.T, smf1, smf2 string) (*RegistrationService, *amfcontext.UEContextManager, *recordingReleaseSender) {
+	t.Helper()
+
+	svc, contextManager := newTestService(newTestConfig())
+	svc.SetSMFClient(client.NewSMFClient(time.Second, nil, zap.NewNop()))
+	sender := &recordingReleaseSender{}
+	svc.SetUEContextReleaseSender(sender)
+
+	ueCtx := contextManager.CreateContext("imsi-001010000000001")
+	ueCtx.UpdateRegistrationState(amfcontext.RegistrationStateRegistered)
+	ueCtx.UpdateConnectionState(amfcontext.ConnectionStateConnected)
+	ueCtx.AddPDUSession(&amfcontext.PDUSessionInfo{SessionID: 1, DNN: "internet", SMFURI: smf1, SMContextRef: "ctx-1"})
+	ueCtx.AddPDUSession(&amfcontext.PDUSessionInfo{SessionID: 2, DNN: "ims", SMFURI: smf2})
+	return svc, contextManager, sender
+}
+
+func TestReleaseUEContext_ReleasesSessionsAtEachSMF(t *testing.T) {
+	smf1 := newFakeSMF(t, http.StatusOK)
+	smf2 := newFakeSMF(t, http.StatusNoContent)
+	svc, contextManager, sender := newReleaseTestUE(t, smf1.URL, smf2.URL)
+
+	err := svc.ReleaseUEContext(context.Background(), "imsi-001010000000001", ReleaseCauseNormal)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"POST /nsmf-pdusession/v1/sm-contexts/ctx-1/release"}, smf1.paths)
+	assert.Equal(t, []client.ReleaseSMContextRequest{
+		{SUPI: "imsi-001010000000001", PDUSessionID: 1, Cause: ReleaseCauseNormal},
+	}, smf1.releases)
+	assert.Equal(t, []string{"POST /nsmf-pdusession/v1/sm-contexts/imsi-001010000000001-2/release"}, smf2.paths)
+	assert.Equal(t, uint8(2), smf2.releases[0].PDUSessionID)
+
+	require.Len(t, sender.commands, 1)
+	assert.Equal(t, &UEContextReleaseCommand{SUPI: "imsi-001010000000001", Cause: ReleaseCauseNormal}, sender.commands[0])
+
+	_, exists := contextManager.GetContext("imsi-001010000000001")
+	assert.False(t, exists)
+}
+
+func TestReleaseUEContext_ContinuesPastFailures(t *testing.T) {
+	failing := newFakeSMF(t, http.StatusInternalServerError)
+	smf2 := newFakeSMF(t, http.StatusOK)
+	svc, contextManager, sender := newReleaseTestUE(t, failing.URL, smf2.URL)
+	sender.err = errors.New("gNB unreachable")
+
+	err := svc.ReleaseUEContext(context.Background(), "imsi-001010000000001", ReleaseCauseNormal)
+	require.ErrorIs(t, err, ErrUEContextReleaseIncomplete)
+	assert.ErrorContains(t, err, "PDU session 1")
+	assert.ErrorContains(t, err, "gNB unreachable")
+
+	// The other SMF, the gNB and the local context are still released
+	assert.Len(t, failing.releases, 1)
+	assert.Len(t, smf2.releases, 1)
+	assert.Len(t, sender.commands, 1)
+	_, exists := contextManager.GetContext("imsi-001010000000001")
+	assert.False(t, exists)
+}
+
+func TestReleaseUEContext_IdleUE(t *testing.T) {
+	svc, contextManager := newTestService(newTestConfig())
+	sender := &recordingReleaseSender{}
+	svc.SetUEContextReleaseSender(sender)
+
+	ueCtx := contextManager.CreateContext("imsi-001010000000001")
+	ueCtx.UpdateRegistrationState(amfcontext.RegistrationStateRegistered)
+
+	require.NoError(t, svc.ReleaseUEContext(context.Background(), "imsi-001010000000001", ReleaseCauseNormal))
+	assert.Empty(t, sender.commands, "an idle UE has no NG connection")
+
+	err := svc.ReleaseUEContext(context.Background(), "imsi-001010000000001", ReleaseCauseNormal)
+	assert.ErrorIs(t, err, ErrUEContextNotFound)
+}
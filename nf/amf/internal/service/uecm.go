@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/your-org/5g-network/nf/amf/internal/client"
+	amfcontext "github.com/your-org/5g-network/nf/amf/internal/context"
+	"go.uber.org/zap"
+)
+
+// SetUDMClient sets the client retrieving the subscribed S-NSSAIs of UEs
+// and registering the AMF as their serving AMF. Without it, requested slices
+// are only checked against those supported and the UDM is not told where
+// UEs are registered.
+func (s *RegistrationService) SetUDMClient(udmClient *client.UDMClient) {
+	s.udmClient = udmClient
+}
+
+// registerWithUDM registers the AMF as serving a UE over 3GPP access
+// (Nudm_UECM_Registration, TS 23.502 4.2.2.2.2 step 14). A failure is
+// logged; the UE stays registered with the AMF.
+func (s *RegistrationService) registerWithUDM(ctx context.Context, ueCtx *amfcontext.UEContext, initial bool) {
+	if s.udmClient == nil {
+		return
+	}
+
+	registration := &client.AMF3GPPAccessRegistration{
+		AMFInstanceID: s.config.NF.InstanceID,
+		GUAMI: client.ServingGUAMI{
+			PLMNID:      client.PLMNID{MCC: s.config.PLMN.MCC, MNC: s.config.PLMN.MNC},
+			AMFRegionID: fmt.Sprintf("%02X", s.config.AMF.RegionID),
+			AMFSetID:    fmt.Sprintf("%03X", s.config.AMF.SetID),
+			AMFPointer:  fmt.Sprintf("%02X", s.config.AMF.Pointer),
+		},
+		RATType:                "NR",
+		PEI:                    ueCtx.PEI,
+		InitialRegistrationInd: initial,
+		RegistrationTime:       time.Now().UTC(),
+	}
+	if err := s.udmClient.RegisterAMF3GPPAccess(ctx, ueCtx.SUPI, registration); err != nil {
+		s.logger.Error("Failed to register with UDM",
+			zap.String("supi", ueCtx.SUPI),
+			zap.Error(err),
+		)
+		return
+	}
+
+	s.logger.Debug("Registered with UDM as serving AMF",
+		zap.String("supi", ueCtx.SUPI),
+	)
+}
+
+// deregisterFromUDM removes the AMF registration of a deregistered UE from
+// the UDM. A failure is logged; the UE stays deregistered.
+func (s *RegistrationService) deregisterFromUDM(ctx context.Context, supi string) {
+	if s.udmClient == nil {
+		return
+	}
+
+	if err := s.udmClient.DeregisterAMF3GPPAccess(ctx, supi); err != nil {
+		s.logger.Error("Failed to deregister from UDM",
+			zap.String("supi", supi),
+			zap.Error(err),
+		)
+	}
+}
@@ -4,12 +4,20 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/your-org/5g-network/common/breaker"
+	"github.com/your-org/5g-network/common/buildinfo"
+	"github.com/your-org/5g-network/common/correlation"
 	"github.com/your-org/5g-network/common/metrics"
+	"github.com/your-org/5g-network/common/nrfpool"
+	"github.com/your-org/5g-network/common/oauth"
+	"github.com/your-org/5g-network/common/reload"
+	"github.com/your-org/5g-network/common/retry"
 	"github.com/your-org/5g-network/nf/ausf/internal/client"
 	"github.com/your-org/5g-network/nf/ausf/internal/config"
 	"github.com/your-org/5g-network/nf/ausf/internal/server"
@@ -21,6 +29,7 @@ import (
 var (
 	Version   = "dev"
 	BuildTime = "unknown"
+	GitCommit = "unknown"
 )
 
 func main() {
@@ -28,8 +37,9 @@ func main() {
 	configPath := flag.String("config", "nf/ausf/config/ausf.yaml", "path to configuration file")
 	flag.Parse()
 
-	// Create logger
-	logger := createLogger("info")
+	// Create logger, at the configured level once loaded
+	level := zap.NewAtomicLevel()
+	logger := createLogger(level)
 	defer logger.Sync()
 
 	logger.Info("Starting AUSF (Authentication Server Function)",
@@ -50,35 +60,66 @@ func main() {
 		zap.String("nrf_url", cfg.NRF.URL),
 	)
 
-	// Create UDM client
-	udmClient := client.NewUDMClient(cfg.UDM.URL, cfg.UDM.Timeout, logger)
+	// Reload the log level and NRF heartbeat interval on SIGHUP
+	reloader := reload.New(*configPath, cfg, config.Load, []string{
+		"observability.logging.level",
+		"nrf.heartbeat_interval",
+	}, logger)
+	if err := reloader.SetLogLevel(level, func(c *config.Config) string { return c.Observability.Logging.Level }); err != nil {
+		logger.Fatal("Invalid configuration", zap.Error(err))
+	}
+
+	// HTTP transport of the SBI clients, with mutual TLS when configured,
+	// retries of transient failures and the correlation ID of the request served
+	tlsTransport, err := cfg.SBI.ClientTLS.Transport()
+	if err != nil {
+		logger.Fatal("Invalid client TLS configuration", zap.Error(err))
+	}
+	sbiTransport := correlation.NewTransport(retry.NewTransport(tlsTransport, cfg.SBI.ClientRetry, logger), logger)
+
+	// Access tokens for requests to other NFs, from the primary NRF
+	tokens, err := oauth.NewTokenSource(cfg.SBI.ClientOAuth2, cfg.NRF.URL, cfg.NF.InstanceID, "AUSF", sbiTransport, logger)
+	if err != nil {
+		logger.Fatal("Invalid client OAuth2 configuration", zap.Error(err))
+	}
+
+	// Create UDM client, failing fast while the UDM is unhealthy when a
+	// circuit breaker is configured. The breaker sees the outcome of each
+	// request after its retries.
+	var udmTransport http.RoundTripper = sbiTransport
+	if cfg.UDM.CircuitBreaker.Enabled {
+		udmTransport = breaker.NewTransport(sbiTransport, breaker.New("UDM", cfg.UDM.CircuitBreaker, logger))
+	}
+	udmClient := client.NewUDMClient(cfg.UDM.URL, cfg.UDM.Timeout, tokens.Transport(udmTransport, "UDM"), logger)
 	logger.Info("UDM client initialized")
 
 	// Create authentication service
-	authService := service.NewAuthenticationService(udmClient, logger)
+	authService := service.NewAuthenticationService(udmClient, cfg.UDM.AuthEvents, logger)
+	authService.SetLimits(cfg.Auth.Limits)
+	authService.SetContextTTL(cfg.Auth.ContextTTL)
+	defer authService.Stop()
 	logger.Info("Authentication service initialized")
 
-	// Start cleanup goroutine for expired contexts
-	go func() {
-		ticker := time.NewTicker(1 * time.Minute)
-		defer ticker.Stop()
-
-		for range ticker.C {
-			authService.CleanupExpiredContexts()
-		}
-	}()
-
 	// Create HTTP server
 	srv := server.NewServer(cfg, authService, logger)
+	srv.AddDependency("UDM", udmClient.Ping)
+	srv.SetBuildInfo(buildinfo.New("AUSF", Version, GitCommit, BuildTime,
+		"nausf-auth"))
 
 	// Create context
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Initialize metrics server (AUSF uses port 9097 to avoid conflict with Alertmanager on 9093)
-	metricsServer := metrics.NewMetricsServer(9097, logger)
+	// Sweep expired authentication contexts
+	go authService.RunSweeper(ctx, cfg.Auth.CleanupInterval)
+
+	// Initialize metrics server, on the port assigned to the AUSF
+	metricsRegistry, err := metrics.NewRegistry("AUSF", cfg.NF.InstanceID)
+	if err != nil {
+		logger.Fatal("Failed to create metrics registry", zap.Error(err))
+	}
+	metricsServer := metrics.NewMetricsServer(metricsRegistry, logger)
 	go func() {
-		logger.Info("Starting metrics server on :9097")
 		if err := metricsServer.Start(); err != nil {
 			logger.Error("Metrics server error", zap.Error(err))
 		}
@@ -91,7 +132,11 @@ func main() {
 
 	// Register with NRF if enabled
 	if cfg.NRF.Enabled {
-		nrfClient := client.NewNRFClient(cfg.NRF.URL, logger)
+		endpoints, err := nrfpool.New(ctx, cfg.NRF.URL, cfg.NRF.Options, logger)
+		if err != nil {
+			logger.Fatal("Failed to resolve NRF endpoints", zap.Error(err))
+		}
+		nrfClient := client.NewNRFClient(endpoints, sbiTransport, logger)
 
 		profile := &client.NFProfile{
 			NFInstanceID: cfg.NF.InstanceID,
@@ -116,7 +161,7 @@ func main() {
 
 			// Start heartbeat goroutine
 			go func() {
-				ticker := time.NewTicker(cfg.NRF.HeartbeatInterval)
+				ticker := reloader.Ticker(func(c *config.Config) time.Duration { return c.NRF.HeartbeatInterval })
 				defer ticker.Stop()
 
 				for {
@@ -145,6 +190,8 @@ func main() {
 		}
 	}
 
+	go reloader.Watch(ctx)
+
 	// Start HTTP server in a goroutine
 	serverErrors := make(chan error, 1)
 	go func() {
@@ -178,24 +225,10 @@ func main() {
 	}
 }
 
-// createLogger creates a structured logger
-func createLogger(level string) *zap.Logger {
-	var zapLevel zapcore.Level
-	switch level {
-	case "debug":
-		zapLevel = zapcore.DebugLevel
-	case "info":
-		zapLevel = zapcore.InfoLevel
-	case "warn":
-		zapLevel = zapcore.WarnLevel
-	case "error":
-		zapLevel = zapcore.ErrorLevel
-	default:
-		zapLevel = zapcore.InfoLevel
-	}
-
+// createLogger creates a structured logger whose level can change at runtime
+func createLogger(level zap.AtomicLevel) *zap.Logger {
 	config := zap.NewProductionConfig()
-	config.Level = zap.NewAtomicLevelAt(zapLevel)
+	config.Level = level
 	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 
 	logger, err := config.Build()
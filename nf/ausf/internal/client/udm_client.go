@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -12,6 +13,10 @@ import (
 	"go.uber.org/zap"
 )
 
+// ErrAuthRejected is returned when UDM rejects the request, e.g. because the
+// MAC-S of a resynchronization request failed verification
+var ErrAuthRejected = errors.New("authentication rejected by UDM")
+
 // UDMClient handles communication with UDM
 type UDMClient struct {
 	baseURL string
@@ -20,11 +25,12 @@ type UDMClient struct {
 }
 
 // NewUDMClient creates a new UDM client
-func NewUDMClient(baseURL string, timeout time.Duration, logger *zap.Logger) *UDMClient {
+func NewUDMClient(baseURL string, timeout time.Duration, transport http.RoundTripper, logger *zap.Logger) *UDMClient {
 	return &UDMClient{
 		baseURL: baseURL,
 		client: &http.Client{
-			Timeout: timeout,
+			Timeout:   timeout,
+			Transport: transport,
 		},
 		logger: logger,
 	}
@@ -40,18 +46,19 @@ type AuthenticationInfo struct {
 	} `json:"resynchronizationInfo,omitempty"`
 }
 
-// AuthenticationVector represents a 5G AKA authentication vector
+// AuthenticationVector represents a 5G HE AKA authentication vector
 type AuthenticationVector struct {
-	RAND  string `json:"rand"`  // Random challenge (hex)
-	AUTN  string `json:"autn"`  // Authentication token (hex)
-	HXRES string `json:"hxres"` // Expected response (hex)
-	KAUSF string `json:"kausf"` // Key for AUSF (hex)
+	RAND     string `json:"rand"`     // Random challenge (hex)
+	AUTN     string `json:"autn"`     // Authentication token (hex)
+	XRESStar string `json:"xresStar"` // Expected response XRES* (hex)
+	KAUSF    string `json:"kausf"`    // Key for AUSF (hex)
 }
 
 // AuthenticationInfoResult represents the authentication response from UDM
 type AuthenticationInfoResult struct {
 	AuthType             string                `json:"authType"` // "5G_AKA" or "EAP_AKA_PRIME"
 	AuthenticationVector *AuthenticationVector `json:"authenticationVector,omitempty"`
+	SUPI                 string                `json:"supi,omitempty"` // De-concealed SUPI when a SUCI was sent
 }
 
 // GenerateAuthData requests UDM to generate authentication data
@@ -83,6 +90,10 @@ func (c *UDMClient) GenerateAuthData(ctx context.Context, authInfo *Authenticati
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusForbidden {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%w: %s", ErrAuthRejected, string(respBody))
+	}
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
 		return nil, fmt.Errorf("UDM returned status %d: %s", resp.StatusCode, string(respBody))
@@ -131,3 +142,23 @@ func (c *UDMClient) ConfirmAuth(ctx context.Context, supi string, authEvent map[
 	c.logger.Debug("Confirmed auth with UDM", zap.String("supi", supi))
 	return nil
 }
+
+// Ping checks that the UDM is reachable and ready to serve requests
+func (c *UDMClient) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/ready", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("UDM returned status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
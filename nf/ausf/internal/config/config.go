@@ -5,6 +5,11 @@ import (
 	"os"
 	"time"
 
+	"github.com/your-org/5g-network/common/breaker"
+	"github.com/your-org/5g-network/common/nrfpool"
+	"github.com/your-org/5g-network/common/oauth"
+	"github.com/your-org/5g-network/common/retry"
+	"github.com/your-org/5g-network/common/tlsconfig"
 	"gopkg.in/yaml.v3"
 )
 
@@ -28,10 +33,14 @@ type NFConfig struct {
 
 // SBIConfig contains Service-Based Interface configuration
 type SBIConfig struct {
-	Scheme      string    `yaml:"scheme"`
-	BindAddress string    `yaml:"bind_address"`
-	Port        int       `yaml:"port"`
-	TLS         TLSConfig `yaml:"tls"`
+	Scheme       string                 `yaml:"scheme"`
+	BindAddress  string                 `yaml:"bind_address"`
+	Port         int                    `yaml:"port"`
+	TLS          TLSConfig              `yaml:"tls"`
+	ClientTLS    tlsconfig.ClientConfig `yaml:"client_tls"`   // TLS towards the SBIs of other NFs
+	ClientRetry  retry.Policy           `yaml:"client_retry"` // Retries of idempotent requests to other NFs
+	OAuth2       oauth.Config           `yaml:"oauth2"`
+	ClientOAuth2 oauth.ClientConfig     `yaml:"client_oauth2"` // Access tokens for requests to other NFs
 }
 
 // TLSConfig contains TLS configuration
@@ -39,6 +48,8 @@ type TLSConfig struct {
 	Enabled  bool   `yaml:"enabled"`
 	CertFile string `yaml:"cert_file"`
 	KeyFile  string `yaml:"key_file"`
+
+	tlsconfig.Options `yaml:",inline"`
 }
 
 // NRFConfig contains NRF client configuration
@@ -46,12 +57,27 @@ type NRFConfig struct {
 	URL               string        `yaml:"url"`
 	Enabled           bool          `yaml:"enabled"`
 	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"`
+
+	// Secondary NRFs and DNS SRV discovery for failover
+	nrfpool.Options `yaml:",inline"`
 }
 
 // UDMConfig contains UDM client configuration
 type UDMConfig struct {
-	URL     string        `yaml:"url"`
-	Timeout time.Duration `yaml:"timeout"`
+	URL            string          `yaml:"url"`
+	Timeout        time.Duration   `yaml:"timeout"`
+	AuthEvents     AuthEventConfig `yaml:"auth_events"`
+	CircuitBreaker breaker.Config  `yaml:"circuit_breaker"` // Fails fast while the UDM is unhealthy
+}
+
+// AuthEventConfig controls delivery of authentication results to the UDM
+// (Nudm_UEAU_ResultConfirmation). Results are sent in the background so the
+// UE's confirmation never waits on the UDM.
+type AuthEventConfig struct {
+	Timeout       time.Duration `yaml:"timeout"`        // Deadline per delivery attempt
+	MaxAttempts   int           `yaml:"max_attempts"`   // Attempts before the event is dropped
+	RetryInterval time.Duration `yaml:"retry_interval"` // Initial backoff, doubled per attempt
+	QueueSize     int           `yaml:"queue_size"`     // Pending events before new ones are dropped
 }
 
 // PLMNConfig contains PLMN configuration
@@ -62,8 +88,40 @@ type PLMNConfig struct {
 
 // AuthConfig contains authentication configuration
 type AuthConfig struct {
-	Methods       []string `yaml:"methods"`        // Supported auth methods
-	DefaultMethod string   `yaml:"default_method"` // Default method
+	Methods       []string        `yaml:"methods"`        // Supported auth methods
+	DefaultMethod string          `yaml:"default_method"` // Default method
+	Limits        AuthLimitConfig `yaml:"limits"`
+
+	// Lifetime of an unconfirmed authentication context, and how often
+	// expired ones are swept
+	ContextTTL      time.Duration `yaml:"context_ttl"`
+	CleanupInterval time.Duration `yaml:"cleanup_interval"`
+}
+
+// AuthLimitConfig bounds authentication initiation, so a flood of requests
+// can exhaust neither the pending authentication contexts nor UDM vector
+// generation. A zero rate disables its limit.
+type AuthLimitConfig struct {
+	PerSUPIRate  float64 `yaml:"per_supi_rate"`  // Initiations per second for one SUPI
+	PerSUPIBurst int     `yaml:"per_supi_burst"` // Initiations for one SUPI allowed at once
+	GlobalRate   float64 `yaml:"global_rate"`    // Initiations per second for all UEs
+	GlobalBurst  int     `yaml:"global_burst"`   // Initiations allowed at once
+	MaxContexts  int     `yaml:"max_contexts"`   // Pending contexts before the oldest are evicted
+}
+
+// Validate checks that every enabled limit allows at least one request
+func (c *AuthLimitConfig) Validate() error {
+	switch {
+	case c.PerSUPIRate < 0 || c.GlobalRate < 0:
+		return fmt.Errorf("rates must not be negative")
+	case c.PerSUPIRate > 0 && c.PerSUPIBurst < 1:
+		return fmt.Errorf("per_supi_burst must be at least 1 when per_supi_rate is set")
+	case c.GlobalRate > 0 && c.GlobalBurst < 1:
+		return fmt.Errorf("global_burst must be at least 1 when global_rate is set")
+	case c.MaxContexts < 0:
+		return fmt.Errorf("max_contexts must not be negative")
+	}
+	return nil
 }
 
 // ObservabilityConfig contains observability settings
@@ -126,14 +184,22 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid sbi.port: %d", c.SBI.Port)
 	}
 
-	if c.NRF.Enabled && c.NRF.URL == "" {
-		return fmt.Errorf("nrf.url is required when nrf.enabled is true")
+	if c.NRF.Enabled && c.NRF.URL == "" && c.NRF.SRVName == "" {
+		return fmt.Errorf("nrf.url or nrf.srv_name is required when nrf.enabled is true")
+	}
+
+	if err := c.NRF.Validate(); err != nil {
+		return fmt.Errorf("invalid nrf: %w", err)
 	}
 
 	if c.UDM.URL == "" {
 		return fmt.Errorf("udm.url is required")
 	}
 
+	if err := c.UDM.CircuitBreaker.Validate(); err != nil {
+		return fmt.Errorf("invalid udm.circuit_breaker: %w", err)
+	}
+
 	if c.PLMN.MCC == "" || c.PLMN.MNC == "" {
 		return fmt.Errorf("plmn.mcc and plmn.mnc are required")
 	}
@@ -142,6 +208,36 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("at least one authentication method must be configured")
 	}
 
+	if c.Auth.ContextTTL < 0 || c.Auth.CleanupInterval < 0 {
+		return fmt.Errorf("auth.context_ttl and auth.cleanup_interval must not be negative")
+	}
+
+	if err := c.Auth.Limits.Validate(); err != nil {
+		return fmt.Errorf("invalid auth.limits: %w", err)
+	}
+
+	if c.SBI.TLS.Enabled {
+		if err := c.SBI.TLS.Validate(); err != nil {
+			return fmt.Errorf("invalid sbi.tls: %w", err)
+		}
+	}
+
+	if err := c.SBI.ClientTLS.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.client_tls: %w", err)
+	}
+
+	if err := c.SBI.ClientRetry.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.client_retry: %w", err)
+	}
+
+	if err := c.SBI.OAuth2.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.oauth2: %w", err)
+	}
+
+	if err := c.SBI.ClientOAuth2.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.client_oauth2: %w", err)
+	}
+
 	return nil
 }
 
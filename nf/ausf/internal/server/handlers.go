@@ -2,11 +2,14 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/your-org/5g-network/common/metrics"
+	"github.com/your-org/5g-network/common/sbi"
+	"github.com/your-org/5g-network/nf/ausf/internal/client"
 	"github.com/your-org/5g-network/nf/ausf/internal/service"
 	"go.uber.org/zap"
 )
@@ -18,7 +21,7 @@ func (s *AUSFServer) handleUEAuthenticationRequest(w http.ResponseWriter, r *htt
 
 	var req service.UEAuthenticationRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		s.respondError(w, http.StatusBadRequest, "invalid request body", err)
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
 		metrics.RecordAuthenticationAttempt("5G-AKA", "failed")
 		return
 	}
@@ -30,7 +33,15 @@ func (s *AUSFServer) handleUEAuthenticationRequest(w http.ResponseWriter, r *htt
 
 	response, err := s.authService.UEAuthenticationCtx(r.Context(), &req)
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, "failed to initiate authentication", err)
+		status, cause := http.StatusInternalServerError, sbi.CauseSystemFailure
+		switch {
+		case errors.Is(err, client.ErrAuthRejected):
+			status, cause = http.StatusForbidden, sbi.CauseAuthenticationRejected
+		case errors.Is(err, service.ErrRateLimited):
+			status, cause = http.StatusTooManyRequests, sbi.CauseNFCongestionRisk
+			w.Header().Set("Retry-After", "1")
+		}
+		s.respondProblem(w, status, cause, "failed to initiate authentication", err)
 		metrics.RecordAuthenticationAttempt("5G-AKA", "failed")
 		return
 	}
@@ -55,7 +66,7 @@ func (s *AUSFServer) handleConfirm5gAkaAuth(w http.ResponseWriter, r *http.Reque
 
 	var confirmData service.ConfirmationData
 	if err := json.NewDecoder(r.Body).Decode(&confirmData); err != nil {
-		s.respondError(w, http.StatusBadRequest, "invalid request body", err)
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
 		return
 	}
 
@@ -65,7 +76,7 @@ func (s *AUSFServer) handleConfirm5gAkaAuth(w http.ResponseWriter, r *http.Reque
 
 	response, err := s.authService.Confirm5gAkaAuth(r.Context(), authCtxID, &confirmData)
 	if err != nil {
-		s.respondError(w, http.StatusNotFound, "failed to confirm authentication", err)
+		s.respondProblem(w, http.StatusNotFound, sbi.CauseContextNotFound, "failed to confirm authentication", err)
 		return
 	}
 
@@ -95,18 +106,19 @@ func (s *AUSFServer) handleGetAuthContext(w http.ResponseWriter, r *http.Request
 
 	authCtx, err := s.authService.GetAuthContext(authCtxID)
 	if err != nil {
-		s.respondError(w, http.StatusNotFound, "authentication context not found", err)
+		s.respondProblem(w, http.StatusNotFound, sbi.CauseContextNotFound, "authentication context not found", err)
 		return
 	}
 
-	// Return context including HXRES* for testing
+	// Return context including XRES* for testing
 	s.respondJSON(w, http.StatusOK, map[string]interface{}{
 		"authCtxId":          authCtx.AuthCtxID,
 		"supi":               authCtx.SUPI,
 		"authType":           authCtx.AuthType,
 		"rand":               authCtx.RAND,
 		"autn":               authCtx.AUTN,
-		"hxres":              authCtx.HXRES, // For testing!
+		"hxres":              authCtx.HXRES,
+		"xresStar":           authCtx.XRESStar, // For testing!
 		"servingNetworkName": authCtx.ServingNetworkName,
 	})
 }
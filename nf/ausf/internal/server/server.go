@@ -9,11 +9,25 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/your-org/5g-network/common/buildinfo"
+	"github.com/your-org/5g-network/common/correlation"
+	"github.com/your-org/5g-network/common/metrics"
+	"github.com/your-org/5g-network/common/oauth"
+	"github.com/your-org/5g-network/common/sbi"
 	"github.com/your-org/5g-network/nf/ausf/internal/config"
 	"github.com/your-org/5g-network/nf/ausf/internal/service"
 	"go.uber.org/zap"
 )
 
+// readinessTimeout bounds each dependency probe of the readiness check
+const readinessTimeout = 2 * time.Second
+
+// dependency is a backend the NF needs to serve requests
+type dependency struct {
+	name string
+	ping func(ctx context.Context) error
+}
+
 // AUSFServer represents the AUSF HTTP server
 type AUSFServer struct {
 	config *config.Config
@@ -23,6 +37,9 @@ type AUSFServer struct {
 
 	// Services
 	authService *service.AuthenticationService
+
+	// Backends probed by the readiness check
+	dependencies []dependency
 }
 
 // NewServer creates a new AUSF server
@@ -44,13 +61,21 @@ func NewServer(
 	return s
 }
 
+// AddDependency makes the readiness check probe a backend with ping
+func (s *AUSFServer) AddDependency(name string, ping func(ctx context.Context) error) {
+	s.dependencies = append(s.dependencies, dependency{name: name, ping: ping})
+}
+
 // setupMiddleware configures HTTP middleware
 func (s *AUSFServer) setupMiddleware() {
 	s.router.Use(middleware.RequestID)
+	s.router.Use(correlation.Middleware)
 	s.router.Use(middleware.RealIP)
+	s.router.Use(metrics.Middleware)
 	s.router.Use(s.loggingMiddleware)
 	s.router.Use(middleware.Recoverer)
 	s.router.Use(middleware.Timeout(60 * time.Second))
+	s.router.Use(oauth.Middleware(s.config.SBI.OAuth2, s.logger, "AUSF", s.config.NF.InstanceID))
 }
 
 // setupRoutes configures HTTP routes
@@ -62,6 +87,8 @@ func (s *AUSFServer) setupRoutes() {
 
 	// Nausf_UEAuthentication service (TS 29.509)
 	s.router.Route("/nausf-auth/v1", func(r chi.Router) {
+		r.Use(oauth.RequireScope("nausf-auth"))
+
 		// UE authentication initiation
 		r.Post("/ue-authentications", s.handleUEAuthenticationRequest)
 
@@ -79,6 +106,11 @@ func (s *AUSFServer) setupRoutes() {
 	})
 }
 
+// SetBuildInfo exposes the NF build info on GET /version
+func (s *AUSFServer) SetBuildInfo(info *buildinfo.Info) {
+	s.router.Get("/version", info.Handler())
+}
+
 // Start starts the HTTP server
 func (s *AUSFServer) Start() error {
 	addr := fmt.Sprintf("%s:%d", s.config.SBI.BindAddress, s.config.SBI.Port)
@@ -94,6 +126,11 @@ func (s *AUSFServer) Start() error {
 	s.logger.Info("Starting AUSF HTTP server", zap.String("address", addr))
 
 	if s.config.SBI.TLS.Enabled {
+		tlsConfig, err := s.config.SBI.TLS.Build()
+		if err != nil {
+			return fmt.Errorf("invalid TLS configuration: %w", err)
+		}
+		s.server.TLSConfig = tlsConfig
 		return s.server.ListenAndServeTLS(s.config.SBI.TLS.CertFile, s.config.SBI.TLS.KeyFile)
 	}
 
@@ -127,6 +164,7 @@ func (s *AUSFServer) loggingMiddleware(next http.Handler) http.Handler {
 			zap.Duration("duration", time.Since(start)),
 			zap.String("remote_addr", r.RemoteAddr),
 			zap.String("request_id", middleware.GetReqID(r.Context())),
+			correlation.Field(r.Context()),
 		)
 	})
 }
@@ -142,22 +180,13 @@ func (s *AUSFServer) respondJSON(w http.ResponseWriter, status int, data interfa
 	}
 }
 
-func (s *AUSFServer) respondError(w http.ResponseWriter, status int, message string, err error) {
-	s.logger.Error(message, zap.Error(err))
-
-	w.Header().Set("Content-Type", "application/problem+json")
-	w.WriteHeader(status)
-
-	response := map[string]interface{}{
-		"status": status,
-		"title":  message,
-	}
+// respondProblem writes a ProblemDetails error response
+func (s *AUSFServer) respondProblem(w http.ResponseWriter, status int, cause, title string, err error) {
+	s.logger.Error(title, zap.String("cause", cause), zap.Error(err))
 
-	if err != nil {
-		response["detail"] = err.Error()
+	if err := sbi.WriteProblem(w, sbi.NewProblem(status, cause, title, err)); err != nil {
+		s.logger.Error("Failed to encode problem details", zap.Error(err))
 	}
-
-	json.NewEncoder(w).Encode(response)
 }
 
 // Health check handlers
@@ -169,7 +198,17 @@ func (s *AUSFServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *AUSFServer) handleReady(w http.ResponseWriter, r *http.Request) {
-	// Check if AUSF is ready to serve requests
+	// Ready once every backend answers, liveness (/health) stays unprobed
+	for _, dep := range s.dependencies {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		err := dep.ping(ctx)
+		cancel()
+		if err != nil {
+			s.respondProblem(w, http.StatusServiceUnavailable, sbi.CauseNFCongestion, dep.name+" unavailable", err)
+			return
+		}
+	}
+
 	s.respondJSON(w, http.StatusOK, map[string]string{
 		"status": "ready",
 	})
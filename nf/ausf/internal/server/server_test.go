@@ -0,0 +1,53 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/common/sbi"
+	"github.com/your-org/5g-network/nf/ausf/internal/client"
+	"github.com/your-org/5g-network/nf/ausf/internal/config"
+)
+
+func TestReadinessProbesUDM(t *testing.T) {
+	var udmReady atomic.Bool
+	udm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ready" || !udmReady.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer udm.Close()
+
+	s := NewServer(&config.Config{}, nil, zap.NewNop())
+	s.AddDependency("UDM", client.NewUDMClient(udm.URL, time.Second, nil, zap.NewNop()).Ping)
+
+	ready := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		return rec
+	}
+
+	rec := ready()
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, sbi.ContentTypeProblem, rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "UDM unavailable")
+
+	// Liveness does not depend on the UDM
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	udmReady.Store(true)
+	assert.Equal(t, http.StatusOK, ready().Code)
+
+	udm.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, ready().Code)
+}
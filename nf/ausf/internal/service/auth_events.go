@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/your-org/5g-network/common/notify"
+	"github.com/your-org/5g-network/nf/ausf/internal/client"
+	"github.com/your-org/5g-network/nf/ausf/internal/config"
+	"go.uber.org/zap"
+)
+
+// Defaults for authentication event delivery, the queue defaulting to
+// those of the notify package
+const (
+	defaultAuthEventTimeout     = 2 * time.Second
+	defaultAuthEventMaxAttempts = 5
+)
+
+// authEvent is an authentication result pending delivery to the UDM
+type authEvent struct {
+	supi  string
+	event map[string]interface{}
+}
+
+// authEventNotifier delivers authentication results to the UDM in the
+// background. Transient UDM failures are retried with backoff so the UDM's
+// authentication status records do not silently diverge.
+type authEventNotifier struct {
+	udmClient *client.UDMClient
+	cfg       config.AuthEventConfig
+	queue     *notify.Queue[*authEvent]
+	logger    *zap.Logger
+
+	mu        sync.Mutex
+	delivered uint64
+	dropped   uint64
+}
+
+// newAuthEventNotifier creates a notifier and starts its delivery worker
+func newAuthEventNotifier(udmClient *client.UDMClient, cfg config.AuthEventConfig, logger *zap.Logger) *authEventNotifier {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultAuthEventTimeout
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultAuthEventMaxAttempts
+	}
+
+	n := &authEventNotifier{
+		udmClient: udmClient,
+		cfg:       cfg,
+		logger:    logger,
+	}
+	// A single worker delivers events in the order they occurred
+	n.queue = notify.NewQueue(notify.Config{
+		Workers:       1,
+		QueueSize:     cfg.QueueSize,
+		MaxAttempts:   cfg.MaxAttempts,
+		RetryInterval: cfg.RetryInterval,
+	}, notify.Hooks[*authEvent]{
+		Deliver:   n.confirm,
+		Delivered: n.onDelivered,
+		Retrying:  n.onRetrying,
+		Failed:    n.onFailed,
+		Dropped:   func(*authEvent) { n.countDropped() },
+	})
+	return n
+}
+
+// enqueue schedules an event for delivery without blocking. The event is
+// dropped if the queue is full.
+func (n *authEventNotifier) enqueue(supi string, event map[string]interface{}) {
+	if !n.queue.Enqueue(&authEvent{supi: supi, event: event}) {
+		n.logger.Error("Auth event queue full, dropping UDM confirmation",
+			zap.String("supi", supi),
+		)
+	}
+}
+
+// stop terminates the delivery worker. Events still queued are dropped.
+func (n *authEventNotifier) stop() {
+	if pending := n.queue.Stop(); pending > 0 {
+		n.logger.Warn("Dropping undelivered auth events on shutdown", zap.Int("pending", pending))
+	}
+}
+
+// confirm makes one attempt at sending an event to the UDM
+func (n *authEventNotifier) confirm(ev *authEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), n.cfg.Timeout)
+	defer cancel()
+	return n.udmClient.ConfirmAuth(ctx, ev.supi, ev.event)
+}
+
+// onDelivered counts a delivered event
+func (n *authEventNotifier) onDelivered(ev *authEvent, attempts int) {
+	n.mu.Lock()
+	n.delivered++
+	n.mu.Unlock()
+	if attempts > 1 {
+		n.logger.Info("Auth event delivered to UDM after retry",
+			zap.String("supi", ev.supi),
+			zap.Int("attempts", attempts),
+		)
+	}
+}
+
+// onRetrying logs a failed attempt that will be retried
+func (n *authEventNotifier) onRetrying(ev *authEvent, attempt int, backoff time.Duration, err error) {
+	n.logger.Warn("Failed to confirm auth with UDM, retrying",
+		zap.String("supi", ev.supi),
+		zap.Int("attempt", attempt),
+		zap.Duration("backoff", backoff),
+		zap.Error(err),
+	)
+}
+
+// onFailed counts and logs an event given up on
+func (n *authEventNotifier) onFailed(ev *authEvent, attempts int, err error) {
+	n.countDropped()
+	n.logger.Error("Giving up on UDM auth confirmation",
+		zap.String("supi", ev.supi),
+		zap.Int("attempts", attempts),
+		zap.Error(err),
+	)
+}
+
+// countDropped counts an event that was not delivered
+func (n *authEventNotifier) countDropped() {
+	n.mu.Lock()
+	n.dropped++
+	n.mu.Unlock()
+}
+
+// stats returns delivery counters
+func (n *authEventNotifier) stats() (pending int, delivered, dropped uint64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.queue.Len(), n.delivered, n.dropped
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/nf/ausf/internal/client"
+	"github.com/your-org/5g-network/nf/ausf/internal/config"
+)
+
+// newFlakyUDM starts a fake UDM whose auth-events endpoint fails the first
+// failures requests with 503
+func newFlakyUDM(t *testing.T, failures int32) (*httptest.Server, *int32) {
+	t.Helper()
+
+	var confirmed int32
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/generate-auth-data"):
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(client.AuthenticationInfoResult{
+				AuthType: "5G_AKA",
+				AuthenticationVector: &client.AuthenticationVector{
+					RAND:     "00112233445566778899aabbccddeeff",
+					AUTN:     "ffeeddccbbaa99887766554433221100",
+					XRESStar: "0123456789abcdef",
+					KAUSF:    "abcdef0123456789",
+				},
+			})
+		case strings.HasSuffix(r.URL.Path, "/auth-events"):
+			if atomic.AddInt32(&calls, 1) <= failures {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			atomic.AddInt32(&confirmed, 1)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &confirmed
+}
+
+func authenticate(t *testing.T, svc *AuthenticationService) *ConfirmationDataResponse {
+	t.Helper()
+
+	ctx := context.Background()
+	authResp, err := svc.UEAuthenticationCtx(ctx, &UEAuthenticationRequest{
+		SUPI:               "imsi-001010000000001",
+		ServingNetworkName: "5G:mnc001.mcc001.3gppnetwork.org",
+	})
+	require.NoError(t, err)
+
+	confirm, err := svc.Confirm5gAkaAuth(ctx, authResp.AuthCtxID, &ConfirmationData{RES: "0123456789abcdef"})
+	require.NoError(t, err)
+	return confirm
+}
+
+func TestAuthEventDeliveredAfterTransientUDMFailure(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	udm, confirmed := newFlakyUDM(t, 2)
+
+	svc := NewAuthenticationService(client.NewUDMClient(udm.URL, 10*time.Second, nil, logger), config.AuthEventConfig{
+		Timeout:       time.Second,
+		MaxAttempts:   5,
+		RetryInterval: 10 * time.Millisecond,
+	}, logger)
+	defer svc.Stop()
+
+	start := time.Now()
+	confirm := authenticate(t, svc)
+	assert.Equal(t, "AUTHENTICATION_SUCCESS", confirm.AuthResult)
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "UE response must not wait on UDM retries")
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(confirmed) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+
+	stats := svc.GetStats()
+	assert.Equal(t, uint64(1), stats["delivered_auth_events"])
+	assert.Equal(t, uint64(0), stats["dropped_auth_events"])
+}
+
+func TestAuthEventDroppedAfterMaxAttempts(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	udm, confirmed := newFlakyUDM(t, 100)
+
+	svc := NewAuthenticationService(client.NewUDMClient(udm.URL, 10*time.Second, nil, logger), config.AuthEventConfig{
+		MaxAttempts:   3,
+		RetryInterval: time.Millisecond,
+	}, logger)
+	defer svc.Stop()
+
+	assert.Equal(t, "AUTHENTICATION_SUCCESS", authenticate(t, svc).AuthResult)
+
+	assert.Eventually(t, func() bool {
+		return svc.GetStats()["dropped_auth_events"] == uint64(1)
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Zero(t, atomic.LoadInt32(confirmed))
+}
+
+func TestAuthEventAttemptDeadline(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+
+	// A hung UDM must not hold a delivery attempt for the full HTTP timeout
+	release := make(chan struct{})
+	udm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer udm.Close()
+	defer close(release)
+
+	n := newAuthEventNotifier(client.NewUDMClient(udm.URL, time.Minute, nil, logger), config.AuthEventConfig{
+		Timeout:       20 * time.Millisecond,
+		MaxAttempts:   2,
+		RetryInterval: time.Millisecond,
+	}, logger)
+	defer n.stop()
+
+	n.enqueue("imsi-001010000000001", map[string]interface{}{"success": true})
+
+	assert.Eventually(t, func() bool {
+		_, _, dropped := n.stats()
+		return dropped == 1
+	}, time.Second, 10*time.Millisecond)
+}
@@ -1,35 +1,56 @@
 package service
 
 import (
+	"container/list"
 	"context"
 	"crypto/rand"
-	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
 	"fmt"
 	"sync"
 	"time"
 
+	"github.com/your-org/5g-network/common/crypto/kdf"
+	"github.com/your-org/5g-network/common/metrics"
 	"github.com/your-org/5g-network/nf/ausf/internal/client"
+	"github.com/your-org/5g-network/nf/ausf/internal/config"
 	"go.uber.org/zap"
 )
 
 // AuthenticationService handles UE authentication operations
 type AuthenticationService struct {
-	udmClient *client.UDMClient
-	contexts  map[string]*AuthenticationContext // authCtxId -> context
-	mu        sync.RWMutex
-	logger    *zap.Logger
+	udmClient  *client.UDMClient
+	authEvents *authEventNotifier
+	limiter    *authLimiter
+	logger     *zap.Logger
+
+	mu           sync.RWMutex
+	contexts     map[string]*AuthenticationContext // authCtxId -> context
+	contextOrder *list.List                        // authCtxIds, oldest first
+	contextTTL   time.Duration
+	maxContexts  int
+	evicted      uint64 // Contexts evicted before expiry
 }
 
 // NewAuthenticationService creates a new authentication service
-func NewAuthenticationService(udmClient *client.UDMClient, logger *zap.Logger) *AuthenticationService {
+func NewAuthenticationService(udmClient *client.UDMClient, authEvents config.AuthEventConfig, logger *zap.Logger) *AuthenticationService {
 	return &AuthenticationService{
-		udmClient: udmClient,
-		contexts:  make(map[string]*AuthenticationContext),
-		logger:    logger,
+		udmClient:    udmClient,
+		authEvents:   newAuthEventNotifier(udmClient, authEvents, logger),
+		limiter:      newAuthLimiter(config.AuthLimitConfig{}),
+		logger:       logger,
+		contexts:     make(map[string]*AuthenticationContext),
+		contextOrder: list.New(),
+		contextTTL:   DefaultContextTTL,
+		maxContexts:  defaultMaxContexts,
 	}
 }
 
+// Stop stops background delivery of authentication events to the UDM
+func (s *AuthenticationService) Stop() {
+	s.authEvents.stop()
+}
+
 // AuthenticationContext represents an ongoing authentication session
 type AuthenticationContext struct {
 	AuthCtxID          string
@@ -38,11 +59,14 @@ type AuthenticationContext struct {
 	AuthType           string // "5G_AKA" or "EAP_AKA_PRIME"
 	RAND               string
 	AUTN               string
-	HXRES              string
+	XRESStar           string
+	HXRES              string // HXRES* sent to the SEAF
 	KAUSF              string
 	KSEAF              string // Derived from KAUSF
 	CreatedAt          time.Time
 	ExpiresAt          time.Time
+
+	element *list.Element // In contextOrder
 }
 
 // UEAuthenticationRequest represents authentication initiation request from AMF
@@ -65,8 +89,9 @@ type UEAuthenticationResponse struct {
 
 // Var5gAuthData represents 5G authentication data
 type Var5gAuthData struct {
-	RAND string `json:"rand"`
-	AUTN string `json:"autn"`
+	RAND      string `json:"rand"`
+	AUTN      string `json:"autn"`
+	HXRESStar string `json:"hxresStar"`
 }
 
 // ConfirmationData represents authentication confirmation from AMF
@@ -88,6 +113,25 @@ func (s *AuthenticationService) UEAuthenticationCtx(ctx context.Context, req *UE
 		zap.String("serving_network", req.ServingNetworkName),
 	)
 
+	// Limits are checked before UDM generates a vector. A concealed SUCI
+	// differs on every attempt, so floods of SUCIs are bounded by the
+	// global limit only.
+	s.mu.RLock()
+	limiter := s.limiter
+	s.mu.RUnlock()
+	if err := limiter.allow(req.SUPI); err != nil {
+		return nil, err
+	}
+
+	// After a synchronisation failure the AMF retries with the RAND and AUTS
+	// from the UE, which UDM needs to recover SQN_MS
+	if req.ResynchronizationInfo != nil {
+		s.logger.Info("Forwarding resynchronization info to UDM",
+			zap.String("supi", req.SUPI),
+			zap.String("rand", req.ResynchronizationInfo.RAND),
+		)
+	}
+
 	// Request authentication vector from UDM
 	authInfo := &client.AuthenticationInfo{
 		SUPI:                  req.SUPI,
@@ -104,35 +148,51 @@ func (s *AuthenticationService) UEAuthenticationCtx(ctx context.Context, req *UE
 		return nil, fmt.Errorf("no authentication vector received from UDM")
 	}
 
+	// UDM de-conceals a SUCI; the context and confirmation carry the SUPI
+	supi := req.SUPI
+	if authResult.SUPI != "" {
+		supi = authResult.SUPI
+	}
+
 	// Generate authentication context ID
 	authCtxID := s.generateAuthCtxID()
 
-	// Derive KSEAF from KAUSF
-	// KSEAF = KDF(KAUSF, serving network name)
-	// Simplified: In production, use proper 3GPP KDF
-	kseaf := s.deriveKSEAF(authResult.AuthenticationVector.KAUSF, req.ServingNetworkName)
+	av := authResult.AuthenticationVector
+
+	// Derive KSEAF from KAUSF (TS 33.501 A.6)
+	kseaf, err := deriveKSEAF(av.KAUSF, req.ServingNetworkName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive KSEAF: %w", err)
+	}
+
+	// HXRES* lets the SEAF check RES* before confirming (TS 33.501 A.5)
+	hxres, err := deriveHXRESStar(av.RAND, av.XRESStar)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive HXRES*: %w", err)
+	}
 
 	// Store authentication context
 	authCtx := &AuthenticationContext{
 		AuthCtxID:          authCtxID,
-		SUPI:               req.SUPI,
+		SUPI:               supi,
 		ServingNetworkName: req.ServingNetworkName,
 		AuthType:           authResult.AuthType,
-		RAND:               authResult.AuthenticationVector.RAND,
-		AUTN:               authResult.AuthenticationVector.AUTN,
-		HXRES:              authResult.AuthenticationVector.HXRES,
-		KAUSF:              authResult.AuthenticationVector.KAUSF,
+		RAND:               av.RAND,
+		AUTN:               av.AUTN,
+		XRESStar:           av.XRESStar,
+		HXRES:              hxres,
+		KAUSF:              av.KAUSF,
 		KSEAF:              kseaf,
-		CreatedAt:          time.Now(),
-		ExpiresAt:          time.Now().Add(5 * time.Minute),
 	}
 
 	s.mu.Lock()
-	s.contexts[authCtxID] = authCtx
+	authCtx.CreatedAt = time.Now()
+	authCtx.ExpiresAt = authCtx.CreatedAt.Add(s.contextTTL)
+	s.storeContext(authCtx)
 	s.mu.Unlock()
 
 	s.logger.Info("Authentication context created",
-		zap.String("supi", req.SUPI),
+		zap.String("supi", supi),
 		zap.String("auth_ctx_id", authCtxID),
 		zap.String("auth_type", authResult.AuthType),
 	)
@@ -142,8 +202,9 @@ func (s *AuthenticationService) UEAuthenticationCtx(ctx context.Context, req *UE
 		AuthType:  authResult.AuthType,
 		AuthCtxID: authCtxID, // Include for convenience
 		Var5gAuthData: &Var5gAuthData{
-			RAND: authResult.AuthenticationVector.RAND,
-			AUTN: authResult.AuthenticationVector.AUTN,
+			RAND:      av.RAND,
+			AUTN:      av.AUTN,
+			HXRESStar: hxres,
 		},
 		Links: map[string]interface{}{
 			"5g-aka": map[string]string{
@@ -173,15 +234,14 @@ func (s *AuthenticationService) Confirm5gAkaAuth(ctx context.Context, authCtxID
 	// Check if context expired
 	if time.Now().After(authCtx.ExpiresAt) {
 		s.mu.Lock()
-		delete(s.contexts, authCtxID)
+		s.removeContext(authCtxID)
 		s.mu.Unlock()
 		return nil, fmt.Errorf("authentication context expired")
 	}
 
-	// Verify RES* matches HXRES*
-	// In production, compute HRES* from RES* and compare with stored HXRES*
-	// Simplified: Direct comparison
-	authSuccess := confirmData.RES == authCtx.HXRES
+	// Verify RES* against HXRES* and XRES* (TS 33.501 6.1.3.2). The result
+	// does not reveal which check failed.
+	authSuccess := verifyRESStar(authCtx, confirmData.RES)
 
 	var response *ConfirmationDataResponse
 	if authSuccess {
@@ -195,20 +255,6 @@ func (s *AuthenticationService) Confirm5gAkaAuth(ctx context.Context, authCtxID
 			SUPI:       authCtx.SUPI,
 			KSEAF:      authCtx.KSEAF,
 		}
-
-		// Notify UDM of successful authentication
-		authEvent := map[string]interface{}{
-			"nfInstanceId":       "ausf-1", // Should use actual instance ID
-			"success":            true,
-			"timeStamp":          time.Now().Format(time.RFC3339),
-			"authType":           authCtx.AuthType,
-			"servingNetworkName": authCtx.ServingNetworkName,
-		}
-
-		if err := s.udmClient.ConfirmAuth(ctx, authCtx.SUPI, authEvent); err != nil {
-			s.logger.Error("Failed to confirm auth with UDM", zap.Error(err))
-			// Continue anyway - authentication was successful
-		}
 	} else {
 		s.logger.Warn("Authentication failed",
 			zap.String("supi", authCtx.SUPI),
@@ -220,9 +266,23 @@ func (s *AuthenticationService) Confirm5gAkaAuth(ctx context.Context, authCtxID
 		}
 	}
 
+	// Notify UDM of the result, which it records for auditing. Delivery is
+	// retried in the background and never delays the response to the AMF.
+	authEvent := map[string]interface{}{
+		"nfInstanceId":       "ausf-1", // Should use actual instance ID
+		"success":            authSuccess,
+		"timeStamp":          time.Now().Format(time.RFC3339),
+		"authType":           authCtx.AuthType,
+		"servingNetworkName": authCtx.ServingNetworkName,
+	}
+	if !authSuccess {
+		authEvent["failureReason"] = "RES* mismatch"
+	}
+	s.authEvents.enqueue(authCtx.SUPI, authEvent)
+
 	// Clean up authentication context
 	s.mu.Lock()
-	delete(s.contexts, authCtxID)
+	s.removeContext(authCtxID)
 	s.mu.Unlock()
 
 	return response, nil
@@ -246,8 +306,13 @@ func (s *AuthenticationService) GetStats() map[string]interface{} {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	pending, delivered, dropped := s.authEvents.stats()
 	return map[string]interface{}{
-		"active_contexts": len(s.contexts),
+		"active_contexts":       len(s.contexts),
+		"evicted_contexts":      s.evicted,
+		"pending_auth_events":   pending,
+		"delivered_auth_events": delivered,
+		"dropped_auth_events":   dropped,
 	}
 }
 
@@ -260,30 +325,73 @@ func (s *AuthenticationService) generateAuthCtxID() string {
 
 // deriveKSEAF derives KSEAF from KAUSF
 // KSEAF = KDF(KAUSF, serving network name)
-// Simplified implementation - in production use 3GPP KDF
-func (s *AuthenticationService) deriveKSEAF(kausfHex, servingNetworkName string) string {
-	kausf, _ := hex.DecodeString(kausfHex)
-
-	// Simple KDF using SHA-256
-	h := sha256.New()
-	h.Write(kausf)
-	h.Write([]byte(servingNetworkName))
-	h.Write([]byte("KSEAF"))
-
-	kseaf := h.Sum(nil)
-	return hex.EncodeToString(kseaf)
+func deriveKSEAF(kausfHex, servingNetworkName string) (string, error) {
+	kausf, err := hex.DecodeString(kausfHex)
+	if err != nil {
+		return "", fmt.Errorf("invalid KAUSF: %w", err)
+	}
+
+	kseaf, err := kdf.KSEAF(kausf, servingNetworkName)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(kseaf), nil
+}
+
+// deriveHXRESStar computes HXRES* from RAND and XRES*
+func deriveHXRESStar(randHex, xresStarHex string) (string, error) {
+	randBytes, err := hex.DecodeString(randHex)
+	if err != nil {
+		return "", fmt.Errorf("invalid RAND: %w", err)
+	}
+	xresStar, err := hex.DecodeString(xresStarHex)
+	if err != nil {
+		return "", fmt.Errorf("invalid XRES*: %w", err)
+	}
+	return hex.EncodeToString(computeHRES(randBytes, xresStar)), nil
 }
 
-// CleanupExpiredContexts removes expired authentication contexts
-func (s *AuthenticationService) CleanupExpiredContexts() {
+// computeHRES computes HRES* as the 128 least significant bits of
+// SHA-256(RAND || RES*) (TS 33.501 A.5)
+func computeHRES(rand, resStar []byte) []byte {
+	return kdf.HXRESStar(rand, resStar)
+}
+
+// verifyRESStar checks a RES* received from the SEAF. HRES* computed from
+// RES* must match the stored HXRES*, and RES* itself must match XRES*.
+func verifyRESStar(authCtx *AuthenticationContext, resStarHex string) bool {
+	resStar, err := hex.DecodeString(resStarHex)
+	if err != nil || len(resStar) == 0 {
+		return false
+	}
+	randBytes, errRAND := hex.DecodeString(authCtx.RAND)
+	hxres, errHXRES := hex.DecodeString(authCtx.HXRES)
+	xresStar, errXRES := hex.DecodeString(authCtx.XRESStar)
+	if errRAND != nil || errHXRES != nil || errXRES != nil {
+		return false
+	}
+
+	hresMatch := subtle.ConstantTimeCompare(computeHRES(randBytes, resStar), hxres)
+	resMatch := subtle.ConstantTimeCompare(resStar, xresStar)
+	return hresMatch&resMatch == 1
+}
+
+// CleanupExpiredContexts removes expired authentication contexts and
+// returns how many it removed
+func (s *AuthenticationService) CleanupExpiredContexts() int {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	now := time.Now()
+	removed := 0
 	for id, ctx := range s.contexts {
 		if now.After(ctx.ExpiresAt) {
-			delete(s.contexts, id)
+			s.removeContext(id)
+			removed++
 			s.logger.Debug("Removed expired auth context", zap.String("auth_ctx_id", id))
 		}
 	}
+
+	metrics.RecordExpiredAuthContexts(removed)
+	return removed
 }
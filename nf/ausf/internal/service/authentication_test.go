@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/nf/ausf/internal/client"
+	"github.com/your-org/5g-network/nf/ausf/internal/config"
+)
+
+// 5G HE AV derived from TS 35.208 test set 1 for the serving network below
+const (
+	testSNName   = "5G:mnc001.mcc001.3gppnetwork.org"
+	testRAND     = "23553cbe9637a89d218ae64dae47bf35"
+	testAUTN     = "55f328b43577b9b94a9ffac354dfafb3"
+	testXRESStar = "f236a7417272bfb2d66d4d670733b527"
+	testHXRES    = "20a71900b01776bfd773e8c15a825446"
+	testKAUSF    = "474698caf02cc715db2ec0726510cfee6caa5bb1a649cb01224f2e23af94de1b"
+	testKSEAF    = "8dff166c02edd5b177950d50cdd3fe93756cc53951856a95cb5ee9aabd35e220"
+)
+
+// newTestAuthService returns a service backed by a fake UDM that serves the
+// test vector
+func newTestAuthService(t *testing.T) *AuthenticationService {
+	svc, _ := newRecordingAuthService(t)
+	return svc
+}
+
+// newRecordingAuthService is newTestAuthService that also returns the
+// generate-auth-data requests received by the fake UDM. UDM rejects
+// resynchronization requests whose AUTS is all zero.
+func newRecordingAuthService(t *testing.T) (*AuthenticationService, chan client.AuthenticationInfo) {
+	t.Helper()
+
+	requests := make(chan client.AuthenticationInfo, 8)
+	udm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/generate-auth-data") {
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+
+		var authInfo client.AuthenticationInfo
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&authInfo))
+		requests <- authInfo
+
+		if resync := authInfo.ResynchronizationInfo; resync != nil && resync.AUTS == strings.Repeat("00", 14) {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		result := client.AuthenticationInfoResult{
+			AuthType: "5G_AKA",
+			AuthenticationVector: &client.AuthenticationVector{
+				RAND:     testRAND,
+				AUTN:     testAUTN,
+				XRESStar: testXRESStar,
+				KAUSF:    testKAUSF,
+			},
+		}
+		// UDM returns the SUPI behind a SUCI
+		if strings.HasPrefix(authInfo.SUPI, "suci-") {
+			result.SUPI = "imsi-001010000000001"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}))
+	t.Cleanup(udm.Close)
+
+	logger, _ := zap.NewDevelopment()
+	svc := NewAuthenticationService(client.NewUDMClient(udm.URL, time.Second, nil, logger), config.AuthEventConfig{}, logger)
+	t.Cleanup(svc.Stop)
+	return svc, requests
+}
+
+func startAuthentication(t *testing.T, svc *AuthenticationService) *UEAuthenticationResponse {
+	t.Helper()
+
+	resp, err := svc.UEAuthenticationCtx(context.Background(), &UEAuthenticationRequest{
+		SUPI:               "imsi-001010000000001",
+		ServingNetworkName: testSNName,
+	})
+	require.NoError(t, err)
+	return resp
+}
+
+func TestComputeHRES(t *testing.T) {
+	rand, _ := hex.DecodeString(testRAND)
+	resStar, _ := hex.DecodeString(testXRESStar)
+	assert.Equal(t, testHXRES, hex.EncodeToString(computeHRES(rand, resStar)))
+}
+
+func TestConfirm5gAkaAuth_ValidRESStar(t *testing.T) {
+	svc := newTestAuthService(t)
+	authResp := startAuthentication(t, svc)
+	assert.Equal(t, testHXRES, authResp.Var5gAuthData.HXRESStar)
+
+	confirm, err := svc.Confirm5gAkaAuth(context.Background(), authResp.AuthCtxID, &ConfirmationData{RES: testXRESStar})
+	require.NoError(t, err)
+	assert.Equal(t, "AUTHENTICATION_SUCCESS", confirm.AuthResult)
+	assert.Equal(t, "imsi-001010000000001", confirm.SUPI)
+	assert.Equal(t, testKSEAF, confirm.KSEAF)
+}
+
+func TestConfirm5gAkaAuth_ReturnsSUPIForSUCI(t *testing.T) {
+	svc, requests := newRecordingAuthService(t)
+
+	authResp, err := svc.UEAuthenticationCtx(context.Background(), &UEAuthenticationRequest{
+		SUPI:               "suci-0-001-01-0000-0-0-0000000001",
+		ServingNetworkName: testSNName,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "suci-0-001-01-0000-0-0-0000000001", (<-requests).SUPI)
+
+	confirm, err := svc.Confirm5gAkaAuth(context.Background(), authResp.AuthCtxID, &ConfirmationData{RES: testXRESStar})
+	require.NoError(t, err)
+	assert.Equal(t, "imsi-001010000000001", confirm.SUPI)
+}
+
+func TestConfirm5gAkaAuth_TamperedRESStar(t *testing.T) {
+	tampered := map[string]string{
+		"hxres":       testHXRES,
+		"flipped bit": "f236a7417272bfb2d66d4d670733b526",
+		"truncated":   testXRESStar[:16],
+		"extended":    testXRESStar + "00",
+		"empty":       "",
+		"not hex":     "not-a-valid-res-star-value-here!",
+	}
+
+	for name, resStar := range tampered {
+		t.Run(name, func(t *testing.T) {
+			svc := newTestAuthService(t)
+			authResp := startAuthentication(t, svc)
+
+			confirm, err := svc.Confirm5gAkaAuth(context.Background(), authResp.AuthCtxID, &ConfirmationData{RES: resStar})
+			require.NoError(t, err)
+			assert.Equal(t, &ConfirmationDataResponse{AuthResult: "AUTHENTICATION_FAILURE"}, confirm)
+		})
+	}
+}
+
+func TestVerifyRESStar_RequiresBothChecks(t *testing.T) {
+	authCtx := &AuthenticationContext{RAND: testRAND, XRESStar: testXRESStar, HXRES: testHXRES}
+	assert.True(t, verifyRESStar(authCtx, testXRESStar))
+
+	// RES* matching XRES* is rejected when HXRES* does not match
+	authCtx.HXRES = "00000000000000000000000000000000"
+	assert.False(t, verifyRESStar(authCtx, testXRESStar))
+}
+
+func TestUEAuthenticationCtx_ForwardsResynchronizationInfo(t *testing.T) {
+	svc, requests := newRecordingAuthService(t)
+	ctx := context.Background()
+
+	// First attempt carries no resynchronization info
+	first := startAuthentication(t, svc)
+	assert.Nil(t, (<-requests).ResynchronizationInfo)
+
+	// The UE reported a synchronisation failure for the first challenge
+	auts := "0123456789abcdef0123456789ab"
+	req := &UEAuthenticationRequest{SUPI: "imsi-001010000000001", ServingNetworkName: testSNName}
+	req.ResynchronizationInfo = &struct {
+		RAND string `json:"rand"`
+		AUTS string `json:"auts"`
+	}{RAND: first.Var5gAuthData.RAND, AUTS: auts}
+
+	second, err := svc.UEAuthenticationCtx(ctx, req)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.AuthCtxID, second.AuthCtxID)
+
+	forwarded := <-requests
+	require.NotNil(t, forwarded.ResynchronizationInfo)
+	assert.Equal(t, testRAND, forwarded.ResynchronizationInfo.RAND)
+	assert.Equal(t, auts, forwarded.ResynchronizationInfo.AUTS)
+	assert.Equal(t, testSNName, forwarded.ServingNetworkName)
+}
+
+func TestUEAuthenticationCtx_ResynchronizationRejected(t *testing.T) {
+	svc, _ := newRecordingAuthService(t)
+
+	req := &UEAuthenticationRequest{SUPI: "imsi-001010000000001", ServingNetworkName: testSNName}
+	req.ResynchronizationInfo = &struct {
+		RAND string `json:"rand"`
+		AUTS string `json:"auts"`
+	}{RAND: testRAND, AUTS: strings.Repeat("00", 14)}
+
+	_, err := svc.UEAuthenticationCtx(context.Background(), req)
+	assert.ErrorIs(t, err, client.ErrAuthRejected)
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/your-org/5g-network/common/metrics"
+	"github.com/your-org/5g-network/nf/ausf/internal/config"
+)
+
+// defaultMaxContexts bounds the pending authentication contexts when no
+// limit is configured
+const defaultMaxContexts = 100000
+
+// maxTrackedSUPIs is the number of per-SUPI buckets above which the idle
+// ones are dropped
+const maxTrackedSUPIs = 10000
+
+// ErrRateLimited is returned when an authentication initiation exceeds the
+// per-SUPI or global rate limit
+var ErrRateLimited = errors.New("authentication rate limit exceeded")
+
+// tokenBucket holds up to burst tokens, refilled at rate per second
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens earned since the last refill
+func (b *tokenBucket) refill(now time.Time, rate float64, burst int) {
+	if b.last.IsZero() {
+		b.tokens = float64(burst)
+	} else {
+		b.tokens = min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	}
+	b.last = now
+}
+
+// authLimiter rate limits authentication initiation per SUPI and globally.
+// A request takes a token from both buckets, or from neither when one is
+// empty, so rejected requests do not eat the allowance of others.
+type authLimiter struct {
+	cfg config.AuthLimitConfig
+
+	mu      sync.Mutex
+	global  tokenBucket
+	perSUPI map[string]*tokenBucket
+	now     func() time.Time // Replaceable in tests
+}
+
+func newAuthLimiter(cfg config.AuthLimitConfig) *authLimiter {
+	return &authLimiter{
+		cfg:     cfg,
+		perSUPI: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// allow takes a token for an initiation for supi, ErrRateLimited when a
+// limit is exceeded
+func (l *authLimiter) allow(supi string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if l.cfg.GlobalRate > 0 {
+		l.global.refill(now, l.cfg.GlobalRate, l.cfg.GlobalBurst)
+		if l.global.tokens < 1 {
+			return fmt.Errorf("%w: global", ErrRateLimited)
+		}
+	}
+
+	if l.cfg.PerSUPIRate > 0 {
+		bucket, ok := l.perSUPI[supi]
+		if !ok {
+			if len(l.perSUPI) >= maxTrackedSUPIs {
+				l.pruneIdle(now)
+			}
+			bucket = &tokenBucket{}
+			l.perSUPI[supi] = bucket
+		}
+		bucket.refill(now, l.cfg.PerSUPIRate, l.cfg.PerSUPIBurst)
+		if bucket.tokens < 1 {
+			return fmt.Errorf("%w: %s", ErrRateLimited, supi)
+		}
+		bucket.tokens--
+	}
+
+	if l.cfg.GlobalRate > 0 {
+		l.global.tokens--
+	}
+	return nil
+}
+
+// pruneIdle drops the per-SUPI buckets that have refilled, which behave as
+// new ones
+func (l *authLimiter) pruneIdle(now time.Time) {
+	for supi, bucket := range l.perSUPI {
+		bucket.refill(now, l.cfg.PerSUPIRate, l.cfg.PerSUPIBurst)
+		if bucket.tokens >= float64(l.cfg.PerSUPIBurst) {
+			delete(l.perSUPI, supi)
+		}
+	}
+}
+
+// SetLimits rate limits authentication initiation and bounds the pending
+// authentication contexts as configured
+func (s *AuthenticationService) SetLimits(cfg config.AuthLimitConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.limiter = newAuthLimiter(cfg)
+	if cfg.MaxContexts > 0 {
+		s.maxContexts = cfg.MaxContexts
+	}
+	for s.contextOrder.Len() > s.maxContexts {
+		s.evictOldestContext()
+	}
+}
+
+// storeContext adds an authentication context, evicting the oldest ones
+// beyond the maximum. The caller holds s.mu.
+func (s *AuthenticationService) storeContext(authCtx *AuthenticationContext) {
+	for s.contextOrder.Len() >= s.maxContexts {
+		s.evictOldestContext()
+	}
+	authCtx.element = s.contextOrder.PushBack(authCtx.AuthCtxID)
+	s.contexts[authCtx.AuthCtxID] = authCtx
+	metrics.SetActiveAuthContexts(len(s.contexts))
+}
+
+// removeContext deletes an authentication context. The caller holds s.mu.
+func (s *AuthenticationService) removeContext(authCtxID string) {
+	if authCtx, ok := s.contexts[authCtxID]; ok {
+		s.contextOrder.Remove(authCtx.element)
+		delete(s.contexts, authCtxID)
+		metrics.SetActiveAuthContexts(len(s.contexts))
+	}
+}
+
+// evictOldestContext removes the context created first. The caller holds
+// s.mu.
+func (s *AuthenticationService) evictOldestContext() {
+	oldest := s.contextOrder.Front()
+	if oldest == nil {
+		return
+	}
+	s.removeContext(oldest.Value.(string))
+	s.evicted++
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/nf/ausf/internal/client"
+	"github.com/your-org/5g-network/nf/ausf/internal/config"
+)
+
+// newLimitedAuthService returns a service with limits backed by a fake UDM
+// that serves the test vector to any SUPI, and a clock the limiter reads
+func newLimitedAuthService(t *testing.T, limits config.AuthLimitConfig) (*AuthenticationService, *time.Time) {
+	t.Helper()
+
+	udm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(client.AuthenticationInfoResult{
+			AuthType: "5G_AKA",
+			AuthenticationVector: &client.AuthenticationVector{
+				RAND:     testRAND,
+				AUTN:     testAUTN,
+				XRESStar: testXRESStar,
+				KAUSF:    testKAUSF,
+			},
+		})
+	}))
+	t.Cleanup(udm.Close)
+
+	svc := NewAuthenticationService(client.NewUDMClient(udm.URL, time.Second, nil, zap.NewNop()), config.AuthEventConfig{}, zap.NewNop())
+	t.Cleanup(svc.Stop)
+	svc.SetLimits(limits)
+
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	svc.limiter.now = func() time.Time { return now }
+	return svc, &now
+}
+
+func initiate(svc *AuthenticationService, supi string) (*UEAuthenticationResponse, error) {
+	return svc.UEAuthenticationCtx(context.Background(), &UEAuthenticationRequest{
+		SUPI:               supi,
+		ServingNetworkName: testSNName,
+	})
+}
+
+func TestUEAuthenticationCtx_PerSUPIRateLimit(t *testing.T) {
+	svc, now := newLimitedAuthService(t, config.AuthLimitConfig{PerSUPIRate: 1, PerSUPIBurst: 2})
+
+	for i := 0; i < 2; i++ {
+		_, err := initiate(svc, "imsi-001010000000001")
+		require.NoError(t, err)
+	}
+	_, err := initiate(svc, "imsi-001010000000001")
+	assert.ErrorIs(t, err, ErrRateLimited)
+
+	// Other SUPIs have their own allowance
+	_, err = initiate(svc, "imsi-001010000000002")
+	assert.NoError(t, err)
+
+	// One token is earned per second
+	*now = now.Add(time.Second)
+	_, err = initiate(svc, "imsi-001010000000001")
+	assert.NoError(t, err)
+	_, err = initiate(svc, "imsi-001010000000001")
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Equal(t, 4, svc.GetStats()["active_contexts"])
+}
+
+func TestUEAuthenticationCtx_GlobalRateLimit(t *testing.T) {
+	svc, now := newLimitedAuthService(t, config.AuthLimitConfig{
+		PerSUPIRate:  1,
+		PerSUPIBurst: 1,
+		GlobalRate:   10,
+		GlobalBurst:  3,
+	})
+
+	// Requests rejected for their SUPI leave the global allowance intact
+	_, err := initiate(svc, "imsi-001010000000001")
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		_, err = initiate(svc, "imsi-001010000000001")
+		assert.ErrorIs(t, err, ErrRateLimited)
+	}
+
+	for i := 2; i <= 3; i++ {
+		_, err = initiate(svc, fmt.Sprintf("imsi-00101000000000%d", i))
+		require.NoError(t, err)
+	}
+	_, err = initiate(svc, "imsi-001010000000004")
+	assert.ErrorIs(t, err, ErrRateLimited)
+
+	*now = now.Add(100 * time.Millisecond)
+	_, err = initiate(svc, "imsi-001010000000004")
+	assert.NoError(t, err)
+}
+
+func TestUEAuthenticationCtx_ContextsBoundedUnderFlood(t *testing.T) {
+	svc, _ := newLimitedAuthService(t, config.AuthLimitConfig{MaxContexts: 10})
+
+	var authCtxIDs []string
+	for i := 0; i < 100; i++ {
+		resp, err := initiate(svc, fmt.Sprintf("imsi-0010100000%05d", i))
+		require.NoError(t, err)
+		authCtxIDs = append(authCtxIDs, resp.AuthCtxID)
+	}
+
+	stats := svc.GetStats()
+	assert.Equal(t, 10, stats["active_contexts"])
+	assert.Equal(t, uint64(90), stats["evicted_contexts"])
+	assert.Equal(t, 10, svc.contextOrder.Len())
+
+	// The oldest contexts were evicted, the newest can still be confirmed
+	_, err := svc.GetAuthContext(authCtxIDs[0])
+	assert.Error(t, err)
+	confirm, err := svc.Confirm5gAkaAuth(context.Background(), authCtxIDs[99], &ConfirmationData{RES: testXRESStar})
+	require.NoError(t, err)
+	assert.Equal(t, "AUTHENTICATION_SUCCESS", confirm.AuthResult)
+	assert.Equal(t, 9, svc.contextOrder.Len())
+	assert.Equal(t, 9, svc.GetStats()["active_contexts"])
+}
+
+func TestAuthLimiterPrunesIdleSUPIs(t *testing.T) {
+	l := newAuthLimiter(config.AuthLimitConfig{PerSUPIRate: 1, PerSUPIBurst: 1})
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < maxTrackedSUPIs; i++ {
+		require.NoError(t, l.allow(fmt.Sprintf("imsi-0010100%08d", i)))
+	}
+	assert.Len(t, l.perSUPI, maxTrackedSUPIs)
+
+	// Once their buckets refill, the tracked SUPIs are dropped
+	now = now.Add(time.Second)
+	require.NoError(t, l.allow("imsi-001019999999999"))
+	assert.Len(t, l.perSUPI, 1)
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Authentication context expiry defaults
+const (
+	DefaultContextTTL      = 5 * time.Minute
+	DefaultCleanupInterval = time.Minute
+)
+
+// SetContextTTL sets how long an authentication context waits for its
+// confirmation, DefaultContextTTL when ttl is 0. It applies to contexts
+// created afterwards.
+func (s *AuthenticationService) SetContextTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = DefaultContextTTL
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.contextTTL = ttl
+}
+
+// RunSweeper removes expired authentication contexts every interval,
+// DefaultCleanupInterval when 0, until ctx is done
+func (s *AuthenticationService) RunSweeper(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultCleanupInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if removed := s.CleanupExpiredContexts(); removed > 0 {
+				s.logger.Info("Swept expired auth contexts", zap.Int("removed", removed))
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/5g-network/common/metrics"
+)
+
+func TestCleanupExpiredContexts_ShortTTL(t *testing.T) {
+	svc := newTestAuthService(t)
+	svc.SetContextTTL(20 * time.Millisecond)
+
+	first := startAuthentication(t, svc)
+	startAuthentication(t, svc)
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.ActiveAuthContexts))
+	assert.Equal(t, 0, svc.CleanupExpiredContexts())
+
+	time.Sleep(50 * time.Millisecond)
+	expired := testutil.ToFloat64(metrics.ExpiredAuthContexts)
+	assert.Equal(t, 2, svc.CleanupExpiredContexts())
+	assert.Equal(t, expired+2, testutil.ToFloat64(metrics.ExpiredAuthContexts))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.ActiveAuthContexts))
+
+	_, err := svc.Confirm5gAkaAuth(context.Background(), first.AuthCtxID, &ConfirmationData{RES: testXRESStar})
+	assert.Error(t, err)
+}
+
+func TestRunSweeperRemovesExpiredContexts(t *testing.T) {
+	svc := newTestAuthService(t)
+	svc.SetContextTTL(10 * time.Millisecond)
+	expired := testutil.ToFloat64(metrics.ExpiredAuthContexts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go svc.RunSweeper(ctx, 10*time.Millisecond)
+
+	startAuthentication(t, svc)
+	require.Eventually(t, func() bool {
+		return svc.GetStats()["active_contexts"] == 0
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, expired+1, testutil.ToFloat64(metrics.ExpiredAuthContexts))
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.ActiveAuthContexts))
+}
+
+func TestSetContextTTLDefault(t *testing.T) {
+	svc := newTestAuthService(t)
+	svc.SetContextTTL(0)
+
+	resp := startAuthentication(t, svc)
+	authCtx, err := svc.GetAuthContext(resp.AuthCtxID)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultContextTTL, authCtx.ExpiresAt.Sub(authCtx.CreatedAt))
+}
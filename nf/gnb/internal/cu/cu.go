@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/your-org/5g-network/common/f1"
+	"github.com/your-org/5g-network/common/sctp"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -34,6 +35,10 @@ type Config struct {
 	N2Address string // AMF address
 	N3Address string // UPF address
 	F1Address string // Listen address for DU connections
+	TAC       uint32 // Tracking area of the cells, advertised in NG Setup
+
+	F1Transport sctp.Config // SCTP unless the TCP fallback is configured
+	N2Transport sctp.Config
 }
 
 // PLMNID
@@ -47,6 +52,7 @@ type UEContext struct {
 	UEID          uint32
 	GNBCUUEF1APID uint32
 	GNBDUUEF1APID uint32
+	AMFUENGAPID   uint64 // Assigned by the AMF's first Downlink NAS Transport
 	IMSI          string
 	GUTI          string
 	RRCState      string // "IDLE", "CONNECTED"
@@ -65,22 +71,27 @@ type Bearer struct {
 // F1Server handles F1 interface with DUs
 type F1Server struct {
 	cu       *CentralUnit
-	listener net.Listener
-	conns    map[string]*F1Connection
+	listener sctp.Listener
+	conns    map[string]*F1Connection // By DU address
+	ues      map[uint32]*F1Connection // By gNB-DU UE F1AP ID
 	mu       sync.RWMutex
 }
 
-// F1Connection represents a connection to a DU
+// F1Connection represents the F1 association with a DU
 type F1Connection struct {
 	GNBDUID uint64
-	conn    net.Conn
+	assoc   sctp.Association
+
+	// UE Context Setup Requests awaiting a response, by gNB-CU UE F1AP ID
+	pending map[uint32]chan *f1.UEContextSetupResponse
+	mu      sync.Mutex
 }
 
 // N2Client handles NGAP to AMF
 type N2Client struct {
 	cu      *CentralUnit
 	amfAddr string
-	conn    net.Conn
+	assoc   sctp.Association
 }
 
 // N3Client handles GTP-U to UPF
@@ -150,6 +161,7 @@ func (cu *CentralUnit) HandleRRCSetupRequest(ctx context.Context, ueID uint32, m
 	ueCtx := &UEContext{
 		UEID:          ueID,
 		GNBCUUEF1APID: cu.generateF1APID(),
+		GNBDUUEF1APID: ueID,
 		RRCState:      "CONNECTED",
 		Bearers:       make(map[uint8]*Bearer),
 		CreatedAt:     time.Now(),
@@ -160,7 +172,7 @@ func (cu *CentralUnit) HandleRRCSetupRequest(ctx context.Context, ueID uint32, m
 	rrcSetup := cu.createRRCSetup(ueCtx)
 
 	// Send RRC Setup to UE via DU (F1)
-	if err := cu.f1Server.SendDLRRCMessage(ctx, ueCtx.GNBDUUEF1APID, 1, rrcSetup); err != nil {
+	if err := cu.f1Server.SendDLRRCMessage(ctx, ueCtx, 1, rrcSetup); err != nil {
 		return fmt.Errorf("failed to send RRC Setup: %w", err)
 	}
 
@@ -223,7 +235,14 @@ func (cu *CentralUnit) HandlePDUSessionSetupRequest(ctx context.Context, req *PD
 							QoSPriorityLevel: req.QoS.Priority,
 						},
 					},
+					NGRANAllocationRetentionPriority: &f1.AllocationRetentionPriority{
+						PriorityLevel:           15, // Lowest; ARP is not signalled by the SMF here
+						PreemptionCapability:    "SHALL_NOT_TRIGGER_PREEMPTION",
+						PreemptionVulnerability: "NOT_PREEMPTABLE",
+					},
 				},
+				SNSSAI:     req.SNSSAI,
+				QoSFlowIDs: []uint8{req.QoS.QFI},
 				ULUPTNLInfo: []*f1.UPTransportLayerInformation{
 					{
 						GTPTunnel: &f1.GTPTunnel{
@@ -331,6 +350,7 @@ type PDUSessionSetupRequest struct {
 	UEID        uint32
 	DRBID       uint8
 	QoS         QoSParameters
+	SNSSAI      *f1.SliceSupport
 	UPFAddress  net.IP
 	UPFTEID     uint32
 	UEIPAddress net.IP
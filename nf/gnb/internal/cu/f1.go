@@ -0,0 +1,236 @@
+package cu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/your-org/5g-network/common/f1"
+	"github.com/your-org/5g-network/common/sctp"
+	"go.uber.org/zap"
+)
+
+// NewF1Server listens for gNB-DU associations on address, over SCTP or the
+// TCP fallback as configured
+func NewF1Server(cu *CentralUnit, address string) (*F1Server, error) {
+	listener, err := f1.Listen(cu.config.F1Transport, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", address, err)
+	}
+
+	return &F1Server{
+		cu:       cu,
+		listener: listener,
+		conns:    make(map[string]*F1Connection),
+		ues:      make(map[uint32]*F1Connection),
+	}, nil
+}
+
+// Listen accepts DU associations until the server is closed
+func (s *F1Server) Listen() {
+	for {
+		assoc, err := s.listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			s.cu.logger.Warn("Failed to accept F1 association", zap.Error(err))
+			continue
+		}
+
+		conn := &F1Connection{
+			assoc:   assoc,
+			pending: make(map[uint32]chan *f1.UEContextSetupResponse),
+		}
+		s.mu.Lock()
+		s.conns[assoc.RemoteAddr().String()] = conn
+		s.mu.Unlock()
+
+		s.cu.logger.Info("F1 association established",
+			zap.String("du_address", assoc.RemoteAddr().String()),
+			zap.Uint16("streams", assoc.Streams()),
+		)
+		go s.serve(conn)
+	}
+}
+
+// serve handles the F1AP messages of one DU until the association ends
+func (s *F1Server) serve(conn *F1Connection) {
+	defer s.remove(conn)
+
+	for {
+		pdu, _, err := conn.assoc.Receive()
+		if errors.Is(err, sctp.ErrAssociationClosed) || errors.Is(err, net.ErrClosed) {
+			s.cu.logger.Info("F1 association closed",
+				zap.String("du_address", conn.assoc.RemoteAddr().String()))
+			return
+		}
+		if err != nil {
+			s.cu.logger.Warn("F1 association failed", zap.Error(err))
+			return
+		}
+
+		msg, err := f1.Decode(pdu)
+		if err != nil {
+			s.cu.logger.Warn("Discarding malformed F1AP message", zap.Error(err))
+			continue
+		}
+
+		switch m := msg.(type) {
+		case *f1.F1SetupRequest:
+			s.handleF1Setup(conn, m)
+		case *f1.InitialULRRCMessage:
+			// Not inline: the CU may be holding its lock awaiting a
+			// response this loop has to deliver
+			go s.handleInitialULRRCMessage(conn, m)
+		case *f1.UEContextSetupResponse:
+			conn.deliver(m)
+		default:
+			s.cu.logger.Debug("Ignoring F1AP message", zap.Int("procedure", msg.MessageType()))
+		}
+	}
+}
+
+// handleF1Setup accepts the DU and activates all the cells it serves
+func (s *F1Server) handleF1Setup(conn *F1Connection, req *f1.F1SetupRequest) {
+	conn.GNBDUID = req.GNBDUID
+
+	resp := &f1.F1SetupResponse{
+		TransactionID:   req.TransactionID,
+		GNBCUNAME:       s.cu.config.GNBCUName,
+		GNBCURRCVersion: &f1.RRCVersion{Latest: []byte{0xE0}},
+	}
+	for _, cell := range req.ServedCellsToAdd {
+		if cell.ServedCellInfo == nil {
+			continue
+		}
+		resp.CellsToActivate = append(resp.CellsToActivate, &f1.CellToActivate{NRCGI: cell.ServedCellInfo.NRCGI})
+	}
+
+	if err := f1.SendMessage(conn.assoc, resp); err != nil {
+		s.cu.logger.Warn("Failed to send F1 Setup Response", zap.Error(err))
+		return
+	}
+
+	s.cu.logger.Info("F1 Setup completed",
+		zap.Uint64("gnb_du_id", req.GNBDUID),
+		zap.String("gnb_du_name", req.GNBDUName),
+		zap.Int("cells", len(resp.CellsToActivate)),
+	)
+}
+
+// handleInitialULRRCMessage starts RRC connection setup for a new UE. The
+// gNB-DU UE F1AP ID identifies the UE at the CU; such IDs are assumed
+// unique across DUs.
+func (s *F1Server) handleInitialULRRCMessage(conn *F1Connection, msg *f1.InitialULRRCMessage) {
+	s.mu.Lock()
+	s.ues[msg.GNBDUUEF1APID] = conn
+	s.mu.Unlock()
+
+	if err := s.cu.HandleRRCSetupRequest(context.Background(), msg.GNBDUUEF1APID,
+		&RRCSetupRequest{UEIdentity: msg.RRCContainer}); err != nil {
+		s.cu.logger.Warn("Failed to handle RRC Setup Request",
+			zap.Uint32("gnb_du_ue_f1ap_id", msg.GNBDUUEF1APID),
+			zap.Error(err))
+	}
+}
+
+// SendDLRRCMessage sends an RRC message to a UE on an SRB
+func (s *F1Server) SendDLRRCMessage(ctx context.Context, ueCtx *UEContext, srbID uint8, rrc []byte) error {
+	conn, err := s.connectionFor(ueCtx.GNBDUUEF1APID)
+	if err != nil {
+		return err
+	}
+
+	return f1.SendMessage(conn.assoc, &f1.DLRRCMessage{
+		GNBCUUEF1APID: ueCtx.GNBCUUEF1APID,
+		GNBDUUEF1APID: ueCtx.GNBDUUEF1APID,
+		SRBID:         srbID,
+		RRCContainer:  rrc,
+	})
+}
+
+// SendUEContextSetupRequest sets up a UE context on the DU serving the UE
+// and waits for the response
+func (s *F1Server) SendUEContextSetupRequest(ctx context.Context, req *f1.UEContextSetupRequest) (*f1.UEContextSetupResponse, error) {
+	conn, err := s.connectionFor(req.GNBDUUEF1APID)
+	if err != nil {
+		return nil, err
+	}
+
+	respCh := make(chan *f1.UEContextSetupResponse, 1)
+	conn.mu.Lock()
+	conn.pending[req.GNBCUUEF1APID] = respCh
+	conn.mu.Unlock()
+	defer func() {
+		conn.mu.Lock()
+		delete(conn.pending, req.GNBCUUEF1APID)
+		conn.mu.Unlock()
+	}()
+
+	if err := f1.SendMessage(conn.assoc, req); err != nil {
+		return nil, err
+	}
+
+	select {
+	case resp := <-respCh:
+		if len(resp.DRBsSetup) == 0 && len(req.DRBsToBeSetup) > 0 {
+			return nil, fmt.Errorf("DU failed to set up the DRBs of UE %d", req.GNBDUUEF1APID)
+		}
+		return resp, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// connectionFor returns the association of the DU serving a UE
+func (s *F1Server) connectionFor(duUEID uint32) (*F1Connection, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	conn, exists := s.ues[duUEID]
+	if !exists {
+		return nil, fmt.Errorf("no F1 association for gNB-DU UE F1AP ID %d", duUEID)
+	}
+	return conn, nil
+}
+
+// remove forgets a DU whose association has ended, with its UEs
+func (s *F1Server) remove(conn *F1Connection) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.conns, conn.assoc.RemoteAddr().String())
+	for id, c := range s.ues {
+		if c == conn {
+			delete(s.ues, id)
+		}
+	}
+	conn.assoc.Close()
+}
+
+// Close stops accepting DUs and ends all associations
+func (s *F1Server) Close() error {
+	err := s.listener.Close()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, conn := range s.conns {
+		conn.assoc.Close()
+	}
+	return err
+}
+
+// deliver passes a UE Context Setup Response to the request waiting for it
+func (c *F1Connection) deliver(resp *f1.UEContextSetupResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if ch, exists := c.pending[resp.GNBCUUEF1APID]; exists {
+		select {
+		case ch <- resp:
+		default:
+		}
+	}
+}
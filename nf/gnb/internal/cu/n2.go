@@ -0,0 +1,162 @@
+package cu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/your-org/5g-network/common/ngap"
+	"github.com/your-org/5g-network/common/sctp"
+	"go.uber.org/zap"
+)
+
+const (
+	n2DialTimeout = 5 * time.Second
+
+	// defaultPagingDRX is advertised in NG Setup, in radio frames
+	defaultPagingDRX = 128
+
+	// servedCellID is the NR cell ID of the one cell the CU serves, as in
+	// its F1 UE context setup
+	servedCellID = 1
+)
+
+// NewN2Client establishes the NG association with the AMF at address and
+// runs NG Setup
+func NewN2Client(cu *CentralUnit, address string) (*N2Client, error) {
+	if cu.config.GNBCUID >= 1<<32 {
+		return nil, fmt.Errorf("gnb-cu id %d does not fit a 32-bit gnb id", cu.config.GNBCUID)
+	}
+
+	assoc, err := ngap.Dial(cu.config.N2Transport, address, n2DialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to AMF at %s: %w", address, err)
+	}
+	c := &N2Client{cu: cu, amfAddr: address, assoc: assoc}
+
+	if err := c.setup(); err != nil {
+		assoc.Close()
+		return nil, err
+	}
+	go c.serve()
+	return c, nil
+}
+
+// setup runs NG Setup, advertising the CU's tracking area and PLMN
+func (c *N2Client) setup() error {
+	plmn := c.plmn()
+	err := ngap.SendMessage(c.assoc, &ngap.NGSetupRequest{
+		// The gNB-CU ID doubles as a 32-bit gNB ID
+		GlobalRANNodeID: &ngap.GlobalGNBID{PLMNID: plmn, GNBID: uint32(c.cu.config.GNBCUID), GNBIDLength: 32},
+		RANNodeName:     c.cu.config.GNBCUName,
+		SupportedTAs: []*ngap.SupportedTA{{
+			TAC: c.cu.config.TAC,
+			BroadcastPLMNs: []*ngap.BroadcastPLMN{{
+				PLMNID:           plmn,
+				SliceSupportList: []*ngap.SNSSAI{{SST: 1}}, // eMBB
+			}},
+		}},
+		DefaultPagingDRX: defaultPagingDRX,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send NG Setup Request: %w", err)
+	}
+
+	msg, err := ngap.ReceiveMessage(c.assoc)
+	if err != nil {
+		return fmt.Errorf("failed to receive NG Setup Response: %w", err)
+	}
+	resp, ok := msg.(*ngap.NGSetupResponse)
+	if !ok {
+		return fmt.Errorf("unexpected NGAP message type %d in NG Setup", msg.MessageType())
+	}
+
+	c.cu.logger.Info("NG Setup completed",
+		zap.String("amf_address", c.amfAddr),
+		zap.String("amf_name", resp.AMFName),
+		zap.Int("served_guamis", len(resp.ServedGUAMIs)),
+	)
+	return nil
+}
+
+// SendInitialUEMessage sends the first NAS message of a UE to the AMF. The
+// CU's UE ID is the RAN UE NGAP ID.
+func (c *N2Client) SendInitialUEMessage(ctx context.Context, msg *InitialUEMessage) error {
+	cause := msg.EstablishmentCause
+	if cause == "" {
+		cause = "mo-Signalling"
+	}
+
+	plmn := c.plmn()
+	return ngap.SendMessage(c.assoc, &ngap.InitialUEMessage{
+		RANUENGAPID: msg.UEID,
+		NASPDU:      msg.NASMessage,
+		UserLocation: &ngap.UserLocationInformationNR{
+			NRCGI: &ngap.NRCGI{PLMNID: plmn, NRCellID: servedCellID},
+			TAI:   &ngap.TAI{PLMNID: plmn, TAC: c.cu.config.TAC},
+		},
+		RRCEstablishmentCause: cause,
+	})
+}
+
+// serve handles the NGAP messages of the AMF until the association ends
+func (c *N2Client) serve() {
+	for {
+		pdu, _, err := c.assoc.Receive()
+		if errors.Is(err, sctp.ErrAssociationClosed) || errors.Is(err, net.ErrClosed) {
+			c.cu.logger.Info("NG association closed", zap.String("amf_address", c.amfAddr))
+			return
+		}
+		if err != nil {
+			c.cu.logger.Warn("NG association failed", zap.Error(err))
+			return
+		}
+
+		msg, err := ngap.Decode(pdu)
+		if err != nil {
+			c.cu.logger.Warn("Discarding malformed NGAP message", zap.Error(err))
+			continue
+		}
+
+		switch m := msg.(type) {
+		case *ngap.DownlinkNASTransport:
+			c.handleDownlinkNASTransport(m)
+		default:
+			c.cu.logger.Debug("Ignoring NGAP message", zap.Int("message_type", msg.MessageType()))
+		}
+	}
+}
+
+// handleDownlinkNASTransport passes a NAS message from the AMF to the UE
+// on SRB1
+func (c *N2Client) handleDownlinkNASTransport(msg *ngap.DownlinkNASTransport) {
+	c.cu.mu.Lock()
+	ueCtx, exists := c.cu.ueContexts[msg.RANUENGAPID]
+	if exists {
+		ueCtx.AMFUENGAPID = msg.AMFUENGAPID
+	}
+	c.cu.mu.Unlock()
+
+	if !exists {
+		c.cu.logger.Warn("Downlink NAS Transport for an unknown UE",
+			zap.Uint32("ran_ue_ngap_id", msg.RANUENGAPID))
+		return
+	}
+
+	if err := c.cu.f1Server.SendDLRRCMessage(context.Background(), ueCtx, 1, msg.NASPDU); err != nil {
+		c.cu.logger.Warn("Failed to forward downlink NAS message",
+			zap.Uint32("ran_ue_ngap_id", msg.RANUENGAPID),
+			zap.Error(err))
+	}
+}
+
+func (c *N2Client) plmn() *ngap.PLMNID {
+	return &ngap.PLMNID{MCC: c.cu.config.PLMN.MCC, MNC: c.cu.config.PLMN.MNC}
+}
+
+// Close ends the NG association
+func (c *N2Client) Close() error {
+	return c.assoc.Close()
+}
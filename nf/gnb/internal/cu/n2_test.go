@@ -0,0 +1,83 @@
+package cu
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/your-org/5g-network/common/ngap"
+	"github.com/your-org/5g-network/common/sctp"
+	"go.uber.org/zap"
+)
+
+// fakeAMF accepts one gNB, answers its NG Setup and hands over the NGAP
+// messages that follow
+func fakeAMF(t *testing.T, transport sctp.Config) (string, <-chan ngap.Message) {
+	t.Helper()
+
+	listener, err := ngap.Listen(transport, "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	msgs := make(chan ngap.Message, 4)
+	go func() {
+		defer close(msgs)
+		assoc, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer assoc.Close()
+
+		for {
+			msg, err := ngap.ReceiveMessage(assoc)
+			if err != nil {
+				return
+			}
+			if _, ok := msg.(*ngap.NGSetupRequest); ok {
+				ngap.SendMessage(assoc, &ngap.NGSetupResponse{
+					AMFName:             "amf-1",
+					ServedGUAMIs:        []*ngap.GUAMI{{PLMNID: &ngap.PLMNID{MCC: "001", MNC: "01"}, AMFRegionID: 128, AMFSetID: 1, AMFPointer: 1}},
+					RelativeAMFCapacity: 255,
+					PLMNSupportList:     []*ngap.PLMNSupport{{PLMNID: &ngap.PLMNID{MCC: "001", MNC: "01"}, SliceSupportList: []*ngap.SNSSAI{{SST: 1}}}},
+				})
+			}
+			msgs <- msg
+		}
+	}()
+	return listener.Addr().String(), msgs
+}
+
+func TestHandleInitialUEMessage_SendsNGAP(t *testing.T) {
+	transport := sctp.Config{Protocol: sctp.PROTOCOL_TCP}
+	amfAddr, msgs := fakeAMF(t, transport)
+
+	logger, _ := zap.NewDevelopment()
+	cu := NewCentralUnit(&Config{
+		GNBCUID:     1,
+		GNBCUName:   "cu-1",
+		PLMN:        &PLMNID{MCC: "001", MNC: "01"},
+		TAC:         1,
+		N2Transport: transport,
+	}, logger)
+
+	n2Client, err := NewN2Client(cu, amfAddr)
+	require.NoError(t, err)
+	defer n2Client.Close()
+	cu.n2Client = n2Client
+
+	setup, ok := (<-msgs).(*ngap.NGSetupRequest)
+	require.True(t, ok)
+	assert.Equal(t, "cu-1", setup.RANNodeName)
+	assert.Equal(t, uint32(1), setup.SupportedTAs[0].TAC)
+
+	nasPDU := []byte{0x7e, 0x00, 0x41, 0x79}
+	require.NoError(t, cu.HandleInitialUEMessage(context.Background(), &InitialUEMessage{UEID: 5, NASMessage: nasPDU}))
+
+	initial, ok := (<-msgs).(*ngap.InitialUEMessage)
+	require.True(t, ok)
+	assert.Equal(t, uint32(5), initial.RANUENGAPID)
+	assert.Equal(t, nasPDU, initial.NASPDU)
+	assert.Equal(t, "mo-Signalling", initial.RRCEstablishmentCause)
+	assert.Equal(t, uint32(1), initial.UserLocation.TAI.TAC)
+}
@@ -9,7 +9,9 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/your-org/5g-network/common/buildinfo"
 	"github.com/your-org/5g-network/common/metrics"
+	"github.com/your-org/5g-network/common/reload"
 	"github.com/your-org/5g-network/nf/nrf/internal/config"
 	"github.com/your-org/5g-network/nf/nrf/internal/server"
 	"go.uber.org/zap"
@@ -19,16 +21,22 @@ import (
 var (
 	Version   = "dev"
 	BuildTime = "unknown"
+	GitCommit = "unknown"
 )
 
 func main() {
 	// Parse command-line flags
 	configPath := flag.String("config", "config/nrf.yaml", "Path to configuration file")
-	logLevel := flag.String("log-level", "info", "Log level (debug, info, warn, error)")
+	logLevel := flag.String("log-level", "", "Log level (debug, info, warn, error), overrides observability.logging.level")
 	flag.Parse()
 
-	// Initialize logger
-	logger := initLogger(*logLevel)
+	// Initialize logger, at the configured level once loaded unless set on
+	// the command line
+	level, err := zap.ParseAtomicLevel(*logLevel)
+	if err != nil {
+		level = zap.NewAtomicLevel()
+	}
+	logger := initLogger(level)
 	defer logger.Sync()
 
 	logger.Info("Starting NRF (Network Repository Function)",
@@ -47,14 +55,27 @@ func main() {
 		zap.Int("sbi_port", cfg.SBI.Port),
 	)
 
+	// Reload the log level on SIGHUP
+	reloader := reload.New(*configPath, cfg, config.Load, []string{
+		"observability.logging.level",
+	}, logger)
+	if *logLevel == "" {
+		if err := reloader.SetLogLevel(level, func(c *config.Config) string { return c.Observability.Logging.Level }); err != nil {
+			logger.Fatal("Invalid configuration", zap.Error(err))
+		}
+	}
+
 	// Create context
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Initialize metrics server
-	metricsServer := metrics.NewMetricsServer(9090, logger)
+	// Initialize metrics server, on the port assigned to the NRF
+	metricsRegistry, err := metrics.NewRegistry("NRF", cfg.NF.InstanceID)
+	if err != nil {
+		logger.Fatal("Failed to create metrics registry", zap.Error(err))
+	}
+	metricsServer := metrics.NewMetricsServer(metricsRegistry, logger)
 	go func() {
-		logger.Info("Starting metrics server on :9090")
 		if err := metricsServer.Start(); err != nil {
 			logger.Error("Metrics server error", zap.Error(err))
 		}
@@ -70,6 +91,10 @@ func main() {
 	if err != nil {
 		logger.Fatal("Failed to create NRF server", zap.Error(err))
 	}
+	nrfServer.SetBuildInfo(buildinfo.New("NRF", Version, GitCommit, BuildTime,
+		"nnrf-nfm", "nnrf-disc", "nnrf-oauth2"))
+
+	go reloader.Watch(ctx)
 
 	// Start server in goroutine
 	errChan := make(chan error, 1)
@@ -107,17 +132,11 @@ func main() {
 	logger.Info("NRF stopped")
 }
 
-// initLogger initializes the logger
-func initLogger(level string) *zap.Logger {
-	// Parse log level
-	var zapLevel zapcore.Level
-	if err := zapLevel.UnmarshalText([]byte(level)); err != nil {
-		zapLevel = zapcore.InfoLevel
-	}
-
+// initLogger initializes the logger, whose level can change at runtime
+func initLogger(level zap.AtomicLevel) *zap.Logger {
 	// Create logger config
 	config := zap.Config{
-		Level:            zap.NewAtomicLevelAt(zapLevel),
+		Level:            level,
 		Development:      false,
 		Encoding:         "json",
 		EncoderConfig:    zap.NewProductionEncoderConfig(),
@@ -3,7 +3,10 @@ package config
 import (
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/your-org/5g-network/common/oauth"
+	"github.com/your-org/5g-network/common/tlsconfig"
 	"gopkg.in/yaml.v3"
 )
 
@@ -17,10 +20,11 @@ type Config struct {
 
 // SBIConfig holds Service Based Interface configuration
 type SBIConfig struct {
-	Scheme      string    `yaml:"scheme"`       // http or https
-	BindAddress string    `yaml:"bind_address"` // 0.0.0.0
-	Port        int       `yaml:"port"`         // 8080
-	TLS         TLSConfig `yaml:"tls"`
+	Scheme      string                 `yaml:"scheme"`       // http or https
+	BindAddress string                 `yaml:"bind_address"` // 0.0.0.0
+	Port        int                    `yaml:"port"`         // 8080
+	TLS         TLSConfig              `yaml:"tls"`
+	ClientTLS   tlsconfig.ClientConfig `yaml:"client_tls"` // TLS towards the SBIs of other NFs
 }
 
 // TLSConfig holds TLS configuration
@@ -28,6 +32,8 @@ type TLSConfig struct {
 	Enabled  bool   `yaml:"enabled"`
 	CertFile string `yaml:"cert_file"`
 	KeyFile  string `yaml:"key_file"`
+
+	tlsconfig.Options `yaml:",inline"`
 }
 
 // NFConfig holds NF-specific configuration
@@ -37,7 +43,9 @@ type NFConfig struct {
 	Description string `yaml:"description"`
 
 	// NF Management configuration
-	Heartbeat HeartbeatConfig `yaml:"heartbeat"`
+	Heartbeat    HeartbeatConfig    `yaml:"heartbeat"`
+	Notification NotificationConfig `yaml:"notification"`
+	OAuth2       OAuth2Config       `yaml:"oauth2"`
 }
 
 // HeartbeatConfig holds heartbeat configuration
@@ -47,11 +55,41 @@ type HeartbeatConfig struct {
 	Timeout  int  `yaml:"timeout"`  // seconds
 }
 
+// NotificationConfig controls delivery of NF status notifications to
+// subscribers (TS 29.510 5.2.2.6)
+type NotificationConfig struct {
+	APIRoot       string        `yaml:"api_root"`       // NRF apiRoot used in nfInstanceUri
+	Workers       int           `yaml:"workers"`        // Concurrent callback deliveries
+	QueueSize     int           `yaml:"queue_size"`     // Pending notifications before new ones are dropped
+	Timeout       time.Duration `yaml:"timeout"`        // Deadline per callback attempt
+	MaxRetries    int           `yaml:"max_retries"`    // Retries after a 5xx or transport error
+	RetryInterval time.Duration `yaml:"retry_interval"` // Initial backoff, doubled per retry
+	MarkStale     bool          `yaml:"mark_stale"`     // Stop notifying a subscription once delivery gives up
+}
+
+// OAuth2Config controls the access token service (TS 29.510 5.4)
+type OAuth2Config struct {
+	Enabled        bool          `yaml:"enabled"`
+	SigningKey     string        `yaml:"signing_key"`      // HS256 key shared with producer NFs
+	SigningKeyFile string        `yaml:"signing_key_file"` // Read instead of signing_key when set
+	TokenTTL       time.Duration `yaml:"token_ttl"`        // Access token lifetime
+
+	// ClientSecrets authenticates token requests, by consumer NF instance ID
+	// (RFC 6749 2.3.1)
+	ClientSecrets map[string]string `yaml:"client_secrets"`
+}
+
+// Key returns the configured signing key
+func (c *OAuth2Config) Key() ([]byte, error) {
+	return oauth.LoadKey(c.SigningKey, c.SigningKeyFile)
+}
+
 // DatabaseConfig holds database configuration
 type DatabaseConfig struct {
 	Type      string `yaml:"type"`      // memory, redis, clickhouse
 	URL       string `yaml:"url"`       // connection string
 	Retention int    `yaml:"retention"` // days
+	Path      string `yaml:"path"`      // memory: state file, empty disables persistence
 }
 
 // ObservabilityConfig holds observability configuration
@@ -120,12 +158,36 @@ func (c *Config) Validate() error {
 		if c.SBI.TLS.CertFile == "" || c.SBI.TLS.KeyFile == "" {
 			return fmt.Errorf("TLS enabled but cert/key files not specified")
 		}
+		if err := c.SBI.TLS.Validate(); err != nil {
+			return fmt.Errorf("invalid TLS configuration: %w", err)
+		}
+	}
+
+	if err := c.SBI.ClientTLS.Validate(); err != nil {
+		return fmt.Errorf("invalid client TLS configuration: %w", err)
 	}
 
 	if c.NF.InstanceID == "" {
 		return fmt.Errorf("NF instance ID is required")
 	}
 
+	if c.NF.OAuth2.Enabled {
+		if _, err := c.NF.OAuth2.Key(); err != nil {
+			return fmt.Errorf("invalid OAuth2 configuration: %w", err)
+		}
+		if c.NF.OAuth2.TokenTTL <= 0 {
+			return fmt.Errorf("OAuth2 token TTL must be positive")
+		}
+		if len(c.NF.OAuth2.ClientSecrets) == 0 {
+			return fmt.Errorf("OAuth2 client_secrets are required to authenticate token requests")
+		}
+		for id, secret := range c.NF.OAuth2.ClientSecrets {
+			if secret == "" {
+				return fmt.Errorf("OAuth2 client secret for %s is empty", id)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -149,6 +211,16 @@ func DefaultConfig() *Config {
 				Interval: 30,
 				Timeout:  60,
 			},
+			Notification: NotificationConfig{
+				Workers:       4,
+				QueueSize:     1000,
+				Timeout:       5 * time.Second,
+				MaxRetries:    3,
+				RetryInterval: 500 * time.Millisecond,
+			},
+			OAuth2: OAuth2Config{
+				TokenTTL: time.Hour,
+			},
 		},
 		Database: DatabaseConfig{
 			Type:      "memory",
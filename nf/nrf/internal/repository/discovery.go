@@ -56,13 +56,19 @@ func (q *DiscoveryQuery) Matches(profile *NFProfile) bool {
 		}
 	}
 
-	// Match S-NSSAIs
+	// Match S-NSSAIs, including the slices advertised in SMF/UPF info
 	if len(q.SNSSAIs) > 0 {
-		if !q.matchesSNSSAIs(profile.SNSSAIs) {
+		if !q.matchesSNSSAIs(supportedSNSSAIs(profile)) {
 			return false
 		}
 	}
 
+	// Match DNN. Only SMF and UPF profiles advertise DNNs; when S-NSSAIs are
+	// also requested the DNN must be served on one of them.
+	if q.DNN != "" && !q.matchesDNN(profile) {
+		return false
+	}
+
 	// Match Service Names
 	if len(q.ServiceNames) > 0 {
 		if !q.matchesServiceNames(profile.NFServices) {
@@ -88,9 +94,6 @@ func (q *DiscoveryQuery) Matches(profile *NFProfile) bool {
 
 	// SMF-specific matching
 	if q.NFType == NFTypeSMF && profile.SMFInfo != nil {
-		if q.DNN != "" && !q.matchesDNN(profile.SMFInfo) {
-			return false
-		}
 		if q.TAI != nil && !q.matchesTAI(profile.SMFInfo.TaiList) {
 			return false
 		}
@@ -108,17 +111,20 @@ func (q *DiscoveryQuery) matchesPLMNID(plmnID *PLMNID) bool {
 func (q *DiscoveryQuery) matchesSNSSAIs(snssais []SNSSAI) bool {
 	for _, querySnssai := range q.SNSSAIs {
 		for _, profileSnssai := range snssais {
-			if querySnssai.SST == profileSnssai.SST {
-				// If SD is specified, it must match
-				if querySnssai.SD == "" || querySnssai.SD == profileSnssai.SD {
-					return true
-				}
+			if matchesSNSSAI(querySnssai, profileSnssai) {
+				return true
 			}
 		}
 	}
 	return false
 }
 
+// matchesSNSSAI checks if a profile S-NSSAI satisfies a queried one. If the
+// query specifies an SD, it must match.
+func matchesSNSSAI(query, profile SNSSAI) bool {
+	return query.SST == profile.SST && (query.SD == "" || query.SD == profile.SD)
+}
+
 // matchesServiceNames checks if any service name matches
 func (q *DiscoveryQuery) matchesServiceNames(services []NFService) bool {
 	for _, queryService := range q.ServiceNames {
@@ -163,10 +169,14 @@ func (q *DiscoveryQuery) matchesTAI(tais []TAI) bool {
 	return false
 }
 
-// matchesDNN checks if the SMF supports the requested DNN
-func (q *DiscoveryQuery) matchesDNN(smfInfo *SMFInfo) bool {
-	for _, snssaiInfo := range smfInfo.SMFInfoList {
-		for _, dnn := range snssaiInfo.DNNList {
+// matchesDNN checks if an SMF or UPF serves the requested DNN on a requested
+// S-NSSAI, or on any S-NSSAI when none is requested
+func (q *DiscoveryQuery) matchesDNN(profile *NFProfile) bool {
+	for _, info := range sliceDNNs(profile) {
+		if len(q.SNSSAIs) > 0 && !q.matchesSNSSAIs([]SNSSAI{info.SNSSAI}) {
+			continue
+		}
+		for _, dnn := range info.DNNList {
 			if dnn == q.DNN {
 				return true
 			}
@@ -174,3 +184,35 @@ func (q *DiscoveryQuery) matchesDNN(smfInfo *SMFInfo) bool {
 	}
 	return false
 }
+
+// sliceDNNs returns the DNNs per S-NSSAI advertised in an SMF or UPF profile
+func sliceDNNs(profile *NFProfile) []SNSSAIInfo {
+	switch {
+	case profile.NFType == NFTypeSMF && profile.SMFInfo != nil:
+		return profile.SMFInfo.SMFInfoList
+	case profile.NFType == NFTypeUPF && profile.UPFInfo != nil:
+		infos := make([]SNSSAIInfo, 0, len(profile.UPFInfo.SNSSAIUPFInfoList))
+		for _, item := range profile.UPFInfo.SNSSAIUPFInfoList {
+			info := SNSSAIInfo{SNSSAI: item.SNSSAI}
+			for _, dnn := range item.DNNUPFInfoList {
+				info.DNNList = append(info.DNNList, dnn.DNN)
+			}
+			infos = append(infos, info)
+		}
+		return infos
+	}
+	return nil
+}
+
+// supportedSNSSAIs returns the S-NSSAIs of a profile together with those
+// advertised in its SMF or UPF info
+func supportedSNSSAIs(profile *NFProfile) []SNSSAI {
+	snssais := append([]SNSSAI(nil), profile.SNSSAIs...)
+	if profile.NFType == NFTypeSMF && profile.SMFInfo != nil {
+		snssais = append(snssais, profile.SMFInfo.SNSSAIs...)
+	}
+	for _, info := range sliceDNNs(profile) {
+		snssais = append(snssais, info.SNSSAI)
+	}
+	return snssais
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var (
+	embbSlice  = SNSSAI{SST: 1, SD: "010203"}
+	urllcSlice = SNSSAI{SST: 2, SD: "000001"}
+)
+
+func smfProfile(id string, infos ...SNSSAIInfo) *NFProfile {
+	return &NFProfile{
+		NFInstanceID: id,
+		NFType:       NFTypeSMF,
+		NFStatus:     NFStatusRegistered,
+		SMFInfo:      &SMFInfo{SMFInfoList: infos},
+	}
+}
+
+func upfProfile(id string, snssai SNSSAI, dnns ...string) *NFProfile {
+	item := SNSSAIUPFInfoItem{SNSSAI: snssai}
+	for _, dnn := range dnns {
+		item.DNNUPFInfoList = append(item.DNNUPFInfoList, DNNUPFInfoItem{DNN: dnn})
+	}
+	return &NFProfile{
+		NFInstanceID: id,
+		NFType:       NFTypeUPF,
+		NFStatus:     NFStatusRegistered,
+		UPFInfo:      &UPFInfo{SNSSAIUPFInfoList: []SNSSAIUPFInfoItem{item}},
+	}
+}
+
+func newDiscoveryRepository(t *testing.T) *MemoryRepository {
+	t.Helper()
+
+	logger, _ := zap.NewDevelopment()
+	repo := NewMemoryRepository(logger)
+	t.Cleanup(func() { repo.Close() })
+
+	profiles := []*NFProfile{
+		smfProfile("smf-internet", SNSSAIInfo{SNSSAI: embbSlice, DNNList: []string{"internet"}}),
+		smfProfile("smf-ims", SNSSAIInfo{SNSSAI: embbSlice, DNNList: []string{"ims"}}),
+		smfProfile("smf-both",
+			SNSSAIInfo{SNSSAI: embbSlice, DNNList: []string{"internet", "ims"}},
+			SNSSAIInfo{SNSSAI: urllcSlice, DNNList: []string{"factory"}},
+		),
+		{
+			NFInstanceID: "smf-no-info",
+			NFType:       NFTypeSMF,
+			NFStatus:     NFStatusRegistered,
+		},
+		upfProfile("upf-internet", embbSlice, "internet"),
+		upfProfile("upf-factory", urllcSlice, "factory"),
+		{
+			NFInstanceID: "amf-1",
+			NFType:       NFTypeAMF,
+			NFStatus:     NFStatusRegistered,
+			SNSSAIs:      []SNSSAI{embbSlice},
+		},
+	}
+	for _, p := range profiles {
+		require.NoError(t, repo.Register(context.Background(), p))
+	}
+	return repo
+}
+
+func discoverIDs(t *testing.T, repo *MemoryRepository, query *DiscoveryQuery) []string {
+	t.Helper()
+
+	results, err := repo.Discover(context.Background(), query)
+	require.NoError(t, err)
+
+	ids := make([]string, 0, len(results))
+	for _, p := range results {
+		ids = append(ids, p.NFInstanceID)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestDiscover_FiltersSMFsByDNN(t *testing.T) {
+	repo := newDiscoveryRepository(t)
+
+	ids := discoverIDs(t, repo, &DiscoveryQuery{NFType: NFTypeSMF, DNN: "internet"})
+	assert.Equal(t, []string{"smf-both", "smf-internet"}, ids)
+
+	ids = discoverIDs(t, repo, &DiscoveryQuery{NFType: NFTypeSMF, DNN: "ims"})
+	assert.Equal(t, []string{"smf-both", "smf-ims"}, ids)
+
+	ids = discoverIDs(t, repo, &DiscoveryQuery{NFType: NFTypeSMF, DNN: "unknown"})
+	assert.Empty(t, ids)
+}
+
+func TestDiscover_FiltersUPFsByDNN(t *testing.T) {
+	repo := newDiscoveryRepository(t)
+
+	ids := discoverIDs(t, repo, &DiscoveryQuery{NFType: NFTypeUPF, DNN: "factory"})
+	assert.Equal(t, []string{"upf-factory"}, ids)
+}
+
+func TestDiscover_DNNWithoutTypeSkipsProfilesWithoutInfo(t *testing.T) {
+	repo := newDiscoveryRepository(t)
+
+	ids := discoverIDs(t, repo, &DiscoveryQuery{DNN: "internet"})
+	assert.Equal(t, []string{"smf-both", "smf-internet", "upf-internet"}, ids)
+}
+
+func TestDiscover_FiltersBySNSSAI(t *testing.T) {
+	repo := newDiscoveryRepository(t)
+
+	ids := discoverIDs(t, repo, &DiscoveryQuery{SNSSAIs: []SNSSAI{urllcSlice}})
+	assert.Equal(t, []string{"smf-both", "upf-factory"}, ids)
+
+	// An SST without SD matches any SD of that slice type
+	ids = discoverIDs(t, repo, &DiscoveryQuery{NFType: NFTypeSMF, SNSSAIs: []SNSSAI{{SST: 1}}})
+	assert.Equal(t, []string{"smf-both", "smf-ims", "smf-internet"}, ids)
+
+	ids = discoverIDs(t, repo, &DiscoveryQuery{SNSSAIs: []SNSSAI{{SST: 1, SD: "ffffff"}}})
+	assert.Empty(t, ids)
+}
+
+func TestDiscover_DNNMustBeServedOnRequestedSNSSAI(t *testing.T) {
+	repo := newDiscoveryRepository(t)
+
+	ids := discoverIDs(t, repo, &DiscoveryQuery{
+		NFType:  NFTypeSMF,
+		DNN:     "internet",
+		SNSSAIs: []SNSSAI{urllcSlice},
+	})
+	assert.Empty(t, ids)
+
+	ids = discoverIDs(t, repo, &DiscoveryQuery{
+		NFType:  NFTypeSMF,
+		DNN:     "factory",
+		SNSSAIs: []SNSSAI{urllcSlice},
+	})
+	assert.Equal(t, []string{"smf-both"}, ids)
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// applyMergePatch returns a copy of profile with an RFC 7396 merge patch
+// applied
+func applyMergePatch(profile *NFProfile, patch []byte) (*NFProfile, error) {
+	original, err := json.Marshal(profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode profile: %w", err)
+	}
+
+	var target interface{}
+	if err := decodeJSON(original, &target); err != nil {
+		return nil, fmt.Errorf("failed to decode profile: %w", err)
+	}
+	var changes interface{}
+	if err := decodeJSON(patch, &changes); err != nil {
+		return nil, fmt.Errorf("invalid merge patch: %w", err)
+	}
+
+	merged, err := json.Marshal(mergePatch(target, changes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode patched profile: %w", err)
+	}
+
+	var result NFProfile
+	if err := json.Unmarshal(merged, &result); err != nil {
+		return nil, fmt.Errorf("patched profile is malformed: %w", err)
+	}
+	return &result, nil
+}
+
+// mergePatch implements the MergePatch function of RFC 7396 section 2
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+			continue
+		}
+		targetObject[name] = mergePatch(targetObject[name], value)
+	}
+	return targetObject
+}
+
+// decodeJSON decodes data keeping numbers exact
+func decodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
@@ -150,9 +150,20 @@ type SNSSAIInfo struct {
 
 // UPFInfo contains UPF-specific information
 type UPFInfo struct {
-	SNSSAIs              []SNSSAI           `json:"sNssaiUpfInfoList,omitempty"`
-	SMFServingArea       []string           `json:"smfServingArea,omitempty"`
-	InterfaceUpfInfoList []InterfaceUpfInfo `json:"interfaceUpfInfoList,omitempty"`
+	SNSSAIUPFInfoList    []SNSSAIUPFInfoItem `json:"sNssaiUpfInfoList,omitempty"`
+	SMFServingArea       []string            `json:"smfServingArea,omitempty"`
+	InterfaceUpfInfoList []InterfaceUpfInfo  `json:"interfaceUpfInfoList,omitempty"`
+}
+
+// SNSSAIUPFInfoItem lists the DNNs a UPF serves on one S-NSSAI
+type SNSSAIUPFInfoItem struct {
+	SNSSAI         SNSSAI           `json:"sNssai"`
+	DNNUPFInfoList []DNNUPFInfoItem `json:"dnnUpfInfoList,omitempty"`
+}
+
+// DNNUPFInfoItem represents a DNN served by a UPF
+type DNNUPFInfoItem struct {
+	DNN string `json:"dnn"`
 }
 
 // InterfaceUpfInfo represents UPF interface information
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/your-org/5g-network/common/notify"
+	"github.com/your-org/5g-network/nf/nrf/internal/config"
+	"go.uber.org/zap"
+)
+
+// NF status notification events (TS 29.510 6.1.6.3.4)
+const (
+	EventNFRegistered     = "NF_REGISTERED"
+	EventNFDeregistered   = "NF_DEREGISTERED"
+	EventNFProfileChanged = "NF_PROFILE_CHANGED"
+)
+
+// Defaults for NF status notification delivery, the queue defaulting to
+// those of the notify package
+const (
+	defaultNotificationTimeout    = 5 * time.Second
+	defaultNotificationMaxRetries = 3
+)
+
+// NotificationData is the NFStatusNotification body POSTed to a
+// subscription's callback URI (TS 29.510 6.1.6.2.17)
+type NotificationData struct {
+	Event         string     `json:"event"`
+	NFInstanceURI string     `json:"nfInstanceUri"`
+	NFProfile     *NFProfile `json:"nfProfile,omitempty"`
+}
+
+// Notifier delivers NF status notifications to subscribers
+type Notifier interface {
+	Notify(sub *Subscription, event string, profile *NFProfile)
+}
+
+// notificationJob is a notification pending delivery to one subscriber
+type notificationJob struct {
+	sub  *Subscription
+	data *NotificationData
+}
+
+// HTTPNotifier POSTs NF status notifications to subscription callback URIs
+// from a bounded pool of workers. Deliveries failing with a 5xx or a
+// transport error are retried with backoff.
+type HTTPNotifier struct {
+	cfg        config.NotificationConfig
+	httpClient *http.Client
+	queue      *notify.Queue[*notificationJob]
+	logger     *zap.Logger
+
+	// Called with the subscription ID when delivery gives up
+	onFailure func(subscriptionID string)
+}
+
+// NewHTTPNotifier creates a notifier and starts its delivery workers
+func NewHTTPNotifier(cfg config.NotificationConfig, transport http.RoundTripper, logger *zap.Logger) *HTTPNotifier {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultNotificationTimeout
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = defaultNotificationMaxRetries
+	}
+
+	n := &HTTPNotifier{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: cfg.Timeout, Transport: transport},
+		logger:     logger,
+	}
+	n.queue = notify.NewQueue(notify.Config{
+		Workers:       cfg.Workers,
+		QueueSize:     cfg.QueueSize,
+		MaxAttempts:   cfg.MaxRetries + 1,
+		RetryInterval: cfg.RetryInterval,
+	}, notify.Hooks[*notificationJob]{
+		Deliver:   n.post,
+		Delivered: n.delivered,
+		Retrying:  n.retrying,
+		Failed:    n.failed,
+	})
+	return n
+}
+
+// OnFailure registers a callback invoked when delivery to a subscription
+// gives up. It must be set before notifications are sent.
+func (n *HTTPNotifier) OnFailure(fn func(subscriptionID string)) {
+	n.onFailure = fn
+}
+
+// Notify schedules a notification for delivery without blocking. The
+// notification is dropped if the queue is full.
+func (n *HTTPNotifier) Notify(sub *Subscription, event string, profile *NFProfile) {
+	data := &NotificationData{
+		Event:         event,
+		NFInstanceURI: n.cfg.APIRoot + "/nnrf-nfm/v1/nf-instances/" + profile.NFInstanceID,
+	}
+	// The profile is only carried for registration and profile changes
+	if event != EventNFDeregistered {
+		data.NFProfile = profile
+	}
+
+	if !n.queue.Enqueue(&notificationJob{sub: sub, data: data}) {
+		n.logger.Error("Notification queue full, dropping NF status notification",
+			zap.String("subscription_id", sub.SubscriptionID),
+			zap.String("event", event),
+		)
+	}
+}
+
+// Stop terminates the delivery workers. Notifications still queued are
+// dropped.
+func (n *HTTPNotifier) Stop() {
+	if pending := n.queue.Stop(); pending > 0 {
+		n.logger.Warn("Dropping undelivered NF status notifications on shutdown", zap.Int("pending", pending))
+	}
+}
+
+// post makes one delivery attempt
+func (n *HTTPNotifier) post(job *notificationJob) error {
+	body, err := json.Marshal(job.data)
+	if err != nil {
+		return notify.Permanent(fmt.Errorf("failed to encode NF status notification: %w", err))
+	}
+	return notify.PostJSON(n.httpClient, n.cfg.Timeout, job.sub.CallbackURI, body)
+}
+
+// delivered logs a delivered notification
+func (n *HTTPNotifier) delivered(job *notificationJob, attempts int) {
+	n.logger.Debug("NF status notification delivered",
+		zap.String("subscription_id", job.sub.SubscriptionID),
+		zap.String("event", job.data.Event),
+		zap.Int("attempts", attempts),
+	)
+}
+
+// retrying logs a failed attempt that will be retried
+func (n *HTTPNotifier) retrying(job *notificationJob, attempt int, backoff time.Duration, err error) {
+	n.logger.Warn("NF status notification failed, retrying",
+		zap.String("subscription_id", job.sub.SubscriptionID),
+		zap.Int("attempt", attempt),
+		zap.Duration("backoff", backoff),
+		zap.Error(err),
+	)
+}
+
+// failed logs a notification given up on and reports it to the failure
+// callback
+func (n *HTTPNotifier) failed(job *notificationJob, attempts int, err error) {
+	n.logger.Error("Giving up on NF status notification",
+		zap.String("subscription_id", job.sub.SubscriptionID),
+		zap.String("callback_uri", job.sub.CallbackURI),
+		zap.String("event", job.data.Event),
+		zap.Int("attempts", attempts),
+		zap.Error(err),
+	)
+	if n.onFailure != nil {
+		n.onFailure(job.sub.SubscriptionID)
+	}
+}
@@ -0,0 +1,177 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/nf/nrf/internal/config"
+)
+
+// callback is a notification received by the test callback server
+type callback struct {
+	path string
+	data NotificationData
+}
+
+// newCallbackServer records notifications POSTed to it, answering with the
+// status returned by status
+func newCallbackServer(t *testing.T, status func() int) (*httptest.Server, chan callback) {
+	t.Helper()
+
+	received := make(chan callback, 16)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data NotificationData
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&data))
+		received <- callback{path: r.URL.Path, data: data}
+		w.WriteHeader(status())
+	}))
+	t.Cleanup(srv.Close)
+	return srv, received
+}
+
+func newNotifyingRepository(t *testing.T, cfg config.NotificationConfig) *MemoryRepository {
+	t.Helper()
+
+	logger, _ := zap.NewDevelopment()
+	repo := NewMemoryRepository(logger)
+	t.Cleanup(repo.Close)
+
+	cfg.APIRoot = "http://nrf.test"
+	cfg.RetryInterval = 10 * time.Millisecond
+	notifier := NewHTTPNotifier(cfg, nil, logger)
+	t.Cleanup(notifier.Stop)
+	if cfg.MarkStale {
+		notifier.OnFailure(repo.MarkSubscriptionStale)
+	}
+	repo.SetNotifier(notifier)
+	return repo
+}
+
+func waitCallback(t *testing.T, received chan callback) callback {
+	t.Helper()
+	select {
+	case cb := <-received:
+		return cb
+	case <-time.After(2 * time.Second):
+		t.Fatal("no notification received")
+		return callback{}
+	}
+}
+
+func assertNoCallback(t *testing.T, received chan callback) {
+	t.Helper()
+	select {
+	case cb := <-received:
+		t.Fatalf("unexpected notification to %s", cb.path)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestNotifier_OnlyMatchingSubscribersNotified(t *testing.T) {
+	srv, received := newCallbackServer(t, func() int { return http.StatusNoContent })
+	repo := newNotifyingRepository(t, config.NotificationConfig{})
+	ctx := context.Background()
+
+	subs := []*Subscription{
+		{SubscriptionID: "amf-registered", NFType: NFTypeAMF, EventType: []string{EventNFRegistered}},
+		{SubscriptionID: "smf-all", NFType: NFTypeSMF},
+		{SubscriptionID: "amf-deregistered", NFType: NFTypeAMF, EventType: []string{EventNFDeregistered}},
+	}
+	for _, sub := range subs {
+		sub.CallbackURI = srv.URL + "/" + sub.SubscriptionID
+		require.NoError(t, repo.Subscribe(ctx, sub))
+	}
+
+	require.NoError(t, repo.Register(ctx, &NFProfile{
+		NFInstanceID: "amf-1",
+		NFType:       NFTypeAMF,
+		NFStatus:     NFStatusRegistered,
+		FQDN:         "amf.5gc.local",
+	}))
+
+	cb := waitCallback(t, received)
+	assert.Equal(t, "/amf-registered", cb.path)
+	assert.Equal(t, EventNFRegistered, cb.data.Event)
+	assert.Equal(t, "http://nrf.test/nnrf-nfm/v1/nf-instances/amf-1", cb.data.NFInstanceURI)
+	require.NotNil(t, cb.data.NFProfile)
+	assert.Equal(t, "amf-1", cb.data.NFProfile.NFInstanceID)
+	assert.Equal(t, "amf.5gc.local", cb.data.NFProfile.FQDN)
+	assertNoCallback(t, received)
+
+	require.NoError(t, repo.Deregister(ctx, "amf-1"))
+
+	cb = waitCallback(t, received)
+	assert.Equal(t, "/amf-deregistered", cb.path)
+	assert.Equal(t, EventNFDeregistered, cb.data.Event)
+	assert.Nil(t, cb.data.NFProfile)
+	assertNoCallback(t, received)
+}
+
+func TestNotifier_RetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	srv, received := newCallbackServer(t, func() int {
+		if calls.Add(1) < 3 {
+			return http.StatusServiceUnavailable
+		}
+		return http.StatusNoContent
+	})
+	repo := newNotifyingRepository(t, config.NotificationConfig{MaxRetries: 3})
+	ctx := context.Background()
+
+	require.NoError(t, repo.Subscribe(ctx, &Subscription{SubscriptionID: "sub-1", CallbackURI: srv.URL}))
+	require.NoError(t, repo.Register(ctx, &NFProfile{NFInstanceID: "smf-1", NFType: NFTypeSMF, NFStatus: NFStatusRegistered}))
+
+	for i := 0; i < 3; i++ {
+		waitCallback(t, received)
+	}
+	assertNoCallback(t, received)
+	assert.Equal(t, int32(3), calls.Load())
+
+	sub, err := repo.GetSubscription(ctx, "sub-1")
+	require.NoError(t, err)
+	assert.False(t, sub.Stale)
+}
+
+func TestNotifier_ClientErrorsNotRetried(t *testing.T) {
+	srv, received := newCallbackServer(t, func() int { return http.StatusNotFound })
+	repo := newNotifyingRepository(t, config.NotificationConfig{MaxRetries: 3})
+	ctx := context.Background()
+
+	require.NoError(t, repo.Subscribe(ctx, &Subscription{SubscriptionID: "sub-1", CallbackURI: srv.URL}))
+	require.NoError(t, repo.Register(ctx, &NFProfile{NFInstanceID: "smf-1", NFType: NFTypeSMF, NFStatus: NFStatusRegistered}))
+
+	waitCallback(t, received)
+	assertNoCallback(t, received)
+}
+
+func TestNotifier_MarksSubscriptionStaleAfterRetries(t *testing.T) {
+	srv, received := newCallbackServer(t, func() int { return http.StatusInternalServerError })
+	repo := newNotifyingRepository(t, config.NotificationConfig{MaxRetries: 1, MarkStale: true})
+	ctx := context.Background()
+
+	require.NoError(t, repo.Subscribe(ctx, &Subscription{SubscriptionID: "sub-1", CallbackURI: srv.URL}))
+	require.NoError(t, repo.Register(ctx, &NFProfile{NFInstanceID: "udm-1", NFType: NFTypeUDM, NFStatus: NFStatusRegistered}))
+
+	// Initial attempt plus one retry
+	waitCallback(t, received)
+	waitCallback(t, received)
+
+	require.Eventually(t, func() bool {
+		sub, err := repo.GetSubscription(ctx, "sub-1")
+		return err == nil && sub.Stale
+	}, time.Second, 10*time.Millisecond)
+
+	// Stale subscriptions are no longer notified
+	require.NoError(t, repo.Deregister(ctx, "udm-1"))
+	assertNoCallback(t, received)
+}
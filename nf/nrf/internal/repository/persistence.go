@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// state is the on-disk form of the repository
+type state struct {
+	Profiles      []*NFProfile    `json:"profiles"`
+	Subscriptions []*Subscription `json:"subscriptions"`
+}
+
+// NewPersistentRepository creates an in-memory repository that writes its
+// state to the JSON file at path after every change, heartbeats included, and
+// restores it from there on start. Expired profiles and subscriptions are
+// dropped on load.
+func NewPersistentRepository(path string, logger *zap.Logger) (*MemoryRepository, error) {
+	repo := NewMemoryRepository(logger)
+	if err := repo.load(path); err != nil {
+		repo.Close()
+		return nil, err
+	}
+	return repo, nil
+}
+
+// load restores the state saved at path and persists every later change
+// there. A missing file starts an empty repository.
+func (r *MemoryRepository) load(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.statePath = path
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		r.logger.Info("No saved NRF state, starting empty", zap.String("path", path))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read NRF state: %w", err)
+	}
+
+	var saved state
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("failed to parse NRF state %s: %w", path, err)
+	}
+
+	pruned := 0
+	for _, profile := range saved.Profiles {
+		if profile.IsExpired() {
+			pruned++
+			continue
+		}
+		r.profiles[profile.NFInstanceID] = profile
+	}
+	for _, subscription := range saved.Subscriptions {
+		if subscription.IsExpired() {
+			pruned++
+			continue
+		}
+		r.subscriptions[subscription.SubscriptionID] = subscription
+	}
+
+	r.logger.Info("NRF state restored",
+		zap.String("path", path),
+		zap.Int("profiles", len(r.profiles)),
+		zap.Int("subscriptions", len(r.subscriptions)),
+		zap.Int("pruned", pruned),
+	)
+
+	if pruned > 0 {
+		r.persist()
+	}
+	return nil
+}
+
+// persist saves the state when persistence is enabled. It must be called
+// with mu held. A failed write is logged and the in-memory state remains
+// authoritative.
+func (r *MemoryRepository) persist() {
+	if r.statePath == "" {
+		return
+	}
+	if err := r.save(); err != nil {
+		r.logger.Error("Failed to persist NRF state",
+			zap.String("path", r.statePath),
+			zap.Error(err),
+		)
+	}
+}
+
+// save writes the state to a temporary file and renames it over the state
+// file so a crash never leaves a partial file behind
+func (r *MemoryRepository) save() error {
+	saved := state{
+		Profiles:      make([]*NFProfile, 0, len(r.profiles)),
+		Subscriptions: make([]*Subscription, 0, len(r.subscriptions)),
+	}
+	for _, profile := range r.profiles {
+		saved.Profiles = append(saved.Profiles, profile)
+	}
+	for _, subscription := range r.subscriptions {
+		saved.Subscriptions = append(saved.Subscriptions, subscription)
+	}
+	sort.Slice(saved.Profiles, func(i, j int) bool {
+		return saved.Profiles[i].NFInstanceID < saved.Profiles[j].NFInstanceID
+	})
+	sort.Slice(saved.Subscriptions, func(i, j int) bool {
+		return saved.Subscriptions[i].SubscriptionID < saved.Subscriptions[j].SubscriptionID
+	})
+
+	data, err := json.Marshal(&saved)
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(r.statePath), filepath.Base(r.statePath)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), r.statePath); err != nil {
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// openPersistentRepository opens the repository saved at path. The caller
+// closes it to simulate an NRF restart.
+func openPersistentRepository(t *testing.T, path string) *MemoryRepository {
+	t.Helper()
+
+	logger, _ := zap.NewDevelopment()
+	repo, err := NewPersistentRepository(path, logger)
+	require.NoError(t, err)
+	return repo
+}
+
+func TestPersistentRepository_RestoresState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nrf-state.json")
+	ctx := context.Background()
+
+	repo := openPersistentRepository(t, path)
+	require.NoError(t, repo.Register(ctx, &NFProfile{
+		NFInstanceID:   "amf-1",
+		NFType:         NFTypeAMF,
+		NFStatus:       NFStatusRegistered,
+		HeartBeatTimer: 60,
+		AMFInfo:        &AMFInfo{AMFSetID: "001", AMFRegionID: "01"},
+	}))
+	require.NoError(t, repo.Register(ctx, &NFProfile{
+		NFInstanceID: "smf-1",
+		NFType:       NFTypeSMF,
+		NFStatus:     NFStatusRegistered,
+	}))
+	require.NoError(t, repo.Register(ctx, &NFProfile{
+		NFInstanceID: "upf-1",
+		NFType:       NFTypeUPF,
+		NFStatus:     NFStatusRegistered,
+	}))
+	_, err := repo.Patch(ctx, "smf-1", []byte(`{"load":40}`))
+	require.NoError(t, err)
+	require.NoError(t, repo.Deregister(ctx, "upf-1"))
+	require.NoError(t, repo.Subscribe(ctx, &Subscription{
+		SubscriptionID: "sub-1",
+		NFType:         NFTypeSMF,
+		CallbackURI:    "http://amf.5gc.local/callback",
+	}))
+	require.NoError(t, repo.Subscribe(ctx, &Subscription{
+		SubscriptionID: "sub-2",
+		CallbackURI:    "http://smf.5gc.local/callback",
+	}))
+	require.NoError(t, repo.Unsubscribe(ctx, "sub-2"))
+	repo.Close()
+
+	restored := openPersistentRepository(t, path)
+	defer restored.Close()
+
+	amf, err := restored.Get(ctx, "amf-1")
+	require.NoError(t, err)
+	assert.Equal(t, NFTypeAMF, amf.NFType)
+	require.NotNil(t, amf.AMFInfo)
+	assert.Equal(t, "001", amf.AMFInfo.AMFSetID)
+
+	smf, err := restored.Get(ctx, "smf-1")
+	require.NoError(t, err)
+	assert.Equal(t, 40, smf.Load)
+
+	_, err = restored.Get(ctx, "upf-1")
+	assert.ErrorIs(t, err, ErrNFNotFound)
+
+	sub, err := restored.GetSubscription(ctx, "sub-1")
+	require.NoError(t, err)
+	assert.Equal(t, NFTypeSMF, sub.NFType)
+	assert.Equal(t, "http://amf.5gc.local/callback", sub.CallbackURI)
+
+	_, err = restored.GetSubscription(ctx, "sub-2")
+	assert.Error(t, err)
+
+	// Restored profiles are discoverable again
+	results, err := restored.Discover(ctx, &DiscoveryQuery{NFType: NFTypeSMF})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "smf-1", results[0].NFInstanceID)
+}
+
+func TestPersistentRepository_PrunesExpiredOnLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nrf-state.json")
+	ctx := context.Background()
+
+	repo := openPersistentRepository(t, path)
+	require.NoError(t, repo.Register(ctx, &NFProfile{
+		NFInstanceID:   "amf-stale",
+		NFType:         NFTypeAMF,
+		NFStatus:       NFStatusRegistered,
+		HeartBeatTimer: 1,
+	}))
+	require.NoError(t, repo.Register(ctx, &NFProfile{
+		NFInstanceID: "amf-live",
+		NFType:       NFTypeAMF,
+		NFStatus:     NFStatusRegistered,
+	}))
+	require.NoError(t, repo.Subscribe(ctx, &Subscription{
+		SubscriptionID: "sub-expired",
+		CallbackURI:    "http://smf.5gc.local/callback",
+		ValidityTime:   time.Now().Add(-time.Minute),
+	}))
+	repo.Close()
+
+	// Let the heartbeat of amf-stale lapse while the NRF is down
+	time.Sleep(1100 * time.Millisecond)
+
+	restored := openPersistentRepository(t, path)
+	defer restored.Close()
+
+	_, err := restored.Get(ctx, "amf-stale")
+	assert.ErrorIs(t, err, ErrNFNotFound)
+	_, err = restored.Get(ctx, "amf-live")
+	assert.NoError(t, err)
+	_, err = restored.GetSubscription(ctx, "sub-expired")
+	assert.Error(t, err)
+}
+
+func TestPersistentRepository_MissingFileStartsEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nrf-state.json")
+
+	repo := openPersistentRepository(t, path)
+	defer repo.Close()
+
+	stats, err := repo.GetStats(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, stats.TotalNFs)
+
+	// The file appears with the first change
+	require.NoError(t, repo.Register(context.Background(), &NFProfile{
+		NFInstanceID: "ausf-1",
+		NFType:       NFTypeAUSF,
+		NFStatus:     NFStatusRegistered,
+	}))
+	_, err = os.Stat(path)
+	assert.NoError(t, err)
+}
+
+func TestPersistentRepository_RejectsCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nrf-state.json")
+	require.NoError(t, os.WriteFile(path, []byte("{not json"), 0o600))
+
+	logger, _ := zap.NewDevelopment()
+	_, err := NewPersistentRepository(path, logger)
+	assert.Error(t, err)
+}
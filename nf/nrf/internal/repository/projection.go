@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// mandatoryAttributes are kept in every projected profile so consumers can
+// still identify the NF instance
+var mandatoryAttributes = []string{"nfInstanceId", "nfType", "nfStatus"}
+
+// ProjectProfile returns the JSON object of a profile trimmed to the given
+// top-level attributes, named as in the NFProfile JSON encoding (e.g.
+// "ipv4Addresses", "nfServices"). Unknown and absent attributes are left out.
+func ProjectProfile(profile *NFProfile, attributes []string) (map[string]interface{}, error) {
+	encoded, err := json.Marshal(profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode profile: %w", err)
+	}
+
+	var full map[string]interface{}
+	if err := decodeJSON(encoded, &full); err != nil {
+		return nil, fmt.Errorf("failed to decode profile: %w", err)
+	}
+
+	projected := make(map[string]interface{}, len(mandatoryAttributes)+len(attributes))
+	for _, names := range [][]string{mandatoryAttributes, attributes} {
+		for _, name := range names {
+			if value, ok := full[name]; ok {
+				projected[name] = value
+			}
+		}
+	}
+	return projected, nil
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -9,11 +10,29 @@ import (
 	"go.uber.org/zap"
 )
 
+var (
+	// ErrNFNotFound is returned when no NF instance has the requested ID
+	ErrNFNotFound = errors.New("NF instance not found")
+
+	// ErrInvalidProfile is returned when an NF profile or a patch to it is
+	// rejected
+	ErrInvalidProfile = errors.New("invalid NF profile")
+
+	// ErrSubscriptionNotFound is returned when no subscription has the
+	// requested ID
+	ErrSubscriptionNotFound = errors.New("subscription not found")
+
+	// ErrInvalidValidityTime is returned when a subscription is extended to a
+	// time that has already passed
+	ErrInvalidValidityTime = errors.New("validity time must be in the future")
+)
+
 // Repository manages NF profiles
 type Repository interface {
 	// NF Profile Management
 	Register(ctx context.Context, profile *NFProfile) error
 	Update(ctx context.Context, nfInstanceID string, profile *NFProfile) error
+	Patch(ctx context.Context, nfInstanceID string, patch []byte) (*NFProfile, error)
 	Deregister(ctx context.Context, nfInstanceID string) error
 	Get(ctx context.Context, nfInstanceID string) (*NFProfile, error)
 	GetAll(ctx context.Context) ([]*NFProfile, error)
@@ -25,6 +44,7 @@ type Repository interface {
 	Subscribe(ctx context.Context, subscription *Subscription) error
 	Unsubscribe(ctx context.Context, subscriptionID string) error
 	GetSubscription(ctx context.Context, subscriptionID string) (*Subscription, error)
+	ExtendSubscription(ctx context.Context, subscriptionID string, validityTime time.Time) (*Subscription, error)
 	GetSubscriptionsByNFInstanceID(ctx context.Context, nfInstanceID string) ([]*Subscription, error)
 
 	// Heartbeat
@@ -39,6 +59,8 @@ type MemoryRepository struct {
 	mu            sync.RWMutex
 	profiles      map[string]*NFProfile    // nfInstanceID -> NFProfile
 	subscriptions map[string]*Subscription // subscriptionID -> Subscription
+	notifier      Notifier                 // nil disables notifications
+	statePath     string                   // empty keeps state in memory only
 	logger        *zap.Logger
 
 	// Cleanup goroutine
@@ -84,6 +106,7 @@ func (r *MemoryRepository) Register(ctx context.Context, profile *NFProfile) err
 	profile.NFStatus = NFStatusRegistered
 
 	r.profiles[profile.NFInstanceID] = profile
+	r.persist()
 
 	r.logger.Info("NF registered",
 		zap.String("nf_instance_id", profile.NFInstanceID),
@@ -91,7 +114,7 @@ func (r *MemoryRepository) Register(ctx context.Context, profile *NFProfile) err
 	)
 
 	// Notify subscribers
-	go r.notifySubscribers(profile, "NF_REGISTERED")
+	go r.notifySubscribers(profile, EventNFRegistered)
 
 	return nil
 }
@@ -107,7 +130,7 @@ func (r *MemoryRepository) Update(ctx context.Context, nfInstanceID string, prof
 
 	existing, exists := r.profiles[nfInstanceID]
 	if !exists {
-		return fmt.Errorf("NF instance not found: %s", nfInstanceID)
+		return fmt.Errorf("%w: %s", ErrNFNotFound, nfInstanceID)
 	}
 
 	// Preserve timestamps
@@ -116,17 +139,60 @@ func (r *MemoryRepository) Update(ctx context.Context, nfInstanceID string, prof
 	profile.LastHeartbeat = existing.LastHeartbeat
 
 	r.profiles[nfInstanceID] = profile
+	r.persist()
 
 	r.logger.Info("NF profile updated",
 		zap.String("nf_instance_id", nfInstanceID),
 	)
 
 	// Notify subscribers
-	go r.notifySubscribers(profile, "NF_PROFILE_CHANGED")
+	go r.notifySubscribers(profile, EventNFProfileChanged)
 
 	return nil
 }
 
+// Patch applies a JSON Merge Patch (RFC 7396) to a stored NF profile and
+// returns the result. Members absent from the patch are preserved and members
+// set to null are removed.
+func (r *MemoryRepository) Patch(ctx context.Context, nfInstanceID string, patch []byte) (*NFProfile, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, exists := r.profiles[nfInstanceID]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrNFNotFound, nfInstanceID)
+	}
+
+	profile, err := applyMergePatch(existing, patch)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProfile, err)
+	}
+	if profile.NFInstanceID != nfInstanceID {
+		return nil, fmt.Errorf("%w: nfInstanceId cannot be changed", ErrInvalidProfile)
+	}
+	if !profile.IsValid() {
+		return nil, fmt.Errorf("%w: nfInstanceId, nfType and nfStatus are required", ErrInvalidProfile)
+	}
+
+	// Timestamps are owned by the NRF
+	profile.CreatedAt = existing.CreatedAt
+	profile.UpdatedAt = time.Now()
+	profile.LastHeartbeat = existing.LastHeartbeat
+
+	r.profiles[nfInstanceID] = profile
+	r.persist()
+
+	r.logger.Info("NF profile patched",
+		zap.String("nf_instance_id", nfInstanceID),
+	)
+
+	// Notify subscribers
+	go r.notifySubscribers(profile, EventNFProfileChanged)
+
+	result := *profile
+	return &result, nil
+}
+
 // Deregister removes an NF profile
 func (r *MemoryRepository) Deregister(ctx context.Context, nfInstanceID string) error {
 	r.mu.Lock()
@@ -134,10 +200,11 @@ func (r *MemoryRepository) Deregister(ctx context.Context, nfInstanceID string)
 
 	profile, exists := r.profiles[nfInstanceID]
 	if !exists {
-		return fmt.Errorf("NF instance not found: %s", nfInstanceID)
+		return fmt.Errorf("%w: %s", ErrNFNotFound, nfInstanceID)
 	}
 
 	delete(r.profiles, nfInstanceID)
+	r.persist()
 
 	r.logger.Info("NF deregistered",
 		zap.String("nf_instance_id", nfInstanceID),
@@ -145,7 +212,7 @@ func (r *MemoryRepository) Deregister(ctx context.Context, nfInstanceID string)
 	)
 
 	// Notify subscribers
-	go r.notifySubscribers(profile, "NF_DEREGISTERED")
+	go r.notifySubscribers(profile, EventNFDeregistered)
 
 	return nil
 }
@@ -157,7 +224,7 @@ func (r *MemoryRepository) Get(ctx context.Context, nfInstanceID string) (*NFPro
 
 	profile, exists := r.profiles[nfInstanceID]
 	if !exists {
-		return nil, fmt.Errorf("NF instance not found: %s", nfInstanceID)
+		return nil, fmt.Errorf("%w: %s", ErrNFNotFound, nfInstanceID)
 	}
 
 	// Return a copy
@@ -208,10 +275,11 @@ func (r *MemoryRepository) UpdateHeartbeat(ctx context.Context, nfInstanceID str
 
 	profile, exists := r.profiles[nfInstanceID]
 	if !exists {
-		return fmt.Errorf("NF instance not found: %s", nfInstanceID)
+		return fmt.Errorf("%w: %s", ErrNFNotFound, nfInstanceID)
 	}
 
 	profile.UpdateHeartbeat()
+	r.persist()
 
 	return nil
 }
@@ -227,6 +295,7 @@ func (r *MemoryRepository) Subscribe(ctx context.Context, subscription *Subscrip
 
 	subscription.CreatedAt = time.Now()
 	r.subscriptions[subscription.SubscriptionID] = subscription
+	r.persist()
 
 	r.logger.Info("Subscription created",
 		zap.String("subscription_id", subscription.SubscriptionID),
@@ -242,10 +311,11 @@ func (r *MemoryRepository) Unsubscribe(ctx context.Context, subscriptionID strin
 	defer r.mu.Unlock()
 
 	if _, exists := r.subscriptions[subscriptionID]; !exists {
-		return fmt.Errorf("subscription not found: %s", subscriptionID)
+		return fmt.Errorf("%w: %s", ErrSubscriptionNotFound, subscriptionID)
 	}
 
 	delete(r.subscriptions, subscriptionID)
+	r.persist()
 
 	r.logger.Info("Subscription removed",
 		zap.String("subscription_id", subscriptionID),
@@ -261,9 +331,35 @@ func (r *MemoryRepository) GetSubscription(ctx context.Context, subscriptionID s
 
 	subscription, exists := r.subscriptions[subscriptionID]
 	if !exists {
-		return nil, fmt.Errorf("subscription not found: %s", subscriptionID)
+		return nil, fmt.Errorf("%w: %s", ErrSubscriptionNotFound, subscriptionID)
+	}
+
+	subCopy := *subscription
+	return &subCopy, nil
+}
+
+// ExtendSubscription moves the validity time of a subscription, which must
+// lie in the future
+func (r *MemoryRepository) ExtendSubscription(ctx context.Context, subscriptionID string, validityTime time.Time) (*Subscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	subscription, exists := r.subscriptions[subscriptionID]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrSubscriptionNotFound, subscriptionID)
+	}
+	if !validityTime.After(time.Now()) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidValidityTime, validityTime.Format(time.RFC3339))
 	}
 
+	subscription.ValidityTime = validityTime
+	r.persist()
+
+	r.logger.Info("Subscription extended",
+		zap.String("subscription_id", subscriptionID),
+		zap.Time("validity_time", validityTime),
+	)
+
 	subCopy := *subscription
 	return &subCopy, nil
 }
@@ -304,7 +400,7 @@ func (r *MemoryRepository) GetStats(ctx context.Context) (*Stats, error) {
 	return stats, nil
 }
 
-// cleanup periodically removes expired NF profiles
+// cleanup periodically removes expired NF profiles and subscriptions
 func (r *MemoryRepository) cleanup() {
 	for {
 		select {
@@ -316,7 +412,7 @@ func (r *MemoryRepository) cleanup() {
 	}
 }
 
-// performCleanup removes expired profiles
+// performCleanup removes expired profiles and subscriptions
 func (r *MemoryRepository) performCleanup() {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -338,24 +434,87 @@ func (r *MemoryRepository) performCleanup() {
 		)
 
 		// Notify subscribers
-		go r.notifySubscribers(profile, "NF_DEREGISTERED")
+		go r.notifySubscribers(profile, EventNFDeregistered)
+	}
+
+	var expiredSubscriptions int
+	for id, subscription := range r.subscriptions {
+		if !subscription.IsExpired() {
+			continue
+		}
+		delete(r.subscriptions, id)
+		expiredSubscriptions++
+
+		r.logger.Info("Subscription expired and removed",
+			zap.String("subscription_id", id),
+		)
 	}
 
-	if len(expired) > 0 {
+	if len(expired) > 0 || expiredSubscriptions > 0 {
+		r.persist()
 		r.logger.Info("Cleanup completed",
 			zap.Int("expired_count", len(expired)),
+			zap.Int("expired_subscriptions", expiredSubscriptions),
 		)
 	}
 }
 
+// SetNotifier sets the notifier used to deliver NF status notifications
+func (r *MemoryRepository) SetNotifier(notifier Notifier) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notifier = notifier
+}
+
+// MarkSubscriptionStale stops notifications to a subscription whose callback
+// keeps failing. The subscription stays readable until it is removed or
+// expires.
+func (r *MemoryRepository) MarkSubscriptionStale(subscriptionID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	subscription, exists := r.subscriptions[subscriptionID]
+	if !exists {
+		return
+	}
+	subscription.Stale = true
+
+	r.logger.Warn("Subscription marked stale",
+		zap.String("subscription_id", subscriptionID),
+		zap.String("callback_uri", subscription.CallbackURI),
+	)
+}
+
 // notifySubscribers notifies all relevant subscribers about an event
 func (r *MemoryRepository) notifySubscribers(profile *NFProfile, eventType string) {
-	// TODO: Implement notification sending to subscribers
-	// This would involve making HTTP POST requests to callback URIs
+	r.mu.RLock()
+	notifier := r.notifier
+	var matched []*Subscription
+	for _, sub := range r.subscriptions {
+		if sub.Stale || sub.IsExpired() {
+			continue
+		}
+		if sub.MatchesEvent(eventType) && sub.MatchesProfile(profile) {
+			subCopy := *sub
+			matched = append(matched, &subCopy)
+		}
+	}
+	// Copy the profile so later heartbeats do not race with encoding
+	profileCopy := *profile
+	r.mu.RUnlock()
+
 	r.logger.Debug("Subscriber notification",
 		zap.String("event_type", eventType),
 		zap.String("nf_instance_id", profile.NFInstanceID),
+		zap.Int("subscribers", len(matched)),
 	)
+
+	if notifier == nil {
+		return
+	}
+	for _, sub := range matched {
+		notifier.Notify(sub, eventType, &profileCopy)
+	}
 }
 
 // Close stops the repository
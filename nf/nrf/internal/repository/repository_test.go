@@ -240,6 +240,43 @@ func TestMemoryRepository_Subscribe(t *testing.T) {
 	assert.Error(t, err)
 }
 
+func TestMemoryRepository_ExtendSubscription(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	repo := NewMemoryRepository(logger)
+	defer repo.Close()
+
+	ctx := context.Background()
+
+	originalExpiry := time.Now().Add(50 * time.Millisecond)
+	for _, id := range []string{"sub-extended", "sub-lapsing"} {
+		require.NoError(t, repo.Subscribe(ctx, &Subscription{
+			SubscriptionID: id,
+			NFType:         NFTypeUDM,
+			CallbackURI:    "http://consumer.local/callback",
+			ValidityTime:   originalExpiry,
+		}))
+	}
+
+	extended, err := repo.ExtendSubscription(ctx, "sub-extended", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.True(t, extended.ValidityTime.After(originalExpiry))
+
+	// Only the subscription left at its original validity is cleaned up
+	time.Sleep(100 * time.Millisecond)
+	repo.performCleanup()
+
+	_, err = repo.GetSubscription(ctx, "sub-extended")
+	assert.NoError(t, err)
+	_, err = repo.GetSubscription(ctx, "sub-lapsing")
+	assert.ErrorIs(t, err, ErrSubscriptionNotFound)
+
+	// The new validity must be in the future
+	_, err = repo.ExtendSubscription(ctx, "sub-extended", time.Now().Add(-time.Minute))
+	assert.ErrorIs(t, err, ErrInvalidValidityTime)
+	_, err = repo.ExtendSubscription(ctx, "sub-unknown", time.Now().Add(time.Hour))
+	assert.ErrorIs(t, err, ErrSubscriptionNotFound)
+}
+
 func TestMemoryRepository_Stats(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	repo := NewMemoryRepository(logger)
@@ -267,3 +304,118 @@ func TestMemoryRepository_Stats(t *testing.T) {
 	assert.Equal(t, 1, stats.NFsByType["SMF"])
 	assert.Equal(t, 3, stats.NFsByStatus["REGISTERED"])
 }
+
+func TestMemoryRepository_Patch(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	repo := NewMemoryRepository(logger)
+	defer repo.Close()
+
+	ctx := context.Background()
+
+	profile := &NFProfile{
+		NFInstanceID: "amf-patch",
+		NFType:       NFTypeAMF,
+		NFStatus:     NFStatusRegistered,
+		FQDN:         "amf.5gc.local",
+		Load:         10,
+		Locality:     "site-a",
+		AMFInfo: &AMFInfo{
+			AMFSetID:    "001",
+			AMFRegionID: "01",
+			GUAMIList: []GUAMI{
+				{PLMNID: PLMNID{MCC: "001", MNC: "01"}, AMFRegionID: "01", AMFSetID: "001", AMFPointer: "01"},
+			},
+		},
+	}
+	require.NoError(t, repo.Register(ctx, profile))
+
+	// Only load and nfStatus change
+	patched, err := repo.Patch(ctx, "amf-patch", []byte(`{"load":75,"nfStatus":"SUSPENDED"}`))
+	require.NoError(t, err)
+	assert.Equal(t, 75, patched.Load)
+	assert.Equal(t, NFStatusSuspended, patched.NFStatus)
+
+	retrieved, err := repo.Get(ctx, "amf-patch")
+	require.NoError(t, err)
+	assert.Equal(t, 75, retrieved.Load)
+	assert.Equal(t, NFStatusSuspended, retrieved.NFStatus)
+	assert.Equal(t, "amf.5gc.local", retrieved.FQDN)
+	assert.Equal(t, "site-a", retrieved.Locality)
+	require.NotNil(t, retrieved.AMFInfo)
+	assert.Equal(t, "001", retrieved.AMFInfo.AMFSetID)
+	assert.Equal(t, "01", retrieved.AMFInfo.AMFRegionID)
+	assert.Len(t, retrieved.AMFInfo.GUAMIList, 1)
+
+	// Nested objects are merged member by member
+	_, err = repo.Patch(ctx, "amf-patch", []byte(`{"amfInfo":{"amfSetId":"002"}}`))
+	require.NoError(t, err)
+	retrieved, err = repo.Get(ctx, "amf-patch")
+	require.NoError(t, err)
+	assert.Equal(t, "002", retrieved.AMFInfo.AMFSetID)
+	assert.Equal(t, "01", retrieved.AMFInfo.AMFRegionID)
+	assert.Len(t, retrieved.AMFInfo.GUAMIList, 1)
+}
+
+func TestMemoryRepository_PatchRemovesNullMembers(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	repo := NewMemoryRepository(logger)
+	defer repo.Close()
+
+	ctx := context.Background()
+
+	profile := &NFProfile{
+		NFInstanceID: "smf-patch",
+		NFType:       NFTypeSMF,
+		NFStatus:     NFStatusRegistered,
+		Locality:     "site-a",
+		SMFInfo:      &SMFInfo{AccessType: []string{"3GPP_ACCESS"}},
+	}
+	require.NoError(t, repo.Register(ctx, profile))
+
+	patched, err := repo.Patch(ctx, "smf-patch", []byte(`{"locality":null,"smfInfo":null}`))
+	require.NoError(t, err)
+	assert.Empty(t, patched.Locality)
+	assert.Nil(t, patched.SMFInfo)
+	assert.Equal(t, NFTypeSMF, patched.NFType)
+}
+
+func TestMemoryRepository_PatchRejectsInvalidResult(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	repo := NewMemoryRepository(logger)
+	defer repo.Close()
+
+	ctx := context.Background()
+
+	profile := &NFProfile{
+		NFInstanceID: "udm-patch",
+		NFType:       NFTypeUDM,
+		NFStatus:     NFStatusRegistered,
+		Load:         10,
+	}
+	require.NoError(t, repo.Register(ctx, profile))
+
+	tests := []struct {
+		name  string
+		patch string
+	}{
+		{"remove required member", `{"nfType":null}`},
+		{"change instance ID", `{"nfInstanceId":"other"}`},
+		{"wrong member type", `{"load":"high"}`},
+		{"malformed JSON", `{"load":`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := repo.Patch(ctx, "udm-patch", []byte(tt.patch))
+			assert.ErrorIs(t, err, ErrInvalidProfile)
+		})
+	}
+
+	// A rejected patch leaves the stored profile untouched
+	retrieved, err := repo.Get(ctx, "udm-patch")
+	require.NoError(t, err)
+	assert.Equal(t, NFTypeUDM, retrieved.NFType)
+	assert.Equal(t, 10, retrieved.Load)
+
+	_, err = repo.Patch(ctx, "missing", []byte(`{"load":1}`))
+	assert.ErrorIs(t, err, ErrNFNotFound)
+}
@@ -17,6 +17,7 @@ type Subscription struct {
 
 	// Metadata
 	CreatedAt time.Time `json:"createdAt"`
+	Stale     bool      `json:"-"` // Callback delivery gave up, no longer notified
 }
 
 // IsExpired checks if the subscription has expired
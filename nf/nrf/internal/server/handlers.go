@@ -2,12 +2,18 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/your-org/5g-network/common/metrics"
+	"github.com/your-org/5g-network/common/sbi"
 	"github.com/your-org/5g-network/nf/nrf/internal/repository"
 	"go.uber.org/zap"
 )
@@ -20,7 +26,7 @@ func (s *NRFServer) handleNFRegister(w http.ResponseWriter, r *http.Request) {
 	// Parse request body
 	var profile repository.NFProfile
 	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
-		s.respondError(w, http.StatusBadRequest, "invalid request body", err)
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
 		return
 	}
 
@@ -30,7 +36,7 @@ func (s *NRFServer) handleNFRegister(w http.ResponseWriter, r *http.Request) {
 	// Register NF
 	err := s.repository.Register(r.Context(), &profile)
 	if err != nil {
-		s.respondError(w, http.StatusConflict, "registration failed", err)
+		s.respondProblem(w, http.StatusConflict, sbi.CauseResourceAlreadyExists, "registration failed", err)
 		metrics.RecordNFRegistration("unknown", "failed")
 		return
 	}
@@ -50,26 +56,33 @@ func (s *NRFServer) handleNFRegister(w http.ResponseWriter, r *http.Request) {
 }
 
 // handleNFUpdate handles NF profile update (PATCH /nf-instances/{nfInstanceId})
-// TS 29.510, Clause 5.2.2.2.2
+// TS 29.510, Clause 5.2.2.2.2. The body is a JSON Merge Patch (RFC 7396).
 func (s *NRFServer) handleNFUpdate(w http.ResponseWriter, r *http.Request) {
 	nfInstanceID := chi.URLParam(r, "nfInstanceId")
 
-	// Parse request body
-	var profile repository.NFProfile
-	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
-		s.respondError(w, http.StatusBadRequest, "invalid request body", err)
+	// Read the merge patch
+	patch, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
 		return
 	}
 
-	// Update NF
-	err := s.repository.Update(r.Context(), nfInstanceID, &profile)
+	// Apply it to the stored profile
+	profile, err := s.repository.Patch(r.Context(), nfInstanceID, patch)
 	if err != nil {
-		s.respondError(w, http.StatusNotFound, "update failed", err)
+		switch {
+		case errors.Is(err, repository.ErrNFNotFound):
+			s.respondProblem(w, http.StatusNotFound, sbi.CauseDataNotFound, "update failed", err)
+		case errors.Is(err, repository.ErrInvalidProfile):
+			s.respondProblem(w, http.StatusBadRequest, sbi.CauseMandatoryIEIncorrect, "update failed", err)
+		default:
+			s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "update failed", err)
+		}
 		return
 	}
 
 	// Return updated profile
-	s.respondJSON(w, http.StatusOK, &profile)
+	s.respondJSON(w, http.StatusOK, profile)
 
 	s.logger.Info("NF profile updated",
 		zap.String("nf_instance_id", nfInstanceID),
@@ -84,7 +97,7 @@ func (s *NRFServer) handleNFDeregister(w http.ResponseWriter, r *http.Request) {
 	// Deregister NF
 	err := s.repository.Deregister(r.Context(), nfInstanceID)
 	if err != nil {
-		s.respondError(w, http.StatusNotFound, "deregistration failed", err)
+		s.respondProblem(w, http.StatusNotFound, sbi.CauseDataNotFound, "deregistration failed", err)
 		metrics.RecordNFDeregistration("failed")
 		return
 	}
@@ -111,7 +124,7 @@ func (s *NRFServer) handleNFGet(w http.ResponseWriter, r *http.Request) {
 	// Get NF profile
 	profile, err := s.repository.Get(r.Context(), nfInstanceID)
 	if err != nil {
-		s.respondError(w, http.StatusNotFound, "NF not found", err)
+		s.respondProblem(w, http.StatusNotFound, sbi.CauseDataNotFound, "NF not found", err)
 		return
 	}
 
@@ -123,7 +136,7 @@ func (s *NRFServer) handleNFList(w http.ResponseWriter, r *http.Request) {
 	// Get all NF profiles
 	profiles, err := s.repository.GetAll(r.Context())
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, "failed to get profiles", err)
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to get profiles", err)
 		return
 	}
 
@@ -144,7 +157,7 @@ func (s *NRFServer) handleHeartbeat(w http.ResponseWriter, r *http.Request) {
 	// Update heartbeat
 	err := s.repository.UpdateHeartbeat(r.Context(), nfInstanceID)
 	if err != nil {
-		s.respondError(w, http.StatusNotFound, "heartbeat failed", err)
+		s.respondProblem(w, http.StatusNotFound, sbi.CauseDataNotFound, "heartbeat failed", err)
 		return
 	}
 
@@ -198,7 +211,15 @@ func (s *NRFServer) handleNFDiscover(w http.ResponseWriter, r *http.Request) {
 		query.AMFSetID = amfSetID
 	}
 
-	// SMF-specific parameters
+	// S-NSSAIs, a JSON array such as [{"sst":1,"sd":"010203"}]
+	if snssais := r.URL.Query().Get("snssais"); snssais != "" {
+		if err := json.Unmarshal([]byte(snssais), &query.SNSSAIs); err != nil {
+			s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidQueryParam, "invalid snssais", err)
+			return
+		}
+	}
+
+	// SMF/UPF-specific parameters
 	if dnn := r.URL.Query().Get("dnn"); dnn != "" {
 		query.DNN = dnn
 	}
@@ -215,22 +236,62 @@ func (s *NRFServer) handleNFDiscover(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Output control: complete=true returns full profiles, otherwise profiles
+	// are trimmed to the comma separated attributes when any are requested
+	complete := true
+	var attributes []string
+	if value := r.URL.Query().Get("attributes"); value != "" {
+		complete = false
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				attributes = append(attributes, name)
+			}
+		}
+	}
+	if value := r.URL.Query().Get("complete"); value != "" {
+		full, err := strconv.ParseBool(value)
+		if err != nil {
+			s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidQueryParam, "invalid complete", err)
+			return
+		}
+		complete = complete || full
+	}
+
 	// Perform discovery
 	profiles, err := s.repository.Discover(r.Context(), query)
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, "discovery failed", err)
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "discovery failed", err)
 		metrics.RecordDiscoveryRequest(string(query.NFType), "failed")
 		return
 	}
 
+	var nfInstances interface{} = profiles
+	if !complete {
+		projected := make([]map[string]interface{}, 0, len(profiles))
+		for _, profile := range profiles {
+			p, err := repository.ProjectProfile(profile, attributes)
+			if err != nil {
+				s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "discovery failed", err)
+				metrics.RecordDiscoveryRequest(string(query.NFType), "failed")
+				return
+			}
+			projected = append(projected, p)
+		}
+		nfInstances = projected
+	}
+
 	// Record successful discovery
 	metrics.RecordDiscoveryRequest(string(query.NFType), "success")
 
+	// Keep the result for retrieval by searchId while it is valid
+	searchID := uuid.New().String()
+	s.searches.put(searchID, nfInstances, discoveryValidity)
+
 	// Return results
 	s.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"validityPeriod": 3600, // seconds
-		"nfInstances":    profiles,
-		"searchId":       uuid.New().String(),
+		"validityPeriod": int(discoveryValidity.Seconds()),
+		"nfInstances":    nfInstances,
+		"searchId":       searchID,
 	})
 
 	s.logger.Info("NF discovery",
@@ -239,13 +300,30 @@ func (s *NRFServer) handleNFDiscover(w http.ResponseWriter, r *http.Request) {
 	)
 }
 
+// handleGetStoredSearch returns the result of an earlier discovery
+// (GET /nnrf-disc/v1/searches/{searchId}), TS 29.510, Clause 5.3.2.2.3
+func (s *NRFServer) handleGetStoredSearch(w http.ResponseWriter, r *http.Request) {
+	searchID := chi.URLParam(r, "searchId")
+
+	nfInstances, ok := s.searches.get(searchID)
+	if !ok {
+		s.respondProblem(w, http.StatusNotFound, sbi.CauseDataNotFound, "search not found",
+			fmt.Errorf("no stored search %s", searchID))
+		return
+	}
+
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"nfInstances": nfInstances,
+	})
+}
+
 // handleSubscribe handles subscription creation (POST /subscriptions)
 // TS 29.510, Clause 5.2.2.3.1
 func (s *NRFServer) handleSubscribe(w http.ResponseWriter, r *http.Request) {
 	// Parse request body
 	var subscription repository.Subscription
 	if err := json.NewDecoder(r.Body).Decode(&subscription); err != nil {
-		s.respondError(w, http.StatusBadRequest, "invalid request body", err)
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
 		return
 	}
 
@@ -262,7 +340,7 @@ func (s *NRFServer) handleSubscribe(w http.ResponseWriter, r *http.Request) {
 	// Create subscription
 	err := s.repository.Subscribe(r.Context(), &subscription)
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, "subscription failed", err)
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "subscription failed", err)
 		return
 	}
 
@@ -283,7 +361,7 @@ func (s *NRFServer) handleUnsubscribe(w http.ResponseWriter, r *http.Request) {
 	// Delete subscription
 	err := s.repository.Unsubscribe(r.Context(), subscriptionID)
 	if err != nil {
-		s.respondError(w, http.StatusNotFound, "unsubscribe failed", err)
+		s.respondProblem(w, http.StatusNotFound, sbi.CauseSubscriptionNotFound, "unsubscribe failed", err)
 		return
 	}
 
@@ -294,6 +372,59 @@ func (s *NRFServer) handleUnsubscribe(w http.ResponseWriter, r *http.Request) {
 	)
 }
 
+// patchItem is a JSON Patch (RFC 6902) operation
+type patchItem struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// handleUpdateSubscription extends the validity of a subscription
+// (PATCH /subscriptions/{subscriptionId}), TS 29.510, Clause 5.2.2.5.6. The
+// body is a JSON Patch replacing /validityTime.
+func (s *NRFServer) handleUpdateSubscription(w http.ResponseWriter, r *http.Request) {
+	subscriptionID := chi.URLParam(r, "subscriptionId")
+
+	var items []patchItem
+	if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
+		return
+	}
+
+	var validityTime time.Time
+	for _, item := range items {
+		if (item.Op != "replace" && item.Op != "add") || item.Path != "/validityTime" {
+			s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body",
+				fmt.Errorf("unsupported patch operation %s %s", item.Op, item.Path))
+			return
+		}
+		if err := json.Unmarshal(item.Value, &validityTime); err != nil {
+			s.respondProblem(w, http.StatusBadRequest, sbi.CauseMandatoryIEIncorrect, "invalid validityTime", err)
+			return
+		}
+	}
+	if validityTime.IsZero() {
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseMandatoryIEMissing, "validityTime is required",
+			fmt.Errorf("no validityTime in patch"))
+		return
+	}
+
+	subscription, err := s.repository.ExtendSubscription(r.Context(), subscriptionID, validityTime)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrSubscriptionNotFound):
+			s.respondProblem(w, http.StatusNotFound, sbi.CauseSubscriptionNotFound, "subscription not found", err)
+		case errors.Is(err, repository.ErrInvalidValidityTime):
+			s.respondProblem(w, http.StatusBadRequest, sbi.CauseMandatoryIEIncorrect, "invalid validityTime", err)
+		default:
+			s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "subscription update failed", err)
+		}
+		return
+	}
+
+	s.respondJSON(w, http.StatusOK, subscription)
+}
+
 // handleGetSubscription handles getting a subscription (GET /subscriptions/{subscriptionId})
 func (s *NRFServer) handleGetSubscription(w http.ResponseWriter, r *http.Request) {
 	subscriptionID := chi.URLParam(r, "subscriptionId")
@@ -301,7 +432,7 @@ func (s *NRFServer) handleGetSubscription(w http.ResponseWriter, r *http.Request
 	// Get subscription
 	subscription, err := s.repository.GetSubscription(r.Context(), subscriptionID)
 	if err != nil {
-		s.respondError(w, http.StatusNotFound, "subscription not found", err)
+		s.respondProblem(w, http.StatusNotFound, sbi.CauseSubscriptionNotFound, "subscription not found", err)
 		return
 	}
 
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/your-org/5g-network/common/oauth"
+	"github.com/your-org/5g-network/common/sbi"
+	"github.com/your-org/5g-network/nf/nrf/internal/repository"
+	"go.uber.org/zap"
+)
+
+// AccessTokenRsp is a successful access token response (TS 29.510 6.3.5.2.3)
+type AccessTokenRsp struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// AccessTokenErr is a rejected access token request (TS 29.510 6.3.5.2.5)
+type AccessTokenErr struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// handleAccessToken issues an access token (POST /oauth2/token)
+// TS 29.510, Clause 5.4.2.2. The consumer must be a registered NF and
+// authenticate with its client secret.
+func (s *NRFServer) handleAccessToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		s.respondTokenError(w, http.StatusBadRequest, "invalid_request", "malformed form body")
+		return
+	}
+
+	if r.PostForm.Get("grant_type") != "client_credentials" {
+		s.respondTokenError(w, http.StatusBadRequest, "unsupported_grant_type", "grant_type must be client_credentials")
+		return
+	}
+
+	nfInstanceID := r.PostForm.Get("nfInstanceId")
+	scope := strings.Join(strings.Fields(r.PostForm.Get("scope")), " ")
+	if nfInstanceID == "" || scope == "" {
+		s.respondTokenError(w, http.StatusBadRequest, "invalid_request", "nfInstanceId and scope are required")
+		return
+	}
+
+	if !s.authenticateClient(r, nfInstanceID) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="nrf"`)
+		s.respondTokenError(w, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return
+	}
+
+	// Only registered NFs may obtain tokens, and only as themselves
+	consumer, err := s.repository.Get(r.Context(), nfInstanceID)
+	if err != nil || consumer.NFStatus != repository.NFStatusRegistered {
+		s.respondTokenError(w, http.StatusBadRequest, "invalid_client", "NF instance is not registered")
+		return
+	}
+	if nfType := r.PostForm.Get("nfType"); nfType != "" && repository.NFType(nfType) != consumer.NFType {
+		s.respondTokenError(w, http.StatusBadRequest, "invalid_client", "nfType does not match the registered profile")
+		return
+	}
+
+	audience, tokenErr := s.tokenAudience(r, scope)
+	if tokenErr != nil {
+		s.respondTokenError(w, http.StatusBadRequest, tokenErr.Error, tokenErr.ErrorDescription)
+		return
+	}
+
+	now := time.Now()
+	ttl := s.config.NF.OAuth2.TokenTTL
+	token, err := oauth.Sign(&oauth.Claims{
+		Issuer:    s.config.NF.InstanceID,
+		Subject:   nfInstanceID,
+		Audience:  audience,
+		Scope:     scope,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	}, s.tokenKey)
+	if err != nil {
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to sign access token", err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	s.respondJSON(w, http.StatusOK, &AccessTokenRsp{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(ttl.Seconds()),
+		Scope:       scope,
+	})
+
+	s.logger.Info("Access token issued",
+		zap.String("nf_instance_id", nfInstanceID),
+		zap.Strings("audience", audience),
+		zap.String("scope", scope),
+	)
+}
+
+// authenticateClient checks the HTTP Basic credentials of a token request
+// (RFC 6749 2.3.1). The client ID must be the consumer's NF instance ID.
+func (s *NRFServer) authenticateClient(r *http.Request, nfInstanceID string) bool {
+	clientID, secret, ok := r.BasicAuth()
+	if !ok || clientID != nfInstanceID {
+		return false
+	}
+	expected, ok := s.config.NF.OAuth2.ClientSecrets[clientID]
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(secret), []byte(expected)) == 1
+}
+
+// tokenAudience resolves the audience of a token: the target NF instance when
+// one is requested, whose services must cover the scope, otherwise the target
+// NF type
+func (s *NRFServer) tokenAudience(r *http.Request, scope string) ([]string, *AccessTokenErr) {
+	targetNFType := r.PostForm.Get("targetNfType")
+
+	targetID := r.PostForm.Get("targetNfInstanceId")
+	if targetID == "" {
+		if targetNFType == "" {
+			return nil, &AccessTokenErr{Error: "invalid_request", ErrorDescription: "targetNfType or targetNfInstanceId is required"}
+		}
+		return []string{targetNFType}, nil
+	}
+
+	target, err := s.repository.Get(r.Context(), targetID)
+	if err != nil {
+		return nil, &AccessTokenErr{Error: "invalid_request", ErrorDescription: "target NF instance is not registered"}
+	}
+	if targetNFType != "" && repository.NFType(targetNFType) != target.NFType {
+		return nil, &AccessTokenErr{Error: "invalid_request", ErrorDescription: "targetNfType does not match the target NF instance"}
+	}
+	if len(target.NFServices) > 0 {
+		for _, service := range strings.Fields(scope) {
+			if !offersService(target, service) {
+				return nil, &AccessTokenErr{Error: "invalid_scope", ErrorDescription: "target NF does not offer " + service}
+			}
+		}
+	}
+	return []string{targetID}, nil
+}
+
+// offersService reports whether an NF profile lists a service
+func offersService(profile *repository.NFProfile, service string) bool {
+	for _, s := range profile.NFServices {
+		if s.ServiceName == service {
+			return true
+		}
+	}
+	return false
+}
+
+// respondTokenError writes an OAuth 2.0 error response (RFC 6749 5.2)
+func (s *NRFServer) respondTokenError(w http.ResponseWriter, status int, code, description string) {
+	s.logger.Warn("Access token request rejected",
+		zap.String("error", code),
+		zap.String("description", description),
+	)
+	w.Header().Set("Cache-Control", "no-store")
+	s.respondJSON(w, status, &AccessTokenErr{Error: code, ErrorDescription: description})
+}
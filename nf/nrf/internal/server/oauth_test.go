@@ -0,0 +1,188 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/common/oauth"
+	"github.com/your-org/5g-network/nf/nrf/internal/config"
+	"github.com/your-org/5g-network/nf/nrf/internal/repository"
+)
+
+const testSigningKey = "0123456789abcdef0123456789abcdef"
+
+func newTokenTestServer(t *testing.T, ttl time.Duration) *NRFServer {
+	logger, _ := zap.NewDevelopment()
+	cfg := config.DefaultConfig()
+	cfg.NF.OAuth2 = config.OAuth2Config{
+		Enabled:    true,
+		SigningKey: testSigningKey,
+		TokenTTL:   ttl,
+		ClientSecrets: map[string]string{
+			"amf-1": "amf-secret",
+			"smf-9": "smf-secret",
+		},
+	}
+
+	s, err := NewNRFServer(cfg, logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Stop(context.Background()) })
+
+	ctx := context.Background()
+	require.NoError(t, s.repository.Register(ctx, &repository.NFProfile{
+		NFInstanceID: "amf-1",
+		NFType:       repository.NFTypeAMF,
+		NFStatus:     repository.NFStatusRegistered,
+	}))
+	require.NoError(t, s.repository.Register(ctx, &repository.NFProfile{
+		NFInstanceID: "ausf-1",
+		NFType:       repository.NFTypeAUSF,
+		NFStatus:     repository.NFStatusRegistered,
+		NFServices:   []repository.NFService{{ServiceInstanceID: "1", ServiceName: "nausf-auth"}},
+	}))
+	return s
+}
+
+// requestToken posts a token request authenticated as the form's consumer
+func requestToken(s *NRFServer, form url.Values) *httptest.ResponseRecorder {
+	id := form.Get("nfInstanceId")
+	return requestTokenAs(s, form, id, s.config.NF.OAuth2.ClientSecrets[id])
+}
+
+func requestTokenAs(s *NRFServer, form url.Values, clientID, secret string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/oauth2/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if clientID != "" {
+		req.SetBasicAuth(clientID, secret)
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func tokenForm(target string) url.Values {
+	return url.Values{
+		"grant_type":         {"client_credentials"},
+		"nfInstanceId":       {"amf-1"},
+		"nfType":             {"AMF"},
+		"targetNfInstanceId": {target},
+		"scope":              {"nausf-auth"},
+	}
+}
+
+func TestAccessToken_Issue(t *testing.T) {
+	s := newTokenTestServer(t, time.Hour)
+
+	rec := requestToken(s, tokenForm("ausf-1"))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var rsp AccessTokenRsp
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&rsp))
+	assert.Equal(t, "Bearer", rsp.TokenType)
+	assert.Equal(t, 3600, rsp.ExpiresIn)
+	assert.Equal(t, "nausf-auth", rsp.Scope)
+
+	claims, err := oauth.NewVerifier([]byte(testSigningKey), "ausf-1", "AUSF").Verify(rsp.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, s.config.NF.InstanceID, claims.Issuer)
+	assert.Equal(t, "amf-1", claims.Subject)
+	assert.Equal(t, []string{"ausf-1"}, claims.Audience)
+	assert.True(t, claims.HasScope("nausf-auth"))
+
+	// A token for another producer is rejected
+	_, err = oauth.NewVerifier([]byte(testSigningKey), "udm-1", "UDM").Verify(rsp.AccessToken)
+	assert.ErrorIs(t, err, oauth.ErrAudienceMismatch)
+}
+
+func TestAccessToken_Expiry(t *testing.T) {
+	s := newTokenTestServer(t, 2*time.Second)
+
+	form := tokenForm("")
+	form.Del("targetNfInstanceId")
+	form.Set("targetNfType", "AUSF")
+	rec := requestToken(s, form)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	var rsp AccessTokenRsp
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&rsp))
+
+	assert.Equal(t, 2, rsp.ExpiresIn)
+
+	verifier := oauth.NewVerifier([]byte(testSigningKey), "ausf-1", "AUSF")
+	claims, err := verifier.Verify(rsp.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, claims.IssuedAt+2, claims.ExpiresAt)
+	assert.Equal(t, []string{"AUSF"}, claims.Audience)
+
+	time.Sleep(time.Until(time.Unix(claims.ExpiresAt, 0)))
+	_, err = verifier.Verify(rsp.AccessToken)
+	assert.ErrorIs(t, err, oauth.ErrTokenExpired)
+}
+
+func TestAccessToken_Rejected(t *testing.T) {
+	s := newTokenTestServer(t, time.Hour)
+
+	tests := []struct {
+		name   string
+		modify func(url.Values)
+		code   string
+	}{
+		{"wrong grant type", func(f url.Values) { f.Set("grant_type", "password") }, "unsupported_grant_type"},
+		{"missing scope", func(f url.Values) { f.Del("scope") }, "invalid_request"},
+		{"unregistered consumer", func(f url.Values) { f.Set("nfInstanceId", "smf-9") }, "invalid_client"},
+		{"consumer type mismatch", func(f url.Values) { f.Set("nfType", "SMF") }, "invalid_client"},
+		{"missing target", func(f url.Values) { f.Del("targetNfInstanceId") }, "invalid_request"},
+		{"unregistered target", func(f url.Values) { f.Set("targetNfInstanceId", "ausf-9") }, "invalid_request"},
+		{"service not offered", func(f url.Values) { f.Set("scope", "nausf-auth nudm-sdm") }, "invalid_scope"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := tokenForm("ausf-1")
+			tt.modify(form)
+
+			rec := requestToken(s, form)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+			var rsp AccessTokenErr
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&rsp))
+			assert.Equal(t, tt.code, rsp.Error)
+		})
+	}
+}
+
+func TestAccessToken_ClientAuthentication(t *testing.T) {
+	s := newTokenTestServer(t, time.Hour)
+
+	tests := []struct {
+		name     string
+		clientID string
+		secret   string
+	}{
+		{"no credentials", "", ""},
+		{"wrong secret", "amf-1", "guess"},
+		{"another NF's credentials", "smf-9", "smf-secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := requestTokenAs(s, tokenForm("ausf-1"), tt.clientID, tt.secret)
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+			assert.NotEmpty(t, rec.Header().Get("WWW-Authenticate"))
+
+			var rsp AccessTokenErr
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&rsp))
+			assert.Equal(t, "invalid_client", rsp.Error)
+		})
+	}
+}
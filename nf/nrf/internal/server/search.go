@@ -0,0 +1,61 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// discoveryValidity is the validityPeriod advertised with discovery results,
+// for which they are kept as a stored search (TS 29.510, Clause 5.3.2.4)
+const discoveryValidity = time.Hour
+
+// storedSearch is a cached discovery result
+type storedSearch struct {
+	nfInstances interface{}
+	expiry      time.Time
+}
+
+// searchStore keeps discovery results by searchId until their validity period
+// ends
+type searchStore struct {
+	mu       sync.Mutex
+	searches map[string]storedSearch
+	now      func() time.Time // Replaceable in tests
+}
+
+func newSearchStore() *searchStore {
+	return &searchStore{
+		searches: make(map[string]storedSearch),
+		now:      time.Now,
+	}
+}
+
+// put stores a discovery result, dropping the expired ones
+func (s *searchStore) put(searchID string, nfInstances interface{}, validity time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for id, search := range s.searches {
+		if !now.Before(search.expiry) {
+			delete(s.searches, id)
+		}
+	}
+	s.searches[searchID] = storedSearch{nfInstances: nfInstances, expiry: now.Add(validity)}
+}
+
+// get returns a stored discovery result that has not expired
+func (s *searchStore) get(searchID string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	search, ok := s.searches[searchID]
+	if !ok {
+		return nil, false
+	}
+	if !s.now().Before(search.expiry) {
+		delete(s.searches, searchID)
+		return nil, false
+	}
+	return search.nfInstances, true
+}
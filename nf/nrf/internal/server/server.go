@@ -9,6 +9,10 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/your-org/5g-network/common/buildinfo"
+	"github.com/your-org/5g-network/common/correlation"
+	"github.com/your-org/5g-network/common/metrics"
+	"github.com/your-org/5g-network/common/sbi"
 	"github.com/your-org/5g-network/nf/nrf/internal/config"
 	"github.com/your-org/5g-network/nf/nrf/internal/repository"
 	"go.uber.org/zap"
@@ -18,23 +22,62 @@ import (
 type NRFServer struct {
 	config     *config.Config
 	repository repository.Repository
+	notifier   *repository.HTTPNotifier
+	searches   *searchStore
 	router     *chi.Mux
 	httpServer *http.Server
 	logger     *zap.Logger
+
+	// Access token signing key, nil when the token service is disabled
+	tokenKey []byte
 }
 
 // NewNRFServer creates a new NRF server instance
 func NewNRFServer(cfg *config.Config, logger *zap.Logger) (*NRFServer, error) {
-	// Create repository
-	repo := repository.NewMemoryRepository(logger)
+	// Create repository, restoring saved state when a path is configured
+	var repo *repository.MemoryRepository
+	if cfg.Database.Path != "" {
+		var err error
+		repo, err = repository.NewPersistentRepository(cfg.Database.Path, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create repository: %w", err)
+		}
+	} else {
+		repo = repository.NewMemoryRepository(logger)
+	}
+
+	// Deliver NF status notifications to subscribers
+	notifyCfg := cfg.NF.Notification
+	if notifyCfg.APIRoot == "" {
+		notifyCfg.APIRoot = fmt.Sprintf("%s://%s:%d", cfg.SBI.Scheme, cfg.SBI.BindAddress, cfg.SBI.Port)
+	}
+	transport, err := cfg.SBI.ClientTLS.Transport()
+	if err != nil {
+		return nil, fmt.Errorf("invalid client TLS configuration: %w", err)
+	}
+	notifier := repository.NewHTTPNotifier(notifyCfg, correlation.NewTransport(transport, logger), logger)
+	if notifyCfg.MarkStale {
+		notifier.OnFailure(repo.MarkSubscriptionStale)
+	}
+	repo.SetNotifier(notifier)
 
 	server := &NRFServer{
 		config:     cfg,
 		repository: repo,
+		notifier:   notifier,
+		searches:   newSearchStore(),
 		router:     chi.NewRouter(),
 		logger:     logger,
 	}
 
+	if cfg.NF.OAuth2.Enabled {
+		key, err := cfg.NF.OAuth2.Key()
+		if err != nil {
+			return nil, fmt.Errorf("invalid OAuth2 configuration: %w", err)
+		}
+		server.tokenKey = key
+	}
+
 	// Setup routes
 	server.setupRoutes()
 
@@ -45,7 +88,9 @@ func NewNRFServer(cfg *config.Config, logger *zap.Logger) (*NRFServer, error) {
 func (s *NRFServer) setupRoutes() {
 	// Middleware
 	s.router.Use(middleware.RequestID)
+	s.router.Use(correlation.Middleware)
 	s.router.Use(middleware.RealIP)
+	s.router.Use(metrics.Middleware)
 	s.router.Use(s.loggingMiddleware)
 	s.router.Use(middleware.Recoverer)
 	s.router.Use(middleware.Timeout(60 * time.Second))
@@ -69,18 +114,30 @@ func (s *NRFServer) setupRoutes() {
 		// Subscriptions
 		r.Post("/subscriptions", s.handleSubscribe)
 		r.Delete("/subscriptions/{subscriptionId}", s.handleUnsubscribe)
+		r.Patch("/subscriptions/{subscriptionId}", s.handleUpdateSubscription)
 		r.Get("/subscriptions/{subscriptionId}", s.handleGetSubscription)
 	})
 
 	// NF Discovery Service (TS 29.510, Clause 5.2.3)
 	s.router.Route("/nnrf-disc/v1", func(r chi.Router) {
 		r.Get("/nf-instances", s.handleNFDiscover)
+		r.Get("/searches/{searchId}", s.handleGetStoredSearch)
 	})
 
+	// Access Token Service (TS 29.510, Clause 5.4)
+	if s.tokenKey != nil {
+		s.router.Post("/oauth2/token", s.handleAccessToken)
+	}
+
 	// Status endpoint
 	s.router.Get("/status", s.handleStatus)
 }
 
+// SetBuildInfo exposes the NF build info on GET /version
+func (s *NRFServer) SetBuildInfo(info *buildinfo.Info) {
+	s.router.Get("/version", info.Handler())
+}
+
 // Start starts the HTTP server
 func (s *NRFServer) Start(ctx context.Context) error {
 	addr := fmt.Sprintf("%s:%d", s.config.SBI.BindAddress, s.config.SBI.Port)
@@ -97,6 +154,11 @@ func (s *NRFServer) Start(ctx context.Context) error {
 
 	// Start server
 	if s.config.SBI.TLS.Enabled {
+		tlsConfig, err := s.config.SBI.TLS.Build()
+		if err != nil {
+			return fmt.Errorf("invalid TLS configuration: %w", err)
+		}
+		s.httpServer.TLSConfig = tlsConfig
 		return s.httpServer.ListenAndServeTLS(
 			s.config.SBI.TLS.CertFile,
 			s.config.SBI.TLS.KeyFile,
@@ -114,6 +176,7 @@ func (s *NRFServer) Stop(ctx context.Context) error {
 	if memRepo, ok := s.repository.(*repository.MemoryRepository); ok {
 		memRepo.Close()
 	}
+	s.notifier.Stop()
 
 	if s.httpServer != nil {
 		return s.httpServer.Shutdown(ctx)
@@ -141,6 +204,7 @@ func (s *NRFServer) loggingMiddleware(next http.Handler) http.Handler {
 			zap.Duration("duration", time.Since(start)),
 			zap.String("remote_addr", r.RemoteAddr),
 			zap.String("request_id", middleware.GetReqID(r.Context())),
+			correlation.Field(r.Context()),
 		)
 	})
 }
@@ -171,7 +235,7 @@ func (s *NRFServer) handleReady(w http.ResponseWriter, r *http.Request) {
 func (s *NRFServer) handleStatus(w http.ResponseWriter, r *http.Request) {
 	stats, err := s.repository.GetStats(r.Context())
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, "failed to get stats", err)
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to get stats", err)
 		return
 	}
 
@@ -195,12 +259,11 @@ func (s *NRFServer) respondJSON(w http.ResponseWriter, status int, data interfac
 	}
 }
 
-// respondError writes an error response
-func (s *NRFServer) respondError(w http.ResponseWriter, status int, message string, err error) {
-	s.logger.Error(message, zap.Error(err))
-
-	w.Header().Set("Content-Type", "application/problem+json")
-	w.WriteHeader(status)
+// respondProblem writes a ProblemDetails error response
+func (s *NRFServer) respondProblem(w http.ResponseWriter, status int, cause, title string, err error) {
+	s.logger.Error(title, zap.String("cause", cause), zap.Error(err))
 
-	fmt.Fprintf(w, `{"status":%d,"title":"%s","detail":"%s"}`, status, message, err.Error())
+	if err := sbi.WriteProblem(w, sbi.NewProblem(status, cause, title, err)); err != nil {
+		s.logger.Error("Failed to encode problem details", zap.Error(err))
+	}
 }
@@ -0,0 +1,227 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/common/sbi"
+	"github.com/your-org/5g-network/nf/nrf/internal/config"
+)
+
+func TestErrorResponsesAreProblemDetails(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	s, err := NewNRFServer(config.DefaultConfig(), logger)
+	require.NoError(t, err)
+	defer s.Stop(context.Background())
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		cause  string
+	}{
+		{"unknown NF instance", http.MethodGet, "/nnrf-nfm/v1/nf-instances/amf-9", "", http.StatusNotFound, sbi.CauseDataNotFound},
+		{"malformed profile", http.MethodPut, "/nnrf-nfm/v1/nf-instances/amf-1", "{", http.StatusBadRequest, sbi.CauseInvalidMsgFormat},
+		{"patch of unknown NF instance", http.MethodPatch, "/nnrf-nfm/v1/nf-instances/amf-9", `{"nfStatus":"SUSPENDED"}`, http.StatusNotFound, sbi.CauseDataNotFound},
+		{"unknown subscription", http.MethodGet, "/nnrf-nfm/v1/subscriptions/sub-9", "", http.StatusNotFound, sbi.CauseSubscriptionNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+
+			require.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, sbi.ContentTypeProblem, rec.Header().Get("Content-Type"))
+
+			var problem sbi.ProblemDetails
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&problem))
+			assert.Equal(t, tt.status, problem.Status)
+			assert.Equal(t, tt.cause, problem.Cause)
+			assert.NotEmpty(t, problem.Title)
+			assert.NotEmpty(t, problem.Detail)
+		})
+	}
+}
+
+func TestDiscoveryOutputControl(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	s, err := NewNRFServer(config.DefaultConfig(), logger)
+	require.NoError(t, err)
+	defer s.Stop(context.Background())
+
+	profile := `{"nfType":"SMF","nfStatus":"REGISTERED","heartBeatTimer":60,"fqdn":"smf.5gc.local",` +
+		`"ipv4Addresses":["10.0.0.5"],"priority":10,"locality":"dc-1",` +
+		`"nfServices":[{"serviceInstanceId":"1","serviceName":"nsmf-pdusession","versions":[],"scheme":"http"}]}`
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/nnrf-nfm/v1/nf-instances/smf-1", strings.NewReader(profile)))
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	discover := func(t *testing.T, query string) map[string]interface{} {
+		t.Helper()
+
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/nnrf-disc/v1/nf-instances?target-nf-type=SMF"+query, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var result struct {
+			NFInstances []map[string]interface{} `json:"nfInstances"`
+		}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&result))
+		require.Len(t, result.NFInstances, 1)
+		return result.NFInstances[0]
+	}
+
+	t.Run("projected", func(t *testing.T) {
+		instance := discover(t, "&attributes=ipv4Addresses,nfServices")
+
+		assert.Equal(t, "smf-1", instance["nfInstanceId"])
+		assert.Equal(t, "SMF", instance["nfType"])
+		assert.Equal(t, []interface{}{"10.0.0.5"}, instance["ipv4Addresses"])
+		assert.Contains(t, instance, "nfServices")
+		for _, name := range []string{"fqdn", "priority", "locality", "heartBeatTimer", "createdAt"} {
+			assert.NotContains(t, instance, name)
+		}
+	})
+
+	t.Run("complete", func(t *testing.T) {
+		for _, query := range []string{"", "&complete=true", "&complete=true&attributes=ipv4Addresses"} {
+			instance := discover(t, query)
+			for _, name := range []string{"nfInstanceId", "ipv4Addresses", "nfServices", "fqdn", "priority", "locality", "heartBeatTimer"} {
+				assert.Contains(t, instance, name, "query %q", query)
+			}
+		}
+	})
+
+	t.Run("invalid complete flag", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/nnrf-disc/v1/nf-instances?complete=maybe", nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestStoredSearch(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	s, err := NewNRFServer(config.DefaultConfig(), logger)
+	require.NoError(t, err)
+	defer s.Stop(context.Background())
+
+	now := time.Now()
+	s.searches.now = func() time.Time { return now }
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/nnrf-nfm/v1/nf-instances/udm-1",
+		strings.NewReader(`{"nfType":"UDM","nfStatus":"REGISTERED","heartBeatTimer":60,"ipv4Addresses":["10.0.0.7"]}`)))
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/nnrf-disc/v1/nf-instances?target-nf-type=UDM&attributes=ipv4Addresses", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var search struct {
+		ValidityPeriod int    `json:"validityPeriod"`
+		SearchID       string `json:"searchId"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&search))
+	assert.Equal(t, 3600, search.ValidityPeriod)
+
+	fetch := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/nnrf-disc/v1/searches/"+search.SearchID, nil))
+		return rec
+	}
+
+	// The stored result is the one returned by the discovery, even once the
+	// NF is gone
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/nnrf-nfm/v1/nf-instances/udm-1", nil))
+	require.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = fetch()
+	require.Equal(t, http.StatusOK, rec.Code)
+	var stored struct {
+		NFInstances []map[string]interface{} `json:"nfInstances"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&stored))
+	require.Len(t, stored.NFInstances, 1)
+	assert.Equal(t, "udm-1", stored.NFInstances[0]["nfInstanceId"])
+	assert.Equal(t, []interface{}{"10.0.0.7"}, stored.NFInstances[0]["ipv4Addresses"])
+	assert.NotContains(t, stored.NFInstances[0], "heartBeatTimer")
+
+	// It expires with the validity period
+	now = now.Add(time.Hour)
+	rec = fetch()
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, sbi.ContentTypeProblem, rec.Header().Get("Content-Type"))
+
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/nnrf-disc/v1/searches/unknown", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestUpdateSubscriptionValidity(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	s, err := NewNRFServer(config.DefaultConfig(), logger)
+	require.NoError(t, err)
+	defer s.Stop(context.Background())
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/nnrf-nfm/v1/subscriptions",
+		strings.NewReader(`{"subscriptionId":"sub-1","nfType":"AMF","nfStatusNotificationUri":"http://smf.local/notify"}`)))
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	patch := func(id, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPatch, "/nnrf-nfm/v1/subscriptions/"+id, strings.NewReader(body)))
+		return rec
+	}
+	validityPatch := func(validity time.Time) string {
+		return `[{"op":"replace","path":"/validityTime","value":"` + validity.Format(time.RFC3339) + `"}]`
+	}
+
+	validity := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+	rec = patch("sub-1", validityPatch(validity))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/nnrf-nfm/v1/subscriptions/sub-1", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var subscription struct {
+		ValidityTime time.Time `json:"validityTime"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&subscription))
+	assert.True(t, validity.Equal(subscription.ValidityTime))
+
+	tests := []struct {
+		name   string
+		id     string
+		body   string
+		status int
+		cause  string
+	}{
+		{"past validity", "sub-1", validityPatch(time.Now().Add(-time.Hour)), http.StatusBadRequest, sbi.CauseMandatoryIEIncorrect},
+		{"other attribute", "sub-1", `[{"op":"replace","path":"/nfType","value":"SMF"}]`, http.StatusBadRequest, sbi.CauseInvalidMsgFormat},
+		{"empty patch", "sub-1", `[]`, http.StatusBadRequest, sbi.CauseMandatoryIEMissing},
+		{"unknown subscription", "sub-9", validityPatch(validity), http.StatusNotFound, sbi.CauseSubscriptionNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := patch(tt.id, tt.body)
+			require.Equal(t, tt.status, rec.Code)
+
+			var problem sbi.ProblemDetails
+			require.NoError(t, json.NewDecoder(rec.Body).Decode(&problem))
+			assert.Equal(t, tt.cause, problem.Cause)
+		})
+	}
+}
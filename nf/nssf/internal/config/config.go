@@ -0,0 +1,203 @@
+package config
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/your-org/5g-network/common/nrfpool"
+	"github.com/your-org/5g-network/common/oauth"
+	"github.com/your-org/5g-network/common/retry"
+	"github.com/your-org/5g-network/common/tlsconfig"
+	"gopkg.in/yaml.v3"
+)
+
+// Config represents the NSSF configuration
+type Config struct {
+	NF            NFConfig            `yaml:"nf"`
+	SBI           SBIConfig           `yaml:"sbi"`
+	NRF           NRFConfig           `yaml:"nrf"`
+	PLMN          PLMNConfig          `yaml:"plmn"`
+	Slices        []SliceConfig       `yaml:"slices"`
+	Observability ObservabilityConfig `yaml:"observability"`
+}
+
+// NFConfig contains NF instance configuration
+type NFConfig struct {
+	Name        string `yaml:"name"`
+	InstanceID  string `yaml:"instance_id"`
+	Description string `yaml:"description"`
+}
+
+// SBIConfig contains Service-Based Interface configuration
+type SBIConfig struct {
+	Scheme      string                 `yaml:"scheme"`
+	BindAddress string                 `yaml:"bind_address"`
+	Port        int                    `yaml:"port"`
+	TLS         TLSConfig              `yaml:"tls"`
+	ClientTLS   tlsconfig.ClientConfig `yaml:"client_tls"`   // TLS towards the SBIs of other NFs
+	ClientRetry retry.Policy           `yaml:"client_retry"` // Retries of idempotent requests to other NFs
+	OAuth2      oauth.Config           `yaml:"oauth2"`
+}
+
+// TLSConfig contains TLS configuration
+type TLSConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+
+	tlsconfig.Options `yaml:",inline"`
+}
+
+// NRFConfig contains NRF client configuration
+type NRFConfig struct {
+	URL               string        `yaml:"url"`
+	Enabled           bool          `yaml:"enabled"`
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"`
+
+	// Secondary NRFs and DNS SRV discovery for failover
+	nrfpool.Options `yaml:",inline"`
+}
+
+// PLMNConfig contains PLMN configuration
+type PLMNConfig struct {
+	MCC string `yaml:"mcc"` // Mobile Country Code
+	MNC string `yaml:"mnc"` // Mobile Network Code
+}
+
+// SliceConfig is a network slice of the PLMN and the AMF sets serving it
+type SliceConfig struct {
+	SST     uint8          `yaml:"sst"`      // Slice/Service Type
+	SD      string         `yaml:"sd"`       // Slice Differentiator, 6 hex digits
+	TACs    []string       `yaml:"tacs"`     // Tracking areas (hex) the slice is available in, all when empty
+	AMFSets []AMFSetConfig `yaml:"amf_sets"` // In order of preference
+}
+
+// AMFSetConfig identifies an AMF set (TS 23.003 2.10.1)
+type AMFSetConfig struct {
+	RegionID uint8  `yaml:"region_id"`
+	SetID    uint16 `yaml:"set_id"` // 10 bits
+}
+
+// ObservabilityConfig contains observability settings
+type ObservabilityConfig struct {
+	Metrics MetricsConfig `yaml:"metrics"`
+	Logging LoggingConfig `yaml:"logging"`
+}
+
+// MetricsConfig contains metrics configuration
+type MetricsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Port    int  `yaml:"port"`
+}
+
+// LoggingConfig contains logging configuration
+type LoggingConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+// Load loads configuration from a YAML file
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	// Validate configuration
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return &config, nil
+}
+
+// Validate validates the configuration
+func (c *Config) Validate() error {
+	if c.NF.Name == "" {
+		return fmt.Errorf("nf.name is required")
+	}
+
+	if c.NF.InstanceID == "" {
+		return fmt.Errorf("nf.instance_id is required")
+	}
+
+	if c.SBI.Port <= 0 || c.SBI.Port > 65535 {
+		return fmt.Errorf("invalid sbi.port: %d", c.SBI.Port)
+	}
+
+	if c.NRF.Enabled && c.NRF.URL == "" && c.NRF.SRVName == "" {
+		return fmt.Errorf("nrf.url or nrf.srv_name is required when nrf.enabled is true")
+	}
+
+	if err := c.NRF.Validate(); err != nil {
+		return fmt.Errorf("invalid nrf: %w", err)
+	}
+
+	if c.PLMN.MCC == "" || c.PLMN.MNC == "" {
+		return fmt.Errorf("plmn.mcc and plmn.mnc are required")
+	}
+
+	if len(c.Slices) == 0 {
+		return fmt.Errorf("at least one slice must be configured")
+	}
+	for i, slice := range c.Slices {
+		if err := slice.Validate(); err != nil {
+			return fmt.Errorf("invalid slices[%d]: %w", i, err)
+		}
+	}
+
+	if c.SBI.TLS.Enabled {
+		if err := c.SBI.TLS.Validate(); err != nil {
+			return fmt.Errorf("invalid sbi.tls: %w", err)
+		}
+	}
+
+	if err := c.SBI.ClientTLS.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.client_tls: %w", err)
+	}
+
+	if err := c.SBI.ClientRetry.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.client_retry: %w", err)
+	}
+
+	if err := c.SBI.OAuth2.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.oauth2: %w", err)
+	}
+
+	return nil
+}
+
+// Validate validates a slice configuration
+func (s *SliceConfig) Validate() error {
+	if s.SD != "" {
+		if sd, err := hex.DecodeString(s.SD); err != nil || len(sd) != 3 {
+			return fmt.Errorf("sd must be 6 hex digits, have %q", s.SD)
+		}
+	}
+	for _, tac := range s.TACs {
+		if b, err := hex.DecodeString(tac); err != nil || len(b) != 3 {
+			return fmt.Errorf("tac must be 6 hex digits, have %q", tac)
+		}
+	}
+	if len(s.AMFSets) == 0 {
+		return fmt.Errorf("at least one amf set is required")
+	}
+	for _, set := range s.AMFSets {
+		if set.SetID > 0x3ff {
+			return fmt.Errorf("amf set id %d exceeds 10 bits", set.SetID)
+		}
+	}
+	return nil
+}
+
+// GetSBIURL returns the full SBI URL
+func (c *Config) GetSBIURL() string {
+	return fmt.Sprintf("%s://%s:%d", c.SBI.Scheme, c.SBI.BindAddress, c.SBI.Port)
+}
@@ -0,0 +1,242 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/your-org/5g-network/common/buildinfo"
+	"github.com/your-org/5g-network/common/correlation"
+	"github.com/your-org/5g-network/common/oauth"
+	"github.com/your-org/5g-network/common/sbi"
+	"github.com/your-org/5g-network/nf/nssf/internal/config"
+	"github.com/your-org/5g-network/nf/nssf/internal/service"
+	"go.uber.org/zap"
+)
+
+// NSSFServer represents the NSSF HTTP server
+type NSSFServer struct {
+	config *config.Config
+	router *chi.Mux
+	server *http.Server
+	logger *zap.Logger
+
+	// Services
+	selectionService *service.SelectionService
+}
+
+// NewServer creates a new NSSF server
+func NewServer(
+	cfg *config.Config,
+	selectionService *service.SelectionService,
+	logger *zap.Logger,
+) *NSSFServer {
+	s := &NSSFServer{
+		config:           cfg,
+		router:           chi.NewRouter(),
+		logger:           logger,
+		selectionService: selectionService,
+	}
+
+	s.setupMiddleware()
+	s.setupRoutes()
+
+	return s
+}
+
+// setupMiddleware configures HTTP middleware
+func (s *NSSFServer) setupMiddleware() {
+	s.router.Use(middleware.RequestID)
+	s.router.Use(correlation.Middleware)
+	s.router.Use(middleware.RealIP)
+	s.router.Use(s.loggingMiddleware)
+	s.router.Use(middleware.Recoverer)
+	s.router.Use(middleware.Timeout(60 * time.Second))
+	s.router.Use(oauth.Middleware(s.config.SBI.OAuth2, s.logger, "NSSF", s.config.NF.InstanceID))
+}
+
+// setupRoutes configures HTTP routes
+func (s *NSSFServer) setupRoutes() {
+	// Health and status
+	s.router.Get("/health", s.handleHealth)
+	s.router.Get("/ready", s.handleReady)
+	s.router.Get("/status", s.handleStatus)
+
+	// Nnssf_NSSelection service (TS 29.531)
+	s.router.Route("/nnssf-nsselection/v2", func(r chi.Router) {
+		r.Use(oauth.RequireScope("nnssf-nsselection"))
+		r.Get("/network-slice-information", s.handleGetNetworkSliceInformation)
+	})
+
+	// Admin endpoints
+	s.router.Route("/admin", func(r chi.Router) {
+		r.Get("/stats", s.handleGetStats)
+	})
+}
+
+// SetBuildInfo exposes the NF build info on GET /version
+func (s *NSSFServer) SetBuildInfo(info *buildinfo.Info) {
+	s.router.Get("/version", info.Handler())
+}
+
+// Start starts the HTTP server
+func (s *NSSFServer) Start() error {
+	addr := fmt.Sprintf("%s:%d", s.config.SBI.BindAddress, s.config.SBI.Port)
+
+	s.server = &http.Server{
+		Addr:         addr,
+		Handler:      s.router,
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		IdleTimeout:  120 * time.Second,
+	}
+
+	s.logger.Info("Starting NSSF HTTP server", zap.String("address", addr))
+
+	if s.config.SBI.TLS.Enabled {
+		tlsConfig, err := s.config.SBI.TLS.Build()
+		if err != nil {
+			return fmt.Errorf("invalid TLS configuration: %w", err)
+		}
+		s.server.TLSConfig = tlsConfig
+		return s.server.ListenAndServeTLS(s.config.SBI.TLS.CertFile, s.config.SBI.TLS.KeyFile)
+	}
+
+	return s.server.ListenAndServe()
+}
+
+// Stop gracefully stops the HTTP server
+func (s *NSSFServer) Stop(ctx context.Context) error {
+	s.logger.Info("Stopping NSSF HTTP server")
+
+	if s.server != nil {
+		return s.server.Shutdown(ctx)
+	}
+
+	return nil
+}
+
+// Middleware
+
+func (s *NSSFServer) loggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		s.logger.Info("HTTP request",
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+			zap.Int("status", ww.Status()),
+			zap.Duration("duration", time.Since(start)),
+			zap.String("remote_addr", r.RemoteAddr),
+			zap.String("request_id", middleware.GetReqID(r.Context())),
+			correlation.Field(r.Context()),
+		)
+	})
+}
+
+// Helper functions
+
+func (s *NSSFServer) respondJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		s.logger.Error("Failed to encode JSON response", zap.Error(err))
+	}
+}
+
+// respondProblem writes a ProblemDetails error response
+func (s *NSSFServer) respondProblem(w http.ResponseWriter, status int, cause, title string, err error) {
+	s.logger.Error(title, zap.String("cause", cause), zap.Error(err))
+
+	if err := sbi.WriteProblem(w, sbi.NewProblem(status, cause, title, err)); err != nil {
+		s.logger.Error("Failed to encode problem details", zap.Error(err))
+	}
+}
+
+// Health check handlers
+
+func (s *NSSFServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	s.respondJSON(w, http.StatusOK, map[string]string{
+		"status": "healthy",
+	})
+}
+
+func (s *NSSFServer) handleReady(w http.ResponseWriter, r *http.Request) {
+	s.respondJSON(w, http.StatusOK, map[string]string{
+		"status": "ready",
+	})
+}
+
+func (s *NSSFServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"service": "NSSF",
+		"version": "1.0.0",
+		"stats":   s.selectionService.GetStats(),
+	})
+}
+
+func (s *NSSFServer) handleGetStats(w http.ResponseWriter, r *http.Request) {
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"service":         "NSSF",
+		"version":         "1.0.0",
+		"selection_stats": s.selectionService.GetStats(),
+	})
+}
+
+// handleGetNetworkSliceInformation handles the slice selection of a
+// registration. The complex query parameters are JSON encoded.
+// TS 29.531, Clause 5.2.2.2
+func (s *NSSFServer) handleGetNetworkSliceInformation(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	for _, param := range []string{"nf-type", "nf-id", "slice-info-request-for-registration"} {
+		if query.Get(param) == "" {
+			s.respondProblem(w, http.StatusBadRequest, sbi.CauseMandatoryIEMissing,
+				"missing query parameter", fmt.Errorf("%s is required", param))
+			return
+		}
+	}
+
+	var info service.SliceInfoForRegistration
+	if err := json.Unmarshal([]byte(query.Get("slice-info-request-for-registration")), &info); err != nil {
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidQueryParam,
+			"invalid slice-info-request-for-registration", err)
+		return
+	}
+
+	var tai *service.TAI
+	if raw := query.Get("tai"); raw != "" {
+		tai = &service.TAI{}
+		if err := json.Unmarshal([]byte(raw), tai); err != nil {
+			s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidQueryParam, "invalid tai", err)
+			return
+		}
+	}
+
+	s.logger.Info("Received network slice selection request",
+		zap.String("nf_type", query.Get("nf-type")),
+		zap.String("nf_id", query.Get("nf-id")),
+		zap.Int("requested", len(info.RequestedNSSAI)),
+	)
+
+	result, err := s.selectionService.SelectForRegistration(&info, tai)
+	if err != nil {
+		status, cause := http.StatusInternalServerError, sbi.CauseSystemFailure
+		if errors.Is(err, service.ErrInvalidSNSSAI) {
+			status, cause = http.StatusBadRequest, sbi.CauseInvalidQueryParam
+		}
+		s.respondProblem(w, status, cause, "failed to select network slices", err)
+		return
+	}
+
+	s.respondJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/common/sbi"
+	"github.com/your-org/5g-network/nf/nssf/internal/config"
+	"github.com/your-org/5g-network/nf/nssf/internal/service"
+)
+
+func newTestServer(t *testing.T) *NSSFServer {
+	logger, _ := zap.NewDevelopment()
+	cfg := &config.Config{
+		PLMN: config.PLMNConfig{MCC: "001", MNC: "01"},
+		Slices: []config.SliceConfig{
+			{SST: 1, SD: "000001", AMFSets: []config.AMFSetConfig{{RegionID: 128, SetID: 1}}},
+		},
+	}
+	return NewServer(cfg, service.NewSelectionService(cfg.PLMN, cfg.Slices, logger), logger)
+}
+
+func sliceInformationRequest(params map[string]string) *http.Request {
+	query := url.Values{}
+	for k, v := range params {
+		query.Set(k, v)
+	}
+	return httptest.NewRequest(http.MethodGet, "/nnssf-nsselection/v2/network-slice-information?"+query.Encode(), nil)
+}
+
+func TestGetNetworkSliceInformation(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, sliceInformationRequest(map[string]string{
+		"nf-type":                             "AMF",
+		"nf-id":                               "00000000-0000-0000-0000-000000000001",
+		"slice-info-request-for-registration": `{"subscribedNssai":[{"subscribedSnssai":{"sst":1,"sd":"000001"},"defaultIndication":true}],"requestedNssai":[{"sst":1,"sd":"000001"},{"sst":9}]}`,
+		"tai":                                 `{"plmnId":{"mcc":"001","mnc":"01"},"tac":"000001"}`,
+	}))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var result service.AuthorizedNetworkSliceInfo
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	require.Len(t, result.AllowedNSSAIList, 1)
+	assert.Equal(t, []service.AllowedSNSSAI{{AllowedSNSSAI: service.SNSSAI{SST: 1, SD: "000001"}}},
+		result.AllowedNSSAIList[0].AllowedSNSSAIList)
+	assert.Equal(t, []service.SNSSAI{{SST: 9}}, result.RejectedNSSAIInPLMN)
+	assert.Equal(t, "00101-80-001", result.TargetAMFSet)
+}
+
+func TestGetNetworkSliceInformationBadRequest(t *testing.T) {
+	s := newTestServer(t)
+	sliceInfo := `{"requestedNssai":[{"sst":1,"sd":"000001"}]}`
+
+	tests := []struct {
+		name   string
+		params map[string]string
+		cause  string
+	}{
+		{"missing nf-id", map[string]string{"nf-type": "AMF", "slice-info-request-for-registration": sliceInfo}, sbi.CauseMandatoryIEMissing},
+		{"missing slice info", map[string]string{"nf-type": "AMF", "nf-id": "amf-1"}, sbi.CauseMandatoryIEMissing},
+		{"malformed slice info", map[string]string{"nf-type": "AMF", "nf-id": "amf-1", "slice-info-request-for-registration": "{"}, sbi.CauseInvalidQueryParam},
+		{"malformed tai", map[string]string{"nf-type": "AMF", "nf-id": "amf-1", "slice-info-request-for-registration": sliceInfo, "tai": "000001"}, sbi.CauseInvalidQueryParam},
+		{"invalid sd", map[string]string{"nf-type": "AMF", "nf-id": "amf-1", "slice-info-request-for-registration": `{"requestedNssai":[{"sst":1,"sd":"1"}]}`}, sbi.CauseInvalidQueryParam},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.router.ServeHTTP(rec, sliceInformationRequest(tt.params))
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+
+			var problem sbi.ProblemDetails
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+			assert.Equal(t, tt.cause, problem.Cause)
+		})
+	}
+}
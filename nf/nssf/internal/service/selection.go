@@ -0,0 +1,261 @@
+package service
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/your-org/5g-network/nf/nssf/internal/config"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidSNSSAI is returned for an S-NSSAI with a malformed SD
+var ErrInvalidSNSSAI = errors.New("invalid S-NSSAI")
+
+// Access type of the Allowed NSSAI, the NSSF serves 3GPP access only
+const accessType3GPP = "3GPP_ACCESS"
+
+// SNSSAI represents an S-NSSAI (TS 29.571 5.4.4.2)
+type SNSSAI struct {
+	SST uint8  `json:"sst"`
+	SD  string `json:"sd,omitempty"`
+}
+
+// String formats the S-NSSAI for logs
+func (s SNSSAI) String() string {
+	if s.SD == "" {
+		return fmt.Sprintf("%d", s.SST)
+	}
+	return fmt.Sprintf("%d-%s", s.SST, s.SD)
+}
+
+// SubscribedSNSSAI is an S-NSSAI of the UE subscription (TS 29.531 6.1.6.2.5)
+type SubscribedSNSSAI struct {
+	SubscribedSNSSAI  SNSSAI `json:"subscribedSnssai"`
+	DefaultIndication bool   `json:"defaultIndication,omitempty"`
+}
+
+// SliceInfoForRegistration is the slice information the AMF provides for a
+// registration (TS 29.531 6.1.6.2.2)
+type SliceInfoForRegistration struct {
+	SubscribedNSSAI []SubscribedSNSSAI `json:"subscribedNssai,omitempty"`
+	RequestedNSSAI  []SNSSAI           `json:"requestedNssai,omitempty"`
+}
+
+// PLMNID represents PLMN identifier
+type PLMNID struct {
+	MCC string `json:"mcc"`
+	MNC string `json:"mnc"`
+}
+
+// TAI represents a Tracking Area Identity (TS 29.571 5.4.4.3)
+type TAI struct {
+	PLMNID PLMNID `json:"plmnId"`
+	TAC    string `json:"tac"`
+}
+
+// AllowedSNSSAI is an S-NSSAI of the Allowed NSSAI (TS 29.531 6.1.6.2.7)
+type AllowedSNSSAI struct {
+	AllowedSNSSAI SNSSAI `json:"allowedSnssai"`
+}
+
+// AllowedNSSAI is the Allowed NSSAI of an access type (TS 29.531 6.1.6.2.6)
+type AllowedNSSAI struct {
+	AllowedSNSSAIList []AllowedSNSSAI `json:"allowedSnssaiList"`
+	AccessType        string          `json:"accessType"`
+}
+
+// AuthorizedNetworkSliceInfo is the slice selection result (TS 29.531
+// 6.1.6.2.3)
+type AuthorizedNetworkSliceInfo struct {
+	AllowedNSSAIList    []AllowedNSSAI `json:"allowedNssaiList,omitempty"`
+	RejectedNSSAIInPLMN []SNSSAI       `json:"rejectedNssaiInPlmn,omitempty"`
+	RejectedNSSAIInTA   []SNSSAI       `json:"rejectedNssaiInTa,omitempty"`
+	TargetAMFSet        string         `json:"targetAmfSet,omitempty"`
+}
+
+// SelectionService implements Nnssf_NSSelection for registrations
+type SelectionService struct {
+	plmn   config.PLMNConfig
+	slices []config.SliceConfig
+	logger *zap.Logger
+
+	mu       sync.Mutex
+	selected uint64
+	rejected uint64
+}
+
+// NewSelectionService creates a selection service for the configured slices
+func NewSelectionService(plmn config.PLMNConfig, slices []config.SliceConfig, logger *zap.Logger) *SelectionService {
+	return &SelectionService{
+		plmn:   plmn,
+		slices: slices,
+		logger: logger,
+	}
+}
+
+// SelectForRegistration returns the Allowed NSSAI of a registering UE in tai
+// and the AMF set serving it (TS 23.501 5.15.5.2.1). The requested S-NSSAIs
+// that are subscribed and available in the tracking area are allowed, or
+// the default subscribed S-NSSAIs when none of them is. A nil tai skips the
+// tracking area check.
+func (s *SelectionService) SelectForRegistration(info *SliceInfoForRegistration, tai *TAI) (*AuthorizedNetworkSliceInfo, error) {
+	for _, snssai := range info.RequestedNSSAI {
+		if err := validateSNSSAI(snssai); err != nil {
+			return nil, err
+		}
+	}
+	for _, subscribed := range info.SubscribedNSSAI {
+		if err := validateSNSSAI(subscribed.SubscribedSNSSAI); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &AuthorizedNetworkSliceInfo{}
+	var allowed []SNSSAI
+	var amfSets []config.AMFSetConfig
+
+	// consider adds snssai to the allowed slices, or to the rejected ones
+	// when reject is set
+	consider := func(snssai SNSSAI, reject bool) {
+		slice := s.findSlice(snssai)
+		switch {
+		case slice == nil || !subscribed(info, snssai):
+			if reject {
+				result.RejectedNSSAIInPLMN = append(result.RejectedNSSAIInPLMN, snssai)
+			}
+		case tai != nil && !availableIn(slice, tai.TAC):
+			if reject {
+				result.RejectedNSSAIInTA = append(result.RejectedNSSAIInTA, snssai)
+			}
+		default:
+			// All allowed slices are served by one AMF set, the first
+			// allowed slice picks the candidates
+			common := commonAMFSets(amfSets, slice.AMFSets)
+			if amfSets != nil && len(common) == 0 {
+				if reject {
+					result.RejectedNSSAIInTA = append(result.RejectedNSSAIInTA, snssai)
+				}
+				return
+			}
+			if amfSets == nil {
+				common = slice.AMFSets
+			}
+			amfSets = common
+			allowed = append(allowed, snssai)
+		}
+	}
+
+	for _, snssai := range info.RequestedNSSAI {
+		consider(snssai, true)
+	}
+	if len(allowed) == 0 {
+		for _, subscribed := range info.SubscribedNSSAI {
+			if subscribed.DefaultIndication {
+				consider(subscribed.SubscribedSNSSAI, false)
+			}
+		}
+	}
+
+	s.mu.Lock()
+	if len(allowed) > 0 {
+		s.selected++
+	} else {
+		s.rejected++
+	}
+	s.mu.Unlock()
+
+	if len(allowed) == 0 {
+		s.logger.Info("No slice allowed",
+			zap.Int("requested", len(info.RequestedNSSAI)),
+			zap.Int("subscribed", len(info.SubscribedNSSAI)))
+		return result, nil
+	}
+
+	allowedList := make([]AllowedSNSSAI, 0, len(allowed))
+	for _, snssai := range allowed {
+		allowedList = append(allowedList, AllowedSNSSAI{AllowedSNSSAI: snssai})
+	}
+	result.AllowedNSSAIList = []AllowedNSSAI{{AllowedSNSSAIList: allowedList, AccessType: accessType3GPP}}
+	result.TargetAMFSet = s.amfSetID(amfSets[0])
+
+	s.logger.Info("Slices selected",
+		zap.Int("allowed", len(allowed)),
+		zap.Int("rejected_in_plmn", len(result.RejectedNSSAIInPLMN)),
+		zap.Int("rejected_in_ta", len(result.RejectedNSSAIInTA)),
+		zap.String("target_amf_set", result.TargetAMFSet))
+	return result, nil
+}
+
+// findSlice returns the configured slice of an S-NSSAI
+func (s *SelectionService) findSlice(snssai SNSSAI) *config.SliceConfig {
+	for i := range s.slices {
+		slice := &s.slices[i]
+		if slice.SST == snssai.SST && strings.EqualFold(slice.SD, snssai.SD) {
+			return slice
+		}
+	}
+	return nil
+}
+
+// amfSetID formats an AMF set as <MCC><MNC>-<region>-<set> (TS 29.531
+// 6.1.6.3.2)
+func (s *SelectionService) amfSetID(set config.AMFSetConfig) string {
+	return fmt.Sprintf("%s%s-%02x-%03x", s.plmn.MCC, s.plmn.MNC, set.RegionID, set.SetID)
+}
+
+// GetStats returns service statistics
+func (s *SelectionService) GetStats() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return map[string]interface{}{
+		"configured_slices": len(s.slices),
+		"selections":        s.selected,
+		"rejections":        s.rejected,
+	}
+}
+
+func validateSNSSAI(snssai SNSSAI) error {
+	if snssai.SD == "" {
+		return nil
+	}
+	if sd, err := hex.DecodeString(snssai.SD); err != nil || len(sd) != 3 {
+		return fmt.Errorf("%w: sd %q is not 6 hex digits", ErrInvalidSNSSAI, snssai.SD)
+	}
+	return nil
+}
+
+// subscribed reports whether the UE subscribes to snssai. Without
+// subscription information every slice is treated as subscribed.
+func subscribed(info *SliceInfoForRegistration, snssai SNSSAI) bool {
+	if len(info.SubscribedNSSAI) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(info.SubscribedNSSAI, func(s SubscribedSNSSAI) bool {
+		return s.SubscribedSNSSAI.SST == snssai.SST && strings.EqualFold(s.SubscribedSNSSAI.SD, snssai.SD)
+	})
+}
+
+// availableIn reports whether a slice is available in a tracking area
+func availableIn(slice *config.SliceConfig, tac string) bool {
+	if len(slice.TACs) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(slice.TACs, func(t string) bool { return strings.EqualFold(t, tac) })
+}
+
+// commonAMFSets returns the sets of candidates that also serve a slice,
+// keeping the order of candidates
+func commonAMFSets(candidates, sets []config.AMFSetConfig) []config.AMFSetConfig {
+	var common []config.AMFSetConfig
+	for _, candidate := range candidates {
+		if slices.Contains(sets, candidate) {
+			common = append(common, candidate)
+		}
+	}
+	return common
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/nf/nssf/internal/config"
+)
+
+var (
+	embb  = SNSSAI{SST: 1, SD: "000001"}
+	urllc = SNSSAI{SST: 2, SD: "000002"}
+	miot  = SNSSAI{SST: 3}
+	v2x   = SNSSAI{SST: 4, SD: "000004"}
+)
+
+func newTestService(t *testing.T) *SelectionService {
+	logger, _ := zap.NewDevelopment()
+	return NewSelectionService(config.PLMNConfig{MCC: "001", MNC: "01"}, []config.SliceConfig{
+		{SST: 1, SD: "000001", AMFSets: []config.AMFSetConfig{{RegionID: 1, SetID: 1}, {RegionID: 1, SetID: 2}}},
+		{SST: 2, SD: "000002", TACs: []string{"000001"}, AMFSets: []config.AMFSetConfig{{RegionID: 1, SetID: 2}}},
+		{SST: 3, AMFSets: []config.AMFSetConfig{{RegionID: 2, SetID: 0x3ff}}},
+	}, logger)
+}
+
+func subscription(defaults []SNSSAI, others ...SNSSAI) []SubscribedSNSSAI {
+	var subscribed []SubscribedSNSSAI
+	for _, s := range defaults {
+		subscribed = append(subscribed, SubscribedSNSSAI{SubscribedSNSSAI: s, DefaultIndication: true})
+	}
+	for _, s := range others {
+		subscribed = append(subscribed, SubscribedSNSSAI{SubscribedSNSSAI: s})
+	}
+	return subscribed
+}
+
+func allowedOf(t *testing.T, result *AuthorizedNetworkSliceInfo) []SNSSAI {
+	t.Helper()
+	if len(result.AllowedNSSAIList) == 0 {
+		return nil
+	}
+	require.Len(t, result.AllowedNSSAIList, 1)
+	assert.Equal(t, "3GPP_ACCESS", result.AllowedNSSAIList[0].AccessType)
+
+	var allowed []SNSSAI
+	for _, a := range result.AllowedNSSAIList[0].AllowedSNSSAIList {
+		allowed = append(allowed, a.AllowedSNSSAI)
+	}
+	return allowed
+}
+
+func TestSelectForRegistration(t *testing.T) {
+	tai := &TAI{PLMNID: PLMNID{MCC: "001", MNC: "01"}, TAC: "000001"}
+
+	tests := []struct {
+		name          string
+		info          *SliceInfoForRegistration
+		tai           *TAI
+		wantAllowed   []SNSSAI
+		wantRejPLMN   []SNSSAI
+		wantRejTA     []SNSSAI
+		wantTargetSet string
+	}{
+		{
+			name: "requested and subscribed slices sharing an AMF set",
+			info: &SliceInfoForRegistration{
+				SubscribedNSSAI: subscription([]SNSSAI{embb}, urllc),
+				RequestedNSSAI:  []SNSSAI{embb, urllc},
+			},
+			tai:           tai,
+			wantAllowed:   []SNSSAI{embb, urllc},
+			wantTargetSet: "00101-01-002",
+		},
+		{
+			name: "unsupported and unsubscribed slices rejected in the PLMN",
+			info: &SliceInfoForRegistration{
+				SubscribedNSSAI: subscription([]SNSSAI{embb}, v2x),
+				RequestedNSSAI:  []SNSSAI{embb, v2x, miot},
+			},
+			tai:           tai,
+			wantAllowed:   []SNSSAI{embb},
+			wantRejPLMN:   []SNSSAI{v2x, miot},
+			wantTargetSet: "00101-01-001",
+		},
+		{
+			name: "slice unavailable in the tracking area",
+			info: &SliceInfoForRegistration{
+				SubscribedNSSAI: subscription([]SNSSAI{embb}, urllc),
+				RequestedNSSAI:  []SNSSAI{urllc, embb},
+			},
+			tai:           &TAI{PLMNID: tai.PLMNID, TAC: "000002"},
+			wantAllowed:   []SNSSAI{embb},
+			wantRejTA:     []SNSSAI{urllc},
+			wantTargetSet: "00101-01-001",
+		},
+		{
+			name: "slice without a common AMF set",
+			info: &SliceInfoForRegistration{
+				SubscribedNSSAI: subscription([]SNSSAI{embb, miot}),
+				RequestedNSSAI:  []SNSSAI{embb, miot},
+			},
+			tai:           tai,
+			wantAllowed:   []SNSSAI{embb},
+			wantRejTA:     []SNSSAI{miot},
+			wantTargetSet: "00101-01-001",
+		},
+		{
+			name: "default subscribed slices without requested NSSAI",
+			info: &SliceInfoForRegistration{
+				SubscribedNSSAI: subscription([]SNSSAI{miot}, embb),
+			},
+			tai:           tai,
+			wantAllowed:   []SNSSAI{miot},
+			wantTargetSet: "00101-02-3ff",
+		},
+		{
+			name: "default subscribed slices when no requested slice is allowed",
+			info: &SliceInfoForRegistration{
+				SubscribedNSSAI: subscription([]SNSSAI{embb}),
+				RequestedNSSAI:  []SNSSAI{v2x},
+			},
+			tai:           tai,
+			wantAllowed:   []SNSSAI{embb},
+			wantRejPLMN:   []SNSSAI{v2x},
+			wantTargetSet: "00101-01-001",
+		},
+		{
+			name: "no tracking area skips the area check",
+			info: &SliceInfoForRegistration{
+				RequestedNSSAI: []SNSSAI{urllc},
+			},
+			wantAllowed:   []SNSSAI{urllc},
+			wantTargetSet: "00101-01-002",
+		},
+		{
+			name: "nothing allowed",
+			info: &SliceInfoForRegistration{
+				SubscribedNSSAI: subscription(nil, v2x),
+				RequestedNSSAI:  []SNSSAI{v2x},
+			},
+			tai:         tai,
+			wantRejPLMN: []SNSSAI{v2x},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newTestService(t)
+
+			result, err := svc.SelectForRegistration(tt.info, tt.tai)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantAllowed, allowedOf(t, result))
+			assert.Equal(t, tt.wantRejPLMN, result.RejectedNSSAIInPLMN)
+			assert.Equal(t, tt.wantRejTA, result.RejectedNSSAIInTA)
+			assert.Equal(t, tt.wantTargetSet, result.TargetAMFSet)
+		})
+	}
+}
+
+func TestSelectForRegistrationInvalidSNSSAI(t *testing.T) {
+	svc := newTestService(t)
+
+	_, err := svc.SelectForRegistration(&SliceInfoForRegistration{
+		RequestedNSSAI: []SNSSAI{{SST: 1, SD: "xyz"}},
+	}, nil)
+	assert.ErrorIs(t, err, ErrInvalidSNSSAI)
+
+	_, err = svc.SelectForRegistration(&SliceInfoForRegistration{
+		SubscribedNSSAI: subscription([]SNSSAI{{SST: 1, SD: "0001"}}),
+	}, nil)
+	assert.ErrorIs(t, err, ErrInvalidSNSSAI)
+}
+
+func TestSelectionStats(t *testing.T) {
+	svc := newTestService(t)
+
+	_, err := svc.SelectForRegistration(&SliceInfoForRegistration{RequestedNSSAI: []SNSSAI{embb}}, nil)
+	require.NoError(t, err)
+	_, err = svc.SelectForRegistration(&SliceInfoForRegistration{RequestedNSSAI: []SNSSAI{v2x}}, nil)
+	require.NoError(t, err)
+
+	stats := svc.GetStats()
+	assert.Equal(t, 3, stats["configured_slices"])
+	assert.Equal(t, uint64(1), stats["selections"])
+	assert.Equal(t, uint64(1), stats["rejections"])
+}
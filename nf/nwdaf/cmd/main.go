@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/your-org/5g-network/common/buildinfo"
+	"github.com/your-org/5g-network/common/correlation"
+	"github.com/your-org/5g-network/common/metrics"
+	"github.com/your-org/5g-network/common/nrfpool"
+	"github.com/your-org/5g-network/common/reload"
+	"github.com/your-org/5g-network/common/retry"
+	"github.com/your-org/5g-network/nf/nwdaf/internal/client"
+	"github.com/your-org/5g-network/nf/nwdaf/internal/config"
+	"github.com/your-org/5g-network/nf/nwdaf/internal/server"
+	"github.com/your-org/5g-network/nf/nwdaf/internal/service"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	Version   = "dev"
+	BuildTime = "unknown"
+	GitCommit = "unknown"
+)
+
+func main() {
+	// Parse command line flags
+	configPath := flag.String("config", "nf/nwdaf/config/nwdaf.yaml", "path to configuration file")
+	flag.Parse()
+
+	// Create logger, at the configured level once loaded
+	level := zap.NewAtomicLevel()
+	logger := createLogger(level)
+	defer logger.Sync()
+
+	logger.Info("Starting NWDAF (Network Data Analytics Function)",
+		zap.String("version", Version),
+		zap.String("build_time", BuildTime),
+	)
+
+	// Load configuration
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		logger.Fatal("Failed to load configuration", zap.Error(err))
+	}
+
+	logger.Info("Configuration loaded",
+		zap.String("sbi_bind", cfg.SBI.BindAddress),
+		zap.Int("sbi_port", cfg.SBI.Port),
+		zap.String("nrf_url", cfg.NRF.URL),
+		zap.Duration("collection_interval", cfg.Analytics.CollectionInterval),
+	)
+
+	// Reload the log level and NRF heartbeat interval on SIGHUP
+	reloader := reload.New(*configPath, cfg, config.Load, []string{
+		"observability.logging.level",
+		"nrf.heartbeat_interval",
+	}, logger)
+	if err := reloader.SetLogLevel(level, func(c *config.Config) string { return c.Observability.Logging.Level }); err != nil {
+		logger.Fatal("Invalid configuration", zap.Error(err))
+	}
+
+	// HTTP transport of the SBI clients, with mutual TLS when configured,
+	// retries of transient failures and the correlation ID of the request served
+	tlsTransport, err := cfg.SBI.ClientTLS.Transport()
+	if err != nil {
+		logger.Fatal("Invalid client TLS configuration", zap.Error(err))
+	}
+	sbiTransport := correlation.NewTransport(retry.NewTransport(tlsTransport, cfg.SBI.ClientRetry, logger), logger)
+
+	// Create context
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The NFs analyzed are those registered with the NRF
+	var nrfClient *client.NRFClient
+	if cfg.NRF.Enabled {
+		endpoints, err := nrfpool.New(ctx, cfg.NRF.URL, cfg.NRF.Options, logger)
+		if err != nil {
+			logger.Fatal("Failed to resolve NRF endpoints", zap.Error(err))
+		}
+		nrfClient = client.NewNRFClient(endpoints, sbiTransport, logger)
+	}
+
+	// Create analytics service, measuring the CPU usage of the NFs on their
+	// metrics endpoints when configured
+	var scraper service.MetricsScraper
+	if cfg.Analytics.ScrapeMetrics {
+		scraper = client.NewMetricsClient(tlsTransport, logger)
+	}
+	analyticsService := service.NewAnalyticsService(nrfClient, scraper, cfg.Analytics.HistorySize, logger)
+	analyticsService.SetNotificationTransport(sbiTransport)
+	logger.Info("Analytics service initialized")
+
+	// Create HTTP server
+	srv := server.NewServer(cfg, analyticsService, logger)
+	srv.SetBuildInfo(buildinfo.New("NWDAF", Version, GitCommit, BuildTime,
+		"nnwdaf-analyticsinfo", "nnwdaf-eventssubscription"))
+
+	// Initialize metrics server, on the port assigned to the NWDAF
+	metricsRegistry, err := metrics.NewRegistry("NWDAF", cfg.NF.InstanceID)
+	if err != nil {
+		logger.Fatal("Failed to create metrics registry", zap.Error(err))
+	}
+	metricsServer := metrics.NewMetricsServer(metricsRegistry, logger)
+	go func() {
+		if err := metricsServer.Start(); err != nil {
+			logger.Error("Metrics server error", zap.Error(err))
+		}
+	}()
+	defer metricsServer.Stop()
+
+	// Set service up
+	metrics.SetServiceUp(true)
+	defer metrics.SetServiceUp(false)
+
+	// Register with NRF if enabled
+	if nrfClient != nil {
+		profile := &client.NFProfile{
+			NFInstanceID: cfg.NF.InstanceID,
+			NFType:       "NWDAF",
+			NFStatus:     "REGISTERED",
+			PLMNID: client.PLMNID{
+				MCC: cfg.PLMN.MCC,
+				MNC: cfg.PLMN.MNC,
+			},
+			IPv4Addresses: []string{fmt.Sprintf("%s:%d", cfg.SBI.BindAddress, cfg.SBI.Port)},
+			Capacity:      100,
+			Priority:      1,
+		}
+
+		if err := nrfClient.Register(ctx, profile); err != nil {
+			logger.Error("Failed to register with NRF", zap.Error(err))
+		} else {
+			logger.Info("Registered with NRF")
+
+			// Start heartbeat goroutine
+			go func() {
+				ticker := reloader.Ticker(func(c *config.Config) time.Duration { return c.NRF.HeartbeatInterval })
+				defer ticker.Stop()
+
+				for {
+					select {
+					case <-ticker.C:
+						if err := nrfClient.Heartbeat(ctx, cfg.NF.InstanceID); err != nil {
+							logger.Error("Heartbeat failed", zap.Error(err))
+						}
+					case <-ctx.Done():
+						return
+					}
+				}
+			}()
+
+			// Deregister on shutdown
+			defer func() {
+				deregCtx, deregCancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer deregCancel()
+
+				if err := nrfClient.Deregister(deregCtx, cfg.NF.InstanceID); err != nil {
+					logger.Error("Failed to deregister from NRF", zap.Error(err))
+				} else {
+					logger.Info("Deregistered from NRF")
+				}
+			}()
+		}
+	}
+
+	// Collect the load of the registered NFs
+	if nrfClient != nil {
+		go analyticsService.Run(ctx, cfg.Analytics.CollectionInterval)
+	} else {
+		logger.Warn("NRF disabled, no NF load analytics are collected")
+	}
+
+	go reloader.Watch(ctx)
+
+	// Start HTTP server in a goroutine
+	serverErrors := make(chan error, 1)
+	go func() {
+		logger.Info("NWDAF started successfully",
+			zap.String("address", fmt.Sprintf("%s:%d", cfg.SBI.BindAddress, cfg.SBI.Port)),
+			zap.String("scheme", cfg.SBI.Scheme),
+		)
+		serverErrors <- srv.Start()
+	}()
+
+	// Wait for interrupt signal or server error
+	shutdown := make(chan os.Signal, 1)
+	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
+
+	select {
+	case err := <-serverErrors:
+		logger.Fatal("Server error", zap.Error(err))
+	case sig := <-shutdown:
+		logger.Info("Shutdown signal received", zap.String("signal", sig.String()))
+
+		// Create shutdown context with timeout
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer shutdownCancel()
+
+		// Gracefully shutdown the server
+		if err := srv.Stop(shutdownCtx); err != nil {
+			logger.Error("Failed to gracefully shutdown server", zap.Error(err))
+		}
+
+		logger.Info("NWDAF shutdown complete")
+	}
+}
+
+// createLogger creates a structured logger whose level can change at runtime
+func createLogger(level zap.AtomicLevel) *zap.Logger {
+	config := zap.NewProductionConfig()
+	config.Level = level
+	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+
+	logger, err := config.Build()
+	if err != nil {
+		panic(fmt.Sprintf("failed to create logger: %v", err))
+	}
+
+	return logger
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Process metrics the NWDAF reads from the metrics endpoint of every NF
+const (
+	metricCPUSeconds          = "process_cpu_seconds_total"
+	metricResidentMemoryBytes = "process_resident_memory_bytes"
+)
+
+// ProcessMetrics are the resource usage counters of an NF process
+type ProcessMetrics struct {
+	CPUSeconds          float64 // CPU time consumed since the process started
+	ResidentMemoryBytes float64
+}
+
+// MetricsClient scrapes the Prometheus metrics endpoints of the NFs
+type MetricsClient struct {
+	client *http.Client
+	logger *zap.Logger
+}
+
+// NewMetricsClient creates a new metrics client
+func NewMetricsClient(transport http.RoundTripper, logger *zap.Logger) *MetricsClient {
+	return &MetricsClient{
+		client: &http.Client{
+			Timeout:   5 * time.Second,
+			Transport: transport,
+		},
+		logger: logger,
+	}
+}
+
+// Scrape reads the process metrics exposed at url
+func (c *MetricsClient) Scrape(ctx context.Context, url string) (*ProcessMetrics, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metrics endpoint returned status %d", resp.StatusCode)
+	}
+
+	return parseProcessMetrics(resp.Body)
+}
+
+// parseProcessMetrics reads the process metrics from a Prometheus text
+// exposition, in which they carry no labels other than the constant labels
+// of the NF
+func parseProcessMetrics(r io.Reader) (*ProcessMetrics, error) {
+	metrics := &ProcessMetrics{}
+	found := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, value, ok := parseSample(line)
+		if !ok {
+			continue
+		}
+		switch name {
+		case metricCPUSeconds:
+			metrics.CPUSeconds = value
+			found = true
+		case metricResidentMemoryBytes:
+			metrics.ResidentMemoryBytes = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read metrics: %w", err)
+	}
+
+	if !found {
+		return nil, fmt.Errorf("%s not exposed", metricCPUSeconds)
+	}
+	return metrics, nil
+}
+
+// parseSample splits a sample line, name{labels} value [timestamp], into its
+// metric name and value
+func parseSample(line string) (string, float64, bool) {
+	name, rest := line, ""
+	if i := strings.IndexAny(line, "{ "); i >= 0 {
+		name, rest = line[:i], line[i:]
+	}
+	if strings.HasPrefix(rest, "{") {
+		end := strings.LastIndex(rest, "}")
+		if end < 0 {
+			return "", 0, false
+		}
+		rest = rest[end+1:]
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return "", 0, false
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return name, value, true
+}
@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestScrape(t *testing.T) {
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`# HELP process_cpu_seconds_total Total user and system CPU time spent in seconds.
+# TYPE process_cpu_seconds_total counter
+process_cpu_seconds_total{nf_instance_id="00000000-0000-0000-0000-000000000001",nf_type="AMF"} 12.5
+# TYPE process_resident_memory_bytes gauge
+process_resident_memory_bytes{nf_instance_id="00000000-0000-0000-0000-000000000001",nf_type="AMF"} 2.4576e+07
+process_virtual_memory_bytes 1.2e+09
+`))
+	}))
+	defer endpoint.Close()
+
+	c := NewMetricsClient(nil, zap.NewNop())
+	metrics, err := c.Scrape(context.Background(), endpoint.URL)
+	require.NoError(t, err)
+	assert.Equal(t, &ProcessMetrics{CPUSeconds: 12.5, ResidentMemoryBytes: 24576000}, metrics)
+}
+
+func TestScrapeWithoutProcessMetrics(t *testing.T) {
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("go_goroutines 12\n"))
+	}))
+	defer endpoint.Close()
+
+	c := NewMetricsClient(nil, zap.NewNop())
+	_, err := c.Scrape(context.Background(), endpoint.URL)
+	assert.Error(t, err)
+}
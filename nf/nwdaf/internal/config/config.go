@@ -0,0 +1,186 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/your-org/5g-network/common/nrfpool"
+	"github.com/your-org/5g-network/common/oauth"
+	"github.com/your-org/5g-network/common/retry"
+	"github.com/your-org/5g-network/common/tlsconfig"
+	"gopkg.in/yaml.v3"
+)
+
+// Config represents the NWDAF configuration
+type Config struct {
+	NF            NFConfig            `yaml:"nf"`
+	SBI           SBIConfig           `yaml:"sbi"`
+	NRF           NRFConfig           `yaml:"nrf"`
+	PLMN          PLMNConfig          `yaml:"plmn"`
+	Analytics     AnalyticsConfig     `yaml:"analytics"`
+	Observability ObservabilityConfig `yaml:"observability"`
+}
+
+// NFConfig contains NF instance configuration
+type NFConfig struct {
+	Name        string `yaml:"name"`
+	InstanceID  string `yaml:"instance_id"`
+	Description string `yaml:"description"`
+}
+
+// SBIConfig contains Service-Based Interface configuration
+type SBIConfig struct {
+	Scheme      string                 `yaml:"scheme"`
+	BindAddress string                 `yaml:"bind_address"`
+	Port        int                    `yaml:"port"`
+	TLS         TLSConfig              `yaml:"tls"`
+	ClientTLS   tlsconfig.ClientConfig `yaml:"client_tls"`   // TLS towards the SBIs of other NFs
+	ClientRetry retry.Policy           `yaml:"client_retry"` // Retries of idempotent requests to other NFs
+	OAuth2      oauth.Config           `yaml:"oauth2"`
+}
+
+// TLSConfig contains TLS configuration
+type TLSConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+
+	tlsconfig.Options `yaml:",inline"`
+}
+
+// NRFConfig contains NRF client configuration
+type NRFConfig struct {
+	URL               string        `yaml:"url"`
+	Enabled           bool          `yaml:"enabled"`
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"`
+
+	// Secondary NRFs and DNS SRV discovery for failover
+	nrfpool.Options `yaml:",inline"`
+}
+
+// PLMNConfig contains PLMN configuration
+type PLMNConfig struct {
+	MCC string `yaml:"mcc"` // Mobile Country Code
+	MNC string `yaml:"mnc"` // Mobile Network Code
+}
+
+// AnalyticsConfig controls the collection of the NF load analytics
+type AnalyticsConfig struct {
+	// CollectionInterval is how often the load of the registered NFs is
+	// sampled, 30s when zero
+	CollectionInterval time.Duration `yaml:"collection_interval"`
+
+	// HistorySize is the number of samples kept per NF instance for
+	// statistics and predictions, 20 when zero
+	HistorySize int `yaml:"history_size"`
+
+	// ScrapeMetrics measures the CPU usage of the NFs on their metrics
+	// endpoints in addition to the load they report to the NRF
+	ScrapeMetrics bool `yaml:"scrape_metrics"`
+}
+
+// Validate validates the analytics configuration
+func (a *AnalyticsConfig) Validate() error {
+	if a.CollectionInterval < 0 {
+		return fmt.Errorf("collection_interval must not be negative")
+	}
+	if a.HistorySize < 0 {
+		return fmt.Errorf("history_size must not be negative")
+	}
+	return nil
+}
+
+// ObservabilityConfig contains observability settings
+type ObservabilityConfig struct {
+	Metrics MetricsConfig `yaml:"metrics"`
+	Logging LoggingConfig `yaml:"logging"`
+}
+
+// MetricsConfig contains metrics configuration
+type MetricsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Port    int  `yaml:"port"`
+}
+
+// LoggingConfig contains logging configuration
+type LoggingConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+// Load loads configuration from a YAML file
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	// Validate configuration
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return &config, nil
+}
+
+// Validate validates the configuration
+func (c *Config) Validate() error {
+	if c.NF.Name == "" {
+		return fmt.Errorf("nf.name is required")
+	}
+
+	if c.NF.InstanceID == "" {
+		return fmt.Errorf("nf.instance_id is required")
+	}
+
+	if c.SBI.Port <= 0 || c.SBI.Port > 65535 {
+		return fmt.Errorf("invalid sbi.port: %d", c.SBI.Port)
+	}
+
+	if c.NRF.Enabled && c.NRF.URL == "" && c.NRF.SRVName == "" {
+		return fmt.Errorf("nrf.url or nrf.srv_name is required when nrf.enabled is true")
+	}
+
+	if err := c.NRF.Validate(); err != nil {
+		return fmt.Errorf("invalid nrf: %w", err)
+	}
+
+	if c.PLMN.MCC == "" || c.PLMN.MNC == "" {
+		return fmt.Errorf("plmn.mcc and plmn.mnc are required")
+	}
+
+	if err := c.Analytics.Validate(); err != nil {
+		return fmt.Errorf("invalid analytics: %w", err)
+	}
+
+	if c.SBI.TLS.Enabled {
+		if err := c.SBI.TLS.Validate(); err != nil {
+			return fmt.Errorf("invalid sbi.tls: %w", err)
+		}
+	}
+
+	if err := c.SBI.ClientTLS.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.client_tls: %w", err)
+	}
+
+	if err := c.SBI.ClientRetry.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.client_retry: %w", err)
+	}
+
+	if err := c.SBI.OAuth2.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.oauth2: %w", err)
+	}
+
+	return nil
+}
+
+// GetSBIURL returns the full SBI URL
+func (c *Config) GetSBIURL() string {
+	return fmt.Sprintf("%s://%s:%d", c.SBI.Scheme, c.SBI.BindAddress, c.SBI.Port)
+}
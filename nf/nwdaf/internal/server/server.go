@@ -0,0 +1,291 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/your-org/5g-network/common/buildinfo"
+	"github.com/your-org/5g-network/common/correlation"
+	"github.com/your-org/5g-network/common/oauth"
+	"github.com/your-org/5g-network/common/sbi"
+	"github.com/your-org/5g-network/nf/nwdaf/internal/config"
+	"github.com/your-org/5g-network/nf/nwdaf/internal/service"
+	"go.uber.org/zap"
+)
+
+// NWDAFServer represents the NWDAF HTTP server
+type NWDAFServer struct {
+	config *config.Config
+	router *chi.Mux
+	server *http.Server
+	logger *zap.Logger
+
+	// Services
+	analyticsService *service.AnalyticsService
+}
+
+// NewServer creates a new NWDAF server
+func NewServer(
+	cfg *config.Config,
+	analyticsService *service.AnalyticsService,
+	logger *zap.Logger,
+) *NWDAFServer {
+	s := &NWDAFServer{
+		config:           cfg,
+		router:           chi.NewRouter(),
+		logger:           logger,
+		analyticsService: analyticsService,
+	}
+
+	s.setupMiddleware()
+	s.setupRoutes()
+
+	return s
+}
+
+// setupMiddleware configures HTTP middleware
+func (s *NWDAFServer) setupMiddleware() {
+	s.router.Use(middleware.RequestID)
+	s.router.Use(correlation.Middleware)
+	s.router.Use(middleware.RealIP)
+	s.router.Use(s.loggingMiddleware)
+	s.router.Use(middleware.Recoverer)
+	s.router.Use(middleware.Timeout(60 * time.Second))
+	s.router.Use(oauth.Middleware(s.config.SBI.OAuth2, s.logger, "NWDAF", s.config.NF.InstanceID))
+}
+
+// setupRoutes configures HTTP routes
+func (s *NWDAFServer) setupRoutes() {
+	// Health and status
+	s.router.Get("/health", s.handleHealth)
+	s.router.Get("/ready", s.handleReady)
+	s.router.Get("/status", s.handleStatus)
+
+	// Nnwdaf_AnalyticsInfo service (TS 29.520)
+	s.router.Route("/nnwdaf-analyticsinfo/v1", func(r chi.Router) {
+		r.Use(oauth.RequireScope("nnwdaf-analyticsinfo"))
+		r.Get("/analytics", s.handleGetAnalytics)
+	})
+
+	// Nnwdaf_EventsSubscription service (TS 29.520)
+	s.router.Route("/nnwdaf-eventssubscription/v1", func(r chi.Router) {
+		r.Use(oauth.RequireScope("nnwdaf-eventssubscription"))
+		r.Post("/subscriptions", s.handleCreateSubscription)
+		r.Delete("/subscriptions/{subscriptionId}", s.handleDeleteSubscription)
+	})
+
+	// Admin endpoints
+	s.router.Route("/admin", func(r chi.Router) {
+		r.Get("/stats", s.handleGetStats)
+	})
+}
+
+// SetBuildInfo exposes the NF build info on GET /version
+func (s *NWDAFServer) SetBuildInfo(info *buildinfo.Info) {
+	s.router.Get("/version", info.Handler())
+}
+
+// Start starts the HTTP server
+func (s *NWDAFServer) Start() error {
+	addr := fmt.Sprintf("%s:%d", s.config.SBI.BindAddress, s.config.SBI.Port)
+
+	s.server = &http.Server{
+		Addr:         addr,
+		Handler:      s.router,
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		IdleTimeout:  120 * time.Second,
+	}
+
+	s.logger.Info("Starting NWDAF HTTP server", zap.String("address", addr))
+
+	if s.config.SBI.TLS.Enabled {
+		tlsConfig, err := s.config.SBI.TLS.Build()
+		if err != nil {
+			return fmt.Errorf("invalid TLS configuration: %w", err)
+		}
+		s.server.TLSConfig = tlsConfig
+		return s.server.ListenAndServeTLS(s.config.SBI.TLS.CertFile, s.config.SBI.TLS.KeyFile)
+	}
+
+	return s.server.ListenAndServe()
+}
+
+// Stop gracefully stops the HTTP server
+func (s *NWDAFServer) Stop(ctx context.Context) error {
+	s.logger.Info("Stopping NWDAF HTTP server")
+
+	if s.server != nil {
+		return s.server.Shutdown(ctx)
+	}
+
+	return nil
+}
+
+// Middleware
+
+func (s *NWDAFServer) loggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		s.logger.Info("HTTP request",
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+			zap.Int("status", ww.Status()),
+			zap.Duration("duration", time.Since(start)),
+			zap.String("remote_addr", r.RemoteAddr),
+			zap.String("request_id", middleware.GetReqID(r.Context())),
+			correlation.Field(r.Context()),
+		)
+	})
+}
+
+// Helper functions
+
+func (s *NWDAFServer) respondJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		s.logger.Error("Failed to encode JSON response", zap.Error(err))
+	}
+}
+
+// respondProblem writes a ProblemDetails error response
+func (s *NWDAFServer) respondProblem(w http.ResponseWriter, status int, cause, title string, err error) {
+	s.logger.Error(title, zap.String("cause", cause), zap.Error(err))
+
+	if err := sbi.WriteProblem(w, sbi.NewProblem(status, cause, title, err)); err != nil {
+		s.logger.Error("Failed to encode problem details", zap.Error(err))
+	}
+}
+
+// Health check handlers
+
+func (s *NWDAFServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	s.respondJSON(w, http.StatusOK, map[string]string{
+		"status": "healthy",
+	})
+}
+
+func (s *NWDAFServer) handleReady(w http.ResponseWriter, r *http.Request) {
+	s.respondJSON(w, http.StatusOK, map[string]string{
+		"status": "ready",
+	})
+}
+
+func (s *NWDAFServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"service": "NWDAF",
+		"version": "1.0.0",
+		"stats":   s.analyticsService.Stats(),
+	})
+}
+
+func (s *NWDAFServer) handleGetStats(w http.ResponseWriter, r *http.Request) {
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"service":         "NWDAF",
+		"version":         "1.0.0",
+		"analytics_stats": s.analyticsService.Stats(),
+	})
+}
+
+// handleGetAnalytics handles an analytics request. The event filter and the
+// analytics window, ana-req, are JSON encoded; a window starting in the
+// future requests predictions.
+// TS 29.520, Clause 5.2.2.2
+func (s *NWDAFServer) handleGetAnalytics(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	eventID := query.Get("event-id")
+	if eventID == "" {
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseMandatoryIEMissing,
+			"missing query parameter", fmt.Errorf("event-id is required"))
+		return
+	}
+	if eventID != service.EventNFLoad {
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidQueryParam,
+			"unsupported event-id", fmt.Errorf("%w: %q", service.ErrUnsupportedEvent, eventID))
+		return
+	}
+
+	var filter service.EventFilter
+	if raw := query.Get("event-filter"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &filter); err != nil {
+			s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidQueryParam, "invalid event-filter", err)
+			return
+		}
+	}
+
+	var req struct {
+		StartTs time.Time `json:"startTs"`
+		EndTs   time.Time `json:"endTs"`
+	}
+	if raw := query.Get("ana-req"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &req); err != nil {
+			s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidQueryParam, "invalid ana-req", err)
+			return
+		}
+	}
+
+	data := s.analyticsService.NFLoad(filter, req.StartTs, req.EndTs)
+	if len(data.NFLoadLevelInfos) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	s.respondJSON(w, http.StatusOK, data)
+}
+
+// handleCreateSubscription handles an analytics subscription
+// TS 29.520, Clause 5.3.2.2.2
+func (s *NWDAFServer) handleCreateSubscription(w http.ResponseWriter, r *http.Request) {
+	var sub service.EventsSubscription
+	if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
+		return
+	}
+
+	subscriptionID, err := s.analyticsService.Subscribe(&sub)
+	if err != nil {
+		status, cause := http.StatusInternalServerError, sbi.CauseSystemFailure
+		switch {
+		case errors.Is(err, service.ErrInvalidSubscription):
+			status, cause = http.StatusBadRequest, sbi.CauseMandatoryIEMissing
+		case errors.Is(err, service.ErrUnsupportedEvent):
+			status, cause = http.StatusBadRequest, sbi.CauseMandatoryIEIncorrect
+		}
+		s.respondProblem(w, status, cause, "failed to create subscription", err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s/nnwdaf-eventssubscription/v1/subscriptions/%s",
+		s.config.GetSBIURL(), subscriptionID))
+	s.respondJSON(w, http.StatusCreated, sub)
+}
+
+// handleDeleteSubscription handles the deletion of an analytics subscription
+// TS 29.520, Clause 5.3.2.3.2
+func (s *NWDAFServer) handleDeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	subscriptionID := chi.URLParam(r, "subscriptionId")
+
+	if err := s.analyticsService.Unsubscribe(subscriptionID); err != nil {
+		status, cause := http.StatusInternalServerError, sbi.CauseSystemFailure
+		if errors.Is(err, service.ErrSubscriptionNotFound) {
+			status, cause = http.StatusNotFound, sbi.CauseSubscriptionNotFound
+		}
+		s.respondProblem(w, status, cause, "failed to delete subscription", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
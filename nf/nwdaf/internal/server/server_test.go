@@ -0,0 +1,178 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/common/sbi"
+	"github.com/your-org/5g-network/nf/nwdaf/internal/client"
+	"github.com/your-org/5g-network/nf/nwdaf/internal/config"
+	"github.com/your-org/5g-network/nf/nwdaf/internal/service"
+)
+
+const amfID = "00000000-0000-0000-0000-000000000001"
+
+// fakeNRF lists the NFs registered with a fake NRF
+type fakeNRF struct {
+	profiles []client.NFProfile
+}
+
+func (f *fakeNRF) ListNFInstances(ctx context.Context) ([]client.NFProfile, error) {
+	return f.profiles, nil
+}
+
+func newTestServer(t *testing.T) (*NWDAFServer, *service.AnalyticsService) {
+	logger, _ := zap.NewDevelopment()
+	cfg := &config.Config{
+		SBI: config.SBIConfig{Scheme: "http", BindAddress: "127.0.0.1", Port: 8088},
+	}
+	nrf := &fakeNRF{profiles: []client.NFProfile{{NFInstanceID: amfID, NFType: "AMF", Load: 42}}}
+	analytics := service.NewAnalyticsService(nrf, nil, 0, logger)
+	return NewServer(cfg, analytics, logger), analytics
+}
+
+func analyticsRequest(params map[string]string) *http.Request {
+	query := url.Values{}
+	for k, v := range params {
+		query.Set(k, v)
+	}
+	return httptest.NewRequest(http.MethodGet, "/nnwdaf-analyticsinfo/v1/analytics?"+query.Encode(), nil)
+}
+
+func TestGetAnalytics(t *testing.T) {
+	s, analytics := newTestServer(t)
+
+	// No data before the first collection
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, analyticsRequest(map[string]string{"event-id": "NF_LOAD"}))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	require.NoError(t, analytics.Collect(context.Background()))
+
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, analyticsRequest(map[string]string{
+		"event-id":     "NF_LOAD",
+		"event-filter": `{"nfTypes":["AMF"]}`,
+	}))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var data service.AnalyticsData
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &data))
+	assert.Equal(t, []service.NFLoadLevelInformation{
+		{NFType: "AMF", NFInstanceID: amfID, NFLoadLevelAverage: 42, NFLoadLevelPeak: 42},
+	}, data.NFLoadLevelInfos)
+
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, analyticsRequest(map[string]string{
+		"event-id":     "NF_LOAD",
+		"event-filter": `{"nfTypes":["SMF"]}`,
+	}))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+}
+
+func TestGetAnalyticsBadRequest(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	tests := []struct {
+		name   string
+		params map[string]string
+		cause  string
+	}{
+		{"missing event-id", map[string]string{}, sbi.CauseMandatoryIEMissing},
+		{"unsupported event-id", map[string]string{"event-id": "UE_MOBILITY"}, sbi.CauseInvalidQueryParam},
+		{"malformed event-filter", map[string]string{"event-id": "NF_LOAD", "event-filter": "{"}, sbi.CauseInvalidQueryParam},
+		{"malformed ana-req", map[string]string{"event-id": "NF_LOAD", "ana-req": `{"startTs":"now"}`}, sbi.CauseInvalidQueryParam},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.router.ServeHTTP(rec, analyticsRequest(tt.params))
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+
+			var problem sbi.ProblemDetails
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+			assert.Equal(t, tt.cause, problem.Cause)
+		})
+	}
+}
+
+func TestSubscribeToNFLoad(t *testing.T) {
+	s, analytics := newTestServer(t)
+
+	notifications := make(chan service.EventsSubscriptionNotification, 1)
+	consumer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var notification service.EventsSubscriptionNotification
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&notification))
+		notifications <- notification
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer consumer.Close()
+
+	body := `{"eventSubscriptions":[{"event":"NF_LOAD","nfInstanceIds":["` + amfID + `"]}],` +
+		`"notificationURI":"` + consumer.URL + `","notifCorrId":"amf-load"}`
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/nnwdaf-eventssubscription/v1/subscriptions", strings.NewReader(body)))
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	location := rec.Header().Get("Location")
+	prefix := "http://127.0.0.1:8088/nnwdaf-eventssubscription/v1/subscriptions/"
+	require.True(t, strings.HasPrefix(location, prefix), location)
+	subscriptionID := strings.TrimPrefix(location, prefix)
+
+	// The load computed at the collection is notified
+	require.NoError(t, analytics.Collect(context.Background()))
+	notification := <-notifications
+	assert.Equal(t, subscriptionID, notification.SubscriptionID)
+	assert.Equal(t, "amf-load", notification.NotifCorrID)
+	require.Len(t, notification.EventNotifications, 1)
+	assert.Equal(t, []service.NFLoadLevelInformation{
+		{NFType: "AMF", NFInstanceID: amfID, NFLoadLevelAverage: 42, NFLoadLevelPeak: 42},
+	}, notification.EventNotifications[0].NFLoadLevelInfos)
+
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/nnwdaf-eventssubscription/v1/subscriptions/"+subscriptionID, nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/nnwdaf-eventssubscription/v1/subscriptions/"+subscriptionID, nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	var problem sbi.ProblemDetails
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	assert.Equal(t, sbi.CauseSubscriptionNotFound, problem.Cause)
+}
+
+func TestSubscribeBadRequest(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	tests := []struct {
+		name  string
+		body  string
+		cause string
+	}{
+		{"malformed body", "{", sbi.CauseInvalidMsgFormat},
+		{"missing notificationURI", `{"eventSubscriptions":[{"event":"NF_LOAD"}]}`, sbi.CauseMandatoryIEMissing},
+		{"unsupported event", `{"eventSubscriptions":[{"event":"QOS_SUSTAINABILITY"}],"notificationURI":"http://consumer/notify"}`, sbi.CauseMandatoryIEIncorrect},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/nnwdaf-eventssubscription/v1/subscriptions", strings.NewReader(tt.body)))
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+
+			var problem sbi.ProblemDetails
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+			assert.Equal(t, tt.cause, problem.Cause)
+		})
+	}
+}
@@ -0,0 +1,534 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/your-org/5g-network/common/metrics"
+	"github.com/your-org/5g-network/nf/nwdaf/internal/client"
+	"go.uber.org/zap"
+)
+
+// Defaults of the analytics configuration
+const (
+	DefaultCollectionInterval = 30 * time.Second
+	DefaultHistorySize        = 20
+)
+
+// notificationTimeout bounds the delivery of one notification
+const notificationTimeout = 5 * time.Second
+
+var (
+	// ErrUnsupportedEvent is returned for analytics other than NF load
+	ErrUnsupportedEvent = errors.New("unsupported analytics event")
+	// ErrInvalidSubscription is returned for subscriptions missing mandatory
+	// attributes
+	ErrInvalidSubscription = errors.New("invalid analytics subscription")
+	// ErrSubscriptionNotFound is returned for unknown subscriptions
+	ErrSubscriptionNotFound = errors.New("analytics subscription not found")
+)
+
+// ProfileLister lists the NFs registered with the NRF
+type ProfileLister interface {
+	ListNFInstances(ctx context.Context) ([]client.NFProfile, error)
+}
+
+// MetricsScraper reads the process metrics of an NF from its metrics endpoint
+type MetricsScraper interface {
+	Scrape(ctx context.Context, url string) (*client.ProcessMetrics, error)
+}
+
+// sample is the load of an NF instance at one collection
+type sample struct {
+	at     time.Time
+	load   float64
+	cpu    float64
+	hasCPU bool
+}
+
+// instance is the load history of an NF instance, oldest sample first
+type instance struct {
+	nfType  string
+	samples []sample
+}
+
+// scrape is the last process metrics read from an NF instance
+type scrape struct {
+	at      time.Time
+	metrics *client.ProcessMetrics
+}
+
+// subscription is an Nnwdaf_EventsSubscription subscription and when each of
+// its events was last notified
+type subscription struct {
+	EventsSubscription
+	lastNotified []time.Time
+}
+
+// AnalyticsService implements Nnwdaf_AnalyticsInfo and
+// Nnwdaf_EventsSubscription for NF load analytics. The load of every NF
+// registered with the NRF is sampled by Collect: the load it reports in its
+// profile, raised to the CPU usage measured on its metrics endpoint.
+type AnalyticsService struct {
+	profiles    ProfileLister
+	scraper     MetricsScraper // Optional
+	historySize int
+	logger      *zap.Logger
+
+	// now returns the current time, replaced by tests
+	now func() time.Time
+
+	// collectMu serializes collections, which own scrapes
+	collectMu sync.Mutex
+	scrapes   map[string]scrape
+
+	mu            sync.RWMutex
+	instances     map[string]*instance
+	subscriptions map[string]*subscription
+
+	notifyClient *http.Client
+}
+
+// NewAnalyticsService creates a new analytics service keeping historySize
+// samples per NF instance, DefaultHistorySize when zero. scraper may be nil
+// to rely on the load the NFs report to the NRF only.
+func NewAnalyticsService(profiles ProfileLister, scraper MetricsScraper, historySize int, logger *zap.Logger) *AnalyticsService {
+	if historySize <= 0 {
+		historySize = DefaultHistorySize
+	}
+	return &AnalyticsService{
+		profiles:      profiles,
+		scraper:       scraper,
+		historySize:   historySize,
+		logger:        logger,
+		now:           time.Now,
+		scrapes:       make(map[string]scrape),
+		instances:     make(map[string]*instance),
+		subscriptions: make(map[string]*subscription),
+		notifyClient:  &http.Client{Timeout: notificationTimeout},
+	}
+}
+
+// SetNotificationTransport sets the transport notifications are delivered with
+func (s *AnalyticsService) SetNotificationTransport(transport http.RoundTripper) {
+	s.notifyClient = &http.Client{Timeout: notificationTimeout, Transport: transport}
+}
+
+// Run collects the load of the NFs every interval until ctx is done
+func (s *AnalyticsService) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultCollectionInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Collect(ctx); err != nil {
+			s.logger.Error("NF load collection failed", zap.Error(err))
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Collect samples the load of the registered NFs, forgets the NFs that
+// deregistered and notifies the subscribers whose repetition period elapsed
+func (s *AnalyticsService) Collect(ctx context.Context) error {
+	s.collectMu.Lock()
+	defer s.collectMu.Unlock()
+
+	profiles, err := s.profiles.ListNFInstances(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list NF instances: %w", err)
+	}
+
+	now := s.now()
+	samples := make(map[string]sample, len(profiles))
+	for _, profile := range profiles {
+		smp := sample{at: now, load: float64(profile.Load)}
+		smp.cpu, smp.hasCPU = s.cpuUsage(ctx, &profile, now)
+		if smp.hasCPU && smp.cpu > smp.load {
+			smp.load = smp.cpu
+		}
+		samples[profile.NFInstanceID] = smp
+	}
+
+	s.mu.Lock()
+	for _, profile := range profiles {
+		inst, ok := s.instances[profile.NFInstanceID]
+		if !ok {
+			inst = &instance{}
+			s.instances[profile.NFInstanceID] = inst
+		}
+		inst.nfType = profile.NFType
+		inst.samples = append(inst.samples, samples[profile.NFInstanceID])
+		if len(inst.samples) > s.historySize {
+			inst.samples = inst.samples[len(inst.samples)-s.historySize:]
+		}
+	}
+	for id := range s.instances {
+		if _, ok := samples[id]; !ok {
+			delete(s.instances, id)
+			delete(s.scrapes, id)
+		}
+	}
+	s.mu.Unlock()
+
+	s.logger.Debug("Collected NF load", zap.Int("nf_instances", len(profiles)))
+
+	s.notifySubscribers(ctx, now)
+	return nil
+}
+
+// cpuUsage returns the CPU usage of an NF in percent since its previous
+// scrape, false when it cannot be measured
+func (s *AnalyticsService) cpuUsage(ctx context.Context, profile *client.NFProfile, now time.Time) (float64, bool) {
+	if s.scraper == nil {
+		return 0, false
+	}
+	url, ok := metricsURL(profile)
+	if !ok {
+		return 0, false
+	}
+
+	current, err := s.scraper.Scrape(ctx, url)
+	if err != nil {
+		s.logger.Debug("Failed to scrape NF metrics",
+			zap.String("nf_instance_id", profile.NFInstanceID),
+			zap.String("url", url),
+			zap.Error(err),
+		)
+		return 0, false
+	}
+
+	previous, ok := s.scrapes[profile.NFInstanceID]
+	s.scrapes[profile.NFInstanceID] = scrape{at: now, metrics: current}
+	elapsed := now.Sub(previous.at).Seconds()
+	if !ok || elapsed <= 0 || current.CPUSeconds < previous.metrics.CPUSeconds {
+		// First scrape or NF restart
+		return 0, false
+	}
+
+	return clampPercent((current.CPUSeconds - previous.metrics.CPUSeconds) / elapsed * 100), true
+}
+
+// metricsURL returns the metrics endpoint of an NF: the host of its first
+// IPv4 address, on the metrics port assigned to its NF type
+func metricsURL(profile *client.NFProfile) (string, bool) {
+	if len(profile.IPv4Addresses) == 0 {
+		return "", false
+	}
+	host := profile.IPv4Addresses[0]
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+		return "", false
+	}
+
+	port, err := metrics.Port(profile.NFType)
+	if err != nil {
+		return "", false
+	}
+	return fmt.Sprintf("http://%s/metrics", net.JoinHostPort(host, strconv.Itoa(port))), true
+}
+
+// NFLoad returns the load analytics of the NFs matching filter between start
+// and end. Statistics are returned for a window that has started, over its
+// samples; predictions for a future window, extrapolated from the history.
+// A zero start covers the whole history, a zero end the rest of the window.
+func (s *AnalyticsService) NFLoad(filter EventFilter, start, end time.Time) *AnalyticsData {
+	now := s.now()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return &AnalyticsData{
+		Start:            start,
+		Expiry:           end,
+		TimeStampGen:     now,
+		NFLoadLevelInfos: s.nfLoadLocked(filter, start, end, now),
+	}
+}
+
+// nfLoadLocked returns the load analytics of the NFs matching filter, sorted
+// by NF type and instance. s.mu must be held.
+func (s *AnalyticsService) nfLoadLocked(filter EventFilter, start, end, now time.Time) []NFLoadLevelInformation {
+	var infos []NFLoadLevelInformation
+	for id, inst := range s.instances {
+		if !filter.matches(id, inst.nfType) {
+			continue
+		}
+
+		var info *NFLoadLevelInformation
+		if start.After(now) {
+			info = s.predict(inst, start, end)
+		} else {
+			info = statistics(inst, start, end)
+		}
+		if info == nil {
+			continue
+		}
+		info.NFType, info.NFInstanceID = inst.nfType, id
+		infos = append(infos, *info)
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].NFType != infos[j].NFType {
+			return infos[i].NFType < infos[j].NFType
+		}
+		return infos[i].NFInstanceID < infos[j].NFInstanceID
+	})
+	return infos
+}
+
+// matches reports whether an NF instance is selected by the filter
+func (f *EventFilter) matches(nfInstanceID, nfType string) bool {
+	if len(f.NFInstanceIDs) > 0 && !slices.Contains(f.NFInstanceIDs, nfInstanceID) {
+		return false
+	}
+	if len(f.NFTypes) > 0 && !slices.Contains(f.NFTypes, nfType) {
+		return false
+	}
+	return true
+}
+
+// statistics returns the average and peak load of the samples between start
+// and end, nil when there are none
+func statistics(inst *instance, start, end time.Time) *NFLoadLevelInformation {
+	var sum, peak, cpuSum float64
+	var count, cpuCount int
+	for _, smp := range inst.samples {
+		if smp.at.Before(start) || (!end.IsZero() && smp.at.After(end)) {
+			continue
+		}
+		sum += smp.load
+		peak = math.Max(peak, smp.load)
+		count++
+		if smp.hasCPU {
+			cpuSum += smp.cpu
+			cpuCount++
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+
+	info := &NFLoadLevelInformation{
+		NFLoadLevelAverage: int(math.Round(sum / float64(count))),
+		NFLoadLevelPeak:    int(math.Round(peak)),
+	}
+	if cpuCount > 0 {
+		info.NFCPUUsage = int(math.Round(cpuSum / float64(cpuCount)))
+	}
+	return info
+}
+
+// predict extrapolates the load between start and end, a single instant when
+// end is zero, by a least squares line through the history. The confidence
+// grows with the history, nil when there is none.
+func (s *AnalyticsService) predict(inst *instance, start, end time.Time) *NFLoadLevelInformation {
+	if len(inst.samples) == 0 {
+		return nil
+	}
+	if end.Before(start) {
+		end = start
+	}
+
+	// Load as a function of the seconds since the first sample
+	origin := inst.samples[0].at
+	var sumX, sumY, sumXX, sumXY float64
+	for _, smp := range inst.samples {
+		x := smp.at.Sub(origin).Seconds()
+		sumX += x
+		sumY += smp.load
+		sumXX += x * x
+		sumXY += x * smp.load
+	}
+	n := float64(len(inst.samples))
+	slope := 0.0
+	if d := n*sumXX - sumX*sumX; d != 0 {
+		slope = (n*sumXY - sumX*sumY) / d
+	}
+	intercept := (sumY - slope*sumX) / n
+	loadAt := func(t time.Time) float64 {
+		return clampPercent(intercept + slope*t.Sub(origin).Seconds())
+	}
+
+	// The load is linear over the window, its average is at the middle
+	atStart, atEnd := loadAt(start), loadAt(end)
+	return &NFLoadLevelInformation{
+		NFLoadLevelAverage: int(math.Round(loadAt(start.Add(end.Sub(start) / 2)))),
+		NFLoadLevelPeak:    int(math.Round(math.Max(atStart, atEnd))),
+		Confidence:         len(inst.samples) * 100 / s.historySize,
+	}
+}
+
+// clampPercent bounds a percentage to [0, 100]
+func clampPercent(v float64) float64 {
+	return math.Min(math.Max(v, 0), 100)
+}
+
+// Subscribe creates an Nnwdaf_EventsSubscription subscription and returns its ID
+func (s *AnalyticsService) Subscribe(sub *EventsSubscription) (string, error) {
+	if sub.NotificationURI == "" {
+		return "", fmt.Errorf("%w: notificationURI is required", ErrInvalidSubscription)
+	}
+	if len(sub.EventSubscriptions) == 0 {
+		return "", fmt.Errorf("%w: eventSubscriptions is required", ErrInvalidSubscription)
+	}
+	for _, event := range sub.EventSubscriptions {
+		if event.Event != EventNFLoad {
+			return "", fmt.Errorf("%w: %q", ErrUnsupportedEvent, event.Event)
+		}
+		if event.RepetitionPeriod < 0 {
+			return "", fmt.Errorf("%w: negative repetitionPeriod", ErrInvalidSubscription)
+		}
+	}
+
+	subscriptionID := uuid.New().String()
+
+	s.mu.Lock()
+	s.subscriptions[subscriptionID] = &subscription{
+		EventsSubscription: *sub,
+		lastNotified:       make([]time.Time, len(sub.EventSubscriptions)),
+	}
+	s.mu.Unlock()
+
+	s.logger.Info("Analytics subscription created",
+		zap.String("subscription_id", subscriptionID),
+		zap.String("notification_uri", sub.NotificationURI),
+		zap.Int("events", len(sub.EventSubscriptions)),
+	)
+	return subscriptionID, nil
+}
+
+// Unsubscribe deletes a subscription
+func (s *AnalyticsService) Unsubscribe(subscriptionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subscriptions[subscriptionID]; !ok {
+		return fmt.Errorf("%w: %s", ErrSubscriptionNotFound, subscriptionID)
+	}
+	delete(s.subscriptions, subscriptionID)
+
+	s.logger.Info("Analytics subscription deleted", zap.String("subscription_id", subscriptionID))
+	return nil
+}
+
+// notifySubscribers notifies every subscription of the load statistics of
+// its events whose repetition period elapsed. Delivery failures are logged.
+func (s *AnalyticsService) notifySubscribers(ctx context.Context, now time.Time) {
+	type delivery struct {
+		uri          string
+		notification EventsSubscriptionNotification
+	}
+	var deliveries []delivery
+
+	s.mu.Lock()
+	for id, sub := range s.subscriptions {
+		notification := EventsSubscriptionNotification{SubscriptionID: id, NotifCorrID: sub.NotifCorrID}
+		for i, event := range sub.EventSubscriptions {
+			period := time.Duration(event.RepetitionPeriod) * time.Second
+			if !sub.lastNotified[i].IsZero() && now.Sub(sub.lastNotified[i]) < period {
+				continue
+			}
+
+			data := s.nfLoadLocked(event.EventFilter, time.Time{}, time.Time{}, now)
+			if len(data) == 0 {
+				continue
+			}
+			sub.lastNotified[i] = now
+			notification.EventNotifications = append(notification.EventNotifications, EventNotification{
+				Event:            event.Event,
+				Start:            s.historyStart(),
+				TimeStampGen:     now,
+				NFLoadLevelInfos: data,
+			})
+		}
+		if len(notification.EventNotifications) > 0 {
+			deliveries = append(deliveries, delivery{uri: sub.NotificationURI, notification: notification})
+		}
+	}
+	s.mu.Unlock()
+
+	for _, d := range deliveries {
+		if err := s.postNotification(ctx, d.uri, &d.notification); err != nil {
+			s.logger.Error("Analytics notification failed",
+				zap.String("subscription_id", d.notification.SubscriptionID),
+				zap.String("notification_uri", d.uri),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		s.logger.Debug("Analytics notification delivered",
+			zap.String("subscription_id", d.notification.SubscriptionID),
+			zap.String("notification_uri", d.uri),
+		)
+	}
+}
+
+// historyStart returns the time of the oldest sample kept. s.mu must be held.
+func (s *AnalyticsService) historyStart() time.Time {
+	var start time.Time
+	for _, inst := range s.instances {
+		if len(inst.samples) > 0 && (start.IsZero() || inst.samples[0].at.Before(start)) {
+			start = inst.samples[0].at
+		}
+	}
+	return start
+}
+
+// postNotification POSTs a notification to a subscriber
+func (s *AnalyticsService) postNotification(ctx context.Context, uri string, notification *EventsSubscriptionNotification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, uri, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid notification URI: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.notifyClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("subscriber returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Stats returns the number of NF instances tracked and of subscriptions
+func (s *AnalyticsService) Stats() map[string]int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return map[string]int{
+		"nf_instances":  len(s.instances),
+		"subscriptions": len(s.subscriptions),
+	}
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/nf/nwdaf/internal/client"
+)
+
+const (
+	amfID = "00000000-0000-0000-0000-000000000001"
+	smfID = "00000000-0000-0000-0000-000000000005"
+)
+
+// fakeNRF lists the NFs registered with a fake NRF
+type fakeNRF struct {
+	profiles []client.NFProfile
+}
+
+func (f *fakeNRF) ListNFInstances(ctx context.Context) ([]client.NFProfile, error) {
+	return f.profiles, nil
+}
+
+// fakeScraper returns the CPU seconds set for each metrics URL
+type fakeScraper struct {
+	cpuSeconds map[string]float64
+}
+
+func (f *fakeScraper) Scrape(ctx context.Context, url string) (*client.ProcessMetrics, error) {
+	seconds, ok := f.cpuSeconds[url]
+	if !ok {
+		return nil, errors.New("connection refused")
+	}
+	return &client.ProcessMetrics{CPUSeconds: seconds}, nil
+}
+
+// loadFixture collects the load of an AMF reporting its load to the NRF and
+// of an SMF whose CPU usage is scraped, every 10s from t0
+type loadFixture struct {
+	service *AnalyticsService
+	nrf     *fakeNRF
+	scraper *fakeScraper
+	smfURL  string
+	t0      time.Time
+	now     time.Time
+}
+
+func newLoadFixture(t *testing.T) *loadFixture {
+	logger, _ := zap.NewDevelopment()
+	f := &loadFixture{
+		nrf:     &fakeNRF{},
+		scraper: &fakeScraper{cpuSeconds: make(map[string]float64)},
+		t0:      time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+	f.now = f.t0
+	f.service = NewAnalyticsService(f.nrf, f.scraper, 4, logger)
+	f.service.now = func() time.Time { return f.now }
+
+	smf := client.NFProfile{NFInstanceID: smfID, NFType: "SMF", IPv4Addresses: []string{"192.168.1.12:8085"}}
+	url, ok := metricsURL(&smf)
+	require.True(t, ok)
+	f.smfURL = url
+	return f
+}
+
+// collect samples the AMF reporting amfLoad and the SMF having consumed
+// smfCPUSeconds, then advances the clock
+func (f *loadFixture) collect(t *testing.T, amfLoad int, smfCPUSeconds float64) {
+	t.Helper()
+	f.nrf.profiles = []client.NFProfile{
+		{NFInstanceID: amfID, NFType: "AMF", Load: amfLoad},
+		{NFInstanceID: smfID, NFType: "SMF", Load: 10, IPv4Addresses: []string{"192.168.1.12:8085"}},
+	}
+	f.scraper.cpuSeconds[f.smfURL] = smfCPUSeconds
+	require.NoError(t, f.service.Collect(context.Background()))
+	f.now = f.now.Add(10 * time.Second)
+}
+
+func TestMetricsURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile client.NFProfile
+		url     string
+	}{
+		{"host and port", client.NFProfile{NFType: "AMF", IPv4Addresses: []string{"192.168.1.10:8084"}}, "http://192.168.1.10:9094/metrics"},
+		{"host only", client.NFProfile{NFType: "NSSF", IPv4Addresses: []string{"192.168.1.16"}}, "http://192.168.1.16:9099/metrics"},
+		{"unspecified address", client.NFProfile{NFType: "AMF", IPv4Addresses: []string{"0.0.0.0:8084"}}, ""},
+		{"no address", client.NFProfile{NFType: "AMF"}, ""},
+		{"unknown NF type", client.NFProfile{NFType: "LMF", IPv4Addresses: []string{"192.168.1.10:8084"}}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url, ok := metricsURL(&tt.profile)
+			assert.Equal(t, tt.url != "", ok)
+			assert.Equal(t, tt.url, url)
+		})
+	}
+}
+
+func TestNFLoadStatistics(t *testing.T) {
+	f := newLoadFixture(t)
+	f.collect(t, 20, 100) // First scrape, no CPU usage yet
+	f.collect(t, 30, 105) // SMF at 50% CPU
+	f.collect(t, 40, 106) // SMF at 10% CPU
+
+	data := f.service.NFLoad(EventFilter{}, time.Time{}, time.Time{})
+	assert.Equal(t, []NFLoadLevelInformation{
+		{NFType: "AMF", NFInstanceID: amfID, NFLoadLevelAverage: 30, NFLoadLevelPeak: 40},
+		// Reported load of 10 raised to the CPU usage
+		{NFType: "SMF", NFInstanceID: smfID, NFCPUUsage: 30, NFLoadLevelAverage: 23, NFLoadLevelPeak: 50},
+	}, data.NFLoadLevelInfos)
+
+	// Window of the last two samples, AMFs only
+	data = f.service.NFLoad(EventFilter{NFTypes: []string{"AMF"}}, f.t0.Add(10*time.Second), f.t0.Add(20*time.Second))
+	assert.Equal(t, []NFLoadLevelInformation{
+		{NFType: "AMF", NFInstanceID: amfID, NFLoadLevelAverage: 35, NFLoadLevelPeak: 40},
+	}, data.NFLoadLevelInfos)
+
+	data = f.service.NFLoad(EventFilter{NFInstanceIDs: []string{"unknown"}}, time.Time{}, time.Time{})
+	assert.Empty(t, data.NFLoadLevelInfos)
+}
+
+func TestNFLoadPrediction(t *testing.T) {
+	f := newLoadFixture(t)
+	f.collect(t, 20, 100)
+	f.collect(t, 30, 100)
+	f.collect(t, 40, 100)
+
+	// The AMF load grows by 1 per second: 60 at t0+40s, 80 at t0+60s
+	data := f.service.NFLoad(EventFilter{NFInstanceIDs: []string{amfID}}, f.t0.Add(40*time.Second), f.t0.Add(60*time.Second))
+	assert.Equal(t, []NFLoadLevelInformation{
+		{NFType: "AMF", NFInstanceID: amfID, NFLoadLevelAverage: 70, NFLoadLevelPeak: 80, Confidence: 75},
+	}, data.NFLoadLevelInfos)
+
+	// Bounded to 100%
+	data = f.service.NFLoad(EventFilter{NFInstanceIDs: []string{amfID}}, f.t0.Add(time.Hour), time.Time{})
+	require.Len(t, data.NFLoadLevelInfos, 1)
+	assert.Equal(t, 100, data.NFLoadLevelInfos[0].NFLoadLevelPeak)
+
+	// The history is bounded, the oldest samples are dropped
+	f.collect(t, 50, 100)
+	f.collect(t, 60, 100)
+	data = f.service.NFLoad(EventFilter{NFInstanceIDs: []string{amfID}}, time.Time{}, time.Time{})
+	assert.Equal(t, 45, data.NFLoadLevelInfos[0].NFLoadLevelAverage)
+}
+
+func TestNFLoadForgetsDeregisteredNFs(t *testing.T) {
+	f := newLoadFixture(t)
+	f.collect(t, 20, 100)
+
+	f.nrf.profiles = f.nrf.profiles[:1]
+	require.NoError(t, f.service.Collect(context.Background()))
+
+	data := f.service.NFLoad(EventFilter{}, time.Time{}, time.Time{})
+	require.Len(t, data.NFLoadLevelInfos, 1)
+	assert.Equal(t, amfID, data.NFLoadLevelInfos[0].NFInstanceID)
+}
+
+func TestSubscriptionNotifiedOfNFLoad(t *testing.T) {
+	var mu sync.Mutex
+	var notifications []EventsSubscriptionNotification
+	consumer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var notification EventsSubscriptionNotification
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&notification))
+		mu.Lock()
+		notifications = append(notifications, notification)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer consumer.Close()
+
+	f := newLoadFixture(t)
+	subscriptionID, err := f.service.Subscribe(&EventsSubscription{
+		EventSubscriptions: []EventSubscription{{
+			Event:            EventNFLoad,
+			EventFilter:      EventFilter{NFTypes: []string{"AMF"}},
+			RepetitionPeriod: 15,
+		}},
+		NotificationURI: consumer.URL,
+		NotifCorrID:     "amf-load",
+	})
+	require.NoError(t, err)
+
+	received := func() []EventsSubscriptionNotification {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]EventsSubscriptionNotification(nil), notifications...)
+	}
+
+	f.collect(t, 20, 100)
+	require.Len(t, received(), 1)
+	f.collect(t, 30, 100) // Within the repetition period
+	require.Len(t, received(), 1)
+	f.collect(t, 40, 100)
+	require.Len(t, received(), 2)
+
+	notification := received()[1]
+	assert.Equal(t, subscriptionID, notification.SubscriptionID)
+	assert.Equal(t, "amf-load", notification.NotifCorrID)
+	require.Len(t, notification.EventNotifications, 1)
+	event := notification.EventNotifications[0]
+	assert.Equal(t, EventNFLoad, event.Event)
+	assert.True(t, f.t0.Equal(event.Start))
+	assert.Equal(t, []NFLoadLevelInformation{
+		{NFType: "AMF", NFInstanceID: amfID, NFLoadLevelAverage: 30, NFLoadLevelPeak: 40},
+	}, event.NFLoadLevelInfos)
+
+	require.NoError(t, f.service.Unsubscribe(subscriptionID))
+	f.collect(t, 50, 100)
+	assert.Len(t, received(), 2)
+}
+
+func TestSubscribeRejected(t *testing.T) {
+	f := newLoadFixture(t)
+
+	_, err := f.service.Subscribe(&EventsSubscription{
+		EventSubscriptions: []EventSubscription{{Event: "UE_MOBILITY"}},
+		NotificationURI:    "http://consumer/notify",
+	})
+	assert.ErrorIs(t, err, ErrUnsupportedEvent)
+
+	_, err = f.service.Subscribe(&EventsSubscription{
+		EventSubscriptions: []EventSubscription{{Event: EventNFLoad}},
+	})
+	assert.ErrorIs(t, err, ErrInvalidSubscription)
+
+	_, err = f.service.Subscribe(&EventsSubscription{NotificationURI: "http://consumer/notify"})
+	assert.ErrorIs(t, err, ErrInvalidSubscription)
+
+	assert.ErrorIs(t, f.service.Unsubscribe("unknown"), ErrSubscriptionNotFound)
+}
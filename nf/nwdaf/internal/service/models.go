@@ -0,0 +1,67 @@
+package service
+
+import "time"
+
+// EventNFLoad is the load analytics of NF instances (TS 23.288 6.5)
+const EventNFLoad = "NF_LOAD"
+
+// EventFilter selects the NF instances an analytics request covers, all of
+// them when empty
+type EventFilter struct {
+	NFTypes       []string `json:"nfTypes,omitempty"`
+	NFInstanceIDs []string `json:"nfInstanceIds,omitempty"`
+}
+
+// NFLoadLevelInformation is the load analytics of one NF instance (TS 29.520
+// 5.1.6.2.9). Usages and load levels are percentages.
+type NFLoadLevelInformation struct {
+	NFType             string `json:"nfType"`
+	NFInstanceID       string `json:"nfInstanceId"`
+	NFCPUUsage         int    `json:"nfCpuUsage,omitempty"`
+	NFLoadLevelAverage int    `json:"nfLoadLevelAverage"`
+	NFLoadLevelPeak    int    `json:"nfLoadLevelpeak"`
+	Confidence         int    `json:"confidence,omitempty"` // Predictions only
+}
+
+// AnalyticsData is the Nnwdaf_AnalyticsInfo response (TS 29.520 5.1.6.2.2)
+type AnalyticsData struct {
+	Start            time.Time                `json:"start"`
+	Expiry           time.Time                `json:"expiry"`
+	TimeStampGen     time.Time                `json:"timeStampGen"`
+	NFLoadLevelInfos []NFLoadLevelInformation `json:"nfLoadLevelInfos,omitempty"`
+}
+
+// EventSubscription is the subscription to one analytics event (TS 29.520
+// 5.1.6.2.3)
+type EventSubscription struct {
+	Event string `json:"event"`
+	EventFilter
+	// RepetitionPeriod is the minimum number of seconds between two
+	// notifications, every collection when zero
+	RepetitionPeriod int `json:"repetitionPeriod,omitempty"`
+}
+
+// EventsSubscription is an Nnwdaf_EventsSubscription subscription (TS 29.520
+// 5.1.6.2.2)
+type EventsSubscription struct {
+	EventSubscriptions []EventSubscription `json:"eventSubscriptions"`
+	NotificationURI    string              `json:"notificationURI"`
+	NotifCorrID        string              `json:"notifCorrId,omitempty"`
+}
+
+// EventNotification reports the analytics of one subscribed event (TS 29.520
+// 5.1.6.2.5)
+type EventNotification struct {
+	Event            string                   `json:"event"`
+	Start            time.Time                `json:"start"`
+	TimeStampGen     time.Time                `json:"timeStampGen"`
+	NFLoadLevelInfos []NFLoadLevelInformation `json:"nfLoadLevelInfos,omitempty"`
+}
+
+// EventsSubscriptionNotification is POSTed to the notificationURI of a
+// subscription (TS 29.520 5.1.6.2.4)
+type EventsSubscriptionNotification struct {
+	EventNotifications []EventNotification `json:"eventNotifications"`
+	SubscriptionID     string              `json:"subscriptionId"`
+	NotifCorrID        string              `json:"notifCorrId,omitempty"`
+}
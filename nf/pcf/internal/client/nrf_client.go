@@ -0,0 +1,129 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/common/nrfpool"
+)
+
+// NRFClient handles communication with NRF
+type NRFClient struct {
+	endpoints *nrfpool.Pool
+	client    *http.Client
+	logger    *zap.Logger
+}
+
+// NewNRFClient creates a new NRF client
+func NewNRFClient(endpoints *nrfpool.Pool, transport http.RoundTripper, logger *zap.Logger) *NRFClient {
+	return &NRFClient{
+		endpoints: endpoints,
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: transport,
+		},
+		logger: logger,
+	}
+}
+
+// NFProfile represents an NF profile for registration
+type NFProfile struct {
+	NFInstanceID  string   `json:"nfInstanceId"`
+	NFType        string   `json:"nfType"`
+	NFStatus      string   `json:"nfStatus"`
+	PLMNID        PLMNID   `json:"plmnId"`
+	IPv4Addresses []string `json:"ipv4Addresses,omitempty"`
+	Capacity      int      `json:"capacity,omitempty"`
+	Priority      int      `json:"priority,omitempty"`
+}
+
+// PLMNID represents PLMN identifier
+type PLMNID struct {
+	MCC string `json:"mcc"`
+	MNC string `json:"mnc"`
+}
+
+// Register registers PCF with NRF
+func (c *NRFClient) Register(ctx context.Context, profile *NFProfile) error {
+	body, err := json.Marshal(profile)
+	if err != nil {
+		return fmt.Errorf("failed to marshal profile: %w", err)
+	}
+
+	resp, err := c.endpoints.Do(c.client, func(baseURL string) (*http.Request, error) {
+		url := fmt.Sprintf("%s/nnrf-nfm/v1/nf-instances/%s", baseURL, profile.NFInstanceID)
+		req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("NRF returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	c.logger.Info("Registered with NRF", zap.String("nf_instance_id", profile.NFInstanceID))
+	return nil
+}
+
+// Deregister removes PCF registration from NRF
+func (c *NRFClient) Deregister(ctx context.Context, nfInstanceID string) error {
+	resp, err := c.endpoints.Do(c.client, func(baseURL string) (*http.Request, error) {
+		url := fmt.Sprintf("%s/nnrf-nfm/v1/nf-instances/%s", baseURL, nfInstanceID)
+		req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("NRF returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	c.logger.Info("Deregistered from NRF", zap.String("nf_instance_id", nfInstanceID))
+	return nil
+}
+
+// Heartbeat sends heartbeat to NRF
+func (c *NRFClient) Heartbeat(ctx context.Context, nfInstanceID string) error {
+	resp, err := c.endpoints.Do(c.client, func(baseURL string) (*http.Request, error) {
+		url := fmt.Sprintf("%s/nnrf-nfm/v1/nf-instances/%s/heartbeat", baseURL, nfInstanceID)
+		req, err := http.NewRequestWithContext(ctx, "PATCH", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("NRF returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	c.logger.Debug("Heartbeat sent to NRF", zap.String("nf_instance_id", nfInstanceID))
+	return nil
+}
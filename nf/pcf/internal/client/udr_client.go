@@ -0,0 +1,79 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrPolicyDataNotFound is returned when the UDR has no policy data for a
+// subscriber
+var ErrPolicyDataNotFound = errors.New("policy data not found")
+
+// UDRClient handles Nudr_DataRepository policy data requests
+type UDRClient struct {
+	baseURL string
+	client  *http.Client
+	logger  *zap.Logger
+}
+
+// NewUDRClient creates a new UDR client
+func NewUDRClient(baseURL string, timeout time.Duration, transport http.RoundTripper, logger *zap.Logger) *UDRClient {
+	return &UDRClient{
+		baseURL: baseURL,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+		},
+		logger: logger,
+	}
+}
+
+// PolicyData is the session management policy data of a subscriber. The
+// UDR stores both parts as opaque JSON documents.
+type PolicyData struct {
+	SUPI                 string          `json:"supi"`
+	SubscriberPolicies   json.RawMessage `json:"subscriberPolicies,omitempty"`
+	SubscribedDefaultQoS json.RawMessage `json:"subscribedDefaultQos,omitempty"`
+}
+
+// GetPolicyData retrieves the SM policy data of a subscriber (TS 29.519
+// 5.2.7)
+func (c *UDRClient) GetPolicyData(ctx context.Context, supi string) (*PolicyData, error) {
+	endpoint := fmt.Sprintf("%s/nudr-dr/v1/policy-data/ues/%s/sm-data", c.baseURL, url.PathEscape(supi))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrPolicyDataNotFound, supi)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("UDR returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var data PolicyData
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	c.logger.Debug("Retrieved policy data from UDR", zap.String("supi", supi))
+	return &data, nil
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/your-org/5g-network/common/nrfpool"
+	"github.com/your-org/5g-network/common/oauth"
+	"github.com/your-org/5g-network/common/retry"
+	"github.com/your-org/5g-network/common/sbi"
+	"github.com/your-org/5g-network/common/tlsconfig"
+	"gopkg.in/yaml.v3"
+)
+
+// Config represents the PCF configuration
+type Config struct {
+	NF            NFConfig            `yaml:"nf"`
+	SBI           SBIConfig           `yaml:"sbi"`
+	NRF           NRFConfig           `yaml:"nrf"`
+	UDR           UDRConfig           `yaml:"udr"`
+	PLMN          PLMNConfig          `yaml:"plmn"`
+	Policy        PolicyConfig        `yaml:"policy"`
+	Observability ObservabilityConfig `yaml:"observability"`
+}
+
+// NFConfig contains NF instance configuration
+type NFConfig struct {
+	Name        string `yaml:"name"`
+	InstanceID  string `yaml:"instance_id"`
+	Description string `yaml:"description"`
+}
+
+// SBIConfig contains Service-Based Interface configuration
+type SBIConfig struct {
+	Scheme       string                 `yaml:"scheme"`
+	BindAddress  string                 `yaml:"bind_address"`
+	Port         int                    `yaml:"port"`
+	TLS          TLSConfig              `yaml:"tls"`
+	ClientTLS    tlsconfig.ClientConfig `yaml:"client_tls"`   // TLS towards the SBIs of other NFs
+	ClientRetry  retry.Policy           `yaml:"client_retry"` // Retries of idempotent requests to other NFs
+	OAuth2       oauth.Config           `yaml:"oauth2"`
+	ClientOAuth2 oauth.ClientConfig     `yaml:"client_oauth2"` // Access tokens for requests to other NFs
+}
+
+// TLSConfig contains TLS configuration
+type TLSConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+
+	tlsconfig.Options `yaml:",inline"`
+}
+
+// NRFConfig contains NRF client configuration
+type NRFConfig struct {
+	URL               string        `yaml:"url"`
+	Enabled           bool          `yaml:"enabled"`
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"`
+
+	// Secondary NRFs and DNS SRV discovery for failover
+	nrfpool.Options `yaml:",inline"`
+}
+
+// UDRConfig contains UDR client configuration
+type UDRConfig struct {
+	URL     string        `yaml:"url"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// PLMNConfig contains PLMN configuration
+type PLMNConfig struct {
+	MCC string `yaml:"mcc"` // Mobile Country Code
+	MNC string `yaml:"mnc"` // Mobile Network Code
+}
+
+// PolicyConfig contains the policy applied when neither the UDR policy
+// data nor the SMF provided subscription set a value
+type PolicyConfig struct {
+	DefaultSessionAMBR AMBRConfig       `yaml:"default_session_ambr"`
+	DefaultQoS         DefaultQoSConfig `yaml:"default_qos"`
+}
+
+// AMBRConfig contains an aggregate maximum bit rate, e.g. "1 Gbps"
+type AMBRConfig struct {
+	Uplink   string `yaml:"uplink"`
+	Downlink string `yaml:"downlink"`
+}
+
+// DefaultQoSConfig contains the QoS of the default QoS flow
+type DefaultQoSConfig struct {
+	FiveQI           uint8 `yaml:"five_qi"`
+	ARPPriorityLevel uint8 `yaml:"arp_priority_level"` // 1 (highest) to 15
+}
+
+// ObservabilityConfig contains observability settings
+type ObservabilityConfig struct {
+	Metrics MetricsConfig `yaml:"metrics"`
+	Logging LoggingConfig `yaml:"logging"`
+}
+
+// MetricsConfig contains metrics configuration
+type MetricsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Port    int  `yaml:"port"`
+}
+
+// LoggingConfig contains logging configuration
+type LoggingConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+// Load loads configuration from a YAML file
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	// Validate configuration
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return &config, nil
+}
+
+// Validate validates the configuration
+func (c *Config) Validate() error {
+	if c.NF.Name == "" {
+		return fmt.Errorf("nf.name is required")
+	}
+
+	if c.NF.InstanceID == "" {
+		return fmt.Errorf("nf.instance_id is required")
+	}
+
+	if c.SBI.Port <= 0 || c.SBI.Port > 65535 {
+		return fmt.Errorf("invalid sbi.port: %d", c.SBI.Port)
+	}
+
+	if c.NRF.Enabled && c.NRF.URL == "" && c.NRF.SRVName == "" {
+		return fmt.Errorf("nrf.url or nrf.srv_name is required when nrf.enabled is true")
+	}
+
+	if err := c.NRF.Validate(); err != nil {
+		return fmt.Errorf("invalid nrf: %w", err)
+	}
+
+	if c.UDR.URL == "" {
+		return fmt.Errorf("udr.url is required")
+	}
+
+	if c.PLMN.MCC == "" || c.PLMN.MNC == "" {
+		return fmt.Errorf("plmn.mcc and plmn.mnc are required")
+	}
+
+	if err := c.Policy.Validate(); err != nil {
+		return fmt.Errorf("invalid policy: %w", err)
+	}
+
+	if c.SBI.TLS.Enabled {
+		if err := c.SBI.TLS.Validate(); err != nil {
+			return fmt.Errorf("invalid sbi.tls: %w", err)
+		}
+	}
+
+	if err := c.SBI.ClientTLS.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.client_tls: %w", err)
+	}
+
+	if err := c.SBI.ClientRetry.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.client_retry: %w", err)
+	}
+
+	if err := c.SBI.OAuth2.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.oauth2: %w", err)
+	}
+
+	if err := c.SBI.ClientOAuth2.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.client_oauth2: %w", err)
+	}
+
+	return nil
+}
+
+// Validate validates the default policy
+func (p *PolicyConfig) Validate() error {
+	if _, err := sbi.ParseBitRate(p.DefaultSessionAMBR.Uplink); err != nil {
+		return fmt.Errorf("default_session_ambr.uplink: %w", err)
+	}
+	if _, err := sbi.ParseBitRate(p.DefaultSessionAMBR.Downlink); err != nil {
+		return fmt.Errorf("default_session_ambr.downlink: %w", err)
+	}
+	if p.DefaultQoS.FiveQI == 0 {
+		return fmt.Errorf("default_qos.five_qi is required")
+	}
+	if p.DefaultQoS.ARPPriorityLevel < 1 || p.DefaultQoS.ARPPriorityLevel > 15 {
+		return fmt.Errorf("default_qos.arp_priority_level must be 1 to 15, have %d", p.DefaultQoS.ARPPriorityLevel)
+	}
+	return nil
+}
+
+// GetSBIURL returns the full SBI URL
+func (c *Config) GetSBIURL() string {
+	return fmt.Sprintf("%s://%s:%d", c.SBI.Scheme, c.SBI.BindAddress, c.SBI.Port)
+}
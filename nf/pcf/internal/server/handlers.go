@@ -0,0 +1,90 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/your-org/5g-network/common/sbi"
+	"github.com/your-org/5g-network/nf/pcf/internal/service"
+	"go.uber.org/zap"
+)
+
+// handleCreateSMPolicy handles POST request to create an SM policy association
+// TS 29.512, Clause 4.2.2
+func (s *PCFServer) handleCreateSMPolicy(w http.ResponseWriter, r *http.Request) {
+	var data service.SMPolicyContextData
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
+		return
+	}
+
+	s.logger.Info("Received SM policy association request",
+		zap.String("supi", data.SUPI),
+		zap.Uint8("pdu_session_id", data.PDUSessionID),
+		zap.String("dnn", data.DNN),
+	)
+
+	id, decision, err := s.policyService.CreateSMPolicy(r.Context(), &data)
+	if err != nil {
+		s.respondPolicyError(w, "failed to create SM policy association", err)
+		return
+	}
+
+	w.Header().Set("Location", "/npcf-smpolicycontrol/v1/sm-policies/"+id)
+	s.respondJSON(w, http.StatusCreated, decision)
+}
+
+// handleGetSMPolicy handles GET request for an SM policy association
+// TS 29.512, Clause 4.2.3
+func (s *PCFServer) handleGetSMPolicy(w http.ResponseWriter, r *http.Request) {
+	policy, err := s.policyService.GetSMPolicy(chi.URLParam(r, "smPolicyId"))
+	if err != nil {
+		s.respondPolicyError(w, "SM policy association not found", err)
+		return
+	}
+
+	s.respondJSON(w, http.StatusOK, policy)
+}
+
+// handleUpdateSMPolicy handles POST request to update an SM policy association
+// TS 29.512, Clause 4.2.4
+func (s *PCFServer) handleUpdateSMPolicy(w http.ResponseWriter, r *http.Request) {
+	var update service.SMPolicyUpdateContextData
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
+		return
+	}
+
+	decision, err := s.policyService.UpdateSMPolicy(r.Context(), chi.URLParam(r, "smPolicyId"), &update)
+	if err != nil {
+		s.respondPolicyError(w, "failed to update SM policy association", err)
+		return
+	}
+
+	s.respondJSON(w, http.StatusOK, decision)
+}
+
+// handleDeleteSMPolicy handles POST request to delete an SM policy association
+// TS 29.512, Clause 4.2.5
+func (s *PCFServer) handleDeleteSMPolicy(w http.ResponseWriter, r *http.Request) {
+	if err := s.policyService.DeleteSMPolicy(chi.URLParam(r, "smPolicyId")); err != nil {
+		s.respondPolicyError(w, "failed to delete SM policy association", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// respondPolicyError maps SM policy service errors to problem details
+func (s *PCFServer) respondPolicyError(w http.ResponseWriter, title string, err error) {
+	status, cause := http.StatusInternalServerError, sbi.CauseSystemFailure
+	switch {
+	case errors.Is(err, service.ErrPolicyNotFound):
+		status, cause = http.StatusNotFound, sbi.CauseContextNotFound
+	case errors.Is(err, service.ErrInvalidPolicyContext):
+		status, cause = http.StatusBadRequest, sbi.CauseMandatoryIEIncorrect
+	}
+	s.respondProblem(w, status, cause, title, err)
+}
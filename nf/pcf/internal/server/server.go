@@ -0,0 +1,195 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/your-org/5g-network/common/buildinfo"
+	"github.com/your-org/5g-network/common/correlation"
+	"github.com/your-org/5g-network/common/oauth"
+	"github.com/your-org/5g-network/common/sbi"
+	"github.com/your-org/5g-network/nf/pcf/internal/config"
+	"github.com/your-org/5g-network/nf/pcf/internal/service"
+	"go.uber.org/zap"
+)
+
+// PCFServer represents the PCF HTTP server
+type PCFServer struct {
+	config *config.Config
+	router *chi.Mux
+	server *http.Server
+	logger *zap.Logger
+
+	// Services
+	policyService *service.SMPolicyService
+}
+
+// NewServer creates a new PCF server
+func NewServer(
+	cfg *config.Config,
+	policyService *service.SMPolicyService,
+	logger *zap.Logger,
+) *PCFServer {
+	s := &PCFServer{
+		config:        cfg,
+		router:        chi.NewRouter(),
+		logger:        logger,
+		policyService: policyService,
+	}
+
+	s.setupMiddleware()
+	s.setupRoutes()
+
+	return s
+}
+
+// setupMiddleware configures HTTP middleware
+func (s *PCFServer) setupMiddleware() {
+	s.router.Use(middleware.RequestID)
+	s.router.Use(correlation.Middleware)
+	s.router.Use(middleware.RealIP)
+	s.router.Use(s.loggingMiddleware)
+	s.router.Use(middleware.Recoverer)
+	s.router.Use(middleware.Timeout(60 * time.Second))
+	s.router.Use(oauth.Middleware(s.config.SBI.OAuth2, s.logger, "PCF", s.config.NF.InstanceID))
+}
+
+// setupRoutes configures HTTP routes
+func (s *PCFServer) setupRoutes() {
+	// Health and status
+	s.router.Get("/health", s.handleHealth)
+	s.router.Get("/ready", s.handleReady)
+	s.router.Get("/status", s.handleStatus)
+
+	// Npcf_SMPolicyControl service (TS 29.512)
+	s.router.Route("/npcf-smpolicycontrol/v1", func(r chi.Router) {
+		r.Use(oauth.RequireScope("npcf-smpolicycontrol"))
+		r.Post("/sm-policies", s.handleCreateSMPolicy)
+		r.Get("/sm-policies/{smPolicyId}", s.handleGetSMPolicy)
+		r.Post("/sm-policies/{smPolicyId}/update", s.handleUpdateSMPolicy)
+		r.Post("/sm-policies/{smPolicyId}/delete", s.handleDeleteSMPolicy)
+	})
+
+	// Admin endpoints
+	s.router.Route("/admin", func(r chi.Router) {
+		r.Get("/stats", s.handleGetStats)
+	})
+}
+
+// SetBuildInfo exposes the NF build info on GET /version
+func (s *PCFServer) SetBuildInfo(info *buildinfo.Info) {
+	s.router.Get("/version", info.Handler())
+}
+
+// Start starts the HTTP server
+func (s *PCFServer) Start() error {
+	addr := fmt.Sprintf("%s:%d", s.config.SBI.BindAddress, s.config.SBI.Port)
+
+	s.server = &http.Server{
+		Addr:         addr,
+		Handler:      s.router,
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		IdleTimeout:  120 * time.Second,
+	}
+
+	s.logger.Info("Starting PCF HTTP server", zap.String("address", addr))
+
+	if s.config.SBI.TLS.Enabled {
+		tlsConfig, err := s.config.SBI.TLS.Build()
+		if err != nil {
+			return fmt.Errorf("invalid TLS configuration: %w", err)
+		}
+		s.server.TLSConfig = tlsConfig
+		return s.server.ListenAndServeTLS(s.config.SBI.TLS.CertFile, s.config.SBI.TLS.KeyFile)
+	}
+
+	return s.server.ListenAndServe()
+}
+
+// Stop gracefully stops the HTTP server
+func (s *PCFServer) Stop(ctx context.Context) error {
+	s.logger.Info("Stopping PCF HTTP server")
+
+	if s.server != nil {
+		return s.server.Shutdown(ctx)
+	}
+
+	return nil
+}
+
+// Middleware
+
+func (s *PCFServer) loggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		s.logger.Info("HTTP request",
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+			zap.Int("status", ww.Status()),
+			zap.Duration("duration", time.Since(start)),
+			zap.String("remote_addr", r.RemoteAddr),
+			zap.String("request_id", middleware.GetReqID(r.Context())),
+			correlation.Field(r.Context()),
+		)
+	})
+}
+
+// Helper functions
+
+func (s *PCFServer) respondJSON(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		s.logger.Error("Failed to encode JSON response", zap.Error(err))
+	}
+}
+
+// respondProblem writes a ProblemDetails error response
+func (s *PCFServer) respondProblem(w http.ResponseWriter, status int, cause, title string, err error) {
+	s.logger.Error(title, zap.String("cause", cause), zap.Error(err))
+
+	if err := sbi.WriteProblem(w, sbi.NewProblem(status, cause, title, err)); err != nil {
+		s.logger.Error("Failed to encode problem details", zap.Error(err))
+	}
+}
+
+// Health check handlers
+
+func (s *PCFServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	s.respondJSON(w, http.StatusOK, map[string]string{
+		"status": "healthy",
+	})
+}
+
+func (s *PCFServer) handleReady(w http.ResponseWriter, r *http.Request) {
+	s.respondJSON(w, http.StatusOK, map[string]string{
+		"status": "ready",
+	})
+}
+
+func (s *PCFServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"service": "PCF",
+		"version": "1.0.0",
+		"stats":   s.policyService.GetStats(),
+	})
+}
+
+func (s *PCFServer) handleGetStats(w http.ResponseWriter, r *http.Request) {
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"service":      "PCF",
+		"version":      "1.0.0",
+		"policy_stats": s.policyService.GetStats(),
+	})
+}
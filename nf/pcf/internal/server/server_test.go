@@ -0,0 +1,95 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/common/sbi"
+	"github.com/your-org/5g-network/nf/pcf/internal/client"
+	"github.com/your-org/5g-network/nf/pcf/internal/config"
+	"github.com/your-org/5g-network/nf/pcf/internal/service"
+)
+
+func newTestServer(t *testing.T) *PCFServer {
+	t.Helper()
+
+	// A UDR without policy data for any subscriber
+	udr := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(udr.Close)
+
+	logger, _ := zap.NewDevelopment()
+	cfg := &config.Config{
+		Policy: config.PolicyConfig{
+			DefaultSessionAMBR: config.AMBRConfig{Uplink: "1 Gbps", Downlink: "2 Gbps"},
+			DefaultQoS:         config.DefaultQoSConfig{FiveQI: 9, ARPPriorityLevel: 8},
+		},
+	}
+	policyService := service.NewSMPolicyService(client.NewUDRClient(udr.URL, time.Second, nil, logger), cfg.Policy, logger)
+	return NewServer(cfg, policyService, logger)
+}
+
+func serve(s *PCFServer, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		_ = json.NewEncoder(&buf).Encode(body)
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(method, path, &buf))
+	return rec
+}
+
+func TestSMPolicyLifecycle(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := serve(s, http.MethodPost, "/npcf-smpolicycontrol/v1/sm-policies", service.SMPolicyContextData{
+		SUPI:         "imsi-001010000000001",
+		PDUSessionID: 5,
+		DNN:          "internet",
+	})
+	require.Equal(t, http.StatusCreated, rec.Code)
+	location := rec.Header().Get("Location")
+	assert.Equal(t, "/npcf-smpolicycontrol/v1/sm-policies/imsi-001010000000001-5", location)
+
+	var decision service.SMPolicyDecision
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &decision))
+	require.Contains(t, decision.SessRules, "sess-1")
+	assert.Equal(t, "2 Gbps", decision.SessRules["sess-1"].AuthSessAMBR.Downlink)
+
+	rec = serve(s, http.MethodPost, location+"/update", service.SMPolicyUpdateContextData{
+		RepPolicyCtrlReqTriggers: []string{"SE_AMBR_CH"},
+		SubsSessAMBR:             &service.AMBR{Uplink: "10 Mbps", Downlink: "20 Mbps"},
+	})
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &decision))
+	assert.Equal(t, "20 Mbps", decision.SessRules["sess-1"].AuthSessAMBR.Downlink)
+
+	rec = serve(s, http.MethodGet, location, nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rec = serve(s, http.MethodPost, location+"/delete", nil)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = serve(s, http.MethodPost, location+"/delete", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestCreateSMPolicyBadRequest(t *testing.T) {
+	s := newTestServer(t)
+
+	rec := serve(s, http.MethodPost, "/npcf-smpolicycontrol/v1/sm-policies", service.SMPolicyContextData{
+		SUPI: "imsi-001010000000001",
+	})
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var problem sbi.ProblemDetails
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	assert.Equal(t, sbi.CauseMandatoryIEIncorrect, problem.Cause)
+}
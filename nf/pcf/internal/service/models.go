@@ -0,0 +1,121 @@
+package service
+
+import "time"
+
+// SNSSAI represents an S-NSSAI (TS 29.571 5.4.4.2)
+type SNSSAI struct {
+	SST int    `json:"sst"`
+	SD  string `json:"sd,omitempty"`
+}
+
+// AMBR is an aggregate maximum bit rate, e.g. "1 Gbps" (TS 29.571 5.5.4.2)
+type AMBR struct {
+	Uplink   string `json:"uplink"`
+	Downlink string `json:"downlink"`
+}
+
+// ARP represents an Allocation and Retention Priority (TS 29.571 5.5.4.1)
+type ARP struct {
+	PriorityLevel uint8  `json:"priorityLevel"` // 1 (highest) to 15
+	PreemptCap    string `json:"preemptCap"`    // "NOT_PREEMPT" or "MAY_PREEMPT"
+	PreemptVuln   string `json:"preemptVuln"`   // "NOT_PREEMPTABLE" or "PREEMPTABLE"
+}
+
+// DefaultQoS is the QoS of the default QoS flow, subscribed (TS 29.571
+// 5.5.4.3) or authorized by the PCF (TS 29.512 5.6.2.34)
+type DefaultQoS struct {
+	FiveQI        uint8 `json:"5qi"`
+	ARP           ARP   `json:"arp"`
+	PriorityLevel uint8 `json:"priorityLevel,omitempty"`
+}
+
+// SMPolicyContextData is the SMF's request for an SM policy association
+// (TS 29.512 5.6.2.3), reduced to the fields the PCF acts on
+type SMPolicyContextData struct {
+	SUPI            string      `json:"supi"`
+	PDUSessionID    uint8       `json:"pduSessionId"`
+	PDUSessionType  string      `json:"pduSessionType,omitempty"`
+	DNN             string      `json:"dnn"`
+	SliceInfo       SNSSAI      `json:"sliceInfo"`
+	IPv4Address     string      `json:"ipv4Address,omitempty"`
+	NotificationURI string      `json:"notificationUri,omitempty"`
+	SubsSessAMBR    *AMBR       `json:"subsSessAmbr,omitempty"`
+	SubsDefQoS      *DefaultQoS `json:"subsDefQos,omitempty"`
+}
+
+// SMPolicyUpdateContextData reports changes of a PDU session to the PCF
+// (TS 29.512 5.6.2.18)
+type SMPolicyUpdateContextData struct {
+	RepPolicyCtrlReqTriggers []string    `json:"repPolicyCtrlReqTriggers,omitempty"`
+	SubsSessAMBR             *AMBR       `json:"subsSessAmbr,omitempty"`
+	SubsDefQoS               *DefaultQoS `json:"subsDefQos,omitempty"`
+}
+
+// SessionRule authorizes the session AMBR and default QoS (TS 29.512
+// 5.6.2.7)
+type SessionRule struct {
+	SessRuleID   string      `json:"sessRuleId"`
+	AuthSessAMBR *AMBR       `json:"authSessAmbr,omitempty"`
+	AuthDefQoS   *DefaultQoS `json:"authDefQos,omitempty"`
+}
+
+// FlowInformation describes a service data flow (TS 29.512 5.6.2.14)
+type FlowInformation struct {
+	FlowDescription string `json:"flowDescription,omitempty"` // IPFilterRule, e.g. "permit out ip from any to assigned"
+	FlowDirection   string `json:"flowDirection,omitempty"`   // "DOWNLINK", "UPLINK" or "BIDIRECTIONAL"
+}
+
+// PCCRule binds service data flows to QoS data (TS 29.512 5.6.2.6)
+type PCCRule struct {
+	PCCRuleID  string            `json:"pccRuleId"`
+	FlowInfos  []FlowInformation `json:"flowInfos,omitempty"`
+	Precedence uint32            `json:"precedence,omitempty"`
+	RefQoSData []string          `json:"refQosData,omitempty"`
+}
+
+// QoSData is the QoS of PCC rules (TS 29.512 5.6.2.8)
+type QoSData struct {
+	QoSID         string `json:"qosId"`
+	FiveQI        uint8  `json:"5qi"`
+	MaxBRUl       string `json:"maxbrUl,omitempty"`
+	MaxBRDl       string `json:"maxbrDl,omitempty"`
+	GBRUl         string `json:"gbrUl,omitempty"`
+	GBRDl         string `json:"gbrDl,omitempty"`
+	ARP           *ARP   `json:"arp,omitempty"`
+	PriorityLevel uint8  `json:"priorityLevel,omitempty"`
+}
+
+// SMPolicyDecision is the policy the SMF enforces on the PDU session (TS
+// 29.512 5.6.2.4)
+type SMPolicyDecision struct {
+	SessRules             map[string]*SessionRule `json:"sessRules,omitempty"`
+	PCCRules              map[string]*PCCRule     `json:"pccRules,omitempty"`
+	QoSDecs               map[string]*QoSData     `json:"qosDecs,omitempty"`
+	PolicyCtrlReqTriggers []string                `json:"policyCtrlReqTriggers,omitempty"`
+}
+
+// SMPolicyControl is an SM policy association (TS 29.512 5.6.2.2)
+type SMPolicyControl struct {
+	Context   SMPolicyContextData `json:"context"`
+	Policy    *SMPolicyDecision   `json:"policy"`
+	CreatedAt time.Time           `json:"createdAt"`
+	UpdatedAt time.Time           `json:"updatedAt"`
+}
+
+// SubscriberPolicies is the subscriberPolicies document of the UDR policy
+// data. DNNSessionAMBR overrides SessionAMBR for a DNN; PCC rules without a
+// DNN apply to every DNN.
+type SubscriberPolicies struct {
+	SessionAMBR    *AMBR           `json:"sessionAmbr,omitempty"`
+	DNNSessionAMBR map[string]AMBR `json:"dnnSessionAmbr,omitempty"`
+	PCCRules       []PolicyRule    `json:"pccRules,omitempty"`
+}
+
+// PolicyRule is a provisioned PCC rule
+type PolicyRule struct {
+	PCCRuleID        string   `json:"pccRuleId"`
+	DNN              string   `json:"dnn,omitempty"`
+	Precedence       uint32   `json:"precedence,omitempty"`
+	FlowDescriptions []string `json:"flowDescriptions,omitempty"`
+	QoS              QoSData  `json:"qos"` // qosId defaults to the rule ID
+}
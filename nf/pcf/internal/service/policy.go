@@ -0,0 +1,312 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/your-org/5g-network/common/sbi"
+	"github.com/your-org/5g-network/nf/pcf/internal/client"
+	"github.com/your-org/5g-network/nf/pcf/internal/config"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrPolicyNotFound is returned for an unknown SM policy association
+	ErrPolicyNotFound = errors.New("SM policy association not found")
+
+	// ErrInvalidPolicyContext is returned when the SMF's context data misses
+	// mandatory fields or carries invalid values
+	ErrInvalidPolicyContext = errors.New("invalid SM policy context")
+
+	// ErrInvalidPolicyData is returned when the UDR policy data of the
+	// subscriber cannot be applied
+	ErrInvalidPolicyData = errors.New("invalid policy data")
+)
+
+// sessionRuleID identifies the single session rule of a decision
+const sessionRuleID = "sess-1"
+
+// Policy control request triggers (TS 29.512 5.6.3.6): the SMF reports
+// changes of the subscribed session AMBR and default QoS
+var policyCtrlReqTriggers = []string{"SE_AMBR_CH", "DEF_QOS_CH"}
+
+// SMPolicyService implements Npcf_SMPolicyControl (TS 29.512)
+type SMPolicyService struct {
+	udrClient *client.UDRClient
+	defaults  config.PolicyConfig
+	logger    *zap.Logger
+
+	mu       sync.RWMutex
+	policies map[string]*SMPolicyControl
+}
+
+// NewSMPolicyService creates an SM policy service reading subscriber policy
+// from the UDR and falling back to defaults
+func NewSMPolicyService(udrClient *client.UDRClient, defaults config.PolicyConfig, logger *zap.Logger) *SMPolicyService {
+	return &SMPolicyService{
+		udrClient: udrClient,
+		defaults:  defaults,
+		logger:    logger,
+		policies:  make(map[string]*SMPolicyControl),
+	}
+}
+
+// smPolicyID identifies the association of a PDU session, so a
+// re-established session replaces its stale association
+func smPolicyID(supi string, pduSessionID uint8) string {
+	return fmt.Sprintf("%s-%d", supi, pduSessionID)
+}
+
+// CreateSMPolicy creates the SM policy association of a PDU session and
+// returns its ID and policy decision (TS 29.512 4.2.2)
+func (s *SMPolicyService) CreateSMPolicy(ctx context.Context, data *SMPolicyContextData) (string, *SMPolicyDecision, error) {
+	if data.SUPI == "" || data.DNN == "" {
+		return "", nil, fmt.Errorf("%w: supi and dnn are required", ErrInvalidPolicyContext)
+	}
+	if data.PDUSessionID < 1 || data.PDUSessionID > 15 {
+		return "", nil, fmt.Errorf("%w: pdu session id %d", ErrInvalidPolicyContext, data.PDUSessionID)
+	}
+
+	decision, err := s.decide(ctx, data)
+	if err != nil {
+		return "", nil, err
+	}
+
+	id := smPolicyID(data.SUPI, data.PDUSessionID)
+	now := time.Now()
+
+	s.mu.Lock()
+	s.policies[id] = &SMPolicyControl{
+		Context:   *data,
+		Policy:    decision,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.mu.Unlock()
+
+	s.logger.Info("SM policy association created",
+		zap.String("sm_policy_id", id),
+		zap.String("supi", data.SUPI),
+		zap.String("dnn", data.DNN),
+		zap.Int("pcc_rules", len(decision.PCCRules)),
+	)
+	return id, decision, nil
+}
+
+// UpdateSMPolicy applies the reported changes to an association and
+// returns the policy decided again from the current UDR policy data (TS
+// 29.512 4.2.4)
+func (s *SMPolicyService) UpdateSMPolicy(ctx context.Context, id string, update *SMPolicyUpdateContextData) (*SMPolicyDecision, error) {
+	s.mu.RLock()
+	policy, exists := s.policies[id]
+	var data SMPolicyContextData
+	if exists {
+		data = policy.Context
+	}
+	s.mu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrPolicyNotFound, id)
+	}
+
+	if update.SubsSessAMBR != nil {
+		data.SubsSessAMBR = update.SubsSessAMBR
+	}
+	if update.SubsDefQoS != nil {
+		data.SubsDefQoS = update.SubsDefQoS
+	}
+
+	decision, err := s.decide(ctx, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The association may have been deleted while the UDR was queried
+	policy, exists = s.policies[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrPolicyNotFound, id)
+	}
+	policy.Context = data
+	policy.Policy = decision
+	policy.UpdatedAt = time.Now()
+
+	s.logger.Info("SM policy association updated",
+		zap.String("sm_policy_id", id),
+		zap.Strings("triggers", update.RepPolicyCtrlReqTriggers),
+	)
+	return decision, nil
+}
+
+// GetSMPolicy returns an SM policy association
+func (s *SMPolicyService) GetSMPolicy(id string) (*SMPolicyControl, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	policy, exists := s.policies[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrPolicyNotFound, id)
+	}
+	copied := *policy
+	return &copied, nil
+}
+
+// DeleteSMPolicy deletes an SM policy association (TS 29.512 4.2.5)
+func (s *SMPolicyService) DeleteSMPolicy(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.policies[id]; !exists {
+		return fmt.Errorf("%w: %s", ErrPolicyNotFound, id)
+	}
+	delete(s.policies, id)
+
+	s.logger.Info("SM policy association deleted", zap.String("sm_policy_id", id))
+	return nil
+}
+
+// decide builds the policy decision of a PDU session. The UDR policy data
+// takes precedence over the subscription reported by the SMF, which takes
+// precedence over the configured defaults.
+func (s *SMPolicyService) decide(ctx context.Context, data *SMPolicyContextData) (*SMPolicyDecision, error) {
+	var policies SubscriberPolicies
+	var subscribedQoS *DefaultQoS
+
+	policyData, err := s.udrClient.GetPolicyData(ctx, data.SUPI)
+	switch {
+	case errors.Is(err, client.ErrPolicyDataNotFound):
+		s.logger.Debug("No policy data, using subscription and defaults", zap.String("supi", data.SUPI))
+	case err != nil:
+		return nil, fmt.Errorf("failed to get policy data: %w", err)
+	default:
+		if len(policyData.SubscriberPolicies) > 0 {
+			if err := json.Unmarshal(policyData.SubscriberPolicies, &policies); err != nil {
+				return nil, fmt.Errorf("%w: subscriberPolicies: %v", ErrInvalidPolicyData, err)
+			}
+		}
+		if len(policyData.SubscribedDefaultQoS) > 0 {
+			subscribedQoS = &DefaultQoS{}
+			if err := json.Unmarshal(policyData.SubscribedDefaultQoS, subscribedQoS); err != nil {
+				return nil, fmt.Errorf("%w: subscribedDefaultQos: %v", ErrInvalidPolicyData, err)
+			}
+		}
+	}
+
+	sessionAMBR := AMBR{
+		Uplink:   s.defaults.DefaultSessionAMBR.Uplink,
+		Downlink: s.defaults.DefaultSessionAMBR.Downlink,
+	}
+	if ambr, ok := policies.DNNSessionAMBR[data.DNN]; ok {
+		sessionAMBR = ambr
+	} else if policies.SessionAMBR != nil {
+		sessionAMBR = *policies.SessionAMBR
+	} else if data.SubsSessAMBR != nil {
+		sessionAMBR = *data.SubsSessAMBR
+	}
+	if err := validateAMBR(sessionAMBR); err != nil {
+		return nil, err
+	}
+
+	defaultQoS := DefaultQoS{
+		FiveQI: s.defaults.DefaultQoS.FiveQI,
+		ARP: ARP{
+			PriorityLevel: s.defaults.DefaultQoS.ARPPriorityLevel,
+			PreemptCap:    "NOT_PREEMPT",
+			PreemptVuln:   "PREEMPTABLE",
+		},
+	}
+	if subscribedQoS != nil {
+		defaultQoS = *subscribedQoS
+	} else if data.SubsDefQoS != nil {
+		defaultQoS = *data.SubsDefQoS
+	}
+
+	decision := &SMPolicyDecision{
+		SessRules: map[string]*SessionRule{
+			sessionRuleID: {
+				SessRuleID:   sessionRuleID,
+				AuthSessAMBR: &sessionAMBR,
+				AuthDefQoS:   &defaultQoS,
+			},
+		},
+		PolicyCtrlReqTriggers: policyCtrlReqTriggers,
+	}
+
+	for _, rule := range policies.PCCRules {
+		if rule.DNN != "" && rule.DNN != data.DNN {
+			continue
+		}
+		if err := addPCCRule(decision, rule); err != nil {
+			return nil, err
+		}
+	}
+
+	return decision, nil
+}
+
+// addPCCRule adds a provisioned rule and its QoS data to a decision
+func addPCCRule(decision *SMPolicyDecision, rule PolicyRule) error {
+	if rule.PCCRuleID == "" {
+		return fmt.Errorf("%w: pcc rule without pccRuleId", ErrInvalidPolicyData)
+	}
+	qos := rule.QoS
+	if qos.QoSID == "" {
+		qos.QoSID = rule.PCCRuleID
+	}
+	if qos.FiveQI == 0 {
+		return fmt.Errorf("%w: pcc rule %s without 5qi", ErrInvalidPolicyData, rule.PCCRuleID)
+	}
+	for _, rate := range []string{qos.MaxBRUl, qos.MaxBRDl, qos.GBRUl, qos.GBRDl} {
+		if rate == "" {
+			continue
+		}
+		if _, err := sbi.ParseBitRate(rate); err != nil {
+			return fmt.Errorf("%w: pcc rule %s: %v", ErrInvalidPolicyData, rule.PCCRuleID, err)
+		}
+	}
+
+	pccRule := &PCCRule{
+		PCCRuleID:  rule.PCCRuleID,
+		Precedence: rule.Precedence,
+		RefQoSData: []string{qos.QoSID},
+	}
+	for _, description := range rule.FlowDescriptions {
+		pccRule.FlowInfos = append(pccRule.FlowInfos, FlowInformation{
+			FlowDescription: description,
+			FlowDirection:   "BIDIRECTIONAL",
+		})
+	}
+
+	if decision.PCCRules == nil {
+		decision.PCCRules = make(map[string]*PCCRule)
+		decision.QoSDecs = make(map[string]*QoSData)
+	}
+	decision.PCCRules[rule.PCCRuleID] = pccRule
+	decision.QoSDecs[qos.QoSID] = &qos
+	return nil
+}
+
+func validateAMBR(ambr AMBR) error {
+	if _, err := sbi.ParseBitRate(ambr.Uplink); err != nil {
+		return fmt.Errorf("%w: session AMBR uplink: %v", ErrInvalidPolicyData, err)
+	}
+	if _, err := sbi.ParseBitRate(ambr.Downlink); err != nil {
+		return fmt.Errorf("%w: session AMBR downlink: %v", ErrInvalidPolicyData, err)
+	}
+	return nil
+}
+
+// GetStats returns service statistics
+func (s *SMPolicyService) GetStats() map[string]interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return map[string]interface{}{
+		"sm_policy_associations": len(s.policies),
+	}
+}
@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/nf/pcf/internal/client"
+	"github.com/your-org/5g-network/nf/pcf/internal/config"
+)
+
+const testSUPI = "imsi-001010000000001"
+
+// fakeUDR serves policy data per SUPI, 404 for unknown subscribers
+type fakeUDR struct {
+	mu   sync.Mutex
+	data map[string]*client.PolicyData
+}
+
+func (u *fakeUDR) set(t *testing.T, supi string, policies *SubscriberPolicies, defaultQoS *DefaultQoS) {
+	t.Helper()
+	data := &client.PolicyData{SUPI: supi}
+	if policies != nil {
+		raw, err := json.Marshal(policies)
+		require.NoError(t, err)
+		data.SubscriberPolicies = raw
+	}
+	if defaultQoS != nil {
+		raw, err := json.Marshal(defaultQoS)
+		require.NoError(t, err)
+		data.SubscribedDefaultQoS = raw
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.data[supi] = data
+}
+
+func (u *fakeUDR) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	supi := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/nudr-dr/v1/policy-data/ues/"), "/sm-data")
+
+	u.mu.Lock()
+	data, exists := u.data[supi]
+	u.mu.Unlock()
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func newTestPolicyService(t *testing.T) (*SMPolicyService, *fakeUDR) {
+	t.Helper()
+
+	udr := &fakeUDR{data: make(map[string]*client.PolicyData)}
+	server := httptest.NewServer(udr)
+	t.Cleanup(server.Close)
+
+	logger, _ := zap.NewDevelopment()
+	defaults := config.PolicyConfig{
+		DefaultSessionAMBR: config.AMBRConfig{Uplink: "1 Gbps", Downlink: "2 Gbps"},
+		DefaultQoS:         config.DefaultQoSConfig{FiveQI: 9, ARPPriorityLevel: 8},
+	}
+	return NewSMPolicyService(client.NewUDRClient(server.URL, time.Second, nil, logger), defaults, logger), udr
+}
+
+func testContextData() *SMPolicyContextData {
+	return &SMPolicyContextData{
+		SUPI:         testSUPI,
+		PDUSessionID: 1,
+		DNN:          "internet",
+		SliceInfo:    SNSSAI{SST: 1, SD: "000001"},
+	}
+}
+
+func sessionRule(t *testing.T, decision *SMPolicyDecision) *SessionRule {
+	t.Helper()
+	require.Len(t, decision.SessRules, 1)
+	rule := decision.SessRules[sessionRuleID]
+	require.NotNil(t, rule)
+	require.NotNil(t, rule.AuthSessAMBR)
+	require.NotNil(t, rule.AuthDefQoS)
+	return rule
+}
+
+func TestCreateSMPolicyDefaults(t *testing.T) {
+	svc, _ := newTestPolicyService(t)
+
+	id, decision, err := svc.CreateSMPolicy(context.Background(), testContextData())
+	require.NoError(t, err)
+	assert.Equal(t, testSUPI+"-1", id)
+
+	rule := sessionRule(t, decision)
+	assert.Equal(t, AMBR{Uplink: "1 Gbps", Downlink: "2 Gbps"}, *rule.AuthSessAMBR)
+	assert.Equal(t, uint8(9), rule.AuthDefQoS.FiveQI)
+	assert.Equal(t, uint8(8), rule.AuthDefQoS.ARP.PriorityLevel)
+	assert.Empty(t, decision.PCCRules)
+	assert.Equal(t, []string{"SE_AMBR_CH", "DEF_QOS_CH"}, decision.PolicyCtrlReqTriggers)
+
+	// The SMF reported subscription applies without UDR policy data
+	data := testContextData()
+	data.PDUSessionID = 2
+	data.SubsSessAMBR = &AMBR{Uplink: "50 Mbps", Downlink: "100 Mbps"}
+	data.SubsDefQoS = &DefaultQoS{FiveQI: 8, ARP: ARP{PriorityLevel: 5}}
+	_, decision, err = svc.CreateSMPolicy(context.Background(), data)
+	require.NoError(t, err)
+	rule = sessionRule(t, decision)
+	assert.Equal(t, *data.SubsSessAMBR, *rule.AuthSessAMBR)
+	assert.Equal(t, uint8(8), rule.AuthDefQoS.FiveQI)
+}
+
+func TestCreateSMPolicyFromUDR(t *testing.T) {
+	svc, udr := newTestPolicyService(t)
+	udr.set(t, testSUPI, &SubscriberPolicies{
+		SessionAMBR:    &AMBR{Uplink: "100 Mbps", Downlink: "200 Mbps"},
+		DNNSessionAMBR: map[string]AMBR{"ims": {Uplink: "1 Mbps", Downlink: "1 Mbps"}},
+		PCCRules: []PolicyRule{
+			{
+				PCCRuleID:        "video",
+				Precedence:       10,
+				FlowDescriptions: []string{"permit out ip from 10.0.0.1 to assigned"},
+				QoS:              QoSData{FiveQI: 2, GBRUl: "1 Mbps", GBRDl: "5 Mbps", MaxBRUl: "2 Mbps", MaxBRDl: "10 Mbps"},
+			},
+			{PCCRuleID: "voice", DNN: "ims", QoS: QoSData{QoSID: "qos-voice", FiveQI: 1}},
+		},
+	}, &DefaultQoS{FiveQI: 7, ARP: ARP{PriorityLevel: 3, PreemptCap: "MAY_PREEMPT", PreemptVuln: "NOT_PREEMPTABLE"}})
+
+	// The SMF's subscription is overridden by the UDR policy data
+	data := testContextData()
+	data.SubsSessAMBR = &AMBR{Uplink: "50 Mbps", Downlink: "50 Mbps"}
+	_, decision, err := svc.CreateSMPolicy(context.Background(), data)
+	require.NoError(t, err)
+
+	rule := sessionRule(t, decision)
+	assert.Equal(t, AMBR{Uplink: "100 Mbps", Downlink: "200 Mbps"}, *rule.AuthSessAMBR)
+	assert.Equal(t, uint8(7), rule.AuthDefQoS.FiveQI)
+	assert.Equal(t, "MAY_PREEMPT", rule.AuthDefQoS.ARP.PreemptCap)
+
+	// Only the rule for every DNN applies to internet
+	require.Len(t, decision.PCCRules, 1)
+	video := decision.PCCRules["video"]
+	require.NotNil(t, video)
+	assert.Equal(t, uint32(10), video.Precedence)
+	assert.Equal(t, []string{"video"}, video.RefQoSData)
+	require.Len(t, video.FlowInfos, 1)
+	assert.Equal(t, "permit out ip from 10.0.0.1 to assigned", video.FlowInfos[0].FlowDescription)
+	require.Contains(t, decision.QoSDecs, "video")
+	assert.Equal(t, uint8(2), decision.QoSDecs["video"].FiveQI)
+	assert.Equal(t, "5 Mbps", decision.QoSDecs["video"].GBRDl)
+
+	// The ims session gets its DNN specific AMBR and voice rule
+	data = testContextData()
+	data.PDUSessionID = 2
+	data.DNN = "ims"
+	_, decision, err = svc.CreateSMPolicy(context.Background(), data)
+	require.NoError(t, err)
+	assert.Equal(t, AMBR{Uplink: "1 Mbps", Downlink: "1 Mbps"}, *sessionRule(t, decision).AuthSessAMBR)
+	assert.Len(t, decision.PCCRules, 2)
+	assert.Equal(t, []string{"qos-voice"}, decision.PCCRules["voice"].RefQoSData)
+	assert.Contains(t, decision.QoSDecs, "qos-voice")
+}
+
+func TestUpdateSMPolicyChangesSessionAMBR(t *testing.T) {
+	svc, udr := newTestPolicyService(t)
+	udr.set(t, testSUPI, &SubscriberPolicies{SessionAMBR: &AMBR{Uplink: "100 Mbps", Downlink: "200 Mbps"}}, nil)
+
+	id, decision, err := svc.CreateSMPolicy(context.Background(), testContextData())
+	require.NoError(t, err)
+	assert.Equal(t, "200 Mbps", sessionRule(t, decision).AuthSessAMBR.Downlink)
+
+	// The operator raises the subscriber's session AMBR in the UDR
+	udr.set(t, testSUPI, &SubscriberPolicies{SessionAMBR: &AMBR{Uplink: "500 Mbps", Downlink: "1 Gbps"}}, nil)
+
+	decision, err = svc.UpdateSMPolicy(context.Background(), id, &SMPolicyUpdateContextData{
+		RepPolicyCtrlReqTriggers: []string{"SE_AMBR_CH"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, AMBR{Uplink: "500 Mbps", Downlink: "1 Gbps"}, *sessionRule(t, decision).AuthSessAMBR)
+
+	policy, err := svc.GetSMPolicy(id)
+	require.NoError(t, err)
+	assert.Equal(t, "1 Gbps", sessionRule(t, policy.Policy).AuthSessAMBR.Downlink)
+}
+
+func TestUpdateSMPolicySubscribedAMBR(t *testing.T) {
+	svc, _ := newTestPolicyService(t)
+
+	id, _, err := svc.CreateSMPolicy(context.Background(), testContextData())
+	require.NoError(t, err)
+
+	decision, err := svc.UpdateSMPolicy(context.Background(), id, &SMPolicyUpdateContextData{
+		RepPolicyCtrlReqTriggers: []string{"SE_AMBR_CH"},
+		SubsSessAMBR:             &AMBR{Uplink: "10 Mbps", Downlink: "20 Mbps"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, AMBR{Uplink: "10 Mbps", Downlink: "20 Mbps"}, *sessionRule(t, decision).AuthSessAMBR)
+
+	// The reported subscription is kept for later updates
+	decision, err = svc.UpdateSMPolicy(context.Background(), id, &SMPolicyUpdateContextData{})
+	require.NoError(t, err)
+	assert.Equal(t, "20 Mbps", sessionRule(t, decision).AuthSessAMBR.Downlink)
+}
+
+func TestSMPolicyErrors(t *testing.T) {
+	svc, udr := newTestPolicyService(t)
+
+	_, _, err := svc.CreateSMPolicy(context.Background(), &SMPolicyContextData{SUPI: testSUPI, PDUSessionID: 1})
+	assert.ErrorIs(t, err, ErrInvalidPolicyContext)
+	_, _, err = svc.CreateSMPolicy(context.Background(), &SMPolicyContextData{SUPI: testSUPI, DNN: "internet"})
+	assert.ErrorIs(t, err, ErrInvalidPolicyContext)
+
+	_, err = svc.UpdateSMPolicy(context.Background(), "unknown", &SMPolicyUpdateContextData{})
+	assert.ErrorIs(t, err, ErrPolicyNotFound)
+	assert.ErrorIs(t, svc.DeleteSMPolicy("unknown"), ErrPolicyNotFound)
+
+	udr.set(t, testSUPI, &SubscriberPolicies{SessionAMBR: &AMBR{Uplink: "fast", Downlink: "1 Gbps"}}, nil)
+	_, _, err = svc.CreateSMPolicy(context.Background(), testContextData())
+	assert.ErrorIs(t, err, ErrInvalidPolicyData)
+
+	udr.set(t, testSUPI, &SubscriberPolicies{PCCRules: []PolicyRule{{PCCRuleID: "r1"}}}, nil)
+	_, _, err = svc.CreateSMPolicy(context.Background(), testContextData())
+	assert.ErrorIs(t, err, ErrInvalidPolicyData)
+}
+
+func TestDeleteSMPolicy(t *testing.T) {
+	svc, _ := newTestPolicyService(t)
+
+	id, _, err := svc.CreateSMPolicy(context.Background(), testContextData())
+	require.NoError(t, err)
+	assert.Equal(t, 1, svc.GetStats()["sm_policy_associations"])
+
+	require.NoError(t, svc.DeleteSMPolicy(id))
+	_, err = svc.GetSMPolicy(id)
+	assert.ErrorIs(t, err, ErrPolicyNotFound)
+	assert.Equal(t, 0, svc.GetStats()["sm_policy_associations"])
+}
@@ -6,10 +6,17 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
+	"github.com/your-org/5g-network/common/buildinfo"
+	"github.com/your-org/5g-network/common/correlation"
 	"github.com/your-org/5g-network/common/metrics"
+	"github.com/your-org/5g-network/common/nrfpool"
+	"github.com/your-org/5g-network/common/oauth"
+	"github.com/your-org/5g-network/common/reload"
+	"github.com/your-org/5g-network/common/retry"
 	"github.com/your-org/5g-network/nf/smf/internal/client"
 	"github.com/your-org/5g-network/nf/smf/internal/config"
 	smfcontext "github.com/your-org/5g-network/nf/smf/internal/context"
@@ -17,7 +24,12 @@ import (
 	"github.com/your-org/5g-network/nf/smf/internal/server"
 	"github.com/your-org/5g-network/nf/smf/internal/service"
 	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
+)
+
+var (
+	Version   = "dev"
+	BuildTime = "unknown"
+	GitCommit = "unknown"
 )
 
 func main() {
@@ -25,8 +37,9 @@ func main() {
 	configPath := flag.String("config", "nf/smf/config/smf.yaml", "Path to configuration file")
 	flag.Parse()
 
-	// Initialize logger
-	logger := initLogger("info")
+	// Initialize logger, at the configured level once loaded
+	level := zap.NewAtomicLevel()
+	logger := initLogger(level)
 	defer func() {
 		if err := logger.Sync(); err != nil {
 			// Ignore sync errors on stdout/stderr
@@ -49,10 +62,44 @@ func main() {
 		zap.String("nrf_url", cfg.NRF.URL),
 	)
 
-	// Initialize metrics server
-	metricsServer := metrics.NewMetricsServer(9095, logger)
+	// Reload the log level and NRF heartbeat interval on SIGHUP
+	reloader := reload.New(*configPath, cfg, config.Load, []string{
+		"observability.log_level",
+		"nrf.heartbeat_interval",
+	}, logger)
+	if err := reloader.SetLogLevel(level, func(c *config.Config) string { return c.Observability.LogLevel }); err != nil {
+		logger.Fatal("Invalid configuration", zap.Error(err))
+	}
+	go reloader.Watch(context.Background())
+
+	// HTTP transport of the SBI clients, with mutual TLS when configured,
+	// retries of transient failures and the correlation ID of the request served
+	tlsTransport, err := cfg.SBI.ClientTLS.Transport()
+	if err != nil {
+		logger.Fatal("Invalid client TLS configuration", zap.Error(err))
+	}
+	sbiTransport := correlation.NewTransport(retry.NewTransport(tlsTransport, cfg.SBI.ClientRetry, logger), logger)
+
+	// Initialize NRF client
+	endpoints, err := nrfpool.New(context.Background(), cfg.NRF.URL, cfg.NRF.Options, logger)
+	if err != nil {
+		logger.Fatal("Failed to resolve NRF endpoints", zap.Error(err))
+	}
+	nrfClient := client.NewNRFClient(cfg, endpoints, sbiTransport, logger)
+
+	// Access tokens for requests to other NFs, from the primary NRF
+	tokens, err := oauth.NewTokenSource(cfg.SBI.ClientOAuth2, cfg.NRF.URL, nrfClient.NFInstanceID(), "SMF", sbiTransport, logger)
+	if err != nil {
+		logger.Fatal("Invalid client OAuth2 configuration", zap.Error(err))
+	}
+
+	// Initialize metrics server, on the port assigned to the SMF
+	metricsRegistry, err := metrics.NewRegistry("SMF", nrfClient.NFInstanceID())
+	if err != nil {
+		logger.Fatal("Failed to create metrics registry", zap.Error(err))
+	}
+	metricsServer := metrics.NewMetricsServer(metricsRegistry, logger)
 	go func() {
-		logger.Info("Starting metrics server on :9095")
 		if err := metricsServer.Start(); err != nil {
 			logger.Error("Metrics server error", zap.Error(err))
 		}
@@ -63,33 +110,41 @@ func main() {
 	metrics.SetServiceUp(true)
 	defer metrics.SetServiceUp(false)
 
-	// Initialize NRF client
-	nrfClient := client.NewNRFClient(cfg, logger)
-
 	// Register with NRF
 	if err := nrfClient.Register(); err != nil {
 		logger.Error("Failed to register with NRF (continuing anyway)", zap.Error(err))
 	}
 
 	// Start NRF heartbeat
-	go startNRFHeartbeat(nrfClient, cfg.NRF.HeartbeatInterval, logger)
+	go startNRFHeartbeat(nrfClient, reloader.Ticker(func(c *config.Config) time.Duration { return c.NRF.HeartbeatInterval }), logger)
 
-	// Initialize PFCP client for UPF communication
-	pfcpClient := n4.NewPFCPClient(
-		cfg.UPF.DefaultUPF.NodeID,
-		cfg.UPF.DefaultUPF.N4Address,
-		logger,
-	)
+	// Initialize SM context store. The file store keeps the SEIDs UPFs
+	// allocated alongside, so restored sessions can be audited.
+	var sessionStore smfcontext.SessionStore = smfcontext.NewMemoryStore()
+	var seidStore n4.SEIDStore
+	if cfg.SMF.SessionStore.Type == "file" {
+		sessionStore, err = smfcontext.NewFileStore(cfg.SMF.SessionStore.Path)
+		if err != nil {
+			logger.Fatal("Failed to open session store", zap.Error(err))
+		}
+		seidStore, err = n4.NewFileSEIDStore(filepath.Join(cfg.SMF.SessionStore.Path, "seids"))
+		if err != nil {
+			logger.Fatal("Failed to open SEID store", zap.Error(err))
+		}
+	}
 
-	// Establish PFCP association with UPF
-	if err := pfcpClient.AssociatePFCPSession(); err != nil {
-		logger.Error("Failed to establish PFCP association with UPF (continuing anyway)", zap.Error(err))
+	// Initialize PFCP client for the default UPF
+	pfcpClient, err := openPFCPClient(cfg, cfg.UPF.DefaultUPF.NodeID, cfg.UPF.DefaultUPF.N4Address, seidStore, logger)
+	if err != nil {
+		logger.Fatal("Failed to open N4 transport", zap.Error(err))
 	}
+	defer pfcpClient.Close()
 
 	// Initialize SMF context
-	smfContext := smfcontext.NewSMFContext(
+	smfContext := smfcontext.NewSMFContextWithStore(
 		cfg.UPF.DefaultUPF.NodeID,
 		cfg.UPF.DefaultUPF.N4Address,
+		sessionStore,
 	)
 
 	// Initialize session service
@@ -97,9 +152,53 @@ func main() {
 	if err != nil {
 		logger.Fatal("Failed to create session service", zap.Error(err))
 	}
+	defer sessionService.Close()
+
+	// Page idle UEs through the AMF on buffered downlink data
+	if cfg.AMF.URL != "" {
+		sessionService.SetAMFClient(client.NewAMFClient(cfg.AMF.URL, tokens.Transport(sbiTransport, "AMF"), logger))
+	}
+
+	// Obtain the subscribed session AMBR and default QoS from the UDM
+	if cfg.UDM.URL != "" {
+		sessionService.SetUDMClient(client.NewUDMClient(cfg.UDM.URL, tokens.Transport(sbiTransport, "UDM"), logger))
+	}
+
+	// Obtain session policy from the PCF
+	if cfg.PCF.URL != "" {
+		sessionService.SetPCFClient(client.NewPCFClient(cfg.PCF.URL, tokens.Transport(sbiTransport, "PCF"), logger))
+	}
+
+	// Select UPFs through NRF discovery, falling back to the default UPF
+	newPFCPClient := func(upf service.UPFInstance) (*n4.PFCPClient, error) {
+		return openPFCPClient(cfg, upf.NodeID, upf.N4Address, seidStore, logger)
+	}
+	if cfg.UPF.Selection.Enabled {
+		selector := service.NewUPFSelector(nrfClient, service.UPFInstance{
+			NodeID:    cfg.UPF.DefaultUPF.NodeID,
+			N4Address: cfg.UPF.DefaultUPF.N4Address,
+		}, cfg.UPF.Selection.CacheTTL, logger)
+		sessionService.SetUPFSelector(selector, newPFCPClient)
+	} else if len(cfg.UPF.LocalBreakout) > 0 {
+		// ULCL UPFs of local breakout DNNs
+		sessionService.SetPFCPClientFactory(newPFCPClient)
+	}
+
+	// Detect UPF failures through PFCP heartbeats
+	if cfg.UPF.Heartbeat.Interval > 0 {
+		go startUPFHeartbeat(sessionService, cfg.UPF.Heartbeat.Interval)
+	}
+
+	// Restore persisted sessions and re-sync them with the UPF
+	if _, err := sessionService.RestoreSessions(); err != nil {
+		logger.Error("Failed to restore sessions", zap.Error(err))
+	}
 
 	// Initialize HTTP server
-	smfServer := server.NewSMFServer(cfg, sessionService, logger)
+	smfServer := server.NewSMFServer(cfg, nrfClient.NFInstanceID(), sessionService, logger)
+	smfServer.SetBuildInfo(buildinfo.New("SMF", Version, GitCommit, BuildTime,
+		"nsmf-pdusession", "qos-monitoring", "indirect-forwarding", "session-persistence",
+		"upf-selection", "upf-failover", "local-breakout"))
 
 	// Start HTTP server in goroutine
 	serverErrors := make(chan error, 1)
@@ -138,19 +237,20 @@ func main() {
 			logger.Error("Error during server shutdown", zap.Error(err))
 		}
 
+		// Release the PFCP association with the default UPF; associations
+		// with selected UPFs are released when the session service closes
+		if err := pfcpClient.ReleaseAssociation(); err != nil {
+			logger.Error("Failed to release PFCP association", zap.Error(err))
+		}
+
 		logger.Info("SMF shutdown complete")
 	}
 }
 
-// initLogger initializes the logger
-func initLogger(level string) *zap.Logger {
-	var zapLevel zapcore.Level
-	if err := zapLevel.UnmarshalText([]byte(level)); err != nil {
-		zapLevel = zapcore.InfoLevel
-	}
-
+// initLogger initializes the logger, whose level can change at runtime
+func initLogger(level zap.AtomicLevel) *zap.Logger {
 	config := zap.Config{
-		Level:            zap.NewAtomicLevelAt(zapLevel),
+		Level:            level,
 		Development:      false,
 		Encoding:         "console",
 		EncoderConfig:    zap.NewDevelopmentEncoderConfig(),
@@ -166,9 +266,38 @@ func initLogger(level string) *zap.Logger {
 	return logger
 }
 
-// startNRFHeartbeat starts periodic NRF heartbeat
-func startNRFHeartbeat(nrfClient *client.NRFClient, interval time.Duration, logger *zap.Logger) {
-	ticker := time.NewTicker(interval)
+// openPFCPClient opens the N4 client towards a UPF over the configured
+// transport, loads the SEIDs persisted in seidStore, if any, and sets up the
+// PFCP association
+func openPFCPClient(cfg *config.Config, nodeID, n4Address string, seidStore n4.SEIDStore, logger *zap.Logger) (*n4.PFCPClient, error) {
+	pfcpClient := n4.NewPFCPClient(nodeID, n4Address, logger)
+	if cfg.UPF.DefaultUPF.Transport == "pfcp" {
+		var err error
+		pfcpClient, err = n4.DialPFCPClient(cfg.SBI.IPv4, nodeID, n4Address, logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+	pfcpClient.SetHeartbeatFailureThreshold(cfg.UPF.Heartbeat.MaxFailures)
+	if seidStore != nil {
+		if err := pfcpClient.SetSEIDStore(seidStore); err != nil {
+			pfcpClient.Close()
+			return nil, err
+		}
+	}
+
+	// Establish PFCP association with UPF
+	if err := pfcpClient.AssociatePFCPSession(); err != nil {
+		logger.Error("Failed to establish PFCP association with UPF (continuing anyway)",
+			zap.String("upf_node_id", nodeID),
+			zap.Error(err),
+		)
+	}
+	return pfcpClient, nil
+}
+
+// startNRFHeartbeat sends an NRF heartbeat on every tick
+func startNRFHeartbeat(nrfClient *client.NRFClient, ticker *time.Ticker, logger *zap.Logger) {
 	defer ticker.Stop()
 
 	for range ticker.C {
@@ -177,3 +306,13 @@ func startNRFHeartbeat(nrfClient *client.NRFClient, interval time.Duration, logg
 		}
 	}
 }
+
+// startUPFHeartbeat starts periodic PFCP heartbeats towards the UPFs
+func startUPFHeartbeat(sessionService *service.SessionService, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		sessionService.CheckUPFs()
+	}
+}
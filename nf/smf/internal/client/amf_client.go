@@ -0,0 +1,85 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// AMFClient handles Namf_Communication requests to the AMF
+type AMFClient struct {
+	baseURL    string
+	httpClient *http.Client
+	logger     *zap.Logger
+}
+
+// NewAMFClient creates a new AMF client
+func NewAMFClient(baseURL string, transport http.RoundTripper, logger *zap.Logger) *AMFClient {
+	return &AMFClient{
+		baseURL: baseURL,
+		httpClient: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: transport,
+		},
+		logger: logger,
+	}
+}
+
+// N1N2MessageTransferRequest is the N1N2MessageTransfer request body
+// (TS 29.518 6.1.6.2.24), reduced to the fields the AMF acts on
+type N1N2MessageTransferRequest struct {
+	PDUSessionID uint8  `json:"pduSessionId,omitempty"`
+	N2InfoClass  string `json:"n2InfoClass,omitempty"`
+}
+
+// N1N2MessageTransferResponse is the N1N2MessageTransfer response body
+type N1N2MessageTransferResponse struct {
+	Cause string `json:"cause"` // "N1_N2_TRANSFER_INITIATED" or "ATTEMPTING_TO_REACH_UE"
+}
+
+// TransferN1N2Message sends an N1N2MessageTransfer for a PDU session of the
+// UE, asking the AMF to page the UE when it is idle
+func (c *AMFClient) TransferN1N2Message(ctx context.Context, supi string, req *N1N2MessageTransferRequest) (*N1N2MessageTransferResponse, error) {
+	endpoint := fmt.Sprintf("%s/namf-comm/v1/ue-contexts/%s/n1-n2-messages", c.baseURL, url.PathEscape(supi))
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("AMF returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result N1N2MessageTransferResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	c.logger.Debug("N1N2 message transfer accepted",
+		zap.String("supi", supi),
+		zap.Uint8("pdu_session_id", req.PDUSessionID),
+		zap.String("cause", result.Cause),
+	)
+	return &result, nil
+}
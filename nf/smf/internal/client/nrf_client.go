@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"time"
 
+	"github.com/your-org/5g-network/common/nrfpool"
 	"github.com/your-org/5g-network/nf/smf/internal/config"
 	"go.uber.org/zap"
 )
@@ -15,23 +17,36 @@ import (
 // NRFClient handles communication with NRF
 type NRFClient struct {
 	config       *config.Config
+	endpoints    *nrfpool.Pool
 	httpClient   *http.Client
 	logger       *zap.Logger
 	nfInstanceID string
 }
 
-// NewNRFClient creates a new NRF client
-func NewNRFClient(cfg *config.Config, logger *zap.Logger) *NRFClient {
+// NewNRFClient creates a new NRF client. The SMF registers with the
+// configured instance ID, or a generated one when none is set.
+func NewNRFClient(cfg *config.Config, endpoints *nrfpool.Pool, transport http.RoundTripper, logger *zap.Logger) *NRFClient {
+	nfInstanceID := cfg.SMF.InstanceID
+	if nfInstanceID == "" {
+		nfInstanceID = generateNFInstanceID("smf")
+	}
 	return &NRFClient{
-		config: cfg,
+		config:    cfg,
+		endpoints: endpoints,
 		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:   10 * time.Second,
+			Transport: transport,
 		},
 		logger:       logger,
-		nfInstanceID: generateNFInstanceID("smf"),
+		nfInstanceID: nfInstanceID,
 	}
 }
 
+// NFInstanceID returns the NF instance ID the SMF registers with
+func (c *NRFClient) NFInstanceID() string {
+	return c.nfInstanceID
+}
+
 // NFProfile represents SMF's NF profile for registration
 type NFProfile struct {
 	NFInstanceID   string      `json:"nfInstanceId"`
@@ -40,10 +55,23 @@ type NFProfile struct {
 	PLMNID         PLMNID      `json:"plmnId"`
 	SNSSAI         []SNSSAI    `json:"sNssai"`
 	IPv4Addresses  []string    `json:"ipv4Addresses"`
+	SMFInfo        *SMFInfo    `json:"smfInfo,omitempty"`
 	NFServices     []NFService `json:"nfServices"`
 	HeartBeatTimer int         `json:"heartBeatTimer"`
 }
 
+// SMFInfo advertises the DNNs the SMF serves on each S-NSSAI, matched by
+// AMFs discovering an SMF for a PDU session
+type SMFInfo struct {
+	SNSSAIInfoList []SNSSAIInfo `json:"sNssaiUpfInfoList"`
+}
+
+// SNSSAIInfo lists the DNNs served on an S-NSSAI
+type SNSSAIInfo struct {
+	SNSSAI  SNSSAI   `json:"sNssai"`
+	DNNList []string `json:"dnnList"`
+}
+
 // PLMNID represents PLMN identifier
 type PLMNID struct {
 	MCC string `json:"mcc"`
@@ -74,15 +102,23 @@ type NFServiceVersion struct {
 
 // Register registers SMF with NRF
 func (c *NRFClient) Register() error {
-	url := fmt.Sprintf("%s/nnrf-nfm/v1/nf-instances/%s", c.config.NRF.URL, c.nfInstanceID)
+	var dnns []string
+	for _, d := range c.config.SMF.SupportedDNN {
+		dnns = append(dnns, d.DNN)
+	}
 
-	// Build SNSSAI list
+	// Build SNSSAI list, every supported DNN being served on each S-NSSAI
 	var snssai []SNSSAI
+	smfInfo := &SMFInfo{}
 	for _, s := range c.config.SMF.SupportedSNSSAI {
 		snssai = append(snssai, SNSSAI{
 			SST: s.SST,
 			SD:  s.SD,
 		})
+		smfInfo.SNSSAIInfoList = append(smfInfo.SNSSAIInfoList, SNSSAIInfo{
+			SNSSAI:  SNSSAI{SST: s.SST, SD: s.SD},
+			DNNList: dnns,
+		})
 	}
 
 	profile := NFProfile{
@@ -95,6 +131,7 @@ func (c *NRFClient) Register() error {
 		},
 		SNSSAI:         snssai,
 		IPv4Addresses:  []string{c.config.SBI.IPv4},
+		SMFInfo:        smfInfo,
 		HeartBeatTimer: 30,
 		NFServices: []NFService{
 			{
@@ -118,18 +155,20 @@ func (c *NRFClient) Register() error {
 		return fmt.Errorf("failed to marshal NF profile: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPut, url, bytes.NewBuffer(body))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-	req.Header.Set("Content-Type", "application/json")
-
 	c.logger.Info("Registering SMF with NRF",
-		zap.String("nrf_url", c.config.NRF.URL),
+		zap.String("nrf_url", c.endpoints.Current()),
 		zap.String("nf_instance_id", c.nfInstanceID),
 	)
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.endpoints.Do(c.httpClient, func(baseURL string) (*http.Request, error) {
+		url := fmt.Sprintf("%s/nnrf-nfm/v1/nf-instances/%s", baseURL, c.nfInstanceID)
+		req, err := http.NewRequest(http.MethodPut, url, bytes.NewBuffer(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
 	if err != nil {
 		return fmt.Errorf("failed to send registration request: %w", err)
 	}
@@ -146,14 +185,14 @@ func (c *NRFClient) Register() error {
 
 // SendHeartbeat sends heartbeat to NRF
 func (c *NRFClient) SendHeartbeat() error {
-	url := fmt.Sprintf("%s/nnrf-nfm/v1/nf-instances/%s/heartbeat", c.config.NRF.URL, c.nfInstanceID)
-
-	req, err := http.NewRequest(http.MethodPatch, url, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create heartbeat request: %w", err)
-	}
-
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.endpoints.Do(c.httpClient, func(baseURL string) (*http.Request, error) {
+		url := fmt.Sprintf("%s/nnrf-nfm/v1/nf-instances/%s/heartbeat", baseURL, c.nfInstanceID)
+		req, err := http.NewRequest(http.MethodPatch, url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create heartbeat request: %w", err)
+		}
+		return req, nil
+	})
 	if err != nil {
 		return fmt.Errorf("failed to send heartbeat: %w", err)
 	}
@@ -169,16 +208,16 @@ func (c *NRFClient) SendHeartbeat() error {
 
 // Deregister deregisters SMF from NRF
 func (c *NRFClient) Deregister() error {
-	url := fmt.Sprintf("%s/nnrf-nfm/v1/nf-instances/%s", c.config.NRF.URL, c.nfInstanceID)
-
-	req, err := http.NewRequest(http.MethodDelete, url, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create deregistration request: %w", err)
-	}
-
 	c.logger.Info("Deregistering SMF from NRF")
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.endpoints.Do(c.httpClient, func(baseURL string) (*http.Request, error) {
+		url := fmt.Sprintf("%s/nnrf-nfm/v1/nf-instances/%s", baseURL, c.nfInstanceID)
+		req, err := http.NewRequest(http.MethodDelete, url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create deregistration request: %w", err)
+		}
+		return req, nil
+	})
 	if err != nil {
 		return fmt.Errorf("failed to send deregistration request: %w", err)
 	}
@@ -192,6 +231,64 @@ func (c *NRFClient) Deregister() error {
 	return nil
 }
 
+// UPFProfile represents the parts of a discovered UPF profile used for UPF
+// selection
+type UPFProfile struct {
+	NFInstanceID  string   `json:"nfInstanceId"`
+	NFStatus      string   `json:"nfStatus"`
+	FQDN          string   `json:"fqdn,omitempty"` // PFCP Node ID
+	IPv4Addresses []string `json:"ipv4Addresses"`  // N4 (PFCP) endpoints
+	Priority      int      `json:"priority,omitempty"`
+	Capacity      int      `json:"capacity,omitempty"`
+	Load          int      `json:"load,omitempty"`
+}
+
+// DiscoverUPFs queries the NRF for UPFs serving dnn on snssai
+// (TS 29.510 5.3.2.2)
+func (c *NRFClient) DiscoverUPFs(dnn string, snssai SNSSAI) ([]UPFProfile, error) {
+	snssais, err := json.Marshal([]SNSSAI{snssai})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal S-NSSAI: %w", err)
+	}
+
+	query := url.Values{}
+	query.Set("target-nf-type", "UPF")
+	query.Set("requester-nf-type", "SMF")
+	query.Set("dnn", dnn)
+	query.Set("snssais", string(snssais))
+
+	resp, err := c.endpoints.Do(c.httpClient, func(baseURL string) (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodGet, baseURL+"/nnrf-disc/v1/nf-instances?"+query.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create discovery request: %w", err)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to send discovery request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("UPF discovery failed with status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var result struct {
+		NFInstances []UPFProfile `json:"nfInstances"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode discovery response: %w", err)
+	}
+
+	c.logger.Debug("UPFs discovered",
+		zap.String("dnn", dnn),
+		zap.Int("sst", snssai.SST),
+		zap.Int("count", len(result.NFInstances)),
+	)
+	return result.NFInstances, nil
+}
+
 // generateNFInstanceID generates a unique NF instance ID
 func generateNFInstanceID(nfType string) string {
 	return fmt.Sprintf("%s-%d", nfType, time.Now().UnixNano())
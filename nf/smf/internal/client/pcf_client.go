@@ -0,0 +1,198 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// PCFClient handles Npcf_SMPolicyControl requests to the PCF
+type PCFClient struct {
+	baseURL    string
+	httpClient *http.Client
+	logger     *zap.Logger
+}
+
+// NewPCFClient creates a new PCF client
+func NewPCFClient(baseURL string, transport http.RoundTripper, logger *zap.Logger) *PCFClient {
+	return &PCFClient{
+		baseURL: baseURL,
+		httpClient: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: transport,
+		},
+		logger: logger,
+	}
+}
+
+// AMBR is an aggregate maximum bit rate, e.g. "1 Gbps"
+type AMBR struct {
+	Uplink   string `json:"uplink"`
+	Downlink string `json:"downlink"`
+}
+
+// ARP represents an Allocation and Retention Priority
+type ARP struct {
+	PriorityLevel uint8  `json:"priorityLevel"`
+	PreemptCap    string `json:"preemptCap,omitempty"`
+	PreemptVuln   string `json:"preemptVuln,omitempty"`
+}
+
+// DefaultQoS is the QoS of the default QoS flow
+type DefaultQoS struct {
+	FiveQI        uint8 `json:"5qi"`
+	ARP           ARP   `json:"arp"`
+	PriorityLevel uint8 `json:"priorityLevel,omitempty"`
+}
+
+// PolicySNSSAI is the S-NSSAI of a PDU session in policy requests
+type PolicySNSSAI struct {
+	SST int    `json:"sst"`
+	SD  string `json:"sd,omitempty"`
+}
+
+// SMPolicyContextData is the SM policy association request (TS 29.512
+// 5.6.2.3), reduced to the fields the PCF acts on
+type SMPolicyContextData struct {
+	SUPI           string       `json:"supi"`
+	PDUSessionID   uint8        `json:"pduSessionId"`
+	PDUSessionType string       `json:"pduSessionType,omitempty"`
+	DNN            string       `json:"dnn"`
+	SliceInfo      PolicySNSSAI `json:"sliceInfo"`
+	IPv4Address    string       `json:"ipv4Address,omitempty"`
+	SubsSessAMBR   *AMBR        `json:"subsSessAmbr,omitempty"`
+	SubsDefQoS     *DefaultQoS  `json:"subsDefQos,omitempty"`
+}
+
+// SessionRule carries the authorized session AMBR and default QoS
+type SessionRule struct {
+	SessRuleID   string      `json:"sessRuleId"`
+	AuthSessAMBR *AMBR       `json:"authSessAmbr,omitempty"`
+	AuthDefQoS   *DefaultQoS `json:"authDefQos,omitempty"`
+}
+
+// FlowInformation describes a service data flow
+type FlowInformation struct {
+	FlowDescription string `json:"flowDescription,omitempty"` // IPFilterRule
+	FlowDirection   string `json:"flowDirection,omitempty"`   // "DOWNLINK", "UPLINK" or "BIDIRECTIONAL"
+}
+
+// PCCRule binds service data flows to QoS data
+type PCCRule struct {
+	PCCRuleID  string            `json:"pccRuleId"`
+	FlowInfos  []FlowInformation `json:"flowInfos,omitempty"`
+	Precedence uint32            `json:"precedence,omitempty"`
+	RefQoSData []string          `json:"refQosData,omitempty"`
+}
+
+// DownlinkFlowDescription returns the first flow description of the rule
+// that applies to downlink traffic, empty if there is none
+func (r *PCCRule) DownlinkFlowDescription() string {
+	for _, info := range r.FlowInfos {
+		if info.FlowDirection == "UPLINK" {
+			continue
+		}
+		if info.FlowDescription != "" {
+			return info.FlowDescription
+		}
+	}
+	return ""
+}
+
+// QoSData is the QoS of PCC rules
+type QoSData struct {
+	QoSID   string `json:"qosId"`
+	FiveQI  uint8  `json:"5qi"`
+	MaxBRUl string `json:"maxbrUl,omitempty"`
+	MaxBRDl string `json:"maxbrDl,omitempty"`
+	GBRUl   string `json:"gbrUl,omitempty"`
+	GBRDl   string `json:"gbrDl,omitempty"`
+	ARP     *ARP   `json:"arp,omitempty"`
+}
+
+// SMPolicyDecision is the policy the PCF decided for a PDU session (TS
+// 29.512 5.6.2.4)
+type SMPolicyDecision struct {
+	SessRules map[string]*SessionRule `json:"sessRules,omitempty"`
+	PCCRules  map[string]*PCCRule     `json:"pccRules,omitempty"`
+	QoSDecs   map[string]*QoSData     `json:"qosDecs,omitempty"`
+}
+
+// CreateSMPolicy creates the SM policy association of a PDU session and
+// returns its ID and the policy decision
+func (c *PCFClient) CreateSMPolicy(ctx context.Context, data *SMPolicyContextData) (string, *SMPolicyDecision, error) {
+	endpoint := fmt.Sprintf("%s/npcf-smpolicycontrol/v1/sm-policies", c.baseURL)
+
+	body, err := json.Marshal(data)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		respBody, _ := io.ReadAll(resp.Body)
+		return "", nil, fmt.Errorf("PCF returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	// The association ID is the last segment of the Location header
+	location := resp.Header.Get("Location")
+	id := location[strings.LastIndex(location, "/")+1:]
+	if id == "" {
+		return "", nil, fmt.Errorf("PCF returned no SM policy location")
+	}
+
+	var decision SMPolicyDecision
+	if err := json.NewDecoder(resp.Body).Decode(&decision); err != nil {
+		return "", nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	c.logger.Debug("SM policy association created",
+		zap.String("supi", data.SUPI),
+		zap.Uint8("pdu_session_id", data.PDUSessionID),
+		zap.String("sm_policy_id", id),
+	)
+	return id, &decision, nil
+}
+
+// DeleteSMPolicy deletes an SM policy association
+func (c *PCFClient) DeleteSMPolicy(ctx context.Context, id string) error {
+	endpoint := fmt.Sprintf("%s/npcf-smpolicycontrol/v1/sm-policies/%s/delete", c.baseURL, url.PathEscape(id))
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("PCF returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	c.logger.Debug("SM policy association deleted", zap.String("sm_policy_id", id))
+	return nil
+}
@@ -0,0 +1,102 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrSubscriptionDataNotFound is returned when the UDM has no SM
+// subscription data for the subscriber
+var ErrSubscriptionDataNotFound = errors.New("SM subscription data not found")
+
+// UDMClient handles Nudm_SDM requests to the UDM
+type UDMClient struct {
+	baseURL    string
+	httpClient *http.Client
+	logger     *zap.Logger
+}
+
+// NewUDMClient creates a new UDM client
+func NewUDMClient(baseURL string, transport http.RoundTripper, logger *zap.Logger) *UDMClient {
+	return &UDMClient{
+		baseURL: baseURL,
+		httpClient: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: transport,
+		},
+		logger: logger,
+	}
+}
+
+// SubscribedQoSProfile is the subscribed default QoS of a DNN
+type SubscribedQoSProfile struct {
+	FiveQI        uint8 `json:"5qi"`
+	ARP           *ARP  `json:"arp,omitempty"`
+	PriorityLevel uint8 `json:"priorityLevel,omitempty"`
+}
+
+// SSCModes are the subscribed SSC modes of a DNN, e.g. "SSC_MODE_1"
+type SSCModes struct {
+	DefaultSSCMode  string   `json:"defaultSscMode"`
+	AllowedSSCModes []string `json:"allowedSscModes,omitempty"`
+}
+
+// DNNConfiguration is the subscription of a DNN (TS 29.503 6.1.6.2.17),
+// reduced to the fields the SMF acts on
+type DNNConfiguration struct {
+	SSCModes    *SSCModes             `json:"sscModes,omitempty"`
+	SessionAMBR *AMBR                 `json:"sessionAmbr,omitempty"`
+	QoSProfile  *SubscribedQoSProfile `json:"5gQosProfile,omitempty"`
+}
+
+// SMSubscriptionData is the session management subscription data of a
+// subscriber (TS 29.503 6.1.6.2.10)
+type SMSubscriptionData struct {
+	SingleNSSAI       PolicySNSSAI                 `json:"singleNssai"`
+	DNNConfigurations map[string]*DNNConfiguration `json:"dnnConfigurations,omitempty"`
+}
+
+// GetSMData retrieves the SM subscription data of a subscriber for a DNN
+func (c *UDMClient) GetSMData(ctx context.Context, supi, dnn string) (*SMSubscriptionData, error) {
+	endpoint := fmt.Sprintf("%s/nudm-sdm/v1/supi/%s/sm-data?dnn=%s",
+		c.baseURL, url.PathEscape(supi), url.QueryEscape(dnn))
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrSubscriptionDataNotFound, supi)
+	}
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("UDM returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var data SMSubscriptionData
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	c.logger.Debug("SM subscription data retrieved",
+		zap.String("supi", supi),
+		zap.String("dnn", dnn),
+	)
+	return &data, nil
+}
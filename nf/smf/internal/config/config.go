@@ -1,9 +1,16 @@
 package config
 
 import (
+	"fmt"
+	"net"
 	"os"
 	"time"
 
+	"github.com/your-org/5g-network/common/nrfpool"
+	"github.com/your-org/5g-network/common/oauth"
+	"github.com/your-org/5g-network/common/retry"
+	"github.com/your-org/5g-network/common/sbi"
+	"github.com/your-org/5g-network/common/tlsconfig"
 	"gopkg.in/yaml.v3"
 )
 
@@ -12,6 +19,7 @@ type Config struct {
 	SBI           SBIConfig           `yaml:"sbi"`
 	NRF           NRFConfig           `yaml:"nrf"`
 	UDM           UDMConfig           `yaml:"udm"`
+	AMF           AMFConfig           `yaml:"amf"`
 	PCF           PCFConfig           `yaml:"pcf"`
 	SMF           SMFConfig           `yaml:"smf"`
 	UPF           UPFConfig           `yaml:"upf"`
@@ -20,10 +28,14 @@ type Config struct {
 
 // SBIConfig represents Service Based Interface configuration
 type SBIConfig struct {
-	Scheme string    `yaml:"scheme"`
-	IPv4   string    `yaml:"ipv4"`
-	Port   int       `yaml:"port"`
-	TLS    TLSConfig `yaml:"tls"`
+	Scheme       string                 `yaml:"scheme"`
+	IPv4         string                 `yaml:"ipv4"`
+	Port         int                    `yaml:"port"`
+	TLS          TLSConfig              `yaml:"tls"`
+	ClientTLS    tlsconfig.ClientConfig `yaml:"client_tls"`   // TLS towards the SBIs of other NFs
+	ClientRetry  retry.Policy           `yaml:"client_retry"` // Retries of idempotent requests to other NFs
+	OAuth2       oauth.Config           `yaml:"oauth2"`
+	ClientOAuth2 oauth.ClientConfig     `yaml:"client_oauth2"` // Access tokens for requests to other NFs
 }
 
 // TLSConfig represents TLS configuration
@@ -31,12 +43,17 @@ type TLSConfig struct {
 	Enabled bool   `yaml:"enabled"`
 	Cert    string `yaml:"cert"`
 	Key     string `yaml:"key"`
+
+	tlsconfig.Options `yaml:",inline"`
 }
 
 // NRFConfig represents NRF client configuration
 type NRFConfig struct {
 	URL               string        `yaml:"url"`
 	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"`
+
+	// Secondary NRFs and DNS SRV discovery for failover
+	nrfpool.Options `yaml:",inline"`
 }
 
 // UDMConfig represents UDM client configuration
@@ -44,23 +61,65 @@ type UDMConfig struct {
 	URL string `yaml:"url"`
 }
 
-// PCFConfig represents PCF client configuration
+// AMFConfig represents AMF client configuration. Downlink data for idle UEs
+// is only buffered, without paging, when the URL is empty.
+type AMFConfig struct {
+	URL string `yaml:"url"`
+}
+
+// PCFConfig represents PCF client configuration. Sessions get the local
+// policy, smf.default_session_ambr and 5QI 9, when the URL is empty.
 type PCFConfig struct {
 	URL string `yaml:"url"`
 }
 
 // SMFConfig represents SMF-specific configuration
 type SMFConfig struct {
-	Name     string `yaml:"name"`
-	SetID    string `yaml:"set_id"`
-	RegionID string `yaml:"region_id"`
-	PLMN     PLMN   `yaml:"plmn"`
+	Name       string `yaml:"name"`
+	InstanceID string `yaml:"instance_id"` // NF instance ID, generated when empty
+	SetID      string `yaml:"set_id"`
+	RegionID   string `yaml:"region_id"`
+	PLMN       PLMN   `yaml:"plmn"`
 
 	SupportedSNSSAI []SNSSAI `yaml:"supported_snssai"`
 	SupportedDNN    []DNN    `yaml:"supported_dnn"`
 
 	UESubnet           UESubnet `yaml:"ue_subnet"`
 	DefaultSessionAMBR AMBR     `yaml:"default_session_ambr"`
+
+	QoSMonitoring  QoSMonitoringConfig  `yaml:"qos_monitoring"`
+	UsageReporting UsageReportingConfig `yaml:"usage_reporting"`
+	Handover       HandoverConfig       `yaml:"handover"`
+	SessionStore   SessionStoreConfig   `yaml:"session_store"`
+}
+
+// SessionStoreConfig represents SM context persistence configuration
+type SessionStoreConfig struct {
+	Type string `yaml:"type"` // "memory" (default) or "file"
+	Path string `yaml:"path"` // Directory for the file store
+}
+
+// HandoverConfig represents N2 handover configuration
+type HandoverConfig struct {
+	IndirectForwardingTimeout time.Duration `yaml:"indirect_forwarding_timeout"`
+}
+
+// QoSMonitoringConfig represents QoS flow packet delay monitoring configuration
+type QoSMonitoringConfig struct {
+	Enabled            bool          `yaml:"enabled"`
+	UplinkThreshold    time.Duration `yaml:"uplink_threshold"`
+	DownlinkThreshold  time.Duration `yaml:"downlink_threshold"`
+	RoundTripThreshold time.Duration `yaml:"round_trip_threshold"`
+	MinimumWaitTime    time.Duration `yaml:"minimum_wait_time"`
+}
+
+// UsageReportingConfig represents the usage the UPF measures and reports
+// for every PDU session
+type UsageReportingConfig struct {
+	Enabled         bool          `yaml:"enabled"`
+	VolumeThreshold uint64        `yaml:"volume_threshold"` // Bytes
+	TimeThreshold   time.Duration `yaml:"time_threshold"`
+	Period          time.Duration `yaml:"period"`
 }
 
 // PLMN represents Public Land Mobile Network
@@ -89,8 +148,9 @@ type DNSConfig struct {
 
 // UESubnet represents UE IP address pool
 type UESubnet struct {
-	IPv4 string `yaml:"ipv4"`
-	IPv6 string `yaml:"ipv6"`
+	IPv4             string `yaml:"ipv4"`
+	IPv6             string `yaml:"ipv6"`
+	IPv6PrefixLength int    `yaml:"ipv6_prefix_length"` // Delegated per UE, /64 when zero
 }
 
 // AMBR represents Aggregate Maximum Bit Rate
@@ -101,13 +161,52 @@ type AMBR struct {
 
 // UPFConfig represents UPF configuration
 type UPFConfig struct {
-	DefaultUPF DefaultUPF `yaml:"default_upf"`
+	DefaultUPF DefaultUPF         `yaml:"default_upf"`
+	Selection  UPFSelectionConfig `yaml:"selection"`
+	Heartbeat  UPFHeartbeatConfig `yaml:"heartbeat"`
+
+	// Uplink classifier UPFs inserted in sessions of a DNN to break out
+	// traffic to local subnets
+	LocalBreakout []LocalBreakoutConfig `yaml:"local_breakout"`
+}
+
+// LocalBreakoutConfig represents local breakout of a DNN through an uplink
+// classifier (ULCL) UPF (TS 23.501 5.6.4.2). Uplink traffic to Subnets leaves
+// through the ULCL UPF to the local DN; other traffic is tunnelled over N9 to
+// the session anchor.
+type LocalBreakoutConfig struct {
+	DNN     string   `yaml:"dnn"`
+	ULCLUPF ULCLUPF  `yaml:"ulcl_upf"`
+	LocalDN string   `yaml:"local_dn"` // Network instance of the local DN, the DNN when empty
+	Subnets []string `yaml:"subnets"`  // Destinations routed locally, in CIDR notation
+}
+
+// ULCLUPF identifies the UPF acting as uplink classifier
+type ULCLUPF struct {
+	NodeID    string `yaml:"node_id"`
+	N4Address string `yaml:"n4_address"`
+}
+
+// UPFHeartbeatConfig represents PFCP heartbeat failure detection towards the
+// UPFs. A UPF is declared down after MaxFailures consecutive unanswered
+// heartbeats.
+type UPFHeartbeatConfig struct {
+	Interval    time.Duration `yaml:"interval"`     // Disabled when zero
+	MaxFailures int           `yaml:"max_failures"` // 3 when zero
+}
+
+// UPFSelectionConfig represents NRF-based UPF selection. The default UPF is
+// used when selection is disabled or discovery finds no UPF.
+type UPFSelectionConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	CacheTTL time.Duration `yaml:"cache_ttl"` // Discovery results reuse, 30s when zero
 }
 
 // DefaultUPF represents static UPF configuration
 type DefaultUPF struct {
 	NodeID    string `yaml:"node_id"`
 	N4Address string `yaml:"n4_address"`
+	Transport string `yaml:"transport"` // "simulated" (default) or "pfcp"
 }
 
 // ObservabilityConfig represents observability configuration
@@ -141,9 +240,101 @@ func Load(path string) (*Config, error) {
 		return nil, err
 	}
 
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
 	return &cfg, nil
 }
 
+// Validate validates the configuration
+func (c *Config) Validate() error {
+	if c.SBI.TLS.Enabled {
+		if err := c.SBI.TLS.Validate(); err != nil {
+			return fmt.Errorf("invalid sbi.tls: %w", err)
+		}
+	}
+
+	if err := c.SBI.ClientTLS.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.client_tls: %w", err)
+	}
+
+	if err := c.SBI.ClientRetry.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.client_retry: %w", err)
+	}
+
+	if err := c.NRF.Validate(); err != nil {
+		return fmt.Errorf("invalid nrf: %w", err)
+	}
+
+	if ambr := c.SMF.DefaultSessionAMBR; ambr.Uplink != "" {
+		if _, err := sbi.ParseBitRate(ambr.Uplink); err != nil {
+			return fmt.Errorf("invalid smf.default_session_ambr.uplink: %w", err)
+		}
+	}
+	if ambr := c.SMF.DefaultSessionAMBR; ambr.Downlink != "" {
+		if _, err := sbi.ParseBitRate(ambr.Downlink); err != nil {
+			return fmt.Errorf("invalid smf.default_session_ambr.downlink: %w", err)
+		}
+	}
+
+	switch c.SMF.SessionStore.Type {
+	case "", "memory":
+	case "file":
+		if c.SMF.SessionStore.Path == "" {
+			return fmt.Errorf("smf.session_store.path is required for the file store")
+		}
+	default:
+		return fmt.Errorf("invalid smf.session_store.type: %s", c.SMF.SessionStore.Type)
+	}
+
+	switch c.UPF.DefaultUPF.Transport {
+	case "", "simulated", "pfcp":
+	default:
+		return fmt.Errorf("invalid upf.default_upf.transport: %s", c.UPF.DefaultUPF.Transport)
+	}
+
+	if err := c.SBI.OAuth2.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.oauth2: %w", err)
+	}
+
+	if err := c.SBI.ClientOAuth2.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.client_oauth2: %w", err)
+	}
+
+	dnns := make(map[string]bool, len(c.UPF.LocalBreakout))
+	for i, breakout := range c.UPF.LocalBreakout {
+		if err := breakout.Validate(); err != nil {
+			return fmt.Errorf("invalid upf.local_breakout[%d]: %w", i, err)
+		}
+		if dnns[breakout.DNN] {
+			return fmt.Errorf("invalid upf.local_breakout[%d]: duplicate dnn %s", i, breakout.DNN)
+		}
+		dnns[breakout.DNN] = true
+	}
+
+	return nil
+}
+
+// Validate validates the local breakout of a DNN
+func (c *LocalBreakoutConfig) Validate() error {
+	if c.DNN == "" {
+		return fmt.Errorf("dnn is required")
+	}
+	if c.ULCLUPF.NodeID == "" || c.ULCLUPF.N4Address == "" {
+		return fmt.Errorf("ulcl_upf.node_id and ulcl_upf.n4_address are required")
+	}
+	if len(c.Subnets) == 0 {
+		return fmt.Errorf("subnets are required")
+	}
+	for _, subnet := range c.Subnets {
+		if _, _, err := net.ParseCIDR(subnet); err != nil {
+			return fmt.Errorf("invalid subnet %q: %w", subnet, err)
+		}
+	}
+	return nil
+}
+
 // GetSBIURI returns the full SBI URI
 func (c *Config) GetSBIURI() string {
 	return c.SBI.Scheme + "://" + c.SBI.IPv4 + ":" + string(rune(c.SBI.Port))
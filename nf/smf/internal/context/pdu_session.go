@@ -1,6 +1,8 @@
 package context
 
 import (
+	"fmt"
+	"sort"
 	"sync"
 	"time"
 )
@@ -27,13 +29,23 @@ const (
 	PDUSessionStateReleased      PDUSessionState = "RELEASED"
 )
 
+// UpCnxState represents the user plane connection state of a PDU session
+// (TS 29.502 6.1.6.3.2)
+type UpCnxState string
+
+const (
+	UpCnxStateActivated   UpCnxState = "ACTIVATED"
+	UpCnxStateDeactivated UpCnxState = "DEACTIVATED"
+	UpCnxStateActivating  UpCnxState = "ACTIVATING"
+)
+
 // SSCMode represents Session and Service Continuity mode
 type SSCMode int
 
 const (
-	SSCMode1 SSCMode = 1 // Connection stability
-	SSCMode2 SSCMode = 2 // Connection relocation
-	SSCMode3 SSCMode = 3 // Connection termination and re-establishment
+	SSCMode1 SSCMode = 1 // Anchor kept for the session lifetime
+	SSCMode2 SSCMode = 2 // Anchor change, break before make
+	SSCMode3 SSCMode = 3 // Anchor change, make before break
 )
 
 // SNSSAI represents Single Network Slice Selection Assistance Information
@@ -53,6 +65,40 @@ type QoSFlow struct {
 	GBR       *BitRate          `json:"gbr,omitempty"` // Guaranteed Bit Rate (for GBR flows)
 	MBR       *BitRate          `json:"mbr,omitempty"` // Maximum Bit Rate (for GBR flows)
 	CreatedAt time.Time         `json:"createdAt"`
+
+	// Downlink packet filter of the flow, an IPFilterRule such as "permit
+	// out udp from 198.51.100.0/24 to assigned". Empty for a flow that
+	// carries all traffic of the session.
+	SDFFilter string `json:"sdfFilter,omitempty"`
+
+	// QoS Monitoring
+	QoSMonitoringEnabled bool         `json:"qosMonitoringEnabled"`
+	PacketDelay          *PacketDelay `json:"packetDelay,omitempty"` // Last reported measurement
+}
+
+// PacketDelay represents a QoS flow packet delay measurement reported by the UPF
+type PacketDelay struct {
+	Uplink     time.Duration `json:"uplink"`
+	Downlink   time.Duration `json:"downlink"`
+	RoundTrip  time.Duration `json:"roundTrip"`
+	ReportedAt time.Time     `json:"reportedAt"`
+}
+
+// IsGBR reports whether the flow is a GBR flow, either by its bit rates or
+// by its standardized 5QI (TS 23.501 Table 5.7.4-1)
+func (f *QoSFlow) IsGBR() bool {
+	if f.GBR != nil {
+		return true
+	}
+
+	switch {
+	case f.FiveQI >= 1 && f.FiveQI <= 4,
+		f.FiveQI >= 65 && f.FiveQI <= 67,
+		f.FiveQI >= 71 && f.FiveQI <= 76,
+		f.FiveQI >= 82 && f.FiveQI <= 85:
+		return true
+	}
+	return false
 }
 
 // BitRate represents uplink and downlink bit rates
@@ -61,6 +107,33 @@ type BitRate struct {
 	Downlink uint64 `json:"downlink"` // bps
 }
 
+// ForwardingTunnel represents an indirect data forwarding tunnel through the
+// UPF from the source gNB to the target gNB
+type ForwardingTunnel struct {
+	PDRID         uint16    `json:"pdrId"`
+	FARID         uint16    `json:"farId"`
+	UPFTEID       uint32    `json:"upfTeid"` // Source gNB sends forwarded data here
+	UPFN3Address  string    `json:"upfN3Address"`
+	TargetTEID    uint32    `json:"targetTeid"`
+	TargetAddress string    `json:"targetAddress"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// ULCL represents an uplink classifier UPF inserted between the gNB and the
+// anchor of a session for local breakout (TS 23.501 5.6.4.2). N3 terminates
+// on the ULCL UPF, which reaches the anchor over N9.
+type ULCL struct {
+	SEID         uint64 `json:"seid"` // PFCP session on the ULCL UPF
+	UPFNodeID    string `json:"upfNodeId"`
+	UPFN4Address string `json:"upfN4Address"`
+	N9TEID       uint32 `json:"n9Teid"` // ULCL F-TEID for downlink from the anchor
+	N9Address    string `json:"n9Address"`
+
+	// Anchor F-TEID for uplink from the ULCL
+	AnchorTEID    uint32 `json:"anchorTeid"`
+	AnchorAddress string `json:"anchorAddress"`
+}
+
 // PDUSession represents a PDU session
 type PDUSession struct {
 	mu sync.RWMutex
@@ -75,6 +148,7 @@ type PDUSession struct {
 	PDUSessionType PDUSessionType  `json:"pduSessionType"`
 	SSCMode        SSCMode         `json:"sscMode"`
 	State          PDUSessionState `json:"state"`
+	UpCnxState     UpCnxState      `json:"upCnxState,omitempty"` // Empty means ACTIVATED
 
 	// UE IP Address
 	UEIPv4Address string `json:"ueIpv4Address,omitempty"`
@@ -83,18 +157,32 @@ type PDUSession struct {
 	// Session AMBR
 	SessionAMBR BitRate `json:"sessionAmbr"`
 
+	// SM policy association with the PCF, empty under local policy
+	SMPolicyID string `json:"smPolicyId,omitempty"`
+
 	// QoS Flows
 	QoSFlows map[QoSFlowIdentifier]*QoSFlow `json:"qosFlows"`
 
 	// UPF Information
-	UPFNodeID       string `json:"upfNodeId"`
-	UPFN4Address    string `json:"upfN4Address"`
-	UPFTEIDUplink   uint32 `json:"upfTeidUplink"`   // F-TEID for uplink
-	UPFTEIDDownlink uint32 `json:"upfTeidDownlink"` // F-TEID for downlink
-
-	// gNB Information (via AMF)
-	GNBTEIDUplink uint32 `json:"gnbTeidUplink"`
-	GNBN3Address  string `json:"gnbN3Address"`
+	SEID           uint64 `json:"seid"` // PFCP Session Endpoint Identifier
+	UPFNodeID      string `json:"upfNodeId"`
+	UPFN4Address   string `json:"upfN4Address"`
+	UPFN3Address   string `json:"upfN3Address,omitempty"`   // N3 address of the uplink F-TEID
+	UPFTEIDUplink  uint32 `json:"upfTeidUplink"`            // UPF-allocated N3 TEID, the gNB tunnels uplink to it
+	UPFUnavailable bool   `json:"upfUnavailable,omitempty"` // Anchoring UPF declared down
+
+	// gNB Information (via AMF). The gNB allocates the TEID the UPF tunnels
+	// downlink to (DL NG-U UP TNL Information, TS 38.413); the JSON name is
+	// kept for stored sessions.
+	GNBTEIDDownlink uint32 `json:"gnbTeidUplink"`
+	GNBN3Address    string `json:"gnbN3Address"`
+
+	// Indirect data forwarding tunnel during N2 handover
+	IndirectForwarding *ForwardingTunnel `json:"indirectForwarding,omitempty"`
+
+	// Uplink classifier for local breakout. The UPF fields above then
+	// describe the anchor, except for the N3 F-TEID which is the ULCL's.
+	ULCL *ULCL `json:"ulcl,omitempty"`
 
 	// Timestamps
 	CreatedAt time.Time `json:"createdAt"`
@@ -127,6 +215,23 @@ func (s *PDUSession) UpdateState(state PDUSessionState) {
 	s.UpdatedAt = time.Now()
 }
 
+// SetUpCnxState sets the user plane connection state
+func (s *PDUSession) SetUpCnxState(state UpCnxState) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.UpCnxState = state
+	s.UpdatedAt = time.Now()
+}
+
+// IsUpCnxDeactivated reports whether the user plane connection is released,
+// with the UPF buffering downlink data
+func (s *PDUSession) IsUpCnxDeactivated() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.UpCnxState == UpCnxStateDeactivated || s.UpCnxState == UpCnxStateActivating
+}
+
 // GetState returns the current session state
 func (s *PDUSession) GetState() PDUSessionState {
 	s.mu.RLock()
@@ -143,6 +248,21 @@ func (s *PDUSession) AddQoSFlow(flow *QoSFlow) {
 	s.UpdatedAt = time.Now()
 }
 
+// GetQoSFlows returns the QoS flows ordered by QFI
+func (s *PDUSession) GetQoSFlows() []*QoSFlow {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	flows := make([]*QoSFlow, 0, len(s.QoSFlows))
+	for _, flow := range s.QoSFlows {
+		flows = append(flows, flow)
+	}
+	sort.Slice(flows, func(i, j int) bool {
+		return flows[i].QFI < flows[j].QFI
+	})
+	return flows
+}
+
 // RemoveQoSFlow removes a QoS flow from the session
 func (s *PDUSession) RemoveQoSFlow(qfi QoSFlowIdentifier) {
 	s.mu.Lock()
@@ -162,24 +282,57 @@ func (s *PDUSession) SetUEIPAddress(ipv4 string, ipv6Prefix string) {
 	s.UpdatedAt = time.Now()
 }
 
-// SetUPFInfo sets UPF information
-func (s *PDUSession) SetUPFInfo(nodeID, n4Address string, teidUplink, teidDownlink uint32) {
+// SetPDUSessionType sets the PDU session type granted to the UE
+func (s *PDUSession) SetPDUSessionType(sessionType PDUSessionType) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.PDUSessionType = sessionType
+	s.UpdatedAt = time.Now()
+}
+
+// SetUPFInfo sets the anchoring UPF and the N3 TEID it allocated for uplink
+func (s *PDUSession) SetUPFInfo(nodeID, n4Address string, teidUplink uint32) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	s.UPFNodeID = nodeID
 	s.UPFN4Address = n4Address
 	s.UPFTEIDUplink = teidUplink
-	s.UPFTEIDDownlink = teidDownlink
 	s.UpdatedAt = time.Now()
 }
 
-// SetGNBInfo sets gNB information
-func (s *PDUSession) SetGNBInfo(teidUplink uint32, n3Address string) {
+// SetUPFN3Address sets the N3 address of the UPF uplink F-TEID
+func (s *PDUSession) SetUPFN3Address(address string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.GNBTEIDUplink = teidUplink
+	s.UPFN3Address = address
+	s.UpdatedAt = time.Now()
+}
+
+// SetUPFUnavailable flags the session as anchored on a UPF that is down
+func (s *PDUSession) SetUPFUnavailable(unavailable bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.UPFUnavailable = unavailable
+	s.UpdatedAt = time.Now()
+}
+
+// IsUPFUnavailable reports whether the anchoring UPF is down
+func (s *PDUSession) IsUPFUnavailable() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.UPFUnavailable
+}
+
+// SetGNBInfo sets the gNB N3 address and the TEID it allocated for downlink
+func (s *PDUSession) SetGNBInfo(teidDownlink uint32, n3Address string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.GNBTEIDDownlink = teidDownlink
 	s.GNBN3Address = n3Address
 	s.UpdatedAt = time.Now()
 }
@@ -195,3 +348,86 @@ func (s *PDUSession) SetSessionAMBR(uplink, downlink uint64) {
 	}
 	s.UpdatedAt = time.Now()
 }
+
+// SetSMPolicyID sets the SM policy association ID
+func (s *PDUSession) SetSMPolicyID(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.SMPolicyID = id
+	s.UpdatedAt = time.Now()
+}
+
+// GetSMPolicyID returns the SM policy association ID
+func (s *PDUSession) GetSMPolicyID() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.SMPolicyID
+}
+
+// SetSEID sets the PFCP session endpoint identifier
+func (s *PDUSession) SetSEID(seid uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.SEID = seid
+	s.UpdatedAt = time.Now()
+}
+
+// UpdatePacketDelay records a packet delay measurement on a QoS flow
+func (s *PDUSession) UpdatePacketDelay(qfi QoSFlowIdentifier, delay *PacketDelay) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	flow, exists := s.QoSFlows[qfi]
+	if !exists {
+		return fmt.Errorf("QoS flow not found: %d", qfi)
+	}
+
+	flow.PacketDelay = delay
+	s.UpdatedAt = time.Now()
+	return nil
+}
+
+// SetIndirectForwarding sets the indirect data forwarding tunnel
+func (s *PDUSession) SetIndirectForwarding(tunnel *ForwardingTunnel) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.IndirectForwarding = tunnel
+	s.UpdatedAt = time.Now()
+}
+
+// TakeIndirectForwarding clears and returns the indirect data forwarding tunnel
+func (s *PDUSession) TakeIndirectForwarding() *ForwardingTunnel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tunnel := s.IndirectForwarding
+	s.IndirectForwarding = nil
+	s.UpdatedAt = time.Now()
+	return tunnel
+}
+
+// GetIndirectForwarding returns the indirect data forwarding tunnel, if any
+func (s *PDUSession) GetIndirectForwarding() *ForwardingTunnel {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.IndirectForwarding
+}
+
+// SetULCL sets the uplink classifier of the session
+func (s *PDUSession) SetULCL(ulcl *ULCL) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ULCL = ulcl
+	s.UpdatedAt = time.Now()
+}
+
+// GetULCL returns the uplink classifier of the session, if any
+func (s *PDUSession) GetULCL() *ULCL {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ULCL
+}
@@ -12,6 +12,9 @@ type SMFContext struct {
 	// PDU Sessions indexed by SUPI + PDU Session ID
 	sessions map[string]*PDUSession
 
+	// Backing store, written through on every change
+	store SessionStore
+
 	// UPF Associations (simplified - one default UPF for now)
 	upfNodeID    string
 	upfN4Address string
@@ -27,10 +30,16 @@ type Statistics struct {
 	ReleasedSessions int `json:"releasedSessions"`
 }
 
-// NewSMFContext creates a new SMF context manager
+// NewSMFContext creates a new SMF context manager with an in-memory store
 func NewSMFContext(upfNodeID, upfN4Address string) *SMFContext {
+	return NewSMFContextWithStore(upfNodeID, upfN4Address, NewMemoryStore())
+}
+
+// NewSMFContextWithStore creates a new SMF context manager backed by store
+func NewSMFContextWithStore(upfNodeID, upfN4Address string, store SessionStore) *SMFContext {
 	return &SMFContext{
 		sessions:     make(map[string]*PDUSession),
+		store:        store,
 		upfNodeID:    upfNodeID,
 		upfN4Address: upfN4Address,
 	}
@@ -51,12 +60,57 @@ func (c *SMFContext) AddSession(session *PDUSession) error {
 		return fmt.Errorf("session already exists: %s", key)
 	}
 
+	if err := c.store.Save(session); err != nil {
+		return fmt.Errorf("failed to persist session %s: %w", key, err)
+	}
+
 	c.sessions[key] = session
 	c.stats.TotalSessions++
 
 	return nil
 }
 
+// UpdateSession persists changes made to a session
+func (c *SMFContext) UpdateSession(session *PDUSession) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	key := sessionKey(session.SUPI, session.PDUSessionID)
+	if _, exists := c.sessions[key]; !exists {
+		return fmt.Errorf("session not found: %s", key)
+	}
+
+	if err := c.store.Save(session); err != nil {
+		return fmt.Errorf("failed to persist session %s: %w", key, err)
+	}
+	return nil
+}
+
+// RestoreSessions loads the persisted sessions into the context, typically
+// at startup. Sessions already present are kept.
+func (c *SMFContext) RestoreSessions() ([]*PDUSession, error) {
+	sessions, err := c.store.LoadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sessions: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	restored := make([]*PDUSession, 0, len(sessions))
+	for _, session := range sessions {
+		key := sessionKey(session.SUPI, session.PDUSessionID)
+		if _, exists := c.sessions[key]; exists {
+			continue
+		}
+		c.sessions[key] = session
+		c.stats.TotalSessions++
+		restored = append(restored, session)
+	}
+
+	return restored, nil
+}
+
 // GetSession retrieves a PDU session
 func (c *SMFContext) GetSession(supi string, pduSessionID uint8) (*PDUSession, error) {
 	c.mu.RLock()
@@ -71,6 +125,24 @@ func (c *SMFContext) GetSession(supi string, pduSessionID uint8) (*PDUSession, e
 	return session, nil
 }
 
+// GetSessionBySEID retrieves a PDU session by its PFCP SEID on the anchor or
+// on the uplink classifier UPF
+func (c *SMFContext) GetSessionBySEID(seid uint64) (*PDUSession, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, session := range c.sessions {
+		if session.SEID == seid {
+			return session, nil
+		}
+		if ulcl := session.GetULCL(); ulcl != nil && ulcl.SEID == seid {
+			return session, nil
+		}
+	}
+
+	return nil, fmt.Errorf("session not found for SEID: %d", seid)
+}
+
 // RemoveSession removes a PDU session
 func (c *SMFContext) RemoveSession(supi string, pduSessionID uint8) error {
 	c.mu.Lock()
@@ -84,9 +156,26 @@ func (c *SMFContext) RemoveSession(supi string, pduSessionID uint8) error {
 	delete(c.sessions, key)
 	c.stats.ReleasedSessions++
 
+	if err := c.store.Delete(supi, pduSessionID); err != nil {
+		return fmt.Errorf("failed to delete persisted session %s: %w", key, err)
+	}
+
 	return nil
 }
 
+// ListSessions returns all PDU sessions
+func (c *SMFContext) ListSessions() []*PDUSession {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	sessions := make([]*PDUSession, 0, len(c.sessions))
+	for _, session := range c.sessions {
+		sessions = append(sessions, session)
+	}
+
+	return sessions
+}
+
 // GetAllSessions returns all PDU sessions for a SUPI
 func (c *SMFContext) GetAllSessions(supi string) []*PDUSession {
 	c.mu.RLock()
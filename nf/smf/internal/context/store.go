@@ -0,0 +1,174 @@
+package context
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// SessionStore persists PDU session contexts so they survive an SMF restart.
+// The SMF context keeps the working set in memory and writes through to the
+// store on every change.
+type SessionStore interface {
+	Save(session *PDUSession) error
+	Delete(supi string, pduSessionID uint8) error
+	LoadAll() ([]*PDUSession, error)
+}
+
+// Snapshot returns the JSON encoding of the session taken under its lock
+func (s *PDUSession) Snapshot() ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Alias type to encode the exported fields without recursing
+	type pduSession PDUSession
+	return json.Marshal((*pduSession)(s))
+}
+
+// decodeSession restores a session from a snapshot
+func decodeSession(data []byte) (*PDUSession, error) {
+	session := &PDUSession{}
+	if err := json.Unmarshal(data, session); err != nil {
+		return nil, err
+	}
+	if session.QoSFlows == nil {
+		session.QoSFlows = make(map[QoSFlowIdentifier]*QoSFlow)
+	}
+	return session, nil
+}
+
+// MemoryStore keeps session snapshots in memory. Sessions are lost when the
+// process exits; this is the default store.
+type MemoryStore struct {
+	mu        sync.RWMutex
+	snapshots map[string][]byte
+}
+
+// NewMemoryStore creates an in-memory session store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		snapshots: make(map[string][]byte),
+	}
+}
+
+// Save stores a snapshot of the session
+func (m *MemoryStore) Save(session *PDUSession) error {
+	data, err := session.Snapshot()
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.snapshots[sessionKey(session.SUPI, session.PDUSessionID)] = data
+	return nil
+}
+
+// Delete removes a session snapshot
+func (m *MemoryStore) Delete(supi string, pduSessionID uint8) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.snapshots, sessionKey(supi, pduSessionID))
+	return nil
+}
+
+// LoadAll returns all stored sessions
+func (m *MemoryStore) LoadAll() ([]*PDUSession, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	sessions := make([]*PDUSession, 0, len(m.snapshots))
+	for key, data := range m.snapshots {
+		session, err := decodeSession(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode session %s: %w", key, err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+// FileStore keeps one JSON file per session in a directory
+type FileStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// NewFileStore creates a file backed session store, creating the directory
+// if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create session store directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// path returns the file holding a session
+func (f *FileStore) path(supi string, pduSessionID uint8) string {
+	return filepath.Join(f.dir, sessionKey(supi, pduSessionID)+".json")
+}
+
+// Save writes the session atomically (write to a temporary file, then rename)
+func (f *FileStore) Save(session *PDUSession) error {
+	data, err := session.Snapshot()
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := f.path(session.SUPI, session.PDUSessionID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o640); err != nil {
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write session: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the session file
+func (f *FileStore) Delete(supi string, pduSessionID uint8) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := os.Remove(f.path(supi, pduSessionID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// LoadAll reads all session files from the directory
+func (f *FileStore) LoadAll() ([]*PDUSession, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session store: %w", err)
+	}
+
+	var sessions []*PDUSession
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(f.dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read session %s: %w", entry.Name(), err)
+		}
+		session, err := decodeSession(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode session %s: %w", entry.Name(), err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
@@ -0,0 +1,81 @@
+package context
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStoreSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := NewFileStore(dir)
+	require.NoError(t, err)
+	smfContext := NewSMFContextWithStore("upf-1", "127.0.0.1:8805", store)
+
+	session := NewPDUSession("imsi-001010000000001", 5, "internet", SNSSAI{SST: 1, SD: "000001"})
+	session.SetUEIPAddress("10.60.0.7", "")
+	session.SetSEID(0xabcdef)
+	session.SetUPFInfo("upf-1", "127.0.0.1:8805", 1001)
+	session.SetSessionAMBR(1000000, 2000000)
+	session.AddQoSFlow(&QoSFlow{QFI: 1, FiveQI: 9, CreatedAt: time.Now()})
+	session.UpdateState(PDUSessionStateActive)
+	require.NoError(t, smfContext.AddSession(session))
+
+	session.SetGNBInfo(0x200, "10.0.0.2")
+	require.NoError(t, smfContext.UpdateSession(session))
+
+	// Simulated restart: a fresh context on the same directory
+	store, err = NewFileStore(dir)
+	require.NoError(t, err)
+	restarted := NewSMFContextWithStore("upf-1", "127.0.0.1:8805", store)
+
+	restored, err := restarted.RestoreSessions()
+	require.NoError(t, err)
+	require.Len(t, restored, 1)
+
+	got, err := restarted.GetSession("imsi-001010000000001", 5)
+	require.NoError(t, err)
+	assert.Equal(t, PDUSessionStateActive, got.GetState())
+	assert.Equal(t, "10.60.0.7", got.UEIPv4Address)
+	assert.Equal(t, uint64(0xabcdef), got.SEID)
+	assert.Equal(t, uint32(0x200), got.GNBTEIDDownlink)
+	assert.Equal(t, "10.0.0.2", got.GNBN3Address)
+	assert.Equal(t, BitRate{Uplink: 1000000, Downlink: 2000000}, got.SessionAMBR)
+	require.Contains(t, got.QoSFlows, QoSFlowIdentifier(1))
+	assert.Equal(t, uint8(9), got.QoSFlows[1].FiveQI)
+
+	bySEID, err := restarted.GetSessionBySEID(0xabcdef)
+	require.NoError(t, err)
+	assert.Same(t, got, bySEID)
+
+	// Removal is persisted as well
+	require.NoError(t, restarted.RemoveSession("imsi-001010000000001", 5))
+	sessions, err := store.LoadAll()
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
+
+func TestMemoryStoreKeepsSnapshots(t *testing.T) {
+	store := NewMemoryStore()
+	smfContext := NewSMFContextWithStore("upf-1", "127.0.0.1:8805", store)
+
+	session := NewPDUSession("imsi-001010000000001", 1, "internet", SNSSAI{SST: 1})
+	require.NoError(t, smfContext.AddSession(session))
+
+	// Changes are only visible in the store once persisted
+	session.UpdateState(PDUSessionStateActive)
+	sessions, err := store.LoadAll()
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, PDUSessionStateInactive, sessions[0].State)
+
+	require.NoError(t, smfContext.UpdateSession(session))
+	sessions, err = store.LoadAll()
+	require.NoError(t, err)
+	assert.Equal(t, PDUSessionStateActive, sessions[0].State)
+
+	assert.Error(t, smfContext.UpdateSession(NewPDUSession("imsi-001010000000002", 1, "internet", SNSSAI{SST: 1})))
+}
@@ -0,0 +1,221 @@
+package n4
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/your-org/5g-network/common/pfcp"
+	"go.uber.org/zap"
+)
+
+// DefaultMaxHeartbeatFailures is the number of consecutive unanswered
+// heartbeats after which the association is declared failed
+const DefaultMaxHeartbeatFailures = 3
+
+// AssociationState represents the state of the PFCP association with a UPF
+type AssociationState string
+
+const (
+	AssociationIdle        AssociationState = "IDLE"
+	AssociationEstablished AssociationState = "ESTABLISHED"
+	AssociationFailed      AssociationState = "FAILED"
+)
+
+var (
+	// ErrUPFRestarted is returned when the UPF reports a new Recovery Time
+	// Stamp, meaning it lost the association and its sessions
+	ErrUPFRestarted = errors.New("UPF restarted")
+
+	// ErrAssociationFailed is returned by the heartbeat that declares the
+	// association failed
+	ErrAssociationFailed = errors.New("PFCP association failed")
+)
+
+// SetHeartbeatFailureThreshold sets the number of consecutive heartbeat
+// failures after which the association is declared failed
+func (c *PFCPClient) SetHeartbeatFailureThreshold(n int) {
+	c.assocMu.Lock()
+	defer c.assocMu.Unlock()
+
+	if n <= 0 {
+		n = DefaultMaxHeartbeatFailures
+	}
+	c.maxHeartbeatFailures = n
+}
+
+// AssociatePFCPSession establishes PFCP association with UPF, exchanging Node
+// IDs and Recovery Time Stamps (TS 29.244 6.2.6.2)
+func (c *PFCPClient) AssociatePFCPSession() error {
+	c.logger.Info("Establishing PFCP association with UPF",
+		zap.String("upf_node_id", c.upfNodeID),
+		zap.String("upf_address", c.upfN4Address),
+	)
+
+	var upfRecoveryTime time.Time
+	if c.transport != nil {
+		resp, err := c.transport.request(pfcp.NewMessage(pfcp.PFCP_ASSOCIATION_SETUP_REQUEST, 0,
+			pfcp.NewNodeIDIE(c.nodeID),
+			pfcp.NewRecoveryTimeStampIE(c.transport.startedAt),
+		))
+		if err != nil {
+			return fmt.Errorf("PFCP association setup failed: %w", err)
+		}
+		cause, err := decodeCause(resp)
+		if err != nil {
+			return fmt.Errorf("PFCP association setup failed: %w", err)
+		}
+		if err := ValidatePFCPResponse(cause); err != nil {
+			return err
+		}
+		if upfRecoveryTime, err = decodeRecoveryTimeStamp(resp); err != nil {
+			return fmt.Errorf("PFCP association setup failed: %w", err)
+		}
+	} else {
+		time.Sleep(20 * time.Millisecond)
+		upfRecoveryTime = time.Now()
+	}
+
+	c.assocMu.Lock()
+	c.assocState = AssociationEstablished
+	c.upfRecoveryTime = upfRecoveryTime
+	c.heartbeatFailures = 0
+	c.assocMu.Unlock()
+
+	c.logger.Info("PFCP association established successfully",
+		zap.String("upf_node_id", c.upfNodeID),
+		zap.Time("upf_recovery_time", upfRecoveryTime),
+	)
+
+	return nil
+}
+
+// ReleaseAssociation releases the PFCP association with the UPF, which
+// deletes the sessions established under it (TS 29.244 6.2.8)
+func (c *PFCPClient) ReleaseAssociation() error {
+	if !c.IsAssociated() {
+		return nil
+	}
+
+	c.logger.Info("Releasing PFCP association with UPF",
+		zap.String("upf_node_id", c.upfNodeID),
+		zap.String("upf_address", c.upfN4Address),
+	)
+
+	if c.transport != nil {
+		resp, err := c.transport.request(pfcp.NewMessage(pfcp.PFCP_ASSOCIATION_RELEASE_REQUEST, 0,
+			pfcp.NewNodeIDIE(c.nodeID),
+		))
+		if err != nil {
+			return fmt.Errorf("PFCP association release failed: %w", err)
+		}
+		cause, err := decodeCause(resp)
+		if err != nil {
+			return fmt.Errorf("PFCP association release failed: %w", err)
+		}
+		if err := ValidatePFCPResponse(cause); err != nil {
+			return err
+		}
+	} else {
+		c.sessionsMu.Lock()
+		seids := make([]uint64, 0, len(c.sessions))
+		for seid := range c.sessions {
+			seids = append(seids, seid)
+		}
+		c.sessionsMu.Unlock()
+		for _, seid := range seids {
+			c.forgetSession(seid)
+		}
+	}
+
+	c.assocMu.Lock()
+	c.assocState = AssociationIdle
+	c.assocMu.Unlock()
+
+	c.logger.Info("PFCP association released", zap.String("upf_node_id", c.upfNodeID))
+	return nil
+}
+
+// IsAssociated reports whether a PFCP association with the UPF is set up
+func (c *PFCPClient) IsAssociated() bool {
+	return c.AssociationState() == AssociationEstablished
+}
+
+// AssociationState returns the state of the PFCP association
+func (c *PFCPClient) AssociationState() AssociationState {
+	c.assocMu.Lock()
+	defer c.assocMu.Unlock()
+	return c.assocState
+}
+
+// UPFRecoveryTime returns the Recovery Time Stamp the UPF reported at
+// association setup
+func (c *PFCPClient) UPFRecoveryTime() time.Time {
+	c.assocMu.Lock()
+	defer c.assocMu.Unlock()
+	return c.upfRecoveryTime
+}
+
+// SendHeartbeat sends PFCP Heartbeat Request to UPF (TS 29.244 6.2.2).
+// Consecutive failures are counted; the one reaching the failure threshold
+// marks the association failed and returns ErrAssociationFailed. A Recovery
+// Time Stamp other than the one received at association setup drops the
+// association and returns ErrUPFRestarted.
+func (c *PFCPClient) SendHeartbeat() error {
+	c.logger.Debug("Sending PFCP Heartbeat to UPF",
+		zap.String("upf_node_id", c.upfNodeID),
+	)
+
+	if c.transport == nil {
+		return nil
+	}
+
+	resp, err := c.transport.request(pfcp.NewMessage(pfcp.PFCP_HEARTBEAT_REQUEST, 0,
+		pfcp.NewRecoveryTimeStampIE(c.transport.startedAt),
+	))
+	if err == nil {
+		var recoveryTime time.Time
+		if recoveryTime, err = decodeRecoveryTimeStamp(resp); err == nil {
+			return c.heartbeatAnswered(recoveryTime)
+		}
+	}
+	return c.heartbeatFailed(err)
+}
+
+// heartbeatAnswered resets the failure count and checks the UPF Recovery Time
+// Stamp
+func (c *PFCPClient) heartbeatAnswered(recoveryTime time.Time) error {
+	c.assocMu.Lock()
+	defer c.assocMu.Unlock()
+
+	c.heartbeatFailures = 0
+	if c.assocState == AssociationEstablished && !recoveryTime.Equal(c.upfRecoveryTime) {
+		c.assocState = AssociationIdle
+		c.logger.Warn("UPF restarted, PFCP association lost",
+			zap.String("upf_node_id", c.upfNodeID),
+			zap.Time("upf_recovery_time", recoveryTime),
+		)
+		return ErrUPFRestarted
+	}
+	return nil
+}
+
+// heartbeatFailed counts a failed heartbeat and declares the association
+// failed once the threshold is reached
+func (c *PFCPClient) heartbeatFailed(err error) error {
+	c.assocMu.Lock()
+	defer c.assocMu.Unlock()
+
+	c.heartbeatFailures++
+	if c.assocState != AssociationEstablished || c.heartbeatFailures < c.maxHeartbeatFailures {
+		return fmt.Errorf("PFCP heartbeat failed: %w", err)
+	}
+
+	c.assocState = AssociationFailed
+	c.logger.Error("UPF not responding, PFCP association failed",
+		zap.String("upf_node_id", c.upfNodeID),
+		zap.Int("heartbeat_failures", c.heartbeatFailures),
+		zap.Error(err),
+	)
+	return fmt.Errorf("%w: %d heartbeats unanswered: %v", ErrAssociationFailed, c.heartbeatFailures, err)
+}
@@ -0,0 +1,285 @@
+package n4
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/your-org/5g-network/common/pfcp"
+)
+
+// Cause strings reported in responses, ValidatePFCPResponse accepts only
+// "Request accepted"
+var causeStrings = map[uint8]string{
+	pfcp.CAUSE_REQUEST_ACCEPTED:             "Request accepted",
+	pfcp.CAUSE_REQUEST_REJECTED:             "Request rejected",
+	pfcp.CAUSE_SESSION_CONTEXT_NOT_FOUND:    "Session context not found",
+	pfcp.CAUSE_MANDATORY_IE_MISSING:         "Mandatory IE missing",
+	pfcp.CAUSE_CONDITIONAL_IE_MISSING:       "Conditional IE missing",
+	pfcp.CAUSE_INVALID_LENGTH:               "Invalid length",
+	pfcp.CAUSE_MANDATORY_IE_INCORRECT:       "Mandatory IE incorrect",
+	pfcp.CAUSE_NO_ESTABLISHED_ASSOCIATION:   "No established PFCP association",
+	pfcp.CAUSE_RULE_CREATION_FAILURE:        "Rule creation/modification failure",
+	pfcp.CAUSE_NO_RESOURCES_AVAILABLE:       "No resources available",
+	pfcp.CAUSE_SERVICE_NOT_SUPPORTED:        "Service not supported",
+	pfcp.CAUSE_SYSTEM_FAILURE:               "System failure",
+	pfcp.CAUSE_ALL_DYNAMIC_ADDRESS_OCCUPIED: "All dynamic addresses are occupied",
+}
+
+// decodeCause returns the cause string of a response
+func decodeCause(msg *pfcp.Message) (string, error) {
+	ie := msg.FindIE(pfcp.IE_CAUSE)
+	if ie == nil {
+		return "", fmt.Errorf("PFCP response without cause")
+	}
+	cause, err := ie.Uint8()
+	if err != nil {
+		return "", err
+	}
+	if s, ok := causeStrings[cause]; ok {
+		return s, nil
+	}
+	return fmt.Sprintf("Cause %d", cause), nil
+}
+
+// decodeRecoveryTimeStamp returns the Recovery Time Stamp of a node-level
+// response
+func decodeRecoveryTimeStamp(msg *pfcp.Message) (time.Time, error) {
+	ie := msg.FindIE(pfcp.IE_RECOVERY_TIME_STAMP)
+	if ie == nil {
+		return time.Time{}, fmt.Errorf("PFCP response without recovery time stamp")
+	}
+	return ie.RecoveryTimeStamp()
+}
+
+// interfaceValue encodes a Source or Destination Interface name
+func interfaceValue(name string) uint8 {
+	switch name {
+	case "CORE":
+		return pfcp.INTERFACE_CORE
+	case "SGI-LAN":
+		return pfcp.INTERFACE_SGI_LAN
+	case "CP-FUNCTION":
+		return pfcp.INTERFACE_CP_FUNCTION
+	default:
+		return pfcp.INTERFACE_ACCESS
+	}
+}
+
+// applyActionFlags encodes an Apply Action name
+func applyActionFlags(action string) uint8 {
+	switch action {
+	case "DROP":
+		return pfcp.APPLY_ACTION_DROP
+	case "BUFFER":
+		// Buffer and notify the SMF of the first downlink packet
+		return pfcp.APPLY_ACTION_BUFF | pfcp.APPLY_ACTION_NOCP
+	default:
+		return pfcp.APPLY_ACTION_FORW
+	}
+}
+
+// encodePDR encodes a Create PDR or Update PDR IE
+func encodePDR(ieType uint16, pdr *PDR) *pfcp.IE {
+	ies := []*pfcp.IE{
+		pfcp.NewUint16IE(pfcp.IE_PDR_ID, pdr.PDRID),
+		pfcp.NewUint32IE(pfcp.IE_PRECEDENCE, pdr.Precedence),
+		encodePDI(&pdr.PDI),
+	}
+	if pdr.OuterHeaderRemoval {
+		ies = append(ies, pfcp.NewUint8IE(pfcp.IE_OUTER_HEADER_REMOVAL, pfcp.OUTER_HEADER_REMOVAL_GTPU_UDP_IPV4))
+	}
+	ies = append(ies, pfcp.NewUint32IE(pfcp.IE_FAR_ID, uint32(pdr.FARID)))
+	for _, qerID := range pdr.QERIDs {
+		ies = append(ies, pfcp.NewUint32IE(pfcp.IE_QER_ID, uint32(qerID)))
+	}
+	for _, urrID := range pdr.URRIDs {
+		ies = append(ies, pfcp.NewUint32IE(pfcp.IE_URR_ID, urrID))
+	}
+	return pfcp.NewGroupedIE(ieType, ies...)
+}
+
+// encodePDI encodes a PDI IE. An F-TEID without a TEID asks the UPF to
+// allocate one.
+func encodePDI(pdi *PDI) *pfcp.IE {
+	ies := []*pfcp.IE{pfcp.NewUint8IE(pfcp.IE_SOURCE_INTERFACE, interfaceValue(pdi.SourceInterface))}
+
+	if pdi.FTEID != nil {
+		if pdi.FTEID.TEID == 0 {
+			ies = append(ies, pfcp.NewFTEIDIE(&pfcp.FTEID{
+				ChooseID: true,
+				ChooseV4: true,
+				HasCHID:  pdi.FTEID.CHID != 0,
+				CHID:     pdi.FTEID.CHID,
+			}))
+		} else {
+			ies = append(ies, pfcp.NewFTEIDIE(&pfcp.FTEID{
+				TEID: pdi.FTEID.TEID,
+				IPv4: net.ParseIP(pdi.FTEID.IPv4),
+				IPv6: net.ParseIP(pdi.FTEID.IPv6),
+			}))
+		}
+	}
+	if pdi.NetworkInstance != "" {
+		ies = append(ies, pfcp.NewIE(pfcp.IE_NETWORK_INSTANCE, []byte(pdi.NetworkInstance)))
+	}
+	ueIP := &pfcp.UEIPAddress{
+		IPv4:          net.ParseIP(pdi.UEIPAddress),
+		IsDestination: pdi.SourceInterface == "CORE",
+	}
+	if _, prefix, err := net.ParseCIDR(pdi.UEIPv6Prefix); err == nil {
+		ueIP.IPv6 = prefix.IP
+		if ones, _ := prefix.Mask.Size(); ones != 64 {
+			ueIP.IPv6PrefixLength = uint8(ones)
+		}
+	}
+	if ueIP.IPv4 != nil || ueIP.IPv6 != nil {
+		ies = append(ies, pfcp.NewUEIPAddressIE(ueIP))
+	}
+	if pdi.SDFFilter != "" {
+		ies = append(ies, pfcp.NewIE(pfcp.IE_SDF_FILTER, []byte(pdi.SDFFilter)))
+	}
+	if pdi.QFI != 0 {
+		ies = append(ies, pfcp.NewUint8IE(pfcp.IE_QFI, pdi.QFI))
+	}
+	return pfcp.NewGroupedIE(pfcp.IE_PDI, ies...)
+}
+
+// encodeFAR encodes a Create FAR or Update FAR IE
+func encodeFAR(ieType uint16, far *FAR) *pfcp.IE {
+	ies := []*pfcp.IE{
+		pfcp.NewUint32IE(pfcp.IE_FAR_ID, uint32(far.FARID)),
+		pfcp.NewUint8IE(pfcp.IE_APPLY_ACTION, applyActionFlags(far.ApplyAction)),
+	}
+
+	if params := far.ForwardingParameters; params != nil {
+		paramsType := uint16(pfcp.IE_FORWARDING_PARAMETERS)
+		if ieType == pfcp.IE_UPDATE_FAR {
+			paramsType = pfcp.IE_UPDATE_FORWARDING_PARAMS
+		}
+
+		children := []*pfcp.IE{pfcp.NewUint8IE(pfcp.IE_DESTINATION_INTERFACE, interfaceValue(params.DestinationInterface))}
+		if params.NetworkInstance != "" {
+			children = append(children, pfcp.NewIE(pfcp.IE_NETWORK_INSTANCE, []byte(params.NetworkInstance)))
+		}
+		if ohc := params.OuterHeaderCreation; ohc != nil {
+			children = append(children, pfcp.NewOuterHeaderCreationIE(&pfcp.OuterHeaderCreation{
+				Description: pfcp.OUTER_HEADER_CREATION_GTPU_UDP_IPV4,
+				TEID:        ohc.TEID,
+				IPv4:        net.ParseIP(ohc.IPv4),
+			}))
+		}
+		ies = append(ies, pfcp.NewGroupedIE(paramsType, children...))
+	}
+
+	return pfcp.NewGroupedIE(ieType, ies...)
+}
+
+// encodeQER encodes a Create QER or Update QER IE
+func encodeQER(ieType uint16, qer *QER) *pfcp.IE {
+	ies := []*pfcp.IE{
+		pfcp.NewUint32IE(pfcp.IE_QER_ID, uint32(qer.QERID)),
+		pfcp.NewGateStatusIE(pfcp.GATE_STATUS_OPEN),
+	}
+	if qer.MBRUplink != 0 || qer.MBRDownlink != 0 {
+		ies = append(ies, pfcp.NewBitRateIE(pfcp.IE_MBR, &pfcp.BitRate{Uplink: qer.MBRUplink, Downlink: qer.MBRDownlink}))
+	}
+	if qer.GBRUplink != 0 || qer.GBRDownlink != 0 {
+		ies = append(ies, pfcp.NewBitRateIE(pfcp.IE_GBR, &pfcp.BitRate{Uplink: qer.GBRUplink, Downlink: qer.GBRDownlink}))
+	}
+	if qer.QFI != 0 {
+		ies = append(ies, pfcp.NewUint8IE(pfcp.IE_QFI, qer.QFI))
+	}
+	if m := qer.QoSMonitoring; m != nil {
+		monitoring := &pfcp.QoSMonitoring{
+			DownlinkThreshold:  m.DownlinkThreshold,
+			UplinkThreshold:    m.UplinkThreshold,
+			RoundTripThreshold: m.RoundTripThreshold,
+			MinimumWaitTime:    m.MinimumWaitTime,
+		}
+		if m.RequestDownlink {
+			monitoring.Requested |= pfcp.QOS_MONITORING_DL
+		}
+		if m.RequestUplink {
+			monitoring.Requested |= pfcp.QOS_MONITORING_UL
+		}
+		if m.RequestRoundTrip {
+			monitoring.Requested |= pfcp.QOS_MONITORING_RP
+		}
+		ies = append(ies, pfcp.NewQoSMonitoringIE(monitoring))
+	}
+	return pfcp.NewGroupedIE(ieType, ies...)
+}
+
+// encodeURR encodes a Create URR IE. The reporting triggers follow from the
+// thresholds and period that are set.
+func encodeURR(urr *URR) *pfcp.IE {
+	var method uint8
+	if urr.MeasureVolume {
+		method |= pfcp.MEASUREMENT_METHOD_VOLUM
+	}
+	if urr.MeasureDuration {
+		method |= pfcp.MEASUREMENT_METHOD_DURAT
+	}
+
+	var triggers uint32
+	var ies []*pfcp.IE
+	if urr.MeasurementPeriod > 0 {
+		triggers |= pfcp.REPORTING_TRIGGER_PERIO
+		ies = append(ies, pfcp.NewUint32IE(pfcp.IE_MEASUREMENT_PERIOD, uint32(urr.MeasurementPeriod/time.Second)))
+	}
+	if urr.VolumeThreshold > 0 {
+		triggers |= pfcp.REPORTING_TRIGGER_VOLTH
+		ies = append(ies, pfcp.NewVolumeThresholdIE(&pfcp.VolumeThreshold{Total: urr.VolumeThreshold}))
+	}
+	if urr.TimeThreshold > 0 {
+		triggers |= pfcp.REPORTING_TRIGGER_TIMTH
+		ies = append(ies, pfcp.NewUint32IE(pfcp.IE_TIME_THRESHOLD, uint32(urr.TimeThreshold/time.Second)))
+	}
+
+	return pfcp.NewGroupedIE(pfcp.IE_CREATE_URR, append([]*pfcp.IE{
+		pfcp.NewUint32IE(pfcp.IE_URR_ID, urr.URRID),
+		pfcp.NewUint8IE(pfcp.IE_MEASUREMENT_METHOD, method),
+		pfcp.NewReportingTriggersIE(pfcp.IE_REPORTING_TRIGGERS, triggers),
+	}, ies...)...)
+}
+
+// encodeRemove encodes a Remove PDR/FAR IE carrying only the rule ID
+func encodeRemove(ieType uint16, id *pfcp.IE) *pfcp.IE {
+	return pfcp.NewGroupedIE(ieType, id)
+}
+
+// decodeCreatedPDRs decodes the Created PDR IEs of a response
+func decodeCreatedPDRs(msg *pfcp.Message) ([]CreatedPDR, error) {
+	var created []CreatedPDR
+	for _, ie := range pfcp.FindIEs(msg.IEs, pfcp.IE_CREATED_PDR) {
+		children, err := ie.ChildIEs()
+		if err != nil {
+			return nil, fmt.Errorf("created PDR: %w", err)
+		}
+
+		idIE := pfcp.FindIE(children, pfcp.IE_PDR_ID)
+		fteidIE := pfcp.FindIE(children, pfcp.IE_F_TEID)
+		if idIE == nil || fteidIE == nil {
+			return nil, fmt.Errorf("created PDR without PDR ID or F-TEID")
+		}
+		pdrID, err := idIE.Uint16()
+		if err != nil {
+			return nil, fmt.Errorf("created PDR: %w", err)
+		}
+		fteid, err := fteidIE.FTEID()
+		if err != nil {
+			return nil, fmt.Errorf("created PDR %d: %w", pdrID, err)
+		}
+
+		pdr := CreatedPDR{PDRID: pdrID, FTEID: &FTEID{TEID: fteid.TEID}}
+		if fteid.IPv4 != nil {
+			pdr.FTEID.IPv4 = fteid.IPv4.String()
+		}
+		if fteid.IPv6 != nil {
+			pdr.FTEID.IPv6 = fteid.IPv6.String()
+		}
+		created = append(created, pdr)
+	}
+	return created, nil
+}
@@ -0,0 +1,350 @@
+package n4
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/5g-network/common/pfcp"
+)
+
+// roundTrip marshals an IE and parses it back from the wire format
+func roundTrip(t *testing.T, ie *pfcp.IE) *pfcp.IE {
+	t.Helper()
+
+	ies, err := pfcp.ParseIEs(ie.Marshal())
+	require.NoError(t, err)
+	require.Len(t, ies, 1)
+	assert.Equal(t, ie.Type, ies[0].Type)
+	return ies[0]
+}
+
+// child returns the only child IE of type ieType
+func child(t *testing.T, ie *pfcp.IE, ieType uint16) *pfcp.IE {
+	t.Helper()
+
+	children, err := ie.ChildIEs()
+	require.NoError(t, err)
+	found := pfcp.FindIEs(children, ieType)
+	require.Len(t, found, 1, "IE type %d", ieType)
+	return found[0]
+}
+
+func uint8Value(t *testing.T, ie *pfcp.IE) uint8 {
+	t.Helper()
+	v, err := ie.Uint8()
+	require.NoError(t, err)
+	return v
+}
+
+func uint32Value(t *testing.T, ie *pfcp.IE) uint32 {
+	t.Helper()
+	v, err := ie.Uint32()
+	require.NoError(t, err)
+	return v
+}
+
+func TestCodec_FTEID(t *testing.T) {
+	t.Run("choose", func(t *testing.T) {
+		pdi := roundTrip(t, encodePDI(&PDI{SourceInterface: "ACCESS", FTEID: &FTEID{CHID: 1}}))
+		fteid, err := child(t, pdi, pfcp.IE_F_TEID).FTEID()
+		require.NoError(t, err)
+		assert.Equal(t, &pfcp.FTEID{ChooseID: true, ChooseV4: true, HasCHID: true, CHID: 1}, fteid)
+	})
+
+	t.Run("choose without CHID", func(t *testing.T) {
+		pdi := roundTrip(t, encodePDI(&PDI{SourceInterface: "ACCESS", FTEID: &FTEID{}}))
+		fteid, err := child(t, pdi, pfcp.IE_F_TEID).FTEID()
+		require.NoError(t, err)
+		assert.Equal(t, &pfcp.FTEID{ChooseID: true, ChooseV4: true}, fteid)
+	})
+
+	t.Run("explicit", func(t *testing.T) {
+		pdi := roundTrip(t, encodePDI(&PDI{
+			SourceInterface: "ACCESS",
+			FTEID:           &FTEID{TEID: 0x1234, IPv4: testN3IP},
+		}))
+		fteid, err := child(t, pdi, pfcp.IE_F_TEID).FTEID()
+		require.NoError(t, err)
+		assert.False(t, fteid.ChooseID)
+		assert.Equal(t, uint32(0x1234), fteid.TEID)
+		assert.True(t, fteid.IPv4.Equal(net.ParseIP(testN3IP)))
+		assert.Nil(t, fteid.IPv6)
+	})
+}
+
+func TestCodec_PDI(t *testing.T) {
+	t.Run("uplink", func(t *testing.T) {
+		pdi := roundTrip(t, encodePDI(&PDI{
+			SourceInterface: "ACCESS",
+			FTEID:           &FTEID{CHID: 1},
+			UEIPAddress:     testUEIP,
+			NetworkInstance: testDNN,
+			QFI:             9,
+		}))
+		assert.Equal(t, uint16(pfcp.IE_PDI), pdi.Type)
+		assert.Equal(t, uint8(pfcp.INTERFACE_ACCESS), uint8Value(t, child(t, pdi, pfcp.IE_SOURCE_INTERFACE)))
+		assert.Equal(t, testDNN, string(child(t, pdi, pfcp.IE_NETWORK_INSTANCE).Value))
+		assert.Equal(t, uint8(9), uint8Value(t, child(t, pdi, pfcp.IE_QFI)))
+
+		ueIP, err := child(t, pdi, pfcp.IE_UE_IP_ADDRESS).UEIPAddress()
+		require.NoError(t, err)
+		assert.True(t, ueIP.IPv4.Equal(net.ParseIP(testUEIP)))
+		assert.False(t, ueIP.IsDestination)
+	})
+
+	t.Run("downlink", func(t *testing.T) {
+		pdi := roundTrip(t, encodePDI(&PDI{SourceInterface: "CORE", UEIPAddress: testUEIP}))
+		assert.Equal(t, uint8(pfcp.INTERFACE_CORE), uint8Value(t, child(t, pdi, pfcp.IE_SOURCE_INTERFACE)))
+
+		children, err := pdi.ChildIEs()
+		require.NoError(t, err)
+		assert.Nil(t, pfcp.FindIE(children, pfcp.IE_F_TEID))
+		assert.Nil(t, pfcp.FindIE(children, pfcp.IE_QFI))
+
+		ueIP, err := child(t, pdi, pfcp.IE_UE_IP_ADDRESS).UEIPAddress()
+		require.NoError(t, err)
+		assert.True(t, ueIP.IsDestination)
+	})
+
+	t.Run("dual stack", func(t *testing.T) {
+		pdi := roundTrip(t, encodePDI(&PDI{
+			SourceInterface: "CORE",
+			UEIPAddress:     testUEIP,
+			UEIPv6Prefix:    "2001:db8:1:2::/64",
+		}))
+
+		ueIP, err := child(t, pdi, pfcp.IE_UE_IP_ADDRESS).UEIPAddress()
+		require.NoError(t, err)
+		assert.True(t, ueIP.IPv4.Equal(net.ParseIP(testUEIP)))
+		assert.True(t, ueIP.IPv6.Equal(net.ParseIP("2001:db8:1:2::")))
+		assert.Zero(t, ueIP.IPv6PrefixLength)
+	})
+
+	t.Run("IPv6 only with /56 prefix", func(t *testing.T) {
+		pdi := roundTrip(t, encodePDI(&PDI{SourceInterface: "ACCESS", UEIPv6Prefix: "2001:db8:ff00:100::/56"}))
+
+		ueIP, err := child(t, pdi, pfcp.IE_UE_IP_ADDRESS).UEIPAddress()
+		require.NoError(t, err)
+		assert.Nil(t, ueIP.IPv4)
+		assert.True(t, ueIP.IPv6.Equal(net.ParseIP("2001:db8:ff00:100::")))
+		assert.Equal(t, uint8(56), ueIP.IPv6PrefixLength)
+	})
+
+	t.Run("SDF filter", func(t *testing.T) {
+		filter := "permit out ip from 10.200.0.0/16 to assigned"
+		pdi := roundTrip(t, encodePDI(&PDI{SourceInterface: "ACCESS", SDFFilter: filter}))
+		assert.Equal(t, filter, string(child(t, pdi, pfcp.IE_SDF_FILTER).Value))
+	})
+}
+
+func TestCodec_CreatePDR(t *testing.T) {
+	pdr := roundTrip(t, encodePDR(pfcp.IE_CREATE_PDR, &PDR{
+		PDRID:              1,
+		Precedence:         100,
+		PDI:                PDI{SourceInterface: "ACCESS", FTEID: &FTEID{CHID: 1}},
+		OuterHeaderRemoval: true,
+		FARID:              3,
+		QERIDs:             []uint16{2, 1},
+	}))
+
+	pdrID, err := child(t, pdr, pfcp.IE_PDR_ID).Uint16()
+	require.NoError(t, err)
+	assert.Equal(t, uint16(1), pdrID)
+	assert.Equal(t, uint32(100), uint32Value(t, child(t, pdr, pfcp.IE_PRECEDENCE)))
+	assert.Equal(t, uint8(pfcp.OUTER_HEADER_REMOVAL_GTPU_UDP_IPV4), uint8Value(t, child(t, pdr, pfcp.IE_OUTER_HEADER_REMOVAL)))
+	assert.Equal(t, uint32(3), uint32Value(t, child(t, pdr, pfcp.IE_FAR_ID)))
+	child(t, pdr, pfcp.IE_PDI)
+
+	children, err := pdr.ChildIEs()
+	require.NoError(t, err)
+	var qerIDs []uint32
+	for _, ie := range pfcp.FindIEs(children, pfcp.IE_QER_ID) {
+		qerIDs = append(qerIDs, uint32Value(t, ie))
+	}
+	assert.Equal(t, []uint32{2, 1}, qerIDs)
+
+	// Outer header removal is only present when requested
+	pdr = roundTrip(t, encodePDR(pfcp.IE_UPDATE_PDR, &PDR{PDRID: 2, PDI: PDI{SourceInterface: "CORE"}, FARID: 2}))
+	assert.Equal(t, uint16(pfcp.IE_UPDATE_PDR), pdr.Type)
+	children, err = pdr.ChildIEs()
+	require.NoError(t, err)
+	assert.Nil(t, pfcp.FindIE(children, pfcp.IE_OUTER_HEADER_REMOVAL))
+	assert.Nil(t, pfcp.FindIE(children, pfcp.IE_QER_ID))
+}
+
+func TestCodec_CreateFAR(t *testing.T) {
+	far := roundTrip(t, encodeFAR(pfcp.IE_CREATE_FAR, &FAR{
+		FARID:       2,
+		ApplyAction: "FORWARD",
+		ForwardingParameters: &ForwardingParameters{
+			DestinationInterface: "ACCESS",
+			NetworkInstance:      testDNN,
+			OuterHeaderCreation:  &OuterHeaderCreation{TEID: testGNBTEID, IPv4: testGNBIP},
+		},
+	}))
+
+	assert.Equal(t, uint32(2), uint32Value(t, child(t, far, pfcp.IE_FAR_ID)))
+	assert.Equal(t, uint8(pfcp.APPLY_ACTION_FORW), uint8Value(t, child(t, far, pfcp.IE_APPLY_ACTION)))
+
+	params := child(t, far, pfcp.IE_FORWARDING_PARAMETERS)
+	assert.Equal(t, uint8(pfcp.INTERFACE_ACCESS), uint8Value(t, child(t, params, pfcp.IE_DESTINATION_INTERFACE)))
+	assert.Equal(t, testDNN, string(child(t, params, pfcp.IE_NETWORK_INSTANCE).Value))
+
+	ohc, err := child(t, params, pfcp.IE_OUTER_HEADER_CREATION).OuterHeaderCreation()
+	require.NoError(t, err)
+	assert.Equal(t, uint16(pfcp.OUTER_HEADER_CREATION_GTPU_UDP_IPV4), ohc.Description)
+	assert.Equal(t, uint32(testGNBTEID), ohc.TEID)
+	assert.True(t, ohc.IPv4.Equal(net.ParseIP(testGNBIP)))
+}
+
+func TestCodec_UpdateFAR(t *testing.T) {
+	far := roundTrip(t, encodeFAR(pfcp.IE_UPDATE_FAR, &FAR{
+		FARID:       2,
+		ApplyAction: "FORWARD",
+		ForwardingParameters: &ForwardingParameters{
+			DestinationInterface: "ACCESS",
+			OuterHeaderCreation:  &OuterHeaderCreation{TEID: 0x300, IPv4: testTargetIP},
+		},
+	}))
+
+	// Update FAR carries Update Forwarding Parameters
+	children, err := far.ChildIEs()
+	require.NoError(t, err)
+	assert.Nil(t, pfcp.FindIE(children, pfcp.IE_FORWARDING_PARAMETERS))
+	params := child(t, far, pfcp.IE_UPDATE_FORWARDING_PARAMS)
+
+	ohc, err := child(t, params, pfcp.IE_OUTER_HEADER_CREATION).OuterHeaderCreation()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0x300), ohc.TEID)
+	assert.True(t, ohc.IPv4.Equal(net.ParseIP(testTargetIP)))
+}
+
+func TestCodec_ApplyAction(t *testing.T) {
+	tests := map[string]uint8{
+		"FORWARD": pfcp.APPLY_ACTION_FORW,
+		"DROP":    pfcp.APPLY_ACTION_DROP,
+		"BUFFER":  pfcp.APPLY_ACTION_BUFF | pfcp.APPLY_ACTION_NOCP,
+	}
+	for action, flags := range tests {
+		t.Run(action, func(t *testing.T) {
+			far := roundTrip(t, encodeFAR(pfcp.IE_CREATE_FAR, &FAR{FARID: 1, ApplyAction: action}))
+			assert.Equal(t, flags, uint8Value(t, child(t, far, pfcp.IE_APPLY_ACTION)))
+
+			children, err := far.ChildIEs()
+			require.NoError(t, err)
+			assert.Nil(t, pfcp.FindIE(children, pfcp.IE_FORWARDING_PARAMETERS))
+		})
+	}
+}
+
+func TestCodec_CreateQER(t *testing.T) {
+	qer := roundTrip(t, encodeQER(pfcp.IE_CREATE_QER, &QER{
+		QERID:       1,
+		QFI:         5,
+		MBRUplink:   100_000_000,
+		MBRDownlink: 200_000_000,
+		GBRUplink:   10_000_000,
+		GBRDownlink: 20_000_000,
+	}))
+
+	assert.Equal(t, uint32(1), uint32Value(t, child(t, qer, pfcp.IE_QER_ID)))
+	assert.Equal(t, uint8(5), uint8Value(t, child(t, qer, pfcp.IE_QFI)))
+
+	ul, dl, err := child(t, qer, pfcp.IE_GATE_STATUS).GateStatus()
+	require.NoError(t, err)
+	assert.Equal(t, uint8(pfcp.GATE_STATUS_OPEN), ul)
+	assert.Equal(t, uint8(pfcp.GATE_STATUS_OPEN), dl)
+
+	mbr, err := child(t, qer, pfcp.IE_MBR).BitRate()
+	require.NoError(t, err)
+	assert.Equal(t, &pfcp.BitRate{Uplink: 100_000_000, Downlink: 200_000_000}, mbr)
+
+	gbr, err := child(t, qer, pfcp.IE_GBR).BitRate()
+	require.NoError(t, err)
+	assert.Equal(t, &pfcp.BitRate{Uplink: 10_000_000, Downlink: 20_000_000}, gbr)
+
+	// Bit rates and QFI are omitted when unset
+	qer = roundTrip(t, encodeQER(pfcp.IE_UPDATE_QER, &QER{QERID: 2}))
+	children, err := qer.ChildIEs()
+	require.NoError(t, err)
+	assert.Nil(t, pfcp.FindIE(children, pfcp.IE_MBR))
+	assert.Nil(t, pfcp.FindIE(children, pfcp.IE_GBR))
+	assert.Nil(t, pfcp.FindIE(children, pfcp.IE_QFI))
+	assert.Nil(t, pfcp.FindIE(children, pfcp.IE_QOS_MONITORING_CONTROL))
+}
+
+func TestCodec_QERQoSMonitoring(t *testing.T) {
+	qer := roundTrip(t, encodeQER(pfcp.IE_CREATE_QER, &QER{
+		QERID: 1,
+		QFI:   5,
+		QoSMonitoring: &QoSMonitoring{
+			RequestDownlink:   true,
+			RequestRoundTrip:  true,
+			DownlinkThreshold: 20 * time.Millisecond,
+			MinimumWaitTime:   10 * time.Second,
+		},
+	}))
+
+	m, err := child(t, qer, pfcp.IE_QOS_MONITORING_CONTROL).QoSMonitoring()
+	require.NoError(t, err)
+	assert.Equal(t, &pfcp.QoSMonitoring{
+		Requested:         pfcp.QOS_MONITORING_DL | pfcp.QOS_MONITORING_RP,
+		DownlinkThreshold: 20 * time.Millisecond,
+		MinimumWaitTime:   10 * time.Second,
+	}, m)
+}
+
+func TestCodec_Remove(t *testing.T) {
+	ie := roundTrip(t, encodeRemove(pfcp.IE_REMOVE_PDR, pfcp.NewUint16IE(pfcp.IE_PDR_ID, 10)))
+	pdrID, err := child(t, ie, pfcp.IE_PDR_ID).Uint16()
+	require.NoError(t, err)
+	assert.Equal(t, uint16(10), pdrID)
+}
+
+func TestCodec_DecodeCause(t *testing.T) {
+	cause, err := decodeCause(pfcp.NewMessage(pfcp.PFCP_HEARTBEAT_RESPONSE, 1, pfcp.NewCauseIE(pfcp.CAUSE_REQUEST_ACCEPTED)))
+	require.NoError(t, err)
+	assert.Equal(t, "Request accepted", cause)
+	assert.NoError(t, ValidatePFCPResponse(cause))
+
+	cause, err = decodeCause(pfcp.NewMessage(pfcp.PFCP_HEARTBEAT_RESPONSE, 1, pfcp.NewCauseIE(pfcp.CAUSE_SESSION_CONTEXT_NOT_FOUND)))
+	require.NoError(t, err)
+	assert.Equal(t, "Session context not found", cause)
+	assert.Error(t, ValidatePFCPResponse(cause))
+
+	cause, err = decodeCause(pfcp.NewMessage(pfcp.PFCP_HEARTBEAT_RESPONSE, 1, pfcp.NewCauseIE(250)))
+	require.NoError(t, err)
+	assert.Equal(t, "Cause 250", cause)
+
+	_, err = decodeCause(pfcp.NewMessage(pfcp.PFCP_HEARTBEAT_RESPONSE, 1))
+	assert.Error(t, err)
+}
+
+func TestCodec_DecodeCreatedPDRs(t *testing.T) {
+	resp := pfcp.NewSessionMessage(pfcp.PFCP_SESSION_ESTABLISHMENT_RESPONSE, testSEID, 1,
+		pfcp.NewCauseIE(pfcp.CAUSE_REQUEST_ACCEPTED),
+		pfcp.NewGroupedIE(pfcp.IE_CREATED_PDR,
+			pfcp.NewUint16IE(pfcp.IE_PDR_ID, 1),
+			pfcp.NewFTEIDIE(&pfcp.FTEID{TEID: 0xabcd, IPv4: net.ParseIP(testN3IP)}),
+		),
+	)
+	parsed, err := pfcp.Parse(resp.Marshal())
+	require.NoError(t, err)
+
+	created, err := decodeCreatedPDRs(parsed)
+	require.NoError(t, err)
+	require.Len(t, created, 1)
+	assert.Equal(t, uint16(1), created[0].PDRID)
+	assert.Equal(t, &FTEID{TEID: 0xabcd, IPv4: testN3IP}, created[0].FTEID)
+
+	// A Created PDR must carry the allocated F-TEID
+	resp = pfcp.NewSessionMessage(pfcp.PFCP_SESSION_ESTABLISHMENT_RESPONSE, testSEID, 1,
+		pfcp.NewGroupedIE(pfcp.IE_CREATED_PDR, pfcp.NewUint16IE(pfcp.IE_PDR_ID, 1)),
+	)
+	_, err = decodeCreatedPDRs(resp)
+	assert.Error(t, err)
+}
@@ -0,0 +1,573 @@
+package n4
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/common/dataplane"
+	"github.com/your-org/5g-network/nf/upf/upftest"
+)
+
+const (
+	testSEID     = 0x5eed
+	testUEIP     = "10.60.0.7"
+	testDNN      = "internet"
+	testGNBTEID  = 0x100
+	testGNBIP    = "192.168.1.10"
+	testN3IP     = "192.168.1.1"
+	testUPFNode  = "upf.test"
+	testSMFNode  = "127.0.0.1"
+	testTargetIP = "192.168.2.20"
+)
+
+// newIntegrationClient starts an in-process UPF and dials it over PFCP
+func newIntegrationClient(t *testing.T) (*PFCPClient, *upftest.UPF) {
+	t.Helper()
+
+	logger, _ := zap.NewDevelopment()
+	upf, err := upftest.Start(testUPFNode, testN3IP, logger)
+	require.NoError(t, err)
+	t.Cleanup(upf.Close)
+
+	client, err := DialPFCPClient(testSMFNode, testUPFNode, upf.Addr, logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	return client, upf
+}
+
+// establishmentRequest mirrors the rules the session service installs for a
+// PDU session with a single default QoS flow
+func establishmentRequest() *SessionEstablishmentRequest {
+	return &SessionEstablishmentRequest{
+		NodeID:        testUPFNode,
+		SEID:          testSEID,
+		UEIPv4Address: testUEIP,
+		DNN:           testDNN,
+		PDRs: []PDR{
+			{
+				PDRID:      1,
+				Precedence: 100,
+				PDI: PDI{
+					SourceInterface: "ACCESS",
+					FTEID:           &FTEID{CHID: 1},
+					UEIPAddress:     testUEIP,
+					NetworkInstance: testDNN,
+					QFI:             1,
+				},
+				OuterHeaderRemoval: true,
+				FARID:              1,
+				QERIDs:             []uint16{2, 1},
+			},
+			{
+				PDRID:      2,
+				Precedence: 100,
+				PDI: PDI{
+					SourceInterface: "CORE",
+					UEIPAddress:     testUEIP,
+					NetworkInstance: testDNN,
+					QFI:             1,
+				},
+				FARID:  2,
+				QERIDs: []uint16{2, 1},
+			},
+		},
+		FARs: []FAR{
+			{
+				FARID:       1,
+				ApplyAction: "FORWARD",
+				ForwardingParameters: &ForwardingParameters{
+					DestinationInterface: "CORE",
+					NetworkInstance:      testDNN,
+				},
+			},
+			{
+				FARID:       2,
+				ApplyAction: "FORWARD",
+				ForwardingParameters: &ForwardingParameters{
+					DestinationInterface: "ACCESS",
+					NetworkInstance:      testDNN,
+					OuterHeaderCreation: &OuterHeaderCreation{
+						TEID: testGNBTEID,
+						IPv4: testGNBIP,
+					},
+				},
+			},
+		},
+		QERs: []QER{
+			{QERID: 1, MBRUplink: 100_000_000, MBRDownlink: 200_000_000},
+			{QERID: 2, QFI: 1},
+		},
+	}
+}
+
+func TestPFCPIntegration_SessionLifecycle(t *testing.T) {
+	client, upf := newIntegrationClient(t)
+
+	require.NoError(t, client.AssociatePFCPSession())
+	require.NoError(t, client.SendHeartbeat())
+
+	// Establishment
+	estResp, err := client.EstablishSession(establishmentRequest())
+	require.NoError(t, err)
+	require.NoError(t, ValidatePFCPResponse(estResp.Cause))
+	require.NotNil(t, estResp.UPFTEID)
+	assert.NotZero(t, estResp.UPFTEID.TEID)
+	assert.Equal(t, testN3IP, estResp.UPFTEID.IPv4)
+
+	session, ok := upf.Session(testSEID)
+	require.True(t, ok)
+	assert.Equal(t, estResp.UPFTEID.TEID, session.UPFTEID)
+	assert.True(t, session.UEAddress.Equal(net.ParseIP(testUEIP)))
+	assert.Equal(t, testDNN, session.DNN)
+	assert.Equal(t, uint32(testGNBTEID), session.GNBTEID)
+	assert.True(t, session.GNBAddress.Equal(net.ParseIP(testGNBIP)))
+
+	require.Len(t, session.PDRs, 2)
+	uplink := session.PDRs[0]
+	assert.Equal(t, uint16(1), uplink.PDRID)
+	assert.Equal(t, uint8(0), uplink.PDI.SourceInterface)
+	require.NotNil(t, uplink.PDI.FTEID)
+	assert.Equal(t, estResp.UPFTEID.TEID, uplink.PDI.FTEID.TEID)
+	assert.Equal(t, uint8(1), uplink.PDI.QFI)
+	assert.Equal(t, uint8(1), uplink.OuterHeaderRemoval)
+	assert.Equal(t, []uint32{2, 1}, uplink.QERIDs)
+
+	downlink := session.PDRs[1]
+	assert.Equal(t, uint8(1), downlink.PDI.SourceInterface)
+	assert.True(t, downlink.PDI.UEIPAddress.Equal(net.ParseIP(testUEIP)))
+	assert.Equal(t, uint32(2), downlink.FARID)
+
+	require.Len(t, session.FARs, 2)
+	assert.Equal(t, uint8(0x02), session.FARs[1].ApplyAction)
+	require.NotNil(t, session.FARs[1].ForwardingParameters.OuterHeaderCreation)
+	assert.Equal(t, uint32(testGNBTEID), session.FARs[1].ForwardingParameters.OuterHeaderCreation.TEID)
+
+	require.Len(t, session.QERs, 2)
+	require.NotNil(t, session.QERs[0].MBR)
+	assert.Equal(t, uint64(100_000_000), session.QERs[0].MBR.Uplink)
+	assert.Equal(t, uint64(200_000_000), session.QERs[0].MBR.Downlink)
+	assert.Equal(t, uint8(1), session.QERs[1].QFI)
+
+	// Modification: path switch to a new gNB and an indirect forwarding
+	// tunnel allocated by the UPF
+	modResp, err := client.ModifySession(&SessionModificationRequest{
+		SEID: testSEID,
+		UpdateFARs: []FAR{{
+			FARID:       2,
+			ApplyAction: "FORWARD",
+			ForwardingParameters: &ForwardingParameters{
+				DestinationInterface: "ACCESS",
+				NetworkInstance:      testDNN,
+				OuterHeaderCreation: &OuterHeaderCreation{
+					TEID: 0x300,
+					IPv4: testTargetIP,
+				},
+			},
+		}},
+		CreatePDRs: []PDR{{
+			PDRID:      10,
+			Precedence: 50,
+			PDI: PDI{
+				SourceInterface: "ACCESS",
+				FTEID:           &FTEID{},
+				NetworkInstance: testDNN,
+			},
+			OuterHeaderRemoval: true,
+			FARID:              2,
+		}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, ValidatePFCPResponse(modResp.Cause))
+	require.Len(t, modResp.CreatedPDRs, 1)
+	forwardingTEID := modResp.CreatedPDRs[0].FTEID.TEID
+	assert.NotZero(t, forwardingTEID)
+	assert.NotEqual(t, estResp.UPFTEID.TEID, forwardingTEID)
+
+	session, ok = upf.Session(testSEID)
+	require.True(t, ok)
+	assert.Equal(t, uint32(0x300), session.GNBTEID)
+	assert.True(t, session.GNBAddress.Equal(net.ParseIP(testTargetIP)))
+	assert.Equal(t, estResp.UPFTEID.TEID, session.UPFTEID)
+	require.Len(t, session.PDRs, 3)
+	assert.Equal(t, forwardingTEID, session.PDRs[2].PDI.FTEID.TEID)
+
+	// Deletion
+	delResp, err := client.DeleteSession(&SessionDeletionRequest{SEID: testSEID})
+	require.NoError(t, err)
+	require.NoError(t, ValidatePFCPResponse(delResp.Cause))
+
+	_, ok = upf.Session(testSEID)
+	assert.False(t, ok)
+
+	seids, err := client.AuditSessions()
+	require.NoError(t, err)
+	assert.Empty(t, seids)
+}
+
+func TestPFCPIntegration_AuditAfterSMFRestart(t *testing.T) {
+	client, upf := newIntegrationClient(t)
+	store, err := NewFileSEIDStore(t.TempDir())
+	require.NoError(t, err)
+	require.NoError(t, client.SetSEIDStore(store))
+	require.NoError(t, client.AssociatePFCPSession())
+
+	const lostSEID = testSEID + 1
+	for i, seid := range []uint64{testSEID, lostSEID} {
+		req := establishmentRequest()
+		req.SEID = seid
+		req.UEIPv4Address = fmt.Sprintf("10.60.0.%d", 10+i)
+		for j := range req.PDRs {
+			req.PDRs[j].PDI.UEIPAddress = req.UEIPv4Address
+		}
+		resp, err := client.EstablishSession(req)
+		require.NoError(t, err)
+		require.NoError(t, ValidatePFCPResponse(resp.Cause))
+	}
+
+	// The UPF loses a session while the SMF is down
+	lost, ok := upf.Session(lostSEID)
+	require.True(t, ok)
+	upf.Context.DeleteSession(lost.SEID)
+	require.NoError(t, client.Close())
+
+	// The restarted SMF only knows its sessions from the SEID store
+	logger, _ := zap.NewDevelopment()
+	restarted, err := DialPFCPClient(testSMFNode, testUPFNode, upf.Addr, logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { restarted.Close() })
+	require.NoError(t, restarted.SetSEIDStore(store))
+	require.NoError(t, restarted.AssociatePFCPSession())
+
+	// The audit reports what the UPF holds. Setting up the association may
+	// already have deleted the old sessions if the SMF Recovery Time Stamp
+	// changed (TS 29.244 6.2.6.2.2).
+	var held []uint64
+	if _, ok := upf.Session(testSEID); ok {
+		held = append(held, testSEID)
+	}
+	seids, err := restarted.AuditSessions()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, held, seids)
+
+	persisted, err := store.Load(testUPFNode)
+	require.NoError(t, err)
+	assert.Len(t, persisted, len(held))
+	assert.NotContains(t, persisted, uint64(lostSEID))
+}
+
+func TestPFCPIntegration_EstablishmentWithoutAssociation(t *testing.T) {
+	client, upf := newIntegrationClient(t)
+
+	resp, err := client.EstablishSession(establishmentRequest())
+	require.NoError(t, err)
+	assert.Error(t, ValidatePFCPResponse(resp.Cause))
+
+	_, ok := upf.Session(testSEID)
+	assert.False(t, ok)
+}
+
+func TestPFCPIntegration_AssociationSetupAndRelease(t *testing.T) {
+	client, upf := newIntegrationClient(t)
+	assert.False(t, client.IsAssociated())
+
+	require.NoError(t, client.AssociatePFCPSession())
+	assert.True(t, client.IsAssociated())
+	assert.False(t, client.UPFRecoveryTime().IsZero())
+	require.NoError(t, client.SendHeartbeat())
+
+	resp, err := client.EstablishSession(establishmentRequest())
+	require.NoError(t, err)
+	require.NoError(t, ValidatePFCPResponse(resp.Cause))
+	session, ok := upf.Session(testSEID)
+	require.True(t, ok)
+	assert.Equal(t, testSMFNode, session.SMFNodeID)
+
+	// Releasing the association deletes its sessions on the UPF
+	require.NoError(t, client.ReleaseAssociation())
+	assert.False(t, client.IsAssociated())
+	_, ok = upf.Session(testSEID)
+	assert.False(t, ok)
+
+	resp, err = client.EstablishSession(establishmentRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "No established PFCP association", resp.Cause)
+
+	// Releasing twice is a no-op
+	require.NoError(t, client.ReleaseAssociation())
+}
+
+func TestPFCPIntegration_HeartbeatTimeoutsFailAssociation(t *testing.T) {
+	client, upf := newIntegrationClient(t)
+	client.SetRequestTimeout(20*time.Millisecond, 0)
+	client.SetHeartbeatFailureThreshold(2)
+
+	require.NoError(t, client.AssociatePFCPSession())
+	require.NoError(t, client.SendHeartbeat())
+	assert.Equal(t, AssociationEstablished, client.AssociationState())
+
+	upf.Close()
+
+	err := client.SendHeartbeat()
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrAssociationFailed)
+	assert.Equal(t, AssociationEstablished, client.AssociationState(), "a single timeout is tolerated")
+
+	err = client.SendHeartbeat()
+	assert.ErrorIs(t, err, ErrAssociationFailed)
+	assert.Equal(t, AssociationFailed, client.AssociationState())
+	assert.False(t, client.IsAssociated())
+
+	// Only the transition is reported as ErrAssociationFailed
+	err = client.SendHeartbeat()
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrAssociationFailed)
+}
+
+func TestPFCPIntegration_HeartbeatDetectsUPFRestart(t *testing.T) {
+	client, upf := newIntegrationClient(t)
+
+	require.NoError(t, client.AssociatePFCPSession())
+	recoveryTime := client.UPFRecoveryTime()
+	require.NoError(t, client.SendHeartbeat())
+
+	require.NoError(t, upf.Restart())
+	assert.ErrorIs(t, client.SendHeartbeat(), ErrUPFRestarted)
+	assert.Equal(t, AssociationIdle, client.AssociationState())
+
+	// A new association picks up the new Recovery Time Stamp
+	require.NoError(t, client.AssociatePFCPSession())
+	assert.True(t, client.UPFRecoveryTime().After(recoveryTime))
+	require.NoError(t, client.SendHeartbeat())
+}
+
+func TestPFCPIntegration_ModifyUnknownSession(t *testing.T) {
+	client, _ := newIntegrationClient(t)
+	require.NoError(t, client.AssociatePFCPSession())
+
+	_, err := client.ModifySession(&SessionModificationRequest{SEID: testSEID})
+	assert.Error(t, err)
+}
+
+func TestPFCPIntegration_AddAndRemoveQoSFlow(t *testing.T) {
+	client, upf := newIntegrationClient(t)
+	require.NoError(t, client.AssociatePFCPSession())
+
+	estResp, err := client.EstablishSession(establishmentRequest())
+	require.NoError(t, err)
+	require.NoError(t, ValidatePFCPResponse(estResp.Cause))
+
+	// Add a GBR flow matching the uplink F-TEID allocated at establishment
+	modResp, err := client.ModifySession(&SessionModificationRequest{
+		SEID: testSEID,
+		CreateQERs: []QER{{
+			QERID:       6,
+			QFI:         5,
+			GBRUplink:   128_000,
+			GBRDownlink: 128_000,
+		}},
+		CreatePDRs: []PDR{
+			{
+				PDRID:      9,
+				Precedence: 100,
+				PDI: PDI{
+					SourceInterface: "ACCESS",
+					FTEID:           &FTEID{TEID: estResp.UPFTEID.TEID, IPv4: estResp.UPFTEID.IPv4},
+					UEIPAddress:     testUEIP,
+					NetworkInstance: testDNN,
+					QFI:             5,
+				},
+				OuterHeaderRemoval: true,
+				FARID:              1,
+				QERIDs:             []uint16{6},
+			},
+			{
+				PDRID:      10,
+				Precedence: 100,
+				PDI: PDI{
+					SourceInterface: "CORE",
+					UEIPAddress:     testUEIP,
+					NetworkInstance: testDNN,
+					QFI:             5,
+				},
+				FARID:  2,
+				QERIDs: []uint16{6},
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.NoError(t, ValidatePFCPResponse(modResp.Cause))
+	assert.Empty(t, modResp.CreatedPDRs)
+
+	session, ok := upf.Session(testSEID)
+	require.True(t, ok)
+	require.Len(t, session.PDRs, 4)
+	assert.Equal(t, estResp.UPFTEID.TEID, session.PDRs[2].PDI.FTEID.TEID)
+	require.Len(t, session.QERs, 3)
+	assert.Equal(t, uint8(5), session.QERs[2].QFI)
+
+	// Remove it again
+	modResp, err = client.ModifySession(&SessionModificationRequest{
+		SEID:       testSEID,
+		RemovePDRs: []uint16{9, 10},
+		RemoveQERs: []uint16{6},
+	})
+	require.NoError(t, err)
+	require.NoError(t, ValidatePFCPResponse(modResp.Cause))
+
+	session, ok = upf.Session(testSEID)
+	require.True(t, ok)
+	assert.Len(t, session.PDRs, 2)
+	assert.Len(t, session.QERs, 2)
+}
+
+func TestPFCPIntegration_DownlinkDataReport(t *testing.T) {
+	client, upf := newIntegrationClient(t)
+	require.NoError(t, client.AssociatePFCPSession())
+
+	reports := make(chan *SessionReportRequest, 1)
+	client.SetSessionReportHandler(func(req *SessionReportRequest) (*SessionReportResponse, error) {
+		reports <- req
+		return &SessionReportResponse{SEID: req.SEID, Cause: "Request accepted"}, nil
+	})
+
+	estResp, err := client.EstablishSession(establishmentRequest())
+	require.NoError(t, err)
+	require.NoError(t, ValidatePFCPResponse(estResp.Cause))
+
+	// The UE goes idle: the UPF buffers downlink data
+	modResp, err := client.ModifySession(&SessionModificationRequest{
+		SEID:       testSEID,
+		UpdateFARs: []FAR{{FARID: 2, ApplyAction: "BUFFER"}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, ValidatePFCPResponse(modResp.Cause))
+
+	require.NoError(t, upf.DataPlane.ProcessPacket(context.Background(), &dataplane.Packet{
+		Data: make([]byte, 100), Interface: "N6",
+		SrcIP: net.ParseIP("8.8.8.8"), DstIP: net.ParseIP(testUEIP),
+	}))
+
+	select {
+	case report := <-reports:
+		assert.Equal(t, uint64(testSEID), report.SEID)
+		require.NotNil(t, report.DownlinkDataReport)
+		assert.Equal(t, uint16(2), report.DownlinkDataReport.PDRID)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no downlink data report received")
+	}
+
+	// The UE is reachable again: forwarding to the gNB releases the buffer
+	modResp, err = client.ModifySession(&SessionModificationRequest{
+		SEID: testSEID,
+		UpdateFARs: []FAR{{
+			FARID:       2,
+			ApplyAction: "FORWARD",
+			ForwardingParameters: &ForwardingParameters{
+				DestinationInterface: "ACCESS",
+				NetworkInstance:      testDNN,
+				OuterHeaderCreation:  &OuterHeaderCreation{TEID: testGNBTEID, IPv4: testGNBIP},
+			},
+		}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, ValidatePFCPResponse(modResp.Cause))
+
+	stats, err := upf.DataPlane.GetStats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), stats.PacketsForwarded)
+}
+
+func TestPFCPIntegration_QoSMonitoringReport(t *testing.T) {
+	client, upf := newIntegrationClient(t)
+	require.NoError(t, client.AssociatePFCPSession())
+
+	reports := make(chan *SessionReportRequest, 1)
+	client.SetSessionReportHandler(func(req *SessionReportRequest) (*SessionReportResponse, error) {
+		reports <- req
+		return &SessionReportResponse{SEID: req.SEID, Cause: "Request accepted"}, nil
+	})
+
+	req := establishmentRequest()
+	req.QERs[1].QoSMonitoring = &QoSMonitoring{RequestUplink: true, UplinkThreshold: 10 * time.Millisecond}
+	estResp, err := client.EstablishSession(req)
+	require.NoError(t, err)
+	require.NoError(t, ValidatePFCPResponse(estResp.Cause))
+
+	// The gNB stamped the uplink packet 50 ms before it arrived
+	received := time.Now()
+	require.NoError(t, upf.DataPlane.ProcessPacket(context.Background(), &dataplane.Packet{
+		Data: make([]byte, 100), Interface: "N3", Timestamp: received,
+		TEID: estResp.UPFTEID.TEID, QFI: 1,
+		SrcIP: net.ParseIP(testUEIP), DstIP: net.ParseIP("8.8.8.8"),
+		SendTimestamp: received.Add(-50 * time.Millisecond),
+	}))
+
+	select {
+	case report := <-reports:
+		assert.Equal(t, uint64(testSEID), report.SEID)
+		require.Len(t, report.QoSMonitoringReports, 1)
+		assert.Equal(t, uint8(1), report.QoSMonitoringReports[0].QFI)
+		assert.Equal(t, 50*time.Millisecond, report.QoSMonitoringReports[0].UplinkDelay)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no QoS monitoring report received")
+	}
+}
+
+func TestPFCPIntegration_UsageReport(t *testing.T) {
+	client, upf := newIntegrationClient(t)
+	require.NoError(t, client.AssociatePFCPSession())
+
+	reports := make(chan *SessionReportRequest, 1)
+	client.SetSessionReportHandler(func(req *SessionReportRequest) (*SessionReportResponse, error) {
+		reports <- req
+		return &SessionReportResponse{SEID: req.SEID, Cause: "Request accepted"}, nil
+	})
+
+	req := establishmentRequest()
+	req.URRs = []URR{{URRID: 1, MeasureVolume: true, VolumeThreshold: 150}}
+	for i := range req.PDRs {
+		req.PDRs[i].URRIDs = []uint32{1}
+	}
+	estResp, err := client.EstablishSession(req)
+	require.NoError(t, err)
+	require.NoError(t, ValidatePFCPResponse(estResp.Cause))
+
+	for _, packet := range []*dataplane.Packet{
+		{Data: make([]byte, 100), Interface: "N3", TEID: estResp.UPFTEID.TEID, QFI: 1,
+			SrcIP: net.ParseIP(testUEIP), DstIP: net.ParseIP("8.8.8.8")},
+		{Data: make([]byte, 60), Interface: "N6",
+			SrcIP: net.ParseIP("8.8.8.8"), DstIP: net.ParseIP(testUEIP)},
+	} {
+		require.NoError(t, upf.DataPlane.ProcessPacket(context.Background(), packet))
+	}
+
+	select {
+	case report := <-reports:
+		assert.Equal(t, uint64(testSEID), report.SEID)
+		require.Len(t, report.UsageReports, 1)
+		usage := report.UsageReports[0]
+		assert.Equal(t, uint32(1), usage.URRID)
+		assert.Equal(t, uint64(160), usage.TotalVolume)
+		assert.Equal(t, uint64(100), usage.UplinkVolume)
+		assert.Equal(t, uint64(60), usage.DownlinkVolume)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no usage report received")
+	}
+
+	// A URR still referenced by the PDRs cannot be removed
+	modResp, err := client.ModifySession(&SessionModificationRequest{
+		SEID:       testSEID,
+		UpdatePDRs: []PDR{},
+		RemoveURRs: []uint32{1},
+	})
+	require.NoError(t, err)
+	assert.Error(t, ValidatePFCPResponse(modResp.Cause))
+}
@@ -2,8 +2,12 @@ package n4
 
 import (
 	"fmt"
+	"net"
+	"sort"
+	"sync"
 	"time"
 
+	"github.com/your-org/5g-network/common/pfcp"
 	"go.uber.org/zap"
 )
 
@@ -14,18 +18,86 @@ type PFCPClient struct {
 	upfN4Address string
 	logger       *zap.Logger
 
+	// N4 transport, nil when the UPF is simulated
+	nodeID    string
+	transport *transport
+
+	// PFCP association state (TS 29.244 6.2.6) and heartbeat failure
+	// detection
+	assocMu              sync.Mutex
+	assocState           AssociationState
+	upfRecoveryTime      time.Time
+	heartbeatFailures    int
+	maxHeartbeatFailures int
+
 	// TEID counter for allocating F-TEIDs
 	teidCounter uint32
+
+	// Sessions established on the UPF, checked by AuditSessions. Maps the
+	// SMF (CP) SEID to the SEID allocated by the UPF and is written through
+	// to seidStore, if set.
+	sessionsMu sync.Mutex
+	sessions   map[uint64]uint64
+	seidStore  SEIDStore
+
+	// Handler of Session Report Requests from the UPF
+	reportMu      sync.Mutex
+	reportHandler SessionReportHandler
 }
 
-// NewPFCPClient creates a new PFCP client
+// NewPFCPClient creates a PFCP client that simulates the UPF responses
 func NewPFCPClient(upfNodeID, upfN4Address string, logger *zap.Logger) *PFCPClient {
 	return &PFCPClient{
-		upfNodeID:    upfNodeID,
-		upfN4Address: upfN4Address,
-		logger:       logger,
-		teidCounter:  1000, // Start TEID allocation from 1000
+		upfNodeID:            upfNodeID,
+		upfN4Address:         upfN4Address,
+		logger:               logger,
+		teidCounter:          1000, // Start TEID allocation from 1000
+		assocState:           AssociationIdle,
+		maxHeartbeatFailures: DefaultMaxHeartbeatFailures,
+		sessions:             make(map[uint64]uint64),
+	}
+}
+
+// DialPFCPClient creates a PFCP client that exchanges PFCP messages with the
+// UPF over UDP. nodeID is the SMF Node ID sent in association and session
+// establishment requests.
+func DialPFCPClient(nodeID, upfNodeID, upfN4Address string, logger *zap.Logger) (*PFCPClient, error) {
+	t, err := dialTransport(upfN4Address, logger)
+	if err != nil {
+		return nil, err
 	}
+
+	c := NewPFCPClient(upfNodeID, upfN4Address, logger)
+	c.nodeID = nodeID
+	c.transport = t
+	t.serve(c.handlePeerRequest)
+	return c, nil
+}
+
+// GetUPFInfo returns the Node ID and N4 address of the UPF
+func (c *PFCPClient) GetUPFInfo() (nodeID, n4Address string) {
+	return c.upfNodeID, c.upfN4Address
+}
+
+// SetRequestTimeout sets how long a request waits for a response and how
+// often it is retransmitted. It has no effect on simulated UPFs.
+func (c *PFCPClient) SetRequestTimeout(timeout time.Duration, retransmits int) {
+	if c.transport == nil {
+		return
+	}
+	c.transport.mu.Lock()
+	defer c.transport.mu.Unlock()
+
+	c.transport.timeout = timeout
+	c.transport.retransmits = retransmits
+}
+
+// Close releases the N4 socket
+func (c *PFCPClient) Close() error {
+	if c.transport == nil {
+		return nil
+	}
+	return c.transport.close()
 }
 
 // SessionEstablishmentRequest represents PFCP Session Establishment Request
@@ -33,6 +105,7 @@ type SessionEstablishmentRequest struct {
 	NodeID        string
 	SEID          uint64 // Session Endpoint Identifier
 	UEIPv4Address string
+	UEIPv6Prefix  string // Delegated prefix in CIDR notation
 	DNN           string
 
 	// PDR - Packet Detection Rule
@@ -43,6 +116,9 @@ type SessionEstablishmentRequest struct {
 
 	// QER - QoS Enforcement Rule
 	QERs []QER
+
+	// URR - Usage Reporting Rule
+	URRs []URR
 }
 
 // PDR represents Packet Detection Rule
@@ -51,8 +127,9 @@ type PDR struct {
 	Precedence         uint32
 	PDI                PDI // Packet Detection Information
 	OuterHeaderRemoval bool
-	FARID              uint16 // Associated FAR
-	QERID              uint16 // Associated QER
+	FARID              uint16   // Associated FAR
+	QERIDs             []uint16 // Associated QERs, all are enforced
+	URRIDs             []uint32 // Associated URRs
 }
 
 // PDI represents Packet Detection Information
@@ -60,7 +137,10 @@ type PDI struct {
 	SourceInterface string // "ACCESS", "CORE"
 	FTEID           *FTEID
 	UEIPAddress     string
+	UEIPv6Prefix    string // CIDR, e.g. "2001:db8:0:1::/64"
 	NetworkInstance string // DNN
+	QFI             uint8  // QoS flow to match, 0 matches any
+	SDFFilter       string // Flow description, e.g. "permit out ip from 10.0.0.0/8 to assigned"
 }
 
 // FTEID represents Fully Qualified Tunnel Endpoint Identifier
@@ -68,6 +148,7 @@ type FTEID struct {
 	TEID uint32
 	IPv4 string
 	IPv6 string
+	CHID uint8 // With TEID 0, PDRs sharing a Choose ID get the same F-TEID
 }
 
 // FAR represents Forwarding Action Rule
@@ -99,6 +180,32 @@ type QER struct {
 	GBRDownlink uint64
 	MBRUplink   uint64 // Maximum Bit Rate (bps)
 	MBRDownlink uint64
+
+	// QoS monitoring indicator, nil when monitoring is not requested
+	QoSMonitoring *QoSMonitoring
+}
+
+// QoSMonitoring represents QoS Monitoring per QoS flow Control Information
+// (3GPP TS 29.244 8.2.168)
+type QoSMonitoring struct {
+	RequestDownlink    bool
+	RequestUplink      bool
+	RequestRoundTrip   bool
+	DownlinkThreshold  time.Duration
+	UplinkThreshold    time.Duration
+	RoundTripThreshold time.Duration
+	MinimumWaitTime    time.Duration
+}
+
+// URR represents Usage Reporting Rule. The UPF reports the usage when a
+// threshold is reached or the measurement period elapses.
+type URR struct {
+	URRID             uint32
+	MeasureVolume     bool
+	MeasureDuration   bool
+	VolumeThreshold   uint64        // Total bytes, 0 for none
+	TimeThreshold     time.Duration // 0 for none
+	MeasurementPeriod time.Duration // Periodic reporting, 0 for none
 }
 
 // SessionEstablishmentResponse represents PFCP Session Establishment Response
@@ -119,15 +226,24 @@ type CreatedPDR struct {
 // SessionModificationRequest represents PFCP Session Modification Request
 type SessionModificationRequest struct {
 	SEID       uint64
+	CreatePDRs []PDR
+	CreateFARs []FAR
 	UpdatePDRs []PDR
 	UpdateFARs []FAR
+	CreateQERs []QER
 	UpdateQERs []QER
+	CreateURRs []URR
+	RemovePDRs []uint16
+	RemoveFARs []uint16
+	RemoveQERs []uint16
+	RemoveURRs []uint32
 }
 
 // SessionModificationResponse represents PFCP Session Modification Response
 type SessionModificationResponse struct {
-	SEID  uint64
-	Cause string
+	SEID        uint64
+	Cause       string
+	CreatedPDRs []CreatedPDR // PDRs for which the UPF allocated an F-TEID
 }
 
 // SessionDeletionRequest represents PFCP Session Deletion Request
@@ -141,6 +257,57 @@ type SessionDeletionResponse struct {
 	Cause string
 }
 
+// SessionReportRequest represents PFCP Session Report Request from UPF
+type SessionReportRequest struct {
+	SEID                 uint64
+	DownlinkDataReport   *DownlinkDataReport
+	ErrorIndication      *ErrorIndicationReport
+	QoSMonitoringReports []QoSMonitoringReport
+	UsageReports         []UsageReport
+}
+
+// DownlinkDataReport represents a Downlink Data Report IE (first packet notification)
+type DownlinkDataReport struct {
+	PDRID uint16
+	QFI   uint8
+}
+
+// ErrorIndicationReport represents an Error Indication Report IE: the peer
+// of the tunnel sent the UPF a GTP-U Error Indication
+type ErrorIndicationReport struct {
+	RemoteTEID    uint32
+	RemoteAddress net.IP
+}
+
+// QoSMonitoringReport represents a QoS Monitoring Report IE
+type QoSMonitoringReport struct {
+	QFI            uint8
+	DownlinkDelay  time.Duration
+	UplinkDelay    time.Duration
+	RoundTripDelay time.Duration
+	EventTime      time.Time
+}
+
+// UsageReport represents a Usage Report IE: the usage a URR measured since
+// its previous report
+type UsageReport struct {
+	URRID          uint32
+	SequenceNumber uint32
+	Trigger        uint32 // Usage Report Trigger flags
+	StartTime      time.Time
+	EndTime        time.Time
+	TotalVolume    uint64 // Bytes
+	UplinkVolume   uint64
+	DownlinkVolume uint64
+	Duration       time.Duration
+}
+
+// SessionReportResponse represents PFCP Session Report Response
+type SessionReportResponse struct {
+	SEID  uint64
+	Cause string
+}
+
 // EstablishSession sends PFCP Session Establishment Request to UPF
 func (c *PFCPClient) EstablishSession(req *SessionEstablishmentRequest) (*SessionEstablishmentResponse, error) {
 	c.logger.Info("Sending PFCP Session Establishment Request to UPF",
@@ -148,36 +315,46 @@ func (c *PFCPClient) EstablishSession(req *SessionEstablishmentRequest) (*Sessio
 		zap.String("upf_address", c.upfN4Address),
 		zap.Uint64("seid", req.SEID),
 		zap.String("ue_ip", req.UEIPv4Address),
+		zap.String("ue_ipv6_prefix", req.UEIPv6Prefix),
 		zap.String("dnn", req.DNN),
 	)
 
-	// TODO: Implement actual PFCP protocol encoding/decoding
-	// For now, simulate successful response
+	if c.transport != nil {
+		return c.establishSession(req)
+	}
 
 	time.Sleep(10 * time.Millisecond) // Simulate network delay
 
-	// Allocate F-TEID for UPF
-	upfTEID := c.allocateTEID()
-
 	response := &SessionEstablishmentResponse{
 		NodeID: c.upfNodeID,
 		SEID:   req.SEID,
 		Cause:  "Request accepted",
-		UPFTEID: &FTEID{
-			TEID: upfTEID,
-			IPv4: c.extractIPFromAddress(c.upfN4Address),
-		},
-		CreatedPDRs: []CreatedPDR{
-			{
-				PDRID: req.PDRs[0].PDRID,
-				FTEID: &FTEID{
-					TEID: upfTEID,
-					IPv4: c.extractIPFromAddress(c.upfN4Address),
-				},
-			},
-		},
 	}
 
+	// Allocate F-TEIDs for PDRs that let the UPF choose, sharing one per
+	// Choose ID
+	chosen := make(map[uint8]*FTEID)
+	for _, pdr := range req.PDRs {
+		if pdr.PDI.FTEID == nil || pdr.PDI.FTEID.TEID != 0 {
+			continue
+		}
+		fteid, ok := chosen[pdr.PDI.FTEID.CHID]
+		if !ok || pdr.PDI.FTEID.CHID == 0 {
+			fteid = &FTEID{
+				TEID: c.allocateTEID(),
+				IPv4: c.extractIPFromAddress(c.upfN4Address),
+			}
+			chosen[pdr.PDI.FTEID.CHID] = fteid
+		}
+		response.CreatedPDRs = append(response.CreatedPDRs, CreatedPDR{PDRID: pdr.PDRID, FTEID: fteid})
+	}
+	if response.UPFTEID = n3FTEID(req, response.CreatedPDRs); response.UPFTEID == nil {
+		return nil, fmt.Errorf("no PDR carries an N3 F-TEID")
+	}
+	upfTEID := response.UPFTEID.TEID
+
+	c.recordSession(req.SEID, req.SEID)
+
 	c.logger.Info("PFCP Session Establishment successful",
 		zap.Uint64("seid", response.SEID),
 		zap.Uint32("upf_teid", upfTEID),
@@ -192,7 +369,10 @@ func (c *PFCPClient) ModifySession(req *SessionModificationRequest) (*SessionMod
 		zap.Uint64("seid", req.SEID),
 	)
 
-	// TODO: Implement actual PFCP protocol
+	if c.transport != nil {
+		return c.modifySession(req)
+	}
+
 	time.Sleep(10 * time.Millisecond)
 
 	response := &SessionModificationResponse{
@@ -200,6 +380,19 @@ func (c *PFCPClient) ModifySession(req *SessionModificationRequest) (*SessionMod
 		Cause: "Request accepted",
 	}
 
+	// Allocate F-TEIDs for created PDRs that let the UPF choose (CH flag)
+	for _, pdr := range req.CreatePDRs {
+		if pdr.PDI.FTEID != nil && pdr.PDI.FTEID.TEID == 0 {
+			response.CreatedPDRs = append(response.CreatedPDRs, CreatedPDR{
+				PDRID: pdr.PDRID,
+				FTEID: &FTEID{
+					TEID: c.allocateTEID(),
+					IPv4: c.extractIPFromAddress(c.upfN4Address),
+				},
+			})
+		}
+	}
+
 	c.logger.Info("PFCP Session Modification successful",
 		zap.Uint64("seid", response.SEID),
 	)
@@ -213,9 +406,14 @@ func (c *PFCPClient) DeleteSession(req *SessionDeletionRequest) (*SessionDeletio
 		zap.Uint64("seid", req.SEID),
 	)
 
-	// TODO: Implement actual PFCP protocol
+	if c.transport != nil {
+		return c.deleteSession(req)
+	}
+
 	time.Sleep(10 * time.Millisecond)
 
+	c.forgetSession(req.SEID)
+
 	response := &SessionDeletionResponse{
 		SEID:  req.SEID,
 		Cause: "Request accepted",
@@ -228,49 +426,304 @@ func (c *PFCPClient) DeleteSession(req *SessionDeletionRequest) (*SessionDeletio
 	return response, nil
 }
 
-// allocateTEID allocates a new F-TEID
-func (c *PFCPClient) allocateTEID() uint32 {
-	c.teidCounter++
-	return c.teidCounter
+// establishSession sends a Session Establishment Request over N4
+func (c *PFCPClient) establishSession(req *SessionEstablishmentRequest) (*SessionEstablishmentResponse, error) {
+	ies := []*pfcp.IE{
+		pfcp.NewNodeIDIE(c.nodeID),
+		pfcp.NewFSEIDIE(req.SEID, c.transport.localIP(), nil),
+	}
+	for i := range req.PDRs {
+		ies = append(ies, encodePDR(pfcp.IE_CREATE_PDR, &req.PDRs[i]))
+	}
+	for i := range req.FARs {
+		ies = append(ies, encodeFAR(pfcp.IE_CREATE_FAR, &req.FARs[i]))
+	}
+	for i := range req.QERs {
+		ies = append(ies, encodeQER(pfcp.IE_CREATE_QER, &req.QERs[i]))
+	}
+	for i := range req.URRs {
+		ies = append(ies, encodeURR(&req.URRs[i]))
+	}
+
+	resp, err := c.transport.request(pfcp.NewSessionMessage(pfcp.PFCP_SESSION_ESTABLISHMENT_REQUEST, 0, 0, ies...))
+	if err != nil {
+		return nil, fmt.Errorf("PFCP session establishment failed: %w", err)
+	}
+
+	response := &SessionEstablishmentResponse{NodeID: c.upfNodeID, SEID: req.SEID}
+	if response.Cause, err = decodeCause(resp); err != nil {
+		return nil, fmt.Errorf("PFCP session establishment failed: %w", err)
+	}
+	if ValidatePFCPResponse(response.Cause) != nil {
+		return response, nil
+	}
+
+	fseidIE := resp.FindIE(pfcp.IE_F_SEID)
+	if fseidIE == nil {
+		return nil, fmt.Errorf("PFCP session establishment response without UP F-SEID")
+	}
+	fseid, err := fseidIE.FSEID()
+	if err != nil {
+		return nil, fmt.Errorf("PFCP session establishment response: %w", err)
+	}
+	if response.CreatedPDRs, err = decodeCreatedPDRs(resp); err != nil {
+		return nil, fmt.Errorf("PFCP session establishment response: %w", err)
+	}
+	if response.UPFTEID = n3FTEID(req, response.CreatedPDRs); response.UPFTEID == nil {
+		return nil, fmt.Errorf("UPF did not allocate an N3 F-TEID")
+	}
+
+	c.recordSession(req.SEID, fseid.SEID)
+
+	c.logger.Info("PFCP Session Establishment successful",
+		zap.Uint64("seid", req.SEID),
+		zap.Uint64("up_seid", fseid.SEID),
+		zap.Uint32("upf_teid", response.UPFTEID.TEID),
+	)
+
+	return response, nil
 }
 
-// extractIPFromAddress extracts IP from "IP:PORT" format
-func (c *PFCPClient) extractIPFromAddress(addr string) string {
-	// Simple extraction - assumes "IP:PORT" format
-	for i := 0; i < len(addr); i++ {
-		if addr[i] == ':' {
-			return addr[:i]
+// n3FTEID returns the N3 F-TEID of an established session: the first one the
+// UPF allocated, or else the one the SMF asked for, as when re-establishing a
+// session under its existing tunnel
+func n3FTEID(req *SessionEstablishmentRequest, created []CreatedPDR) *FTEID {
+	if len(created) > 0 {
+		return created[0].FTEID
+	}
+	for _, pdr := range req.PDRs {
+		if pdr.PDI.FTEID != nil && pdr.PDI.FTEID.TEID != 0 {
+			fteid := *pdr.PDI.FTEID
+			return &fteid
 		}
 	}
-	return addr
+	return nil
 }
 
-// AssociatePFCPSession establishes PFCP association with UPF
-func (c *PFCPClient) AssociatePFCPSession() error {
-	c.logger.Info("Establishing PFCP association with UPF",
-		zap.String("upf_node_id", c.upfNodeID),
-		zap.String("upf_address", c.upfN4Address),
+// modifySession sends a Session Modification Request over N4
+func (c *PFCPClient) modifySession(req *SessionModificationRequest) (*SessionModificationResponse, error) {
+	upSEID, err := c.upSEID(req.SEID)
+	if err != nil {
+		return nil, err
+	}
+
+	var ies []*pfcp.IE
+	for i := range req.CreatePDRs {
+		ies = append(ies, encodePDR(pfcp.IE_CREATE_PDR, &req.CreatePDRs[i]))
+	}
+	for i := range req.CreateFARs {
+		ies = append(ies, encodeFAR(pfcp.IE_CREATE_FAR, &req.CreateFARs[i]))
+	}
+	for i := range req.CreateQERs {
+		ies = append(ies, encodeQER(pfcp.IE_CREATE_QER, &req.CreateQERs[i]))
+	}
+	for i := range req.CreateURRs {
+		ies = append(ies, encodeURR(&req.CreateURRs[i]))
+	}
+	for i := range req.UpdatePDRs {
+		ies = append(ies, encodePDR(pfcp.IE_UPDATE_PDR, &req.UpdatePDRs[i]))
+	}
+	for i := range req.UpdateFARs {
+		ies = append(ies, encodeFAR(pfcp.IE_UPDATE_FAR, &req.UpdateFARs[i]))
+	}
+	for i := range req.UpdateQERs {
+		ies = append(ies, encodeQER(pfcp.IE_UPDATE_QER, &req.UpdateQERs[i]))
+	}
+	for _, id := range req.RemovePDRs {
+		ies = append(ies, encodeRemove(pfcp.IE_REMOVE_PDR, pfcp.NewUint16IE(pfcp.IE_PDR_ID, id)))
+	}
+	for _, id := range req.RemoveFARs {
+		ies = append(ies, encodeRemove(pfcp.IE_REMOVE_FAR, pfcp.NewUint32IE(pfcp.IE_FAR_ID, uint32(id))))
+	}
+	for _, id := range req.RemoveQERs {
+		ies = append(ies, encodeRemove(pfcp.IE_REMOVE_QER, pfcp.NewUint32IE(pfcp.IE_QER_ID, uint32(id))))
+	}
+	for _, id := range req.RemoveURRs {
+		ies = append(ies, encodeRemove(pfcp.IE_REMOVE_URR, pfcp.NewUint32IE(pfcp.IE_URR_ID, id)))
+	}
+
+	resp, err := c.transport.request(pfcp.NewSessionMessage(pfcp.PFCP_SESSION_MODIFICATION_REQUEST, upSEID, 0, ies...))
+	if err != nil {
+		return nil, fmt.Errorf("PFCP session modification failed: %w", err)
+	}
+
+	response := &SessionModificationResponse{SEID: req.SEID}
+	if response.Cause, err = decodeCause(resp); err != nil {
+		return nil, fmt.Errorf("PFCP session modification failed: %w", err)
+	}
+	if response.CreatedPDRs, err = decodeCreatedPDRs(resp); err != nil {
+		return nil, fmt.Errorf("PFCP session modification response: %w", err)
+	}
+
+	c.logger.Info("PFCP Session Modification completed",
+		zap.Uint64("seid", req.SEID),
+		zap.String("cause", response.Cause),
 	)
 
-	// TODO: Send PFCP Association Setup Request
-	// For now, simulate successful association
+	return response, nil
+}
+
+// deleteSession sends a Session Deletion Request over N4
+func (c *PFCPClient) deleteSession(req *SessionDeletionRequest) (*SessionDeletionResponse, error) {
+	upSEID, err := c.upSEID(req.SEID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.transport.request(pfcp.NewSessionMessage(pfcp.PFCP_SESSION_DELETION_REQUEST, upSEID, 0))
+	if err != nil {
+		return nil, fmt.Errorf("PFCP session deletion failed: %w", err)
+	}
 
-	time.Sleep(20 * time.Millisecond)
+	response := &SessionDeletionResponse{SEID: req.SEID}
+	if response.Cause, err = decodeCause(resp); err != nil {
+		return nil, fmt.Errorf("PFCP session deletion failed: %w", err)
+	}
+
+	// A UPF that no longer knows the session has nothing left to delete
+	if response.Cause == "Request accepted" || response.Cause == causeStrings[pfcp.CAUSE_SESSION_CONTEXT_NOT_FOUND] {
+		c.forgetSession(req.SEID)
+	}
 
-	c.logger.Info("PFCP association established successfully")
+	c.logger.Info("PFCP Session Deletion completed",
+		zap.Uint64("seid", req.SEID),
+		zap.String("cause", response.Cause),
+	)
+
+	return response, nil
+}
 
+// upSEID returns the SEID the UPF allocated for an SMF session
+func (c *PFCPClient) upSEID(seid uint64) (uint64, error) {
+	c.sessionsMu.Lock()
+	defer c.sessionsMu.Unlock()
+
+	upSEID, ok := c.sessions[seid]
+	if !ok {
+		return 0, fmt.Errorf("no PFCP session established for SEID %d", seid)
+	}
+	return upSEID, nil
+}
+
+// SetSEIDStore persists the SEIDs of the sessions established through the
+// client and loads those recorded before a restart, so AuditSessions covers
+// them
+func (c *PFCPClient) SetSEIDStore(store SEIDStore) error {
+	seids, err := store.Load(c.upfNodeID)
+	if err != nil {
+		return err
+	}
+
+	c.sessionsMu.Lock()
+	defer c.sessionsMu.Unlock()
+
+	c.seidStore = store
+	for cpSEID, upSEID := range seids {
+		c.sessions[cpSEID] = upSEID
+	}
 	return nil
 }
 
-// SendHeartbeat sends PFCP Heartbeat Request to UPF
-func (c *PFCPClient) SendHeartbeat() error {
-	c.logger.Debug("Sending PFCP Heartbeat to UPF",
+// recordSession records the SEID the UPF allocated for an SMF session
+func (c *PFCPClient) recordSession(cpSEID, upSEID uint64) {
+	c.sessionsMu.Lock()
+	defer c.sessionsMu.Unlock()
+
+	c.sessions[cpSEID] = upSEID
+	if c.seidStore != nil {
+		if err := c.seidStore.Put(c.upfNodeID, cpSEID, upSEID); err != nil {
+			c.logger.Error("Failed to persist SEID", zap.Uint64("seid", cpSEID), zap.Error(err))
+		}
+	}
+}
+
+// forgetSession drops an SMF session the UPF no longer holds
+func (c *PFCPClient) forgetSession(cpSEID uint64) {
+	c.sessionsMu.Lock()
+	defer c.sessionsMu.Unlock()
+
+	delete(c.sessions, cpSEID)
+	if c.seidStore != nil {
+		if err := c.seidStore.Delete(c.upfNodeID, cpSEID); err != nil {
+			c.logger.Error("Failed to delete persisted SEID", zap.Uint64("seid", cpSEID), zap.Error(err))
+		}
+	}
+}
+
+// AuditSessions returns the SEIDs of the sessions installed on the UPF, used
+// to reconcile SMF and UPF state after an SMF restart. PFCP has no audit
+// procedure, so every session the client knows of, including those loaded
+// from its SEID store, is checked with an empty Session Modification
+// Request; sessions the UPF no longer holds are forgotten.
+func (c *PFCPClient) AuditSessions() ([]uint64, error) {
+	c.logger.Info("Auditing PFCP sessions on UPF",
 		zap.String("upf_node_id", c.upfNodeID),
 	)
 
-	// TODO: Implement PFCP Heartbeat Request/Response
+	c.sessionsMu.Lock()
+	known := make(map[uint64]uint64, len(c.sessions))
+	for cpSEID, upSEID := range c.sessions {
+		known[cpSEID] = upSEID
+	}
+	c.sessionsMu.Unlock()
+
+	seids := make([]uint64, 0, len(known))
+	for cpSEID, upSEID := range known {
+		if c.transport != nil {
+			held, err := c.probeSession(cpSEID, upSEID)
+			if err != nil {
+				return nil, err
+			}
+			if !held {
+				c.logger.Info("PFCP session no longer on UPF", zap.Uint64("seid", cpSEID))
+				c.forgetSession(cpSEID)
+				continue
+			}
+		}
+		seids = append(seids, cpSEID)
+	}
+	sort.Slice(seids, func(i, j int) bool { return seids[i] < seids[j] })
+	return seids, nil
+}
+
+// probeSession asks the UPF whether it holds a session. The UPF answers an
+// empty Session Modification Request on a known UP SEID with the CP SEID of
+// the session.
+func (c *PFCPClient) probeSession(cpSEID, upSEID uint64) (bool, error) {
+	resp, err := c.transport.request(pfcp.NewSessionMessage(pfcp.PFCP_SESSION_MODIFICATION_REQUEST, upSEID, 0))
+	if err != nil {
+		return false, fmt.Errorf("PFCP session audit failed: %w", err)
+	}
+	cause, err := decodeCause(resp)
+	if err != nil {
+		return false, fmt.Errorf("PFCP session audit failed: %w", err)
+	}
 
-	return nil
+	switch {
+	case cause == causeStrings[pfcp.CAUSE_SESSION_CONTEXT_NOT_FOUND]:
+		return false, nil
+	case ValidatePFCPResponse(cause) != nil:
+		return false, fmt.Errorf("PFCP session audit failed: %s", cause)
+	}
+	// The UP SEID may have been reused for another SMF's session
+	return resp.Header.SEID == cpSEID, nil
+}
+
+// allocateTEID allocates a new F-TEID
+func (c *PFCPClient) allocateTEID() uint32 {
+	c.teidCounter++
+	return c.teidCounter
+}
+
+// extractIPFromAddress extracts IP from "IP:PORT" format
+func (c *PFCPClient) extractIPFromAddress(addr string) string {
+	// Simple extraction - assumes "IP:PORT" format
+	for i := 0; i < len(addr); i++ {
+		if addr[i] == ':' {
+			return addr[:i]
+		}
+	}
+	return addr
 }
 
 // GenerateSEID generates a unique Session Endpoint Identifier
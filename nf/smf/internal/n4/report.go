@@ -0,0 +1,164 @@
+package n4
+
+import (
+	"github.com/your-org/5g-network/common/pfcp"
+	"go.uber.org/zap"
+)
+
+// SessionReportHandler handles a Session Report Request the UPF sent for one
+// of the SMF's sessions
+type SessionReportHandler func(req *SessionReportRequest) (*SessionReportResponse, error)
+
+// SetSessionReportHandler sets the handler of Session Report Requests
+// received from the UPF. Reports are rejected until a handler is set.
+func (c *PFCPClient) SetSessionReportHandler(handler SessionReportHandler) {
+	c.reportMu.Lock()
+	defer c.reportMu.Unlock()
+
+	c.reportHandler = handler
+}
+
+// handlePeerRequest handles a request the UPF initiated
+func (c *PFCPClient) handlePeerRequest(msg *pfcp.Message) {
+	if msg.Header.MessageType == pfcp.PFCP_SESSION_REPORT_REQUEST {
+		c.handleSessionReportRequest(msg)
+	}
+}
+
+// handleSessionReportRequest decodes a Session Report Request (TS 29.244
+// 7.5.8), passes it to the report handler and answers the UPF
+func (c *PFCPClient) handleSessionReportRequest(msg *pfcp.Message) {
+	cpSEID := msg.Header.SEID
+	upSEID, _ := c.upSEID(cpSEID)
+
+	cause := c.sessionReportCause(msg)
+	c.transport.send(pfcp.NewSessionMessage(pfcp.PFCP_SESSION_REPORT_RESPONSE, upSEID, msg.Header.SequenceNumber,
+		pfcp.NewCauseIE(cause),
+	))
+}
+
+// sessionReportCause runs the report handler and returns the cause to answer
+// the UPF with
+func (c *PFCPClient) sessionReportCause(msg *pfcp.Message) uint8 {
+	req, cause := decodeSessionReportRequest(msg)
+	if cause != pfcp.CAUSE_REQUEST_ACCEPTED {
+		c.logger.Warn("Rejecting malformed PFCP session report",
+			zap.Uint64("seid", msg.Header.SEID),
+			zap.Uint8("cause", cause))
+		return cause
+	}
+
+	c.reportMu.Lock()
+	handler := c.reportHandler
+	c.reportMu.Unlock()
+	if handler == nil {
+		return pfcp.CAUSE_SERVICE_NOT_SUPPORTED
+	}
+
+	resp, err := handler(req)
+	if resp != nil {
+		return causeValue(resp.Cause)
+	}
+	if err != nil {
+		c.logger.Warn("PFCP session report failed", zap.Uint64("seid", req.SEID), zap.Error(err))
+	}
+	return pfcp.CAUSE_SYSTEM_FAILURE
+}
+
+// decodeSessionReportRequest decodes the reports of a Session Report
+// Request, returning the cause to reject it with when it is malformed
+func decodeSessionReportRequest(msg *pfcp.Message) (*SessionReportRequest, uint8) {
+	reportTypeIE := msg.FindIE(pfcp.IE_REPORT_TYPE)
+	if reportTypeIE == nil {
+		return nil, pfcp.CAUSE_MANDATORY_IE_MISSING
+	}
+	reportType, err := reportTypeIE.Uint8()
+	if err != nil {
+		return nil, pfcp.CAUSE_MANDATORY_IE_INCORRECT
+	}
+
+	req := &SessionReportRequest{SEID: msg.Header.SEID}
+	if reportType&pfcp.REPORT_TYPE_DLDR != 0 {
+		ie := msg.FindIE(pfcp.IE_DOWNLINK_DATA_REPORT)
+		if ie == nil {
+			return nil, pfcp.CAUSE_CONDITIONAL_IE_MISSING
+		}
+		report, err := ie.DownlinkDataReport()
+		if err != nil {
+			return nil, pfcp.CAUSE_MANDATORY_IE_INCORRECT
+		}
+		req.DownlinkDataReport = &DownlinkDataReport{PDRID: report.PDRID, QFI: report.QFI}
+	}
+	if reportType&pfcp.REPORT_TYPE_USAR != 0 {
+		ies := pfcp.FindIEs(msg.IEs, pfcp.IE_USAGE_REPORT_REPORT_REQ)
+		if len(ies) == 0 {
+			return nil, pfcp.CAUSE_CONDITIONAL_IE_MISSING
+		}
+		for _, ie := range ies {
+			report, err := ie.UsageReport()
+			if err != nil {
+				return nil, pfcp.CAUSE_MANDATORY_IE_INCORRECT
+			}
+			usage := UsageReport{
+				URRID:          report.URRID,
+				SequenceNumber: report.SequenceNumber,
+				Trigger:        report.Trigger,
+				StartTime:      report.StartTime,
+				EndTime:        report.EndTime,
+				Duration:       report.Duration,
+			}
+			if v := report.Volume; v != nil {
+				usage.TotalVolume = v.TotalVolume
+				usage.UplinkVolume = v.UplinkVolume
+				usage.DownlinkVolume = v.DownlinkVolume
+			}
+			req.UsageReports = append(req.UsageReports, usage)
+		}
+	}
+	if reportType&pfcp.REPORT_TYPE_ERIR != 0 {
+		ie := msg.FindIE(pfcp.IE_ERROR_INDICATION_REPORT)
+		if ie == nil {
+			return nil, pfcp.CAUSE_CONDITIONAL_IE_MISSING
+		}
+		report, err := ie.ErrorIndicationReport()
+		if err != nil {
+			return nil, pfcp.CAUSE_MANDATORY_IE_INCORRECT
+		}
+		remote := report.RemoteFTEIDs[0]
+		address := remote.IPv4
+		if address == nil {
+			address = remote.IPv6
+		}
+		req.ErrorIndication = &ErrorIndicationReport{RemoteTEID: remote.TEID, RemoteAddress: address}
+	}
+	if reportType&pfcp.REPORT_TYPE_SESR != 0 {
+		ies := pfcp.FindIEs(msg.IEs, pfcp.IE_QOS_MONITORING_REPORT)
+		if len(ies) == 0 {
+			return nil, pfcp.CAUSE_CONDITIONAL_IE_MISSING
+		}
+		for _, ie := range ies {
+			report, err := ie.QoSMonitoringReport()
+			if err != nil {
+				return nil, pfcp.CAUSE_MANDATORY_IE_INCORRECT
+			}
+			req.QoSMonitoringReports = append(req.QoSMonitoringReports, QoSMonitoringReport{
+				QFI:            report.QFI,
+				DownlinkDelay:  report.DownlinkDelay,
+				UplinkDelay:    report.UplinkDelay,
+				RoundTripDelay: report.RoundTripDelay,
+				EventTime:      report.EventTime,
+			})
+		}
+	}
+	return req, pfcp.CAUSE_REQUEST_ACCEPTED
+}
+
+// causeValue returns the cause value of a cause string
+func causeValue(cause string) uint8 {
+	for value, s := range causeStrings {
+		if s == cause {
+			return value
+		}
+	}
+	return pfcp.CAUSE_REQUEST_REJECTED
+}
@@ -0,0 +1,105 @@
+package n4
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// SEIDStore persists the SEIDs UPFs allocated for the SMF's sessions, so a
+// restarted SMF can still address and audit the sessions it established
+type SEIDStore interface {
+	Put(upfNodeID string, cpSEID, upSEID uint64) error
+	Delete(upfNodeID string, cpSEID uint64) error
+	Load(upfNodeID string) (map[uint64]uint64, error)
+}
+
+// FileSEIDStore keeps one file per session, named after the CP SEID and
+// holding the UP SEID, in a directory per UPF
+type FileSEIDStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// NewFileSEIDStore creates a file backed SEID store, creating the directory
+// if needed
+func NewFileSEIDStore(dir string) (*FileSEIDStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create SEID store directory: %w", err)
+	}
+	return &FileSEIDStore{dir: dir}, nil
+}
+
+// upfDir returns the directory holding the sessions of a UPF
+func (f *FileSEIDStore) upfDir(upfNodeID string) string {
+	return filepath.Join(f.dir, url.PathEscape(upfNodeID))
+}
+
+// Put records the UP SEID of a session, writing it atomically
+func (f *FileSEIDStore) Put(upfNodeID string, cpSEID, upSEID uint64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	dir := f.upfDir(upfNodeID)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("failed to write SEID: %w", err)
+	}
+	path := filepath.Join(dir, strconv.FormatUint(cpSEID, 16))
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatUint(upSEID, 16)), 0o640); err != nil {
+		return fmt.Errorf("failed to write SEID: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write SEID: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the record of a session
+func (f *FileSEIDStore) Delete(upfNodeID string, cpSEID uint64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := filepath.Join(f.upfDir(upfNodeID), strconv.FormatUint(cpSEID, 16))
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete SEID: %w", err)
+	}
+	return nil
+}
+
+// Load returns the UP SEIDs of the sessions on a UPF by CP SEID
+func (f *FileSEIDStore) Load(upfNodeID string) (map[uint64]uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	seids := make(map[uint64]uint64)
+	dir := f.upfDir(upfNodeID)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return seids, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SEID store: %w", err)
+	}
+
+	for _, entry := range entries {
+		cpSEID, err := strconv.ParseUint(entry.Name(), 16, 64)
+		if entry.IsDir() || err != nil {
+			continue // Leftover temporary files
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SEID %s: %w", entry.Name(), err)
+		}
+		upSEID, err := strconv.ParseUint(string(data), 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode SEID %s: %w", entry.Name(), err)
+		}
+		seids[cpSEID] = upSEID
+	}
+	return seids, nil
+}
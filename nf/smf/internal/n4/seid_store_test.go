@@ -0,0 +1,35 @@
+package n4
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSEIDStoreSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := NewFileSEIDStore(dir)
+	require.NoError(t, err)
+	require.NoError(t, store.Put("upf-1", 0x10, 0x1001))
+	require.NoError(t, store.Put("upf-1", 0x20, 0x1002))
+	require.NoError(t, store.Put("[2001:db8::1]", 0x10, 0x2001))
+	require.NoError(t, store.Delete("upf-1", 0x20))
+	require.NoError(t, store.Delete("upf-1", 0x30))
+
+	reopened, err := NewFileSEIDStore(dir)
+	require.NoError(t, err)
+
+	seids, err := reopened.Load("upf-1")
+	require.NoError(t, err)
+	assert.Equal(t, map[uint64]uint64{0x10: 0x1001}, seids)
+
+	seids, err = reopened.Load("[2001:db8::1]")
+	require.NoError(t, err)
+	assert.Equal(t, map[uint64]uint64{0x10: 0x2001}, seids)
+
+	seids, err = reopened.Load("upf-2")
+	require.NoError(t, err)
+	assert.Empty(t, seids)
+}
@@ -0,0 +1,208 @@
+package n4
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/your-org/5g-network/common/pfcp"
+	"go.uber.org/zap"
+)
+
+// PFCP request retransmission (3GPP TS 29.244 6.4: T1 and N1)
+const (
+	DefaultRequestTimeout     = 3 * time.Second
+	DefaultRequestRetransmits = 3
+)
+
+// transport exchanges PFCP messages with one UPF over UDP. Requests are
+// serialized; a response is matched to its request by sequence number and
+// message type. A reader goroutine delivers responses to the outstanding
+// request and hands requests initiated by the UPF to onRequest.
+type transport struct {
+	mu          sync.Mutex
+	conn        *net.UDPConn
+	seq         uint32
+	timeout     time.Duration
+	retransmits int
+	startedAt   time.Time // Reported as the Recovery Time Stamp
+	logger      *zap.Logger
+
+	onRequest func(msg *pfcp.Message) // UPF initiated requests other than heartbeats
+	received  chan received
+	done      chan struct{}
+}
+
+// received is a message, or the read error, delivered by the reader
+type received struct {
+	msg *pfcp.Message
+	err error
+}
+
+// dialTransport opens a UDP socket towards the UPF N4 address
+func dialTransport(upfN4Address string, logger *zap.Logger) (*transport, error) {
+	addr, err := net.ResolveUDPAddr("udp", upfN4Address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve UPF N4 address: %w", err)
+	}
+
+	conn, err := net.DialUDP("udp", nil, addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open N4 socket: %w", err)
+	}
+
+	return &transport{
+		conn:        conn,
+		timeout:     DefaultRequestTimeout,
+		retransmits: DefaultRequestRetransmits,
+		startedAt:   time.Now(),
+		logger:      logger,
+		received:    make(chan received, 16),
+		done:        make(chan struct{}),
+	}, nil
+}
+
+// serve starts the reader, passing UPF initiated requests to onRequest
+func (t *transport) serve(onRequest func(msg *pfcp.Message)) {
+	t.onRequest = onRequest
+	go t.read()
+}
+
+// read receives messages until the socket is closed
+func (t *transport) read() {
+	defer close(t.done)
+
+	buf := make([]byte, 65535)
+	for {
+		n, err := t.conn.Read(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			// e.g. ICMP port unreachable, fails the outstanding request
+			t.deliver(received{err: err})
+			continue
+		}
+
+		msg, err := pfcp.Parse(append([]byte(nil), buf[:n]...))
+		if err != nil {
+			t.logger.Warn("Discarding malformed PFCP message", zap.Error(err))
+			continue
+		}
+
+		switch msg.Header.MessageType {
+		case pfcp.PFCP_HEARTBEAT_REQUEST:
+			t.answerHeartbeat(msg)
+		case pfcp.PFCP_SESSION_REPORT_REQUEST:
+			if t.onRequest != nil {
+				t.onRequest(msg)
+			}
+		default:
+			t.deliver(received{msg: msg})
+		}
+	}
+}
+
+// deliver passes a response to the outstanding request, dropping it when
+// nobody is waiting
+func (t *transport) deliver(r received) {
+	select {
+	case t.received <- r:
+	default:
+		t.logger.Debug("Discarding PFCP message, no request waiting")
+	}
+}
+
+// localIP returns the local address of the N4 socket
+func (t *transport) localIP() net.IP {
+	return t.conn.LocalAddr().(*net.UDPAddr).IP
+}
+
+// request sends msg and waits for the response, retransmitting on timeout
+func (t *transport) request(msg *pfcp.Message) (*pfcp.Message, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.seq = (t.seq + 1) & 0xffffff
+	msg.Header.SequenceNumber = t.seq
+	data := msg.Marshal()
+	wantType := msg.Header.MessageType + 1 // Responses follow their request type
+
+	// Drop late responses to earlier requests
+	for drained := false; !drained; {
+		select {
+		case <-t.received:
+		default:
+			drained = true
+		}
+	}
+
+	for attempt := 0; attempt <= t.retransmits; attempt++ {
+		if _, err := t.conn.Write(data); err != nil {
+			return nil, fmt.Errorf("failed to send PFCP message: %w", err)
+		}
+
+		resp, err := t.await(wantType)
+		if err != nil || resp != nil {
+			return resp, err
+		}
+
+		t.logger.Debug("PFCP request timed out",
+			zap.Uint8("type", msg.Header.MessageType),
+			zap.Int("attempt", attempt+1))
+	}
+
+	return nil, fmt.Errorf("no response from UPF to PFCP message type %d after %d attempts",
+		msg.Header.MessageType, t.retransmits+1)
+}
+
+// await waits up to the request timeout for the response to the current
+// request, returning nil without error on timeout
+func (t *transport) await(wantType uint8) (*pfcp.Message, error) {
+	timer := time.NewTimer(t.timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case r := <-t.received:
+			if r.err != nil {
+				return nil, fmt.Errorf("failed to receive PFCP message: %w", r.err)
+			}
+			if r.msg.Header.MessageType == wantType && r.msg.Header.SequenceNumber == t.seq {
+				return r.msg, nil
+			}
+			t.logger.Debug("Discarding unexpected PFCP message",
+				zap.Uint8("type", r.msg.Header.MessageType),
+				zap.Uint32("seq", r.msg.Header.SequenceNumber))
+		case <-timer.C:
+			return nil, nil
+		case <-t.done:
+			return nil, fmt.Errorf("failed to receive PFCP message: %w", net.ErrClosed)
+		}
+	}
+}
+
+// send sends a response to a UPF initiated request
+func (t *transport) send(msg *pfcp.Message) {
+	if _, err := t.conn.Write(msg.Marshal()); err != nil {
+		t.logger.Warn("Failed to send PFCP message",
+			zap.Uint8("type", msg.Header.MessageType),
+			zap.Error(err))
+	}
+}
+
+// answerHeartbeat replies to a heartbeat from the UPF
+func (t *transport) answerHeartbeat(req *pfcp.Message) {
+	t.send(pfcp.NewMessage(pfcp.PFCP_HEARTBEAT_RESPONSE, req.Header.SequenceNumber,
+		pfcp.NewRecoveryTimeStampIE(t.startedAt),
+	))
+}
+
+// close closes the N4 socket and waits for the reader to stop
+func (t *transport) close() error {
+	err := t.conn.Close()
+	<-t.done
+	return err
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -88,16 +89,25 @@ func (s *SMFServer) handleUpdateSMContext(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	s.logger.Info("PDU session update requested",
+	resp, err := s.sessionService.UpdateSession(&req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrDefaultQoSFlowRemoval) || errors.Is(err, service.ErrInvalidQoSFlowUpdate) ||
+			errors.Is(err, service.ErrInvalidUpCnxState) {
+			status = http.StatusBadRequest
+		}
+		s.respondError(w, status, "failed to update session", err)
+		return
+	}
+
+	s.logger.Info("PDU session updated via API",
 		zap.String("sm_context_ref", smContextRef),
-		zap.String("supi", req.SUPI),
+		zap.String("supi", resp.SUPI),
+		zap.Uint8("pdu_session_id", resp.PDUSessionID),
+		zap.Int("qos_flows", len(resp.QoSFlows)),
 	)
 
-	// TODO: Implement session update logic
-	s.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"result": "SUCCESS",
-		"supi":   req.SUPI,
-	})
+	s.respondJSON(w, http.StatusOK, resp)
 }
 
 // handleReleaseSMContext handles POST /nsmf-pdusession/v1/sm-contexts/{smContextRef}/release
@@ -126,6 +136,58 @@ func (s *SMFServer) handleReleaseSMContext(w http.ResponseWriter, r *http.Reques
 	s.respondJSON(w, http.StatusOK, resp)
 }
 
+// handlePrepareHandover handles POST /nsmf-pdusession/v1/sm-contexts/{smContextRef}/handover/prepare
+// TS 23.502, Clause 4.9.1.3.2
+func (s *SMFServer) handlePrepareHandover(w http.ResponseWriter, r *http.Request) {
+	smContextRef := chi.URLParam(r, "smContextRef")
+
+	var req service.HandoverRequiredRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondError(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	resp, err := s.sessionService.PrepareHandover(&req)
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, "failed to prepare handover", err)
+		return
+	}
+
+	s.logger.Info("Handover prepared via API",
+		zap.String("sm_context_ref", smContextRef),
+		zap.String("supi", resp.SUPI),
+		zap.Uint8("pdu_session_id", resp.PDUSessionID),
+	)
+
+	s.respondJSON(w, http.StatusOK, resp)
+}
+
+// handleCompleteHandover handles POST /nsmf-pdusession/v1/sm-contexts/{smContextRef}/handover/complete
+// TS 23.502, Clause 4.9.1.3.3
+func (s *SMFServer) handleCompleteHandover(w http.ResponseWriter, r *http.Request) {
+	smContextRef := chi.URLParam(r, "smContextRef")
+
+	var req service.HandoverCompleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.respondError(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	resp, err := s.sessionService.CompleteHandover(&req)
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, "failed to complete handover", err)
+		return
+	}
+
+	s.logger.Info("Handover completed via API",
+		zap.String("sm_context_ref", smContextRef),
+		zap.String("supi", resp.SUPI),
+		zap.Uint8("pdu_session_id", resp.PDUSessionID),
+	)
+
+	s.respondJSON(w, http.StatusOK, resp)
+}
+
 // handleGetSMContext handles GET /nsmf-pdusession/v1/sm-contexts/{smContextRef}
 func (s *SMFServer) handleGetSMContext(w http.ResponseWriter, r *http.Request) {
 	smContextRef := chi.URLParam(r, "smContextRef")
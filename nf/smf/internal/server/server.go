@@ -8,6 +8,9 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/your-org/5g-network/common/buildinfo"
+	"github.com/your-org/5g-network/common/correlation"
+	"github.com/your-org/5g-network/common/oauth"
 	"github.com/your-org/5g-network/nf/smf/internal/config"
 	"github.com/your-org/5g-network/nf/smf/internal/service"
 	"go.uber.org/zap"
@@ -20,11 +23,14 @@ type SMFServer struct {
 	server         *http.Server
 	logger         *zap.Logger
 	sessionService *service.SessionService
+	nfInstanceID   string
 }
 
-// NewSMFServer creates a new SMF HTTP server
+// NewSMFServer creates a new SMF HTTP server for the SMF instance
+// registered with the NRF as nfInstanceID
 func NewSMFServer(
 	cfg *config.Config,
+	nfInstanceID string,
 	sessionService *service.SessionService,
 	logger *zap.Logger,
 ) *SMFServer {
@@ -33,6 +39,7 @@ func NewSMFServer(
 		router:         chi.NewRouter(),
 		logger:         logger,
 		sessionService: sessionService,
+		nfInstanceID:   nfInstanceID,
 	}
 
 	s.setupRoutes()
@@ -51,10 +58,12 @@ func NewSMFServer(
 // setupRoutes configures the API routes
 func (s *SMFServer) setupRoutes() {
 	s.router.Use(middleware.RequestID)
+	s.router.Use(correlation.Middleware)
 	s.router.Use(middleware.RealIP)
 	s.router.Use(s.loggingMiddleware)
 	s.router.Use(middleware.Recoverer)
 	s.router.Use(middleware.Timeout(60 * time.Second))
+	s.router.Use(oauth.Middleware(s.config.SBI.OAuth2, s.logger, "SMF", s.nfInstanceID))
 
 	// Health & monitoring
 	s.router.Get("/health", s.handleHealthCheck)
@@ -63,10 +72,14 @@ func (s *SMFServer) setupRoutes() {
 
 	// 3GPP TS 29.502 - Nsmf_PDUSession API
 	s.router.Route("/nsmf-pdusession/v1", func(r chi.Router) {
+		r.Use(oauth.RequireScope("nsmf-pdusession"))
+
 		// SM Contexts (PDU Sessions)
 		r.Post("/sm-contexts", s.handleCreateSMContext)
 		r.Put("/sm-contexts/{smContextRef}/modify", s.handleUpdateSMContext)
 		r.Post("/sm-contexts/{smContextRef}/release", s.handleReleaseSMContext)
+		r.Post("/sm-contexts/{smContextRef}/handover/prepare", s.handlePrepareHandover)
+		r.Post("/sm-contexts/{smContextRef}/handover/complete", s.handleCompleteHandover)
 		r.Get("/sm-contexts/{smContextRef}", s.handleGetSMContext)
 	})
 
@@ -78,6 +91,11 @@ func (s *SMFServer) setupRoutes() {
 	})
 }
 
+// SetBuildInfo exposes the NF build info on GET /version
+func (s *SMFServer) SetBuildInfo(info *buildinfo.Info) {
+	s.router.Get("/version", info.Handler())
+}
+
 // Start starts the HTTP server
 func (s *SMFServer) Start() error {
 	s.logger.Info("Starting SMF HTTP server",
@@ -85,6 +103,11 @@ func (s *SMFServer) Start() error {
 	)
 
 	if s.config.SBI.TLS.Enabled {
+		tlsConfig, err := s.config.SBI.TLS.Build()
+		if err != nil {
+			return fmt.Errorf("invalid TLS configuration: %w", err)
+		}
+		s.server.TLSConfig = tlsConfig
 		return s.server.ListenAndServeTLS(s.config.SBI.TLS.Cert, s.config.SBI.TLS.Key)
 	}
 
@@ -116,6 +139,7 @@ func (s *SMFServer) loggingMiddleware(next http.Handler) http.Handler {
 			zap.Int("status", ww.Status()),
 			zap.Duration("duration", time.Since(start)),
 			zap.String("remote_addr", r.RemoteAddr),
+			correlation.Field(r.Context()),
 		)
 	})
 }
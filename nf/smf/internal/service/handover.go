@@ -0,0 +1,298 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/your-org/5g-network/nf/smf/internal/context"
+	"github.com/your-org/5g-network/nf/smf/internal/n4"
+	"go.uber.org/zap"
+)
+
+// Rule IDs reserved for the indirect data forwarding tunnel, outside the
+// range used for per QoS flow PDRs
+const (
+	indirectForwardingPDRID uint16 = 0x1000
+	indirectForwardingFARID uint16 = 0x1000
+)
+
+// defaultIndirectForwardingTimeout is used when no timeout is configured
+const defaultIndirectForwardingTimeout = 10 * time.Second
+
+// HandoverRequiredRequest represents an N2 handover preparation trigger from
+// the AMF (TS 23.502 4.9.1.3.2)
+type HandoverRequiredRequest struct {
+	SUPI         string `json:"supi"`
+	PDUSessionID uint8  `json:"pduSessionId"`
+
+	// DL forwarding tunnel allocated by the target gNB
+	TargetGNBN3Address string `json:"targetGnbN3Address"`
+	TargetGNBTEID      uint32 `json:"targetGnbTeid"`
+
+	IndirectForwarding bool `json:"indirectForwarding"`
+}
+
+// HandoverPreparationResponse represents the result of handover preparation
+type HandoverPreparationResponse struct {
+	Result       string `json:"result"`
+	SUPI         string `json:"supi"`
+	PDUSessionID uint8  `json:"pduSessionId"`
+
+	// Indirect forwarding tunnel endpoint for the source gNB
+	ForwardingUPFN3Address string `json:"forwardingUpfN3Address,omitempty"`
+	ForwardingUPFTEID      uint32 `json:"forwardingUpfTeid,omitempty"`
+
+	Reason string `json:"reason,omitempty"`
+}
+
+// HandoverCompleteRequest represents handover completion from the AMF
+type HandoverCompleteRequest struct {
+	SUPI         string `json:"supi"`
+	PDUSessionID uint8  `json:"pduSessionId"`
+	GNBN3Address string `json:"gnbN3Address"`
+	GNBTEID      uint32 `json:"gnbTeid"`
+}
+
+// HandoverCompleteResponse represents the result of handover completion
+type HandoverCompleteResponse struct {
+	Result       string `json:"result"`
+	SUPI         string `json:"supi"`
+	PDUSessionID uint8  `json:"pduSessionId"`
+	Reason       string `json:"reason,omitempty"`
+}
+
+// PrepareHandover handles handover preparation in the source SMF role,
+// setting up an indirect data forwarding tunnel on the UPF when requested
+func (s *SessionService) PrepareHandover(req *HandoverRequiredRequest) (*HandoverPreparationResponse, error) {
+	s.logger.Info("Preparing N2 handover",
+		zap.String("supi", req.SUPI),
+		zap.Uint8("pdu_session_id", req.PDUSessionID),
+		zap.Bool("indirect_forwarding", req.IndirectForwarding),
+	)
+
+	session, err := s.smfContext.GetSession(req.SUPI, req.PDUSessionID)
+	if err != nil {
+		return &HandoverPreparationResponse{
+			Result: "FAILURE",
+			Reason: fmt.Sprintf("session not found: %v", err),
+		}, err
+	}
+
+	resp := &HandoverPreparationResponse{
+		Result:       "SUCCESS",
+		SUPI:         req.SUPI,
+		PDUSessionID: req.PDUSessionID,
+	}
+	if !req.IndirectForwarding {
+		return resp, nil
+	}
+
+	if session.GetIndirectForwarding() != nil {
+		err := fmt.Errorf("indirect forwarding tunnel already exists")
+		return &HandoverPreparationResponse{
+			Result: "FAILURE",
+			Reason: err.Error(),
+		}, err
+	}
+
+	// Forwarded DL data arrives from the source gNB and is tunnelled to the
+	// target gNB
+	pfcpReq := &n4.SessionModificationRequest{
+		SEID: session.SEID,
+		CreatePDRs: []n4.PDR{
+			{
+				PDRID:      indirectForwardingPDRID,
+				Precedence: 50,
+				PDI: n4.PDI{
+					SourceInterface: "ACCESS",
+					FTEID:           &n4.FTEID{}, // UPF allocates the F-TEID
+					NetworkInstance: session.DNN,
+				},
+				OuterHeaderRemoval: true,
+				FARID:              indirectForwardingFARID,
+			},
+		},
+		CreateFARs: []n4.FAR{
+			{
+				FARID:       indirectForwardingFARID,
+				ApplyAction: "FORWARD",
+				ForwardingParameters: &n4.ForwardingParameters{
+					DestinationInterface: "ACCESS",
+					NetworkInstance:      session.DNN,
+					OuterHeaderCreation: &n4.OuterHeaderCreation{
+						TEID: req.TargetGNBTEID,
+						IPv4: req.TargetGNBN3Address,
+					},
+				},
+			},
+		},
+	}
+
+	pfcpResp, err := s.modifyN3Session(session, pfcpReq)
+	if err == nil {
+		err = n4.ValidatePFCPResponse(pfcpResp.Cause)
+	}
+	if err == nil && len(pfcpResp.CreatedPDRs) == 0 {
+		err = fmt.Errorf("UPF did not allocate a forwarding F-TEID")
+	}
+	if err != nil {
+		s.logger.Error("Failed to set up indirect forwarding tunnel", zap.Error(err))
+		return &HandoverPreparationResponse{
+			Result: "FAILURE",
+			Reason: fmt.Sprintf("indirect forwarding setup failed: %v", err),
+		}, err
+	}
+
+	created := pfcpResp.CreatedPDRs[0].FTEID
+	session.SetIndirectForwarding(&context.ForwardingTunnel{
+		PDRID:         indirectForwardingPDRID,
+		FARID:         indirectForwardingFARID,
+		UPFTEID:       created.TEID,
+		UPFN3Address:  created.IPv4,
+		TargetTEID:    req.TargetGNBTEID,
+		TargetAddress: req.TargetGNBN3Address,
+		CreatedAt:     time.Now(),
+	})
+	s.persistSession(session)
+	s.startForwardingTimer(session)
+
+	s.logger.Info("Indirect forwarding tunnel established",
+		zap.String("supi", req.SUPI),
+		zap.Uint8("pdu_session_id", req.PDUSessionID),
+		zap.Uint32("upf_teid", created.TEID),
+		zap.Uint32("target_teid", req.TargetGNBTEID),
+	)
+
+	resp.ForwardingUPFN3Address = created.IPv4
+	resp.ForwardingUPFTEID = created.TEID
+	return resp, nil
+}
+
+// CompleteHandover switches the downlink path to the target gNB and tears
+// down the indirect forwarding tunnel
+func (s *SessionService) CompleteHandover(req *HandoverCompleteRequest) (*HandoverCompleteResponse, error) {
+	s.logger.Info("Completing N2 handover",
+		zap.String("supi", req.SUPI),
+		zap.Uint8("pdu_session_id", req.PDUSessionID),
+	)
+
+	session, err := s.smfContext.GetSession(req.SUPI, req.PDUSessionID)
+	if err != nil {
+		return &HandoverCompleteResponse{
+			Result: "FAILURE",
+			Reason: fmt.Sprintf("session not found: %v", err),
+		}, err
+	}
+
+	// Switch the downlink FAR to the target gNB. The N3 session, on the
+	// anchor or the ULCL UPF, tunnels downlink to the gNB with the same FAR.
+	pfcpReq := &n4.SessionModificationRequest{
+		SEID: session.SEID,
+		UpdateFARs: []n4.FAR{
+			{
+				FARID:       downlinkFARID,
+				ApplyAction: "FORWARD",
+				ForwardingParameters: &n4.ForwardingParameters{
+					DestinationInterface: "ACCESS",
+					NetworkInstance:      session.DNN,
+					OuterHeaderCreation: &n4.OuterHeaderCreation{
+						TEID: req.GNBTEID,
+						IPv4: req.GNBN3Address,
+					},
+				},
+			},
+		},
+	}
+
+	pfcpResp, err := s.modifyN3Session(session, pfcpReq)
+	if err == nil {
+		err = n4.ValidatePFCPResponse(pfcpResp.Cause)
+	}
+	if err != nil {
+		s.logger.Error("Failed to switch downlink path", zap.Error(err))
+		return &HandoverCompleteResponse{
+			Result: "FAILURE",
+			Reason: fmt.Sprintf("path switch failed: %v", err),
+		}, err
+	}
+
+	session.SetGNBInfo(req.GNBTEID, req.GNBN3Address)
+	s.persistSession(session)
+	s.stopForwardingTimer(session)
+	s.releaseIndirectForwarding(session, "handover completed")
+
+	return &HandoverCompleteResponse{
+		Result:       "SUCCESS",
+		SUPI:         req.SUPI,
+		PDUSessionID: req.PDUSessionID,
+	}, nil
+}
+
+// releaseIndirectForwarding removes the forwarding tunnel rules from the UPF
+func (s *SessionService) releaseIndirectForwarding(session *context.PDUSession, reason string) {
+	tunnel := session.TakeIndirectForwarding()
+	if tunnel == nil {
+		return
+	}
+	s.persistSession(session)
+
+	pfcpReq := &n4.SessionModificationRequest{
+		SEID:       session.SEID,
+		RemovePDRs: []uint16{tunnel.PDRID},
+		RemoveFARs: []uint16{tunnel.FARID},
+	}
+
+	pfcpResp, err := s.modifyN3Session(session, pfcpReq)
+	if err == nil {
+		err = n4.ValidatePFCPResponse(pfcpResp.Cause)
+	}
+	if err != nil {
+		s.logger.Error("Failed to remove indirect forwarding tunnel", zap.Error(err))
+		return
+	}
+
+	s.logger.Info("Indirect forwarding tunnel released",
+		zap.String("supi", session.SUPI),
+		zap.Uint8("pdu_session_id", session.PDUSessionID),
+		zap.Uint32("upf_teid", tunnel.UPFTEID),
+		zap.String("reason", reason),
+	)
+}
+
+// startForwardingTimer releases the forwarding tunnel if the handover does
+// not complete in time
+func (s *SessionService) startForwardingTimer(session *context.PDUSession) {
+	timeout := s.config.SMF.Handover.IndirectForwardingTimeout
+	if timeout <= 0 {
+		timeout = defaultIndirectForwardingTimeout
+	}
+
+	key := fmt.Sprintf("%s-%d", session.SUPI, session.PDUSessionID)
+
+	s.forwardingTimersMu.Lock()
+	defer s.forwardingTimersMu.Unlock()
+
+	if timer, exists := s.forwardingTimers[key]; exists {
+		timer.Stop()
+	}
+	s.forwardingTimers[key] = time.AfterFunc(timeout, func() {
+		s.forwardingTimersMu.Lock()
+		delete(s.forwardingTimers, key)
+		s.forwardingTimersMu.Unlock()
+
+		s.releaseIndirectForwarding(session, "timer expired")
+	})
+}
+
+// stopForwardingTimer cancels a pending forwarding tunnel timer
+func (s *SessionService) stopForwardingTimer(session *context.PDUSession) {
+	key := fmt.Sprintf("%s-%d", session.SUPI, session.PDUSessionID)
+
+	s.forwardingTimersMu.Lock()
+	defer s.forwardingTimersMu.Unlock()
+
+	if timer, exists := s.forwardingTimers[key]; exists {
+		timer.Stop()
+		delete(s.forwardingTimers, key)
+	}
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/5g-network/nf/smf/internal/config"
+	"github.com/your-org/5g-network/nf/smf/internal/context"
+)
+
+func newHandoverTestSession(t *testing.T, timeout time.Duration) (*SessionService, *context.PDUSession) {
+	t.Helper()
+
+	svc := newTestSessionService(t, config.QoSMonitoringConfig{})
+	svc.config.SMF.Handover.IndirectForwardingTimeout = timeout
+
+	_, err := svc.CreateSession(&CreateSessionRequest{
+		SUPI:            "imsi-001010000000001",
+		PDUSessionID:    1,
+		DNN:             "internet",
+		GNBN3Address:    "10.0.0.1",
+		GNBTEIDDownlink: 0x100,
+	})
+	require.NoError(t, err)
+
+	session, err := svc.smfContext.GetSession("imsi-001010000000001", 1)
+	require.NoError(t, err)
+	return svc, session
+}
+
+func TestPrepareHandoverEstablishesIndirectForwarding(t *testing.T) {
+	svc, session := newHandoverTestSession(t, time.Minute)
+
+	resp, err := svc.PrepareHandover(&HandoverRequiredRequest{
+		SUPI:               "imsi-001010000000001",
+		PDUSessionID:       1,
+		TargetGNBN3Address: "10.0.0.2",
+		TargetGNBTEID:      0x200,
+		IndirectForwarding: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "SUCCESS", resp.Result)
+	assert.NotZero(t, resp.ForwardingUPFTEID)
+	assert.Equal(t, "127.0.0.1", resp.ForwardingUPFN3Address)
+
+	tunnel := session.GetIndirectForwarding()
+	require.NotNil(t, tunnel)
+	assert.Equal(t, resp.ForwardingUPFTEID, tunnel.UPFTEID)
+	assert.Equal(t, uint32(0x200), tunnel.TargetTEID)
+	assert.Equal(t, "10.0.0.2", tunnel.TargetAddress)
+
+	// A second preparation while the tunnel exists is rejected
+	_, err = svc.PrepareHandover(&HandoverRequiredRequest{
+		SUPI:               "imsi-001010000000001",
+		PDUSessionID:       1,
+		IndirectForwarding: true,
+	})
+	assert.Error(t, err)
+
+	// Completion switches the path and releases the tunnel
+	complete, err := svc.CompleteHandover(&HandoverCompleteRequest{
+		SUPI:         "imsi-001010000000001",
+		PDUSessionID: 1,
+		GNBN3Address: "10.0.0.2",
+		GNBTEID:      0x300,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "SUCCESS", complete.Result)
+	assert.Nil(t, session.GetIndirectForwarding())
+	assert.Equal(t, "10.0.0.2", session.GNBN3Address)
+	assert.Equal(t, uint32(0x300), session.GNBTEIDDownlink)
+}
+
+func TestIndirectForwardingTunnelTimesOut(t *testing.T) {
+	svc, session := newHandoverTestSession(t, 50*time.Millisecond)
+
+	_, err := svc.PrepareHandover(&HandoverRequiredRequest{
+		SUPI:               "imsi-001010000000001",
+		PDUSessionID:       1,
+		TargetGNBN3Address: "10.0.0.2",
+		TargetGNBTEID:      0x200,
+		IndirectForwarding: true,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, session.GetIndirectForwarding())
+
+	assert.Eventually(t, func() bool {
+		return session.GetIndirectForwarding() == nil
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestPrepareHandoverWithoutIndirectForwarding(t *testing.T) {
+	svc, session := newHandoverTestSession(t, time.Minute)
+
+	resp, err := svc.PrepareHandover(&HandoverRequiredRequest{
+		SUPI:         "imsi-001010000000001",
+		PDUSessionID: 1,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "SUCCESS", resp.Result)
+	assert.Zero(t, resp.ForwardingUPFTEID)
+	assert.Nil(t, session.GetIndirectForwarding())
+}
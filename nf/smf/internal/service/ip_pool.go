@@ -0,0 +1,422 @@
+package service
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"net"
+	"sync"
+
+	"github.com/your-org/5g-network/nf/smf/internal/config"
+	"github.com/your-org/5g-network/nf/smf/internal/context"
+)
+
+// defaultIPv6PrefixLength is the prefix length delegated to a UE when none is
+// configured (TS 23.501 5.8.2.2.3)
+const defaultIPv6PrefixLength = 64
+
+var (
+	// ErrIPPoolExhausted is returned when a pool has no free address left
+	ErrIPPoolExhausted = errors.New("IP pool exhausted")
+
+	// ErrIPPoolNotConfigured is returned when no pool serves the address
+	// family a session needs
+	ErrIPPoolNotConfigured = errors.New("IP pool not configured")
+
+	// ErrUnsupportedPDUSessionType is returned for session types the SMF
+	// cannot assign addresses to
+	ErrUnsupportedPDUSessionType = errors.New("unsupported PDU session type")
+)
+
+// UEAddress holds the addresses assigned to a PDU session
+type UEAddress struct {
+	PDUSessionType context.PDUSessionType // Type granted, may differ from the request
+	IPv4           string
+	IPv6Prefix     string // e.g. "2001:db8:0:1::/64"
+}
+
+// UEAddressPool assigns UE IPv4 addresses and IPv6 prefixes. Either family
+// may be left unconfigured; each family is exhausted independently.
+type UEAddressPool struct {
+	ipv4 *IPPool
+	ipv6 *IPv6PrefixPool
+}
+
+// NewUEAddressPool creates the pools for the configured UE subnets
+func NewUEAddressPool(subnet config.UESubnet) (*UEAddressPool, error) {
+	pool := &UEAddressPool{}
+
+	if subnet.IPv4 != "" {
+		ipv4, err := NewIPPool(subnet.IPv4)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IPv4 UE subnet: %w", err)
+		}
+		pool.ipv4 = ipv4
+	}
+	if subnet.IPv6 != "" {
+		ipv6, err := NewIPv6PrefixPool(subnet.IPv6, subnet.IPv6PrefixLength)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IPv6 UE subnet: %w", err)
+		}
+		pool.ipv6 = ipv6
+	}
+
+	if pool.ipv4 == nil && pool.ipv6 == nil {
+		return nil, fmt.Errorf("no UE subnet configured")
+	}
+	return pool, nil
+}
+
+// Allocate assigns addresses for the requested PDU session type, IPv4 when
+// none is given. An IPv4v6 request is granted a single family when the
+// other one is not configured or exhausted (TS 23.501 5.8.2.2.1).
+func (p *UEAddressPool) Allocate(requested context.PDUSessionType) (*UEAddress, error) {
+	switch requested {
+	case "", context.PDUSessionTypeIPv4:
+		ipv4, err := p.allocateIPv4()
+		if err != nil {
+			return nil, err
+		}
+		return &UEAddress{PDUSessionType: context.PDUSessionTypeIPv4, IPv4: ipv4}, nil
+
+	case context.PDUSessionTypeIPv6:
+		prefix, err := p.allocateIPv6()
+		if err != nil {
+			return nil, err
+		}
+		return &UEAddress{PDUSessionType: context.PDUSessionTypeIPv6, IPv6Prefix: prefix}, nil
+
+	case context.PDUSessionTypeIPv4v6:
+		ipv4, errV4 := p.allocateIPv4()
+		prefix, errV6 := p.allocateIPv6()
+		switch {
+		case errV4 == nil && errV6 == nil:
+			return &UEAddress{PDUSessionType: context.PDUSessionTypeIPv4v6, IPv4: ipv4, IPv6Prefix: prefix}, nil
+		case errV4 == nil:
+			return &UEAddress{PDUSessionType: context.PDUSessionTypeIPv4, IPv4: ipv4}, nil
+		case errV6 == nil:
+			return &UEAddress{PDUSessionType: context.PDUSessionTypeIPv6, IPv6Prefix: prefix}, nil
+		}
+		return nil, errors.Join(errV4, errV6)
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedPDUSessionType, requested)
+}
+
+// Reserve marks the addresses of a restored session as allocated
+func (p *UEAddressPool) Reserve(ipv4, ipv6Prefix string) error {
+	var errs []error
+	if ipv4 != "" {
+		if p.ipv4 == nil {
+			errs = append(errs, fmt.Errorf("IPv4 %w", ErrIPPoolNotConfigured))
+		} else if err := p.ipv4.Reserve(ipv4); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if ipv6Prefix != "" {
+		if p.ipv6 == nil {
+			errs = append(errs, fmt.Errorf("IPv6 %w", ErrIPPoolNotConfigured))
+		} else if err := p.ipv6.Reserve(ipv6Prefix); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Release returns a session's addresses to their pools
+func (p *UEAddressPool) Release(ipv4, ipv6Prefix string) {
+	if ipv4 != "" && p.ipv4 != nil {
+		p.ipv4.Release(ipv4)
+	}
+	if ipv6Prefix != "" && p.ipv6 != nil {
+		p.ipv6.Release(ipv6Prefix)
+	}
+}
+
+// AllocatedIPv4Count returns the number of allocated IPv4 addresses
+func (p *UEAddressPool) AllocatedIPv4Count() int {
+	if p.ipv4 == nil {
+		return 0
+	}
+	return p.ipv4.AllocatedCount()
+}
+
+// AllocatedIPv6Count returns the number of delegated IPv6 prefixes
+func (p *UEAddressPool) AllocatedIPv6Count() int {
+	if p.ipv6 == nil {
+		return 0
+	}
+	return p.ipv6.AllocatedCount()
+}
+
+func (p *UEAddressPool) allocateIPv4() (string, error) {
+	if p.ipv4 == nil {
+		return "", fmt.Errorf("IPv4 %w", ErrIPPoolNotConfigured)
+	}
+	return p.ipv4.Allocate()
+}
+
+func (p *UEAddressPool) allocateIPv6() (string, error) {
+	if p.ipv6 == nil {
+		return "", fmt.Errorf("IPv6 %w", ErrIPPoolNotConfigured)
+	}
+	return p.ipv6.Allocate()
+}
+
+// IPPool manages UE IPv4 address allocation. Addresses are tracked by their
+// offset within the subnet: released offsets are kept on a stack and reused
+// first, otherwise a cursor hands out offsets never used before, so
+// allocation is amortized O(1).
+type IPPool struct {
+	subnet    *net.IPNet
+	base      uint32   // Network address
+	size      uint32   // Addresses in the subnet
+	next      uint32   // First offset never handed out
+	free      []uint32 // Released offsets, most recent last
+	allocated map[uint32]bool
+	mu        sync.Mutex
+}
+
+// NewIPPool creates a new IPv4 pool
+func NewIPPool(cidr string) (*IPPool, error) {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR: %w", err)
+	}
+	ip4 := ipNet.IP.To4()
+	if ip4 == nil {
+		return nil, fmt.Errorf("%s is not an IPv4 subnet", cidr)
+	}
+
+	ones, _ := ipNet.Mask.Size()
+	return &IPPool{
+		subnet:    ipNet,
+		base:      binary.BigEndian.Uint32(ip4),
+		size:      uint32(1 << (32 - ones)),
+		next:      1,
+		allocated: make(map[uint32]bool),
+	}, nil
+}
+
+// Allocate allocates a new IP address, reusing released ones first. The
+// network address is never handed out.
+func (p *IPPool) Allocate() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for len(p.free) > 0 {
+		offset := p.free[len(p.free)-1]
+		p.free = p.free[:len(p.free)-1]
+		// Skip offsets reserved again after their release
+		if !p.allocated[offset] {
+			p.allocated[offset] = true
+			return p.address(offset), nil
+		}
+	}
+
+	for p.next < p.size {
+		offset := p.next
+		p.next++
+		if !p.allocated[offset] {
+			p.allocated[offset] = true
+			return p.address(offset), nil
+		}
+	}
+
+	return "", fmt.Errorf("IPv4 %w", ErrIPPoolExhausted)
+}
+
+// Reserve marks an address as allocated, e.g. for a restored session
+func (p *IPPool) Reserve(ip string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	offset, ok := p.offset(ip)
+	if !ok {
+		return fmt.Errorf("address %s not in pool %s", ip, p.subnet)
+	}
+	if p.allocated[offset] {
+		return fmt.Errorf("address %s already allocated", ip)
+	}
+
+	p.allocated[offset] = true
+	return nil
+}
+
+// Release releases an IP address back to the pool
+func (p *IPPool) Release(ip string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	offset, ok := p.offset(ip)
+	if !ok || !p.allocated[offset] {
+		return
+	}
+	delete(p.allocated, offset)
+	p.free = append(p.free, offset)
+}
+
+// AllocatedCount returns the number of allocated IPs
+func (p *IPPool) AllocatedCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.allocated)
+}
+
+// address returns the address at offset
+func (p *IPPool) address(offset uint32) string {
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, p.base+offset)
+	return ip.String()
+}
+
+// offset returns the offset of an address in the pool, excluding the
+// network address
+func (p *IPPool) offset(ip string) (uint32, bool) {
+	ip4 := net.ParseIP(ip).To4()
+	if ip4 == nil || !p.subnet.Contains(ip4) {
+		return 0, false
+	}
+	offset := binary.BigEndian.Uint32(ip4) - p.base
+	return offset, offset != 0
+}
+
+// IPv6PrefixPool delegates fixed length IPv6 prefixes to UEs out of a larger
+// subnet. Prefixes are tracked by their index within the subnet.
+type IPv6PrefixPool struct {
+	subnet       *net.IPNet
+	prefixLength int
+	count        uint64 // Prefixes in the subnet
+	next         uint64 // Index the next search starts from
+	allocated    map[uint64]bool
+	mu           sync.Mutex
+}
+
+// NewIPv6PrefixPool creates a pool handing out prefixes of prefixLength
+// bits, /64 when zero
+func NewIPv6PrefixPool(cidr string, prefixLength int) (*IPv6PrefixPool, error) {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR: %w", err)
+	}
+	if ipNet.IP.To4() != nil {
+		return nil, fmt.Errorf("%s is not an IPv6 subnet", cidr)
+	}
+
+	if prefixLength == 0 {
+		prefixLength = defaultIPv6PrefixLength
+	}
+	ones, _ := ipNet.Mask.Size()
+	if prefixLength < ones || prefixLength > 128 {
+		return nil, fmt.Errorf("prefix length /%d does not fit in %s", prefixLength, cidr)
+	}
+	// Indexes must fit in a uint64
+	if prefixLength-ones > 63 {
+		return nil, fmt.Errorf("%s holds too many /%d prefixes", cidr, prefixLength)
+	}
+
+	return &IPv6PrefixPool{
+		subnet:       ipNet,
+		prefixLength: prefixLength,
+		count:        1 << (prefixLength - ones),
+		allocated:    make(map[uint64]bool),
+	}, nil
+}
+
+// Allocate delegates a free prefix in CIDR notation
+func (p *IPv6PrefixPool) Allocate() (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i := uint64(0); i < p.count; i++ {
+		index := p.next
+		p.next++
+		if p.next >= p.count {
+			p.next = 0
+		}
+
+		if !p.allocated[index] {
+			p.allocated[index] = true
+			return p.prefix(index), nil
+		}
+	}
+
+	return "", fmt.Errorf("IPv6 %w", ErrIPPoolExhausted)
+}
+
+// Reserve marks a prefix as delegated, e.g. for a restored session
+func (p *IPv6PrefixPool) Reserve(prefix string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	index, err := p.index(prefix)
+	if err != nil {
+		return err
+	}
+	if p.allocated[index] {
+		return fmt.Errorf("prefix %s already allocated", prefix)
+	}
+
+	p.allocated[index] = true
+	return nil
+}
+
+// Release returns a prefix to the pool
+func (p *IPv6PrefixPool) Release(prefix string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if index, err := p.index(prefix); err == nil {
+		delete(p.allocated, index)
+	}
+}
+
+// AllocatedCount returns the number of delegated prefixes
+func (p *IPv6PrefixPool) AllocatedCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.allocated)
+}
+
+// prefix returns the CIDR of the prefix at index
+func (p *IPv6PrefixPool) prefix(index uint64) string {
+	hi := binary.BigEndian.Uint64(p.subnet.IP[:8])
+	lo := binary.BigEndian.Uint64(p.subnet.IP[8:])
+
+	shift := uint(128 - p.prefixLength)
+	if shift >= 64 {
+		hi += index << (shift - 64)
+	} else {
+		var carry uint64
+		lo, carry = bits.Add64(lo, index<<shift, 0)
+		hi += index>>(64-shift) + carry
+	}
+
+	ip := make(net.IP, net.IPv6len)
+	binary.BigEndian.PutUint64(ip[:8], hi)
+	binary.BigEndian.PutUint64(ip[8:], lo)
+	return fmt.Sprintf("%s/%d", ip, p.prefixLength)
+}
+
+// index returns the index of a prefix delegated from this pool
+func (p *IPv6PrefixPool) index(prefix string) (uint64, error) {
+	ip, ipNet, err := net.ParseCIDR(prefix)
+	if err != nil {
+		return 0, fmt.Errorf("invalid prefix %s: %w", prefix, err)
+	}
+	if ones, _ := ipNet.Mask.Size(); ones != p.prefixLength || !p.subnet.Contains(ip) || ip.To4() != nil {
+		return 0, fmt.Errorf("prefix %s not in pool %s", prefix, p.subnet)
+	}
+
+	lo, borrow := bits.Sub64(binary.BigEndian.Uint64(ipNet.IP[8:]), binary.BigEndian.Uint64(p.subnet.IP[8:]), 0)
+	hi, _ := bits.Sub64(binary.BigEndian.Uint64(ipNet.IP[:8]), binary.BigEndian.Uint64(p.subnet.IP[:8]), borrow)
+
+	shift := uint(128 - p.prefixLength)
+	if shift >= 64 {
+		return hi >> (shift - 64), nil
+	}
+	return lo>>shift | hi<<(64-shift), nil
+}
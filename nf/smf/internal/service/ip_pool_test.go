@@ -0,0 +1,225 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/nf/smf/internal/config"
+	"github.com/your-org/5g-network/nf/smf/internal/context"
+	"github.com/your-org/5g-network/nf/smf/internal/n4"
+)
+
+func TestUEAddressPool_AllocatesByPDUSessionType(t *testing.T) {
+	pool, err := NewUEAddressPool(config.UESubnet{IPv4: "10.60.0.0/24", IPv6: "2001:db8:1::/48"})
+	require.NoError(t, err)
+
+	addr, err := pool.Allocate("")
+	require.NoError(t, err)
+	assert.Equal(t, context.PDUSessionTypeIPv4, addr.PDUSessionType)
+	assert.Equal(t, "10.60.0.1", addr.IPv4)
+	assert.Empty(t, addr.IPv6Prefix)
+
+	addr, err = pool.Allocate(context.PDUSessionTypeIPv6)
+	require.NoError(t, err)
+	assert.Equal(t, context.PDUSessionTypeIPv6, addr.PDUSessionType)
+	assert.Empty(t, addr.IPv4)
+	assert.Equal(t, "2001:db8:1::/64", addr.IPv6Prefix)
+
+	addr, err = pool.Allocate(context.PDUSessionTypeIPv4v6)
+	require.NoError(t, err)
+	assert.Equal(t, context.PDUSessionTypeIPv4v6, addr.PDUSessionType)
+	assert.Equal(t, "10.60.0.2", addr.IPv4)
+	assert.Equal(t, "2001:db8:1:1::/64", addr.IPv6Prefix)
+
+	assert.Equal(t, 2, pool.AllocatedIPv4Count())
+	assert.Equal(t, 2, pool.AllocatedIPv6Count())
+
+	pool.Release(addr.IPv4, addr.IPv6Prefix)
+	assert.Equal(t, 1, pool.AllocatedIPv4Count())
+	assert.Equal(t, 1, pool.AllocatedIPv6Count())
+
+	_, err = pool.Allocate(context.PDUSessionTypeEthernet)
+	assert.ErrorIs(t, err, ErrUnsupportedPDUSessionType)
+}
+
+func TestUEAddressPool_FamiliesExhaustIndependently(t *testing.T) {
+	// Three usable IPv4 addresses and two /64 prefixes
+	pool, err := NewUEAddressPool(config.UESubnet{IPv4: "10.60.0.0/30", IPv6: "2001:db8:1::/63"})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err := pool.Allocate(context.PDUSessionTypeIPv4)
+		require.NoError(t, err)
+	}
+	_, err = pool.Allocate(context.PDUSessionTypeIPv4)
+	assert.ErrorIs(t, err, ErrIPPoolExhausted)
+
+	// IPv6 is unaffected, and dual-stack requests fall back to IPv6 only
+	addr, err := pool.Allocate(context.PDUSessionTypeIPv4v6)
+	require.NoError(t, err)
+	assert.Equal(t, context.PDUSessionTypeIPv6, addr.PDUSessionType)
+	assert.Empty(t, addr.IPv4)
+
+	_, err = pool.Allocate(context.PDUSessionTypeIPv6)
+	require.NoError(t, err)
+	_, err = pool.Allocate(context.PDUSessionTypeIPv6)
+	assert.ErrorIs(t, err, ErrIPPoolExhausted)
+
+	_, err = pool.Allocate(context.PDUSessionTypeIPv4v6)
+	assert.ErrorIs(t, err, ErrIPPoolExhausted)
+}
+
+func TestUEAddressPool_IPv4v6FallsBackWithoutIPv6Subnet(t *testing.T) {
+	pool, err := NewUEAddressPool(config.UESubnet{IPv4: "10.60.0.0/24"})
+	require.NoError(t, err)
+
+	addr, err := pool.Allocate(context.PDUSessionTypeIPv4v6)
+	require.NoError(t, err)
+	assert.Equal(t, context.PDUSessionTypeIPv4, addr.PDUSessionType)
+	assert.NotEmpty(t, addr.IPv4)
+
+	_, err = pool.Allocate(context.PDUSessionTypeIPv6)
+	assert.ErrorIs(t, err, ErrIPPoolNotConfigured)
+
+	_, err = NewUEAddressPool(config.UESubnet{})
+	assert.Error(t, err)
+}
+
+func TestIPv6PrefixPool_ReserveAndRelease(t *testing.T) {
+	pool, err := NewIPv6PrefixPool("2001:db8:ff00::/40", 56)
+	require.NoError(t, err)
+
+	require.NoError(t, pool.Reserve("2001:db8:ff00::/56"))
+	assert.Error(t, pool.Reserve("2001:db8:ff00::/56"))
+	assert.Error(t, pool.Reserve("2001:db8:ff00::/64"))
+	assert.Error(t, pool.Reserve("2001:db9::/56"))
+
+	// The reserved prefix is skipped
+	prefix, err := pool.Allocate()
+	require.NoError(t, err)
+	assert.Equal(t, "2001:db8:ff00:100::/56", prefix)
+
+	pool.Release(prefix)
+	assert.Equal(t, 1, pool.AllocatedCount())
+
+	_, err = NewIPv6PrefixPool("2001:db8::/64", 48)
+	assert.Error(t, err)
+	_, err = NewIPv6PrefixPool("10.60.0.0/24", 0)
+	assert.Error(t, err)
+}
+
+func TestCreateSession_DualStack(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	cfg := &config.Config{}
+	cfg.SMF.UESubnet.IPv4 = "10.60.0.0/24"
+	cfg.SMF.UESubnet.IPv6 = "2001:db8:1::/48"
+
+	smfContext := context.NewSMFContext("upf-1", "127.0.0.1:8805")
+	pfcpClient := n4.NewPFCPClient("upf-1", "127.0.0.1:8805", logger)
+	svc, err := NewSessionService(cfg, smfContext, pfcpClient, logger)
+	require.NoError(t, err)
+
+	resp, err := svc.CreateSession(&CreateSessionRequest{
+		SUPI:           "imsi-001010000000001",
+		PDUSessionID:   1,
+		DNN:            "internet",
+		PDUSessionType: string(context.PDUSessionTypeIPv4v6),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, string(context.PDUSessionTypeIPv4v6), resp.PDUSessionType)
+	assert.Equal(t, "10.60.0.1", resp.UEIPv4Address)
+	assert.Equal(t, "2001:db8:1::/64", resp.UEIPv6Prefix)
+
+	session, err := smfContext.GetSession("imsi-001010000000001", 1)
+	require.NoError(t, err)
+	assert.Equal(t, context.PDUSessionTypeIPv4v6, session.PDUSessionType)
+
+	req := svc.buildPFCPEstablishmentRequest(session, session.SEID, "upf-1")
+	assert.Equal(t, "2001:db8:1::/64", req.UEIPv6Prefix)
+	for _, pdr := range req.PDRs {
+		assert.Equal(t, "2001:db8:1::/64", pdr.PDI.UEIPv6Prefix)
+	}
+
+	_, err = svc.ReleaseSession(&ReleaseSessionRequest{SUPI: "imsi-001010000000001", PDUSessionID: 1})
+	require.NoError(t, err)
+	assert.Zero(t, svc.ueIPPool.AllocatedIPv4Count())
+	assert.Zero(t, svc.ueIPPool.AllocatedIPv6Count())
+}
+
+func TestIPPool_ReusesReleasedAddressesFirst(t *testing.T) {
+	pool, err := NewIPPool("10.60.0.0/24")
+	require.NoError(t, err)
+
+	for i := 1; i <= 5; i++ {
+		ip, err := pool.Allocate()
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("10.60.0.%d", i), ip)
+	}
+
+	pool.Release("10.60.0.2")
+	pool.Release("10.60.0.4")
+	assert.Equal(t, 3, pool.AllocatedCount())
+
+	// Most recently released first, then fresh addresses again
+	for _, want := range []string{"10.60.0.4", "10.60.0.2", "10.60.0.6"} {
+		ip, err := pool.Allocate()
+		require.NoError(t, err)
+		assert.Equal(t, want, ip)
+	}
+
+	// A reserved address is not handed out even if it was released before
+	pool.Release("10.60.0.3")
+	require.NoError(t, pool.Reserve("10.60.0.3"))
+	ip, err := pool.Allocate()
+	require.NoError(t, err)
+	assert.Equal(t, "10.60.0.7", ip)
+}
+
+func TestIPPool_Exhaustion(t *testing.T) {
+	pool, err := NewIPPool("10.60.0.0/30")
+	require.NoError(t, err)
+	require.NoError(t, pool.Reserve("10.60.0.2"))
+
+	for _, want := range []string{"10.60.0.1", "10.60.0.3"} {
+		ip, err := pool.Allocate()
+		require.NoError(t, err)
+		assert.Equal(t, want, ip)
+	}
+	_, err = pool.Allocate()
+	assert.ErrorIs(t, err, ErrIPPoolExhausted)
+
+	pool.Release("10.60.0.2")
+	pool.Release("10.60.0.9") // Not in the pool, ignored
+	ip, err := pool.Allocate()
+	require.NoError(t, err)
+	assert.Equal(t, "10.60.0.2", ip)
+	assert.Equal(t, 3, pool.AllocatedCount())
+}
+
+// BenchmarkIPPoolAllocate measures session churn on a /16 that is 90% full
+func BenchmarkIPPoolAllocate(b *testing.B) {
+	pool, err := NewIPPool("10.60.0.0/16")
+	require.NoError(b, err)
+
+	ips := make([]string, 0, 65536*9/10)
+	for len(ips) < cap(ips) {
+		ip, err := pool.Allocate()
+		require.NoError(b, err)
+		ips = append(ips, ip)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		victim := i * 7919 % len(ips)
+		pool.Release(ips[victim])
+		ip, err := pool.Allocate()
+		if err != nil {
+			b.Fatal(err)
+		}
+		ips[victim] = ip
+	}
+}
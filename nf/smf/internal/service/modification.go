@@ -0,0 +1,229 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/your-org/5g-network/nf/smf/internal/context"
+	"github.com/your-org/5g-network/nf/smf/internal/n4"
+	"go.uber.org/zap"
+)
+
+// maxQFI is the highest QoS Flow Identifier (TS 23.501 5.7.1.1)
+const maxQFI = 63
+
+var (
+	// ErrDefaultQoSFlowRemoval is returned when an update tries to remove
+	// the default QoS flow, which is only released with the session
+	ErrDefaultQoSFlowRemoval = errors.New("default QoS flow cannot be removed")
+
+	// ErrInvalidQoSFlowUpdate is returned when the flows to add or remove
+	// do not fit the session's flow set
+	ErrInvalidQoSFlowUpdate = errors.New("invalid QoS flow update")
+
+	// ErrInvalidUpCnxState is returned when an update requests an unknown
+	// user plane connection state or activates it without a gNB tunnel
+	ErrInvalidUpCnxState = errors.New("invalid user plane connection state")
+)
+
+// UpdateSession handles PDU session modification, adding and removing QoS
+// flows on the UPF (TS 23.502 4.3.3.2)
+func (s *SessionService) UpdateSession(req *UpdateSessionRequest) (*UpdateSessionResponse, error) {
+	s.logger.Info("Updating PDU session",
+		zap.String("supi", req.SUPI),
+		zap.Uint8("pdu_session_id", req.PDUSessionID),
+		zap.Int("flows_to_add", len(req.QoSFlowsToAdd)),
+		zap.Int("flows_to_remove", len(req.QoSFlowsToRemove)),
+		zap.String("up_cnx_state", req.UpCnxState),
+	)
+
+	// 1. Get session from context
+	session, err := s.smfContext.GetSession(req.SUPI, req.PDUSessionID)
+	if err != nil {
+		return &UpdateSessionResponse{
+			Result: "FAILURE",
+			Reason: fmt.Sprintf("session not found: %v", err),
+		}, err
+	}
+
+	// 2. Validate the requested flow changes
+	added, err := s.validateQoSFlowUpdate(session, req)
+	if err != nil {
+		return &UpdateSessionResponse{
+			Result: "FAILURE",
+			Reason: err.Error(),
+		}, err
+	}
+
+	// 3. Send PFCP Session Modification to UPF
+	if len(added) > 0 || len(req.QoSFlowsToRemove) > 0 {
+		session.UpdateState(context.PDUSessionStateModifying)
+
+		pfcpResp, err := s.modifyUPFSession(session, s.buildPFCPModificationRequest(session, added, req.QoSFlowsToRemove))
+		if err == nil {
+			err = n4.ValidatePFCPResponse(pfcpResp.Cause)
+		}
+		if err == nil && session.GetULCL() != nil {
+			// The uplink classifier carries the QoS flow rules as well
+			pfcpResp, err = s.modifyN3Session(session, s.buildQoSFlowModification(session, added, req.QoSFlowsToRemove, &n4.FTEID{
+				TEID: session.UPFTEIDUplink,
+				IPv4: session.UPFN3Address,
+			}))
+			if err == nil {
+				err = n4.ValidatePFCPResponse(pfcpResp.Cause)
+			}
+		}
+		if err != nil {
+			s.logger.Error("PFCP session modification failed", zap.Error(err))
+			session.UpdateState(context.PDUSessionStateActive)
+			return &UpdateSessionResponse{
+				Result: "FAILURE",
+				Reason: fmt.Sprintf("PFCP modification failed: %v", err),
+			}, err
+		}
+
+		// 4. Apply the flow changes once the UPF accepted them
+		for _, qfi := range req.QoSFlowsToRemove {
+			session.RemoveQoSFlow(context.QoSFlowIdentifier(qfi))
+		}
+		for _, flow := range added {
+			session.AddQoSFlow(flow)
+		}
+		session.UpdateState(context.PDUSessionStateActive)
+		s.persistSession(session)
+	}
+
+	// 5. Activate or deactivate the user plane connection
+	if req.UpCnxState != "" {
+		if err := s.updateUpCnxState(session, req); err != nil {
+			s.logger.Error("User plane connection update failed", zap.Error(err))
+			return &UpdateSessionResponse{
+				Result: "FAILURE",
+				Reason: err.Error(),
+			}, err
+		}
+	}
+
+	flows := session.GetQoSFlows()
+	resp := &UpdateSessionResponse{
+		Result:       "SUCCESS",
+		SUPI:         req.SUPI,
+		PDUSessionID: req.PDUSessionID,
+		QoSFlows:     make([]QoSFlowInfo, 0, len(flows)),
+	}
+	for _, flow := range flows {
+		resp.QoSFlows = append(resp.QoSFlows, qosFlowInfo(flow))
+	}
+
+	s.logger.Info("PDU session updated successfully",
+		zap.String("supi", req.SUPI),
+		zap.Uint8("pdu_session_id", req.PDUSessionID),
+		zap.Int("qos_flows", len(resp.QoSFlows)),
+	)
+
+	return resp, nil
+}
+
+// validateQoSFlowUpdate checks the flows to add and remove against the
+// session and returns the flows to add
+func (s *SessionService) validateQoSFlowUpdate(session *context.PDUSession, req *UpdateSessionRequest) ([]*context.QoSFlow, error) {
+	existing := make(map[context.QoSFlowIdentifier]bool)
+	for _, flow := range session.GetQoSFlows() {
+		existing[flow.QFI] = true
+	}
+
+	removed := make(map[context.QoSFlowIdentifier]bool)
+	for _, id := range req.QoSFlowsToRemove {
+		qfi := context.QoSFlowIdentifier(id)
+		if qfi == defaultQFI {
+			return nil, fmt.Errorf("%w (QFI %d)", ErrDefaultQoSFlowRemoval, qfi)
+		}
+		if !existing[qfi] {
+			return nil, fmt.Errorf("%w: QoS flow %d does not exist", ErrInvalidQoSFlowUpdate, qfi)
+		}
+		if removed[qfi] {
+			return nil, fmt.Errorf("%w: QoS flow %d removed twice", ErrInvalidQoSFlowUpdate, qfi)
+		}
+		removed[qfi] = true
+	}
+
+	added := make([]*context.QoSFlow, 0, len(req.QoSFlowsToAdd))
+	for _, info := range req.QoSFlowsToAdd {
+		qfi := context.QoSFlowIdentifier(info.QFI)
+		if qfi == 0 || qfi > maxQFI {
+			return nil, fmt.Errorf("%w: QFI %d out of range", ErrInvalidQoSFlowUpdate, qfi)
+		}
+		if existing[qfi] || removed[qfi] {
+			return nil, fmt.Errorf("%w: QoS flow %d already exists", ErrInvalidQoSFlowUpdate, qfi)
+		}
+		existing[qfi] = true
+
+		flow := &context.QoSFlow{
+			QFI:                  qfi,
+			FiveQI:               info.FiveQI,
+			Priority:             info.Priority,
+			GBR:                  info.GBR,
+			MBR:                  info.MBR,
+			SDFFilter:            info.SDFFilter,
+			CreatedAt:            time.Now(),
+			QoSMonitoringEnabled: s.config.SMF.QoSMonitoring.Enabled,
+		}
+		if flow.MBR != nil && !flow.IsGBR() {
+			return nil, fmt.Errorf("%w: MBR set on non-GBR QoS flow %d", ErrInvalidQoSFlowUpdate, qfi)
+		}
+		added = append(added, flow)
+	}
+
+	return added, nil
+}
+
+// buildPFCPModificationRequest builds the PFCP Session Modification Request
+// of the anchor creating the rules of added flows and removing those of
+// removed flows
+func (s *SessionService) buildPFCPModificationRequest(
+	session *context.PDUSession,
+	added []*context.QoSFlow,
+	removed []uint8,
+) *n4.SessionModificationRequest {
+	// Uplink PDRs of new flows match the F-TEID the UPF allocated at
+	// establishment, the N9 one behind a ULCL
+	uplinkFTEID := &n4.FTEID{
+		TEID: session.UPFTEIDUplink,
+		IPv4: session.UPFN3Address,
+	}
+	if ulcl := session.GetULCL(); ulcl != nil {
+		uplinkFTEID = &n4.FTEID{
+			TEID: ulcl.AnchorTEID,
+			IPv4: ulcl.AnchorAddress,
+		}
+	}
+	return s.buildQoSFlowModification(session, added, removed, uplinkFTEID)
+}
+
+// buildQoSFlowModification builds a PFCP Session Modification Request
+// creating the rules of added flows, matching uplink traffic on the given
+// F-TEID, and removing those of removed flows
+func (s *SessionService) buildQoSFlowModification(
+	session *context.PDUSession,
+	added []*context.QoSFlow,
+	removed []uint8,
+	uplinkFTEID *n4.FTEID,
+) *n4.SessionModificationRequest {
+	req := &n4.SessionModificationRequest{SEID: session.SEID}
+
+	for _, flow := range added {
+		flowQER, flowPDRs := s.buildQoSFlowRules(session, flow, uplinkFTEID)
+		req.CreateQERs = append(req.CreateQERs, flowQER)
+		req.CreatePDRs = append(req.CreatePDRs, flowPDRs...)
+	}
+
+	for _, id := range removed {
+		qfi := context.QoSFlowIdentifier(id)
+		uplinkPDRID, downlinkPDRID := flowPDRIDs(qfi)
+		req.RemovePDRs = append(req.RemovePDRs, uplinkPDRID, downlinkPDRID)
+		req.RemoveQERs = append(req.RemoveQERs, flowQERID(qfi))
+	}
+
+	return req
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/5g-network/nf/smf/internal/config"
+	"github.com/your-org/5g-network/nf/smf/internal/context"
+)
+
+func newModificationTestSession(t *testing.T) (*SessionService, *context.PDUSession) {
+	t.Helper()
+
+	svc := newTestSessionService(t, config.QoSMonitoringConfig{})
+	_, err := svc.CreateSession(&CreateSessionRequest{
+		SUPI:         "imsi-001010000000001",
+		PDUSessionID: 1,
+		DNN:          "internet",
+	})
+	require.NoError(t, err)
+
+	session, err := svc.smfContext.GetSession("imsi-001010000000001", 1)
+	require.NoError(t, err)
+	return svc, session
+}
+
+func TestUpdateSessionAddsGBRFlow(t *testing.T) {
+	svc, session := newModificationTestSession(t)
+
+	voice := QoSFlowInfo{
+		QFI:      5,
+		FiveQI:   1, // Conversational voice
+		Priority: 2,
+		GBR:      &context.BitRate{Uplink: 128000, Downlink: 128000},
+		MBR:      &context.BitRate{Uplink: 256000, Downlink: 256000},
+	}
+
+	req := svc.buildPFCPModificationRequest(session, []*context.QoSFlow{{
+		QFI:    5,
+		FiveQI: voice.FiveQI,
+		GBR:    voice.GBR,
+		MBR:    voice.MBR,
+	}}, nil)
+	require.Len(t, req.CreateQERs, 1)
+	assert.Equal(t, flowQERID(5), req.CreateQERs[0].QERID)
+	assert.Equal(t, uint64(128000), req.CreateQERs[0].GBRUplink)
+	assert.Equal(t, uint64(256000), req.CreateQERs[0].MBRDownlink)
+	require.Len(t, req.CreatePDRs, 2)
+	assert.Equal(t, uint16(9), req.CreatePDRs[0].PDRID)
+	assert.Equal(t, uint16(10), req.CreatePDRs[1].PDRID)
+	// The new uplink PDR matches the existing N3 tunnel
+	require.NotNil(t, req.CreatePDRs[0].PDI.FTEID)
+	assert.Equal(t, session.UPFTEIDUplink, req.CreatePDRs[0].PDI.FTEID.TEID)
+	assert.Equal(t, session.UPFN3Address, req.CreatePDRs[0].PDI.FTEID.IPv4)
+	assert.Equal(t, []uint16{flowQERID(5)}, req.CreatePDRs[0].QERIDs, "GBR flow must not be limited by session AMBR")
+
+	resp, err := svc.UpdateSession(&UpdateSessionRequest{
+		SUPI:          "imsi-001010000000001",
+		PDUSessionID:  1,
+		QoSFlowsToAdd: []QoSFlowInfo{voice},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "SUCCESS", resp.Result)
+	require.Len(t, resp.QoSFlows, 2)
+	assert.Equal(t, uint8(1), resp.QoSFlows[0].QFI)
+	assert.Equal(t, voice, resp.QoSFlows[1])
+
+	flow := session.QoSFlows[5]
+	require.NotNil(t, flow)
+	assert.True(t, flow.IsGBR())
+	assert.Equal(t, context.PDUSessionStateActive, session.GetState())
+
+	// Adding an existing flow again is rejected
+	_, err = svc.UpdateSession(&UpdateSessionRequest{
+		SUPI:          "imsi-001010000000001",
+		PDUSessionID:  1,
+		QoSFlowsToAdd: []QoSFlowInfo{voice},
+	})
+	assert.ErrorIs(t, err, ErrInvalidQoSFlowUpdate)
+}
+
+func TestUpdateSessionRemovesNonDefaultFlow(t *testing.T) {
+	svc, session := newModificationTestSession(t)
+
+	_, err := svc.UpdateSession(&UpdateSessionRequest{
+		SUPI:          "imsi-001010000000001",
+		PDUSessionID:  1,
+		QoSFlowsToAdd: []QoSFlowInfo{{QFI: 2, FiveQI: 8, Priority: 20}},
+	})
+	require.NoError(t, err)
+	require.Len(t, session.GetQoSFlows(), 2)
+
+	req := svc.buildPFCPModificationRequest(session, nil, []uint8{2})
+	assert.Equal(t, []uint16{3, 4}, req.RemovePDRs)
+	assert.Equal(t, []uint16{flowQERID(2)}, req.RemoveQERs)
+	assert.Empty(t, req.CreatePDRs)
+
+	resp, err := svc.UpdateSession(&UpdateSessionRequest{
+		SUPI:             "imsi-001010000000001",
+		PDUSessionID:     1,
+		QoSFlowsToRemove: []uint8{2},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "SUCCESS", resp.Result)
+	require.Len(t, resp.QoSFlows, 1)
+	assert.Equal(t, uint8(1), resp.QoSFlows[0].QFI)
+	assert.NotContains(t, session.QoSFlows, context.QoSFlowIdentifier(2))
+
+	// The flow is gone, removing it again fails
+	_, err = svc.UpdateSession(&UpdateSessionRequest{
+		SUPI:             "imsi-001010000000001",
+		PDUSessionID:     1,
+		QoSFlowsToRemove: []uint8{2},
+	})
+	assert.ErrorIs(t, err, ErrInvalidQoSFlowUpdate)
+}
+
+func TestUpdateSessionRejectsDefaultFlowRemoval(t *testing.T) {
+	svc, session := newModificationTestSession(t)
+
+	resp, err := svc.UpdateSession(&UpdateSessionRequest{
+		SUPI:             "imsi-001010000000001",
+		PDUSessionID:     1,
+		QoSFlowsToRemove: []uint8{1},
+	})
+	assert.ErrorIs(t, err, ErrDefaultQoSFlowRemoval)
+	assert.Equal(t, "FAILURE", resp.Result)
+	assert.Contains(t, session.QoSFlows, defaultQFI)
+
+	_, err = svc.UpdateSession(&UpdateSessionRequest{
+		SUPI:          "imsi-001010000000001",
+		PDUSessionID:  1,
+		QoSFlowsToAdd: []QoSFlowInfo{{QFI: 64, FiveQI: 9}},
+	})
+	assert.ErrorIs(t, err, ErrInvalidQoSFlowUpdate)
+
+	_, err = svc.UpdateSession(&UpdateSessionRequest{SUPI: "imsi-001010000000001", PDUSessionID: 2})
+	assert.Error(t, err)
+}
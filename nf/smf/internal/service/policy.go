@@ -0,0 +1,298 @@
+package service
+
+import (
+	gocontext "context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/your-org/5g-network/common/sbi"
+	"github.com/your-org/5g-network/nf/smf/internal/client"
+	"github.com/your-org/5g-network/nf/smf/internal/context"
+	"go.uber.org/zap"
+)
+
+// pcfRequestTimeout bounds the SM policy requests sent to the PCF
+const pcfRequestTimeout = 5 * time.Second
+
+// udmRequestTimeout bounds the SM subscription data requests sent to the
+// UDM
+const udmRequestTimeout = 5 * time.Second
+
+// Local policy, applied when neither the PCF nor the subscription decide
+const (
+	localSessionAMBRUplink   = "1 Gbps"
+	localSessionAMBRDownlink = "2 Gbps"
+	localFiveQI              = 9 // Non-GBR, internet
+	localARPPriority         = 10
+)
+
+// SetPCFClient sets the PCF deciding the session AMBR, default QoS and PCC
+// rules of new sessions. Without a client the local policy applies.
+func (s *SessionService) SetPCFClient(pcfClient *client.PCFClient) {
+	s.pcfClient = pcfClient
+}
+
+// SetUDMClient sets the UDM providing the subscribed session AMBR and
+// default QoS of new sessions. Without a client the configured defaults
+// apply.
+func (s *SessionService) SetUDMClient(udmClient *client.UDMClient) {
+	s.udmClient = udmClient
+}
+
+// sessionPolicy is the policy enforced on a new session
+type sessionPolicy struct {
+	smPolicyID  string // Empty under local policy
+	sessionAMBR context.BitRate
+	fiveQI      uint8 // Default QoS flow
+	arpPriority uint8
+	flows       []*context.QoSFlow // QoS flows of PCC rules
+}
+
+// subscribedDNN returns the subscription of a session's DNN from the UDM
+// (TS 23.502 4.3.2.2.1 step 4), nil without a UDM or when the subscription
+// data is unavailable. Invalid values are left out.
+func (s *SessionService) subscribedDNN(session *context.PDUSession) *client.DNNConfiguration {
+	if s.udmClient == nil {
+		return nil
+	}
+
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), udmRequestTimeout)
+	defer cancel()
+
+	data, err := s.udmClient.GetSMData(ctx, session.SUPI, session.DNN)
+	if err != nil {
+		s.logger.Warn("SM subscription data unavailable, applying configured defaults",
+			zap.String("supi", session.SUPI),
+			zap.String("dnn", session.DNN),
+			zap.Error(err))
+		return nil
+	}
+	subscribed, exists := data.DNNConfigurations[session.DNN]
+	if !exists || subscribed == nil {
+		s.logger.Warn("DNN not in SM subscription data, applying configured defaults",
+			zap.String("supi", session.SUPI),
+			zap.String("dnn", session.DNN))
+		return nil
+	}
+
+	dnn := *subscribed
+	if ambr := dnn.SessionAMBR; ambr != nil {
+		if _, err := sbi.ParseBitRate(ambr.Uplink); err != nil {
+			dnn.SessionAMBR = nil
+		} else if _, err := sbi.ParseBitRate(ambr.Downlink); err != nil {
+			dnn.SessionAMBR = nil
+		}
+		if dnn.SessionAMBR == nil {
+			s.logger.Warn("Invalid subscribed session AMBR, applying configured default",
+				zap.String("supi", session.SUPI),
+				zap.String("dnn", session.DNN),
+				zap.String("uplink", ambr.Uplink),
+				zap.String("downlink", ambr.Downlink))
+		}
+	}
+	if qos := dnn.QoSProfile; qos != nil && (qos.FiveQI == 0 || qos.ARP == nil) {
+		s.logger.Warn("Incomplete subscribed default QoS, applying configured default",
+			zap.String("supi", session.SUPI),
+			zap.String("dnn", session.DNN))
+		dnn.QoSProfile = nil
+	}
+	return &dnn
+}
+
+// localPolicy returns the policy of a session's subscription, taking the
+// values it leaves out from the SMF configuration
+func (s *SessionService) localPolicy(subscribed *client.DNNConfiguration) *sessionPolicy {
+	policy := &sessionPolicy{fiveQI: localFiveQI, arpPriority: localARPPriority}
+
+	ambr := s.config.SMF.DefaultSessionAMBR
+	if ambr.Uplink == "" {
+		ambr.Uplink = localSessionAMBRUplink
+	}
+	if ambr.Downlink == "" {
+		ambr.Downlink = localSessionAMBRDownlink
+	}
+	if subscribed != nil && subscribed.SessionAMBR != nil {
+		ambr.Uplink = subscribed.SessionAMBR.Uplink
+		ambr.Downlink = subscribed.SessionAMBR.Downlink
+	}
+	// Validated with the configuration or the subscription
+	policy.sessionAMBR.Uplink, _ = sbi.ParseBitRate(ambr.Uplink)
+	policy.sessionAMBR.Downlink, _ = sbi.ParseBitRate(ambr.Downlink)
+
+	if subscribed != nil && subscribed.QoSProfile != nil {
+		policy.fiveQI = subscribed.QoSProfile.FiveQI
+		policy.arpPriority = subscribed.QoSProfile.ARP.PriorityLevel
+	}
+	return policy
+}
+
+// establishPolicy creates the SM policy association of a session with the
+// PCF (TS 23.502 4.3.2.2.1 step 7b), reporting the subscribed session AMBR
+// and default QoS, and returns the policy it decided. Without a PCF, or
+// when it fails, the local policy applies.
+func (s *SessionService) establishPolicy(session *context.PDUSession, subscribed *client.DNNConfiguration) *sessionPolicy {
+	if s.pcfClient == nil {
+		return s.localPolicy(subscribed)
+	}
+
+	data := &client.SMPolicyContextData{
+		SUPI:           session.SUPI,
+		PDUSessionID:   session.PDUSessionID,
+		PDUSessionType: string(session.PDUSessionType),
+		DNN:            session.DNN,
+		SliceInfo:      client.PolicySNSSAI{SST: session.SNSSAI.SST, SD: session.SNSSAI.SD},
+		IPv4Address:    session.UEIPv4Address,
+	}
+	if subscribed != nil {
+		data.SubsSessAMBR = subscribed.SessionAMBR
+		if qos := subscribed.QoSProfile; qos != nil {
+			data.SubsDefQoS = &client.DefaultQoS{
+				FiveQI:        qos.FiveQI,
+				ARP:           *qos.ARP,
+				PriorityLevel: qos.PriorityLevel,
+			}
+		}
+	}
+
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), pcfRequestTimeout)
+	defer cancel()
+
+	id, decision, err := s.pcfClient.CreateSMPolicy(ctx, data)
+	if err != nil {
+		s.logger.Warn("SM policy association failed, applying local policy",
+			zap.String("supi", session.SUPI),
+			zap.Uint8("pdu_session_id", session.PDUSessionID),
+			zap.Error(err))
+		return s.localPolicy(subscribed)
+	}
+
+	policy, err := s.policyFromDecision(decision, subscribed)
+	if err != nil {
+		s.logger.Warn("Invalid SM policy decision, applying local policy",
+			zap.String("sm_policy_id", id),
+			zap.Error(err))
+		policy = s.localPolicy(subscribed)
+	}
+	policy.smPolicyID = id
+	return policy
+}
+
+// policyFromDecision converts a PCF decision to the session policy. Values
+// the decision leaves out come from the local policy of the subscription. Each QoS data
+// referenced by PCC rules gets its own QoS flow, unless it matches the
+// default QoS and binds to the default flow (TS 23.501 5.7.1.5).
+func (s *SessionService) policyFromDecision(decision *client.SMPolicyDecision, subscribed *client.DNNConfiguration) (*sessionPolicy, error) {
+	policy := s.localPolicy(subscribed)
+
+	for _, rule := range decision.SessRules {
+		if rule.AuthSessAMBR != nil {
+			uplink, err := sbi.ParseBitRate(rule.AuthSessAMBR.Uplink)
+			if err != nil {
+				return nil, fmt.Errorf("session AMBR: %w", err)
+			}
+			downlink, err := sbi.ParseBitRate(rule.AuthSessAMBR.Downlink)
+			if err != nil {
+				return nil, fmt.Errorf("session AMBR: %w", err)
+			}
+			policy.sessionAMBR = context.BitRate{Uplink: uplink, Downlink: downlink}
+		}
+		if rule.AuthDefQoS != nil {
+			policy.fiveQI = rule.AuthDefQoS.FiveQI
+			policy.arpPriority = rule.AuthDefQoS.ARP.PriorityLevel
+		}
+	}
+
+	// Bind PCC rules to QoS flows in order of precedence
+	rules := make([]*client.PCCRule, 0, len(decision.PCCRules))
+	for _, rule := range decision.PCCRules {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].Precedence != rules[j].Precedence {
+			return rules[i].Precedence < rules[j].Precedence
+		}
+		return rules[i].PCCRuleID < rules[j].PCCRuleID
+	})
+
+	bound := make(map[string]bool)
+	nextQFI := defaultQFI + 1
+	for _, rule := range rules {
+		for _, qosID := range rule.RefQoSData {
+			qos, exists := decision.QoSDecs[qosID]
+			if !exists {
+				return nil, fmt.Errorf("pcc rule %s references unknown QoS data %s", rule.PCCRuleID, qosID)
+			}
+			if bound[qosID] {
+				continue
+			}
+			bound[qosID] = true
+
+			flow, err := s.policyQoSFlow(qos, policy.arpPriority)
+			if err != nil {
+				return nil, fmt.Errorf("QoS data %s: %w", qosID, err)
+			}
+			if flow.FiveQI == policy.fiveQI && flow.GBR == nil && flow.MBR == nil {
+				continue
+			}
+			if nextQFI > maxQFI {
+				return nil, fmt.Errorf("more than %d QoS flows", maxQFI)
+			}
+			flow.QFI = nextQFI
+			flow.SDFFilter = rule.DownlinkFlowDescription()
+			nextQFI++
+			policy.flows = append(policy.flows, flow)
+		}
+	}
+
+	return policy, nil
+}
+
+// policyQoSFlow builds the QoS flow of PCC rule QoS data
+func (s *SessionService) policyQoSFlow(qos *client.QoSData, arpPriority uint8) (*context.QoSFlow, error) {
+	flow := &context.QoSFlow{
+		FiveQI:               qos.FiveQI,
+		Priority:             arpPriority,
+		CreatedAt:            time.Now(),
+		QoSMonitoringEnabled: s.config.SMF.QoSMonitoring.Enabled,
+	}
+	if qos.ARP != nil {
+		flow.Priority = qos.ARP.PriorityLevel
+	}
+
+	rates := make([]uint64, 4)
+	for i, rate := range []string{qos.GBRUl, qos.GBRDl, qos.MaxBRUl, qos.MaxBRDl} {
+		if rate == "" {
+			continue
+		}
+		bps, err := sbi.ParseBitRate(rate)
+		if err != nil {
+			return nil, err
+		}
+		rates[i] = bps
+	}
+	if qos.GBRUl != "" || qos.GBRDl != "" {
+		flow.GBR = &context.BitRate{Uplink: rates[0], Downlink: rates[1]}
+	}
+	if qos.MaxBRUl != "" || qos.MaxBRDl != "" {
+		flow.MBR = &context.BitRate{Uplink: rates[2], Downlink: rates[3]}
+	}
+	return flow, nil
+}
+
+// deleteSMPolicy deletes the SM policy association of a session, if any
+func (s *SessionService) deleteSMPolicy(session *context.PDUSession) {
+	id := session.GetSMPolicyID()
+	if id == "" || s.pcfClient == nil {
+		return
+	}
+
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), pcfRequestTimeout)
+	defer cancel()
+
+	if err := s.pcfClient.DeleteSMPolicy(ctx, id); err != nil {
+		s.logger.Error("Failed to delete SM policy association",
+			zap.String("sm_policy_id", id),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/nf/smf/internal/client"
+	"github.com/your-org/5g-network/nf/smf/internal/config"
+	"github.com/your-org/5g-network/nf/smf/internal/context"
+)
+
+// fakePCF answers SM policy requests with a fixed decision and records
+// the requests it received
+type fakePCF struct {
+	decision *client.SMPolicyDecision
+
+	mu      sync.Mutex
+	created []client.SMPolicyContextData
+	deleted []string
+}
+
+func (p *fakePCF) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/npcf-smpolicycontrol/v1/sm-policies":
+		var data client.SMPolicyContextData
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		p.created = append(p.created, data)
+		w.Header().Set("Location", "/npcf-smpolicycontrol/v1/sm-policies/policy-1")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(p.decision)
+	case r.Method == http.MethodPost && r.URL.Path == "/npcf-smpolicycontrol/v1/sm-policies/policy-1/delete":
+		p.deleted = append(p.deleted, "policy-1")
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// fakeUDM serves the SM subscription data of one DNN per SUPI, 404 for
+// unknown subscribers
+type fakeUDM struct {
+	dnn  string
+	data map[string]*client.DNNConfiguration
+}
+
+func (u *fakeUDM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	supi := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/nudm-sdm/v1/supi/"), "/sm-data")
+	dnnConfig, exists := u.data[supi]
+	if !exists || r.URL.Query().Get("dnn") != u.dnn {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(client.SMSubscriptionData{
+		SingleNSSAI:       client.PolicySNSSAI{SST: 1, SD: "000001"},
+		DNNConfigurations: map[string]*client.DNNConfiguration{u.dnn: dnnConfig},
+	})
+}
+
+func newUDMTestServer(t *testing.T, data map[string]*client.DNNConfiguration) *client.UDMClient {
+	t.Helper()
+
+	server := httptest.NewServer(&fakeUDM{dnn: "internet", data: data})
+	t.Cleanup(server.Close)
+
+	logger, _ := zap.NewDevelopment()
+	return client.NewUDMClient(server.URL, nil, logger)
+}
+
+func newPolicyTestSessionService(t *testing.T, decision *client.SMPolicyDecision) (*SessionService, *fakePCF) {
+	t.Helper()
+
+	pcf := &fakePCF{decision: decision}
+	server := httptest.NewServer(pcf)
+	t.Cleanup(server.Close)
+
+	logger, _ := zap.NewDevelopment()
+	svc := newTestSessionService(t, config.QoSMonitoringConfig{})
+	svc.SetPCFClient(client.NewPCFClient(server.URL, nil, logger))
+	return svc, pcf
+}
+
+func TestCreateSessionAppliesPCFDecision(t *testing.T) {
+	svc, pcf := newPolicyTestSessionService(t, &client.SMPolicyDecision{
+		SessRules: map[string]*client.SessionRule{
+			"sess-1": {
+				SessRuleID:   "sess-1",
+				AuthSessAMBR: &client.AMBR{Uplink: "100 Mbps", Downlink: "200 Mbps"},
+				AuthDefQoS:   &client.DefaultQoS{FiveQI: 8, ARP: client.ARP{PriorityLevel: 4}},
+			},
+		},
+		PCCRules: map[string]*client.PCCRule{
+			"video": {
+				PCCRuleID:  "video",
+				FlowInfos:  []client.FlowInformation{{FlowDescription: "permit out 17 from 198.51.100.0/24 to assigned", FlowDirection: "BIDIRECTIONAL"}},
+				Precedence: 10,
+				RefQoSData: []string{"qos-video"},
+			},
+			"web": {PCCRuleID: "web", Precedence: 20, RefQoSData: []string{"qos-web"}},
+		},
+		QoSDecs: map[string]*client.QoSData{
+			"qos-video": {QoSID: "qos-video", FiveQI: 2, GBRUl: "1 Mbps", GBRDl: "5 Mbps", MaxBRUl: "2 Mbps", MaxBRDl: "10 Mbps"},
+			"qos-web":   {QoSID: "qos-web", FiveQI: 8}, // Same as the default QoS
+		},
+	})
+
+	resp, err := svc.CreateSession(&CreateSessionRequest{
+		SUPI:         "imsi-001010000000001",
+		PDUSessionID: 1,
+		DNN:          "internet",
+		SNSSAI:       context.SNSSAI{SST: 1, SD: "000001"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "SUCCESS", resp.Result)
+
+	require.Len(t, pcf.created, 1)
+	assert.Equal(t, "imsi-001010000000001", pcf.created[0].SUPI)
+	assert.Equal(t, "internet", pcf.created[0].DNN)
+	assert.Equal(t, resp.UEIPv4Address, pcf.created[0].IPv4Address)
+
+	assert.Equal(t, context.BitRate{Uplink: 100_000_000, Downlink: 200_000_000}, resp.SessionAMBR)
+	require.Len(t, resp.QoSFlows, 2)
+	assert.Equal(t, QoSFlowInfo{QFI: 1, FiveQI: 8, Priority: 4}, resp.QoSFlows[0])
+	assert.Equal(t, uint8(2), resp.QoSFlows[1].QFI)
+	assert.Equal(t, uint8(2), resp.QoSFlows[1].FiveQI)
+	assert.Equal(t, &context.BitRate{Uplink: 1_000_000, Downlink: 5_000_000}, resp.QoSFlows[1].GBR)
+	assert.Equal(t, "permit out 17 from 198.51.100.0/24 to assigned", resp.QoSFlows[1].SDFFilter)
+
+	// The UPF enforces the session AMBR and the GBR flow's bit rates
+	session, err := svc.smfContext.GetSession("imsi-001010000000001", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "policy-1", session.GetSMPolicyID())
+	req := svc.buildPFCPEstablishmentRequest(session, session.SEID, "upf-1")
+	assert.Equal(t, uint64(200_000_000), findQER(req.QERs, sessionAMBRQERID).MBRDownlink)
+	assert.Equal(t, uint64(10_000_000), findQER(req.QERs, flowQERID(2)).MBRDownlink)
+
+	_, err = svc.ReleaseSession(&ReleaseSessionRequest{SUPI: "imsi-001010000000001", PDUSessionID: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"policy-1"}, pcf.deleted)
+}
+
+func TestCreateSessionLocalPolicy(t *testing.T) {
+	// Without a PCF, the configured default session AMBR applies
+	svc := newTestSessionService(t, config.QoSMonitoringConfig{})
+	svc.config.SMF.DefaultSessionAMBR = config.AMBR{Uplink: "50 Mbps", Downlink: "100 Mbps"}
+
+	resp, err := svc.CreateSession(&CreateSessionRequest{SUPI: "imsi-001010000000001", PDUSessionID: 1, DNN: "internet"})
+	require.NoError(t, err)
+	assert.Equal(t, context.BitRate{Uplink: 50_000_000, Downlink: 100_000_000}, resp.SessionAMBR)
+	require.Len(t, resp.QoSFlows, 1)
+	assert.Equal(t, uint8(9), resp.QoSFlows[0].FiveQI)
+
+	// An unreachable PCF falls back to the local policy
+	logger, _ := zap.NewDevelopment()
+	pcf := httptest.NewServer(http.NotFoundHandler())
+	pcf.Close()
+	svc.SetPCFClient(client.NewPCFClient(pcf.URL, nil, logger))
+
+	resp, err = svc.CreateSession(&CreateSessionRequest{SUPI: "imsi-001010000000002", PDUSessionID: 1, DNN: "internet"})
+	require.NoError(t, err)
+	assert.Equal(t, context.BitRate{Uplink: 50_000_000, Downlink: 100_000_000}, resp.SessionAMBR)
+
+	session, err := svc.smfContext.GetSession("imsi-001010000000002", 1)
+	require.NoError(t, err)
+	assert.Empty(t, session.GetSMPolicyID())
+}
+
+func TestCreateSessionAppliesSubscription(t *testing.T) {
+	svc := newTestSessionService(t, config.QoSMonitoringConfig{})
+	svc.config.SMF.DefaultSessionAMBR = config.AMBR{Uplink: "50 Mbps", Downlink: "100 Mbps"}
+	svc.SetUDMClient(newUDMTestServer(t, map[string]*client.DNNConfiguration{
+		"imsi-001010000000001": {
+			SessionAMBR: &client.AMBR{Uplink: "300 Mbps", Downlink: "600 Mbps"},
+			QoSProfile:  &client.SubscribedQoSProfile{FiveQI: 7, ARP: &client.ARP{PriorityLevel: 3}},
+		},
+		"imsi-001010000000002": {
+			SessionAMBR: &client.AMBR{Uplink: "fast", Downlink: "600 Mbps"},
+			QoSProfile:  &client.SubscribedQoSProfile{FiveQI: 7}, // No ARP
+		},
+	}))
+
+	resp, err := svc.CreateSession(&CreateSessionRequest{SUPI: "imsi-001010000000001", PDUSessionID: 1, DNN: "internet"})
+	require.NoError(t, err)
+	assert.Equal(t, context.BitRate{Uplink: 300_000_000, Downlink: 600_000_000}, resp.SessionAMBR)
+	require.Len(t, resp.QoSFlows, 1)
+	assert.Equal(t, QoSFlowInfo{QFI: 1, FiveQI: 7, Priority: 3}, resp.QoSFlows[0])
+
+	// The UPF enforces the subscribed session AMBR
+	session, err := svc.smfContext.GetSession("imsi-001010000000001", 1)
+	require.NoError(t, err)
+	req := svc.buildPFCPEstablishmentRequest(session, session.SEID, "upf-1")
+	ambr := findQER(req.QERs, sessionAMBRQERID)
+	assert.Equal(t, uint64(300_000_000), ambr.MBRUplink)
+	assert.Equal(t, uint64(600_000_000), ambr.MBRDownlink)
+
+	// Invalid subscription values and unknown subscribers fall back to the
+	// configured defaults
+	for _, supi := range []string{"imsi-001010000000002", "imsi-001010000000003"} {
+		resp, err = svc.CreateSession(&CreateSessionRequest{SUPI: supi, PDUSessionID: 1, DNN: "internet"})
+		require.NoError(t, err)
+		assert.Equal(t, context.BitRate{Uplink: 50_000_000, Downlink: 100_000_000}, resp.SessionAMBR, supi)
+		require.Len(t, resp.QoSFlows, 1)
+		assert.Equal(t, QoSFlowInfo{QFI: 1, FiveQI: 9, Priority: 10}, resp.QoSFlows[0], supi)
+	}
+}
+
+func TestCreateSessionReportsSubscriptionToPCF(t *testing.T) {
+	// The PCF decides no session rule, so the subscription applies
+	svc, pcf := newPolicyTestSessionService(t, &client.SMPolicyDecision{})
+	svc.SetUDMClient(newUDMTestServer(t, map[string]*client.DNNConfiguration{
+		"imsi-001010000000001": {
+			SessionAMBR: &client.AMBR{Uplink: "300 Mbps", Downlink: "600 Mbps"},
+			QoSProfile:  &client.SubscribedQoSProfile{FiveQI: 7, ARP: &client.ARP{PriorityLevel: 3}},
+		},
+	}))
+
+	resp, err := svc.CreateSession(&CreateSessionRequest{SUPI: "imsi-001010000000001", PDUSessionID: 1, DNN: "internet"})
+	require.NoError(t, err)
+
+	require.Len(t, pcf.created, 1)
+	assert.Equal(t, &client.AMBR{Uplink: "300 Mbps", Downlink: "600 Mbps"}, pcf.created[0].SubsSessAMBR)
+	require.NotNil(t, pcf.created[0].SubsDefQoS)
+	assert.Equal(t, uint8(7), pcf.created[0].SubsDefQoS.FiveQI)
+	assert.Equal(t, uint8(3), pcf.created[0].SubsDefQoS.ARP.PriorityLevel)
+
+	assert.Equal(t, context.BitRate{Uplink: 300_000_000, Downlink: 600_000_000}, resp.SessionAMBR)
+	require.Len(t, resp.QoSFlows, 1)
+	assert.Equal(t, QoSFlowInfo{QFI: 1, FiveQI: 7, Priority: 3}, resp.QoSFlows[0])
+}
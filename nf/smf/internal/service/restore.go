@@ -0,0 +1,252 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/your-org/5g-network/nf/smf/internal/context"
+	"github.com/your-org/5g-network/nf/smf/internal/n4"
+	"go.uber.org/zap"
+)
+
+// RestoreResult summarizes session restoration at startup
+type RestoreResult struct {
+	Restored     int `json:"restored"`     // Sessions loaded from the store
+	InSync       int `json:"inSync"`       // Sessions already present on the UPF
+	Resynced     int `json:"resynced"`     // Sessions re-established on the UPF
+	Released     int `json:"released"`     // Sessions that could not be recovered
+	StaleDeleted int `json:"staleDeleted"` // UPF sessions unknown to the SMF
+}
+
+// persistSession writes session changes through to the session store. A
+// failure is logged; the in-memory session stays authoritative.
+func (s *SessionService) persistSession(session *context.PDUSession) {
+	if err := s.smfContext.UpdateSession(session); err != nil {
+		s.logger.Error("Failed to persist session",
+			zap.String("supi", session.SUPI),
+			zap.Uint8("pdu_session_id", session.PDUSessionID),
+			zap.Error(err),
+		)
+	}
+}
+
+// upfAudit holds the sessions a UPF reported in a PFCP session audit
+type upfAudit struct {
+	upf    UPFInstance
+	client *n4.PFCPClient
+	seids  map[uint64]bool
+}
+
+// RestoreSessions reloads persisted sessions after a restart and reconciles
+// them with their UPFs through PFCP session audits. Active sessions missing
+// on their UPF are re-established, sessions caught mid-procedure are
+// released and UPF sessions the SMF no longer knows about are deleted.
+func (s *SessionService) RestoreSessions() (*RestoreResult, error) {
+	sessions, err := s.smfContext.RestoreSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	// Audit the default UPF and every UPF a restored session is anchored on
+	defaultUPF := s.defaultUPF()
+	upfs := []UPFInstance{defaultUPF}
+	for _, session := range sessions {
+		upf := s.anchorUPF(session)
+		if !containsUPF(upfs, upf) {
+			upfs = append(upfs, upf)
+		}
+	}
+
+	audits := make(map[string]*upfAudit, len(upfs))
+	for _, upf := range upfs {
+		audit, err := s.auditUPF(upf)
+		if err != nil {
+			if upf == defaultUPF {
+				return nil, fmt.Errorf("PFCP session audit failed: %w", err)
+			}
+			// Sessions on the UPF are re-established or released below
+			s.logger.Error("PFCP session audit failed", zap.String("upf_node_id", upf.NodeID), zap.Error(err))
+			continue
+		}
+		audits[upf.N4Address] = audit
+	}
+	onUPF := func(session *context.PDUSession) bool {
+		audit, ok := audits[s.anchorUPF(session).N4Address]
+		return ok && audit.seids[session.SEID]
+	}
+
+	result := &RestoreResult{Restored: len(sessions)}
+
+	for _, session := range sessions {
+		if err := s.ueIPPool.Reserve(session.UEIPv4Address, session.UEIPv6Prefix); err != nil {
+			s.logger.Warn("Failed to reserve UE IP of restored session",
+				zap.String("supi", session.SUPI),
+				zap.Error(err),
+			)
+		}
+
+		// Forwarding tunnels do not outlive the handover timer that was lost
+		// with the restart
+		s.releaseIndirectForwarding(session, "SMF restart")
+
+		if session.GetState() != context.PDUSessionStateActive {
+			s.releaseUnrecoverableSession(session, onUPF(session), "procedure interrupted by restart")
+			result.Released++
+			continue
+		}
+
+		if onUPF(session) {
+			result.InSync++
+			continue
+		}
+
+		if !s.reestablishSession(session) {
+			result.Released++
+			continue
+		}
+		result.Resynced++
+	}
+
+	// Delete UPF sessions the SMF has no context for
+	for _, audit := range audits {
+		for seid := range audit.seids {
+			if _, err := s.smfContext.GetSessionBySEID(seid); err == nil {
+				continue
+			}
+
+			pfcpResp, err := audit.client.DeleteSession(&n4.SessionDeletionRequest{SEID: seid})
+			if err == nil {
+				err = n4.ValidatePFCPResponse(pfcpResp.Cause)
+			}
+			if err != nil {
+				s.logger.Error("Failed to delete stale UPF session",
+					zap.String("upf_node_id", audit.upf.NodeID),
+					zap.Uint64("seid", seid),
+					zap.Error(err),
+				)
+				continue
+			}
+			result.StaleDeleted++
+		}
+	}
+
+	s.logger.Info("Sessions restored",
+		zap.Int("restored", result.Restored),
+		zap.Int("in_sync", result.InSync),
+		zap.Int("resynced", result.Resynced),
+		zap.Int("released", result.Released),
+		zap.Int("stale_deleted", result.StaleDeleted),
+	)
+
+	return result, nil
+}
+
+// reestablishSession re-establishes an active session missing on its UPF and
+// releases it if that fails. It reports whether the session was
+// re-established.
+func (s *SessionService) reestablishSession(session *context.PDUSession) bool {
+	// The anchor cannot be re-established without its uplink classifier
+	if session.GetULCL() != nil {
+		s.releaseUnrecoverableSession(session, false, "ULCL session cannot be re-established")
+		return false
+	}
+
+	if err := s.resyncSession(session); err != nil {
+		s.logger.Error("Failed to re-establish session on UPF",
+			zap.String("supi", session.SUPI),
+			zap.Uint8("pdu_session_id", session.PDUSessionID),
+			zap.Error(err),
+		)
+		s.releaseUnrecoverableSession(session, false, "UPF re-establishment failed")
+		return false
+	}
+	return true
+}
+
+// resyncSession re-establishes a session on its UPF
+func (s *SessionService) resyncSession(session *context.PDUSession) error {
+	upf := s.anchorUPF(session)
+	pfcpClient, err := s.pfcpClientFor(upf)
+	if err != nil {
+		return err
+	}
+
+	// Keep the N3 F-TEID the gNB already tunnels uplink traffic to
+	req := s.buildPFCPEstablishmentRequest(session, session.SEID, upf.NodeID)
+	if teid, n3Address := session.UPFTEIDUplink, session.UPFN3Address; teid != 0 {
+		for i := range req.PDRs {
+			if req.PDRs[i].PDI.FTEID != nil {
+				req.PDRs[i].PDI.FTEID = &n4.FTEID{TEID: teid, IPv4: n3Address}
+			}
+		}
+	}
+
+	pfcpResp, err := pfcpClient.EstablishSession(req)
+	if err != nil {
+		return err
+	}
+	if err := n4.ValidatePFCPResponse(pfcpResp.Cause); err != nil {
+		return err
+	}
+
+	session.SetUPFInfo(upf.NodeID, upf.N4Address, pfcpResp.UPFTEID.TEID)
+	session.SetUPFN3Address(pfcpResp.UPFTEID.IPv4)
+	s.persistSession(session)
+
+	s.logger.Info("Session re-established on UPF",
+		zap.String("supi", session.SUPI),
+		zap.Uint8("pdu_session_id", session.PDUSessionID),
+		zap.Uint32("upf_teid", pfcpResp.UPFTEID.TEID),
+	)
+	return nil
+}
+
+// releaseUnrecoverableSession drops a session that cannot be recovered on its
+// UPF
+func (s *SessionService) releaseUnrecoverableSession(session *context.PDUSession, onUPF bool, reason string) {
+	if onUPF {
+		if _, err := s.deleteUPFSession(session); err != nil {
+			s.logger.Error("PFCP session deletion failed", zap.Error(err))
+		}
+	}
+	s.deleteULCLSession(session)
+
+	s.ueIPPool.Release(session.UEIPv4Address, session.UEIPv6Prefix)
+	if err := s.smfContext.RemoveSession(session.SUPI, session.PDUSessionID); err != nil {
+		s.logger.Error("Failed to remove session from context", zap.Error(err))
+	}
+
+	s.logger.Warn("Unrecoverable session released",
+		zap.String("supi", session.SUPI),
+		zap.Uint8("pdu_session_id", session.PDUSessionID),
+		zap.String("reason", reason),
+	)
+}
+
+// auditUPF runs a PFCP session audit on a UPF
+func (s *SessionService) auditUPF(upf UPFInstance) (*upfAudit, error) {
+	pfcpClient, err := s.pfcpClientFor(upf)
+	if err != nil {
+		return nil, err
+	}
+
+	seids, err := pfcpClient.AuditSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	audit := &upfAudit{upf: upf, client: pfcpClient, seids: make(map[uint64]bool, len(seids))}
+	for _, seid := range seids {
+		audit.seids[seid] = true
+	}
+	return audit, nil
+}
+
+// containsUPF reports whether upfs holds a UPF with the N4 address of upf
+func containsUPF(upfs []UPFInstance, upf UPFInstance) bool {
+	for _, u := range upfs {
+		if u.N4Address == upf.N4Address {
+			return true
+		}
+	}
+	return false
+}
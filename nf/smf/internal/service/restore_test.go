@@ -0,0 +1,137 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/nf/smf/internal/config"
+	"github.com/your-org/5g-network/nf/smf/internal/context"
+	"github.com/your-org/5g-network/nf/smf/internal/n4"
+)
+
+// newPersistentSessionService creates a session service backed by a file
+// store in dir. The PFCP client stands in for the UPF and outlives restarts.
+func newPersistentSessionService(t *testing.T, dir string, pfcpClient *n4.PFCPClient) *SessionService {
+	t.Helper()
+
+	logger, _ := zap.NewDevelopment()
+	cfg := &config.Config{}
+	cfg.SMF.UESubnet.IPv4 = "10.60.0.0/24"
+
+	store, err := context.NewFileStore(dir)
+	require.NoError(t, err)
+	smfContext := context.NewSMFContextWithStore("upf-1", "127.0.0.1:8805", store)
+
+	svc, err := NewSessionService(cfg, smfContext, pfcpClient, logger)
+	require.NoError(t, err)
+	return svc
+}
+
+// newPersistentPFCPClient creates a simulated UPF client that records the
+// SEIDs of its sessions in dir, as a client opened after a restart would
+func newPersistentPFCPClient(t *testing.T, dir string) *n4.PFCPClient {
+	t.Helper()
+
+	logger, _ := zap.NewDevelopment()
+	store, err := n4.NewFileSEIDStore(filepath.Join(dir, "seids"))
+	require.NoError(t, err)
+	pfcpClient := n4.NewPFCPClient("upf-1", "127.0.0.1:8805", logger)
+	require.NoError(t, pfcpClient.SetSEIDStore(store))
+	return pfcpClient
+}
+
+func createTestSession(t *testing.T, svc *SessionService, supi string, id uint8) *CreateSessionResponse {
+	t.Helper()
+
+	resp, err := svc.CreateSession(&CreateSessionRequest{
+		SUPI:            supi,
+		PDUSessionID:    id,
+		DNN:             "internet",
+		GNBN3Address:    "10.0.0.1",
+		GNBTEIDDownlink: 0x100,
+	})
+	require.NoError(t, err)
+	return resp
+}
+
+func TestRestoreSessionsAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	upf := newPersistentPFCPClient(t, dir)
+
+	svc := newPersistentSessionService(t, dir, upf)
+	kept := createTestSession(t, svc, "imsi-001010000000001", 1)
+	lost := createTestSession(t, svc, "imsi-001010000000002", 1)
+
+	// While the SMF is down the UPF loses one session and keeps one the SMF
+	// never committed
+	_, err := upf.DeleteSession(&n4.SessionDeletionRequest{SEID: n4.GenerateSEID("imsi-001010000000002", 1)})
+	require.NoError(t, err)
+	_, err = upf.EstablishSession(&n4.SessionEstablishmentRequest{
+		SEID: 0xdead,
+		PDRs: []n4.PDR{{PDRID: 1, PDI: n4.PDI{SourceInterface: "ACCESS", FTEID: &n4.FTEID{}}}},
+	})
+	require.NoError(t, err)
+
+	// The restarted SMF only knows the UPF sessions through its SEID store
+	upf = newPersistentPFCPClient(t, dir)
+	restarted := newPersistentSessionService(t, dir, upf)
+	result, err := restarted.RestoreSessions()
+	require.NoError(t, err)
+	assert.Equal(t, &RestoreResult{Restored: 2, InSync: 1, Resynced: 1, StaleDeleted: 1}, result)
+
+	session, err := restarted.smfContext.GetSession("imsi-001010000000001", 1)
+	require.NoError(t, err)
+	assert.Equal(t, kept.UEIPv4Address, session.UEIPv4Address)
+	assert.Equal(t, kept.UPFTEIDUplink, session.UPFTEIDUplink)
+	assert.Equal(t, context.PDUSessionStateActive, session.GetState())
+
+	// The re-established session keeps its N3 tunnel
+	session, err = restarted.smfContext.GetSession("imsi-001010000000002", 1)
+	require.NoError(t, err)
+	assert.Equal(t, lost.UEIPv4Address, session.UEIPv4Address)
+	assert.Equal(t, lost.UPFTEIDUplink, session.UPFTEIDUplink)
+
+	// SMF and UPF agree on the session set
+	seids, err := upf.AuditSessions()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []uint64{
+		n4.GenerateSEID("imsi-001010000000001", 1),
+		n4.GenerateSEID("imsi-001010000000002", 1),
+	}, seids)
+
+	// Restored UE addresses are not handed out again
+	fresh := createTestSession(t, restarted, "imsi-001010000000003", 1)
+	assert.NotEqual(t, kept.UEIPv4Address, fresh.UEIPv4Address)
+	assert.NotEqual(t, lost.UEIPv4Address, fresh.UEIPv4Address)
+}
+
+func TestRestoreReleasesInterruptedSessions(t *testing.T) {
+	dir := t.TempDir()
+	logger, _ := zap.NewDevelopment()
+	upf := n4.NewPFCPClient("upf-1", "127.0.0.1:8805", logger)
+
+	svc := newPersistentSessionService(t, dir, upf)
+	createTestSession(t, svc, "imsi-001010000000001", 1)
+
+	// The SMF stops in the middle of a release
+	session, err := svc.smfContext.GetSession("imsi-001010000000001", 1)
+	require.NoError(t, err)
+	session.UpdateState(context.PDUSessionStateReleasing)
+	svc.persistSession(session)
+
+	restarted := newPersistentSessionService(t, dir, upf)
+	result, err := restarted.RestoreSessions()
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Released)
+
+	_, err = restarted.smfContext.GetSession("imsi-001010000000001", 1)
+	assert.Error(t, err)
+
+	seids, err := upf.AuditSessions()
+	require.NoError(t, err)
+	assert.Empty(t, seids)
+}
@@ -2,10 +2,10 @@ package service
 
 import (
 	"fmt"
-	"net"
 	"sync"
 	"time"
 
+	"github.com/your-org/5g-network/nf/smf/internal/client"
 	"github.com/your-org/5g-network/nf/smf/internal/config"
 	"github.com/your-org/5g-network/nf/smf/internal/context"
 	"github.com/your-org/5g-network/nf/smf/internal/n4"
@@ -20,7 +20,30 @@ type SessionService struct {
 	smfContext *context.SMFContext
 	pfcpClient *n4.PFCPClient
 	logger     *zap.Logger
-	ueIPPool   *IPPool
+	ueIPPool   *UEAddressPool
+
+	// AMF paging UEs with downlink data buffered by the UPF, nil to only
+	// log downlink data reports
+	amfClient *client.AMFClient
+
+	// PCF deciding the policy of new sessions, nil to apply the local
+	// policy
+	pcfClient *client.PCFClient
+
+	// UDM providing the subscribed session AMBR and default QoS, nil to
+	// apply the configured defaults
+	udmClient *client.UDMClient
+
+	// Indirect forwarding tunnel expiry timers keyed by session
+	forwardingTimers   map[string]*time.Timer
+	forwardingTimersMu sync.Mutex
+
+	// NRF-based UPF selection, nil to anchor every session on the default
+	// UPF. PFCP clients of selected UPFs are keyed by N4 address.
+	upfSelector   *UPFSelector
+	newPFCPClient PFCPClientFactory
+	upfClients    map[string]*n4.PFCPClient
+	upfClientsMu  sync.Mutex
 }
 
 // NewSessionService creates a new session service
@@ -30,19 +53,26 @@ func NewSessionService(
 	pfcpClient *n4.PFCPClient,
 	logger *zap.Logger,
 ) (*SessionService, error) {
-	// Initialize UE IP pool
-	ipPool, err := NewIPPool(cfg.SMF.UESubnet.IPv4)
+	// Initialize UE IP pools
+	ipPool, err := NewUEAddressPool(cfg.SMF.UESubnet)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create IP pool: %w", err)
 	}
 
-	return &SessionService{
+	s := &SessionService{
 		config:     cfg,
 		smfContext: smfContext,
 		pfcpClient: pfcpClient,
 		logger:     logger,
 		ueIPPool:   ipPool,
-	}, nil
+
+		forwardingTimers: make(map[string]*time.Timer),
+		upfClients:       make(map[string]*n4.PFCPClient),
+	}
+	if pfcpClient != nil {
+		pfcpClient.SetSessionReportHandler(s.HandleSessionReport)
+	}
+	return s, nil
 }
 
 // CreateSessionRequest represents a PDU session creation request from AMF
@@ -52,33 +82,44 @@ type CreateSessionRequest struct {
 	DNN            string         `json:"dnn"`
 	SNSSAI         context.SNSSAI `json:"snssai"`
 	PDUSessionType string         `json:"pduSessionType"`
+	SSCMode        string         `json:"sscMode,omitempty"` // Requested by the UE, e.g. "SSC_MODE_2"
 
-	// From gNB (via AMF)
-	GNBN3Address  string `json:"gnbN3Address"`
-	GNBTEIDUplink uint32 `json:"gnbTeidUplink"`
+	// From gNB (via AMF): the gNB end of the N3 tunnel, where the UPF sends
+	// downlink
+	GNBN3Address    string `json:"gnbN3Address"`
+	GNBTEIDDownlink uint32 `json:"gnbTeidUplink"`
 }
 
 // CreateSessionResponse represents a PDU session creation response
 type CreateSessionResponse struct {
-	Result        string          `json:"result"` // "SUCCESS", "FAILURE"
-	SUPI          string          `json:"supi"`
-	PDUSessionID  uint8           `json:"pduSessionId"`
-	UEIPv4Address string          `json:"ueIpv4Address,omitempty"`
-	SessionAMBR   context.BitRate `json:"sessionAmbr"`
-	QoSFlows      []QoSFlowInfo   `json:"qosFlows"`
-
-	// For gNB (via AMF)
-	UPFN3Address    string `json:"upfN3Address"`
-	UPFTEIDDownlink uint32 `json:"upfTeidDownlink"`
+	Result         string          `json:"result"` // "SUCCESS", "FAILURE"
+	SUPI           string          `json:"supi"`
+	PDUSessionID   uint8           `json:"pduSessionId"`
+	PDUSessionType string          `json:"pduSessionType,omitempty"` // Granted type
+	SSCMode        string          `json:"sscMode,omitempty"`        // Selected mode
+	UEIPv4Address  string          `json:"ueIpv4Address,omitempty"`
+	UEIPv6Prefix   string          `json:"ueIpv6Prefix,omitempty"`
+	SessionAMBR    context.BitRate `json:"sessionAmbr"`
+	QoSFlows       []QoSFlowInfo   `json:"qosFlows"`
+
+	// For gNB (via AMF): the UPF end of the N3 tunnel, where the gNB sends
+	// uplink
+	UPFN3Address  string `json:"upfN3Address"`
+	UPFTEIDUplink uint32 `json:"upfTeidDownlink"`
 
 	Reason string `json:"reason,omitempty"`
 }
 
 // QoSFlowInfo represents QoS flow information
 type QoSFlowInfo struct {
-	QFI      uint8 `json:"qfi"`
-	FiveQI   uint8 `json:"fiveQI"`
-	Priority uint8 `json:"priority"`
+	QFI      uint8            `json:"qfi"`
+	FiveQI   uint8            `json:"fiveQI"`
+	Priority uint8            `json:"priority"`
+	GBR      *context.BitRate `json:"gbr,omitempty"` // GBR flows only
+	MBR      *context.BitRate `json:"mbr,omitempty"` // GBR flows only
+
+	// Downlink packet filter, empty for a flow matching all session traffic
+	SDFFilter string `json:"sdfFilter,omitempty"`
 }
 
 // UpdateSessionRequest represents a PDU session update request
@@ -87,14 +128,21 @@ type UpdateSessionRequest struct {
 	PDUSessionID     uint8         `json:"pduSessionId"`
 	QoSFlowsToAdd    []QoSFlowInfo `json:"qosFlowsToAdd,omitempty"`
 	QoSFlowsToRemove []uint8       `json:"qosFlowsToRemove,omitempty"`
+
+	// User plane activation (TS 29.502 6.1.6.2.2): "ACTIVATED" with the gNB
+	// tunnel or "DEACTIVATED" when the UE goes idle
+	UpCnxState   string `json:"upCnxState,omitempty"`
+	GNBN3Address string `json:"gnbN3Address,omitempty"`
+	GNBTEID      uint32 `json:"gnbTeid,omitempty"`
 }
 
 // UpdateSessionResponse represents a PDU session update response
 type UpdateSessionResponse struct {
-	Result       string `json:"result"`
-	SUPI         string `json:"supi"`
-	PDUSessionID uint8  `json:"pduSessionId"`
-	Reason       string `json:"reason,omitempty"`
+	Result       string        `json:"result"`
+	SUPI         string        `json:"supi"`
+	PDUSessionID uint8         `json:"pduSessionId"`
+	QoSFlows     []QoSFlowInfo `json:"qosFlows,omitempty"` // Flow set after the update
+	Reason       string        `json:"reason,omitempty"`
 }
 
 // ReleaseSessionRequest represents a PDU session release request
@@ -124,107 +172,148 @@ func (s *SessionService) CreateSession(req *CreateSessionRequest) (*CreateSessio
 
 	// 1. Create PDU session context
 	session := context.NewPDUSession(req.SUPI, req.PDUSessionID, req.DNN, req.SNSSAI)
-	session.SetGNBInfo(req.GNBTEIDUplink, req.GNBN3Address)
+	session.SetGNBInfo(req.GNBTEIDDownlink, req.GNBN3Address)
 
-	// 2. Allocate UE IP address
-	ueIP, err := s.ueIPPool.Allocate()
+	// 2. Allocate UE IP address and/or IPv6 prefix for the session type
+	ueAddr, err := s.ueIPPool.Allocate(context.PDUSessionType(req.PDUSessionType))
 	if err != nil {
 		return &CreateSessionResponse{
 			Result: "FAILURE",
 			Reason: fmt.Sprintf("failed to allocate UE IP: %v", err),
 		}, err
 	}
-	session.SetUEIPAddress(ueIP, "")
+	session.SetPDUSessionType(ueAddr.PDUSessionType)
+	session.SetUEIPAddress(ueAddr.IPv4, ueAddr.IPv6Prefix)
 
-	// 3. Set Session AMBR (from policy or default)
-	session.SetSessionAMBR(1000000000, 2000000000) // 1 Gbps UL, 2 Gbps DL
+	// 3. Select the SSC mode from the request and the subscription
+	subscribed := s.subscribedDNN(session)
+	session.SSCMode = s.selectSSCMode(req.SSCMode, subscribed)
 
-	// 4. Add default QoS flow (QFI=1, 5QI=9 for internet)
-	defaultQoSFlow := &context.QoSFlow{
-		QFI:       1,
-		FiveQI:    9, // Non-GBR, internet
-		Priority:  10,
-		CreatedAt: time.Now(),
+	// 4. Anchor the session on the UPF selected for its DNN and S-NSSAI
+	if err := s.setupSession(session, subscribed, s.selectUPF(session)); err != nil {
+		s.ueIPPool.Release(ueAddr.IPv4, ueAddr.IPv6Prefix)
+		return &CreateSessionResponse{
+			Result: "FAILURE",
+			Reason: err.Error(),
+		}, err
 	}
-	session.AddQoSFlow(defaultQoSFlow)
 
-	// 5. Get UPF information
-	upfNodeID, upfN4Addr := s.smfContext.GetUPFInfo()
+	s.logger.Info("PDU session created successfully",
+		zap.String("supi", req.SUPI),
+		zap.Uint8("pdu_session_id", req.PDUSessionID),
+		zap.String("pdu_session_type", string(ueAddr.PDUSessionType)),
+		zap.String("ssc_mode", sscModeName(session.SSCMode)),
+		zap.String("ue_ip", ueAddr.IPv4),
+		zap.String("ue_ipv6_prefix", ueAddr.IPv6Prefix),
+		zap.Uint32("upf_teid", session.UPFTEIDUplink),
+	)
 
-	// 6. Generate SEID for PFCP session
-	seid := n4.GenerateSEID(req.SUPI, req.PDUSessionID)
+	return createSessionResponse(session), nil
+}
 
-	// 7. Build PFCP Session Establishment Request
-	pfcpReq := s.buildPFCPEstablishmentRequest(session, seid, upfNodeID)
+// setupSession applies the policy of a new session, establishes it on the
+// UPF and adds it to the SMF context. On failure the SM policy association
+// is deleted; the caller releases the UE addresses.
+func (s *SessionService) setupSession(session *context.PDUSession, subscribed *client.DNNConfiguration, upf UPFInstance) error {
+	// 1. Set Session AMBR and QoS flows from the subscription and PCF
+	// decision
+	policy := s.establishPolicy(session, subscribed)
+	session.SetSMPolicyID(policy.smPolicyID)
+	session.SetSessionAMBR(policy.sessionAMBR.Uplink, policy.sessionAMBR.Downlink)
+
+	// 2. Add the default QoS flow and the flows of PCC rules
+	session.AddQoSFlow(&context.QoSFlow{
+		QFI:                  defaultQFI,
+		FiveQI:               policy.fiveQI,
+		Priority:             policy.arpPriority,
+		CreatedAt:            time.Now(),
+		QoSMonitoringEnabled: s.config.SMF.QoSMonitoring.Enabled,
+	})
+	for _, flow := range policy.flows {
+		session.AddQoSFlow(flow)
+	}
 
-	// 8. Send PFCP Session Establishment to UPF
+	// 3. Open the PFCP client of the UPF
+	pfcpClient, err := s.pfcpClientFor(upf)
+	if err != nil {
+		s.logger.Error("UPF unavailable", zap.String("upf_node_id", upf.NodeID), zap.Error(err))
+		s.deleteSMPolicy(session)
+		return fmt.Errorf("UPF unavailable: %w", err)
+	}
+
+	// 4. Generate SEID for PFCP session
+	seid := n4.GenerateSEID(session.SUPI, session.PDUSessionID)
+	session.SetSEID(seid)
+
+	// 5. Build PFCP Session Establishment Request
+	pfcpReq := s.buildPFCPEstablishmentRequest(session, seid, upf.NodeID)
+
+	// 6. Send PFCP Session Establishment to UPF
 	session.UpdateState(context.PDUSessionStateActivePending)
 
-	pfcpResp, err := s.pfcpClient.EstablishSession(pfcpReq)
+	pfcpResp, err := pfcpClient.EstablishSession(pfcpReq)
 	if err != nil {
 		s.logger.Error("PFCP session establishment failed", zap.Error(err))
-		s.ueIPPool.Release(ueIP)
-		return &CreateSessionResponse{
-			Result: "FAILURE",
-			Reason: fmt.Sprintf("PFCP establishment failed: %v", err),
-		}, err
+		s.deleteSMPolicy(session)
+		return fmt.Errorf("PFCP establishment failed: %w", err)
 	}
 
-	// 9. Validate PFCP response
+	// 7. Validate PFCP response
 	if err := n4.ValidatePFCPResponse(pfcpResp.Cause); err != nil {
 		s.logger.Error("PFCP response invalid", zap.Error(err))
-		s.ueIPPool.Release(ueIP)
-		return &CreateSessionResponse{
-			Result: "FAILURE",
-			Reason: fmt.Sprintf("PFCP response invalid: %v", err),
-		}, err
+		s.deleteSMPolicy(session)
+		return fmt.Errorf("PFCP response invalid: %w", err)
 	}
 
-	// 10. Update session with UPF information
-	session.SetUPFInfo(
-		upfNodeID,
-		upfN4Addr,
-		pfcpResp.UPFTEID.TEID,
-		pfcpResp.UPFTEID.TEID, // Use same TEID for simplicity
-	)
+	// 8. Update session with UPF information
+	session.SetUPFInfo(upf.NodeID, upf.N4Address, pfcpResp.UPFTEID.TEID)
+	session.SetUPFN3Address(pfcpResp.UPFTEID.IPv4)
+
+	// 9. Insert the uplink classifier of a local breakout DNN in front of
+	// the anchor
+	if breakout := s.localBreakout(session.DNN); breakout != nil {
+		if err := s.insertULCL(session, breakout); err != nil {
+			s.logger.Error("ULCL insertion failed", zap.Error(err))
+			if _, delErr := s.deleteUPFSession(session); delErr != nil {
+				s.logger.Error("PFCP session deletion failed", zap.Error(delErr))
+			}
+			s.deleteSMPolicy(session)
+			return err
+		}
+	}
 
-	// 11. Update session state to active
+	// 10. Update session state to active
 	session.UpdateState(context.PDUSessionStateActive)
 
-	// 12. Add session to SMF context
+	// 11. Add session to SMF context
 	if err := s.smfContext.AddSession(session); err != nil {
 		s.logger.Error("Failed to add session to context", zap.Error(err))
-		s.ueIPPool.Release(ueIP)
-		return &CreateSessionResponse{
-			Result: "FAILURE",
-			Reason: fmt.Sprintf("failed to add session: %v", err),
-		}, err
+		s.deleteSMPolicy(session)
+		return fmt.Errorf("failed to add session: %w", err)
 	}
+	return nil
+}
 
-	s.logger.Info("PDU session created successfully",
-		zap.String("supi", req.SUPI),
-		zap.Uint8("pdu_session_id", req.PDUSessionID),
-		zap.String("ue_ip", ueIP),
-		zap.Uint32("upf_teid", pfcpResp.UPFTEID.TEID),
-	)
-
-	// 13. Build response
+// createSessionResponse builds the response for an established session
+func createSessionResponse(session *context.PDUSession) *CreateSessionResponse {
+	flows := session.GetQoSFlows()
+	qosFlows := make([]QoSFlowInfo, 0, len(flows))
+	for _, flow := range flows {
+		qosFlows = append(qosFlows, qosFlowInfo(flow))
+	}
 	return &CreateSessionResponse{
-		Result:        "SUCCESS",
-		SUPI:          req.SUPI,
-		PDUSessionID:  req.PDUSessionID,
-		UEIPv4Address: ueIP,
-		SessionAMBR:   session.SessionAMBR,
-		QoSFlows: []QoSFlowInfo{
-			{
-				QFI:      uint8(defaultQoSFlow.QFI),
-				FiveQI:   defaultQoSFlow.FiveQI,
-				Priority: defaultQoSFlow.Priority,
-			},
-		},
-		UPFN3Address:    pfcpResp.UPFTEID.IPv4,
-		UPFTEIDDownlink: pfcpResp.UPFTEID.TEID,
-	}, nil
+		Result:         "SUCCESS",
+		SUPI:           session.SUPI,
+		PDUSessionID:   session.PDUSessionID,
+		PDUSessionType: string(session.PDUSessionType),
+		SSCMode:        sscModeName(session.SSCMode),
+		UEIPv4Address:  session.UEIPv4Address,
+		UEIPv6Prefix:   session.UEIPv6Prefix,
+		SessionAMBR:    session.SessionAMBR,
+		QoSFlows:       qosFlows,
+		UPFN3Address:   session.UPFN3Address,
+		UPFTEIDUplink:  session.UPFTEIDUplink,
+	}
 }
 
 // ReleaseSession handles PDU session release
@@ -246,16 +335,11 @@ func (s *SessionService) ReleaseSession(req *ReleaseSessionRequest) (*ReleaseSes
 
 	// 2. Update session state
 	session.UpdateState(context.PDUSessionStateReleasing)
+	s.persistSession(session)
 
-	// 3. Generate SEID
-	seid := n4.GenerateSEID(req.SUPI, req.PDUSessionID)
-
-	// 4. Send PFCP Session Deletion to UPF
-	pfcpReq := &n4.SessionDeletionRequest{
-		SEID: seid,
-	}
-
-	pfcpResp, err := s.pfcpClient.DeleteSession(pfcpReq)
+	// 3. Send PFCP Session Deletion to the ULCL and anchor UPFs
+	s.deleteULCLSession(session)
+	pfcpResp, err := s.deleteUPFSession(session)
 	if err != nil {
 		s.logger.Error("PFCP session deletion failed", zap.Error(err))
 		// Continue with local cleanup
@@ -263,10 +347,12 @@ func (s *SessionService) ReleaseSession(req *ReleaseSessionRequest) (*ReleaseSes
 		s.logger.Error("PFCP deletion response invalid", zap.Error(err))
 	}
 
-	// 5. Release UE IP address
-	s.ueIPPool.Release(session.UEIPv4Address)
+	// 4. Release UE IP address and IPv6 prefix, and the SM policy
+	s.ueIPPool.Release(session.UEIPv4Address, session.UEIPv6Prefix)
+	s.stopForwardingTimer(session)
+	s.deleteSMPolicy(session)
 
-	// 6. Remove session from context
+	// 5. Remove session from context
 	if err := s.smfContext.RemoveSession(req.SUPI, req.PDUSessionID); err != nil {
 		s.logger.Error("Failed to remove session from context", zap.Error(err))
 	}
@@ -283,45 +369,82 @@ func (s *SessionService) ReleaseSession(req *ReleaseSessionRequest) (*ReleaseSes
 	}, nil
 }
 
+// defaultQFI identifies the default QoS flow, which lives as long as the
+// session
+const defaultQFI context.QoSFlowIdentifier = 1
+
+// QER IDs: the session AMBR QER is shared, every QoS flow has its own QER
+const sessionAMBRQERID uint16 = 1
+
+// sessionURRID identifies the URR measuring the usage of the whole session
+const sessionURRID uint32 = 1
+
+// flowQERID returns the QER ID of a QoS flow
+func flowQERID(qfi context.QoSFlowIdentifier) uint16 {
+	return sessionAMBRQERID + uint16(qfi)
+}
+
+// flowPDRIDs returns the uplink and downlink PDR IDs of a QoS flow. They
+// depend on the QFI only, so flows can be added and removed independently.
+func flowPDRIDs(qfi context.QoSFlowIdentifier) (uplink, downlink uint16) {
+	return 2*uint16(qfi) - 1, 2 * uint16(qfi)
+}
+
+// PDR precedences of QoS flows. Flows with a packet filter are matched
+// before the flows carrying all traffic of the session; the QFI keeps the
+// precedences of a session's flows distinct.
+const (
+	filteredFlowPrecedence uint32 = 100
+	matchAllFlowPrecedence uint32 = 200
+)
+
+// flowPrecedence returns the precedence of the PDRs of a QoS flow
+func flowPrecedence(flow *context.QoSFlow) uint32 {
+	if flow.SDFFilter != "" {
+		return filteredFlowPrecedence + uint32(flow.QFI)
+	}
+	return matchAllFlowPrecedence + uint32(flow.QFI)
+}
+
+// qosFlowInfo converts a QoS flow to its API representation
+func qosFlowInfo(flow *context.QoSFlow) QoSFlowInfo {
+	return QoSFlowInfo{
+		QFI:       uint8(flow.QFI),
+		FiveQI:    flow.FiveQI,
+		Priority:  flow.Priority,
+		GBR:       flow.GBR,
+		MBR:       flow.MBR,
+		SDFFilter: flow.SDFFilter,
+	}
+}
+
 // buildPFCPEstablishmentRequest builds PFCP Session Establishment Request
 func (s *SessionService) buildPFCPEstablishmentRequest(
 	session *context.PDUSession,
 	seid uint64,
 	upfNodeID string,
 ) *n4.SessionEstablishmentRequest {
-	// Build PDRs (Packet Detection Rules)
-	pdrs := []n4.PDR{
-		// PDR for uplink (from UE to DN)
-		{
-			PDRID:      1,
-			Precedence: 100,
-			PDI: n4.PDI{
-				SourceInterface: "ACCESS",
-				FTEID: &n4.FTEID{
-					TEID: session.GNBTEIDUplink,
-					IPv4: session.GNBN3Address,
-				},
-				UEIPAddress:     session.UEIPv4Address,
-				NetworkInstance: session.DNN,
-			},
-			OuterHeaderRemoval: true,
-			FARID:              1,
-			QERID:              1,
-		},
-		// PDR for downlink (from DN to UE)
+	// Build QERs (QoS Enforcement Rules). The session AMBR is shared by all
+	// non-GBR flows; GBR flows are only limited by their own GFBR/MFBR
+	// (TS 23.501 5.7.2.6).
+	qers := []n4.QER{
 		{
-			PDRID:      2,
-			Precedence: 100,
-			PDI: n4.PDI{
-				SourceInterface: "CORE",
-				UEIPAddress:     session.UEIPv4Address,
-				NetworkInstance: session.DNN,
-			},
-			FARID: 2,
-			QERID: 1,
+			QERID:       sessionAMBRQERID,
+			MBRUplink:   session.SessionAMBR.Uplink,
+			MBRDownlink: session.SessionAMBR.Downlink,
 		},
 	}
 
+	// Build PDRs (Packet Detection Rules), an uplink/downlink pair per QoS
+	// flow. The UPF allocates the N3 F-TEID; uplink PDRs of all flows share
+	// it through the same Choose ID.
+	var pdrs []n4.PDR
+	for _, flow := range session.GetQoSFlows() {
+		flowQER, flowPDRs := s.buildQoSFlowRules(session, flow, &n4.FTEID{CHID: n3CHID})
+		qers = append(qers, flowQER)
+		pdrs = append(pdrs, flowPDRs...)
+	}
+
 	// Build FARs (Forwarding Action Rules)
 	fars := []n4.FAR{
 		// FAR for uplink
@@ -333,116 +456,232 @@ func (s *SessionService) buildPFCPEstablishmentRequest(
 				NetworkInstance:      session.DNN,
 			},
 		},
-		// FAR for downlink
+		// FAR for downlink, tunnelled to the TEID the gNB allocated
 		{
-			FARID:       2,
+			FARID:       downlinkFARID,
 			ApplyAction: "FORWARD",
 			ForwardingParameters: &n4.ForwardingParameters{
 				DestinationInterface: "ACCESS",
 				NetworkInstance:      session.DNN,
 				OuterHeaderCreation: &n4.OuterHeaderCreation{
-					TEID: session.GNBTEIDUplink,
+					TEID: session.GNBTEIDDownlink,
 					IPv4: session.GNBN3Address,
 				},
 			},
 		},
 	}
-
-	// Build QERs (QoS Enforcement Rules)
-	qers := []n4.QER{
-		{
-			QERID:       1,
-			QFI:         1,
-			MBRUplink:   session.SessionAMBR.Uplink,
-			MBRDownlink: session.SessionAMBR.Downlink,
-		},
+	// Until the gNB has allocated its tunnel, downlink is buffered
+	if session.IsUpCnxDeactivated() {
+		fars[1] = n4.FAR{FARID: downlinkFARID, ApplyAction: "BUFFER"}
 	}
 
 	return &n4.SessionEstablishmentRequest{
 		NodeID:        upfNodeID,
 		SEID:          seid,
 		UEIPv4Address: session.UEIPv4Address,
+		UEIPv6Prefix:  session.UEIPv6Prefix,
 		DNN:           session.DNN,
 		PDRs:          pdrs,
 		FARs:          fars,
 		QERs:          qers,
+		URRs:          s.buildUsageReporting(),
 	}
 }
 
-// GetSessionStatistics returns session statistics
-func (s *SessionService) GetSessionStatistics() map[string]interface{} {
-	stats := s.smfContext.GetStatistics()
-	return map[string]interface{}{
-		"total_sessions":    stats.TotalSessions,
-		"active_sessions":   stats.ActiveSessions,
-		"released_sessions": stats.ReleasedSessions,
-		"allocated_ue_ips":  s.ueIPPool.AllocatedCount(),
+// buildQoSFlowRules builds the QER and the uplink/downlink PDR pair of a
+// QoS flow, matching uplink traffic on the given N3 F-TEID
+func (s *SessionService) buildQoSFlowRules(
+	session *context.PDUSession,
+	flow *context.QoSFlow,
+	uplinkFTEID *n4.FTEID,
+) (n4.QER, []n4.PDR) {
+	flowQER := n4.QER{
+		QERID: flowQERID(flow.QFI),
+		QFI:   uint8(flow.QFI),
+	}
+	qerIDs := []uint16{flowQER.QERID}
+
+	if flow.IsGBR() {
+		if flow.GBR != nil {
+			flowQER.GBRUplink = flow.GBR.Uplink
+			flowQER.GBRDownlink = flow.GBR.Downlink
+		}
+		if flow.MBR != nil {
+			flowQER.MBRUplink = flow.MBR.Uplink
+			flowQER.MBRDownlink = flow.MBR.Downlink
+		}
+	} else {
+		qerIDs = append(qerIDs, sessionAMBRQERID)
+	}
+
+	// Request QoS monitoring for flows that have it enabled
+	if flow.QoSMonitoringEnabled {
+		flowQER.QoSMonitoring = s.buildQoSMonitoring()
+	}
+
+	var urrIDs []uint32
+	if s.config.SMF.UsageReporting.Enabled {
+		urrIDs = []uint32{sessionURRID}
+	}
+
+	uplinkPDRID, downlinkPDRID := flowPDRIDs(flow.QFI)
+	precedence := flowPrecedence(flow)
+	pdrs := []n4.PDR{
+		// PDR for uplink (from UE to DN)
+		{
+			PDRID:      uplinkPDRID,
+			Precedence: precedence,
+			PDI: n4.PDI{
+				SourceInterface: "ACCESS",
+				FTEID:           uplinkFTEID,
+				UEIPAddress:     session.UEIPv4Address,
+				UEIPv6Prefix:    session.UEIPv6Prefix,
+				NetworkInstance: session.DNN,
+				QFI:             uint8(flow.QFI),
+			},
+			OuterHeaderRemoval: true,
+			FARID:              1,
+			QERIDs:             qerIDs,
+			URRIDs:             urrIDs,
+		},
+		// PDR for downlink (from DN to UE), told apart from the other
+		// flows by the flow's packet filter
+		{
+			PDRID:      downlinkPDRID,
+			Precedence: precedence,
+			PDI: n4.PDI{
+				SourceInterface: "CORE",
+				UEIPAddress:     session.UEIPv4Address,
+				UEIPv6Prefix:    session.UEIPv6Prefix,
+				NetworkInstance: session.DNN,
+				QFI:             uint8(flow.QFI),
+				SDFFilter:       flow.SDFFilter,
+			},
+			FARID:  2,
+			QERIDs: qerIDs,
+			URRIDs: urrIDs,
+		},
 	}
-}
 
-// IPPool manages UE IP address allocation
-type IPPool struct {
-	subnet    *net.IPNet
-	allocated map[string]bool
-	mu        sync.Mutex
+	return flowQER, pdrs
 }
 
-// NewIPPool creates a new IP pool
-func NewIPPool(cidr string) (*IPPool, error) {
-	_, ipNet, err := net.ParseCIDR(cidr)
-	if err != nil {
-		return nil, fmt.Errorf("invalid CIDR: %w", err)
+// buildQoSMonitoring builds the QoS monitoring indicator from configuration
+func (s *SessionService) buildQoSMonitoring() *n4.QoSMonitoring {
+	cfg := s.config.SMF.QoSMonitoring
+	return &n4.QoSMonitoring{
+		RequestDownlink:    true,
+		RequestUplink:      true,
+		RequestRoundTrip:   true,
+		DownlinkThreshold:  cfg.DownlinkThreshold,
+		UplinkThreshold:    cfg.UplinkThreshold,
+		RoundTripThreshold: cfg.RoundTripThreshold,
+		MinimumWaitTime:    cfg.MinimumWaitTime,
 	}
+}
 
-	return &IPPool{
-		subnet:    ipNet,
-		allocated: make(map[string]bool),
-	}, nil
+// buildUsageReporting builds the session URR from configuration, none when
+// usage reporting is disabled
+func (s *SessionService) buildUsageReporting() []n4.URR {
+	cfg := s.config.SMF.UsageReporting
+	if !cfg.Enabled {
+		return nil
+	}
+	return []n4.URR{{
+		URRID:             sessionURRID,
+		MeasureVolume:     true,
+		MeasureDuration:   true,
+		VolumeThreshold:   cfg.VolumeThreshold,
+		TimeThreshold:     cfg.TimeThreshold,
+		MeasurementPeriod: cfg.Period,
+	}}
 }
 
-// Allocate allocates a new IP address
-func (p *IPPool) Allocate() (string, error) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
+// HandleSessionReport handles a PFCP Session Report Request from the UPF
+func (s *SessionService) HandleSessionReport(req *n4.SessionReportRequest) (*n4.SessionReportResponse, error) {
+	session, err := s.smfContext.GetSessionBySEID(req.SEID)
+	if err != nil {
+		return &n4.SessionReportResponse{
+			SEID:  req.SEID,
+			Cause: "Session context not found",
+		}, err
+	}
+
+	if req.DownlinkDataReport != nil {
+		// Downlink data for an idle session, the AMF must page the UE
+		s.logger.Info("Downlink data notification received",
+			zap.String("supi", session.SUPI),
+			zap.Uint8("pdu_session_id", session.PDUSessionID),
+			zap.Uint16("pdr_id", req.DownlinkDataReport.PDRID),
+			zap.Uint8("qfi", req.DownlinkDataReport.QFI),
+		)
+		go s.notifyDownlinkData(session)
+	}
 
-	// Simple allocation - increment from network base
-	ip := make(net.IP, len(p.subnet.IP))
-	copy(ip, p.subnet.IP)
+	if req.ErrorIndication != nil {
+		// The gNB lost the N3 tunnel, the UPF has stopped downlink on it
+		// until the session is modified with a new one
+		s.logger.Warn("N3 tunnel reported unknown by the gNB",
+			zap.String("supi", session.SUPI),
+			zap.Uint8("pdu_session_id", session.PDUSessionID),
+			zap.Uint32("gnb_teid", req.ErrorIndication.RemoteTEID),
+			zap.Stringer("gnb_address", req.ErrorIndication.RemoteAddress),
+		)
+	}
 
-	// Start from .1 (skip .0)
-	ip[len(ip)-1]++
+	for _, report := range req.UsageReports {
+		s.logger.Info("Usage report received",
+			zap.String("supi", session.SUPI),
+			zap.Uint8("pdu_session_id", session.PDUSessionID),
+			zap.Uint32("urr_id", report.URRID),
+			zap.Uint32("ur_seqn", report.SequenceNumber),
+			zap.Uint64("uplink_bytes", report.UplinkVolume),
+			zap.Uint64("downlink_bytes", report.DownlinkVolume),
+			zap.Duration("duration", report.Duration),
+		)
+	}
 
-	for p.subnet.Contains(ip) {
-		ipStr := ip.String()
-		if !p.allocated[ipStr] {
-			p.allocated[ipStr] = true
-			return ipStr, nil
+	for _, report := range req.QoSMonitoringReports {
+		reportedAt := report.EventTime
+		if reportedAt.IsZero() {
+			reportedAt = time.Now()
 		}
 
-		// Increment IP
-		for i := len(ip) - 1; i >= 0; i-- {
-			ip[i]++
-			if ip[i] != 0 {
-				break
-			}
+		delay := &context.PacketDelay{
+			Uplink:     report.UplinkDelay,
+			Downlink:   report.DownlinkDelay,
+			RoundTrip:  report.RoundTripDelay,
+			ReportedAt: reportedAt,
+		}
+		if err := session.UpdatePacketDelay(context.QoSFlowIdentifier(report.QFI), delay); err != nil {
+			s.logger.Warn("Ignoring QoS monitoring report", zap.Uint64("seid", req.SEID), zap.Error(err))
+			continue
 		}
-	}
-
-	return "", fmt.Errorf("IP pool exhausted")
-}
 
-// Release releases an IP address back to the pool
-func (p *IPPool) Release(ip string) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
+		s.logger.Info("QoS monitoring report received",
+			zap.String("supi", session.SUPI),
+			zap.Uint8("pdu_session_id", session.PDUSessionID),
+			zap.Uint8("qfi", report.QFI),
+			zap.Duration("ul_delay", report.UplinkDelay),
+			zap.Duration("dl_delay", report.DownlinkDelay),
+			zap.Duration("rtt", report.RoundTripDelay),
+		)
+	}
 
-	delete(p.allocated, ip)
+	return &n4.SessionReportResponse{
+		SEID:  req.SEID,
+		Cause: "Request accepted",
+	}, nil
 }
 
-// AllocatedCount returns the number of allocated IPs
-func (p *IPPool) AllocatedCount() int {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
-	return len(p.allocated)
+// GetSessionStatistics returns session statistics
+func (s *SessionService) GetSessionStatistics() map[string]interface{} {
+	stats := s.smfContext.GetStatistics()
+	return map[string]interface{}{
+		"total_sessions":             stats.TotalSessions,
+		"active_sessions":            stats.ActiveSessions,
+		"released_sessions":          stats.ReleasedSessions,
+		"allocated_ue_ips":           s.ueIPPool.AllocatedIPv4Count(),
+		"allocated_ue_ipv6_prefixes": s.ueIPPool.AllocatedIPv6Count(),
+	}
 }
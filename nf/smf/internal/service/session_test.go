@@ -0,0 +1,237 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/nf/smf/internal/config"
+	"github.com/your-org/5g-network/nf/smf/internal/context"
+	"github.com/your-org/5g-network/nf/smf/internal/n4"
+)
+
+func newTestSessionService(t *testing.T, monitoring config.QoSMonitoringConfig) *SessionService {
+	t.Helper()
+
+	logger, _ := zap.NewDevelopment()
+	cfg := &config.Config{}
+	cfg.SMF.UESubnet.IPv4 = "10.60.0.0/24"
+	cfg.SMF.QoSMonitoring = monitoring
+
+	smfContext := context.NewSMFContext("upf-1", "127.0.0.1:8805")
+	pfcpClient := n4.NewPFCPClient("upf-1", "127.0.0.1:8805", logger)
+
+	svc, err := NewSessionService(cfg, smfContext, pfcpClient, logger)
+	require.NoError(t, err)
+	return svc
+}
+
+func TestQoSMonitoringIndicatorSentToUPF(t *testing.T) {
+	svc := newTestSessionService(t, config.QoSMonitoringConfig{
+		Enabled:         true,
+		UplinkThreshold: 20 * time.Millisecond,
+		MinimumWaitTime: 5 * time.Second,
+	})
+
+	session := context.NewPDUSession("imsi-001010000000001", 1, "internet", context.SNSSAI{SST: 1})
+	session.AddQoSFlow(&context.QoSFlow{QFI: 1, FiveQI: 9, QoSMonitoringEnabled: true})
+
+	req := svc.buildPFCPEstablishmentRequest(session, 1, "upf-1")
+	qer := findQER(req.QERs, flowQERID(1))
+	require.NotNil(t, qer)
+	require.NotNil(t, qer.QoSMonitoring)
+	assert.True(t, qer.QoSMonitoring.RequestUplink)
+	assert.Equal(t, 20*time.Millisecond, qer.QoSMonitoring.UplinkThreshold)
+	assert.Equal(t, 5*time.Second, qer.QoSMonitoring.MinimumWaitTime)
+	assert.Nil(t, findQER(req.QERs, sessionAMBRQERID).QoSMonitoring)
+}
+
+func TestCreateSessionKeepsN3TunnelEndsApart(t *testing.T) {
+	svc := newTestSessionService(t, config.QoSMonitoringConfig{})
+	const gnbTEID = 0xabcd0001
+
+	resp, err := svc.CreateSession(&CreateSessionRequest{
+		SUPI:            "imsi-001010000000001",
+		PDUSessionID:    1,
+		DNN:             "internet",
+		GNBN3Address:    "192.168.1.10",
+		GNBTEIDDownlink: gnbTEID,
+	})
+	require.NoError(t, err)
+	session, err := svc.smfContext.GetSession("imsi-001010000000001", 1)
+	require.NoError(t, err)
+
+	// The gNB is told the UPF end of the tunnel, not its own TEID
+	assert.NotZero(t, session.UPFTEIDUplink)
+	assert.NotEqual(t, uint32(gnbTEID), session.UPFTEIDUplink)
+	assert.Equal(t, session.UPFTEIDUplink, resp.UPFTEIDUplink)
+	assert.Equal(t, uint32(gnbTEID), session.GNBTEIDDownlink)
+
+	// Uplink PDRs let the UPF choose its TEID, downlink is tunnelled to the
+	// gNB TEID
+	req := svc.buildPFCPEstablishmentRequest(session, session.SEID, "upf-1")
+	for _, pdr := range req.PDRs {
+		if pdr.PDI.SourceInterface == "ACCESS" {
+			require.NotNil(t, pdr.PDI.FTEID)
+			assert.Equal(t, n3CHID, pdr.PDI.FTEID.CHID)
+			assert.Zero(t, pdr.PDI.FTEID.TEID)
+		} else {
+			assert.Nil(t, pdr.PDI.FTEID)
+		}
+	}
+	for _, far := range req.FARs {
+		ohc := far.ForwardingParameters.OuterHeaderCreation
+		if far.ForwardingParameters.DestinationInterface == "ACCESS" {
+			require.NotNil(t, ohc)
+			assert.Equal(t, uint32(gnbTEID), ohc.TEID)
+			assert.Equal(t, "192.168.1.10", ohc.IPv4)
+		} else {
+			assert.Nil(t, ohc)
+		}
+	}
+}
+
+func TestQoSMonitoringDisabledByDefault(t *testing.T) {
+	svc := newTestSessionService(t, config.QoSMonitoringConfig{})
+
+	resp, err := svc.CreateSession(&CreateSessionRequest{
+		SUPI:         "imsi-001010000000001",
+		PDUSessionID: 1,
+		DNN:          "internet",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "SUCCESS", resp.Result)
+
+	session, err := svc.smfContext.GetSession("imsi-001010000000001", 1)
+	require.NoError(t, err)
+	assert.False(t, session.QoSFlows[1].QoSMonitoringEnabled)
+
+	req := svc.buildPFCPEstablishmentRequest(session, session.SEID, "upf-1")
+	for _, qer := range req.QERs {
+		assert.Nil(t, qer.QoSMonitoring)
+	}
+}
+
+func TestHandleSessionReportStoresPacketDelay(t *testing.T) {
+	svc := newTestSessionService(t, config.QoSMonitoringConfig{Enabled: true})
+
+	_, err := svc.CreateSession(&CreateSessionRequest{
+		SUPI:         "imsi-001010000000001",
+		PDUSessionID: 1,
+		DNN:          "internet",
+	})
+	require.NoError(t, err)
+
+	seid := n4.GenerateSEID("imsi-001010000000001", 1)
+	resp, err := svc.HandleSessionReport(&n4.SessionReportRequest{
+		SEID: seid,
+		QoSMonitoringReports: []n4.QoSMonitoringReport{
+			{QFI: 1, UplinkDelay: 30 * time.Millisecond, DownlinkDelay: 15 * time.Millisecond, RoundTripDelay: 45 * time.Millisecond},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Request accepted", resp.Cause)
+
+	session, err := svc.smfContext.GetSessionBySEID(seid)
+	require.NoError(t, err)
+	delay := session.QoSFlows[1].PacketDelay
+	require.NotNil(t, delay)
+	assert.Equal(t, 30*time.Millisecond, delay.Uplink)
+	assert.Equal(t, 15*time.Millisecond, delay.Downlink)
+	assert.Equal(t, 45*time.Millisecond, delay.RoundTrip)
+
+	_, err = svc.HandleSessionReport(&n4.SessionReportRequest{SEID: 42})
+	assert.Error(t, err)
+}
+
+func findQER(qers []n4.QER, qerID uint16) *n4.QER {
+	for i := range qers {
+		if qers[i].QERID == qerID {
+			return &qers[i]
+		}
+	}
+	return nil
+}
+
+func TestSessionAMBRAppliesOnlyToNonGBRFlows(t *testing.T) {
+	svc := newTestSessionService(t, config.QoSMonitoringConfig{})
+
+	session := context.NewPDUSession("imsi-001010000000001", 1, "internet", context.SNSSAI{SST: 1})
+	session.SetSessionAMBR(1000000, 2000000)
+	session.AddQoSFlow(&context.QoSFlow{QFI: 1, FiveQI: 9})
+	session.AddQoSFlow(&context.QoSFlow{QFI: 2, FiveQI: 8})
+	session.AddQoSFlow(&context.QoSFlow{
+		QFI:    5,
+		FiveQI: 1, // Conversational voice
+		GBR:    &context.BitRate{Uplink: 128000, Downlink: 128000},
+		MBR:    &context.BitRate{Uplink: 50000000, Downlink: 50000000},
+	})
+
+	req := svc.buildPFCPEstablishmentRequest(session, 1, "upf-1")
+
+	ambr := findQER(req.QERs, sessionAMBRQERID)
+	require.NotNil(t, ambr)
+	assert.Zero(t, ambr.QFI)
+	assert.Equal(t, uint64(1000000), ambr.MBRUplink)
+	assert.Equal(t, uint64(2000000), ambr.MBRDownlink)
+
+	gbr := findQER(req.QERs, flowQERID(5))
+	require.NotNil(t, gbr)
+	assert.Equal(t, uint8(5), gbr.QFI)
+	assert.Equal(t, uint64(128000), gbr.GBRUplink)
+	assert.Equal(t, uint64(50000000), gbr.MBRDownlink)
+
+	require.Len(t, req.PDRs, 6)
+	for _, pdr := range req.PDRs {
+		switch pdr.PDI.QFI {
+		case 5:
+			assert.Equal(t, []uint16{flowQERID(5)}, pdr.QERIDs, "GBR flow must not be limited by session AMBR")
+		default:
+			assert.Contains(t, pdr.QERIDs, sessionAMBRQERID, "non-GBR flow QFI %d must share the session AMBR", pdr.PDI.QFI)
+			assert.Contains(t, pdr.QERIDs, flowQERID(context.QoSFlowIdentifier(pdr.PDI.QFI)))
+		}
+	}
+
+	// The default flow keeps the well-known uplink/downlink PDR IDs
+	assert.Equal(t, uint16(1), req.PDRs[0].PDRID)
+	assert.Equal(t, uint16(2), req.PDRs[1].PDRID)
+	assert.Equal(t, uint8(1), req.PDRs[0].PDI.QFI)
+}
+
+func TestDownlinkPDRsMatchFlowFilterBeforeDefaultFlow(t *testing.T) {
+	svc := newTestSessionService(t, config.QoSMonitoringConfig{})
+
+	session := context.NewPDUSession("imsi-001010000000001", 1, "internet", context.SNSSAI{SST: 1})
+	session.AddQoSFlow(&context.QoSFlow{QFI: 1, FiveQI: 9})
+	session.AddQoSFlow(&context.QoSFlow{QFI: 2, FiveQI: 8})
+	session.AddQoSFlow(&context.QoSFlow{
+		QFI:       5,
+		FiveQI:    1,
+		GBR:       &context.BitRate{Uplink: 128000, Downlink: 128000},
+		SDFFilter: "permit out 17 from 198.51.100.0/24 to assigned",
+	})
+
+	req := svc.buildPFCPEstablishmentRequest(session, 1, "upf-1")
+
+	downlink := make(map[uint8]n4.PDR)
+	precedences := make(map[uint32]bool)
+	for _, pdr := range req.PDRs {
+		if pdr.PDI.SourceInterface != "CORE" {
+			continue
+		}
+		downlink[pdr.PDI.QFI] = pdr
+		assert.False(t, precedences[pdr.Precedence], "downlink PDRs share precedence %d", pdr.Precedence)
+		precedences[pdr.Precedence] = true
+	}
+	require.Len(t, downlink, 3)
+
+	gbr := downlink[5]
+	assert.Equal(t, "permit out 17 from 198.51.100.0/24 to assigned", gbr.PDI.SDFFilter)
+	assert.Equal(t, []uint16{flowQERID(5)}, gbr.QERIDs)
+	assert.Empty(t, downlink[1].PDI.SDFFilter)
+	assert.Less(t, gbr.Precedence, downlink[1].Precedence, "the filtered flow must match before the default flow")
+	assert.Less(t, downlink[1].Precedence, downlink[2].Precedence)
+}
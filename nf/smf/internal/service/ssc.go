@@ -0,0 +1,307 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/your-org/5g-network/nf/smf/internal/client"
+	"github.com/your-org/5g-network/nf/smf/internal/context"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrAnchorRelocationNotAllowed is returned when relocation is requested
+	// for an SSC mode 1 session, which keeps its anchor
+	ErrAnchorRelocationNotAllowed = errors.New("SSC mode 1 session keeps its anchor")
+
+	// ErrNoRelocationTarget is returned when no other UPF can anchor the
+	// session
+	ErrNoRelocationTarget = errors.New("no other UPF available")
+
+	// ErrNoFreePDUSessionID is returned when an SSC mode 3 relocation finds
+	// all PDU session IDs of the UE in use
+	ErrNoFreePDUSessionID = errors.New("no free PDU session ID")
+)
+
+// maxPDUSessionID is the highest PDU session ID (TS 24.007 11.2.3.1b)
+const maxPDUSessionID = 15
+
+// RelocateSessionRequest triggers a change of the PDU session anchor of a
+// session, e.g. when the UE moves out of the serving area of its UPF
+type RelocateSessionRequest struct {
+	SUPI         string `json:"supi"`
+	PDUSessionID uint8  `json:"pduSessionId"`
+
+	// ID of the new PDU session under SSC mode 3, zero to use the lowest
+	// free ID. SSC mode 2 keeps the session ID.
+	NewPDUSessionID uint8 `json:"newPduSessionId,omitempty"`
+}
+
+// RelocateSessionResponse represents the result of an anchor relocation
+type RelocateSessionResponse struct {
+	Result string `json:"result"`
+
+	// Session anchored on the new UPF
+	Session *CreateSessionResponse `json:"session,omitempty"`
+
+	// Session released on the old UPF
+	ReleasedPDUSessionID uint8 `json:"releasedPduSessionId,omitempty"`
+
+	Reason string `json:"reason,omitempty"`
+}
+
+// sscModeName returns the TS 29.571 name of an SSC mode
+func sscModeName(mode context.SSCMode) string {
+	return fmt.Sprintf("SSC_MODE_%d", mode)
+}
+
+// parseSSCMode parses an SSC mode name such as "SSC_MODE_2"
+func parseSSCMode(name string) (context.SSCMode, bool) {
+	for _, mode := range []context.SSCMode{context.SSCMode1, context.SSCMode2, context.SSCMode3} {
+		if strings.EqualFold(name, sscModeName(mode)) {
+			return mode, true
+		}
+	}
+	return 0, false
+}
+
+// selectSSCMode selects the SSC mode of a new session (TS 23.501 5.6.9.3).
+// The requested mode applies when the subscription allows it, otherwise the
+// subscribed default. Without subscription data the requested mode applies,
+// and SSC mode 1 when the UE requests none.
+func (s *SessionService) selectSSCMode(requested string, subscribed *client.DNNConfiguration) context.SSCMode {
+	mode, ok := parseSSCMode(requested)
+	if requested != "" && !ok {
+		s.logger.Warn("Ignoring unknown requested SSC mode", zap.String("ssc_mode", requested))
+	}
+
+	if subscribed == nil || subscribed.SSCModes == nil {
+		if !ok {
+			return context.SSCMode1
+		}
+		return mode
+	}
+
+	if ok {
+		for _, allowed := range subscribed.SSCModes.AllowedSSCModes {
+			if allowedMode, _ := parseSSCMode(allowed); allowedMode == mode {
+				return mode
+			}
+		}
+	}
+
+	defaultMode, defaultOK := parseSSCMode(subscribed.SSCModes.DefaultSSCMode)
+	if !defaultOK {
+		defaultMode = context.SSCMode1
+	}
+	if ok && mode != defaultMode {
+		s.logger.Info("Requested SSC mode not subscribed, applying default",
+			zap.String("requested", requested),
+			zap.String("ssc_mode", sscModeName(defaultMode)))
+	}
+	return defaultMode
+}
+
+// RelocateSession changes the PDU session anchor of an SSC mode 2 or 3
+// session to another UPF, giving the UE a new IP address (TS 23.502
+// 4.3.5). SSC mode 2 releases the session before re-establishing it on the
+// new UPF; SSC mode 3 establishes a new PDU session on the new UPF before
+// releasing the old one.
+func (s *SessionService) RelocateSession(req *RelocateSessionRequest) (*RelocateSessionResponse, error) {
+	s.logger.Info("Relocating PDU session anchor",
+		zap.String("supi", req.SUPI),
+		zap.Uint8("pdu_session_id", req.PDUSessionID),
+	)
+
+	session, err := s.smfContext.GetSession(req.SUPI, req.PDUSessionID)
+	if err != nil {
+		return &RelocateSessionResponse{
+			Result: "FAILURE",
+			Reason: fmt.Sprintf("session not found: %v", err),
+		}, err
+	}
+
+	var resp *RelocateSessionResponse
+	switch session.SSCMode {
+	case context.SSCMode2:
+		resp, err = s.relocateBreakBeforeMake(session)
+	case context.SSCMode3:
+		resp, err = s.relocateMakeBeforeBreak(session, req.NewPDUSessionID)
+	default:
+		err = fmt.Errorf("%w: %s", ErrAnchorRelocationNotAllowed, sscModeName(session.SSCMode))
+	}
+	if err != nil {
+		s.logger.Error("PDU session anchor relocation failed",
+			zap.String("supi", req.SUPI),
+			zap.Uint8("pdu_session_id", req.PDUSessionID),
+			zap.Error(err))
+		return &RelocateSessionResponse{
+			Result: "FAILURE",
+			Reason: err.Error(),
+		}, err
+	}
+
+	s.logger.Info("PDU session anchor relocated",
+		zap.String("supi", req.SUPI),
+		zap.String("ssc_mode", sscModeName(session.SSCMode)),
+		zap.Uint8("released_pdu_session_id", resp.ReleasedPDUSessionID),
+		zap.Uint8("pdu_session_id", resp.Session.PDUSessionID),
+		zap.String("ue_ip", resp.Session.UEIPv4Address),
+	)
+	return resp, nil
+}
+
+// relocationTarget returns the UPF to move a session's anchor to
+func (s *SessionService) relocationTarget(session *context.PDUSession) (UPFInstance, error) {
+	if s.upfSelector == nil {
+		return UPFInstance{}, ErrNoRelocationTarget
+	}
+	upf, ok := s.upfSelector.SelectOther(session.DNN, session.SNSSAI, s.anchorUPF(session))
+	if !ok {
+		return UPFInstance{}, ErrNoRelocationTarget
+	}
+	return upf, nil
+}
+
+// relocateBreakBeforeMake releases an SSC mode 2 session and establishes it
+// again with the same ID on another UPF. The new UE address is allocated
+// before the old one is released so that it differs.
+func (s *SessionService) relocateBreakBeforeMake(old *context.PDUSession) (*RelocateSessionResponse, error) {
+	target, err := s.relocationTarget(old)
+	if err != nil {
+		return nil, err
+	}
+
+	ueAddr, err := s.ueIPPool.Allocate(old.PDUSessionType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate UE IP: %w", err)
+	}
+
+	// Break: the old anchor is gone before the new one is set up
+	s.releaseRelocatedSession(old)
+
+	// The gNB tunnel of the session ID is taken over with it
+	session := s.relocatedSession(old, old.PDUSessionID, ueAddr)
+	session.SetGNBInfo(old.GNBTEIDDownlink, old.GNBN3Address)
+	subscribed := s.subscribedDNN(session)
+	if err := s.setupSession(session, subscribed, target); err != nil {
+		s.ueIPPool.Release(ueAddr.IPv4, ueAddr.IPv6Prefix)
+		return nil, err
+	}
+
+	return &RelocateSessionResponse{
+		Result:               "SUCCESS",
+		Session:              createSessionResponse(session),
+		ReleasedPDUSessionID: old.PDUSessionID,
+	}, nil
+}
+
+// relocateMakeBeforeBreak establishes a new PDU session to the same DNN on
+// another UPF, then releases the SSC mode 3 session
+func (s *SessionService) relocateMakeBeforeBreak(old *context.PDUSession, newID uint8) (*RelocateSessionResponse, error) {
+	if newID == 0 {
+		newID = s.freePDUSessionID(old.SUPI)
+		if newID == 0 {
+			return nil, ErrNoFreePDUSessionID
+		}
+	}
+	if newID == old.PDUSessionID || newID > maxPDUSessionID {
+		return nil, fmt.Errorf("invalid new PDU session ID %d", newID)
+	}
+
+	target, err := s.relocationTarget(old)
+	if err != nil {
+		return nil, err
+	}
+
+	ueAddr, err := s.ueIPPool.Allocate(old.PDUSessionType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate UE IP: %w", err)
+	}
+
+	// Make: the new session is up before the old one is released. It has no
+	// gNB tunnel yet, so the UPF buffers its downlink until the gNB has set
+	// one up and the AMF activates the user plane with it.
+	session := s.relocatedSession(old, newID, ueAddr)
+	session.SetUpCnxState(context.UpCnxStateActivating)
+	subscribed := s.subscribedDNN(session)
+	if err := s.setupSession(session, subscribed, target); err != nil {
+		s.ueIPPool.Release(ueAddr.IPv4, ueAddr.IPv6Prefix)
+		return nil, err
+	}
+	s.requestAccessTunnel(session)
+
+	s.releaseRelocatedSession(old)
+
+	return &RelocateSessionResponse{
+		Result:               "SUCCESS",
+		Session:              createSessionResponse(session),
+		ReleasedPDUSessionID: old.PDUSessionID,
+	}, nil
+}
+
+// relocatedSession creates the session replacing a relocated one, with the
+// same DNN, S-NSSAI and SSC mode
+func (s *SessionService) relocatedSession(old *context.PDUSession, id uint8, ueAddr *UEAddress) *context.PDUSession {
+	session := context.NewPDUSession(old.SUPI, id, old.DNN, old.SNSSAI)
+	session.SSCMode = old.SSCMode
+	session.SetPDUSessionType(ueAddr.PDUSessionType)
+	session.SetUEIPAddress(ueAddr.IPv4, ueAddr.IPv6Prefix)
+	return session
+}
+
+// requestAccessTunnel asks the AMF to set up the N2 resources of a new
+// session at the gNB, whose tunnel the AMF returns by activating the user
+// plane of the session
+func (s *SessionService) requestAccessTunnel(session *context.PDUSession) {
+	resp, err := s.transferN2SMInfo(session)
+	if err != nil {
+		s.logger.Error("Failed to request the gNB tunnel of the relocated session",
+			zap.String("supi", session.SUPI),
+			zap.Uint8("pdu_session_id", session.PDUSessionID),
+			zap.Error(err))
+		return
+	}
+	if resp == nil {
+		s.logger.Warn("No AMF configured, downlink of the relocated session stays buffered",
+			zap.String("supi", session.SUPI),
+			zap.Uint8("pdu_session_id", session.PDUSessionID))
+		return
+	}
+
+	s.logger.Info("gNB tunnel of the relocated session requested",
+		zap.String("supi", session.SUPI),
+		zap.Uint8("pdu_session_id", session.PDUSessionID),
+		zap.String("cause", resp.Cause))
+}
+
+// releaseRelocatedSession deletes a session from its old UPF and releases
+// its resources
+func (s *SessionService) releaseRelocatedSession(old *context.PDUSession) {
+	if _, err := s.ReleaseSession(&ReleaseSessionRequest{
+		SUPI:         old.SUPI,
+		PDUSessionID: old.PDUSessionID,
+		Cause:        "PDU_SESSION_ANCHOR_RELOCATION",
+	}); err != nil {
+		s.logger.Error("Failed to release relocated session",
+			zap.String("supi", old.SUPI),
+			zap.Uint8("pdu_session_id", old.PDUSessionID),
+			zap.Error(err))
+	}
+}
+
+// freePDUSessionID returns the lowest PDU session ID the UE does not use,
+// zero when all are in use
+func (s *SessionService) freePDUSessionID(supi string) uint8 {
+	used := make(map[uint8]bool)
+	for _, session := range s.smfContext.GetAllSessions(supi) {
+		used[session.PDUSessionID] = true
+	}
+	for id := uint8(1); id <= maxPDUSessionID; id++ {
+		if !used[id] {
+			return id
+		}
+	}
+	return 0
+}
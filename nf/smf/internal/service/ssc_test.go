@@ -0,0 +1,251 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/your-org/5g-network/nf/smf/internal/client"
+	"github.com/your-org/5g-network/nf/smf/internal/config"
+	"github.com/your-org/5g-network/nf/smf/internal/context"
+	"github.com/your-org/5g-network/nf/smf/internal/n4"
+)
+
+const testSSCSUPI = "imsi-001010000000001"
+
+// newSSCTestSessionService returns a session service anchoring new sessions
+// on upf-2, discovered through the NRF, with upf-1 as the default UPF. PFCP
+// requests are logged to the returned observer.
+func newSSCTestSessionService(t *testing.T) (*SessionService, *observer.ObservedLogs) {
+	t.Helper()
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(core)
+
+	cfg := &config.Config{}
+	cfg.SMF.UESubnet.IPv4 = "10.60.0.0/24"
+	smfContext := context.NewSMFContext(testDefaultUPF.NodeID, testDefaultUPF.N4Address)
+	pfcpClient := n4.NewPFCPClient(testDefaultUPF.NodeID, testDefaultUPF.N4Address, logger)
+	svc, err := NewSessionService(cfg, smfContext, pfcpClient, logger)
+	require.NoError(t, err)
+
+	nrf := newMockNRF(t,
+		client.UPFProfile{NFInstanceID: "upf-2", FQDN: "upf-2", NFStatus: "REGISTERED", IPv4Addresses: []string{"10.0.0.2:8805"}},
+	)
+	svc.SetUPFSelector(newTestUPFSelector(t, nrf), func(upf UPFInstance) (*n4.PFCPClient, error) {
+		return n4.NewPFCPClient(upf.NodeID, upf.N4Address, logger), nil
+	})
+	return svc, logs
+}
+
+// pfcpMessages returns the PFCP session establishment and deletion requests
+// sent, in order
+func pfcpMessages(logs *observer.ObservedLogs) []string {
+	var messages []string
+	for _, entry := range logs.All() {
+		fields := entry.ContextMap()
+		switch entry.Message {
+		case "Sending PFCP Session Establishment Request to UPF":
+			messages = append(messages, fmt.Sprintf("establish %d on %s", fields["seid"], fields["upf_node_id"]))
+		case "Sending PFCP Session Deletion Request to UPF":
+			messages = append(messages, fmt.Sprintf("delete %d", fields["seid"]))
+		}
+	}
+	return messages
+}
+
+func createSSCTestSession(t *testing.T, svc *SessionService, sscMode string) *CreateSessionResponse {
+	t.Helper()
+
+	resp, err := svc.CreateSession(&CreateSessionRequest{
+		SUPI:            testSSCSUPI,
+		PDUSessionID:    1,
+		DNN:             "internet",
+		SNSSAI:          testSNSSAI,
+		SSCMode:         sscMode,
+		GNBN3Address:    "192.168.1.10",
+		GNBTEIDDownlink: 0x100,
+	})
+	require.NoError(t, err)
+	require.Equal(t, sscMode, resp.SSCMode)
+	return resp
+}
+
+func TestSelectSSCMode(t *testing.T) {
+	svc := newTestSessionService(t, config.QoSMonitoringConfig{})
+	subscribed := func(defaultMode string, allowed ...string) *client.DNNConfiguration {
+		return &client.DNNConfiguration{SSCModes: &client.SSCModes{DefaultSSCMode: defaultMode, AllowedSSCModes: allowed}}
+	}
+
+	tests := []struct {
+		name       string
+		requested  string
+		subscribed *client.DNNConfiguration
+		want       context.SSCMode
+	}{
+		{"no request, no subscription", "", nil, context.SSCMode1},
+		{"request without subscription", "SSC_MODE_3", nil, context.SSCMode3},
+		{"unknown request", "SSC_MODE_9", nil, context.SSCMode1},
+		{"subscribed default", "", subscribed("SSC_MODE_2", "SSC_MODE_1", "SSC_MODE_2"), context.SSCMode2},
+		{"allowed request", "SSC_MODE_3", subscribed("SSC_MODE_1", "SSC_MODE_1", "SSC_MODE_3"), context.SSCMode3},
+		{"request not allowed", "SSC_MODE_3", subscribed("SSC_MODE_1", "SSC_MODE_1", "SSC_MODE_2"), context.SSCMode1},
+		{"only the default allowed", "SSC_MODE_1", subscribed("SSC_MODE_2"), context.SSCMode2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, svc.selectSSCMode(tt.requested, tt.subscribed))
+		})
+	}
+}
+
+func TestCreateSessionSubscribedSSCMode(t *testing.T) {
+	svc := newTestSessionService(t, config.QoSMonitoringConfig{})
+	svc.SetUDMClient(newUDMTestServer(t, map[string]*client.DNNConfiguration{
+		testSSCSUPI: {SSCModes: &client.SSCModes{DefaultSSCMode: "SSC_MODE_2", AllowedSSCModes: []string{"SSC_MODE_2"}}},
+	}))
+
+	resp, err := svc.CreateSession(&CreateSessionRequest{SUPI: testSSCSUPI, PDUSessionID: 1, DNN: "internet", SSCMode: "SSC_MODE_3"})
+	require.NoError(t, err)
+	assert.Equal(t, "SSC_MODE_2", resp.SSCMode)
+
+	session, err := svc.smfContext.GetSession(testSSCSUPI, 1)
+	require.NoError(t, err)
+	assert.Equal(t, context.SSCMode2, session.SSCMode)
+}
+
+func TestRelocateSessionSSCMode2(t *testing.T) {
+	svc, logs := newSSCTestSessionService(t)
+	created := createSSCTestSession(t, svc, "SSC_MODE_2")
+	seid := n4.GenerateSEID(testSSCSUPI, 1)
+
+	resp, err := svc.RelocateSession(&RelocateSessionRequest{SUPI: testSSCSUPI, PDUSessionID: 1})
+	require.NoError(t, err)
+	require.Equal(t, "SUCCESS", resp.Result)
+	assert.Equal(t, uint8(1), resp.ReleasedPDUSessionID)
+	assert.Equal(t, uint8(1), resp.Session.PDUSessionID)
+	assert.NotEqual(t, created.UEIPv4Address, resp.Session.UEIPv4Address)
+	assert.Equal(t, "127.0.0.1", resp.Session.UPFN3Address)
+
+	// Break before make: the session leaves upf-2 before upf-1 anchors it
+	assert.Equal(t, []string{
+		fmt.Sprintf("establish %d on upf-2", seid),
+		fmt.Sprintf("delete %d", seid),
+		fmt.Sprintf("establish %d on upf-1", seid),
+	}, pfcpMessages(logs))
+
+	session, err := svc.smfContext.GetSession(testSSCSUPI, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "upf-1", session.UPFNodeID)
+	assert.Equal(t, resp.Session.UEIPv4Address, session.UEIPv4Address)
+	assert.Equal(t, context.SSCMode2, session.SSCMode)
+	assert.Equal(t, uint32(0x100), session.GNBTEIDDownlink)
+
+	// Only the new address is held
+	assert.Equal(t, 1, svc.ueIPPool.AllocatedIPv4Count())
+}
+
+func TestRelocateSessionSSCMode3(t *testing.T) {
+	svc, logs := newSSCTestSessionService(t)
+	created := createSSCTestSession(t, svc, "SSC_MODE_3")
+
+	transfers := make(chan client.N1N2MessageTransferRequest, 2)
+	amf := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req client.N1N2MessageTransferRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		transfers <- req
+		json.NewEncoder(w).Encode(client.N1N2MessageTransferResponse{Cause: "N1_N2_TRANSFER_INITIATED"})
+	}))
+	defer amf.Close()
+	svc.SetAMFClient(client.NewAMFClient(amf.URL, nil, zap.NewNop()))
+	oldSEID := n4.GenerateSEID(testSSCSUPI, 1)
+	newSEID := n4.GenerateSEID(testSSCSUPI, 2)
+
+	resp, err := svc.RelocateSession(&RelocateSessionRequest{SUPI: testSSCSUPI, PDUSessionID: 1})
+	require.NoError(t, err)
+	require.Equal(t, "SUCCESS", resp.Result)
+	assert.Equal(t, uint8(1), resp.ReleasedPDUSessionID)
+	assert.Equal(t, uint8(2), resp.Session.PDUSessionID, "the lowest free ID")
+	assert.NotEqual(t, created.UEIPv4Address, resp.Session.UEIPv4Address)
+
+	// Make before break: the new session is up on upf-1 before the old one
+	// leaves upf-2
+	assert.Equal(t, []string{
+		fmt.Sprintf("establish %d on upf-2", oldSEID),
+		fmt.Sprintf("establish %d on upf-1", newSEID),
+		fmt.Sprintf("delete %d", oldSEID),
+	}, pfcpMessages(logs))
+
+	_, err = svc.smfContext.GetSession(testSSCSUPI, 1)
+	assert.Error(t, err)
+	session, err := svc.smfContext.GetSession(testSSCSUPI, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "upf-1", session.UPFNodeID)
+	assert.Equal(t, context.SSCMode3, session.SSCMode)
+	assert.Equal(t, 1, svc.ueIPPool.AllocatedIPv4Count())
+
+	// The new session does not share the gNB tunnel of the old one: its
+	// downlink is buffered until the gNB has set up a tunnel for it
+	assert.Empty(t, session.GNBN3Address)
+	assert.Zero(t, session.GNBTEIDDownlink)
+	assert.True(t, session.IsUpCnxDeactivated())
+	establishment := svc.buildPFCPEstablishmentRequest(session, session.SEID, session.UPFNodeID)
+	assert.Equal(t, n4.FAR{FARID: downlinkFARID, ApplyAction: "BUFFER"}, establishment.FARs[1])
+
+	select {
+	case req := <-transfers:
+		assert.Equal(t, uint8(2), req.PDUSessionID)
+		assert.Equal(t, "SM", req.N2InfoClass)
+	default:
+		t.Fatal("the AMF was not asked for the gNB tunnel of the new session")
+	}
+
+	_, err = svc.UpdateSession(&UpdateSessionRequest{
+		SUPI:         testSSCSUPI,
+		PDUSessionID: 2,
+		UpCnxState:   "ACTIVATED",
+		GNBN3Address: "192.168.1.10",
+		GNBTEID:      0x200,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0x200), session.GNBTEIDDownlink)
+	assert.False(t, session.IsUpCnxDeactivated())
+
+	// Relocating again moves the session back to upf-2 under the given ID
+	resp, err = svc.RelocateSession(&RelocateSessionRequest{SUPI: testSSCSUPI, PDUSessionID: 2, NewPDUSessionID: 5})
+	require.NoError(t, err)
+	assert.Equal(t, uint8(5), resp.Session.PDUSessionID)
+	session, err = svc.smfContext.GetSession(testSSCSUPI, 5)
+	require.NoError(t, err)
+	assert.Equal(t, "upf-2", session.UPFNodeID)
+}
+
+func TestRelocateSessionErrors(t *testing.T) {
+	svc, logs := newSSCTestSessionService(t)
+	createSSCTestSession(t, svc, "SSC_MODE_1")
+
+	// SSC mode 1 keeps its anchor
+	resp, err := svc.RelocateSession(&RelocateSessionRequest{SUPI: testSSCSUPI, PDUSessionID: 1})
+	assert.ErrorIs(t, err, ErrAnchorRelocationNotAllowed)
+	assert.Equal(t, "FAILURE", resp.Result)
+	assert.Len(t, pfcpMessages(logs), 1)
+
+	_, err = svc.RelocateSession(&RelocateSessionRequest{SUPI: testSSCSUPI, PDUSessionID: 9})
+	assert.Error(t, err)
+
+	// Without UPF selection there is no other UPF
+	single := newTestSessionService(t, config.QoSMonitoringConfig{})
+	_, err = single.CreateSession(&CreateSessionRequest{SUPI: testSSCSUPI, PDUSessionID: 1, DNN: "internet", SSCMode: "SSC_MODE_2"})
+	require.NoError(t, err)
+	_, err = single.RelocateSession(&RelocateSessionRequest{SUPI: testSSCSUPI, PDUSessionID: 1})
+	assert.ErrorIs(t, err, ErrNoRelocationTarget)
+	_, err = single.smfContext.GetSession(testSSCSUPI, 1)
+	assert.NoError(t, err, "the session is kept")
+}
@@ -0,0 +1,250 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/your-org/5g-network/nf/smf/internal/config"
+	"github.com/your-org/5g-network/nf/smf/internal/context"
+	"github.com/your-org/5g-network/nf/smf/internal/n4"
+	"go.uber.org/zap"
+)
+
+// Rule IDs of the ULCL session beyond the per QoS flow rules, outside the
+// ranges used for those and the indirect forwarding tunnel
+const (
+	ulclN9PDRID           uint16 = 0x2000 // Downlink from the anchor
+	ulclLocalPDRID        uint16 = 0x2001 // Downlink from the local DN
+	ulclBreakoutPDRIDBase uint16 = 0x2100 // Uplink to the local DN, one per subnet
+	ulclBreakoutFARID     uint16 = 0x2000
+)
+
+// uplinkFARID is the FAR carrying uplink traffic towards the DN
+const uplinkFARID uint16 = 1
+
+// Choose IDs of the F-TEIDs the ULCL UPF allocates: uplink PDRs share the
+// N3 F-TEID, the anchor sends downlink to the N9 F-TEID
+const (
+	n3CHID uint8 = 1
+	n9CHID uint8 = 2
+)
+
+// ulclBreakoutPrecedence makes breakout PDRs match before the per QoS flow
+// uplink PDRs
+const ulclBreakoutPrecedence uint32 = 50
+
+// ulclSEID derives the SEID of the ULCL session from the anchor's. The low
+// byte of SEIDs holds the PDU session ID, which never reaches bit 7.
+func ulclSEID(seid uint64) uint64 {
+	return seid | 0x80
+}
+
+// ulclUPF returns the uplink classifier UPF of a session
+func ulclUPF(ulcl *context.ULCL) UPFInstance {
+	return UPFInstance{NodeID: ulcl.UPFNodeID, N4Address: ulcl.UPFN4Address}
+}
+
+// localBreakout returns the local breakout configuration of a DNN, nil when
+// its sessions use the anchor alone
+func (s *SessionService) localBreakout(dnn string) *config.LocalBreakoutConfig {
+	for i := range s.config.UPF.LocalBreakout {
+		if s.config.UPF.LocalBreakout[i].DNN == dnn {
+			return &s.config.UPF.LocalBreakout[i]
+		}
+	}
+	return nil
+}
+
+// insertULCL establishes a session on the uplink classifier UPF of its DNN
+// and chains it to the anchor over N9 (TS 23.502 4.3.5.4). The anchor is
+// already established: its uplink F-TEID becomes the N9 endpoint of the
+// ULCL, and its downlink is redirected from the gNB to the ULCL.
+func (s *SessionService) insertULCL(session *context.PDUSession, breakout *config.LocalBreakoutConfig) error {
+	upf := UPFInstance{NodeID: breakout.ULCLUPF.NodeID, N4Address: breakout.ULCLUPF.N4Address}
+	pfcpClient, err := s.pfcpClientFor(upf)
+	if err != nil {
+		return fmt.Errorf("ULCL UPF unavailable: %w", err)
+	}
+
+	anchor := &n4.FTEID{TEID: session.UPFTEIDUplink, IPv4: session.UPFN3Address}
+	seid := ulclSEID(session.SEID)
+	pfcpResp, err := pfcpClient.EstablishSession(s.buildULCLEstablishmentRequest(session, breakout, seid, upf.NodeID, anchor))
+	if err == nil {
+		err = n4.ValidatePFCPResponse(pfcpResp.Cause)
+	}
+	if err != nil {
+		return fmt.Errorf("ULCL PFCP establishment failed: %w", err)
+	}
+
+	uplinkPDRID, _ := flowPDRIDs(defaultQFI)
+	n3 := createdFTEID(pfcpResp.CreatedPDRs, uplinkPDRID)
+	n9 := createdFTEID(pfcpResp.CreatedPDRs, ulclN9PDRID)
+	if n3 == nil || n9 == nil {
+		err = fmt.Errorf("ULCL UPF did not allocate the N3 and N9 F-TEIDs")
+	} else {
+		// Downlink leaves the anchor towards the ULCL instead of the gNB
+		var modResp *n4.SessionModificationResponse
+		modResp, err = s.modifyUPFSession(session, &n4.SessionModificationRequest{
+			SEID: session.SEID,
+			UpdateFARs: []n4.FAR{
+				{
+					FARID:       downlinkFARID,
+					ApplyAction: "FORWARD",
+					ForwardingParameters: &n4.ForwardingParameters{
+						DestinationInterface: "ACCESS",
+						NetworkInstance:      session.DNN,
+						OuterHeaderCreation: &n4.OuterHeaderCreation{
+							TEID: n9.TEID,
+							IPv4: n9.IPv4,
+						},
+					},
+				},
+			},
+		})
+		if err == nil {
+			err = n4.ValidatePFCPResponse(modResp.Cause)
+		}
+	}
+	if err != nil {
+		if _, delErr := pfcpClient.DeleteSession(&n4.SessionDeletionRequest{SEID: seid}); delErr != nil {
+			s.logger.Error("PFCP session deletion on ULCL UPF failed", zap.Error(delErr))
+		}
+		return fmt.Errorf("failed to chain ULCL to the anchor: %w", err)
+	}
+
+	session.SetULCL(&context.ULCL{
+		SEID:          seid,
+		UPFNodeID:     upf.NodeID,
+		UPFN4Address:  upf.N4Address,
+		N9TEID:        n9.TEID,
+		N9Address:     n9.IPv4,
+		AnchorTEID:    anchor.TEID,
+		AnchorAddress: anchor.IPv4,
+	})
+
+	// The gNB reaches the session on the ULCL
+	session.SetUPFInfo(session.UPFNodeID, session.UPFN4Address, n3.TEID)
+	session.SetUPFN3Address(n3.IPv4)
+
+	s.logger.Info("ULCL inserted for local breakout",
+		zap.String("supi", session.SUPI),
+		zap.Uint8("pdu_session_id", session.PDUSessionID),
+		zap.String("ulcl_upf", upf.NodeID),
+		zap.String("anchor_upf", session.UPFNodeID),
+		zap.Strings("subnets", breakout.Subnets),
+	)
+	return nil
+}
+
+// buildULCLEstablishmentRequest builds the PFCP Session Establishment Request
+// of the uplink classifier. It carries the QoS flow rules of the session;
+// uplink traffic to the breakout subnets leaves to the local DN, the rest is
+// tunnelled to the anchor's N9 F-TEID. Downlink from the anchor and from the
+// local DN goes to the gNB.
+func (s *SessionService) buildULCLEstablishmentRequest(
+	session *context.PDUSession,
+	breakout *config.LocalBreakoutConfig,
+	seid uint64,
+	upfNodeID string,
+	anchor *n4.FTEID,
+) *n4.SessionEstablishmentRequest {
+	req := s.buildPFCPEstablishmentRequest(session, seid, upfNodeID)
+
+	localDN := breakout.LocalDN
+	if localDN == "" {
+		localDN = session.DNN
+	}
+
+	for i := range req.FARs {
+		if req.FARs[i].FARID == uplinkFARID {
+			req.FARs[i].ForwardingParameters.OuterHeaderCreation = &n4.OuterHeaderCreation{
+				TEID: anchor.TEID,
+				IPv4: anchor.IPv4,
+			}
+		}
+	}
+	req.FARs = append(req.FARs, n4.FAR{
+		FARID:       ulclBreakoutFARID,
+		ApplyAction: "FORWARD",
+		ForwardingParameters: &n4.ForwardingParameters{
+			DestinationInterface: "CORE",
+			NetworkInstance:      localDN,
+		},
+	})
+
+	for i, subnet := range breakout.Subnets {
+		req.PDRs = append(req.PDRs, n4.PDR{
+			PDRID:      ulclBreakoutPDRIDBase + uint16(i),
+			Precedence: ulclBreakoutPrecedence,
+			PDI: n4.PDI{
+				SourceInterface: "ACCESS",
+				FTEID:           &n4.FTEID{CHID: n3CHID},
+				UEIPAddress:     session.UEIPv4Address,
+				UEIPv6Prefix:    session.UEIPv6Prefix,
+				NetworkInstance: session.DNN,
+				SDFFilter:       fmt.Sprintf("permit out ip from %s to assigned", subnet),
+			},
+			OuterHeaderRemoval: true,
+			FARID:              ulclBreakoutFARID,
+			QERIDs:             []uint16{sessionAMBRQERID},
+		})
+	}
+
+	req.PDRs = append(req.PDRs,
+		n4.PDR{
+			PDRID:      ulclN9PDRID,
+			Precedence: 100,
+			PDI: n4.PDI{
+				SourceInterface: "CORE",
+				FTEID:           &n4.FTEID{CHID: n9CHID},
+				NetworkInstance: session.DNN,
+			},
+			OuterHeaderRemoval: true,
+			FARID:              downlinkFARID,
+		},
+		n4.PDR{
+			PDRID:      ulclLocalPDRID,
+			Precedence: 100,
+			PDI: n4.PDI{
+				SourceInterface: "CORE",
+				UEIPAddress:     session.UEIPv4Address,
+				UEIPv6Prefix:    session.UEIPv6Prefix,
+				NetworkInstance: localDN,
+			},
+			FARID:  downlinkFARID,
+			QERIDs: []uint16{sessionAMBRQERID},
+		},
+	)
+	return req
+}
+
+// deleteULCLSession deletes a session from its uplink classifier UPF, if any
+func (s *SessionService) deleteULCLSession(session *context.PDUSession) {
+	ulcl := session.GetULCL()
+	if ulcl == nil {
+		return
+	}
+
+	pfcpClient, err := s.pfcpClientFor(ulclUPF(ulcl))
+	if err == nil {
+		var pfcpResp *n4.SessionDeletionResponse
+		pfcpResp, err = pfcpClient.DeleteSession(&n4.SessionDeletionRequest{SEID: ulcl.SEID})
+		if err == nil {
+			err = n4.ValidatePFCPResponse(pfcpResp.Cause)
+		}
+	}
+	if err != nil {
+		s.logger.Error("PFCP session deletion on ULCL UPF failed",
+			zap.String("ulcl_upf", ulcl.UPFNodeID),
+			zap.Error(err))
+	}
+}
+
+// createdFTEID returns the F-TEID the UPF allocated for a PDR, nil if none
+func createdFTEID(created []n4.CreatedPDR, pdrID uint16) *n4.FTEID {
+	for _, pdr := range created {
+		if pdr.PDRID == pdrID {
+			return pdr.FTEID
+		}
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/your-org/5g-network/nf/smf/internal/config"
+	"github.com/your-org/5g-network/nf/smf/internal/context"
+	"github.com/your-org/5g-network/nf/smf/internal/n4"
+)
+
+const testBreakoutSubnet = "10.200.0.0/16"
+
+// newULCLTestSessionService returns a session service inserting the ULCL
+// UPF upf-edge in sessions of the "internet" DNN, anchored on the default
+// UPF upf-1. PFCP requests are logged to the returned observer.
+func newULCLTestSessionService(t *testing.T) (*SessionService, *observer.ObservedLogs) {
+	t.Helper()
+
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(core)
+
+	cfg := &config.Config{}
+	cfg.SMF.UESubnet.IPv4 = "10.60.0.0/24"
+	cfg.UPF.LocalBreakout = []config.LocalBreakoutConfig{{
+		DNN:     "internet",
+		ULCLUPF: config.ULCLUPF{NodeID: "upf-edge", N4Address: "10.0.0.9:8805"},
+		LocalDN: "edge",
+		Subnets: []string{testBreakoutSubnet},
+	}}
+	smfContext := context.NewSMFContext(testDefaultUPF.NodeID, testDefaultUPF.N4Address)
+	pfcpClient := n4.NewPFCPClient(testDefaultUPF.NodeID, testDefaultUPF.N4Address, logger)
+	svc, err := NewSessionService(cfg, smfContext, pfcpClient, logger)
+	require.NoError(t, err)
+
+	svc.SetPFCPClientFactory(func(upf UPFInstance) (*n4.PFCPClient, error) {
+		return n4.NewPFCPClient(upf.NodeID, upf.N4Address, logger), nil
+	})
+	return svc, logs
+}
+
+// pfcpModifications returns the SEIDs of the PFCP session modification
+// requests sent, in order
+func pfcpModifications(logs *observer.ObservedLogs) []uint64 {
+	var seids []uint64
+	for _, entry := range logs.FilterMessage("Sending PFCP Session Modification Request to UPF").All() {
+		seids = append(seids, entry.ContextMap()["seid"].(uint64))
+	}
+	return seids
+}
+
+func createULCLTestSession(t *testing.T, svc *SessionService, dnn string) *CreateSessionResponse {
+	t.Helper()
+
+	resp, err := svc.CreateSession(&CreateSessionRequest{
+		SUPI:            testSSCSUPI,
+		PDUSessionID:    1,
+		DNN:             dnn,
+		SNSSAI:          testSNSSAI,
+		GNBN3Address:    "192.168.1.10",
+		GNBTEIDDownlink: 0x100,
+	})
+	require.NoError(t, err)
+	return resp
+}
+
+func TestCreateSessionInsertsULCL(t *testing.T) {
+	svc, logs := newULCLTestSessionService(t)
+	resp := createULCLTestSession(t, svc, "internet")
+	seid := n4.GenerateSEID(testSSCSUPI, 1)
+
+	// Anchor first, then the ULCL, then the anchor downlink is chained to it
+	assert.Equal(t, []string{
+		fmt.Sprintf("establish %d on upf-1", seid),
+		fmt.Sprintf("establish %d on upf-edge", ulclSEID(seid)),
+	}, pfcpMessages(logs))
+	assert.Equal(t, []uint64{seid}, pfcpModifications(logs))
+
+	session, err := svc.smfContext.GetSession(testSSCSUPI, 1)
+	require.NoError(t, err)
+	ulcl := session.GetULCL()
+	require.NotNil(t, ulcl)
+	assert.Equal(t, ulclSEID(seid), ulcl.SEID)
+	assert.Equal(t, "upf-edge", ulcl.UPFNodeID)
+	assert.Equal(t, "127.0.0.1", ulcl.AnchorAddress)
+	assert.Equal(t, "10.0.0.9", ulcl.N9Address)
+	assert.NotZero(t, ulcl.N9TEID)
+	assert.Equal(t, "upf-1", session.UPFNodeID)
+
+	// The gNB sends uplink to the ULCL
+	assert.Equal(t, "10.0.0.9", resp.UPFN3Address)
+	assert.Equal(t, session.UPFTEIDUplink, resp.UPFTEIDUplink)
+
+	// Reports of the ULCL UPF resolve to the session
+	bySEID, err := svc.smfContext.GetSessionBySEID(ulcl.SEID)
+	require.NoError(t, err)
+	assert.Same(t, session, bySEID)
+
+	// Release deletes both PFCP sessions
+	_, err = svc.ReleaseSession(&ReleaseSessionRequest{SUPI: testSSCSUPI, PDUSessionID: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		fmt.Sprintf("establish %d on upf-1", seid),
+		fmt.Sprintf("establish %d on upf-edge", ulclSEID(seid)),
+		fmt.Sprintf("delete %d", ulclSEID(seid)),
+		fmt.Sprintf("delete %d", seid),
+	}, pfcpMessages(logs))
+}
+
+func TestCreateSessionWithoutLocalBreakout(t *testing.T) {
+	svc, logs := newULCLTestSessionService(t)
+	resp := createULCLTestSession(t, svc, "ims")
+
+	assert.Equal(t, []string{
+		fmt.Sprintf("establish %d on upf-1", n4.GenerateSEID(testSSCSUPI, 1)),
+	}, pfcpMessages(logs))
+	assert.Empty(t, pfcpModifications(logs))
+	assert.Equal(t, "127.0.0.1", resp.UPFN3Address)
+
+	session, err := svc.smfContext.GetSession(testSSCSUPI, 1)
+	require.NoError(t, err)
+	assert.Nil(t, session.GetULCL())
+}
+
+func TestCreateSessionULCLUnavailable(t *testing.T) {
+	svc, logs := newULCLTestSessionService(t)
+	svc.SetPFCPClientFactory(nil)
+
+	_, err := svc.CreateSession(&CreateSessionRequest{SUPI: testSSCSUPI, PDUSessionID: 1, DNN: "internet"})
+	require.Error(t, err)
+
+	seid := n4.GenerateSEID(testSSCSUPI, 1)
+	assert.Equal(t, []string{
+		fmt.Sprintf("establish %d on upf-1", seid),
+		fmt.Sprintf("delete %d", seid),
+	}, pfcpMessages(logs), "the anchor session is removed")
+	assert.Zero(t, svc.ueIPPool.AllocatedIPv4Count())
+}
+
+func TestBuildULCLEstablishmentRequest(t *testing.T) {
+	svc, _ := newULCLTestSessionService(t)
+	session := context.NewPDUSession(testSSCSUPI, 1, "internet", testSNSSAI)
+	session.SetGNBInfo(0x100, "192.168.1.10")
+	session.SetUEIPAddress("10.60.0.1", "")
+	session.AddQoSFlow(&context.QoSFlow{QFI: defaultQFI, FiveQI: 9})
+
+	anchor := &n4.FTEID{TEID: 0x42, IPv4: "127.0.0.1"}
+	req := svc.buildULCLEstablishmentRequest(session, svc.localBreakout("internet"), 0x81, "upf-edge", anchor)
+
+	pdrs := make(map[uint16]n4.PDR)
+	for _, pdr := range req.PDRs {
+		pdrs[pdr.PDRID] = pdr
+	}
+	fars := make(map[uint16]n4.FAR)
+	for _, far := range req.FARs {
+		fars[far.FARID] = far
+	}
+
+	// Uplink to the breakout subnet leaves to the local DN, ahead of the
+	// QoS flow rule
+	uplinkPDRID, _ := flowPDRIDs(defaultQFI)
+	breakout := pdrs[ulclBreakoutPDRIDBase]
+	assert.Equal(t, "ACCESS", breakout.PDI.SourceInterface)
+	assert.Equal(t, "permit out ip from "+testBreakoutSubnet+" to assigned", breakout.PDI.SDFFilter)
+	assert.Equal(t, n3CHID, breakout.PDI.FTEID.CHID)
+	assert.Less(t, breakout.Precedence, pdrs[uplinkPDRID].Precedence)
+	breakoutFAR := fars[breakout.FARID]
+	assert.Equal(t, "CORE", breakoutFAR.ForwardingParameters.DestinationInterface)
+	assert.Equal(t, "edge", breakoutFAR.ForwardingParameters.NetworkInstance)
+	assert.Nil(t, breakoutFAR.ForwardingParameters.OuterHeaderCreation)
+
+	// Other uplink is tunnelled to the anchor over N9
+	assert.Equal(t, n3CHID, pdrs[uplinkPDRID].PDI.FTEID.CHID)
+	uplinkFAR := fars[pdrs[uplinkPDRID].FARID]
+	require.NotNil(t, uplinkFAR.ForwardingParameters.OuterHeaderCreation)
+	assert.Equal(t, uint32(0x42), uplinkFAR.ForwardingParameters.OuterHeaderCreation.TEID)
+	assert.Equal(t, "127.0.0.1", uplinkFAR.ForwardingParameters.OuterHeaderCreation.IPv4)
+
+	// Downlink from the anchor and the local DN goes to the gNB
+	n9 := pdrs[ulclN9PDRID]
+	assert.Equal(t, "CORE", n9.PDI.SourceInterface)
+	assert.Equal(t, n9CHID, n9.PDI.FTEID.CHID)
+	assert.True(t, n9.OuterHeaderRemoval)
+	assert.Equal(t, downlinkFARID, n9.FARID)
+	assert.Equal(t, "edge", pdrs[ulclLocalPDRID].PDI.NetworkInstance)
+	assert.Equal(t, downlinkFARID, pdrs[ulclLocalPDRID].FARID)
+	assert.Equal(t, uint32(0x100), fars[downlinkFARID].ForwardingParameters.OuterHeaderCreation.TEID)
+}
+
+func TestULCLSessionModifications(t *testing.T) {
+	svc, logs := newULCLTestSessionService(t)
+	createULCLTestSession(t, svc, "internet")
+	seid := n4.GenerateSEID(testSSCSUPI, 1)
+	session, err := svc.smfContext.GetSession(testSSCSUPI, 1)
+	require.NoError(t, err)
+
+	// New QoS flows are classified by both UPFs
+	_, err = svc.UpdateSession(&UpdateSessionRequest{
+		SUPI:          testSSCSUPI,
+		PDUSessionID:  1,
+		QoSFlowsToAdd: []QoSFlowInfo{{QFI: 2, FiveQI: 7}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{seid, seid, ulclSEID(seid)}, pfcpModifications(logs))
+
+	req := svc.buildPFCPModificationRequest(session, []*context.QoSFlow{{QFI: 3, FiveQI: 8}}, nil)
+	assert.Equal(t, session.GetULCL().AnchorTEID, req.CreatePDRs[0].PDI.FTEID.TEID, "the anchor matches the N9 F-TEID")
+
+	// The gNB tunnel terminates on the ULCL
+	_, err = svc.UpdateSession(&UpdateSessionRequest{
+		SUPI:         testSSCSUPI,
+		PDUSessionID: 1,
+		UpCnxState:   string(context.UpCnxStateDeactivated),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{seid, seid, ulclSEID(seid), ulclSEID(seid)}, pfcpModifications(logs))
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/your-org/5g-network/nf/smf/internal/context"
+	"github.com/your-org/5g-network/nf/smf/internal/n4"
+	"go.uber.org/zap"
+)
+
+// PFCPClientFactory opens a PFCP client towards a UPF other than the
+// default one
+type PFCPClientFactory func(upf UPFInstance) (*n4.PFCPClient, error)
+
+// SetUPFSelector enables UPF selection for new sessions. newClient opens the
+// N4 connection to selected UPFs; the default UPF keeps the service's PFCP
+// client.
+func (s *SessionService) SetUPFSelector(selector *UPFSelector, newClient PFCPClientFactory) {
+	s.upfClientsMu.Lock()
+	defer s.upfClientsMu.Unlock()
+
+	s.upfSelector = selector
+	s.newPFCPClient = newClient
+}
+
+// SetPFCPClientFactory sets how N4 connections to UPFs other than the
+// default one are opened, for ULCL UPFs when UPF selection is disabled
+func (s *SessionService) SetPFCPClientFactory(newClient PFCPClientFactory) {
+	s.upfClientsMu.Lock()
+	defer s.upfClientsMu.Unlock()
+
+	s.newPFCPClient = newClient
+}
+
+// Close releases the PFCP associations with selected UPFs and closes their
+// clients
+func (s *SessionService) Close() {
+	s.upfClientsMu.Lock()
+	defer s.upfClientsMu.Unlock()
+
+	for address, c := range s.upfClients {
+		if err := c.ReleaseAssociation(); err != nil {
+			s.logger.Warn("Failed to release PFCP association", zap.String("n4_address", address), zap.Error(err))
+		}
+		if err := c.Close(); err != nil {
+			s.logger.Warn("Failed to close PFCP client", zap.String("n4_address", address), zap.Error(err))
+		}
+	}
+	s.upfClients = make(map[string]*n4.PFCPClient)
+}
+
+// selectUPF returns the UPF to anchor a new session on
+func (s *SessionService) selectUPF(session *context.PDUSession) UPFInstance {
+	if s.upfSelector != nil {
+		return s.upfSelector.Select(session.DNN, session.SNSSAI)
+	}
+	return s.defaultUPF()
+}
+
+// defaultUPF returns the statically configured UPF
+func (s *SessionService) defaultUPF() UPFInstance {
+	nodeID, n4Address := s.smfContext.GetUPFInfo()
+	return UPFInstance{NodeID: nodeID, N4Address: n4Address}
+}
+
+// anchorUPF returns the UPF a session is anchored on. Sessions stored
+// without UPF information belong to the default UPF.
+func (s *SessionService) anchorUPF(session *context.PDUSession) UPFInstance {
+	if session.UPFN4Address == "" {
+		return s.defaultUPF()
+	}
+	return UPFInstance{NodeID: session.UPFNodeID, N4Address: session.UPFN4Address}
+}
+
+// pfcpClientFor returns the PFCP client of a UPF, opening it on first use
+func (s *SessionService) pfcpClientFor(upf UPFInstance) (*n4.PFCPClient, error) {
+	if upf.N4Address == s.defaultUPF().N4Address {
+		return s.pfcpClient, nil
+	}
+
+	s.upfClientsMu.Lock()
+	defer s.upfClientsMu.Unlock()
+
+	if c, ok := s.upfClients[upf.N4Address]; ok {
+		return c, nil
+	}
+	if s.newPFCPClient == nil {
+		return nil, fmt.Errorf("no PFCP client for UPF %s at %s", upf.NodeID, upf.N4Address)
+	}
+
+	c, err := s.newPFCPClient(upf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PFCP client for UPF %s: %w", upf.NodeID, err)
+	}
+	c.SetSessionReportHandler(s.HandleSessionReport)
+	s.upfClients[upf.N4Address] = c
+	return c, nil
+}
+
+// modifyUPFSession sends a PFCP Session Modification Request to the UPF
+// anchoring the session
+func (s *SessionService) modifyUPFSession(session *context.PDUSession, req *n4.SessionModificationRequest) (*n4.SessionModificationResponse, error) {
+	pfcpClient, err := s.pfcpClientFor(s.anchorUPF(session))
+	if err != nil {
+		return nil, err
+	}
+	return pfcpClient.ModifySession(req)
+}
+
+// modifyN3Session sends a PFCP Session Modification Request for the rules
+// facing the gNB to the UPF terminating N3: the ULCL UPF when one is inserted,
+// the anchor otherwise
+func (s *SessionService) modifyN3Session(session *context.PDUSession, req *n4.SessionModificationRequest) (*n4.SessionModificationResponse, error) {
+	ulcl := session.GetULCL()
+	if ulcl == nil {
+		return s.modifyUPFSession(session, req)
+	}
+
+	pfcpClient, err := s.pfcpClientFor(ulclUPF(ulcl))
+	if err != nil {
+		return nil, err
+	}
+	req.SEID = ulcl.SEID
+	return pfcpClient.ModifySession(req)
+}
+
+// deleteUPFSession sends a PFCP Session Deletion Request to the UPF
+// anchoring the session
+func (s *SessionService) deleteUPFSession(session *context.PDUSession) (*n4.SessionDeletionResponse, error) {
+	pfcpClient, err := s.pfcpClientFor(s.anchorUPF(session))
+	if err != nil {
+		return nil, err
+	}
+	return pfcpClient.DeleteSession(&n4.SessionDeletionRequest{SEID: session.SEID})
+}
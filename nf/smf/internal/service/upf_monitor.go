@@ -0,0 +1,148 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/your-org/5g-network/common/metrics"
+	"github.com/your-org/5g-network/nf/smf/internal/context"
+	"github.com/your-org/5g-network/nf/smf/internal/n4"
+	"go.uber.org/zap"
+)
+
+// CheckUPFs sends a PFCP heartbeat to every UPF the SMF holds an N4 client
+// for. A UPF whose association fails is declared down: its sessions are
+// flagged and the UPF selector routes new sessions elsewhere until the UPF
+// answers again.
+func (s *SessionService) CheckUPFs() {
+	for _, c := range s.pfcpClients() {
+		s.checkUPF(c)
+	}
+}
+
+// pfcpClients returns the default UPF client and the clients of selected
+// UPFs
+func (s *SessionService) pfcpClients() []*n4.PFCPClient {
+	s.upfClientsMu.Lock()
+	defer s.upfClientsMu.Unlock()
+
+	clients := make([]*n4.PFCPClient, 0, len(s.upfClients)+1)
+	clients = append(clients, s.pfcpClient)
+	for _, c := range s.upfClients {
+		clients = append(clients, c)
+	}
+	return clients
+}
+
+// checkUPF sends one heartbeat and handles association failure and recovery
+func (s *SessionService) checkUPF(c *n4.PFCPClient) {
+	nodeID, n4Address := c.GetUPFInfo()
+	upf := UPFInstance{NodeID: nodeID, N4Address: n4Address}
+
+	err := c.SendHeartbeat()
+	switch {
+	case errors.Is(err, n4.ErrAssociationFailed):
+		s.handleUPFDown(upf)
+	case errors.Is(err, n4.ErrUPFRestarted):
+		// The UPF lost its sessions along with the association; they are
+		// rebuilt once the association is set up again
+		s.setUPFSessionsUnavailable(upf, true)
+		s.reassociateUPF(c, upf)
+	case err != nil:
+		s.logger.Warn("PFCP heartbeat failed",
+			zap.String("upf_node_id", upf.NodeID),
+			zap.Error(err),
+		)
+	case c.AssociationState() == n4.AssociationFailed:
+		// The UPF answers again
+		s.reassociateUPF(c, upf)
+	}
+}
+
+// handleUPFDown flags the sessions anchored on a UPF whose association failed
+// and excludes it from selection
+func (s *SessionService) handleUPFDown(upf UPFInstance) {
+	flagged := s.setUPFSessionsUnavailable(upf, true)
+	if s.upfSelector != nil {
+		s.upfSelector.MarkDown(upf)
+	}
+
+	s.logger.Error("UPF declared down",
+		zap.String("upf_node_id", upf.NodeID),
+		zap.String("n4_address", upf.N4Address),
+		zap.Int("sessions_affected", flagged),
+	)
+}
+
+// reassociateUPF sets up a new association with a UPF that answers again. If
+// the UPF did not restart meanwhile, it still holds its sessions and they are
+// no longer flagged; otherwise they are rebuilt.
+func (s *SessionService) reassociateUPF(c *n4.PFCPClient, upf UPFInstance) {
+	previous := c.UPFRecoveryTime()
+	if err := c.AssociatePFCPSession(); err != nil {
+		s.logger.Warn("Failed to re-establish PFCP association",
+			zap.String("upf_node_id", upf.NodeID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	if c.UPFRecoveryTime().Equal(previous) {
+		s.setUPFSessionsUnavailable(upf, false)
+	} else {
+		metrics.RecordUPFRestart(upf.NodeID)
+		s.rebuildUPFSessions(upf)
+	}
+	if s.upfSelector != nil {
+		s.upfSelector.MarkUp(upf)
+	}
+	s.logger.Info("UPF available again", zap.String("upf_node_id", upf.NodeID))
+}
+
+// rebuildUPFSessions re-establishes the sessions a restarted UPF lost. Active
+// sessions get their PFCP session back; sessions caught mid-procedure, ULCL
+// sessions and those the UPF refuses are released.
+func (s *SessionService) rebuildUPFSessions(upf UPFInstance) {
+	resynced, released := 0, 0
+	for _, session := range s.smfContext.ListSessions() {
+		if s.anchorUPF(session).N4Address != upf.N4Address {
+			continue
+		}
+
+		if session.GetState() != context.PDUSessionStateActive {
+			s.releaseUnrecoverableSession(session, false, "procedure interrupted by UPF restart")
+			released++
+			continue
+		}
+		// Forwarding tunnels were lost with the UPF sessions
+		s.releaseIndirectForwarding(session, "UPF restart")
+
+		if !s.reestablishSession(session) {
+			released++
+			continue
+		}
+		session.SetUPFUnavailable(false)
+		s.persistSession(session)
+		resynced++
+	}
+
+	s.logger.Warn("UPF restart handled",
+		zap.String("upf_node_id", upf.NodeID),
+		zap.Int("resynced", resynced),
+		zap.Int("released", released),
+	)
+}
+
+// setUPFSessionsUnavailable flags or unflags the sessions anchored on a UPF
+// and returns how many changed
+func (s *SessionService) setUPFSessionsUnavailable(upf UPFInstance, unavailable bool) int {
+	changed := 0
+	for _, session := range s.smfContext.ListSessions() {
+		if s.anchorUPF(session).N4Address != upf.N4Address || session.IsUPFUnavailable() == unavailable {
+			continue
+		}
+		session.SetUPFUnavailable(unavailable)
+		s.persistSession(session)
+		changed++
+	}
+	return changed
+}
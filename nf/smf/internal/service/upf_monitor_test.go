@@ -0,0 +1,135 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/common/metrics"
+	"github.com/your-org/5g-network/nf/smf/internal/client"
+	"github.com/your-org/5g-network/nf/smf/internal/config"
+	"github.com/your-org/5g-network/nf/smf/internal/context"
+	"github.com/your-org/5g-network/nf/smf/internal/n4"
+	"github.com/your-org/5g-network/nf/upf/upftest"
+)
+
+// newFailoverTestService returns a session service selecting upf-2, an
+// in-process UPF reached over PFCP, ahead of upf-3, a simulated UPF
+func newFailoverTestService(t *testing.T) (*SessionService, *upftest.UPF) {
+	t.Helper()
+
+	logger, _ := zap.NewDevelopment()
+	upf, err := upftest.Start("upf-2", "192.168.1.1", logger)
+	require.NoError(t, err)
+	t.Cleanup(upf.Close)
+
+	nrf := newMockNRF(t,
+		client.UPFProfile{NFInstanceID: "upf-2", FQDN: "upf-2", NFStatus: "REGISTERED", IPv4Addresses: []string{upf.Addr}, Priority: 1},
+		client.UPFProfile{NFInstanceID: "upf-3", FQDN: "upf-3", NFStatus: "REGISTERED", IPv4Addresses: []string{"10.0.0.3:8805"}, Priority: 5},
+	)
+	svc := newTestSessionService(t, config.QoSMonitoringConfig{})
+	svc.SetUPFSelector(newTestUPFSelector(t, nrf), func(selected UPFInstance) (*n4.PFCPClient, error) {
+		if selected.N4Address != upf.Addr {
+			return n4.NewPFCPClient(selected.NodeID, selected.N4Address, logger), nil
+		}
+		c, err := n4.DialPFCPClient("127.0.0.1", selected.NodeID, selected.N4Address, logger)
+		if err != nil {
+			return nil, err
+		}
+		c.SetRequestTimeout(20*time.Millisecond, 0)
+		c.SetHeartbeatFailureThreshold(2)
+		return c, c.AssociatePFCPSession()
+	})
+	t.Cleanup(svc.Close)
+	return svc, upf
+}
+
+// createFailoverTestSession creates PDU session 1 of supi
+func createFailoverTestSession(t *testing.T, svc *SessionService, supi string) *context.PDUSession {
+	t.Helper()
+
+	resp, err := svc.CreateSession(&CreateSessionRequest{
+		SUPI:            supi,
+		PDUSessionID:    1,
+		DNN:             "internet",
+		SNSSAI:          testSNSSAI,
+		GNBN3Address:    "192.168.1.10",
+		GNBTEIDDownlink: 0x100,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "SUCCESS", resp.Result)
+
+	session, err := svc.smfContext.GetSession(supi, 1)
+	require.NoError(t, err)
+	return session
+}
+
+func TestCheckUPFsFailsOverToAlternateUPF(t *testing.T) {
+	svc, upf := newFailoverTestService(t)
+
+	onUPF2 := createFailoverTestSession(t, svc, "imsi-001010000000001")
+	require.Equal(t, "upf-2", onUPF2.UPFNodeID)
+
+	// The UPF answers heartbeats while it is up
+	svc.CheckUPFs()
+	assert.False(t, onUPF2.IsUPFUnavailable())
+
+	// The association fails after two unanswered heartbeats
+	upf.Close()
+	svc.CheckUPFs()
+	assert.False(t, onUPF2.IsUPFUnavailable(), "a single timeout is tolerated")
+	svc.CheckUPFs()
+	assert.True(t, onUPF2.IsUPFUnavailable())
+
+	// New sessions go to the alternate UPF
+	onUPF3 := createFailoverTestSession(t, svc, "imsi-001010000000002")
+	assert.Equal(t, "upf-3", onUPF3.UPFNodeID)
+	assert.False(t, onUPF3.IsUPFUnavailable())
+}
+
+func TestCheckUPFsRebuildsSessionsAfterUPFRestart(t *testing.T) {
+	svc, upf := newFailoverTestService(t)
+	restarts := testutil.ToFloat64(metrics.UPFRestarts.WithLabelValues("upf-2"))
+
+	active := createFailoverTestSession(t, svc, "imsi-001010000000001")
+	modifying := createFailoverTestSession(t, svc, "imsi-001010000000002")
+	modifying.UpdateState(context.PDUSessionStateModifying)
+	// The ULCL UPF session is not known to the UPF, deleting it only fails
+	withULCL := createFailoverTestSession(t, svc, "imsi-001010000000003")
+	withULCL.SetULCL(&context.ULCL{SEID: 0xdead, UPFNodeID: "upf-2", UPFN4Address: upf.Addr})
+	require.Len(t, upf.Context.GetAllSessions(), 3)
+
+	svc.CheckUPFs()
+	assert.Equal(t, restarts, testutil.ToFloat64(metrics.UPFRestarts.WithLabelValues("upf-2")))
+
+	// The UPF comes back with a new Recovery Time Stamp and no sessions
+	require.NoError(t, upf.Restart())
+	require.Empty(t, upf.Context.GetAllSessions())
+
+	svc.CheckUPFs()
+	assert.Equal(t, restarts+1, testutil.ToFloat64(metrics.UPFRestarts.WithLabelValues("upf-2")))
+
+	// The active session is re-established under its SEID
+	_, ok := upf.Session(active.SEID)
+	assert.True(t, ok)
+	assert.False(t, active.IsUPFUnavailable())
+	assert.Equal(t, context.PDUSessionStateActive, active.GetState())
+
+	// The session caught mid-procedure is released
+	_, ok = upf.Session(modifying.SEID)
+	assert.False(t, ok)
+	_, err := svc.smfContext.GetSession("imsi-001010000000002", 1)
+	assert.Error(t, err)
+
+	// As after an SMF restart, the ULCL session is released rather than
+	// re-established without its uplink classifier
+	_, ok = upf.Session(withULCL.SEID)
+	assert.False(t, ok)
+	_, err = svc.smfContext.GetSession("imsi-001010000000003", 1)
+	assert.Error(t, err)
+	assert.Len(t, upf.Context.GetAllSessions(), 1)
+}
@@ -0,0 +1,256 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/your-org/5g-network/nf/smf/internal/client"
+	"github.com/your-org/5g-network/nf/smf/internal/context"
+	"go.uber.org/zap"
+)
+
+// defaultUPFCacheTTL is used when no discovery cache TTL is configured
+const defaultUPFCacheTTL = 30 * time.Second
+
+// failedUPFCacheTTL is how long a failed discovery is remembered, so that
+// sessions fall back to the default UPF without querying the NRF each time
+const failedUPFCacheTTL = 5 * time.Second
+
+// pfcpPort is the PFCP port assumed for N4 addresses registered without one
+const pfcpPort = "8805"
+
+// UPFDiscoverer looks up the UPFs serving a DNN on an S-NSSAI, normally the
+// NRF client
+type UPFDiscoverer interface {
+	DiscoverUPFs(dnn string, snssai client.SNSSAI) ([]client.UPFProfile, error)
+}
+
+// UPFInstance identifies a UPF by its PFCP Node ID and N4 address
+type UPFInstance struct {
+	NodeID    string
+	N4Address string
+}
+
+// upfCacheEntry holds the UPF chosen for a DNN and S-NSSAI
+type upfCacheEntry struct {
+	upf     UPFInstance
+	expires time.Time
+}
+
+// UPFSelector selects the UPF anchoring a PDU session through NRF discovery
+// (TS 23.501 6.3.3). Discovery results are cached per DNN and S-NSSAI; the
+// default UPF is used when discovery fails or finds no usable UPF. UPFs
+// marked down are skipped until marked up again.
+type UPFSelector struct {
+	discoverer UPFDiscoverer
+	fallback   UPFInstance
+	ttl        time.Duration
+	logger     *zap.Logger
+
+	mu    sync.Mutex
+	cache map[string]upfCacheEntry
+	down  map[string]bool // N4 addresses of UPFs declared down
+	now   func() time.Time
+}
+
+// NewUPFSelector creates a UPF selector falling back to the given UPF. A
+// zero ttl selects the default of 30s.
+func NewUPFSelector(discoverer UPFDiscoverer, fallback UPFInstance, ttl time.Duration, logger *zap.Logger) *UPFSelector {
+	if ttl <= 0 {
+		ttl = defaultUPFCacheTTL
+	}
+	return &UPFSelector{
+		discoverer: discoverer,
+		fallback:   fallback,
+		ttl:        ttl,
+		logger:     logger,
+		cache:      make(map[string]upfCacheEntry),
+		down:       make(map[string]bool),
+		now:        time.Now,
+	}
+}
+
+// Select returns the UPF for a session on dnn and snssai. The NRF is
+// queried without holding the selector's lock.
+func (s *UPFSelector) Select(dnn string, snssai context.SNSSAI) UPFInstance {
+	key := fmt.Sprintf("%s/%d/%s", dnn, snssai.SST, snssai.SD)
+
+	s.mu.Lock()
+	if entry, ok := s.cache[key]; ok && s.now().Before(entry.expires) && !s.down[entry.upf.N4Address] {
+		s.mu.Unlock()
+		return entry.upf
+	}
+	s.mu.Unlock()
+
+	profiles, err := s.discoverer.DiscoverUPFs(dnn, client.SNSSAI{SST: snssai.SST, SD: snssai.SD})
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		s.logger.Warn("UPF discovery failed, using default UPF",
+			zap.String("dnn", dnn),
+			zap.String("default_upf", s.fallback.NodeID),
+			zap.Error(err),
+		)
+		s.cacheFailure(key)
+		return s.fallback
+	}
+
+	upf, ok := selectUPF(profiles, s.down)
+	if !ok {
+		s.cacheFailure(key)
+		if s.down[s.fallback.N4Address] {
+			s.logger.Error("No UPF available, default UPF is down",
+				zap.String("dnn", dnn),
+				zap.String("default_upf", s.fallback.NodeID),
+			)
+			return s.fallback
+		}
+		s.logger.Warn("No UPF discovered, using default UPF",
+			zap.String("dnn", dnn),
+			zap.Int("sst", snssai.SST),
+			zap.String("default_upf", s.fallback.NodeID),
+		)
+		return s.fallback
+	}
+
+	s.cache[key] = upfCacheEntry{upf: upf, expires: s.now().Add(s.ttl)}
+	s.logger.Info("UPF selected",
+		zap.String("dnn", dnn),
+		zap.Int("sst", snssai.SST),
+		zap.String("upf_node_id", upf.NodeID),
+		zap.String("n4_address", upf.N4Address),
+	)
+	return upf
+}
+
+// cacheFailure remembers for a short while that discovery found no UPF
+// for key, so the default UPF is used without querying the NRF again
+func (s *UPFSelector) cacheFailure(key string) {
+	expires := s.now().Add(min(failedUPFCacheTTL, s.ttl))
+	s.cache[key] = upfCacheEntry{upf: s.fallback, expires: expires}
+}
+
+// SelectOther returns a UPF other than current for a session on dnn and
+// snssai, used to relocate the session anchor. Discovery bypasses the
+// cache; the default UPF is the last resort. ok is false when no other
+// UPF is available.
+func (s *UPFSelector) SelectOther(dnn string, snssai context.SNSSAI, current UPFInstance) (upf UPFInstance, ok bool) {
+	s.mu.Lock()
+	excluded := map[string]bool{current.N4Address: true}
+	for address := range s.down {
+		excluded[address] = true
+	}
+	s.mu.Unlock()
+
+	profiles, err := s.discoverer.DiscoverUPFs(dnn, client.SNSSAI{SST: snssai.SST, SD: snssai.SD})
+	if err != nil {
+		s.logger.Warn("UPF discovery failed", zap.String("dnn", dnn), zap.Error(err))
+	} else if upf, ok := selectUPF(profiles, excluded); ok {
+		return upf, true
+	}
+
+	if excluded[s.fallback.N4Address] {
+		return UPFInstance{}, false
+	}
+	return s.fallback, true
+}
+
+// MarkDown excludes a UPF from selection, typically after its PFCP
+// association failed
+func (s *UPFSelector) MarkDown(upf UPFInstance) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.down[upf.N4Address] = true
+	s.logger.Warn("UPF excluded from selection",
+		zap.String("upf_node_id", upf.NodeID),
+		zap.String("n4_address", upf.N4Address),
+	)
+}
+
+// MarkUp makes a UPF marked down selectable again
+func (s *UPFSelector) MarkUp(upf UPFInstance) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.down[upf.N4Address] {
+		delete(s.down, upf.N4Address)
+		s.logger.Info("UPF selectable again",
+			zap.String("upf_node_id", upf.NodeID),
+			zap.String("n4_address", upf.N4Address),
+		)
+	}
+}
+
+// selectUPF picks the registered UPF with the lowest priority value, then
+// the lowest load, then the highest capacity, skipping UPFs that are down
+// or registered without a usable N4 address
+func selectUPF(profiles []client.UPFProfile, down map[string]bool) (UPFInstance, bool) {
+	type candidate struct {
+		profile client.UPFProfile
+		upf     UPFInstance
+	}
+	candidates := make([]candidate, 0, len(profiles))
+	for _, p := range profiles {
+		if p.NFStatus != "" && p.NFStatus != "REGISTERED" {
+			continue
+		}
+		upf, ok := upfInstance(p)
+		if !ok || down[upf.N4Address] {
+			continue
+		}
+		candidates = append(candidates, candidate{profile: p, upf: upf})
+	}
+	if len(candidates) == 0 {
+		return UPFInstance{}, false
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i].profile, candidates[j].profile
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
+		}
+		if a.Load != b.Load {
+			return a.Load < b.Load
+		}
+		return a.Capacity > b.Capacity
+	})
+	return candidates[0].upf, true
+}
+
+// upfInstance returns the PFCP Node ID and N4 address of a UPF profile. The
+// N4 address is the first registered address that is not unspecified. The
+// Node ID is the UPF's FQDN, or the N4 address's IP when it registered
+// none (TS 29.244 8.2.38).
+func upfInstance(p client.UPFProfile) (UPFInstance, bool) {
+	for _, address := range p.IPv4Addresses {
+		address = n4Address(address)
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			continue
+		}
+		ip := net.ParseIP(host)
+		if ip == nil || ip.IsUnspecified() {
+			continue
+		}
+		nodeID := p.FQDN
+		if nodeID == "" {
+			nodeID = ip.String()
+		}
+		return UPFInstance{NodeID: nodeID, N4Address: address}, true
+	}
+	return UPFInstance{}, false
+}
+
+// n4Address adds the PFCP port to an address registered without one
+func n4Address(address string) string {
+	if _, _, err := net.SplitHostPort(address); err == nil {
+		return address
+	}
+	return net.JoinHostPort(address, pfcpPort)
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/common/nrfpool"
+	"github.com/your-org/5g-network/nf/smf/internal/client"
+	"github.com/your-org/5g-network/nf/smf/internal/config"
+	"github.com/your-org/5g-network/nf/smf/internal/context"
+	"github.com/your-org/5g-network/nf/smf/internal/n4"
+)
+
+var testDefaultUPF = UPFInstance{NodeID: "upf-1", N4Address: "127.0.0.1:8805"}
+
+// mockNRF answers UPF discovery with profiles, or with status when it is not
+// 200
+type mockNRF struct {
+	server   *httptest.Server
+	requests atomic.Int32
+	status   atomic.Int32
+	profiles []client.UPFProfile
+}
+
+func newMockNRF(t *testing.T, profiles ...client.UPFProfile) *mockNRF {
+	t.Helper()
+
+	m := &mockNRF{profiles: profiles}
+	m.status.Store(http.StatusOK)
+	m.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.requests.Add(1)
+		assert.Equal(t, "/nnrf-disc/v1/nf-instances", r.URL.Path)
+		assert.Equal(t, "UPF", r.URL.Query().Get("target-nf-type"))
+		assert.Equal(t, "internet", r.URL.Query().Get("dnn"))
+		assert.JSONEq(t, `[{"sst":1,"sd":"010203"}]`, r.URL.Query().Get("snssais"))
+
+		if status := int(m.status.Load()); status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"nfInstances": m.profiles})
+	}))
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func newTestUPFSelector(t *testing.T, nrf *mockNRF) *UPFSelector {
+	t.Helper()
+
+	logger, _ := zap.NewDevelopment()
+	endpoints, err := nrfpool.New(gocontext.Background(), nrf.server.URL, nrfpool.Options{}, logger)
+	require.NoError(t, err)
+	nrfClient := client.NewNRFClient(&config.Config{}, endpoints, nil, logger)
+
+	return NewUPFSelector(nrfClient, testDefaultUPF, time.Minute, logger)
+}
+
+var testSNSSAI = context.SNSSAI{SST: 1, SD: "010203"}
+
+func TestUPFSelectorPrefersPriorityThenLoad(t *testing.T) {
+	nrf := newMockNRF(t,
+		client.UPFProfile{NFInstanceID: "upf-low-priority", FQDN: "upf-low-priority", NFStatus: "REGISTERED", IPv4Addresses: []string{"10.0.0.1:8805"}, Priority: 5},
+		client.UPFProfile{NFInstanceID: "upf-busy", FQDN: "upf-busy", NFStatus: "REGISTERED", IPv4Addresses: []string{"10.0.0.2:8805"}, Priority: 1, Load: 80},
+		client.UPFProfile{NFInstanceID: "upf-idle", FQDN: "upf-idle", NFStatus: "REGISTERED", IPv4Addresses: []string{"10.0.0.3"}, Priority: 1, Load: 10},
+		client.UPFProfile{NFInstanceID: "upf-suspended", FQDN: "upf-suspended", NFStatus: "SUSPENDED", IPv4Addresses: []string{"10.0.0.4:8805"}},
+	)
+	selector := newTestUPFSelector(t, nrf)
+
+	upf := selector.Select("internet", testSNSSAI)
+	assert.Equal(t, "upf-idle", upf.NodeID)
+	assert.Equal(t, "10.0.0.3:8805", upf.N4Address, "the PFCP port is added when missing")
+
+	// Cached until the TTL expires
+	assert.Equal(t, upf, selector.Select("internet", testSNSSAI))
+	assert.Equal(t, int32(1), nrf.requests.Load())
+
+	now := time.Now()
+	selector.now = func() time.Time { return now.Add(2 * time.Minute) }
+	selector.Select("internet", testSNSSAI)
+	assert.Equal(t, int32(2), nrf.requests.Load())
+}
+
+func TestUPFSelectorFallsBackToDefaultUPF(t *testing.T) {
+	nrf := newMockNRF(t)
+	selector := newTestUPFSelector(t, nrf)
+
+	// No UPF serves the DNN
+	assert.Equal(t, testDefaultUPF, selector.Select("internet", testSNSSAI))
+
+	// The failure is remembered for a short while
+	assert.Equal(t, testDefaultUPF, selector.Select("internet", testSNSSAI))
+	assert.Equal(t, int32(1), nrf.requests.Load())
+
+	// Discovery fails
+	now := time.Now()
+	selector.now = func() time.Time { return now.Add(failedUPFCacheTTL) }
+	nrf.status.Store(http.StatusInternalServerError)
+	assert.Equal(t, testDefaultUPF, selector.Select("internet", testSNSSAI))
+	requests := nrf.requests.Load()
+	assert.Greater(t, requests, int32(1))
+	assert.Equal(t, testDefaultUPF, selector.Select("internet", testSNSSAI))
+	assert.Equal(t, requests, nrf.requests.Load())
+}
+
+func TestUPFSelectorSkipsUnspecifiedN4Address(t *testing.T) {
+	nrf := newMockNRF(t,
+		client.UPFProfile{NFInstanceID: "upf-unreachable", NFStatus: "REGISTERED", IPv4Addresses: []string{"0.0.0.0:8805"}, Priority: 1},
+		client.UPFProfile{NFInstanceID: "upf-2", NFStatus: "REGISTERED", IPv4Addresses: []string{"0.0.0.0:8805", "10.0.0.2:8805"}, Priority: 5},
+	)
+	selector := newTestUPFSelector(t, nrf)
+
+	// Without an FQDN the Node ID is the N4 address's IP
+	upf := selector.Select("internet", testSNSSAI)
+	assert.Equal(t, UPFInstance{NodeID: "10.0.0.2", N4Address: "10.0.0.2:8805"}, upf)
+}
+
+func TestCreateSessionUsesSelectedUPF(t *testing.T) {
+	nrf := newMockNRF(t,
+		client.UPFProfile{NFInstanceID: "upf-2", FQDN: "upf-2", NFStatus: "REGISTERED", IPv4Addresses: []string{"10.0.0.2:8805"}},
+	)
+	svc := newTestSessionService(t, config.QoSMonitoringConfig{})
+
+	logger, _ := zap.NewDevelopment()
+	opened := make(map[string]*n4.PFCPClient)
+	svc.SetUPFSelector(newTestUPFSelector(t, nrf), func(upf UPFInstance) (*n4.PFCPClient, error) {
+		c := n4.NewPFCPClient(upf.NodeID, upf.N4Address, logger)
+		opened[upf.N4Address] = c
+		return c, nil
+	})
+
+	for id := uint8(1); id <= 2; id++ {
+		resp, err := svc.CreateSession(&CreateSessionRequest{
+			SUPI:         "imsi-001010000000001",
+			PDUSessionID: id,
+			DNN:          "internet",
+			SNSSAI:       testSNSSAI,
+		})
+		require.NoError(t, err)
+		require.Equal(t, "SUCCESS", resp.Result)
+	}
+
+	session, err := svc.smfContext.GetSession("imsi-001010000000001", 1)
+	require.NoError(t, err)
+	assert.Equal(t, "upf-2", session.UPFNodeID)
+	assert.Equal(t, "10.0.0.2:8805", session.UPFN4Address)
+
+	// One client for the selected UPF holds both sessions; the default UPF
+	// holds none
+	require.Len(t, opened, 1)
+	upf2 := opened["10.0.0.2:8805"]
+	require.NotNil(t, upf2)
+	seids, err := upf2.AuditSessions()
+	require.NoError(t, err)
+	assert.Len(t, seids, 2)
+	seids, err = svc.pfcpClient.AuditSessions()
+	require.NoError(t, err)
+	assert.Empty(t, seids)
+
+	// Release goes to the anchoring UPF
+	_, err = svc.ReleaseSession(&ReleaseSessionRequest{SUPI: "imsi-001010000000001", PDUSessionID: 1})
+	require.NoError(t, err)
+	seids, err = upf2.AuditSessions()
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{n4.GenerateSEID("imsi-001010000000001", 2)}, seids)
+}
+
+func TestCreateSessionFallsBackToDefaultUPF(t *testing.T) {
+	nrf := newMockNRF(t)
+	nrf.status.Store(http.StatusServiceUnavailable)
+	svc := newTestSessionService(t, config.QoSMonitoringConfig{})
+	svc.SetUPFSelector(newTestUPFSelector(t, nrf), func(upf UPFInstance) (*n4.PFCPClient, error) {
+		t.Fatalf("unexpected PFCP client for %s", upf.NodeID)
+		return nil, nil
+	})
+
+	resp, err := svc.CreateSession(&CreateSessionRequest{
+		SUPI:         "imsi-001010000000001",
+		PDUSessionID: 1,
+		DNN:          "internet",
+		SNSSAI:       testSNSSAI,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "SUCCESS", resp.Result)
+
+	session, err := svc.smfContext.GetSession("imsi-001010000000001", 1)
+	require.NoError(t, err)
+	assert.Equal(t, testDefaultUPF.NodeID, session.UPFNodeID)
+	assert.Equal(t, testDefaultUPF.N4Address, session.UPFN4Address)
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	gocontext "context"
+	"fmt"
+	"time"
+
+	"github.com/your-org/5g-network/nf/smf/internal/client"
+	"github.com/your-org/5g-network/nf/smf/internal/context"
+	"github.com/your-org/5g-network/nf/smf/internal/n4"
+	"go.uber.org/zap"
+)
+
+// downlinkFARID is the FAR carrying downlink traffic to the gNB
+const downlinkFARID uint16 = 2
+
+// n1n2TransferTimeout bounds the N1N2MessageTransfer sent to the AMF on
+// downlink data for an idle UE
+const n1n2TransferTimeout = 5 * time.Second
+
+// SetAMFClient sets the AMF notified of downlink data for sessions whose
+// user plane is deactivated. Without a client the UPF keeps buffering until
+// the user plane is activated.
+func (s *SessionService) SetAMFClient(amfClient *client.AMFClient) {
+	s.amfClient = amfClient
+}
+
+// updateUpCnxState activates or deactivates the user plane connection of a
+// session (TS 23.502 4.2.6, 4.2.3.2). Deactivation makes the UPF buffer
+// downlink data and report it; activation forwards it to the gNB tunnel,
+// releasing the buffer.
+func (s *SessionService) updateUpCnxState(session *context.PDUSession, req *UpdateSessionRequest) error {
+	far := n4.FAR{FARID: downlinkFARID}
+	switch context.UpCnxState(req.UpCnxState) {
+	case context.UpCnxStateDeactivated:
+		far.ApplyAction = "BUFFER"
+	case context.UpCnxStateActivated:
+		if req.GNBN3Address == "" {
+			return fmt.Errorf("%w: activation requires the gNB N3 tunnel", ErrInvalidUpCnxState)
+		}
+		far.ApplyAction = "FORWARD"
+		far.ForwardingParameters = &n4.ForwardingParameters{
+			DestinationInterface: "ACCESS",
+			NetworkInstance:      session.DNN,
+			OuterHeaderCreation: &n4.OuterHeaderCreation{
+				TEID: req.GNBTEID,
+				IPv4: req.GNBN3Address,
+			},
+		}
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidUpCnxState, req.UpCnxState)
+	}
+
+	pfcpResp, err := s.modifyN3Session(session, &n4.SessionModificationRequest{
+		SEID:       session.SEID,
+		UpdateFARs: []n4.FAR{far},
+	})
+	if err == nil {
+		err = n4.ValidatePFCPResponse(pfcpResp.Cause)
+	}
+	if err != nil {
+		return fmt.Errorf("PFCP modification failed: %w", err)
+	}
+
+	if far.ForwardingParameters != nil {
+		session.SetGNBInfo(req.GNBTEID, req.GNBN3Address)
+	}
+	session.SetUpCnxState(context.UpCnxState(req.UpCnxState))
+	s.persistSession(session)
+
+	s.logger.Info("User plane connection updated",
+		zap.String("supi", session.SUPI),
+		zap.Uint8("pdu_session_id", session.PDUSessionID),
+		zap.String("up_cnx_state", req.UpCnxState),
+	)
+	return nil
+}
+
+// notifyDownlinkData asks the AMF to page the UE owning a session with
+// buffered downlink data (TS 23.502 4.2.3.3 step 3a)
+func (s *SessionService) notifyDownlinkData(session *context.PDUSession) {
+	resp, err := s.transferN2SMInfo(session)
+	if err != nil {
+		s.logger.Error("N1N2 message transfer failed",
+			zap.String("supi", session.SUPI),
+			zap.Uint8("pdu_session_id", session.PDUSessionID),
+			zap.Error(err),
+		)
+		return
+	}
+	if resp == nil {
+		s.logger.Warn("No AMF configured, downlink data stays buffered",
+			zap.String("supi", session.SUPI),
+			zap.Uint8("pdu_session_id", session.PDUSessionID),
+		)
+		return
+	}
+
+	s.logger.Info("AMF notified of downlink data",
+		zap.String("supi", session.SUPI),
+		zap.Uint8("pdu_session_id", session.PDUSessionID),
+		zap.String("cause", resp.Cause),
+	)
+}
+
+// transferN2SMInfo sends the AMF an N1N2MessageTransfer for a session, so
+// that the gNB sets up its resources and the AMF activates the user plane
+// with the gNB tunnel. It returns nil without an AMF client.
+func (s *SessionService) transferN2SMInfo(session *context.PDUSession) (*client.N1N2MessageTransferResponse, error) {
+	if s.amfClient == nil {
+		return nil, nil
+	}
+
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), n1n2TransferTimeout)
+	defer cancel()
+
+	return s.amfClient.TransferN1N2Message(ctx, session.SUPI, &client.N1N2MessageTransferRequest{
+		PDUSessionID: session.PDUSessionID,
+		N2InfoClass:  "SM",
+	})
+}
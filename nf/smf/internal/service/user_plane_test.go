@@ -0,0 +1,86 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/nf/smf/internal/client"
+	"github.com/your-org/5g-network/nf/smf/internal/context"
+	"github.com/your-org/5g-network/nf/smf/internal/n4"
+)
+
+func TestDownlinkDataReportNotifiesAMF(t *testing.T) {
+	svc, session := newModificationTestSession(t)
+
+	transfers := make(chan client.N1N2MessageTransferRequest, 1)
+	amf := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/namf-comm/v1/ue-contexts/imsi-001010000000001/n1-n2-messages", r.URL.Path)
+		var req client.N1N2MessageTransferRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		transfers <- req
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(client.N1N2MessageTransferResponse{Cause: "ATTEMPTING_TO_REACH_UE"})
+	}))
+	defer amf.Close()
+
+	logger, _ := zap.NewDevelopment()
+	svc.SetAMFClient(client.NewAMFClient(amf.URL, nil, logger))
+
+	resp, err := svc.HandleSessionReport(&n4.SessionReportRequest{
+		SEID:               session.SEID,
+		DownlinkDataReport: &n4.DownlinkDataReport{PDRID: 2, QFI: 1},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Request accepted", resp.Cause)
+
+	select {
+	case req := <-transfers:
+		assert.Equal(t, uint8(1), req.PDUSessionID)
+		assert.Equal(t, "SM", req.N2InfoClass)
+	case <-time.After(2 * time.Second):
+		t.Fatal("AMF was not notified of downlink data")
+	}
+}
+
+func TestUpdateSessionUpCnxState(t *testing.T) {
+	svc, session := newModificationTestSession(t)
+
+	resp, err := svc.UpdateSession(&UpdateSessionRequest{
+		SUPI:         "imsi-001010000000001",
+		PDUSessionID: 1,
+		UpCnxState:   "DEACTIVATED",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "SUCCESS", resp.Result)
+	assert.True(t, session.IsUpCnxDeactivated())
+
+	// Activation needs the gNB tunnel to forward the buffered data to
+	_, err = svc.UpdateSession(&UpdateSessionRequest{
+		SUPI:         "imsi-001010000000001",
+		PDUSessionID: 1,
+		UpCnxState:   "ACTIVATED",
+	})
+	assert.ErrorIs(t, err, ErrInvalidUpCnxState)
+
+	resp, err = svc.UpdateSession(&UpdateSessionRequest{
+		SUPI:         "imsi-001010000000001",
+		PDUSessionID: 1,
+		UpCnxState:   "ACTIVATED",
+		GNBN3Address: "192.168.1.20",
+		GNBTEID:      0x200,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "SUCCESS", resp.Result)
+	assert.False(t, session.IsUpCnxDeactivated())
+	assert.Equal(t, context.UpCnxStateActivated, session.UpCnxState)
+	assert.Equal(t, "192.168.1.20", session.GNBN3Address)
+	assert.Equal(t, uint32(0x200), session.GNBTEIDDownlink)
+}
@@ -4,12 +4,20 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/your-org/5g-network/common/breaker"
+	"github.com/your-org/5g-network/common/buildinfo"
+	"github.com/your-org/5g-network/common/correlation"
 	"github.com/your-org/5g-network/common/metrics"
+	"github.com/your-org/5g-network/common/nrfpool"
+	"github.com/your-org/5g-network/common/oauth"
+	"github.com/your-org/5g-network/common/reload"
+	"github.com/your-org/5g-network/common/retry"
 	"github.com/your-org/5g-network/nf/udm/internal/client"
 	"github.com/your-org/5g-network/nf/udm/internal/config"
 	"github.com/your-org/5g-network/nf/udm/internal/server"
@@ -21,6 +29,7 @@ import (
 var (
 	Version   = "dev"
 	BuildTime = "unknown"
+	GitCommit = "unknown"
 )
 
 func main() {
@@ -28,8 +37,9 @@ func main() {
 	configPath := flag.String("config", "nf/udm/config/udm.yaml", "path to configuration file")
 	flag.Parse()
 
-	// Create logger
-	logger := createLogger("info")
+	// Create logger, at the configured level once loaded
+	level := zap.NewAtomicLevel()
+	logger := createLogger(level)
 	defer logger.Sync()
 
 	logger.Info("Starting UDM (Unified Data Management)",
@@ -50,28 +60,71 @@ func main() {
 		zap.String("nrf_url", cfg.NRF.URL),
 	)
 
-	// Create UDR client
-	udrClient := client.NewUDRClient(cfg.UDR.URL, cfg.UDR.Timeout, logger)
+	// Reload the log level and NRF heartbeat interval on SIGHUP
+	reloader := reload.New(*configPath, cfg, config.Load, []string{
+		"observability.logging.level",
+		"nrf.heartbeat_interval",
+	}, logger)
+	if err := reloader.SetLogLevel(level, func(c *config.Config) string { return c.Observability.Logging.Level }); err != nil {
+		logger.Fatal("Invalid configuration", zap.Error(err))
+	}
+
+	// HTTP transport of the SBI clients, with mutual TLS when configured,
+	// retries of transient failures and the correlation ID of the request served
+	tlsTransport, err := cfg.SBI.ClientTLS.Transport()
+	if err != nil {
+		logger.Fatal("Invalid client TLS configuration", zap.Error(err))
+	}
+	sbiTransport := correlation.NewTransport(retry.NewTransport(tlsTransport, cfg.SBI.ClientRetry, logger), logger)
+
+	// Access tokens for requests to other NFs, from the primary NRF
+	tokens, err := oauth.NewTokenSource(cfg.SBI.ClientOAuth2, cfg.NRF.URL, cfg.NF.InstanceID, "UDM", sbiTransport, logger)
+	if err != nil {
+		logger.Fatal("Invalid client OAuth2 configuration", zap.Error(err))
+	}
+
+	// Create UDR client, failing fast while the UDR is unhealthy when a
+	// circuit breaker is configured. The breaker sees the outcome of each
+	// request after its retries.
+	var udrTransport http.RoundTripper = sbiTransport
+	if cfg.UDR.CircuitBreaker.Enabled {
+		udrTransport = breaker.NewTransport(sbiTransport, breaker.New("UDR", cfg.UDR.CircuitBreaker, logger))
+	}
+	udrClient := client.NewUDRClient(cfg.UDR.URL, cfg.UDR.Timeout, tokens.Transport(udrTransport, "UDR"), logger)
 	logger.Info("UDR client initialized")
 
 	// Create services
 	authService := service.NewAuthenticationService(udrClient, logger)
+	hnKeys, err := cfg.Auth.SUCIKeys()
+	if err != nil {
+		logger.Fatal("Invalid home network keys", zap.Error(err))
+	}
+	authService.SetHomeNetworkKeys(hnKeys)
+	authService.SetSQNBlockSize(cfg.Auth.SQNBlockSize)
 	sdmService := service.NewSDMService(udrClient, logger)
-	uecmService := service.NewUECMService(logger)
+	sdmService.EnableNotifications(cfg.GetAPIRoot(), sbiTransport)
+	uecmService := service.NewUECMService(udrClient, logger)
 
 	logger.Info("Services initialized")
 
 	// Create HTTP server
 	srv := server.NewServer(cfg, authService, sdmService, uecmService, logger)
+	srv.AddDependency("UDR", udrClient.Ping)
+	srv.SetBuildInfo(buildinfo.New("UDM", Version, GitCommit, BuildTime,
+		"nudm-ueau", "nudm-sdm", "nudm-uecm", "log-level"))
+	srv.SetLogLevel(level)
 
 	// Create context
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Initialize metrics server
-	metricsServer := metrics.NewMetricsServer(9092, logger)
+	// Initialize metrics server, on the port assigned to the UDM
+	metricsRegistry, err := metrics.NewRegistry("UDM", cfg.NF.InstanceID)
+	if err != nil {
+		logger.Fatal("Failed to create metrics registry", zap.Error(err))
+	}
+	metricsServer := metrics.NewMetricsServer(metricsRegistry, logger)
 	go func() {
-		logger.Info("Starting metrics server on :9092")
 		if err := metricsServer.Start(); err != nil {
 			logger.Error("Metrics server error", zap.Error(err))
 		}
@@ -84,7 +137,11 @@ func main() {
 
 	// Register with NRF if enabled
 	if cfg.NRF.Enabled {
-		nrfClient := client.NewNRFClient(cfg.NRF.URL, logger)
+		endpoints, err := nrfpool.New(ctx, cfg.NRF.URL, cfg.NRF.Options, logger)
+		if err != nil {
+			logger.Fatal("Failed to resolve NRF endpoints", zap.Error(err))
+		}
+		nrfClient := client.NewNRFClient(endpoints, sbiTransport, logger)
 
 		profile := &client.NFProfile{
 			NFInstanceID: cfg.NF.InstanceID,
@@ -109,7 +166,7 @@ func main() {
 
 			// Start heartbeat goroutine
 			go func() {
-				ticker := time.NewTicker(cfg.NRF.HeartbeatInterval)
+				ticker := reloader.Ticker(func(c *config.Config) time.Duration { return c.NRF.HeartbeatInterval })
 				defer ticker.Stop()
 
 				for {
@@ -138,6 +195,8 @@ func main() {
 		}
 	}
 
+	go reloader.Watch(ctx)
+
 	// Start HTTP server in a goroutine
 	serverErrors := make(chan error, 1)
 	go func() {
@@ -171,24 +230,10 @@ func main() {
 	}
 }
 
-// createLogger creates a structured logger
-func createLogger(level string) *zap.Logger {
-	var zapLevel zapcore.Level
-	switch level {
-	case "debug":
-		zapLevel = zapcore.DebugLevel
-	case "info":
-		zapLevel = zapcore.InfoLevel
-	case "warn":
-		zapLevel = zapcore.WarnLevel
-	case "error":
-		zapLevel = zapcore.ErrorLevel
-	default:
-		zapLevel = zapcore.InfoLevel
-	}
-
+// createLogger creates a structured logger whose level can change at runtime
+func createLogger(level zap.AtomicLevel) *zap.Logger {
 	config := zap.NewProductionConfig()
-	config.Level = zap.NewAtomicLevelAt(zapLevel)
+	config.Level = level
 	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 
 	logger, err := config.Build()
@@ -10,21 +10,24 @@ import (
 	"time"
 
 	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/common/nrfpool"
 )
 
 // NRFClient handles communication with NRF
 type NRFClient struct {
-	baseURL string
-	client  *http.Client
-	logger  *zap.Logger
+	endpoints *nrfpool.Pool
+	client    *http.Client
+	logger    *zap.Logger
 }
 
 // NewNRFClient creates a new NRF client
-func NewNRFClient(baseURL string, logger *zap.Logger) *NRFClient {
+func NewNRFClient(endpoints *nrfpool.Pool, transport http.RoundTripper, logger *zap.Logger) *NRFClient {
 	return &NRFClient{
-		baseURL: baseURL,
+		endpoints: endpoints,
 		client: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:   10 * time.Second,
+			Transport: transport,
 		},
 		logger: logger,
 	}
@@ -61,21 +64,20 @@ type UDMInfo struct {
 
 // Register registers UDM with NRF
 func (c *NRFClient) Register(ctx context.Context, profile *NFProfile) error {
-	url := fmt.Sprintf("%s/nnrf-nfm/v1/nf-instances/%s", c.baseURL, profile.NFInstanceID)
-
 	body, err := json.Marshal(profile)
 	if err != nil {
 		return fmt.Errorf("failed to marshal profile: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewReader(body))
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := c.client.Do(req)
+	resp, err := c.endpoints.Do(c.client, func(baseURL string) (*http.Request, error) {
+		url := fmt.Sprintf("%s/nnrf-nfm/v1/nf-instances/%s", baseURL, profile.NFInstanceID)
+		req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		return req, nil
+	})
 	if err != nil {
 		return fmt.Errorf("failed to send request: %w", err)
 	}
@@ -92,14 +94,14 @@ func (c *NRFClient) Register(ctx context.Context, profile *NFProfile) error {
 
 // Deregister removes UDM registration from NRF
 func (c *NRFClient) Deregister(ctx context.Context, nfInstanceID string) error {
-	url := fmt.Sprintf("%s/nnrf-nfm/v1/nf-instances/%s", c.baseURL, nfInstanceID)
-
-	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	resp, err := c.client.Do(req)
+	resp, err := c.endpoints.Do(c.client, func(baseURL string) (*http.Request, error) {
+		url := fmt.Sprintf("%s/nnrf-nfm/v1/nf-instances/%s", baseURL, nfInstanceID)
+		req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		return req, nil
+	})
 	if err != nil {
 		return fmt.Errorf("failed to send request: %w", err)
 	}
@@ -116,14 +118,14 @@ func (c *NRFClient) Deregister(ctx context.Context, nfInstanceID string) error {
 
 // Heartbeat sends heartbeat to NRF
 func (c *NRFClient) Heartbeat(ctx context.Context, nfInstanceID string) error {
-	url := fmt.Sprintf("%s/nnrf-nfm/v1/nf-instances/%s/heartbeat", c.baseURL, nfInstanceID)
-
-	req, err := http.NewRequestWithContext(ctx, "PATCH", url, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	resp, err := c.client.Do(req)
+	resp, err := c.endpoints.Do(c.client, func(baseURL string) (*http.Request, error) {
+		url := fmt.Sprintf("%s/nnrf-nfm/v1/nf-instances/%s/heartbeat", baseURL, nfInstanceID)
+		req, err := http.NewRequestWithContext(ctx, "PATCH", url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		return req, nil
+	})
 	if err != nil {
 		return fmt.Errorf("failed to send request: %w", err)
 	}
@@ -1,8 +1,10 @@
 package client
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -19,11 +21,12 @@ type UDRClient struct {
 }
 
 // NewUDRClient creates a new UDR client
-func NewUDRClient(baseURL string, timeout time.Duration, logger *zap.Logger) *UDRClient {
+func NewUDRClient(baseURL string, timeout time.Duration, transport http.RoundTripper, logger *zap.Logger) *UDRClient {
 	return &UDRClient{
 		baseURL: baseURL,
 		client: &http.Client{
-			Timeout: timeout,
+			Timeout:   timeout,
+			Transport: transport,
 		},
 		logger: logger,
 	}
@@ -171,6 +174,124 @@ func (c *UDRClient) IncrementSQN(ctx context.Context, supi string) (uint64, erro
 	return result.SQN, nil
 }
 
+// SetSQN overwrites the sequence number in UDR, used when resynchronising
+// with SQN_MS recovered from AUTS
+func (c *UDRClient) SetSQN(ctx context.Context, supi string, sqn uint64) error {
+	url := fmt.Sprintf("%s/nudr-dr/v1/subscription-data/%s/authentication-data/authentication-subscription/sqn", c.baseURL, supi)
+
+	body, err := json.Marshal(map[string]uint64{"sqn": sqn})
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("UDR returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	c.logger.Debug("Set SQN in UDR", zap.String("supi", supi), zap.Uint64("sqn", sqn))
+	return nil
+}
+
+// AuthEvent is the result of an authentication of a UE, recorded in UDR for
+// auditing
+type AuthEvent struct {
+	Success        bool      `json:"success"`
+	AuthMethod     string    `json:"authMethod"`
+	ServingNetwork string    `json:"servingNetwork"`
+	Timestamp      time.Time `json:"timestamp"`
+	FailureReason  string    `json:"failureReason,omitempty"`
+}
+
+// RecordAuthEvent stores the result of an authentication of a SUPI in UDR
+func (c *UDRClient) RecordAuthEvent(ctx context.Context, supi string, event *AuthEvent) error {
+	url := fmt.Sprintf("%s/nudr-dr/v1/subscription-data/%s/authentication-data/authentication-status", c.baseURL, supi)
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("UDR returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	c.logger.Debug("Recorded auth event in UDR", zap.String("supi", supi), zap.Bool("success", event.Success))
+	return nil
+}
+
+// SQNBlock is a range of sequence numbers reserved in UDR, First to Last
+// inclusive
+type SQNBlock struct {
+	First uint64 `json:"first"`
+	Last  uint64 `json:"last"`
+}
+
+// ReserveSQNBlock reserves the next size sequence numbers of a subscriber
+// in UDR
+func (c *UDRClient) ReserveSQNBlock(ctx context.Context, supi string, size uint64) (*SQNBlock, error) {
+	url := fmt.Sprintf("%s/nudr-dr/v1/subscription-data/%s/authentication-data/authentication-subscription/sqn-block", c.baseURL, supi)
+
+	body, err := json.Marshal(map[string]uint64{"size": size})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("UDR returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var block SQNBlock
+	if err := json.NewDecoder(resp.Body).Decode(&block); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	c.logger.Debug("Reserved SQN block in UDR",
+		zap.String("supi", supi),
+		zap.Uint64("first", block.First),
+		zap.Uint64("last", block.Last),
+	)
+	return &block, nil
+}
+
 // GetSessionManagementData retrieves session management subscription data
 func (c *UDRClient) GetSessionManagementData(ctx context.Context, supi, dnn string) (*SessionManagementSubscriptionData, error) {
 	url := fmt.Sprintf("%s/nudr-dr/v1/subscription-data/%s/provisioned-data/sm-data?dnn=%s", c.baseURL, supi, dnn)
@@ -201,3 +322,247 @@ func (c *UDRClient) GetSessionManagementData(ctx context.Context, supi, dnn stri
 	c.logger.Debug("Retrieved SM data from UDR", zap.String("supi", supi), zap.String("dnn", dnn))
 	return &data, nil
 }
+
+// Ping checks that the UDR is reachable and ready to serve requests
+func (c *UDRClient) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/ready", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("UDR returned status %d: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// ErrNotFound is returned when the UDR holds no data for a request
+var ErrNotFound = errors.New("not found in UDR")
+
+// AMF3GPPAccessRegistration is the AMF serving a UE over 3GPP access
+// (TS 29.503 Amf3GppAccessRegistration)
+type AMF3GPPAccessRegistration struct {
+	AMFInstanceID          string        `json:"amfInstanceId"`
+	DeregCallbackURI       string        `json:"deregCallbackUri,omitempty"`
+	GUAMI                  *GUAMI        `json:"guami,omitempty"`
+	RATType                string        `json:"ratType"`       // NR, EUTRA
+	PEI                    string        `json:"pei,omitempty"` // Permanent Equipment Identifier
+	PurgeFlag              bool          `json:"purgeFlag,omitempty"`
+	InitialRegistrationInd bool          `json:"initialRegistrationInd,omitempty"`
+	BackupAMFInfo          []interface{} `json:"backupAmfInfo,omitempty"`
+	RegistrationTime       time.Time     `json:"registrationTime,omitempty"`
+}
+
+// GUAMI represents Globally Unique AMF Identifier
+type GUAMI struct {
+	PlmnID      PlmnID `json:"plmnId"`
+	AMFRegionID string `json:"amfRegionId"`
+	AMFSetID    string `json:"amfSetId"`
+	AMFPointer  string `json:"amfPointer"`
+}
+
+// PlmnID represents PLMN identifier
+type PlmnID struct {
+	MCC string `json:"mcc"`
+	MNC string `json:"mnc"`
+}
+
+// amfRegistrationURL returns the UE context data URL of the AMF registration
+// of a SUPI
+func (c *UDRClient) amfRegistrationURL(supi string) string {
+	return fmt.Sprintf("%s/nudr-dr/v1/subscription-data/%s/context-data/amf-3gpp-access", c.baseURL, supi)
+}
+
+// GetAMF3GPPRegistration retrieves the AMF registration of a SUPI from UDR,
+// ErrNotFound when there is none
+func (c *UDRClient) GetAMF3GPPRegistration(ctx context.Context, supi string) (*AMF3GPPAccessRegistration, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.amfRegistrationURL(supi), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("AMF registration for %s: %w", supi, ErrNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("UDR returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var registration AMF3GPPAccessRegistration
+	if err := json.NewDecoder(resp.Body).Decode(&registration); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	c.logger.Debug("Retrieved AMF registration from UDR", zap.String("supi", supi))
+	return &registration, nil
+}
+
+// PutAMF3GPPRegistration stores the AMF registration of a SUPI in UDR
+func (c *UDRClient) PutAMF3GPPRegistration(ctx context.Context, supi string, registration *AMF3GPPAccessRegistration) error {
+	body, err := json.Marshal(registration)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", c.amfRegistrationURL(supi), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("UDR returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	c.logger.Debug("Stored AMF registration in UDR", zap.String("supi", supi))
+	return nil
+}
+
+// DeleteAMF3GPPRegistration removes the AMF registration of a SUPI from
+// UDR, ErrNotFound when there is none
+func (c *UDRClient) DeleteAMF3GPPRegistration(ctx context.Context, supi string) error {
+	req, err := http.NewRequestWithContext(ctx, "DELETE", c.amfRegistrationURL(supi), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("AMF registration for %s: %w", supi, ErrNotFound)
+	}
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("UDR returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	c.logger.Debug("Deleted AMF registration from UDR", zap.String("supi", supi))
+	return nil
+}
+
+// SDMSubscription is a UDR subscription to the data changes of a UE (TS 29.505
+// SubscriptionDataSubscriptions). The UDR notifies CallbackURI, the UDM, which
+// relays to OriginalCallbackURI, the consumer that subscribed to the UDM.
+type SDMSubscription struct {
+	SubscriptionID        string   `json:"subscriptionId,omitempty"`
+	UEID                  string   `json:"ueId"` // SUPI
+	NFInstanceID          string   `json:"nfInstanceId,omitempty"`
+	CallbackURI           string   `json:"callbackReference"`
+	OriginalCallbackURI   string   `json:"originalCallbackReference,omitempty"`
+	MonitoredResourceURIs []string `json:"monitoredResourceUris,omitempty"`
+}
+
+// DataChangeNotify is the notification the UDR sends on subscription data
+// changes (TS 29.504)
+type DataChangeNotify struct {
+	OriginalCallbackReference []string     `json:"originalCallbackReference,omitempty"`
+	UEID                      string       `json:"ueId"`
+	NotifyItems               []NotifyItem `json:"notifyItems"`
+}
+
+// NotifyItem lists the changes of one resource
+type NotifyItem struct {
+	ResourceID string       `json:"resourceId"`
+	Changes    []ChangeItem `json:"changes"`
+}
+
+// ChangeItem is one change of a resource
+type ChangeItem struct {
+	Op       string      `json:"op"` // ADD, MOVE, REMOVE, REPLACE
+	Path     string      `json:"path"`
+	NewValue interface{} `json:"newValue,omitempty"`
+}
+
+// CreateSDMSubscription subscribes to data changes in UDR, returning the
+// subscription with the ID UDR assigned
+func (c *UDRClient) CreateSDMSubscription(ctx context.Context, subscription *SDMSubscription) (*SDMSubscription, error) {
+	url := fmt.Sprintf("%s/nudr-dr/v1/subscription-data/subs-to-notify", c.baseURL)
+
+	body, err := json.Marshal(subscription)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("UDR returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var created SDMSubscription
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	c.logger.Debug("Created SDM subscription in UDR",
+		zap.String("supi", created.UEID),
+		zap.String("subscription_id", created.SubscriptionID),
+	)
+	return &created, nil
+}
+
+// DeleteSDMSubscription removes a data change subscription from UDR,
+// ErrNotFound when there is none
+func (c *UDRClient) DeleteSDMSubscription(ctx context.Context, subscriptionID string) error {
+	url := fmt.Sprintf("%s/nudr-dr/v1/subscription-data/subs-to-notify/%s", c.baseURL, subscriptionID)
+
+	req, err := http.NewRequestWithContext(ctx, "DELETE", url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("SDM subscription %s: %w", subscriptionID, ErrNotFound)
+	}
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("UDR returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	c.logger.Debug("Deleted SDM subscription from UDR", zap.String("subscription_id", subscriptionID))
+	return nil
+}
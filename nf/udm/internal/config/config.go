@@ -1,10 +1,17 @@
 package config
 
 import (
+	"encoding/hex"
 	"fmt"
 	"os"
 	"time"
 
+	"github.com/your-org/5g-network/common/breaker"
+	"github.com/your-org/5g-network/common/crypto/suci"
+	"github.com/your-org/5g-network/common/nrfpool"
+	"github.com/your-org/5g-network/common/oauth"
+	"github.com/your-org/5g-network/common/retry"
+	"github.com/your-org/5g-network/common/tlsconfig"
 	"gopkg.in/yaml.v3"
 )
 
@@ -28,10 +35,15 @@ type NFConfig struct {
 
 // SBIConfig contains Service-Based Interface configuration
 type SBIConfig struct {
-	Scheme      string    `yaml:"scheme"`
-	BindAddress string    `yaml:"bind_address"`
-	Port        int       `yaml:"port"`
-	TLS         TLSConfig `yaml:"tls"`
+	Scheme       string                 `yaml:"scheme"`
+	BindAddress  string                 `yaml:"bind_address"`
+	Port         int                    `yaml:"port"`
+	APIRoot      string                 `yaml:"api_root"` // URL other NFs reach the UDM at, for callbacks
+	TLS          TLSConfig              `yaml:"tls"`
+	ClientTLS    tlsconfig.ClientConfig `yaml:"client_tls"`   // TLS towards the SBIs of other NFs
+	ClientRetry  retry.Policy           `yaml:"client_retry"` // Retries of idempotent requests to other NFs
+	OAuth2       oauth.Config           `yaml:"oauth2"`
+	ClientOAuth2 oauth.ClientConfig     `yaml:"client_oauth2"` // Access tokens for requests to other NFs
 }
 
 // TLSConfig contains TLS configuration
@@ -39,6 +51,8 @@ type TLSConfig struct {
 	Enabled  bool   `yaml:"enabled"`
 	CertFile string `yaml:"cert_file"`
 	KeyFile  string `yaml:"key_file"`
+
+	tlsconfig.Options `yaml:",inline"`
 }
 
 // NRFConfig contains NRF client configuration
@@ -46,12 +60,16 @@ type NRFConfig struct {
 	URL               string        `yaml:"url"`
 	Enabled           bool          `yaml:"enabled"`
 	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"`
+
+	// Secondary NRFs and DNS SRV discovery for failover
+	nrfpool.Options `yaml:",inline"`
 }
 
 // UDRConfig contains UDR client configuration
 type UDRConfig struct {
-	URL     string        `yaml:"url"`
-	Timeout time.Duration `yaml:"timeout"`
+	URL            string         `yaml:"url"`
+	Timeout        time.Duration  `yaml:"timeout"`
+	CircuitBreaker breaker.Config `yaml:"circuit_breaker"` // Fails fast while the UDR is unhealthy
 }
 
 // PLMNConfig contains PLMN configuration
@@ -60,10 +78,58 @@ type PLMNConfig struct {
 	MNC string `yaml:"mnc"` // Mobile Network Code
 }
 
+// maxSQNBlockSize is the largest SQN block UDR reserves
+const maxSQNBlockSize = 1000
+
 // AuthConfig contains authentication configuration
 type AuthConfig struct {
 	Algorithm string `yaml:"algorithm"`  // milenage, tuak
 	KeyLength int    `yaml:"key_length"` // 128 or 256
+
+	// Home network private keys for SUCI de-concealment (TS 33.501 6.12.2)
+	HomeNetworkKeys []HomeNetworkKeyConfig `yaml:"home_network_keys"`
+
+	// SQNs reserved from UDR at once, 0 or 1 to update UDR per vector
+	SQNBlockSize uint64 `yaml:"sqn_block_size"`
+}
+
+// HomeNetworkKeyConfig is a home network private key of an ECIES protection
+// scheme (TS 33.501 Annex C)
+type HomeNetworkKeyConfig struct {
+	ID         int    `yaml:"id"`          // Home network public key identifier
+	Scheme     string `yaml:"scheme"`      // profile_a or profile_b
+	PrivateKey string `yaml:"private_key"` // Hex encoded private key
+}
+
+// SUCIKeys decodes the configured home network keys
+func (c *AuthConfig) SUCIKeys() ([]suci.HomeNetworkKey, error) {
+	keys := make([]suci.HomeNetworkKey, 0, len(c.HomeNetworkKeys))
+	for _, k := range c.HomeNetworkKeys {
+		var scheme int
+		switch k.Scheme {
+		case "profile_a":
+			scheme = suci.SchemeProfileA
+		case "profile_b":
+			scheme = suci.SchemeProfileB
+		default:
+			return nil, fmt.Errorf("home network key %d: invalid scheme %q (must be profile_a or profile_b)", k.ID, k.Scheme)
+		}
+		if k.ID < 0 || k.ID > 255 {
+			return nil, fmt.Errorf("home network key %d: id must be 0-255", k.ID)
+		}
+
+		privateKey, err := hex.DecodeString(k.PrivateKey)
+		if err != nil || len(privateKey) != 32 {
+			return nil, fmt.Errorf("home network key %d: private key must be 32 hex encoded bytes", k.ID)
+		}
+
+		keys = append(keys, suci.HomeNetworkKey{
+			ID:         k.ID,
+			Scheme:     scheme,
+			PrivateKey: privateKey,
+		})
+	}
+	return keys, nil
 }
 
 // ObservabilityConfig contains observability settings
@@ -126,14 +192,22 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid sbi.port: %d", c.SBI.Port)
 	}
 
-	if c.NRF.Enabled && c.NRF.URL == "" {
-		return fmt.Errorf("nrf.url is required when nrf.enabled is true")
+	if c.NRF.Enabled && c.NRF.URL == "" && c.NRF.SRVName == "" {
+		return fmt.Errorf("nrf.url or nrf.srv_name is required when nrf.enabled is true")
+	}
+
+	if err := c.NRF.Validate(); err != nil {
+		return fmt.Errorf("invalid nrf: %w", err)
 	}
 
 	if c.UDR.URL == "" {
 		return fmt.Errorf("udr.url is required")
 	}
 
+	if err := c.UDR.CircuitBreaker.Validate(); err != nil {
+		return fmt.Errorf("invalid udr.circuit_breaker: %w", err)
+	}
+
 	if c.PLMN.MCC == "" || c.PLMN.MNC == "" {
 		return fmt.Errorf("plmn.mcc and plmn.mnc are required")
 	}
@@ -146,6 +220,36 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid auth.key_length: %d (must be 128 or 256)", c.Auth.KeyLength)
 	}
 
+	if c.Auth.SQNBlockSize > maxSQNBlockSize {
+		return fmt.Errorf("invalid auth.sqn_block_size: %d (must be at most %d)", c.Auth.SQNBlockSize, maxSQNBlockSize)
+	}
+
+	if _, err := c.Auth.SUCIKeys(); err != nil {
+		return fmt.Errorf("invalid auth.home_network_keys: %w", err)
+	}
+
+	if c.SBI.TLS.Enabled {
+		if err := c.SBI.TLS.Validate(); err != nil {
+			return fmt.Errorf("invalid sbi.tls: %w", err)
+		}
+	}
+
+	if err := c.SBI.ClientTLS.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.client_tls: %w", err)
+	}
+
+	if err := c.SBI.ClientRetry.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.client_retry: %w", err)
+	}
+
+	if err := c.SBI.OAuth2.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.oauth2: %w", err)
+	}
+
+	if err := c.SBI.ClientOAuth2.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.client_oauth2: %w", err)
+	}
+
 	return nil
 }
 
@@ -153,3 +257,12 @@ func (c *Config) Validate() error {
 func (c *Config) GetSBIURL() string {
 	return fmt.Sprintf("%s://%s:%d", c.SBI.Scheme, c.SBI.BindAddress, c.SBI.Port)
 }
+
+// GetAPIRoot returns the apiRoot of the UDM callbacks, the SBI URL unless
+// configured
+func (c *Config) GetAPIRoot() string {
+	if c.SBI.APIRoot != "" {
+		return c.SBI.APIRoot
+	}
+	return c.GetSBIURL()
+}
@@ -2,11 +2,14 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/your-org/5g-network/common/crypto/suci"
 	"github.com/your-org/5g-network/common/metrics"
+	"github.com/your-org/5g-network/common/sbi"
 	"github.com/your-org/5g-network/nf/udm/internal/client"
 	"github.com/your-org/5g-network/nf/udm/internal/service"
 	"go.uber.org/zap"
@@ -20,7 +23,7 @@ func (s *UDMServer) handleGenerateAuthData(w http.ResponseWriter, r *http.Reques
 
 	var authInfo service.AuthenticationInfo
 	if err := json.NewDecoder(r.Body).Decode(&authInfo); err != nil {
-		s.respondError(w, http.StatusBadRequest, "invalid request body", err)
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
 		metrics.RecordVectorGeneration("failed")
 		return
 	}
@@ -29,7 +32,14 @@ func (s *UDMServer) handleGenerateAuthData(w http.ResponseWriter, r *http.Reques
 
 	result, err := s.authService.GenerateAuthData(r.Context(), &authInfo)
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, "failed to generate auth data", err)
+		status, cause := http.StatusInternalServerError, sbi.CauseSystemFailure
+		switch {
+		case errors.Is(err, service.ErrInvalidResyncInfo), errors.Is(err, suci.ErrInvalidSUCI):
+			status, cause = http.StatusBadRequest, sbi.CauseMandatoryIEIncorrect
+		case errors.Is(err, service.ErrResyncRejected), errors.Is(err, service.ErrDeconcealment):
+			status, cause = http.StatusForbidden, sbi.CauseAuthenticationRejected
+		}
+		s.respondProblem(w, status, cause, "failed to generate auth data", err)
 		metrics.RecordVectorGeneration("failed")
 		return
 	}
@@ -38,21 +48,21 @@ func (s *UDMServer) handleGenerateAuthData(w http.ResponseWriter, r *http.Reques
 	metrics.RecordVectorGeneration("success")
 	metrics.RecordVectorGenerationDuration(time.Since(start).Seconds())
 
-	s.logger.Info("Generated authentication data", zap.String("supi", supi))
+	s.logger.Info("Generated authentication data", zap.String("supi", authInfo.SUPI))
 	s.respondJSON(w, http.StatusOK, result)
 }
 
 func (s *UDMServer) handleConfirmAuth(w http.ResponseWriter, r *http.Request) {
 	supi := chi.URLParam(r, "supi")
 
-	var authEvent map[string]interface{}
+	var authEvent service.AuthEvent
 	if err := json.NewDecoder(r.Body).Decode(&authEvent); err != nil {
-		s.respondError(w, http.StatusBadRequest, "invalid request body", err)
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
 		return
 	}
 
-	if err := s.authService.ConfirmAuth(r.Context(), supi, authEvent); err != nil {
-		s.respondError(w, http.StatusInternalServerError, "failed to confirm auth", err)
+	if err := s.authService.ConfirmAuth(r.Context(), supi, &authEvent); err != nil {
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to confirm auth", err)
 		return
 	}
 
@@ -82,7 +92,7 @@ func (s *UDMServer) handleGetAMData(w http.ResponseWriter, r *http.Request) {
 
 	amData, err := s.sdmService.GetAMData(r.Context(), supi, plmnID)
 	if err != nil {
-		s.respondError(w, http.StatusNotFound, "failed to get AM data", err)
+		s.respondProblem(w, http.StatusNotFound, sbi.CauseSubscriberNotFound, "failed to get AM data", err)
 		return
 	}
 
@@ -101,7 +111,7 @@ func (s *UDMServer) handleGetSMData(w http.ResponseWriter, r *http.Request) {
 
 	smData, err := s.sdmService.GetSMData(r.Context(), supi, plmnID, dnn)
 	if err != nil {
-		s.respondError(w, http.StatusNotFound, "failed to get SM data", err)
+		s.respondProblem(w, http.StatusNotFound, sbi.CauseDataNotFound, "failed to get SM data", err)
 		return
 	}
 
@@ -128,7 +138,7 @@ func (s *UDMServer) handleGetSMDataWithPlmn(w http.ResponseWriter, r *http.Reque
 
 	smData, err := s.sdmService.GetSMData(r.Context(), supi, plmnID, dnn)
 	if err != nil {
-		s.respondError(w, http.StatusNotFound, "failed to get SM data", err)
+		s.respondProblem(w, http.StatusNotFound, sbi.CauseDataNotFound, "failed to get SM data", err)
 		return
 	}
 
@@ -144,13 +154,18 @@ func (s *UDMServer) handleSubscribeSDM(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&subscription); err != nil {
-		s.respondError(w, http.StatusBadRequest, "invalid request body", err)
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
+		return
+	}
+
+	if subscription.CallbackReference == "" {
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseMandatoryIEMissing, "callbackReference is required", nil)
 		return
 	}
 
-	subscriptionID, err := s.sdmService.SubscribeToDataChanges(r.Context(), supi, subscription.CallbackReference)
+	subscriptionID, err := s.sdmService.SubscribeToDataChanges(r.Context(), supi, subscription.CallbackReference, subscription.MonitoredResourceUris)
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, "failed to create subscription", err)
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to create subscription", err)
 		return
 	}
 
@@ -164,8 +179,13 @@ func (s *UDMServer) handleUnsubscribeSDM(w http.ResponseWriter, r *http.Request)
 	supi := chi.URLParam(r, "supi")
 	subscriptionID := chi.URLParam(r, "subscriptionId")
 
-	if err := s.sdmService.UnsubscribeFromDataChanges(r.Context(), subscriptionID); err != nil {
-		s.respondError(w, http.StatusNotFound, "failed to delete subscription", err)
+	err := s.sdmService.UnsubscribeFromDataChanges(r.Context(), subscriptionID)
+	if errors.Is(err, service.ErrSubscriptionNotFound) {
+		s.respondProblem(w, http.StatusNotFound, sbi.CauseSubscriptionNotFound, "failed to delete subscription", err)
+		return
+	}
+	if err != nil {
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to delete subscription", err)
 		return
 	}
 
@@ -177,19 +197,32 @@ func (s *UDMServer) handleUnsubscribeSDM(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// handleDataChangeNotify relays UDR data change notifications to the SDM
+// subscribers
+func (s *UDMServer) handleDataChangeNotify(w http.ResponseWriter, r *http.Request) {
+	var notify client.DataChangeNotify
+	if err := json.NewDecoder(r.Body).Decode(&notify); err != nil {
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
+		return
+	}
+
+	s.sdmService.NotifyDataChange(r.Context(), &notify)
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // UE Context Management Handlers (Nudm_UECM)
 
 func (s *UDMServer) handleRegisterAMF3GPP(w http.ResponseWriter, r *http.Request) {
 	supi := chi.URLParam(r, "supi")
 
-	var registration service.AMF3GPPAccessRegistration
+	var registration client.AMF3GPPAccessRegistration
 	if err := json.NewDecoder(r.Body).Decode(&registration); err != nil {
-		s.respondError(w, http.StatusBadRequest, "invalid request body", err)
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
 		return
 	}
 
 	if err := s.uecmService.RegisterAMF3GPPAccess(r.Context(), supi, &registration); err != nil {
-		s.respondError(w, http.StatusInternalServerError, "failed to register AMF", err)
+		s.respondUECMError(w, "failed to register AMF", err)
 		return
 	}
 
@@ -206,12 +239,12 @@ func (s *UDMServer) handleUpdateAMF3GPP(w http.ResponseWriter, r *http.Request)
 
 	var updates map[string]interface{}
 	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
-		s.respondError(w, http.StatusBadRequest, "invalid request body", err)
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
 		return
 	}
 
 	if err := s.uecmService.UpdateAMF3GPPAccess(r.Context(), supi, updates); err != nil {
-		s.respondError(w, http.StatusNotFound, "failed to update AMF registration", err)
+		s.respondUECMError(w, "failed to update AMF registration", err)
 		return
 	}
 
@@ -224,7 +257,7 @@ func (s *UDMServer) handleGetAMF3GPP(w http.ResponseWriter, r *http.Request) {
 
 	registration, err := s.uecmService.Get3GPPRegistration(r.Context(), supi)
 	if err != nil {
-		s.respondError(w, http.StatusNotFound, "AMF registration not found", err)
+		s.respondUECMError(w, "AMF registration not found", err)
 		return
 	}
 
@@ -236,7 +269,7 @@ func (s *UDMServer) handleDeregisterAMF3GPP(w http.ResponseWriter, r *http.Reque
 	supi := chi.URLParam(r, "supi")
 
 	if err := s.uecmService.DeregisterAMF3GPPAccess(r.Context(), supi); err != nil {
-		s.respondError(w, http.StatusNotFound, "failed to deregister AMF", err)
+		s.respondUECMError(w, "failed to deregister AMF", err)
 		return
 	}
 
@@ -249,7 +282,7 @@ func (s *UDMServer) handleGetUEContext(w http.ResponseWriter, r *http.Request) {
 
 	ueContext, err := s.uecmService.GetUEContext(r.Context(), supi)
 	if err != nil {
-		s.respondError(w, http.StatusNotFound, "UE context not found", err)
+		s.respondProblem(w, http.StatusNotFound, sbi.CauseContextNotFound, "UE context not found", err)
 		return
 	}
 
@@ -257,6 +290,18 @@ func (s *UDMServer) handleGetUEContext(w http.ResponseWriter, r *http.Request) {
 	s.respondJSON(w, http.StatusOK, ueContext)
 }
 
+// respondUECMError maps a UECM service error to a problem response
+func (s *UDMServer) respondUECMError(w http.ResponseWriter, title string, err error) {
+	switch {
+	case errors.Is(err, service.ErrRegistrationNotFound):
+		s.respondProblem(w, http.StatusNotFound, sbi.CauseContextNotFound, title, err)
+	case errors.Is(err, service.ErrInvalidRegistration):
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseMandatoryIEIncorrect, title, err)
+	default:
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, title, err)
+	}
+}
+
 // Admin Handlers
 
 func (s *UDMServer) handleGetStats(w http.ResponseWriter, r *http.Request) {
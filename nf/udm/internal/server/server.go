@@ -9,11 +9,26 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/your-org/5g-network/common/buildinfo"
+	"github.com/your-org/5g-network/common/correlation"
+	"github.com/your-org/5g-network/common/loglevel"
+	"github.com/your-org/5g-network/common/metrics"
+	"github.com/your-org/5g-network/common/oauth"
+	"github.com/your-org/5g-network/common/sbi"
 	"github.com/your-org/5g-network/nf/udm/internal/config"
 	"github.com/your-org/5g-network/nf/udm/internal/service"
 	"go.uber.org/zap"
 )
 
+// readinessTimeout bounds each dependency probe of the readiness check
+const readinessTimeout = 2 * time.Second
+
+// dependency is a backend the NF needs to serve requests
+type dependency struct {
+	name string
+	ping func(ctx context.Context) error
+}
+
 // UDMServer represents the UDM HTTP server
 type UDMServer struct {
 	config *config.Config
@@ -25,6 +40,9 @@ type UDMServer struct {
 	authService *service.AuthenticationService
 	sdmService  *service.SDMService
 	uecmService *service.UECMService
+
+	// Backends probed by the readiness check
+	dependencies []dependency
 }
 
 // NewServer creates a new UDM server
@@ -50,13 +68,21 @@ func NewServer(
 	return s
 }
 
+// AddDependency makes the readiness check probe a backend with ping
+func (s *UDMServer) AddDependency(name string, ping func(ctx context.Context) error) {
+	s.dependencies = append(s.dependencies, dependency{name: name, ping: ping})
+}
+
 // setupMiddleware configures HTTP middleware
 func (s *UDMServer) setupMiddleware() {
 	s.router.Use(middleware.RequestID)
+	s.router.Use(correlation.Middleware)
 	s.router.Use(middleware.RealIP)
+	s.router.Use(metrics.Middleware)
 	s.router.Use(s.loggingMiddleware)
 	s.router.Use(middleware.Recoverer)
 	s.router.Use(middleware.Timeout(60 * time.Second))
+	s.router.Use(oauth.Middleware(s.config.SBI.OAuth2, s.logger, "UDM", s.config.NF.InstanceID))
 }
 
 // setupRoutes configures HTTP routes
@@ -68,12 +94,17 @@ func (s *UDMServer) setupRoutes() {
 
 	// Nudm_UEAuthentication service (TS 29.503)
 	s.router.Route("/nudm-ueau/v1", func(r chi.Router) {
+		r.Use(oauth.RequireScope("nudm-ueau"))
+
+		// {supi} also accepts a SUCI, de-concealed before vector generation
 		r.Post("/supi/{supi}/security-information/generate-auth-data", s.handleGenerateAuthData)
 		r.Post("/supi/{supi}/auth-events", s.handleConfirmAuth)
 	})
 
 	// Nudm_SDM service (TS 29.503)
 	s.router.Route("/nudm-sdm/v1", func(r chi.Router) {
+		r.Use(oauth.RequireScope("nudm-sdm"))
+
 		// Access and Mobility subscription data
 		r.Get("/supi/{supi}/am-data", s.handleGetAMData)
 
@@ -86,8 +117,13 @@ func (s *UDMServer) setupRoutes() {
 		r.Delete("/supi/{supi}/sdm-subscriptions/{subscriptionId}", s.handleUnsubscribeSDM)
 	})
 
+	// Data change notifications from the UDR, relayed to SDM subscribers
+	s.router.Post(service.DataChangeNotifyPath, s.handleDataChangeNotify)
+
 	// Nudm_UECM service (TS 29.503)
 	s.router.Route("/nudm-uecm/v1", func(r chi.Router) {
+		r.Use(oauth.RequireScope("nudm-uecm"))
+
 		// 3GPP access registration
 		r.Put("/supi/{supi}/registrations/amf-3gpp-access", s.handleRegisterAMF3GPP)
 		r.Patch("/supi/{supi}/registrations/amf-3gpp-access", s.handleUpdateAMF3GPP)
@@ -104,6 +140,19 @@ func (s *UDMServer) setupRoutes() {
 	})
 }
 
+// SetBuildInfo exposes the NF build info on GET /version
+func (s *UDMServer) SetBuildInfo(info *buildinfo.Info) {
+	s.router.Get("/version", info.Handler())
+}
+
+// SetLogLevel exposes the level the NF's loggers are built from on
+// /admin/log-level, read with GET and changed at runtime with PUT
+func (s *UDMServer) SetLogLevel(level zap.AtomicLevel) {
+	handler := loglevel.Handler(level, s.logger)
+	s.router.Get("/admin/log-level", handler)
+	s.router.Put("/admin/log-level", handler)
+}
+
 // Start starts the HTTP server
 func (s *UDMServer) Start() error {
 	addr := fmt.Sprintf("%s:%d", s.config.SBI.BindAddress, s.config.SBI.Port)
@@ -119,6 +168,11 @@ func (s *UDMServer) Start() error {
 	s.logger.Info("Starting UDM HTTP server", zap.String("address", addr))
 
 	if s.config.SBI.TLS.Enabled {
+		tlsConfig, err := s.config.SBI.TLS.Build()
+		if err != nil {
+			return fmt.Errorf("invalid TLS configuration: %w", err)
+		}
+		s.server.TLSConfig = tlsConfig
 		return s.server.ListenAndServeTLS(s.config.SBI.TLS.CertFile, s.config.SBI.TLS.KeyFile)
 	}
 
@@ -152,6 +206,7 @@ func (s *UDMServer) loggingMiddleware(next http.Handler) http.Handler {
 			zap.Duration("duration", time.Since(start)),
 			zap.String("remote_addr", r.RemoteAddr),
 			zap.String("request_id", middleware.GetReqID(r.Context())),
+			correlation.Field(r.Context()),
 		)
 	})
 }
@@ -167,22 +222,13 @@ func (s *UDMServer) respondJSON(w http.ResponseWriter, status int, data interfac
 	}
 }
 
-func (s *UDMServer) respondError(w http.ResponseWriter, status int, message string, err error) {
-	s.logger.Error(message, zap.Error(err))
-
-	w.Header().Set("Content-Type", "application/problem+json")
-	w.WriteHeader(status)
-
-	response := map[string]interface{}{
-		"status": status,
-		"title":  message,
-	}
+// respondProblem writes a ProblemDetails error response
+func (s *UDMServer) respondProblem(w http.ResponseWriter, status int, cause, title string, err error) {
+	s.logger.Error(title, zap.String("cause", cause), zap.Error(err))
 
-	if err != nil {
-		response["detail"] = err.Error()
+	if err := sbi.WriteProblem(w, sbi.NewProblem(status, cause, title, err)); err != nil {
+		s.logger.Error("Failed to encode problem details", zap.Error(err))
 	}
-
-	json.NewEncoder(w).Encode(response)
 }
 
 // Health check handlers
@@ -194,8 +240,17 @@ func (s *UDMServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *UDMServer) handleReady(w http.ResponseWriter, r *http.Request) {
-	// Check if UDM is ready to serve requests
-	// Could check UDR connectivity, etc.
+	// Ready once every backend answers, liveness (/health) stays unprobed
+	for _, dep := range s.dependencies {
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		err := dep.ping(ctx)
+		cancel()
+		if err != nil {
+			s.respondProblem(w, http.StatusServiceUnavailable, sbi.CauseNFCongestion, dep.name+" unavailable", err)
+			return
+		}
+	}
+
 	s.respondJSON(w, http.StatusOK, map[string]string{
 		"status": "ready",
 	})
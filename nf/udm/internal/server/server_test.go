@@ -0,0 +1,207 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/common/sbi"
+	"github.com/your-org/5g-network/nf/udm/internal/client"
+	"github.com/your-org/5g-network/nf/udm/internal/config"
+	"github.com/your-org/5g-network/nf/udm/internal/service"
+)
+
+func TestReadinessProbesUDR(t *testing.T) {
+	var udrReady atomic.Bool
+	udr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ready" || !udrReady.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer udr.Close()
+
+	s := NewServer(&config.Config{}, nil, nil, nil, zap.NewNop())
+	s.AddDependency("UDR", client.NewUDRClient(udr.URL, time.Second, nil, zap.NewNop()).Ping)
+
+	ready := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		return rec
+	}
+
+	rec := ready()
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, sbi.ContentTypeProblem, rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), "UDR unavailable")
+
+	// Liveness does not depend on the UDR
+	rec = httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	udrReady.Store(true)
+	assert.Equal(t, http.StatusOK, ready().Code)
+
+	udr.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, ready().Code)
+}
+
+// notifyingUDR stores data change subscriptions and notifies them of AM data
+// updates, as the UDR does
+type notifyingUDR struct {
+	mu            sync.Mutex
+	subscriptions map[string]*client.SDMSubscription
+	nextID        int
+}
+
+func newNotifyingUDR() http.Handler {
+	u := &notifyingUDR{subscriptions: make(map[string]*client.SDMSubscription)}
+
+	r := chi.NewRouter()
+	r.Post("/nudr-dr/v1/subscription-data/subs-to-notify", func(w http.ResponseWriter, r *http.Request) {
+		var sub client.SDMSubscription
+		json.NewDecoder(r.Body).Decode(&sub)
+
+		u.mu.Lock()
+		u.nextID++
+		sub.SubscriptionID = fmt.Sprintf("sub-%d", u.nextID)
+		u.subscriptions[sub.SubscriptionID] = &sub
+		u.mu.Unlock()
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(&sub)
+	})
+	r.Delete("/nudr-dr/v1/subscription-data/subs-to-notify/{subscriptionId}", func(w http.ResponseWriter, r *http.Request) {
+		u.mu.Lock()
+		defer u.mu.Unlock()
+
+		id := chi.URLParam(r, "subscriptionId")
+		if _, ok := u.subscriptions[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(u.subscriptions, id)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	r.Put("/nudr-dr/v1/subscription-data/{supi}/provisioned-data/am-data", func(w http.ResponseWriter, r *http.Request) {
+		var data map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&data)
+
+		u.mu.Lock()
+		var subscriptions []*client.SDMSubscription
+		for _, sub := range u.subscriptions {
+			if sub.UEID == chi.URLParam(r, "supi") {
+				subscriptions = append(subscriptions, sub)
+			}
+		}
+		u.mu.Unlock()
+
+		for _, sub := range subscriptions {
+			body, _ := json.Marshal(&client.DataChangeNotify{
+				OriginalCallbackReference: []string{sub.OriginalCallbackURI},
+				UEID:                      sub.UEID,
+				NotifyItems: []client.NotifyItem{{
+					ResourceID: r.URL.Path,
+					Changes:    []client.ChangeItem{{Op: "REPLACE", NewValue: data}},
+				}},
+			})
+			resp, err := http.Post(sub.CallbackURI, "application/json", bytes.NewReader(body))
+			if err == nil {
+				resp.Body.Close()
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	return r
+}
+
+func TestSDMSubscriberNotifiedOfAMDataChange(t *testing.T) {
+	const supi = "imsi-001010000000001"
+
+	// AMF callback
+	notifications := make(chan service.ModificationNotification, 1)
+	amf := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var notification service.ModificationNotification
+		json.NewDecoder(r.Body).Decode(&notification)
+		notifications <- notification
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer amf.Close()
+
+	udr := httptest.NewServer(newNotifyingUDR())
+	defer udr.Close()
+
+	// The UDM callback is only known once its listener is up
+	var s *UDMServer
+	udm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.router.ServeHTTP(w, r)
+	}))
+	defer udm.Close()
+	sdm := service.NewSDMService(client.NewUDRClient(udr.URL, 5*time.Second, nil, zap.NewNop()), zap.NewNop())
+	sdm.EnableNotifications(udm.URL, nil)
+	s = NewServer(&config.Config{}, nil, sdm, nil, zap.NewNop())
+
+	resp, err := http.Post(udm.URL+"/nudm-sdm/v1/supi/"+supi+"/sdm-subscriptions", "application/json", strings.NewReader(
+		`{"callbackReference":"`+amf.URL+`","monitoredResourceUris":["`+udm.URL+`/nudm-sdm/v1/supi/`+supi+`/am-data"]}`))
+	require.NoError(t, err)
+	var created struct {
+		SubscriptionID string `json:"subscriptionId"`
+	}
+	json.NewDecoder(resp.Body).Decode(&created)
+	resp.Body.Close()
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.NotEmpty(t, created.SubscriptionID)
+
+	updateAMData := func() {
+		req, err := http.NewRequest(http.MethodPut, udr.URL+"/nudr-dr/v1/subscription-data/"+supi+"/provisioned-data/am-data",
+			strings.NewReader(`{"subscriberStatus":"OPERATOR_DETERMINED_BARRING"}`))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	updateAMData()
+	select {
+	case notification := <-notifications:
+		require.Len(t, notification.NotifyItems, 1)
+		assert.Equal(t, udm.URL+"/nudm-sdm/v1/supi/"+supi+"/am-data", notification.NotifyItems[0].ResourceID)
+		assert.Equal(t, "REPLACE", notification.NotifyItems[0].Changes[0].Op)
+	case <-time.After(5 * time.Second):
+		t.Fatal("AMF callback not notified of the AM data change")
+	}
+
+	// No more notifications once unsubscribed
+	req, err := http.NewRequest(http.MethodDelete, udm.URL+"/nudm-sdm/v1/supi/"+supi+"/sdm-subscriptions/"+created.SubscriptionID, nil)
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	updateAMData()
+	select {
+	case <-notifications:
+		t.Fatal("AMF callback notified after unsubscribing")
+	default:
+	}
+
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
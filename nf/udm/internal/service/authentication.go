@@ -4,8 +4,13 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/your-org/5g-network/common/crypto/kdf"
+	"github.com/your-org/5g-network/common/crypto/milenage"
+	"github.com/your-org/5g-network/common/crypto/suci"
 	"github.com/your-org/5g-network/nf/udm/internal/client"
 	"github.com/your-org/5g-network/nf/udm/internal/crypto"
 	"go.uber.org/zap"
@@ -13,8 +18,13 @@ import (
 
 // AuthenticationService handles UE authentication operations
 type AuthenticationService struct {
-	udrClient *client.UDRClient
-	logger    *zap.Logger
+	udrClient       *client.UDRClient
+	homeNetworkKeys []suci.HomeNetworkKey // SUCI de-concealment keys
+	logger          *zap.Logger
+
+	// Allocates SQNs from blocks reserved in UDR, nil to increment SQN in
+	// UDR for every vector
+	sqnAllocator *sqnAllocator
 }
 
 // NewAuthenticationService creates a new authentication service
@@ -25,13 +35,39 @@ func NewAuthenticationService(udrClient *client.UDRClient, logger *zap.Logger) *
 	}
 }
 
+// SetHomeNetworkKeys sets the home network private keys used to de-conceal
+// SUCIs. Without keys only null-scheme SUCIs are accepted.
+func (s *AuthenticationService) SetHomeNetworkKeys(keys []suci.HomeNetworkKey) {
+	s.homeNetworkKeys = keys
+}
+
+// SetSQNBlockSize makes the service reserve SQNs from UDR in blocks of size
+// and hand them out locally. A size of 0 or 1 increments SQN in UDR for
+// every authentication vector.
+func (s *AuthenticationService) SetSQNBlockSize(size uint64) {
+	if size <= 1 {
+		s.sqnAllocator = nil
+		return
+	}
+	s.sqnAllocator = newSQNAllocator(s.udrClient.ReserveSQNBlock, size)
+}
+
+// Resynchronisation errors
+var (
+	ErrInvalidResyncInfo = errors.New("invalid resynchronization info")
+	ErrResyncRejected    = errors.New("resynchronization rejected")
+)
+
+// ErrDeconcealment is returned when a SUCI cannot be de-concealed
+var ErrDeconcealment = errors.New("SUCI de-concealment failed")
+
 // AuthenticationInfo represents authentication information request
 type AuthenticationInfo struct {
-	SUPI                  string `json:"supi"`
+	SUPI                  string `json:"supi"` // SUPI or SUCI
 	ServingNetworkName    string `json:"servingNetworkName"`
 	ResynchronizationInfo *struct {
-		RAND []byte `json:"rand"`
-		AUTS []byte `json:"auts"`
+		RAND string `json:"rand"` // RAND of the failed challenge (hex)
+		AUTS string `json:"auts"` // AUTS returned by the UE (hex)
 	} `json:"resynchronizationInfo,omitempty"`
 }
 
@@ -39,18 +75,33 @@ type AuthenticationInfo struct {
 type AuthenticationInfoResult struct {
 	AuthType             string       `json:"authType"` // "5G_AKA" or "EAP_AKA_PRIME"
 	AuthenticationVector *AVType5GAKA `json:"authenticationVector,omitempty"`
+	SUPI                 string       `json:"supi,omitempty"` // Set when the request carried a SUCI
 }
 
-// AVType5GAKA represents a 5G AKA authentication vector
+// AVType5GAKA represents a 5G HE AKA authentication vector (TS 29.503
+// Av5GHeAka)
 type AVType5GAKA struct {
-	RAND  string `json:"rand"`  // Random challenge (hex)
-	AUTN  string `json:"autn"`  // Authentication token (hex)
-	HXRES string `json:"hxres"` // Expected response (hex)
-	KAUSF string `json:"kausf"` // Key for AUSF (hex)
+	RAND     string `json:"rand"`     // Random challenge (hex)
+	AUTN     string `json:"autn"`     // Authentication token (hex)
+	XRESStar string `json:"xresStar"` // Expected response XRES* (hex)
+	KAUSF    string `json:"kausf"`    // Key for AUSF (hex)
 }
 
 // GenerateAuthData generates authentication vectors for a UE
 func (s *AuthenticationService) GenerateAuthData(ctx context.Context, authInfo *AuthenticationInfo) (*AuthenticationInfoResult, error) {
+	// The UE presents a SUCI; subscription data is keyed by SUPI (TS 33.501
+	// 6.12.5)
+	var deconcealed string
+	if suci.IsSUCI(authInfo.SUPI) {
+		supi, err := suci.ToSUPI(authInfo.SUPI, s.homeNetworkKeys)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrDeconcealment, err)
+		}
+		s.logger.Debug("De-concealed SUCI", zap.String("suci", authInfo.SUPI), zap.String("supi", supi))
+		authInfo.SUPI = supi
+		deconcealed = supi
+	}
+
 	s.logger.Info("Generating authentication data",
 		zap.String("supi", authInfo.SUPI),
 		zap.String("serving_network", authInfo.ServingNetworkName),
@@ -95,8 +146,16 @@ func (s *AuthenticationService) GenerateAuthData(ctx context.Context, authInfo *
 		return nil, fmt.Errorf("failed to generate RAND: %w", err)
 	}
 
+	// On a synchronisation failure the UE sends AUTS; bring SQN_HE in line
+	// with SQN_MS before generating the fresh vector
+	if authInfo.ResynchronizationInfo != nil {
+		if err := s.resynchronize(ctx, authInfo, authSub.SQN, k, opc); err != nil {
+			return nil, err
+		}
+	}
+
 	// Get and increment SQN from UDR
-	sqnValue, err := s.udrClient.IncrementSQN(ctx, authInfo.SUPI)
+	sqnValue, err := s.nextSQN(ctx, authInfo.SUPI)
 	if err != nil {
 		return nil, fmt.Errorf("failed to increment SQN: %w", err)
 	}
@@ -119,16 +178,16 @@ func (s *AuthenticationService) GenerateAuthData(ctx context.Context, authInfo *
 		return nil, fmt.Errorf("failed to generate auth vector: %w", err)
 	}
 
-	// Derive KAUSF (for 5G)
-	// KAUSF = KDF(CK || IK, SN name, SQN ⊕ AK)
-	// Simplified version for now - in production, use proper KDF
-	kausf := make([]byte, 32)
-	copy(kausf[:16], av.CK)
-	copy(kausf[16:], av.IK)
-
-	// Compute HXRES* (hash of XRES for 5G)
-	// Simplified: In production, use SHA-256(RAND || XRES || serving network name)
-	hxres := av.XRES
+	// Derive KAUSF and XRES* (TS 33.501 A.2, A.4). SQN ⊕ AK is the first
+	// six bytes of AUTN.
+	kausf, err := kdf.KAUSF(av.CK, av.IK, authInfo.ServingNetworkName, av.AUTN[:6])
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive KAUSF: %w", err)
+	}
+	xresStar, err := kdf.XRESStar(av.CK, av.IK, authInfo.ServingNetworkName, av.RAND, av.XRES)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive XRES*: %w", err)
+	}
 
 	s.logger.Info("Generated authentication vector",
 		zap.String("supi", authInfo.SUPI),
@@ -138,17 +197,111 @@ func (s *AuthenticationService) GenerateAuthData(ctx context.Context, authInfo *
 	return &AuthenticationInfoResult{
 		AuthType: "5G_AKA",
 		AuthenticationVector: &AVType5GAKA{
-			RAND:  crypto.BytesToHex(av.RAND),
-			AUTN:  crypto.BytesToHex(av.AUTN),
-			HXRES: crypto.BytesToHex(hxres),
-			KAUSF: crypto.BytesToHex(kausf),
+			RAND:     crypto.BytesToHex(av.RAND),
+			AUTN:     crypto.BytesToHex(av.AUTN),
+			XRESStar: crypto.BytesToHex(xresStar),
+			KAUSF:    crypto.BytesToHex(kausf),
 		},
+		SUPI: deconcealed,
 	}, nil
 }
 
-// ConfirmAuth confirms authentication result
-func (s *AuthenticationService) ConfirmAuth(ctx context.Context, supi string, authEvent interface{}) error {
-	s.logger.Info("Confirming authentication", zap.String("supi", supi))
-	// In production, store authentication event in UDR
+// nextSQN returns the SQN of the next authentication vector
+func (s *AuthenticationService) nextSQN(ctx context.Context, supi string) (uint64, error) {
+	if s.sqnAllocator != nil {
+		return s.sqnAllocator.next(ctx, supi)
+	}
+	return s.udrClient.IncrementSQN(ctx, supi)
+}
+
+// resynchronize recovers SQN_MS from AUTS, verifies MAC-S and stores SQN_MS
+// in UDR so the next vector is fresh for the USIM (TS 33.102 6.3.5)
+func (s *AuthenticationService) resynchronize(ctx context.Context, authInfo *AuthenticationInfo, sqnHE uint64, k, opc []byte) error {
+	resync := authInfo.ResynchronizationInfo
+
+	randBytes, err := crypto.HexToBytes(resync.RAND)
+	if err != nil {
+		return fmt.Errorf("%w: RAND: %v", ErrInvalidResyncInfo, err)
+	}
+	auts, err := crypto.HexToBytes(resync.AUTS)
+	if err != nil {
+		return fmt.Errorf("%w: AUTS: %v", ErrInvalidResyncInfo, err)
+	}
+
+	sqnMSBytes, err := crypto.RecoverSQN(k, opc, randBytes, auts)
+	if errors.Is(err, milenage.ErrMACSMismatch) {
+		s.logger.Warn("Resynchronization rejected", zap.String("supi", authInfo.SUPI), zap.Error(err))
+		return fmt.Errorf("%w: %v", ErrResyncRejected, err)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidResyncInfo, err)
+	}
+
+	buf := make([]byte, 8)
+	copy(buf[2:], sqnMSBytes)
+	sqnMS := binary.BigEndian.Uint64(buf)
+
+	// The local block may lie behind SQN_MS; the next vector must come from
+	// a block reserved past it
+	if s.sqnAllocator != nil {
+		s.sqnAllocator.discard(authInfo.SUPI)
+	}
+
+	// SQN_HE already ahead of SQN_MS only needs the next vector
+	if sqnHE > sqnMS {
+		s.logger.Info("SQN_HE ahead of SQN_MS, not resetting",
+			zap.String("supi", authInfo.SUPI),
+			zap.Uint64("sqn_he", sqnHE),
+			zap.Uint64("sqn_ms", sqnMS),
+		)
+		return nil
+	}
+
+	if err := s.udrClient.SetSQN(ctx, authInfo.SUPI, sqnMS); err != nil {
+		return fmt.Errorf("failed to store resynchronized SQN: %w", err)
+	}
+
+	s.logger.Info("Resynchronized SQN",
+		zap.String("supi", authInfo.SUPI),
+		zap.Uint64("sqn_he", sqnHE),
+		zap.Uint64("sqn_ms", sqnMS),
+	)
+	return nil
+}
+
+// AuthEvent is the result of an authentication reported by the AUSF
+// (TS 29.503 AuthEvent)
+type AuthEvent struct {
+	NFInstanceID       string    `json:"nfInstanceId"`
+	Success            bool      `json:"success"`
+	TimeStamp          time.Time `json:"timeStamp"`
+	AuthType           string    `json:"authType"`
+	ServingNetworkName string    `json:"servingNetworkName"`
+	FailureReason      string    `json:"failureReason,omitempty"`
+}
+
+// ConfirmAuth records the result of an authentication in UDR for auditing
+func (s *AuthenticationService) ConfirmAuth(ctx context.Context, supi string, event *AuthEvent) error {
+	s.logger.Info("Confirming authentication",
+		zap.String("supi", supi),
+		zap.Bool("success", event.Success),
+		zap.String("nf_instance_id", event.NFInstanceID),
+	)
+
+	timestamp := event.TimeStamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	err := s.udrClient.RecordAuthEvent(ctx, supi, &client.AuthEvent{
+		Success:        event.Success,
+		AuthMethod:     event.AuthType,
+		ServingNetwork: event.ServingNetworkName,
+		Timestamp:      timestamp,
+		FailureReason:  event.FailureReason,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record auth event: %w", err)
+	}
 	return nil
 }
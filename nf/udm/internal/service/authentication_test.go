@@ -0,0 +1,390 @@
+package service
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/common/crypto/milenage"
+	"github.com/your-org/5g-network/common/crypto/suci"
+	"github.com/your-org/5g-network/nf/udm/internal/client"
+)
+
+// Subscriber credentials from TS 35.208 test set 1
+const (
+	testSUPI   = "imsi-001010000000001"
+	testSNName = "5G:mnc001.mcc001.3gppnetwork.org"
+	testK      = "465b5ce8b199b49faa5f0a2ee238a6bc"
+	testOPc    = "cd63cb71954a9f4e48a5994e37a02baf"
+)
+
+// fakeUDR serves one authentication subscription and tracks its SQN
+type fakeUDR struct {
+	mu           sync.Mutex
+	sqn          uint64
+	paths        []string // Request paths, to check the SUPI UDM looked up
+	reservations int      // SQN blocks reserved
+	authEvents   []client.AuthEvent
+}
+
+func (u *fakeUDR) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.paths = append(u.paths, r.URL.Path)
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/authentication-subscription"):
+		json.NewEncoder(w).Encode(client.AuthenticationSubscription{
+			SUPI:                          testSUPI,
+			AuthenticationMethod:          "5G_AKA",
+			PermanentKey:                  testK,
+			EncOPC:                        testOPc,
+			SQN:                           u.sqn,
+			AuthenticationManagementField: "8000",
+		})
+	case r.Method == http.MethodPatch && strings.HasSuffix(r.URL.Path, "/sqn"):
+		u.sqn++
+		json.NewEncoder(w).Encode(map[string]uint64{"sqn": u.sqn})
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/sqn-block"):
+		var body struct {
+			Size uint64 `json:"size"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		block := client.SQNBlock{First: u.sqn + 1, Last: u.sqn + body.Size}
+		u.sqn = block.Last
+		u.reservations++
+		json.NewEncoder(w).Encode(block)
+	case r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/sqn"):
+		var body struct {
+			SQN uint64 `json:"sqn"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		u.sqn = body.SQN
+		json.NewEncoder(w).Encode(map[string]uint64{"sqn": u.sqn})
+	case r.Method == http.MethodPut && strings.HasSuffix(r.URL.Path, "/authentication-status"):
+		var event client.AuthEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		u.authEvents = append(u.authEvents, event)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (u *fakeUDR) SQN() uint64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.sqn
+}
+
+func newTestAuthService(t *testing.T, sqnHE uint64) (*AuthenticationService, *fakeUDR) {
+	t.Helper()
+
+	udr := &fakeUDR{sqn: sqnHE}
+	srv := httptest.NewServer(udr)
+	t.Cleanup(srv.Close)
+
+	logger, _ := zap.NewDevelopment()
+	return NewAuthenticationService(client.NewUDRClient(srv.URL, time.Second, nil, logger), logger), udr
+}
+
+// usim simulates the USIM side of 5G AKA with a highest accepted SQN
+type usim struct {
+	m     *milenage.Milenage
+	sqnMS uint64
+}
+
+func newUSIM(t *testing.T, sqnMS uint64) *usim {
+	t.Helper()
+	k, _ := hex.DecodeString(testK)
+	opc, _ := hex.DecodeString(testOPc)
+	m, err := milenage.New(k, opc)
+	require.NoError(t, err)
+	return &usim{m: m, sqnMS: sqnMS}
+}
+
+// authenticate verifies MAC-A and SQN freshness. On a synchronisation
+// failure it returns the AUTS to send back to the network.
+func (u *usim) authenticate(t *testing.T, av *AVType5GAKA) (auts []byte, ok bool) {
+	t.Helper()
+
+	rand, err := hex.DecodeString(av.RAND)
+	require.NoError(t, err)
+	autn, err := hex.DecodeString(av.AUTN)
+	require.NoError(t, err)
+
+	_, _, _, ak, err := u.m.F2345(rand)
+	require.NoError(t, err)
+	sqn := make([]byte, milenage.SQNLen)
+	for i := range sqn {
+		sqn[i] = autn[i] ^ ak[i]
+	}
+
+	macA, _, err := u.m.F1(rand, sqn, autn[6:8])
+	require.NoError(t, err)
+	require.Equal(t, macA, autn[8:], "MAC-A must verify")
+
+	if sqnValue(sqn) <= u.sqnMS {
+		auts, err := u.m.GenerateAUTS(rand, sqnBytes(u.sqnMS))
+		require.NoError(t, err)
+		return auts, false
+	}
+	u.sqnMS = sqnValue(sqn)
+	return nil, true
+}
+
+func sqnValue(sqn []byte) uint64 {
+	buf := make([]byte, 8)
+	copy(buf[2:], sqn)
+	return binary.BigEndian.Uint64(buf)
+}
+
+func sqnBytes(v uint64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, v)
+	return buf[2:]
+}
+
+func resyncInfo(rand string, auts []byte) *AuthenticationInfo {
+	info := &AuthenticationInfo{SUPI: testSUPI, ServingNetworkName: testSNName}
+	info.ResynchronizationInfo = &struct {
+		RAND string `json:"rand"`
+		AUTS string `json:"auts"`
+	}{RAND: rand, AUTS: hex.EncodeToString(auts)}
+	return info
+}
+
+func TestGenerateAuthData_ResynchronizesAfterSQNFailure(t *testing.T) {
+	svc, udr := newTestAuthService(t, 5)
+	ue := newUSIM(t, 100)
+	ctx := context.Background()
+
+	// First attempt: SQN_HE is behind the USIM, so the challenge is stale
+	first, err := svc.GenerateAuthData(ctx, &AuthenticationInfo{SUPI: testSUPI, ServingNetworkName: testSNName})
+	require.NoError(t, err)
+	auts, ok := ue.authenticate(t, first.AuthenticationVector)
+	require.False(t, ok)
+	require.Len(t, auts, milenage.AUTSLen)
+
+	// Second attempt carries the resynchronisation info
+	second, err := svc.GenerateAuthData(ctx, resyncInfo(first.AuthenticationVector.RAND, auts))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(101), udr.SQN())
+
+	_, ok = ue.authenticate(t, second.AuthenticationVector)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(101), ue.sqnMS)
+}
+
+func TestGenerateAuthData_ResyncRejectsBadMACS(t *testing.T) {
+	svc, udr := newTestAuthService(t, 5)
+	ue := newUSIM(t, 100)
+	ctx := context.Background()
+
+	first, err := svc.GenerateAuthData(ctx, &AuthenticationInfo{SUPI: testSUPI, ServingNetworkName: testSNName})
+	require.NoError(t, err)
+	auts, ok := ue.authenticate(t, first.AuthenticationVector)
+	require.False(t, ok)
+
+	auts[len(auts)-1] ^= 0x01
+	_, err = svc.GenerateAuthData(ctx, resyncInfo(first.AuthenticationVector.RAND, auts))
+	assert.ErrorIs(t, err, ErrResyncRejected)
+	assert.Equal(t, uint64(6), udr.SQN())
+}
+
+func TestGenerateAuthData_ResyncInvalidInfo(t *testing.T) {
+	svc, _ := newTestAuthService(t, 5)
+
+	_, err := svc.GenerateAuthData(context.Background(), resyncInfo("not-hex", make([]byte, milenage.AUTSLen)))
+	assert.ErrorIs(t, err, ErrInvalidResyncInfo)
+
+	_, err = svc.GenerateAuthData(context.Background(), resyncInfo(strings.Repeat("00", 16), make([]byte, 4)))
+	assert.ErrorIs(t, err, ErrInvalidResyncInfo)
+}
+
+func TestGenerateAuthData_ResyncKeepsSQNHEAhead(t *testing.T) {
+	svc, udr := newTestAuthService(t, 200)
+	ue := newUSIM(t, 100)
+
+	rand := strings.Repeat("11", 16)
+	randBytes, _ := hex.DecodeString(rand)
+	auts, err := ue.m.GenerateAUTS(randBytes, sqnBytes(ue.sqnMS))
+	require.NoError(t, err)
+
+	resp, err := svc.GenerateAuthData(context.Background(), resyncInfo(rand, auts))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(201), udr.SQN())
+
+	_, ok := ue.authenticate(t, resp.AuthenticationVector)
+	assert.True(t, ok)
+}
+
+// SUCI of SUPI imsi-20893001002086 from TS 33.501 Annex C.4.3 (profile A)
+const (
+	profileAPrivateKey = "c53c22208b61860b06c62e5406a7b330c2b577aa5558981510d128247d38bd1d"
+	profileASUCI       = "suci-0-208-93-0-1-1-b2e92f836055a255837debf850b528997ce0201cb82adfe4be1f587d07d8457dcb02352410cddd9e730ef3fa87"
+)
+
+func TestGenerateAuthData_DeconcealsProfileASUCI(t *testing.T) {
+	svc, udr := newTestAuthService(t, 0)
+	privateKey, _ := hex.DecodeString(profileAPrivateKey)
+	svc.SetHomeNetworkKeys([]suci.HomeNetworkKey{{ID: 1, Scheme: suci.SchemeProfileA, PrivateKey: privateKey}})
+
+	result, err := svc.GenerateAuthData(context.Background(), &AuthenticationInfo{SUPI: profileASUCI, ServingNetworkName: testSNName})
+	require.NoError(t, err)
+	assert.Equal(t, "imsi-20893001002086", result.SUPI)
+	require.NotEmpty(t, udr.paths)
+	assert.Contains(t, udr.paths[0], "/imsi-20893001002086/")
+}
+
+func TestGenerateAuthData_NullSchemeSUCI(t *testing.T) {
+	svc, udr := newTestAuthService(t, 0)
+
+	result, err := svc.GenerateAuthData(context.Background(), &AuthenticationInfo{
+		SUPI:               "suci-0-001-01-0000-0-0-0000000001",
+		ServingNetworkName: testSNName,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, testSUPI, result.SUPI)
+	assert.Contains(t, udr.paths[0], "/"+testSUPI+"/")
+}
+
+func TestGenerateAuthData_SUCIWithoutKey(t *testing.T) {
+	svc, udr := newTestAuthService(t, 0)
+
+	_, err := svc.GenerateAuthData(context.Background(), &AuthenticationInfo{SUPI: profileASUCI, ServingNetworkName: testSNName})
+	assert.ErrorIs(t, err, ErrDeconcealment)
+	assert.ErrorIs(t, err, suci.ErrUnknownKey)
+	assert.Empty(t, udr.paths)
+}
+
+func TestGenerateAuthData_PlainSUPIHasNoResultSUPI(t *testing.T) {
+	svc, _ := newTestAuthService(t, 0)
+
+	result, err := svc.GenerateAuthData(context.Background(), &AuthenticationInfo{SUPI: testSUPI, ServingNetworkName: testSNName})
+	require.NoError(t, err)
+	assert.Empty(t, result.SUPI)
+}
+
+func TestGenerateAuthData_SQNBlocksNotReusedAcrossRefills(t *testing.T) {
+	svc, udr := newTestAuthService(t, 10)
+	svc.SetSQNBlockSize(3)
+	ue := newUSIM(t, 10)
+	ctx := context.Background()
+
+	for i := 0; i < 7; i++ {
+		resp, err := svc.GenerateAuthData(ctx, &AuthenticationInfo{SUPI: testSUPI, ServingNetworkName: testSNName})
+		require.NoError(t, err)
+		_, ok := ue.authenticate(t, resp.AuthenticationVector)
+		require.True(t, ok, "vector %d must carry a fresh SQN", i)
+		assert.Equal(t, uint64(11+i), ue.sqnMS)
+	}
+
+	// Seven vectors take three blocks; UDR holds the last reserved ceiling
+	udr.mu.Lock()
+	defer udr.mu.Unlock()
+	assert.Equal(t, 3, udr.reservations)
+	assert.Equal(t, uint64(19), udr.sqn)
+}
+
+func TestSQNAllocator_ConcurrentNoReuse(t *testing.T) {
+	var mu sync.Mutex
+	var ceiling uint64
+	reserve := func(ctx context.Context, supi string, size uint64) (*client.SQNBlock, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		block := &client.SQNBlock{First: ceiling + 1, Last: ceiling + size}
+		ceiling = block.Last
+		return block, nil
+	}
+	allocator := newSQNAllocator(reserve, 4)
+
+	const workers = 50
+	results := make(chan uint64, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sqn, err := allocator.next(context.Background(), testSUPI)
+			assert.NoError(t, err)
+			results <- sqn
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	seen := make(map[uint64]bool)
+	for sqn := range results {
+		assert.False(t, seen[sqn], "SQN %d returned twice", sqn)
+		seen[sqn] = true
+	}
+	assert.Len(t, seen, workers)
+	assert.Equal(t, uint64(52), ceiling, "13 blocks of 4")
+}
+
+func TestGenerateAuthData_ResyncDiscardsSQNBlock(t *testing.T) {
+	svc, udr := newTestAuthService(t, 5)
+	svc.SetSQNBlockSize(10)
+	ue := newUSIM(t, 100)
+	ctx := context.Background()
+
+	first, err := svc.GenerateAuthData(ctx, &AuthenticationInfo{SUPI: testSUPI, ServingNetworkName: testSNName})
+	require.NoError(t, err)
+	auts, ok := ue.authenticate(t, first.AuthenticationVector)
+	require.False(t, ok)
+
+	// The rest of the block [6, 15] is behind SQN_MS and must not be used
+	second, err := svc.GenerateAuthData(ctx, resyncInfo(first.AuthenticationVector.RAND, auts))
+	require.NoError(t, err)
+	_, ok = ue.authenticate(t, second.AuthenticationVector)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(101), ue.sqnMS)
+	assert.Equal(t, uint64(110), udr.SQN())
+}
+
+func TestConfirmAuth_RecordsEventInUDR(t *testing.T) {
+	svc, udr := newTestAuthService(t, 0)
+	timeStamp := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	require.NoError(t, svc.ConfirmAuth(context.Background(), testSUPI, &AuthEvent{
+		NFInstanceID:       "ausf-1",
+		Success:            false,
+		TimeStamp:          timeStamp,
+		AuthType:           "5G_AKA",
+		ServingNetworkName: testSNName,
+		FailureReason:      "RES* mismatch",
+	}))
+
+	udr.mu.Lock()
+	defer udr.mu.Unlock()
+	assert.Contains(t, udr.paths, "/nudr-dr/v1/subscription-data/"+testSUPI+"/authentication-data/authentication-status")
+	require.Len(t, udr.authEvents, 1)
+	event := udr.authEvents[0]
+	assert.False(t, event.Success)
+	assert.Equal(t, "5G_AKA", event.AuthMethod)
+	assert.Equal(t, testSNName, event.ServingNetwork)
+	assert.True(t, timeStamp.Equal(event.Timestamp))
+	assert.Equal(t, "RES* mismatch", event.FailureReason)
+}
@@ -1,17 +1,43 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"path"
+	"time"
 
+	"github.com/your-org/5g-network/common/sbi"
 	"github.com/your-org/5g-network/nf/udm/internal/client"
 	"go.uber.org/zap"
 )
 
+// DataChangeNotifyPath is the UDM callback the UDR notifies of subscription
+// data changes
+const DataChangeNotifyPath = "/nudm-sdm-callback/v1/data-change-notify"
+
+// notificationTimeout bounds the delivery of one ModificationNotification
+const notificationTimeout = 5 * time.Second
+
+var (
+	// ErrSubscriptionNotFound is returned for unknown SDM subscriptions
+	ErrSubscriptionNotFound = errors.New("SDM subscription not found")
+	// ErrNotificationsDisabled is returned by subscriptions made before
+	// EnableNotifications
+	ErrNotificationsDisabled = errors.New("data change notifications are not enabled")
+)
+
 // SDMService handles Subscriber Data Management (Nudm_SDM)
 type SDMService struct {
 	udrClient *client.UDRClient
 	logger    *zap.Logger
+
+	// Data change notifications, enabled by EnableNotifications
+	apiRoot      string
+	notifyClient *http.Client
 }
 
 // NewSDMService creates a new SDM service
@@ -175,8 +201,8 @@ func (s *SDMService) GetSMData(ctx context.Context, supi string, plmnID *client.
 			AllowedSscModes: []string{"SSC_MODE_1", "SSC_MODE_2", "SSC_MODE_3"},
 		},
 		SessionAMBR: &AMBR{
-			Uplink:   fmt.Sprintf("%d", subData.SubscribedUeAmbrUplink),
-			Downlink: fmt.Sprintf("%d", subData.SubscribedUeAmbrDownlink),
+			Uplink:   sbi.FormatBitRate(subData.SubscribedUeAmbrUplink),
+			Downlink: sbi.FormatBitRate(subData.SubscribedUeAmbrDownlink),
 		},
 		Var5gQosProfile: &Var5gQosProfile{
 			Var5qi:        9, // Default 5QI for internet
@@ -192,14 +218,17 @@ func (s *SDMService) GetSMData(ctx context.Context, supi string, plmnID *client.
 	// Override with UDR data if available
 	if smData != nil {
 		if smData.SessionAmbrUplink > 0 {
-			dnnConfig.SessionAMBR.Uplink = fmt.Sprintf("%d", smData.SessionAmbrUplink)
+			dnnConfig.SessionAMBR.Uplink = sbi.FormatBitRate(smData.SessionAmbrUplink)
 		}
 		if smData.SessionAmbrDownlink > 0 {
-			dnnConfig.SessionAMBR.Downlink = fmt.Sprintf("%d", smData.SessionAmbrDownlink)
+			dnnConfig.SessionAMBR.Downlink = sbi.FormatBitRate(smData.SessionAmbrDownlink)
 		}
 		if smData.Default5QI > 0 {
 			dnnConfig.Var5gQosProfile.Var5qi = int(smData.Default5QI)
 		}
+		if smData.ARPPriorityLevel > 0 {
+			dnnConfig.Var5gQosProfile.ARP.PriorityLevel = int(smData.ARPPriorityLevel)
+		}
 		if smData.DefaultPDUSessionType != "" {
 			dnnConfig.PduSessionTypes.DefaultSessionType = smData.DefaultPDUSessionType
 		}
@@ -218,17 +247,44 @@ func (s *SDMService) GetSMData(ctx context.Context, supi string, plmnID *client.
 	return smSubData, nil
 }
 
-// SubscribeToDataChanges subscribes to data change notifications
-func (s *SDMService) SubscribeToDataChanges(ctx context.Context, supi string, callbackURI string) (string, error) {
+// ModificationNotification is POSTed to the callbackReference of SDM
+// subscriptions when the data they monitor changes (TS 29.503)
+type ModificationNotification struct {
+	NotifyItems []client.NotifyItem `json:"notifyItems"`
+}
+
+// EnableNotifications lets consumers subscribe to data changes. apiRoot is
+// the URL the UDR reaches this UDM at, transport carries the notifications to
+// the consumers.
+func (s *SDMService) EnableNotifications(apiRoot string, transport http.RoundTripper) {
+	s.apiRoot = apiRoot
+	s.notifyClient = &http.Client{Timeout: notificationTimeout, Transport: transport}
+}
+
+// SubscribeToDataChanges subscribes to data change notifications. The
+// subscription is stored in the UDR, which notifies this UDM of changes to
+// relay to callbackURI.
+func (s *SDMService) SubscribeToDataChanges(ctx context.Context, supi string, callbackURI string, monitoredResourceURIs []string) (string, error) {
 	s.logger.Info("Creating SDM subscription",
 		zap.String("supi", supi),
 		zap.String("callback_uri", callbackURI),
 	)
 
-	// In production, create subscription in UDR
-	subscriptionID := fmt.Sprintf("sdm-sub-%s", supi)
+	if s.notifyClient == nil {
+		return "", ErrNotificationsDisabled
+	}
+
+	subscription, err := s.udrClient.CreateSDMSubscription(ctx, &client.SDMSubscription{
+		UEID:                  supi,
+		CallbackURI:           s.apiRoot + DataChangeNotifyPath,
+		OriginalCallbackURI:   callbackURI,
+		MonitoredResourceURIs: monitoredResourceURIs,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to store SDM subscription: %w", err)
+	}
 
-	return subscriptionID, nil
+	return subscription.SubscriptionID, nil
 }
 
 // UnsubscribeFromDataChanges unsubscribes from data change notifications
@@ -237,6 +293,68 @@ func (s *SDMService) UnsubscribeFromDataChanges(ctx context.Context, subscriptio
 		zap.String("subscription_id", subscriptionID),
 	)
 
-	// In production, delete subscription from UDR
+	if err := s.udrClient.DeleteSDMSubscription(ctx, subscriptionID); err != nil {
+		if errors.Is(err, client.ErrNotFound) {
+			return fmt.Errorf("%w: %s", ErrSubscriptionNotFound, subscriptionID)
+		}
+		return fmt.Errorf("failed to delete SDM subscription: %w", err)
+	}
+	return nil
+}
+
+// NotifyDataChange relays a UDR data change notification to the consumers
+// that subscribed, naming the changed resources by their Nudm_SDM URI.
+// Delivery failures are logged.
+func (s *SDMService) NotifyDataChange(ctx context.Context, notify *client.DataChangeNotify) {
+	notification := &ModificationNotification{NotifyItems: make([]client.NotifyItem, 0, len(notify.NotifyItems))}
+	for _, item := range notify.NotifyItems {
+		item.ResourceID = fmt.Sprintf("%s/nudm-sdm/v1/supi/%s/%s", s.apiRoot, notify.UEID, path.Base(item.ResourceID))
+		notification.NotifyItems = append(notification.NotifyItems, item)
+	}
+
+	body, err := json.Marshal(notification)
+	if err != nil {
+		s.logger.Error("Failed to encode modification notification", zap.Error(err))
+		return
+	}
+
+	for _, callbackURI := range notify.OriginalCallbackReference {
+		if err := s.postNotification(ctx, callbackURI, body); err != nil {
+			s.logger.Error("Modification notification failed",
+				zap.String("supi", notify.UEID),
+				zap.String("callback_uri", callbackURI),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		s.logger.Debug("Modification notification delivered",
+			zap.String("supi", notify.UEID),
+			zap.String("callback_uri", callbackURI),
+		)
+	}
+}
+
+// postNotification POSTs a notification to a consumer callback
+func (s *SDMService) postNotification(ctx context.Context, callbackURI string, body []byte) error {
+	if s.notifyClient == nil {
+		return ErrNotificationsDisabled
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURI, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid callback URI: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.notifyClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("callback returned status %d", resp.StatusCode)
+	}
 	return nil
 }
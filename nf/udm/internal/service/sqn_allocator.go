@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/your-org/5g-network/nf/udm/internal/client"
+)
+
+// sqnReserver reserves blocks of sequence numbers in UDR
+type sqnReserver func(ctx context.Context, supi string, size uint64) (*client.SQNBlock, error)
+
+// sqnAllocator hands out sequence numbers from blocks reserved in UDR, so
+// only the block's ceiling is persisted instead of every SQN. Numbers left
+// in a block when the UDM stops are skipped, never reused.
+type sqnAllocator struct {
+	reserve   sqnReserver
+	blockSize uint64
+
+	mu     sync.Mutex
+	blocks map[string]*sqnRange // SUPI -> unused part of its block
+}
+
+// sqnRange is the unused part of a reserved block, next to last inclusive
+type sqnRange struct {
+	mu   sync.Mutex
+	next uint64
+	last uint64
+}
+
+func newSQNAllocator(reserve sqnReserver, blockSize uint64) *sqnAllocator {
+	return &sqnAllocator{
+		reserve:   reserve,
+		blockSize: blockSize,
+		blocks:    make(map[string]*sqnRange),
+	}
+}
+
+// next returns the next SQN of the subscriber, reserving a new block once
+// the current one is used up
+func (a *sqnAllocator) next(ctx context.Context, supi string) (uint64, error) {
+	r := a.rangeOf(supi)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.next == 0 || r.next > r.last {
+		block, err := a.reserve(ctx, supi, a.blockSize)
+		if err != nil {
+			return 0, err
+		}
+		if block.First == 0 || block.First > block.Last {
+			return 0, fmt.Errorf("UDR reserved an empty SQN block %d-%d", block.First, block.Last)
+		}
+		r.next, r.last = block.First, block.Last
+	}
+
+	sqn := r.next
+	r.next++
+	return sqn, nil
+}
+
+// discard drops the unused part of the subscriber's block, e.g. after a
+// resynchronisation moved SQN_HE past it
+func (a *sqnAllocator) discard(supi string) {
+	r := a.rangeOf(supi)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.next, r.last = 0, 0
+}
+
+func (a *sqnAllocator) rangeOf(supi string) *sqnRange {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	r, ok := a.blocks[supi]
+	if !ok {
+		r = &sqnRange{}
+		a.blocks[supi] = r
+	}
+	return r
+}
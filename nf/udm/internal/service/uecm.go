@@ -2,91 +2,76 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
 
+	"github.com/your-org/5g-network/nf/udm/internal/client"
 	"go.uber.org/zap"
 )
 
-// UECMService handles UE Context Management (Nudm_UECM)
+var (
+	// ErrRegistrationNotFound is returned when a SUPI has no AMF registration
+	ErrRegistrationNotFound = errors.New("AMF registration not found")
+	// ErrInvalidRegistration is returned for registrations or updates that
+	// cannot be applied
+	ErrInvalidRegistration = errors.New("invalid AMF registration")
+)
+
+// UECMService handles UE Context Management (Nudm_UECM). AMF registrations
+// are stored in the UDR, so they survive restarts and are shared by all UDM
+// instances; contexts only caches what this instance has seen.
 type UECMService struct {
-	contexts map[string]*UEContext // supi -> UE context
-	mu       sync.RWMutex
-	logger   *zap.Logger
+	udrClient *client.UDRClient
+	contexts  map[string]*UEContext // supi -> UE context
+	mu        sync.RWMutex
+	logger    *zap.Logger
 }
 
 // NewUECMService creates a new UECM service
-func NewUECMService(logger *zap.Logger) *UECMService {
+func NewUECMService(udrClient *client.UDRClient, logger *zap.Logger) *UECMService {
 	return &UECMService{
-		contexts: make(map[string]*UEContext),
-		logger:   logger,
+		udrClient: udrClient,
+		contexts:  make(map[string]*UEContext),
+		logger:    logger,
 	}
 }
 
 // UEContext represents UE context information
 type UEContext struct {
-	SUPI               string    `json:"supi"`
-	AMFInstanceID      string    `json:"amfInstanceId,omitempty"`
-	GUAMI              *GUAMI    `json:"guami,omitempty"`
-	PEI                string    `json:"pei,omitempty"` // Permanent Equipment Identifier
-	UDMGroupID         string    `json:"udmGroupId,omitempty"`
-	RoutingIndicator   string    `json:"routingIndicator,omitempty"`
-	RegistrationTime   time.Time `json:"registrationTime,omitempty"`
-	DeregistrationTime time.Time `json:"deregistrationTime,omitempty"`
-	PurgeFlag          bool      `json:"purgeFlag,omitempty"`
-	IratChangeAllowed  bool      `json:"iratChangeAllowed,omitempty"`
-}
-
-// GUAMI represents Globally Unique AMF Identifier
-type GUAMI struct {
-	PlmnID      PlmnID `json:"plmnId"`
-	AMFRegionID string `json:"amfRegionId"`
-	AMFSetID    string `json:"amfSetId"`
-	AMFPointer  string `json:"amfPointer"`
-}
-
-// PlmnID represents PLMN identifier
-type PlmnID struct {
-	MCC string `json:"mcc"`
-	MNC string `json:"mnc"`
-}
-
-// AMF3GPPAccessRegistration represents AMF registration for 3GPP access
-type AMF3GPPAccessRegistration struct {
-	AMFInstanceID          string        `json:"amfInstanceId"`
-	DeregestrationReason   string        `json:"deregCallbackUri,omitempty"`
-	GUAMI                  *GUAMI        `json:"guami,omitempty"`
-	RATType                string        `json:"ratType"` // NR, EUTRA
-	InitialRegistrationInd bool          `json:"initialRegistrationInd,omitempty"`
-	BackupAMFInfo          []interface{} `json:"backupAmfInfo,omitempty"`
+	SUPI               string        `json:"supi"`
+	AMFInstanceID      string        `json:"amfInstanceId,omitempty"`
+	GUAMI              *client.GUAMI `json:"guami,omitempty"`
+	PEI                string        `json:"pei,omitempty"` // Permanent Equipment Identifier
+	UDMGroupID         string        `json:"udmGroupId,omitempty"`
+	RoutingIndicator   string        `json:"routingIndicator,omitempty"`
+	RegistrationTime   time.Time     `json:"registrationTime,omitempty"`
+	DeregistrationTime time.Time     `json:"deregistrationTime,omitempty"`
+	PurgeFlag          bool          `json:"purgeFlag,omitempty"`
+	IratChangeAllowed  bool          `json:"iratChangeAllowed,omitempty"`
 }
 
 // RegisterAMF3GPPAccess registers AMF context for 3GPP access
-func (s *UECMService) RegisterAMF3GPPAccess(ctx context.Context, supi string, registration *AMF3GPPAccessRegistration) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
+func (s *UECMService) RegisterAMF3GPPAccess(ctx context.Context, supi string, registration *client.AMF3GPPAccessRegistration) error {
 	s.logger.Info("Registering AMF context",
 		zap.String("supi", supi),
 		zap.String("amf_instance_id", registration.AMFInstanceID),
 		zap.String("rat_type", registration.RATType),
 	)
 
-	// Create or update UE context
-	ueContext, exists := s.contexts[supi]
-	if !exists {
-		ueContext = &UEContext{
-			SUPI: supi,
-		}
-		s.contexts[supi] = ueContext
+	if registration.AMFInstanceID == "" {
+		return fmt.Errorf("%w: amfInstanceId is required", ErrInvalidRegistration)
 	}
 
-	// Update context with AMF information
-	ueContext.AMFInstanceID = registration.AMFInstanceID
-	ueContext.GUAMI = registration.GUAMI
-	ueContext.RegistrationTime = time.Now()
-	ueContext.PurgeFlag = false
+	registration.RegistrationTime = time.Now()
+	registration.PurgeFlag = false
+
+	if err := s.udrClient.PutAMF3GPPRegistration(ctx, supi, registration); err != nil {
+		return fmt.Errorf("failed to store AMF registration: %w", err)
+	}
+	s.cache(supi, registration)
 
 	s.logger.Info("AMF context registered",
 		zap.String("supi", supi),
@@ -96,22 +81,48 @@ func (s *UECMService) RegisterAMF3GPPAccess(ctx context.Context, supi string, re
 	return nil
 }
 
-// UpdateAMF3GPPAccess updates AMF context
+// UpdateAMF3GPPAccess updates AMF context. The updates replace the top-level
+// attributes of the stored registration; the serving AMF cannot be changed,
+// that takes a new registration.
 func (s *UECMService) UpdateAMF3GPPAccess(ctx context.Context, supi string, updates map[string]interface{}) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	s.logger.Info("Updating AMF context",
 		zap.String("supi", supi),
 	)
 
-	_, exists := s.contexts[supi]
-	if !exists {
-		return fmt.Errorf("UE context not found for SUPI: %s", supi)
+	registration, err := s.Get3GPPRegistration(ctx, supi)
+	if err != nil {
+		return err
+	}
+
+	if id, ok := updates["amfInstanceId"]; ok && id != registration.AMFInstanceID {
+		return fmt.Errorf("%w: amfInstanceId cannot be changed", ErrInvalidRegistration)
+	}
+
+	// Merge the updates over the JSON form of the stored registration
+	encoded, err := json.Marshal(registration)
+	if err != nil {
+		return fmt.Errorf("failed to encode AMF registration: %w", err)
+	}
+	var merged map[string]interface{}
+	if err := json.Unmarshal(encoded, &merged); err != nil {
+		return fmt.Errorf("failed to decode AMF registration: %w", err)
+	}
+	for name, value := range updates {
+		merged[name] = value
+	}
+	encoded, err = json.Marshal(merged)
+	if err != nil {
+		return fmt.Errorf("failed to encode AMF registration: %w", err)
+	}
+	var updated client.AMF3GPPAccessRegistration
+	if err := json.Unmarshal(encoded, &updated); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRegistration, err)
 	}
 
-	// Update context fields
-	// In production, parse and apply updates from the map
+	if err := s.udrClient.PutAMF3GPPRegistration(ctx, supi, &updated); err != nil {
+		return fmt.Errorf("failed to store AMF registration: %w", err)
+	}
+	s.cache(supi, &updated)
 
 	s.logger.Debug("AMF context updated",
 		zap.String("supi", supi),
@@ -122,24 +133,24 @@ func (s *UECMService) UpdateAMF3GPPAccess(ctx context.Context, supi string, upda
 
 // DeregisterAMF3GPPAccess deregisters AMF context
 func (s *UECMService) DeregisterAMF3GPPAccess(ctx context.Context, supi string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	s.logger.Info("Deregistering AMF context",
 		zap.String("supi", supi),
 	)
 
-	ueContext, exists := s.contexts[supi]
-	if !exists {
-		return fmt.Errorf("UE context not found for SUPI: %s", supi)
+	if err := s.udrClient.DeleteAMF3GPPRegistration(ctx, supi); err != nil {
+		if errors.Is(err, client.ErrNotFound) {
+			return fmt.Errorf("%w for SUPI: %s", ErrRegistrationNotFound, supi)
+		}
+		return fmt.Errorf("failed to delete AMF registration: %w", err)
 	}
 
 	// Mark as deregistered
-	ueContext.DeregistrationTime = time.Now()
-	ueContext.AMFInstanceID = ""
-
-	// Optionally delete the context
-	// delete(s.contexts, supi)
+	s.mu.Lock()
+	if ueContext, exists := s.contexts[supi]; exists {
+		ueContext.DeregistrationTime = time.Now()
+		ueContext.AMFInstanceID = ""
+	}
+	s.mu.Unlock()
 
 	s.logger.Info("AMF context deregistered",
 		zap.String("supi", supi),
@@ -149,34 +160,34 @@ func (s *UECMService) DeregisterAMF3GPPAccess(ctx context.Context, supi string)
 }
 
 // Get3GPPRegistration retrieves AMF registration information
-func (s *UECMService) Get3GPPRegistration(ctx context.Context, supi string) (*AMF3GPPAccessRegistration, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
+func (s *UECMService) Get3GPPRegistration(ctx context.Context, supi string) (*client.AMF3GPPAccessRegistration, error) {
 	s.logger.Debug("Getting AMF registration",
 		zap.String("supi", supi),
 	)
 
-	ueContext, exists := s.contexts[supi]
-	if !exists {
-		return nil, fmt.Errorf("UE context not found for SUPI: %s", supi)
-	}
-
-	if ueContext.AMFInstanceID == "" {
-		return nil, fmt.Errorf("no AMF registration found for SUPI: %s", supi)
-	}
-
-	registration := &AMF3GPPAccessRegistration{
-		AMFInstanceID: ueContext.AMFInstanceID,
-		GUAMI:         ueContext.GUAMI,
-		RATType:       "NR",
+	registration, err := s.udrClient.GetAMF3GPPRegistration(ctx, supi)
+	if err != nil {
+		if errors.Is(err, client.ErrNotFound) {
+			return nil, fmt.Errorf("%w for SUPI: %s", ErrRegistrationNotFound, supi)
+		}
+		return nil, fmt.Errorf("failed to get AMF registration: %w", err)
 	}
 
 	return registration, nil
 }
 
-// GetUEContext retrieves UE context
+// GetUEContext retrieves UE context, built from the stored AMF registration
+// when there is one
 func (s *UECMService) GetUEContext(ctx context.Context, supi string) (*UEContext, error) {
+	registration, err := s.Get3GPPRegistration(ctx, supi)
+	if err == nil {
+		return s.cache(supi, registration), nil
+	}
+	if !errors.Is(err, ErrRegistrationNotFound) {
+		return nil, err
+	}
+
+	// Deregistered UEs are only known to the instance that saw them
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -185,10 +196,35 @@ func (s *UECMService) GetUEContext(ctx context.Context, supi string) (*UEContext
 		return nil, fmt.Errorf("UE context not found for SUPI: %s", supi)
 	}
 
-	return ueContext, nil
+	copied := *ueContext
+	return &copied, nil
+}
+
+// cache records a registration in the UE context of a SUPI and returns a
+// copy of the context
+func (s *UECMService) cache(supi string, registration *client.AMF3GPPAccessRegistration) *UEContext {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ueContext, exists := s.contexts[supi]
+	if !exists {
+		ueContext = &UEContext{
+			SUPI: supi,
+		}
+		s.contexts[supi] = ueContext
+	}
+
+	ueContext.AMFInstanceID = registration.AMFInstanceID
+	ueContext.GUAMI = registration.GUAMI
+	ueContext.PEI = registration.PEI
+	ueContext.RegistrationTime = registration.RegistrationTime
+	ueContext.PurgeFlag = registration.PurgeFlag
+
+	copied := *ueContext
+	return &copied
 }
 
-// GetStats returns UECM statistics
+// GetStats returns UECM statistics for the contexts seen by this instance
 func (s *UECMService) GetStats() map[string]interface{} {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
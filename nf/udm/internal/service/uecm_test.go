@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/nf/udm/internal/client"
+)
+
+// registrationUDR stores AMF registrations by SUPI, as the UDR context data
+// endpoints do
+type registrationUDR struct {
+	mu            sync.Mutex
+	registrations map[string]json.RawMessage
+}
+
+func (u *registrationUDR) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	supi := strings.TrimPrefix(r.URL.Path, "/nudr-dr/v1/subscription-data/")
+	supi = strings.TrimSuffix(supi, "/context-data/amf-3gpp-access")
+
+	switch r.Method {
+	case http.MethodGet:
+		registration, ok := u.registrations[supi]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(registration)
+	case http.MethodPut:
+		var registration json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&registration); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		u.registrations[supi] = registration
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		if _, ok := u.registrations[supi]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(u.registrations, supi)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// newUECMInstance returns a UDM UECM service backed by the given UDR, a new
+// one standing for a restarted UDM or another UDM instance
+func newUECMInstance(udrURL string) *UECMService {
+	return NewUECMService(client.NewUDRClient(udrURL, 5*time.Second, nil, zap.NewNop()), zap.NewNop())
+}
+
+func TestUECMRegistrationSurvivesRestart(t *testing.T) {
+	udr := httptest.NewServer(&registrationUDR{registrations: make(map[string]json.RawMessage)})
+	defer udr.Close()
+	ctx := context.Background()
+
+	udm := newUECMInstance(udr.URL)
+	require.NoError(t, udm.RegisterAMF3GPPAccess(ctx, testSUPI, &client.AMF3GPPAccessRegistration{
+		AMFInstanceID:    "amf-1",
+		DeregCallbackURI: "http://amf-1/namf-callback/v1/dereg",
+		GUAMI: &client.GUAMI{
+			PlmnID:      client.PlmnID{MCC: "001", MNC: "01"},
+			AMFRegionID: "01",
+			AMFSetID:    "001",
+			AMFPointer:  "01",
+		},
+		RATType: "NR",
+	}))
+
+	// The registration outlives the UDM that stored it
+	udm = newUECMInstance(udr.URL)
+	registration, err := udm.Get3GPPRegistration(ctx, testSUPI)
+	require.NoError(t, err)
+	assert.Equal(t, "amf-1", registration.AMFInstanceID)
+	assert.Equal(t, "001", registration.GUAMI.PlmnID.MCC)
+	assert.False(t, registration.RegistrationTime.IsZero())
+
+	ueContext, err := udm.GetUEContext(ctx, testSUPI)
+	require.NoError(t, err)
+	assert.Equal(t, "amf-1", ueContext.AMFInstanceID)
+
+	// Updates apply to the stored registration
+	require.NoError(t, udm.UpdateAMF3GPPAccess(ctx, testSUPI, map[string]interface{}{"pei": "imeisv-4370816125816151", "purgeFlag": true}))
+	udm = newUECMInstance(udr.URL)
+	registration, err = udm.Get3GPPRegistration(ctx, testSUPI)
+	require.NoError(t, err)
+	assert.Equal(t, "imeisv-4370816125816151", registration.PEI)
+	assert.True(t, registration.PurgeFlag)
+	assert.Equal(t, "http://amf-1/namf-callback/v1/dereg", registration.DeregCallbackURI)
+
+	err = udm.UpdateAMF3GPPAccess(ctx, testSUPI, map[string]interface{}{"amfInstanceId": "amf-2"})
+	assert.True(t, errors.Is(err, ErrInvalidRegistration))
+
+	// Deregistration is seen by the next instance too
+	require.NoError(t, udm.DeregisterAMF3GPPAccess(ctx, testSUPI))
+	udm = newUECMInstance(udr.URL)
+	_, err = udm.Get3GPPRegistration(ctx, testSUPI)
+	assert.True(t, errors.Is(err, ErrRegistrationNotFound))
+	err = udm.UpdateAMF3GPPAccess(ctx, testSUPI, map[string]interface{}{"pei": "imeisv-0"})
+	assert.True(t, errors.Is(err, ErrRegistrationNotFound))
+	err = udm.DeregisterAMF3GPPAccess(ctx, testSUPI)
+	assert.True(t, errors.Is(err, ErrRegistrationNotFound))
+}
+
+func TestUECMRegisterRequiresAMFInstanceID(t *testing.T) {
+	udr := httptest.NewServer(&registrationUDR{registrations: make(map[string]json.RawMessage)})
+	defer udr.Close()
+
+	err := newUECMInstance(udr.URL).RegisterAMF3GPPAccess(context.Background(), testSUPI, &client.AMF3GPPAccessRegistration{RATType: "NR"})
+	assert.True(t, errors.Is(err, ErrInvalidRegistration))
+}
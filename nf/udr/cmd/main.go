@@ -10,10 +10,17 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/redis/go-redis/v9"
+	"github.com/your-org/5g-network/common/buildinfo"
+	"github.com/your-org/5g-network/common/correlation"
 	"github.com/your-org/5g-network/common/metrics"
+	"github.com/your-org/5g-network/common/nrfpool"
+	"github.com/your-org/5g-network/common/reload"
+	"github.com/your-org/5g-network/common/retry"
 	"github.com/your-org/5g-network/nf/udr/internal/clickhouse"
 	"github.com/your-org/5g-network/nf/udr/internal/client"
 	"github.com/your-org/5g-network/nf/udr/internal/config"
+	"github.com/your-org/5g-network/nf/udr/internal/events"
 	"github.com/your-org/5g-network/nf/udr/internal/repository"
 	"github.com/your-org/5g-network/nf/udr/internal/server"
 	"go.uber.org/zap"
@@ -23,17 +30,23 @@ import (
 var (
 	Version   = "dev"
 	BuildTime = "unknown"
+	GitCommit = "unknown"
 )
 
 func main() {
 	// Parse command-line flags
 	configPath := flag.String("config", "config/udr.yaml", "Path to configuration file")
-	logLevel := flag.String("log-level", "info", "Log level (debug, info, warn, error)")
+	logLevel := flag.String("log-level", "", "Log level (debug, info, warn, error), overrides observability.logging.level")
 	initSchema := flag.Bool("init-schema", false, "Initialize ClickHouse schema")
 	flag.Parse()
 
-	// Initialize logger
-	logger := initLogger(*logLevel)
+	// Initialize logger, at the configured level once loaded unless set on
+	// the command line
+	level, err := zap.ParseAtomicLevel(*logLevel)
+	if err != nil {
+		level = zap.NewAtomicLevel()
+	}
+	logger := initLogger(level)
 	defer logger.Sync()
 
 	logger.Info("Starting UDR (Unified Data Repository)",
@@ -53,17 +66,36 @@ func main() {
 		zap.Strings("clickhouse_addresses", cfg.ClickHouse.Addresses),
 	)
 
+	// Reload the log level and NRF heartbeat interval on SIGHUP
+	reloader := reload.New(*configPath, cfg, config.Load, []string{
+		"observability.logging.level",
+		"nrf.heartbeat_interval",
+	}, logger)
+	if *logLevel == "" {
+		if err := reloader.SetLogLevel(level, func(c *config.Config) string { return c.Observability.Logging.Level }); err != nil {
+			logger.Fatal("Invalid configuration", zap.Error(err))
+		}
+	}
+
+	// HTTP transport of the SBI clients, with mutual TLS when configured,
+	// retries of transient failures and the correlation ID of the request served
+	tlsTransport, err := cfg.SBI.ClientTLS.Transport()
+	if err != nil {
+		logger.Fatal("Invalid client TLS configuration", zap.Error(err))
+	}
+	sbiTransport := correlation.NewTransport(retry.NewTransport(tlsTransport, cfg.SBI.ClientRetry, logger), logger)
+
 	// Create ClickHouse client
 	chClient, err := clickhouse.NewClient(&cfg.ClickHouse, logger)
 	if err != nil {
 		logger.Fatal("Failed to create ClickHouse client", zap.Error(err))
 	}
-	defer chClient.Close()
 
 	logger.Info("Connected to ClickHouse successfully")
 
 	// Initialize schema if requested
 	if *initSchema {
+		defer chClient.Close()
 		logger.Info("Initializing ClickHouse schema...")
 		if err := initializeSchema(chClient, logger); err != nil {
 			logger.Fatal("Failed to initialize schema", zap.Error(err))
@@ -74,15 +106,57 @@ func main() {
 
 	// Create repository
 	repo := repository.NewClickHouseRepository(chClient, logger)
+	defer repo.Close()
+	repo.EnableReconnect(cfg.ClickHouse)
+	var keyCipher *repository.KeyCipher
+	if cfg.Encryption.Enabled {
+		keys, err := cfg.Encryption.DataKeys()
+		if err != nil {
+			logger.Fatal("Failed to load data keys", zap.Error(err))
+		}
+		keyCipher, err = repository.NewKeyCipher(keys, cfg.Encryption.ActiveVersion)
+		if err != nil {
+			logger.Fatal("Failed to create data key cipher", zap.Error(err))
+		}
+		repo.SetFieldCipher(keyCipher)
+		logger.Info("Authentication keys encrypted at rest",
+			zap.Uint32("active_key_version", cfg.Encryption.ActiveVersion))
+	}
+
+	// Cache subscriber and authentication reads in Redis
+	var dataRepo repository.Repository = repo
+	if cfg.Cache.Enabled {
+		redisClient := redis.NewClient(&redis.Options{
+			Addr:     cfg.Cache.Address,
+			Password: cfg.Cache.Password,
+			DB:       cfg.Cache.DB,
+		})
+		defer redisClient.Close()
+
+		cachingRepo := repository.NewCachingRepository(repo, repository.NewRedisCache(redisClient), cfg.Cache.TTL, logger)
+		if keyCipher != nil {
+			cachingRepo.SetFieldCipher(keyCipher)
+		}
+		dataRepo = cachingRepo
+		logger.Info("Subscriber cache enabled",
+			zap.String("address", cfg.Cache.Address),
+			zap.Duration("ttl", cfg.Cache.TTL))
+	}
 
 	// Create context
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Initialize metrics server
-	metricsServer := metrics.NewMetricsServer(9091, logger)
+	// Reconnect to ClickHouse when it stops answering
+	go repo.RunHealthCheck(ctx, repository.HealthCheckInterval)
+
+	// Initialize metrics server, on the port assigned to the UDR
+	metricsRegistry, err := metrics.NewRegistry("UDR", cfg.NF.InstanceID)
+	if err != nil {
+		logger.Fatal("Failed to create metrics registry", zap.Error(err))
+	}
+	metricsServer := metrics.NewMetricsServer(metricsRegistry, logger)
 	go func() {
-		logger.Info("Starting metrics server on :9091")
 		if err := metricsServer.Start(); err != nil {
 			logger.Error("Metrics server error", zap.Error(err))
 		}
@@ -95,7 +169,11 @@ func main() {
 
 	// Register with NRF if enabled
 	if cfg.NRF.Enabled {
-		nrfClient := client.NewNRFClient(cfg.NRF.URL, logger)
+		endpoints, err := nrfpool.New(ctx, cfg.NRF.URL, cfg.NRF.Options, logger)
+		if err != nil {
+			logger.Fatal("Failed to resolve NRF endpoints", zap.Error(err))
+		}
+		nrfClient := client.NewNRFClient(endpoints, sbiTransport, logger)
 
 		profile := &client.NFProfile{
 			NFInstanceID: cfg.NF.InstanceID,
@@ -120,7 +198,7 @@ func main() {
 
 			// Start heartbeat goroutine
 			go func() {
-				ticker := time.NewTicker(cfg.NRF.HeartbeatInterval)
+				ticker := reloader.Ticker(func(c *config.Config) time.Duration { return c.NRF.HeartbeatInterval })
 				defer ticker.Stop()
 
 				for {
@@ -144,11 +222,25 @@ func main() {
 		}
 	}
 
+	// Publish subscriber data changes, the source of data change notifications
+	bus := events.NewBus()
+
 	// Create and start UDR server
-	udrServer, err := server.NewUDRServer(cfg, repo, logger)
+	udrServer, err := server.NewUDRServer(cfg, events.NewRepository(dataRepo, bus, logger), logger)
 	if err != nil {
 		logger.Fatal("Failed to create UDR server", zap.Error(err))
 	}
+	udrServer.SetBuildInfo(buildinfo.New("UDR", Version, GitCommit, BuildTime,
+		"nudr-dr", "log-level"))
+	udrServer.SetLogLevel(level)
+
+	// Notify subscribers, the UDM, of subscription data changes
+	notifier := server.NewHTTPNotifier(sbiTransport, logger)
+	defer notifier.Stop()
+	udrServer.SetNotifier(notifier)
+	bus.Subscribe(udrServer.NotifyDataChange)
+
+	go reloader.Watch(ctx)
 
 	// Start server in goroutine
 	errChan := make(chan error, 1)
@@ -186,17 +278,11 @@ func main() {
 	logger.Info("UDR stopped")
 }
 
-// initLogger initializes the logger
-func initLogger(level string) *zap.Logger {
-	// Parse log level
-	var zapLevel zapcore.Level
-	if err := zapLevel.UnmarshalText([]byte(level)); err != nil {
-		zapLevel = zapcore.InfoLevel
-	}
-
+// initLogger initializes the logger, whose level can change at runtime
+func initLogger(level zap.AtomicLevel) *zap.Logger {
 	// Create logger config
 	config := zap.Config{
-		Level:            zap.NewAtomicLevelAt(zapLevel),
+		Level:            level,
 		Development:      false,
 		Encoding:         "json",
 		EncoderConfig:    zap.NewProductionEncoderConfig(),
@@ -254,6 +340,26 @@ func initializeSchema(client *clickhouse.Client, logger *zap.Logger) error {
 		}
 	}
 
+	if err := client.Exec(ctx, repository.SMSubscriptionsSchema); err != nil {
+		return fmt.Errorf("failed to create SM subscriptions table: %w", err)
+	}
+
+	if err := client.Exec(ctx, repository.AMFRegistrationsSchema); err != nil {
+		return fmt.Errorf("failed to create AMF registrations table: %w", err)
+	}
+
+	if err := client.Exec(ctx, repository.SDMSubscriptionsSchema); err != nil {
+		return fmt.Errorf("failed to create SDM subscriptions table: %w", err)
+	}
+
+	if err := client.Exec(ctx, repository.AuthEventsSchema); err != nil {
+		return fmt.Errorf("failed to create auth events table: %w", err)
+	}
+
+	if err := client.Exec(ctx, repository.AuthKeyVersionMigration); err != nil {
+		return fmt.Errorf("failed to add authentication key version column: %w", err)
+	}
+
 	return nil
 }
 
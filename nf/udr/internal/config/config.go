@@ -1,10 +1,16 @@
 package config
 
 import (
+	"encoding/hex"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/your-org/5g-network/common/nrfpool"
+	"github.com/your-org/5g-network/common/oauth"
+	"github.com/your-org/5g-network/common/retry"
+	"github.com/your-org/5g-network/common/tlsconfig"
 	"github.com/your-org/5g-network/nf/udr/internal/clickhouse"
 	"gopkg.in/yaml.v3"
 )
@@ -15,6 +21,8 @@ type Config struct {
 	SBI           SBIConfig           `yaml:"sbi"`
 	PLMN          PLMNConfig          `yaml:"plmn"`
 	ClickHouse    clickhouse.Config   `yaml:"clickhouse"`
+	Encryption    EncryptionConfig    `yaml:"encryption"`
+	Cache         CacheConfig         `yaml:"cache"`
 	NRF           NRFConfig           `yaml:"nrf"`
 	Observability ObservabilityConfig `yaml:"observability"`
 }
@@ -28,10 +36,13 @@ type NFConfig struct {
 
 // SBIConfig holds Service Based Interface configuration
 type SBIConfig struct {
-	Scheme      string    `yaml:"scheme"`
-	BindAddress string    `yaml:"bind_address"`
-	Port        int       `yaml:"port"`
-	TLS         TLSConfig `yaml:"tls"`
+	Scheme      string                 `yaml:"scheme"`
+	BindAddress string                 `yaml:"bind_address"`
+	Port        int                    `yaml:"port"`
+	TLS         TLSConfig              `yaml:"tls"`
+	ClientTLS   tlsconfig.ClientConfig `yaml:"client_tls"`   // TLS towards the SBIs of other NFs
+	ClientRetry retry.Policy           `yaml:"client_retry"` // Retries of idempotent requests to other NFs
+	OAuth2      oauth.Config           `yaml:"oauth2"`
 }
 
 // TLSConfig holds TLS configuration
@@ -39,6 +50,8 @@ type TLSConfig struct {
 	Enabled  bool   `yaml:"enabled"`
 	CertFile string `yaml:"cert_file"`
 	KeyFile  string `yaml:"key_file"`
+
+	tlsconfig.Options `yaml:",inline"`
 }
 
 // PLMNConfig holds PLMN configuration
@@ -52,6 +65,93 @@ type NRFConfig struct {
 	URL               string        `yaml:"url"`
 	Enabled           bool          `yaml:"enabled"`
 	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"`
+
+	// Secondary NRFs and DNS SRV discovery for failover
+	nrfpool.Options `yaml:",inline"`
+}
+
+// EncryptionConfig holds the AES-256-GCM data keys authentication keys are
+// encrypted with at rest. New rows use the active version; keep retired
+// versions listed until every row has been rewritten.
+type EncryptionConfig struct {
+	Enabled       bool            `yaml:"enabled"`
+	ActiveVersion uint32          `yaml:"active_version"`
+	Keys          []DataKeyConfig `yaml:"keys"`
+}
+
+// DataKeyConfig holds one version of the data key
+type DataKeyConfig struct {
+	Version uint32 `yaml:"version"`
+	Key     string `yaml:"key"`      // 64 hex characters
+	KeyFile string `yaml:"key_file"` // read instead of key when set
+}
+
+// DataKeys loads the data keys by version
+func (c *EncryptionConfig) DataKeys() (map[uint32][]byte, error) {
+	keys := make(map[uint32][]byte, len(c.Keys))
+	for _, k := range c.Keys {
+		if _, dup := keys[k.Version]; dup {
+			return nil, fmt.Errorf("duplicate data key version %d", k.Version)
+		}
+
+		encoded := k.Key
+		if k.KeyFile != "" {
+			data, err := os.ReadFile(k.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read data key version %d: %w", k.Version, err)
+			}
+			encoded = strings.TrimSpace(string(data))
+		}
+
+		key, err := hex.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("data key version %d is not hex: %w", k.Version, err)
+		}
+		keys[k.Version] = key
+	}
+	return keys, nil
+}
+
+// Validate checks that the active data key is configured and loadable
+func (c *EncryptionConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.ActiveVersion == 0 {
+		return fmt.Errorf("active_version must be at least 1")
+	}
+	keys, err := c.DataKeys()
+	if err != nil {
+		return err
+	}
+	if _, ok := keys[c.ActiveVersion]; !ok {
+		return fmt.Errorf("no data key for active_version %d", c.ActiveVersion)
+	}
+	return nil
+}
+
+// CacheConfig holds the Redis read-through cache of subscribers and
+// authentication subscriptions
+type CacheConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	Address  string        `yaml:"address"` // host:port
+	Password string        `yaml:"password"`
+	DB       int           `yaml:"db"`
+	TTL      time.Duration `yaml:"ttl"`
+}
+
+// Validate checks the Redis address and TTL of an enabled cache
+func (c *CacheConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Address == "" {
+		return fmt.Errorf("address is required")
+	}
+	if c.TTL <= 0 {
+		return fmt.Errorf("ttl must be positive")
+	}
+	return nil
 }
 
 // ObservabilityConfig holds observability configuration
@@ -123,6 +223,36 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("ClickHouse addresses are required")
 	}
 
+	if c.SBI.TLS.Enabled {
+		if err := c.SBI.TLS.Validate(); err != nil {
+			return fmt.Errorf("invalid sbi.tls: %w", err)
+		}
+	}
+
+	if err := c.SBI.ClientTLS.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.client_tls: %w", err)
+	}
+
+	if err := c.SBI.ClientRetry.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.client_retry: %w", err)
+	}
+
+	if err := c.NRF.Validate(); err != nil {
+		return fmt.Errorf("invalid NRF config: %w", err)
+	}
+
+	if err := c.SBI.OAuth2.Validate(); err != nil {
+		return fmt.Errorf("invalid sbi.oauth2: %w", err)
+	}
+
+	if err := c.Encryption.Validate(); err != nil {
+		return fmt.Errorf("invalid encryption config: %w", err)
+	}
+
+	if err := c.Cache.Validate(); err != nil {
+		return fmt.Errorf("invalid cache config: %w", err)
+	}
+
 	return nil
 }
 
@@ -151,6 +281,10 @@ func DefaultConfig() *Config {
 			MaxIdleConns: 5,
 			Timeout:      10 * time.Second,
 		},
+		Cache: CacheConfig{
+			Address: "localhost:6379",
+			TTL:     5 * time.Minute,
+		},
 		NRF: NRFConfig{
 			URL:     "http://localhost:8080",
 			Enabled: true,
@@ -0,0 +1,118 @@
+// Package events publishes the changes made to subscriber data in the UDR, the
+// source of the data change notifications sent to subscribers such as the UDM.
+package events
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// Resource types of data change events
+const (
+	ResourceAMData     = "am-data"     // Subscriber data, Access and Mobility
+	ResourceSMData     = "sm-data"     // SM subscription data of a DNN
+	ResourcePolicyData = "policy-data" // PCF policy data
+)
+
+// ignoredFields are never reported as changed: timestamps change on every
+// write and credentials must not leave the UDR in notifications
+var ignoredFields = map[string]bool{
+	"createdAt": true,
+	"updatedAt": true,
+	"opcKey":    true,
+}
+
+// Event describes a change of one resource of a UE
+type Event struct {
+	SUPI     string
+	Resource string // ResourceAMData, ResourceSMData or ResourcePolicyData
+	DNN      string // For ResourceSMData
+
+	// Top-level fields that changed, named as in the JSON encoding of the
+	// resource, and their new values. A field that was removed has a nil
+	// value.
+	ChangedFields []string
+	NewValues     map[string]interface{}
+}
+
+// Bus delivers events to its subscribers. Handlers run synchronously in the
+// publishing goroutine and must not block.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []func(Event)
+}
+
+// NewBus creates an event bus without subscribers
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers a handler called with every event published
+func (b *Bus) Subscribe(handler func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// Publish delivers an event to the subscribers
+func (b *Bus) Publish(event Event) {
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+// diff returns the top-level JSON fields that differ between two values of a
+// resource, sorted, and their values in current. A nil previous value
+// reports every field of current.
+func diff(previous, current interface{}) ([]string, map[string]interface{}, error) {
+	before, err := fields(previous)
+	if err != nil {
+		return nil, nil, err
+	}
+	after, err := fields(current)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values := make(map[string]interface{})
+	for name, value := range after {
+		if !ignoredFields[name] && !reflect.DeepEqual(before[name], value) {
+			values[name] = value
+		}
+	}
+	for name := range before {
+		if _, ok := after[name]; !ok && !ignoredFields[name] {
+			values[name] = nil
+		}
+	}
+
+	changed := make([]string, 0, len(values))
+	for name := range values {
+		changed = append(changed, name)
+	}
+	sort.Strings(changed)
+	return changed, values, nil
+}
+
+// fields decodes the JSON encoding of a value into its top-level fields
+func fields(value interface{}) (map[string]interface{}, error) {
+	if v := reflect.ValueOf(value); !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil()) {
+		return nil, nil
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
@@ -0,0 +1,159 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/nf/udr/internal/repository"
+)
+
+// memoryRepository keeps subscriber, SM and policy data in memory
+type memoryRepository struct {
+	repository.Repository
+	subscribers map[string]*repository.SubscriberData
+	sm          map[string]*repository.SessionManagementSubscriptionData
+	policies    map[string]*repository.PolicyData
+	failUpdates bool
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{
+		subscribers: make(map[string]*repository.SubscriberData),
+		sm:          make(map[string]*repository.SessionManagementSubscriptionData),
+		policies:    make(map[string]*repository.PolicyData),
+	}
+}
+
+func (r *memoryRepository) GetSubscriber(ctx context.Context, supi string) (*repository.SubscriberData, error) {
+	if data, ok := r.subscribers[supi]; ok {
+		return data, nil
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *memoryRepository) UpdateSubscriber(ctx context.Context, supi string, data *repository.SubscriberData) error {
+	if r.failUpdates {
+		return errors.New("clickhouse unavailable")
+	}
+	r.subscribers[supi] = data
+	return nil
+}
+
+func (r *memoryRepository) GetSMSubscription(ctx context.Context, supi, dnn string) (*repository.SessionManagementSubscriptionData, error) {
+	if data, ok := r.sm[supi+"/"+dnn]; ok {
+		return data, nil
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *memoryRepository) UpdateSMSubscription(ctx context.Context, supi, dnn string, data *repository.SessionManagementSubscriptionData) error {
+	r.sm[supi+"/"+dnn] = data
+	return nil
+}
+
+func (r *memoryRepository) GetPolicyData(ctx context.Context, supi string) (*repository.PolicyData, error) {
+	if data, ok := r.policies[supi]; ok {
+		return data, nil
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *memoryRepository) UpdatePolicyData(ctx context.Context, supi string, data *repository.PolicyData) error {
+	r.policies[supi] = data
+	return nil
+}
+
+// recordEvents returns a repository publishing on a bus and the events
+// published
+func recordEvents(inner *memoryRepository) (*Repository, *[]Event) {
+	bus := NewBus()
+	var published []Event
+	bus.Subscribe(func(event Event) { published = append(published, event) })
+	return NewRepository(inner, bus, zap.NewNop()), &published
+}
+
+func TestUpdateSubscriberPublishesChangedFields(t *testing.T) {
+	inner := newMemoryRepository()
+	inner.subscribers["imsi-001010000000001"] = &repository.SubscriberData{
+		SUPI:                   "imsi-001010000000001",
+		SubscriberStatus:       "ACTIVE",
+		SubscribedUeAmbrUplink: 1000000,
+		RoamingAllowed:         true,
+		OPCKey:                 "cd63cb71954a9f4e48a5994e37a02baf",
+	}
+	repo, published := recordEvents(inner)
+
+	require.NoError(t, repo.UpdateSubscriber(context.Background(), "imsi-001010000000001", &repository.SubscriberData{
+		SUPI:                   "imsi-001010000000001",
+		SubscriberStatus:       "ACTIVE",
+		SubscribedUeAmbrUplink: 2000000,
+		RoamingAllowed:         false,
+		OPCKey:                 "0000000000000000000000000000000",
+	}))
+
+	require.Len(t, *published, 1)
+	event := (*published)[0]
+	assert.Equal(t, "imsi-001010000000001", event.SUPI)
+	assert.Equal(t, ResourceAMData, event.Resource)
+	// Credentials are never reported
+	assert.Equal(t, []string{"roamingAllowed", "subscribedUeAmbr.uplink"}, event.ChangedFields)
+	assert.Equal(t, map[string]interface{}{
+		"roamingAllowed":          false,
+		"subscribedUeAmbr.uplink": "2000000",
+	}, event.NewValues)
+}
+
+func TestUpdateSMSubscriptionPublishesEvent(t *testing.T) {
+	repo, published := recordEvents(newMemoryRepository())
+
+	require.NoError(t, repo.UpdateSMSubscription(context.Background(), "imsi-001010000000001", "internet",
+		&repository.SessionManagementSubscriptionData{SUPI: "imsi-001010000000001", DNN: "internet", Default5QI: 9}))
+
+	require.Len(t, *published, 1)
+	event := (*published)[0]
+	assert.Equal(t, ResourceSMData, event.Resource)
+	assert.Equal(t, "internet", event.DNN)
+	// A new resource reports all its fields
+	assert.Contains(t, event.ChangedFields, "dnn")
+	assert.NotContains(t, event.ChangedFields, "createdAt")
+}
+
+func TestUpdatePolicyDataPublishesRemovedFields(t *testing.T) {
+	inner := newMemoryRepository()
+	inner.policies["imsi-001010000000001"] = &repository.PolicyData{
+		SUPI:               "imsi-001010000000001",
+		SubscriberPolicies: []byte(`{"chargingRule":"gold"}`),
+	}
+	repo, published := recordEvents(inner)
+
+	require.NoError(t, repo.UpdatePolicyData(context.Background(), "imsi-001010000000001",
+		&repository.PolicyData{SUPI: "imsi-001010000000001"}))
+
+	require.Len(t, *published, 1)
+	assert.Equal(t, ResourcePolicyData, (*published)[0].Resource)
+	assert.Equal(t, []string{"subscriberPolicies"}, (*published)[0].ChangedFields)
+	assert.Nil(t, (*published)[0].NewValues["subscriberPolicies"])
+}
+
+func TestNoEventWithoutChange(t *testing.T) {
+	inner := newMemoryRepository()
+	data := &repository.SubscriberData{SUPI: "imsi-001010000000001", SubscriberStatus: "ACTIVE"}
+	inner.subscribers[data.SUPI] = data
+	repo, published := recordEvents(inner)
+
+	// Unchanged data
+	copied := *data
+	require.NoError(t, repo.UpdateSubscriber(context.Background(), data.SUPI, &copied))
+	assert.Empty(t, *published)
+
+	// Failed update
+	inner.failUpdates = true
+	copied.SubscriberStatus = "SUSPENDED"
+	assert.Error(t, repo.UpdateSubscriber(context.Background(), data.SUPI, &copied))
+	assert.Empty(t, *published)
+}
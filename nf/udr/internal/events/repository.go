@@ -0,0 +1,81 @@
+package events
+
+import (
+	"context"
+
+	"github.com/your-org/5g-network/nf/udr/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Repository publishes an event for every successful update of subscriber,
+// SM subscription and policy data made through it. The other methods are
+// those of the wrapped repository.
+type Repository struct {
+	repository.Repository
+	bus    *Bus
+	logger *zap.Logger
+}
+
+// NewRepository wraps repo, publishing its data changes on bus
+func NewRepository(repo repository.Repository, bus *Bus, logger *zap.Logger) *Repository {
+	return &Repository{Repository: repo, bus: bus, logger: logger}
+}
+
+// UpdateSubscriber updates a subscriber and publishes its AM data changes
+func (r *Repository) UpdateSubscriber(ctx context.Context, supi string, data *repository.SubscriberData) error {
+	previous, _ := r.Repository.GetSubscriber(ctx, supi)
+	if err := r.Repository.UpdateSubscriber(ctx, supi, data); err != nil {
+		return err
+	}
+
+	r.publish(Event{SUPI: supi, Resource: ResourceAMData}, previous, data)
+	return nil
+}
+
+// UpdateSMSubscription updates SM subscription data and publishes its changes
+func (r *Repository) UpdateSMSubscription(ctx context.Context, supi, dnn string, data *repository.SessionManagementSubscriptionData) error {
+	previous, _ := r.Repository.GetSMSubscription(ctx, supi, dnn)
+	if err := r.Repository.UpdateSMSubscription(ctx, supi, dnn, data); err != nil {
+		return err
+	}
+
+	r.publish(Event{SUPI: supi, Resource: ResourceSMData, DNN: dnn}, previous, data)
+	return nil
+}
+
+// UpdatePolicyData updates policy data and publishes its changes
+func (r *Repository) UpdatePolicyData(ctx context.Context, supi string, data *repository.PolicyData) error {
+	previous, _ := r.Repository.GetPolicyData(ctx, supi)
+	if err := r.Repository.UpdatePolicyData(ctx, supi, data); err != nil {
+		return err
+	}
+
+	r.publish(Event{SUPI: supi, Resource: ResourcePolicyData}, previous, data)
+	return nil
+}
+
+// publish completes an event with the fields changed between the previous
+// and current values of its resource, publishing it when any did
+func (r *Repository) publish(event Event, previous, current interface{}) {
+	changed, values, err := diff(previous, current)
+	if err != nil {
+		r.logger.Error("Failed to compare data change",
+			zap.String("supi", event.SUPI),
+			zap.String("resource", event.Resource),
+			zap.Error(err),
+		)
+		return
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	event.ChangedFields = changed
+	event.NewValues = values
+	r.logger.Debug("Publishing data change",
+		zap.String("supi", event.SUPI),
+		zap.String("resource", event.Resource),
+		zap.Strings("changed_fields", changed),
+	)
+	r.bus.Publish(event)
+}
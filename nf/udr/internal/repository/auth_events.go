@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RecordAuthEvent stores the result of an authentication of a UE
+func (r *ClickHouseRepository) RecordAuthEvent(ctx context.Context, event *AuthEvent) error {
+	query := `
+		INSERT INTO udr.auth_events (
+			supi, success, auth_method, serving_network, failure_reason, timestamp
+		) VALUES (?, ?, ?, ?, ?, ?)
+	`
+
+	timestamp := event.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	var success uint8
+	if event.Success {
+		success = 1
+	}
+
+	err := r.conn().Exec(ctx, query,
+		event.SUPI, success, event.AuthMethod, event.ServingNetwork, event.FailureReason, timestamp,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record auth event: %w", err)
+	}
+
+	r.logger.Debug("Auth event recorded",
+		zap.String("supi", event.SUPI),
+		zap.Bool("success", event.Success),
+	)
+	return nil
+}
+
+// ListAuthEvents lists the most recent authentication events of a SUPI
+// within the filter, newest first
+func (r *ClickHouseRepository) ListAuthEvents(ctx context.Context, supi string, filter AuthEventFilter, limit int) ([]*AuthEvent, error) {
+	conditions := []string{"supi = ?"}
+	args := []interface{}{supi}
+	if !filter.Since.IsZero() {
+		conditions = append(conditions, "timestamp >= ?")
+		args = append(args, filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		conditions = append(conditions, "timestamp < ?")
+		args = append(args, filter.Until)
+	}
+	args = append(args, limit)
+
+	query := `
+		SELECT supi, success, auth_method, serving_network, failure_reason, timestamp
+		FROM udr.auth_events
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY timestamp DESC
+		LIMIT ?
+	`
+
+	rows, err := r.conn().Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list auth events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*AuthEvent
+	for rows.Next() {
+		var event AuthEvent
+		var success uint8
+		if err := rows.Scan(&event.SUPI, &success, &event.AuthMethod, &event.ServingNetwork, &event.FailureReason, &event.Timestamp); err != nil {
+			r.logger.Error("Failed to scan auth event", zap.Error(err))
+			continue
+		}
+		event.Success = success != 0
+		events = append(events, &event)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrCacheMiss is returned by a Cache that holds no value for a key
+var ErrCacheMiss = errors.New("cache miss")
+
+// Cache stores serialized values by key for a limited time
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+type bypassKey struct{}
+
+// WithoutCache returns a context whose reads go to the database, refreshing
+// the cache with the values read
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+// cacheBypassed reports whether reads made with ctx skip the cache
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassKey{}).(bool)
+	return bypass
+}
+
+// CachingRepository is a read-through cache of subscribers and
+// authentication subscriptions, the point lookups the UDM and AUSF repeat.
+// Writes made through it invalidate the cached values; the other methods
+// are those of the wrapped repository. Cache failures are logged and the
+// database is used instead.
+type CachingRepository struct {
+	Repository
+	cache  Cache
+	ttl    time.Duration
+	logger *zap.Logger
+
+	// Encrypts cached authentication subscriptions, nil to cache them as read
+	cipher FieldCipher
+}
+
+// NewCachingRepository wraps repo, caching its reads in cache for ttl
+func NewCachingRepository(repo Repository, cache Cache, ttl time.Duration, logger *zap.Logger) *CachingRepository {
+	return &CachingRepository{Repository: repo, cache: cache, ttl: ttl, logger: logger}
+}
+
+// SetFieldCipher encrypts the cached authentication subscriptions, which
+// hold the decrypted permanent key, so they are not kept in the clear
+func (r *CachingRepository) SetFieldCipher(cipher FieldCipher) {
+	r.cipher = cipher
+}
+
+func subscriberCacheKey(supi string) string {
+	return "udr:subscriber:" + supi
+}
+
+func authSubscriptionCacheKey(supi string) string {
+	return "udr:auth-subscription:" + supi
+}
+
+// GetSubscriber retrieves a subscriber from the cache, or from the database
+// on a miss
+func (r *CachingRepository) GetSubscriber(ctx context.Context, supi string) (*SubscriberData, error) {
+	key := subscriberCacheKey(supi)
+
+	var data SubscriberData
+	if r.lookup(ctx, key, &data) {
+		return &data, nil
+	}
+
+	subscriber, err := r.Repository.GetSubscriber(ctx, supi)
+	if err != nil {
+		return nil, err
+	}
+	r.store(ctx, key, subscriber)
+	return subscriber, nil
+}
+
+// GetAuthenticationSubscription retrieves an authentication subscription
+// from the cache, or from the database on a miss
+func (r *CachingRepository) GetAuthenticationSubscription(ctx context.Context, supi string) (*AuthenticationSubscription, error) {
+	key := authSubscriptionCacheKey(supi)
+
+	var data AuthenticationSubscription
+	if r.lookup(ctx, key, &data) {
+		return &data, nil
+	}
+
+	subscription, err := r.Repository.GetAuthenticationSubscription(ctx, supi)
+	if err != nil {
+		return nil, err
+	}
+	r.store(ctx, key, subscription)
+	return subscription, nil
+}
+
+// CreateSubscriber creates a subscriber, replacing any cached one
+func (r *CachingRepository) CreateSubscriber(ctx context.Context, data *SubscriberData) error {
+	defer r.invalidate(ctx, subscriberCacheKey(data.SUPI))
+	return r.Repository.CreateSubscriber(ctx, data)
+}
+
+// BatchCreateSubscribers creates subscribers, replacing any cached ones
+func (r *CachingRepository) BatchCreateSubscribers(ctx context.Context, data []*SubscriberData) ([]SubscriberFailure, error) {
+	keys := make([]string, 0, len(data))
+	for _, subscriber := range data {
+		keys = append(keys, subscriberCacheKey(subscriber.SUPI))
+	}
+	defer r.invalidate(ctx, keys...)
+	return r.Repository.BatchCreateSubscribers(ctx, data)
+}
+
+// UpdateSubscriber updates a subscriber and invalidates its cached value
+func (r *CachingRepository) UpdateSubscriber(ctx context.Context, supi string, data *SubscriberData) error {
+	defer r.invalidate(ctx, subscriberCacheKey(supi))
+	return r.Repository.UpdateSubscriber(ctx, supi, data)
+}
+
+// DeleteSubscriber deletes a subscriber and invalidates its cached value
+func (r *CachingRepository) DeleteSubscriber(ctx context.Context, supi string) error {
+	defer r.invalidate(ctx, subscriberCacheKey(supi))
+	return r.Repository.DeleteSubscriber(ctx, supi)
+}
+
+// CreateAuthenticationSubscription creates an authentication subscription,
+// replacing any cached one
+func (r *CachingRepository) CreateAuthenticationSubscription(ctx context.Context, data *AuthenticationSubscription) error {
+	defer r.invalidate(ctx, authSubscriptionCacheKey(data.SUPI))
+	return r.Repository.CreateAuthenticationSubscription(ctx, data)
+}
+
+// UpdateAuthenticationSubscription updates an authentication subscription
+// and invalidates its cached value
+func (r *CachingRepository) UpdateAuthenticationSubscription(ctx context.Context, supi string, data *AuthenticationSubscription) error {
+	defer r.invalidate(ctx, authSubscriptionCacheKey(supi))
+	return r.Repository.UpdateAuthenticationSubscription(ctx, supi, data)
+}
+
+// DeleteAuthenticationSubscription deletes an authentication subscription
+// and invalidates its cached value
+func (r *CachingRepository) DeleteAuthenticationSubscription(ctx context.Context, supi string) error {
+	defer r.invalidate(ctx, authSubscriptionCacheKey(supi))
+	return r.Repository.DeleteAuthenticationSubscription(ctx, supi)
+}
+
+// IncrementSQN increments the SQN and invalidates the cached authentication
+// subscription, which carries it
+func (r *CachingRepository) IncrementSQN(ctx context.Context, supi string) (uint64, error) {
+	defer r.invalidate(ctx, authSubscriptionCacheKey(supi))
+	return r.Repository.IncrementSQN(ctx, supi)
+}
+
+// SetSQN sets the SQN and invalidates the cached authentication subscription
+func (r *CachingRepository) SetSQN(ctx context.Context, supi string, sqn uint64) error {
+	defer r.invalidate(ctx, authSubscriptionCacheKey(supi))
+	return r.Repository.SetSQN(ctx, supi, sqn)
+}
+
+// ReserveSQNBlock reserves SQNs and invalidates the cached authentication
+// subscription
+func (r *CachingRepository) ReserveSQNBlock(ctx context.Context, supi string, size uint64) (*SQNBlock, error) {
+	defer r.invalidate(ctx, authSubscriptionCacheKey(supi))
+	return r.Repository.ReserveSQNBlock(ctx, supi, size)
+}
+
+// cachedValue is a cache entry encrypted with the field cipher
+type cachedValue struct {
+	Ciphertext string `json:"ciphertext"`
+	KeyVersion uint32 `json:"keyVersion"`
+}
+
+// lookup decodes the cached value of key into v, reporting whether it was
+// found
+func (r *CachingRepository) lookup(ctx context.Context, key string, v interface{}) bool {
+	if cacheBypassed(ctx) {
+		return false
+	}
+
+	value, err := r.cache.Get(ctx, key)
+	if errors.Is(err, ErrCacheMiss) {
+		return false
+	}
+	if err != nil {
+		r.logger.Warn("Cache read failed", zap.String("key", key), zap.Error(err))
+		return false
+	}
+
+	if r.cipher != nil {
+		var cached cachedValue
+		if err := json.Unmarshal(value, &cached); err != nil {
+			r.logger.Warn("Invalid cache entry", zap.String("key", key), zap.Error(err))
+			return false
+		}
+		plaintext, err := r.cipher.Decrypt(cached.Ciphertext, key, cached.KeyVersion)
+		if err != nil {
+			r.logger.Warn("Invalid cache entry", zap.String("key", key), zap.Error(err))
+			return false
+		}
+		value = []byte(plaintext)
+	}
+
+	if err := json.Unmarshal(value, v); err != nil {
+		r.logger.Warn("Invalid cache entry", zap.String("key", key), zap.Error(err))
+		return false
+	}
+	return true
+}
+
+// store caches v under key
+func (r *CachingRepository) store(ctx context.Context, key string, v interface{}) {
+	value, err := r.encode(key, v)
+	if err != nil {
+		r.logger.Warn("Failed to encode cache entry", zap.String("key", key), zap.Error(err))
+		return
+	}
+	if err := r.cache.Set(ctx, key, value, r.ttl); err != nil {
+		r.logger.Warn("Cache write failed", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// encode serializes v, encrypted when a field cipher is set
+func (r *CachingRepository) encode(key string, v interface{}) ([]byte, error) {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if r.cipher == nil {
+		return value, nil
+	}
+
+	ciphertext, version, err := r.cipher.Encrypt(string(value), key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt: %w", err)
+	}
+	return json.Marshal(cachedValue{Ciphertext: ciphertext, KeyVersion: version})
+}
+
+// invalidate deletes cached values, once the write they follow is done. A
+// failure leaves the stale values cached until their TTL ends.
+func (r *CachingRepository) invalidate(ctx context.Context, keys ...string) {
+	if err := r.cache.Delete(ctx, keys...); err != nil {
+		r.logger.Error("Cache invalidation failed", zap.Strings("keys", keys), zap.Error(err))
+	}
+}
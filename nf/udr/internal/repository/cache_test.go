@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// memoryCache is a Cache kept in a map, without expiry
+type memoryCache struct {
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{values: make(map[string][]byte)}
+}
+
+func (c *memoryCache) Get(ctx context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, ok := c.values[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	return value, nil
+}
+
+func (c *memoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = value
+	return nil
+}
+
+func (c *memoryCache) Delete(ctx context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		delete(c.values, key)
+	}
+	return nil
+}
+
+// countingRepository serves one subscriber and authentication subscription,
+// counting the reads that reach it
+type countingRepository struct {
+	Repository
+	subscriber       *SubscriberData
+	authSubscription *AuthenticationSubscription
+	subscriberReads  int
+	authReads        int
+}
+
+func (r *countingRepository) GetSubscriber(ctx context.Context, supi string) (*SubscriberData, error) {
+	r.subscriberReads++
+	if r.subscriber == nil || r.subscriber.SUPI != supi {
+		return nil, ErrNotFound
+	}
+	data := *r.subscriber
+	return &data, nil
+}
+
+func (r *countingRepository) UpdateSubscriber(ctx context.Context, supi string, data *SubscriberData) error {
+	r.subscriber = data
+	return nil
+}
+
+func (r *countingRepository) GetAuthenticationSubscription(ctx context.Context, supi string) (*AuthenticationSubscription, error) {
+	r.authReads++
+	if r.authSubscription == nil || r.authSubscription.SUPI != supi {
+		return nil, ErrNotFound
+	}
+	data := *r.authSubscription
+	return &data, nil
+}
+
+func (r *countingRepository) IncrementSQN(ctx context.Context, supi string) (uint64, error) {
+	r.authSubscription.SQN++
+	return r.authSubscription.SQN, nil
+}
+
+func TestCachingRepositorySecondReadHitsCache(t *testing.T) {
+	backend := &countingRepository{subscriber: &SubscriberData{SUPI: "imsi-001010000000001", MSISDN: "1234"}}
+	repo := NewCachingRepository(backend, newMemoryCache(), time.Minute, zap.NewNop())
+	ctx := context.Background()
+
+	first, err := repo.GetSubscriber(ctx, "imsi-001010000000001")
+	require.NoError(t, err)
+	second, err := repo.GetSubscriber(ctx, "imsi-001010000000001")
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+	assert.Equal(t, 1, backend.subscriberReads)
+
+	// Misses are not cached
+	_, err = repo.GetSubscriber(ctx, "imsi-001010000000002")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = repo.GetSubscriber(ctx, "imsi-001010000000002")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, 3, backend.subscriberReads)
+
+	// A bypassing read goes to the database
+	_, err = repo.GetSubscriber(WithoutCache(ctx), "imsi-001010000000001")
+	require.NoError(t, err)
+	assert.Equal(t, 4, backend.subscriberReads)
+}
+
+func TestCachingRepositoryUpdateInvalidates(t *testing.T) {
+	backend := &countingRepository{
+		subscriber:       &SubscriberData{SUPI: "imsi-001010000000001", MSISDN: "1234"},
+		authSubscription: testAuthSubscription(),
+	}
+	repo := NewCachingRepository(backend, newMemoryCache(), time.Minute, zap.NewNop())
+	ctx := context.Background()
+
+	_, err := repo.GetSubscriber(ctx, "imsi-001010000000001")
+	require.NoError(t, err)
+	require.NoError(t, repo.UpdateSubscriber(ctx, "imsi-001010000000001",
+		&SubscriberData{SUPI: "imsi-001010000000001", MSISDN: "5678"}))
+
+	subscriber, err := repo.GetSubscriber(ctx, "imsi-001010000000001")
+	require.NoError(t, err)
+	assert.Equal(t, "5678", subscriber.MSISDN)
+	assert.Equal(t, 2, backend.subscriberReads)
+
+	// The SQN is part of the cached authentication subscription
+	_, err = repo.GetAuthenticationSubscription(ctx, "imsi-001010000000001")
+	require.NoError(t, err)
+	sqn, err := repo.IncrementSQN(ctx, "imsi-001010000000001")
+	require.NoError(t, err)
+
+	auth, err := repo.GetAuthenticationSubscription(ctx, "imsi-001010000000001")
+	require.NoError(t, err)
+	assert.Equal(t, sqn, auth.SQN)
+	assert.Equal(t, 2, backend.authReads)
+}
+
+func TestCachingRepositoryEncryptsAuthSubscriptions(t *testing.T) {
+	c, err := NewKeyCipher(map[uint32][]byte{1: testDataKey(1)}, 1)
+	require.NoError(t, err)
+
+	backend := &countingRepository{authSubscription: testAuthSubscription()}
+	cache := newMemoryCache()
+	repo := NewCachingRepository(backend, cache, time.Minute, zap.NewNop())
+	repo.SetFieldCipher(c)
+	ctx := context.Background()
+
+	_, err = repo.GetAuthenticationSubscription(ctx, "imsi-001010000000001")
+	require.NoError(t, err)
+	cached, err := cache.Get(ctx, authSubscriptionCacheKey("imsi-001010000000001"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(cached), testAuthSubscription().PermanentKey)
+
+	auth, err := repo.GetAuthenticationSubscription(ctx, "imsi-001010000000001")
+	require.NoError(t, err)
+	assert.Equal(t, testAuthSubscription().PermanentKey, auth.PermanentKey)
+	assert.Equal(t, 1, backend.authReads)
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/your-org/5g-network/nf/udr/internal/clickhouse"
+	"go.uber.org/zap"
+)
+
+// HealthCheckInterval is how often RunHealthCheck pings ClickHouse
+const HealthCheckInterval = 10 * time.Second
+
+// healthCheckTimeout bounds one ping of the health check
+const healthCheckTimeout = 5 * time.Second
+
+// ConnectionStats reports the ClickHouse connection pool and its health
+type ConnectionStats struct {
+	Healthy      bool      `json:"healthy"`
+	Reconnects   uint64    `json:"reconnects"`
+	LastCheck    time.Time `json:"last_check,omitempty"`
+	LastError    string    `json:"last_error,omitempty"`
+	MaxOpenConns int       `json:"max_open_conns"`
+	MaxIdleConns int       `json:"max_idle_conns"`
+}
+
+// EnableReconnect lets the health check replace the client with a new one
+// opened with cfg when ClickHouse stops answering, e.g. after a restart
+func (r *ClickHouseRepository) EnableReconnect(cfg clickhouse.Config) {
+	r.clientMu.Lock()
+	defer r.clientMu.Unlock()
+
+	r.clientConfig = &cfg
+	r.connStats.MaxOpenConns = cfg.MaxOpenConns
+	r.connStats.MaxIdleConns = cfg.MaxIdleConns
+}
+
+// RunHealthCheck pings ClickHouse every interval until ctx is done,
+// reconnecting when a ping fails
+func (r *ClickHouseRepository) RunHealthCheck(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.checkHealth(ctx); err != nil {
+				r.logger.Error("ClickHouse health check failed", zap.Error(err))
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// checkHealth pings ClickHouse and, when the ping fails and reconnecting is
+// enabled, replaces the client. Queries in flight keep the client they
+// started with.
+func (r *ClickHouseRepository) checkHealth(ctx context.Context) error {
+	pingCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	err := r.conn().Ping(pingCtx)
+	cancel()
+
+	r.clientMu.Lock()
+	r.connStats.LastCheck = time.Now()
+	r.connStats.Healthy = err == nil
+	r.connStats.LastError = ""
+	if err != nil {
+		r.connStats.LastError = err.Error()
+	}
+	cfg := r.clientConfig
+	r.clientMu.Unlock()
+
+	if err == nil {
+		return nil
+	}
+	if cfg == nil {
+		return fmt.Errorf("ClickHouse unreachable: %w", err)
+	}
+
+	r.logger.Warn("ClickHouse unreachable, reconnecting", zap.Error(err))
+	client, err := clickhouse.NewClient(cfg, r.logger)
+	if err != nil {
+		return fmt.Errorf("failed to reconnect to ClickHouse: %w", err)
+	}
+
+	pingCtx, cancel = context.WithTimeout(ctx, healthCheckTimeout)
+	err = client.Ping(pingCtx)
+	cancel()
+	if err != nil {
+		client.Close()
+		return fmt.Errorf("failed to reconnect to ClickHouse: %w", err)
+	}
+
+	r.clientMu.Lock()
+	previous := r.client
+	r.client = client
+	r.connStats.Healthy = true
+	r.connStats.LastError = ""
+	r.connStats.Reconnects++
+	r.clientMu.Unlock()
+
+	previous.Close()
+	r.logger.Info("Reconnected to ClickHouse")
+	return nil
+}
+
+// conn returns the client queries are sent with
+func (r *ClickHouseRepository) conn() *clickhouse.Client {
+	r.clientMu.RLock()
+	defer r.clientMu.RUnlock()
+	return r.client
+}
+
+// connectionStats returns a snapshot of the connection health
+func (r *ClickHouseRepository) connectionStats() ConnectionStats {
+	r.clientMu.RLock()
+	defer r.clientMu.RUnlock()
+	return r.connStats
+}
+
+// Close closes the current client
+func (r *ClickHouseRepository) Close() error {
+	return r.conn().Close()
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// DataKeySize is the size of an AES-256 data key in bytes
+const DataKeySize = 32
+
+// PlaintextKeyVersion marks rows written before encryption was enabled.
+// Their key fields are stored and read back as they are.
+const PlaintextKeyVersion uint32 = 0
+
+// ErrUnknownKeyVersion is returned when a row was encrypted with a data key
+// the cipher does not hold
+var ErrUnknownKeyVersion = errors.New("unknown data key version")
+
+// FieldCipher encrypts authentication key fields at rest. The associated
+// data binds a ciphertext to its row and column, so it cannot be copied to
+// another subscriber. A KMS-backed implementation can replace KeyCipher.
+type FieldCipher interface {
+	// Encrypt returns the ciphertext of plaintext and the version of the
+	// data key it was encrypted with
+	Encrypt(plaintext, associatedData string) (string, uint32, error)
+	// Decrypt returns the plaintext of a ciphertext encrypted with the data
+	// key of the given version
+	Decrypt(ciphertext, associatedData string, version uint32) (string, error)
+}
+
+// KeyCipher is a FieldCipher using AES-256-GCM data keys. New values are
+// encrypted with the active key; older keys are kept to read rows written
+// before a rotation.
+type KeyCipher struct {
+	active uint32
+	aeads  map[uint32]cipher.AEAD
+}
+
+// NewKeyCipher creates a cipher from data keys by version, encrypting with
+// the active version
+func NewKeyCipher(keys map[uint32][]byte, active uint32) (*KeyCipher, error) {
+	if active == PlaintextKeyVersion {
+		return nil, fmt.Errorf("data key version %d is reserved for plaintext rows", PlaintextKeyVersion)
+	}
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("%w: active version %d", ErrUnknownKeyVersion, active)
+	}
+
+	c := &KeyCipher{active: active, aeads: make(map[uint32]cipher.AEAD, len(keys))}
+	for version, key := range keys {
+		if version == PlaintextKeyVersion {
+			return nil, fmt.Errorf("data key version %d is reserved for plaintext rows", PlaintextKeyVersion)
+		}
+		if len(key) != DataKeySize {
+			return nil, fmt.Errorf("data key version %d must be %d bytes, got %d", version, DataKeySize, len(key))
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid data key version %d: %w", version, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("invalid data key version %d: %w", version, err)
+		}
+		c.aeads[version] = aead
+	}
+	return c, nil
+}
+
+// Encrypt seals plaintext with the active data key. The ciphertext is the
+// base64 encoding of the nonce followed by the sealed value.
+func (c *KeyCipher) Encrypt(plaintext, associatedData string) (string, uint32, error) {
+	aead := c.aeads[c.active]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", 0, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(associatedData))
+	return base64.StdEncoding.EncodeToString(sealed), c.active, nil
+}
+
+// Decrypt opens a ciphertext sealed with the data key of the given version
+func (c *KeyCipher) Decrypt(ciphertext, associatedData string, version uint32) (string, error) {
+	aead, ok := c.aeads[version]
+	if !ok {
+		return "", fmt.Errorf("%w: %d", ErrUnknownKeyVersion, version)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return "", fmt.Errorf("malformed ciphertext: %w", err)
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", errors.New("malformed ciphertext: too short")
+	}
+
+	nonce, sealed := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, []byte(associatedData))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func testDataKey(fill byte) []byte {
+	return bytes.Repeat([]byte{fill}, DataKeySize)
+}
+
+func testAuthSubscription() *AuthenticationSubscription {
+	return &AuthenticationSubscription{
+		SUPI:                 "imsi-001010000000001",
+		AuthenticationMethod: "5G_AKA",
+		PermanentKey:         "465b5ce8b199b49faa5f0a2ee238a6bc",
+		EncOPC:               "e8ed289deba952e4283b54e88e6183ca",
+		EncAlgorithm:         "milenage",
+	}
+}
+
+func TestKeyCipherRoundTrip(t *testing.T) {
+	c, err := NewKeyCipher(map[uint32][]byte{1: testDataKey(1)}, 1)
+	require.NoError(t, err)
+
+	ciphertext, version, err := c.Encrypt("465b5ce8b199b49faa5f0a2ee238a6bc", "ad")
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), version)
+	assert.NotContains(t, ciphertext, "465b5ce8b199b49faa5f0a2ee238a6bc")
+
+	// Fresh nonces: the same plaintext never encrypts the same way twice
+	again, _, err := c.Encrypt("465b5ce8b199b49faa5f0a2ee238a6bc", "ad")
+	require.NoError(t, err)
+	assert.NotEqual(t, ciphertext, again)
+
+	plaintext, err := c.Decrypt(ciphertext, "ad", version)
+	require.NoError(t, err)
+	assert.Equal(t, "465b5ce8b199b49faa5f0a2ee238a6bc", plaintext)
+
+	_, err = c.Decrypt(ciphertext, "other row", version)
+	assert.Error(t, err)
+	_, err = c.Decrypt(ciphertext, "ad", 2)
+	assert.ErrorIs(t, err, ErrUnknownKeyVersion)
+}
+
+func TestNewKeyCipherRejectsInvalidKeys(t *testing.T) {
+	_, err := NewKeyCipher(map[uint32][]byte{1: testDataKey(1)}, 2)
+	assert.ErrorIs(t, err, ErrUnknownKeyVersion)
+
+	_, err = NewKeyCipher(map[uint32][]byte{1: make([]byte, 16)}, 1)
+	assert.Error(t, err)
+
+	_, err = NewKeyCipher(map[uint32][]byte{0: testDataKey(1)}, 0)
+	assert.Error(t, err)
+}
+
+func TestAuthKeysEncryptedAtRest(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	repo := NewClickHouseRepository(nil, logger)
+	c, err := NewKeyCipher(map[uint32][]byte{1: testDataKey(1)}, 1)
+	require.NoError(t, err)
+	repo.SetFieldCipher(c)
+
+	data := testAuthSubscription()
+	stored, err := repo.encryptAuthKeys(data)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), stored.version)
+	assert.NotEqual(t, data.PermanentKey, stored.permanentKey)
+	assert.NotEqual(t, data.EncOPC, stored.encOPC)
+	assert.Empty(t, stored.encOP, "unset keys stay empty")
+	assert.Equal(t, "465b5ce8b199b49faa5f0a2ee238a6bc", data.PermanentKey, "caller keeps the plaintext")
+
+	read := *data
+	read.PermanentKey, read.EncOPC, read.EncOP = stored.permanentKey, stored.encOPC, stored.encOP
+	require.NoError(t, repo.decryptAuthKeys(&read, stored.version))
+	assert.Equal(t, *data, read)
+
+	// A ciphertext copied to another subscriber does not decrypt
+	read = *data
+	read.SUPI = "imsi-001010000000002"
+	read.PermanentKey, read.EncOPC = stored.permanentKey, stored.encOPC
+	assert.Error(t, repo.decryptAuthKeys(&read, stored.version))
+}
+
+func TestAuthKeysKeyRotation(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	repo := NewClickHouseRepository(nil, logger)
+	v1, err := NewKeyCipher(map[uint32][]byte{1: testDataKey(1)}, 1)
+	require.NoError(t, err)
+	repo.SetFieldCipher(v1)
+
+	data := testAuthSubscription()
+	old, err := repo.encryptAuthKeys(data)
+	require.NoError(t, err)
+
+	// Rotate: version 2 becomes active, version 1 still reads old rows
+	v2, err := NewKeyCipher(map[uint32][]byte{1: testDataKey(1), 2: testDataKey(2)}, 2)
+	require.NoError(t, err)
+	repo.SetFieldCipher(v2)
+
+	read := *data
+	read.PermanentKey, read.EncOPC = old.permanentKey, old.encOPC
+	require.NoError(t, repo.decryptAuthKeys(&read, old.version))
+	assert.Equal(t, data.PermanentKey, read.PermanentKey)
+
+	// Rewriting the row re-encrypts it with the active key
+	rewritten, err := repo.encryptAuthKeys(&read)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2), rewritten.version)
+
+	// Once version 1 is retired, only rewritten rows read back
+	v2only, err := NewKeyCipher(map[uint32][]byte{2: testDataKey(2)}, 2)
+	require.NoError(t, err)
+	repo.SetFieldCipher(v2only)
+
+	read = *data
+	read.PermanentKey, read.EncOPC = rewritten.permanentKey, rewritten.encOPC
+	require.NoError(t, repo.decryptAuthKeys(&read, rewritten.version))
+	assert.Equal(t, data.EncOPC, read.EncOPC)
+
+	read.PermanentKey, read.EncOPC = old.permanentKey, old.encOPC
+	assert.ErrorIs(t, repo.decryptAuthKeys(&read, old.version), ErrUnknownKeyVersion)
+}
+
+func TestAuthenticationSubscriptionStoredEncrypted(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+	require.NoError(t, repo.client.Exec(ctx, AuthKeyVersionMigration))
+
+	c, err := NewKeyCipher(map[uint32][]byte{1: testDataKey(1)}, 1)
+	require.NoError(t, err)
+	repo.SetFieldCipher(c)
+
+	data := testAuthSubscription()
+	data.SUPI = testSubscribers(1)[0].SUPI
+	require.NoError(t, repo.CreateAuthenticationSubscription(ctx, data))
+
+	var storedKey string
+	var version uint32
+	require.NoError(t, repo.client.QueryRow(ctx,
+		"SELECT permanent_key, key_version FROM udr.authentication_subscription WHERE supi = ? LIMIT 1",
+		data.SUPI).Scan(&storedKey, &version))
+	assert.NotEqual(t, data.PermanentKey, storedKey)
+	assert.Equal(t, uint32(1), version)
+
+	got, err := repo.GetAuthenticationSubscription(ctx, data.SUPI)
+	require.NoError(t, err)
+	assert.Equal(t, data.PermanentKey, got.PermanentKey)
+	assert.Equal(t, data.EncOPC, got.EncOPC)
+}
@@ -1,10 +1,35 @@
 package repository
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
+var (
+	// ErrInvalidSubscriber is returned when subscriber data fails validation
+	ErrInvalidSubscriber = errors.New("invalid subscriber")
+	// ErrInvalidFilter is returned when a subscriber listing filter is malformed
+	ErrInvalidFilter = errors.New("invalid subscriber filter")
+	// ErrInvalidCursor is returned for subscriber listing cursors that were
+	// not issued by the UDR
+	ErrInvalidCursor = errors.New("invalid subscriber cursor")
+	// ErrInvalidSQNBlockSize is returned when an SQN block of zero or more
+	// than MaxSQNBlockSize numbers is requested
+	ErrInvalidSQNBlockSize = errors.New("invalid SQN block size")
+)
+
+// MaxSQNBlockSize bounds the sequence numbers reserved at once, keeping
+// SQN_HE within reach of the USIM's freshness window (TS 33.102 C.2.2)
+const MaxSQNBlockSize = 1000
+
+// subscriberStatuses are the accepted subscriber status values
+var subscriberStatuses = map[string]bool{"ACTIVE": true, "INACTIVE": true, "SUSPENDED": true}
+
 // SubscriberData represents complete subscriber information (TS 29.505)
 type SubscriberData struct {
 	SUPI     string `json:"supi"`
@@ -42,6 +67,127 @@ type SubscriberData struct {
 	UpdatedAt time.Time `json:"updatedAt"`
 }
 
+// SubscriberFailure reports a subscriber of a batch that was not created
+type SubscriberFailure struct {
+	SUPI   string `json:"supi"`
+	Reason string `json:"reason"`
+}
+
+// Validate checks the fields a subscriber row cannot be stored without
+func (s *SubscriberData) Validate() error {
+	switch {
+	case s.SUPI == "":
+		return fmt.Errorf("%w: missing SUPI", ErrInvalidSubscriber)
+	case !strings.HasPrefix(s.SUPI, "imsi-") && !strings.HasPrefix(s.SUPI, "nai-"):
+		return fmt.Errorf("%w: SUPI %q is neither imsi- nor nai-", ErrInvalidSubscriber, s.SUPI)
+	case s.SUPIType != "" && s.SUPIType != "imsi" && s.SUPIType != "nai":
+		return fmt.Errorf("%w: unknown SUPI type %q", ErrInvalidSubscriber, s.SUPIType)
+	}
+	for _, snssai := range s.NSSAI {
+		if snssai.SST < 0 || snssai.SST > 255 {
+			return fmt.Errorf("%w: SST %d out of range", ErrInvalidSubscriber, snssai.SST)
+		}
+	}
+	return nil
+}
+
+// SubscriberFilter narrows a subscriber listing. Empty fields match any
+// subscriber.
+type SubscriberFilter struct {
+	PLMNIDmcc    string
+	PLMNIDmnc    string
+	Status       string
+	MSISDNPrefix string
+}
+
+// Validate checks the filter values before they reach a query
+func (f *SubscriberFilter) Validate() error {
+	switch {
+	case f.PLMNIDmcc != "" && (len(f.PLMNIDmcc) != 3 || !isDigits(f.PLMNIDmcc)):
+		return fmt.Errorf("%w: MCC %q must be 3 digits", ErrInvalidFilter, f.PLMNIDmcc)
+	case f.PLMNIDmnc != "" && (len(f.PLMNIDmnc) < 2 || len(f.PLMNIDmnc) > 3 || !isDigits(f.PLMNIDmnc)):
+		return fmt.Errorf("%w: MNC %q must be 2 or 3 digits", ErrInvalidFilter, f.PLMNIDmnc)
+	case f.Status != "" && !subscriberStatuses[f.Status]:
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidFilter, f.Status)
+	case f.MSISDNPrefix != "" && (len(f.MSISDNPrefix) > 15 || !isDigits(f.MSISDNPrefix)):
+		return fmt.Errorf("%w: MSISDN prefix %q must be up to 15 digits", ErrInvalidFilter, f.MSISDNPrefix)
+	}
+	return nil
+}
+
+// whereClause returns the WHERE clause selecting the filtered subscribers
+// and its arguments, or an empty clause when the filter matches everything
+func (f *SubscriberFilter) whereClause() (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if f.PLMNIDmcc != "" {
+		conditions = append(conditions, "plmn_id_mcc = ?")
+		args = append(args, f.PLMNIDmcc)
+	}
+	if f.PLMNIDmnc != "" {
+		conditions = append(conditions, "plmn_id_mnc = ?")
+		args = append(args, f.PLMNIDmnc)
+	}
+	if f.Status != "" {
+		conditions = append(conditions, "subscriber_status = ?")
+		args = append(args, f.Status)
+	}
+	if f.MSISDNPrefix != "" {
+		conditions = append(conditions, "startsWith(msisdn, ?)")
+		args = append(args, f.MSISDNPrefix)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// SubscriberCursor is the position of a subscriber in listings, which are
+// ordered by (created_at, supi), newest first. Paging by cursor neither skips
+// nor repeats subscribers when others are created between pages.
+type SubscriberCursor struct {
+	CreatedAt time.Time
+	SUPI      string
+}
+
+// CursorAfter returns the cursor of the page following a subscriber
+func CursorAfter(subscriber *SubscriberData) *SubscriberCursor {
+	return &SubscriberCursor{CreatedAt: subscriber.CreatedAt, SUPI: subscriber.SUPI}
+}
+
+// Encode returns the cursor as an opaque URL-safe string
+func (c *SubscriberCursor) Encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(c.CreatedAt.UnixNano(), 10) + ":" + c.SUPI))
+}
+
+// DecodeSubscriberCursor parses a cursor returned by Encode
+func DecodeSubscriberCursor(encoded string) (*SubscriberCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	nanos, supi, ok := strings.Cut(string(decoded), ":")
+	if !ok || supi == "" {
+		return nil, ErrInvalidCursor
+	}
+	createdAt, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	return &SubscriberCursor{CreatedAt: time.Unix(0, createdAt).UTC(), SUPI: supi}, nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
 // SNSSAI represents Single Network Slice Selection Assistance Information
 type SNSSAI struct {
 	SST int    `json:"sst"`          // Slice/Service Type (0-255)
@@ -86,6 +232,13 @@ type AuthenticationSubscription struct {
 	UpdatedAt time.Time `json:"updatedAt"`
 }
 
+// SQNBlock is a range of sequence numbers reserved for one UDM, First to
+// Last inclusive
+type SQNBlock struct {
+	First uint64 `json:"first"`
+	Last  uint64 `json:"last"`
+}
+
 // SessionManagementSubscriptionData represents SM subscription data
 type SessionManagementSubscriptionData struct {
 	SUPI string `json:"supi"`
@@ -119,11 +272,29 @@ type SessionManagementSubscriptionData struct {
 	UpdatedAt time.Time `json:"updatedAt"`
 }
 
+// AMF3GPPAccessRegistration is the AMF serving a UE over 3GPP access, kept
+// as UE context data for the UDM (TS 29.505, Clause 5.2.12)
+type AMF3GPPAccessRegistration struct {
+	AMFInstanceID          string          `json:"amfInstanceId"`
+	DeregCallbackURI       string          `json:"deregCallbackUri,omitempty"`
+	GUAMI                  json.RawMessage `json:"guami,omitempty"`
+	RATType                string          `json:"ratType,omitempty"`
+	PEI                    string          `json:"pei,omitempty"`
+	PurgeFlag              bool            `json:"purgeFlag,omitempty"`
+	InitialRegistrationInd bool            `json:"initialRegistrationInd,omitempty"`
+	BackupAMFInfo          json.RawMessage `json:"backupAmfInfo,omitempty"`
+	RegistrationTime       time.Time       `json:"registrationTime,omitempty"`
+}
+
 // SDMSubscription represents a subscription for data change notifications
+// (TS 29.505 SubscriptionDataSubscriptions). The UDM subscribes on behalf of
+// its own consumers, whose callback is kept in OriginalCallbackURI.
 type SDMSubscription struct {
 	SubscriptionID        string    `json:"subscriptionId"`
+	UEID                  string    `json:"ueId"` // SUPI
 	NFInstanceID          string    `json:"nfInstanceId"`
 	CallbackURI           string    `json:"callbackReference"`
+	OriginalCallbackURI   string    `json:"originalCallbackReference,omitempty"`
 	MonitoredResourceURIs []string  `json:"monitoredResourceUris"`
 	SingleNSSAI           *SNSSAI   `json:"singleNssai,omitempty"`
 	DNN                   string    `json:"dnn,omitempty"`
@@ -131,6 +302,28 @@ type SDMSubscription struct {
 	CreatedAt             time.Time `json:"createdAt"`
 }
 
+// DataChangeNotify is POSTed to the callback of the subscriptions monitoring
+// changed subscription data (TS 29.504)
+type DataChangeNotify struct {
+	OriginalCallbackReference []string     `json:"originalCallbackReference,omitempty"`
+	UEID                      string       `json:"ueId"`
+	NotifyItems               []NotifyItem `json:"notifyItems"`
+}
+
+// NotifyItem lists the changes of one resource
+type NotifyItem struct {
+	ResourceID string       `json:"resourceId"`
+	Changes    []ChangeItem `json:"changes"`
+}
+
+// ChangeItem is one change of a resource. Updates of whole resources are
+// reported as a REPLACE of the root path.
+type ChangeItem struct {
+	Op       string      `json:"op"` // ADD, MOVE, REMOVE, REPLACE
+	Path     string      `json:"path"`
+	NewValue interface{} `json:"newValue,omitempty"`
+}
+
 // PolicyData represents policy data for a subscriber
 type PolicyData struct {
 	SUPI                 string          `json:"supi"`
@@ -164,6 +357,13 @@ type AuthEvent struct {
 	FailureReason  string    `json:"failureReason,omitempty"`
 }
 
+// AuthEventFilter selects authentication events by time, unbounded on the
+// sides left zero
+type AuthEventFilter struct {
+	Since time.Time // Inclusive
+	Until time.Time // Exclusive
+}
+
 // MarshalJSON custom marshaling for SNSSAI arrays
 func (s *SubscriberData) MarshalNSSAI() (string, error) {
 	if len(s.NSSAI) == 0 {
@@ -199,3 +399,29 @@ func (s *SubscriberData) UnmarshalDNNConfigurations(data string) error {
 	}
 	return json.Unmarshal([]byte(data), &s.DNNConfigurations)
 }
+
+// MarshalSSCModes converts allowed SSC modes to a ClickHouse Array(UInt8)
+func (s *SessionManagementSubscriptionData) MarshalSSCModes() []uint8 {
+	modes := make([]uint8, 0, len(s.SSCModes))
+	for _, mode := range s.SSCModes {
+		modes = append(modes, uint8(mode))
+	}
+	return modes
+}
+
+// UnmarshalSSCModes sets allowed SSC modes from a ClickHouse Array(UInt8)
+func (s *SessionManagementSubscriptionData) UnmarshalSSCModes(modes []uint8) {
+	s.SSCModes = make([]int, 0, len(modes))
+	for _, mode := range modes {
+		s.SSCModes = append(s.SSCModes, int(mode))
+	}
+}
+
+// MarshalPDUSessionTypes returns the allowed PDU session types for a
+// ClickHouse Array(String), which does not accept nil
+func (s *SessionManagementSubscriptionData) MarshalPDUSessionTypes() []string {
+	if s.PDUSessionTypes == nil {
+		return []string{}
+	}
+	return s.PDUSessionTypes
+}
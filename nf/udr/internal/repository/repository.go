@@ -2,22 +2,31 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/your-org/5g-network/nf/udr/internal/clickhouse"
 	"go.uber.org/zap"
 )
 
+// ErrNotFound is returned when the requested record does not exist
+var ErrNotFound = errors.New("not found")
+
 // Repository defines the UDR data repository interface (TS 29.504, 29.505)
 type Repository interface {
 	// Subscriber Data Management (TS 29.505)
 	CreateSubscriber(ctx context.Context, data *SubscriberData) error
+	BatchCreateSubscribers(ctx context.Context, data []*SubscriberData) ([]SubscriberFailure, error)
 	GetSubscriber(ctx context.Context, supi string) (*SubscriberData, error)
 	UpdateSubscriber(ctx context.Context, supi string, data *SubscriberData) error
 	DeleteSubscriber(ctx context.Context, supi string) error
-	ListSubscribers(ctx context.Context, limit, offset int) ([]*SubscriberData, error)
+	ListSubscribers(ctx context.Context, filter SubscriberFilter, limit, offset int) ([]*SubscriberData, error)
+	ListSubscribersAfter(ctx context.Context, filter SubscriberFilter, after *SubscriberCursor, limit int) ([]*SubscriberData, error)
+	CountSubscribers(ctx context.Context, filter SubscriberFilter) (uint64, error)
 
 	// Authentication Subscription Data (TS 29.503)
 	CreateAuthenticationSubscription(ctx context.Context, data *AuthenticationSubscription) error
@@ -25,6 +34,8 @@ type Repository interface {
 	UpdateAuthenticationSubscription(ctx context.Context, supi string, data *AuthenticationSubscription) error
 	DeleteAuthenticationSubscription(ctx context.Context, supi string) error
 	IncrementSQN(ctx context.Context, supi string) (uint64, error)
+	SetSQN(ctx context.Context, supi string, sqn uint64) error
+	ReserveSQNBlock(ctx context.Context, supi string, size uint64) (*SQNBlock, error)
 
 	// Session Management Subscription Data
 	CreateSMSubscription(ctx context.Context, data *SessionManagementSubscriptionData) error
@@ -33,10 +44,20 @@ type Repository interface {
 	DeleteSMSubscription(ctx context.Context, supi, dnn string) error
 	ListSMSubscriptions(ctx context.Context, supi string) ([]*SessionManagementSubscriptionData, error)
 
+	// UE Context Data (TS 29.505), written by the UDM
+	PutAMF3GPPRegistration(ctx context.Context, supi string, data *AMF3GPPAccessRegistration) error
+	GetAMF3GPPRegistration(ctx context.Context, supi string) (*AMF3GPPAccessRegistration, error)
+	DeleteAMF3GPPRegistration(ctx context.Context, supi string) error
+
+	// Authentication events, recorded by the UDM for auditing
+	RecordAuthEvent(ctx context.Context, event *AuthEvent) error
+	ListAuthEvents(ctx context.Context, supi string, filter AuthEventFilter, limit int) ([]*AuthEvent, error)
+
 	// SDM Subscriptions (for notifications)
 	CreateSDMSubscription(ctx context.Context, sub *SDMSubscription) error
 	GetSDMSubscription(ctx context.Context, subscriptionID string) (*SDMSubscription, error)
 	DeleteSDMSubscription(ctx context.Context, subscriptionID string) error
+	ListSDMSubscriptions(ctx context.Context, supi string) ([]*SDMSubscription, error)
 
 	// Policy Data
 	CreatePolicyData(ctx context.Context, data *PolicyData) error
@@ -50,48 +71,55 @@ type Repository interface {
 
 // ClickHouseRepository implements Repository using ClickHouse
 type ClickHouseRepository struct {
-	client *clickhouse.Client
+	// Replaced by the health check on reconnection, read through conn
+	client       *clickhouse.Client
+	clientMu     sync.RWMutex
+	clientConfig *clickhouse.Config // Reconnection disabled when nil
+	connStats    ConnectionStats
+
 	logger *zap.Logger
+
+	// Encrypts authentication keys at rest, nil to store them as given
+	cipher FieldCipher
+
+	// Serializes SQN read-modify-writes per SUPI
+	sqnLocks supiLocks
 }
 
 // NewClickHouseRepository creates a new ClickHouse-based repository
 func NewClickHouseRepository(client *clickhouse.Client, logger *zap.Logger) *ClickHouseRepository {
 	return &ClickHouseRepository{
-		client: client,
-		logger: logger,
+		client:    client,
+		connStats: ConnectionStats{Healthy: true},
+		logger:    logger,
 	}
 }
 
-// CreateSubscriber creates a new subscriber
-func (r *ClickHouseRepository) CreateSubscriber(ctx context.Context, data *SubscriberData) error {
-	now := time.Now()
-	data.CreatedAt = now
-	data.UpdatedAt = now
+// subscriberColumns are the columns a subscriber row is inserted with, in
+// the order returned by subscriberRow
+const subscriberColumns = `
+			supi, supi_type, plmn_id_mcc, plmn_id_mnc,
+			subscriber_status, msisdn,
+			subscribed_ue_ambr_uplink, subscribed_ue_ambr_downlink,
+			nssai, dnn_configurations,
+			roaming_allowed, roaming_areas,
+			opc_key, authentication_method,
+			created_at, updated_at`
 
+// subscriberRow returns the column values of a subscriber row
+func subscriberRow(data *SubscriberData) ([]interface{}, error) {
 	// Marshal NSSAI and DNN configs
 	nssaiJSON, err := data.MarshalNSSAI()
 	if err != nil {
-		return fmt.Errorf("failed to marshal NSSAI: %w", err)
+		return nil, fmt.Errorf("failed to marshal NSSAI: %w", err)
 	}
 
 	dnnJSON, err := data.MarshalDNNConfigurations()
 	if err != nil {
-		return fmt.Errorf("failed to marshal DNN configurations: %w", err)
+		return nil, fmt.Errorf("failed to marshal DNN configurations: %w", err)
 	}
 
-	query := `
-		INSERT INTO udr.subscribers (
-			supi, supi_type, plmn_id_mcc, plmn_id_mnc,
-			subscriber_status, msisdn,
-			subscribed_ue_ambr_uplink, subscribed_ue_ambr_downlink,
-			nssai, dnn_configurations,
-			roaming_allowed, roaming_areas,
-			opc_key, authentication_method,
-			created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`
-
-	err = r.client.Exec(ctx, query,
+	return []interface{}{
 		data.SUPI, data.SUPIType, data.PLMNIDmcc, data.PLMNIDmnc,
 		data.SubscriberStatus, data.MSISDN,
 		data.SubscribedUeAmbrUplink, data.SubscribedUeAmbrDownlink,
@@ -99,9 +127,31 @@ func (r *ClickHouseRepository) CreateSubscriber(ctx context.Context, data *Subsc
 		data.RoamingAllowed, data.RoamingAreas,
 		data.OPCKey, data.AuthenticationMethod,
 		data.CreatedAt, data.UpdatedAt,
-	)
+	}, nil
+}
+
+// SetFieldCipher enables encryption of the permanent key, OPc and OP of
+// authentication subscriptions. Rows written before keep reading as
+// plaintext until they are updated.
+func (r *ClickHouseRepository) SetFieldCipher(cipher FieldCipher) {
+	r.cipher = cipher
+}
 
+// CreateSubscriber creates a new subscriber
+func (r *ClickHouseRepository) CreateSubscriber(ctx context.Context, data *SubscriberData) error {
+	now := time.Now()
+	data.CreatedAt = now
+	data.UpdatedAt = now
+
+	row, err := subscriberRow(data)
 	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO udr.subscribers (` + subscriberColumns + `
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	if err := r.conn().Exec(ctx, query, row...); err != nil {
 		return fmt.Errorf("failed to create subscriber: %w", err)
 	}
 
@@ -109,6 +159,84 @@ func (r *ClickHouseRepository) CreateSubscriber(ctx context.Context, data *Subsc
 	return nil
 }
 
+// BatchCreateSubscribers creates subscribers with a single batch insert.
+// Rows that fail validation or cannot be encoded are returned as failures.
+// The other rows are sent as one INSERT, so they are either all written or,
+// when an error is returned, none of them are.
+func (r *ClickHouseRepository) BatchCreateSubscribers(ctx context.Context, data []*SubscriberData) ([]SubscriberFailure, error) {
+	valid, failures := partitionSubscribers(data)
+
+	// Encode every row before preparing the batch, so that a row that cannot
+	// be encoded is reported without affecting the others
+	now := time.Now()
+	rows := make([][]interface{}, 0, len(valid))
+	supis := make([]string, 0, len(valid))
+	for _, subscriber := range valid {
+		subscriber.CreatedAt = now
+		subscriber.UpdatedAt = now
+
+		row, err := subscriberRow(subscriber)
+		if err != nil {
+			failures = append(failures, SubscriberFailure{SUPI: subscriber.SUPI, Reason: err.Error()})
+			continue
+		}
+		rows = append(rows, row)
+		supis = append(supis, subscriber.SUPI)
+	}
+	if len(rows) == 0 {
+		return failures, nil
+	}
+
+	batch, err := r.conn().PrepareBatch(ctx, `INSERT INTO udr.subscribers (`+subscriberColumns+`)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare subscriber batch: %w", err)
+	}
+
+	// A failed append can leave the batch with a partial row, so the whole
+	// batch is dropped rather than sent without it
+	for i, row := range rows {
+		if err := batch.Append(row...); err != nil {
+			batch.Abort()
+			return nil, fmt.Errorf("failed to append subscriber %s, no subscribers created: %w", supis[i], err)
+		}
+	}
+	if err := batch.Send(); err != nil {
+		return nil, fmt.Errorf("failed to send subscriber batch, no subscribers created: %w", err)
+	}
+
+	r.logger.Info("Subscribers created",
+		zap.Int("created", len(rows)),
+		zap.Int("failed", len(failures)),
+	)
+	return failures, nil
+}
+
+// partitionSubscribers splits a batch into the subscribers to insert and
+// failures for invalid or repeated SUPIs
+func partitionSubscribers(data []*SubscriberData) ([]*SubscriberData, []SubscriberFailure) {
+	valid := make([]*SubscriberData, 0, len(data))
+	var failures []SubscriberFailure
+	seen := make(map[string]bool, len(data))
+
+	for _, subscriber := range data {
+		if subscriber == nil {
+			failures = append(failures, SubscriberFailure{Reason: ErrInvalidSubscriber.Error() + ": empty entry"})
+			continue
+		}
+		if err := subscriber.Validate(); err != nil {
+			failures = append(failures, SubscriberFailure{SUPI: subscriber.SUPI, Reason: err.Error()})
+			continue
+		}
+		if seen[subscriber.SUPI] {
+			failures = append(failures, SubscriberFailure{SUPI: subscriber.SUPI, Reason: "duplicate SUPI in batch"})
+			continue
+		}
+		seen[subscriber.SUPI] = true
+		valid = append(valid, subscriber)
+	}
+	return valid, failures
+}
+
 // GetSubscriber retrieves a subscriber by SUPI
 func (r *ClickHouseRepository) GetSubscriber(ctx context.Context, supi string) (*SubscriberData, error) {
 	query := `
@@ -131,7 +259,7 @@ func (r *ClickHouseRepository) GetSubscriber(ctx context.Context, supi string) (
 	var dnnJSON string
 	var roamingAreas []string // ClickHouse Array(String)
 
-	row := r.client.QueryRow(ctx, query, supi)
+	row := r.conn().QueryRow(ctx, query, supi)
 	err := row.Scan(
 		&data.SUPI, &data.SUPIType, &data.PLMNIDmcc, &data.PLMNIDmnc,
 		&data.SubscriberStatus, &data.MSISDN,
@@ -201,7 +329,7 @@ func (r *ClickHouseRepository) UpdateSubscriber(ctx context.Context, supi string
 		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	err = r.client.Exec(ctx, query,
+	err = r.conn().Exec(ctx, query,
 		data.SUPI, data.SUPIType, data.PLMNIDmcc, data.PLMNIDmnc,
 		data.SubscriberStatus, data.MSISDN,
 		data.SubscribedUeAmbrUplink, data.SubscribedUeAmbrDownlink,
@@ -227,7 +355,7 @@ func (r *ClickHouseRepository) DeleteSubscriber(ctx context.Context, supi string
 		DELETE WHERE supi = ?
 	`
 
-	err := r.client.Exec(ctx, query, supi)
+	err := r.conn().Exec(ctx, query, supi)
 	if err != nil {
 		return fmt.Errorf("failed to delete subscriber: %w", err)
 	}
@@ -236,23 +364,71 @@ func (r *ClickHouseRepository) DeleteSubscriber(ctx context.Context, supi string
 	return nil
 }
 
-// ListSubscribers lists subscribers with pagination
-func (r *ClickHouseRepository) ListSubscribers(ctx context.Context, limit, offset int) ([]*SubscriberData, error) {
+// ListSubscribers lists subscribers with offset pagination.
+//
+// Deprecated: offsets skip or repeat subscribers created between pages and
+// slow down with the offset; use ListSubscribersAfter.
+func (r *ClickHouseRepository) ListSubscribers(ctx context.Context, filter SubscriberFilter, limit, offset int) ([]*SubscriberData, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	where, args := filter.whereClause()
+
 	query := `
-		SELECT 
+		SELECT ` + subscriberListColumns + `
+		FROM udr.subscribers
+		` + where + `
+		ORDER BY created_at DESC, supi DESC
+		LIMIT ? OFFSET ?
+	`
+
+	return r.querySubscribers(ctx, query, append(args, limit, offset)...)
+}
+
+// ListSubscribersAfter lists up to limit subscribers following a cursor,
+// from the newest when after is nil. The cursor of the next page is that of
+// the last subscriber returned (CursorAfter).
+func (r *ClickHouseRepository) ListSubscribersAfter(ctx context.Context, filter SubscriberFilter, after *SubscriberCursor, limit int) ([]*SubscriberData, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	where, args := filter.whereClause()
+
+	if after != nil {
+		if where == "" {
+			where = "WHERE "
+		} else {
+			where += " AND "
+		}
+		where += "(created_at, supi) < (?, ?)"
+		args = append(args, after.CreatedAt, after.SUPI)
+	}
+
+	query := `
+		SELECT ` + subscriberListColumns + `
+		FROM udr.subscribers
+		` + where + `
+		ORDER BY created_at DESC, supi DESC
+		LIMIT ?
+	`
+
+	return r.querySubscribers(ctx, query, append(args, limit)...)
+}
+
+// subscriberListColumns lists the udr.subscribers columns of listings in scan
+// order
+const subscriberListColumns = `
 			supi, supi_type, plmn_id_mcc, plmn_id_mnc,
 			subscriber_status, msisdn,
 			subscribed_ue_ambr_uplink, subscribed_ue_ambr_downlink,
 			nssai, dnn_configurations,
 			roaming_allowed, roaming_areas,
 			opc_key, authentication_method,
-			created_at, updated_at
-		FROM udr.subscribers
-		ORDER BY created_at DESC
-		LIMIT ? OFFSET ?
-	`
+			created_at, updated_at`
 
-	rows, err := r.client.Query(ctx, query, limit, offset)
+// querySubscribers runs a subscriber listing query
+func (r *ClickHouseRepository) querySubscribers(ctx context.Context, query string, args ...interface{}) ([]*SubscriberData, error) {
+	rows, err := r.conn().Query(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list subscribers: %w", err)
 	}
@@ -288,6 +464,24 @@ func (r *ClickHouseRepository) ListSubscribers(ctx context.Context, limit, offse
 	return subscribers, nil
 }
 
+// CountSubscribers returns how many subscribers match the filter,
+// independent of any listing page
+func (r *ClickHouseRepository) CountSubscribers(ctx context.Context, filter SubscriberFilter) (uint64, error) {
+	if err := filter.Validate(); err != nil {
+		return 0, err
+	}
+	where, args := filter.whereClause()
+
+	// Updates insert new rows, count each SUPI once
+	query := `SELECT uniqExact(supi) FROM udr.subscribers ` + where
+
+	var total uint64
+	if err := r.conn().QueryRow(ctx, query, args...).Scan(&total); err != nil {
+		return 0, fmt.Errorf("failed to count subscribers: %w", err)
+	}
+	return total, nil
+}
+
 // CreateAuthenticationSubscription creates authentication subscription data
 func (r *ClickHouseRepository) CreateAuthenticationSubscription(ctx context.Context, data *AuthenticationSubscription) error {
 	now := time.Now()
@@ -301,16 +495,23 @@ func (r *ClickHouseRepository) CreateAuthenticationSubscription(ctx context.Cont
 			enc_algorithm, enc_opc, enc_op,
 			sqn, sqn_scheme,
 			authentication_management_field,
+			key_version,
 			created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	err := r.client.Exec(ctx, query,
+	keys, err := r.encryptAuthKeys(data)
+	if err != nil {
+		return err
+	}
+
+	err = r.conn().Exec(ctx, query,
 		data.SUPI, data.AuthenticationMethod,
-		data.PermanentKey, data.PermanentKeyID,
-		data.EncAlgorithm, data.EncOPC, data.EncOP,
+		keys.permanentKey, data.PermanentKeyID,
+		data.EncAlgorithm, keys.encOPC, keys.encOP,
 		data.SQN, data.SQNScheme,
 		data.AuthenticationManagementField,
+		keys.version,
 		data.CreatedAt, data.UpdatedAt,
 	)
 
@@ -331,15 +532,17 @@ func (r *ClickHouseRepository) GetAuthenticationSubscription(ctx context.Context
 			enc_algorithm, enc_opc, enc_op,
 			sqn, sqn_scheme,
 			authentication_management_field,
+			key_version,
 			created_at, updated_at
 		FROM udr.authentication_subscription
 		WHERE supi = ?
-		ORDER BY updated_at DESC
+		ORDER BY updated_at DESC, sqn DESC
 		LIMIT 1
 	`
 
 	var data AuthenticationSubscription
-	row := r.client.QueryRow(ctx, query, supi)
+	var keyVersion uint32
+	row := r.conn().QueryRow(ctx, query, supi)
 
 	err := row.Scan(
 		&data.SUPI, &data.AuthenticationMethod,
@@ -347,6 +550,7 @@ func (r *ClickHouseRepository) GetAuthenticationSubscription(ctx context.Context
 		&data.EncAlgorithm, &data.EncOPC, &data.EncOP,
 		&data.SQN, &data.SQNScheme,
 		&data.AuthenticationManagementField,
+		&keyVersion,
 		&data.CreatedAt, &data.UpdatedAt,
 	)
 
@@ -354,9 +558,88 @@ func (r *ClickHouseRepository) GetAuthenticationSubscription(ctx context.Context
 		return nil, fmt.Errorf("authentication subscription not found: %w", err)
 	}
 
+	if err := r.decryptAuthKeys(&data, keyVersion); err != nil {
+		return nil, err
+	}
 	return &data, nil
 }
 
+// storedAuthKeys are the authentication key columns as written to the
+// database
+type storedAuthKeys struct {
+	permanentKey, encOPC, encOP string
+	version                     uint32
+}
+
+// encryptAuthKeys returns the key columns of an authentication subscription,
+// encrypted when a cipher is set. data keeps the plaintext values.
+func (r *ClickHouseRepository) encryptAuthKeys(data *AuthenticationSubscription) (*storedAuthKeys, error) {
+	keys := &storedAuthKeys{
+		permanentKey: data.PermanentKey,
+		encOPC:       data.EncOPC,
+		encOP:        data.EncOP,
+		version:      PlaintextKeyVersion,
+	}
+	if r.cipher == nil {
+		return keys, nil
+	}
+
+	for _, field := range []struct {
+		column string
+		value  *string
+	}{
+		{"permanent_key", &keys.permanentKey},
+		{"enc_opc", &keys.encOPC},
+		{"enc_op", &keys.encOP},
+	} {
+		if *field.value == "" {
+			continue
+		}
+		ciphertext, version, err := r.cipher.Encrypt(*field.value, authKeyAssociatedData(data.SUPI, field.column))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt %s: %w", field.column, err)
+		}
+		*field.value = ciphertext
+		keys.version = version
+	}
+	return keys, nil
+}
+
+// decryptAuthKeys replaces the stored key columns of an authentication
+// subscription with their plaintext
+func (r *ClickHouseRepository) decryptAuthKeys(data *AuthenticationSubscription, version uint32) error {
+	if version == PlaintextKeyVersion {
+		return nil
+	}
+	if r.cipher == nil {
+		return fmt.Errorf("%w: %d, no cipher configured", ErrUnknownKeyVersion, version)
+	}
+
+	for _, field := range []struct {
+		column string
+		value  *string
+	}{
+		{"permanent_key", &data.PermanentKey},
+		{"enc_opc", &data.EncOPC},
+		{"enc_op", &data.EncOP},
+	} {
+		if *field.value == "" {
+			continue
+		}
+		plaintext, err := r.cipher.Decrypt(*field.value, authKeyAssociatedData(data.SUPI, field.column), version)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s: %w", field.column, err)
+		}
+		*field.value = plaintext
+	}
+	return nil
+}
+
+// authKeyAssociatedData binds an encrypted key to its subscriber and column
+func authKeyAssociatedData(supi, column string) string {
+	return "authentication_subscription/" + supi + "/" + column
+}
+
 // UpdateAuthenticationSubscription updates authentication subscription data
 func (r *ClickHouseRepository) UpdateAuthenticationSubscription(ctx context.Context, supi string, data *AuthenticationSubscription) error {
 	data.UpdatedAt = time.Now()
@@ -368,16 +651,23 @@ func (r *ClickHouseRepository) UpdateAuthenticationSubscription(ctx context.Cont
 			enc_algorithm, enc_opc, enc_op,
 			sqn, sqn_scheme,
 			authentication_management_field,
+			key_version,
 			created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	err := r.client.Exec(ctx, query,
+	keys, err := r.encryptAuthKeys(data)
+	if err != nil {
+		return err
+	}
+
+	err = r.conn().Exec(ctx, query,
 		data.SUPI, data.AuthenticationMethod,
-		data.PermanentKey, data.PermanentKeyID,
-		data.EncAlgorithm, data.EncOPC, data.EncOP,
+		keys.permanentKey, data.PermanentKeyID,
+		data.EncAlgorithm, keys.encOPC, keys.encOP,
 		data.SQN, data.SQNScheme,
 		data.AuthenticationManagementField,
+		keys.version,
 		data.CreatedAt, data.UpdatedAt,
 	)
 
@@ -396,7 +686,7 @@ func (r *ClickHouseRepository) DeleteAuthenticationSubscription(ctx context.Cont
 		DELETE WHERE supi = ?
 	`
 
-	err := r.client.Exec(ctx, query, supi)
+	err := r.conn().Exec(ctx, query, supi)
 	if err != nil {
 		return fmt.Errorf("failed to delete authentication subscription: %w", err)
 	}
@@ -405,8 +695,13 @@ func (r *ClickHouseRepository) DeleteAuthenticationSubscription(ctx context.Cont
 	return nil
 }
 
-// IncrementSQN atomically increments the SQN for a subscriber
+// IncrementSQN atomically increments the SQN for a subscriber. Concurrent
+// increments for the same SUPI are serialized, so each returns a distinct
+// SQN (TS 33.102 C.1.1).
 func (r *ClickHouseRepository) IncrementSQN(ctx context.Context, supi string) (uint64, error) {
+	unlock := r.sqnLocks.lock(supi)
+	defer unlock()
+
 	// Get current SQN
 	authSub, err := r.GetAuthenticationSubscription(ctx, supi)
 	if err != nil {
@@ -425,9 +720,49 @@ func (r *ClickHouseRepository) IncrementSQN(ctx context.Context, supi string) (u
 	return newSQN, nil
 }
 
+// SetSQN overwrites the SQN for a subscriber, e.g. with SQN_MS recovered
+// during resynchronisation
+func (r *ClickHouseRepository) SetSQN(ctx context.Context, supi string, sqn uint64) error {
+	unlock := r.sqnLocks.lock(supi)
+	defer unlock()
+
+	authSub, err := r.GetAuthenticationSubscription(ctx, supi)
+	if err != nil {
+		return err
+	}
+
+	authSub.SQN = sqn
+	return r.UpdateAuthenticationSubscription(ctx, supi, authSub)
+}
+
+// ReserveSQNBlock reserves the next size sequence numbers of a subscriber
+// with a single update, storing the last one as the new SQN. The caller
+// hands the block out locally; numbers it never uses are skipped.
+func (r *ClickHouseRepository) ReserveSQNBlock(ctx context.Context, supi string, size uint64) (*SQNBlock, error) {
+	if size == 0 || size > MaxSQNBlockSize {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidSQNBlockSize, size)
+	}
+
+	unlock := r.sqnLocks.lock(supi)
+	defer unlock()
+
+	authSub, err := r.GetAuthenticationSubscription(ctx, supi)
+	if err != nil {
+		return nil, err
+	}
+
+	block := &SQNBlock{First: authSub.SQN + 1, Last: authSub.SQN + size}
+	authSub.SQN = block.Last
+	if err := r.UpdateAuthenticationSubscription(ctx, supi, authSub); err != nil {
+		return nil, err
+	}
+
+	return block, nil
+}
+
 // Ping checks database connectivity
 func (r *ClickHouseRepository) Ping(ctx context.Context) error {
-	return r.client.Ping(ctx)
+	return r.conn().Ping(ctx)
 }
 
 // GetStats returns repository statistics
@@ -440,59 +775,361 @@ func (r *ClickHouseRepository) GetStats(ctx context.Context) (*Stats, error) {
 	`
 
 	var stats Stats
-	row := r.client.QueryRow(ctx, query)
+	row := r.conn().QueryRow(ctx, query)
 	err := row.Scan(&stats.TotalSubscribers, &stats.TotalPLMNs)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get stats: %w", err)
 	}
+	stats.Connection = r.connectionStats()
 
 	return &stats, nil
 }
 
-// CreateSMSubscription, GetSMSubscription, etc. would follow similar patterns
-// Implementing stubs for now
-
+// CreateSMSubscription creates SM subscription data for a SUPI and DNN
 func (r *ClickHouseRepository) CreateSMSubscription(ctx context.Context, data *SessionManagementSubscriptionData) error {
-	// TODO: Implement
+	now := time.Now()
+	data.CreatedAt = now
+	data.UpdatedAt = now
+
+	if err := r.insertSMSubscription(ctx, data); err != nil {
+		return fmt.Errorf("failed to create SM subscription: %w", err)
+	}
+
+	r.logger.Info("SM subscription created", zap.String("supi", data.SUPI), zap.String("dnn", data.DNN))
 	return nil
 }
 
+// GetSMSubscription retrieves SM subscription data for a SUPI and DNN
 func (r *ClickHouseRepository) GetSMSubscription(ctx context.Context, supi, dnn string) (*SessionManagementSubscriptionData, error) {
-	// TODO: Implement
-	return nil, fmt.Errorf("not implemented")
+	query := `
+		SELECT ` + smSubscriptionColumns + `
+		FROM udr.sm_subscriptions
+		WHERE supi = ? AND dnn = ?
+		ORDER BY updated_at DESC
+		LIMIT 1
+	`
+
+	data, err := scanSMSubscription(r.conn().QueryRow(ctx, query, supi, dnn))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("SM subscription for %s/%s: %w", supi, dnn, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SM subscription: %w", err)
+	}
+
+	return data, nil
 }
 
+// UpdateSMSubscription updates SM subscription data for a SUPI and DNN
 func (r *ClickHouseRepository) UpdateSMSubscription(ctx context.Context, supi, dnn string, data *SessionManagementSubscriptionData) error {
-	// TODO: Implement
+	data.SUPI = supi
+	data.DNN = dnn
+	data.UpdatedAt = time.Now()
+	if data.CreatedAt.IsZero() {
+		data.CreatedAt = data.UpdatedAt
+	}
+
+	// In ClickHouse with ReplacingMergeTree, we INSERT with same key to update
+	if err := r.insertSMSubscription(ctx, data); err != nil {
+		return fmt.Errorf("failed to update SM subscription: %w", err)
+	}
+
+	r.logger.Info("SM subscription updated", zap.String("supi", supi), zap.String("dnn", dnn))
 	return nil
 }
 
+// DeleteSMSubscription deletes SM subscription data for a SUPI and DNN
 func (r *ClickHouseRepository) DeleteSMSubscription(ctx context.Context, supi, dnn string) error {
-	// TODO: Implement
+	query := `
+		ALTER TABLE udr.sm_subscriptions
+		DELETE WHERE supi = ? AND dnn = ?
+	`
+
+	err := r.conn().Exec(ctx, query, supi, dnn)
+	if err != nil {
+		return fmt.Errorf("failed to delete SM subscription: %w", err)
+	}
+
+	r.logger.Info("SM subscription deleted", zap.String("supi", supi), zap.String("dnn", dnn))
 	return nil
 }
 
+// ListSMSubscriptions lists the SM subscription data of a SUPI, one entry per DNN
 func (r *ClickHouseRepository) ListSMSubscriptions(ctx context.Context, supi string) ([]*SessionManagementSubscriptionData, error) {
-	// TODO: Implement
-	return nil, nil
+	// FINAL collapses rows replaced by updates that are not merged yet
+	query := `
+		SELECT ` + smSubscriptionColumns + `
+		FROM udr.sm_subscriptions FINAL
+		WHERE supi = ?
+		ORDER BY dnn
+	`
+
+	rows, err := r.conn().Query(ctx, query, supi)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list SM subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subscriptions []*SessionManagementSubscriptionData
+	for rows.Next() {
+		data, err := scanSMSubscription(rows)
+		if err != nil {
+			r.logger.Error("Failed to scan SM subscription", zap.Error(err))
+			continue
+		}
+		subscriptions = append(subscriptions, data)
+	}
+
+	return subscriptions, nil
+}
+
+// smSubscriptionColumns lists the udr.sm_subscriptions columns in scan order
+const smSubscriptionColumns = `
+			supi, dnn,
+			session_ambr_uplink, session_ambr_downlink,
+			default_5qi, arp_priority_level,
+			ssc_modes, default_ssc_mode,
+			pdu_session_types, default_pdu_session_type,
+			static_ip_address, static_ipv6_prefix,
+			charging_characteristics,
+			created_at, updated_at`
+
+// insertSMSubscription writes one row of SM subscription data
+func (r *ClickHouseRepository) insertSMSubscription(ctx context.Context, data *SessionManagementSubscriptionData) error {
+	query := `
+		INSERT INTO udr.sm_subscriptions (` + smSubscriptionColumns + `
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	return r.conn().Exec(ctx, query,
+		data.SUPI, data.DNN,
+		data.SessionAMBRUplink, data.SessionAMBRDownlink,
+		uint8(data.Default5QI), uint8(data.ARPPriorityLevel),
+		data.MarshalSSCModes(), uint8(data.DefaultSSCMode),
+		data.MarshalPDUSessionTypes(), data.DefaultPDUSessionType,
+		data.StaticIPAddress, data.StaticIPv6Prefix,
+		data.ChargingCharacteristics,
+		data.CreatedAt, data.UpdatedAt,
+	)
 }
 
+// rowScanner is satisfied by both a single row and a row cursor
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanSMSubscription scans a row selected with smSubscriptionColumns
+func scanSMSubscription(row rowScanner) (*SessionManagementSubscriptionData, error) {
+	var data SessionManagementSubscriptionData
+	var default5QI, arpPriorityLevel, defaultSSCMode uint8
+	var sscModes []uint8 // ClickHouse Array(UInt8)
+
+	err := row.Scan(
+		&data.SUPI, &data.DNN,
+		&data.SessionAMBRUplink, &data.SessionAMBRDownlink,
+		&default5QI, &arpPriorityLevel,
+		&sscModes, &defaultSSCMode,
+		&data.PDUSessionTypes, &data.DefaultPDUSessionType,
+		&data.StaticIPAddress, &data.StaticIPv6Prefix,
+		&data.ChargingCharacteristics,
+		&data.CreatedAt, &data.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	data.Default5QI = int(default5QI)
+	data.ARPPriorityLevel = int(arpPriorityLevel)
+	data.DefaultSSCMode = int(defaultSSCMode)
+	data.UnmarshalSSCModes(sscModes)
+
+	return &data, nil
+}
+
+// PutAMF3GPPRegistration stores the AMF registration of a SUPI, replacing
+// any previous one
+func (r *ClickHouseRepository) PutAMF3GPPRegistration(ctx context.Context, supi string, data *AMF3GPPAccessRegistration) error {
+	registration, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal AMF registration: %w", err)
+	}
+
+	if err := r.insertAMF3GPPRegistration(ctx, supi, data.AMFInstanceID, string(registration), false); err != nil {
+		return fmt.Errorf("failed to store AMF registration: %w", err)
+	}
+
+	r.logger.Info("AMF registration stored", zap.String("supi", supi), zap.String("amf_instance_id", data.AMFInstanceID))
+	return nil
+}
+
+// GetAMF3GPPRegistration retrieves the AMF registration of a SUPI
+func (r *ClickHouseRepository) GetAMF3GPPRegistration(ctx context.Context, supi string) (*AMF3GPPAccessRegistration, error) {
+	query := `
+		SELECT registration, deleted
+		FROM udr.amf_3gpp_access_registrations
+		WHERE supi = ?
+		ORDER BY updated_at DESC
+		LIMIT 1
+	`
+
+	var registration string
+	var deleted uint8
+	err := r.conn().QueryRow(ctx, query, supi).Scan(&registration, &deleted)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && deleted != 0) {
+		return nil, fmt.Errorf("AMF registration for %s: %w", supi, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get AMF registration: %w", err)
+	}
+
+	var data AMF3GPPAccessRegistration
+	if err := json.Unmarshal([]byte(registration), &data); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal AMF registration: %w", err)
+	}
+	return &data, nil
+}
+
+// DeleteAMF3GPPRegistration removes the AMF registration of a SUPI
+func (r *ClickHouseRepository) DeleteAMF3GPPRegistration(ctx context.Context, supi string) error {
+	if _, err := r.GetAMF3GPPRegistration(ctx, supi); err != nil {
+		return err
+	}
+
+	if err := r.insertAMF3GPPRegistration(ctx, supi, "", "", true); err != nil {
+		return fmt.Errorf("failed to delete AMF registration: %w", err)
+	}
+
+	r.logger.Info("AMF registration deleted", zap.String("supi", supi))
+	return nil
+}
+
+// insertAMF3GPPRegistration writes one row of AMF registration, a tombstone
+// when deleted
+func (r *ClickHouseRepository) insertAMF3GPPRegistration(ctx context.Context, supi, amfInstanceID, registration string, deleted bool) error {
+	query := `
+		INSERT INTO udr.amf_3gpp_access_registrations (
+			supi, amf_instance_id, registration, deleted, updated_at
+		) VALUES (?, ?, ?, ?, ?)
+	`
+
+	var tombstone uint8
+	if deleted {
+		tombstone = 1
+	}
+	return r.conn().Exec(ctx, query, supi, amfInstanceID, registration, tombstone, time.Now())
+}
+
+// CreateSDMSubscription stores a data change subscription
 func (r *ClickHouseRepository) CreateSDMSubscription(ctx context.Context, sub *SDMSubscription) error {
-	// TODO: Implement
+	subscription, err := json.Marshal(sub)
+	if err != nil {
+		return fmt.Errorf("failed to marshal SDM subscription: %w", err)
+	}
+
+	if err := r.insertSDMSubscription(ctx, sub.SubscriptionID, sub.UEID, string(subscription), false); err != nil {
+		return fmt.Errorf("failed to create SDM subscription: %w", err)
+	}
+
+	r.logger.Info("SDM subscription created",
+		zap.String("subscription_id", sub.SubscriptionID),
+		zap.String("supi", sub.UEID),
+	)
 	return nil
 }
 
+// GetSDMSubscription retrieves a data change subscription
 func (r *ClickHouseRepository) GetSDMSubscription(ctx context.Context, subscriptionID string) (*SDMSubscription, error) {
-	// TODO: Implement
-	return nil, fmt.Errorf("not implemented")
+	query := `
+		SELECT subscription, deleted
+		FROM udr.sdm_subscriptions
+		WHERE subscription_id = ?
+		ORDER BY updated_at DESC
+		LIMIT 1
+	`
+
+	var subscription string
+	var deleted uint8
+	err := r.conn().QueryRow(ctx, query, subscriptionID).Scan(&subscription, &deleted)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && deleted != 0) {
+		return nil, fmt.Errorf("SDM subscription %s: %w", subscriptionID, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SDM subscription: %w", err)
+	}
+
+	var sub SDMSubscription
+	if err := json.Unmarshal([]byte(subscription), &sub); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal SDM subscription: %w", err)
+	}
+	return &sub, nil
 }
 
+// DeleteSDMSubscription removes a data change subscription
 func (r *ClickHouseRepository) DeleteSDMSubscription(ctx context.Context, subscriptionID string) error {
-	// TODO: Implement
+	sub, err := r.GetSDMSubscription(ctx, subscriptionID)
+	if err != nil {
+		return err
+	}
+
+	if err := r.insertSDMSubscription(ctx, subscriptionID, sub.UEID, "", true); err != nil {
+		return fmt.Errorf("failed to delete SDM subscription: %w", err)
+	}
+
+	r.logger.Info("SDM subscription deleted", zap.String("subscription_id", subscriptionID))
 	return nil
 }
 
+// ListSDMSubscriptions lists the data change subscriptions of a SUPI
+func (r *ClickHouseRepository) ListSDMSubscriptions(ctx context.Context, supi string) ([]*SDMSubscription, error) {
+	// FINAL keeps the latest row of each subscription, tombstones included
+	query := `
+		SELECT subscription
+		FROM udr.sdm_subscriptions FINAL
+		WHERE supi = ? AND deleted = 0
+		ORDER BY subscription_id
+	`
+
+	rows, err := r.conn().Query(ctx, query, supi)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list SDM subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subscriptions []*SDMSubscription
+	for rows.Next() {
+		var subscription string
+		if err := rows.Scan(&subscription); err != nil {
+			r.logger.Error("Failed to scan SDM subscription", zap.Error(err))
+			continue
+		}
+		var sub SDMSubscription
+		if err := json.Unmarshal([]byte(subscription), &sub); err != nil {
+			r.logger.Error("Failed to unmarshal SDM subscription", zap.Error(err))
+			continue
+		}
+		subscriptions = append(subscriptions, &sub)
+	}
+
+	return subscriptions, nil
+}
+
+// insertSDMSubscription writes one row of data change subscription, a
+// tombstone when deleted
+func (r *ClickHouseRepository) insertSDMSubscription(ctx context.Context, subscriptionID, supi, subscription string, deleted bool) error {
+	query := `
+		INSERT INTO udr.sdm_subscriptions (
+			subscription_id, supi, subscription, deleted, updated_at
+		) VALUES (?, ?, ?, ?, ?)
+	`
+
+	var tombstone uint8
+	if deleted {
+		tombstone = 1
+	}
+	return r.conn().Exec(ctx, query, subscriptionID, supi, subscription, tombstone, time.Now())
+}
+
 func (r *ClickHouseRepository) CreatePolicyData(ctx context.Context, data *PolicyData) error {
 	// TODO: Implement
 	return nil
@@ -510,6 +1147,7 @@ func (r *ClickHouseRepository) UpdatePolicyData(ctx context.Context, supi string
 
 // Stats represents repository statistics
 type Stats struct {
-	TotalSubscribers int `json:"total_subscribers"`
-	TotalPLMNs       int `json:"total_plmns"`
+	TotalSubscribers int             `json:"total_subscribers"`
+	TotalPLMNs       int             `json:"total_plmns"`
+	Connection       ConnectionStats `json:"connection"`
 }
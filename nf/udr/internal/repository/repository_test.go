@@ -0,0 +1,561 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/nf/udr/internal/clickhouse"
+)
+
+// newTestRepository connects to the ClickHouse server named by
+// UDR_TEST_CLICKHOUSE_ADDR, skipping the test when it is not set. The
+// subscribers table is expected to exist (internal/clickhouse/schema.sql).
+func newTestRepository(t testing.TB) *ClickHouseRepository {
+	t.Helper()
+
+	addr := os.Getenv("UDR_TEST_CLICKHOUSE_ADDR")
+	if addr == "" {
+		t.Skip("UDR_TEST_CLICKHOUSE_ADDR not set")
+	}
+
+	logger, _ := zap.NewDevelopment()
+	client, err := clickhouse.NewClient(&clickhouse.Config{
+		Addresses:    strings.Split(addr, ","),
+		Database:     "udr",
+		Username:     "default",
+		MaxOpenConns: 2,
+		MaxIdleConns: 1,
+		Timeout:      10 * time.Second,
+	}, logger)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	ctx := context.Background()
+	require.NoError(t, client.Exec(ctx, "CREATE DATABASE IF NOT EXISTS udr"))
+	require.NoError(t, client.Exec(ctx, SMSubscriptionsSchema))
+	require.NoError(t, client.Exec(ctx, AMFRegistrationsSchema))
+	require.NoError(t, client.Exec(ctx, AuthEventsSchema))
+
+	return NewClickHouseRepository(client, logger)
+}
+
+func TestSMSubscriptionCRUD(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+	supi := "imsi-00101" + strings.ReplaceAll(time.Now().Format("150405.000"), ".", "")
+
+	internet := &SessionManagementSubscriptionData{
+		SUPI:                  supi,
+		DNN:                   "internet",
+		SessionAMBRUplink:     1000000000,
+		SessionAMBRDownlink:   2000000000,
+		Default5QI:            9,
+		ARPPriorityLevel:      8,
+		SSCModes:              []int{1, 2},
+		DefaultSSCMode:        1,
+		PDUSessionTypes:       []string{"IPV4", "IPV4V6"},
+		DefaultPDUSessionType: "IPV4",
+	}
+	ims := &SessionManagementSubscriptionData{
+		SUPI:                  supi,
+		DNN:                   "ims",
+		Default5QI:            5,
+		SSCModes:              []int{1},
+		DefaultSSCMode:        1,
+		DefaultPDUSessionType: "IPV4V6",
+	}
+	require.NoError(t, repo.CreateSMSubscription(ctx, internet))
+	require.NoError(t, repo.CreateSMSubscription(ctx, ims))
+
+	got, err := repo.GetSMSubscription(ctx, supi, "internet")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2000000000), got.SessionAMBRDownlink)
+	assert.Equal(t, 9, got.Default5QI)
+	assert.Equal(t, []int{1, 2}, got.SSCModes)
+	assert.Equal(t, []string{"IPV4", "IPV4V6"}, got.PDUSessionTypes)
+
+	list, err := repo.ListSMSubscriptions(ctx, supi)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "ims", list[0].DNN)
+	assert.Equal(t, "internet", list[1].DNN)
+
+	require.NoError(t, repo.DeleteSMSubscription(ctx, supi, "internet"))
+
+	// ALTER TABLE DELETE is applied as an asynchronous mutation
+	assert.Eventually(t, func() bool {
+		_, err := repo.GetSMSubscription(ctx, supi, "internet")
+		return errors.Is(err, ErrNotFound)
+	}, 10*time.Second, 100*time.Millisecond)
+
+	list, err = repo.ListSMSubscriptions(ctx, supi)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "ims", list[0].DNN)
+}
+
+func TestAuthEventsFilteredByTime(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+	supi := "imsi-00101" + strings.ReplaceAll(time.Now().Format("150405.000"), ".", "")
+
+	start := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+	for i := 0; i < 3; i++ {
+		event := &AuthEvent{
+			SUPI:           supi,
+			Success:        i > 0,
+			AuthMethod:     "5G_AKA",
+			ServingNetwork: "5G:mnc001.mcc001.3gppnetwork.org",
+			Timestamp:      start.Add(time.Duration(i) * time.Minute),
+		}
+		if !event.Success {
+			event.FailureReason = "RES* mismatch"
+		}
+		require.NoError(t, repo.RecordAuthEvent(ctx, event))
+	}
+
+	events, err := repo.ListAuthEvents(ctx, supi, AuthEventFilter{}, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.True(t, events[0].Timestamp.Equal(start.Add(2*time.Minute)))
+	assert.False(t, events[2].Success)
+	assert.Equal(t, "RES* mismatch", events[2].FailureReason)
+
+	events, err = repo.ListAuthEvents(ctx, supi, AuthEventFilter{Since: start.Add(time.Minute)}, 10)
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+
+	events, err = repo.ListAuthEvents(ctx, supi, AuthEventFilter{Until: start.Add(time.Minute)}, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.False(t, events[0].Success)
+
+	events, err = repo.ListAuthEvents(ctx, supi, AuthEventFilter{}, 1)
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+}
+
+func TestGetSMSubscriptionNotFound(t *testing.T) {
+	repo := newTestRepository(t)
+
+	_, err := repo.GetSMSubscription(context.Background(), "imsi-001019999999999", "internet")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+// testSubscribers returns n valid subscribers with SUPIs unique to this run
+func testSubscribers(n int) []*SubscriberData {
+	prefix := "imsi-00101" + time.Now().Format("150405")
+	subscribers := make([]*SubscriberData, n)
+	for i := range subscribers {
+		subscribers[i] = &SubscriberData{
+			SUPI:                     fmt.Sprintf("%s%05d", prefix, i),
+			SUPIType:                 "imsi",
+			PLMNIDmcc:                "001",
+			PLMNIDmnc:                "01",
+			SubscriberStatus:         "ACTIVE",
+			SubscribedUeAmbrUplink:   1000000000,
+			SubscribedUeAmbrDownlink: 2000000000,
+			NSSAI:                    []SNSSAI{{SST: 1}},
+			RoamingAreas:             []string{},
+		}
+	}
+	return subscribers
+}
+
+func TestPartitionSubscribers(t *testing.T) {
+	subscribers := testSubscribers(3)
+	data := []*SubscriberData{
+		subscribers[0],
+		{SUPI: ""},
+		subscribers[1],
+		{SUPI: "msisdn-123"},
+		{SUPI: subscribers[0].SUPI},
+		nil,
+		{SUPI: "imsi-001010000000009", NSSAI: []SNSSAI{{SST: 300}}},
+		subscribers[2],
+	}
+
+	valid, failures := partitionSubscribers(data)
+	assert.Equal(t, subscribers, valid)
+	require.Len(t, failures, 5)
+	assert.Equal(t, "", failures[0].SUPI)
+	assert.Equal(t, "msisdn-123", failures[1].SUPI)
+	assert.Equal(t, subscribers[0].SUPI, failures[2].SUPI)
+	assert.Contains(t, failures[2].Reason, "duplicate")
+	assert.Equal(t, "imsi-001010000000009", failures[4].SUPI)
+}
+
+func TestBatchCreateSubscribersMixedRows(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	subscribers := testSubscribers(2)
+	failures, err := repo.BatchCreateSubscribers(ctx, []*SubscriberData{
+		subscribers[0],
+		{SUPI: "not-a-supi"},
+		subscribers[1],
+	})
+	require.NoError(t, err)
+	require.Len(t, failures, 1)
+	assert.Equal(t, "not-a-supi", failures[0].SUPI)
+
+	for _, subscriber := range subscribers {
+		got, err := repo.GetSubscriber(ctx, subscriber.SUPI)
+		require.NoError(t, err)
+		assert.Equal(t, "ACTIVE", got.SubscriberStatus)
+	}
+}
+
+func BenchmarkCreateSubscribers(b *testing.B) {
+	repo := newTestRepository(b)
+	ctx := context.Background()
+	const rows = 500
+
+	b.Run("PerRow", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, subscriber := range testSubscribers(rows) {
+				if err := repo.CreateSubscriber(ctx, subscriber); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+
+	b.Run("Batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			failures, err := repo.BatchCreateSubscribers(ctx, testSubscribers(rows))
+			if err != nil || len(failures) > 0 {
+				b.Fatal(err, failures)
+			}
+		}
+	})
+}
+
+func TestSubscriberFilterValidate(t *testing.T) {
+	valid := []SubscriberFilter{
+		{},
+		{PLMNIDmcc: "001", PLMNIDmnc: "01"},
+		{PLMNIDmnc: "001", Status: "SUSPENDED"},
+		{MSISDNPrefix: "4479"},
+	}
+	for _, filter := range valid {
+		assert.NoError(t, filter.Validate(), "%+v", filter)
+	}
+
+	invalid := []SubscriberFilter{
+		{PLMNIDmcc: "01"},
+		{PLMNIDmcc: "0a1"},
+		{PLMNIDmnc: "1"},
+		{PLMNIDmnc: "0011"},
+		{Status: "active"},
+		{MSISDNPrefix: "+44"},
+		{MSISDNPrefix: "1234567890123456"},
+	}
+	for _, filter := range invalid {
+		assert.ErrorIs(t, filter.Validate(), ErrInvalidFilter, "%+v", filter)
+	}
+}
+
+func TestSubscriberFilterWhereClause(t *testing.T) {
+	where, args := (&SubscriberFilter{}).whereClause()
+	assert.Empty(t, where)
+	assert.Empty(t, args)
+
+	where, args = (&SubscriberFilter{PLMNIDmcc: "001", Status: "ACTIVE", MSISDNPrefix: "4479"}).whereClause()
+	assert.Equal(t, "WHERE plmn_id_mcc = ? AND subscriber_status = ? AND startsWith(msisdn, ?)", where)
+	assert.Equal(t, []interface{}{"001", "ACTIVE", "4479"}, args)
+}
+
+func TestListSubscribersFilters(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	// MSISDNs unique to this run keep the filters clear of other rows
+	msisdn := fmt.Sprintf("99%010d", time.Now().UnixMilli()%1e10)
+	subscribers := testSubscribers(3)
+	subscribers[0].MSISDN = msisdn + "0"
+	subscribers[1].MSISDN = msisdn + "1"
+	subscribers[1].PLMNIDmnc = "02"
+	subscribers[2].MSISDN = msisdn + "2"
+	subscribers[2].PLMNIDmcc = "999"
+	subscribers[2].SubscriberStatus = "SUSPENDED"
+	failures, err := repo.BatchCreateSubscribers(ctx, subscribers)
+	require.NoError(t, err)
+	require.Empty(t, failures)
+
+	supis := func(filter SubscriberFilter) []string {
+		t.Helper()
+		filter.MSISDNPrefix = msisdn
+		list, err := repo.ListSubscribers(ctx, filter, 10, 0)
+		require.NoError(t, err)
+		total, err := repo.CountSubscribers(ctx, filter)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(len(list)), total)
+
+		var got []string
+		for _, subscriber := range list {
+			got = append(got, subscriber.SUPI)
+		}
+		return got
+	}
+
+	assert.Len(t, supis(SubscriberFilter{}), 3, "msisdn-prefix")
+	assert.ElementsMatch(t, []string{subscribers[0].SUPI, subscribers[1].SUPI}, supis(SubscriberFilter{PLMNIDmcc: "001"}))
+	assert.ElementsMatch(t, []string{subscribers[1].SUPI}, supis(SubscriberFilter{PLMNIDmnc: "02"}))
+	assert.ElementsMatch(t, []string{subscribers[2].SUPI}, supis(SubscriberFilter{Status: "SUSPENDED"}))
+	assert.ElementsMatch(t, []string{subscribers[0].SUPI},
+		supis(SubscriberFilter{PLMNIDmcc: "001", PLMNIDmnc: "01", Status: "ACTIVE"}))
+
+	// The total is independent of the page size
+	page, err := repo.ListSubscribers(ctx, SubscriberFilter{MSISDNPrefix: msisdn}, 1, 0)
+	require.NoError(t, err)
+	assert.Len(t, page, 1)
+
+	_, err = repo.ListSubscribers(ctx, SubscriberFilter{Status: "UNKNOWN"}, 10, 0)
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+}
+
+func TestSubscriberCursorRoundTrip(t *testing.T) {
+	cursor := &SubscriberCursor{CreatedAt: time.Date(2026, 10, 16, 9, 30, 0, 123000000, time.UTC), SUPI: "nai-user:1@example.com"}
+
+	decoded, err := DecodeSubscriberCursor(cursor.Encode())
+	require.NoError(t, err)
+	assert.True(t, cursor.CreatedAt.Equal(decoded.CreatedAt))
+	assert.Equal(t, cursor.SUPI, decoded.SUPI)
+
+	for _, encoded := range []string{"", "not base64!", "MTIz", "YWJjOmltc2k"} {
+		_, err := DecodeSubscriberCursor(encoded)
+		assert.ErrorIs(t, err, ErrInvalidCursor, encoded)
+	}
+}
+
+func TestListSubscribersAfterStableUnderInserts(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+
+	msisdn := fmt.Sprintf("98%010d", time.Now().UnixMilli()%1e10)
+	filter := SubscriberFilter{MSISDNPrefix: msisdn}
+	create := func(subscribers []*SubscriberData, first int) {
+		t.Helper()
+		for i, subscriber := range subscribers {
+			subscriber.SUPI += fmt.Sprintf("%d", first)
+			subscriber.MSISDN = fmt.Sprintf("%s%03d", msisdn, first+i)
+		}
+		failures, err := repo.BatchCreateSubscribers(ctx, subscribers)
+		require.NoError(t, err)
+		require.Empty(t, failures)
+	}
+	existing := testSubscribers(7)
+	create(existing, 0)
+
+	seen := make(map[string]int)
+	var after *SubscriberCursor
+	for pages := 0; ; pages++ {
+		page, err := repo.ListSubscribersAfter(ctx, filter, after, 3)
+		require.NoError(t, err)
+		for _, subscriber := range page {
+			seen[subscriber.SUPI]++
+		}
+		if len(page) < 3 {
+			break
+		}
+		after = CursorAfter(page[len(page)-1])
+
+		// Subscribers created mid-iteration sort before the cursor
+		if pages == 0 {
+			create(testSubscribers(2), 100)
+		}
+	}
+
+	for _, subscriber := range existing {
+		assert.Equal(t, 1, seen[subscriber.SUPI], subscriber.SUPI)
+	}
+	for supi, count := range seen {
+		assert.Equal(t, 1, count, supi)
+	}
+}
+
+func TestSUPILocksSerializeReadModifyWrite(t *testing.T) {
+	var locks supiLocks
+	sqns := map[string]*uint64{"imsi-001010000000001": new(uint64), "imsi-001010000000002": new(uint64)}
+
+	const workers = 50
+	results := make(chan uint64, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(supi string) {
+			defer wg.Done()
+			unlock := locks.lock(supi)
+			defer unlock()
+
+			// Read, yield, write: lost updates show up as duplicates
+			next := *sqns[supi] + 1
+			time.Sleep(time.Millisecond)
+			*sqns[supi] = next
+			if supi == "imsi-001010000000001" {
+				results <- next
+			}
+		}([]string{"imsi-001010000000001", "imsi-001010000000002"}[i%2])
+	}
+	wg.Wait()
+	close(results)
+
+	seen := make(map[uint64]bool)
+	for sqn := range results {
+		assert.False(t, seen[sqn], "SQN %d returned twice", sqn)
+		seen[sqn] = true
+	}
+	assert.Len(t, seen, workers/2)
+	assert.Empty(t, locks.locks, "released locks are removed")
+}
+
+func TestIncrementSQNConcurrent(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+	require.NoError(t, repo.client.Exec(ctx, AuthKeyVersionMigration))
+
+	supi := testSubscribers(1)[0].SUPI
+	require.NoError(t, repo.CreateAuthenticationSubscription(ctx, &AuthenticationSubscription{
+		SUPI:                 supi,
+		AuthenticationMethod: "5G_AKA",
+		SQN:                  100,
+	}))
+
+	const workers = 20
+	results := make(chan uint64, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sqn, err := repo.IncrementSQN(ctx, supi)
+			assert.NoError(t, err)
+			results <- sqn
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	seen := make(map[uint64]bool)
+	for sqn := range results {
+		assert.False(t, seen[sqn], "SQN %d returned twice", sqn)
+		seen[sqn] = true
+	}
+	for sqn := uint64(101); sqn <= 100+workers; sqn++ {
+		assert.True(t, seen[sqn], "SQN %d not returned", sqn)
+	}
+}
+
+func TestReserveSQNBlockPersistsCeiling(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+	require.NoError(t, repo.client.Exec(ctx, AuthKeyVersionMigration))
+
+	supi := testSubscribers(1)[0].SUPI
+	require.NoError(t, repo.CreateAuthenticationSubscription(ctx, &AuthenticationSubscription{
+		SUPI:                 supi,
+		AuthenticationMethod: "5G_AKA",
+		SQN:                  100,
+	}))
+
+	block, err := repo.ReserveSQNBlock(ctx, supi, 32)
+	require.NoError(t, err)
+	assert.Equal(t, &SQNBlock{First: 101, Last: 132}, block)
+
+	authSub, err := repo.GetAuthenticationSubscription(ctx, supi)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(132), authSub.SQN)
+
+	// The next block and single increments continue past the ceiling
+	block, err = repo.ReserveSQNBlock(ctx, supi, 8)
+	require.NoError(t, err)
+	assert.Equal(t, &SQNBlock{First: 133, Last: 140}, block)
+	sqn, err := repo.IncrementSQN(ctx, supi)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(141), sqn)
+
+	_, err = repo.ReserveSQNBlock(ctx, supi, 0)
+	assert.ErrorIs(t, err, ErrInvalidSQNBlockSize)
+	_, err = repo.ReserveSQNBlock(ctx, supi, MaxSQNBlockSize+1)
+	assert.ErrorIs(t, err, ErrInvalidSQNBlockSize)
+}
+
+func TestAMF3GPPRegistrationCRUD(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+	supi := "imsi-00101" + strings.ReplaceAll(time.Now().Format("150405.000"), ".", "")
+
+	_, err := repo.GetAMF3GPPRegistration(ctx, supi)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	registration := &AMF3GPPAccessRegistration{
+		AMFInstanceID:    "amf-1",
+		DeregCallbackURI: "http://amf-1.5gc.local/dereg",
+		GUAMI:            []byte(`{"plmnId":{"mcc":"001","mnc":"01"},"amfId":"cafe00"}`),
+		RATType:          "NR",
+		RegistrationTime: time.Now().UTC().Truncate(time.Millisecond),
+	}
+	require.NoError(t, repo.PutAMF3GPPRegistration(ctx, supi, registration))
+
+	stored, err := repo.GetAMF3GPPRegistration(ctx, supi)
+	require.NoError(t, err)
+	assert.Equal(t, "amf-1", stored.AMFInstanceID)
+	assert.JSONEq(t, string(registration.GUAMI), string(stored.GUAMI))
+	assert.True(t, registration.RegistrationTime.Equal(stored.RegistrationTime))
+
+	// A new AMF replaces the old one
+	time.Sleep(5 * time.Millisecond)
+	registration.AMFInstanceID = "amf-2"
+	require.NoError(t, repo.PutAMF3GPPRegistration(ctx, supi, registration))
+	stored, err = repo.GetAMF3GPPRegistration(ctx, supi)
+	require.NoError(t, err)
+	assert.Equal(t, "amf-2", stored.AMFInstanceID)
+
+	time.Sleep(5 * time.Millisecond)
+	require.NoError(t, repo.DeleteAMF3GPPRegistration(ctx, supi))
+	_, err = repo.GetAMF3GPPRegistration(ctx, supi)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, repo.DeleteAMF3GPPRegistration(ctx, supi), ErrNotFound)
+}
+
+func TestHealthCheckReconnectsDroppedConnection(t *testing.T) {
+	repo := newTestRepository(t)
+	ctx := context.Background()
+	repo.EnableReconnect(clickhouse.Config{
+		Addresses:    strings.Split(os.Getenv("UDR_TEST_CLICKHOUSE_ADDR"), ","),
+		Database:     "udr",
+		Username:     "default",
+		MaxOpenConns: 2,
+		MaxIdleConns: 1,
+		Timeout:      10 * time.Second,
+	})
+	t.Cleanup(func() { repo.Close() })
+
+	require.NoError(t, repo.checkHealth(ctx))
+	assert.Equal(t, uint64(0), repo.connectionStats().Reconnects)
+
+	// Drop the connection, as a ClickHouse restart would
+	require.NoError(t, repo.conn().Close())
+	_, err := repo.ListSMSubscriptions(ctx, "imsi-001010000000001")
+	require.Error(t, err)
+
+	require.NoError(t, repo.checkHealth(ctx))
+	stats := repo.connectionStats()
+	assert.True(t, stats.Healthy)
+	assert.Equal(t, uint64(1), stats.Reconnects)
+	assert.Equal(t, 2, stats.MaxOpenConns)
+
+	_, err = repo.ListSMSubscriptions(ctx, "imsi-001010000000001")
+	assert.NoError(t, err)
+}
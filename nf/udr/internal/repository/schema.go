@@ -0,0 +1,68 @@
+package repository
+
+// SMSubscriptionsSchema creates the SM subscription table. Rows are keyed by
+// (supi, dnn); updates insert a newer row that replaces the old one on merge.
+const SMSubscriptionsSchema = `
+CREATE TABLE IF NOT EXISTS udr.sm_subscriptions (
+    supi String,
+    dnn String,
+    session_ambr_uplink UInt64,
+    session_ambr_downlink UInt64,
+    default_5qi UInt8,
+    arp_priority_level UInt8,
+    ssc_modes Array(UInt8),
+    default_ssc_mode UInt8,
+    pdu_session_types Array(String),
+    default_pdu_session_type String,
+    static_ip_address String,
+    static_ipv6_prefix String,
+    charging_characteristics String,
+    created_at DateTime64(3),
+    updated_at DateTime64(3)
+) ENGINE = ReplacingMergeTree(updated_at)
+ORDER BY (supi, dnn)`
+
+// AMFRegistrationsSchema creates the AMF 3GPP access registration table, one
+// row per SUPI. Deregistration inserts a tombstone row instead of running a
+// DELETE mutation, which ClickHouse applies asynchronously.
+const AMFRegistrationsSchema = `
+CREATE TABLE IF NOT EXISTS udr.amf_3gpp_access_registrations (
+    supi String,
+    amf_instance_id String,
+    registration String,
+    deleted UInt8,
+    updated_at DateTime64(3)
+) ENGINE = ReplacingMergeTree(updated_at)
+ORDER BY supi`
+
+// SDMSubscriptionsSchema creates the data change subscription table, one row
+// per subscription. Like AMF registrations, deletions insert a tombstone row.
+const SDMSubscriptionsSchema = `
+CREATE TABLE IF NOT EXISTS udr.sdm_subscriptions (
+    subscription_id String,
+    supi String,
+    subscription String,
+    deleted UInt8,
+    updated_at DateTime64(3)
+) ENGINE = ReplacingMergeTree(updated_at)
+ORDER BY subscription_id`
+
+// AuthEventsSchema creates the authentication audit table, one row per
+// result reported by the UDM. Rows expire after 90 days.
+const AuthEventsSchema = `
+CREATE TABLE IF NOT EXISTS udr.auth_events (
+    supi String,
+    success UInt8,
+    auth_method String,
+    serving_network String,
+    failure_reason String,
+    timestamp DateTime64(3)
+) ENGINE = MergeTree()
+ORDER BY (supi, timestamp)
+TTL toDateTime(timestamp) + INTERVAL 90 DAY`
+
+// AuthKeyVersionMigration adds the version of the data key authentication
+// keys are encrypted with. Version 0 marks plaintext rows.
+const AuthKeyVersionMigration = `
+ALTER TABLE udr.authentication_subscription
+    ADD COLUMN IF NOT EXISTS key_version UInt32 DEFAULT 0 AFTER authentication_management_field`
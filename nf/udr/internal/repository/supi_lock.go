@@ -0,0 +1,44 @@
+package repository
+
+import "sync"
+
+// supiLocks serializes read-modify-write operations on the rows of one
+// SUPI. It only orders writers within this UDR instance.
+type supiLocks struct {
+	mu    sync.Mutex
+	locks map[string]*supiLock
+}
+
+// supiLock is the lock of one SUPI, removed once no caller holds or waits
+// for it
+type supiLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// lock locks the SUPI and returns the function unlocking it
+func (l *supiLocks) lock(supi string) func() {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*supiLock)
+	}
+	entry, ok := l.locks[supi]
+	if !ok {
+		entry = &supiLock{}
+		l.locks[supi] = entry
+	}
+	entry.refs++
+	l.mu.Unlock()
+
+	entry.mu.Lock()
+	return func() {
+		entry.mu.Unlock()
+
+		l.mu.Lock()
+		entry.refs--
+		if entry.refs == 0 {
+			delete(l.locks, supi)
+		}
+		l.mu.Unlock()
+	}
+}
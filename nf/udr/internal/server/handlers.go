@@ -2,12 +2,17 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/your-org/5g-network/common/metrics"
+	"github.com/your-org/5g-network/common/sbi"
 	"github.com/your-org/5g-network/nf/udr/internal/repository"
 	"go.uber.org/zap"
 )
@@ -19,7 +24,7 @@ func (s *UDRServer) handleGetAMData(w http.ResponseWriter, r *http.Request) {
 
 	subscriber, err := s.repository.GetSubscriber(r.Context(), supi)
 	if err != nil {
-		s.respondError(w, http.StatusNotFound, "subscriber not found", err)
+		s.respondProblem(w, http.StatusNotFound, sbi.CauseSubscriberNotFound, "subscriber not found", err)
 		return
 	}
 
@@ -44,14 +49,14 @@ func (s *UDRServer) handleUpdateAMData(w http.ResponseWriter, r *http.Request) {
 
 	var data repository.SubscriberData
 	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		s.respondError(w, http.StatusBadRequest, "invalid request body", err)
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
 		return
 	}
 
 	data.SUPI = supi
 	err := s.repository.UpdateSubscriber(r.Context(), supi, &data)
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, "failed to update subscriber", err)
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to update subscriber", err)
 		return
 	}
 
@@ -68,7 +73,7 @@ func (s *UDRServer) handleGetSMData(w http.ResponseWriter, r *http.Request) {
 		// Return all SM data for subscriber
 		subscriber, err := s.repository.GetSubscriber(r.Context(), supi)
 		if err != nil {
-			s.respondError(w, http.StatusNotFound, "subscriber not found", err)
+			s.respondProblem(w, http.StatusNotFound, sbi.CauseSubscriberNotFound, "subscriber not found", err)
 			return
 		}
 
@@ -78,8 +83,12 @@ func (s *UDRServer) handleGetSMData(w http.ResponseWriter, r *http.Request) {
 
 	// Return specific DNN data
 	smData, err := s.repository.GetSMSubscription(r.Context(), supi, dnn)
+	if errors.Is(err, repository.ErrNotFound) {
+		s.respondProblem(w, http.StatusNotFound, sbi.CauseDataNotFound, "SM data not found", err)
+		return
+	}
 	if err != nil {
-		s.respondError(w, http.StatusNotFound, "SM data not found", err)
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to get SM data", err)
 		return
 	}
 
@@ -93,7 +102,7 @@ func (s *UDRServer) handleUpdateSMData(w http.ResponseWriter, r *http.Request) {
 
 	var data repository.SessionManagementSubscriptionData
 	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		s.respondError(w, http.StatusBadRequest, "invalid request body", err)
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
 		return
 	}
 
@@ -102,13 +111,72 @@ func (s *UDRServer) handleUpdateSMData(w http.ResponseWriter, r *http.Request) {
 
 	err := s.repository.UpdateSMSubscription(r.Context(), supi, dnn, &data)
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, "failed to update SM data", err)
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to update SM data", err)
 		return
 	}
 
 	s.respondJSON(w, http.StatusOK, &data)
 }
 
+// handleGetAMF3GPPRegistration handles GET request for the AMF registration
+// of a UE. TS 29.505, Clause 5.2.12
+func (s *UDRServer) handleGetAMF3GPPRegistration(w http.ResponseWriter, r *http.Request) {
+	supi := chi.URLParam(r, "supi")
+
+	registration, err := s.repository.GetAMF3GPPRegistration(r.Context(), supi)
+	if errors.Is(err, repository.ErrNotFound) {
+		s.respondProblem(w, http.StatusNotFound, sbi.CauseDataNotFound, "AMF registration not found", err)
+		return
+	}
+	if err != nil {
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to get AMF registration", err)
+		return
+	}
+
+	s.respondJSON(w, http.StatusOK, registration)
+}
+
+// handlePutAMF3GPPRegistration handles PUT request storing the AMF
+// registration of a UE
+func (s *UDRServer) handlePutAMF3GPPRegistration(w http.ResponseWriter, r *http.Request) {
+	supi := chi.URLParam(r, "supi")
+
+	var registration repository.AMF3GPPAccessRegistration
+	if err := json.NewDecoder(r.Body).Decode(&registration); err != nil {
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
+		return
+	}
+	if registration.AMFInstanceID == "" {
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseMandatoryIEMissing, "amfInstanceId is required", nil)
+		return
+	}
+
+	if err := s.repository.PutAMF3GPPRegistration(r.Context(), supi, &registration); err != nil {
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to store AMF registration", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleDeleteAMF3GPPRegistration handles DELETE request removing the AMF
+// registration of a UE
+func (s *UDRServer) handleDeleteAMF3GPPRegistration(w http.ResponseWriter, r *http.Request) {
+	supi := chi.URLParam(r, "supi")
+
+	err := s.repository.DeleteAMF3GPPRegistration(r.Context(), supi)
+	if errors.Is(err, repository.ErrNotFound) {
+		s.respondProblem(w, http.StatusNotFound, sbi.CauseDataNotFound, "AMF registration not found", err)
+		return
+	}
+	if err != nil {
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to delete AMF registration", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // handleGetAuthSubscription handles GET request for authentication subscription
 // TS 29.503, Clause 5.2.3.2.2
 func (s *UDRServer) handleGetAuthSubscription(w http.ResponseWriter, r *http.Request) {
@@ -117,7 +185,7 @@ func (s *UDRServer) handleGetAuthSubscription(w http.ResponseWriter, r *http.Req
 	start := time.Now()
 	authSub, err := s.repository.GetAuthenticationSubscription(r.Context(), supi)
 	if err != nil {
-		s.respondError(w, http.StatusNotFound, "authentication subscription not found", err)
+		s.respondProblem(w, http.StatusNotFound, sbi.CauseDataNotFound, "authentication subscription not found", err)
 		metrics.RecordAuthSubscriptionQuery("failed")
 		return
 	}
@@ -136,14 +204,14 @@ func (s *UDRServer) handleUpdateAuthSubscription(w http.ResponseWriter, r *http.
 
 	var data repository.AuthenticationSubscription
 	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		s.respondError(w, http.StatusBadRequest, "invalid request body", err)
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
 		return
 	}
 
 	data.SUPI = supi
 	err := s.repository.UpdateAuthenticationSubscription(r.Context(), supi, &data)
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, "failed to update auth subscription", err)
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to update auth subscription", err)
 		return
 	}
 
@@ -157,7 +225,7 @@ func (s *UDRServer) handleIncrementSQN(w http.ResponseWriter, r *http.Request) {
 
 	newSQN, err := s.repository.IncrementSQN(r.Context(), supi)
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, "failed to increment SQN", err)
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to increment SQN", err)
 		return
 	}
 
@@ -171,6 +239,90 @@ func (s *UDRServer) handleIncrementSQN(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleSetSQN handles PUT request to overwrite SQN after resynchronisation
+func (s *UDRServer) handleSetSQN(w http.ResponseWriter, r *http.Request) {
+	supi := chi.URLParam(r, "supi")
+
+	var body struct {
+		SQN *uint64 `json:"sqn"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
+		return
+	}
+	if body.SQN == nil {
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseMandatoryIEMissing, "invalid request body", errors.New("missing sqn"))
+		return
+	}
+
+	if err := s.repository.SetSQN(r.Context(), supi, *body.SQN); err != nil {
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to set SQN", err)
+		return
+	}
+
+	s.logger.Info("SQN resynchronised",
+		zap.String("supi", supi),
+		zap.Uint64("sqn", *body.SQN),
+	)
+
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"sqn": *body.SQN,
+	})
+}
+
+// handleReserveSQNBlock handles POST request to reserve a block of sequence
+// numbers, letting the UDM allocate SQNs without a round trip per
+// authentication
+func (s *UDRServer) handleReserveSQNBlock(w http.ResponseWriter, r *http.Request) {
+	supi := chi.URLParam(r, "supi")
+
+	var body struct {
+		Size uint64 `json:"size"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
+		return
+	}
+
+	block, err := s.repository.ReserveSQNBlock(r.Context(), supi, body.Size)
+	if errors.Is(err, repository.ErrInvalidSQNBlockSize) {
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid SQN block size", err)
+		return
+	}
+	if err != nil {
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to reserve SQN block", err)
+		return
+	}
+
+	s.logger.Debug("SQN block reserved",
+		zap.String("supi", supi),
+		zap.Uint64("first", block.First),
+		zap.Uint64("last", block.Last),
+	)
+
+	s.respondJSON(w, http.StatusOK, block)
+}
+
+// handlePutAuthStatus handles PUT request recording the result of an
+// authentication of a UE, reported by the UDM. TS 29.505, Clause 5.2.2.2.9
+func (s *UDRServer) handlePutAuthStatus(w http.ResponseWriter, r *http.Request) {
+	supi := chi.URLParam(r, "supi")
+
+	var event repository.AuthEvent
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
+		return
+	}
+	event.SUPI = supi
+
+	if err := s.repository.RecordAuthEvent(r.Context(), &event); err != nil {
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to record auth event", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // handleGetPolicyData handles GET request for policy data
 // TS 29.519
 func (s *UDRServer) handleGetPolicyData(w http.ResponseWriter, r *http.Request) {
@@ -178,7 +330,7 @@ func (s *UDRServer) handleGetPolicyData(w http.ResponseWriter, r *http.Request)
 
 	policyData, err := s.repository.GetPolicyData(r.Context(), supi)
 	if err != nil {
-		s.respondError(w, http.StatusNotFound, "policy data not found", err)
+		s.respondProblem(w, http.StatusNotFound, sbi.CauseDataNotFound, "policy data not found", err)
 		return
 	}
 
@@ -191,40 +343,64 @@ func (s *UDRServer) handleUpdatePolicyData(w http.ResponseWriter, r *http.Reques
 
 	var data repository.PolicyData
 	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		s.respondError(w, http.StatusBadRequest, "invalid request body", err)
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
 		return
 	}
 
 	data.SUPI = supi
 	err := s.repository.UpdatePolicyData(r.Context(), supi, &data)
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, "failed to update policy data", err)
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to update policy data", err)
 		return
 	}
 
 	s.respondJSON(w, http.StatusOK, &data)
 }
 
-// handleGetSubscriptions handles GET request for SDM subscriptions
+// handleGetSubscriptions handles GET request for the SDM subscriptions of
+// the UE given by the ue-id query parameter
 func (s *UDRServer) handleGetSubscriptions(w http.ResponseWriter, r *http.Request) {
-	// TODO: Implement SDM subscriptions listing
-	s.respondJSON(w, http.StatusOK, []interface{}{})
+	supi := r.URL.Query().Get("ue-id")
+	if supi == "" {
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseMandatoryIEMissing, "ue-id is required", nil)
+		return
+	}
+
+	subscriptions, err := s.repository.ListSDMSubscriptions(r.Context(), supi)
+	if err != nil {
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to list subscriptions", err)
+		return
+	}
+	if subscriptions == nil {
+		subscriptions = []*repository.SDMSubscription{}
+	}
+
+	s.respondJSON(w, http.StatusOK, subscriptions)
 }
 
-// handleCreateSubscription handles POST request to create SDM subscription
+// handleCreateSubscription handles POST request to create SDM subscription.
+// TS 29.505, Clause 5.2.21
 func (s *UDRServer) handleCreateSubscription(w http.ResponseWriter, r *http.Request) {
 	var subscription repository.SDMSubscription
 	if err := json.NewDecoder(r.Body).Decode(&subscription); err != nil {
-		s.respondError(w, http.StatusBadRequest, "invalid request body", err)
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
+		return
+	}
+	if subscription.UEID == "" || subscription.CallbackURI == "" {
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseMandatoryIEMissing, "ueId and callbackReference are required", nil)
 		return
 	}
 
+	subscription.SubscriptionID = uuid.New().String()
+	subscription.CreatedAt = time.Now()
+
 	err := s.repository.CreateSDMSubscription(r.Context(), &subscription)
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, "failed to create subscription", err)
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to create subscription", err)
 		return
 	}
 
+	w.Header().Set("Location", r.URL.Path+"/"+subscription.SubscriptionID)
 	s.respondJSON(w, http.StatusCreated, &subscription)
 }
 
@@ -233,8 +409,12 @@ func (s *UDRServer) handleDeleteSubscription(w http.ResponseWriter, r *http.Requ
 	subscriptionID := chi.URLParam(r, "subscriptionId")
 
 	err := s.repository.DeleteSDMSubscription(r.Context(), subscriptionID)
+	if errors.Is(err, repository.ErrNotFound) {
+		s.respondProblem(w, http.StatusNotFound, sbi.CauseSubscriptionNotFound, "subscription not found", err)
+		return
+	}
 	if err != nil {
-		s.respondError(w, http.StatusNotFound, "subscription not found", err)
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to delete subscription", err)
 		return
 	}
 
@@ -244,51 +424,117 @@ func (s *UDRServer) handleDeleteSubscription(w http.ResponseWriter, r *http.Requ
 // Administrative Handlers
 
 // handleListSubscribers handles GET request to list all subscribers
+// Filters are plmn-mcc, plmn-mnc, status and msisdn-prefix; total counts all
+// matching subscribers, not just the returned page. Pages follow the cursor
+// of the previous page's nextCursor; offset is a deprecated alternative.
 func (s *UDRServer) handleListSubscribers(w http.ResponseWriter, r *http.Request) {
-	// Parse pagination parameters
-	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
-
-	limit := 100 // default
-	offset := 0
-
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil {
-			limit = l
-		}
+	filter, page, err := parseSubscriberListQuery(r.URL.Query())
+	if err == nil {
+		err = filter.Validate()
+	}
+	if err != nil {
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidQueryParam, "invalid query parameter", err)
+		return
 	}
 
-	if offsetStr != "" {
-		if o, err := strconv.Atoi(offsetStr); err == nil {
-			offset = o
-		}
+	var subscribers []*repository.SubscriberData
+	if page.offset != nil {
+		w.Header().Set("Deprecation", "true")
+		subscribers, err = s.repository.ListSubscribers(r.Context(), filter, page.limit, *page.offset)
+	} else {
+		subscribers, err = s.repository.ListSubscribersAfter(r.Context(), filter, page.cursor, page.limit)
+	}
+	if err != nil {
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to list subscribers", err)
+		return
 	}
 
-	subscribers, err := s.repository.ListSubscribers(r.Context(), limit, offset)
+	total, err := s.repository.CountSubscribers(r.Context(), filter)
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, "failed to list subscribers", err)
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to count subscribers", err)
 		return
 	}
 
-	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+	response := map[string]interface{}{
 		"subscribers": subscribers,
-		"total":       len(subscribers),
-		"limit":       limit,
-		"offset":      offset,
-	})
+		"count":       len(subscribers),
+		"total":       total,
+		"limit":       page.limit,
+	}
+	if page.offset != nil {
+		response["offset"] = *page.offset
+	} else if len(subscribers) == page.limit {
+		// A short page is the last one
+		response["nextCursor"] = repository.CursorAfter(subscribers[len(subscribers)-1]).Encode()
+	}
+	s.respondJSON(w, http.StatusOK, response)
+}
+
+// subscriberPage selects a page of a subscriber listing, by offset when set
+// and by cursor otherwise
+type subscriberPage struct {
+	limit  int
+	offset *int
+	cursor *repository.SubscriberCursor // nil for the first page
+}
+
+// parseSubscriberListQuery parses the filter and page of a subscriber
+// listing, rejecting unknown parameters
+func parseSubscriberListQuery(query url.Values) (repository.SubscriberFilter, subscriberPage, error) {
+	var filter repository.SubscriberFilter
+	page := subscriberPage{limit: 100} // default
+
+	for name := range query {
+		value := query.Get(name)
+		switch name {
+		case "limit":
+			l, err := strconv.Atoi(value)
+			if err != nil || l < 1 || l > 1000 {
+				return filter, page, fmt.Errorf("limit %q must be between 1 and 1000", value)
+			}
+			page.limit = l
+		case "offset":
+			o, err := strconv.Atoi(value)
+			if err != nil || o < 0 {
+				return filter, page, fmt.Errorf("offset %q must be a non-negative integer", value)
+			}
+			page.offset = &o
+		case "cursor":
+			cursor, err := repository.DecodeSubscriberCursor(value)
+			if err != nil {
+				return filter, page, err
+			}
+			page.cursor = cursor
+		case "plmn-mcc":
+			filter.PLMNIDmcc = value
+		case "plmn-mnc":
+			filter.PLMNIDmnc = value
+		case "status":
+			filter.Status = value
+		case "msisdn-prefix":
+			filter.MSISDNPrefix = value
+		default:
+			return filter, page, fmt.Errorf("unknown filter %q", name)
+		}
+	}
+
+	if page.offset != nil && page.cursor != nil {
+		return filter, page, fmt.Errorf("offset and cursor cannot be combined")
+	}
+	return filter, page, nil
 }
 
 // handleCreateSubscriber handles POST request to create a new subscriber
 func (s *UDRServer) handleCreateSubscriber(w http.ResponseWriter, r *http.Request) {
 	var data repository.SubscriberData
 	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		s.respondError(w, http.StatusBadRequest, "invalid request body", err)
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
 		return
 	}
 
 	err := s.repository.CreateSubscriber(r.Context(), &data)
 	if err != nil {
-		s.respondError(w, http.StatusConflict, "failed to create subscriber", err)
+		s.respondProblem(w, http.StatusConflict, sbi.CauseResourceAlreadyExists, "failed to create subscriber", err)
 		return
 	}
 
@@ -296,13 +542,61 @@ func (s *UDRServer) handleCreateSubscriber(w http.ResponseWriter, r *http.Reques
 	s.respondJSON(w, http.StatusCreated, &data)
 }
 
+// maxSubscriberBatch bounds the subscribers accepted by one batch request
+const maxSubscriberBatch = 10000
+
+// BatchCreateSubscribersResponse reports the outcome of a batch subscriber
+// creation
+type BatchCreateSubscribersResponse struct {
+	Created int                            `json:"created"`
+	Failed  []repository.SubscriberFailure `json:"failed,omitempty"`
+}
+
+// handleBatchCreateSubscribers handles POST request to create subscribers in
+// bulk. Invalid subscribers are listed in the response and 207 is returned
+// when there are any; the valid ones are created together, or none are when
+// the insert fails.
+func (s *UDRServer) handleBatchCreateSubscribers(w http.ResponseWriter, r *http.Request) {
+	var data []*repository.SubscriberData
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
+		return
+	}
+	if len(data) == 0 || len(data) > maxSubscriberBatch {
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid batch size",
+			fmt.Errorf("batch must hold 1 to %d subscribers, got %d", maxSubscriberBatch, len(data)))
+		return
+	}
+
+	failures, err := s.repository.BatchCreateSubscribers(r.Context(), data)
+	if err != nil {
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to create subscribers", err)
+		return
+	}
+
+	resp := BatchCreateSubscribersResponse{
+		Created: len(data) - len(failures),
+		Failed:  failures,
+	}
+	s.logger.Info("Subscribers created via admin API",
+		zap.Int("created", resp.Created),
+		zap.Int("failed", len(resp.Failed)),
+	)
+
+	status := http.StatusCreated
+	if len(failures) > 0 {
+		status = http.StatusMultiStatus
+	}
+	s.respondJSON(w, status, resp)
+}
+
 // handleGetSubscriber handles GET request for a specific subscriber
 func (s *UDRServer) handleGetSubscriber(w http.ResponseWriter, r *http.Request) {
 	supi := chi.URLParam(r, "supi")
 
 	subscriber, err := s.repository.GetSubscriber(r.Context(), supi)
 	if err != nil {
-		s.respondError(w, http.StatusNotFound, "subscriber not found", err)
+		s.respondProblem(w, http.StatusNotFound, sbi.CauseSubscriberNotFound, "subscriber not found", err)
 		return
 	}
 
@@ -315,14 +609,14 @@ func (s *UDRServer) handlePutSubscriber(w http.ResponseWriter, r *http.Request)
 
 	var data repository.SubscriberData
 	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		s.respondError(w, http.StatusBadRequest, "invalid request body", err)
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
 		return
 	}
 
 	data.SUPI = supi
 	err := s.repository.UpdateSubscriber(r.Context(), supi, &data)
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, "failed to update subscriber", err)
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to update subscriber", err)
 		return
 	}
 
@@ -335,7 +629,7 @@ func (s *UDRServer) handleDeleteSubscriber(w http.ResponseWriter, r *http.Reques
 
 	err := s.repository.DeleteSubscriber(r.Context(), supi)
 	if err != nil {
-		s.respondError(w, http.StatusNotFound, "subscriber not found", err)
+		s.respondProblem(w, http.StatusNotFound, sbi.CauseSubscriberNotFound, "subscriber not found", err)
 		return
 	}
 
@@ -347,7 +641,7 @@ func (s *UDRServer) handleDeleteSubscriber(w http.ResponseWriter, r *http.Reques
 func (s *UDRServer) handleGetStats(w http.ResponseWriter, r *http.Request) {
 	stats, err := s.repository.GetStats(r.Context())
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, "failed to get stats", err)
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to get stats", err)
 		return
 	}
 
@@ -358,16 +652,79 @@ func (s *UDRServer) handleGetStats(w http.ResponseWriter, r *http.Request) {
 func (s *UDRServer) handleCreateAuthSubscription(w http.ResponseWriter, r *http.Request) {
 	var data repository.AuthenticationSubscription
 	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
-		s.respondError(w, http.StatusBadRequest, "invalid request body", err)
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidMsgFormat, "invalid request body", err)
 		return
 	}
 
 	err := s.repository.CreateAuthenticationSubscription(r.Context(), &data)
 	if err != nil {
-		s.respondError(w, http.StatusConflict, "failed to create auth subscription", err)
+		s.respondProblem(w, http.StatusConflict, sbi.CauseResourceAlreadyExists, "failed to create auth subscription", err)
 		return
 	}
 
 	s.logger.Info("Authentication subscription created via admin API", zap.String("supi", data.SUPI))
 	s.respondJSON(w, http.StatusCreated, &data)
 }
+
+// handleListAuthEvents handles GET request for the recent authentication
+// events of a SUPI, optionally between the RFC 3339 times since and until
+func (s *UDRServer) handleListAuthEvents(w http.ResponseWriter, r *http.Request) {
+	supi := chi.URLParam(r, "supi")
+
+	filter, limit, err := parseAuthEventQuery(r.URL.Query())
+	if err != nil {
+		s.respondProblem(w, http.StatusBadRequest, sbi.CauseInvalidQueryParam, "invalid query parameter", err)
+		return
+	}
+
+	events, err := s.repository.ListAuthEvents(r.Context(), supi, filter, limit)
+	if err != nil {
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to list auth events", err)
+		return
+	}
+	if events == nil {
+		events = []*repository.AuthEvent{}
+	}
+
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"supi":   supi,
+		"events": events,
+		"count":  len(events),
+	})
+}
+
+// parseAuthEventQuery parses the time filter and limit of an auth event
+// listing, rejecting unknown parameters
+func parseAuthEventQuery(query url.Values) (repository.AuthEventFilter, int, error) {
+	var filter repository.AuthEventFilter
+	limit := 100 // default
+
+	for name := range query {
+		value := query.Get(name)
+		switch name {
+		case "limit":
+			l, err := strconv.Atoi(value)
+			if err != nil || l < 1 || l > 1000 {
+				return filter, limit, fmt.Errorf("limit %q must be between 1 and 1000", value)
+			}
+			limit = l
+		case "since", "until":
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, limit, fmt.Errorf("%s %q must be an RFC 3339 time", name, value)
+			}
+			if name == "since" {
+				filter.Since = t
+			} else {
+				filter.Until = t
+			}
+		default:
+			return filter, limit, fmt.Errorf("unknown filter %q", name)
+		}
+	}
+
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Since.Before(filter.Until) {
+		return filter, limit, fmt.Errorf("since must be before until")
+	}
+	return filter, limit, nil
+}
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/your-org/5g-network/common/notify"
+	"github.com/your-org/5g-network/nf/udr/internal/events"
+	"github.com/your-org/5g-network/nf/udr/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Data change notification delivery
+const (
+	notificationWorkers   = 4
+	notificationQueueSize = 1000
+	notificationTimeout   = 5 * time.Second
+)
+
+// Notifier delivers data change notifications to subscribers
+type Notifier interface {
+	Notify(sub *repository.SDMSubscription, data *repository.DataChangeNotify)
+}
+
+// notificationJob is a notification pending delivery to one subscriber
+type notificationJob struct {
+	sub  *repository.SDMSubscription
+	data *repository.DataChangeNotify
+}
+
+// HTTPNotifier POSTs data change notifications to subscription callback URIs
+// from a bounded pool of workers, as the NRF does for NF status
+// notifications. Failed deliveries are logged, not retried.
+type HTTPNotifier struct {
+	httpClient *http.Client
+	queue      *notify.Queue[*notificationJob]
+	logger     *zap.Logger
+}
+
+// NewHTTPNotifier creates a notifier and starts its delivery workers
+func NewHTTPNotifier(transport http.RoundTripper, logger *zap.Logger) *HTTPNotifier {
+	n := &HTTPNotifier{
+		httpClient: &http.Client{Timeout: notificationTimeout, Transport: transport},
+		logger:     logger,
+	}
+	n.queue = notify.NewQueue(notify.Config{
+		Workers:   notificationWorkers,
+		QueueSize: notificationQueueSize,
+	}, notify.Hooks[*notificationJob]{
+		Deliver:   n.post,
+		Delivered: n.delivered,
+		Failed:    n.failed,
+	})
+	return n
+}
+
+// Notify schedules a notification for delivery without blocking. The
+// notification is dropped if the queue is full.
+func (n *HTTPNotifier) Notify(sub *repository.SDMSubscription, data *repository.DataChangeNotify) {
+	if !n.queue.Enqueue(&notificationJob{sub: sub, data: data}) {
+		n.logger.Error("Notification queue full, dropping data change notification",
+			zap.String("subscription_id", sub.SubscriptionID),
+			zap.String("supi", data.UEID),
+		)
+	}
+}
+
+// Stop terminates the delivery workers. Notifications still queued are
+// dropped.
+func (n *HTTPNotifier) Stop() {
+	if pending := n.queue.Stop(); pending > 0 {
+		n.logger.Warn("Dropping undelivered data change notifications on shutdown", zap.Int("pending", pending))
+	}
+}
+
+// post sends one notification
+func (n *HTTPNotifier) post(job *notificationJob) error {
+	body, err := json.Marshal(job.data)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	return notify.PostJSON(n.httpClient, notificationTimeout, job.sub.CallbackURI, body)
+}
+
+// delivered logs a delivered notification
+func (n *HTTPNotifier) delivered(job *notificationJob, attempts int) {
+	n.logger.Debug("Data change notification delivered",
+		zap.String("subscription_id", job.sub.SubscriptionID),
+		zap.String("supi", job.data.UEID),
+	)
+}
+
+// failed logs a notification that could not be delivered
+func (n *HTTPNotifier) failed(job *notificationJob, attempts int, err error) {
+	n.logger.Error("Data change notification failed",
+		zap.String("subscription_id", job.sub.SubscriptionID),
+		zap.String("callback_uri", job.sub.CallbackURI),
+		zap.Error(err),
+	)
+}
+
+// resourceURI returns the UDR URI of the resource changed by an event
+func resourceURI(event events.Event) string {
+	switch event.Resource {
+	case events.ResourcePolicyData:
+		return fmt.Sprintf("/nudr-dr/v1/policy-data/ues/%s/sm-data", event.SUPI)
+	default:
+		return fmt.Sprintf("/nudr-dr/v1/subscription-data/%s/provisioned-data/%s", event.SUPI, event.Resource)
+	}
+}
+
+// monitors reports whether a subscription monitors the resource changed by
+// an event. Subscription data resources are matched on the last segment of
+// the monitored URIs (e.g. "am-data"), policy data on its policy-data path.
+// Subscriptions without monitored resources receive every change of their
+// UE's subscription data.
+func monitors(sub *repository.SDMSubscription, event events.Event) bool {
+	policy := event.Resource == events.ResourcePolicyData
+	if len(sub.MonitoredResourceURIs) == 0 {
+		return !policy
+	}
+	for _, uri := range sub.MonitoredResourceURIs {
+		if strings.Contains(uri, "/policy-data/") != policy {
+			continue
+		}
+		if policy || path.Base(uri) == event.Resource {
+			return true
+		}
+	}
+	return false
+}
+
+// NotifyDataChange notifies the subscriptions monitoring the resource
+// changed by an event of the new values of its changed fields. It is meant
+// to be subscribed to the UDR event bus.
+func (s *UDRServer) NotifyDataChange(event events.Event) {
+	if s.notifier == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+	defer cancel()
+	subscriptions, err := s.repository.ListSDMSubscriptions(ctx, event.SUPI)
+	if err != nil {
+		s.logger.Error("Failed to list SDM subscriptions", zap.String("supi", event.SUPI), zap.Error(err))
+		return
+	}
+
+	changes := make([]repository.ChangeItem, 0, len(event.ChangedFields))
+	for _, field := range event.ChangedFields {
+		change := repository.ChangeItem{Op: "REPLACE", Path: "/" + field, NewValue: event.NewValues[field]}
+		if change.NewValue == nil {
+			change.Op = "REMOVE"
+		}
+		changes = append(changes, change)
+	}
+
+	for _, sub := range subscriptions {
+		if !monitors(sub, event) {
+			continue
+		}
+
+		data := &repository.DataChangeNotify{
+			UEID: event.SUPI,
+			NotifyItems: []repository.NotifyItem{{
+				ResourceID: resourceURI(event),
+				Changes:    changes,
+			}},
+		}
+		if sub.OriginalCallbackURI != "" {
+			data.OriginalCallbackReference = []string{sub.OriginalCallbackURI}
+		}
+		s.notifier.Notify(sub, data)
+	}
+}
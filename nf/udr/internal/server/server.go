@@ -5,15 +5,25 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/your-org/5g-network/common/buildinfo"
+	"github.com/your-org/5g-network/common/correlation"
+	"github.com/your-org/5g-network/common/loglevel"
+	"github.com/your-org/5g-network/common/metrics"
+	"github.com/your-org/5g-network/common/oauth"
+	"github.com/your-org/5g-network/common/sbi"
 	"github.com/your-org/5g-network/nf/udr/internal/config"
 	"github.com/your-org/5g-network/nf/udr/internal/repository"
 	"go.uber.org/zap"
 )
 
+// readinessTimeout bounds the database probe of the readiness check
+const readinessTimeout = 2 * time.Second
+
 // UDRServer represents the UDR HTTP server
 type UDRServer struct {
 	config     *config.Config
@@ -21,6 +31,9 @@ type UDRServer struct {
 	router     *chi.Mux
 	httpServer *http.Server
 	logger     *zap.Logger
+
+	// Delivers data change notifications, none are sent when nil
+	notifier Notifier
 }
 
 // NewUDRServer creates a new UDR server instance
@@ -42,10 +55,14 @@ func NewUDRServer(cfg *config.Config, repo repository.Repository, logger *zap.Lo
 func (s *UDRServer) setupRoutes() {
 	// Middleware
 	s.router.Use(middleware.RequestID)
+	s.router.Use(correlation.Middleware)
 	s.router.Use(middleware.RealIP)
+	s.router.Use(metrics.Middleware)
 	s.router.Use(s.loggingMiddleware)
 	s.router.Use(middleware.Recoverer)
 	s.router.Use(middleware.Timeout(60 * time.Second))
+	s.router.Use(cacheControlMiddleware)
+	s.router.Use(oauth.Middleware(s.config.SBI.OAuth2, s.logger, "UDR", s.config.NF.InstanceID))
 
 	// Health endpoints
 	s.router.Get("/health", s.handleHealth)
@@ -54,6 +71,8 @@ func (s *UDRServer) setupRoutes() {
 
 	// Data Repository Service (TS 29.504)
 	s.router.Route("/nudr-dr/v1", func(r chi.Router) {
+		r.Use(oauth.RequireScope("nudr-dr"))
+
 		// Subscription Data (TS 29.505)
 		r.Route("/subscription-data", func(r chi.Router) {
 			// Access and Mobility Data
@@ -64,10 +83,23 @@ func (s *UDRServer) setupRoutes() {
 			r.Get("/{supi}/provisioned-data/sm-data", s.handleGetSMData)
 			r.Put("/{supi}/provisioned-data/sm-data", s.handleUpdateSMData)
 
+			// UE Context Data, AMF registrations kept for the UDM
+			r.Get("/{supi}/context-data/amf-3gpp-access", s.handleGetAMF3GPPRegistration)
+			r.Put("/{supi}/context-data/amf-3gpp-access", s.handlePutAMF3GPPRegistration)
+			r.Delete("/{supi}/context-data/amf-3gpp-access", s.handleDeleteAMF3GPPRegistration)
+
+			// Subscriptions to data change notifications, made by the UDM
+			r.Get("/subs-to-notify", s.handleGetSubscriptions)
+			r.Post("/subs-to-notify", s.handleCreateSubscription)
+			r.Delete("/subs-to-notify/{subscriptionId}", s.handleDeleteSubscription)
+
 			// Authentication Subscription
 			r.Get("/{supi}/authentication-data/authentication-subscription", s.handleGetAuthSubscription)
 			r.Put("/{supi}/authentication-data/authentication-subscription", s.handleUpdateAuthSubscription)
 			r.Patch("/{supi}/authentication-data/authentication-subscription/sqn", s.handleIncrementSQN)
+			r.Put("/{supi}/authentication-data/authentication-subscription/sqn", s.handleSetSQN)
+			r.Post("/{supi}/authentication-data/authentication-subscription/sqn-block", s.handleReserveSQNBlock)
+			r.Put("/{supi}/authentication-data/authentication-status", s.handlePutAuthStatus)
 		})
 
 		// Policy Data (TS 29.519)
@@ -88,6 +120,7 @@ func (s *UDRServer) setupRoutes() {
 	s.router.Route("/admin", func(r chi.Router) {
 		r.Get("/subscribers", s.handleListSubscribers)
 		r.Post("/subscribers", s.handleCreateSubscriber)
+		r.Post("/subscribers/batch", s.handleBatchCreateSubscribers)
 		r.Get("/subscribers/{supi}", s.handleGetSubscriber)
 		r.Put("/subscribers/{supi}", s.handlePutSubscriber)
 		r.Delete("/subscribers/{supi}", s.handleDeleteSubscriber)
@@ -95,11 +128,30 @@ func (s *UDRServer) setupRoutes() {
 		// Authentication subscription management
 		r.Post("/auth-subscriptions", s.handleCreateAuthSubscription)
 		r.Get("/auth-subscriptions/{supi}", s.handleGetAuthSubscription)
+		r.Get("/auth-events/{supi}", s.handleListAuthEvents)
 
 		r.Get("/stats", s.handleGetStats)
 	})
 }
 
+// SetNotifier sets the notifier used to deliver data change notifications
+func (s *UDRServer) SetNotifier(notifier Notifier) {
+	s.notifier = notifier
+}
+
+// SetBuildInfo exposes the NF build info on GET /version
+func (s *UDRServer) SetBuildInfo(info *buildinfo.Info) {
+	s.router.Get("/version", info.Handler())
+}
+
+// SetLogLevel exposes the level the NF's loggers are built from on
+// /admin/log-level, read with GET and changed at runtime with PUT
+func (s *UDRServer) SetLogLevel(level zap.AtomicLevel) {
+	handler := loglevel.Handler(level, s.logger)
+	s.router.Get("/admin/log-level", handler)
+	s.router.Put("/admin/log-level", handler)
+}
+
 // Start starts the HTTP server
 func (s *UDRServer) Start(ctx context.Context) error {
 	addr := fmt.Sprintf("%s:%d", s.config.SBI.BindAddress, s.config.SBI.Port)
@@ -116,6 +168,11 @@ func (s *UDRServer) Start(ctx context.Context) error {
 
 	// Start server
 	if s.config.SBI.TLS.Enabled {
+		tlsConfig, err := s.config.SBI.TLS.Build()
+		if err != nil {
+			return fmt.Errorf("invalid TLS configuration: %w", err)
+		}
+		s.httpServer.TLSConfig = tlsConfig
 		return s.httpServer.ListenAndServeTLS(
 			s.config.SBI.TLS.CertFile,
 			s.config.SBI.TLS.KeyFile,
@@ -155,10 +212,25 @@ func (s *UDRServer) loggingMiddleware(next http.Handler) http.Handler {
 			zap.Duration("duration", time.Since(start)),
 			zap.String("remote_addr", r.RemoteAddr),
 			zap.String("request_id", middleware.GetReqID(r.Context())),
+			correlation.Field(r.Context()),
 		)
 	})
 }
 
+// cacheControlMiddleware reads from the database, bypassing the subscriber
+// cache, for requests with "Cache-Control: no-cache"
+func cacheControlMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, directive := range strings.Split(r.Header.Get("Cache-Control"), ",") {
+			if strings.EqualFold(strings.TrimSpace(directive), "no-cache") {
+				r = r.WithContext(repository.WithoutCache(r.Context()))
+				break
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // handleHealth handles health check requests
 func (s *UDRServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -168,9 +240,11 @@ func (s *UDRServer) handleHealth(w http.ResponseWriter, r *http.Request) {
 
 // handleReady handles readiness check requests
 func (s *UDRServer) handleReady(w http.ResponseWriter, r *http.Request) {
-	// Check if repository is ready
-	if err := s.repository.Ping(r.Context()); err != nil {
-		s.respondError(w, http.StatusServiceUnavailable, "repository unavailable", err)
+	// Check if repository is ready, liveness (/health) stays unprobed
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+	if err := s.repository.Ping(ctx); err != nil {
+		s.respondProblem(w, http.StatusServiceUnavailable, sbi.CauseNFCongestion, "repository unavailable", err)
 		return
 	}
 
@@ -183,7 +257,7 @@ func (s *UDRServer) handleReady(w http.ResponseWriter, r *http.Request) {
 func (s *UDRServer) handleStatus(w http.ResponseWriter, r *http.Request) {
 	stats, err := s.repository.GetStats(r.Context())
 	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, "failed to get stats", err)
+		s.respondProblem(w, http.StatusInternalServerError, sbi.CauseSystemFailure, "failed to get stats", err)
 		return
 	}
 
@@ -205,12 +279,11 @@ func (s *UDRServer) respondJSON(w http.ResponseWriter, status int, data interfac
 	}
 }
 
-// respondError writes an error response
-func (s *UDRServer) respondError(w http.ResponseWriter, status int, message string, err error) {
-	s.logger.Error(message, zap.Error(err))
+// respondProblem writes a ProblemDetails error response
+func (s *UDRServer) respondProblem(w http.ResponseWriter, status int, cause, title string, err error) {
+	s.logger.Error(title, zap.String("cause", cause), zap.Error(err))
 
-	w.Header().Set("Content-Type", "application/problem+json")
-	w.WriteHeader(status)
-
-	fmt.Fprintf(w, `{"status":%d,"title":"%s","detail":"%s"}`, status, message, err.Error())
+	if err := sbi.WriteProblem(w, sbi.NewProblem(status, cause, title, err)); err != nil {
+		s.logger.Error("Failed to encode problem details", zap.Error(err))
+	}
 }
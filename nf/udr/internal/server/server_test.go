@@ -0,0 +1,324 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/common/sbi"
+	"github.com/your-org/5g-network/nf/udr/internal/config"
+	"github.com/your-org/5g-network/nf/udr/internal/events"
+	"github.com/your-org/5g-network/nf/udr/internal/repository"
+)
+
+// pingRepository is a repository whose only working method is Ping
+type pingRepository struct {
+	repository.Repository
+	err error
+}
+
+func (r *pingRepository) Ping(ctx context.Context) error {
+	return r.err
+}
+
+func TestReadinessProbesDatabase(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"database up", nil, http.StatusOK},
+		{"database down", errors.New("dial tcp 10.0.0.9:9000: connection refused"), http.StatusServiceUnavailable},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewUDRServer(&config.Config{}, &pingRepository{err: tt.err}, zap.NewNop())
+			assert.NoError(t, err)
+
+			rec := httptest.NewRecorder()
+			s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+			assert.Equal(t, tt.status, rec.Code)
+			if tt.err != nil {
+				assert.Equal(t, sbi.ContentTypeProblem, rec.Header().Get("Content-Type"))
+				assert.Contains(t, rec.Body.String(), "connection refused")
+			}
+
+			// Liveness never touches the database
+			rec = httptest.NewRecorder()
+			s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+			assert.Equal(t, http.StatusOK, rec.Code)
+		})
+	}
+}
+
+// subscriptionRepository stores one subscriber and serves fixed SDM
+// subscriptions
+type subscriptionRepository struct {
+	repository.Repository
+	subscriber    *repository.SubscriberData
+	subscriptions []*repository.SDMSubscription
+}
+
+func (r *subscriptionRepository) GetSubscriber(ctx context.Context, supi string) (*repository.SubscriberData, error) {
+	return r.subscriber, nil
+}
+
+func (r *subscriptionRepository) UpdateSubscriber(ctx context.Context, supi string, data *repository.SubscriberData) error {
+	r.subscriber = data
+	return nil
+}
+
+func (r *subscriptionRepository) ListSDMSubscriptions(ctx context.Context, supi string) ([]*repository.SDMSubscription, error) {
+	var subscriptions []*repository.SDMSubscription
+	for _, sub := range r.subscriptions {
+		if sub.UEID == supi {
+			subscriptions = append(subscriptions, sub)
+		}
+	}
+	return subscriptions, nil
+}
+
+// recordingNotifier records the notifications it is asked to deliver
+type recordingNotifier struct {
+	notifications map[string]*repository.DataChangeNotify // subscription ID -> notification
+}
+
+func (n *recordingNotifier) Notify(sub *repository.SDMSubscription, data *repository.DataChangeNotify) {
+	n.notifications[sub.SubscriptionID] = data
+}
+
+func TestAMDataUpdateNotifiesSubscribers(t *testing.T) {
+	repo := &subscriptionRepository{
+		subscriber: &repository.SubscriberData{SUPI: "imsi-001010000000001", SubscriberStatus: "ACTIVE", RoamingAllowed: true},
+		subscriptions: []*repository.SDMSubscription{
+			{SubscriptionID: "am", UEID: "imsi-001010000000001", CallbackURI: "http://udm/notify", OriginalCallbackURI: "http://amf/sdm", MonitoredResourceURIs: []string{"http://udm/nudm-sdm/v1/supi/imsi-001010000000001/am-data"}},
+			{SubscriptionID: "all", UEID: "imsi-001010000000001", CallbackURI: "http://udm/notify"},
+			{SubscriptionID: "sm", UEID: "imsi-001010000000001", CallbackURI: "http://udm/notify", MonitoredResourceURIs: []string{"http://udm/nudm-sdm/v1/supi/imsi-001010000000001/sm-data"}},
+			{SubscriptionID: "policy", UEID: "imsi-001010000000001", CallbackURI: "http://pcf/notify", MonitoredResourceURIs: []string{"/nudr-dr/v1/policy-data/ues/imsi-001010000000001/sm-data"}},
+			{SubscriptionID: "other-ue", UEID: "imsi-001010000000002", CallbackURI: "http://udm/notify"},
+		},
+	}
+	bus := events.NewBus()
+	notifier := &recordingNotifier{notifications: make(map[string]*repository.DataChangeNotify)}
+	s, err := NewUDRServer(&config.Config{}, events.NewRepository(repo, bus, zap.NewNop()), zap.NewNop())
+	assert.NoError(t, err)
+	s.SetNotifier(notifier)
+	bus.Subscribe(s.NotifyDataChange)
+
+	path := "/nudr-dr/v1/subscription-data/imsi-001010000000001/provisioned-data/am-data"
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, path, strings.NewReader(`{"gpsis":"OPERATOR_DETERMINED_BARRING","roamingAllowed":true}`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// Only the subscriptions of the UE monitoring AM data are notified, of
+	// the fields that changed
+	assert.Len(t, notifier.notifications, 2)
+	data := notifier.notifications["am"]
+	if assert.NotNil(t, data) {
+		assert.Equal(t, "imsi-001010000000001", data.UEID)
+		assert.Equal(t, []string{"http://amf/sdm"}, data.OriginalCallbackReference)
+		assert.Equal(t, path, data.NotifyItems[0].ResourceID)
+		assert.Equal(t, []repository.ChangeItem{
+			{Op: "REPLACE", Path: "/gpsis", NewValue: "OPERATOR_DETERMINED_BARRING"},
+		}, data.NotifyItems[0].Changes)
+	}
+	assert.Contains(t, notifier.notifications, "all")
+}
+
+// listingRepository lists in-memory subscribers newest first, as the
+// ClickHouse repository does
+type listingRepository struct {
+	repository.Repository
+	subscribers []*repository.SubscriberData
+}
+
+func (r *listingRepository) create(supi string, createdAt time.Time) {
+	r.subscribers = append(r.subscribers, &repository.SubscriberData{SUPI: supi, CreatedAt: createdAt})
+	sort.Slice(r.subscribers, func(i, j int) bool {
+		a, b := r.subscribers[i], r.subscribers[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.SUPI > b.SUPI
+	})
+}
+
+func (r *listingRepository) ListSubscribers(ctx context.Context, filter repository.SubscriberFilter, limit, offset int) ([]*repository.SubscriberData, error) {
+	if offset > len(r.subscribers) {
+		offset = len(r.subscribers)
+	}
+	return r.subscribers[offset:min(offset+limit, len(r.subscribers))], nil
+}
+
+func (r *listingRepository) ListSubscribersAfter(ctx context.Context, filter repository.SubscriberFilter, after *repository.SubscriberCursor, limit int) ([]*repository.SubscriberData, error) {
+	start := 0
+	if after != nil {
+		start = sort.Search(len(r.subscribers), func(i int) bool {
+			s := r.subscribers[i]
+			return s.CreatedAt.Before(after.CreatedAt) || (s.CreatedAt.Equal(after.CreatedAt) && s.SUPI < after.SUPI)
+		})
+	}
+	return r.subscribers[start:min(start+limit, len(r.subscribers))], nil
+}
+
+func (r *listingRepository) CountSubscribers(ctx context.Context, filter repository.SubscriberFilter) (uint64, error) {
+	return uint64(len(r.subscribers)), nil
+}
+
+func TestListSubscribersCursorPaging(t *testing.T) {
+	repo := &listingRepository{}
+	created := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 10; i++ {
+		// Pairs created in the same millisecond are ordered by SUPI
+		repo.create(fmt.Sprintf("imsi-0010100000000%02d", i), created.Add(time.Duration(i/2)*time.Millisecond))
+	}
+	s, err := NewUDRServer(&config.Config{}, repo, zap.NewNop())
+	assert.NoError(t, err)
+
+	type page struct {
+		Subscribers []repository.SubscriberData `json:"subscribers"`
+		NextCursor  string                      `json:"nextCursor"`
+		Offset      *int                        `json:"offset"`
+	}
+	list := func(query string) (*httptest.ResponseRecorder, page) {
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/subscribers?"+query, nil))
+		var p page
+		json.Unmarshal(rec.Body.Bytes(), &p)
+		return rec, p
+	}
+
+	seen := make(map[string]int)
+	query := "limit=3"
+	for pages := 0; ; pages++ {
+		rec, p := list(query)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		for _, subscriber := range p.Subscribers {
+			seen[subscriber.SUPI]++
+		}
+		if p.NextCursor == "" {
+			break
+		}
+		query = "limit=3&cursor=" + p.NextCursor
+
+		// Subscribers created mid-iteration are newer than the cursor
+		if pages == 0 {
+			repo.create("imsi-001010000000100", created.Add(time.Hour))
+			repo.create("imsi-001010000000101", created.Add(time.Hour))
+		}
+	}
+	assert.Len(t, seen, 10)
+	for supi, count := range seen {
+		assert.Equal(t, 1, count, supi)
+		assert.NotContains(t, []string{"imsi-001010000000100", "imsi-001010000000101"}, supi)
+	}
+
+	// Offset paging is still served, flagged as deprecated
+	rec, p := list("limit=5&offset=10")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+	assert.Len(t, p.Subscribers, 2)
+	assert.Empty(t, p.NextCursor)
+	assert.NotNil(t, p.Offset)
+
+	rec, _ = list("cursor=not-a-cursor")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec, _ = list("offset=3&cursor=" + repository.CursorAfter(repo.subscribers[0]).Encode())
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+// auditRepository keeps authentication events in memory, filtering them as
+// the ClickHouse repository does
+type auditRepository struct {
+	repository.Repository
+	events []*repository.AuthEvent
+}
+
+func (r *auditRepository) RecordAuthEvent(ctx context.Context, event *repository.AuthEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *auditRepository) ListAuthEvents(ctx context.Context, supi string, filter repository.AuthEventFilter, limit int) ([]*repository.AuthEvent, error) {
+	var events []*repository.AuthEvent
+	for i := len(r.events) - 1; i >= 0 && len(events) < limit; i-- {
+		event := r.events[i]
+		if event.SUPI != supi ||
+			(!filter.Since.IsZero() && event.Timestamp.Before(filter.Since)) ||
+			(!filter.Until.IsZero() && !event.Timestamp.Before(filter.Until)) {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func TestAuthEventsRecordedAndListed(t *testing.T) {
+	repo := &auditRepository{}
+	s, err := NewUDRServer(&config.Config{}, repo, zap.NewNop())
+	assert.NoError(t, err)
+
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	for i, success := range []bool{false, true, true} {
+		event := repository.AuthEvent{
+			Success:        success,
+			AuthMethod:     "5G_AKA",
+			ServingNetwork: "5G:mnc001.mcc001.3gppnetwork.org",
+			Timestamp:      start.Add(time.Duration(i) * time.Hour),
+		}
+		if !success {
+			event.FailureReason = "RES* mismatch"
+		}
+		body, _ := json.Marshal(event)
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodPut,
+			"/nudr-dr/v1/subscription-data/imsi-001010000000001/authentication-data/authentication-status",
+			strings.NewReader(string(body))))
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	}
+	assert.Len(t, repo.events, 3)
+	assert.Equal(t, "imsi-001010000000001", repo.events[0].SUPI)
+
+	type listing struct {
+		Events []repository.AuthEvent `json:"events"`
+		Count  int                    `json:"count"`
+	}
+	list := func(supi, query string) (*httptest.ResponseRecorder, listing) {
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/auth-events/"+supi+"?"+query, nil))
+		var l listing
+		json.Unmarshal(rec.Body.Bytes(), &l)
+		return rec, l
+	}
+
+	rec, l := list("imsi-001010000000001", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 3, l.Count)
+	assert.True(t, l.Events[0].Timestamp.After(l.Events[2].Timestamp))
+	assert.Equal(t, "RES* mismatch", l.Events[2].FailureReason)
+
+	// Only the first two hours
+	rec, l = list("imsi-001010000000001", "since="+start.Format(time.RFC3339)+"&until="+start.Add(2*time.Hour).Format(time.RFC3339))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, l.Events, 2)
+	assert.False(t, l.Events[1].Success)
+
+	rec, l = list("imsi-001010000000002", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotNil(t, l.Events)
+	assert.Empty(t, l.Events)
+
+	for _, query := range []string{"since=yesterday", "limit=0", "supi=x", "since=" + start.Format(time.RFC3339) + "&until=" + start.Format(time.RFC3339)} {
+		rec, _ = list("imsi-001010000000001", query)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, query)
+	}
+}
@@ -4,19 +4,28 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
+	"github.com/your-org/5g-network/common/buildinfo"
+	"github.com/your-org/5g-network/common/correlation"
 	"github.com/your-org/5g-network/common/metrics"
+	"github.com/your-org/5g-network/common/nrfpool"
+	"github.com/your-org/5g-network/common/reload"
+	"github.com/your-org/5g-network/common/retry"
 	"github.com/your-org/5g-network/nf/upf/internal/client"
 	"github.com/your-org/5g-network/nf/upf/internal/config"
 	upfcontext "github.com/your-org/5g-network/nf/upf/internal/context"
+	"github.com/your-org/5g-network/nf/upf/internal/dataplane"
 	"github.com/your-org/5g-network/nf/upf/internal/gtpu"
 	"github.com/your-org/5g-network/nf/upf/internal/pfcp"
 	"github.com/your-org/5g-network/nf/upf/internal/server"
+	"github.com/your-org/5g-network/nf/upf/internal/throughput"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -24,6 +33,7 @@ import (
 var (
 	Version   = "dev"
 	BuildTime = "unknown"
+	GitCommit = "unknown"
 )
 
 func main() {
@@ -31,8 +41,9 @@ func main() {
 	flag.StringVar(&configPath, "config", "nf/upf/config/upf.yaml", "Path to configuration file")
 	flag.Parse()
 
-	// Initialize logger
-	logger := initLogger()
+	// Initialize logger, at the configured level once loaded
+	level := zap.NewAtomicLevel()
+	logger := initLogger(level)
 	defer logger.Sync()
 
 	logger.Info("Starting UPF (User Plane Function)",
@@ -50,6 +61,23 @@ func main() {
 		zap.String("n3_bind", cfg.GetN3Address()),
 		zap.String("node_id", cfg.PFCP.NodeID))
 
+	// Reload the log level and NRF heartbeat interval on SIGHUP
+	reloader := reload.New(configPath, cfg, config.Load, []string{
+		"observability.logging.level",
+		"nrf.heartbeat_interval",
+	}, logger)
+	if err := reloader.SetLogLevel(level, func(c *config.Config) string { return c.Observability.Logging.Level }); err != nil {
+		logger.Fatal("Invalid configuration", zap.Error(err))
+	}
+
+	// HTTP transport towards the NRF, with mutual TLS when configured,
+	// retries of transient failures and correlation headers
+	tlsTransport, err := cfg.NRF.TLS.Transport()
+	if err != nil {
+		logger.Fatal("Invalid client TLS configuration", zap.Error(err))
+	}
+	sbiTransport := correlation.NewTransport(retry.NewTransport(tlsTransport, cfg.NRF.Retry, logger), logger)
+
 	// Create UPF context
 	upfCtx := upfcontext.NewUPFContext()
 	logger.Info("UPF context initialized")
@@ -58,22 +86,42 @@ func main() {
 	pfcpServer := pfcp.NewPFCPServer(cfg, upfCtx, logger)
 	logger.Info("PFCP server initialized")
 
+	// Data plane the PFCP rules are installed into
+	dataPlane, err := dataplane.New(cfg, logger)
+	if err != nil {
+		logger.Fatal("Failed to create data plane", zap.Error(err))
+	}
+	if err := dataPlane.Initialize(context.Background(), dataplane.Config(cfg)); err != nil {
+		logger.Fatal("Failed to initialize data plane", zap.Error(err))
+	}
+	pfcpServer.SetDataPlane(dataPlane)
+	logger.Info("Data plane initialized", zap.String("type", cfg.DataPlane.Type))
+
 	// Create GTP-U handler (N3/N6)
 	gtpuHandler := gtpu.NewGTPUHandler(cfg, upfCtx, logger)
+	gtpuHandler.SetReportHandler(pfcpServer.HandleSessionReport)
+	pfcpServer.SetEndMarkerSender(gtpuHandler)
 	logger.Info("GTP-U handler initialized")
 
 	// Create admin/monitoring HTTP server
 	httpServer := server.NewServer(cfg, upfCtx, gtpuHandler, logger)
+	sampler := throughput.NewSampler(upfCtx, throughput.DefaultInterval, throughput.DefaultWindow, logger)
+	httpServer.SetSampler(sampler)
+	httpServer.SetBuildInfo(buildinfo.New("UPF", Version, GitCommit, BuildTime,
+		"pfcp", "gtp-u", "qos-monitoring", "downlink-data-notification"))
 	logger.Info("HTTP admin server initialized")
 
 	// Create context
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Initialize metrics server (UPF uses port 9098, admin server uses 9096)
-	metricsServer := metrics.NewMetricsServer(9098, logger)
+	// Initialize metrics server, on the port assigned to the UPF
+	metricsRegistry, err := metrics.NewRegistry("UPF", cfg.NF.InstanceID)
+	if err != nil {
+		logger.Fatal("Failed to create metrics registry", zap.Error(err))
+	}
+	metricsServer := metrics.NewMetricsServer(metricsRegistry, logger)
 	go func() {
-		logger.Info("Starting metrics server on :9098")
 		if err := metricsServer.Start(); err != nil {
 			logger.Error("Metrics server error", zap.Error(err))
 		}
@@ -86,7 +134,11 @@ func main() {
 
 	// Register with NRF if enabled
 	if cfg.NRF.Enabled {
-		nrfClient := client.NewNRFClient(cfg.NRF.URL, logger)
+		endpoints, err := nrfpool.New(ctx, cfg.NRF.URL, cfg.NRF.Options, logger)
+		if err != nil {
+			logger.Fatal("Failed to resolve NRF endpoints", zap.Error(err))
+		}
+		nrfClient := client.NewNRFClient(endpoints, sbiTransport, logger)
 
 		profile := &client.NFProfile{
 			NFInstanceID: cfg.NF.InstanceID,
@@ -96,7 +148,8 @@ func main() {
 				MCC: cfg.PLMN.MCC,
 				MNC: cfg.PLMN.MNC,
 			},
-			IPv4Addresses: []string{fmt.Sprintf("%s:%d", cfg.PFCP.BindAddress, cfg.PFCP.Port)},
+			FQDN:          cfg.PFCP.NodeID,
+			IPv4Addresses: []string{net.JoinHostPort(cfg.PFCP.AdvertisedAddress(), strconv.Itoa(cfg.PFCP.Port))},
 			Capacity:      100,
 			Priority:      1,
 			UPFInfo: &client.UPFInfo{
@@ -128,7 +181,7 @@ func main() {
 
 			// Start heartbeat goroutine
 			go func() {
-				ticker := time.NewTicker(cfg.NRF.HeartbeatInterval)
+				ticker := reloader.Ticker(func(c *config.Config) time.Duration { return c.NRF.HeartbeatInterval })
 				defer ticker.Stop()
 
 				for {
@@ -168,6 +221,11 @@ func main() {
 		}
 	}()
 
+	go reloader.Watch(ctx)
+
+	// Sample session throughput for the metrics and admin server
+	go sampler.Run(ctx)
+
 	// Start HTTP admin server
 	httpErrChan := make(chan error, 1)
 	go func() {
@@ -198,11 +256,16 @@ func main() {
 
 	// Graceful shutdown
 	logger.Info("Shutting down UPF...")
-	cancel()
-
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer shutdownCancel()
 
+	// Drain the data plane while PFCP can still deliver the final usage
+	// reports to the SMF
+	if err := dataPlane.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Error draining data plane", zap.Error(err))
+	}
+	cancel()
+
 	if err := httpServer.Stop(shutdownCtx); err != nil {
 		logger.Error("Error stopping HTTP server", zap.Error(err))
 	}
@@ -210,7 +273,7 @@ func main() {
 	logger.Info("UPF shutdown complete")
 }
 
-func initLogger() *zap.Logger {
+func initLogger(level zap.AtomicLevel) *zap.Logger {
 	encoderConfig := zapcore.EncoderConfig{
 		TimeKey:        "ts",
 		LevelKey:       "level",
@@ -227,7 +290,7 @@ func initLogger() *zap.Logger {
 	}
 
 	config := zap.Config{
-		Level:            zap.NewAtomicLevelAt(zap.InfoLevel),
+		Level:            level,
 		Development:      false,
 		Encoding:         "json",
 		EncoderConfig:    encoderConfig,
@@ -2,10 +2,15 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/your-org/5g-network/common/nrfpool"
+	"github.com/your-org/5g-network/common/retry"
+	"github.com/your-org/5g-network/common/tlsconfig"
 )
 
 // Config holds the UPF configuration
@@ -19,6 +24,7 @@ type Config struct {
 	DNN           []DNNConfig         `yaml:"dnn"`
 	QoS           QoSConfig           `yaml:"qos"`
 	Forwarding    ForwardingConfig    `yaml:"forwarding"`
+	DataPlane     DataPlaneConfig     `yaml:"dataplane"`
 	NRF           NRFConfig           `yaml:"nrf"`
 	Observability ObservabilityConfig `yaml:"observability"`
 }
@@ -32,9 +38,22 @@ type NFConfig struct {
 
 // PFCPConfig holds PFCP (N4) interface configuration
 type PFCPConfig struct {
-	BindAddress string `yaml:"bind_address"`
-	Port        int    `yaml:"port"`
-	NodeID      string `yaml:"node_id"`
+	BindAddress  string `yaml:"bind_address"`
+	Port         int    `yaml:"port"`
+	LocalAddress string `yaml:"local_address"` // N4 address the SMFs reach the UPF on
+	NodeID       string `yaml:"node_id"`
+}
+
+// AdvertisedAddress returns the N4 IP address registered with the NRF: the
+// local address, or the bind address when it is a specific one. It is
+// empty when neither is routable.
+func (c PFCPConfig) AdvertisedAddress() string {
+	for _, address := range []string{c.LocalAddress, c.BindAddress} {
+		if ip := net.ParseIP(address); ip != nil && !ip.IsUnspecified() {
+			return ip.String()
+		}
+	}
+	return ""
 }
 
 // N3Config holds N3 interface configuration (gNB-UPF)
@@ -42,13 +61,29 @@ type N3Config struct {
 	BindAddress  string `yaml:"bind_address"`
 	Port         int    `yaml:"port"`
 	LocalAddress string `yaml:"local_address"`
+	MTU          int    `yaml:"mtu"` // Outer IP MTU of the N3 path
 }
 
+// DefaultN3MTU is the N3 path MTU used when none is configured
+const DefaultN3MTU = 1500
+
+// minN3MTU leaves room for the GTP-U encapsulation and a 576 byte inner
+// packet (RFC 791 minimum reassembly size)
+const minN3MTU = 576 + 44
+
+// N6 data path types
+const (
+	N6TypeUDP = "udp" // UDP simulation of the data network
+	N6TypeTUN = "tun" // TUN device routed by the kernel
+)
+
 // N6Config holds N6 interface configuration (Data Network)
 type N6Config struct {
-	InterfaceName string `yaml:"interface_name"`
+	Type          string `yaml:"type"`           // udp (default) or tun
+	InterfaceName string `yaml:"interface_name"` // The TUN device with type tun
 	Subnet        string `yaml:"subnet"`
 	Gateway       string `yaml:"gateway"`
+	GatewayIPv6   string `yaml:"gateway_ipv6"` // UPF's IPv6 address towards the DN, optional
 	DNSPrimary    string `yaml:"dns_primary"`
 	DNSSecondary  string `yaml:"dns_secondary"`
 }
@@ -87,11 +122,38 @@ type ForwardingConfig struct {
 	BufferSize         int           `yaml:"buffer_size"`
 }
 
+// Data plane backends
+const (
+	DataPlaneTypeSimulated = "simulated" // Rules matched in Go
+	DataPlaneTypeXDP       = "xdp"       // eBPF fast path, needs the xdp build tag
+)
+
+// DataPlaneConfig selects the data plane the PFCP rules are installed into
+type DataPlaneConfig struct {
+	Type string    `yaml:"type"` // simulated (default) or xdp
+	XDP  XDPConfig `yaml:"xdp"`
+}
+
+// XDPConfig holds the XDP data plane configuration
+type XDPConfig struct {
+	Interface string `yaml:"interface"` // N3 interface the program is attached to
+	Program   string `yaml:"program"`   // Compiled eBPF object file
+}
+
 // NRFConfig holds NRF client configuration
 type NRFConfig struct {
 	URL               string        `yaml:"url"`
 	Enabled           bool          `yaml:"enabled"`
 	HeartbeatInterval time.Duration `yaml:"heartbeat_interval"`
+
+	// TLS towards the NRF, the UPF having no SBI server of its own
+	TLS tlsconfig.ClientConfig `yaml:"tls"`
+
+	// Retries of heartbeats and other idempotent requests to the NRF
+	Retry retry.Policy `yaml:"retry"`
+
+	// Secondary NRFs and DNS SRV discovery for failover
+	nrfpool.Options `yaml:",inline"`
 }
 
 // ObservabilityConfig holds observability configuration
@@ -139,12 +201,49 @@ func Load(configPath string) (*Config, error) {
 	if config.N3.Port == 0 {
 		config.N3.Port = 2152
 	}
+	if config.N3.MTU == 0 {
+		config.N3.MTU = DefaultN3MTU
+	}
+	if config.N3.MTU < minN3MTU {
+		return nil, fmt.Errorf("invalid n3 config: mtu must be at least %d", minN3MTU)
+	}
+	switch config.N6.Type {
+	case "":
+		config.N6.Type = N6TypeUDP
+	case N6TypeUDP, N6TypeTUN:
+	default:
+		return nil, fmt.Errorf("invalid n6 config: unknown type %q", config.N6.Type)
+	}
 	if config.N9.Port == 0 {
 		config.N9.Port = 2153
 	}
 	if config.Forwarding.BufferSize == 0 {
 		config.Forwarding.BufferSize = 65535
 	}
+	switch config.DataPlane.Type {
+	case "":
+		config.DataPlane.Type = DataPlaneTypeSimulated
+	case DataPlaneTypeSimulated:
+	case DataPlaneTypeXDP:
+		if config.DataPlane.XDP.Interface == "" || config.DataPlane.XDP.Program == "" {
+			return nil, fmt.Errorf("invalid dataplane config: xdp needs an interface and a program")
+		}
+	default:
+		return nil, fmt.Errorf("invalid dataplane config: unknown type %q", config.DataPlane.Type)
+	}
+
+	if config.NRF.Enabled && config.PFCP.AdvertisedAddress() == "" {
+		return nil, fmt.Errorf("invalid pfcp config: local_address is needed to register with the NRF when bind_address is unspecified")
+	}
+	if err := config.NRF.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid nrf config: %w", err)
+	}
+	if err := config.NRF.TLS.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid nrf tls config: %w", err)
+	}
+	if err := config.NRF.Retry.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid nrf retry config: %w", err)
+	}
 
 	return &config, nil
 }
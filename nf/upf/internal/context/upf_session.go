@@ -8,43 +8,64 @@ import (
 
 // UPFSession represents a PDU session in the UPF
 type UPFSession struct {
-	SEID         uint64 // F-SEID (Session Endpoint Identifier)
-	SMFSEID      uint64 // SMF's F-SEID
-	UEAddress    net.IP // UE IP address
-	GNBTEID      uint32 // gNB Tunnel Endpoint ID (N3)
-	UPFTEID      uint32 // UPF Tunnel Endpoint ID (N3)
-	GNBAddress   net.IP // gNB IP address
-	DNN          string // Data Network Name
-	PDRs         []PDR  // Packet Detection Rules
-	FARs         []FAR  // Forwarding Action Rules
-	QERs         []QER  // QoS Enforcement Rules
+	SEID         uint64     // F-SEID (Session Endpoint Identifier)
+	SMFSEID      uint64     // SMF's F-SEID
+	SMFNodeID    string     // Node ID of the SMF association owning the session
+	UEAddress    net.IP     // UE IPv4 address
+	UEIPv6Prefix *net.IPNet // UE IPv6 prefix, downlink to any address in it
+	GNBTEID      uint32     // gNB Tunnel Endpoint ID (N3)
+	UPFTEID      uint32     // UPF Tunnel Endpoint ID (N3)
+	GNBAddress   net.IP     // gNB IP address
+	DNN          string     // Data Network Name
+	PDRs         []PDR      // Packet Detection Rules
+	FARs         []FAR      // Forwarding Action Rules
+	QERs         []QER      // QoS Enforcement Rules
+	URRs         []URR      // Usage Reporting Rules
 	CreatedAt    time.Time
 	LastActivity time.Time
+
+	// ErrorIndicated is set when the gNB sent an Error Indication for
+	// GNBTEID. Downlink is not forwarded until the SMF sets a new tunnel.
+	ErrorIndicated bool
+
+	// Traffic forwarded for the session, updated with RecordTraffic
+	Traffic SessionTraffic
+}
+
+// SessionTraffic counts the user plane packets forwarded for a session
+type SessionTraffic struct {
+	UplinkPackets   uint64
+	UplinkBytes     uint64
+	DownlinkPackets uint64
+	DownlinkBytes   uint64
 }
 
 // PDR represents a Packet Detection Rule (3GPP TS 29.244)
 type PDR struct {
-	PDRID              uint16 // PDR ID
-	Precedence         uint32 // Rule precedence
-	PDI                PDI    // Packet Detection Information
-	OuterHeaderRemoval uint8  // 0=None, 1=GTP-U/UDP/IPv4
-	FARID              uint32 // Forwarding Action Rule ID
-	QERID              uint32 // QoS Enforcement Rule ID
+	PDRID              uint16   // PDR ID
+	Precedence         uint32   // Rule precedence
+	PDI                PDI      // Packet Detection Information
+	OuterHeaderRemoval uint8    // 0=None, 1=GTP-U/UDP/IPv4
+	FARID              uint32   // Forwarding Action Rule ID
+	QERIDs             []uint32 // QoS Enforcement Rule IDs, all are enforced
+	URRIDs             []uint32 // Usage Reporting Rule IDs
 }
 
 // PDI represents Packet Detection Information
 type PDI struct {
-	SourceInterface uint8  // 0=Access (N3), 1=Core (N6), 2=SGi-LAN, 3=CP-function
-	NetworkInstance string // DNN/APN
-	FTEID           *FTEID // F-TEID for GTP-U
-	UEIPAddress     net.IP // UE IP address
-	SDFFilter       string // Service Data Flow filter
+	SourceInterface uint8      // 0=Access (N3), 1=Core (N6), 2=SGi-LAN, 3=CP-function
+	NetworkInstance string     // DNN/APN
+	FTEID           *FTEID     // F-TEID for GTP-U
+	UEIPAddress     net.IP     // UE IPv4 address
+	UEIPv6Prefix    *net.IPNet // UE IPv6 prefix
+	SDFFilter       string     // Service Data Flow filter
+	QFI             uint8      // QoS Flow Identifier, 0 matches any
 }
 
 // FAR represents a Forwarding Action Rule (3GPP TS 29.244)
 type FAR struct {
 	FARID                 uint32 // FAR ID
-	ApplyAction           uint8  // 0=DROP, 1=FORW, 2=BUFF, 3=NOCP, 4=DUPL
+	ApplyAction           uint8  // Flags: 0x01=DROP, 0x02=FORW, 0x04=BUFF, 0x08=NOCP, 0x10=DUPL
 	ForwardingParameters  *ForwardingParameters
 	DuplicatingParameters *DuplicatingParameters
 }
@@ -80,6 +101,36 @@ type QER struct {
 	GBR        *GBR   // Guaranteed Bit Rate
 	PacketRate *PacketRate
 	GateStatus uint8 // 0=OPEN, 1=CLOSED
+
+	// Packet delay monitoring of the QoS flow, nil when not requested
+	QoSMonitoring *QoSMonitoring
+}
+
+// QoSMonitoring represents the QoS monitoring the SMF requested for a QoS
+// flow (3GPP TS 29.244 5.24.4)
+type QoSMonitoring struct {
+	RequestedMonitoring uint8 // DL=0x01, UL=0x02, RP=0x04
+	DownlinkThreshold   time.Duration
+	UplinkThreshold     time.Duration
+	RoundTripThreshold  time.Duration
+	MinimumWaitTime     time.Duration
+}
+
+// URR represents a Usage Reporting Rule (3GPP TS 29.244)
+type URR struct {
+	URRID             uint32 // URR ID
+	MeasurementMethod uint8  // DURAT=0x01, VOLUM=0x02, EVENT=0x04
+	ReportingTriggers uint32 // Reporting Triggers, octet 5 in the low byte
+	MeasurementPeriod time.Duration
+	VolumeThreshold   *VolumeThreshold
+	TimeThreshold     time.Duration
+}
+
+// VolumeThreshold represents the volumes in bytes after which a URR reports
+type VolumeThreshold struct {
+	Total    uint64
+	Uplink   uint64
+	Downlink uint64
 }
 
 // MBR represents Maximum Bit Rate
@@ -106,6 +157,7 @@ type FTEID struct {
 	IPv4Address net.IP
 	IPv6Address net.IP
 	ChooseID    uint8 // 0=Use provided, 1=UPF allocates
+	CHID        uint8 // Choose ID, PDRs sharing it share the allocated F-TEID
 }
 
 // UPFContext manages all UPF sessions
@@ -113,6 +165,7 @@ type UPFContext struct {
 	sessions map[uint64]*UPFSession // Key: SEID
 	mu       sync.RWMutex
 	teidPool *TEIDPool
+	nextSEID uint64
 }
 
 // TEIDPool manages TEID allocation
@@ -168,6 +221,11 @@ func (c *UPFContext) DeleteSession(seid uint64) {
 	if session, exists := c.sessions[seid]; exists {
 		// Release TEIDs
 		c.teidPool.Release(session.UPFTEID)
+		for _, pdr := range session.PDRs {
+			if pdr.PDI.FTEID != nil && pdr.PDI.FTEID.ChooseID == 1 {
+				c.teidPool.Release(pdr.PDI.FTEID.TEID)
+			}
+		}
 		delete(c.sessions, seid)
 	}
 }
@@ -194,11 +252,85 @@ func (c *UPFContext) UpdateActivity(seid uint64) {
 	}
 }
 
+// RecordTraffic counts a packet forwarded for a session
+func (c *UPFContext) RecordTraffic(seid uint64, uplink bool, bytes int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	session, exists := c.sessions[seid]
+	if !exists {
+		return
+	}
+	if uplink {
+		session.Traffic.UplinkPackets++
+		session.Traffic.UplinkBytes += uint64(bytes)
+	} else {
+		session.Traffic.DownlinkPackets++
+		session.Traffic.DownlinkBytes += uint64(bytes)
+	}
+}
+
+// GetTraffic returns the traffic counters of a session
+func (c *UPFContext) GetTraffic(seid uint64) (SessionTraffic, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	session, exists := c.sessions[seid]
+	if !exists {
+		return SessionTraffic{}, false
+	}
+	return session.Traffic, true
+}
+
+// GetAllTraffic returns the traffic counters of all sessions by SEID
+func (c *UPFContext) GetAllTraffic() map[uint64]SessionTraffic {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	traffic := make(map[uint64]SessionTraffic, len(c.sessions))
+	for seid, session := range c.sessions {
+		traffic[seid] = session.Traffic
+	}
+	return traffic
+}
+
+// MarkErrorIndicated flags a session whose downlink tunnel the gNB reported
+// unknown. It returns false when the session was already flagged or is gone.
+func (c *UPFContext) MarkErrorIndicated(seid uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	session, exists := c.sessions[seid]
+	if !exists || session.ErrorIndicated {
+		return false
+	}
+	session.ErrorIndicated = true
+	return true
+}
+
+// AllocateSEID allocates a local F-SEID that is not in use
+func (c *UPFContext) AllocateSEID() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for {
+		c.nextSEID++
+		if _, exists := c.sessions[c.nextSEID]; !exists && c.nextSEID != 0 {
+			return c.nextSEID
+		}
+	}
+}
+
 // AllocateTEID allocates a new TEID from the pool
 func (c *UPFContext) AllocateTEID() uint32 {
 	return c.teidPool.Allocate()
 }
 
+// ReleaseTEID returns a TEID to the pool
+func (c *UPFContext) ReleaseTEID(teid uint32) {
+	c.teidPool.Release(teid)
+}
+
 // AllocateTEID allocates a new TEID
 func (p *TEIDPool) Allocate() uint32 {
 	p.mu.Lock()
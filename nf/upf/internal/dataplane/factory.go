@@ -0,0 +1,45 @@
+// Package dataplane constructs the UPF data plane selected by the
+// configuration.
+package dataplane
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/your-org/5g-network/common/dataplane"
+	"github.com/your-org/5g-network/nf/upf/internal/config"
+	"github.com/your-org/5g-network/nf/upf/internal/dataplane/simulated"
+	"go.uber.org/zap"
+)
+
+// ErrXDPUnavailable is returned for the xdp type by UPFs built without the
+// xdp build tag, or for other systems than Linux
+var ErrXDPUnavailable = errors.New("XDP data plane is not built in, rebuild with -tags xdp on Linux")
+
+// New creates the data plane of cfg.DataPlane.Type. It still needs to be
+// initialized with Config(cfg).
+func New(cfg *config.Config, logger *zap.Logger) (dataplane.DataPlane, error) {
+	switch cfg.DataPlane.Type {
+	case config.DataPlaneTypeSimulated, "":
+		return simulated.NewSimulatedDataPlane(logger), nil
+	case config.DataPlaneTypeXDP:
+		return newXDPDataPlane(&cfg.DataPlane.XDP, logger)
+	default:
+		return nil, fmt.Errorf("unknown data plane type %q", cfg.DataPlane.Type)
+	}
+}
+
+// Config returns the data plane configuration of the UPF configuration
+func Config(cfg *config.Config) *dataplane.Config {
+	dpConfig := &dataplane.Config{
+		N3Address:   net.ParseIP(cfg.N3.LocalAddress),
+		N6Interface: cfg.N6.InterfaceName,
+		Type:        cfg.DataPlane.Type,
+		MTU:         cfg.N3.MTU,
+	}
+	if cfg.DataPlane.Type == config.DataPlaneTypeXDP {
+		dpConfig.N3Interface = cfg.DataPlane.XDP.Interface
+	}
+	return dpConfig
+}
@@ -0,0 +1,43 @@
+package dataplane
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/your-org/5g-network/nf/upf/internal/config"
+	"github.com/your-org/5g-network/nf/upf/internal/dataplane/simulated"
+	"go.uber.org/zap"
+)
+
+func TestNew_Simulated(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+
+	for _, dpType := range []string{"", config.DataPlaneTypeSimulated} {
+		dp, err := New(&config.Config{DataPlane: config.DataPlaneConfig{Type: dpType}}, logger)
+		require.NoError(t, err)
+		assert.IsType(t, &simulated.SimulatedDataPlane{}, dp, "type %q", dpType)
+	}
+}
+
+func TestNew_UnknownType(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+
+	_, err := New(&config.Config{DataPlane: config.DataPlaneConfig{Type: "dpdk"}}, logger)
+	assert.ErrorContains(t, err, "dpdk")
+}
+
+func TestConfig_XDPAttachesToN3Interface(t *testing.T) {
+	cfg := &config.Config{
+		N3:        config.N3Config{LocalAddress: "10.0.0.1", MTU: 1500},
+		DataPlane: config.DataPlaneConfig{Type: config.DataPlaneTypeSimulated, XDP: config.XDPConfig{Interface: "eth1"}},
+	}
+	dpConfig := Config(cfg)
+	assert.Equal(t, "simulated", dpConfig.Type)
+	assert.Equal(t, "10.0.0.1", dpConfig.N3Address.String())
+	assert.Equal(t, 1500, dpConfig.MTU)
+	assert.Empty(t, dpConfig.N3Interface)
+
+	cfg.DataPlane.Type = config.DataPlaneTypeXDP
+	assert.Equal(t, "eth1", Config(cfg).N3Interface)
+}
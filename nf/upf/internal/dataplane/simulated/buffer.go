@@ -0,0 +1,91 @@
+package simulated
+
+import (
+	"sync"
+
+	"github.com/your-org/5g-network/common/dataplane"
+)
+
+// defaultBufferingPacketsCount is used when no BAR limits the buffer
+const defaultBufferingPacketsCount = 64
+
+// downlinkBuffer holds packets for FARs with the BUFF apply action and
+// tracks which FARs have already notified the control plane (NOCP)
+type downlinkBuffer struct {
+	mu       sync.Mutex
+	packets  map[bufferKey][]*dataplane.Packet
+	notified map[bufferKey]bool
+}
+
+type bufferKey struct {
+	sessionID uint64
+	farID     uint16
+}
+
+// newDownlinkBuffer creates a new downlink buffer
+func newDownlinkBuffer() *downlinkBuffer {
+	return &downlinkBuffer{
+		packets:  make(map[bufferKey][]*dataplane.Packet),
+		notified: make(map[bufferKey]bool),
+	}
+}
+
+// notify returns true only for the first packet since the FAR was installed
+func (b *downlinkBuffer) notify(sessionID uint64, farID uint16) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := bufferKey{sessionID: sessionID, farID: farID}
+	if b.notified[key] {
+		return false
+	}
+	b.notified[key] = true
+	return true
+}
+
+// add buffers a packet, returning false if the buffer is full
+func (b *downlinkBuffer) add(sessionID uint64, farID uint16, limit int, packet *dataplane.Packet) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if limit <= 0 {
+		limit = defaultBufferingPacketsCount
+	}
+
+	key := bufferKey{sessionID: sessionID, farID: farID}
+	if len(b.packets[key]) >= limit {
+		return false
+	}
+	b.packets[key] = append(b.packets[key], packet)
+	return true
+}
+
+// release removes and returns the packets buffered for a FAR and re-arms
+// the control plane notification
+func (b *downlinkBuffer) release(sessionID uint64, farID uint16) []*dataplane.Packet {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := bufferKey{sessionID: sessionID, farID: farID}
+	packets := b.packets[key]
+	delete(b.packets, key)
+	delete(b.notified, key)
+	return packets
+}
+
+// forget drops all buffered packets of a session
+func (b *downlinkBuffer) forget(sessionID uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for key := range b.packets {
+		if key.sessionID == sessionID {
+			delete(b.packets, key)
+		}
+	}
+	for key := range b.notified {
+		if key.sessionID == sessionID {
+			delete(b.notified, key)
+		}
+	}
+}
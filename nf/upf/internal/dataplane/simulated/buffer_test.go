@@ -0,0 +1,123 @@
+package simulated
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/common/dataplane"
+)
+
+var testUEIP = net.ParseIP("10.60.0.1")
+
+func newIdleSessionDataPlane(t *testing.T, bar *dataplane.BAR) (*SimulatedDataPlane, *[]*dataplane.SessionReport) {
+	t.Helper()
+
+	logger, _ := zap.NewDevelopment()
+	dp := NewSimulatedDataPlane(logger)
+	ctx := context.Background()
+
+	require.NoError(t, dp.InstallPDR(ctx, testSessionID, &dataplane.PDR{
+		PDRID:      2,
+		Precedence: 100,
+		PDI: &dataplane.PacketDetectionInfo{
+			SourceInterface: "CORE",
+			UEIPAddress:     &dataplane.UEIPAddress{IPv4: testUEIP},
+		},
+		FARID: 2,
+	}))
+	require.NoError(t, dp.InstallFAR(ctx, testSessionID, &dataplane.FAR{
+		FARID:       2,
+		ApplyAction: dataplane.ApplyActionBuff | dataplane.ApplyActionNocp,
+		BARID:       1,
+	}))
+	if bar != nil {
+		require.NoError(t, dp.InstallBAR(ctx, testSessionID, bar))
+	}
+
+	var reports []*dataplane.SessionReport
+	dp.SetReportHandler(func(report *dataplane.SessionReport) {
+		reports = append(reports, report)
+	})
+
+	return dp, &reports
+}
+
+func downlinkPacket() *dataplane.Packet {
+	return &dataplane.Packet{
+		Data:      make([]byte, 100),
+		Interface: "N6",
+		DstIP:     testUEIP,
+		QFI:       1,
+	}
+}
+
+func TestFirstDownlinkPacketNotifiesControlPlaneOnce(t *testing.T) {
+	dp, reports := newIdleSessionDataPlane(t, nil)
+
+	for i := 0; i < 3; i++ {
+		dp.processPacketInternal(downlinkPacket())
+	}
+
+	require.Len(t, *reports, 1)
+	report := (*reports)[0]
+	assert.Equal(t, uint64(testSessionID), report.SessionID)
+	require.NotNil(t, report.DownlinkDataReport)
+	assert.Equal(t, uint16(2), report.DownlinkDataReport.PDRID)
+	assert.Equal(t, uint16(2), report.DownlinkDataReport.FARID)
+	assert.Equal(t, uint8(1), report.DownlinkDataReport.QFI)
+
+	stats, err := dp.GetStats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), stats.PacketsBuffered)
+	assert.Zero(t, stats.PacketsForwarded)
+}
+
+func TestBufferedPacketsForwardedAfterFARUpdate(t *testing.T) {
+	dp, reports := newIdleSessionDataPlane(t, nil)
+	ctx := context.Background()
+
+	dp.processPacketInternal(downlinkPacket())
+	dp.processPacketInternal(downlinkPacket())
+
+	// SMF activates the user plane after paging the UE
+	require.NoError(t, dp.InstallFAR(ctx, testSessionID, &dataplane.FAR{
+		FARID:                2,
+		ApplyAction:          dataplane.ApplyActionForw,
+		ForwardingParameters: &dataplane.ForwardingParameters{DestinationInterface: "ACCESS"},
+	}))
+
+	stats, err := dp.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), stats.PacketsForwarded)
+
+	// The notification is re-armed once the session goes idle again
+	require.NoError(t, dp.InstallFAR(ctx, testSessionID, &dataplane.FAR{
+		FARID:       2,
+		ApplyAction: dataplane.ApplyActionBuff | dataplane.ApplyActionNocp,
+	}))
+	dp.processPacketInternal(downlinkPacket())
+	assert.Len(t, *reports, 2)
+}
+
+func TestBufferLimitedByBAR(t *testing.T) {
+	dp, reports := newIdleSessionDataPlane(t, &dataplane.BAR{
+		BARID:                          1,
+		SuggestedBufferingPacketsCount: 2,
+	})
+
+	for i := 0; i < 5; i++ {
+		dp.processPacketInternal(downlinkPacket())
+	}
+
+	assert.Len(t, *reports, 1)
+
+	stats, err := dp.GetStats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), stats.PacketsBuffered)
+	assert.Equal(t, uint64(3), stats.PacketsDropped)
+}
@@ -0,0 +1,226 @@
+package simulated
+
+import (
+	"net"
+
+	"github.com/your-org/5g-network/common/dataplane"
+)
+
+// pdrRef is a PDR of a session in the lookup index
+type pdrRef struct {
+	session *SessionRules
+	pdr     *dataplane.PDR
+}
+
+// pdrIndex finds the PDRs a packet may match without scanning every session.
+// A PDR is indexed by its local TEID, else by its UE IPv4 address and IPv6
+// prefix. PDRs with none of them may match any packet and are checked for
+// all of them.
+type pdrIndex struct {
+	byTEID     map[uint32][]pdrRef
+	byUEIP     map[[4]byte][]pdrRef
+	byUEPrefix map[ipv6PrefixKey][]pdrRef
+	wildcard   []pdrRef
+
+	// Number of indexed PDRs per UE IPv6 prefix length, the lengths a
+	// packet's address is looked up with
+	prefixLengths map[uint8]int
+}
+
+// ipv6PrefixKey is the index key of an IPv6 prefix
+type ipv6PrefixKey struct {
+	prefix [16]byte
+	length uint8
+}
+
+func newPDRIndex() *pdrIndex {
+	return &pdrIndex{
+		byTEID:        make(map[uint32][]pdrRef),
+		byUEIP:        make(map[[4]byte][]pdrRef),
+		byUEPrefix:    make(map[ipv6PrefixKey][]pdrRef),
+		prefixLengths: make(map[uint8]int),
+	}
+}
+
+// ueIPv4Key returns the index key of an IPv4 address
+func ueIPv4Key(ip net.IP) ([4]byte, bool) {
+	var key [4]byte
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return key, false
+	}
+	copy(key[:], ip4)
+	return key, true
+}
+
+// ueIPv6PrefixLength returns the length of the IPv6 prefix of a UE, /64
+// unless another length is delegated
+func ueIPv6PrefixLength(ue *dataplane.UEIPAddress) uint8 {
+	if ue.IPv6Prefix == 0 || ue.IPv6Prefix > 128 {
+		return 64
+	}
+	return ue.IPv6Prefix
+}
+
+// ueIPv6Key returns the index key of the prefix of length bits holding an
+// IPv6 address
+func ueIPv6Key(ip net.IP, length uint8) (ipv6PrefixKey, bool) {
+	key := ipv6PrefixKey{length: length}
+	if ip.To4() != nil || ip.To16() == nil {
+		return key, false
+	}
+	copy(key.prefix[:], ip.Mask(net.CIDRMask(int(length), 8*net.IPv6len)))
+	return key, true
+}
+
+// ueIPv6PrefixKey returns the index key of the IPv6 prefix of a UE
+func ueIPv6PrefixKey(ue *dataplane.UEIPAddress) (ipv6PrefixKey, bool) {
+	return ueIPv6Key(ue.IPv6, ueIPv6PrefixLength(ue))
+}
+
+// ueAddressMatches reports whether ip is the UE IPv4 address or in the UE
+// IPv6 prefix. A UE IP address without either matches every packet.
+func ueAddressMatches(ue *dataplane.UEIPAddress, ip net.IP) bool {
+	if ue.IPv4 == nil && ue.IPv6 == nil {
+		return true
+	}
+	if ip.To4() != nil {
+		return ue.IPv4 != nil && ue.IPv4.Equal(ip)
+	}
+	if ue.IPv6 == nil {
+		return false
+	}
+	ueKey, _ := ueIPv6PrefixKey(ue)
+	key, ok := ueIPv6Key(ip, ueKey.length)
+	return ok && key == ueKey
+}
+
+// add indexes a PDR of a session. PDRs without a PDI never match and are
+// not indexed.
+func (x *pdrIndex) add(session *SessionRules, pdr *dataplane.PDR) {
+	if pdr.PDI == nil {
+		return
+	}
+	ref := pdrRef{session: session, pdr: pdr}
+
+	if pdr.PDI.LocalFTEID != nil {
+		teid := pdr.PDI.LocalFTEID.TEID
+		x.byTEID[teid] = append(x.byTEID[teid], ref)
+		return
+	}
+	if ue := pdr.PDI.UEIPAddress; ue != nil {
+		// A dual-stack PDR is found by either address
+		key, hasIPv4 := ueIPv4Key(ue.IPv4)
+		if hasIPv4 {
+			x.byUEIP[key] = append(x.byUEIP[key], ref)
+		}
+		prefix, hasIPv6 := ueIPv6PrefixKey(ue)
+		if hasIPv6 {
+			x.byUEPrefix[prefix] = append(x.byUEPrefix[prefix], ref)
+			x.prefixLengths[prefix.length]++
+		}
+		if hasIPv4 || hasIPv6 {
+			return
+		}
+	}
+	x.wildcard = append(x.wildcard, ref)
+}
+
+// remove drops a PDR added with add
+func (x *pdrIndex) remove(session *SessionRules, pdr *dataplane.PDR) {
+	if pdr.PDI == nil {
+		return
+	}
+
+	if pdr.PDI.LocalFTEID != nil {
+		teid := pdr.PDI.LocalFTEID.TEID
+		if refs := removeRef(x.byTEID[teid], session, pdr); len(refs) > 0 {
+			x.byTEID[teid] = refs
+		} else {
+			delete(x.byTEID, teid)
+		}
+		return
+	}
+	if ue := pdr.PDI.UEIPAddress; ue != nil {
+		key, hasIPv4 := ueIPv4Key(ue.IPv4)
+		if hasIPv4 {
+			if refs := removeRef(x.byUEIP[key], session, pdr); len(refs) > 0 {
+				x.byUEIP[key] = refs
+			} else {
+				delete(x.byUEIP, key)
+			}
+		}
+		prefix, hasIPv6 := ueIPv6PrefixKey(ue)
+		if hasIPv6 {
+			if refs := removeRef(x.byUEPrefix[prefix], session, pdr); len(refs) > 0 {
+				x.byUEPrefix[prefix] = refs
+			} else {
+				delete(x.byUEPrefix, prefix)
+			}
+			if x.prefixLengths[prefix.length]--; x.prefixLengths[prefix.length] == 0 {
+				delete(x.prefixLengths, prefix.length)
+			}
+		}
+		if hasIPv4 || hasIPv6 {
+			return
+		}
+	}
+	x.wildcard = removeRef(x.wildcard, session, pdr)
+}
+
+func removeRef(refs []pdrRef, session *SessionRules, pdr *dataplane.PDR) []pdrRef {
+	for i, ref := range refs {
+		if ref.session == session && ref.pdr == pdr {
+			return append(refs[:i:i], refs[i+1:]...)
+		}
+	}
+	return refs
+}
+
+// lookup returns the matching PDR with the lowest precedence value (TS
+// 29.244 5.2.1). Ties go to the lowest session ID, then the lowest PDR ID,
+// so the result does not depend on map order.
+func (x *pdrIndex) lookup(packet *dataplane.Packet, match func(*dataplane.PDR, *dataplane.Packet) bool) (pdrRef, bool) {
+	var best pdrRef
+	found := false
+	consider := func(refs []pdrRef) {
+		for _, ref := range refs {
+			if (!found || precedes(ref, best)) && match(ref.pdr, packet) {
+				best, found = ref, true
+			}
+		}
+	}
+
+	consider(x.byTEID[packet.TEID])
+	ueIP := packetUEIP(packet)
+	if key, ok := ueIPv4Key(ueIP); ok {
+		consider(x.byUEIP[key])
+	}
+	for length := range x.prefixLengths {
+		if key, ok := ueIPv6Key(ueIP, length); ok {
+			consider(x.byUEPrefix[key])
+		}
+	}
+	consider(x.wildcard)
+	return best, found
+}
+
+// precedes reports whether a takes priority over b
+func precedes(a, b pdrRef) bool {
+	if a.pdr.Precedence != b.pdr.Precedence {
+		return a.pdr.Precedence < b.pdr.Precedence
+	}
+	if a.session.SessionID != b.session.SessionID {
+		return a.session.SessionID < b.session.SessionID
+	}
+	return a.pdr.PDRID < b.pdr.PDRID
+}
+
+// packetUEIP returns the UE address of a packet: the source of uplink
+// packets, the destination of downlink packets
+func packetUEIP(packet *dataplane.Packet) net.IP {
+	if packet.Interface == "N3" {
+		return packet.SrcIP
+	}
+	return packet.DstIP
+}
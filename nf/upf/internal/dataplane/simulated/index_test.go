@@ -0,0 +1,230 @@
+package simulated
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/common/dataplane"
+)
+
+// scanPDR is the reference matcher the index replaces: every PDR of every
+// session is checked
+func scanPDR(s *SimulatedDataPlane, packet *dataplane.Packet) (pdrRef, bool) {
+	var best pdrRef
+	found := false
+	for _, session := range s.sessions {
+		for _, pdr := range session.PDRs {
+			ref := pdrRef{session: session, pdr: pdr}
+			if (!found || precedes(ref, best)) && s.matchPDR(pdr, packet) {
+				best, found = ref, true
+			}
+		}
+	}
+	return best, found
+}
+
+func sessionUEIP(i int) net.IP {
+	return net.IPv4(10, 60, byte(i>>8), byte(i)).To4()
+}
+
+// installSessions installs count sessions with an uplink PDR on TEID
+// 0x1000+i and a downlink PDR on the session's UE IP
+func installSessions(tb testing.TB, dp *SimulatedDataPlane, count int) {
+	tb.Helper()
+	ctx := context.Background()
+
+	for i := 0; i < count; i++ {
+		sessionID := uint64(i + 1)
+		require.NoError(tb, dp.InstallPDR(ctx, sessionID, &dataplane.PDR{
+			PDRID:      1,
+			Precedence: 100,
+			PDI: &dataplane.PacketDetectionInfo{
+				SourceInterface: "ACCESS",
+				LocalFTEID:      &dataplane.FTEID{TEID: uint32(0x1000 + i)},
+			},
+			FARID: 1,
+		}))
+		require.NoError(tb, dp.InstallPDR(ctx, sessionID, &dataplane.PDR{
+			PDRID:      2,
+			Precedence: 200,
+			PDI: &dataplane.PacketDetectionInfo{
+				SourceInterface: "CORE",
+				UEIPAddress:     &dataplane.UEIPAddress{IPv4: sessionUEIP(i)},
+			},
+			FARID: 2,
+		}))
+	}
+}
+
+func TestPDRIndexMatchesScan(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	dp := NewSimulatedDataPlane(logger)
+	ctx := context.Background()
+	installSessions(t, dp, 64)
+
+	// Overlapping rules: QFI specific PDRs on shared TEIDs, a second
+	// session on the same UE IP and a PDR matching any core packet
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 64; i++ {
+		require.NoError(t, dp.InstallPDR(ctx, uint64(i+1), &dataplane.PDR{
+			PDRID:      3,
+			Precedence: uint32(rng.Intn(3) * 50),
+			PDI: &dataplane.PacketDetectionInfo{
+				SourceInterface: "ACCESS",
+				LocalFTEID:      &dataplane.FTEID{TEID: uint32(0x1000 + rng.Intn(64))},
+				QFI:             uint8(rng.Intn(3) + 5),
+			},
+		}))
+	}
+	require.NoError(t, dp.InstallPDR(ctx, 1000, &dataplane.PDR{
+		PDRID:      1,
+		Precedence: 200,
+		PDI: &dataplane.PacketDetectionInfo{
+			SourceInterface: "CORE",
+			UEIPAddress:     &dataplane.UEIPAddress{IPv4: sessionUEIP(7)},
+		},
+	}))
+	require.NoError(t, dp.InstallPDR(ctx, 1001, &dataplane.PDR{
+		PDRID:      1,
+		Precedence: 150,
+		PDI:        &dataplane.PacketDetectionInfo{SourceInterface: "CORE"},
+	}))
+
+	// Updates and removals must leave no stale entries behind
+	require.NoError(t, dp.InstallPDR(ctx, 2, &dataplane.PDR{
+		PDRID:      1,
+		Precedence: 10,
+		PDI: &dataplane.PacketDetectionInfo{
+			SourceInterface: "ACCESS",
+			LocalFTEID:      &dataplane.FTEID{TEID: 0x2000},
+		},
+	}))
+	require.NoError(t, dp.RemovePDR(ctx, 3, 1))
+	require.NoError(t, dp.RemoveSession(ctx, 4))
+
+	var packets []*dataplane.Packet
+	for i := 0; i < 70; i++ {
+		for qfi := uint8(0); qfi < 8; qfi++ {
+			packets = append(packets, &dataplane.Packet{Interface: "N3", TEID: uint32(0x1000 + i), QFI: qfi, SrcIP: sessionUEIP(i)})
+		}
+		packets = append(packets, &dataplane.Packet{Interface: "N6", DstIP: sessionUEIP(i)})
+	}
+	packets = append(packets, &dataplane.Packet{Interface: "N3", TEID: 0x2000})
+
+	for _, packet := range packets {
+		want, wantFound := scanPDR(dp, packet)
+		got, gotFound := dp.index.lookup(packet, dp.matchPDR)
+		require.Equal(t, wantFound, gotFound, "packet %+v", packet)
+		if wantFound {
+			assert.Equal(t, want.session.SessionID, got.session.SessionID, "packet %+v", packet)
+			assert.Equal(t, want.pdr.PDRID, got.pdr.PDRID, "packet %+v", packet)
+		}
+	}
+
+	// Spot checks: the update moved session 2 to a new TEID and the
+	// wildcard wins over precedence 200
+	ref, found := dp.index.lookup(&dataplane.Packet{Interface: "N3", TEID: 0x2000}, dp.matchPDR)
+	require.True(t, found)
+	assert.Equal(t, uint64(2), ref.session.SessionID)
+	ref, found = dp.index.lookup(&dataplane.Packet{Interface: "N6", DstIP: sessionUEIP(7)}, dp.matchPDR)
+	require.True(t, found)
+	assert.Equal(t, uint64(1001), ref.session.SessionID)
+}
+
+func TestPDRIndexMatchesUEIPv6Prefix(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	dp := NewSimulatedDataPlane(logger)
+	ctx := context.Background()
+
+	// Two IPv6 UEs and a dual-stack UE with a delegated /56
+	for sessionID, ue := range map[uint64]*dataplane.UEIPAddress{
+		1: {IPv6: net.ParseIP("2001:db8:1:1::"), IPv6Prefix: 64},
+		2: {IPv6: net.ParseIP("2001:db8:1:2::"), IPv6Prefix: 64},
+		3: {IPv4: sessionUEIP(3), IPv6: net.ParseIP("2001:db8:2::"), IPv6Prefix: 56},
+	} {
+		require.NoError(t, dp.InstallPDR(ctx, sessionID, &dataplane.PDR{
+			PDRID:      2,
+			Precedence: 200,
+			PDI: &dataplane.PacketDetectionInfo{
+				SourceInterface: "CORE",
+				UEIPAddress:     ue,
+			},
+		}))
+	}
+
+	for dst, want := range map[string]uint64{
+		"2001:db8:1:1::1":         1,
+		"2001:db8:1:1:abcd::7":    1,
+		"2001:db8:1:2::1":         2,
+		"2001:db8:2:ff:1::1":      3,
+		sessionUEIP(3).String():   3,
+		"2001:db8:1:3::1":         0,
+		"2001:db8:2:100::1":       0,
+		sessionUEIP(1).String():   0,
+		"2001:db8:1:1:ffff::ffff": 1,
+	} {
+		packet := &dataplane.Packet{Interface: "N6", DstIP: net.ParseIP(dst)}
+		ref, found := dp.index.lookup(packet, dp.matchPDR)
+		_, scanFound := scanPDR(dp, packet)
+		assert.Equal(t, scanFound, found, dst)
+		if want == 0 {
+			assert.False(t, found, dst)
+			continue
+		}
+		require.True(t, found, dst)
+		assert.Equal(t, want, ref.session.SessionID, dst)
+	}
+
+	// Removing a session leaves the prefix of the other one
+	require.NoError(t, dp.RemoveSession(ctx, 1))
+	_, found := dp.index.lookup(&dataplane.Packet{Interface: "N6", DstIP: net.ParseIP("2001:db8:1:1::1")}, dp.matchPDR)
+	assert.False(t, found)
+	ref, found := dp.index.lookup(&dataplane.Packet{Interface: "N6", DstIP: net.ParseIP("2001:db8:1:2::1")}, dp.matchPDR)
+	require.True(t, found)
+	assert.Equal(t, uint64(2), ref.session.SessionID)
+
+	require.NoError(t, dp.RemoveSession(ctx, 2))
+	require.NoError(t, dp.RemoveSession(ctx, 3))
+	assert.Empty(t, dp.index.byUEPrefix)
+	assert.Empty(t, dp.index.prefixLengths)
+}
+
+func BenchmarkPDRMatch(b *testing.B) {
+	logger := zap.NewNop()
+	dp := NewSimulatedDataPlane(logger)
+	const sessions = 10000
+	installSessions(b, dp, sessions)
+
+	packets := make([]*dataplane.Packet, 1024)
+	for i := range packets {
+		n := (i * 7919) % sessions
+		if i%2 == 0 {
+			packets[i] = &dataplane.Packet{Interface: "N3", TEID: uint32(0x1000 + n), SrcIP: sessionUEIP(n)}
+		} else {
+			packets[i] = &dataplane.Packet{Interface: "N6", DstIP: sessionUEIP(n)}
+		}
+	}
+
+	for _, bench := range []struct {
+		name  string
+		match func(*dataplane.Packet) (pdrRef, bool)
+	}{
+		{"indexed", func(p *dataplane.Packet) (pdrRef, bool) { return dp.index.lookup(p, dp.matchPDR) }},
+		{"scan", func(p *dataplane.Packet) (pdrRef, bool) { return scanPDR(dp, p) }},
+	} {
+		b.Run(fmt.Sprintf("%s/%d_sessions", bench.name, sessions), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, found := bench.match(packets[i%len(packets)]); !found {
+					b.Fatal("no PDR matched")
+				}
+			}
+		})
+	}
+}
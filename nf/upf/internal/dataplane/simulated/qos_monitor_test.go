@@ -0,0 +1,123 @@
+package simulated
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/common/dataplane"
+)
+
+const testSessionID = 0x1001
+
+func newMonitoredDataPlane(t *testing.T, mon *dataplane.QoSMonitoring) (*SimulatedDataPlane, *[]*dataplane.SessionReport) {
+	t.Helper()
+
+	logger, _ := zap.NewDevelopment()
+	dp := NewSimulatedDataPlane(logger)
+	ctx := context.Background()
+
+	require.NoError(t, dp.InstallPDR(ctx, testSessionID, &dataplane.PDR{
+		PDRID:      1,
+		Precedence: 100,
+		PDI: &dataplane.PacketDetectionInfo{
+			SourceInterface: "ACCESS",
+			LocalFTEID:      &dataplane.FTEID{TEID: 0x100},
+		},
+		FARID: 1,
+		QERID: []uint16{1},
+	}))
+	require.NoError(t, dp.InstallFAR(ctx, testSessionID, &dataplane.FAR{
+		FARID:                1,
+		ApplyAction:          0x02,
+		ForwardingParameters: &dataplane.ForwardingParameters{DestinationInterface: "CORE"},
+	}))
+	require.NoError(t, dp.InstallQER(ctx, testSessionID, &dataplane.QER{
+		QERID:         1,
+		QFI:           5,
+		QoSMonitoring: mon,
+	}))
+
+	var reports []*dataplane.SessionReport
+	dp.SetReportHandler(func(report *dataplane.SessionReport) {
+		reports = append(reports, report)
+	})
+
+	return dp, &reports
+}
+
+func uplinkPacket(sent, received time.Time, dlDelay time.Duration) *dataplane.Packet {
+	return &dataplane.Packet{
+		Data:          make([]byte, 100),
+		Timestamp:     received,
+		Interface:     "N3",
+		TEID:          0x100,
+		QFI:           5,
+		SendTimestamp: sent,
+		DownlinkDelay: dlDelay,
+	}
+}
+
+func TestQoSMonitoringReportsDelayAboveThreshold(t *testing.T) {
+	dp, reports := newMonitoredDataPlane(t, &dataplane.QoSMonitoring{
+		RequestedMonitoring: dataplane.QoSMonitoringUplink | dataplane.QoSMonitoringDownlink | dataplane.QoSMonitoringRoundTrip,
+		UplinkThreshold:     10 * time.Millisecond,
+		RoundTripThreshold:  50 * time.Millisecond,
+	})
+
+	now := time.Now()
+	dp.processPacketInternal(uplinkPacket(now.Add(-30*time.Millisecond), now, 15*time.Millisecond))
+
+	require.Len(t, *reports, 1)
+	report := (*reports)[0]
+	assert.Equal(t, uint64(testSessionID), report.SessionID)
+	require.Len(t, report.QoSMonitoringReports, 1)
+
+	qos := report.QoSMonitoringReports[0]
+	assert.Equal(t, uint16(1), qos.QERID)
+	assert.Equal(t, uint8(5), qos.QFI)
+	assert.Equal(t, 30*time.Millisecond, qos.UplinkDelay)
+	assert.Equal(t, 15*time.Millisecond, qos.DownlinkDelay)
+	assert.Equal(t, 45*time.Millisecond, qos.RoundTripDelay)
+}
+
+func TestQoSMonitoringNoReportBelowThreshold(t *testing.T) {
+	dp, reports := newMonitoredDataPlane(t, &dataplane.QoSMonitoring{
+		RequestedMonitoring: dataplane.QoSMonitoringUplink,
+		UplinkThreshold:     10 * time.Millisecond,
+	})
+
+	now := time.Now()
+	dp.processPacketInternal(uplinkPacket(now.Add(-5*time.Millisecond), now, 0))
+
+	assert.Empty(t, *reports)
+}
+
+func TestQoSMonitoringDisabledWithoutIndicator(t *testing.T) {
+	dp, reports := newMonitoredDataPlane(t, nil)
+
+	now := time.Now()
+	dp.processPacketInternal(uplinkPacket(now.Add(-time.Second), now, time.Second))
+
+	assert.Empty(t, *reports)
+}
+
+func TestQoSMonitoringMinimumWaitTime(t *testing.T) {
+	dp, reports := newMonitoredDataPlane(t, &dataplane.QoSMonitoring{
+		RequestedMonitoring: dataplane.QoSMonitoringUplink,
+		UplinkThreshold:     10 * time.Millisecond,
+		MinimumWaitTime:     time.Second,
+	})
+
+	now := time.Now()
+	dp.processPacketInternal(uplinkPacket(now.Add(-20*time.Millisecond), now, 0))
+	dp.processPacketInternal(uplinkPacket(now.Add(480*time.Millisecond), now.Add(500*time.Millisecond), 0))
+	assert.Len(t, *reports, 1)
+
+	dp.processPacketInternal(uplinkPacket(now.Add(1480*time.Millisecond), now.Add(1500*time.Millisecond), 0))
+	assert.Len(t, *reports, 2)
+}
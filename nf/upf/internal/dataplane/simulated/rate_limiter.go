@@ -0,0 +1,109 @@
+package simulated
+
+import (
+	"sync"
+	"time"
+
+	"github.com/your-org/5g-network/common/dataplane"
+)
+
+// rateLimiterBurst is the burst tolerated on top of the MBR, expressed as
+// the amount of traffic the MBR allows in this window
+const rateLimiterBurst = 100 * time.Millisecond
+
+// rateLimiter enforces QER maximum bit rates with per direction token buckets
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[rateLimiterKey]*tokenBucket
+}
+
+type rateLimiterKey struct {
+	sessionID uint64
+	qerID     uint16
+	uplink    bool
+}
+
+// tokenBucket holds the bytes currently available to a QER
+type tokenBucket struct {
+	tokens     float64
+	lastRefill time.Time
+}
+
+// newRateLimiter creates a new rate limiter
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		buckets: make(map[rateLimiterKey]*tokenBucket),
+	}
+}
+
+// exceeded returns the first QER whose MBR the packet exceeds, or nil if it
+// fits within all of them. The packet is only charged against the buckets
+// when all of them admit it, so traffic dropped by one QER does not consume
+// the budget of another.
+func (l *rateLimiter) exceeded(sessionID uint64, qers []*dataplane.QER, packet *dataplane.Packet) *dataplane.QER {
+	now := packet.Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+	uplink := packet.Interface == "N3"
+	size := float64(len(packet.Data))
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	buckets := make([]*tokenBucket, 0, len(qers))
+	for _, qer := range qers {
+		rate := mbrFor(qer, uplink)
+		if rate == 0 {
+			continue
+		}
+
+		key := rateLimiterKey{sessionID: sessionID, qerID: qer.QERID, uplink: uplink}
+		capacity := float64(rate) / 8 * rateLimiterBurst.Seconds()
+
+		bucket, exists := l.buckets[key]
+		if !exists {
+			bucket = &tokenBucket{tokens: capacity, lastRefill: now}
+			l.buckets[key] = bucket
+		} else if elapsed := now.Sub(bucket.lastRefill); elapsed > 0 {
+			bucket.tokens += float64(rate) / 8 * elapsed.Seconds()
+			if bucket.tokens > capacity {
+				bucket.tokens = capacity
+			}
+			bucket.lastRefill = now
+		}
+
+		if bucket.tokens < size {
+			return qer
+		}
+		buckets = append(buckets, bucket)
+	}
+
+	for _, bucket := range buckets {
+		bucket.tokens -= size
+	}
+	return nil
+}
+
+// forget drops the bucket state of a removed session
+func (l *rateLimiter) forget(sessionID uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key := range l.buckets {
+		if key.sessionID == sessionID {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// mbrFor returns the MBR of a QER in bps for the given direction, 0 if unset
+func mbrFor(qer *dataplane.QER, uplink bool) uint64 {
+	if qer.MBR == nil {
+		return 0
+	}
+	if uplink {
+		return qer.MBR.Uplink
+	}
+	return qer.MBR.Downlink
+}
@@ -0,0 +1,328 @@
+package simulated
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/common/dataplane"
+)
+
+const (
+	testNonGBRQFI = 1
+	testGBRQFI    = 5
+)
+
+// newAMBRDataPlane installs a session with a non-GBR flow sharing a 1 Mbps
+// session AMBR and a GBR flow policed by its own 100 Mbps MBR only
+func newAMBRDataPlane(t *testing.T) *SimulatedDataPlane {
+	t.Helper()
+
+	logger, _ := zap.NewDevelopment()
+	dp := NewSimulatedDataPlane(logger)
+	ctx := context.Background()
+
+	for _, pdr := range []struct {
+		PDRID  uint16
+		QFI    uint8
+		QERIDs []uint16
+	}{
+		{PDRID: 1, QFI: testNonGBRQFI, QERIDs: []uint16{2, 1}},
+		{PDRID: 3, QFI: testGBRQFI, QERIDs: []uint16{6}},
+	} {
+		require.NoError(t, dp.InstallPDR(ctx, testSessionID, &dataplane.PDR{
+			PDRID:      pdr.PDRID,
+			Precedence: 100,
+			PDI: &dataplane.PacketDetectionInfo{
+				SourceInterface: "ACCESS",
+				LocalFTEID:      &dataplane.FTEID{TEID: 0x100},
+				QFI:             pdr.QFI,
+			},
+			FARID: 1,
+			QERID: pdr.QERIDs,
+		}))
+	}
+
+	require.NoError(t, dp.InstallFAR(ctx, testSessionID, &dataplane.FAR{
+		FARID:                1,
+		ApplyAction:          dataplane.ApplyActionForw,
+		ForwardingParameters: &dataplane.ForwardingParameters{DestinationInterface: "CORE"},
+	}))
+	require.NoError(t, dp.InstallQER(ctx, testSessionID, &dataplane.QER{
+		QERID: 1, // Session AMBR
+		MBR:   &dataplane.MBR{Uplink: 1000000, Downlink: 1000000},
+	}))
+	require.NoError(t, dp.InstallQER(ctx, testSessionID, &dataplane.QER{
+		QERID: 2,
+		QFI:   testNonGBRQFI,
+	}))
+	require.NoError(t, dp.InstallQER(ctx, testSessionID, &dataplane.QER{
+		QERID: 6,
+		QFI:   testGBRQFI,
+		GBR:   &dataplane.GBR{Uplink: 50000000, Downlink: 50000000},
+		MBR:   &dataplane.MBR{Uplink: 100000000, Downlink: 100000000},
+	}))
+
+	return dp
+}
+
+// sendBurst sends packets of one QoS flow at the same instant and returns
+// how many were forwarded
+func sendBurst(dp *SimulatedDataPlane, qfi uint8, count int, now time.Time) uint64 {
+	before := dp.stats.PacketsForwarded
+	for i := 0; i < count; i++ {
+		dp.processPacketInternal(&dataplane.Packet{
+			Data:      make([]byte, 1500),
+			Timestamp: now,
+			Interface: "N3",
+			TEID:      0x100,
+			QFI:       qfi,
+		})
+	}
+	return dp.stats.PacketsForwarded - before
+}
+
+func TestGBRFlowNotLimitedBySessionAMBR(t *testing.T) {
+	dp := newAMBRDataPlane(t)
+	now := time.Now()
+
+	// 150 KB in one burst is far above the 1 Mbps session AMBR but well
+	// within the GBR flow MBR
+	assert.Equal(t, uint64(100), sendBurst(dp, testGBRQFI, 100, now))
+
+	forwarded := sendBurst(dp, testNonGBRQFI, 100, now)
+	assert.Less(t, forwarded, uint64(100))
+	assert.NotZero(t, forwarded)
+	assert.Equal(t, 100-forwarded, dp.stats.QoSViolations)
+	assert.Equal(t, 100-forwarded, dp.stats.PacketsDropped)
+
+	// The session AMBR budget refills over time
+	assert.NotZero(t, sendBurst(dp, testNonGBRQFI, 1, now.Add(time.Second)))
+}
+
+// newDownlinkAMBRDataPlane installs the downlink PDRs the SMF builds for a
+// default flow sharing a 1 Mbps session AMBR and a GBR flow told apart by
+// its packet filter
+func newDownlinkAMBRDataPlane(t *testing.T) *SimulatedDataPlane {
+	t.Helper()
+
+	dp := newAMBRDataPlane(t)
+	ctx := context.Background()
+	ueIP := &dataplane.UEIPAddress{IPv4: net.ParseIP("10.60.0.1")}
+
+	require.NoError(t, dp.InstallPDR(ctx, testSessionID, &dataplane.PDR{
+		PDRID:      2,
+		Precedence: 201,
+		PDI:        &dataplane.PacketDetectionInfo{SourceInterface: "CORE", UEIPAddress: ueIP, QFI: testNonGBRQFI},
+		FARID:      2,
+		QERID:      []uint16{2, 1},
+	}))
+	require.NoError(t, dp.InstallPDR(ctx, testSessionID, &dataplane.PDR{
+		PDRID:      10,
+		Precedence: 105,
+		PDI: &dataplane.PacketDetectionInfo{
+			SourceInterface: "CORE",
+			UEIPAddress:     ueIP,
+			QFI:             testGBRQFI,
+			SDFFilter:       []string{"permit out 17 from 198.51.100.0/24 to assigned"},
+		},
+		FARID: 2,
+		QERID: []uint16{6},
+	}))
+	require.NoError(t, dp.InstallFAR(ctx, testSessionID, &dataplane.FAR{
+		FARID:       2,
+		ApplyAction: dataplane.ApplyActionForw,
+		ForwardingParameters: &dataplane.ForwardingParameters{
+			DestinationInterface: "ACCESS",
+			OuterHeaderCreation:  &dataplane.OuterHeaderCreation{TEID: 0x200, IPv4: net.ParseIP("192.168.1.20")},
+		},
+	}))
+
+	return dp
+}
+
+// sendDownlinkBurst sends downlink packets from a server at the same instant
+// and returns how many were forwarded
+func sendDownlinkBurst(dp *SimulatedDataPlane, server string, count int, now time.Time) uint64 {
+	before := dp.stats.PacketsForwarded
+	for i := 0; i < count; i++ {
+		dp.processPacketInternal(&dataplane.Packet{
+			Data:      make([]byte, 1500),
+			Timestamp: now,
+			SrcIP:     net.ParseIP(server),
+			DstIP:     net.ParseIP("10.60.0.1"),
+			SrcPort:   5004,
+			DstPort:   40000,
+			Protocol:  17,
+			Interface: "N6",
+		})
+	}
+	return dp.stats.PacketsForwarded - before
+}
+
+func TestDownlinkGBRTrafficUsesFlowQER(t *testing.T) {
+	dp := newDownlinkAMBRDataPlane(t)
+	now := time.Now()
+
+	// Traffic from the GBR flow's server only hits the GBR QER, so the burst
+	// is well within its MBR although far above the session AMBR
+	assert.Equal(t, uint64(100), sendDownlinkBurst(dp, "198.51.100.7", 100, now))
+
+	// Other traffic falls to the default flow and the session AMBR
+	forwarded := sendDownlinkBurst(dp, "203.0.113.9", 100, now)
+	assert.Less(t, forwarded, uint64(100))
+	assert.NotZero(t, forwarded)
+	assert.Equal(t, 100-forwarded, qerDrops(t, dp, 1).Downlink)
+}
+
+func TestClosedGateDropsPackets(t *testing.T) {
+	dp := newAMBRDataPlane(t)
+	require.NoError(t, dp.InstallQER(context.Background(), testSessionID, &dataplane.QER{
+		QERID:      6,
+		QFI:        testGBRQFI,
+		GateStatus: 1,
+	}))
+
+	assert.Zero(t, sendBurst(dp, testGBRQFI, 1, time.Now()))
+	assert.Equal(t, uint64(1), dp.stats.QoSViolations)
+}
+
+const (
+	testMBRUplink   = 1000000 // 1 Mbps
+	testMBRDownlink = 8000000 // 8 Mbps
+	testMBRTEID     = 0x200
+)
+
+// newMBRDataPlane installs a session whose uplink and downlink PDRs share a
+// QER with a 1 Mbps uplink and 8 Mbps downlink MBR
+func newMBRDataPlane(t *testing.T) *SimulatedDataPlane {
+	t.Helper()
+
+	logger, _ := zap.NewDevelopment()
+	dp := NewSimulatedDataPlane(logger)
+	ctx := context.Background()
+
+	require.NoError(t, dp.InstallPDR(ctx, testSessionID, &dataplane.PDR{
+		PDRID:      1,
+		Precedence: 100,
+		PDI: &dataplane.PacketDetectionInfo{
+			SourceInterface: "ACCESS",
+			LocalFTEID:      &dataplane.FTEID{TEID: testMBRTEID},
+		},
+		FARID: 1,
+		QERID: []uint16{1},
+	}))
+	require.NoError(t, dp.InstallPDR(ctx, testSessionID, &dataplane.PDR{
+		PDRID:      2,
+		Precedence: 100,
+		PDI: &dataplane.PacketDetectionInfo{
+			SourceInterface: "CORE",
+			UEIPAddress:     &dataplane.UEIPAddress{IPv4: testUEIP},
+		},
+		FARID: 1,
+		QERID: []uint16{1},
+	}))
+	require.NoError(t, dp.InstallFAR(ctx, testSessionID, &dataplane.FAR{
+		FARID:                1,
+		ApplyAction:          dataplane.ApplyActionForw,
+		ForwardingParameters: &dataplane.ForwardingParameters{DestinationInterface: "CORE"},
+	}))
+	require.NoError(t, dp.InstallQER(ctx, testSessionID, &dataplane.QER{
+		QERID: 1,
+		MBR:   &dataplane.MBR{Uplink: testMBRUplink, Downlink: testMBRDownlink},
+	}))
+	return dp
+}
+
+// sendPaced sends count 1500 byte packets spaced by interval and returns how
+// many were forwarded
+func sendPaced(dp *SimulatedDataPlane, uplink bool, count int, interval time.Duration, start time.Time) uint64 {
+	before := dp.stats.PacketsForwarded
+	for i := 0; i < count; i++ {
+		packet := &dataplane.Packet{
+			Data:      make([]byte, 1500),
+			Timestamp: start.Add(time.Duration(i) * interval),
+			Interface: "N6",
+			DstIP:     testUEIP,
+		}
+		if uplink {
+			packet.Interface = "N3"
+			packet.TEID = testMBRTEID
+		}
+		dp.processPacketInternal(packet)
+	}
+	return dp.stats.PacketsForwarded - before
+}
+
+func qerDrops(t *testing.T, dp *SimulatedDataPlane, qerID uint16) dataplane.QERDrops {
+	t.Helper()
+
+	stats, err := dp.GetStats(context.Background())
+	require.NoError(t, err)
+	for _, drops := range stats.QERDrops {
+		if drops.SessionID == testSessionID && drops.QERID == qerID {
+			return drops
+		}
+	}
+	t.Fatalf("no drop counter for QER %d", qerID)
+	return dataplane.QERDrops{}
+}
+
+func TestMBR_TrafficBelowRateForwarded(t *testing.T) {
+	dp := newMBRDataPlane(t)
+
+	// 1500 bytes every 20ms is 600 kbps, below the 1 Mbps uplink MBR
+	assert.Equal(t, uint64(200), sendPaced(dp, true, 200, 20*time.Millisecond, time.Now()))
+	assert.Zero(t, dp.stats.PacketsDropped)
+	assert.Equal(t, dataplane.QERDrops{SessionID: testSessionID, QERID: 1}, qerDrops(t, dp, 1))
+}
+
+func TestMBR_TrafficAboveRateDropped(t *testing.T) {
+	dp := newMBRDataPlane(t)
+	start := time.Now()
+
+	// 1500 bytes every 6ms is 2 Mbps, twice the uplink MBR. Over 2 seconds
+	// the bucket admits its 100ms burst plus 1 Mbps worth of traffic.
+	const count = 334
+	forwarded := sendPaced(dp, true, count, 6*time.Millisecond, start)
+	duration := time.Duration(count-1) * 6 * time.Millisecond
+	allowed := uint64((float64(testMBRUplink)/8*(duration+rateLimiterBurst).Seconds())/1500) + 1
+	assert.LessOrEqual(t, forwarded, allowed)
+	assert.Greater(t, forwarded, uint64(count/2-10))
+
+	dropped := count - forwarded
+	assert.Equal(t, dropped, dp.stats.PacketsDropped)
+	assert.Equal(t, dropped, dp.stats.QoSViolations)
+	assert.Equal(t, dataplane.QERDrops{SessionID: testSessionID, QERID: 1, Uplink: dropped}, qerDrops(t, dp, 1))
+
+	// The same rate downlink is within the 8 Mbps downlink MBR, which has
+	// its own bucket
+	assert.Equal(t, uint64(count), sendPaced(dp, false, count, 6*time.Millisecond, start))
+	assert.Zero(t, qerDrops(t, dp, 1).Downlink)
+}
+
+func TestMBR_DropCountersFollowQER(t *testing.T) {
+	dp := newMBRDataPlane(t)
+	ctx := context.Background()
+
+	sendPaced(dp, true, 50, 0, time.Now())
+	dropped := qerDrops(t, dp, 1).Uplink
+	require.NotZero(t, dropped)
+
+	// Updating the QER keeps its counter, removing it drops the counter
+	require.NoError(t, dp.InstallQER(ctx, testSessionID, &dataplane.QER{
+		QERID: 1,
+		MBR:   &dataplane.MBR{Uplink: 2 * testMBRUplink, Downlink: testMBRDownlink},
+	}))
+	assert.Equal(t, dropped, qerDrops(t, dp, 1).Uplink)
+
+	require.NoError(t, dp.RemoveQER(ctx, testSessionID, 1))
+	stats, err := dp.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, stats.QERDrops)
+}
@@ -0,0 +1,143 @@
+package simulated
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/your-org/5g-network/common/dataplane"
+)
+
+// sdfFilter is a parsed SDF filter flow description, an IPFilterRule (RFC
+// 6733 4.3) of the form "permit out <proto> from <addr> [ports] to <addr>
+// [ports]". The "from" end is the remote host and the "to" end the UE,
+// whichever way the packet travels (TS 29.212 5.4.2).
+type sdfFilter struct {
+	protocol int // -1 for any protocol ("ip")
+	remote   endpointFilter
+	ue       endpointFilter
+}
+
+// endpointFilter matches one end of a flow
+type endpointFilter struct {
+	network *net.IPNet // nil for "any" and "assigned"
+	ports   []portRange
+}
+
+type portRange struct {
+	low, high uint16
+}
+
+// parseSDFFilter parses a flow description
+func parseSDFFilter(description string) (*sdfFilter, error) {
+	fields := strings.Fields(description)
+	if len(fields) < 7 || fields[0] != "permit" || fields[1] != "out" || fields[3] != "from" {
+		return nil, fmt.Errorf("unsupported flow description %q", description)
+	}
+
+	filter := &sdfFilter{protocol: -1}
+	if fields[2] != "ip" {
+		protocol, err := strconv.ParseUint(fields[2], 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid protocol in flow description %q", description)
+		}
+		filter.protocol = int(protocol)
+	}
+
+	rest := fields[4:]
+	var err error
+	if filter.remote, rest, err = parseEndpoint(rest); err != nil {
+		return nil, fmt.Errorf("flow description %q: %w", description, err)
+	}
+	if len(rest) == 0 || rest[0] != "to" {
+		return nil, fmt.Errorf("flow description %q has no destination", description)
+	}
+	if filter.ue, rest, err = parseEndpoint(rest[1:]); err != nil {
+		return nil, fmt.Errorf("flow description %q: %w", description, err)
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("flow description %q has unsupported options", description)
+	}
+
+	return filter, nil
+}
+
+// parseEndpoint parses an address and its optional ports and returns the
+// remaining fields
+func parseEndpoint(fields []string) (endpointFilter, []string, error) {
+	var endpoint endpointFilter
+	if len(fields) == 0 {
+		return endpoint, nil, fmt.Errorf("missing address")
+	}
+
+	// The UE address itself is matched by the PDI, so "assigned" matches
+	// like "any"
+	switch address := fields[0]; address {
+	case "any", "assigned":
+	default:
+		if !strings.Contains(address, "/") {
+			if ip := net.ParseIP(address); ip != nil && ip.To4() != nil {
+				address += "/32"
+			} else {
+				address += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(address)
+		if err != nil {
+			return endpoint, nil, fmt.Errorf("invalid address %s", fields[0])
+		}
+		endpoint.network = network
+	}
+	fields = fields[1:]
+
+	if len(fields) > 0 && fields[0] != "to" {
+		for _, item := range strings.Split(fields[0], ",") {
+			low, high, found := strings.Cut(item, "-")
+			if !found {
+				high = low
+			}
+			lowPort, err := strconv.ParseUint(low, 10, 16)
+			if err != nil {
+				return endpoint, nil, fmt.Errorf("invalid port %s", item)
+			}
+			highPort, err := strconv.ParseUint(high, 10, 16)
+			if err != nil || highPort < lowPort {
+				return endpoint, nil, fmt.Errorf("invalid port %s", item)
+			}
+			endpoint.ports = append(endpoint.ports, portRange{low: uint16(lowPort), high: uint16(highPort)})
+		}
+		fields = fields[1:]
+	}
+
+	return endpoint, fields, nil
+}
+
+// matches reports whether a packet belongs to the flow
+func (f *sdfFilter) matches(packet *dataplane.Packet) bool {
+	if f.protocol >= 0 && int(packet.Protocol) != f.protocol {
+		return false
+	}
+
+	ueIP, uePort, remoteIP, remotePort := packet.DstIP, packet.DstPort, packet.SrcIP, packet.SrcPort
+	if packet.Interface == "N3" {
+		ueIP, uePort, remoteIP, remotePort = packet.SrcIP, packet.SrcPort, packet.DstIP, packet.DstPort
+	}
+	return f.remote.matches(remoteIP, remotePort) && f.ue.matches(ueIP, uePort)
+}
+
+// matches reports whether an address and port fall within the endpoint
+func (e *endpointFilter) matches(ip net.IP, port uint16) bool {
+	if e.network != nil && !e.network.Contains(ip) {
+		return false
+	}
+	if len(e.ports) == 0 {
+		return true
+	}
+	for _, r := range e.ports {
+		if port >= r.low && port <= r.high {
+			return true
+		}
+	}
+	return false
+}
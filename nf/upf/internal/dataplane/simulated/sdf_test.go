@@ -0,0 +1,56 @@
+package simulated
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/5g-network/common/dataplane"
+)
+
+func TestSDFFilterMatchesBothDirections(t *testing.T) {
+	filter, err := parseSDFFilter("permit out 17 from 198.51.100.0/24 5000-5010 to assigned")
+	require.NoError(t, err)
+
+	downlink := &dataplane.Packet{
+		SrcIP: net.ParseIP("198.51.100.7"), SrcPort: 5004,
+		DstIP: net.ParseIP("10.60.0.1"), DstPort: 40000,
+		Protocol: 17, Interface: "N6",
+	}
+	uplink := &dataplane.Packet{
+		SrcIP: net.ParseIP("10.60.0.1"), SrcPort: 40000,
+		DstIP: net.ParseIP("198.51.100.7"), DstPort: 5004,
+		Protocol: 17, Interface: "N3",
+	}
+	assert.True(t, filter.matches(downlink))
+	assert.True(t, filter.matches(uplink))
+
+	otherServer := *downlink
+	otherServer.SrcIP = net.ParseIP("203.0.113.9")
+	assert.False(t, filter.matches(&otherServer))
+
+	otherPort := *downlink
+	otherPort.SrcPort = 443
+	assert.False(t, filter.matches(&otherPort))
+
+	tcp := *downlink
+	tcp.Protocol = 6
+	assert.False(t, filter.matches(&tcp))
+}
+
+func TestParseSDFFilterRejectsUnsupported(t *testing.T) {
+	for _, description := range []string{
+		"deny out ip from any to assigned",
+		"permit in ip from any to assigned",
+		"permit out tcp from any to assigned",
+		"permit out ip from 10.0.0.0/33 to assigned",
+		"permit out ip from any 80-20 to assigned",
+		"permit out ip from any",
+		"permit out ip from any to assigned frag",
+	} {
+		_, err := parseSDFFilter(description)
+		assert.Error(t, err, description)
+	}
+}
@@ -0,0 +1,112 @@
+package simulated
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/5g-network/common/dataplane"
+)
+
+// reportCollector records session reports delivered by concurrent workers
+type reportCollector struct {
+	mu      sync.Mutex
+	reports []*dataplane.SessionReport
+	delay   time.Duration // Before returning from a threshold report
+}
+
+func (c *reportCollector) handle(report *dataplane.SessionReport) {
+	c.mu.Lock()
+	c.reports = append(c.reports, report)
+	c.mu.Unlock()
+
+	if len(report.UsageReports) > 0 && report.UsageReports[0].Trigger != dataplane.UsageReportTriggerTermination {
+		time.Sleep(c.delay)
+	}
+}
+
+// finalReports returns the usage reports sent at shutdown
+func (c *reportCollector) finalReports() []dataplane.UsageReport {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var final []dataplane.UsageReport
+	for _, report := range c.reports {
+		for _, usage := range report.UsageReports {
+			if usage.Trigger == dataplane.UsageReportTriggerTermination {
+				final = append(final, usage)
+			}
+		}
+	}
+	return final
+}
+
+func startMeteredDataPlane(t *testing.T, urr *dataplane.URR, workers int) (*SimulatedDataPlane, *reportCollector) {
+	t.Helper()
+
+	dp, _ := newMeteredDataPlane(t, urr)
+	collector := &reportCollector{}
+	dp.SetReportHandler(collector.handle)
+	require.NoError(t, dp.Initialize(context.Background(), &dataplane.Config{Workers: workers}))
+	return dp, collector
+}
+
+func TestShutdownDrainsQueuedPackets(t *testing.T) {
+	dp, collector := startMeteredDataPlane(t, &dataplane.URR{
+		URRID:             7,
+		MeasurementMethod: dataplane.MeasurementMethodVolume,
+	}, 4)
+
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, dp.ProcessPacket(context.Background(), meteredPacket(time.Now(), 100)))
+	}
+	require.NoError(t, dp.Shutdown(context.Background()))
+
+	stats, err := dp.GetStats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1000), stats.PacketsProcessed)
+	assert.Zero(t, stats.PacketsDropped)
+
+	// One final report carries all the usage
+	final := collector.finalReports()
+	require.Len(t, final, 1)
+	assert.Equal(t, uint32(7), final[0].URRID)
+	assert.Equal(t, uint64(1000), final[0].Volume.TotalPackets)
+	assert.Equal(t, uint64(100000), final[0].Volume.UplinkVolume)
+
+	// Packets are refused once shut down, and shutting down again is a no-op
+	assert.ErrorIs(t, dp.ProcessPacket(context.Background(), meteredPacket(time.Now(), 100)), ErrShutDown)
+	assert.NoError(t, dp.Shutdown(context.Background()))
+	assert.Len(t, collector.finalReports(), 1)
+}
+
+func TestShutdownDropsPacketsPastDeadline(t *testing.T) {
+	// Every packet reaches the threshold and its report takes 10ms
+	dp, collector := startMeteredDataPlane(t, &dataplane.URR{
+		URRID:             7,
+		MeasurementMethod: dataplane.MeasurementMethodVolume,
+		ReportingTriggers: dataplane.ReportingTriggerVolumeThreshold,
+		VolumeThreshold:   &dataplane.VolumeThreshold{Total: 1},
+	}, 1)
+	collector.delay = 10 * time.Millisecond
+
+	for i := 0; i < 100; i++ {
+		require.NoError(t, dp.ProcessPacket(context.Background(), meteredPacket(time.Now(), 100)))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := dp.Shutdown(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	stats, statsErr := dp.GetStats(context.Background())
+	require.NoError(t, statsErr)
+	assert.NotZero(t, stats.PacketsDropped)
+	assert.Equal(t, uint64(100), stats.PacketsProcessed+stats.PacketsDropped)
+
+	// The final report is still sent
+	assert.Len(t, collector.finalReports(), 1)
+}
@@ -2,7 +2,9 @@ package simulated
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -13,19 +15,41 @@ import (
 	"go.uber.org/zap"
 )
 
+// DefaultDrainTimeout bounds the processing of queued packets at shutdown
+// when the caller sets no deadline
+const DefaultDrainTimeout = 5 * time.Second
+
+// ErrShutDown is returned for packets received after shutdown started
+var ErrShutDown = errors.New("data plane shut down")
+
 // SimulatedDataPlane implements a simulated UPF data plane in Go
 type SimulatedDataPlane struct {
 	config   *dataplane.Config
 	sessions map[uint64]*SessionRules
-	stats    *dataplane.Stats
-	logger   *zap.Logger
-	tracer   trace.Tracer
-	mu       sync.RWMutex
-
-	// Processing workers
+	index    *pdrIndex // PDRs of all sessions by TEID, UE IP and UE prefix
+
+	// Parsed SDF filters of installed PDRs by flow description
+	sdfFilters map[string]*sdfFilter
+	stats      *dataplane.Stats
+	logger     *zap.Logger
+	tracer     trace.Tracer
+	mu         sync.RWMutex
+	errMu      sync.Mutex // Guards stats.Errors
+
+	// Session reporting
+	reportHandler dataplane.ReportHandler
+	qosMonitor    *dataplane.QoSMonitor
+	rateLimiter   *rateLimiter
+	buffer        *downlinkBuffer
+
+	// Processing workers. ProcessPacket holds inputMu for reading while it
+	// queues a packet, Shutdown holds it to close packetChan.
 	workers    int
 	packetChan chan *dataplane.Packet
-	stopChan   chan struct{}
+	stopChan   chan struct{} // Closed when the drain deadline passes
+	workerWG   sync.WaitGroup
+	inputMu    sync.RWMutex
+	closed     bool
 }
 
 // SessionRules holds all rules for a PFCP session
@@ -35,6 +59,13 @@ type SessionRules struct {
 	FARs      map[uint16]*dataplane.FAR
 	QERs      map[uint16]*dataplane.QER
 	URRs      map[uint32]*dataplane.URR
+	BARs      map[uint16]*dataplane.BAR
+
+	// Enforcement drops per QER, kept across QER updates
+	qerDrops map[uint16]*qerDropCounter
+
+	// Ongoing measurement per URR, kept across URR updates
+	usage map[uint32]*urrUsage
 
 	// Statistics
 	PacketsProcessed uint64
@@ -42,18 +73,29 @@ type SessionRules struct {
 	CreatedAt        time.Time
 }
 
+// qerDropCounter counts the packets dropped by one QER per direction
+type qerDropCounter struct {
+	uplink   uint64
+	downlink uint64
+}
+
 // NewSimulatedDataPlane creates a new simulated data plane
 func NewSimulatedDataPlane(logger *zap.Logger) *SimulatedDataPlane {
 	return &SimulatedDataPlane{
-		sessions: make(map[uint64]*SessionRules),
+		sessions:   make(map[uint64]*SessionRules),
+		index:      newPDRIndex(),
+		sdfFilters: make(map[string]*sdfFilter),
 		stats: &dataplane.Stats{
 			Errors:    make(map[string]uint64),
 			Timestamp: time.Now(),
 		},
-		logger:     logger,
-		tracer:     otel.Tracer("upf-dataplane"),
-		packetChan: make(chan *dataplane.Packet, 10000),
-		stopChan:   make(chan struct{}),
+		logger:      logger,
+		tracer:      otel.Tracer("upf-dataplane"),
+		qosMonitor:  dataplane.NewQoSMonitor(),
+		rateLimiter: newRateLimiter(),
+		buffer:      newDownlinkBuffer(),
+		packetChan:  make(chan *dataplane.Packet, 10000),
+		stopChan:    make(chan struct{}),
 	}
 }
 
@@ -69,6 +111,7 @@ func (s *SimulatedDataPlane) Initialize(ctx context.Context, config *dataplane.C
 	}
 
 	// Start packet processing workers
+	s.workerWG.Add(s.workers)
 	for i := 0; i < s.workers; i++ {
 		go s.packetWorker(i)
 	}
@@ -95,6 +138,20 @@ func (s *SimulatedDataPlane) InstallPDR(ctx context.Context, sessionID uint64, p
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	// Parse the SDF filters once, rejecting the PDR if one is invalid
+	if pdr.PDI != nil {
+		for _, description := range pdr.PDI.SDFFilter {
+			if _, parsed := s.sdfFilters[description]; parsed {
+				continue
+			}
+			filter, err := parseSDFFilter(description)
+			if err != nil {
+				return err
+			}
+			s.sdfFilters[description] = filter
+		}
+	}
+
 	// Get or create session
 	session, exists := s.sessions[sessionID]
 	if !exists {
@@ -104,6 +161,9 @@ func (s *SimulatedDataPlane) InstallPDR(ctx context.Context, sessionID uint64, p
 			FARs:      make(map[uint16]*dataplane.FAR),
 			QERs:      make(map[uint16]*dataplane.QER),
 			URRs:      make(map[uint32]*dataplane.URR),
+			BARs:      make(map[uint16]*dataplane.BAR),
+			qerDrops:  make(map[uint16]*qerDropCounter),
+			usage:     make(map[uint32]*urrUsage),
 			CreatedAt: time.Now(),
 		}
 		s.sessions[sessionID] = session
@@ -111,8 +171,12 @@ func (s *SimulatedDataPlane) InstallPDR(ctx context.Context, sessionID uint64, p
 		s.stats.ActiveSessions++
 	}
 
-	// Install PDR
+	// Install PDR, replacing the index entry of the previous version
+	if previous, exists := session.PDRs[pdr.PDRID]; exists {
+		s.index.remove(session, previous)
+	}
 	session.PDRs[pdr.PDRID] = pdr
+	s.index.add(session, pdr)
 
 	s.logger.Debug("PDR installed",
 		zap.Uint64("session_id", sessionID),
@@ -144,6 +208,11 @@ func (s *SimulatedDataPlane) InstallFAR(ctx context.Context, sessionID uint64, f
 
 	session.FARs[far.FARID] = far
 
+	// Release packets buffered under the previous FAR once it stops buffering
+	if far.ApplyAction&dataplane.ApplyActionBuff == 0 {
+		s.releaseBuffered(session, far)
+	}
+
 	s.logger.Debug("FAR installed",
 		zap.Uint64("session_id", sessionID),
 		zap.Uint16("far_id", far.FARID),
@@ -172,6 +241,9 @@ func (s *SimulatedDataPlane) InstallQER(ctx context.Context, sessionID uint64, q
 	}
 
 	session.QERs[qer.QERID] = qer
+	if _, exists := session.qerDrops[qer.QERID]; !exists {
+		session.qerDrops[qer.QERID] = &qerDropCounter{}
+	}
 
 	s.logger.Debug("QER installed",
 		zap.Uint64("session_id", sessionID),
@@ -193,6 +265,23 @@ func (s *SimulatedDataPlane) InstallURR(ctx context.Context, sessionID uint64, u
 	}
 
 	session.URRs[urr.URRID] = urr
+	if _, exists := session.usage[urr.URRID]; !exists {
+		session.usage[urr.URRID] = newURRUsage(time.Now())
+	}
+	return nil
+}
+
+// InstallBAR installs a Buffering Action Rule
+func (s *SimulatedDataPlane) InstallBAR(ctx context.Context, sessionID uint64, bar *dataplane.BAR) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, exists := s.sessions[sessionID]
+	if !exists {
+		return fmt.Errorf("session %d not found", sessionID)
+	}
+
+	session.BARs[bar.BARID] = bar
 	return nil
 }
 
@@ -202,7 +291,10 @@ func (s *SimulatedDataPlane) RemovePDR(ctx context.Context, sessionID uint64, pd
 	defer s.mu.Unlock()
 
 	if session, exists := s.sessions[sessionID]; exists {
-		delete(session.PDRs, pdrID)
+		if pdr, exists := session.PDRs[pdrID]; exists {
+			s.index.remove(session, pdr)
+			delete(session.PDRs, pdrID)
+		}
 	}
 	return nil
 }
@@ -225,6 +317,7 @@ func (s *SimulatedDataPlane) RemoveQER(ctx context.Context, sessionID uint64, qe
 
 	if session, exists := s.sessions[sessionID]; exists {
 		delete(session.QERs, qerID)
+		delete(session.qerDrops, qerID)
 	}
 	return nil
 }
@@ -236,6 +329,18 @@ func (s *SimulatedDataPlane) RemoveURR(ctx context.Context, sessionID uint64, ur
 
 	if session, exists := s.sessions[sessionID]; exists {
 		delete(session.URRs, urrID)
+		delete(session.usage, urrID)
+	}
+	return nil
+}
+
+// RemoveBAR removes a BAR
+func (s *SimulatedDataPlane) RemoveBAR(ctx context.Context, sessionID uint64, barID uint16) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if session, exists := s.sessions[sessionID]; exists {
+		delete(session.BARs, barID)
 	}
 	return nil
 }
@@ -248,9 +353,15 @@ func (s *SimulatedDataPlane) RemoveSession(ctx context.Context, sessionID uint64
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, exists := s.sessions[sessionID]; exists {
+	if session, exists := s.sessions[sessionID]; exists {
+		for _, pdr := range session.PDRs {
+			s.index.remove(session, pdr)
+		}
 		delete(s.sessions, sessionID)
 		s.stats.ActiveSessions--
+		s.qosMonitor.Forget(sessionID)
+		s.rateLimiter.forget(sessionID)
+		s.buffer.forget(sessionID)
 
 		s.logger.Info("Session removed",
 			zap.Uint64("session_id", sessionID),
@@ -262,6 +373,12 @@ func (s *SimulatedDataPlane) RemoveSession(ctx context.Context, sessionID uint64
 
 // ProcessPacket simulates packet processing
 func (s *SimulatedDataPlane) ProcessPacket(ctx context.Context, packet *dataplane.Packet) error {
+	s.inputMu.RLock()
+	defer s.inputMu.RUnlock()
+	if s.closed {
+		return ErrShutDown
+	}
+
 	select {
 	case s.packetChan <- packet:
 		return nil
@@ -273,16 +390,22 @@ func (s *SimulatedDataPlane) ProcessPacket(ctx context.Context, packet *dataplan
 	}
 }
 
-// packetWorker processes packets from the queue
+// packetWorker processes packets from the queue until it is closed and
+// drained, or the drain deadline passes
 func (s *SimulatedDataPlane) packetWorker(id int) {
+	defer s.workerWG.Done()
 	s.logger.Info("Packet worker started", zap.Int("worker_id", id))
 
 	for {
 		select {
-		case packet := <-s.packetChan:
+		case packet, ok := <-s.packetChan:
+			if !ok {
+				s.logger.Info("Packet worker stopped", zap.Int("worker_id", id))
+				return
+			}
 			s.processPacketInternal(packet)
 		case <-s.stopChan:
-			s.logger.Info("Packet worker stopped", zap.Int("worker_id", id))
+			s.logger.Info("Packet worker stopped before draining", zap.Int("worker_id", id))
 			return
 		}
 	}
@@ -293,38 +416,32 @@ func (s *SimulatedDataPlane) processPacketInternal(packet *dataplane.Packet) {
 	ctx, span := s.tracer.Start(context.Background(), "SimulatedDataPlane.processPacket")
 	defer span.End()
 
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	// Match packet against all PDRs to find matching session
-	var matchedSession *SessionRules
-	var matchedPDR *dataplane.PDR
-	var matchedFAR *dataplane.FAR
-
-	for _, session := range s.sessions {
-		for _, pdr := range session.PDRs {
-			if s.matchPDR(pdr, packet) {
-				matchedSession = session
-				matchedPDR = pdr
-				// Get associated FAR
-				if far, exists := session.FARs[pdr.FARID]; exists {
-					matchedFAR = far
-				}
-				break
-			}
-		}
-		if matchedSession != nil {
-			break
+	// Reports are delivered after the lock is released so the handler may
+	// call back into the data plane
+	var report *dataplane.SessionReport
+	var handler dataplane.ReportHandler
+	defer func() {
+		if report != nil && handler != nil {
+			handler(report)
 		}
-	}
+	}()
+
+	// Processing updates counters and per session state, so it holds the
+	// write lock
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	if matchedSession == nil {
+	// Match the packet against the PDRs indexed by its TEID and UE IP
+	matched, found := s.index.lookup(packet, s.matchPDR)
+	if !found {
 		// No matching PDR found - drop packet
 		s.stats.PacketsDropped++
 		s.incrementError(dataplane.ErrSessionNotFound)
 		span.SetAttributes(attribute.String("action", "drop"))
 		return
 	}
+	matchedSession, matchedPDR := matched.session, matched.pdr
+	matchedFAR := matchedSession.FARs[matchedPDR.FARID]
 
 	// Update statistics
 	s.stats.PacketsProcessed++
@@ -332,18 +449,53 @@ func (s *SimulatedDataPlane) processPacketInternal(packet *dataplane.Packet) {
 	matchedSession.PacketsProcessed++
 	matchedSession.BytesProcessed += uint64(len(packet.Data))
 
+	// Enforce the QERs before forwarding. A PDR may reference several QERs
+	// (e.g. a per flow QER and the shared session AMBR QER) and the packet
+	// must satisfy all of them.
+	qers := make([]*dataplane.QER, 0, len(matchedPDR.QERID))
+	for _, qerID := range matchedPDR.QERID {
+		if qer, exists := matchedSession.QERs[qerID]; exists {
+			qers = append(qers, qer)
+		}
+	}
+
+	for _, qer := range qers {
+		if !s.applyQER(qer, packet) {
+			s.dropQoSViolation(matchedSession, qer, packet, span)
+			return
+		}
+	}
+	if qer := s.rateLimiter.exceeded(matchedSession.SessionID, qers, packet); qer != nil {
+		s.dropQoSViolation(matchedSession, qer, packet, span)
+		return
+	}
+
+	usageReports := s.measureUsage(matchedSession, matchedPDR, packet)
+
 	// Apply FAR action
+	var dlReport *dataplane.DownlinkDataReport
 	if matchedFAR != nil {
-		s.applyFAR(ctx, matchedFAR, packet, matchedPDR, span)
+		dlReport = s.applyFAR(ctx, matchedSession, matchedFAR, packet, matchedPDR, span)
 	}
 
-	// Apply QER if present
-	for _, qerID := range matchedPDR.QERID {
-		if qer, exists := matchedSession.QERs[qerID]; exists {
-			s.applyQER(qer, packet)
+	var qosReports []dataplane.QoSMonitoringReport
+	for _, qer := range qers {
+		if r := s.qosMonitor.Measure(matchedSession.SessionID, qer, packet); r != nil {
+			qosReports = append(qosReports, *r)
 		}
 	}
 
+	if dlReport != nil || len(qosReports) > 0 || len(usageReports) > 0 {
+		report = &dataplane.SessionReport{
+			SessionID:            matchedSession.SessionID,
+			DownlinkDataReport:   dlReport,
+			QoSMonitoringReports: qosReports,
+			UsageReports:         usageReports,
+			Timestamp:            time.Now(),
+		}
+		handler = s.reportHandler
+	}
+
 	span.SetAttributes(
 		attribute.Int64("session_id", int64(matchedSession.SessionID)),
 		attribute.Int("pdr_id", int(matchedPDR.PDRID)),
@@ -365,11 +517,10 @@ func (s *SimulatedDataPlane) matchPDR(pdr *dataplane.PDR, packet *dataplane.Pack
 		}
 	}
 
-	// Match on UE IP
-	if pdr.PDI.UEIPAddress != nil {
-		if pdr.PDI.UEIPAddress.IPv4 != nil && !pdr.PDI.UEIPAddress.IPv4.Equal(packet.DstIP) {
-			return false
-		}
+	// Match on UE IP: the source of uplink packets, the destination of
+	// downlink packets, against the UE IPv4 address or IPv6 prefix
+	if pdr.PDI.UEIPAddress != nil && !ueAddressMatches(pdr.PDI.UEIPAddress, packetUEIP(packet)) {
+		return false
 	}
 
 	// Match on F-TEID (for GTP-U packets)
@@ -379,25 +530,40 @@ func (s *SimulatedDataPlane) matchPDR(pdr *dataplane.PDR, packet *dataplane.Pack
 		}
 	}
 
-	// Match on QFI
-	if pdr.PDI.QFI != 0 {
-		// Would need to extract QFI from packet headers
+	// Match on QFI, carried in the PDU Session Container of GTP-U packets
+	if pdr.PDI.QFI != 0 && packet.QFI != 0 && pdr.PDI.QFI != packet.QFI {
+		return false
+	}
+
+	// Match on SDF filters, any of which may match
+	if len(pdr.PDI.SDFFilter) > 0 {
+		matched := false
+		for _, description := range pdr.PDI.SDFFilter {
+			if filter := s.sdfFilters[description]; filter != nil && filter.matches(packet) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
 	}
 
 	return true
 }
 
-// applyFAR applies forwarding actions
-func (s *SimulatedDataPlane) applyFAR(ctx context.Context, far *dataplane.FAR, packet *dataplane.Packet, pdr *dataplane.PDR, span trace.Span) {
+// applyFAR applies forwarding actions and returns a downlink data report
+// when the control plane must be notified
+func (s *SimulatedDataPlane) applyFAR(ctx context.Context, session *SessionRules, far *dataplane.FAR, packet *dataplane.Packet, pdr *dataplane.PDR, span trace.Span) *dataplane.DownlinkDataReport {
 	// DROP action
-	if far.ApplyAction&0x01 != 0 {
+	if far.ApplyAction&dataplane.ApplyActionDrop != 0 {
 		s.stats.PacketsDropped++
 		span.SetAttributes(attribute.String("action", "drop"))
-		return
+		return nil
 	}
 
 	// FORWARD action
-	if far.ApplyAction&0x02 != 0 {
+	if far.ApplyAction&dataplane.ApplyActionForw != 0 {
 		s.stats.PacketsForwarded++
 
 		// Simulate GTP-U encapsulation/decapsulation
@@ -422,41 +588,145 @@ func (s *SimulatedDataPlane) applyFAR(ctx context.Context, far *dataplane.FAR, p
 			attribute.String("action", "forward"),
 			attribute.String("destination", far.ForwardingParameters.DestinationInterface),
 		)
-		return
+		return nil
+	}
+
+	// NOCP action: notify the control plane of the first packet only
+	var report *dataplane.DownlinkDataReport
+	if far.ApplyAction&dataplane.ApplyActionNocp != 0 && s.buffer.notify(session.SessionID, far.FARID) {
+		report = &dataplane.DownlinkDataReport{
+			PDRID: pdr.PDRID,
+			FARID: far.FARID,
+			QFI:   packet.QFI,
+		}
+		span.SetAttributes(attribute.Bool("notify_cp", true))
 	}
 
 	// BUFFER action
-	if far.ApplyAction&0x04 != 0 {
-		s.stats.PacketsBuffered++
-		span.SetAttributes(attribute.String("action", "buffer"))
-		return
+	if far.ApplyAction&dataplane.ApplyActionBuff != 0 {
+		limit := 0
+		if bar, exists := session.BARs[far.BARID]; exists {
+			limit = int(bar.SuggestedBufferingPacketsCount)
+		}
+
+		if s.buffer.add(session.SessionID, far.FARID, limit, packet) {
+			s.stats.PacketsBuffered++
+			span.SetAttributes(attribute.String("action", "buffer"))
+		} else {
+			s.stats.PacketsDropped++
+			span.SetAttributes(attribute.String("action", "drop"))
+		}
 	}
+
+	return report
 }
 
-// applyQER applies QoS enforcement
-func (s *SimulatedDataPlane) applyQER(qer *dataplane.QER, packet *dataplane.Packet) {
-	// Check gate status
-	if qer.GateStatus == 1 { // CLOSED
-		// Would drop packet in real implementation
+// releaseBuffered forwards or drops the packets buffered for a FAR
+// according to its new apply action
+func (s *SimulatedDataPlane) releaseBuffered(session *SessionRules, far *dataplane.FAR) {
+	packets := s.buffer.release(session.SessionID, far.FARID)
+	if len(packets) == 0 {
 		return
 	}
 
-	// Simulate rate limiting (simplified)
-	if qer.MBR != nil {
-		// Would enforce maximum bit rate here
-		s.logger.Debug("Simulating MBR enforcement",
-			zap.Uint64("mbr_uplink", qer.MBR.Uplink),
-			zap.Uint64("mbr_downlink", qer.MBR.Downlink),
-		)
+	if far.ApplyAction&dataplane.ApplyActionForw != 0 {
+		s.stats.PacketsForwarded += uint64(len(packets))
+	} else {
+		s.stats.PacketsDropped += uint64(len(packets))
+	}
+
+	s.logger.Debug("Released buffered packets",
+		zap.Uint64("session_id", session.SessionID),
+		zap.Uint16("far_id", far.FARID),
+		zap.Int("count", len(packets)),
+		zap.Uint8("apply_action", far.ApplyAction),
+	)
+}
+
+// applyQER checks the QER gate and returns false if the packet must be
+// dropped. Bit rates are enforced by the rate limiter across all QERs of
+// the PDR.
+func (s *SimulatedDataPlane) applyQER(qer *dataplane.QER, packet *dataplane.Packet) bool {
+	// Check gate status
+	if qer.GateStatus == 1 { // CLOSED
+		return false
 	}
 
 	if qer.GBR != nil {
-		// Would enforce guaranteed bit rate here
+		// GBR is a resource reservation, nothing to police per packet
 		s.logger.Debug("Simulating GBR enforcement",
 			zap.Uint64("gbr_uplink", qer.GBR.Uplink),
 			zap.Uint64("gbr_downlink", qer.GBR.Downlink),
 		)
 	}
+
+	return true
+}
+
+// dropQoSViolation accounts for a packet dropped by a QER
+func (s *SimulatedDataPlane) dropQoSViolation(session *SessionRules, qer *dataplane.QER, packet *dataplane.Packet, span trace.Span) {
+	s.stats.PacketsDropped++
+	s.stats.QoSViolations++
+
+	if counter, exists := session.qerDrops[qer.QERID]; exists {
+		if packet.Interface == "N3" {
+			counter.uplink++
+		} else {
+			counter.downlink++
+		}
+	}
+
+	span.SetAttributes(
+		attribute.String("action", "drop"),
+		attribute.String("reason", "qos"),
+		attribute.Int("qer_id", int(qer.QERID)),
+	)
+}
+
+// measureUsage counts a packet against the URRs of its PDR. It returns a
+// usage report for each URR whose volume or time threshold the packet
+// reaches and restarts that URR's measurement. Time thresholds are checked
+// as traffic for the URR arrives.
+func (s *SimulatedDataPlane) measureUsage(session *SessionRules, pdr *dataplane.PDR, packet *dataplane.Packet) []dataplane.UsageReport {
+	now := packet.Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	var reports []dataplane.UsageReport
+	for _, urrID := range pdr.URRID {
+		urr, exists := session.URRs[urrID]
+		if !exists {
+			continue
+		}
+		usage := session.usage[urrID]
+
+		usage.add(urr, packet)
+		if trigger := usage.triggered(urr, now); trigger != 0 {
+			reports = append(reports, usage.report(urr, trigger, now))
+			usage.reset(now)
+		}
+	}
+	return reports
+}
+
+// GetUsageReport returns the usage a URR has measured since its previous
+// report without restarting the measurement
+func (s *SimulatedDataPlane) GetUsageReport(sessionID uint64, urrID uint32) (*dataplane.UsageReport, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	session, exists := s.sessions[sessionID]
+	if !exists {
+		return nil, fmt.Errorf("session %d not found", sessionID)
+	}
+	urr, exists := session.URRs[urrID]
+	if !exists {
+		return nil, fmt.Errorf("URR %d not found in session %d", urrID, sessionID)
+	}
+
+	report := session.usage[urrID].report(urr, dataplane.UsageReportTriggerImmediate, time.Now())
+	return &report, nil
 }
 
 // GetStats returns current statistics
@@ -469,29 +739,141 @@ func (s *SimulatedDataPlane) GetStats(ctx context.Context) (*dataplane.Stats, er
 	stats.Timestamp = time.Now()
 	stats.ActiveTunnels = stats.ActiveSessions // Simplified
 
+	s.errMu.Lock()
+	stats.Errors = make(map[string]uint64, len(s.stats.Errors))
+	for errType, count := range s.stats.Errors {
+		stats.Errors[errType] = count
+	}
+	s.errMu.Unlock()
+
+	for _, session := range s.sessions {
+		for qerID, counter := range session.qerDrops {
+			stats.QERDrops = append(stats.QERDrops, dataplane.QERDrops{
+				SessionID: session.SessionID,
+				QERID:     qerID,
+				Uplink:    counter.uplink,
+				Downlink:  counter.downlink,
+			})
+		}
+	}
+	sort.Slice(stats.QERDrops, func(i, j int) bool {
+		a, b := stats.QERDrops[i], stats.QERDrops[j]
+		if a.SessionID != b.SessionID {
+			return a.SessionID < b.SessionID
+		}
+		return a.QERID < b.QERID
+	})
+
 	return &stats, nil
 }
 
-// Shutdown stops the data plane
+// SetReportHandler registers the handler for session reports
+func (s *SimulatedDataPlane) SetReportHandler(handler dataplane.ReportHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reportHandler = handler
+}
+
+// Shutdown stops the data plane. New packets are refused, the queued ones
+// are processed until ctx is done, DefaultDrainTimeout from now when ctx
+// has no deadline, then a final usage report of every URR is delivered to
+// the report handler. Packets still queued at the deadline are dropped and
+// reported in the returned error.
 func (s *SimulatedDataPlane) Shutdown(ctx context.Context) error {
 	ctx, span := s.tracer.Start(ctx, "SimulatedDataPlane.Shutdown")
 	defer span.End()
 
-	s.logger.Info("Shutting down simulated data plane")
+	s.inputMu.Lock()
+	if s.closed {
+		s.inputMu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.packetChan)
+	s.inputMu.Unlock()
 
-	// Stop all workers
-	close(s.stopChan)
+	s.logger.Info("Shutting down simulated data plane", zap.Int("queued_packets", len(s.packetChan)))
 
-	// Wait for workers to finish
-	time.Sleep(100 * time.Millisecond)
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultDrainTimeout)
+		defer cancel()
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		s.workerWG.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		// Workers finish the packet at hand, the rest of the queue is lost
+		close(s.stopChan)
+		<-drained
+	}
+
+	var err error
+	dropped := 0
+	for range s.packetChan {
+		dropped++
+	}
+	if dropped > 0 {
+		s.mu.Lock()
+		s.stats.PacketsDropped += uint64(dropped)
+		s.mu.Unlock()
+		err = fmt.Errorf("drain incomplete, %d packets dropped: %w", dropped, context.Cause(ctx))
+		s.logger.Warn("Data plane drain incomplete", zap.Int("dropped_packets", dropped))
+	}
+
+	s.reportFinalUsage()
+	span.SetAttributes(attribute.Bool("drained", err == nil))
 
 	s.logger.Info("Simulated data plane stopped")
-	return nil
+	return err
 }
 
-// incrementError safely increments error counter
-func (s *SimulatedDataPlane) incrementError(errType string) {
+// reportFinalUsage delivers the usage measured by every URR since its last
+// report, one session report per session, with the termination trigger
+func (s *SimulatedDataPlane) reportFinalUsage() {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	now := time.Now()
+	var reports []*dataplane.SessionReport
+	for _, session := range s.sessions {
+		var usageReports []dataplane.UsageReport
+		for urrID, urr := range session.URRs {
+			usage := session.usage[urrID]
+			usageReports = append(usageReports, usage.report(urr, dataplane.UsageReportTriggerTermination, now))
+			usage.reset(now)
+		}
+		if len(usageReports) == 0 {
+			continue
+		}
+		sort.Slice(usageReports, func(i, j int) bool { return usageReports[i].URRID < usageReports[j].URRID })
+		reports = append(reports, &dataplane.SessionReport{
+			SessionID:    session.SessionID,
+			UsageReports: usageReports,
+			Timestamp:    now,
+		})
+	}
+	handler := s.reportHandler
+	s.mu.Unlock()
+
+	if handler == nil {
+		return
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].SessionID < reports[j].SessionID })
+	for _, report := range reports {
+		handler(report)
+	}
+	s.logger.Info("Final usage reports sent", zap.Int("sessions", len(reports)))
+}
+
+// incrementError safely increments error counter. It takes its own lock as
+// packet workers call it while holding mu.
+func (s *SimulatedDataPlane) incrementError(errType string) {
+	s.errMu.Lock()
+	defer s.errMu.Unlock()
 	s.stats.Errors[errType]++
 }
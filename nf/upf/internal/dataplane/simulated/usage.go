@@ -0,0 +1,91 @@
+package simulated
+
+import (
+	"time"
+
+	"github.com/your-org/5g-network/common/dataplane"
+)
+
+// urrUsage accumulates the usage of one URR since its previous report
+type urrUsage struct {
+	seqn   uint32
+	start  time.Time
+	volume dataplane.VolumeMeasurement
+}
+
+// newURRUsage starts a measurement at now
+func newURRUsage(now time.Time) *urrUsage {
+	return &urrUsage{start: now}
+}
+
+// add counts a packet when the URR measures volume
+func (u *urrUsage) add(urr *dataplane.URR, packet *dataplane.Packet) {
+	if urr.MeasurementMethod&dataplane.MeasurementMethodVolume == 0 {
+		return
+	}
+
+	size := uint64(len(packet.Data))
+	u.volume.TotalVolume += size
+	u.volume.TotalPackets++
+	if packet.Interface == "N3" {
+		u.volume.UplinkVolume += size
+		u.volume.UplinkPackets++
+	} else {
+		u.volume.DownlinkVolume += size
+		u.volume.DownlinkPackets++
+	}
+}
+
+// triggered returns the Usage Report Trigger flags of the thresholds the
+// measurement has reached, or zero
+func (u *urrUsage) triggered(urr *dataplane.URR, now time.Time) uint32 {
+	var trigger uint32
+
+	if urr.ReportingTriggers&dataplane.ReportingTriggerVolumeThreshold != 0 &&
+		urr.MeasurementMethod&dataplane.MeasurementMethodVolume != 0 &&
+		volumeThresholdReached(urr.VolumeThreshold, &u.volume) {
+		trigger |= dataplane.ReportingTriggerVolumeThreshold
+	}
+
+	if urr.ReportingTriggers&dataplane.ReportingTriggerTimeThreshold != 0 &&
+		urr.MeasurementMethod&dataplane.MeasurementMethodDuration != 0 &&
+		urr.TimeThreshold > 0 && now.Sub(u.start) >= urr.TimeThreshold {
+		trigger |= dataplane.ReportingTriggerTimeThreshold
+	}
+
+	return trigger
+}
+
+// report builds a usage report of the measurement so far
+func (u *urrUsage) report(urr *dataplane.URR, trigger uint32, now time.Time) dataplane.UsageReport {
+	report := dataplane.UsageReport{
+		URRID:          urr.URRID,
+		SequenceNumber: u.seqn,
+		Trigger:        trigger,
+		StartTime:      u.start,
+		EndTime:        now,
+		Volume:         u.volume,
+	}
+	if urr.MeasurementMethod&dataplane.MeasurementMethodDuration != 0 {
+		report.Duration = now.Sub(u.start)
+	}
+	return report
+}
+
+// reset starts a new measurement after a report was sent
+func (u *urrUsage) reset(now time.Time) {
+	u.seqn++
+	u.start = now
+	u.volume = dataplane.VolumeMeasurement{}
+}
+
+// volumeThresholdReached reports whether any configured volume threshold
+// has been reached
+func volumeThresholdReached(threshold *dataplane.VolumeThreshold, volume *dataplane.VolumeMeasurement) bool {
+	if threshold == nil {
+		return false
+	}
+	return (threshold.Total > 0 && volume.TotalVolume >= threshold.Total) ||
+		(threshold.Uplink > 0 && volume.UplinkVolume >= threshold.Uplink) ||
+		(threshold.Downlink > 0 && volume.DownlinkVolume >= threshold.Downlink)
+}
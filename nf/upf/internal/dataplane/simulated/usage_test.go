@@ -0,0 +1,151 @@
+package simulated
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/common/dataplane"
+)
+
+func newMeteredDataPlane(t *testing.T, urr *dataplane.URR) (*SimulatedDataPlane, *[]*dataplane.SessionReport) {
+	t.Helper()
+
+	logger, _ := zap.NewDevelopment()
+	dp := NewSimulatedDataPlane(logger)
+	ctx := context.Background()
+
+	require.NoError(t, dp.InstallPDR(ctx, testSessionID, &dataplane.PDR{
+		PDRID:      1,
+		Precedence: 100,
+		PDI: &dataplane.PacketDetectionInfo{
+			SourceInterface: "ACCESS",
+			LocalFTEID:      &dataplane.FTEID{TEID: 0x300},
+		},
+		FARID: 1,
+		URRID: []uint32{urr.URRID},
+	}))
+	require.NoError(t, dp.InstallFAR(ctx, testSessionID, &dataplane.FAR{
+		FARID:                1,
+		ApplyAction:          dataplane.ApplyActionForw,
+		ForwardingParameters: &dataplane.ForwardingParameters{DestinationInterface: "CORE"},
+	}))
+	require.NoError(t, dp.InstallURR(ctx, testSessionID, urr))
+
+	var reports []*dataplane.SessionReport
+	dp.SetReportHandler(func(report *dataplane.SessionReport) {
+		reports = append(reports, report)
+	})
+
+	return dp, &reports
+}
+
+func meteredPacket(at time.Time, size int) *dataplane.Packet {
+	return &dataplane.Packet{
+		Data:      make([]byte, size),
+		Timestamp: at,
+		Interface: "N3",
+		TEID:      0x300,
+	}
+}
+
+func TestUsageReportAtVolumeThresholdFiresOnce(t *testing.T) {
+	dp, reports := newMeteredDataPlane(t, &dataplane.URR{
+		URRID:             7,
+		MeasurementMethod: dataplane.MeasurementMethodVolume,
+		ReportingTriggers: dataplane.ReportingTriggerVolumeThreshold,
+		VolumeThreshold:   &dataplane.VolumeThreshold{Total: 1000},
+	})
+	now := time.Now()
+
+	// 900 bytes stay below the threshold
+	for i := 0; i < 9; i++ {
+		dp.processPacketInternal(meteredPacket(now, 100))
+	}
+	require.Empty(t, *reports)
+
+	// The tenth packet reaches it
+	dp.processPacketInternal(meteredPacket(now, 100))
+	require.Len(t, *reports, 1)
+	report := (*reports)[0]
+	assert.Equal(t, uint64(testSessionID), report.SessionID)
+	require.Len(t, report.UsageReports, 1)
+
+	usage := report.UsageReports[0]
+	assert.Equal(t, uint32(7), usage.URRID)
+	assert.Equal(t, uint32(0), usage.SequenceNumber)
+	assert.Equal(t, dataplane.ReportingTriggerVolumeThreshold, usage.Trigger)
+	assert.Equal(t, uint64(1000), usage.Volume.TotalVolume)
+	assert.Equal(t, uint64(1000), usage.Volume.UplinkVolume)
+	assert.Equal(t, uint64(10), usage.Volume.UplinkPackets)
+
+	// The measurement restarts, so the next packets do not report again
+	for i := 0; i < 5; i++ {
+		dp.processPacketInternal(meteredPacket(now, 100))
+	}
+	assert.Len(t, *reports, 1)
+}
+
+func TestUsageReportAtTimeThreshold(t *testing.T) {
+	dp, reports := newMeteredDataPlane(t, &dataplane.URR{
+		URRID:             3,
+		MeasurementMethod: dataplane.MeasurementMethodVolume | dataplane.MeasurementMethodDuration,
+		ReportingTriggers: dataplane.ReportingTriggerTimeThreshold,
+		TimeThreshold:     time.Minute,
+	})
+	start := time.Now()
+
+	dp.processPacketInternal(meteredPacket(start, 100))
+	require.Empty(t, *reports)
+
+	dp.processPacketInternal(meteredPacket(start.Add(2*time.Minute), 100))
+	require.Len(t, *reports, 1)
+	require.Len(t, (*reports)[0].UsageReports, 1)
+
+	usage := (*reports)[0].UsageReports[0]
+	assert.Equal(t, dataplane.ReportingTriggerTimeThreshold, usage.Trigger)
+	assert.GreaterOrEqual(t, usage.Duration, time.Minute)
+	assert.Equal(t, uint64(200), usage.Volume.TotalVolume)
+
+	dp.processPacketInternal(meteredPacket(start.Add(2*time.Minute+time.Second), 100))
+	assert.Len(t, *reports, 1)
+}
+
+func TestGetUsageReport(t *testing.T) {
+	dp, reports := newMeteredDataPlane(t, &dataplane.URR{
+		URRID:             7,
+		MeasurementMethod: dataplane.MeasurementMethodVolume,
+		ReportingTriggers: dataplane.ReportingTriggerVolumeThreshold,
+		VolumeThreshold:   &dataplane.VolumeThreshold{Uplink: 250},
+	})
+	now := time.Now()
+
+	dp.processPacketInternal(meteredPacket(now, 100))
+	dp.processPacketInternal(meteredPacket(now, 100))
+
+	// Polling does not restart the measurement
+	for i := 0; i < 2; i++ {
+		usage, err := dp.GetUsageReport(testSessionID, 7)
+		require.NoError(t, err)
+		assert.Equal(t, dataplane.UsageReportTriggerImmediate, usage.Trigger)
+		assert.Equal(t, uint64(200), usage.Volume.UplinkVolume)
+		assert.Equal(t, uint64(2), usage.Volume.TotalPackets)
+	}
+
+	dp.processPacketInternal(meteredPacket(now, 100))
+	require.Len(t, *reports, 1)
+
+	usage, err := dp.GetUsageReport(testSessionID, 7)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), usage.SequenceNumber)
+	assert.Zero(t, usage.Volume.TotalVolume)
+
+	_, err = dp.GetUsageReport(testSessionID, 8)
+	assert.Error(t, err)
+	_, err = dp.GetUsageReport(testSessionID+1, 7)
+	assert.Error(t, err)
+}
@@ -0,0 +1,145 @@
+// SPDX-License-Identifier: GPL-2.0
+//
+// UPF N3 fast path: G-PDUs whose TEID has an uplink rule are decapsulated
+// and passed to the kernel, which routes the inner packet towards N6. All
+// other packets are passed unchanged to the UPF's GTP-U socket.
+
+#include <linux/bpf.h>
+#include <linux/if_ether.h>
+#include <linux/in.h>
+#include <linux/ip.h>
+#include <linux/udp.h>
+#include <bpf/bpf_endian.h>
+#include <bpf/bpf_helpers.h>
+
+#define GTPU_PORT 2152
+#define GTPU_G_PDU 0xff
+#define GTPU_FLAGS_OPTIONAL 0x07 // E, S and PN
+#define GTPU_FLAG_E 0x04
+#define MAX_EXTENSION_HEADERS 2
+
+struct gtpu_hdr {
+	__u8 flags;
+	__u8 type;
+	__be16 length;
+	__be32 teid;
+};
+
+struct uplink_rule {
+	__u64 seid;
+};
+
+struct counter {
+	__u64 packets;
+	__u64 bytes;
+};
+
+enum stat {
+	STAT_RECEIVED,
+	STAT_FORWARDED,
+	STAT_SLOW_PATH,
+	STAT_MAX,
+};
+
+struct {
+	__uint(type, BPF_MAP_TYPE_HASH);
+	__uint(max_entries, 65536);
+	__type(key, __u32); // TEID in host byte order
+	__type(value, struct uplink_rule);
+} uplink_rules SEC(".maps");
+
+struct {
+	__uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+	__uint(max_entries, STAT_MAX);
+	__type(key, __u32);
+	__type(value, struct counter);
+} stats SEC(".maps");
+
+static __always_inline void count(__u32 stat, __u64 bytes)
+{
+	struct counter *c = bpf_map_lookup_elem(&stats, &stat);
+	if (c) {
+		c->packets++;
+		c->bytes += bytes;
+	}
+}
+
+static __always_inline int slow_path(__u64 bytes)
+{
+	count(STAT_SLOW_PATH, bytes);
+	return XDP_PASS;
+}
+
+SEC("xdp")
+int upf_xdp(struct xdp_md *ctx)
+{
+	void *data = (void *)(long)ctx->data;
+	void *data_end = (void *)(long)ctx->data_end;
+	__u64 bytes = data_end - data;
+
+	struct ethhdr *eth = data;
+	if ((void *)(eth + 1) > data_end || eth->h_proto != bpf_htons(ETH_P_IP))
+		return XDP_PASS;
+
+	struct iphdr *ip = (void *)(eth + 1);
+	if ((void *)(ip + 1) > data_end || ip->protocol != IPPROTO_UDP || ip->ihl != 5)
+		return XDP_PASS;
+
+	struct udphdr *udp = (void *)(ip + 1);
+	if ((void *)(udp + 1) > data_end || udp->dest != bpf_htons(GTPU_PORT))
+		return XDP_PASS;
+
+	struct gtpu_hdr *gtp = (void *)(udp + 1);
+	if ((void *)(gtp + 1) > data_end)
+		return XDP_PASS;
+	count(STAT_RECEIVED, bytes);
+	if (gtp->type != GTPU_G_PDU)
+		return slow_path(bytes);
+
+	__u32 teid = bpf_ntohl(gtp->teid);
+	if (!bpf_map_lookup_elem(&uplink_rules, &teid))
+		return slow_path(bytes);
+
+	// Skip the optional fields and the extension headers, such as the PDU
+	// Session Container (TS 29.281 5.2)
+	__u8 *next = (void *)(gtp + 1);
+	__u32 outer = sizeof(*ip) + sizeof(*udp) + sizeof(*gtp);
+	if (gtp->flags & GTPU_FLAGS_OPTIONAL) {
+		if ((void *)(next + 4) > data_end)
+			return slow_path(bytes);
+		__u8 type = gtp->flags & GTPU_FLAG_E ? next[3] : 0;
+		next += 4;
+		outer += 4;
+#pragma unroll
+		for (int i = 0; i < MAX_EXTENSION_HEADERS && type; i++) {
+			if ((void *)(next + 1) > data_end)
+				return slow_path(bytes);
+			__u32 len = next[0] * 4;
+			if (len == 0 || len > 16 || (void *)(next + len) > data_end)
+				return slow_path(bytes);
+			type = next[len - 1];
+			next += len;
+			outer += len;
+		}
+		if (type)
+			return slow_path(bytes);
+	}
+
+	// Move the Ethernet header in front of the inner packet and drop the
+	// outer IP, UDP and GTP-U headers
+	struct ethhdr copy = *eth;
+	if (bpf_xdp_adjust_head(ctx, outer))
+		return slow_path(bytes);
+
+	data = (void *)(long)ctx->data;
+	data_end = (void *)(long)ctx->data_end;
+	eth = data;
+	if ((void *)(eth + 1) > data_end)
+		return XDP_DROP;
+	*eth = copy;
+
+	count(STAT_FORWARDED, bytes);
+	return XDP_PASS;
+}
+
+char _license[] SEC("license") = "GPL";
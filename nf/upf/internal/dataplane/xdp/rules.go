@@ -0,0 +1,67 @@
+// Package xdp implements a UPF data plane that installs an eBPF program on
+// the N3 interface. Uplink G-PDUs of simple sessions are decapsulated in the
+// kernel; everything else is passed to the GTP-U handler as before.
+//
+// The data plane itself is only built with the xdp build tag on Linux, the
+// program is compiled from bpf/upf_xdp.c with clang.
+package xdp
+
+import (
+	"sort"
+
+	"github.com/your-org/5g-network/common/dataplane"
+)
+
+// sessionRules holds the rules of a session the fast path entries are
+// derived from
+type sessionRules struct {
+	pdrs map[uint16]*dataplane.PDR
+	fars map[uint16]*dataplane.FAR
+}
+
+func newSessionRules() *sessionRules {
+	return &sessionRules{
+		pdrs: make(map[uint16]*dataplane.PDR),
+		fars: make(map[uint16]*dataplane.FAR),
+	}
+}
+
+// fastPathTEIDs returns the local TEIDs whose uplink packets the program
+// may decapsulate: every PDR on the TEID removes the outer header and
+// forwards to the core, without QFI or SDF matching, QoS enforcement or
+// usage reporting, which stay on the slow path
+func (r *sessionRules) fastPathTEIDs() []uint32 {
+	eligible := make(map[uint32]bool)
+	for _, pdr := range r.pdrs {
+		if pdr.PDI == nil || pdr.PDI.LocalFTEID == nil {
+			continue
+		}
+		teid := pdr.PDI.LocalFTEID.TEID
+		ok, seen := eligible[teid]
+		eligible[teid] = (ok || !seen) && r.fastPath(pdr)
+	}
+
+	var teids []uint32
+	for teid, ok := range eligible {
+		if ok {
+			teids = append(teids, teid)
+		}
+	}
+	sort.Slice(teids, func(i, j int) bool { return teids[i] < teids[j] })
+	return teids
+}
+
+func (r *sessionRules) fastPath(pdr *dataplane.PDR) bool {
+	if pdr.PDI.SourceInterface != "ACCESS" || pdr.OuterHeaderRemoval == nil ||
+		pdr.PDI.QFI != 0 || len(pdr.PDI.SDFFilter) > 0 ||
+		len(pdr.QERID) > 0 || len(pdr.URRID) > 0 {
+		return false
+	}
+
+	far, ok := r.fars[pdr.FARID]
+	if !ok || far.ApplyAction != dataplane.ApplyActionForw {
+		return false
+	}
+	params := far.ForwardingParameters
+	return params == nil || (params.DestinationInterface == "CORE" && params.OuterHeaderCreation == nil)
+}
@@ -0,0 +1,46 @@
+package xdp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/your-org/5g-network/common/dataplane"
+)
+
+// uplinkPDR strips the outer header of packets on teid and applies farID
+func uplinkPDR(id uint16, teid uint32, farID uint16) *dataplane.PDR {
+	return &dataplane.PDR{
+		PDRID: id,
+		PDI: &dataplane.PacketDetectionInfo{
+			SourceInterface: "ACCESS",
+			LocalFTEID:      &dataplane.FTEID{TEID: teid},
+		},
+		OuterHeaderRemoval: &dataplane.OuterHeaderRemoval{},
+		FARID:              farID,
+	}
+}
+
+func TestFastPathTEIDs(t *testing.T) {
+	rules := newSessionRules()
+	rules.fars[1] = &dataplane.FAR{
+		FARID:                1,
+		ApplyAction:          dataplane.ApplyActionForw,
+		ForwardingParameters: &dataplane.ForwardingParameters{DestinationInterface: "CORE"},
+	}
+	rules.fars[2] = &dataplane.FAR{FARID: 2, ApplyAction: dataplane.ApplyActionBuff}
+
+	rules.pdrs[1] = uplinkPDR(1, 0x100, 1)
+	rules.pdrs[2] = uplinkPDR(2, 0x200, 2)
+	rules.pdrs[3] = uplinkPDR(3, 0x300, 1)
+	rules.pdrs[3].QERID = []uint16{1}
+	assert.Equal(t, []uint32{0x100}, rules.fastPathTEIDs())
+
+	// A TEID stays on the slow path while any of its PDRs needs it
+	rules.pdrs[4] = uplinkPDR(4, 0x100, 1)
+	rules.pdrs[4].PDI.QFI = 9
+	assert.Empty(t, rules.fastPathTEIDs())
+
+	delete(rules.pdrs, 4)
+	rules.fars[2].ApplyAction = dataplane.ApplyActionForw
+	assert.Equal(t, []uint32{0x100, 0x200}, rules.fastPathTEIDs())
+}
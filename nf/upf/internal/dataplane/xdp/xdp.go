@@ -0,0 +1,294 @@
+//go:build linux && xdp
+
+package xdp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"github.com/your-org/5g-network/common/dataplane"
+	"go.uber.org/zap"
+)
+
+//go:generate clang -O2 -g -Wall -target bpf -c bpf/upf_xdp.c -o upf_xdp.o
+
+// Names of the program and maps in bpf/upf_xdp.c
+const (
+	programName    = "upf_xdp"
+	uplinkRulesMap = "uplink_rules"
+	statsMap       = "stats"
+)
+
+// Indexes of the stats map, enum stat in bpf/upf_xdp.c
+const (
+	statReceived uint32 = iota
+	statForwarded
+)
+
+// uplinkRule is struct uplink_rule in bpf/upf_xdp.c
+type uplinkRule struct {
+	SEID uint64
+}
+
+// counter is struct counter in bpf/upf_xdp.c
+type counter struct {
+	Packets uint64
+	Bytes   uint64
+}
+
+// DataPlane attaches bpf/upf_xdp.c to the N3 interface and mirrors the fast
+// path eligible uplink rules into its maps
+type DataPlane struct {
+	program string
+	logger  *zap.Logger
+
+	mu          sync.Mutex
+	collection  *ebpf.Collection
+	link        link.Link
+	uplinkRules *ebpf.Map
+	stats       *ebpf.Map
+	sessions    map[uint64]*sessionRules
+	teids       map[uint64][]uint32 // Installed uplink entries per session
+	total       uint64
+}
+
+// NewDataPlane creates an XDP data plane loading the compiled program at path
+func NewDataPlane(program string, logger *zap.Logger) *DataPlane {
+	return &DataPlane{
+		program:  program,
+		logger:   logger,
+		sessions: make(map[uint64]*sessionRules),
+		teids:    make(map[uint64][]uint32),
+	}
+}
+
+// Initialize loads the program and attaches it to config.N3Interface
+func (d *DataPlane) Initialize(ctx context.Context, config *dataplane.Config) error {
+	iface, err := net.InterfaceByName(config.N3Interface)
+	if err != nil {
+		return fmt.Errorf("failed to find N3 interface %q: %w", config.N3Interface, err)
+	}
+
+	spec, err := ebpf.LoadCollectionSpec(d.program)
+	if err != nil {
+		return fmt.Errorf("failed to load eBPF program %s: %w", d.program, err)
+	}
+	collection, err := ebpf.NewCollection(spec)
+	if err != nil {
+		return fmt.Errorf("failed to load eBPF collection: %w", err)
+	}
+
+	program, uplinkRules, stats := collection.Programs[programName], collection.Maps[uplinkRulesMap], collection.Maps[statsMap]
+	if program == nil || uplinkRules == nil || stats == nil {
+		collection.Close()
+		return fmt.Errorf("eBPF program %s lacks %s, %s or %s", d.program, programName, uplinkRulesMap, statsMap)
+	}
+
+	l, err := link.AttachXDP(link.XDPOptions{Program: program, Interface: iface.Index})
+	if err != nil {
+		collection.Close()
+		return fmt.Errorf("failed to attach XDP program to %s: %w", iface.Name, err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.collection = collection
+	d.link = l
+	d.uplinkRules = uplinkRules
+	d.stats = stats
+
+	d.logger.Info("XDP data plane initialized",
+		zap.String("program", d.program),
+		zap.String("n3_interface", iface.Name))
+	return nil
+}
+
+// session returns the rules of a session, creating them if needed
+func (d *DataPlane) session(sessionID uint64) *sessionRules {
+	session, ok := d.sessions[sessionID]
+	if !ok {
+		session = newSessionRules()
+		d.sessions[sessionID] = session
+		d.total++
+	}
+	return session
+}
+
+// sync rewrites the uplink entries of a session after a rule change
+func (d *DataPlane) sync(sessionID uint64) error {
+	if d.uplinkRules == nil {
+		return errors.New("XDP data plane is not initialized")
+	}
+
+	var teids []uint32
+	if session, ok := d.sessions[sessionID]; ok {
+		teids = session.fastPathTEIDs()
+	}
+
+	for _, teid := range d.teids[sessionID] {
+		if err := d.uplinkRules.Delete(teid); err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+			return fmt.Errorf("failed to remove uplink rule for TEID 0x%x: %w", teid, err)
+		}
+	}
+	delete(d.teids, sessionID)
+
+	for _, teid := range teids {
+		if err := d.uplinkRules.Put(teid, uplinkRule{SEID: sessionID}); err != nil {
+			return fmt.Errorf("failed to install uplink rule for TEID 0x%x: %w", teid, err)
+		}
+	}
+	if len(teids) > 0 {
+		d.teids[sessionID] = teids
+	}
+
+	d.logger.Debug("XDP uplink rules synced",
+		zap.Uint64("session_id", sessionID),
+		zap.Int("fast_path_teids", len(teids)))
+	return nil
+}
+
+// InstallPDR installs a Packet Detection Rule
+func (d *DataPlane) InstallPDR(ctx context.Context, sessionID uint64, pdr *dataplane.PDR) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.session(sessionID).pdrs[pdr.PDRID] = pdr
+	return d.sync(sessionID)
+}
+
+// InstallFAR installs a Forwarding Action Rule
+func (d *DataPlane) InstallFAR(ctx context.Context, sessionID uint64, far *dataplane.FAR) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.session(sessionID).fars[far.FARID] = far
+	return d.sync(sessionID)
+}
+
+// InstallQER is a no-op: PDRs with QERs are kept on the slow path
+func (d *DataPlane) InstallQER(ctx context.Context, sessionID uint64, qer *dataplane.QER) error {
+	return nil
+}
+
+// InstallURR is a no-op: PDRs with URRs are kept on the slow path
+func (d *DataPlane) InstallURR(ctx context.Context, sessionID uint64, urr *dataplane.URR) error {
+	return nil
+}
+
+// InstallBAR is a no-op: buffering FARs are kept on the slow path
+func (d *DataPlane) InstallBAR(ctx context.Context, sessionID uint64, bar *dataplane.BAR) error {
+	return nil
+}
+
+// RemovePDR removes a Packet Detection Rule
+func (d *DataPlane) RemovePDR(ctx context.Context, sessionID uint64, pdrID uint16) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if session, ok := d.sessions[sessionID]; ok {
+		delete(session.pdrs, pdrID)
+	}
+	return d.sync(sessionID)
+}
+
+// RemoveFAR removes a Forwarding Action Rule
+func (d *DataPlane) RemoveFAR(ctx context.Context, sessionID uint64, farID uint16) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if session, ok := d.sessions[sessionID]; ok {
+		delete(session.fars, farID)
+	}
+	return d.sync(sessionID)
+}
+
+// RemoveQER is a no-op, see InstallQER
+func (d *DataPlane) RemoveQER(ctx context.Context, sessionID uint64, qerID uint16) error {
+	return nil
+}
+
+// RemoveURR is a no-op, see InstallURR
+func (d *DataPlane) RemoveURR(ctx context.Context, sessionID uint64, urrID uint32) error {
+	return nil
+}
+
+// RemoveBAR is a no-op, see InstallBAR
+func (d *DataPlane) RemoveBAR(ctx context.Context, sessionID uint64, barID uint16) error {
+	return nil
+}
+
+// RemoveSession removes all uplink entries of a session
+func (d *DataPlane) RemoveSession(ctx context.Context, sessionID uint64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.sessions, sessionID)
+	return d.sync(sessionID)
+}
+
+// ProcessPacket is not supported: the program forwards packets in the
+// kernel and passes the rest to the GTP-U handler
+func (d *DataPlane) ProcessPacket(ctx context.Context, packet *dataplane.Packet) error {
+	return errors.New("XDP data plane does not process packets in user space")
+}
+
+// GetStats sums the per-CPU counters of the program
+func (d *DataPlane) GetStats(ctx context.Context) (*dataplane.Stats, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stats == nil {
+		return nil, errors.New("XDP data plane is not initialized")
+	}
+
+	read := func(index uint32) (counter, error) {
+		var perCPU []counter
+		if err := d.stats.Lookup(index, &perCPU); err != nil {
+			return counter{}, fmt.Errorf("failed to read XDP stats: %w", err)
+		}
+		var sum counter
+		for _, c := range perCPU {
+			sum.Packets += c.Packets
+			sum.Bytes += c.Bytes
+		}
+		return sum, nil
+	}
+	received, err := read(statReceived)
+	if err != nil {
+		return nil, err
+	}
+	forwarded, err := read(statForwarded)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dataplane.Stats{
+		PacketsProcessed: received.Packets,
+		PacketsForwarded: forwarded.Packets,
+		BytesProcessed:   received.Bytes,
+		BytesForwarded:   forwarded.Bytes,
+		ActiveSessions:   uint32(len(d.sessions)),
+		TotalSessions:    d.total,
+		ActiveTunnels:    uint32(len(d.teids)),
+		Errors:           make(map[string]uint64),
+		Timestamp:        time.Now(),
+	}, nil
+}
+
+// SetReportHandler is a no-op: the reports are raised on the slow path
+func (d *DataPlane) SetReportHandler(handler dataplane.ReportHandler) {}
+
+// Shutdown detaches the program and releases its maps
+func (d *DataPlane) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.link == nil {
+		return nil
+	}
+
+	err := d.link.Close()
+	d.collection.Close()
+	d.link, d.collection, d.uplinkRules, d.stats = nil, nil, nil, nil
+	d.logger.Info("XDP data plane stopped")
+	return err
+}
@@ -0,0 +1,13 @@
+//go:build !linux || !xdp
+
+package dataplane
+
+import (
+	"github.com/your-org/5g-network/common/dataplane"
+	"github.com/your-org/5g-network/nf/upf/internal/config"
+	"go.uber.org/zap"
+)
+
+func newXDPDataPlane(cfg *config.XDPConfig, logger *zap.Logger) (dataplane.DataPlane, error) {
+	return nil, ErrXDPUnavailable
+}
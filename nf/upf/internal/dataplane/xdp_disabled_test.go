@@ -0,0 +1,18 @@
+//go:build !linux || !xdp
+
+package dataplane
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/your-org/5g-network/nf/upf/internal/config"
+	"go.uber.org/zap"
+)
+
+func TestNew_XDPNotBuiltIn(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+
+	_, err := New(&config.Config{DataPlane: config.DataPlaneConfig{Type: config.DataPlaneTypeXDP}}, logger)
+	assert.ErrorIs(t, err, ErrXDPUnavailable)
+}
@@ -0,0 +1,14 @@
+//go:build linux && xdp
+
+package dataplane
+
+import (
+	"github.com/your-org/5g-network/common/dataplane"
+	"github.com/your-org/5g-network/nf/upf/internal/config"
+	"github.com/your-org/5g-network/nf/upf/internal/dataplane/xdp"
+	"go.uber.org/zap"
+)
+
+func newXDPDataPlane(cfg *config.XDPConfig, logger *zap.Logger) (dataplane.DataPlane, error) {
+	return xdp.NewDataPlane(cfg.Program, logger), nil
+}
@@ -0,0 +1,24 @@
+//go:build linux && xdp
+
+package dataplane
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/your-org/5g-network/nf/upf/internal/config"
+	"github.com/your-org/5g-network/nf/upf/internal/dataplane/xdp"
+	"go.uber.org/zap"
+)
+
+func TestNew_XDP(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+
+	dp, err := New(&config.Config{DataPlane: config.DataPlaneConfig{
+		Type: config.DataPlaneTypeXDP,
+		XDP:  config.XDPConfig{Interface: "eth0", Program: "upf_xdp.o"},
+	}}, logger)
+	require.NoError(t, err)
+	assert.IsType(t, &xdp.DataPlane{}, dp)
+}
@@ -3,9 +3,17 @@ package gtpu
 import (
 	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"net"
-
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/your-org/5g-network/common/dataplane"
+	gtpumsg "github.com/your-org/5g-network/common/gtpu"
+	"github.com/your-org/5g-network/common/metrics"
+	"github.com/your-org/5g-network/common/netutil"
 	"github.com/your-org/5g-network/nf/upf/internal/config"
 	upfcontext "github.com/your-org/5g-network/nf/upf/internal/context"
 	"go.uber.org/zap"
@@ -24,10 +32,15 @@ const (
 type GTPUHandler struct {
 	config     *config.Config
 	n3Conn     *net.UDPConn
-	n6Conn     *net.UDPConn
+	n6         N6Interface
 	upfContext *upfcontext.UPFContext
 	logger     *zap.Logger
 	stats      *GTPUStats
+
+	// reportHandler receives error indication and QoS monitoring reports
+	// for the SMF
+	reportHandler dataplane.ReportHandler
+	qosMonitor    *dataplane.QoSMonitor
 }
 
 // GTPUStats holds GTP-U statistics
@@ -37,6 +50,16 @@ type GTPUStats struct {
 	UplinkBytes     uint64
 	DownlinkBytes   uint64
 	DroppedPackets  uint64
+
+	// Downlink packets split to fit the N3 MTU
+	FragmentedPackets uint64
+	// Downlink packets dropped with ICMP "fragmentation needed" (DF set)
+	TooBigPackets uint64
+	// Error Indications received for tunnels of known sessions
+	ErrorIndications uint64
+	// End Markers sent on replaced downlink tunnels and received on N3
+	EndMarkersSent     uint64
+	EndMarkersReceived uint64
 }
 
 // GTPUHeader represents GTP-U header (simplified)
@@ -48,8 +71,19 @@ type GTPUHeader struct {
 	SequenceNumber uint16
 	NPDU           uint8
 	NextExtHeader  uint8
+	QFI            uint8 // From the PDU Session Container, 0 without one
+
+	// QoS monitoring information of an UL PDU Session Container
+	SendTimestamp time.Time     // UL sending time stamp of the gNB
+	DownlinkDelay time.Duration // DL delay result measured by the gNB
 }
 
+// PDR source interfaces (3GPP TS 29.244 8.2.2)
+const (
+	sourceInterfaceAccess = 0
+	sourceInterfaceCore   = 1
+)
+
 // NewGTPUHandler creates a new GTP-U handler
 func NewGTPUHandler(cfg *config.Config, upfCtx *upfcontext.UPFContext, logger *zap.Logger) *GTPUHandler {
 	return &GTPUHandler{
@@ -57,27 +91,38 @@ func NewGTPUHandler(cfg *config.Config, upfCtx *upfcontext.UPFContext, logger *z
 		upfContext: upfCtx,
 		logger:     logger,
 		stats:      &GTPUStats{},
+		qosMonitor: dataplane.NewQoSMonitor(),
 	}
 }
 
-// Start starts the GTP-U handler
+// SetReportHandler makes the handler report tunnels the gNB sent an Error
+// Indication for and packet delays exceeding the QoS monitoring thresholds.
+// It must be called before Start.
+func (h *GTPUHandler) SetReportHandler(handler dataplane.ReportHandler) {
+	h.reportHandler = handler
+}
+
+// Start starts the GTP-U handler and blocks until ctx is cancelled and
+// both interfaces are closed
 func (h *GTPUHandler) Start(ctx context.Context) error {
+	var wg sync.WaitGroup
+
 	// Start N3 listener (gNB -> UPF)
-	if err := h.startN3Listener(ctx); err != nil {
+	if err := h.startN3Listener(ctx, &wg); err != nil {
 		return err
 	}
 
 	// Start N6 listener (Data Network -> UPF)
-	if err := h.startN6Listener(ctx); err != nil {
+	if err := h.startN6Listener(ctx, &wg); err != nil {
 		return err
 	}
 
-	<-ctx.Done()
+	wg.Wait()
 	return nil
 }
 
 // startN3Listener starts N3 interface listener
-func (h *GTPUHandler) startN3Listener(ctx context.Context) error {
+func (h *GTPUHandler) startN3Listener(ctx context.Context, wg *sync.WaitGroup) error {
 	addr, err := net.ResolveUDPAddr("udp", h.config.GetN3Address())
 	if err != nil {
 		return fmt.Errorf("failed to resolve N3 address: %w", err)
@@ -91,107 +136,99 @@ func (h *GTPUHandler) startN3Listener(ctx context.Context) error {
 
 	h.logger.Info("N3 (GTP-U) interface started", zap.String("address", h.config.GetN3Address()))
 
-	go h.handleN3Traffic(ctx)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := netutil.ServeUDP(ctx, conn, h.config.Forwarding.BufferSize, h.handleN3Datagram, h.logger); err != nil {
+			h.logger.Error("N3 interface stopped", zap.Error(err))
+		}
+	}()
 	return nil
 }
 
-// startN6Listener starts N6 interface listener
-func (h *GTPUHandler) startN6Listener(ctx context.Context) error {
-	// For development, use raw socket or TUN/TAP interface
-	// Simplified: Listen on a UDP port for testing
-	addr, err := net.ResolveUDPAddr("udp", "0.0.0.0:2153")
-	if err != nil {
-		return fmt.Errorf("failed to resolve N6 address: %w", err)
-	}
-
-	conn, err := net.ListenUDP("udp", addr)
+// startN6Listener starts the N6 data path of the configured type
+func (h *GTPUHandler) startN6Listener(ctx context.Context, wg *sync.WaitGroup) error {
+	n6, err := newN6Interface(h.config, h.logger)
 	if err != nil {
-		return fmt.Errorf("failed to listen on N6: %w", err)
+		h.n3Conn.Close()
+		return fmt.Errorf("failed to start N6: %w", err)
 	}
-	h.n6Conn = conn
+	h.n6 = n6
 
-	h.logger.Info("N6 (Data Network) interface started", zap.String("address", "0.0.0.0:2153"))
+	h.logger.Info("N6 (Data Network) interface started", zap.String("type", h.config.N6.Type))
 
-	go h.handleN6Traffic(ctx)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer n6.Close()
+		if err := n6.Serve(ctx, h.handleN6Datagram); err != nil {
+			h.logger.Error("N6 interface stopped", zap.Error(err))
+		}
+	}()
 	return nil
 }
 
-// handleN3Traffic processes uplink traffic from gNB
-func (h *GTPUHandler) handleN3Traffic(ctx context.Context) {
-	buffer := make([]byte, h.config.Forwarding.BufferSize)
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			n, addr, err := h.n3Conn.ReadFromUDP(buffer)
-			if err != nil {
-				h.logger.Error("Failed to read from N3", zap.Error(err))
-				continue
-			}
-
-			// Parse GTP-U header
-			if n < 8 {
-				h.logger.Warn("GTP-U packet too short", zap.Int("length", n))
-				continue
-			}
-
-			header := h.parseGTPUHeader(buffer[:n])
+// handleN3Datagram processes uplink traffic from gNB
+func (h *GTPUHandler) handleN3Datagram(data []byte, addr *net.UDPAddr) {
+	header, payload, err := h.parseGTPUHeader(data)
+	if err != nil {
+		h.logger.Warn("Discarding malformed GTP-U packet", zap.Int("length", len(data)), zap.Error(err))
+		return
+	}
 
-			// Handle based on message type
-			switch header.MessageType {
-			case GTPU_ECHO_REQUEST:
-				h.handleEchoRequest(addr)
-			case GTPU_G_PDU:
-				h.handleUplinkPacket(header, buffer[8:n], addr)
-			default:
-				h.logger.Debug("Unsupported GTP-U message type", zap.Uint8("type", header.MessageType))
-			}
-		}
+	// Handle based on message type
+	switch header.MessageType {
+	case GTPU_ECHO_REQUEST:
+		h.handleEchoRequest(addr)
+	case GTPU_G_PDU:
+		h.handleUplinkPacket(header, payload, addr)
+	case GTPU_ERROR_INDICATION:
+		h.handleErrorIndication(payload, addr)
+	case GTPU_END_MARKER:
+		h.handleEndMarker(header, addr)
+	default:
+		h.logger.Debug("Unsupported GTP-U message type", zap.Uint8("type", header.MessageType))
 	}
 }
 
-// handleN6Traffic processes downlink traffic from data network
-func (h *GTPUHandler) handleN6Traffic(ctx context.Context) {
-	buffer := make([]byte, h.config.Forwarding.BufferSize)
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			n, addr, err := h.n6Conn.ReadFromUDP(buffer)
-			if err != nil {
-				h.logger.Error("Failed to read from N6", zap.Error(err))
-				continue
-			}
+// handleN6Datagram processes downlink traffic from data network
+func (h *GTPUHandler) handleN6Datagram(data []byte, addr *net.UDPAddr) {
+	// Find session based on destination IP (UE IP)
+	h.handleDownlinkPacket(data, addr)
+}
 
-			// Find session based on destination IP (UE IP)
-			h.handleDownlinkPacket(buffer[:n], addr)
-		}
+// parseGTPUHeader parses the GTP-U header with its extension headers and
+// returns the T-PDU it carries. The QFI is taken from the PDU Session
+// Container (TS 38.415), when there is one.
+func (h *GTPUHandler) parseGTPUHeader(data []byte) (*GTPUHeader, []byte, error) {
+	packet, err := gtpumsg.Parse(data)
+	if err != nil {
+		return nil, nil, err
 	}
-}
 
-// parseGTPUHeader parses GTP-U header
-func (h *GTPUHandler) parseGTPUHeader(data []byte) *GTPUHeader {
 	header := &GTPUHeader{
-		Flags:       data[0],
-		MessageType: data[1],
-		Length:      binary.BigEndian.Uint16(data[2:4]),
-		TEID:        binary.BigEndian.Uint32(data[4:8]),
-	}
-
-	// Check for optional fields (S, PN, E flags)
-	if (data[0] & 0x07) != 0 {
-		if len(data) >= 12 {
-			header.SequenceNumber = binary.BigEndian.Uint16(data[8:10])
-			header.NPDU = data[10]
-			header.NextExtHeader = data[11]
+		Flags:          data[0],
+		MessageType:    packet.Header.MessageType,
+		Length:         packet.Header.Length,
+		TEID:           packet.Header.TEID,
+		SequenceNumber: packet.Header.SequenceNumber,
+		NPDU:           packet.Header.NPDUNumber,
+	}
+	if len(packet.Header.ExtensionHeaders) > 0 {
+		header.NextExtHeader = packet.Header.ExtensionHeaders[0].Type
+	}
+
+	if ext, ok := packet.Header.FindExtensionHeader(gtpumsg.EXT_HEADER_PDU_SESSION_CONTAINER); ok {
+		container, err := gtpumsg.ParsePDUSessionContainer(ext)
+		if err != nil {
+			return nil, nil, err
 		}
+		header.QFI = container.QFI
+		header.SendTimestamp = container.ULSendTime
+		header.DownlinkDelay = container.DLDelayResult
 	}
 
-	return header
+	return header, packet.Payload, nil
 }
 
 // handleUplinkPacket processes uplink data (N3 -> N6)
@@ -211,6 +248,15 @@ func (h *GTPUHandler) handleUplinkPacket(header *GTPUHeader, payload []byte, src
 		return
 	}
 
+	pdr, ok := matchPDR(session, sourceInterfaceAccess, header.QFI)
+	if !ok {
+		h.logger.Debug("No PDR matches QoS flow",
+			zap.Uint32("teid", header.TEID),
+			zap.Uint8("qfi", header.QFI))
+		h.stats.DroppedPackets++
+		return
+	}
+
 	// Update activity
 	h.upfContext.UpdateActivity(session.SEID)
 
@@ -218,19 +264,25 @@ func (h *GTPUHandler) handleUplinkPacket(header *GTPUHeader, payload []byte, src
 	ipPacket := payload
 
 	// Apply QoS enforcement (simplified)
-	if !h.applyQoS(session, ipPacket, true) {
+	if !h.applyQoS(session, pdr, header.QFI, ipPacket, true) {
 		h.stats.DroppedPackets++
 		return
 	}
 
 	// Forward to N6 (Data Network)
-	h.forwardToN6(ipPacket, session)
+	if !h.forwardToN6(ipPacket) {
+		h.stats.DroppedPackets++
+		return
+	}
 
 	h.stats.UplinkPackets++
 	h.stats.UplinkBytes += uint64(len(ipPacket))
+	h.recordTraffic(session, true, len(ipPacket))
+	h.monitorQoS(session, pdr, header)
 
 	h.logger.Debug("Uplink packet forwarded",
 		zap.Uint32("teid", header.TEID),
+		zap.Uint8("qfi", header.QFI),
 		zap.Int("size", len(ipPacket)),
 		zap.String("ue_ip", session.UEAddress.String()))
 }
@@ -238,65 +290,309 @@ func (h *GTPUHandler) handleUplinkPacket(header *GTPUHeader, payload []byte, src
 // handleDownlinkPacket processes downlink data (N6 -> N3)
 func (h *GTPUHandler) handleDownlinkPacket(ipPacket []byte, srcAddr *net.UDPAddr) {
 	// Extract destination IP (UE IP) from IP header
-	if len(ipPacket) < 20 {
+	dstIP, ok := destinationIP(ipPacket)
+	if !ok {
 		return
 	}
 
-	dstIP := net.IP(ipPacket[16:20])
+	session := h.sessionForUE(dstIP)
+	if session == nil {
+		h.logger.Debug("No session found for UE IP", zap.String("ip", dstIP.String()))
+		h.stats.DroppedPackets++
+		return
+	}
 
-	// Find session by UE IP
-	var session *upfcontext.UPFSession
-	for _, s := range h.upfContext.GetAllSessions() {
-		if s.UEAddress.Equal(dstIP) {
-			session = s
-			break
-		}
+	if session.ErrorIndicated {
+		h.logger.Debug("Downlink tunnel reported unknown by the gNB",
+			zap.Uint64("seid", session.SEID),
+			zap.Uint32("gnb_teid", session.GNBTEID))
+		h.stats.DroppedPackets++
+		return
 	}
 
-	if session == nil {
-		h.logger.Debug("No session found for UE IP", zap.String("ip", dstIP.String()))
+	pdr, ok := matchPDR(session, sourceInterfaceCore, 0)
+	if !ok {
+		h.logger.Debug("No downlink PDR for UE IP", zap.String("ip", dstIP.String()))
 		h.stats.DroppedPackets++
 		return
 	}
+	qfi := h.downlinkQFI(session, pdr)
 
 	// Apply QoS enforcement
-	if !h.applyQoS(session, ipPacket, false) {
+	if !h.applyQoS(session, pdr, qfi, ipPacket, false) {
 		h.stats.DroppedPackets++
 		return
 	}
 
 	// Encapsulate in GTP-U and forward to gNB
-	h.forwardToN3(ipPacket, session)
+	if !h.forwardToN3WithinMTU(ipPacket, session, qfi, srcAddr) {
+		h.stats.DroppedPackets++
+		return
+	}
 
 	h.stats.DownlinkPackets++
 	h.stats.DownlinkBytes += uint64(len(ipPacket))
+	h.recordTraffic(session, false, len(ipPacket))
 
 	h.logger.Debug("Downlink packet forwarded",
 		zap.Uint32("gnb_teid", session.GNBTEID),
+		zap.Uint8("qfi", qfi),
 		zap.Int("size", len(ipPacket)),
 		zap.String("ue_ip", session.UEAddress.String()))
 }
 
+// destinationIP returns the destination address of an IPv4 or IPv6 packet
+func destinationIP(ipPacket []byte) (net.IP, bool) {
+	if len(ipPacket) == 0 {
+		return nil, false
+	}
+	switch ipPacket[0] >> 4 {
+	case 4:
+		if len(ipPacket) < 20 {
+			return nil, false
+		}
+		return net.IP(ipPacket[16:20]), true
+	case 6:
+		if len(ipPacket) < 40 {
+			return nil, false
+		}
+		return net.IP(ipPacket[24:40]), true
+	}
+	return nil, false
+}
+
+// sessionForUE returns the session of the UE owning an IPv4 address or an
+// address in its IPv6 prefix
+func (h *GTPUHandler) sessionForUE(ip net.IP) *upfcontext.UPFSession {
+	ipv4 := ip.To4() != nil
+	for _, s := range h.upfContext.GetAllSessions() {
+		if ipv4 && s.UEAddress.Equal(ip) {
+			return s
+		}
+		if !ipv4 && s.UEIPv6Prefix != nil && s.UEIPv6Prefix.Contains(ip) {
+			return s
+		}
+	}
+	return nil
+}
+
+// recordTraffic counts a forwarded packet for its session and DNN
+func (h *GTPUHandler) recordTraffic(session *upfcontext.UPFSession, uplink bool, bytes int) {
+	direction := "downlink"
+	if uplink {
+		direction = "uplink"
+	}
+	h.upfContext.RecordTraffic(session.SEID, uplink, bytes)
+	metrics.RecordGTPUPacket(direction, bytes)
+	metrics.RecordDNNBytes(session.DNN, direction, bytes)
+}
+
+// handleErrorIndication handles a gNB reporting that it has no tunnel for
+// a TEID the UPF sends downlink to (TS 29.281 7.3.1). Downlink forwarding on
+// the tunnel stops and the SMF is told, so that it repairs or releases the
+// session.
+func (h *GTPUHandler) handleErrorIndication(payload []byte, addr *net.UDPAddr) {
+	indication, err := gtpumsg.ParseErrorIndication(payload)
+	if err != nil {
+		h.logger.Warn("Discarding malformed Error Indication",
+			zap.String("from", addr.String()),
+			zap.Error(err))
+		return
+	}
+
+	var session *upfcontext.UPFSession
+	for _, s := range h.upfContext.GetAllSessions() {
+		if s.GNBTEID == indication.TEID && s.GNBAddress.Equal(indication.PeerAddress) {
+			session = s
+			break
+		}
+	}
+
+	if session == nil {
+		h.logger.Debug("Error Indication for an unknown tunnel",
+			zap.Uint32("teid", indication.TEID),
+			zap.String("peer_address", indication.PeerAddress.String()))
+		return
+	}
+
+	// Only the first Error Indication of the tunnel is reported
+	if !h.upfContext.MarkErrorIndicated(session.SEID) {
+		return
+	}
+	h.stats.ErrorIndications++
+
+	h.logger.Warn("gNB reported downlink tunnel unknown",
+		zap.Uint64("seid", session.SEID),
+		zap.Uint32("gnb_teid", indication.TEID),
+		zap.String("gnb_address", indication.PeerAddress.String()))
+
+	if h.reportHandler == nil {
+		return
+	}
+	remote := dataplane.FTEID{TEID: indication.TEID, IPv4: indication.PeerAddress.To4()}
+	if remote.IPv4 == nil {
+		remote.IPv6 = indication.PeerAddress
+	}
+	h.reportHandler(&dataplane.SessionReport{
+		SessionID:       session.SEID,
+		ErrorIndication: &dataplane.ErrorIndicationReport{RemoteFTEID: remote},
+		Timestamp:       time.Now(),
+	})
+}
+
+// handleEndMarker handles the last packet of an uplink tunnel the gNB is
+// leaving. Uplink packets are forwarded as they arrive, so none are left
+// in flight on the tunnel; the End Marker is not forwarded to N6.
+func (h *GTPUHandler) handleEndMarker(header *GTPUHeader, addr *net.UDPAddr) {
+	var session *upfcontext.UPFSession
+	for _, s := range h.upfContext.GetAllSessions() {
+		if s.UPFTEID == header.TEID {
+			session = s
+			break
+		}
+	}
+
+	if session == nil {
+		h.logger.Debug("End Marker for an unknown tunnel", zap.Uint32("teid", header.TEID))
+		return
+	}
+	h.stats.EndMarkersReceived++
+
+	h.logger.Info("End Marker received",
+		zap.Uint64("seid", session.SEID),
+		zap.Uint32("teid", header.TEID),
+		zap.String("from", addr.String()))
+}
+
+// SendEndMarker sends an End Marker on a downlink tunnel a session has
+// switched away from, telling the gNB no more packets follow on it
+func (h *GTPUHandler) SendEndMarker(teid uint32, gnbAddress net.IP) error {
+	if h.n3Conn == nil {
+		return errors.New("N3 interface not started")
+	}
+
+	gnbAddr := &net.UDPAddr{IP: gnbAddress, Port: h.config.N3.Port}
+	if _, err := h.n3Conn.WriteToUDP(gtpumsg.NewEndMarker(teid).Marshal(), gnbAddr); err != nil {
+		return fmt.Errorf("failed to send End Marker to %s: %w", gnbAddr, err)
+	}
+	h.stats.EndMarkersSent++
+	return nil
+}
+
 // forwardToN6 forwards packet to data network
-func (h *GTPUHandler) forwardToN6(ipPacket []byte, session *upfcontext.UPFSession) {
-	// In development: forward to localhost or drop
-	// In production: use TUN/TAP or raw sockets
-	h.logger.Debug("Packet forwarded to N6", zap.Int("size", len(ipPacket)))
+func (h *GTPUHandler) forwardToN6(ipPacket []byte) bool {
+	if err := h.n6.WritePacket(ipPacket, nil); err != nil {
+		h.logger.Warn("Failed to forward packet to N6", zap.Int("size", len(ipPacket)), zap.Error(err))
+		return false
+	}
+	return true
+}
+
+// forwardToN3WithinMTU forwards a downlink packet to the gNB, fragmenting it
+// first when the encapsulated packet would exceed the N3 MTU. IPv4 packets
+// with DF set and IPv6 packets, which routers never fragment, are dropped and
+// the sender is told the usable MTU instead.
+func (h *GTPUHandler) forwardToN3WithinMTU(ipPacket []byte, session *upfcontext.UPFSession, qfi uint8, srcAddr *net.UDPAddr) bool {
+	maxSize := maxInnerSize(h.config.N3.MTU)
+	if len(ipPacket) <= maxSize {
+		h.forwardToN3(ipPacket, session, qfi)
+		return true
+	}
+
+	if ipPacket[0]>>4 == 6 {
+		h.stats.TooBigPackets++
+		h.sendPacketTooBig(ipPacket, maxSize, srcAddr)
+		h.logger.Debug("Downlink IPv6 packet exceeds N3 MTU",
+			zap.Int("size", len(ipPacket)),
+			zap.Int("mtu", h.config.N3.MTU))
+		return false
+	}
+
+	fragments, err := fragmentIPv4(ipPacket, maxSize)
+	if err != nil {
+		if errors.Is(err, errFragmentationNeeded) {
+			h.stats.TooBigPackets++
+			h.sendFragmentationNeeded(ipPacket, maxSize, srcAddr)
+		}
+		h.logger.Debug("Downlink packet exceeds N3 MTU",
+			zap.Int("size", len(ipPacket)),
+			zap.Int("mtu", h.config.N3.MTU),
+			zap.Error(err))
+		return false
+	}
+
+	h.stats.FragmentedPackets++
+	for _, fragment := range fragments {
+		h.forwardToN3(fragment, session, qfi)
+	}
+	return true
+}
+
+// sendFragmentationNeeded returns an ICMP "fragmentation needed" message to
+// the sender of an oversized packet over N6
+func (h *GTPUHandler) sendFragmentationNeeded(ipPacket []byte, mtu int, srcAddr *net.UDPAddr) {
+	if h.n6 == nil {
+		return
+	}
+
+	local := h.icmpSource(false)
+	if local == nil {
+		h.logger.Debug("No IPv4 N6 or N3 address to send ICMP fragmentation needed from")
+		return
+	}
+
+	if err := h.n6.WritePacket(buildICMPFragNeeded(ipPacket, local, mtu), srcAddr); err != nil {
+		h.logger.Error("Failed to send ICMP fragmentation needed", zap.Error(err))
+	}
 }
 
-// forwardToN3 encapsulates and forwards packet to gNB
-func (h *GTPUHandler) forwardToN3(ipPacket []byte, session *upfcontext.UPFSession) {
-	// Build GTP-U header
-	gtpuPacket := make([]byte, 8+len(ipPacket))
+// sendPacketTooBig returns an ICMPv6 Packet Too Big message to the sender of
+// an oversized IPv6 packet over N6. The reported MTU is never below the
+// IPv6 minimum link MTU, which senders ignore (RFC 8201 4).
+func (h *GTPUHandler) sendPacketTooBig(ipPacket []byte, mtu int, srcAddr *net.UDPAddr) {
+	if h.n6 == nil {
+		return
+	}
+
+	local := h.icmpSource(true)
+	if local == nil {
+		h.logger.Debug("No IPv6 N6 or N3 address to send ICMPv6 packet too big from")
+		return
+	}
+
+	if err := h.n6.WritePacket(buildICMPv6PacketTooBig(ipPacket, local, max(mtu, ipv6MinMTU)), srcAddr); err != nil {
+		h.logger.Error("Failed to send ICMPv6 packet too big", zap.Error(err))
+	}
+}
 
-	// GTP-U header
-	gtpuPacket[0] = 0x30 // Version 1, PT=1, no optional fields
-	gtpuPacket[1] = GTPU_G_PDU
-	binary.BigEndian.PutUint16(gtpuPacket[2:4], uint16(len(ipPacket)))
-	binary.BigEndian.PutUint32(gtpuPacket[4:8], session.GNBTEID)
+// icmpSource returns the address ICMP errors to DN hosts are sent from: the
+// UPF's N6 address of the family, else its N3 address if of the family. It
+// is nil when the UPF has neither.
+func (h *GTPUHandler) icmpSource(ipv6 bool) net.IP {
+	candidates := []string{h.config.N6.Gateway, h.config.N3.LocalAddress}
+	if ipv6 {
+		candidates[0] = h.config.N6.GatewayIPv6
+	}
+	for _, candidate := range candidates {
+		ip := net.ParseIP(candidate)
+		if ip == nil || ip.IsUnspecified() || (ip.To4() == nil) != ipv6 {
+			continue
+		}
+		if !ipv6 {
+			return ip.To4()
+		}
+		return ip
+	}
+	return nil
+}
 
-	// Copy IP packet
-	copy(gtpuPacket[8:], ipPacket)
+// forwardToN3 encapsulates and forwards packet to gNB. The PDU Session
+// Container tells the gNB the QoS flow of the packet (TS 38.415).
+func (h *GTPUHandler) forwardToN3(ipPacket []byte, session *upfcontext.UPFSession, qfi uint8) {
+	gtpuPacket := gtpumsg.NewGPDU(session.GNBTEID, ipPacket, gtpumsg.NewPDUSessionContainer(&gtpumsg.PDUSessionContainer{
+		PDUType: gtpumsg.PDU_TYPE_DL_PDU_SESSION_INFORMATION,
+		QFI:     qfi,
+	})).Marshal()
 
 	// Send to gNB
 	if session.GNBAddress != nil {
@@ -312,10 +608,68 @@ func (h *GTPUHandler) forwardToN3(ipPacket []byte, session *upfcontext.UPFSessio
 	}
 }
 
+// matchPDR returns the highest precedence PDR of the session for packets
+// from the source interface on the QoS flow. PDRs without a QFI match every
+// flow, as do packets without a PDU Session Container. A session without
+// PDRs matches everything, with a nil PDR.
+func matchPDR(session *upfcontext.UPFSession, sourceInterface uint8, qfi uint8) (*upfcontext.PDR, bool) {
+	if len(session.PDRs) == 0 {
+		return nil, true
+	}
+
+	var matched *upfcontext.PDR
+	for i := range session.PDRs {
+		pdr := &session.PDRs[i]
+		if pdr.PDI.SourceInterface != sourceInterface {
+			continue
+		}
+		if pdr.PDI.QFI != 0 && qfi != 0 && pdr.PDI.QFI != qfi {
+			continue
+		}
+		if matched == nil || pdr.Precedence < matched.Precedence {
+			matched = pdr
+		}
+	}
+	return matched, matched != nil
+}
+
+// selectQERs returns the QERs enforced on a packet: those referenced by its
+// PDR or, without a PDR, those of its QoS flow
+func selectQERs(session *upfcontext.UPFSession, pdr *upfcontext.PDR, qfi uint8) []*upfcontext.QER {
+	var qers []*upfcontext.QER
+	for i := range session.QERs {
+		qer := &session.QERs[i]
+		if pdr != nil {
+			if slices.Contains(pdr.QERIDs, qer.QERID) {
+				qers = append(qers, qer)
+			}
+			continue
+		}
+		if qer.QFI == 0 || qfi == 0 || qer.QFI == qfi {
+			qers = append(qers, qer)
+		}
+	}
+	return qers
+}
+
+// downlinkQFI returns the QoS flow of a downlink packet: that of its PDR,
+// else that of the first QER with one, else the configured default
+func (h *GTPUHandler) downlinkQFI(session *upfcontext.UPFSession, pdr *upfcontext.PDR) uint8 {
+	if pdr != nil && pdr.PDI.QFI != 0 {
+		return pdr.PDI.QFI
+	}
+	for _, qer := range selectQERs(session, pdr, 0) {
+		if qer.QFI != 0 {
+			return qer.QFI
+		}
+	}
+	return h.config.QoS.DefaultQFI
+}
+
 // applyQoS applies QoS enforcement
-func (h *GTPUHandler) applyQoS(session *upfcontext.UPFSession, packet []byte, uplink bool) bool {
+func (h *GTPUHandler) applyQoS(session *upfcontext.UPFSession, pdr *upfcontext.PDR, qfi uint8, packet []byte, uplink bool) bool {
 	// Simplified QoS: check against MBR
-	for _, qer := range session.QERs {
+	for _, qer := range selectQERs(session, pdr, qfi) {
 		if qer.GateStatus == 1 { // Closed
 			return false
 		}
@@ -326,6 +680,44 @@ func (h *GTPUHandler) applyQoS(session *upfcontext.UPFSession, packet []byte, up
 	return true
 }
 
+// monitorQoS measures the packet delays the gNB stamped on an uplink packet
+// against the QoS monitoring of its QERs and reports those exceeding a
+// threshold to the SMF (TS 23.501 5.33.3)
+func (h *GTPUHandler) monitorQoS(session *upfcontext.UPFSession, pdr *upfcontext.PDR, header *GTPUHeader) {
+	if h.reportHandler == nil || (header.SendTimestamp.IsZero() && header.DownlinkDelay == 0) {
+		return
+	}
+
+	packet := &dataplane.Packet{
+		Timestamp:     time.Now(),
+		Interface:     "N3",
+		TEID:          header.TEID,
+		QFI:           header.QFI,
+		SendTimestamp: header.SendTimestamp,
+		DownlinkDelay: header.DownlinkDelay,
+	}
+	var reports []dataplane.QoSMonitoringReport
+	for _, qer := range selectQERs(session, pdr, header.QFI) {
+		if qer.QoSMonitoring == nil {
+			continue
+		}
+		monitoring := dataplane.QoSMonitoring(*qer.QoSMonitoring)
+		monitored := &dataplane.QER{QERID: uint16(qer.QERID), QFI: qer.QFI, QoSMonitoring: &monitoring}
+		if r := h.qosMonitor.Measure(session.SEID, monitored, packet); r != nil {
+			reports = append(reports, *r)
+		}
+	}
+	if len(reports) == 0 {
+		return
+	}
+
+	h.reportHandler(&dataplane.SessionReport{
+		SessionID:            session.SEID,
+		QoSMonitoringReports: reports,
+		Timestamp:            packet.Timestamp,
+	})
+}
+
 // handleEchoRequest handles GTP-U echo request
 func (h *GTPUHandler) handleEchoRequest(addr *net.UDPAddr) {
 	response := make([]byte, 8)
@@ -0,0 +1,682 @@
+package gtpu
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/common/dataplane"
+	gtpumsg "github.com/your-org/5g-network/common/gtpu"
+	"github.com/your-org/5g-network/common/metrics"
+	pfcpmsg "github.com/your-org/5g-network/common/pfcp"
+	"github.com/your-org/5g-network/nf/upf/internal/config"
+	upfcontext "github.com/your-org/5g-network/nf/upf/internal/context"
+	"github.com/your-org/5g-network/nf/upf/internal/pfcp"
+)
+
+const testMTU = 1400
+
+// newTestHandler returns a handler whose N3 traffic goes to the returned gNB
+// socket
+func newTestHandler(t *testing.T) (*GTPUHandler, *net.UDPConn) {
+	t.Helper()
+
+	gnb := listenUDP(t)
+	cfg := &config.Config{}
+	cfg.N3.Port = gnb.LocalAddr().(*net.UDPAddr).Port
+	cfg.N3.MTU = testMTU
+	cfg.N3.LocalAddress = "127.0.0.1"
+	cfg.N6.Gateway = "10.60.0.1"
+	cfg.N6.GatewayIPv6 = "2001:db8:60::1"
+
+	logger, _ := zap.NewDevelopment()
+	upfCtx := upfcontext.NewUPFContext()
+	session := upfCtx.CreateSession(1)
+	session.UEAddress = net.IPv4(10, 60, 0, 1)
+	session.GNBAddress = net.IPv4(127, 0, 0, 1)
+	session.GNBTEID = 0x100
+
+	h := NewGTPUHandler(cfg, upfCtx, logger)
+	h.n3Conn = listenUDP(t)
+	h.n6 = &udpInterface{conn: listenUDP(t)}
+	return h, gnb
+}
+
+func listenUDP(t *testing.T) *net.UDPConn {
+	t.Helper()
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// buildIPv4 builds a downlink IPv4 packet to the test UE
+func buildIPv4(size int, df bool) []byte {
+	packet := make([]byte, size)
+	packet[0] = 0x45
+	binary.BigEndian.PutUint16(packet[2:4], uint16(size))
+	binary.BigEndian.PutUint16(packet[4:6], 0x1234)
+	if df {
+		binary.BigEndian.PutUint16(packet[6:8], ipv4FlagDF)
+	}
+	packet[8] = 64
+	packet[9] = 17
+	copy(packet[12:16], net.IPv4(8, 8, 8, 8).To4())
+	copy(packet[16:20], net.IPv4(10, 60, 0, 1).To4())
+	setIPv4Checksum(packet[:20])
+	for i := 20; i < size; i++ {
+		packet[i] = byte(i)
+	}
+	return packet
+}
+
+// buildIPv6 builds a downlink IPv6 packet to dst
+func buildIPv6(size int, dst net.IP) []byte {
+	packet := make([]byte, size)
+	packet[0] = 0x60
+	binary.BigEndian.PutUint16(packet[4:6], uint16(size-40))
+	packet[6] = 17
+	packet[7] = 64
+	copy(packet[8:24], net.ParseIP("2001:4860:4860::8888"))
+	copy(packet[24:40], dst.To16())
+	for i := 40; i < size; i++ {
+		packet[i] = byte(i)
+	}
+	return packet
+}
+
+func readDatagram(t *testing.T, conn *net.UDPConn) []byte {
+	t.Helper()
+	buf := make([]byte, 65535)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, _, err := conn.ReadFromUDP(buf)
+	require.NoError(t, err)
+	return buf[:n]
+}
+
+func TestOversizedDownlinkPacketIsFragmented(t *testing.T) {
+	h, gnb := newTestHandler(t)
+	packet := buildIPv4(3000, false)
+
+	h.handleDownlinkPacket(packet, nil)
+
+	var payload []byte
+	for {
+		datagram := readDatagram(t, gnb)
+		assert.LessOrEqual(t, len(datagram)+outerIPv4HeaderLen+udpHeaderLen, testMTU)
+		assert.Equal(t, uint32(0x100), binary.BigEndian.Uint32(datagram[4:8]))
+
+		fragment := datagram[gtpuHeaderLen:]
+		assert.Zero(t, internetChecksum(fragment[:20]), "fragment header checksum")
+		assert.Equal(t, uint16(0x1234), binary.BigEndian.Uint16(fragment[4:6]))
+
+		flags := binary.BigEndian.Uint16(fragment[6:8])
+		assert.Equal(t, len(payload), int(flags&ipv4FragOffMask)*8)
+		payload = append(payload, fragment[20:]...)
+		if flags&ipv4FlagMF == 0 {
+			break
+		}
+	}
+
+	assert.Equal(t, packet[20:], payload)
+	assert.Equal(t, uint64(1), h.stats.FragmentedPackets)
+	assert.Equal(t, uint64(1), h.stats.DownlinkPackets)
+	assert.Zero(t, h.stats.DroppedPackets)
+}
+
+func TestOversizedDownlinkPacketWithDFIsRejected(t *testing.T) {
+	h, _ := newTestHandler(t)
+	sender := listenUDP(t)
+	packet := buildIPv4(3000, true)
+
+	h.handleDownlinkPacket(packet, sender.LocalAddr().(*net.UDPAddr))
+
+	msg := readDatagram(t, sender)
+	require.Len(t, msg, 20+8+28)
+	assert.Equal(t, uint8(ipv4ProtocolICMP), msg[9])
+	assert.Equal(t, net.IPv4(10, 60, 0, 1).To4(), net.IP(msg[12:16]), "sent from the N6 address")
+	assert.Equal(t, net.IPv4(8, 8, 8, 8).To4(), net.IP(msg[16:20]))
+	assert.Zero(t, internetChecksum(msg[:20]))
+
+	icmp := msg[20:]
+	assert.Equal(t, uint8(icmpDestUnreach), icmp[0])
+	assert.Equal(t, uint8(icmpFragNeeded), icmp[1])
+	assert.Equal(t, uint16(testMTU-n3Overhead), binary.BigEndian.Uint16(icmp[6:8]))
+	assert.Zero(t, internetChecksum(icmp))
+	assert.Equal(t, packet[:28], icmp[8:])
+
+	assert.Equal(t, uint64(1), h.stats.TooBigPackets)
+	assert.Equal(t, uint64(1), h.stats.DroppedPackets)
+	assert.Zero(t, h.stats.DownlinkPackets)
+}
+
+func TestOversizedDownlinkIPv6PacketIsRejected(t *testing.T) {
+	h, _ := newTestHandler(t)
+	_, prefix, err := net.ParseCIDR("2001:db8:1:2::/64")
+	require.NoError(t, err)
+	session := h.upfContext.CreateSession(2)
+	session.UEIPv6Prefix = prefix
+	session.GNBAddress = net.IPv4(127, 0, 0, 1)
+	session.GNBTEID = 0x200
+	sender := listenUDP(t)
+	packet := buildIPv6(3000, net.ParseIP("2001:db8:1:2::abcd"))
+
+	h.handleDownlinkPacket(packet, sender.LocalAddr().(*net.UDPAddr))
+
+	msg := readDatagram(t, sender)
+	require.Len(t, msg, ipv6MinMTU)
+	assert.Equal(t, uint8(ipv6NextHeaderICMPv6), msg[6])
+	assert.Equal(t, net.ParseIP("2001:db8:60::1"), net.IP(msg[8:24]), "sent from the N6 address")
+	assert.Equal(t, net.ParseIP("2001:4860:4860::8888"), net.IP(msg[24:40]))
+
+	icmp := msg[ipv6HeaderLen:]
+	assert.Equal(t, uint8(icmpv6PacketTooBig), icmp[0])
+	assert.Equal(t, uint32(testMTU-n3Overhead), binary.BigEndian.Uint32(icmp[4:8]))
+	assert.Equal(t, packet[:len(icmp)-8], icmp[8:])
+
+	pseudo := append(append([]byte{}, msg[8:40]...), 0, 0, byte(len(icmp)>>8), byte(len(icmp)), 0, 0, 0, ipv6NextHeaderICMPv6)
+	assert.Zero(t, internetChecksum(append(pseudo, icmp...)))
+
+	assert.Equal(t, uint64(1), h.stats.TooBigPackets)
+	assert.Equal(t, uint64(1), h.stats.DroppedPackets)
+	assert.Zero(t, h.stats.DownlinkPackets)
+}
+
+func TestPacketTooBigReportsAtLeastIPv6MinimumMTU(t *testing.T) {
+	h, _ := newTestHandler(t)
+	h.config.N3.MTU = 1000
+	_, prefix, err := net.ParseCIDR("2001:db8:1:2::/64")
+	require.NoError(t, err)
+	session := h.upfContext.CreateSession(2)
+	session.UEIPv6Prefix = prefix
+	session.GNBAddress = net.IPv4(127, 0, 0, 1)
+	session.GNBTEID = 0x200
+	sender := listenUDP(t)
+
+	h.handleDownlinkPacket(buildIPv6(1500, net.ParseIP("2001:db8:1:2::abcd")), sender.LocalAddr().(*net.UDPAddr))
+
+	msg := readDatagram(t, sender)
+	icmp := msg[ipv6HeaderLen:]
+	assert.Equal(t, uint8(icmpv6PacketTooBig), icmp[0])
+	assert.Equal(t, uint32(ipv6MinMTU), binary.BigEndian.Uint32(icmp[4:8]))
+}
+
+func TestFragmentsAfterFirstOnlyCarryCopiedOptions(t *testing.T) {
+	options := []byte{
+		ipv4OptionNOP,
+		7, 7, 4, 0, 0, 0, 0, // Record Route, not copied
+		0x83, 7, 4, 192, 0, 2, 1, // Loose Source Route, copied
+		ipv4OptionEOL,
+	}
+	packet := buildIPv4(1000, false)
+	packet = append(append(append([]byte{}, packet[:20]...), options...), packet[20:]...)
+	packet[0] = 0x40 | byte((20+len(options))/4)
+	binary.BigEndian.PutUint16(packet[2:4], uint16(len(packet)))
+
+	fragments, err := fragmentIPv4(packet, 400)
+	require.NoError(t, err)
+	require.Greater(t, len(fragments), 1)
+
+	assert.Equal(t, packet[20:36], fragments[0][20:36], "the first fragment keeps every option")
+	var payload []byte
+	payload = append(payload, fragments[0][36:]...)
+	for _, fragment := range fragments[1:] {
+		headerLen := int(fragment[0]&0x0f) * 4
+		require.Equal(t, 28, headerLen)
+		assert.Equal(t, []byte{0x83, 7, 4, 192, 0, 2, 1, ipv4OptionEOL}, fragment[20:28])
+		assert.Zero(t, internetChecksum(fragment[:headerLen]))
+		assert.Equal(t, len(fragment), int(binary.BigEndian.Uint16(fragment[2:4])))
+		payload = append(payload, fragment[headerLen:]...)
+	}
+	assert.Equal(t, packet[36:], payload)
+
+	// Options running past the header are rejected
+	packet[21], packet[22] = 7, 40
+	_, err = fragmentIPv4(packet, 400)
+	assert.ErrorIs(t, err, errMalformedIPv4)
+}
+
+func TestDownlinkPacketWithinMTUIsNotFragmented(t *testing.T) {
+	h, gnb := newTestHandler(t)
+	packet := buildIPv4(testMTU-n3Overhead, true)
+
+	h.handleDownlinkPacket(packet, nil)
+
+	datagram := readDatagram(t, gnb)
+	assert.Equal(t, packet, datagram[gtpuHeaderLen:])
+	assert.Zero(t, h.stats.FragmentedPackets)
+}
+
+// ulPacketQFI9 is an uplink G-PDU to TEID 0x200 with a PDU Session Container
+// for QFI 9 and a 4 octet T-PDU
+var ulPacketQFI9 = []byte{
+	0x34, 0xff, 0x00, 0x0c, 0x00, 0x00, 0x02, 0x00, // E flag, G-PDU, length 12, TEID
+	0x00, 0x00, 0x00, 0x85, // Sequence, N-PDU, next: PDU Session Container
+	0x01, 0x10, 0x09, 0x00, // Length 1, UL PDU SESSION INFORMATION, QFI 9, no next
+	0x45, 0x00, 0x00, 0x04,
+}
+
+// withQoSFlows gives the test session uplink TEID 0x200 and a PDR and QER
+// per QoS flow, with the gate of QFI 9 closed
+func withQoSFlows(t *testing.T, h *GTPUHandler) *upfcontext.UPFSession {
+	t.Helper()
+
+	session, ok := h.upfContext.GetSession(1)
+	require.True(t, ok)
+	session.UPFTEID = 0x200
+	session.PDRs = []upfcontext.PDR{
+		{PDRID: 1, Precedence: 100, PDI: upfcontext.PDI{SourceInterface: sourceInterfaceAccess, QFI: 5}, QERIDs: []uint32{1}},
+		{PDRID: 2, Precedence: 100, PDI: upfcontext.PDI{SourceInterface: sourceInterfaceAccess, QFI: 9}, QERIDs: []uint32{2}},
+		{PDRID: 3, Precedence: 200, PDI: upfcontext.PDI{SourceInterface: sourceInterfaceCore}, QERIDs: []uint32{1}},
+	}
+	session.QERs = []upfcontext.QER{
+		{QERID: 1, QFI: 5},
+		{QERID: 2, QFI: 9, GateStatus: 1},
+	}
+	return session
+}
+
+func TestParseGTPUHeader_PDUSessionContainer(t *testing.T) {
+	h, _ := newTestHandler(t)
+
+	header, payload, err := h.parseGTPUHeader(ulPacketQFI9)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(GTPU_G_PDU), header.MessageType)
+	assert.Equal(t, uint32(0x200), header.TEID)
+	assert.Equal(t, uint8(gtpumsg.EXT_HEADER_PDU_SESSION_CONTAINER), header.NextExtHeader)
+	assert.Equal(t, uint8(9), header.QFI)
+	assert.Equal(t, []byte{0x45, 0x00, 0x00, 0x04}, payload)
+
+	// Without extension headers the T-PDU follows the mandatory header
+	header, payload, err = h.parseGTPUHeader([]byte{0x30, 0xff, 0x00, 0x01, 0x00, 0x00, 0x02, 0x00, 0x45})
+	require.NoError(t, err)
+	assert.Zero(t, header.QFI)
+	assert.Equal(t, []byte{0x45}, payload)
+
+	// An extension header running past the packet
+	truncated := append([]byte(nil), ulPacketQFI9[:16]...)
+	truncated[3] = 0x08
+	truncated[12] = 0x02
+	_, _, err = h.parseGTPUHeader(truncated)
+	assert.Error(t, err)
+}
+
+func TestUplinkPacketMatchesPDRByQFI(t *testing.T) {
+	h, _ := newTestHandler(t)
+	withQoSFlows(t, h)
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 2152}
+
+	// QFI 9 matches PDR 2, whose QER gate is closed
+	h.handleN3Datagram(ulPacketQFI9, addr)
+	assert.Zero(t, h.stats.UplinkPackets)
+	assert.Equal(t, uint64(1), h.stats.DroppedPackets)
+
+	// QFI 5 matches PDR 1 and passes its QER
+	qfi5 := append([]byte(nil), ulPacketQFI9...)
+	qfi5[14] = 5
+	h.handleN3Datagram(qfi5, addr)
+	assert.Equal(t, uint64(1), h.stats.UplinkPackets)
+	assert.Equal(t, uint64(4), h.stats.UplinkBytes)
+
+	// No PDR detects QFI 7
+	qfi7 := append([]byte(nil), ulPacketQFI9...)
+	qfi7[14] = 7
+	h.handleN3Datagram(qfi7, addr)
+	assert.Equal(t, uint64(1), h.stats.UplinkPackets)
+	assert.Equal(t, uint64(2), h.stats.DroppedPackets)
+}
+
+func TestDownlinkPacketCarriesQFI(t *testing.T) {
+	h, gnb := newTestHandler(t)
+	withQoSFlows(t, h)
+	packet := buildIPv4(100, false)
+
+	h.handleDownlinkPacket(packet, nil)
+
+	gpdu, err := gtpumsg.Parse(readDatagram(t, gnb))
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0x100), gpdu.Header.TEID)
+	assert.Equal(t, packet, gpdu.Payload)
+
+	ext, ok := gpdu.Header.FindExtensionHeader(gtpumsg.EXT_HEADER_PDU_SESSION_CONTAINER)
+	require.True(t, ok)
+	container, err := gtpumsg.ParsePDUSessionContainer(ext)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(gtpumsg.PDU_TYPE_DL_PDU_SESSION_INFORMATION), container.PDUType)
+	// The core PDR has no QFI, so the flow of its QER is used
+	assert.Equal(t, uint8(5), container.QFI)
+}
+
+func TestForwardedTrafficIsCounted(t *testing.T) {
+	h, gnb := newTestHandler(t)
+	session := withQoSFlows(t, h)
+	session.DNN = "traffic.test"
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 2152}
+
+	qfi5 := append([]byte(nil), ulPacketQFI9...)
+	qfi5[14] = 5
+	h.handleN3Datagram(qfi5, addr)
+	h.handleN3Datagram(qfi5, addr)
+	h.handleN3Datagram(ulPacketQFI9, addr) // Gate closed, not counted
+	h.handleDownlinkPacket(buildIPv4(100, false), nil)
+	readDatagram(t, gnb)
+
+	traffic, ok := h.upfContext.GetTraffic(1)
+	require.True(t, ok)
+	assert.Equal(t, upfcontext.SessionTraffic{
+		UplinkPackets:   2,
+		UplinkBytes:     8,
+		DownlinkPackets: 1,
+		DownlinkBytes:   100,
+	}, traffic)
+
+	assert.Equal(t, float64(8), testutil.ToFloat64(metrics.DNNBytes.WithLabelValues("traffic.test", "uplink")))
+	assert.Equal(t, float64(100), testutil.ToFloat64(metrics.DNNBytes.WithLabelValues("traffic.test", "downlink")))
+}
+
+func TestErrorIndicationFlagsSession(t *testing.T) {
+	h, gnb := newTestHandler(t)
+	var reports []*dataplane.SessionReport
+	h.SetReportHandler(func(report *dataplane.SessionReport) {
+		reports = append(reports, report)
+	})
+	addr := gnb.LocalAddr().(*net.UDPAddr)
+
+	// The gNB does not know the downlink TEID of session 1
+	indication := gtpumsg.NewErrorIndication(&gtpumsg.ErrorIndication{
+		TEID:        0x100,
+		PeerAddress: net.IPv4(127, 0, 0, 1),
+	}, 1).Marshal()
+	h.handleN3Datagram(indication, addr)
+
+	session, ok := h.upfContext.GetSession(1)
+	require.True(t, ok)
+	assert.True(t, session.ErrorIndicated)
+	assert.Equal(t, uint64(1), h.stats.ErrorIndications)
+
+	require.Len(t, reports, 1)
+	assert.Equal(t, uint64(1), reports[0].SessionID)
+	require.NotNil(t, reports[0].ErrorIndication)
+	assert.Equal(t, uint32(0x100), reports[0].ErrorIndication.RemoteFTEID.TEID)
+	assert.Equal(t, net.IPv4(127, 0, 0, 1).To4(), reports[0].ErrorIndication.RemoteFTEID.IPv4)
+
+	// Downlink on the tunnel stops
+	h.handleDownlinkPacket(buildIPv4(100, false), nil)
+	assert.Zero(t, h.stats.DownlinkPackets)
+	assert.Equal(t, uint64(1), h.stats.DroppedPackets)
+
+	// Repeated indications are reported once
+	h.handleN3Datagram(indication, addr)
+	assert.Len(t, reports, 1)
+
+	// Indications for tunnels of no session are ignored
+	other := gtpumsg.NewErrorIndication(&gtpumsg.ErrorIndication{
+		TEID:        0x999,
+		PeerAddress: net.IPv4(127, 0, 0, 1),
+	}, 2).Marshal()
+	h.handleN3Datagram(other, addr)
+	assert.Len(t, reports, 1)
+	assert.Equal(t, uint64(1), h.stats.ErrorIndications)
+}
+
+func TestSendEndMarker(t *testing.T) {
+	h, gnb := newTestHandler(t)
+
+	require.NoError(t, h.SendEndMarker(0x100, net.IPv4(127, 0, 0, 1)))
+
+	packet, err := gtpumsg.Parse(readDatagram(t, gnb))
+	require.NoError(t, err)
+	assert.Equal(t, uint8(GTPU_END_MARKER), packet.Header.MessageType)
+	assert.Equal(t, uint32(0x100), packet.Header.TEID)
+	assert.Empty(t, packet.Payload)
+	assert.Equal(t, uint64(1), h.stats.EndMarkersSent)
+}
+
+func TestReceivedEndMarkerIsNotForwarded(t *testing.T) {
+	h, _ := newTestHandler(t)
+	withQoSFlows(t, h)
+	addr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 2152}
+
+	h.handleN3Datagram(gtpumsg.NewEndMarker(0x200).Marshal(), addr)
+	assert.Equal(t, uint64(1), h.stats.EndMarkersReceived)
+	assert.Zero(t, h.stats.UplinkPackets)
+
+	// Unknown tunnels are ignored
+	h.handleN3Datagram(gtpumsg.NewEndMarker(0x999).Marshal(), addr)
+	assert.Equal(t, uint64(1), h.stats.EndMarkersReceived)
+}
+
+func TestDownlinkIPv6PacketMatchesUEPrefix(t *testing.T) {
+	h, gnb := newTestHandler(t)
+	_, prefix, err := net.ParseCIDR("2001:db8:1:2::/64")
+	require.NoError(t, err)
+	session := h.upfContext.CreateSession(2)
+	session.UEIPv6Prefix = prefix
+	session.GNBAddress = net.IPv4(127, 0, 0, 1)
+	session.GNBTEID = 0x200
+
+	// Any address of the UE prefix reaches the UE's tunnel
+	packet := buildIPv6(100, net.ParseIP("2001:db8:1:2::abcd"))
+	h.handleDownlinkPacket(packet, nil)
+
+	gpdu, err := gtpumsg.Parse(readDatagram(t, gnb))
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0x200), gpdu.Header.TEID)
+	assert.Equal(t, packet, gpdu.Payload)
+	assert.Equal(t, uint64(1), h.stats.DownlinkPackets)
+
+	// IPv4 sessions still match by address
+	h.handleDownlinkPacket(buildIPv4(100, false), nil)
+	gpdu, err = gtpumsg.Parse(readDatagram(t, gnb))
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0x100), gpdu.Header.TEID)
+
+	// Outside every UE prefix, or truncated
+	h.handleDownlinkPacket(buildIPv6(100, net.ParseIP("2001:db8:1:3::1")), nil)
+	h.handleDownlinkPacket(packet[:39], nil)
+	assert.Equal(t, uint64(2), h.stats.DownlinkPackets)
+	assert.Equal(t, uint64(1), h.stats.DroppedPackets)
+}
+
+// pfcpExchange sends a PFCP request to the UPF and returns its response
+func pfcpExchange(t *testing.T, conn *net.UDPConn, msg *pfcpmsg.Message) *pfcpmsg.Message {
+	t.Helper()
+
+	_, err := conn.Write(msg.Marshal())
+	require.NoError(t, err)
+
+	buf := make([]byte, 4096)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	resp, err := pfcpmsg.Parse(buf[:n])
+	require.NoError(t, err)
+
+	cause, err := resp.FindIE(pfcpmsg.IE_CAUSE).Uint8()
+	require.NoError(t, err)
+	require.Equal(t, uint8(pfcpmsg.CAUSE_REQUEST_ACCEPTED), cause)
+	return resp
+}
+
+// startPFCPServer runs a PFCP server on the handler's sessions and returns
+// it with the socket of an SMF associated with it
+func startPFCPServer(t *testing.T, h *GTPUHandler) (*pfcp.PFCPServer, *net.UDPConn) {
+	t.Helper()
+
+	logger, _ := zap.NewDevelopment()
+	server := pfcp.NewPFCPServer(&config.Config{
+		PFCP: config.PFCPConfig{BindAddress: "127.0.0.1", NodeID: "upf.test"},
+		N3:   config.N3Config{LocalAddress: "127.0.0.1"},
+	}, h.upfContext, logger)
+	require.NoError(t, server.Listen())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.Start(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	conn, err := net.DialUDP("udp", nil, server.LocalAddr())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	pfcpExchange(t, conn, pfcpmsg.NewMessage(pfcpmsg.PFCP_ASSOCIATION_SETUP_REQUEST, 1,
+		pfcpmsg.NewNodeIDIE("127.0.0.1"),
+		pfcpmsg.NewRecoveryTimeStampIE(time.Now()),
+	))
+	return server, conn
+}
+
+func TestDownlinkFollowsUpdatedFAR(t *testing.T) {
+	h, oldGNB := newTestHandler(t)
+	h.upfContext = upfcontext.NewUPFContext()
+
+	// The target gNB listens on the same GTP-U port at another address
+	newGNB, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 2), Port: h.config.N3.Port})
+	require.NoError(t, err)
+	t.Cleanup(func() { newGNB.Close() })
+
+	_, conn := startPFCPServer(t, h)
+	resp := pfcpExchange(t, conn, pfcpmsg.NewSessionMessage(pfcpmsg.PFCP_SESSION_ESTABLISHMENT_REQUEST, 0, 2,
+		pfcpmsg.NewNodeIDIE("127.0.0.1"),
+		pfcpmsg.NewFSEIDIE(0x5eed, net.ParseIP("127.0.0.1"), nil),
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_CREATE_PDR,
+			pfcpmsg.NewUint16IE(pfcpmsg.IE_PDR_ID, 2),
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_PRECEDENCE, 100),
+			pfcpmsg.NewGroupedIE(pfcpmsg.IE_PDI,
+				pfcpmsg.NewUint8IE(pfcpmsg.IE_SOURCE_INTERFACE, pfcpmsg.INTERFACE_CORE),
+				pfcpmsg.NewUEIPAddressIE(&pfcpmsg.UEIPAddress{IPv4: net.IPv4(10, 60, 0, 1), IsDestination: true}),
+			),
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_FAR_ID, 2),
+		),
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_CREATE_FAR,
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_FAR_ID, 2),
+			pfcpmsg.NewUint8IE(pfcpmsg.IE_APPLY_ACTION, pfcpmsg.APPLY_ACTION_FORW),
+			pfcpmsg.NewGroupedIE(pfcpmsg.IE_FORWARDING_PARAMETERS,
+				pfcpmsg.NewUint8IE(pfcpmsg.IE_DESTINATION_INTERFACE, pfcpmsg.INTERFACE_ACCESS),
+				pfcpmsg.NewOuterHeaderCreationIE(&pfcpmsg.OuterHeaderCreation{
+					Description: pfcpmsg.OUTER_HEADER_CREATION_GTPU_UDP_IPV4,
+					TEID:        0x100,
+					IPv4:        net.IPv4(127, 0, 0, 1),
+				}),
+			),
+		),
+	))
+	fseid, err := resp.FindIE(pfcpmsg.IE_F_SEID).FSEID()
+	require.NoError(t, err)
+
+	packet := buildIPv4(100, false)
+	h.handleDownlinkPacket(packet, nil)
+	gpdu, err := gtpumsg.Parse(readDatagram(t, oldGNB))
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0x100), gpdu.Header.TEID)
+
+	// Update FAR moves the downlink tunnel to the target gNB
+	pfcpExchange(t, conn, pfcpmsg.NewSessionMessage(pfcpmsg.PFCP_SESSION_MODIFICATION_REQUEST, fseid.SEID, 3,
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_UPDATE_FAR,
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_FAR_ID, 2),
+			pfcpmsg.NewGroupedIE(pfcpmsg.IE_UPDATE_FORWARDING_PARAMS,
+				pfcpmsg.NewOuterHeaderCreationIE(&pfcpmsg.OuterHeaderCreation{
+					Description: pfcpmsg.OUTER_HEADER_CREATION_GTPU_UDP_IPV4,
+					TEID:        0x200,
+					IPv4:        net.IPv4(127, 0, 0, 2),
+				}),
+			),
+		),
+	))
+
+	// The encapsulated packet is addressed to the target gNB, which alone
+	// listens on 127.0.0.2, with its TEID
+	h.handleDownlinkPacket(packet, nil)
+	buf := make([]byte, 65535)
+	require.NoError(t, newGNB.SetReadDeadline(time.Now().Add(time.Second)))
+	n, from, err := newGNB.ReadFromUDP(buf)
+	require.NoError(t, err)
+	gpdu, err = gtpumsg.Parse(buf[:n])
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0x200), gpdu.Header.TEID)
+	assert.Equal(t, packet, gpdu.Payload)
+	assert.Equal(t, h.n3Conn.LocalAddr().(*net.UDPAddr).Port, from.Port)
+
+	// Nothing more reaches the source gNB
+	require.NoError(t, oldGNB.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+	_, _, err = oldGNB.ReadFromUDP(buf)
+	assert.Error(t, err)
+}
+
+func TestQoSMonitoringReportReachesSMF(t *testing.T) {
+	h, _ := newTestHandler(t)
+	h.upfContext = upfcontext.NewUPFContext()
+	server, conn := startPFCPServer(t, h)
+	h.SetReportHandler(server.HandleSessionReport)
+
+	// The uplink of QoS flow 5 is monitored with a 10 ms threshold
+	pfcpExchange(t, conn, pfcpmsg.NewSessionMessage(pfcpmsg.PFCP_SESSION_ESTABLISHMENT_REQUEST, 0, 2,
+		pfcpmsg.NewNodeIDIE("127.0.0.1"),
+		pfcpmsg.NewFSEIDIE(0x5eed, net.ParseIP("127.0.0.1"), nil),
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_CREATE_PDR,
+			pfcpmsg.NewUint16IE(pfcpmsg.IE_PDR_ID, 1),
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_PRECEDENCE, 100),
+			pfcpmsg.NewGroupedIE(pfcpmsg.IE_PDI,
+				pfcpmsg.NewUint8IE(pfcpmsg.IE_SOURCE_INTERFACE, pfcpmsg.INTERFACE_ACCESS),
+				pfcpmsg.NewFTEIDIE(&pfcpmsg.FTEID{TEID: 0x300, IPv4: net.IPv4(127, 0, 0, 1)}),
+				pfcpmsg.NewUint8IE(pfcpmsg.IE_QFI, 5),
+			),
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_FAR_ID, 1),
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_QER_ID, 1),
+		),
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_CREATE_FAR,
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_FAR_ID, 1),
+			pfcpmsg.NewUint8IE(pfcpmsg.IE_APPLY_ACTION, pfcpmsg.APPLY_ACTION_FORW),
+		),
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_CREATE_QER,
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_QER_ID, 1),
+			pfcpmsg.NewUint8IE(pfcpmsg.IE_QFI, 5),
+			pfcpmsg.NewQoSMonitoringIE(&pfcpmsg.QoSMonitoring{
+				Requested:       pfcpmsg.QOS_MONITORING_UL,
+				UplinkThreshold: 10 * time.Millisecond,
+			}),
+		),
+	))
+
+	// The gNB stamped the packet 50 ms ago
+	sent := time.Now().Add(-50 * time.Millisecond)
+	uplink := gtpumsg.NewGPDU(0x300, buildIPv4(100, false), gtpumsg.NewPDUSessionContainer(&gtpumsg.PDUSessionContainer{
+		PDUType:    gtpumsg.PDU_TYPE_UL_PDU_SESSION_INFORMATION,
+		QFI:        5,
+		QMP:        true,
+		ULSendTime: sent,
+	}))
+	h.handleN3Datagram(uplink.Marshal(), &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 2152})
+	require.Equal(t, uint64(1), h.stats.UplinkPackets)
+
+	buf := make([]byte, 65535)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	report, err := pfcpmsg.Parse(buf[:n])
+	require.NoError(t, err)
+	assert.Equal(t, uint8(pfcpmsg.PFCP_SESSION_REPORT_REQUEST), report.Header.MessageType)
+	assert.Equal(t, uint64(0x5eed), report.Header.SEID)
+
+	reportType, err := report.FindIE(pfcpmsg.IE_REPORT_TYPE).Uint8()
+	require.NoError(t, err)
+	assert.Equal(t, uint8(pfcpmsg.REPORT_TYPE_SESR), reportType)
+	ie := report.FindIE(pfcpmsg.IE_QOS_MONITORING_REPORT)
+	require.NotNil(t, ie)
+	qmr, err := ie.QoSMonitoringReport()
+	require.NoError(t, err)
+	assert.Equal(t, uint8(5), qmr.QFI)
+	assert.GreaterOrEqual(t, qmr.UplinkDelay, 50*time.Millisecond)
+	assert.Zero(t, qmr.DownlinkDelay)
+}
@@ -0,0 +1,254 @@
+package gtpu
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+)
+
+// N3 encapsulation overhead: outer IPv4 header, UDP header and the GTP-U
+// header with its optional fields and the downlink PDU Session Container
+const (
+	outerIPv4HeaderLen = 20
+	udpHeaderLen       = 8
+	gtpuHeaderLen      = 16
+	n3Overhead         = outerIPv4HeaderLen + udpHeaderLen + gtpuHeaderLen
+)
+
+// IPv4 header fields used for fragmentation (RFC 791)
+const (
+	ipv4FlagDF        = 0x4000
+	ipv4FlagMF        = 0x2000
+	ipv4FragOffMask   = 0x1fff
+	ipv4ProtocolICMP  = 1
+	icmpDestUnreach   = 3
+	icmpFragNeeded    = 4
+	icmpDefaultTTL    = 64
+	icmpQuotedDataLen = 8
+)
+
+// IPv4 options (RFC 791): the copy flag marks options that every fragment
+// carries
+const (
+	ipv4OptionEOL      = 0
+	ipv4OptionNOP      = 1
+	ipv4OptionCopyFlag = 0x80
+)
+
+// ICMPv6 Packet Too Big (RFC 4443 3.2)
+const (
+	ipv6HeaderLen        = 40
+	ipv6NextHeaderICMPv6 = 58
+	ipv6MinMTU           = 1280
+	icmpv6PacketTooBig   = 2
+)
+
+var (
+	// errFragmentationNeeded is returned when an inner packet exceeds the
+	// N3 MTU and has the DF bit set
+	errFragmentationNeeded = errors.New("packet exceeds N3 MTU and DF is set")
+
+	// errMalformedIPv4 is returned for inner packets that cannot be fragmented
+	errMalformedIPv4 = errors.New("malformed IPv4 packet")
+)
+
+// maxInnerSize returns the largest inner packet that fits the N3 MTU once
+// encapsulated in GTP-U
+func maxInnerSize(mtu int) int {
+	return mtu - n3Overhead
+}
+
+// fragmentIPv4 splits an IPv4 packet into fragments of at most maxSize bytes.
+// Fragmenting the inner packet before encapsulation keeps the outer GTP-U
+// packets within the N3 MTU, so the gNB never has to reassemble outer IP
+// fragments (TS 29.281 section 4.4.3).
+func fragmentIPv4(packet []byte, maxSize int) ([][]byte, error) {
+	if len(packet) < 20 || packet[0]>>4 != 4 {
+		return nil, errMalformedIPv4
+	}
+	headerLen := int(packet[0]&0x0f) * 4
+	totalLen := int(binary.BigEndian.Uint16(packet[2:4]))
+	if headerLen < 20 || totalLen < headerLen || totalLen > len(packet) {
+		return nil, errMalformedIPv4
+	}
+	packet = packet[:totalLen]
+
+	if totalLen <= maxSize {
+		return [][]byte{packet}, nil
+	}
+
+	flags := binary.BigEndian.Uint16(packet[6:8])
+	if flags&ipv4FlagDF != 0 {
+		return nil, errFragmentationNeeded
+	}
+
+	// Fragment payloads must be multiples of 8 bytes except the last one
+	chunk := (maxSize - headerLen) &^ 7
+	if chunk <= 0 {
+		return nil, errFragmentationNeeded
+	}
+
+	// Fragments after the first only carry the options with the copy flag
+	// set, which keeps them within the size of the first one's
+	laterHeader, err := copiedIPv4Header(packet[:headerLen])
+	if err != nil {
+		return nil, err
+	}
+
+	// The packet may itself be a fragment; keep its offset and MF bit
+	baseOffset := int(flags&ipv4FragOffMask) * 8
+	moreFragments := flags&ipv4FlagMF != 0
+	payload := packet[headerLen:]
+
+	var fragments [][]byte
+	for off := 0; off < len(payload); off += chunk {
+		end := off + chunk
+		last := end >= len(payload)
+		if last {
+			end = len(payload)
+		}
+
+		header := packet[:headerLen]
+		if baseOffset+off > 0 {
+			header = laterHeader
+		}
+		fragHeaderLen := len(header)
+
+		frag := make([]byte, fragHeaderLen+end-off)
+		copy(frag, header)
+		copy(frag[fragHeaderLen:], payload[off:end])
+
+		fragFlags := uint16((baseOffset + off) / 8)
+		if !last || moreFragments {
+			fragFlags |= ipv4FlagMF
+		}
+		binary.BigEndian.PutUint16(frag[2:4], uint16(len(frag)))
+		binary.BigEndian.PutUint16(frag[6:8], fragFlags)
+		setIPv4Checksum(frag[:fragHeaderLen])
+
+		fragments = append(fragments, frag)
+	}
+	return fragments, nil
+}
+
+// copiedIPv4Header returns the header of a non-first fragment: the fixed
+// header followed by the options whose copy flag is set, padded with
+// End of Option List to a multiple of 4 bytes (RFC 791)
+func copiedIPv4Header(header []byte) ([]byte, error) {
+	copied := make([]byte, 20, len(header))
+	copy(copied, header[:20])
+
+	options := header[20:]
+	for i := 0; i < len(options); {
+		optionType := options[i]
+		if optionType == ipv4OptionEOL {
+			break
+		}
+		if optionType == ipv4OptionNOP {
+			i++
+			continue
+		}
+		if i+1 >= len(options) {
+			return nil, errMalformedIPv4
+		}
+		optionLen := int(options[i+1])
+		if optionLen < 2 || i+optionLen > len(options) {
+			return nil, errMalformedIPv4
+		}
+		if optionType&ipv4OptionCopyFlag != 0 {
+			copied = append(copied, options[i:i+optionLen]...)
+		}
+		i += optionLen
+	}
+
+	for len(copied)%4 != 0 {
+		copied = append(copied, ipv4OptionEOL)
+	}
+	copied[0] = 0x40 | byte(len(copied)/4)
+	return copied, nil
+}
+
+// buildICMPFragNeeded builds an ICMP "fragmentation needed" message (type 3,
+// code 4) from src back to the sender of packet, advertising the next-hop MTU
+// the sender should use (RFC 1191)
+func buildICMPFragNeeded(packet []byte, src net.IP, nextHopMTU int) []byte {
+	headerLen := int(packet[0]&0x0f) * 4
+	quoted := headerLen + icmpQuotedDataLen
+	if quoted > len(packet) {
+		quoted = len(packet)
+	}
+
+	icmp := make([]byte, 8+quoted)
+	icmp[0] = icmpDestUnreach
+	icmp[1] = icmpFragNeeded
+	binary.BigEndian.PutUint16(icmp[6:8], uint16(nextHopMTU))
+	copy(icmp[8:], packet[:quoted])
+	binary.BigEndian.PutUint16(icmp[2:4], internetChecksum(icmp))
+
+	msg := make([]byte, 20+len(icmp))
+	msg[0] = 0x45
+	binary.BigEndian.PutUint16(msg[2:4], uint16(len(msg)))
+	msg[8] = icmpDefaultTTL
+	msg[9] = ipv4ProtocolICMP
+	copy(msg[12:16], src.To4())
+	copy(msg[16:20], packet[12:16]) // original source
+	setIPv4Checksum(msg[:20])
+	copy(msg[20:], icmp)
+	return msg
+}
+
+// setIPv4Checksum recomputes the IPv4 header checksum in place
+func setIPv4Checksum(header []byte) {
+	header[10], header[11] = 0, 0
+	binary.BigEndian.PutUint16(header[10:12], internetChecksum(header))
+}
+
+// internetChecksum computes the RFC 1071 ones' complement checksum
+func internetChecksum(data []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(data); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(data[i : i+2]))
+	}
+	if len(data)%2 == 1 {
+		sum += uint32(data[len(data)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = (sum & 0xffff) + (sum >> 16)
+	}
+	return ^uint16(sum)
+}
+
+// buildICMPv6PacketTooBig builds an ICMPv6 Packet Too Big message from src
+// back to the sender of packet, advertising the MTU the sender should use.
+// It quotes as much of the packet as fits the IPv6 minimum MTU (RFC 4443
+// 3.2).
+func buildICMPv6PacketTooBig(packet []byte, src net.IP, mtu int) []byte {
+	quoted := ipv6MinMTU - ipv6HeaderLen - 8
+	if quoted > len(packet) {
+		quoted = len(packet)
+	}
+
+	icmp := make([]byte, 8+quoted)
+	icmp[0] = icmpv6PacketTooBig
+	binary.BigEndian.PutUint32(icmp[4:8], uint32(mtu))
+	copy(icmp[8:], packet[:quoted])
+
+	msg := make([]byte, ipv6HeaderLen+len(icmp))
+	msg[0] = 0x60
+	binary.BigEndian.PutUint16(msg[4:6], uint16(len(icmp)))
+	msg[6] = ipv6NextHeaderICMPv6
+	msg[7] = icmpDefaultTTL
+	copy(msg[8:24], src.To16())
+	copy(msg[24:40], packet[8:24]) // original source
+
+	// The checksum covers the IPv6 pseudo-header (RFC 8200 8.1)
+	pseudo := make([]byte, 40+len(icmp))
+	copy(pseudo[0:32], msg[8:40])
+	binary.BigEndian.PutUint32(pseudo[32:36], uint32(len(icmp)))
+	pseudo[39] = ipv6NextHeaderICMPv6
+	copy(pseudo[40:], icmp)
+	binary.BigEndian.PutUint16(icmp[2:4], internetChecksum(pseudo))
+
+	copy(msg[ipv6HeaderLen:], icmp)
+	return msg
+}
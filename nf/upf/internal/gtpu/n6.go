@@ -0,0 +1,80 @@
+package gtpu
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/your-org/5g-network/common/netutil"
+	"github.com/your-org/5g-network/nf/upf/internal/config"
+	"go.uber.org/zap"
+)
+
+// n6SimulationAddress is where the UDP simulation of the data network
+// listens for downlink packets
+const n6SimulationAddress = "0.0.0.0:2153"
+
+// N6Interface carries decapsulated IP packets between the UPF and the data
+// network
+type N6Interface interface {
+	// Serve passes each downlink packet to handle until ctx is cancelled.
+	// from is the sender in the UDP simulation, nil otherwise.
+	Serve(ctx context.Context, handle func(packet []byte, from *net.UDPAddr)) error
+
+	// WritePacket sends an IP packet towards the data network. to is the
+	// peer in the UDP simulation and ignored otherwise.
+	WritePacket(packet []byte, to *net.UDPAddr) error
+
+	Close() error
+}
+
+// newN6Interface opens the N6 data path of the configured type
+func newN6Interface(cfg *config.Config, logger *zap.Logger) (N6Interface, error) {
+	switch cfg.N6.Type {
+	case config.N6TypeTUN:
+		return newTUNInterface(&cfg.N6, cfg.Forwarding.BufferSize, logger)
+	default:
+		udp, err := newUDPInterface(n6SimulationAddress, cfg.Forwarding.BufferSize, logger)
+		if err != nil {
+			return nil, err
+		}
+		return udp, nil
+	}
+}
+
+// udpInterface simulates the data network with a UDP socket: downlink IP
+// packets arrive as datagrams and uplink packets are dropped
+type udpInterface struct {
+	conn       *net.UDPConn
+	bufferSize int
+	logger     *zap.Logger
+}
+
+func newUDPInterface(address string, bufferSize int, logger *zap.Logger) (*udpInterface, error) {
+	addr, err := net.ResolveUDPAddr("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve N6 address: %w", err)
+	}
+
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on N6: %w", err)
+	}
+	return &udpInterface{conn: conn, bufferSize: bufferSize, logger: logger}, nil
+}
+
+func (u *udpInterface) Serve(ctx context.Context, handle func(packet []byte, from *net.UDPAddr)) error {
+	return netutil.ServeUDP(ctx, u.conn, u.bufferSize, handle, u.logger)
+}
+
+func (u *udpInterface) WritePacket(packet []byte, to *net.UDPAddr) error {
+	if to == nil {
+		return nil
+	}
+	_, err := u.conn.WriteToUDP(packet, to)
+	return err
+}
+
+func (u *udpInterface) Close() error {
+	return u.conn.Close()
+}
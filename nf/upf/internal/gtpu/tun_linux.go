@@ -0,0 +1,162 @@
+//go:build linux
+
+package gtpu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"github.com/your-org/5g-network/nf/upf/internal/config"
+	"go.uber.org/zap"
+)
+
+// tunDevice is the TUN clone device (linux/if_tun.h)
+const tunDevice = "/dev/net/tun"
+
+// ifreq is struct ifreq with the union as a sockaddr or flags
+type ifreq struct {
+	name [syscall.IFNAMSIZ]byte
+	data [24]byte
+}
+
+// tunInterface routes N6 through a TUN device: uplink packets are written
+// to the kernel, which routes downlink packets for the UE subnet back
+// through the device
+type tunInterface struct {
+	file       *os.File
+	name       string
+	bufferSize int
+	logger     *zap.Logger
+}
+
+// newTUNInterface creates the TUN device of the N6 config and gives it the
+// gateway address on the UE subnet. Creating it needs CAP_NET_ADMIN, errors
+// match os.ErrPermission without it.
+func newTUNInterface(cfg *config.N6Config, bufferSize int, logger *zap.Logger) (N6Interface, error) {
+	if len(cfg.InterfaceName) == 0 || len(cfg.InterfaceName) >= syscall.IFNAMSIZ {
+		return nil, fmt.Errorf("invalid TUN device name %q", cfg.InterfaceName)
+	}
+	gateway := net.ParseIP(cfg.Gateway).To4()
+	_, subnet, err := net.ParseCIDR(cfg.Subnet)
+	if gateway == nil || err != nil || subnet.IP.To4() == nil {
+		return nil, fmt.Errorf("TUN device needs an IPv4 gateway and subnet, have %q and %q", cfg.Gateway, cfg.Subnet)
+	}
+
+	fd, err := syscall.Open(tunDevice, syscall.O_RDWR|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", tunDevice, err)
+	}
+
+	var req ifreq
+	copy(req.name[:], cfg.InterfaceName)
+	*(*uint16)(unsafe.Pointer(&req.data[0])) = syscall.IFF_TUN | syscall.IFF_NO_PI
+	if err := ioctl(fd, syscall.TUNSETIFF, &req); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("failed to create TUN device %s: %w", cfg.InterfaceName, err)
+	}
+
+	if err := configureInterface(cfg.InterfaceName, gateway, net.IP(subnet.Mask)); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("failed to configure TUN device %s: %w", cfg.InterfaceName, err)
+	}
+
+	// Non-blocking, the runtime poller can interrupt reads on Close
+	if err := syscall.SetNonblock(fd, true); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	logger.Info("N6 TUN device created",
+		zap.String("device", cfg.InterfaceName),
+		zap.String("address", gateway.String()),
+		zap.String("subnet", subnet.String()))
+	return &tunInterface{
+		file:       os.NewFile(uintptr(fd), tunDevice),
+		name:       cfg.InterfaceName,
+		bufferSize: bufferSize,
+		logger:     logger,
+	}, nil
+}
+
+// configureInterface sets the address and netmask of an interface and
+// brings it up. The kernel adds the route to the subnet.
+func configureInterface(name string, address, mask net.IP) error {
+	sock, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(sock)
+
+	var req ifreq
+	copy(req.name[:], name)
+
+	putSockaddrInet4(&req, address)
+	if err := ioctl(sock, syscall.SIOCSIFADDR, &req); err != nil {
+		return fmt.Errorf("set address: %w", err)
+	}
+	putSockaddrInet4(&req, mask.To4())
+	if err := ioctl(sock, syscall.SIOCSIFNETMASK, &req); err != nil {
+		return fmt.Errorf("set netmask: %w", err)
+	}
+
+	clear(req.data[:])
+	if err := ioctl(sock, syscall.SIOCGIFFLAGS, &req); err != nil {
+		return fmt.Errorf("get flags: %w", err)
+	}
+	*(*uint16)(unsafe.Pointer(&req.data[0])) |= syscall.IFF_UP | syscall.IFF_RUNNING
+	if err := ioctl(sock, syscall.SIOCSIFFLAGS, &req); err != nil {
+		return fmt.Errorf("set flags: %w", err)
+	}
+	return nil
+}
+
+// putSockaddrInet4 stores an IPv4 struct sockaddr_in in the ifreq union
+func putSockaddrInet4(req *ifreq, ip net.IP) {
+	clear(req.data[:])
+	*(*uint16)(unsafe.Pointer(&req.data[0])) = syscall.AF_INET
+	copy(req.data[4:8], ip.To4())
+}
+
+func ioctl(fd int, request uintptr, req *ifreq) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), request, uintptr(unsafe.Pointer(req)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+func (t *tunInterface) Serve(ctx context.Context, handle func(packet []byte, from *net.UDPAddr)) error {
+	stop := context.AfterFunc(ctx, func() { t.file.Close() })
+	defer stop()
+
+	buffer := make([]byte, t.bufferSize)
+	for {
+		n, err := t.file.Read(buffer)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, os.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("failed to read TUN device %s: %w", t.name, err)
+		}
+		handle(buffer[:n], nil)
+	}
+}
+
+func (t *tunInterface) WritePacket(packet []byte, _ *net.UDPAddr) error {
+	_, err := t.file.Write(packet)
+	return err
+}
+
+// Close removes the TUN device
+func (t *tunInterface) Close() error {
+	err := t.file.Close()
+	if errors.Is(err, os.ErrClosed) {
+		return nil
+	}
+	return err
+}
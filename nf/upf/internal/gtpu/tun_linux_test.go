@@ -0,0 +1,120 @@
+//go:build linux
+
+package gtpu
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	gtpumsg "github.com/your-org/5g-network/common/gtpu"
+	"github.com/your-org/5g-network/nf/upf/internal/config"
+)
+
+// newTUNTestHandler returns a test handler whose N6 is the TUN device name
+// on 10.250.<subnet>.0/24, with the test session's UE at .2
+func newTUNTestHandler(t *testing.T, name string, subnet byte) (*GTPUHandler, *net.UDPConn) {
+	t.Helper()
+
+	h, gnb := newTestHandler(t)
+	logger, _ := zap.NewDevelopment()
+	n6, err := newTUNInterface(&config.N6Config{
+		Type:          config.N6TypeTUN,
+		InterfaceName: name,
+		Subnet:        net.IPv4(10, 250, subnet, 0).String() + "/24",
+		Gateway:       net.IPv4(10, 250, subnet, 1).String(),
+	}, 65535, logger)
+	if errors.Is(err, os.ErrPermission) || errors.Is(err, os.ErrNotExist) {
+		t.Skipf("no TUN devices here: %v", err)
+	}
+	require.NoError(t, err)
+	t.Cleanup(func() { n6.Close() })
+	h.n6 = n6
+
+	session, ok := h.upfContext.GetSession(1)
+	require.True(t, ok)
+	session.UEAddress = net.IPv4(10, 250, subnet, 2)
+	session.UPFTEID = 0x200
+	return h, gnb
+}
+
+// buildUDPv4 builds an IPv4 UDP packet without a UDP checksum
+func buildUDPv4(src, dst *net.UDPAddr, payload []byte) []byte {
+	packet := make([]byte, 28+len(payload))
+	packet[0] = 0x45
+	binary.BigEndian.PutUint16(packet[2:4], uint16(len(packet)))
+	packet[8] = 64
+	packet[9] = 17
+	copy(packet[12:16], src.IP.To4())
+	copy(packet[16:20], dst.IP.To4())
+	setIPv4Checksum(packet[:20])
+
+	binary.BigEndian.PutUint16(packet[20:22], uint16(src.Port))
+	binary.BigEndian.PutUint16(packet[22:24], uint16(dst.Port))
+	binary.BigEndian.PutUint16(packet[24:26], uint16(8+len(payload)))
+	copy(packet[28:], payload)
+	return packet
+}
+
+func TestTUN_UplinkPacketIsDecapsulated(t *testing.T) {
+	h, _ := newTUNTestHandler(t, "upftest0", 1)
+
+	// A data network host on the gateway address
+	dn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(10, 250, 1, 1)})
+	require.NoError(t, err)
+	defer dn.Close()
+
+	ue := &net.UDPAddr{IP: net.IPv4(10, 250, 1, 2), Port: 5000}
+	inner := buildUDPv4(ue, dn.LocalAddr().(*net.UDPAddr), []byte("hello"))
+	gpdu := gtpumsg.NewGPDU(0x200, inner, gtpumsg.NewPDUSessionContainer(&gtpumsg.PDUSessionContainer{
+		PDUType: gtpumsg.PDU_TYPE_UL_PDU_SESSION_INFORMATION,
+		QFI:     9,
+	}))
+	h.handleN3Datagram(gpdu.Marshal(), &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 2152})
+
+	// The kernel routes the inner packet, and only it, to the host
+	buf := make([]byte, 1500)
+	require.NoError(t, dn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	n, from, err := dn.ReadFromUDP(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(buf[:n]))
+	assert.Equal(t, ue.String(), from.String())
+	assert.Equal(t, uint64(1), h.stats.UplinkPackets)
+}
+
+func TestTUN_DownlinkPacketIsEncapsulated(t *testing.T) {
+	h, gnb := newTUNTestHandler(t, "upftest1", 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- h.n6.Serve(ctx, h.handleN6Datagram) }()
+	defer func() {
+		cancel()
+		assert.NoError(t, <-served)
+	}()
+
+	// The kernel routes packets for the UE subnet to the TUN device
+	dn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: net.IPv4(10, 250, 2, 2), Port: 6000})
+	require.NoError(t, err)
+	defer dn.Close()
+	_, err = dn.Write([]byte("world"))
+	require.NoError(t, err)
+
+	packet, err := gtpumsg.Parse(readDatagram(t, gnb))
+	require.NoError(t, err)
+	assert.Equal(t, uint32(0x100), packet.Header.TEID)
+
+	inner := packet.Payload
+	require.Len(t, inner, 28+5)
+	assert.Equal(t, net.IPv4(10, 250, 2, 2).To4(), net.IP(inner[16:20]))
+	assert.Equal(t, uint16(6000), binary.BigEndian.Uint16(inner[22:24]))
+	assert.Equal(t, "world", string(inner[28:]))
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package gtpu
+
+import (
+	"errors"
+	"runtime"
+
+	"github.com/your-org/5g-network/nf/upf/internal/config"
+	"go.uber.org/zap"
+)
+
+// newTUNInterface is only implemented on Linux
+func newTUNInterface(cfg *config.N6Config, bufferSize int, logger *zap.Logger) (N6Interface, error) {
+	return nil, errors.New("TUN N6 interface is not supported on " + runtime.GOOS)
+}
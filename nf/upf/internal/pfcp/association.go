@@ -0,0 +1,155 @@
+package pfcp
+
+import (
+	"net"
+	"time"
+
+	pfcpmsg "github.com/your-org/5g-network/common/pfcp"
+	"go.uber.org/zap"
+)
+
+// smfAssociation is a PFCP association with an SMF (TS 29.244 6.2.6)
+type smfAssociation struct {
+	nodeID       string
+	addr         *net.UDPAddr
+	recoveryTime time.Time // SMF Recovery Time Stamp
+}
+
+// handleAssociationSetupRequest handles PFCP association setup. A setup from
+// an associated SMF with a new Recovery Time Stamp means the SMF restarted;
+// the sessions it held are deleted (TS 29.244 6.2.6.2.2).
+func (s *PFCPServer) handleAssociationSetupRequest(msg *pfcpmsg.Message, addr *net.UDPAddr) {
+	nodeID, recoveryTime, cause := decodeAssociationPeer(msg)
+	if cause != pfcpmsg.CAUSE_REQUEST_ACCEPTED {
+		s.logger.Warn("PFCP association rejected", zap.String("smf", addr.String()), zap.Uint8("cause", cause))
+		s.sendAssociationResponse(pfcpmsg.PFCP_ASSOCIATION_SETUP_RESPONSE, msg, cause, addr)
+		return
+	}
+
+	s.assocMu.Lock()
+	previous := s.associations[nodeID]
+	s.associations[nodeID] = &smfAssociation{
+		nodeID:       nodeID,
+		addr:         addr,
+		recoveryTime: recoveryTime,
+	}
+	s.assocMu.Unlock()
+
+	if previous != nil && !previous.recoveryTime.Equal(recoveryTime) {
+		deleted := s.deleteSMFSessions(nodeID)
+		s.logger.Warn("SMF restarted, deleted its PFCP sessions",
+			zap.String("smf_node_id", nodeID),
+			zap.Int("sessions", deleted))
+	}
+
+	s.sendAssociationResponse(pfcpmsg.PFCP_ASSOCIATION_SETUP_RESPONSE, msg, pfcpmsg.CAUSE_REQUEST_ACCEPTED, addr,
+		pfcpmsg.NewRecoveryTimeStampIE(s.startedAt),
+	)
+	s.logger.Info("PFCP association established",
+		zap.String("smf", addr.String()),
+		zap.String("smf_node_id", nodeID),
+		zap.Time("smf_recovery_time", recoveryTime))
+}
+
+// handleAssociationReleaseRequest releases an SMF association and deletes the
+// sessions established under it (TS 29.244 6.2.8.3)
+func (s *PFCPServer) handleAssociationReleaseRequest(msg *pfcpmsg.Message, addr *net.UDPAddr) {
+	nodeIDIE := msg.FindIE(pfcpmsg.IE_NODE_ID)
+	if nodeIDIE == nil {
+		s.sendAssociationResponse(pfcpmsg.PFCP_ASSOCIATION_RELEASE_RESPONSE, msg, pfcpmsg.CAUSE_MANDATORY_IE_MISSING, addr)
+		return
+	}
+	peer, err := nodeIDIE.NodeID()
+	if err != nil {
+		s.sendAssociationResponse(pfcpmsg.PFCP_ASSOCIATION_RELEASE_RESPONSE, msg, pfcpmsg.CAUSE_MANDATORY_IE_INCORRECT, addr)
+		return
+	}
+	nodeID := peer.String()
+
+	s.assocMu.Lock()
+	_, exists := s.associations[nodeID]
+	delete(s.associations, nodeID)
+	s.assocMu.Unlock()
+
+	if !exists {
+		s.sendAssociationResponse(pfcpmsg.PFCP_ASSOCIATION_RELEASE_RESPONSE, msg, pfcpmsg.CAUSE_NO_ESTABLISHED_ASSOCIATION, addr)
+		return
+	}
+
+	deleted := s.deleteSMFSessions(nodeID)
+	s.sendAssociationResponse(pfcpmsg.PFCP_ASSOCIATION_RELEASE_RESPONSE, msg, pfcpmsg.CAUSE_REQUEST_ACCEPTED, addr)
+	s.logger.Info("PFCP association released",
+		zap.String("smf", addr.String()),
+		zap.String("smf_node_id", nodeID),
+		zap.Int("sessions_deleted", deleted))
+}
+
+// associationFor returns the association of the SMF sending from addr
+func (s *PFCPServer) associationFor(addr *net.UDPAddr) (*smfAssociation, bool) {
+	s.assocMu.Lock()
+	defer s.assocMu.Unlock()
+
+	for _, assoc := range s.associations {
+		if assoc.addr.IP.Equal(addr.IP) && assoc.addr.Port == addr.Port {
+			return assoc, true
+		}
+	}
+	return nil, false
+}
+
+// associatedSMFs returns the addresses of the associated SMFs
+func (s *PFCPServer) associatedSMFs() []*net.UDPAddr {
+	s.assocMu.Lock()
+	defer s.assocMu.Unlock()
+
+	addrs := make([]*net.UDPAddr, 0, len(s.associations))
+	for _, assoc := range s.associations {
+		addrs = append(addrs, assoc.addr)
+	}
+	return addrs
+}
+
+// deleteSMFSessions deletes the sessions established by an SMF and returns
+// how many were deleted
+func (s *PFCPServer) deleteSMFSessions(nodeID string) int {
+	deleted := 0
+	for _, session := range s.upfContext.GetAllSessions() {
+		if session.SMFNodeID != nodeID {
+			continue
+		}
+		s.upfContext.DeleteSession(session.SEID)
+		s.removeFromDataPlane(session.SEID)
+		deleted++
+	}
+	return deleted
+}
+
+// sendAssociationResponse answers an association request with the UPF Node
+// ID, a cause and any extra IEs
+func (s *PFCPServer) sendAssociationResponse(msgType uint8, req *pfcpmsg.Message, cause uint8, addr *net.UDPAddr, ies ...*pfcpmsg.IE) {
+	response := pfcpmsg.NewMessage(msgType, req.Header.SequenceNumber,
+		append([]*pfcpmsg.IE{s.nodeIDIE(), pfcpmsg.NewCauseIE(cause)}, ies...)...,
+	)
+	s.sendResponse(response, addr)
+}
+
+// decodeAssociationPeer decodes the Node ID and Recovery Time Stamp of an
+// association setup request, returning the cause to reject it with when they
+// are missing or malformed
+func decodeAssociationPeer(msg *pfcpmsg.Message) (string, time.Time, uint8) {
+	nodeIDIE := msg.FindIE(pfcpmsg.IE_NODE_ID)
+	recoveryIE := msg.FindIE(pfcpmsg.IE_RECOVERY_TIME_STAMP)
+	if nodeIDIE == nil || recoveryIE == nil {
+		return "", time.Time{}, pfcpmsg.CAUSE_MANDATORY_IE_MISSING
+	}
+
+	nodeID, err := nodeIDIE.NodeID()
+	if err != nil {
+		return "", time.Time{}, pfcpmsg.CAUSE_MANDATORY_IE_INCORRECT
+	}
+	recoveryTime, err := recoveryIE.RecoveryTimeStamp()
+	if err != nil {
+		return "", time.Time{}, pfcpmsg.CAUSE_MANDATORY_IE_INCORRECT
+	}
+	return nodeID.String(), recoveryTime, pfcpmsg.CAUSE_REQUEST_ACCEPTED
+}
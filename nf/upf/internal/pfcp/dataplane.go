@@ -0,0 +1,244 @@
+package pfcp
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/your-org/5g-network/common/dataplane"
+	pfcpmsg "github.com/your-org/5g-network/common/pfcp"
+	upfcontext "github.com/your-org/5g-network/nf/upf/internal/context"
+	"go.uber.org/zap"
+)
+
+// SetDataPlane makes the server mirror installed session rules into dp so
+// packets are matched against them, and report downlink data for buffering
+// sessions to their SMF. It must be called before Start.
+func (s *PFCPServer) SetDataPlane(dp dataplane.DataPlane) {
+	s.dataPlane = dp
+	dp.SetReportHandler(s.HandleSessionReport)
+}
+
+// ruleIDs identifies the rules a session had before a request was applied
+type ruleIDs struct {
+	pdrs []uint16
+	fars []uint32
+	qers []uint32
+	urrs []uint32
+}
+
+func sessionRuleIDs(session *upfcontext.UPFSession) ruleIDs {
+	var ids ruleIDs
+	for _, pdr := range session.PDRs {
+		ids.pdrs = append(ids.pdrs, pdr.PDRID)
+	}
+	for _, far := range session.FARs {
+		ids.fars = append(ids.fars, far.FARID)
+	}
+	for _, qer := range session.QERs {
+		ids.qers = append(ids.qers, qer.QERID)
+	}
+	for _, urr := range session.URRs {
+		ids.urrs = append(ids.urrs, urr.URRID)
+	}
+	return ids
+}
+
+// syncDataPlane installs the session's rules into the data plane and removes
+// the rules it no longer has
+func (s *PFCPServer) syncDataPlane(session *upfcontext.UPFSession, previous ruleIDs) error {
+	if s.dataPlane == nil {
+		return nil
+	}
+	ctx := context.Background()
+	current := sessionRuleIDs(session)
+
+	for _, id := range missing(previous.pdrs, current.pdrs) {
+		if err := s.dataPlane.RemovePDR(ctx, session.SEID, id); err != nil {
+			return fmt.Errorf("remove PDR %d: %w", id, err)
+		}
+	}
+	for _, id := range missing(previous.fars, current.fars) {
+		if err := s.dataPlane.RemoveFAR(ctx, session.SEID, uint16(id)); err != nil {
+			return fmt.Errorf("remove FAR %d: %w", id, err)
+		}
+	}
+	for _, id := range missing(previous.qers, current.qers) {
+		if err := s.dataPlane.RemoveQER(ctx, session.SEID, uint16(id)); err != nil {
+			return fmt.Errorf("remove QER %d: %w", id, err)
+		}
+	}
+	for _, id := range missing(previous.urrs, current.urrs) {
+		if err := s.dataPlane.RemoveURR(ctx, session.SEID, id); err != nil {
+			return fmt.Errorf("remove URR %d: %w", id, err)
+		}
+	}
+
+	// PDRs first: the data plane creates its session with the first PDR
+	for i := range session.PDRs {
+		if err := s.dataPlane.InstallPDR(ctx, session.SEID, toDataPlanePDR(&session.PDRs[i])); err != nil {
+			return fmt.Errorf("install PDR %d: %w", session.PDRs[i].PDRID, err)
+		}
+	}
+	for i := range session.FARs {
+		if err := s.dataPlane.InstallFAR(ctx, session.SEID, toDataPlaneFAR(&session.FARs[i])); err != nil {
+			return fmt.Errorf("install FAR %d: %w", session.FARs[i].FARID, err)
+		}
+	}
+	for i := range session.QERs {
+		if err := s.dataPlane.InstallQER(ctx, session.SEID, toDataPlaneQER(&session.QERs[i])); err != nil {
+			return fmt.Errorf("install QER %d: %w", session.QERs[i].QERID, err)
+		}
+	}
+	for i := range session.URRs {
+		if err := s.dataPlane.InstallURR(ctx, session.SEID, toDataPlaneURR(&session.URRs[i])); err != nil {
+			return fmt.Errorf("install URR %d: %w", session.URRs[i].URRID, err)
+		}
+	}
+	return nil
+}
+
+// removeFromDataPlane drops a deleted session from the data plane
+func (s *PFCPServer) removeFromDataPlane(seid uint64) {
+	if s.dataPlane == nil {
+		return
+	}
+	if err := s.dataPlane.RemoveSession(context.Background(), seid); err != nil {
+		s.logger.Warn("Failed to remove session from data plane", zap.Uint64("seid", seid), zap.Error(err))
+	}
+}
+
+// missing returns the IDs of previous not in current
+func missing[T comparable](previous, current []T) []T {
+	keep := make(map[T]bool, len(current))
+	for _, id := range current {
+		keep[id] = true
+	}
+	var out []T
+	for _, id := range previous {
+		if !keep[id] {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// interfaceName maps a Source/Destination Interface value to the data plane
+// name
+func interfaceName(v uint8) string {
+	switch v {
+	case pfcpmsg.INTERFACE_CORE:
+		return "CORE"
+	case pfcpmsg.INTERFACE_SGI_LAN:
+		return "SGI-LAN"
+	case pfcpmsg.INTERFACE_CP_FUNCTION:
+		return "CP-FUNCTION"
+	default:
+		return "ACCESS"
+	}
+}
+
+func toDataPlanePDR(pdr *upfcontext.PDR) *dataplane.PDR {
+	out := &dataplane.PDR{
+		PDRID:      pdr.PDRID,
+		Precedence: pdr.Precedence,
+		PDI: &dataplane.PacketDetectionInfo{
+			SourceInterface: interfaceName(pdr.PDI.SourceInterface),
+			NetworkInstance: pdr.PDI.NetworkInstance,
+			QFI:             pdr.PDI.QFI,
+		},
+		FARID: uint16(pdr.FARID),
+	}
+	if fteid := pdr.PDI.FTEID; fteid != nil {
+		out.PDI.LocalFTEID = &dataplane.FTEID{
+			TEID:     fteid.TEID,
+			IPv4:     fteid.IPv4Address,
+			IPv6:     fteid.IPv6Address,
+			ChooseID: fteid.ChooseID != 0,
+		}
+	}
+	if pdr.PDI.UEIPAddress != nil || pdr.PDI.UEIPv6Prefix != nil {
+		out.PDI.UEIPAddress = &dataplane.UEIPAddress{IPv4: pdr.PDI.UEIPAddress}
+		if prefix := pdr.PDI.UEIPv6Prefix; prefix != nil {
+			ones, _ := prefix.Mask.Size()
+			out.PDI.UEIPAddress.IPv6 = prefix.IP
+			out.PDI.UEIPAddress.IPv6Prefix = uint8(ones)
+		}
+	}
+	if pdr.PDI.SDFFilter != "" {
+		out.PDI.SDFFilter = []string{pdr.PDI.SDFFilter}
+	}
+	if pdr.OuterHeaderRemoval != 0 {
+		out.OuterHeaderRemoval = &dataplane.OuterHeaderRemoval{Description: pdr.OuterHeaderRemoval}
+	}
+	for _, id := range pdr.QERIDs {
+		out.QERID = append(out.QERID, uint16(id))
+	}
+	out.URRID = append(out.URRID, pdr.URRIDs...)
+	return out
+}
+
+func toDataPlaneFAR(far *upfcontext.FAR) *dataplane.FAR {
+	out := &dataplane.FAR{
+		FARID:       uint16(far.FARID),
+		ApplyAction: far.ApplyAction,
+	}
+	if params := far.ForwardingParameters; params != nil {
+		out.ForwardingParameters = &dataplane.ForwardingParameters{
+			DestinationInterface: interfaceName(params.DestinationInterface),
+			NetworkInstance:      params.NetworkInstance,
+			ForwardingPolicy:     params.ForwardingPolicy,
+		}
+		if ohc := params.OuterHeaderCreation; ohc != nil {
+			out.ForwardingParameters.OuterHeaderCreation = &dataplane.OuterHeaderCreation{
+				Description: ohc.Description,
+				TEID:        ohc.TEID,
+				IPv4:        ohc.IPv4Address,
+				IPv6:        ohc.IPv6Address,
+				PortNumber:  ohc.Port,
+			}
+		}
+	}
+	return out
+}
+
+func toDataPlaneQER(qer *upfcontext.QER) *dataplane.QER {
+	out := &dataplane.QER{
+		QERID:      uint16(qer.QERID),
+		QFI:        qer.QFI,
+		GateStatus: qer.GateStatus,
+	}
+	if qer.MBR != nil {
+		out.MBR = &dataplane.MBR{Uplink: qer.MBR.Uplink, Downlink: qer.MBR.Downlink}
+	}
+	if qer.GBR != nil {
+		out.GBR = &dataplane.GBR{Uplink: qer.GBR.Uplink, Downlink: qer.GBR.Downlink}
+	}
+	if qer.QoSMonitoring != nil {
+		monitoring := dataplane.QoSMonitoring(*qer.QoSMonitoring)
+		out.QoSMonitoring = &monitoring
+	}
+	return out
+}
+
+func toDataPlaneURR(urr *upfcontext.URR) *dataplane.URR {
+	out := &dataplane.URR{
+		URRID:             urr.URRID,
+		ReportingTriggers: urr.ReportingTriggers,
+		MeasurementPeriod: urr.MeasurementPeriod,
+		TimeThreshold:     urr.TimeThreshold,
+	}
+	// The data plane numbers the measurement methods differently
+	if urr.MeasurementMethod&pfcpmsg.MEASUREMENT_METHOD_VOLUM != 0 {
+		out.MeasurementMethod |= dataplane.MeasurementMethodVolume
+	}
+	if urr.MeasurementMethod&pfcpmsg.MEASUREMENT_METHOD_DURAT != 0 {
+		out.MeasurementMethod |= dataplane.MeasurementMethodDuration
+	}
+	if urr.MeasurementMethod&pfcpmsg.MEASUREMENT_METHOD_EVENT != 0 {
+		out.MeasurementMethod |= dataplane.MeasurementMethodEvent
+	}
+	if vt := urr.VolumeThreshold; vt != nil {
+		out.VolumeThreshold = &dataplane.VolumeThreshold{Total: vt.Total, Uplink: vt.Uplink, Downlink: vt.Downlink}
+	}
+	return out
+}
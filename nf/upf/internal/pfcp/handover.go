@@ -0,0 +1,56 @@
+package pfcp
+
+import (
+	"net"
+
+	upfcontext "github.com/your-org/5g-network/nf/upf/internal/context"
+	"go.uber.org/zap"
+)
+
+// EndMarkerSender sends GTP-U End Markers on N3
+type EndMarkerSender interface {
+	SendEndMarker(teid uint32, gnbAddress net.IP) error
+}
+
+// SetEndMarkerSender makes the server send an End Marker on the old downlink
+// tunnel of a session whose modification switches it to a new one. It must
+// be called before Start.
+func (s *PFCPServer) SetEndMarkerSender(sender EndMarkerSender) {
+	s.endMarkers = sender
+}
+
+// downlinkTunnel is the N3 tunnel downlink packets of a session are sent on
+type downlinkTunnel struct {
+	teid    uint32
+	address net.IP
+}
+
+func sessionDownlinkTunnel(session *upfcontext.UPFSession) downlinkTunnel {
+	return downlinkTunnel{teid: session.GNBTEID, address: session.GNBAddress}
+}
+
+// switchedFrom sends an End Marker on the previous downlink tunnel of the
+// session if a modification moved downlink to another one, as on a path
+// switch after handover (TS 23.502 4.9.1.2.2). The target gNB releases the
+// packets it buffered from the new path once the source gNB relays it.
+func (s *PFCPServer) switchedFrom(session *upfcontext.UPFSession, previous downlinkTunnel) {
+	current := sessionDownlinkTunnel(session)
+	if s.endMarkers == nil || previous.address == nil ||
+		(current.teid == previous.teid && current.address.Equal(previous.address)) {
+		return
+	}
+
+	if err := s.endMarkers.SendEndMarker(previous.teid, previous.address); err != nil {
+		s.logger.Warn("Failed to send End Marker",
+			zap.Uint64("seid", session.SEID),
+			zap.Uint32("gnb_teid", previous.teid),
+			zap.Error(err))
+		return
+	}
+	s.logger.Info("Downlink path switched",
+		zap.Uint64("seid", session.SEID),
+		zap.Uint32("old_gnb_teid", previous.teid),
+		zap.String("old_gnb_address", previous.address.String()),
+		zap.Uint32("gnb_teid", current.teid),
+		zap.String("gnb_address", current.address.String()))
+}
@@ -0,0 +1,110 @@
+package pfcp
+
+import (
+	"sync/atomic"
+
+	"github.com/your-org/5g-network/common/dataplane"
+	pfcpmsg "github.com/your-org/5g-network/common/pfcp"
+	"go.uber.org/zap"
+)
+
+// HandleSessionReport sends a PFCP Session Report Request to the SMF owning
+// the session when the data plane or the GTP-U handler raises a downlink
+// data, usage, error indication or QoS monitoring report (TS 29.244 7.5.8)
+func (s *PFCPServer) HandleSessionReport(report *dataplane.SessionReport) {
+	var reportType uint8
+	var ies []*pfcpmsg.IE
+	if r := report.DownlinkDataReport; r != nil {
+		reportType |= pfcpmsg.REPORT_TYPE_DLDR
+		ies = append(ies, pfcpmsg.NewDownlinkDataReportIE(&pfcpmsg.DownlinkDataReport{
+			PDRID: r.PDRID,
+			QFI:   r.QFI,
+		}))
+	}
+	if len(report.UsageReports) > 0 {
+		reportType |= pfcpmsg.REPORT_TYPE_USAR
+		for _, r := range report.UsageReports {
+			ies = append(ies, pfcpmsg.NewUsageReportIE(pfcpmsg.IE_USAGE_REPORT_REPORT_REQ, &pfcpmsg.UsageReport{
+				URRID:          r.URRID,
+				SequenceNumber: r.SequenceNumber,
+				Trigger:        r.Trigger,
+				StartTime:      r.StartTime,
+				EndTime:        r.EndTime,
+				Volume: &pfcpmsg.VolumeMeasurement{
+					TotalVolume:     r.Volume.TotalVolume,
+					UplinkVolume:    r.Volume.UplinkVolume,
+					DownlinkVolume:  r.Volume.DownlinkVolume,
+					TotalPackets:    r.Volume.TotalPackets,
+					UplinkPackets:   r.Volume.UplinkPackets,
+					DownlinkPackets: r.Volume.DownlinkPackets,
+				},
+				Duration: r.Duration,
+			}))
+		}
+	}
+	if r := report.ErrorIndication; r != nil {
+		reportType |= pfcpmsg.REPORT_TYPE_ERIR
+		ies = append(ies, pfcpmsg.NewErrorIndicationReportIE(&pfcpmsg.ErrorIndicationReport{
+			RemoteFTEIDs: []*pfcpmsg.FTEID{{TEID: r.RemoteFTEID.TEID, IPv4: r.RemoteFTEID.IPv4, IPv6: r.RemoteFTEID.IPv6}},
+		}))
+	}
+	if len(report.QoSMonitoringReports) > 0 {
+		reportType |= pfcpmsg.REPORT_TYPE_SESR
+		for _, r := range report.QoSMonitoringReports {
+			ies = append(ies, pfcpmsg.NewQoSMonitoringReportIE(&pfcpmsg.QoSMonitoringReport{
+				QFI:            r.QFI,
+				DownlinkDelay:  r.DownlinkDelay,
+				UplinkDelay:    r.UplinkDelay,
+				RoundTripDelay: r.RoundTripDelay,
+				EventTime:      r.Timestamp,
+			}))
+		}
+	}
+	if reportType == 0 {
+		return
+	}
+
+	session, exists := s.upfContext.GetSession(report.SessionID)
+	if !exists {
+		s.logger.Warn("Dropping report for unknown session", zap.Uint64("seid", report.SessionID))
+		return
+	}
+
+	s.assocMu.Lock()
+	assoc, associated := s.associations[session.SMFNodeID]
+	s.assocMu.Unlock()
+	if !associated {
+		s.logger.Warn("Dropping report, SMF not associated",
+			zap.Uint64("seid", session.SEID),
+			zap.String("smf_node_id", session.SMFNodeID))
+		return
+	}
+
+	ies = append([]*pfcpmsg.IE{pfcpmsg.NewUint8IE(pfcpmsg.IE_REPORT_TYPE, reportType)}, ies...)
+	request := pfcpmsg.NewSessionMessage(pfcpmsg.PFCP_SESSION_REPORT_REQUEST, session.SMFSEID, s.nextSequence(), ies...)
+	s.sendResponse(request, assoc.addr)
+
+	s.logger.Info("Sent session report",
+		zap.Uint64("seid", session.SEID),
+		zap.Uint64("cp_seid", session.SMFSEID),
+		zap.Uint8("report_type", reportType),
+		zap.String("smf_node_id", assoc.nodeID))
+}
+
+// handleSessionReportResponse logs an SMF rejecting a session report
+func (s *PFCPServer) handleSessionReportResponse(msg *pfcpmsg.Message) {
+	cause := uint8(0)
+	if ie := msg.FindIE(pfcpmsg.IE_CAUSE); ie != nil {
+		cause, _ = ie.Uint8()
+	}
+	if cause != pfcpmsg.CAUSE_REQUEST_ACCEPTED {
+		s.logger.Warn("SMF rejected session report",
+			zap.Uint64("cp_seid", msg.Header.SEID),
+			zap.Uint8("cause", cause))
+	}
+}
+
+// nextSequence returns the sequence number for a UPF initiated request
+func (s *PFCPServer) nextSequence() uint32 {
+	return atomic.AddUint32(&s.sequenceNum, 1) & 0xffffff
+}
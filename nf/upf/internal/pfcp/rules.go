@@ -0,0 +1,717 @@
+package pfcp
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	pfcpmsg "github.com/your-org/5g-network/common/pfcp"
+	upfcontext "github.com/your-org/5g-network/nf/upf/internal/context"
+)
+
+// ruleError is a rule that could not be installed, with the cause to report
+// to the SMF
+type ruleError struct {
+	cause uint8
+	err   error
+}
+
+func (e *ruleError) Error() string { return e.err.Error() }
+
+func ruleErrorf(cause uint8, format string, args ...interface{}) error {
+	return &ruleError{cause: cause, err: fmt.Errorf(format, args...)}
+}
+
+// causeOf returns the PFCP cause for an error returned by applyRules
+func causeOf(err error) uint8 {
+	if re, ok := err.(*ruleError); ok {
+		return re.cause
+	}
+	return pfcpmsg.CAUSE_RULE_CREATION_FAILURE
+}
+
+// ruleSet is a working copy of a session's rules. Changes are applied to the
+// copy and only committed to the session once the whole request is valid.
+type ruleSet struct {
+	pdrs map[uint16]upfcontext.PDR
+	fars map[uint32]upfcontext.FAR
+	qers map[uint32]upfcontext.QER
+	urrs map[uint32]upfcontext.URR
+
+	// TEIDs allocated while applying the request, released on failure
+	allocated []uint32
+	// F-TEIDs allocated per Choose ID, shared by the PDRs of one request
+	chosen map[uint8]*upfcontext.FTEID
+	// Created PDR IEs reporting the allocated F-TEIDs
+	created []*pfcpmsg.IE
+}
+
+func newRuleSet(session *upfcontext.UPFSession) *ruleSet {
+	rs := &ruleSet{
+		pdrs:   make(map[uint16]upfcontext.PDR, len(session.PDRs)),
+		fars:   make(map[uint32]upfcontext.FAR, len(session.FARs)),
+		qers:   make(map[uint32]upfcontext.QER, len(session.QERs)),
+		urrs:   make(map[uint32]upfcontext.URR, len(session.URRs)),
+		chosen: make(map[uint8]*upfcontext.FTEID),
+	}
+	for _, pdr := range session.PDRs {
+		rs.pdrs[pdr.PDRID] = pdr
+	}
+	for _, far := range session.FARs {
+		rs.fars[far.FARID] = far
+	}
+	for _, qer := range session.QERs {
+		rs.qers[qer.QERID] = qer
+	}
+	for _, urr := range session.URRs {
+		rs.urrs[urr.URRID] = urr
+	}
+	return rs
+}
+
+// applyRules applies the Create, Update and Remove PDR/FAR/QER/URR IEs of a
+// session establishment or modification request to the session. It returns
+// the Created PDR IEs for the response.
+func (s *PFCPServer) applyRules(session *upfcontext.UPFSession, ies []*pfcpmsg.IE) ([]*pfcpmsg.IE, error) {
+	rs := newRuleSet(session)
+
+	err := s.buildRules(rs, ies)
+	if err == nil {
+		err = rs.validate()
+	}
+	if err != nil {
+		for _, teid := range rs.allocated {
+			s.upfContext.ReleaseTEID(teid)
+		}
+		return nil, err
+	}
+
+	for _, teid := range rs.commit(session) {
+		s.upfContext.ReleaseTEID(teid)
+	}
+	return rs.created, nil
+}
+
+// buildRules applies the rule IEs to the working copy. FARs, QERs and URRs
+// are handled before PDRs so a request may create a PDR and the rules it
+// references together.
+func (s *PFCPServer) buildRules(rs *ruleSet, ies []*pfcpmsg.IE) error {
+	for _, ie := range pfcpmsg.FindIEs(ies, pfcpmsg.IE_CREATE_FAR) {
+		far, err := decodeFAR(ie, upfcontext.FAR{})
+		if err != nil {
+			return err
+		}
+		rs.fars[far.FARID] = far
+	}
+	for _, ie := range pfcpmsg.FindIEs(ies, pfcpmsg.IE_UPDATE_FAR) {
+		id, err := ruleID32(ie, pfcpmsg.IE_FAR_ID)
+		if err != nil {
+			return err
+		}
+		existing, ok := rs.fars[id]
+		if !ok {
+			return ruleErrorf(pfcpmsg.CAUSE_RULE_CREATION_FAILURE, "update of unknown FAR %d", id)
+		}
+		far, err := decodeFAR(ie, existing)
+		if err != nil {
+			return err
+		}
+		rs.fars[id] = far
+	}
+
+	for _, ie := range pfcpmsg.FindIEs(ies, pfcpmsg.IE_CREATE_QER) {
+		qer, err := decodeQER(ie, upfcontext.QER{})
+		if err != nil {
+			return err
+		}
+		rs.qers[qer.QERID] = qer
+	}
+	for _, ie := range pfcpmsg.FindIEs(ies, pfcpmsg.IE_UPDATE_QER) {
+		id, err := ruleID32(ie, pfcpmsg.IE_QER_ID)
+		if err != nil {
+			return err
+		}
+		existing, ok := rs.qers[id]
+		if !ok {
+			return ruleErrorf(pfcpmsg.CAUSE_RULE_CREATION_FAILURE, "update of unknown QER %d", id)
+		}
+		qer, err := decodeQER(ie, existing)
+		if err != nil {
+			return err
+		}
+		rs.qers[id] = qer
+	}
+
+	for _, ie := range pfcpmsg.FindIEs(ies, pfcpmsg.IE_CREATE_URR) {
+		urr, err := decodeURR(ie, upfcontext.URR{})
+		if err != nil {
+			return err
+		}
+		rs.urrs[urr.URRID] = urr
+	}
+	for _, ie := range pfcpmsg.FindIEs(ies, pfcpmsg.IE_UPDATE_URR) {
+		id, err := ruleID32(ie, pfcpmsg.IE_URR_ID)
+		if err != nil {
+			return err
+		}
+		existing, ok := rs.urrs[id]
+		if !ok {
+			return ruleErrorf(pfcpmsg.CAUSE_RULE_CREATION_FAILURE, "update of unknown URR %d", id)
+		}
+		urr, err := decodeURR(ie, existing)
+		if err != nil {
+			return err
+		}
+		rs.urrs[id] = urr
+	}
+
+	for _, ie := range pfcpmsg.FindIEs(ies, pfcpmsg.IE_CREATE_PDR) {
+		pdr, err := decodePDR(ie, upfcontext.PDR{})
+		if err != nil {
+			return err
+		}
+		if err := s.allocateFTEID(rs, &pdr); err != nil {
+			return err
+		}
+		rs.pdrs[pdr.PDRID] = pdr
+	}
+	for _, ie := range pfcpmsg.FindIEs(ies, pfcpmsg.IE_UPDATE_PDR) {
+		children, err := ie.ChildIEs()
+		if err != nil {
+			return ruleErrorf(pfcpmsg.CAUSE_MANDATORY_IE_INCORRECT, "update PDR: %v", err)
+		}
+		idIE := pfcpmsg.FindIE(children, pfcpmsg.IE_PDR_ID)
+		if idIE == nil {
+			return ruleErrorf(pfcpmsg.CAUSE_MANDATORY_IE_MISSING, "update PDR without PDR ID")
+		}
+		id, err := idIE.Uint16()
+		if err != nil {
+			return ruleErrorf(pfcpmsg.CAUSE_MANDATORY_IE_INCORRECT, "update PDR: %v", err)
+		}
+		existing, ok := rs.pdrs[id]
+		if !ok {
+			return ruleErrorf(pfcpmsg.CAUSE_RULE_CREATION_FAILURE, "update of unknown PDR %d", id)
+		}
+		pdr, err := decodePDR(ie, existing)
+		if err != nil {
+			return err
+		}
+		// A new PDI may ask the UPF to choose the F-TEID again
+		if pfcpmsg.FindIE(children, pfcpmsg.IE_PDI) != nil {
+			if err := s.allocateFTEID(rs, &pdr); err != nil {
+				return err
+			}
+		}
+		rs.pdrs[id] = pdr
+	}
+
+	for _, ie := range pfcpmsg.FindIEs(ies, pfcpmsg.IE_REMOVE_PDR) {
+		children, err := ie.ChildIEs()
+		if err != nil {
+			return ruleErrorf(pfcpmsg.CAUSE_MANDATORY_IE_INCORRECT, "remove PDR: %v", err)
+		}
+		idIE := pfcpmsg.FindIE(children, pfcpmsg.IE_PDR_ID)
+		if idIE == nil {
+			return ruleErrorf(pfcpmsg.CAUSE_MANDATORY_IE_MISSING, "remove PDR without PDR ID")
+		}
+		id, err := idIE.Uint16()
+		if err != nil {
+			return ruleErrorf(pfcpmsg.CAUSE_MANDATORY_IE_INCORRECT, "remove PDR: %v", err)
+		}
+		delete(rs.pdrs, id)
+	}
+	for _, ie := range pfcpmsg.FindIEs(ies, pfcpmsg.IE_REMOVE_FAR) {
+		id, err := ruleID32(ie, pfcpmsg.IE_FAR_ID)
+		if err != nil {
+			return err
+		}
+		delete(rs.fars, id)
+	}
+	for _, ie := range pfcpmsg.FindIEs(ies, pfcpmsg.IE_REMOVE_QER) {
+		id, err := ruleID32(ie, pfcpmsg.IE_QER_ID)
+		if err != nil {
+			return err
+		}
+		delete(rs.qers, id)
+	}
+	for _, ie := range pfcpmsg.FindIEs(ies, pfcpmsg.IE_REMOVE_URR) {
+		id, err := ruleID32(ie, pfcpmsg.IE_URR_ID)
+		if err != nil {
+			return err
+		}
+		delete(rs.urrs, id)
+	}
+
+	return nil
+}
+
+// allocateFTEID allocates the local F-TEID of a PDR that asks the UPF to
+// choose it. PDRs of one request sharing a Choose ID get the same F-TEID.
+func (s *PFCPServer) allocateFTEID(rs *ruleSet, pdr *upfcontext.PDR) error {
+	fteid := pdr.PDI.FTEID
+	if fteid == nil || fteid.ChooseID == 0 {
+		return nil
+	}
+
+	shared := fteid.CHID != 0
+	if shared {
+		if existing, ok := rs.chosen[fteid.CHID]; ok {
+			copied := *existing
+			pdr.PDI.FTEID = &copied
+			rs.created = append(rs.created, createdPDRIE(pdr.PDRID, &copied))
+			return nil
+		}
+	}
+
+	allocated := &upfcontext.FTEID{
+		TEID:        s.upfContext.AllocateTEID(),
+		IPv4Address: s.n3Address(),
+		ChooseID:    1,
+		CHID:        fteid.CHID,
+	}
+	rs.allocated = append(rs.allocated, allocated.TEID)
+	if shared {
+		rs.chosen[fteid.CHID] = allocated
+	}
+
+	copied := *allocated
+	pdr.PDI.FTEID = &copied
+	rs.created = append(rs.created, createdPDRIE(pdr.PDRID, &copied))
+	return nil
+}
+
+// validate checks that every PDR references installed FARs, QERs and URRs
+func (rs *ruleSet) validate() error {
+	for _, pdr := range rs.pdrs {
+		if _, ok := rs.fars[pdr.FARID]; !ok {
+			return ruleErrorf(pfcpmsg.CAUSE_RULE_CREATION_FAILURE, "PDR %d references unknown FAR %d", pdr.PDRID, pdr.FARID)
+		}
+		for _, qerID := range pdr.QERIDs {
+			if _, ok := rs.qers[qerID]; !ok {
+				return ruleErrorf(pfcpmsg.CAUSE_RULE_CREATION_FAILURE, "PDR %d references unknown QER %d", pdr.PDRID, qerID)
+			}
+		}
+		for _, urrID := range pdr.URRIDs {
+			if _, ok := rs.urrs[urrID]; !ok {
+				return ruleErrorf(pfcpmsg.CAUSE_RULE_CREATION_FAILURE, "PDR %d references unknown URR %d", pdr.PDRID, urrID)
+			}
+		}
+	}
+	return nil
+}
+
+// commit installs the rules on the session, ordered by rule ID, and derives
+// the session's tunnel and UE address information from them. It returns the
+// allocated TEIDs no PDR uses any more.
+func (rs *ruleSet) commit(session *upfcontext.UPFSession) []uint32 {
+	previous := allocatedTEIDs(session.PDRs)
+
+	session.PDRs = make([]upfcontext.PDR, 0, len(rs.pdrs))
+	for _, pdr := range rs.pdrs {
+		session.PDRs = append(session.PDRs, pdr)
+	}
+	sort.Slice(session.PDRs, func(i, j int) bool { return session.PDRs[i].PDRID < session.PDRs[j].PDRID })
+
+	session.FARs = make([]upfcontext.FAR, 0, len(rs.fars))
+	for _, far := range rs.fars {
+		session.FARs = append(session.FARs, far)
+	}
+	sort.Slice(session.FARs, func(i, j int) bool { return session.FARs[i].FARID < session.FARs[j].FARID })
+
+	session.QERs = make([]upfcontext.QER, 0, len(rs.qers))
+	for _, qer := range rs.qers {
+		session.QERs = append(session.QERs, qer)
+	}
+	sort.Slice(session.QERs, func(i, j int) bool { return session.QERs[i].QERID < session.QERs[j].QERID })
+
+	session.URRs = make([]upfcontext.URR, 0, len(rs.urrs))
+	for _, urr := range rs.urrs {
+		session.URRs = append(session.URRs, urr)
+	}
+	sort.Slice(session.URRs, func(i, j int) bool { return session.URRs[i].URRID < session.URRs[j].URRID })
+
+	// The N3 tunnel is the F-TEID of the first uplink PDR, and the UE
+	// addresses and DNN those of the first PDR carrying them
+	session.UPFTEID = 0
+	session.UEAddress = nil
+	session.UEIPv6Prefix = nil
+	session.DNN = ""
+	for _, pdr := range session.PDRs {
+		if pdr.PDI.UEIPAddress != nil && session.UEAddress == nil {
+			session.UEAddress = pdr.PDI.UEIPAddress
+		}
+		if pdr.PDI.UEIPv6Prefix != nil && session.UEIPv6Prefix == nil {
+			session.UEIPv6Prefix = pdr.PDI.UEIPv6Prefix
+		}
+		if pdr.PDI.NetworkInstance != "" && session.DNN == "" {
+			session.DNN = pdr.PDI.NetworkInstance
+		}
+		if pdr.PDI.SourceInterface == pfcpmsg.INTERFACE_ACCESS && pdr.PDI.FTEID != nil && session.UPFTEID == 0 {
+			session.UPFTEID = pdr.PDI.FTEID.TEID
+		}
+	}
+
+	// Downlink goes to the gNB of the first FAR tunnelling towards access
+	for _, far := range session.FARs {
+		params := far.ForwardingParameters
+		if params == nil || params.DestinationInterface != pfcpmsg.INTERFACE_ACCESS || params.OuterHeaderCreation == nil {
+			continue
+		}
+		if params.OuterHeaderCreation.TEID != session.GNBTEID || !params.OuterHeaderCreation.IPv4Address.Equal(session.GNBAddress) {
+			// A new tunnel replaces one the gNB may have reported unknown
+			session.ErrorIndicated = false
+		}
+		session.GNBTEID = params.OuterHeaderCreation.TEID
+		session.GNBAddress = params.OuterHeaderCreation.IPv4Address
+		break
+	}
+
+	current := allocatedTEIDs(session.PDRs)
+	var released []uint32
+	for teid := range previous {
+		if !current[teid] {
+			released = append(released, teid)
+		}
+	}
+	return released
+}
+
+// allocatedTEIDs returns the UPF allocated F-TEIDs in use by pdrs
+func allocatedTEIDs(pdrs []upfcontext.PDR) map[uint32]bool {
+	teids := make(map[uint32]bool)
+	for _, pdr := range pdrs {
+		if pdr.PDI.FTEID != nil && pdr.PDI.FTEID.ChooseID == 1 {
+			teids[pdr.PDI.FTEID.TEID] = true
+		}
+	}
+	return teids
+}
+
+// decodePDR applies a Create PDR or Update PDR IE to pdr. QER and URR IDs
+// in an Update PDR replace those the PDR had.
+func decodePDR(ie *pfcpmsg.IE, pdr upfcontext.PDR) (upfcontext.PDR, error) {
+	children, err := ie.ChildIEs()
+	if err != nil {
+		return pdr, ruleErrorf(pfcpmsg.CAUSE_MANDATORY_IE_INCORRECT, "PDR: %v", err)
+	}
+	if pfcpmsg.FindIE(children, pfcpmsg.IE_QER_ID) != nil {
+		pdr.QERIDs = nil
+	}
+	if pfcpmsg.FindIE(children, pfcpmsg.IE_URR_ID) != nil {
+		pdr.URRIDs = nil
+	}
+
+	for _, child := range children {
+		switch child.Type {
+		case pfcpmsg.IE_PDR_ID:
+			pdr.PDRID, err = child.Uint16()
+		case pfcpmsg.IE_PRECEDENCE:
+			pdr.Precedence, err = child.Uint32()
+		case pfcpmsg.IE_PDI:
+			err = decodePDI(child, &pdr.PDI)
+		case pfcpmsg.IE_OUTER_HEADER_REMOVAL:
+			var removal uint8
+			removal, err = child.Uint8()
+			pdr.OuterHeaderRemoval = removal + 1 // 0 means none in the context
+		case pfcpmsg.IE_FAR_ID:
+			pdr.FARID, err = child.Uint32()
+		case pfcpmsg.IE_QER_ID:
+			var qerID uint32
+			qerID, err = child.Uint32()
+			pdr.QERIDs = appendUnique(pdr.QERIDs, qerID)
+		case pfcpmsg.IE_URR_ID:
+			var urrID uint32
+			urrID, err = child.Uint32()
+			pdr.URRIDs = appendUnique(pdr.URRIDs, urrID)
+		}
+		if err != nil {
+			return pdr, ruleErrorf(pfcpmsg.CAUSE_MANDATORY_IE_INCORRECT, "PDR %d: %v", pdr.PDRID, err)
+		}
+	}
+
+	if ie.Type == pfcpmsg.IE_CREATE_PDR && pfcpmsg.FindIE(children, pfcpmsg.IE_PDR_ID) == nil {
+		return pdr, ruleErrorf(pfcpmsg.CAUSE_MANDATORY_IE_MISSING, "create PDR without PDR ID")
+	}
+	return pdr, nil
+}
+
+// decodePDI decodes a PDI IE
+func decodePDI(ie *pfcpmsg.IE, pdi *upfcontext.PDI) error {
+	children, err := ie.ChildIEs()
+	if err != nil {
+		return err
+	}
+
+	*pdi = upfcontext.PDI{}
+	for _, child := range children {
+		switch child.Type {
+		case pfcpmsg.IE_SOURCE_INTERFACE:
+			var iface uint8
+			iface, err = child.Uint8()
+			pdi.SourceInterface = iface & 0x0f
+		case pfcpmsg.IE_NETWORK_INSTANCE:
+			pdi.NetworkInstance = string(child.Value)
+		case pfcpmsg.IE_F_TEID:
+			var fteid *pfcpmsg.FTEID
+			if fteid, err = child.FTEID(); err == nil {
+				pdi.FTEID = &upfcontext.FTEID{
+					TEID:        fteid.TEID,
+					IPv4Address: fteid.IPv4,
+					IPv6Address: fteid.IPv6,
+				}
+				if fteid.ChooseID {
+					pdi.FTEID.ChooseID = 1
+					pdi.FTEID.CHID = fteid.CHID
+				}
+			}
+		case pfcpmsg.IE_UE_IP_ADDRESS:
+			var ueIP *pfcpmsg.UEIPAddress
+			if ueIP, err = child.UEIPAddress(); err == nil {
+				pdi.UEIPAddress = ueIP.IPv4
+				pdi.UEIPv6Prefix = ueIPv6Prefix(ueIP)
+			}
+		case pfcpmsg.IE_SDF_FILTER:
+			pdi.SDFFilter = string(child.Value)
+		case pfcpmsg.IE_QFI:
+			var qfi uint8
+			qfi, err = child.Uint8()
+			pdi.QFI = qfi & 0x3f
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if pfcpmsg.FindIE(children, pfcpmsg.IE_SOURCE_INTERFACE) == nil {
+		return fmt.Errorf("PDI without source interface")
+	}
+	return nil
+}
+
+// decodeFAR applies a Create FAR or Update FAR IE to far
+func decodeFAR(ie *pfcpmsg.IE, far upfcontext.FAR) (upfcontext.FAR, error) {
+	children, err := ie.ChildIEs()
+	if err != nil {
+		return far, ruleErrorf(pfcpmsg.CAUSE_MANDATORY_IE_INCORRECT, "FAR: %v", err)
+	}
+
+	for _, child := range children {
+		switch child.Type {
+		case pfcpmsg.IE_FAR_ID:
+			far.FARID, err = child.Uint32()
+		case pfcpmsg.IE_APPLY_ACTION:
+			far.ApplyAction, err = child.Uint8()
+		case pfcpmsg.IE_FORWARDING_PARAMETERS, pfcpmsg.IE_UPDATE_FORWARDING_PARAMS:
+			params := &upfcontext.ForwardingParameters{}
+			if far.ForwardingParameters != nil && child.Type == pfcpmsg.IE_UPDATE_FORWARDING_PARAMS {
+				*params = *far.ForwardingParameters
+			}
+			err = decodeForwardingParameters(child, params)
+			far.ForwardingParameters = params
+		}
+		if err != nil {
+			return far, ruleErrorf(pfcpmsg.CAUSE_MANDATORY_IE_INCORRECT, "FAR %d: %v", far.FARID, err)
+		}
+	}
+
+	if ie.Type == pfcpmsg.IE_CREATE_FAR {
+		if pfcpmsg.FindIE(children, pfcpmsg.IE_FAR_ID) == nil || pfcpmsg.FindIE(children, pfcpmsg.IE_APPLY_ACTION) == nil {
+			return far, ruleErrorf(pfcpmsg.CAUSE_MANDATORY_IE_MISSING, "create FAR without FAR ID or apply action")
+		}
+	}
+	return far, nil
+}
+
+// decodeForwardingParameters decodes Forwarding Parameters or Update
+// Forwarding Parameters into params
+func decodeForwardingParameters(ie *pfcpmsg.IE, params *upfcontext.ForwardingParameters) error {
+	children, err := ie.ChildIEs()
+	if err != nil {
+		return err
+	}
+
+	for _, child := range children {
+		switch child.Type {
+		case pfcpmsg.IE_DESTINATION_INTERFACE:
+			var iface uint8
+			iface, err = child.Uint8()
+			params.DestinationInterface = iface & 0x0f
+		case pfcpmsg.IE_NETWORK_INSTANCE:
+			params.NetworkInstance = string(child.Value)
+		case pfcpmsg.IE_OUTER_HEADER_CREATION:
+			var ohc *pfcpmsg.OuterHeaderCreation
+			if ohc, err = child.OuterHeaderCreation(); err == nil {
+				params.OuterHeaderCreation = &upfcontext.OuterHeaderCreation{
+					Description: ohc.Description,
+					TEID:        ohc.TEID,
+					IPv4Address: ohc.IPv4,
+					IPv6Address: ohc.IPv6,
+					Port:        2152,
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeQER applies a Create QER or Update QER IE to qer
+func decodeQER(ie *pfcpmsg.IE, qer upfcontext.QER) (upfcontext.QER, error) {
+	children, err := ie.ChildIEs()
+	if err != nil {
+		return qer, ruleErrorf(pfcpmsg.CAUSE_MANDATORY_IE_INCORRECT, "QER: %v", err)
+	}
+
+	for _, child := range children {
+		switch child.Type {
+		case pfcpmsg.IE_QER_ID:
+			qer.QERID, err = child.Uint32()
+		case pfcpmsg.IE_QFI:
+			var qfi uint8
+			qfi, err = child.Uint8()
+			qer.QFI = qfi & 0x3f
+		case pfcpmsg.IE_GATE_STATUS:
+			var ul, dl uint8
+			ul, dl, err = child.GateStatus()
+			qer.GateStatus = pfcpmsg.GATE_STATUS_OPEN
+			if ul == pfcpmsg.GATE_STATUS_CLOSED || dl == pfcpmsg.GATE_STATUS_CLOSED {
+				qer.GateStatus = pfcpmsg.GATE_STATUS_CLOSED
+			}
+		case pfcpmsg.IE_MBR:
+			var br *pfcpmsg.BitRate
+			if br, err = child.BitRate(); err == nil {
+				qer.MBR = &upfcontext.MBR{Uplink: br.Uplink, Downlink: br.Downlink}
+			}
+		case pfcpmsg.IE_GBR:
+			var br *pfcpmsg.BitRate
+			if br, err = child.BitRate(); err == nil {
+				qer.GBR = &upfcontext.GBR{Uplink: br.Uplink, Downlink: br.Downlink}
+			}
+		case pfcpmsg.IE_QOS_MONITORING_CONTROL:
+			var m *pfcpmsg.QoSMonitoring
+			if m, err = child.QoSMonitoring(); err == nil {
+				qer.QoSMonitoring = &upfcontext.QoSMonitoring{
+					RequestedMonitoring: m.Requested,
+					DownlinkThreshold:   m.DownlinkThreshold,
+					UplinkThreshold:     m.UplinkThreshold,
+					RoundTripThreshold:  m.RoundTripThreshold,
+					MinimumWaitTime:     m.MinimumWaitTime,
+				}
+			}
+		}
+		if err != nil {
+			return qer, ruleErrorf(pfcpmsg.CAUSE_MANDATORY_IE_INCORRECT, "QER %d: %v", qer.QERID, err)
+		}
+	}
+
+	if ie.Type == pfcpmsg.IE_CREATE_QER && pfcpmsg.FindIE(children, pfcpmsg.IE_QER_ID) == nil {
+		return qer, ruleErrorf(pfcpmsg.CAUSE_MANDATORY_IE_MISSING, "create QER without QER ID")
+	}
+	return qer, nil
+}
+
+// decodeURR applies a Create URR or Update URR IE to urr
+func decodeURR(ie *pfcpmsg.IE, urr upfcontext.URR) (upfcontext.URR, error) {
+	children, err := ie.ChildIEs()
+	if err != nil {
+		return urr, ruleErrorf(pfcpmsg.CAUSE_MANDATORY_IE_INCORRECT, "URR: %v", err)
+	}
+
+	for _, child := range children {
+		switch child.Type {
+		case pfcpmsg.IE_URR_ID:
+			urr.URRID, err = child.Uint32()
+		case pfcpmsg.IE_MEASUREMENT_METHOD:
+			urr.MeasurementMethod, err = child.Uint8()
+		case pfcpmsg.IE_REPORTING_TRIGGERS:
+			urr.ReportingTriggers, err = child.ReportingTriggers()
+		case pfcpmsg.IE_MEASUREMENT_PERIOD:
+			var seconds uint32
+			seconds, err = child.Uint32()
+			urr.MeasurementPeriod = time.Duration(seconds) * time.Second
+		case pfcpmsg.IE_VOLUME_THRESHOLD:
+			var vt *pfcpmsg.VolumeThreshold
+			if vt, err = child.VolumeThreshold(); err == nil {
+				urr.VolumeThreshold = &upfcontext.VolumeThreshold{Total: vt.Total, Uplink: vt.Uplink, Downlink: vt.Downlink}
+			}
+		case pfcpmsg.IE_TIME_THRESHOLD:
+			var seconds uint32
+			seconds, err = child.Uint32()
+			urr.TimeThreshold = time.Duration(seconds) * time.Second
+		}
+		if err != nil {
+			return urr, ruleErrorf(pfcpmsg.CAUSE_MANDATORY_IE_INCORRECT, "URR %d: %v", urr.URRID, err)
+		}
+	}
+
+	if ie.Type == pfcpmsg.IE_CREATE_URR {
+		if pfcpmsg.FindIE(children, pfcpmsg.IE_URR_ID) == nil ||
+			pfcpmsg.FindIE(children, pfcpmsg.IE_MEASUREMENT_METHOD) == nil ||
+			pfcpmsg.FindIE(children, pfcpmsg.IE_REPORTING_TRIGGERS) == nil {
+			return urr, ruleErrorf(pfcpmsg.CAUSE_MANDATORY_IE_MISSING, "create URR without URR ID, measurement method or reporting triggers")
+		}
+	}
+	return urr, nil
+}
+
+// ruleID32 returns the four octet rule ID carried in a grouped IE
+func ruleID32(ie *pfcpmsg.IE, idType uint16) (uint32, error) {
+	children, err := ie.ChildIEs()
+	if err != nil {
+		return 0, ruleErrorf(pfcpmsg.CAUSE_MANDATORY_IE_INCORRECT, "IE type %d: %v", ie.Type, err)
+	}
+	idIE := pfcpmsg.FindIE(children, idType)
+	if idIE == nil {
+		return 0, ruleErrorf(pfcpmsg.CAUSE_MANDATORY_IE_MISSING, "IE type %d without rule ID", ie.Type)
+	}
+	id, err := idIE.Uint32()
+	if err != nil {
+		return 0, ruleErrorf(pfcpmsg.CAUSE_MANDATORY_IE_INCORRECT, "IE type %d: %v", ie.Type, err)
+	}
+	return id, nil
+}
+
+// ueIPv6Prefix returns the IPv6 prefix of a UE IP Address IE, nil when it
+// has no IPv6 address. The prefix is /64 unless it delegates another length.
+func ueIPv6Prefix(ueIP *pfcpmsg.UEIPAddress) *net.IPNet {
+	if ueIP.IPv6 == nil {
+		return nil
+	}
+	length := 64
+	if ueIP.IPv6PrefixLength != 0 && ueIP.IPv6PrefixLength <= 128 {
+		length = int(ueIP.IPv6PrefixLength)
+	}
+	mask := net.CIDRMask(length, 8*net.IPv6len)
+	return &net.IPNet{IP: ueIP.IPv6.Mask(mask), Mask: mask}
+}
+
+// createdPDRIE builds a Created PDR IE reporting an allocated F-TEID
+func createdPDRIE(pdrID uint16, fteid *upfcontext.FTEID) *pfcpmsg.IE {
+	return pfcpmsg.NewGroupedIE(pfcpmsg.IE_CREATED_PDR,
+		pfcpmsg.NewUint16IE(pfcpmsg.IE_PDR_ID, pdrID),
+		pfcpmsg.NewFTEIDIE(&pfcpmsg.FTEID{TEID: fteid.TEID, IPv4: fteid.IPv4Address}),
+	)
+}
+
+func appendUnique(ids []uint32, id uint32) []uint32 {
+	for _, existing := range ids {
+		if existing == id {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
+
+// n3Address returns the IPv4 address advertised in allocated F-TEIDs
+func (s *PFCPServer) n3Address() net.IP {
+	if ip := net.ParseIP(s.config.N3.LocalAddress); ip != nil {
+		return ip.To4()
+	}
+	if ip := net.ParseIP(s.config.N3.BindAddress); ip != nil && !ip.IsUnspecified() {
+		return ip.To4()
+	}
+	return s.n4Address()
+}
@@ -2,61 +2,59 @@ package pfcp
 
 import (
 	"context"
-	"encoding/binary"
 	"fmt"
 	"net"
+	"sync"
 	"time"
 
+	"github.com/your-org/5g-network/common/dataplane"
+	"github.com/your-org/5g-network/common/netutil"
+	pfcpmsg "github.com/your-org/5g-network/common/pfcp"
 	"github.com/your-org/5g-network/nf/upf/internal/config"
 	upfcontext "github.com/your-org/5g-network/nf/upf/internal/context"
 	"go.uber.org/zap"
 )
 
-// PFCP Message Types (3GPP TS 29.244)
-const (
-	PFCP_HEARTBEAT_REQUEST              = 1
-	PFCP_HEARTBEAT_RESPONSE             = 2
-	PFCP_ASSOCIATION_SETUP_REQUEST      = 5
-	PFCP_ASSOCIATION_SETUP_RESPONSE     = 6
-	PFCP_SESSION_ESTABLISHMENT_REQUEST  = 50
-	PFCP_SESSION_ESTABLISHMENT_RESPONSE = 51
-	PFCP_SESSION_MODIFICATION_REQUEST   = 52
-	PFCP_SESSION_MODIFICATION_RESPONSE  = 53
-	PFCP_SESSION_DELETION_REQUEST       = 54
-	PFCP_SESSION_DELETION_RESPONSE      = 55
-)
-
 // PFCPServer handles PFCP protocol on N4 interface
 type PFCPServer struct {
 	config      *config.Config
 	conn        *net.UDPConn
 	upfContext  *upfcontext.UPFContext
 	logger      *zap.Logger
-	smfAddr     *net.UDPAddr
-	sequenceNum uint32
-}
+	sequenceNum uint32    // Last sequence number of a UPF initiated request
+	startedAt   time.Time // Reported as the Recovery Time Stamp
 
-// PFCPHeader represents PFCP message header
-type PFCPHeader struct {
-	Version        uint8
-	MessageType    uint8
-	MessageLength  uint16
-	SEID           uint64
-	SequenceNumber uint32
+	// PFCP associations keyed by SMF Node ID
+	assocMu      sync.Mutex
+	associations map[string]*smfAssociation
+
+	// Data plane the session rules are mirrored into, optional
+	dataPlane dataplane.DataPlane
+
+	// Sends End Markers on replaced downlink tunnels, optional
+	endMarkers EndMarkerSender
 }
 
 // NewPFCPServer creates a new PFCP server
 func NewPFCPServer(cfg *config.Config, upfCtx *upfcontext.UPFContext, logger *zap.Logger) *PFCPServer {
 	return &PFCPServer{
-		config:      cfg,
-		upfContext:  upfCtx,
-		logger:      logger,
-		sequenceNum: 1,
+		config:       cfg,
+		upfContext:   upfCtx,
+		logger:       logger,
+		startedAt:    time.Now(),
+		associations: make(map[string]*smfAssociation),
 	}
 }
 
-// Start starts the PFCP server
-func (s *PFCPServer) Start(ctx context.Context) error {
+// SetRecoveryTime sets the Recovery Time Stamp reported to SMFs, the server
+// creation time by default. Call it before Start.
+func (s *PFCPServer) SetRecoveryTime(t time.Time) {
+	s.startedAt = t
+}
+
+// Listen binds the N4 socket. Start calls it when the socket is not bound
+// yet; calling it first makes the bound address available via LocalAddr.
+func (s *PFCPServer) Listen() error {
 	addr, err := net.ResolveUDPAddr("udp", s.config.GetPFCPAddress())
 	if err != nil {
 		return fmt.Errorf("failed to resolve PFCP address: %w", err)
@@ -67,151 +65,266 @@ func (s *PFCPServer) Start(ctx context.Context) error {
 		return fmt.Errorf("failed to listen on PFCP port: %w", err)
 	}
 	s.conn = conn
+	return nil
+}
+
+// LocalAddr returns the bound N4 address, nil before Listen
+func (s *PFCPServer) LocalAddr() *net.UDPAddr {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.LocalAddr().(*net.UDPAddr)
+}
+
+// Start starts the PFCP server
+func (s *PFCPServer) Start(ctx context.Context) error {
+	if s.conn == nil {
+		if err := s.Listen(); err != nil {
+			return err
+		}
+	}
 
 	s.logger.Info("PFCP server started",
-		zap.String("address", s.config.GetPFCPAddress()),
+		zap.String("address", s.LocalAddr().String()),
 		zap.String("node_id", s.config.PFCP.NodeID))
 
-	// Handle incoming messages
-	go s.handleMessages(ctx)
-
 	// Send periodic heartbeats
 	go s.sendHeartbeats(ctx)
 
-	<-ctx.Done()
-	return conn.Close()
-}
-
-// handleMessages processes incoming PFCP messages
-func (s *PFCPServer) handleMessages(ctx context.Context) {
-	buffer := make([]byte, 65535)
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			n, addr, err := s.conn.ReadFromUDP(buffer)
-			if err != nil {
-				s.logger.Error("Failed to read PFCP message", zap.Error(err))
-				continue
-			}
-
-			// Parse header
-			if n < 16 {
-				s.logger.Warn("PFCP message too short", zap.Int("length", n))
-				continue
-			}
-
-			header := s.parsePFCPHeader(buffer[:n])
-			s.logger.Debug("Received PFCP message",
-				zap.Uint8("type", header.MessageType),
-				zap.Uint64("seid", header.SEID),
-				zap.String("from", addr.String()))
-
-			// Handle message based on type
-			s.handleMessage(header, buffer[:n], addr)
-		}
+	// Handle incoming messages until the context is cancelled
+	if err := netutil.ServeUDP(ctx, s.conn, 65535, s.handleDatagram, s.logger); err != nil {
+		return fmt.Errorf("PFCP server stopped: %w", err)
 	}
+	return nil
 }
 
-// parsePFCPHeader parses PFCP message header
-func (s *PFCPServer) parsePFCPHeader(data []byte) *PFCPHeader {
-	header := &PFCPHeader{
-		Version:       (data[0] >> 5) & 0x07,
-		MessageType:   data[1],
-		MessageLength: binary.BigEndian.Uint16(data[2:4]),
+// handleDatagram processes an incoming PFCP message
+func (s *PFCPServer) handleDatagram(data []byte, addr *net.UDPAddr) {
+	msg, err := pfcpmsg.Parse(data)
+	if err != nil {
+		s.logger.Warn("Malformed PFCP message", zap.String("from", addr.String()), zap.Error(err))
+		s.rejectMalformed(data, addr)
+		return
 	}
 
-	// Check if S flag is set (SEID present)
-	if (data[0] & 0x01) == 1 {
-		header.SEID = binary.BigEndian.Uint64(data[4:12])
-		header.SequenceNumber = binary.BigEndian.Uint32(data[12:15]) >> 8
-	} else {
-		header.SequenceNumber = binary.BigEndian.Uint32(data[4:7]) >> 8
-	}
+	s.logger.Debug("Received PFCP message",
+		zap.Uint8("type", msg.Header.MessageType),
+		zap.Uint64("seid", msg.Header.SEID),
+		zap.String("from", addr.String()))
 
-	return header
+	// Handle message based on type
+	s.handleMessage(msg, addr)
 }
 
 // handleMessage routes messages to appropriate handlers
-func (s *PFCPServer) handleMessage(header *PFCPHeader, data []byte, addr *net.UDPAddr) {
-	switch header.MessageType {
-	case PFCP_HEARTBEAT_REQUEST:
-		s.handleHeartbeatRequest(header, addr)
-	case PFCP_ASSOCIATION_SETUP_REQUEST:
-		s.handleAssociationSetupRequest(header, data, addr)
-	case PFCP_SESSION_ESTABLISHMENT_REQUEST:
-		s.handleSessionEstablishmentRequest(header, data, addr)
-	case PFCP_SESSION_MODIFICATION_REQUEST:
-		s.handleSessionModificationRequest(header, data, addr)
-	case PFCP_SESSION_DELETION_REQUEST:
-		s.handleSessionDeletionRequest(header, data, addr)
+func (s *PFCPServer) handleMessage(msg *pfcpmsg.Message, addr *net.UDPAddr) {
+	switch msg.Header.MessageType {
+	case pfcpmsg.PFCP_HEARTBEAT_REQUEST:
+		s.handleHeartbeatRequest(msg, addr)
+	case pfcpmsg.PFCP_HEARTBEAT_RESPONSE:
+		s.logger.Debug("Received heartbeat response", zap.String("from", addr.String()))
+	case pfcpmsg.PFCP_ASSOCIATION_SETUP_REQUEST:
+		s.handleAssociationSetupRequest(msg, addr)
+	case pfcpmsg.PFCP_ASSOCIATION_RELEASE_REQUEST:
+		s.handleAssociationReleaseRequest(msg, addr)
+	case pfcpmsg.PFCP_SESSION_ESTABLISHMENT_REQUEST:
+		s.handleSessionEstablishmentRequest(msg, addr)
+	case pfcpmsg.PFCP_SESSION_MODIFICATION_REQUEST:
+		s.handleSessionModificationRequest(msg, addr)
+	case pfcpmsg.PFCP_SESSION_DELETION_REQUEST:
+		s.handleSessionDeletionRequest(msg, addr)
+	case pfcpmsg.PFCP_SESSION_REPORT_RESPONSE:
+		s.handleSessionReportResponse(msg)
 	default:
-		s.logger.Warn("Unsupported PFCP message type", zap.Uint8("type", header.MessageType))
+		s.logger.Warn("Unsupported PFCP message type", zap.Uint8("type", msg.Header.MessageType))
 	}
 }
 
 // handleHeartbeatRequest handles PFCP heartbeat request
-func (s *PFCPServer) handleHeartbeatRequest(header *PFCPHeader, addr *net.UDPAddr) {
-	response := s.buildHeartbeatResponse(header.SequenceNumber)
+func (s *PFCPServer) handleHeartbeatRequest(msg *pfcpmsg.Message, addr *net.UDPAddr) {
+	response := pfcpmsg.NewMessage(pfcpmsg.PFCP_HEARTBEAT_RESPONSE, msg.Header.SequenceNumber,
+		pfcpmsg.NewRecoveryTimeStampIE(s.startedAt),
+	)
 	s.sendResponse(response, addr)
 	s.logger.Debug("Sent heartbeat response", zap.String("to", addr.String()))
 }
 
-// handleAssociationSetupRequest handles PFCP association setup
-func (s *PFCPServer) handleAssociationSetupRequest(header *PFCPHeader, data []byte, addr *net.UDPAddr) {
-	s.smfAddr = addr
-	response := s.buildAssociationSetupResponse(header.SequenceNumber)
-	s.sendResponse(response, addr)
-	s.logger.Info("PFCP association established", zap.String("smf", addr.String()))
-}
+// handleSessionEstablishmentRequest creates a session and installs the
+// requested PDRs, FARs and QERs
+func (s *PFCPServer) handleSessionEstablishmentRequest(msg *pfcpmsg.Message, addr *net.UDPAddr) {
+	seq := msg.Header.SequenceNumber
+
+	assoc, associated := s.associationFor(addr)
+	if !associated {
+		s.rejectSession(pfcpmsg.PFCP_SESSION_ESTABLISHMENT_RESPONSE, 0, seq, pfcpmsg.CAUSE_NO_ESTABLISHED_ASSOCIATION, addr)
+		return
+	}
 
-// handleSessionEstablishmentRequest handles session establishment
-func (s *PFCPServer) handleSessionEstablishmentRequest(header *PFCPHeader, data []byte, addr *net.UDPAddr) {
-	// Create new session
-	session := s.upfContext.CreateSession(header.SEID)
+	fseidIE := msg.FindIE(pfcpmsg.IE_F_SEID)
+	if fseidIE == nil {
+		s.rejectSession(pfcpmsg.PFCP_SESSION_ESTABLISHMENT_RESPONSE, 0, seq, pfcpmsg.CAUSE_MANDATORY_IE_MISSING, addr)
+		return
+	}
+	cpFSEID, err := fseidIE.FSEID()
+	if err != nil {
+		s.rejectSession(pfcpmsg.PFCP_SESSION_ESTABLISHMENT_RESPONSE, 0, seq, pfcpmsg.CAUSE_MANDATORY_IE_INCORRECT, addr)
+		return
+	}
 
-	// Allocate UPF F-TEID for N3
-	session.UPFTEID = s.upfContext.AllocateTEID()
+	// Create new session under a locally allocated SEID
+	session := s.upfContext.CreateSession(s.upfContext.AllocateSEID())
+	session.SMFSEID = cpFSEID.SEID
+	session.SMFNodeID = assoc.nodeID
 
-	s.logger.Info("PFCP session established",
-		zap.Uint64("seid", header.SEID),
-		zap.Uint32("upf_teid", session.UPFTEID))
+	created, err := s.applyRules(session, msg.IEs)
+	if err != nil {
+		s.upfContext.DeleteSession(session.SEID)
+		s.logger.Warn("PFCP session establishment rejected",
+			zap.Uint64("cp_seid", cpFSEID.SEID),
+			zap.Error(err))
+		s.rejectSession(pfcpmsg.PFCP_SESSION_ESTABLISHMENT_RESPONSE, cpFSEID.SEID, seq, causeOf(err), addr)
+		return
+	}
+	if err := s.syncDataPlane(session, ruleIDs{}); err != nil {
+		s.removeFromDataPlane(session.SEID)
+		s.upfContext.DeleteSession(session.SEID)
+		s.logger.Error("Failed to install session rules in data plane",
+			zap.Uint64("cp_seid", cpFSEID.SEID),
+			zap.Error(err))
+		s.rejectSession(pfcpmsg.PFCP_SESSION_ESTABLISHMENT_RESPONSE, cpFSEID.SEID, seq, pfcpmsg.CAUSE_SYSTEM_FAILURE, addr)
+		return
+	}
 
-	// Build and send response
-	response := s.buildSessionEstablishmentResponse(header.SequenceNumber, header.SEID, session.UPFTEID)
+	s.logger.Info("PFCP session established",
+		zap.Uint64("seid", session.SEID),
+		zap.Uint64("cp_seid", session.SMFSEID),
+		zap.Uint32("upf_teid", session.UPFTEID),
+		zap.Int("pdrs", len(session.PDRs)),
+		zap.Int("fars", len(session.FARs)),
+		zap.Int("qers", len(session.QERs)))
+
+	ies := []*pfcpmsg.IE{
+		s.nodeIDIE(),
+		pfcpmsg.NewCauseIE(pfcpmsg.CAUSE_REQUEST_ACCEPTED),
+		pfcpmsg.NewFSEIDIE(session.SEID, s.n4Address(), nil),
+	}
+	response := pfcpmsg.NewSessionMessage(pfcpmsg.PFCP_SESSION_ESTABLISHMENT_RESPONSE, session.SMFSEID, seq,
+		append(ies, created...)...)
 	s.sendResponse(response, addr)
 }
 
-// handleSessionModificationRequest handles session modification
-func (s *PFCPServer) handleSessionModificationRequest(header *PFCPHeader, data []byte, addr *net.UDPAddr) {
-	_, exists := s.upfContext.GetSession(header.SEID)
+// handleSessionModificationRequest applies rule changes to a session
+func (s *PFCPServer) handleSessionModificationRequest(msg *pfcpmsg.Message, addr *net.UDPAddr) {
+	seq := msg.Header.SequenceNumber
+
+	if _, associated := s.associationFor(addr); !associated {
+		s.rejectSession(pfcpmsg.PFCP_SESSION_MODIFICATION_RESPONSE, 0, seq, pfcpmsg.CAUSE_NO_ESTABLISHED_ASSOCIATION, addr)
+		return
+	}
+
+	session, exists := s.upfContext.GetSession(msg.Header.SEID)
 	if !exists {
-		s.logger.Error("Session not found", zap.Uint64("seid", header.SEID))
+		s.logger.Error("Session not found", zap.Uint64("seid", msg.Header.SEID))
+		s.rejectSession(pfcpmsg.PFCP_SESSION_MODIFICATION_RESPONSE, 0, seq, pfcpmsg.CAUSE_SESSION_CONTEXT_NOT_FOUND, addr)
 		return
 	}
 
-	// Update session (simplified - full implementation would parse IEs)
-	s.upfContext.UpdateActivity(header.SEID)
+	previous := sessionRuleIDs(session)
+	previousTunnel := sessionDownlinkTunnel(session)
+	created, err := s.applyRules(session, msg.IEs)
+	if err != nil {
+		s.logger.Warn("PFCP session modification rejected",
+			zap.Uint64("seid", session.SEID),
+			zap.Error(err))
+		s.rejectSession(pfcpmsg.PFCP_SESSION_MODIFICATION_RESPONSE, session.SMFSEID, seq, causeOf(err), addr)
+		return
+	}
+	if err := s.syncDataPlane(session, previous); err != nil {
+		s.logger.Error("Failed to update session rules in data plane",
+			zap.Uint64("seid", session.SEID),
+			zap.Error(err))
+		s.rejectSession(pfcpmsg.PFCP_SESSION_MODIFICATION_RESPONSE, session.SMFSEID, seq, pfcpmsg.CAUSE_SYSTEM_FAILURE, addr)
+		return
+	}
+	s.upfContext.UpdateActivity(session.SEID)
+	s.switchedFrom(session, previousTunnel)
 
-	s.logger.Info("PFCP session modified", zap.Uint64("seid", header.SEID))
+	s.logger.Info("PFCP session modified", zap.Uint64("seid", session.SEID))
 
-	response := s.buildSessionModificationResponse(header.SequenceNumber, header.SEID)
+	response := pfcpmsg.NewSessionMessage(pfcpmsg.PFCP_SESSION_MODIFICATION_RESPONSE, session.SMFSEID, seq,
+		append([]*pfcpmsg.IE{pfcpmsg.NewCauseIE(pfcpmsg.CAUSE_REQUEST_ACCEPTED)}, created...)...)
 	s.sendResponse(response, addr)
 }
 
 // handleSessionDeletionRequest handles session deletion
-func (s *PFCPServer) handleSessionDeletionRequest(header *PFCPHeader, data []byte, addr *net.UDPAddr) {
-	s.upfContext.DeleteSession(header.SEID)
+func (s *PFCPServer) handleSessionDeletionRequest(msg *pfcpmsg.Message, addr *net.UDPAddr) {
+	seq := msg.Header.SequenceNumber
+
+	if _, associated := s.associationFor(addr); !associated {
+		s.rejectSession(pfcpmsg.PFCP_SESSION_DELETION_RESPONSE, 0, seq, pfcpmsg.CAUSE_NO_ESTABLISHED_ASSOCIATION, addr)
+		return
+	}
+
+	session, exists := s.upfContext.GetSession(msg.Header.SEID)
+	if !exists {
+		s.rejectSession(pfcpmsg.PFCP_SESSION_DELETION_RESPONSE, 0, seq, pfcpmsg.CAUSE_SESSION_CONTEXT_NOT_FOUND, addr)
+		return
+	}
+	s.upfContext.DeleteSession(session.SEID)
+	s.removeFromDataPlane(session.SEID)
+
+	s.logger.Info("PFCP session deleted", zap.Uint64("seid", session.SEID))
+
+	response := pfcpmsg.NewSessionMessage(pfcpmsg.PFCP_SESSION_DELETION_RESPONSE, session.SMFSEID, seq,
+		pfcpmsg.NewCauseIE(pfcpmsg.CAUSE_REQUEST_ACCEPTED),
+	)
+	s.sendResponse(response, addr)
+}
+
+// rejectMalformed answers a request whose header decodes but whose length or
+// IEs do not with Mandatory IE incorrect (TS 29.244 7.6). Messages
+// without a decodable header, and requests answered without a cause, are
+// only dropped.
+func (s *PFCPServer) rejectMalformed(data []byte, addr *net.UDPAddr) {
+	header, _, err := pfcpmsg.ParseHeader(data)
+	if err != nil {
+		return
+	}
+	seq := header.SequenceNumber
+	cause := uint8(pfcpmsg.CAUSE_MANDATORY_IE_INCORRECT)
 
-	s.logger.Info("PFCP session deleted", zap.Uint64("seid", header.SEID))
+	switch header.MessageType {
+	case pfcpmsg.PFCP_ASSOCIATION_SETUP_REQUEST:
+		s.sendAssociationResponse(pfcpmsg.PFCP_ASSOCIATION_SETUP_RESPONSE, &pfcpmsg.Message{Header: *header}, cause, addr)
+	case pfcpmsg.PFCP_ASSOCIATION_RELEASE_REQUEST:
+		s.sendAssociationResponse(pfcpmsg.PFCP_ASSOCIATION_RELEASE_RESPONSE, &pfcpmsg.Message{Header: *header}, cause, addr)
+	case pfcpmsg.PFCP_SESSION_ESTABLISHMENT_REQUEST:
+		s.rejectSession(pfcpmsg.PFCP_SESSION_ESTABLISHMENT_RESPONSE, 0, seq, cause, addr)
+	case pfcpmsg.PFCP_SESSION_MODIFICATION_REQUEST:
+		s.rejectSession(pfcpmsg.PFCP_SESSION_MODIFICATION_RESPONSE, s.cpSEIDOf(header.SEID), seq, cause, addr)
+	case pfcpmsg.PFCP_SESSION_DELETION_REQUEST:
+		s.rejectSession(pfcpmsg.PFCP_SESSION_DELETION_RESPONSE, s.cpSEIDOf(header.SEID), seq, cause, addr)
+	}
+}
+
+// cpSEIDOf returns the SMF SEID of a session, 0 when it does not exist
+func (s *PFCPServer) cpSEIDOf(seid uint64) uint64 {
+	if session, exists := s.upfContext.GetSession(seid); exists {
+		return session.SMFSEID
+	}
+	return 0
+}
 
-	response := s.buildSessionDeletionResponse(header.SequenceNumber, header.SEID)
+// rejectSession answers a session request with a rejection cause
+func (s *PFCPServer) rejectSession(msgType uint8, cpSEID uint64, seq uint32, cause uint8, addr *net.UDPAddr) {
+	response := pfcpmsg.NewSessionMessage(msgType, cpSEID, seq, pfcpmsg.NewCauseIE(cause))
+	if msgType == pfcpmsg.PFCP_SESSION_ESTABLISHMENT_RESPONSE {
+		response.IEs = append([]*pfcpmsg.IE{s.nodeIDIE()}, response.IEs...)
+	}
 	s.sendResponse(response, addr)
 }
 
-// sendHeartbeats sends periodic heartbeats to SMF
+// sendHeartbeats sends periodic heartbeats to the associated SMFs
 func (s *PFCPServer) sendHeartbeats(ctx context.Context) {
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
@@ -221,114 +334,37 @@ func (s *PFCPServer) sendHeartbeats(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			if s.smfAddr != nil {
-				request := s.buildHeartbeatRequest()
-				s.sendResponse(request, s.smfAddr)
+			for _, addr := range s.associatedSMFs() {
+				request := pfcpmsg.NewMessage(pfcpmsg.PFCP_HEARTBEAT_REQUEST, s.nextSequence(),
+					pfcpmsg.NewRecoveryTimeStampIE(s.startedAt),
+				)
+				s.sendResponse(request, addr)
 			}
 		}
 	}
 }
 
-// Helper functions to build PFCP messages (simplified)
-func (s *PFCPServer) buildHeartbeatResponse(seqNum uint32) []byte {
-	msg := make([]byte, 12)
-	msg[0] = 0x20 // Version 1, no S flag
-	msg[1] = PFCP_HEARTBEAT_RESPONSE
-	binary.BigEndian.PutUint16(msg[2:4], 8) // Length
-	msg[4] = byte(seqNum >> 16)
-	msg[5] = byte(seqNum >> 8)
-	msg[6] = byte(seqNum)
-	return msg
-}
-
-func (s *PFCPServer) buildHeartbeatRequest() []byte {
-	msg := make([]byte, 12)
-	msg[0] = 0x20
-	msg[1] = PFCP_HEARTBEAT_REQUEST
-	binary.BigEndian.PutUint16(msg[2:4], 8)
-	seqNum := s.sequenceNum
-	s.sequenceNum++
-	msg[4] = byte(seqNum >> 16)
-	msg[5] = byte(seqNum >> 8)
-	msg[6] = byte(seqNum)
-	return msg
-}
-
-func (s *PFCPServer) buildAssociationSetupResponse(seqNum uint32) []byte {
-	msg := make([]byte, 20)
-	msg[0] = 0x20
-	msg[1] = PFCP_ASSOCIATION_SETUP_RESPONSE
-	binary.BigEndian.PutUint16(msg[2:4], 16)
-	msg[4] = byte(seqNum >> 16)
-	msg[5] = byte(seqNum >> 8)
-	msg[6] = byte(seqNum)
-	// Add Cause IE: Request accepted
-	msg[8] = 0x00
-	msg[9] = 0x13 // Cause IE type
-	binary.BigEndian.PutUint16(msg[10:12], 1)
-	msg[12] = 0x01 // Cause: Request accepted
-	return msg
-}
-
-func (s *PFCPServer) buildSessionEstablishmentResponse(seqNum uint32, seid uint64, teid uint32) []byte {
-	msg := make([]byte, 32)
-	msg[0] = 0x21 // Version 1, S flag set
-	msg[1] = PFCP_SESSION_ESTABLISHMENT_RESPONSE
-	binary.BigEndian.PutUint16(msg[2:4], 28)
-	binary.BigEndian.PutUint64(msg[4:12], seid)
-	msg[12] = byte(seqNum >> 16)
-	msg[13] = byte(seqNum >> 8)
-	msg[14] = byte(seqNum)
-	// Add Cause IE
-	msg[16] = 0x00
-	msg[17] = 0x13
-	binary.BigEndian.PutUint16(msg[18:20], 1)
-	msg[20] = 0x01 // Accepted
-	// Add F-TEID IE (simplified)
-	msg[22] = 0x00
-	msg[23] = 0x15 // F-TEID type
-	binary.BigEndian.PutUint16(msg[24:26], 5)
-	msg[26] = 0x01 // CH flag
-	binary.BigEndian.PutUint32(msg[27:31], teid)
-	return msg
-}
-
-func (s *PFCPServer) buildSessionModificationResponse(seqNum uint32, seid uint64) []byte {
-	msg := make([]byte, 24)
-	msg[0] = 0x21
-	msg[1] = PFCP_SESSION_MODIFICATION_RESPONSE
-	binary.BigEndian.PutUint16(msg[2:4], 20)
-	binary.BigEndian.PutUint64(msg[4:12], seid)
-	msg[12] = byte(seqNum >> 16)
-	msg[13] = byte(seqNum >> 8)
-	msg[14] = byte(seqNum)
-	// Cause
-	msg[16] = 0x00
-	msg[17] = 0x13
-	binary.BigEndian.PutUint16(msg[18:20], 1)
-	msg[20] = 0x01
-	return msg
+// nodeIDIE returns the UPF Node ID IE
+func (s *PFCPServer) nodeIDIE() *pfcpmsg.IE {
+	if s.config.PFCP.NodeID != "" {
+		return pfcpmsg.NewNodeIDIE(s.config.PFCP.NodeID)
+	}
+	return pfcpmsg.NewNodeIDIE(s.n4Address().String())
 }
 
-func (s *PFCPServer) buildSessionDeletionResponse(seqNum uint32, seid uint64) []byte {
-	msg := make([]byte, 24)
-	msg[0] = 0x21
-	msg[1] = PFCP_SESSION_DELETION_RESPONSE
-	binary.BigEndian.PutUint16(msg[2:4], 20)
-	binary.BigEndian.PutUint64(msg[4:12], seid)
-	msg[12] = byte(seqNum >> 16)
-	msg[13] = byte(seqNum >> 8)
-	msg[14] = byte(seqNum)
-	// Cause
-	msg[16] = 0x00
-	msg[17] = 0x13
-	binary.BigEndian.PutUint16(msg[18:20], 1)
-	msg[20] = 0x01
-	return msg
+// n4Address returns the IPv4 address advertised in the UP F-SEID
+func (s *PFCPServer) n4Address() net.IP {
+	if ip := net.ParseIP(s.config.PFCP.AdvertisedAddress()); ip != nil {
+		return ip.To4()
+	}
+	if addr := s.LocalAddr(); addr != nil && !addr.IP.IsUnspecified() {
+		return addr.IP.To4()
+	}
+	return net.IPv4(127, 0, 0, 1).To4()
 }
 
-func (s *PFCPServer) sendResponse(msg []byte, addr *net.UDPAddr) {
-	_, err := s.conn.WriteToUDP(msg, addr)
+func (s *PFCPServer) sendResponse(msg *pfcpmsg.Message, addr *net.UDPAddr) {
+	_, err := s.conn.WriteToUDP(msg.Marshal(), addr)
 	if err != nil {
 		s.logger.Error("Failed to send PFCP response", zap.Error(err))
 	}
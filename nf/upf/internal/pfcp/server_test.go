@@ -0,0 +1,1003 @@
+package pfcp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/common/dataplane"
+	pfcpmsg "github.com/your-org/5g-network/common/pfcp"
+	"github.com/your-org/5g-network/nf/upf/internal/config"
+	upfcontext "github.com/your-org/5g-network/nf/upf/internal/context"
+	"github.com/your-org/5g-network/nf/upf/internal/dataplane/simulated"
+)
+
+const (
+	testN3IP   = "192.168.1.1"
+	testUEIP   = "10.60.0.7"
+	testGNBIP  = "192.168.1.10"
+	testDNN    = "internet"
+	testCPSEID = 0x5eed
+)
+
+func newTestServer(t *testing.T) *PFCPServer {
+	t.Helper()
+
+	logger, _ := zap.NewDevelopment()
+	cfg := &config.Config{
+		PFCP: config.PFCPConfig{
+			BindAddress: "127.0.0.1",
+			Port:        0,
+			NodeID:      "upf.test",
+		},
+		N3: config.N3Config{LocalAddress: testN3IP},
+	}
+	return NewPFCPServer(cfg, upfcontext.NewUPFContext(), logger)
+}
+
+func TestStartReturnsPromptlyOnCancel(t *testing.T) {
+	server := newTestServer(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Start(ctx)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("PFCP server did not stop after context cancellation")
+	}
+}
+
+// startTestServer runs a server backed by a simulated data plane and returns
+// a socket connected to it. The setup functions run before the server starts.
+func startTestServer(t *testing.T, setup ...func(*PFCPServer)) (*PFCPServer, *simulated.SimulatedDataPlane, *net.UDPConn) {
+	t.Helper()
+
+	server := newTestServer(t)
+	logger, _ := zap.NewDevelopment()
+	dp := simulated.NewSimulatedDataPlane(logger)
+	require.NoError(t, dp.Initialize(context.Background(), &dataplane.Config{Workers: 1}))
+	t.Cleanup(func() { dp.Shutdown(context.Background()) })
+	server.SetDataPlane(dp)
+	for _, fn := range setup {
+		fn(server)
+	}
+
+	require.NoError(t, server.Listen())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.Start(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	conn, err := net.DialUDP("udp", nil, server.LocalAddr())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return server, dp, conn
+}
+
+// exchange sends a request and waits for its response
+func exchange(t *testing.T, conn *net.UDPConn, msg *pfcpmsg.Message) *pfcpmsg.Message {
+	t.Helper()
+
+	_, err := conn.Write(msg.Marshal())
+	require.NoError(t, err)
+
+	buf := make([]byte, 4096)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	resp, err := pfcpmsg.Parse(buf[:n])
+	require.NoError(t, err)
+	assert.Equal(t, msg.Header.SequenceNumber, resp.Header.SequenceNumber)
+	return resp
+}
+
+func requireCause(t *testing.T, msg *pfcpmsg.Message, want uint8) {
+	t.Helper()
+	ie := msg.FindIE(pfcpmsg.IE_CAUSE)
+	require.NotNil(t, ie)
+	cause, err := ie.Uint8()
+	require.NoError(t, err)
+	require.Equal(t, want, cause)
+}
+
+// establishmentRequest is a session establishment request for a PDU session
+// with one QoS flow, as encoded by the SMF
+func establishmentRequest() *pfcpmsg.Message {
+	ueIP := net.ParseIP(testUEIP)
+	return pfcpmsg.NewSessionMessage(pfcpmsg.PFCP_SESSION_ESTABLISHMENT_REQUEST, 0, 2,
+		pfcpmsg.NewNodeIDIE("127.0.0.1"),
+		pfcpmsg.NewFSEIDIE(testCPSEID, net.ParseIP("127.0.0.1"), nil),
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_CREATE_PDR,
+			pfcpmsg.NewUint16IE(pfcpmsg.IE_PDR_ID, 1),
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_PRECEDENCE, 100),
+			pfcpmsg.NewGroupedIE(pfcpmsg.IE_PDI,
+				pfcpmsg.NewUint8IE(pfcpmsg.IE_SOURCE_INTERFACE, pfcpmsg.INTERFACE_ACCESS),
+				pfcpmsg.NewFTEIDIE(&pfcpmsg.FTEID{ChooseID: true, ChooseV4: true, HasCHID: true, CHID: 1}),
+				pfcpmsg.NewIE(pfcpmsg.IE_NETWORK_INSTANCE, []byte(testDNN)),
+				pfcpmsg.NewUEIPAddressIE(&pfcpmsg.UEIPAddress{IPv4: ueIP}),
+				pfcpmsg.NewUint8IE(pfcpmsg.IE_QFI, 1),
+			),
+			pfcpmsg.NewUint8IE(pfcpmsg.IE_OUTER_HEADER_REMOVAL, pfcpmsg.OUTER_HEADER_REMOVAL_GTPU_UDP_IPV4),
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_FAR_ID, 1),
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_QER_ID, 1),
+		),
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_CREATE_PDR,
+			pfcpmsg.NewUint16IE(pfcpmsg.IE_PDR_ID, 2),
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_PRECEDENCE, 100),
+			pfcpmsg.NewGroupedIE(pfcpmsg.IE_PDI,
+				pfcpmsg.NewUint8IE(pfcpmsg.IE_SOURCE_INTERFACE, pfcpmsg.INTERFACE_CORE),
+				pfcpmsg.NewIE(pfcpmsg.IE_NETWORK_INSTANCE, []byte(testDNN)),
+				pfcpmsg.NewUEIPAddressIE(&pfcpmsg.UEIPAddress{IPv4: ueIP, IsDestination: true}),
+			),
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_FAR_ID, 2),
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_QER_ID, 1),
+		),
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_CREATE_FAR,
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_FAR_ID, 1),
+			pfcpmsg.NewUint8IE(pfcpmsg.IE_APPLY_ACTION, pfcpmsg.APPLY_ACTION_FORW),
+			pfcpmsg.NewGroupedIE(pfcpmsg.IE_FORWARDING_PARAMETERS,
+				pfcpmsg.NewUint8IE(pfcpmsg.IE_DESTINATION_INTERFACE, pfcpmsg.INTERFACE_CORE),
+				pfcpmsg.NewIE(pfcpmsg.IE_NETWORK_INSTANCE, []byte(testDNN)),
+			),
+		),
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_CREATE_FAR,
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_FAR_ID, 2),
+			pfcpmsg.NewUint8IE(pfcpmsg.IE_APPLY_ACTION, pfcpmsg.APPLY_ACTION_FORW),
+			pfcpmsg.NewGroupedIE(pfcpmsg.IE_FORWARDING_PARAMETERS,
+				pfcpmsg.NewUint8IE(pfcpmsg.IE_DESTINATION_INTERFACE, pfcpmsg.INTERFACE_ACCESS),
+				pfcpmsg.NewOuterHeaderCreationIE(&pfcpmsg.OuterHeaderCreation{
+					Description: pfcpmsg.OUTER_HEADER_CREATION_GTPU_UDP_IPV4,
+					TEID:        0x100,
+					IPv4:        net.ParseIP(testGNBIP),
+				}),
+			),
+		),
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_CREATE_QER,
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_QER_ID, 1),
+			pfcpmsg.NewGateStatusIE(pfcpmsg.GATE_STATUS_OPEN),
+			pfcpmsg.NewBitRateIE(pfcpmsg.IE_MBR, &pfcpmsg.BitRate{Uplink: 100_000_000, Downlink: 200_000_000}),
+		),
+	)
+}
+
+func TestSessionEstablishment_InstallsRules(t *testing.T) {
+	server, dp, conn := startTestServer(t)
+
+	resp := exchange(t, conn, pfcpmsg.NewMessage(pfcpmsg.PFCP_ASSOCIATION_SETUP_REQUEST, 1,
+		pfcpmsg.NewNodeIDIE("127.0.0.1"),
+		pfcpmsg.NewRecoveryTimeStampIE(time.Now()),
+	))
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+
+	resp = exchange(t, conn, establishmentRequest())
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+	assert.Equal(t, uint64(testCPSEID), resp.Header.SEID)
+
+	// The response carries the UP F-SEID and the allocated N3 F-TEID
+	fseid, err := resp.FindIE(pfcpmsg.IE_F_SEID).FSEID()
+	require.NoError(t, err)
+	createdPDR := resp.FindIE(pfcpmsg.IE_CREATED_PDR)
+	require.NotNil(t, createdPDR)
+	children, err := createdPDR.ChildIEs()
+	require.NoError(t, err)
+	fteid, err := pfcpmsg.FindIE(children, pfcpmsg.IE_F_TEID).FTEID()
+	require.NoError(t, err)
+	assert.NotZero(t, fteid.TEID)
+	assert.True(t, fteid.IPv4.Equal(net.ParseIP(testN3IP)))
+
+	session, ok := server.upfContext.GetSession(fseid.SEID)
+	require.True(t, ok)
+	assert.Equal(t, uint64(testCPSEID), session.SMFSEID)
+	assert.True(t, session.UEAddress.Equal(net.ParseIP(testUEIP)))
+	assert.Equal(t, testDNN, session.DNN)
+	assert.Equal(t, fteid.TEID, session.UPFTEID)
+	assert.Equal(t, uint32(0x100), session.GNBTEID)
+	assert.True(t, session.GNBAddress.Equal(net.ParseIP(testGNBIP)))
+
+	require.Len(t, session.PDRs, 2)
+	uplink := session.PDRs[0]
+	assert.Equal(t, uint16(1), uplink.PDRID)
+	assert.Equal(t, uint32(100), uplink.Precedence)
+	assert.Equal(t, uint8(pfcpmsg.INTERFACE_ACCESS), uplink.PDI.SourceInterface)
+	require.NotNil(t, uplink.PDI.FTEID)
+	assert.Equal(t, fteid.TEID, uplink.PDI.FTEID.TEID)
+	assert.Equal(t, uint8(1), uplink.PDI.QFI)
+	assert.Equal(t, uint32(1), uplink.FARID)
+	assert.Equal(t, []uint32{1}, uplink.QERIDs)
+	downlink := session.PDRs[1]
+	assert.Equal(t, uint8(pfcpmsg.INTERFACE_CORE), downlink.PDI.SourceInterface)
+	assert.True(t, downlink.PDI.UEIPAddress.Equal(net.ParseIP(testUEIP)))
+	assert.Nil(t, downlink.PDI.FTEID)
+	assert.Equal(t, uint32(2), downlink.FARID)
+
+	require.Len(t, session.FARs, 2)
+	assert.Equal(t, uint8(pfcpmsg.APPLY_ACTION_FORW), session.FARs[0].ApplyAction)
+	assert.Equal(t, uint8(pfcpmsg.INTERFACE_CORE), session.FARs[0].ForwardingParameters.DestinationInterface)
+	assert.Nil(t, session.FARs[0].ForwardingParameters.OuterHeaderCreation)
+	require.NotNil(t, session.FARs[1].ForwardingParameters.OuterHeaderCreation)
+	assert.Equal(t, uint32(0x100), session.FARs[1].ForwardingParameters.OuterHeaderCreation.TEID)
+
+	require.Len(t, session.QERs, 1)
+	require.NotNil(t, session.QERs[0].MBR)
+	assert.Equal(t, uint64(100_000_000), session.QERs[0].MBR.Uplink)
+
+	// Packets are matched against the installed rules
+	ctx := context.Background()
+	require.NoError(t, dp.ProcessPacket(ctx, &dataplane.Packet{
+		Data: make([]byte, 100), Interface: "N3", TEID: fteid.TEID, QFI: 1,
+		SrcIP: net.ParseIP(testUEIP), DstIP: net.ParseIP("8.8.8.8"),
+	}))
+	require.NoError(t, dp.ProcessPacket(ctx, &dataplane.Packet{
+		Data: make([]byte, 100), Interface: "N6",
+		SrcIP: net.ParseIP("8.8.8.8"), DstIP: net.ParseIP(testUEIP),
+	}))
+	require.NoError(t, dp.ProcessPacket(ctx, &dataplane.Packet{
+		Data: make([]byte, 100), Interface: "N3", TEID: fteid.TEID + 1,
+		SrcIP: net.ParseIP(testUEIP), DstIP: net.ParseIP("8.8.8.8"),
+	}))
+	require.Eventually(t, func() bool {
+		stats, _ := dp.GetStats(ctx)
+		return stats.PacketsForwarded == 2 && stats.PacketsDropped == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// Deletion removes the session from the data plane
+	resp = exchange(t, conn, pfcpmsg.NewSessionMessage(pfcpmsg.PFCP_SESSION_DELETION_REQUEST, fseid.SEID, 3))
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+	stats, err := dp.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, stats.ActiveSessions)
+}
+
+func TestSessionModification_SyncsDataPlane(t *testing.T) {
+	server, dp, conn := startTestServer(t)
+
+	exchange(t, conn, pfcpmsg.NewMessage(pfcpmsg.PFCP_ASSOCIATION_SETUP_REQUEST, 1,
+		pfcpmsg.NewNodeIDIE("127.0.0.1"),
+		pfcpmsg.NewRecoveryTimeStampIE(time.Now()),
+	))
+	resp := exchange(t, conn, establishmentRequest())
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+	fseid, err := resp.FindIE(pfcpmsg.IE_F_SEID).FSEID()
+	require.NoError(t, err)
+
+	// Remove the downlink PDR; downlink packets no longer match
+	resp = exchange(t, conn, pfcpmsg.NewSessionMessage(pfcpmsg.PFCP_SESSION_MODIFICATION_REQUEST, fseid.SEID, 3,
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_REMOVE_PDR, pfcpmsg.NewUint16IE(pfcpmsg.IE_PDR_ID, 2)),
+	))
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+
+	session, ok := server.upfContext.GetSession(fseid.SEID)
+	require.True(t, ok)
+	assert.Len(t, session.PDRs, 1)
+
+	ctx := context.Background()
+	require.NoError(t, dp.ProcessPacket(ctx, &dataplane.Packet{
+		Data: make([]byte, 100), Interface: "N6",
+		SrcIP: net.ParseIP("8.8.8.8"), DstIP: net.ParseIP(testUEIP),
+	}))
+	require.Eventually(t, func() bool {
+		stats, _ := dp.GetStats(ctx)
+		return stats.PacketsDropped == 1 && stats.PacketsForwarded == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSessionEstablishment_DualStackUE(t *testing.T) {
+	server, _, conn := startTestServer(t)
+
+	exchange(t, conn, pfcpmsg.NewMessage(pfcpmsg.PFCP_ASSOCIATION_SETUP_REQUEST, 1,
+		pfcpmsg.NewNodeIDIE("127.0.0.1"),
+		pfcpmsg.NewRecoveryTimeStampIE(time.Now()),
+	))
+	resp := exchange(t, conn, pfcpmsg.NewSessionMessage(pfcpmsg.PFCP_SESSION_ESTABLISHMENT_REQUEST, 0, 2,
+		pfcpmsg.NewNodeIDIE("127.0.0.1"),
+		pfcpmsg.NewFSEIDIE(testCPSEID, net.ParseIP("127.0.0.1"), nil),
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_CREATE_PDR,
+			pfcpmsg.NewUint16IE(pfcpmsg.IE_PDR_ID, 1),
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_PRECEDENCE, 100),
+			pfcpmsg.NewGroupedIE(pfcpmsg.IE_PDI,
+				pfcpmsg.NewUint8IE(pfcpmsg.IE_SOURCE_INTERFACE, pfcpmsg.INTERFACE_CORE),
+				pfcpmsg.NewUEIPAddressIE(&pfcpmsg.UEIPAddress{
+					IPv4:          net.ParseIP(testUEIP),
+					IPv6:          net.ParseIP("2001:db8:1:2::1"),
+					IsDestination: true,
+				}),
+			),
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_FAR_ID, 1),
+		),
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_CREATE_FAR,
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_FAR_ID, 1),
+			pfcpmsg.NewUint8IE(pfcpmsg.IE_APPLY_ACTION, pfcpmsg.APPLY_ACTION_FORW),
+		),
+	))
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+	fseid, err := resp.FindIE(pfcpmsg.IE_F_SEID).FSEID()
+	require.NoError(t, err)
+
+	// Downlink to the whole /64 of the UE belongs to the session
+	session, ok := server.upfContext.GetSession(fseid.SEID)
+	require.True(t, ok)
+	assert.True(t, session.UEAddress.Equal(net.ParseIP(testUEIP)))
+	require.NotNil(t, session.UEIPv6Prefix)
+	assert.Equal(t, "2001:db8:1:2::/64", session.UEIPv6Prefix.String())
+}
+
+func TestAssociation_SMFRestartAndRelease(t *testing.T) {
+	server, dp, conn := startTestServer(t)
+	smfStarted := time.Now().Add(-time.Hour)
+
+	resp := exchange(t, conn, pfcpmsg.NewMessage(pfcpmsg.PFCP_ASSOCIATION_SETUP_REQUEST, 1,
+		pfcpmsg.NewNodeIDIE("127.0.0.1"),
+		pfcpmsg.NewRecoveryTimeStampIE(smfStarted),
+	))
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+	recoveryTime, err := resp.FindIE(pfcpmsg.IE_RECOVERY_TIME_STAMP).RecoveryTimeStamp()
+	require.NoError(t, err)
+	assert.Equal(t, server.startedAt.Unix(), recoveryTime.Unix())
+
+	requireCause(t, exchange(t, conn, establishmentRequest()), pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+	require.Len(t, server.upfContext.GetAllSessions(), 1)
+	assert.Equal(t, "127.0.0.1", server.upfContext.GetAllSessions()[0].SMFNodeID)
+
+	// Setup again with the same Recovery Time Stamp keeps the sessions
+	exchange(t, conn, pfcpmsg.NewMessage(pfcpmsg.PFCP_ASSOCIATION_SETUP_REQUEST, 3,
+		pfcpmsg.NewNodeIDIE("127.0.0.1"),
+		pfcpmsg.NewRecoveryTimeStampIE(smfStarted),
+	))
+	assert.Len(t, server.upfContext.GetAllSessions(), 1)
+
+	// A new Recovery Time Stamp means the SMF restarted
+	exchange(t, conn, pfcpmsg.NewMessage(pfcpmsg.PFCP_ASSOCIATION_SETUP_REQUEST, 4,
+		pfcpmsg.NewNodeIDIE("127.0.0.1"),
+		pfcpmsg.NewRecoveryTimeStampIE(time.Now()),
+	))
+	assert.Empty(t, server.upfContext.GetAllSessions())
+
+	// Release deletes the sessions and ends the association
+	requireCause(t, exchange(t, conn, establishmentRequest()), pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+	resp = exchange(t, conn, pfcpmsg.NewMessage(pfcpmsg.PFCP_ASSOCIATION_RELEASE_REQUEST, 6,
+		pfcpmsg.NewNodeIDIE("127.0.0.1"),
+	))
+	assert.Equal(t, uint8(pfcpmsg.PFCP_ASSOCIATION_RELEASE_RESPONSE), resp.Header.MessageType)
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+	assert.Empty(t, server.upfContext.GetAllSessions())
+	stats, err := dp.GetStats(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, stats.ActiveSessions)
+
+	resp = exchange(t, conn, establishmentRequest())
+	requireCause(t, resp, pfcpmsg.CAUSE_NO_ESTABLISHED_ASSOCIATION)
+	resp = exchange(t, conn, pfcpmsg.NewMessage(pfcpmsg.PFCP_ASSOCIATION_RELEASE_REQUEST, 8,
+		pfcpmsg.NewNodeIDIE("127.0.0.1"),
+	))
+	requireCause(t, resp, pfcpmsg.CAUSE_NO_ESTABLISHED_ASSOCIATION)
+}
+
+func TestBufferingFAR_ReportsDownlinkData(t *testing.T) {
+	_, dp, conn := startTestServer(t)
+
+	exchange(t, conn, pfcpmsg.NewMessage(pfcpmsg.PFCP_ASSOCIATION_SETUP_REQUEST, 1,
+		pfcpmsg.NewNodeIDIE("127.0.0.1"),
+		pfcpmsg.NewRecoveryTimeStampIE(time.Now()),
+	))
+	resp := exchange(t, conn, establishmentRequest())
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+	fseid, err := resp.FindIE(pfcpmsg.IE_F_SEID).FSEID()
+	require.NoError(t, err)
+
+	// The AN is released: buffer downlink packets and notify the SMF
+	resp = exchange(t, conn, pfcpmsg.NewSessionMessage(pfcpmsg.PFCP_SESSION_MODIFICATION_REQUEST, fseid.SEID, 3,
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_UPDATE_FAR,
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_FAR_ID, 2),
+			pfcpmsg.NewUint8IE(pfcpmsg.IE_APPLY_ACTION, pfcpmsg.APPLY_ACTION_BUFF|pfcpmsg.APPLY_ACTION_NOCP),
+		),
+	))
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+
+	ctx := context.Background()
+	downlink := func() {
+		require.NoError(t, dp.ProcessPacket(ctx, &dataplane.Packet{
+			Data: make([]byte, 100), Interface: "N6",
+			SrcIP: net.ParseIP("8.8.8.8"), DstIP: net.ParseIP(testUEIP),
+		}))
+	}
+	downlink()
+	downlink()
+
+	// Only the first packet is reported
+	buf := make([]byte, 4096)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	report, err := pfcpmsg.Parse(buf[:n])
+	require.NoError(t, err)
+	assert.Equal(t, uint8(pfcpmsg.PFCP_SESSION_REPORT_REQUEST), report.Header.MessageType)
+	assert.Equal(t, uint64(testCPSEID), report.Header.SEID)
+
+	reportType, err := report.FindIE(pfcpmsg.IE_REPORT_TYPE).Uint8()
+	require.NoError(t, err)
+	assert.Equal(t, uint8(pfcpmsg.REPORT_TYPE_DLDR), reportType)
+	dldr, err := report.FindIE(pfcpmsg.IE_DOWNLINK_DATA_REPORT).DownlinkDataReport()
+	require.NoError(t, err)
+	assert.Equal(t, uint16(2), dldr.PDRID)
+
+	_, err = conn.Write(pfcpmsg.NewSessionMessage(pfcpmsg.PFCP_SESSION_REPORT_RESPONSE, fseid.SEID,
+		report.Header.SequenceNumber, pfcpmsg.NewCauseIE(pfcpmsg.CAUSE_REQUEST_ACCEPTED)).Marshal())
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		stats, _ := dp.GetStats(ctx)
+		return stats.PacketsBuffered == 2
+	}, time.Second, 10*time.Millisecond)
+
+	// The UE is reachable again: forwarding releases the buffer
+	resp = exchange(t, conn, pfcpmsg.NewSessionMessage(pfcpmsg.PFCP_SESSION_MODIFICATION_REQUEST, fseid.SEID, 4,
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_UPDATE_FAR,
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_FAR_ID, 2),
+			pfcpmsg.NewUint8IE(pfcpmsg.IE_APPLY_ACTION, pfcpmsg.APPLY_ACTION_FORW),
+		),
+	))
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+	stats, err := dp.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), stats.PacketsForwarded)
+}
+
+func TestErrorIndication_ReportsToSMF(t *testing.T) {
+	server, _, conn := startTestServer(t)
+
+	exchange(t, conn, pfcpmsg.NewMessage(pfcpmsg.PFCP_ASSOCIATION_SETUP_REQUEST, 1,
+		pfcpmsg.NewNodeIDIE("127.0.0.1"),
+		pfcpmsg.NewRecoveryTimeStampIE(time.Now()),
+	))
+	resp := exchange(t, conn, establishmentRequest())
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+	fseid, err := resp.FindIE(pfcpmsg.IE_F_SEID).FSEID()
+	require.NoError(t, err)
+
+	// The GTP-U handler received an Error Indication for the gNB tunnel
+	server.HandleSessionReport(&dataplane.SessionReport{
+		SessionID: fseid.SEID,
+		ErrorIndication: &dataplane.ErrorIndicationReport{
+			RemoteFTEID: dataplane.FTEID{TEID: 0x100, IPv4: net.ParseIP(testGNBIP).To4()},
+		},
+	})
+
+	buf := make([]byte, 4096)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	report, err := pfcpmsg.Parse(buf[:n])
+	require.NoError(t, err)
+	assert.Equal(t, uint8(pfcpmsg.PFCP_SESSION_REPORT_REQUEST), report.Header.MessageType)
+	assert.Equal(t, uint64(testCPSEID), report.Header.SEID)
+
+	reportType, err := report.FindIE(pfcpmsg.IE_REPORT_TYPE).Uint8()
+	require.NoError(t, err)
+	assert.Equal(t, uint8(pfcpmsg.REPORT_TYPE_ERIR), reportType)
+	erir, err := report.FindIE(pfcpmsg.IE_ERROR_INDICATION_REPORT).ErrorIndicationReport()
+	require.NoError(t, err)
+	require.Len(t, erir.RemoteFTEIDs, 1)
+	assert.Equal(t, uint32(0x100), erir.RemoteFTEIDs[0].TEID)
+	assert.Equal(t, net.ParseIP(testGNBIP).To4(), erir.RemoteFTEIDs[0].IPv4)
+}
+
+func TestUsageReport_ReportsToSMF(t *testing.T) {
+	server, dp, conn := startTestServer(t)
+
+	exchange(t, conn, pfcpmsg.NewMessage(pfcpmsg.PFCP_ASSOCIATION_SETUP_REQUEST, 1,
+		pfcpmsg.NewNodeIDIE("127.0.0.1"),
+		pfcpmsg.NewRecoveryTimeStampIE(time.Now()),
+	))
+	resp := exchange(t, conn, establishmentRequest())
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+	fseid, err := resp.FindIE(pfcpmsg.IE_F_SEID).FSEID()
+	require.NoError(t, err)
+
+	// A URR referenced by the uplink PDR reports every 150 bytes
+	resp = exchange(t, conn, pfcpmsg.NewSessionMessage(pfcpmsg.PFCP_SESSION_MODIFICATION_REQUEST, fseid.SEID, 3,
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_CREATE_URR,
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_URR_ID, 1),
+			pfcpmsg.NewUint8IE(pfcpmsg.IE_MEASUREMENT_METHOD, pfcpmsg.MEASUREMENT_METHOD_VOLUM),
+			pfcpmsg.NewReportingTriggersIE(pfcpmsg.IE_REPORTING_TRIGGERS, pfcpmsg.REPORTING_TRIGGER_VOLTH),
+			pfcpmsg.NewVolumeThresholdIE(&pfcpmsg.VolumeThreshold{Total: 150}),
+		),
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_UPDATE_PDR,
+			pfcpmsg.NewUint16IE(pfcpmsg.IE_PDR_ID, 1),
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_URR_ID, 1),
+		),
+	))
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+
+	session, ok := server.upfContext.GetSession(fseid.SEID)
+	require.True(t, ok)
+	require.Len(t, session.URRs, 1)
+	assert.Equal(t, uint64(150), session.URRs[0].VolumeThreshold.Total)
+	assert.Equal(t, []uint32{1}, session.PDRs[0].URRIDs)
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		require.NoError(t, dp.ProcessPacket(ctx, &dataplane.Packet{
+			Data: make([]byte, 100), Interface: "N3", TEID: session.UPFTEID, QFI: 1,
+			SrcIP: net.ParseIP(testUEIP), DstIP: net.ParseIP("8.8.8.8"),
+		}))
+	}
+
+	buf := make([]byte, 4096)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	report, err := pfcpmsg.Parse(buf[:n])
+	require.NoError(t, err)
+	assert.Equal(t, uint8(pfcpmsg.PFCP_SESSION_REPORT_REQUEST), report.Header.MessageType)
+	assert.Equal(t, uint64(testCPSEID), report.Header.SEID)
+
+	reportType, err := report.FindIE(pfcpmsg.IE_REPORT_TYPE).Uint8()
+	require.NoError(t, err)
+	assert.Equal(t, uint8(pfcpmsg.REPORT_TYPE_USAR), reportType)
+	usar, err := report.FindIE(pfcpmsg.IE_USAGE_REPORT_REPORT_REQ).UsageReport()
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), usar.URRID)
+	assert.Equal(t, uint32(pfcpmsg.REPORTING_TRIGGER_VOLTH), usar.Trigger)
+	require.NotNil(t, usar.Volume)
+	assert.Equal(t, uint64(200), usar.Volume.TotalVolume)
+	assert.Equal(t, uint64(200), usar.Volume.UplinkVolume)
+
+	// A URR still referenced by a PDR cannot be removed
+	resp = exchange(t, conn, pfcpmsg.NewSessionMessage(pfcpmsg.PFCP_SESSION_MODIFICATION_REQUEST, fseid.SEID, 4,
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_REMOVE_URR, pfcpmsg.NewUint32IE(pfcpmsg.IE_URR_ID, 1)),
+	))
+	requireCause(t, resp, pfcpmsg.CAUSE_RULE_CREATION_FAILURE)
+}
+
+// endMarkerRecorder records the End Markers the server asks for
+type endMarkerRecorder struct {
+	mu      sync.Mutex
+	tunnels []string
+}
+
+func (r *endMarkerRecorder) SendEndMarker(teid uint32, gnbAddress net.IP) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tunnels = append(r.tunnels, fmt.Sprintf("0x%x@%s", teid, gnbAddress))
+	return nil
+}
+
+func (r *endMarkerRecorder) sent() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.tunnels...)
+}
+
+func TestPathSwitch_SendsEndMarkerOnOldTunnel(t *testing.T) {
+	endMarkers := &endMarkerRecorder{}
+	_, _, conn := startTestServer(t, func(s *PFCPServer) { s.SetEndMarkerSender(endMarkers) })
+
+	exchange(t, conn, pfcpmsg.NewMessage(pfcpmsg.PFCP_ASSOCIATION_SETUP_REQUEST, 1,
+		pfcpmsg.NewNodeIDIE("127.0.0.1"),
+		pfcpmsg.NewRecoveryTimeStampIE(time.Now()),
+	))
+	resp := exchange(t, conn, establishmentRequest())
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+	fseid, err := resp.FindIE(pfcpmsg.IE_F_SEID).FSEID()
+	require.NoError(t, err)
+	assert.Empty(t, endMarkers.sent())
+
+	// Handover: downlink moves to the tunnel of the target gNB
+	resp = exchange(t, conn, pfcpmsg.NewSessionMessage(pfcpmsg.PFCP_SESSION_MODIFICATION_REQUEST, fseid.SEID, 3,
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_UPDATE_FAR,
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_FAR_ID, 2),
+			pfcpmsg.NewGroupedIE(pfcpmsg.IE_UPDATE_FORWARDING_PARAMS,
+				pfcpmsg.NewOuterHeaderCreationIE(&pfcpmsg.OuterHeaderCreation{
+					Description: pfcpmsg.OUTER_HEADER_CREATION_GTPU_UDP_IPV4,
+					TEID:        0x200,
+					IPv4:        net.ParseIP("192.168.1.20"),
+				}),
+			),
+		),
+	))
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+	assert.Equal(t, []string{"0x100@" + testGNBIP}, endMarkers.sent())
+
+	// Modifications keeping the tunnel send none
+	resp = exchange(t, conn, pfcpmsg.NewSessionMessage(pfcpmsg.PFCP_SESSION_MODIFICATION_REQUEST, fseid.SEID, 4,
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_UPDATE_QER,
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_QER_ID, 1),
+			pfcpmsg.NewGateStatusIE(pfcpmsg.GATE_STATUS_OPEN),
+		),
+	))
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+	assert.Len(t, endMarkers.sent(), 1)
+}
+
+func TestSessionModification_UpdatePDRAndRemoveFAR(t *testing.T) {
+	server, dp, conn := startTestServer(t)
+
+	exchange(t, conn, pfcpmsg.NewMessage(pfcpmsg.PFCP_ASSOCIATION_SETUP_REQUEST, 1,
+		pfcpmsg.NewNodeIDIE("127.0.0.1"),
+		pfcpmsg.NewRecoveryTimeStampIE(time.Now()),
+	))
+	resp := exchange(t, conn, establishmentRequest())
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+	fseid, err := resp.FindIE(pfcpmsg.IE_F_SEID).FSEID()
+	require.NoError(t, err)
+
+	// Point the downlink PDR at a new FAR and remove the old one
+	resp = exchange(t, conn, pfcpmsg.NewSessionMessage(pfcpmsg.PFCP_SESSION_MODIFICATION_REQUEST, fseid.SEID, 3,
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_CREATE_FAR,
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_FAR_ID, 3),
+			pfcpmsg.NewUint8IE(pfcpmsg.IE_APPLY_ACTION, pfcpmsg.APPLY_ACTION_FORW),
+			pfcpmsg.NewGroupedIE(pfcpmsg.IE_FORWARDING_PARAMETERS,
+				pfcpmsg.NewUint8IE(pfcpmsg.IE_DESTINATION_INTERFACE, pfcpmsg.INTERFACE_ACCESS),
+				pfcpmsg.NewOuterHeaderCreationIE(&pfcpmsg.OuterHeaderCreation{
+					Description: pfcpmsg.OUTER_HEADER_CREATION_GTPU_UDP_IPV4,
+					TEID:        0x300,
+					IPv4:        net.ParseIP("192.168.1.30"),
+				}),
+			),
+		),
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_UPDATE_PDR,
+			pfcpmsg.NewUint16IE(pfcpmsg.IE_PDR_ID, 2),
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_PRECEDENCE, 50),
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_FAR_ID, 3),
+		),
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_REMOVE_FAR, pfcpmsg.NewUint32IE(pfcpmsg.IE_FAR_ID, 2)),
+	))
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+
+	session, ok := server.upfContext.GetSession(fseid.SEID)
+	require.True(t, ok)
+	require.Len(t, session.PDRs, 2)
+	downlink := session.PDRs[1]
+	assert.Equal(t, uint16(2), downlink.PDRID)
+	assert.Equal(t, uint32(50), downlink.Precedence)
+	assert.Equal(t, uint32(3), downlink.FARID)
+	assert.True(t, downlink.PDI.UEIPAddress.Equal(net.ParseIP(testUEIP)))
+
+	farIDs := make([]uint32, 0, len(session.FARs))
+	for _, far := range session.FARs {
+		farIDs = append(farIDs, far.FARID)
+	}
+	assert.ElementsMatch(t, []uint32{1, 3}, farIDs)
+
+	// An update of an unknown PDR is rejected
+	resp = exchange(t, conn, pfcpmsg.NewSessionMessage(pfcpmsg.PFCP_SESSION_MODIFICATION_REQUEST, fseid.SEID, 4,
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_UPDATE_PDR,
+			pfcpmsg.NewUint16IE(pfcpmsg.IE_PDR_ID, 9),
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_FAR_ID, 3),
+		),
+	))
+	requireCause(t, resp, pfcpmsg.CAUSE_RULE_CREATION_FAILURE)
+
+	ctx := context.Background()
+	require.NoError(t, dp.ProcessPacket(ctx, &dataplane.Packet{
+		Data: make([]byte, 100), Interface: "N6",
+		SrcIP: net.ParseIP("8.8.8.8"), DstIP: net.ParseIP(testUEIP),
+	}))
+	require.Eventually(t, func() bool {
+		stats, _ := dp.GetStats(ctx)
+		return stats.PacketsForwarded == 1 && stats.PacketsDropped == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSessionModification_UpdatePDRChoosesFTEID(t *testing.T) {
+	server, _, conn := startTestServer(t)
+
+	exchange(t, conn, pfcpmsg.NewMessage(pfcpmsg.PFCP_ASSOCIATION_SETUP_REQUEST, 1,
+		pfcpmsg.NewNodeIDIE("127.0.0.1"),
+		pfcpmsg.NewRecoveryTimeStampIE(time.Now()),
+	))
+	resp := exchange(t, conn, establishmentRequest())
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+	fseid, err := resp.FindIE(pfcpmsg.IE_F_SEID).FSEID()
+	require.NoError(t, err)
+	session, ok := server.upfContext.GetSession(fseid.SEID)
+	require.True(t, ok)
+	previous := session.UPFTEID
+
+	// A new PDI asking the UPF to choose the F-TEID gets a new one
+	resp = exchange(t, conn, pfcpmsg.NewSessionMessage(pfcpmsg.PFCP_SESSION_MODIFICATION_REQUEST, fseid.SEID, 3,
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_UPDATE_PDR,
+			pfcpmsg.NewUint16IE(pfcpmsg.IE_PDR_ID, 1),
+			pfcpmsg.NewGroupedIE(pfcpmsg.IE_PDI,
+				pfcpmsg.NewUint8IE(pfcpmsg.IE_SOURCE_INTERFACE, pfcpmsg.INTERFACE_ACCESS),
+				pfcpmsg.NewFTEIDIE(&pfcpmsg.FTEID{ChooseID: true, ChooseV4: true, HasCHID: true, CHID: 2}),
+				pfcpmsg.NewIE(pfcpmsg.IE_NETWORK_INSTANCE, []byte(testDNN)),
+				pfcpmsg.NewUEIPAddressIE(&pfcpmsg.UEIPAddress{IPv4: net.ParseIP(testUEIP)}),
+				pfcpmsg.NewUint8IE(pfcpmsg.IE_QFI, 1),
+			),
+		),
+	))
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+
+	created := resp.FindIE(pfcpmsg.IE_CREATED_PDR)
+	require.NotNil(t, created)
+	children, err := created.ChildIEs()
+	require.NoError(t, err)
+	pdrID, err := pfcpmsg.FindIE(children, pfcpmsg.IE_PDR_ID).Uint16()
+	require.NoError(t, err)
+	assert.Equal(t, uint16(1), pdrID)
+	fteid, err := pfcpmsg.FindIE(children, pfcpmsg.IE_F_TEID).FTEID()
+	require.NoError(t, err)
+	assert.NotEqual(t, previous, fteid.TEID)
+	assert.True(t, fteid.IPv4.Equal(net.ParseIP(testN3IP)))
+
+	session, ok = server.upfContext.GetSession(fseid.SEID)
+	require.True(t, ok)
+	assert.Equal(t, fteid.TEID, session.UPFTEID)
+	assert.Equal(t, fteid.TEID, session.PDRs[0].PDI.FTEID.TEID)
+}
+
+func TestSessionModification_UpdatePDRReplacesQERs(t *testing.T) {
+	server, _, conn := startTestServer(t)
+
+	exchange(t, conn, pfcpmsg.NewMessage(pfcpmsg.PFCP_ASSOCIATION_SETUP_REQUEST, 1,
+		pfcpmsg.NewNodeIDIE("127.0.0.1"),
+		pfcpmsg.NewRecoveryTimeStampIE(time.Now()),
+	))
+	resp := exchange(t, conn, establishmentRequest())
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+	fseid, err := resp.FindIE(pfcpmsg.IE_F_SEID).FSEID()
+	require.NoError(t, err)
+
+	resp = exchange(t, conn, pfcpmsg.NewSessionMessage(pfcpmsg.PFCP_SESSION_MODIFICATION_REQUEST, fseid.SEID, 3,
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_CREATE_QER,
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_QER_ID, 2),
+			pfcpmsg.NewGateStatusIE(pfcpmsg.GATE_STATUS_OPEN),
+		),
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_CREATE_URR,
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_URR_ID, 1),
+			pfcpmsg.NewUint8IE(pfcpmsg.IE_MEASUREMENT_METHOD, pfcpmsg.MEASUREMENT_METHOD_VOLUM),
+			pfcpmsg.NewReportingTriggersIE(pfcpmsg.IE_REPORTING_TRIGGERS, pfcpmsg.REPORTING_TRIGGER_VOLTH),
+			pfcpmsg.NewVolumeThresholdIE(&pfcpmsg.VolumeThreshold{Total: 1000}),
+		),
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_CREATE_URR,
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_URR_ID, 2),
+			pfcpmsg.NewUint8IE(pfcpmsg.IE_MEASUREMENT_METHOD, pfcpmsg.MEASUREMENT_METHOD_VOLUM),
+			pfcpmsg.NewReportingTriggersIE(pfcpmsg.IE_REPORTING_TRIGGERS, pfcpmsg.REPORTING_TRIGGER_VOLTH),
+			pfcpmsg.NewVolumeThresholdIE(&pfcpmsg.VolumeThreshold{Total: 2000}),
+		),
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_UPDATE_PDR,
+			pfcpmsg.NewUint16IE(pfcpmsg.IE_PDR_ID, 1),
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_URR_ID, 1),
+		),
+	))
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+
+	// The QER and URR IDs of an update replace those of the PDR
+	resp = exchange(t, conn, pfcpmsg.NewSessionMessage(pfcpmsg.PFCP_SESSION_MODIFICATION_REQUEST, fseid.SEID, 4,
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_UPDATE_PDR,
+			pfcpmsg.NewUint16IE(pfcpmsg.IE_PDR_ID, 1),
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_QER_ID, 2),
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_URR_ID, 2),
+		),
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_REMOVE_URR, pfcpmsg.NewUint32IE(pfcpmsg.IE_URR_ID, 1)),
+	))
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+
+	session, ok := server.upfContext.GetSession(fseid.SEID)
+	require.True(t, ok)
+	assert.Equal(t, []uint32{2}, session.PDRs[0].QERIDs)
+	assert.Equal(t, []uint32{2}, session.PDRs[0].URRIDs)
+	assert.Equal(t, []uint32{1}, session.PDRs[1].QERIDs)
+
+	// An update without QER or URR IDs keeps them
+	resp = exchange(t, conn, pfcpmsg.NewSessionMessage(pfcpmsg.PFCP_SESSION_MODIFICATION_REQUEST, fseid.SEID, 5,
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_UPDATE_PDR,
+			pfcpmsg.NewUint16IE(pfcpmsg.IE_PDR_ID, 1),
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_PRECEDENCE, 50),
+		),
+	))
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+
+	session, ok = server.upfContext.GetSession(fseid.SEID)
+	require.True(t, ok)
+	assert.Equal(t, []uint32{2}, session.PDRs[0].QERIDs)
+	assert.Equal(t, []uint32{2}, session.PDRs[0].URRIDs)
+}
+
+func TestSessionModification_UpdatePDRChangesUEAddress(t *testing.T) {
+	server, _, conn := startTestServer(t)
+
+	exchange(t, conn, pfcpmsg.NewMessage(pfcpmsg.PFCP_ASSOCIATION_SETUP_REQUEST, 1,
+		pfcpmsg.NewNodeIDIE("127.0.0.1"),
+		pfcpmsg.NewRecoveryTimeStampIE(time.Now()),
+	))
+	resp := exchange(t, conn, establishmentRequest())
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+	fseid, err := resp.FindIE(pfcpmsg.IE_F_SEID).FSEID()
+	require.NoError(t, err)
+
+	const newUEIP = "10.60.0.2"
+	resp = exchange(t, conn, pfcpmsg.NewSessionMessage(pfcpmsg.PFCP_SESSION_MODIFICATION_REQUEST, fseid.SEID, 3,
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_UPDATE_PDR,
+			pfcpmsg.NewUint16IE(pfcpmsg.IE_PDR_ID, 1),
+			pfcpmsg.NewGroupedIE(pfcpmsg.IE_PDI,
+				pfcpmsg.NewUint8IE(pfcpmsg.IE_SOURCE_INTERFACE, pfcpmsg.INTERFACE_ACCESS),
+				pfcpmsg.NewFTEIDIE(&pfcpmsg.FTEID{ChooseID: true, ChooseV4: true, HasCHID: true, CHID: 1}),
+				pfcpmsg.NewIE(pfcpmsg.IE_NETWORK_INSTANCE, []byte("ims")),
+				pfcpmsg.NewUEIPAddressIE(&pfcpmsg.UEIPAddress{IPv4: net.ParseIP(newUEIP)}),
+				pfcpmsg.NewUint8IE(pfcpmsg.IE_QFI, 1),
+			),
+		),
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_UPDATE_PDR,
+			pfcpmsg.NewUint16IE(pfcpmsg.IE_PDR_ID, 2),
+			pfcpmsg.NewGroupedIE(pfcpmsg.IE_PDI,
+				pfcpmsg.NewUint8IE(pfcpmsg.IE_SOURCE_INTERFACE, pfcpmsg.INTERFACE_CORE),
+				pfcpmsg.NewIE(pfcpmsg.IE_NETWORK_INSTANCE, []byte("ims")),
+				pfcpmsg.NewUEIPAddressIE(&pfcpmsg.UEIPAddress{IPv4: net.ParseIP(newUEIP), IsDestination: true}),
+			),
+		),
+	))
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+
+	session, ok := server.upfContext.GetSession(fseid.SEID)
+	require.True(t, ok)
+	assert.True(t, session.UEAddress.Equal(net.ParseIP(newUEIP)))
+	assert.Equal(t, "ims", session.DNN)
+}
+
+// farRecorder records the FARs installed in the data plane it wraps
+type farRecorder struct {
+	dataplane.DataPlane
+
+	mu   sync.Mutex
+	fars map[uint16]dataplane.FAR
+}
+
+func (r *farRecorder) InstallFAR(ctx context.Context, sessionID uint64, far *dataplane.FAR) error {
+	r.mu.Lock()
+	r.fars[far.FARID] = *far
+	r.mu.Unlock()
+	return r.DataPlane.InstallFAR(ctx, sessionID, far)
+}
+
+func (r *farRecorder) far(id uint16) dataplane.FAR {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.fars[id]
+}
+
+func TestSessionModification_UpdatesDownlinkTunnel(t *testing.T) {
+	var recorder *farRecorder
+	_, dp, conn := startTestServer(t, func(s *PFCPServer) {
+		recorder = &farRecorder{DataPlane: s.dataPlane, fars: make(map[uint16]dataplane.FAR)}
+		s.SetDataPlane(recorder)
+	})
+
+	exchange(t, conn, pfcpmsg.NewMessage(pfcpmsg.PFCP_ASSOCIATION_SETUP_REQUEST, 1,
+		pfcpmsg.NewNodeIDIE("127.0.0.1"),
+		pfcpmsg.NewRecoveryTimeStampIE(time.Now()),
+	))
+	resp := exchange(t, conn, establishmentRequest())
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+	fseid, err := resp.FindIE(pfcpmsg.IE_F_SEID).FSEID()
+	require.NoError(t, err)
+
+	ohc := recorder.far(2).ForwardingParameters.OuterHeaderCreation
+	require.NotNil(t, ohc)
+	assert.Equal(t, uint32(0x100), ohc.TEID)
+
+	resp = exchange(t, conn, pfcpmsg.NewSessionMessage(pfcpmsg.PFCP_SESSION_MODIFICATION_REQUEST, fseid.SEID, 3,
+		pfcpmsg.NewGroupedIE(pfcpmsg.IE_UPDATE_FAR,
+			pfcpmsg.NewUint32IE(pfcpmsg.IE_FAR_ID, 2),
+			pfcpmsg.NewGroupedIE(pfcpmsg.IE_UPDATE_FORWARDING_PARAMS,
+				pfcpmsg.NewOuterHeaderCreationIE(&pfcpmsg.OuterHeaderCreation{
+					Description: pfcpmsg.OUTER_HEADER_CREATION_GTPU_UDP_IPV4,
+					TEID:        0x200,
+					IPv4:        net.ParseIP("192.168.1.20"),
+				}),
+			),
+		),
+	))
+	requireCause(t, resp, pfcpmsg.CAUSE_REQUEST_ACCEPTED)
+
+	// The data plane now encapsulates downlink towards the new tunnel
+	far := recorder.far(2)
+	assert.Equal(t, dataplane.ApplyActionForw, far.ApplyAction)
+	require.NotNil(t, far.ForwardingParameters.OuterHeaderCreation)
+	assert.Equal(t, uint32(0x200), far.ForwardingParameters.OuterHeaderCreation.TEID)
+	assert.True(t, far.ForwardingParameters.OuterHeaderCreation.IPv4.Equal(net.ParseIP("192.168.1.20")))
+
+	ctx := context.Background()
+	require.NoError(t, dp.ProcessPacket(ctx, &dataplane.Packet{
+		Data: make([]byte, 100), Interface: "N6",
+		SrcIP: net.ParseIP("8.8.8.8"), DstIP: net.ParseIP(testUEIP),
+	}))
+	require.Eventually(t, func() bool {
+		stats, _ := dp.GetStats(ctx)
+		return stats.PacketsForwarded == 1 && stats.PacketsDropped == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestMalformedMessages_DoNotPanic(t *testing.T) {
+	server, _, conn := startTestServer(t)
+
+	// Responses to the malformed requests go to a socket nobody reads
+	sink, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer sink.Close()
+	from := sink.LocalAddr().(*net.UDPAddr)
+
+	valid := [][]byte{
+		pfcpmsg.NewMessage(pfcpmsg.PFCP_HEARTBEAT_REQUEST, 1, pfcpmsg.NewRecoveryTimeStampIE(time.Now())).Marshal(),
+		pfcpmsg.NewMessage(pfcpmsg.PFCP_ASSOCIATION_SETUP_REQUEST, 2,
+			pfcpmsg.NewNodeIDIE("127.0.0.1"),
+			pfcpmsg.NewRecoveryTimeStampIE(time.Now()),
+		).Marshal(),
+		establishmentRequest().Marshal(),
+		pfcpmsg.NewSessionMessage(pfcpmsg.PFCP_SESSION_DELETION_REQUEST, 1, 3).Marshal(),
+	}
+
+	for _, raw := range valid {
+		require.NotPanics(t, func() {
+			// Truncated at every octet
+			for n := 0; n < len(raw); n++ {
+				server.handleDatagram(raw[:n], from)
+			}
+
+			// Declared lengths larger than the datagram
+			oversized := append([]byte(nil), raw...)
+			oversized[2], oversized[3] = 0xff, 0xff
+			server.handleDatagram(oversized, from)
+			for offset := pfcpmsg.PFCP_HEADER_LENGTH; offset+4 <= len(raw); offset++ {
+				bad := append([]byte(nil), raw...)
+				bad[offset+2], bad[offset+3] = 0xff, 0xff
+				server.handleDatagram(bad, from)
+			}
+
+			// Every octet corrupted in turn
+			for i := range raw {
+				bad := append([]byte(nil), raw...)
+				bad[i] ^= 0xff
+				server.handleDatagram(bad, from)
+			}
+		})
+	}
+
+	// The server still answers well-formed requests
+	resp := exchange(t, conn, pfcpmsg.NewMessage(pfcpmsg.PFCP_HEARTBEAT_REQUEST, 9,
+		pfcpmsg.NewRecoveryTimeStampIE(time.Now()),
+	))
+	assert.Equal(t, uint8(pfcpmsg.PFCP_HEARTBEAT_RESPONSE), resp.Header.MessageType)
+}
+
+func TestMalformedRequest_RejectedWithCause(t *testing.T) {
+	_, _, conn := startTestServer(t)
+
+	// An IE length running past the end of the message
+	raw := establishmentRequest().Marshal()
+	raw[pfcpmsg.PFCP_HEADER_LENGTH_WITH_SEID+2] = 0xff
+	_, err := conn.Write(raw)
+	require.NoError(t, err)
+
+	buf := make([]byte, 4096)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	resp, err := pfcpmsg.Parse(buf[:n])
+	require.NoError(t, err)
+	assert.Equal(t, uint8(pfcpmsg.PFCP_SESSION_ESTABLISHMENT_RESPONSE), resp.Header.MessageType)
+	assert.Equal(t, uint32(2), resp.Header.SequenceNumber)
+	requireCause(t, resp, pfcpmsg.CAUSE_MANDATORY_IE_INCORRECT)
+}
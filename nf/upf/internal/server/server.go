@@ -4,13 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/your-org/5g-network/common/buildinfo"
+	"github.com/your-org/5g-network/common/correlation"
 	"github.com/your-org/5g-network/nf/upf/internal/config"
 	upfcontext "github.com/your-org/5g-network/nf/upf/internal/context"
 	"github.com/your-org/5g-network/nf/upf/internal/gtpu"
+	"github.com/your-org/5g-network/nf/upf/internal/throughput"
 	"go.uber.org/zap"
 )
 
@@ -21,6 +25,7 @@ type Server struct {
 	httpServer  *http.Server
 	upfContext  *upfcontext.UPFContext
 	gtpuHandler *gtpu.GTPUHandler
+	sampler     *throughput.Sampler
 	logger      *zap.Logger
 }
 
@@ -41,6 +46,7 @@ func NewServer(cfg *config.Config, upfCtx *upfcontext.UPFContext, gtpuHandler *g
 // setupRoutes configures the API routes
 func (s *Server) setupRoutes() {
 	s.router.Use(middleware.RequestID)
+	s.router.Use(correlation.Middleware)
 	s.router.Use(middleware.RealIP)
 	s.router.Use(middleware.Logger)
 	s.router.Use(middleware.Recoverer)
@@ -50,9 +56,20 @@ func (s *Server) setupRoutes() {
 	s.router.Get("/ready", s.handleReadinessCheck)
 	s.router.Get("/status", s.handleStatus)
 	s.router.Get("/sessions", s.handleGetSessions)
+	s.router.Get("/sessions/{seid}/stats", s.handleGetSessionStats)
 	s.router.Get("/stats", s.handleGetStats)
 }
 
+// SetBuildInfo exposes the NF build info on GET /version
+func (s *Server) SetBuildInfo(info *buildinfo.Info) {
+	s.router.Get("/version", info.Handler())
+}
+
+// SetSampler adds the throughput computed by sampler to the statistics
+func (s *Server) SetSampler(sampler *throughput.Sampler) {
+	s.sampler = sampler
+}
+
 // Start starts the HTTP server
 func (s *Server) Start() error {
 	addr := ":9096" // Admin port
@@ -122,20 +139,62 @@ func (s *Server) handleGetSessions(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleGetSessionStats returns the traffic counters and throughput of a
+// session
+func (s *Server) handleGetSessionStats(w http.ResponseWriter, r *http.Request) {
+	seid, err := strconv.ParseUint(chi.URLParam(r, "seid"), 10, 64)
+	if err != nil {
+		s.respondJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid SEID"})
+		return
+	}
+	session, exists := s.upfContext.GetSession(seid)
+	traffic, counted := s.upfContext.GetTraffic(seid)
+	if !exists || !counted {
+		s.respondJSON(w, http.StatusNotFound, map[string]string{"error": "session not found"})
+		return
+	}
+
+	var rate throughput.Rate
+	if s.sampler != nil {
+		rate, _ = s.sampler.SessionRate(seid)
+	}
+
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"seid":             seid,
+		"dnn":              session.DNN,
+		"uplink_packets":   traffic.UplinkPackets,
+		"uplink_bytes":     traffic.UplinkBytes,
+		"downlink_packets": traffic.DownlinkPackets,
+		"downlink_bytes":   traffic.DownlinkBytes,
+		"uplink_bps":       rate.UplinkBps,
+		"downlink_bps":     rate.DownlinkBps,
+	})
+}
+
 // handleGetStats returns GTP-U statistics
 func (s *Server) handleGetStats(w http.ResponseWriter, r *http.Request) {
 	gtpuStats := s.gtpuHandler.GetStats()
 	upfStats := s.upfContext.GetStats()
 
+	var rate throughput.Rate
+	if s.sampler != nil {
+		rate = s.sampler.Rate()
+	}
+
 	s.respondJSON(w, http.StatusOK, map[string]interface{}{
 		"gtpu": map[string]interface{}{
-			"uplink_packets":   gtpuStats.UplinkPackets,
-			"downlink_packets": gtpuStats.DownlinkPackets,
-			"uplink_bytes":     gtpuStats.UplinkBytes,
-			"downlink_bytes":   gtpuStats.DownlinkBytes,
-			"dropped_packets":  gtpuStats.DroppedPackets,
+			"uplink_packets":    gtpuStats.UplinkPackets,
+			"downlink_packets":  gtpuStats.DownlinkPackets,
+			"uplink_bytes":      gtpuStats.UplinkBytes,
+			"downlink_bytes":    gtpuStats.DownlinkBytes,
+			"dropped_packets":   gtpuStats.DroppedPackets,
+			"error_indications": gtpuStats.ErrorIndications,
 		},
 		"sessions": upfStats,
+		"throughput": map[string]interface{}{
+			"uplink_bps":   rate.UplinkBps,
+			"downlink_bps": rate.DownlinkBps,
+		},
 	})
 }
 
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/nf/upf/internal/config"
+	upfcontext "github.com/your-org/5g-network/nf/upf/internal/context"
+	"github.com/your-org/5g-network/nf/upf/internal/throughput"
+)
+
+func TestGetSessionStats(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	upfCtx := upfcontext.NewUPFContext()
+	upfCtx.CreateSession(7).DNN = "internet"
+	sampler := throughput.NewSampler(upfCtx, time.Second, 10*time.Second, logger)
+	s := NewServer(&config.Config{}, upfCtx, nil, logger)
+	s.SetSampler(sampler)
+
+	start := time.Now()
+	sampler.Sample(start)
+	upfCtx.RecordTraffic(7, true, 500)
+	upfCtx.RecordTraffic(7, false, 1500)
+	sampler.Sample(start.Add(time.Second))
+
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/sessions/7/stats", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var stats map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	assert.Equal(t, "internet", stats["dnn"])
+	assert.Equal(t, float64(1), stats["uplink_packets"])
+	assert.Equal(t, float64(500), stats["uplink_bytes"])
+	assert.Equal(t, float64(1500), stats["downlink_bytes"])
+	assert.Equal(t, float64(4000), stats["uplink_bps"])
+	assert.Equal(t, float64(12000), stats["downlink_bps"])
+
+	for path, code := range map[string]int{
+		"/sessions/8/stats":   http.StatusNotFound,
+		"/sessions/abc/stats": http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		s.router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, code, rec.Code, path)
+	}
+}
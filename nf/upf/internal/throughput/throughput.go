@@ -0,0 +1,161 @@
+// Package throughput samples the traffic counters of the UPF sessions and
+// computes uplink and downlink bit rates over a sliding window.
+package throughput
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/your-org/5g-network/common/metrics"
+	upfcontext "github.com/your-org/5g-network/nf/upf/internal/context"
+	"go.uber.org/zap"
+)
+
+// Sampling defaults
+const (
+	DefaultInterval = time.Second
+	DefaultWindow   = 10 * time.Second
+)
+
+// Rate is a throughput in bits per second
+type Rate struct {
+	UplinkBps   float64
+	DownlinkBps float64
+}
+
+// sample is the cumulative byte count of a session at a point in time
+type sample struct {
+	at       time.Time
+	uplink   uint64
+	downlink uint64
+}
+
+// window keeps the samples of a session covering the sliding window
+type window struct {
+	samples []sample
+}
+
+// add appends s and drops the samples no longer needed: the oldest one
+// kept is the last sample at or before the start of the window
+func (w *window) add(s sample, length time.Duration) {
+	w.samples = append(w.samples, s)
+	start := s.at.Add(-length)
+	drop := 0
+	for drop+1 < len(w.samples) && !w.samples[drop+1].at.After(start) {
+		drop++
+	}
+	w.samples = w.samples[drop:]
+}
+
+// rate returns the throughput between the oldest and newest samples
+func (w *window) rate() Rate {
+	if len(w.samples) < 2 {
+		return Rate{}
+	}
+	first, last := w.samples[0], w.samples[len(w.samples)-1]
+	elapsed := last.at.Sub(first.at).Seconds()
+	if elapsed <= 0 {
+		return Rate{}
+	}
+	return Rate{
+		UplinkBps:   float64(last.uplink-first.uplink) * 8 / elapsed,
+		DownlinkBps: float64(last.downlink-first.downlink) * 8 / elapsed,
+	}
+}
+
+// Sampler periodically samples the session traffic counters and exports
+// the aggregate throughput and active session count to Prometheus
+type Sampler struct {
+	upfContext *upfcontext.UPFContext
+	interval   time.Duration
+	length     time.Duration
+	logger     *zap.Logger
+
+	mu        sync.RWMutex
+	sessions  map[uint64]*window
+	aggregate Rate
+}
+
+// NewSampler creates a sampler taking a sample every interval and
+// computing rates over the last length of samples
+func NewSampler(upfCtx *upfcontext.UPFContext, interval, length time.Duration, logger *zap.Logger) *Sampler {
+	return &Sampler{
+		upfContext: upfCtx,
+		interval:   interval,
+		length:     length,
+		logger:     logger,
+		sessions:   make(map[uint64]*window),
+	}
+}
+
+// Run samples until ctx is cancelled
+func (s *Sampler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	s.logger.Info("Throughput sampler started",
+		zap.Duration("interval", s.interval),
+		zap.Duration("window", s.length))
+	for {
+		select {
+		case now := <-ticker.C:
+			s.Sample(now)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Sample records the counters of all sessions at now. The aggregate rate
+// is the sum of the session rates, so released sessions leave it.
+func (s *Sampler) Sample(now time.Time) {
+	traffic := s.upfContext.GetAllTraffic()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var aggregate Rate
+	for seid, t := range traffic {
+		w, exists := s.sessions[seid]
+		if !exists {
+			w = &window{}
+			s.sessions[seid] = w
+		}
+		w.add(sample{at: now, uplink: t.UplinkBytes, downlink: t.DownlinkBytes}, s.length)
+
+		rate := w.rate()
+		aggregate.UplinkBps += rate.UplinkBps
+		aggregate.DownlinkBps += rate.DownlinkBps
+	}
+	for seid := range s.sessions {
+		if _, exists := traffic[seid]; !exists {
+			delete(s.sessions, seid)
+		}
+	}
+	s.aggregate = aggregate
+
+	metrics.SetUplinkThroughput(aggregate.UplinkBps)
+	metrics.SetDownlinkThroughput(aggregate.DownlinkBps)
+	metrics.SetUPFActiveSessions(len(traffic))
+}
+
+// Rate returns the aggregate throughput of all sessions
+func (s *Sampler) Rate() Rate {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.aggregate
+}
+
+// SessionRate returns the throughput of a session, false until the
+// session has been sampled
+func (s *Sampler) SessionRate(seid uint64) (Rate, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	w, exists := s.sessions[seid]
+	if !exists {
+		return Rate{}, false
+	}
+	return w.rate(), true
+}
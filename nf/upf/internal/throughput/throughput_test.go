@@ -0,0 +1,82 @@
+package throughput
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/your-org/5g-network/common/metrics"
+	upfcontext "github.com/your-org/5g-network/nf/upf/internal/context"
+)
+
+// pushTraffic records count packets of size bytes for a session
+func pushTraffic(upfCtx *upfcontext.UPFContext, seid uint64, uplink bool, count, size int) {
+	for i := 0; i < count; i++ {
+		upfCtx.RecordTraffic(seid, uplink, size)
+	}
+}
+
+func TestSamplerComputesRates(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	upfCtx := upfcontext.NewUPFContext()
+	upfCtx.CreateSession(1)
+	upfCtx.CreateSession(2)
+	sampler := NewSampler(upfCtx, time.Second, 4*time.Second, logger)
+
+	start := time.Now()
+	sampler.Sample(start)
+	rate, ok := sampler.SessionRate(1)
+	require.True(t, ok)
+	assert.Zero(t, rate, "a single sample has no rate")
+
+	// Session 1 sends 1250 bytes (10 kbit) uplink and session 2 receives
+	// 2500 bytes downlink per second
+	for i := 1; i <= 4; i++ {
+		pushTraffic(upfCtx, 1, true, 10, 125)
+		pushTraffic(upfCtx, 2, false, 2, 1250)
+		sampler.Sample(start.Add(time.Duration(i) * time.Second))
+	}
+
+	rate, ok = sampler.SessionRate(1)
+	require.True(t, ok)
+	assert.Equal(t, Rate{UplinkBps: 10000}, rate)
+	rate, ok = sampler.SessionRate(2)
+	require.True(t, ok)
+	assert.Equal(t, Rate{DownlinkBps: 20000}, rate)
+
+	assert.Equal(t, Rate{UplinkBps: 10000, DownlinkBps: 20000}, sampler.Rate())
+	assert.Equal(t, float64(10000), testutil.ToFloat64(metrics.UplinkThroughput))
+	assert.Equal(t, float64(20000), testutil.ToFloat64(metrics.DownlinkThroughput))
+	assert.Equal(t, float64(2), testutil.ToFloat64(metrics.UPFActiveSessions))
+}
+
+func TestSamplerWindowSlides(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	upfCtx := upfcontext.NewUPFContext()
+	upfCtx.CreateSession(1)
+	sampler := NewSampler(upfCtx, time.Second, 2*time.Second, logger)
+
+	start := time.Now()
+	sampler.Sample(start)
+	pushTraffic(upfCtx, 1, true, 1, 1000)
+	sampler.Sample(start.Add(time.Second))
+	rate, _ := sampler.SessionRate(1)
+	assert.Equal(t, float64(8000), rate.UplinkBps)
+
+	// Once the burst is older than the window the session is idle
+	sampler.Sample(start.Add(2 * time.Second))
+	sampler.Sample(start.Add(3 * time.Second))
+	rate, _ = sampler.SessionRate(1)
+	assert.Zero(t, rate.UplinkBps)
+
+	// Released sessions leave the aggregate
+	upfCtx.DeleteSession(1)
+	sampler.Sample(start.Add(4 * time.Second))
+	_, ok := sampler.SessionRate(1)
+	assert.False(t, ok)
+	assert.Equal(t, float64(0), testutil.ToFloat64(metrics.UPFActiveSessions))
+}
@@ -51,6 +51,7 @@ type EBPFTracer struct {
 	tracer     trace.Tracer
 	eventChan  chan *HTTPEvent
 	stopChan   chan struct{}
+	noop       bool // Loads nothing, see NewNoopTracer
 }
 
 // Config holds eBPF tracer configuration
@@ -72,13 +73,24 @@ func NewEBPFTracer(config *Config, logger *zap.Logger) (*EBPFTracer, error) {
 	}, nil
 }
 
-// Load loads and attaches eBPF programs
+// Load loads and attaches eBPF programs. It returns an error wrapping
+// ErrEBPFUnsupported when the host cannot run them.
 func (t *EBPFTracer) Load(ctx context.Context) error {
+	if t.noop {
+		return nil
+	}
+
 	ctx, span := t.tracer.Start(ctx, "EBPFTracer.Load")
 	defer span.End()
 
 	t.logger.Info("Loading eBPF programs", zap.String("nf", t.nfName))
 
+	// Check kernel support and privileges before loading anything
+	if err := probeSupport(); err != nil {
+		t.logger.Warn("eBPF tracing unavailable", zap.String("nf", t.nfName), zap.Error(err))
+		return err
+	}
+
 	// Load compiled eBPF object
 	spec, err := loadTracehttp()
 	if err != nil {
@@ -88,6 +100,9 @@ func (t *EBPFTracer) Load(ctx context.Context) error {
 	// Load into kernel
 	coll, err := ebpf.NewCollection(spec)
 	if err != nil {
+		if unsupported := unsupportedError(err); unsupported != nil {
+			return unsupported
+		}
 		return fmt.Errorf("failed to create eBPF collection: %w", err)
 	}
 	t.collection = coll
@@ -257,26 +272,18 @@ func (t *EBPFTracer) exportToOTel(event *HTTPEvent) {
 	path := string(bytes.TrimRight(event.Path[:], "\x00"))
 	traceparent := string(bytes.TrimRight(event.Traceparent[:], "\x00"))
 
-	// Parse W3C trace context from traceparent header
-	var traceID trace.TraceID
-	var spanID trace.SpanID
+	// Continue the caller's trace; without a valid traceparent the span
+	// starts a new trace
+	ctx := context.Background()
 	if traceparent != "" {
-		// Parse: 00-{trace-id}-{span-id}-{flags}
-		// For simplicity, using a basic parser
-		// Production would use proper W3C trace context parser
-		copy(traceID[:], traceparent[3:35])
-		copy(spanID[:], traceparent[36:52])
+		spanContext, err := parseTraceparent(traceparent)
+		if err != nil {
+			t.logger.Debug("Ignoring traceparent", zap.String("traceparent", traceparent), zap.Error(err))
+		} else {
+			ctx = trace.ContextWithRemoteSpanContext(ctx, spanContext)
+		}
 	}
 
-	// Create span context
-	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
-		TraceID:    traceID,
-		SpanID:     spanID,
-		TraceFlags: trace.FlagsSampled,
-	})
-
-	ctx := trace.ContextWithSpanContext(context.Background(), spanContext)
-
 	// Create span
 	_, span := t.tracer.Start(ctx, fmt.Sprintf("HTTP %s %s", method, path),
 		trace.WithTimestamp(nsToTime(event.TimestampNS)),
@@ -349,8 +356,14 @@ func nsToTime(ns uint64) time.Time {
 	return time.Unix(0, int64(ns))
 }
 
-// AttachToProcess attaches eBPF programs to a running process
+// AttachToProcess attaches eBPF programs to a running process. When the
+// host cannot run eBPF programs the error wraps ErrEBPFUnsupported; callers
+// then fall back to the OTel HTTP middleware or a tracer from NewNoopTracer.
 func AttachToProcess(pid int, config *Config, logger *zap.Logger) (*EBPFTracer, error) {
+	if err := probeSupport(); err != nil {
+		return nil, err
+	}
+
 	// Find binary path from PID
 	binaryPath, err := filepath.EvalSymlinks(fmt.Sprintf("/proc/%d/exe", pid))
 	if err != nil {
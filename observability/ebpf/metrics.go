@@ -0,0 +1,37 @@
+package ebpf
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/your-org/5g-network/common/metrics"
+)
+
+// RecordMetrics updates Prometheus RED metrics from the events of
+// GetEventChannel until ctx is cancelled or the channel is closed, so request
+// rates, errors and latencies are available without a tracing backend
+func RecordMetrics(ctx context.Context, events <-chan *HTTPEvent, m *metrics.EBPFHTTPMetrics) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			recordEvent(event, m)
+		}
+	}
+}
+
+// recordEvent records one HTTP event
+func recordEvent(event *HTTPEvent, m *metrics.EBPFHTTPMetrics) {
+	m.Observe(
+		string(bytes.TrimRight(event.Method[:], "\x00")),
+		string(bytes.TrimRight(event.Path[:], "\x00")),
+		int(event.StatusCode),
+		time.Duration(event.DurationNS),
+		int(event.ContentLength),
+	)
+}
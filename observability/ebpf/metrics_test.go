@@ -0,0 +1,102 @@
+package ebpf
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/5g-network/common/metrics"
+)
+
+func httpEvent(method, path string, status uint16, duration time.Duration, size uint32) *HTTPEvent {
+	event := &HTTPEvent{
+		StatusCode:    status,
+		DurationNS:    uint64(duration),
+		ContentLength: size,
+	}
+	copy(event.Method[:], method)
+	copy(event.Path[:], path)
+	return event
+}
+
+func TestRecordMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	m, err := metrics.NewEBPFHTTPMetrics("smf", metrics.EBPFHTTPBuckets{
+		Duration:     []float64{0.01, 0.1, 1},
+		ResponseSize: []float64{100, 1000},
+	}, registry)
+	require.NoError(t, err)
+
+	events := make(chan *HTTPEvent, 3)
+	events <- httpEvent("POST", "/nsmf-pdusession/v1/sm-contexts", 201, 5*time.Millisecond, 512)
+	events <- httpEvent("POST", "/nsmf-pdusession/v1/sm-contexts", 201, 50*time.Millisecond, 2048)
+	events <- httpEvent("GET", "/health", 500, 2*time.Second, 10)
+	close(events)
+
+	// Returns once the channel is closed
+	RecordMetrics(context.Background(), events, m)
+
+	expected := `
+# HELP ebpf_http_requests_total Total number of HTTP requests captured by eBPF
+# TYPE ebpf_http_requests_total counter
+ebpf_http_requests_total{method="GET",nf="smf",path="/health",status="500"} 1
+ebpf_http_requests_total{method="POST",nf="smf",path="/nsmf-pdusession/v1/sm-contexts",status="201"} 2
+# HELP ebpf_http_request_duration_seconds HTTP request latency in seconds captured by eBPF
+# TYPE ebpf_http_request_duration_seconds histogram
+ebpf_http_request_duration_seconds_bucket{method="GET",nf="smf",path="/health",status="500",le="0.01"} 0
+ebpf_http_request_duration_seconds_bucket{method="GET",nf="smf",path="/health",status="500",le="0.1"} 0
+ebpf_http_request_duration_seconds_bucket{method="GET",nf="smf",path="/health",status="500",le="1"} 0
+ebpf_http_request_duration_seconds_bucket{method="GET",nf="smf",path="/health",status="500",le="+Inf"} 1
+ebpf_http_request_duration_seconds_sum{method="GET",nf="smf",path="/health",status="500"} 2
+ebpf_http_request_duration_seconds_count{method="GET",nf="smf",path="/health",status="500"} 1
+ebpf_http_request_duration_seconds_bucket{method="POST",nf="smf",path="/nsmf-pdusession/v1/sm-contexts",status="201",le="0.01"} 1
+ebpf_http_request_duration_seconds_bucket{method="POST",nf="smf",path="/nsmf-pdusession/v1/sm-contexts",status="201",le="0.1"} 2
+ebpf_http_request_duration_seconds_bucket{method="POST",nf="smf",path="/nsmf-pdusession/v1/sm-contexts",status="201",le="1"} 2
+ebpf_http_request_duration_seconds_bucket{method="POST",nf="smf",path="/nsmf-pdusession/v1/sm-contexts",status="201",le="+Inf"} 2
+ebpf_http_request_duration_seconds_sum{method="POST",nf="smf",path="/nsmf-pdusession/v1/sm-contexts",status="201"} 0.055
+ebpf_http_request_duration_seconds_count{method="POST",nf="smf",path="/nsmf-pdusession/v1/sm-contexts",status="201"} 2
+# HELP ebpf_http_response_size_bytes HTTP response size in bytes captured by eBPF
+# TYPE ebpf_http_response_size_bytes histogram
+ebpf_http_response_size_bytes_bucket{method="GET",nf="smf",path="/health",le="100"} 1
+ebpf_http_response_size_bytes_bucket{method="GET",nf="smf",path="/health",le="1000"} 1
+ebpf_http_response_size_bytes_bucket{method="GET",nf="smf",path="/health",le="+Inf"} 1
+ebpf_http_response_size_bytes_sum{method="GET",nf="smf",path="/health"} 10
+ebpf_http_response_size_bytes_count{method="GET",nf="smf",path="/health"} 1
+ebpf_http_response_size_bytes_bucket{method="POST",nf="smf",path="/nsmf-pdusession/v1/sm-contexts",le="100"} 0
+ebpf_http_response_size_bytes_bucket{method="POST",nf="smf",path="/nsmf-pdusession/v1/sm-contexts",le="1000"} 1
+ebpf_http_response_size_bytes_bucket{method="POST",nf="smf",path="/nsmf-pdusession/v1/sm-contexts",le="+Inf"} 2
+ebpf_http_response_size_bytes_sum{method="POST",nf="smf",path="/nsmf-pdusession/v1/sm-contexts"} 2560
+ebpf_http_response_size_bytes_count{method="POST",nf="smf",path="/nsmf-pdusession/v1/sm-contexts"} 2
+`
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expected)))
+}
+
+func TestRecordMetricsStopsOnCancel(t *testing.T) {
+	m, err := metrics.NewEBPFHTTPMetrics("smf", metrics.EBPFHTTPBuckets{}, prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		RecordMetrics(ctx, make(chan *HTTPEvent), m)
+	}()
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("RecordMetrics did not return after cancellation")
+	}
+}
+
+func TestNewEBPFHTTPMetricsRejectsUnsortedBuckets(t *testing.T) {
+	_, err := metrics.NewEBPFHTTPMetrics("smf", metrics.EBPFHTTPBuckets{Duration: []float64{1, 0.1}}, prometheus.NewRegistry())
+	assert.Error(t, err)
+}
@@ -0,0 +1,43 @@
+package ebpf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/cilium/ebpf"
+	"go.uber.org/zap"
+)
+
+// ErrEBPFUnsupported is returned when eBPF programs cannot be loaded on this
+// host: the OS is not Linux, the kernel lacks the needed program types or
+// the process lacks CAP_BPF/CAP_SYS_ADMIN. Callers should fall back to the
+// OTel HTTP middleware.
+var ErrEBPFUnsupported = errors.New("eBPF tracing unsupported")
+
+// probeSupport checks that eBPF programs can be loaded, replaced in tests
+var probeSupport = probeEBPFSupport
+
+// unsupportedError returns err wrapped in ErrEBPFUnsupported when it reports
+// missing privileges or kernel support, nil otherwise
+func unsupportedError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrEBPFUnsupported):
+		return err
+	case errors.Is(err, os.ErrPermission):
+		return fmt.Errorf("%w: missing privileges (CAP_BPF or CAP_SYS_ADMIN required): %v", ErrEBPFUnsupported, err)
+	case errors.Is(err, ebpf.ErrNotSupported):
+		return fmt.Errorf("%w: kernel not supported: %v", ErrEBPFUnsupported, err)
+	}
+	return nil
+}
+
+// NewNoopTracer returns a tracer that loads nothing and never emits events,
+// for hosts where eBPF is unsupported
+func NewNoopTracer(config *Config, logger *zap.Logger) *EBPFTracer {
+	t, _ := NewEBPFTracer(config, logger)
+	t.noop = true
+	return t
+}
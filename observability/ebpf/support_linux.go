@@ -0,0 +1,23 @@
+//go:build linux
+
+package ebpf
+
+import (
+	"fmt"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/features"
+)
+
+// probeEBPFSupport loads a minimal kprobe program to check kernel support and
+// privileges. Inconclusive probe failures are reported as unsupported too.
+func probeEBPFSupport() error {
+	err := features.HaveProgramType(ebpf.Kprobe)
+	if err == nil {
+		return nil
+	}
+	if unsupported := unsupportedError(err); unsupported != nil {
+		return unsupported
+	}
+	return fmt.Errorf("%w: kprobe probe failed: %v", ErrEBPFUnsupported, err)
+}
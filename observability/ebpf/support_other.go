@@ -0,0 +1,13 @@
+//go:build !linux
+
+package ebpf
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// probeEBPFSupport reports eBPF as unsupported outside Linux
+func probeEBPFSupport() error {
+	return fmt.Errorf("%w: %s is not Linux", ErrEBPFUnsupported, runtime.GOOS)
+}
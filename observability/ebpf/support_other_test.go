@@ -0,0 +1,13 @@
+//go:build !linux
+
+package ebpf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProbeReportsNonLinuxUnsupported(t *testing.T) {
+	assert.ErrorIs(t, probeEBPFSupport(), ErrEBPFUnsupported)
+}
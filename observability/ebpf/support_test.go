@@ -0,0 +1,81 @@
+package ebpf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// withProbe replaces the support probe for the duration of a test
+func withProbe(t *testing.T, probe func() error) {
+	t.Helper()
+
+	previous := probeSupport
+	probeSupport = probe
+	t.Cleanup(func() { probeSupport = previous })
+}
+
+func unsupportedProbe() error {
+	return fmt.Errorf("%w: no CAP_BPF in test", ErrEBPFUnsupported)
+}
+
+func TestLoadReturnsErrEBPFUnsupported(t *testing.T) {
+	withProbe(t, unsupportedProbe)
+	logger, _ := zap.NewDevelopment()
+
+	tracer, err := NewEBPFTracer(&Config{NFName: "smf"}, logger)
+	require.NoError(t, err)
+
+	err = tracer.Load(context.Background())
+	assert.ErrorIs(t, err, ErrEBPFUnsupported)
+	assert.Nil(t, tracer.collection)
+	assert.NoError(t, tracer.Close())
+}
+
+func TestAttachToProcessSurfacesErrEBPFUnsupported(t *testing.T) {
+	withProbe(t, unsupportedProbe)
+	logger, _ := zap.NewDevelopment()
+
+	tracer, err := AttachToProcess(os.Getpid(), &Config{NFName: "smf"}, logger)
+	assert.ErrorIs(t, err, ErrEBPFUnsupported)
+	assert.Nil(t, tracer)
+}
+
+func TestUnsupportedErrorClassifiesLoadFailures(t *testing.T) {
+	err := unsupportedError(fmt.Errorf("load program: %w", syscall.EPERM))
+	assert.ErrorIs(t, err, ErrEBPFUnsupported)
+	assert.Contains(t, err.Error(), "missing privileges")
+
+	err = unsupportedError(fmt.Errorf("map type: %w", ebpf.ErrNotSupported))
+	assert.ErrorIs(t, err, ErrEBPFUnsupported)
+	assert.Contains(t, err.Error(), "kernel not supported")
+
+	assert.NoError(t, unsupportedError(errors.New("verifier rejected program")))
+	assert.NoError(t, unsupportedError(nil))
+}
+
+func TestNoopTracerIsUsable(t *testing.T) {
+	withProbe(t, func() error {
+		t.Fatal("no-op tracer must not probe eBPF support")
+		return nil
+	})
+	logger, _ := zap.NewDevelopment()
+
+	tracer := NewNoopTracer(&Config{NFName: "smf"}, logger)
+	require.NoError(t, tracer.Load(context.Background()))
+
+	select {
+	case event := <-tracer.GetEventChannel():
+		t.Fatalf("unexpected event %+v", event)
+	default:
+	}
+	assert.NoError(t, tracer.Close())
+}
@@ -0,0 +1,51 @@
+package ebpf
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceparentLength is the length of a version 00 traceparent header:
+// 00-{32 hex trace-id}-{16 hex parent-id}-{2 hex flags}
+const traceparentLength = 55
+
+var errInvalidTraceparent = errors.New("invalid traceparent")
+
+// parseTraceparent parses a W3C Trace Context traceparent header
+// (https://www.w3.org/TR/trace-context/#traceparent-header) into the remote
+// span context it carries
+func parseTraceparent(header string) (trace.SpanContext, error) {
+	if len(header) != traceparentLength {
+		return trace.SpanContext{}, fmt.Errorf("%w: length %d", errInvalidTraceparent, len(header))
+	}
+	if header[:3] != "00-" {
+		return trace.SpanContext{}, fmt.Errorf("%w: unsupported version %q", errInvalidTraceparent, header[:2])
+	}
+	if header[35] != '-' || header[52] != '-' {
+		return trace.SpanContext{}, fmt.Errorf("%w: malformed separators", errInvalidTraceparent)
+	}
+
+	// TraceIDFromHex and SpanIDFromHex reject non-hex and all-zero IDs
+	traceID, err := trace.TraceIDFromHex(header[3:35])
+	if err != nil {
+		return trace.SpanContext{}, fmt.Errorf("%w: trace-id: %v", errInvalidTraceparent, err)
+	}
+	spanID, err := trace.SpanIDFromHex(header[36:52])
+	if err != nil {
+		return trace.SpanContext{}, fmt.Errorf("%w: parent-id: %v", errInvalidTraceparent, err)
+	}
+	flags, err := hex.DecodeString(header[53:55])
+	if err != nil {
+		return trace.SpanContext{}, fmt.Errorf("%w: trace-flags: %v", errInvalidTraceparent, err)
+	}
+
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.TraceFlags(flags[0]),
+		Remote:     true,
+	}), nil
+}
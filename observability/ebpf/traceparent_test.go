@@ -0,0 +1,43 @@
+package ebpf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	sc, err := parseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	require.NoError(t, err)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanID().String())
+	assert.True(t, sc.IsSampled())
+	assert.True(t, sc.IsRemote())
+	assert.True(t, sc.IsValid())
+
+	sc, err = parseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	require.NoError(t, err)
+	assert.Equal(t, trace.TraceFlags(0), sc.TraceFlags())
+}
+
+func TestParseTraceparentRejectsMalformedHeaders(t *testing.T) {
+	for name, header := range map[string]string{
+		"empty":            "",
+		"truncated":        "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba9",
+		"too long":         "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"unknown version":  "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"bad separator":    "00-4bf92f3577b34da6a3ce929d0e0e4736_00f067aa0ba902b7-01",
+		"non-hex trace-id": "00-4bf92f3577b34da6a3ce929d0e0e47zz-00f067aa0ba902b7-01",
+		"non-hex span-id":  "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902zz-01",
+		"non-hex flags":    "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0x",
+		"zero trace-id":    "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"zero span-id":     "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseTraceparent(header)
+			assert.ErrorIs(t, err, errInvalidTraceparent)
+		})
+	}
+}
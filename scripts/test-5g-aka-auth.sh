@@ -105,22 +105,22 @@ echo -e "  ${YELLOW}AUTN:${NC}      $AUTN"
 echo ""
 
 #━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
-# STEP 2: Get HXRES* for Testing (Simulates UE Computing RES*)
+# STEP 2: Get XRES* for Testing (Simulates UE Computing RES*)
 #━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
 
 echo -e "${CYAN}━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━${NC}"
-echo -e "${CYAN}  STEP 2: [TEST] Get HXRES* from Authentication Context${NC}"
+echo -e "${CYAN}  STEP 2: [TEST] Get XRES* from Authentication Context${NC}"
 echo -e "${CYAN}━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━${NC}"
 echo ""
 echo -e "${YELLOW}Note:${NC} In a real deployment, the UE would compute RES* using RAND,"
-echo -e "      its permanent key (K), and OPc. For testing, we retrieve HXRES*"
+echo -e "      its permanent key (K), and OPc. For testing, we retrieve XRES*"
 echo -e "      from AUSF's test endpoint."
 echo ""
 
 TEST_RESPONSE=$(curl -s "${AUSF_URL}/admin/test/auth-context/${AUTH_CTX_ID}")
 
 # Check if request was successful
-if ! echo "$TEST_RESPONSE" | jq -e '.hxres' > /dev/null 2>&1; then
+if ! echo "$TEST_RESPONSE" | jq -e '.xresStar' > /dev/null 2>&1; then
     echo -e "${RED}Error: Failed to get authentication context${NC}"
     echo "$TEST_RESPONSE" | jq .
     exit 1
@@ -131,9 +131,9 @@ echo ""
 echo "$TEST_RESPONSE" | jq .
 echo ""
 
-HXRES=$(echo "$TEST_RESPONSE" | jq -r '.hxres')
+XRES_STAR=$(echo "$TEST_RESPONSE" | jq -r '.xresStar')
 
-echo -e "${BLUE}Using HXRES* as RES*:${NC} $HXRES"
+echo -e "${BLUE}Using XRES* as RES*:${NC} $XRES_STAR"
 echo ""
 
 #━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
@@ -147,7 +147,7 @@ echo ""
 
 CONFIRM_REQUEST=$(cat <<EOF
 {
-  "resStar": "$HXRES"
+  "resStar": "$XRES_STAR"
 }
 EOF
 )
@@ -121,13 +121,13 @@ echo -e "      2. Compute RES* using RAND, its permanent key (K), and OPc"
 echo -e "      3. Verify AUTN to authenticate the network"
 echo -e "      4. Send RES* back to AMF"
 echo ""
-echo -e "${BLUE}For testing, we retrieve HXRES* from AUSF test endpoint...${NC}"
+echo -e "${BLUE}For testing, we retrieve XRES* from AUSF test endpoint...${NC}"
 echo ""
 
 TEST_RESPONSE=$(curl -s "${AUSF_URL}/admin/test/auth-context/${AUTH_CTX_ID}")
 
 # Check if request was successful
-if ! echo "$TEST_RESPONSE" | jq -e '.hxres' > /dev/null 2>&1; then
+if ! echo "$TEST_RESPONSE" | jq -e '.xresStar' > /dev/null 2>&1; then
     echo -e "${RED}Error: Failed to get authentication context${NC}"
     echo "$TEST_RESPONSE" | jq .
     exit 1
@@ -136,10 +136,10 @@ fi
 echo -e "${GREEN}✓ Retrieved test context${NC}"
 echo ""
 
-HXRES=$(echo "$TEST_RESPONSE" | jq -r '.hxres')
+XRES_STAR=$(echo "$TEST_RESPONSE" | jq -r '.xresStar')
 
 echo -e "${MAGENTA}UE Computed (simulated):${NC}"
-echo -e "  ${YELLOW}RES*:${NC} $HXRES"
+echo -e "  ${YELLOW}RES*:${NC} $XRES_STAR"
 echo ""
 
 #━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━
@@ -153,13 +153,13 @@ echo ""
 
 CONFIRM_REQUEST=$(cat <<EOF
 {
-  "resStar": "$HXRES"
+  "resStar": "$XRES_STAR"
 }
 EOF
 )
 
 echo -e "${YELLOW}Sending RES* to AMF for verification...${NC}"
-echo -e "${BLUE}Flow: UE → AMF → AUSF (verify RES* == HXRES*)${NC}"
+echo -e "${BLUE}Flow: UE → AMF → AUSF (verify RES* == XRES*)${NC}"
 echo ""
 
 CONFIRM_RESPONSE=$(curl -s -X PUT \
@@ -320,7 +320,7 @@ echo -e "  ${CYAN}8.${NC} AMF: Receive challenge (RAND, AUTN)"
 echo -e "  ${CYAN}9.${NC} [UE: Compute RES* from RAND]"
 echo -e "  ${CYAN}10.${NC} UE → AMF: Send RES*"
 echo -e "  ${CYAN}11.${NC} AMF → AUSF: Confirm authentication"
-echo -e "  ${CYAN}12.${NC} AUSF: Verify RES* == HXRES*"
+echo -e "  ${CYAN}12.${NC} AUSF: Verify RES* == XRES*"
 echo -e "  ${CYAN}13.${NC} AMF: Establish security context (KSEAF)"
 echo -e "  ${CYAN}14.${NC} UE → AMF: Registration request"
 echo -e "  ${CYAN}15.${NC} AMF: Assign GUAMI, TAI, S-NSSAI"
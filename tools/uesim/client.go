@@ -0,0 +1,156 @@
+package uesim
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// ErrAuthenticationRejected is returned when the network rejects the RES*
+// of the UE
+var ErrAuthenticationRejected = errors.New("authentication rejected")
+
+// ErrKeyMismatch is returned when the KSEAF of the network differs from the
+// one derived by the USIM
+var ErrKeyMismatch = errors.New("KSEAF mismatch")
+
+// Client drives the authentication (namf-auth) and registration
+// (namf-reg) endpoints of the AMF as the UE of a USIM
+type Client struct {
+	amfURL             string
+	servingNetworkName string
+	usim               *USIM
+	client             *http.Client
+}
+
+// NewClient creates a UE registering with the AMF at amfURL. The serving
+// network name must be the one the AMF authenticates UEs for, e.g.
+// "5G:mnc01.mcc001.3gppnetwork.org".
+func NewClient(amfURL, servingNetworkName string, usim *USIM) *Client {
+	return &Client{
+		amfURL:             amfURL,
+		servingNetworkName: servingNetworkName,
+		usim:               usim,
+		client:             &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// AuthenticationResult is the outcome of a successful authentication
+type AuthenticationResult struct {
+	SUPI     string
+	Response *Response
+}
+
+// Authenticate runs 5G AKA through the AMF: it requests a challenge for
+// identity, a SUPI or SUCI, answers it with the RES* of the USIM and checks
+// that the network derived the same KSEAF. A challenge the USIM refuses
+// returns its error, a *SyncFailure on a stale SQN; the AMF test API cannot
+// carry the failure back to the network.
+func (c *Client) Authenticate(ctx context.Context, identity string) (*AuthenticationResult, error) {
+	var challenge struct {
+		AuthType  string `json:"authType"`
+		AuthCtxID string `json:"authCtxId"`
+		RAND      string `json:"rand"`
+		AUTN      string `json:"autn"`
+	}
+	err := c.do(ctx, http.MethodPost, "/namf-auth/v1/authenticate", map[string]string{"supi": identity}, &challenge, http.StatusCreated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request challenge: %w", err)
+	}
+
+	rand, err := hex.DecodeString(challenge.RAND)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RAND: %w", err)
+	}
+	autn, err := hex.DecodeString(challenge.AUTN)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AUTN: %w", err)
+	}
+
+	resp, err := c.usim.Authenticate(rand, autn, c.servingNetworkName)
+	if err != nil {
+		return nil, err
+	}
+
+	var confirm struct {
+		Result string `json:"result"`
+		SUPI   string `json:"supi"`
+		KSEAF  string `json:"kseaf"`
+	}
+	path := "/namf-auth/v1/authenticate/" + challenge.AuthCtxID + "/confirm"
+	err = c.do(ctx, http.MethodPut, path, map[string]string{"resStar": hex.EncodeToString(resp.RESStar)}, &confirm, http.StatusOK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to confirm authentication: %w", err)
+	}
+	if confirm.Result != "SUCCESS" {
+		return nil, ErrAuthenticationRejected
+	}
+	if confirm.KSEAF != "" && confirm.KSEAF != hex.EncodeToString(resp.KSEAF) {
+		return nil, ErrKeyMismatch
+	}
+
+	return &AuthenticationResult{SUPI: confirm.SUPI, Response: resp}, nil
+}
+
+// RegistrationResult is the answer of the AMF to a registration, reduced to
+// what tests check
+type RegistrationResult struct {
+	Result string `json:"result"`
+	SUPI   string `json:"supi"`
+	GUTI   string `json:"guti"`
+	T3512  int    `json:"t3512"`
+	Reason string `json:"reason"`
+}
+
+// Register registers an authenticated UE with the AMF. registrationType is
+// "INITIAL", "MOBILITY", "PERIODIC" or "EMERGENCY". A rejected registration
+// is returned with Result "FAILURE" and its reason.
+func (c *Client) Register(ctx context.Context, supi, registrationType string) (*RegistrationResult, error) {
+	req := map[string]string{
+		"supi":             supi,
+		"registrationType": registrationType,
+	}
+
+	var result RegistrationResult
+	if err := c.do(ctx, http.MethodPost, "/namf-reg/v1/register", req, &result, http.StatusCreated, http.StatusForbidden); err != nil {
+		return nil, fmt.Errorf("failed to register: %w", err)
+	}
+	return &result, nil
+}
+
+// do sends a JSON request to the AMF and decodes the response, which must
+// have one of the expected statuses
+func (c *Client) do(ctx context.Context, method, path string, body, result interface{}, expected ...int) error {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, method, c.amfURL+path, bytes.NewReader(encoded))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if !slices.Contains(expected, resp.StatusCode) {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("AMF returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
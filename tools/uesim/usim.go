@@ -0,0 +1,166 @@
+// Package uesim is a reference UE for tests: a USIM running 5G AKA with
+// MILENAGE (TS 33.501 6.1.3.2), and a client driving the authentication and
+// registration endpoints of the AMF with it.
+package uesim
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/your-org/5g-network/common/crypto/kdf"
+	"github.com/your-org/5g-network/common/crypto/milenage"
+)
+
+// DefaultSQNDelta is the largest step from the highest SQN accepted that a
+// challenge may make before it is considered out of range (TS 33.102 C.2.1)
+const DefaultSQNDelta = 1 << 28
+
+// maxSQN is the largest 48-bit sequence number
+const maxSQN = 1<<48 - 1
+
+// Authentication failures of the USIM (TS 24.501 5.4.1.3.7)
+var (
+	ErrMACFailure          = errors.New("MAC failure")
+	ErrNon5GAuthentication = errors.New("non-5G authentication unacceptable")
+	ErrSyncFailure         = errors.New("synch failure")
+)
+
+// SyncFailure is returned when the SQN of a challenge is not fresh. The
+// network resynchronises with AUTS, which carries the SQN of the USIM.
+type SyncFailure struct {
+	AUTS []byte
+}
+
+func (e *SyncFailure) Error() string {
+	return fmt.Sprintf("%v: AUTS %x", ErrSyncFailure, e.AUTS)
+}
+
+// Unwrap makes a SyncFailure match ErrSyncFailure
+func (e *SyncFailure) Unwrap() error {
+	return ErrSyncFailure
+}
+
+// Response is the answer of a USIM to an accepted challenge, with the keys
+// derived from it
+type Response struct {
+	RESStar  []byte // Sent to the network
+	HRESStar []byte // What the SEAF compares with HXRES*
+	KAUSF    []byte
+	KSEAF    []byte
+}
+
+// USIM holds the subscription credentials of a UE and the highest SQN it
+// has accepted
+type USIM struct {
+	SUPI string
+
+	milenage *milenage.Milenage
+	sqnDelta uint64
+
+	mu    sync.Mutex
+	sqnMS uint64
+}
+
+// NewUSIM creates a USIM for subscriber key k and operator variant OPc
+// whose highest accepted SQN is sqn, the SQN the network last used
+func NewUSIM(supi string, k, opc []byte, sqn uint64) (*USIM, error) {
+	m, err := milenage.New(k, opc)
+	if err != nil {
+		return nil, err
+	}
+	if sqn > maxSQN {
+		return nil, fmt.Errorf("SQN %d exceeds 48 bits", sqn)
+	}
+	return &USIM{SUPI: supi, milenage: m, sqnDelta: DefaultSQNDelta, sqnMS: sqn}, nil
+}
+
+// SQN returns the highest SQN the USIM accepted
+func (u *USIM) SQN() uint64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.sqnMS
+}
+
+// Authenticate runs the USIM side of 5G AKA on a challenge: it verifies
+// that AUTN was generated by the home network (MAC-A and the AMF separation
+// bit) and carries a fresh SQN, then computes RES* for the serving network
+// and the keys of the new security context. A stale SQN fails with a
+// *SyncFailure carrying AUTS.
+func (u *USIM) Authenticate(rand, autn []byte, servingNetworkName string) (*Response, error) {
+	if len(autn) != milenage.AUTNLen {
+		return nil, fmt.Errorf("AUTN has invalid length %d", len(autn))
+	}
+
+	res, ck, ik, ak, err := u.milenage.F2345(rand)
+	if err != nil {
+		return nil, err
+	}
+
+	sqnXorAK, amf, mac := autn[:milenage.SQNLen], autn[6:8], autn[8:]
+	sqn := make([]byte, milenage.SQNLen)
+	for i := range sqn {
+		sqn[i] = sqnXorAK[i] ^ ak[i]
+	}
+
+	xmac, _, err := u.milenage.F1(rand, sqn, amf)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(xmac, mac) != 1 {
+		return nil, ErrMACFailure
+	}
+
+	// Vectors for 5G have the AMF separation bit set (TS 33.501 Annex A.1)
+	if amf[0]&0x80 == 0 {
+		return nil, ErrNon5GAuthentication
+	}
+
+	if err := u.acceptSQN(rand, sqn); err != nil {
+		return nil, err
+	}
+
+	resStar, err := kdf.XRESStar(ck, ik, servingNetworkName, rand, res)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive RES*: %w", err)
+	}
+	kausf, err := kdf.KAUSF(ck, ik, servingNetworkName, sqnXorAK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive KAUSF: %w", err)
+	}
+	kseaf, err := kdf.KSEAF(kausf, servingNetworkName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive KSEAF: %w", err)
+	}
+
+	return &Response{
+		RESStar:  resStar,
+		HRESStar: kdf.HXRESStar(rand, resStar),
+		KAUSF:    kausf,
+		KSEAF:    kseaf,
+	}, nil
+}
+
+// acceptSQN moves SQN_MS to the SQN of a challenge when it is greater and
+// within the allowed step, otherwise it returns a *SyncFailure
+func (u *USIM) acceptSQN(rand, sqn []byte) error {
+	value := binary.BigEndian.Uint64(append([]byte{0, 0}, sqn...))
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if value > u.sqnMS && value-u.sqnMS <= u.sqnDelta {
+		u.sqnMS = value
+		return nil
+	}
+
+	sqnMS := make([]byte, 8)
+	binary.BigEndian.PutUint64(sqnMS, u.sqnMS)
+	auts, err := u.milenage.GenerateAUTS(rand, sqnMS[2:])
+	if err != nil {
+		return err
+	}
+	return &SyncFailure{AUTS: auts}
+}
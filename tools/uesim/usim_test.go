@@ -0,0 +1,209 @@
+package uesim
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/your-org/5g-network/common/crypto/kdf"
+	"github.com/your-org/5g-network/common/crypto/milenage"
+)
+
+// TS 35.208 4.3 test set 1
+const (
+	testK    = "465b5ce8b199b49faa5f0a2ee238a6bc"
+	testOPc  = "cd63cb71954a9f4e48a5994e37a02baf"
+	testRAND = "23553cbe9637a89d218ae64dae47bf35"
+	testSQN  = 0xff9bb4d0b607
+	testAMF  = "b9b9"
+	testRES  = "a54211d5e3ba50bf"
+	testCK   = "b40ba9a3c58b2a05bbf0d987b21bf8cb"
+	testIK   = "f769bcd751044604127672711c6d3441"
+
+	testSNN = "5G:mnc001.mcc001.3gppnetwork.org"
+)
+
+func decode(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+// sqnBytes returns a 48-bit SQN as 6 bytes
+func sqnBytes(sqn uint64) []byte {
+	return []byte{byte(sqn >> 40), byte(sqn >> 32), byte(sqn >> 24), byte(sqn >> 16), byte(sqn >> 8), byte(sqn)}
+}
+
+// challenge returns the AUTN the home network generates for an SQN
+func challenge(t *testing.T, sqn uint64, amf string) (rand, autn []byte) {
+	t.Helper()
+	m, err := milenage.New(decode(t, testK), decode(t, testOPc))
+	require.NoError(t, err)
+	rand = decode(t, testRAND)
+	autn, _, _, _, _, err = m.GenerateAUTN(rand, sqnBytes(sqn), decode(t, amf))
+	require.NoError(t, err)
+	return rand, autn
+}
+
+func newTestUSIM(t *testing.T, sqn uint64) *USIM {
+	t.Helper()
+	usim, err := NewUSIM("imsi-001010000000001", decode(t, testK), decode(t, testOPc), sqn)
+	require.NoError(t, err)
+	return usim
+}
+
+func TestAuthenticate(t *testing.T) {
+	usim := newTestUSIM(t, testSQN-1)
+	rand, autn := challenge(t, testSQN, testAMF)
+
+	resp, err := usim.Authenticate(rand, autn, testSNN)
+	require.NoError(t, err)
+
+	// RES* and HRES* are derived from the TS 35.208 RES, CK and IK
+	xresStar, err := kdf.XRESStar(decode(t, testCK), decode(t, testIK), testSNN, rand, decode(t, testRES))
+	require.NoError(t, err)
+	assert.Equal(t, xresStar, resp.RESStar)
+	assert.Equal(t, kdf.HXRESStar(rand, xresStar), resp.HRESStar)
+
+	kausf, err := kdf.KAUSF(decode(t, testCK), decode(t, testIK), testSNN, autn[:milenage.SQNLen])
+	require.NoError(t, err)
+	assert.Equal(t, kausf, resp.KAUSF)
+	assert.Len(t, resp.KSEAF, 32)
+	assert.Equal(t, uint64(testSQN), usim.SQN())
+}
+
+func TestAuthenticate_MACFailure(t *testing.T) {
+	usim := newTestUSIM(t, testSQN-1)
+	rand, autn := challenge(t, testSQN, testAMF)
+	autn[15] ^= 0x01
+
+	_, err := usim.Authenticate(rand, autn, testSNN)
+	assert.ErrorIs(t, err, ErrMACFailure)
+	assert.Equal(t, uint64(testSQN-1), usim.SQN())
+}
+
+func TestAuthenticate_Non5GVector(t *testing.T) {
+	usim := newTestUSIM(t, testSQN-1)
+	rand, autn := challenge(t, testSQN, "0000")
+
+	_, err := usim.Authenticate(rand, autn, testSNN)
+	assert.ErrorIs(t, err, ErrNon5GAuthentication)
+}
+
+func TestAuthenticate_SyncFailure(t *testing.T) {
+	tests := []struct {
+		name  string
+		sqnMS uint64
+	}{
+		{name: "replayed SQN", sqnMS: testSQN},
+		{name: "older SQN", sqnMS: testSQN + 32},
+		{name: "SQN out of range", sqnMS: testSQN - DefaultSQNDelta - 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usim := newTestUSIM(t, tt.sqnMS)
+			rand, autn := challenge(t, testSQN, testAMF)
+
+			_, err := usim.Authenticate(rand, autn, testSNN)
+			require.ErrorIs(t, err, ErrSyncFailure)
+
+			// The home network recovers SQN_MS from AUTS
+			var syncFailure *SyncFailure
+			require.True(t, errors.As(err, &syncFailure))
+			m, err := milenage.New(decode(t, testK), decode(t, testOPc))
+			require.NoError(t, err)
+			sqnMS, err := m.RecoverSQN(rand, syncFailure.AUTS)
+			require.NoError(t, err)
+			assert.Equal(t, sqnBytes(tt.sqnMS), sqnMS)
+			assert.Equal(t, tt.sqnMS, usim.SQN())
+		})
+	}
+}
+
+// newFakeAMF serves the AMF authentication and registration endpoints for
+// one challenge, accepting the expected RES*
+func newFakeAMF(t *testing.T, rand, autn, xresStar, kseaf []byte) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /namf-auth/v1/authenticate", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{
+			"authType":  "5G_AKA",
+			"authCtxId": "ctx-1",
+			"rand":      hex.EncodeToString(rand),
+			"autn":      hex.EncodeToString(autn),
+		})
+	})
+	mux.HandleFunc("PUT /namf-auth/v1/authenticate/ctx-1/confirm", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			RESStar string `json:"resStar"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		result := map[string]string{"result": "FAILURE"}
+		if req.RESStar == hex.EncodeToString(xresStar) {
+			result = map[string]string{
+				"result": "SUCCESS",
+				"supi":   "imsi-001010000000001",
+				"kseaf":  hex.EncodeToString(kseaf),
+			}
+		}
+		json.NewEncoder(w).Encode(result)
+	})
+	mux.HandleFunc("POST /namf-reg/v1/register", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"result": "SUCCESS",
+			"supi":   "imsi-001010000000001",
+			"guti":   "5g-guti-00101cafe000000001",
+			"t3512":  3240,
+		})
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient_AuthenticateAndRegister(t *testing.T) {
+	rand, autn := challenge(t, testSQN, testAMF)
+	expected, err := newTestUSIM(t, testSQN-1).Authenticate(rand, autn, testSNN)
+	require.NoError(t, err)
+	amf := newFakeAMF(t, rand, autn, expected.RESStar, expected.KSEAF)
+
+	client := NewClient(amf.URL, testSNN, newTestUSIM(t, testSQN-1))
+	auth, err := client.Authenticate(context.Background(), "imsi-001010000000001")
+	require.NoError(t, err)
+	assert.Equal(t, "imsi-001010000000001", auth.SUPI)
+	assert.Equal(t, expected.KSEAF, auth.Response.KSEAF)
+
+	reg, err := client.Register(context.Background(), auth.SUPI, "INITIAL")
+	require.NoError(t, err)
+	assert.Equal(t, "SUCCESS", reg.Result)
+	assert.Equal(t, 3240, reg.T3512)
+
+	// The network answers the same challenge again: the USIM asks to
+	// resynchronise instead of reusing the SQN
+	_, err = client.Authenticate(context.Background(), "imsi-001010000000001")
+	assert.ErrorIs(t, err, ErrSyncFailure)
+}
+
+func TestClient_WrongServingNetwork(t *testing.T) {
+	rand, autn := challenge(t, testSQN, testAMF)
+	expected, err := newTestUSIM(t, testSQN-1).Authenticate(rand, autn, testSNN)
+	require.NoError(t, err)
+	amf := newFakeAMF(t, rand, autn, expected.RESStar, expected.KSEAF)
+
+	client := NewClient(amf.URL, "5G:mnc002.mcc001.3gppnetwork.org", newTestUSIM(t, testSQN-1))
+	_, err = client.Authenticate(context.Background(), "imsi-001010000000001")
+	assert.ErrorIs(t, err, ErrAuthenticationRejected)
+}